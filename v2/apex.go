@@ -0,0 +1,84 @@
+package exif
+
+import (
+	"math"
+)
+
+// These convert between the APEX (Additive System of Photographic Exposure)
+// values that EXIF stores in ShutterSpeedValue, ApertureValue,
+// BrightnessValue, and related tags, and conventional units. APEX values are
+// logarithmic, in stops, so that a correct exposure satisfies:
+//
+//   Ev = Av + Tv = Bv + Sv
+
+const (
+	// ApexSpeedConstant is the ratio between an arithmetic ISO speed and
+	// 2^Sv. ISO 100 is an Sv of 5.
+	ApexSpeedConstant = 3.125
+
+	// ReflectedLightMeterConstant (K) is the calibration constant of
+	// reflected-light meters, for luminance in cd/m^2. Canon, Nikon, and
+	// Sekonic use 12.5.
+	ReflectedLightMeterConstant = 12.5
+)
+
+// ApexAvFromFNumber returns the aperture value (Av) for an f-number:
+// 2 * log2(N).
+func ApexAvFromFNumber(fNumber float64) float64 {
+	return 2 * math.Log2(fNumber)
+}
+
+// ApexFNumberFromAv returns the f-number for an aperture value (Av):
+// 2^(Av/2).
+func ApexFNumberFromAv(av float64) float64 {
+	return math.Pow(2, av/2)
+}
+
+// ApexTvFromExposureTime returns the time value (Tv) for an exposure time in
+// seconds: -log2(t).
+func ApexTvFromExposureTime(exposureTime float64) float64 {
+	return -math.Log2(exposureTime)
+}
+
+// ApexExposureTimeFromTv returns the exposure time in seconds for a time
+// value (Tv): 2^-Tv.
+func ApexExposureTimeFromTv(tv float64) float64 {
+	return math.Pow(2, -tv)
+}
+
+// ApexSvFromIso returns the speed value (Sv) for an arithmetic ISO speed:
+// log2(ISO / 3.125).
+func ApexSvFromIso(iso float64) float64 {
+	return math.Log2(iso / ApexSpeedConstant)
+}
+
+// ApexIsoFromSv returns the arithmetic ISO speed for a speed value (Sv):
+// 3.125 * 2^Sv.
+func ApexIsoFromSv(sv float64) float64 {
+	return ApexSpeedConstant * math.Pow(2, sv)
+}
+
+// ApexBvFromLuminance returns the brightness value (Bv) for a luminance in
+// cd/m^2: log2(L * 3.125 / K).
+func ApexBvFromLuminance(luminance float64) float64 {
+	return math.Log2(luminance * ApexSpeedConstant / ReflectedLightMeterConstant)
+}
+
+// ApexLuminanceFromBv returns the luminance in cd/m^2 for a brightness value
+// (Bv): 2^Bv * K / 3.125.
+func ApexLuminanceFromBv(bv float64) float64 {
+	return math.Pow(2, bv) * ReflectedLightMeterConstant / ApexSpeedConstant
+}
+
+// ApexEvFromExposure returns the exposure value (Ev = Av + Tv) of an f-number
+// and an exposure time in seconds.
+func ApexEvFromExposure(fNumber, exposureTime float64) float64 {
+	return ApexAvFromFNumber(fNumber) + ApexTvFromExposureTime(exposureTime)
+}
+
+// ApexBvFromExposure returns the brightness value (Bv = Av + Tv - Sv) of a
+// scene that was correctly exposed with the given f-number, exposure time in
+// seconds, and arithmetic ISO speed.
+func ApexBvFromExposure(fNumber, exposureTime, iso float64) float64 {
+	return ApexEvFromExposure(fNumber, exposureTime) - ApexSvFromIso(iso)
+}
@@ -0,0 +1,83 @@
+package exif
+
+import (
+	"math"
+	"testing"
+)
+
+func apexValueEquals(actual, expected float64) bool {
+	return math.Abs(actual-expected) < 0.01
+}
+
+func TestApex_Av(t *testing.T) {
+	values := map[float64]float64{
+		1:   0,
+		1.4: 1,
+		2:   2,
+		2.8: 3,
+		8:   6,
+		22:  9,
+	}
+
+	// The marked f-numbers are rounded, so they're only within a tenth of a
+	// stop.
+	for fNumber, av := range values {
+		if actual := ApexAvFromFNumber(fNumber); math.Abs(actual-av) > 0.1 {
+			t.Fatalf("Av for f/%.1f not correct: (%f)", fNumber, actual)
+		} else if actual := ApexFNumberFromAv(av); math.Abs(actual-fNumber) > 0.05*fNumber {
+			t.Fatalf("f-number for Av (%f) not correct: (%f)", av, actual)
+		}
+	}
+}
+
+func TestApex_Tv(t *testing.T) {
+	values := map[float64]float64{
+		1:        0,
+		2:        -1,
+		1.0 / 8:  3,
+		1.0 / 64: 6,
+	}
+
+	for exposureTime, tv := range values {
+		if actual := ApexTvFromExposureTime(exposureTime); apexValueEquals(actual, tv) == false {
+			t.Fatalf("Tv for (%f) not correct: (%f)", exposureTime, actual)
+		} else if actual := ApexExposureTimeFromTv(tv); apexValueEquals(actual, exposureTime) == false {
+			t.Fatalf("Exposure time for Tv (%f) not correct: (%f)", tv, actual)
+		}
+	}
+}
+
+func TestApex_Sv(t *testing.T) {
+	values := map[float64]float64{
+		100:  5,
+		200:  6,
+		3200: 10,
+	}
+
+	for iso, sv := range values {
+		if actual := ApexSvFromIso(iso); apexValueEquals(actual, sv) == false {
+			t.Fatalf("Sv for ISO (%f) not correct: (%f)", iso, actual)
+		} else if actual := ApexIsoFromSv(sv); apexValueEquals(actual, iso) == false {
+			t.Fatalf("ISO for Sv (%f) not correct: (%f)", sv, actual)
+		}
+	}
+}
+
+func TestApex_Bv(t *testing.T) {
+	// Sunny 16: f/16 at 1/100s and ISO 100, which is about Ev 15.
+	bv := ApexBvFromExposure(16, 1.0/100, 100)
+
+	if ev := ApexEvFromExposure(16, 1.0/100); apexValueEquals(ev, 14.64) == false {
+		t.Fatalf("Ev not correct: (%f)", ev)
+	} else if apexValueEquals(bv, 9.64) == false {
+		t.Fatalf("Bv not correct: (%f)", bv)
+	}
+
+	luminance := ApexLuminanceFromBv(bv)
+
+	if apexValueEquals(ApexBvFromLuminance(luminance), bv) == false {
+		t.Fatalf("Luminance not reversible: (%f)", luminance)
+	} else if luminance < 3000 || luminance > 4000 {
+		t.Fatalf("Luminance not correct: (%f)", luminance)
+	}
+}
@@ -0,0 +1,184 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"io"
+	"path"
+	"strings"
+
+	"archive/tar"
+	"archive/zip"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	archiveLogger = log.NewLogger("exif.archive")
+)
+
+var (
+	// archiveImageExtensions are the extensions of entries that we'll look
+	// for EXIF in.
+	archiveImageExtensions = map[string]struct{}{
+		".jpg":  {},
+		".jpeg": {},
+		".jpe":  {},
+		".tif":  {},
+		".tiff": {},
+		".heic": {},
+		".heif": {},
+		".png":  {},
+		".webp": {},
+		".dng":  {},
+		".cr2":  {},
+		".nef":  {},
+		".arw":  {},
+		".orf":  {},
+		".rw2":  {},
+	}
+)
+
+// ArchiveEntry is the result of extracting EXIF from one image in an archive.
+type ArchiveEntry struct {
+	// Name is the path of the entry within the archive.
+	Name string
+
+	// Header is the EXIF header. It's only valid if `Err` is nil.
+	Header ExifHeader
+
+	// Tags are the tags that were found.
+	Tags []ExifTag
+
+	// Err is the error encountered while parsing this entry, if any. It'll be
+	// `ErrNoExif` if the image didn't have any EXIF.
+	Err error
+}
+
+// ArchiveEntryHandlerFn receives the result for each image in an archive.
+// Returning an error stops the walk.
+type ArchiveEntryHandlerFn func(ae ArchiveEntry) (err error)
+
+// IsArchiveImageName returns true if the filename has an extension that we
+// look for EXIF in.
+func IsArchiveImageName(name string) bool {
+	extension := strings.ToLower(path.Ext(name))
+	_, found := archiveImageExtensions[extension]
+
+	return found
+}
+
+// ExtractTarArchive walks a tar stream and calls the handler with the EXIF
+// for every image entry. Each entry is parsed as it streams past, and the
+// stream is only read once. Wrap the reader with `gzip.NewReader()` for
+// compressed archives. A failure to parse one entry is reported via
+// `ArchiveEntry.Err` rather than stopping the walk.
+func ExtractTarArchive(r io.Reader, ifdMapping *IfdMapping, tagIndex *TagIndex, handler ArchiveEntryHandlerFn) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		log.PanicIf(err)
+
+		if header.Typeflag != tar.TypeReg || IsArchiveImageName(header.Name) == false {
+			continue
+		}
+
+		ae := extractArchiveEntry(header.Name, tr, ifdMapping, tagIndex)
+
+		err = handler(ae)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// ExtractZipArchive walks a zip archive and calls the handler with the EXIF
+// for every image entry. Zip requires random access since the directory is at
+// the end. A failure to parse one entry is reported via `ArchiveEntry.Err`
+// rather than stopping the walk.
+func ExtractZipArchive(r io.ReaderAt, size int64, ifdMapping *IfdMapping, tagIndex *TagIndex, handler ArchiveEntryHandlerFn) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	zr, err := zip.NewReader(r, size)
+	log.PanicIf(err)
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() == true || IsArchiveImageName(f.Name) == false {
+			continue
+		}
+
+		var ae ArchiveEntry
+
+		rc, err := f.Open()
+		if err != nil {
+			ae = ArchiveEntry{
+				Name: f.Name,
+				Err:  err,
+			}
+		} else {
+			ae = extractArchiveEntry(f.Name, rc, ifdMapping, tagIndex)
+			rc.Close()
+		}
+
+		err = handler(ae)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// extractArchiveEntry parses the EXIF from a single entry. Errors are
+// returned in the result.
+func extractArchiveEntry(name string, r io.Reader, ifdMapping *IfdMapping, tagIndex *TagIndex) (ae ArchiveEntry) {
+	ae = ArchiveEntry{
+		Name: name,
+		Tags: make([]ExifTag, 0),
+	}
+
+	visitor := func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) (err error) {
+		defer func() {
+			if state := recover(); state != nil {
+				err = log.Wrap(state.(error))
+			}
+		}()
+
+		et, ok, err := newExifTag(tagIndex, ite.IfdPath(), ite)
+		log.PanicIf(err)
+
+		if ok == true {
+			ae.Tags = append(ae.Tags, et)
+		}
+
+		return nil
+	}
+
+	eh, err := ParseProgressively(r, ifdMapping, tagIndex, visitor)
+	if err != nil {
+		if err != ErrNoExif {
+			archiveLogger.Warningf(nil, "Could not parse EXIF from archive entry [%s]: %s", name, err)
+		}
+
+		ae.Err = err
+		return ae
+	}
+
+	ae.Header = eh
+
+	return ae
+}
@@ -0,0 +1,141 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"archive/tar"
+	"archive/zip"
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+)
+
+type archiveTestFile struct {
+	name string
+	data []byte
+}
+
+func getArchiveTestFiles() []archiveTestFile {
+	imageData, err := ioutil.ReadFile(getTestImageFilepath())
+	log.PanicIf(err)
+
+	return []archiveTestFile{
+		{name: "DCIM/100CANON/IMG_0001.JPG", data: imageData},
+		{name: "notes.txt", data: []byte("not an image")},
+		{name: "DCIM/100CANON/IMG_0002.jpg", data: []byte{0xff, 0xd8, 0xff, 0xd9}},
+	}
+}
+
+func checkArchiveEntries(t *testing.T, entries []ArchiveEntry) {
+	if len(entries) != 2 {
+		t.Fatalf("Entry count not correct: (%d)", len(entries))
+	}
+
+	if entries[0].Name != "DCIM/100CANON/IMG_0001.JPG" {
+		t.Fatalf("First entry not correct: [%s]", entries[0].Name)
+	} else if entries[0].Err != nil {
+		t.Fatalf("First entry had an error: %v", entries[0].Err)
+	}
+
+	found := false
+	for _, et := range entries[0].Tags {
+		if et.TagName == "Model" {
+			if et.Value != "Canon EOS 5D Mark III" {
+				t.Fatalf("Model not correct: [%v]", et.Value)
+			}
+
+			found = true
+		}
+	}
+
+	if found == false {
+		t.Fatalf("Model tag not found.")
+	}
+
+	if entries[1].Name != "DCIM/100CANON/IMG_0002.jpg" {
+		t.Fatalf("Second entry not correct: [%s]", entries[1].Name)
+	} else if entries[1].Err != ErrNoExif {
+		t.Fatalf("Second entry should not have had EXIF: %v", entries[1].Err)
+	}
+}
+
+func TestExtractTarArchive(t *testing.T) {
+	b := new(bytes.Buffer)
+	tw := tar.NewWriter(b)
+
+	for _, atf := range getArchiveTestFiles() {
+		header := &tar.Header{
+			Name:     atf.name,
+			Mode:     0644,
+			Size:     int64(len(atf.data)),
+			Typeflag: tar.TypeReg,
+		}
+
+		err := tw.WriteHeader(header)
+		log.PanicIf(err)
+
+		_, err = tw.Write(atf.data)
+		log.PanicIf(err)
+	}
+
+	err := tw.Close()
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	entries := make([]ArchiveEntry, 0)
+	handler := func(ae ArchiveEntry) (err error) {
+		entries = append(entries, ae)
+		return nil
+	}
+
+	err = ExtractTarArchive(b, im, ti, handler)
+	log.PanicIf(err)
+
+	checkArchiveEntries(t, entries)
+}
+
+func TestExtractZipArchive(t *testing.T) {
+	b := new(bytes.Buffer)
+	zw := zip.NewWriter(b)
+
+	for _, atf := range getArchiveTestFiles() {
+		w, err := zw.Create(atf.name)
+		log.PanicIf(err)
+
+		_, err = w.Write(atf.data)
+		log.PanicIf(err)
+	}
+
+	err := zw.Close()
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	entries := make([]ArchiveEntry, 0)
+	handler := func(ae ArchiveEntry) (err error) {
+		entries = append(entries, ae)
+		return nil
+	}
+
+	data := b.Bytes()
+
+	err = ExtractZipArchive(bytes.NewReader(data), int64(len(data)), im, ti, handler)
+	log.PanicIf(err)
+
+	checkArchiveEntries(t, entries)
+}
+
+func TestIsArchiveImageName(t *testing.T) {
+	if IsArchiveImageName("a/b/IMG_0001.JPG") != true {
+		t.Fatalf("Expected image.")
+	} else if IsArchiveImageName("a/b/readme.md") != false {
+		t.Fatalf("Expected non-image.")
+	}
+}
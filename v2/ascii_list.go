@@ -0,0 +1,179 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// AsciiListSeparator separates the values of ASCII tags that can have
+	// more than one, such as Artist.
+	AsciiListSeparator = ";"
+
+	// copyrightSeparator separates the photographer's copyright from the
+	// editor's in the Copyright tag.
+	copyrightSeparator = "\x00"
+)
+
+// SplitAsciiList returns the values of a semicolon-separated ASCII tag with
+// their surrounding whitespace trimmed. Blank values are dropped.
+func SplitAsciiList(value string) []string {
+	values := make([]string, 0)
+
+	for _, part := range strings.Split(value, AsciiListSeparator) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		values = append(values, part)
+	}
+
+	return values
+}
+
+// JoinAsciiList returns the values joined the way `SplitAsciiList()` splits
+// them. A value that itself contains a semicolon is treated as more than one,
+// so that joining is stable across a split.
+func JoinAsciiList(values []string) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, SplitAsciiList(value)...)
+	}
+
+	return strings.Join(parts, AsciiListSeparator+" ")
+}
+
+// SplitCopyright returns the photographer's and the editor's copyright from
+// the Copyright tag, which separates them with a NUL. The spec has a lone
+// space stand in for a missing photographer's copyright, which is returned as
+// empty.
+func SplitCopyright(value string) (photographer, editor string) {
+	parts := strings.SplitN(value, copyrightSeparator, 2)
+
+	photographer = strings.TrimSpace(parts[0])
+
+	if len(parts) > 1 {
+		editor = strings.TrimSpace(strings.TrimRight(parts[1], copyrightSeparator))
+	}
+
+	return photographer, editor
+}
+
+// JoinCopyright returns the Copyright tag value for the photographer's and
+// the editor's copyright (see `SplitCopyright()`).
+func JoinCopyright(photographer, editor string) string {
+	photographer = strings.TrimSpace(photographer)
+	editor = strings.TrimSpace(editor)
+
+	if editor == "" {
+		return photographer
+	} else if photographer == "" {
+		photographer = " "
+	}
+
+	return photographer + copyrightSeparator + editor
+}
+
+// GetAsciiList returns the values of a semicolon-separated ASCII tag in the
+// IFD. The list is empty if the tag isn't present.
+func GetAsciiList(ifd *Ifd, tagId uint16) (values []string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	results, err := ifd.FindTagWithId(tagId)
+	if err != nil {
+		if log.Is(err, ErrTagNotFound) == true {
+			return make([]string, 0), nil
+		}
+
+		log.Panic(err)
+	}
+
+	raw, err := results[0].Value()
+	log.PanicIf(err)
+
+	s, ok := raw.(string)
+	if ok == false {
+		log.Panicf("tag is not a string: (0x%04x)", tagId)
+	}
+
+	return SplitAsciiList(s), nil
+}
+
+// GetBuilderAsciiList returns the values of a semicolon-separated ASCII tag
+// in the builder. The list is empty if the tag isn't present.
+func GetBuilderAsciiList(ib *IfdBuilder, tagId uint16) (values []string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	bt, err := ib.FindTag(tagId)
+	if err != nil {
+		if log.Is(err, ErrTagEntryNotFound) == true {
+			return make([]string, 0), nil
+		}
+
+		log.Panic(err)
+	}
+
+	if bt.Value().IsBytes() == false {
+		log.Panicf("tag is not a string: (0x%04x)", tagId)
+	}
+
+	return SplitAsciiList(strings.TrimRight(string(bt.Value().Bytes()), "\x00")), nil
+}
+
+// SetAsciiList writes the values into a semicolon-separated ASCII tag (see
+// `JoinAsciiList()`). The tag is removed if there are no values.
+func SetAsciiList(ib *IfdBuilder, tagId uint16, values []string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	value := JoinAsciiList(values)
+
+	if value == "" {
+		_, err := ib.DeleteAll(tagId)
+		log.PanicIf(err)
+
+		return nil
+	}
+
+	err = ib.SetStandard(tagId, value)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// AddToAsciiList appends the values to a semicolon-separated ASCII tag,
+// creating it if necessary. Values that are already present, ignoring case,
+// aren't added again.
+func AddToAsciiList(ib *IfdBuilder, tagId uint16, values ...string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	existing, err := GetBuilderAsciiList(ib, tagId)
+	log.PanicIf(err)
+
+	all := SplitAsciiList(JoinAsciiList(append(existing, values...)))
+
+	err = SetAsciiList(ib, tagId, dedupeKeywords(all))
+	log.PanicIf(err)
+
+	return nil
+}
@@ -0,0 +1,104 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestSplitAsciiList(t *testing.T) {
+	values := SplitAsciiList(" Jane Doe;John Doe ;; ")
+
+	if reflect.DeepEqual(values, []string{"Jane Doe", "John Doe"}) != true {
+		t.Fatalf("Values not correct: %v", values)
+	} else if values := SplitAsciiList(""); len(values) != 0 {
+		t.Fatalf("Expected no values: %v", values)
+	}
+}
+
+func TestJoinAsciiList(t *testing.T) {
+	value := JoinAsciiList([]string{" Jane Doe ", "", "John Doe;Max Mustermann"})
+
+	if value != "Jane Doe; John Doe; Max Mustermann" {
+		t.Fatalf("Value not correct: [%s]", value)
+	} else if JoinAsciiList(SplitAsciiList(value)) != value {
+		t.Fatalf("Joining not stable across a split.")
+	}
+}
+
+func TestSplitCopyright(t *testing.T) {
+	tests := []struct {
+		value        string
+		photographer string
+		editor       string
+	}{
+		{"(c) Jane Doe", "(c) Jane Doe", ""},
+		{"(c) Jane Doe\x00(c) John Doe", "(c) Jane Doe", "(c) John Doe"},
+		{" \x00(c) John Doe", "", "(c) John Doe"},
+	}
+
+	for _, test := range tests {
+		photographer, editor := SplitCopyright(test.value)
+
+		if photographer != test.photographer || editor != test.editor {
+			t.Fatalf("Copyright not split correctly: [%q] => [%s] [%s]", test.value, photographer, editor)
+		} else if JoinCopyright(photographer, editor) != test.value {
+			t.Fatalf("Copyright not joined correctly: [%q]", JoinCopyright(photographer, editor))
+		}
+	}
+}
+
+func TestSetAsciiList(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := SetAsciiList(rootIb, ArtistTagId, []string{"Jane Doe", "John Doe"})
+	log.PanicIf(err)
+
+	err = AddToAsciiList(rootIb, ArtistTagId, "john doe", "Max Mustermann")
+	log.PanicIf(err)
+
+	artists, err := GetBuilderAsciiList(rootIb, ArtistTagId)
+	log.PanicIf(err)
+
+	expected := []string{"Jane Doe", "John Doe", "Max Mustermann"}
+	if reflect.DeepEqual(artists, expected) != true {
+		t.Fatalf("Builder values not correct: %v", artists)
+	}
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	artists, err = GetAsciiList(index.RootIfd, ArtistTagId)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(artists, expected) != true {
+		t.Fatalf("Decoded values not correct: %v", artists)
+	}
+
+	copyrights, err := GetAsciiList(index.RootIfd, CopyrightTagId)
+	log.PanicIf(err)
+
+	if len(copyrights) != 0 {
+		t.Fatalf("Expected no values for a missing tag: %v", copyrights)
+	}
+
+	// No values removes the tag.
+	err = SetAsciiList(rootIb, ArtistTagId, []string{" "})
+	log.PanicIf(err)
+
+	if _, err := rootIb.FindTag(ArtistTagId); log.Is(err, ErrTagEntryNotFound) != true {
+		t.Fatalf("Expected tag to be removed: %v", err)
+	}
+}
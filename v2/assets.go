@@ -0,0 +1,333 @@
+package exif
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// AssetImage is an image file.
+	AssetImage = "image"
+
+	// AssetSound is a sound file (e.g. a voice memo).
+	AssetSound = "sound"
+
+	// AssetEmbeddedImage is an image stored inside another file and listed
+	// in its MPF index.
+	AssetEmbeddedImage = "embedded-image"
+)
+
+const (
+	// AssetLinkRelatedSound is an image's RelatedSoundFile reference.
+	AssetLinkRelatedSound = "related-sound"
+
+	// AssetLinkRelatedImage is a sound file's reference to its image ("erel").
+	AssetLinkRelatedImage = "related-image"
+
+	// AssetLinkEmbedded links a file to an image embedded in it.
+	AssetLinkEmbedded = "embedded"
+
+	// AssetLinkMpfDependent is an MPF dependent-image reference (e.g. between
+	// the views of a stereo pair).
+	AssetLinkMpfDependent = "mpf-dependent"
+)
+
+var (
+	assetsLogger = log.NewLogger("exif.assets")
+)
+
+// Asset is a node in an `AssetGraph`.
+type Asset struct {
+	// Kind is one of the Asset* constants.
+	Kind string
+
+	// Filepath is the file that the asset is or is stored in.
+	Filepath string
+
+	// Missing is true if the asset was referenced but the file wasn't found.
+	// Filepath is then the name as it was referenced, in the directory of the
+	// file that referenced it.
+	Missing bool
+
+	// MpfIndex, Offset, and Length locate an embedded image: its zero-based
+	// position in the MPF index and where it is in the file.
+	MpfIndex int
+	Offset   int64
+	Length   int64
+}
+
+// String returns a descriptive string.
+func (asset Asset) String() string {
+	if asset.Kind == AssetEmbeddedImage {
+		return fmt.Sprintf("Asset<KIND=[%s] FILEPATH=[%s] MPF-INDEX=(%d) OFFSET=(%d) LENGTH=(%d)>", asset.Kind, asset.Filepath, asset.MpfIndex, asset.Offset, asset.Length)
+	}
+
+	return fmt.Sprintf("Asset<KIND=[%s] FILEPATH=[%s] MISSING=[%v]>", asset.Kind, asset.Filepath, asset.Missing)
+}
+
+// AssetLink is a directed edge in an `AssetGraph`. `From` and `To` are
+// indices into `Assets`.
+type AssetLink struct {
+	Kind string
+	From int
+	To   int
+}
+
+// AssetGraph is the files (and embedded images) that reference each other.
+type AssetGraph struct {
+	Assets []Asset
+	Links  []AssetLink
+
+	fileAssets map[string]int
+}
+
+// fileAsset returns the index of the asset for the file, adding it if it's
+// not already present.
+func (ag *AssetGraph) fileAsset(kind, filepath string, missing bool) int {
+	if i, found := ag.fileAssets[filepath]; found == true {
+		return i
+	}
+
+	ag.Assets = append(ag.Assets, Asset{
+		Kind:     kind,
+		Filepath: filepath,
+		Missing:  missing,
+	})
+
+	i := len(ag.Assets) - 1
+	ag.fileAssets[filepath] = i
+
+	return i
+}
+
+// addLink adds a link unless it's already present.
+func (ag *AssetGraph) addLink(kind string, from, to int) {
+	for _, link := range ag.Links {
+		if link.Kind == kind && link.From == from && link.To == to {
+			return
+		}
+	}
+
+	ag.Links = append(ag.Links, AssetLink{
+		Kind: kind,
+		From: from,
+		To:   to,
+	})
+}
+
+// Companions returns the other files that are linked, directly or
+// indirectly and in either direction, to the given file. These are the files
+// to move along with it. Missing files aren't included.
+func (ag AssetGraph) Companions(filepath string) []string {
+	companions := make([]string, 0)
+
+	start, found := ag.fileAssets[filepath]
+	if found == false {
+		return companions
+	}
+
+	visited := map[int]bool{start: true}
+	queue := []int{start}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, link := range ag.Links {
+			var next int
+
+			if link.From == current {
+				next = link.To
+			} else if link.To == current {
+				next = link.From
+			} else {
+				continue
+			}
+
+			if visited[next] == true {
+				continue
+			}
+
+			visited[next] = true
+			queue = append(queue, next)
+
+			asset := ag.Assets[next]
+			if asset.Kind != AssetEmbeddedImage && asset.Missing == false {
+				companions = append(companions, asset.Filepath)
+			}
+		}
+	}
+
+	return companions
+}
+
+// Missing returns the assets that were referenced but not found.
+func (ag AssetGraph) Missing() []Asset {
+	missing := make([]Asset, 0)
+
+	for _, asset := range ag.Assets {
+		if asset.Missing == true {
+			missing = append(missing, asset)
+		}
+	}
+
+	return missing
+}
+
+// addRelated links the asset to the file referenced from it, which is looked
+// for next to it.
+func (ag *AssetGraph) addRelated(from int, linkKind, assetKind, filepath, filename string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	relatedFilepath, err := FindRelatedFile(path.Dir(filepath), filename)
+	missing := false
+
+	if err == ErrRelatedFileNotFound {
+		relatedFilepath = path.Join(path.Dir(filepath), path.Base(filename))
+		missing = true
+	} else if err != nil {
+		log.Panic(err)
+	}
+
+	to := ag.fileAsset(assetKind, relatedFilepath, missing)
+	ag.addLink(linkKind, from, to)
+
+	return nil
+}
+
+// addImage adds the references from an image file: its RelatedSoundFile and
+// the images in its MPF index.
+func (ag *AssetGraph) addImage(filepath string, data []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	from := ag.fileAsset(AssetImage, filepath, false)
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == nil {
+		_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+		if err != nil {
+			assetsLogger.Warningf(nil, "Could not parse EXIF of [%s]: %s", filepath, err)
+		} else {
+			filename, err := GetRelatedSoundFile(index)
+			log.PanicIf(err)
+
+			if filename != "" {
+				err := ag.addRelated(from, AssetLinkRelatedSound, AssetSound, filepath, filename)
+				log.PanicIf(err)
+			}
+		}
+	} else if err != ErrNoExif {
+		log.Panic(err)
+	}
+
+	entries, err := readMpfEntries(data)
+	log.PanicIf(err)
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// The primary image is the file itself.
+	mpfAssets := make([]int, len(entries))
+	mpfAssets[0] = from
+
+	for i := 1; i < len(entries); i++ {
+		ag.Assets = append(ag.Assets, Asset{
+			Kind:     AssetEmbeddedImage,
+			Filepath: filepath,
+			MpfIndex: i,
+			Offset:   entries[i].offset,
+			Length:   entries[i].length,
+		})
+
+		mpfAssets[i] = len(ag.Assets) - 1
+		ag.addLink(AssetLinkEmbedded, from, mpfAssets[i])
+	}
+
+	for i, me := range entries {
+		for _, dependent := range me.dependents {
+			if dependent == 0 {
+				continue
+			} else if int(dependent) > len(entries) {
+				assetsLogger.Warningf(nil, "MPF entry (%d) of [%s] depends on an entry that doesn't exist: (%d)", i, filepath, dependent)
+				continue
+			}
+
+			ag.addLink(AssetLinkMpfDependent, mpfAssets[i], mpfAssets[dependent-1])
+		}
+	}
+
+	return nil
+}
+
+// addSound adds the reference from a sound file to its image.
+func (ag *AssetGraph) addSound(filepath string, data []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	from := ag.fileAsset(AssetSound, filepath, false)
+
+	ae, err := ParseWavExif(data)
+	if err == ErrNoAudioExif {
+		return nil
+	}
+
+	log.PanicIf(err)
+
+	if ae.RelatedImageFile != "" {
+		err := ag.addRelated(from, AssetLinkRelatedImage, AssetImage, filepath, ae.RelatedImageFile)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// BuildAssetGraph reads the given files and links them by their
+// RelatedSoundFile tags, the related-image references of WAV files, and
+// their MPF indices. Referenced files are looked for next to the file that
+// references them and are added to the graph even if they weren't given.
+// Files that have no metadata are added without any links.
+//
+// The Interoperability IFD's RelatedImageFileFormat tag only describes the
+// format of a related image, not its name, so it isn't followed.
+func BuildAssetGraph(filepaths []string) (ag AssetGraph, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ag.Assets = make([]Asset, 0)
+	ag.Links = make([]AssetLink, 0)
+	ag.fileAssets = make(map[string]int)
+
+	for _, filepath := range filepaths {
+		data, err := ioutil.ReadFile(filepath)
+		log.PanicIf(err)
+
+		if len(data) >= 12 && bytes.Equal(data[:4], riffSignature) == true && bytes.Equal(data[8:12], riffWaveSignature) == true {
+			err := ag.addSound(filepath, data)
+			log.PanicIf(err)
+		} else {
+			err := ag.addImage(filepath, data)
+			log.PanicIf(err)
+		}
+	}
+
+	return ag, nil
+}
@@ -0,0 +1,143 @@
+package exif
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// getTestJpegWithRelatedSoundFile returns a JPEG whose EXIF references the
+// given sound file.
+func getTestJpegWithRelatedSoundFile(filename string) []byte {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	err = exifIb.SetStandard(RelatedSoundFileTagId, filename)
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	data := []byte{jpegMarkerPrefix, jpegMarkerSoi}
+	data = append(data, getTestJpegSegment(jpegMarkerApp1, append(append([]byte{}, jpegExifPreamble...), exifData...))...)
+	data = append(data, jpegMarkerPrefix, jpegMarkerEoi)
+
+	return data
+}
+
+// getTestMpfJpegWithDependent returns the MPF test JPEG with the primary
+// image marked as depending on the secondary one.
+func getTestMpfJpegWithDependent() []byte {
+	data := getTestMpfJpeg()
+
+	primaryEntry := make([]byte, 4)
+	binary.BigEndian.PutUint32(primaryEntry, 0x20030000)
+
+	i := bytes.Index(data, primaryEntry)
+	binary.BigEndian.PutUint16(data[i+12:], 2)
+
+	return data
+}
+
+func TestBuildAssetGraph(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	imageFilepath := path.Join(tempPath, "IMG_0001.JPG")
+	soundFilepath := path.Join(tempPath, "SND00001.WAV")
+	orphanFilepath := path.Join(tempPath, "IMG_0002.JPG")
+	mpfFilepath := path.Join(tempPath, "IMG_0003.JPG")
+
+	err = ioutil.WriteFile(imageFilepath, getTestJpegWithRelatedSoundFile("SND00001.WAV"), 0644)
+	log.PanicIf(err)
+
+	// The sound file refers to "IMG_0001.JPG" as well.
+	err = ioutil.WriteFile(soundFilepath, getTestWavWithExif(), 0644)
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(orphanFilepath, getTestJpegWithRelatedSoundFile("SND00002.WAV"), 0644)
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(mpfFilepath, getTestMpfJpegWithDependent(), 0644)
+	log.PanicIf(err)
+
+	ag, err := BuildAssetGraph([]string{imageFilepath, soundFilepath, orphanFilepath, mpfFilepath})
+	log.PanicIf(err)
+
+	// The image and the sound file refer to each other.
+	companions := ag.Companions(imageFilepath)
+	if reflect.DeepEqual(companions, []string{soundFilepath}) != true {
+		t.Fatalf("Image companions not correct: %v", companions)
+	}
+
+	companions = ag.Companions(soundFilepath)
+	if reflect.DeepEqual(companions, []string{imageFilepath}) != true {
+		t.Fatalf("Sound companions not correct: %v", companions)
+	}
+
+	kinds := make([]string, 0)
+	for _, link := range ag.Links {
+		kinds = append(kinds, link.Kind)
+	}
+
+	sort.Strings(kinds)
+
+	expectedKinds := []string{
+		AssetLinkEmbedded,
+		AssetLinkMpfDependent,
+		AssetLinkRelatedImage,
+		AssetLinkRelatedSound,
+		AssetLinkRelatedSound,
+	}
+
+	if reflect.DeepEqual(kinds, expectedKinds) != true {
+		t.Fatalf("Links not correct: %v", kinds)
+	}
+
+	missing := ag.Missing()
+	if len(missing) != 1 || missing[0].Filepath != path.Join(tempPath, "SND00002.WAV") || missing[0].Kind != AssetSound {
+		t.Fatalf("Missing assets not correct: %v", missing)
+	}
+
+	// The orphan's sound file is missing, so it has no companions.
+	if len(ag.Companions(orphanFilepath)) != 0 {
+		t.Fatalf("Orphan should not have companions: %v", ag.Companions(orphanFilepath))
+	}
+
+	for _, link := range ag.Links {
+		if link.Kind != AssetLinkMpfDependent {
+			continue
+		}
+
+		from := ag.Assets[link.From]
+		to := ag.Assets[link.To]
+
+		if from.Filepath != mpfFilepath || from.Kind != AssetImage {
+			t.Fatalf("Dependent link source not correct: %s", from)
+		} else if to.Kind != AssetEmbeddedImage || to.MpfIndex != 1 {
+			t.Fatalf("Dependent link target not correct: %s", to)
+		}
+	}
+
+	// Embedded images aren't separate files.
+	if len(ag.Companions(mpfFilepath)) != 0 {
+		t.Fatalf("MPF image should not have companions: %v", ag.Companions(mpfFilepath))
+	}
+}
@@ -0,0 +1,328 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+const (
+	// RelatedSoundFileTagId is the ID of the RelatedSoundFile tag in the EXIF
+	// IFD.
+	RelatedSoundFileTagId = 0xa004
+)
+
+var (
+	// ErrNotWav means that the data isn't a RIFF WAVE file.
+	ErrNotWav = errors.New("not a wav file")
+
+	// ErrNoAudioExif means that the WAV file doesn't have an EXIF list.
+	ErrNoAudioExif = errors.New("no audio exif data")
+
+	// ErrRelatedFileNotFound means that a related file is referenced but
+	// isn't next to the file that references it.
+	ErrRelatedFileNotFound = errors.New("related file not found")
+)
+
+var (
+	riffSignature     = []byte("RIFF")
+	riffWaveSignature = []byte("WAVE")
+	riffListChunkId   = "LIST"
+	riffExifListType  = "exif"
+	riffInfoListType  = "INFO"
+)
+
+// AudioExif is the EXIF that a camera records in the "exif" list of a WAV
+// file, such as a voice memo attached to an image.
+type AudioExif struct {
+	// Version is "ever" (e.g. "0220").
+	Version string
+
+	// RelatedImageFile is "erel", the name of the image that the audio
+	// belongs to.
+	RelatedImageFile string
+
+	// Timestamp is "etim", the time the recording was made.
+	Timestamp string
+
+	// Make and Model are "ecor" and "emdl".
+	Make  string
+	Model string
+
+	// MakerNote is "emnt".
+	MakerNote []byte
+
+	// UserComment is "eucm", decoded the same way as the UserComment tag.
+	UserComment string
+
+	// Info is the standard RIFF INFO list (e.g. "ICRD" and "IART"), if
+	// present.
+	Info map[string]string
+}
+
+// String returns a descriptive string.
+func (ae AudioExif) String() string {
+	return fmt.Sprintf("AudioExif<VERSION=[%s] RELATED-IMAGE=[%s] TIMESTAMP=[%s] MAKE=[%s] MODEL=[%s]>", ae.Version, ae.RelatedImageFile, ae.Timestamp, ae.Make, ae.Model)
+}
+
+// riffChunk is one chunk of a RIFF file.
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// parseRiffChunks parses a sequence of chunks, each padded to an even size.
+func parseRiffChunks(data []byte) (chunks []riffChunk, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	chunks = make([]riffChunk, 0)
+
+	for i := 0; i+8 <= len(data); {
+		id := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+
+		i += 8
+
+		if size < 0 || size > len(data)-i {
+			log.Panicf("RIFF chunk [%s] truncated", id)
+		}
+
+		chunks = append(chunks, riffChunk{
+			id:   id,
+			data: data[i : i+size],
+		})
+
+		i += size
+		if size%2 == 1 {
+			i++
+		}
+	}
+
+	return chunks, nil
+}
+
+// riffString returns the NUL-terminated string in a chunk.
+func riffString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i != -1 {
+		data = data[:i]
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// decodeAudioUserComment decodes an "eucm" chunk, which has the same
+// character-code prefix as the UserComment tag.
+func decodeAudioUserComment(data []byte) string {
+	if len(data) < 8 {
+		return riffString(data)
+	}
+
+	for encodingType, encodingBytes := range exifundefined.TagUndefinedType_9286_UserComment_Encodings {
+		if bytes.Equal(data[:8], encodingBytes) == true {
+			uc := exifundefined.Tag9286UserComment{
+				EncodingType:  encodingType,
+				EncodingBytes: data[8:],
+			}
+
+			// JIS and undefined comments are usually ASCII in practice.
+			if text := uc.Text(); text != "" {
+				return text
+			}
+
+			return riffString(data[8:])
+		}
+	}
+
+	return riffString(data)
+}
+
+// ParseWavExif reads the EXIF list (and the INFO list, if present) of a WAV
+// file. `ErrNoAudioExif` is returned if there's no EXIF list.
+func ParseWavExif(data []byte) (ae AudioExif, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(data) < 12 || bytes.Equal(data[:4], riffSignature) == false || bytes.Equal(data[8:12], riffWaveSignature) == false {
+		log.Panic(ErrNotWav)
+	}
+
+	// Tolerate a RIFF size that is off, which some recorders write.
+	size := int(binary.LittleEndian.Uint32(data[4:]))
+	end := len(data)
+	if size >= 4 && 8+size < end {
+		end = 8 + size
+	}
+
+	chunks, err := parseRiffChunks(data[12:end])
+	log.PanicIf(err)
+
+	ae.Info = make(map[string]string)
+	found := false
+
+	for _, chunk := range chunks {
+		if chunk.id != riffListChunkId || len(chunk.data) < 4 {
+			continue
+		}
+
+		listType := string(chunk.data[:4])
+		if listType != riffExifListType && listType != riffInfoListType {
+			continue
+		}
+
+		subchunks, err := parseRiffChunks(chunk.data[4:])
+		log.PanicIf(err)
+
+		if listType == riffInfoListType {
+			for _, subchunk := range subchunks {
+				ae.Info[subchunk.id] = riffString(subchunk.data)
+			}
+
+			continue
+		}
+
+		found = true
+
+		for _, subchunk := range subchunks {
+			switch subchunk.id {
+			case "ever":
+				ae.Version = riffString(subchunk.data)
+			case "erel":
+				ae.RelatedImageFile = riffString(subchunk.data)
+			case "etim":
+				ae.Timestamp = riffString(subchunk.data)
+			case "ecor":
+				ae.Make = riffString(subchunk.data)
+			case "emdl":
+				ae.Model = riffString(subchunk.data)
+			case "emnt":
+				ae.MakerNote = subchunk.data
+			case "eucm":
+				ae.UserComment = decodeAudioUserComment(subchunk.data)
+			}
+		}
+	}
+
+	if found == false {
+		return ae, ErrNoAudioExif
+	}
+
+	return ae, nil
+}
+
+// GetRelatedSoundFile returns the RelatedSoundFile tag, or an empty string if
+// it isn't present.
+func GetRelatedSoundFile(index IfdIndex) (filename string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifds := index.Lookup[exifcommon.IfdPathStandardExif]
+	if len(ifds) == 0 {
+		return "", nil
+	}
+
+	filename, err = getIfdTagString(ifds[0], "RelatedSoundFile")
+	log.PanicIf(err)
+
+	return filename, nil
+}
+
+// FindRelatedFile returns the path of the file with the given name in the
+// given directory. Cameras record these names in 8.3 upper-case (e.g.
+// "SND00001.WAV"), so the match isn't case-sensitive. `ErrRelatedFileNotFound`
+// is returned if there isn't one.
+func FindRelatedFile(dirpath, filename string) (filepath string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	// Only the name is meaningful. Don't let it point elsewhere.
+	filename = path.Base(strings.Replace(filename, "\\", "/", -1))
+
+	files, err := ioutil.ReadDir(dirpath)
+	log.PanicIf(err)
+
+	for _, fi := range files {
+		if fi.IsDir() == false && strings.EqualFold(fi.Name(), filename) == true {
+			return path.Join(dirpath, fi.Name()), nil
+		}
+	}
+
+	return "", ErrRelatedFileNotFound
+}
+
+// ResolveRelatedSoundFile returns the path of the sound file referenced by an
+// image's RelatedSoundFile tag. `ErrRelatedFileNotFound` is returned if the
+// tag isn't present or the file isn't next to the image.
+func ResolveRelatedSoundFile(imageFilepath string, index IfdIndex) (soundFilepath string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	filename, err := GetRelatedSoundFile(index)
+	log.PanicIf(err)
+
+	if filename == "" {
+		return "", ErrRelatedFileNotFound
+	}
+
+	soundFilepath, err = FindRelatedFile(path.Dir(imageFilepath), filename)
+	if err != nil {
+		if err == ErrRelatedFileNotFound {
+			return "", err
+		}
+
+		log.Panic(err)
+	}
+
+	return soundFilepath, nil
+}
+
+// ResolveRelatedImageFile returns the path of the image referenced by a sound
+// file's EXIF. `ErrRelatedFileNotFound` is returned if there's no reference or
+// the image isn't next to the sound file.
+func ResolveRelatedImageFile(soundFilepath string, ae AudioExif) (imageFilepath string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ae.RelatedImageFile == "" {
+		return "", ErrRelatedFileNotFound
+	}
+
+	imageFilepath, err = FindRelatedFile(path.Dir(soundFilepath), ae.RelatedImageFile)
+	if err != nil {
+		if err == ErrRelatedFileNotFound {
+			return "", err
+		}
+
+		log.Panic(err)
+	}
+
+	return imageFilepath, nil
+}
@@ -0,0 +1,150 @@
+package exif
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func writeTestRiffChunk(b *bytes.Buffer, id string, data []byte) {
+	b.WriteString(id)
+	binary.Write(b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+
+	if len(data)%2 == 1 {
+		b.WriteByte(0)
+	}
+}
+
+func getTestWavWithExif() []byte {
+	exifList := new(bytes.Buffer)
+	exifList.WriteString("exif")
+	writeTestRiffChunk(exifList, "ever", []byte("0220"))
+	writeTestRiffChunk(exifList, "erel", []byte("IMG_0001.JPG\x00"))
+	writeTestRiffChunk(exifList, "etim", []byte("10:20:30.00\x00"))
+	writeTestRiffChunk(exifList, "ecor", []byte("Canon\x00"))
+	writeTestRiffChunk(exifList, "emdl", []byte("Canon EOS 5D\x00"))
+	writeTestRiffChunk(exifList, "eucm", []byte("ASCII\x00\x00\x00Birthday party"))
+
+	infoList := new(bytes.Buffer)
+	infoList.WriteString("INFO")
+	writeTestRiffChunk(infoList, "ICRD", []byte("2020-01-02\x00"))
+
+	body := new(bytes.Buffer)
+	body.WriteString("WAVE")
+	writeTestRiffChunk(body, "fmt ", make([]byte, 16))
+	writeTestRiffChunk(body, "LIST", exifList.Bytes())
+	writeTestRiffChunk(body, "LIST", infoList.Bytes())
+	writeTestRiffChunk(body, "data", []byte{1, 2, 3})
+
+	wav := new(bytes.Buffer)
+	writeTestRiffChunk(wav, "RIFF", body.Bytes())
+
+	return wav.Bytes()
+}
+
+func TestParseWavExif(t *testing.T) {
+	ae, err := ParseWavExif(getTestWavWithExif())
+	log.PanicIf(err)
+
+	if ae.Version != "0220" {
+		t.Fatalf("Version not correct: [%s]", ae.Version)
+	} else if ae.RelatedImageFile != "IMG_0001.JPG" {
+		t.Fatalf("RelatedImageFile not correct: [%s]", ae.RelatedImageFile)
+	} else if ae.Timestamp != "10:20:30.00" {
+		t.Fatalf("Timestamp not correct: [%s]", ae.Timestamp)
+	} else if ae.Make != "Canon" || ae.Model != "Canon EOS 5D" {
+		t.Fatalf("Make/model not correct: %s", ae)
+	} else if ae.UserComment != "Birthday party" {
+		t.Fatalf("UserComment not correct: [%s]", ae.UserComment)
+	} else if ae.Info["ICRD"] != "2020-01-02" {
+		t.Fatalf("INFO not correct: %v", ae.Info)
+	}
+}
+
+func TestParseWavExif_NoExif(t *testing.T) {
+	body := new(bytes.Buffer)
+	body.WriteString("WAVE")
+	writeTestRiffChunk(body, "data", []byte{1, 2, 3, 4})
+
+	wav := new(bytes.Buffer)
+	writeTestRiffChunk(wav, "RIFF", body.Bytes())
+
+	_, err := ParseWavExif(wav.Bytes())
+	if err != ErrNoAudioExif {
+		t.Fatalf("Expected ErrNoAudioExif: %v", err)
+	}
+}
+
+func TestParseWavExif_NotWav(t *testing.T) {
+	_, err := ParseWavExif(getTestJpegWithExif())
+	if log.Is(err, ErrNotWav) == false {
+		t.Fatalf("Expected ErrNotWav: %v", err)
+	}
+}
+
+func TestResolveRelatedFiles(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	imageFilepath := path.Join(tempPath, "img_0001.jpg")
+	soundFilepath := path.Join(tempPath, "snd00001.wav")
+
+	err = ioutil.WriteFile(imageFilepath, []byte{}, 0644)
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(soundFilepath, []byte{}, 0644)
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	err = exifIb.SetStandard(RelatedSoundFileTagId, "SND00001.WAV")
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	resolvedSoundFilepath, err := ResolveRelatedSoundFile(imageFilepath, index)
+	log.PanicIf(err)
+
+	if resolvedSoundFilepath != soundFilepath {
+		t.Fatalf("Sound file not correct: [%s]", resolvedSoundFilepath)
+	}
+
+	ae, err := ParseWavExif(getTestWavWithExif())
+	log.PanicIf(err)
+
+	resolvedImageFilepath, err := ResolveRelatedImageFile(soundFilepath, ae)
+	log.PanicIf(err)
+
+	if resolvedImageFilepath != imageFilepath {
+		t.Fatalf("Image file not correct: [%s]", resolvedImageFilepath)
+	}
+
+	err = os.Remove(soundFilepath)
+	log.PanicIf(err)
+
+	_, err = ResolveRelatedSoundFile(imageFilepath, index)
+	if err != ErrRelatedFileNotFound {
+		t.Fatalf("Expected ErrRelatedFileNotFound: %v", err)
+	}
+}
@@ -0,0 +1,480 @@
+package exif
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// AuxiliaryGainMap is an HDR gain map.
+	AuxiliaryGainMap = "gain-map"
+
+	// AuxiliaryDepth is depth or disparity data (e.g. for portrait mode).
+	AuxiliaryDepth = "depth"
+
+	// AuxiliaryMatte is a segmentation matte (e.g. portrait effects).
+	AuxiliaryMatte = "matte"
+
+	// AuxiliaryPreview is a reduced-size copy of the primary image.
+	AuxiliaryPreview = "preview"
+
+	// AuxiliaryAlpha is the alpha plane of the primary image (HEIF only).
+	AuxiliaryAlpha = "alpha"
+
+	// AuxiliaryUnknown is an image whose purpose couldn't be determined.
+	AuxiliaryUnknown = "unknown"
+)
+
+const (
+	// Apple maker-note tags.
+	appleHdrImageTypeTagId = 0x000a
+	appleHdrHeadroomTagId  = 0x0021
+
+	// MPF tags.
+	mpfNumberOfImagesTagId = 0xb001
+	mpfEntryTagId          = 0xb002
+
+	mpfEntrySize = 16
+)
+
+var (
+	auxiliaryLogger = log.NewLogger("exif.auxiliary")
+)
+
+var (
+	mpfSignature = []byte("MPF\x00")
+
+	hdrGainMapProperty = []byte("hdrgm:Version")
+)
+
+// AuxiliaryImage is an image that is stored alongside the primary one.
+type AuxiliaryImage struct {
+	// Kind is one of the Auxiliary* constants.
+	Kind string
+
+	// AuxiliaryType is the URN that identified the image, if there was one
+	// (e.g. "urn:com:apple:photo:2020:aux:hdrgainmap").
+	AuxiliaryType string
+
+	// MpfType is the image type from the MPF entry.
+	MpfType uint32
+
+	// ItemId is the ID of the HEIF item.
+	ItemId uint32
+
+	// Offset is the position of the image from the start of the file.
+	Offset int64
+
+	// Length is the size of the image.
+	Length int64
+}
+
+// AuxiliaryMetadata describes what's embedded alongside the primary image.
+type AuxiliaryMetadata struct {
+	// Images are the secondary images listed in the MPF index or referred to
+	// by HEIF items.
+	Images []AuxiliaryImage
+
+	// AppleHdrImageType is the HDR image-type from the Apple maker-note (3
+	// for an HDR image and 4 for the original), or zero if not present.
+	AppleHdrImageType int32
+
+	// AppleHdrHeadroom is the HDR headroom from the Apple maker-note. Only
+	// valid if `HasAppleHdrHeadroom` is true.
+	AppleHdrHeadroom    float64
+	HasAppleHdrHeadroom bool
+}
+
+// HasGainMap returns true if there's an HDR gain map.
+func (am AuxiliaryMetadata) HasGainMap() bool {
+	for _, ai := range am.Images {
+		if ai.Kind == AuxiliaryGainMap {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasDepth returns true if there's depth or disparity data.
+func (am AuxiliaryMetadata) HasDepth() bool {
+	for _, ai := range am.Images {
+		if ai.Kind == AuxiliaryDepth {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetAuxiliaryMetadata finds the gain maps, depth maps, and other auxiliary
+// images in a JPEG or HEIF (HEIC, AVIF) file as well as the HDR information
+// in an Apple maker-note. In a JPEG, the images are found via the MPF (CIPA
+// DC-007) index and identified by their own XMP where possible. In a HEIF,
+// they're the items with an auxiliary ("auxl") or thumbnail ("thmb")
+// reference, identified by their "auxC" property.
+func GetAuxiliaryMetadata(data []byte) (am AuxiliaryMetadata, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	am.Images, err = getMpfImages(data)
+	log.PanicIf(err)
+
+	heifImages, err := getHeifImages(data)
+	log.PanicIf(err)
+
+	am.Images = append(am.Images, heifImages...)
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err != nil {
+		if err == ErrNoExif {
+			return am, nil
+		}
+
+		log.Panic(err)
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	if err != nil {
+		auxiliaryLogger.Warningf(nil, "Could not parse EXIF while looking for maker-note: %s", err)
+		return am, nil
+	}
+
+	err = am.loadAppleMakerNote(index)
+	log.PanicIf(err)
+
+	return am, nil
+}
+
+// loadAppleMakerNote reads the HDR tags from an Apple maker-note.
+func (am *AuxiliaryMetadata) loadAppleMakerNote(index IfdIndex) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	amn, err := getAppleMakerNote(index)
+	log.PanicIf(err)
+
+	if amn == nil {
+		return nil
+	}
+
+	if hdrImageType, found := amn.Int(appleHdrImageTypeTagId); found == true {
+		am.AppleHdrImageType = int32(hdrImageType)
+	}
+
+	am.AppleHdrHeadroom, am.HasAppleHdrHeadroom = amn.Float(appleHdrHeadroomTagId)
+
+	return nil
+}
+
+// mpfEntry is one entry of the MPF index.
+type mpfEntry struct {
+	attribute uint32
+
+	// offset is the position of the image from the start of the file and
+	// length is its size.
+	offset int64
+	length int64
+
+	// dependents are the one-based numbers of the entries that this image
+	// depends on (zero if none).
+	dependents [2]uint16
+}
+
+// readMpfEntries reads the MPF index from the APP2 segment, including the
+// entry for the primary image. The index is a TIFF-style IFD, and image
+// offsets are relative to its header.
+func readMpfEntries(data []byte) (entries []mpfEntry, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	entries = make([]mpfEntry, 0)
+
+	mpf, mpfOffset := jpegMpfSegment(data)
+	if mpf == nil {
+		return entries, nil
+	}
+
+	eh, err := ParseExifHeader(mpf)
+	if err != nil {
+		auxiliaryLogger.Warningf(nil, "MPF header not valid: %s", err)
+		return entries, nil
+	}
+
+	byteOrder := eh.ByteOrder
+
+	rawTagCount, err := exifcommon.CheckedSlice(mpf, eh.FirstIfdOffset, 2)
+	if err != nil {
+		return entries, nil
+	}
+
+	tagCount := int(byteOrder.Uint16(rawTagCount))
+
+	var entriesOffset, entriesSize uint32
+
+	for i := 0; i < tagCount; i++ {
+		entryOffset, err := exifcommon.CheckedAdd(eh.FirstIfdOffset, 2+uint32(i)*IfdTagEntrySize)
+		if err != nil {
+			break
+		}
+
+		entry, err := exifcommon.CheckedSlice(mpf, entryOffset, IfdTagEntrySize)
+		if err != nil {
+			break
+		}
+
+		if byteOrder.Uint16(entry[0:]) == mpfEntryTagId {
+			entriesSize = byteOrder.Uint32(entry[4:])
+			entriesOffset = byteOrder.Uint32(entry[8:])
+		}
+	}
+
+	if entriesSize == 0 {
+		return entries, nil
+	}
+
+	table, err := exifcommon.CheckedSlice(mpf, entriesOffset, entriesSize)
+	if err != nil {
+		return entries, nil
+	}
+
+	for i := 0; i < len(table)/mpfEntrySize; i++ {
+		entry := table[i*mpfEntrySize:]
+
+		me := mpfEntry{
+			attribute: byteOrder.Uint32(entry[0:]),
+			length:    int64(byteOrder.Uint32(entry[4:])),
+			offset:    int64(byteOrder.Uint32(entry[8:])),
+			dependents: [2]uint16{
+				byteOrder.Uint16(entry[12:]),
+				byteOrder.Uint16(entry[14:]),
+			},
+		}
+
+		// The primary image's offset is always zero (the start of the file).
+		if i > 0 {
+			me.offset += mpfOffset
+		}
+
+		entries = append(entries, me)
+	}
+
+	return entries, nil
+}
+
+// getMpfImages returns the secondary images from the MPF index.
+func getMpfImages(data []byte) (images []AuxiliaryImage, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	images = make([]AuxiliaryImage, 0)
+
+	entries, err := readMpfEntries(data)
+	log.PanicIf(err)
+
+	// The first entry is the primary image.
+	for i := 1; i < len(entries); i++ {
+		me := entries[i]
+
+		ai := AuxiliaryImage{
+			MpfType: me.attribute & 0xffffff,
+			Offset:  me.offset,
+			Length:  me.length,
+		}
+
+		if ai.Offset+ai.Length > int64(len(data)) {
+			auxiliaryLogger.Warningf(nil, "MPF image (%d) is past the end of the file.", i)
+			continue
+		}
+
+		ai.Kind, ai.AuxiliaryType = classifyAuxiliaryImage(data[ai.Offset:ai.Offset+ai.Length], ai.MpfType)
+
+		images = append(images, ai)
+	}
+
+	return images, nil
+}
+
+// getHeifImages returns the auxiliary images and thumbnails of a HEIF file.
+func getHeifImages(data []byte) (images []AuxiliaryImage, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	images = make([]AuxiliaryImage, 0)
+
+	hm, err := readHeifMeta(data)
+	if err != nil {
+		auxiliaryLogger.Warningf(nil, "HEIF item information not valid: %s", err)
+		return images, nil
+	} else if hm == nil {
+		return images, nil
+	}
+
+	for _, hr := range hm.references {
+		if hr.referenceType != "auxl" && hr.referenceType != "thmb" {
+			continue
+		}
+
+		ai := AuxiliaryImage{
+			Kind:   AuxiliaryPreview,
+			ItemId: hr.fromItemId,
+		}
+
+		if hr.referenceType == "auxl" {
+			ai.Kind = AuxiliaryUnknown
+
+			if auxC, found := hm.property(hr.fromItemId, "auxC"); found == true {
+				ai.AuxiliaryType = readHeifAuxiliaryType(auxC)
+
+				if kind, found := classifyAuxiliaryType(ai.AuxiliaryType); found == true {
+					ai.Kind = kind
+				}
+			}
+		}
+
+		if hl, found := hm.locations[hr.fromItemId]; found == true {
+			if hl.offset+hl.length > int64(len(data)) {
+				auxiliaryLogger.Warningf(nil, "HEIF item (%d) is past the end of the file.", hr.fromItemId)
+			} else {
+				ai.Offset = hl.offset
+				ai.Length = hl.length
+			}
+		}
+
+		images = append(images, ai)
+	}
+
+	return images, nil
+}
+
+// readHeifAuxiliaryType returns the URN in an "auxC" property.
+func readHeifAuxiliaryType(auxC isoBox) (auxiliaryType string) {
+	defer func() {
+		if state := recover(); state != nil {
+			auxiliaryLogger.Warningf(nil, "HEIF auxiliary type not valid: %v", state)
+		}
+	}()
+
+	ir := &isoReader{data: auxC.payload}
+	ir.fullBoxHeader()
+
+	return ir.string()
+}
+
+// classifyAuxiliaryType determines what an image is for from its auxiliary
+// type (e.g. "urn:com:apple:photo:2020:aux:hdrgainmap").
+func classifyAuxiliaryType(auxiliaryType string) (kind string, found bool) {
+	lowered := strings.ToLower(auxiliaryType)
+
+	switch {
+	case strings.Contains(lowered, "gainmap"):
+		return AuxiliaryGainMap, true
+	case strings.Contains(lowered, "depth") || strings.Contains(lowered, "disparity"):
+		return AuxiliaryDepth, true
+	case strings.Contains(lowered, "matte"):
+		return AuxiliaryMatte, true
+	case lowered == "urn:mpeg:hevc:2015:auxid:1" || lowered == "urn:mpeg:mpegb:cicp:systems:auxiliary:alpha":
+		return AuxiliaryAlpha, true
+	case lowered == "urn:mpeg:hevc:2015:auxid:2":
+		return AuxiliaryDepth, true
+	}
+
+	return "", false
+}
+
+// findXmpPropertyValue returns the value of the first occurrence of the
+// property in the XMP that has one, whether it's written as an attribute
+// (name="value") or an element (<name>value</...>), or nil. The name can
+// include the end of the prefix (e.g. ":ContentIdentifier") to match any
+// namespace.
+func findXmpPropertyValue(xmp []byte, name string) []byte {
+	for i := bytes.Index(xmp, []byte(name)); i >= 0; {
+		rest := xmp[i+len(name):]
+
+		if bytes.HasPrefix(rest, []byte("=\"")) == true {
+			rest = rest[2:]
+		} else if bytes.HasPrefix(rest, []byte(">")) == true {
+			rest = rest[1:]
+		} else {
+			rest = nil
+		}
+
+		if rest != nil {
+			end := bytes.IndexAny(rest, "\"<")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			if end > 0 {
+				return rest[:end]
+			}
+		}
+
+		next := bytes.Index(xmp[i+1:], []byte(name))
+		if next < 0 {
+			break
+		}
+
+		i += 1 + next
+	}
+
+	return nil
+}
+
+// classifyAuxiliaryImage determines what an image is for, first from its own
+// XMP and then from its MPF type.
+func classifyAuxiliaryImage(image []byte, mpfType uint32) (kind, auxiliaryType string) {
+	for _, xmp := range jpegXmpPackets(image) {
+		if value := findXmpPropertyValue(xmp, "AuxiliaryImageType"); value != nil {
+			auxiliaryType = string(value)
+
+			if kind, found := classifyAuxiliaryType(auxiliaryType); found == true {
+				return kind, auxiliaryType
+			}
+		}
+
+		if bytes.Contains(xmp, hdrGainMapProperty) == true {
+			return AuxiliaryGainMap, auxiliaryType
+		}
+	}
+
+	switch mpfType {
+	case 0x010001, 0x010002:
+		return AuxiliaryPreview, auxiliaryType
+	case 0x020002:
+		return AuxiliaryDepth, auxiliaryType
+	}
+
+	return AuxiliaryUnknown, auxiliaryType
+}
+
+// jpegMpfSegment returns the MPF data (starting with its TIFF header) from
+// the APP2 segment and the position of that data in the file.
+func jpegMpfSegment(data []byte) (mpf []byte, offset int64) {
+	for _, segment := range jpegSegments(data) {
+		if segment.marker == jpegMarkerApp2 && bytes.HasPrefix(segment.payload, mpfSignature) == true {
+			return segment.payload[len(mpfSignature):], int64(segment.offset) + int64(len(mpfSignature))
+		}
+	}
+
+	return nil, 0
+}
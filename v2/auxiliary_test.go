@@ -0,0 +1,221 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestJpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{jpegMarkerPrefix, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+len(payload)))
+
+	return append(segment, payload...)
+}
+
+// getTestAppleMakerNote returns an Apple maker-note with an HDR image-type
+// of (3) and a headroom of (1.7).
+func getTestAppleMakerNote() []byte {
+	b := new(bytes.Buffer)
+
+	b.Write(appleMakerNoteSignature)
+	b.Write([]byte{0, 1, 'M', 'M'})
+
+	binary.Write(b, binary.BigEndian, uint16(2))
+
+	binary.Write(b, binary.BigEndian, uint16(appleHdrImageTypeTagId))
+	binary.Write(b, binary.BigEndian, uint16(exifcommon.TypeSignedLong))
+	binary.Write(b, binary.BigEndian, uint32(1))
+	binary.Write(b, binary.BigEndian, int32(3))
+
+	binary.Write(b, binary.BigEndian, uint16(appleHdrHeadroomTagId))
+	binary.Write(b, binary.BigEndian, uint16(exifcommon.TypeSignedRational))
+	binary.Write(b, binary.BigEndian, uint32(1))
+	binary.Write(b, binary.BigEndian, uint32(44))
+
+	binary.Write(b, binary.BigEndian, uint32(0))
+
+	binary.Write(b, binary.BigEndian, int32(17))
+	binary.Write(b, binary.BigEndian, int32(10))
+
+	return b.Bytes()
+}
+
+// getTestMpfJpeg returns a JPEG with an Apple maker-note and an MPF index
+// that points to a gain map appended after the primary image.
+func getTestMpfJpeg() []byte {
+	root := exiftest.NewRealisticIfd()
+	exifIfd := root.Children[0].Ifd
+
+	makerNoteTag := exiftest.Tag{
+		Id:   0x927c,
+		Raw:  getTestAppleMakerNote(),
+		Type: exifcommon.TypeUndefined,
+	}
+
+	exifIfd.Tags = append(exifIfd.Tags, makerNoteTag)
+
+	exifData, err := exiftest.Build(root, binary.LittleEndian)
+	log.PanicIf(err)
+
+	xmp := `<rdf:Description apdi:AuxiliaryImageType="urn:com:apple:photo:2020:aux:hdrgainmap"/>`
+
+	secondary := []byte{jpegMarkerPrefix, jpegMarkerSoi}
+	secondary = append(secondary, getTestJpegSegment(jpegMarkerApp1, append(append([]byte{}, xmpNamespace...), xmp...))...)
+	secondary = append(secondary, jpegMarkerPrefix, jpegMarkerEoi)
+
+	return getTestMpfJpegWith(exifData, secondary, 0)
+}
+
+// getTestMpfJpegWith returns a JPEG with the EXIF data and an MPF index that
+// points to the secondary image, with the given MPF attribute, appended after
+// the primary image.
+func getTestMpfJpegWith(exifData, secondary []byte, attribute uint32) []byte {
+	app1 := getTestJpegSegment(jpegMarkerApp1, append(append([]byte{}, jpegExifPreamble...), exifData...))
+
+	// The MPF index is fixed-size: the signature, the TIFF header, an IFD
+	// with two entries, and two MP entries.
+	mpfSize := 4 + 8 + 2 + 2*12 + 4 + 2*mpfEntrySize
+
+	primarySize := 2 + len(app1) + 4 + mpfSize + 2
+	mpfTiffOffset := 2 + len(app1) + 4 + 4
+
+	mpf := new(bytes.Buffer)
+	mpf.Write(mpfSignature)
+	mpf.Write([]byte{'M', 'M', 0, 0x2a, 0, 0, 0, 8})
+
+	binary.Write(mpf, binary.BigEndian, uint16(2))
+
+	binary.Write(mpf, binary.BigEndian, uint16(mpfNumberOfImagesTagId))
+	binary.Write(mpf, binary.BigEndian, uint16(exifcommon.TypeLong))
+	binary.Write(mpf, binary.BigEndian, uint32(1))
+	binary.Write(mpf, binary.BigEndian, uint32(2))
+
+	binary.Write(mpf, binary.BigEndian, uint16(mpfEntryTagId))
+	binary.Write(mpf, binary.BigEndian, uint16(exifcommon.TypeUndefined))
+	binary.Write(mpf, binary.BigEndian, uint32(2*mpfEntrySize))
+	binary.Write(mpf, binary.BigEndian, uint32(8+2+2*12+4))
+
+	binary.Write(mpf, binary.BigEndian, uint32(0))
+
+	binary.Write(mpf, binary.BigEndian, []uint32{0x20030000, uint32(primarySize), 0, 0})
+	binary.Write(mpf, binary.BigEndian, []uint32{attribute, uint32(len(secondary)), uint32(primarySize - mpfTiffOffset), 0})
+
+	data := []byte{jpegMarkerPrefix, jpegMarkerSoi}
+	data = append(data, app1...)
+	data = append(data, getTestJpegSegment(jpegMarkerApp2, mpf.Bytes())...)
+	data = append(data, jpegMarkerPrefix, jpegMarkerEoi)
+	data = append(data, secondary...)
+
+	return data
+}
+
+func TestGetAuxiliaryMetadata(t *testing.T) {
+	am, err := GetAuxiliaryMetadata(getTestMpfJpeg())
+	log.PanicIf(err)
+
+	if len(am.Images) != 1 {
+		t.Fatalf("Image count not correct: (%d)", len(am.Images))
+	}
+
+	ai := am.Images[0]
+
+	if ai.Kind != AuxiliaryGainMap {
+		t.Fatalf("Kind not correct: [%s]", ai.Kind)
+	} else if ai.AuxiliaryType != "urn:com:apple:photo:2020:aux:hdrgainmap" {
+		t.Fatalf("Auxiliary-type not correct: [%s]", ai.AuxiliaryType)
+	} else if am.HasGainMap() != true || am.HasDepth() != false {
+		t.Fatalf("Summary not correct.")
+	}
+
+	if am.AppleHdrImageType != 3 {
+		t.Fatalf("HDR image-type not correct: (%d)", am.AppleHdrImageType)
+	} else if am.HasAppleHdrHeadroom != true || am.AppleHdrHeadroom != 1.7 {
+		t.Fatalf("HDR headroom not correct: (%f)", am.AppleHdrHeadroom)
+	}
+}
+
+func TestGetAuxiliaryMetadata_None(t *testing.T) {
+	am, err := GetAuxiliaryMetadata(getTestJpegWithExif())
+	log.PanicIf(err)
+
+	if len(am.Images) != 0 || am.HasAppleHdrHeadroom != false {
+		t.Fatalf("Expected nothing: %v", am)
+	}
+}
+
+func TestClassifyAuxiliaryImage(t *testing.T) {
+	if kind, _ := classifyAuxiliaryImage(nil, 0x020002); kind != AuxiliaryDepth {
+		t.Fatalf("Disparity not classified as depth: [%s]", kind)
+	} else if kind, _ := classifyAuxiliaryImage(nil, 0x010001); kind != AuxiliaryPreview {
+		t.Fatalf("Preview not classified: [%s]", kind)
+	} else if kind, _ := classifyAuxiliaryImage(nil, 0); kind != AuxiliaryUnknown {
+		t.Fatalf("Unknown not classified: [%s]", kind)
+	}
+}
+
+func TestGetAuxiliaryMetadata_Heic(t *testing.T) {
+	data, gainMapOffset, thumbnailOffset := getTestHeic()
+
+	am, err := GetAuxiliaryMetadata(data)
+	log.PanicIf(err)
+
+	if len(am.Images) != 2 {
+		t.Fatalf("Image count not correct: (%d)", len(am.Images))
+	}
+
+	ai := am.Images[0]
+	if ai.Kind != AuxiliaryGainMap || ai.AuxiliaryType != "urn:com:apple:photo:2020:aux:hdrgainmap" || ai.ItemId != 2 {
+		t.Fatalf("Gain map not correct: %v", ai)
+	} else if ai.Offset != gainMapOffset || bytes.Equal(data[ai.Offset:ai.Offset+ai.Length], testHeifGainMap) != true {
+		t.Fatalf("Gain-map location not correct: %v", ai)
+	} else if am.HasGainMap() != true {
+		t.Fatalf("Expected gain map.")
+	}
+
+	ai = am.Images[1]
+	if ai.Kind != AuxiliaryPreview || ai.ItemId != 3 || ai.Offset != thumbnailOffset {
+		t.Fatalf("Thumbnail not correct: %v", ai)
+	}
+}
+
+func TestClassifyAuxiliaryType(t *testing.T) {
+	if kind, _ := classifyAuxiliaryType("urn:mpeg:hevc:2015:auxid:1"); kind != AuxiliaryAlpha {
+		t.Fatalf("Alpha not classified: [%s]", kind)
+	} else if kind, _ := classifyAuxiliaryType("urn:mpeg:hevc:2015:auxid:2"); kind != AuxiliaryDepth {
+		t.Fatalf("Depth not classified: [%s]", kind)
+	} else if kind, _ := classifyAuxiliaryType("urn:com:apple:photo:2018:aux:portraiteffectsmatte"); kind != AuxiliaryMatte {
+		t.Fatalf("Matte not classified: [%s]", kind)
+	} else if _, found := classifyAuxiliaryType("urn:example:other"); found == true {
+		t.Fatalf("Unknown type should not be classified.")
+	}
+}
+
+func TestFindXmpPropertyValue(t *testing.T) {
+	tests := []struct {
+		xmp      string
+		name     string
+		expected string
+	}{
+		{`<rdf:Description GCamera:AuxiliaryImageType="image/jpeg"/>`, "AuxiliaryImageType", "image/jpeg"},
+		{`<apple:ContentIdentifier>ABC-123</apple:ContentIdentifier>`, ":ContentIdentifier", "ABC-123"},
+		{`<x AuxiliaryImageTypes="no" AuxiliaryImageType=""/> AuxiliaryImageType="yes"`, "AuxiliaryImageType", "yes"},
+		{`AuxiliaryImageType="unterminated`, "AuxiliaryImageType", "unterminated"},
+	}
+
+	for _, test := range tests {
+		if value := findXmpPropertyValue([]byte(test.xmp), test.name); string(value) != test.expected {
+			t.Fatalf("Value for [%s] not correct: [%s]", test.xmp, value)
+		}
+	}
+
+	if value := findXmpPropertyValue([]byte(`AuxiliaryImageType=""`), "AuxiliaryImageType"); value != nil {
+		t.Fatalf("Empty value should not be found: [%s]", value)
+	}
+}
@@ -0,0 +1,208 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// BackupFormatRaw writes the raw EXIF block to "<file>.exif.bak".
+	BackupFormatRaw = "raw"
+
+	// BackupFormatJson writes an `ExifBackup` to "<file>.exif.json", which has
+	// the raw EXIF block as well as its tags, so that it can be read as well
+	// as restored.
+	BackupFormatJson = "json"
+)
+
+var (
+	backupSidecarSuffixes = map[string]string{
+		BackupFormatRaw:  ".exif.bak",
+		BackupFormatJson: ".exif.json",
+	}
+)
+
+var (
+	// ErrNoBackup means that there's no sidecar to restore the file from.
+	ErrNoBackup = errors.New("no EXIF backup found")
+
+	// ErrBackupFormatUnknown means that a backup format isn't one of the
+	// BackupFormat* constants.
+	ErrBackupFormatUnknown = errors.New("unknown backup format")
+)
+
+// ExifBackup is the content of a JSON sidecar.
+type ExifBackup struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+
+	// Exif is the raw EXIF block. It's empty if the file didn't have any.
+	Exif []byte `json:"exif"`
+
+	// Tags are only for reference. `Exif` is what's restored.
+	Tags []ExifTag `json:"tags,omitempty"`
+}
+
+// String returns a descriptive string.
+func (eb ExifBackup) String() string {
+	return fmt.Sprintf("ExifBackup<NAME=[%s] CREATED=[%s] EXIF-SIZE=(%d) TAGS=(%d)>", eb.Name, eb.Created, len(eb.Exif), len(eb.Tags))
+}
+
+// BackupSidecarPath returns the path of the sidecar that a file is backed-up
+// to in the given format.
+func BackupSidecarPath(filepath string, format string) (sidecarPath string, err error) {
+	suffix, found := backupSidecarSuffixes[format]
+	if found == false {
+		return "", ErrBackupFormatUnknown
+	}
+
+	return filepath + suffix, nil
+}
+
+// BackupJpegExif writes the EXIF of a JPEG file to a sidecar in the given
+// format (one of the BackupFormat* constants) so that it can be put back with
+// `RestoreJpegExif()`. An existing sidecar is left alone, so that running a
+// batch more than once doesn't replace the original EXIF with the result of
+// the first run.
+func BackupJpegExif(filepath string, format string) (sidecarPath string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	sidecarPath, err = backupJpegExifData(filepath, data, format)
+	log.PanicIf(err)
+
+	return sidecarPath, nil
+}
+
+// backupJpegExifData writes the sidecar for the data of the given file. It's
+// used by operations that have already read the file.
+func backupJpegExifData(filepath string, data []byte, format string) (sidecarPath string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	sidecarPath, err = BackupSidecarPath(filepath, format)
+	log.PanicIf(err)
+
+	if _, err := os.Stat(sidecarPath); err == nil {
+		return sidecarPath, nil
+	} else if os.IsNotExist(err) == false {
+		log.Panic(err)
+	}
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == ErrNoExif {
+		rawExif = nil
+	} else {
+		log.PanicIf(err)
+	}
+
+	content := rawExif
+
+	if format == BackupFormatJson {
+		eb := ExifBackup{
+			Name:    filepath,
+			Created: time.Now().UTC(),
+			Exif:    rawExif,
+		}
+
+		if rawExif != nil {
+			eb.Tags, err = GetFlatExifData(rawExif)
+			log.PanicIf(err)
+		}
+
+		content, err = json.MarshalIndent(eb, "", "  ")
+		log.PanicIf(err)
+	}
+
+	// The sidecar is only replaced once it's complete, so an interruption
+	// doesn't leave a partial backup that would then be kept.
+	err = replaceFile(sidecarPath, content)
+	log.PanicIf(err)
+
+	return sidecarPath, nil
+}
+
+// readBackup returns the raw EXIF block in the first sidecar found for the
+// file.
+func readBackup(filepath string) (rawExif []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for _, format := range []string{BackupFormatRaw, BackupFormatJson} {
+		sidecarPath, err := BackupSidecarPath(filepath, format)
+		log.PanicIf(err)
+
+		content, err := ioutil.ReadFile(sidecarPath)
+		if os.IsNotExist(err) == true {
+			continue
+		}
+
+		log.PanicIf(err)
+
+		if format == BackupFormatRaw {
+			return content, nil
+		}
+
+		var eb ExifBackup
+
+		err = json.Unmarshal(content, &eb)
+		log.PanicIf(err)
+
+		return eb.Exif, nil
+	}
+
+	return nil, ErrNoBackup
+}
+
+// RestoreJpegExif puts the EXIF from the file's sidecar back into a JPEG
+// file, replacing whatever EXIF it has now (or removing it, if it didn't have
+// any when it was backed-up). The raw sidecar is preferred to the JSON one
+// if both exist. `ErrNoBackup` is returned if there isn't either. The sidecar
+// is kept.
+func RestoreJpegExif(filepath string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rawExif, err := readBackup(filepath)
+	if err == ErrNoBackup {
+		return err
+	}
+
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	updated, err := SetJpegExif(data, rawExif)
+	log.PanicIf(err)
+
+	err = replaceFile(filepath, updated)
+	log.PanicIf(err)
+
+	return nil
+}
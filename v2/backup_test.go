@@ -0,0 +1,160 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestBackupJpegExif_Raw(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+	original := getTestPipelineJpeg()
+
+	err = ioutil.WriteFile(filepath, original, 0644)
+	log.PanicIf(err)
+
+	sidecarPath, err := BackupJpegExif(filepath, BackupFormatRaw)
+	log.PanicIf(err)
+
+	if sidecarPath != filepath+".exif.bak" {
+		t.Fatalf("Sidecar path not correct: [%s]", sidecarPath)
+	}
+
+	rawExif, err := SearchAndExtractExif(original)
+	log.PanicIf(err)
+
+	backedUp, err := ioutil.ReadFile(sidecarPath)
+	log.PanicIf(err)
+
+	if bytes.HasPrefix(rawExif, backedUp) == false {
+		t.Fatalf("Sidecar doesn't have the EXIF.")
+	}
+
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpStripSegments, Kinds: []string{JpegSegmentExif}},
+		},
+	}
+
+	err = p.ApplyFile(filepath)
+	log.PanicIf(err)
+
+	// The existing sidecar isn't replaced by a second backup.
+
+	_, err = BackupJpegExif(filepath, BackupFormatRaw)
+	log.PanicIf(err)
+
+	err = RestoreJpegExif(filepath)
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	restored, err := SearchAndExtractExif(data)
+	log.PanicIf(err)
+
+	if bytes.HasPrefix(restored, backedUp) == false {
+		t.Fatalf("EXIF not restored.")
+	}
+}
+
+func TestPipeline_ApplyFile_Backup(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+	original := getTestPipelineJpeg()
+
+	err = ioutil.WriteFile(filepath, original, 0644)
+	log.PanicIf(err)
+
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpSetTag, FqIfdPath: "IFD", TagName: "Model", Value: "Other"},
+		},
+		Backup: BackupFormatJson,
+	}
+
+	err = p.ApplyFile(filepath)
+	log.PanicIf(err)
+
+	content, err := ioutil.ReadFile(filepath + ".exif.json")
+	log.PanicIf(err)
+
+	var eb ExifBackup
+
+	err = json.Unmarshal(content, &eb)
+	log.PanicIf(err)
+
+	found := false
+	for _, et := range eb.Tags {
+		if et.TagName == "Model" && et.Value == "Canon EOS 5D Mark III" {
+			found = true
+		}
+	}
+
+	if found == false {
+		t.Fatalf("Original Model not in sidecar: %s", eb)
+	} else if eb.Name != filepath || len(eb.Exif) == 0 {
+		t.Fatalf("Sidecar not correct: %s", eb)
+	}
+
+	err = RestoreJpegExif(filepath)
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	md, err := DiffMetadata(original, data)
+	log.PanicIf(err)
+
+	if len(md.Tags) != 0 {
+		t.Fatalf("Tags not restored: %v", md.Tags)
+	}
+}
+
+func TestRestoreJpegExif_NoBackup(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+
+	err = ioutil.WriteFile(filepath, getTestPipelineJpeg(), 0644)
+	log.PanicIf(err)
+
+	err = RestoreJpegExif(filepath)
+	if err != ErrNoBackup {
+		t.Fatalf("Expected ErrNoBackup: %v", err)
+	}
+
+	_, err = BackupJpegExif(filepath, "xml")
+	if log.Is(err, ErrBackupFormatUnknown) == false {
+		t.Fatalf("Expected ErrBackupFormatUnknown: %v", err)
+	}
+
+	p := Pipeline{
+		Backup: "xml",
+	}
+
+	if p.Validate() != ErrBackupFormatUnknown {
+		t.Fatalf("Expected invalid backup format.")
+	}
+}
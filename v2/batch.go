@@ -0,0 +1,278 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// ErrSourceTooLarge means that a source was larger than
+	// `ParseManyOptions.MaxSourceSize`.
+	ErrSourceTooLarge = errors.New("source too large")
+)
+
+// ParseSource is one of the files given to `ParseMany()`.
+type ParseSource struct {
+	// Name identifies the source in the results (e.g. a path).
+	Name string
+
+	// Open returns the data. It's called by the worker that parses it, so
+	// that only as many sources are open as there are workers.
+	Open func() (rc io.ReadCloser, err error)
+
+	// Stat, if not nil, returns the size and modification time of the data,
+	// which identify it in a `BatchManifest`.
+	Stat func() (size int64, modTime time.Time, err error)
+}
+
+// FileSource returns a source that reads the given file.
+func FileSource(filepath string) ParseSource {
+	return ParseSource{
+		Name: filepath,
+		Open: func() (rc io.ReadCloser, err error) {
+			return os.Open(filepath)
+		},
+		Stat: func() (size int64, modTime time.Time, err error) {
+			fi, err := os.Stat(filepath)
+			if err != nil {
+				return 0, time.Time{}, err
+			}
+
+			return fi.Size(), fi.ModTime(), nil
+		},
+	}
+}
+
+// BytesSource returns a source that reads the given data.
+func BytesSource(name string, data []byte) ParseSource {
+	return ParseSource{
+		Name: name,
+		Open: func() (rc io.ReadCloser, err error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		},
+		Stat: func() (size int64, modTime time.Time, err error) {
+			return int64(len(data)), time.Time{}, nil
+		},
+	}
+}
+
+// ParseManyOptions are the options for `ParseMany()`.
+type ParseManyOptions struct {
+	// Concurrency is the number of sources parsed at once. It defaults to the
+	// number of CPUs.
+	Concurrency int
+
+	// MaxSourceSize is the most that's read from each source. Larger sources
+	// fail with `ErrSourceTooLarge`. Zero is unlimited.
+	MaxSourceSize int64
+
+	// ShallowParse only parses the IFDs of the root chain (see
+	// `CollectShallow()`). It doesn't apply to sources parsed through the
+	// cache, which are always parsed completely.
+	ShallowParse bool
+
+	// Cache, if not nil, is consulted for each source by the hash of its
+	// content, and sources that aren't in it are added (see
+	// `ReadCachedMetadata()`), so that only the sources that changed are
+	// parsed again. Sources that no registered format recognizes are parsed
+	// without it.
+	Cache MetadataCache
+
+	// OnResult, if not nil, is called with the result of each source that's
+	// parsed as soon as it's ready, in the order that they finish. Calls
+	// aren't concurrent.
+	// Returning an error stops the sources that haven't been started yet
+	// (they're given `context.Canceled`) and is returned by `ParseMany()`.
+	OnResult func(pr ParseResult) (err error)
+}
+
+// ParseResult is the result of parsing one source.
+type ParseResult struct {
+	// Name is the name of the source.
+	Name string
+
+	// Header and Index are only valid if `Err` is nil.
+	Header ExifHeader
+	Index  IfdIndex
+
+	// Err is the error encountered while parsing this source, if any. It'll
+	// be `ErrNoExif` if the source didn't have any EXIF and the context's
+	// error if the source wasn't parsed before the context was done.
+	Err error
+}
+
+// String returns a descriptive string.
+func (pr ParseResult) String() string {
+	return fmt.Sprintf("ParseResult<NAME=[%s] ERR=[%v]>", pr.Name, pr.Err)
+}
+
+// ParseMany parses the EXIF of every source with a bounded pool of workers
+// and returns a result for each, in the same order as the sources. A failure
+// to parse one source is attached to its result rather than stopping the
+// others. If the context is done, the sources that haven't been started yet
+// are given its error, and it's also returned.
+func ParseMany(ctx context.Context, sources []ParseSource, opts ParseManyOptions) (results []ParseResult, err error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	results = make([]ParseResult, len(sources))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var handlerErr error
+	handlerM := new(sync.Mutex)
+
+	handle := func(pr ParseResult) {
+		if opts.OnResult == nil {
+			return
+		}
+
+		handlerM.Lock()
+		defer handlerM.Unlock()
+
+		if handlerErr != nil {
+			return
+		}
+
+		handlerErr = opts.OnResult(pr)
+		if handlerErr != nil {
+			cancel()
+		}
+	}
+
+	indices := make(chan int)
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// The tag index loads tags lazily, so it can't be shared
+			// between workers.
+			ifdMapping := NewIfdMappingWithStandard()
+			tagIndex := NewTagIndex()
+
+			for i := range indices {
+				results[i] = parseManySource(sources[i], opts, ifdMapping, tagIndex)
+				handle(results[i])
+			}
+		}()
+	}
+
+	for i, source := range sources {
+		if ctx.Err() != nil {
+			results[i] = ParseResult{
+				Name: source.Name,
+				Err:  ctx.Err(),
+			}
+
+			continue
+		}
+
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			results[i] = ParseResult{
+				Name: source.Name,
+				Err:  ctx.Err(),
+			}
+		}
+	}
+
+	close(indices)
+	wg.Wait()
+
+	if handlerErr != nil {
+		return results, handlerErr
+	}
+
+	return results, ctx.Err()
+}
+
+// parseManySource parses one source. Errors, including panics, are returned
+// in the result.
+func parseManySource(source ParseSource, opts ParseManyOptions, ifdMapping *IfdMapping, tagIndex *TagIndex) (pr ParseResult) {
+	pr = ParseResult{
+		Name: source.Name,
+	}
+
+	defer func() {
+		if state := recover(); state != nil {
+			pr.Err = log.Wrap(state.(error))
+		}
+	}()
+
+	rc, err := source.Open()
+	log.PanicIf(err)
+
+	defer rc.Close()
+
+	r := io.Reader(rc)
+	if opts.MaxSourceSize > 0 {
+		r = io.LimitReader(rc, opts.MaxSourceSize+1)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	log.PanicIf(err)
+
+	if opts.MaxSourceSize > 0 && int64(len(data)) > opts.MaxSourceSize {
+		pr.Err = ErrSourceTooLarge
+		return pr
+	}
+
+	if opts.Cache != nil {
+		cm, err := ReadCachedMetadata(data, opts.Cache)
+		if err == nil {
+			pr.Index, err = cm.Exif()
+			if err == ErrNoExif {
+				pr.Err = err
+				return pr
+			}
+
+			log.PanicIf(err)
+
+			pr.Header, err = ParseExifHeader(cm.RawExif)
+			log.PanicIf(err)
+
+			return pr
+		} else if err != ErrMediaFormatNotFound {
+			log.Panic(err)
+		}
+	}
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == ErrNoExif {
+		pr.Err = err
+		return pr
+	}
+
+	log.PanicIf(err)
+
+	if opts.ShallowParse == true {
+		pr.Header, pr.Index, err = CollectShallow(ifdMapping, tagIndex, rawExif)
+	} else {
+		pr.Header, pr.Index, err = Collect(ifdMapping, tagIndex, rawExif)
+	}
+
+	log.PanicIf(err)
+
+	return pr
+}
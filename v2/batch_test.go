@@ -0,0 +1,122 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestParseMany(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	filepath := path.Join(assetsPath, "NDM_8901.jpg")
+
+	sources := []ParseSource{
+		BytesSource("synthetic", exiftest.WrapJpeg(rawExif)),
+		FileSource(filepath),
+		BytesSource("empty", []byte("not an image")),
+		FileSource(path.Join(assetsPath, "does-not-exist.jpg")),
+		{
+			Name: "open-error",
+			Open: func() (rc io.ReadCloser, err error) {
+				return nil, errors.New("open failed")
+			},
+		},
+	}
+
+	results, err := ParseMany(context.Background(), sources, ParseManyOptions{Concurrency: 2})
+	log.PanicIf(err)
+
+	if len(results) != len(sources) {
+		t.Fatalf("Result count not correct: (%d)", len(results))
+	}
+
+	for i, pr := range results {
+		if pr.Name != sources[i].Name {
+			t.Fatalf("Result (%d) out of order: [%s]", i, pr.Name)
+		}
+	}
+
+	if results[0].Err != nil {
+		t.Fatalf("Synthetic source failed: %v", results[0].Err)
+	} else if _, err := results[0].Index.RootIfd.FindTagWithName("Make"); err != nil {
+		t.Fatalf("Synthetic source not parsed: %v", err)
+	} else if results[1].Err != nil {
+		t.Fatalf("File source failed: %v", results[1].Err)
+	} else if len(results[1].Index.Ifds) < 2 {
+		t.Fatalf("File source not fully parsed: (%d)", len(results[1].Index.Ifds))
+	} else if results[2].Err != ErrNoExif {
+		t.Fatalf("Expected no-EXIF error: %v", results[2].Err)
+	} else if results[3].Err == nil {
+		t.Fatalf("Expected error for a missing file.")
+	} else if results[4].Err == nil || results[4].Err.Error() != "open failed" {
+		t.Fatalf("Expected open error: %v", results[4].Err)
+	}
+}
+
+func TestParseMany_MaxSourceSize(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	data := exiftest.WrapJpeg(rawExif)
+
+	sources := []ParseSource{
+		BytesSource("large", data),
+	}
+
+	opts := ParseManyOptions{
+		MaxSourceSize: int64(len(data) - 1),
+	}
+
+	results, err := ParseMany(context.Background(), sources, opts)
+	log.PanicIf(err)
+
+	if results[0].Err != ErrSourceTooLarge {
+		t.Fatalf("Expected too-large error: %v", results[0].Err)
+	}
+
+	opts.MaxSourceSize = int64(len(data))
+
+	results, err = ParseMany(context.Background(), sources, opts)
+	log.PanicIf(err)
+
+	if results[0].Err != nil {
+		t.Fatalf("Source at the limit failed: %v", results[0].Err)
+	}
+}
+
+func TestParseMany_Canceled(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	sources := []ParseSource{
+		BytesSource("first", exiftest.WrapJpeg(rawExif)),
+		BytesSource("second", exiftest.WrapJpeg(rawExif)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := ParseMany(ctx, sources, ParseManyOptions{})
+	if err != context.Canceled {
+		t.Fatalf("Expected canceled error: %v", err)
+	}
+
+	for _, pr := range results {
+		if pr.Err != context.Canceled {
+			t.Fatalf("Expected source to be canceled: %s", pr)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package exif
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	// exifSpecVersion is the version of the EXIF specification whose tags are
+	// in the tag table.
+	exifSpecVersion = "2.32"
+)
+
+var (
+	// buildFeatures are the optional features that were compiled in, which
+	// depend on the build tags.
+	buildFeatures = make([]string, 0)
+)
+
+// BuildCapabilities describes what this build of the package can do.
+type BuildCapabilities struct {
+	// ExifVersion is the version of the EXIF specification that the tag
+	// table follows.
+	ExifVersion string
+
+	// TagTable is "full", or "minimal" for TinyGo builds and builds with the
+	// "exif_minimal" tag.
+	TagTable string
+
+	// TagCounts is the number of tags in the table for each IFD path.
+	TagCounts map[string]int
+
+	// Containers are the registered media formats, in the order that they're
+	// tried (see `RegisterMediaFormat()`).
+	Containers []Kind
+
+	// MakerNoteDecoders are the Make prefixes that have a maker-note codec,
+	// in the order that they're tried (see `RegisterMakerNoteCodec()`).
+	MakerNoteDecoders []string
+
+	// Quirks are the names of the registered quirks (see `RegisterQuirk()`).
+	Quirks []string
+
+	// Features are the optional features that were compiled in (e.g.
+	// "pipeline-yaml" and "s2-cell-id").
+	Features []string
+
+	// GoVersion is the version of Go that the build used.
+	GoVersion string
+}
+
+// String returns a descriptive string.
+func (bc BuildCapabilities) String() string {
+	containers := make([]string, len(bc.Containers))
+	for i, kind := range bc.Containers {
+		containers[i] = string(kind)
+	}
+
+	return fmt.Sprintf("BuildCapabilities<EXIF-VERSION=[%s] TAG-TABLE=[%s] CONTAINERS=[%s] MAKER-NOTE-DECODERS=[%s] QUIRKS=[%s] FEATURES=[%s] GO-VERSION=[%s]>", bc.ExifVersion, bc.TagTable, strings.Join(containers, ","), strings.Join(bc.MakerNoteDecoders, ","), strings.Join(bc.Quirks, ","), strings.Join(bc.Features, ","), bc.GoVersion)
+}
+
+// HasContainer returns true if the container is supported.
+func (bc BuildCapabilities) HasContainer(kind Kind) bool {
+	for _, current := range bc.Containers {
+		if current == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasFeature returns true if the optional feature was compiled in.
+func (bc BuildCapabilities) HasFeature(feature string) bool {
+	for _, current := range bc.Features {
+		if current == feature {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Capabilities reports what this build can do: the tag table and the version
+// of the specification that it follows, the containers, maker-note codecs, and
+// quirks that are registered, and the optional features that were compiled
+// in. Applications can use it to say what they support.
+func Capabilities() BuildCapabilities {
+	bc := BuildCapabilities{
+		ExifVersion:       exifSpecVersion,
+		TagTable:          tagTableName,
+		TagCounts:         make(map[string]int),
+		Containers:        make([]Kind, 0),
+		MakerNoteDecoders: make([]string, 0),
+		Quirks:            make([]string, 0),
+		Features:          append([]string{}, buildFeatures...),
+		GoVersion:         runtime.Version(),
+	}
+
+	if encodedIfds, err := loadStandardTagDefinitions(); err == nil {
+		for ifdPath, tags := range encodedIfds {
+			bc.TagCounts[ifdPath] = len(tags)
+		}
+	}
+
+	mediaFormatsMutex.RLock()
+	for _, mf := range mediaFormats {
+		bc.Containers = append(bc.Containers, mf.Kind())
+	}
+
+	mediaFormatsMutex.RUnlock()
+
+	makerNoteCodecsMutex.RLock()
+	for _, rmnc := range makerNoteCodecs {
+		bc.MakerNoteDecoders = append(bc.MakerNoteDecoders, rmnc.makePrefix)
+	}
+
+	makerNoteCodecsMutex.RUnlock()
+
+	quirksMutex.RLock()
+	for _, q := range quirks {
+		bc.Quirks = append(bc.Quirks, q.Name)
+	}
+
+	quirksMutex.RUnlock()
+
+	sort.Strings(bc.Features)
+
+	return bc
+}
@@ -0,0 +1,65 @@
+package exif
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	bc := Capabilities()
+
+	if bc.ExifVersion != "2.32" {
+		t.Fatalf("EXIF version not correct: [%s]", bc.ExifVersion)
+	} else if bc.TagTable != tagTableName {
+		t.Fatalf("Tag table not correct: [%s]", bc.TagTable)
+	} else if bc.TagCounts["IFD"] == 0 || bc.TagCounts["IFD/Exif"] == 0 || bc.TagCounts["IFD/GPSInfo"] == 0 {
+		t.Fatalf("Tag counts not correct: %v", bc.TagCounts)
+	} else if len(bc.Containers) != 6 || bc.Containers[0] != KindJpeg || bc.Containers[5] != KindTiff {
+		t.Fatalf("Containers not correct: %v", bc.Containers)
+	} else if bc.HasContainer(KindCr3) != true || bc.HasContainer(Kind("test")) != false {
+		t.Fatalf("HasContainer not correct.")
+	} else if len(bc.MakerNoteDecoders) == 0 {
+		t.Fatalf("Maker-note decoders not correct: %v", bc.MakerNoteDecoders)
+	} else if bc.Quirks == nil {
+		t.Fatalf("Quirks not set.")
+	} else if bc.GoVersion == "" {
+		t.Fatalf("Go version not set.")
+	}
+
+	if bc.TagTable == "full" && (bc.HasFeature("pipeline-yaml") != true || bc.HasFeature("s2-cell-id") != true) {
+		t.Fatalf("Features not correct: %v", bc.Features)
+	} else if bc.TagTable == "minimal" && len(bc.Features) != 0 {
+		t.Fatalf("Features not correct: %v", bc.Features)
+	}
+
+	if s := bc.String(); strings.Contains(s, "CONTAINERS=[jpeg,") != true {
+		t.Fatalf("String not correct: [%s]", s)
+	}
+}
+
+func TestCapabilities_Registered(t *testing.T) {
+	original := mediaFormats
+	originalQuirks := quirks
+	defer func() {
+		mediaFormats = original
+		quirks = originalQuirks
+	}()
+
+	RegisterMediaFormat(testMediaFormat{})
+	RegisterStandardQuirks()
+
+	bc := Capabilities()
+
+	if bc.Containers[0] != Kind("test") {
+		t.Fatalf("Registered container not reported: %v", bc.Containers)
+	} else if len(bc.Quirks) != 2 || bc.Quirks[1] != "zero-resolution" {
+		t.Fatalf("Registered quirks not reported: %v", bc.Quirks)
+	}
+
+	// The report is a copy.
+	bc.Features = append(bc.Features, "extra")
+
+	if Capabilities().HasFeature("extra") == true {
+		t.Fatalf("Features not copied.")
+	}
+}
@@ -0,0 +1,195 @@
+package exif
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+const (
+	userCommentTagId = 0x9286
+)
+
+var (
+	builderMapLogger = log.NewLogger("exif.builder_map")
+)
+
+var (
+	// ErrTagPathNotValid means that a tag path (e.g. a key given to
+	// `BuilderFromMap()`) isn't an IFD path followed by a tag name or ID.
+	ErrTagPathNotValid = errors.New("tag path not valid")
+)
+
+// BuilderFromMap returns a builder for a new EXIF block with the tags in the
+// given map. The keys are fully-qualified tag paths, which are the
+// fully-qualified path of the IFD followed by the name of the tag or its ID in
+// hex (e.g. "IFD/Make", "IFD/Exif/ExposureTime", "IFD1/Compression", or
+// "IFD/Exif/0x9003"). IFDs are created as needed.
+//
+// Values are converted to the type of their tag as for `CoerceValue()` (e.g.
+// 0.004 for ExposureTime is 1/250), and `ErrValueNotConvertible` is returned
+// for values that don't fit. Undefined-type tags also take raw bytes or a
+// string, and UserComment takes a string as an ASCII comment.
+func BuilderFromMap(fields map[string]interface{}) (rootIb *IfdBuilder, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb = NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.EncodeDefaultByteOrder)
+
+	// Sort the paths so that the tags are always added in the same order.
+	tagPaths := make([]string, 0, len(fields))
+	for tagPath := range fields {
+		tagPaths = append(tagPaths, tagPath)
+	}
+
+	sort.Strings(tagPaths)
+
+	for _, tagPath := range tagPaths {
+		err := setMapTag(rootIb, tagPath, fields[tagPath])
+		log.PanicIf(err)
+	}
+
+	return rootIb, nil
+}
+
+// setMapTag sets one tag given to `BuilderFromMap()`.
+func setMapTag(rootIb *IfdBuilder, tagPath string, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fqIfdPath, it, err := resolveTagPath(rootIb.ifdMapping, rootIb.tagIndex, tagPath)
+	log.PanicIf(err)
+
+	err = setBuilderTag(rootIb, fqIfdPath, it, value)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// setBuilderTag sets a tag of the given IFD, adding the IFD if it's missing.
+func setBuilderTag(rootIb *IfdBuilder, fqIfdPath string, it *IndexedTag, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ib, err := GetOrCreateIbFromRootIb(rootIb, fqIfdPath)
+	log.PanicIf(err)
+
+	value, rawBytes := mapTagValue(it, value)
+	if rawBytes != nil {
+		bt := NewBuilderTag(ib.ifdPath, it.Id, exifcommon.TypeUndefined, NewIfdBuilderTagValueFromBytes(rawBytes), ib.byteOrder)
+
+		err := ib.Set(bt)
+		log.PanicIf(err)
+
+		return nil
+	}
+
+	err = ib.SetStandard(it.Id, value)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// mapTagValue returns the value given for a tag by path. A string for
+// UserComment becomes an ASCII comment. The raw bytes are returned instead
+// for other undefined-type tags that were given bytes or a string.
+func mapTagValue(it *IndexedTag, value interface{}) (tagValue interface{}, rawBytes []byte) {
+	if it.Type != exifcommon.TypeUndefined {
+		return value, nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return nil, v
+	case string:
+		if it.Id == userCommentTagId {
+			uc := exifundefined.Tag9286UserComment{
+				EncodingType:  exifundefined.TagUndefinedType_9286_UserComment_Encoding_ASCII,
+				EncodingBytes: []byte(v),
+			}
+
+			return uc, nil
+		}
+
+		return nil, []byte(v)
+	}
+
+	return value, nil
+}
+
+// resolveTagPath splits a fully-qualified tag path (e.g. "IFD/Exif/FNumber",
+// "IFD/Exif/0x829d", or "page:1/IFD/Exif/FNumber") into the fully-qualified
+// path of the IFD and the tag. Item selectors aren't allowed since the path
+// applies to one EXIF block (see `ParseTagAddress()`).
+func resolveTagPath(im *IfdMapping, ti *TagIndex, tagPath string) (fqIfdPath string, it *IndexedTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ta, err := ParseTagAddress(im, ti, tagPath)
+	log.PanicIf(err)
+
+	if ta.ItemId != 0 {
+		builderMapLogger.Warningf(nil, "Tag path [%s] can't select an item.", tagPath)
+		log.Panic(ErrTagPathNotValid)
+	}
+
+	return ta.FqIfdPath, ta.Tag, nil
+}
+
+// splitTagPath splits a fully-qualified tag path without selectors.
+func splitTagPath(im *IfdMapping, ti *TagIndex, tagPath string) (fqIfdPath string, it *IndexedTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	i := strings.LastIndex(tagPath, "/")
+	if i <= 0 || i == len(tagPath)-1 {
+		builderMapLogger.Warningf(nil, "Tag path [%s] doesn't have an IFD path and a tag.", tagPath)
+		log.Panic(ErrTagPathNotValid)
+	}
+
+	fqIfdPath = tagPath[:i]
+	tagName := tagPath[i+1:]
+
+	ifdPath, err := im.StripPathPhraseIndices(fqIfdPath)
+	log.PanicIf(err)
+
+	if strings.HasPrefix(tagName, "0x") == true {
+		tagId, err := strconv.ParseUint(tagName[2:], 16, 16)
+		if err != nil {
+			builderMapLogger.Warningf(nil, "Tag ID in path [%s] not valid.", tagPath)
+			log.Panic(ErrTagPathNotValid)
+		}
+
+		it, err = ti.Get(ifdPath, uint16(tagId))
+		log.PanicIf(err)
+	} else {
+		it, err = ti.GetWithName(ifdPath, tagName)
+		log.PanicIf(err)
+	}
+
+	return fqIfdPath, it, nil
+}
@@ -0,0 +1,106 @@
+package exif
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestBuilderFromMap(t *testing.T) {
+	fields := map[string]interface{}{
+		"IFD/Make":                  "Canon",
+		"IFD/Orientation":           6,
+		"IFD/Exif/ExposureTime":     0.004,
+		"IFD/Exif/FNumber":          "28/10",
+		"IFD/Exif/ISOSpeedRatings":  []int{400},
+		"IFD/Exif/0x9204":           -0.5,
+		"IFD/Exif/ExifVersion":      "0230",
+		"IFD/Exif/DateTimeOriginal": time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"IFD/GPSInfo/GPSLatitude":   []float64{26, 35, 12.5},
+		"IFD1/Compression":          uint8(6),
+	}
+
+	rootIb, err := BuilderFromMap(fields)
+	log.PanicIf(err)
+
+	rawExif, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	getValue := func(fqIfdPath, tagName string) interface{} {
+		ifd := index.RootIfd.NextIfd
+		if fqIfdPath != "IFD1" {
+			ifd = index.Lookup[fqIfdPath][0]
+		}
+
+		results, err := ifd.FindTagWithName(tagName)
+		log.PanicIf(err)
+
+		value, err := results[0].Value()
+		log.PanicIf(err)
+
+		return value
+	}
+
+	cases := []struct {
+		fqIfdPath string
+		tagName   string
+		expected  interface{}
+	}{
+		{"IFD", "Make", "Canon"},
+		{"IFD", "Orientation", []uint16{6}},
+		{"IFD/Exif", "ExposureTime", []exifcommon.Rational{{Numerator: 1, Denominator: 250}}},
+		{"IFD/Exif", "FNumber", []exifcommon.Rational{{Numerator: 28, Denominator: 10}}},
+		{"IFD/Exif", "ISOSpeedRatings", []uint16{400}},
+		{"IFD/Exif", "ExposureBiasValue", []exifcommon.SignedRational{{Numerator: -1, Denominator: 2}}},
+		{"IFD/Exif", "DateTimeOriginal", "2020:01:02 03:04:05"},
+		{"IFD/GPSInfo", "GPSLatitude", []exifcommon.Rational{{Numerator: 26, Denominator: 1}, {Numerator: 35, Denominator: 1}, {Numerator: 25, Denominator: 2}}},
+		{"IFD1", "Compression", []uint16{6}},
+	}
+
+	for _, c := range cases {
+		if value := getValue(c.fqIfdPath, c.tagName); reflect.DeepEqual(value, c.expected) != true {
+			t.Fatalf("Value of [%s] [%s] not correct: %v != %v", c.fqIfdPath, c.tagName, value, c.expected)
+		}
+	}
+
+	results, err := index.Lookup["IFD/Exif"][0].FindTagWithName("ExifVersion")
+	log.PanicIf(err)
+
+	rawBytes, err := results[0].GetRawBytes()
+	log.PanicIf(err)
+
+	if bytes.Equal(rawBytes, []byte("0230")) != true {
+		t.Fatalf("ExifVersion not correct: %v", rawBytes)
+	}
+}
+
+func TestBuilderFromMap_Errors(t *testing.T) {
+	cases := []struct {
+		name     string
+		fields   map[string]interface{}
+		expected error
+	}{
+		{name: "no IFD", fields: map[string]interface{}{"Make": "Canon"}, expected: ErrTagPathNotValid},
+		{name: "bad ID", fields: map[string]interface{}{"IFD/0xzz": 1}, expected: ErrTagPathNotValid},
+		{name: "out of range", fields: map[string]interface{}{"IFD/Orientation": 70000}, expected: ErrValueNotConvertible},
+		{name: "fraction", fields: map[string]interface{}{"IFD/Orientation": 1.5}, expected: ErrValueNotConvertible},
+		{name: "negative rational", fields: map[string]interface{}{"IFD/Exif/FNumber": -2.8}, expected: ErrValueNotConvertible},
+		{name: "number for string", fields: map[string]interface{}{"IFD/Make": 1}, expected: ErrValueNotConvertible},
+		{name: "nil", fields: map[string]interface{}{"IFD/Orientation": nil}, expected: ErrValueNotConvertible},
+		{name: "unknown tag", fields: map[string]interface{}{"IFD/NotATag": 1}, expected: ErrTagNotFound},
+	}
+
+	for _, c := range cases {
+		if _, err := BuilderFromMap(c.fields); log.Is(err, c.expected) == false {
+			t.Fatalf("Error not correct for [%s]: %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package exif
+
+import (
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// maxPlausibleIfdEntries is the most entries that we'll believe an IFD
+	// has when detecting its byte order. Real IFDs have far fewer.
+	maxPlausibleIfdEntries = 512
+)
+
+// oppositeByteOrder returns the other byte order.
+func oppositeByteOrder(byteOrder binary.ByteOrder) binary.ByteOrder {
+	if byteOrder == binary.BigEndian {
+		return binary.LittleEndian
+	}
+
+	return binary.BigEndian
+}
+
+// isPlausibleIfd returns true if the data at the offset looks like an IFD in
+// the given byte order: it has a reasonable number of entries, all of which
+// are present, and the first one has a valid type.
+func isPlausibleIfd(data []byte, ifdOffset uint32, byteOrder binary.ByteOrder) bool {
+	raw, err := exifcommon.CheckedSlice(data, ifdOffset, 2)
+	if err != nil {
+		return false
+	}
+
+	tagCount := byteOrder.Uint16(raw)
+	if tagCount == 0 || tagCount > maxPlausibleIfdEntries {
+		return false
+	}
+
+	table, err := exifcommon.CheckedSlice(data, ifdOffset, rawIfdTableSize(int(tagCount)))
+	if err != nil {
+		return false
+	}
+
+	tagType := exifcommon.TagTypePrimitive(byteOrder.Uint16(table[4:]))
+
+	return tagType.IsValid()
+}
+
+// DetectIfdByteOrder returns the byte order of the IFD at the given offset.
+// Some IFDs, usually maker-notes, are written in the opposite byte order from
+// the rest of the file. The given byte order is returned unless the IFD only
+// makes sense in the other one.
+func DetectIfdByteOrder(data []byte, ifdOffset uint32, byteOrder binary.ByteOrder) binary.ByteOrder {
+	if isPlausibleIfd(data, ifdOffset, byteOrder) == true {
+		return byteOrder
+	}
+
+	opposite := oppositeByteOrder(byteOrder)
+	if isPlausibleIfd(data, ifdOffset, opposite) == true {
+		return opposite
+	}
+
+	return byteOrder
+}
+
+// SetIfdByteOrder has the IFDs with the given (non-fully-qualified) path
+// (e.g. "IFD/Exif") parsed in the given byte order rather than the file's.
+// This is for IFDs, such as the maker-notes of some vendors that have been
+// added to the IFD mapping, that are known to use their own byte order.
+func (ie *IfdEnumerate) SetIfdByteOrder(ifdPath string, byteOrder binary.ByteOrder) {
+	if ie.ifdByteOrders == nil {
+		ie.ifdByteOrders = make(map[string]binary.ByteOrder)
+	}
+
+	ie.ifdByteOrders[ifdPath] = byteOrder
+}
+
+// SetDetectIfdByteOrder has the byte order of every IFD other than the root
+// IFDs detected with `DetectIfdByteOrder()`. Byte orders set with
+// `SetIfdByteOrder()` take precedence.
+func (ie *IfdEnumerate) SetDetectIfdByteOrder(detect bool) {
+	ie.detectIfdByteOrder = detect
+}
+
+// ifdByteOrder returns the byte order to parse the IFD at the given offset
+// with.
+func (ie *IfdEnumerate) ifdByteOrder(fqIfdPath string, ifdOffset uint32) binary.ByteOrder {
+	if ie.ifdByteOrders == nil && ie.detectIfdByteOrder == false {
+		return ie.byteOrder
+	}
+
+	ifdPath, err := ie.ifdMapping.StripPathPhraseIndices(fqIfdPath)
+	if err != nil {
+		return ie.byteOrder
+	}
+
+	if byteOrder, found := ie.ifdByteOrders[ifdPath]; found == true {
+		return byteOrder
+	}
+
+	if ie.detectIfdByteOrder == true && ifdPath != exifcommon.IfdPathStandard {
+		if ie.reader != nil {
+			// Only the table is needed to tell.
+
+			window, err := ie.readWindow(ifdOffset, rawIfdTableSize(maxPlausibleIfdEntries))
+			if err != nil {
+				return ie.byteOrder
+			}
+
+			return DetectIfdByteOrder(window, 0, ie.byteOrder)
+		}
+
+		return DetectIfdByteOrder(ie.exifData[ExifAddressableAreaStart:], ifdOffset, ie.byteOrder)
+	}
+
+	return ie.byteOrder
+}
+
+// convertRawByteOrder returns the raw bytes of the entry's value in the given
+// byte order, which they're already in unless the entry came from an IFD with
+// a different byte order. Undefined values are returned as they are since we
+// can't know how to convert them.
+func convertRawByteOrder(ite *IfdTagEntry, rawBytes []byte, byteOrder binary.ByteOrder) (convertedBytes []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ite.byteOrder == byteOrder {
+		return rawBytes, nil
+	}
+
+	switch ite.TagType() {
+	case exifcommon.TypeShort, exifcommon.TypeLong, exifcommon.TypeRational, exifcommon.TypeSignedLong, exifcommon.TypeSignedRational:
+	default:
+		return rawBytes, nil
+	}
+
+	value, err := ite.Value()
+	log.PanicIf(err)
+
+	ed, err := exifcommon.NewValueEncoder(byteOrder).Encode(value)
+	log.PanicIf(err)
+
+	return ed.Encoded, nil
+}
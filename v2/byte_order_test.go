@@ -0,0 +1,210 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestMixedByteOrderExifData returns big-endian EXIF data whose Exif IFD
+// has been rewritten in little-endian, like the maker-notes of some cameras.
+func getTestMixedByteOrderExifData() (exifData []byte, eh ExifHeader) {
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x010f, Value: "Canon"},
+		},
+		Children: []exiftest.Child{
+			{
+				TagId: exifcommon.IfdExifId,
+				Ifd: &exiftest.Ifd{
+					Tags: []exiftest.Tag{
+						{Id: 0x8827, Value: []uint16{400}},
+						{Id: 0xa002, Value: []uint32{4000}},
+					},
+				},
+			},
+		},
+	}
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	exifIfd, err := index.RootIfd.ChildWithIfdPath(exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	// Swap the fields of the table, all of whose values are inline.
+	table := exifData[ExifAddressableAreaStart+exifIfd.Offset:]
+
+	tagCount := int(binary.BigEndian.Uint16(table))
+	binary.LittleEndian.PutUint16(table, uint16(tagCount))
+
+	for i := 0; i < tagCount; i++ {
+		entry := table[2+i*int(IfdTagEntrySize):]
+
+		tagId := binary.BigEndian.Uint16(entry[0:])
+		tagType := exifcommon.TagTypePrimitive(binary.BigEndian.Uint16(entry[2:]))
+		unitCount := binary.BigEndian.Uint32(entry[4:])
+
+		binary.LittleEndian.PutUint16(entry[0:], tagId)
+		binary.LittleEndian.PutUint16(entry[2:], uint16(tagType))
+		binary.LittleEndian.PutUint32(entry[4:], unitCount)
+
+		if tagType == exifcommon.TypeShort {
+			binary.LittleEndian.PutUint16(entry[8:], binary.BigEndian.Uint16(entry[8:]))
+		} else {
+			binary.LittleEndian.PutUint32(entry[8:], binary.BigEndian.Uint32(entry[8:]))
+		}
+	}
+
+	eh, err = ParseExifHeader(exifData)
+	log.PanicIf(err)
+
+	return exifData, eh
+}
+
+func checkTestMixedByteOrderIndex(t *testing.T, index IfdIndex) {
+	exifIfd, err := index.RootIfd.ChildWithIfdPath(exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	if exifIfd.ByteOrder != binary.LittleEndian {
+		t.Fatalf("Exif IFD byte order not correct: %v", exifIfd.ByteOrder)
+	} else if index.RootIfd.ByteOrder != binary.BigEndian {
+		t.Fatalf("Root IFD byte order not correct: %v", index.RootIfd.ByteOrder)
+	}
+
+	results, err := exifIfd.FindTagWithId(0x8827)
+	log.PanicIf(err)
+
+	value, err := results[0].Value()
+	log.PanicIf(err)
+
+	if value.([]uint16)[0] != 400 {
+		t.Fatalf("SHORT value not correct: %v", value)
+	}
+
+	results, err = exifIfd.FindTagWithId(0xa002)
+	log.PanicIf(err)
+
+	value, err = results[0].Value()
+	log.PanicIf(err)
+
+	if value.([]uint32)[0] != 4000 {
+		t.Fatalf("LONG value not correct: %v", value)
+	}
+}
+
+func TestDetectIfdByteOrder(t *testing.T) {
+	exifData, eh := getTestMixedByteOrderExifData()
+	data := exifData[ExifAddressableAreaStart:]
+
+	if byteOrder := DetectIfdByteOrder(data, eh.FirstIfdOffset, binary.BigEndian); byteOrder != binary.BigEndian {
+		t.Fatalf("Root IFD byte order not correct: %v", byteOrder)
+	} else if byteOrder := DetectIfdByteOrder(data, eh.FirstIfdOffset, binary.LittleEndian); byteOrder != binary.BigEndian {
+		t.Fatalf("Root IFD byte order not detected: %v", byteOrder)
+	} else if byteOrder := DetectIfdByteOrder(data, uint32(len(data)), binary.LittleEndian); byteOrder != binary.LittleEndian {
+		t.Fatalf("Expected the given byte order for an offset out of bounds: %v", byteOrder)
+	}
+}
+
+func TestIfdEnumerate_SetDetectIfdByteOrder(t *testing.T) {
+	exifData, eh := getTestMixedByteOrderExifData()
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), exifData, eh.ByteOrder)
+
+	_, err := ie.Collect(eh.FirstIfdOffset)
+	if err == nil {
+		t.Fatalf("Expected the mixed byte order to fail without detection.")
+	}
+
+	ie = NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), exifData, eh.ByteOrder)
+	ie.SetDetectIfdByteOrder(true)
+
+	index, err := ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+
+	checkTestMixedByteOrderIndex(t, index)
+}
+
+func TestIfdEnumerate_SetIfdByteOrder(t *testing.T) {
+	exifData, eh := getTestMixedByteOrderExifData()
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), exifData, eh.ByteOrder)
+	ie.SetIfdByteOrder(exifcommon.IfdPathStandardExif, binary.LittleEndian)
+
+	index, err := ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+
+	checkTestMixedByteOrderIndex(t, index)
+}
+
+func TestNewIfdBuilderFromExistingChain_MixedByteOrder(t *testing.T) {
+	exifData, eh := getTestMixedByteOrderExifData()
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), exifData, eh.ByteOrder)
+	ie.SetDetectIfdByteOrder(true)
+
+	index, err := ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	updatedExifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	// The Exif IFD is written in the byte order of the file.
+	_, updatedIndex, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), updatedExifData)
+	log.PanicIf(err)
+
+	exifIfd, err := updatedIndex.RootIfd.ChildWithIfdPath(exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	results, err := exifIfd.FindTagWithId(0xa002)
+	log.PanicIf(err)
+
+	value, err := results[0].Value()
+	log.PanicIf(err)
+
+	if exifIfd.ByteOrder != binary.BigEndian || value.([]uint32)[0] != 4000 {
+		t.Fatalf("Value not converted: %v", value)
+	}
+}
+
+func TestGetVendorMakerNote_SonyOppositeByteOrder(t *testing.T) {
+	b := new(bytes.Buffer)
+	b.Write(sonyMakerNoteSignatures[0])
+
+	binary.Write(b, binary.LittleEndian, uint16(1))
+	binary.Write(b, binary.LittleEndian, []uint16{0x0001, uint16(exifcommon.TypeShort)})
+	binary.Write(b, binary.LittleEndian, []uint32{1, 0x00000102})
+	binary.Write(b, binary.LittleEndian, uint32(0))
+
+	index := getTestMakerNoteIndex("SONY", b.Bytes(), nil, nil)
+
+	vendor, mni, err := getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	if vendor != makerNoteVendorSony {
+		t.Fatalf("Vendor not correct: [%s]", vendor)
+	} else if value, found := mni.Int(0x0001); found != true || value != 0x0102 {
+		t.Fatalf("Value not correct: (%d)", value)
+	}
+
+	RegisterMakerNoteByteOrder(makerNoteVendorSony, binary.BigEndian)
+	defer delete(makerNoteByteOrders, makerNoteVendorSony)
+
+	_, mni, err = getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	if _, found := mni.Int(0x0001); found != false {
+		t.Fatalf("Expected the registered byte order to be used.")
+	}
+}
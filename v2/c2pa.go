@@ -0,0 +1,159 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// jumbfPacketHeaderSize is the size of the header of each APP11 packet:
+	// the "JP" common identifier, the box instance number, and the packet
+	// sequence number.
+	jumbfPacketHeaderSize = 8
+)
+
+var (
+	// ErrNoC2pa means that there's no C2PA manifest store.
+	ErrNoC2pa = errors.New("no c2pa manifest store")
+)
+
+var (
+	jumbfSignature = []byte("JP")
+
+	// c2paManifestStoreUuid is the type of the JUMBF description box of a
+	// C2PA manifest store.
+	c2paManifestStoreUuid = []byte{'c', '2', 'p', 'a', 0x00, 0x11, 0x00, 0x10, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+
+	// c2paBmffUuid is the extended type of the "uuid" box that holds the
+	// manifest store in HEIF (and other ISO base media) files.
+	c2paBmffUuid = []byte{0xd8, 0xfe, 0xc3, 0xd6, 0x1b, 0x0e, 0x48, 0x3c, 0x92, 0x97, 0x58, 0x28, 0x87, 0x7e, 0xc4, 0x81}
+)
+
+// C2paInfo says where a C2PA (Content Credentials) manifest store is. The
+// manifest store isn't parsed. It's reported so that callers know that the
+// file carries a provenance chain, which the writers in this package leave
+// untouched, but which signs the file's bytes and so won't validate after
+// the file is changed unless a new manifest is added.
+type C2paInfo struct {
+	// Kind is `KindJpeg` or `KindHeif`.
+	Kind Kind
+
+	// Segments are the APP11 segments that the manifest store is split
+	// across in a JPEG.
+	Segments []SegmentInfo
+
+	// Offset is the position of the first segment (JPEG) or of the "uuid"
+	// box (HEIF), and Size is the total size of the segments or the size of
+	// the box.
+	Offset int
+	Size   int
+}
+
+// String returns a descriptive string.
+func (ci C2paInfo) String() string {
+	return fmt.Sprintf("C2paInfo<KIND=[%s] SEGMENTS=(%d) OFFSET=(%d) SIZE=(%d)>", ci.Kind, len(ci.Segments), ci.Offset, ci.Size)
+}
+
+// isC2paJumbf returns true if the JUMBF superbox is a C2PA manifest store,
+// according to the type of its description box.
+func isC2paJumbf(box []byte) bool {
+	if len(box) < 16+len(c2paManifestStoreUuid) {
+		return false
+	} else if string(box[4:8]) != "jumb" || string(box[12:16]) != "jumd" {
+		return false
+	}
+
+	return bytes.Equal(box[16:16+len(c2paManifestStoreUuid)], c2paManifestStoreUuid)
+}
+
+// jpegC2paInstances returns the box instance numbers of the APP11 packets
+// that carry a C2PA manifest store. Only the first packet of an instance has
+// the description box, so the rest are identified by their instance number.
+func jpegC2paInstances(segments []jpegSegment) map[uint16]bool {
+	instances := make(map[uint16]bool)
+
+	for _, segment := range segments {
+		payload := segment.payload
+		if segment.marker != jpegMarkerApp11 || len(payload) < jumbfPacketHeaderSize || bytes.HasPrefix(payload, jumbfSignature) == false {
+			continue
+		}
+
+		instance := binary.BigEndian.Uint16(payload[2:])
+		sequence := binary.BigEndian.Uint32(payload[4:])
+
+		if sequence == 1 && isC2paJumbf(payload[jumbfPacketHeaderSize:]) == true {
+			instances[instance] = true
+		}
+	}
+
+	return instances
+}
+
+// isJpegC2paSegment returns true if the segment is a packet of one of the
+// given instances.
+func isJpegC2paSegment(segment jpegSegment, instances map[uint16]bool) bool {
+	payload := segment.payload
+	if segment.marker != jpegMarkerApp11 || len(payload) < jumbfPacketHeaderSize || bytes.HasPrefix(payload, jumbfSignature) == false {
+		return false
+	}
+
+	return instances[binary.BigEndian.Uint16(payload[2:])]
+}
+
+// GetC2pa returns where the C2PA manifest store of a JPEG or HEIF file is.
+// `ErrNoC2pa` is returned if there isn't one.
+func GetC2pa(data []byte) (ci C2paInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if isHeif(data) == true {
+		for _, ib := range readIsoBoxes(data, 0) {
+			if ib.boxType != "uuid" || bytes.HasPrefix(ib.payload, c2paBmffUuid) == false {
+				continue
+			}
+
+			// The payload is preceded by the size and the type.
+			ci = C2paInfo{
+				Kind:   KindHeif,
+				Offset: int(ib.offset) - 8,
+				Size:   8 + len(ib.payload),
+			}
+
+			return ci, nil
+		}
+
+		return ci, ErrNoC2pa
+	}
+
+	si, err := GetJpegSegmentsInfo(data)
+	if err == ErrNotJpeg {
+		return ci, ErrNoC2pa
+	}
+
+	log.PanicIf(err)
+
+	segments := si.Find(JpegSegmentC2pa)
+	if len(segments) == 0 {
+		return ci, ErrNoC2pa
+	}
+
+	ci = C2paInfo{
+		Kind:     KindJpeg,
+		Segments: segments,
+		Offset:   segments[0].Offset,
+	}
+
+	for _, segment := range segments {
+		ci.Size += segment.Size
+	}
+
+	return ci, nil
+}
@@ -0,0 +1,155 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// getTestJumbfPacket returns the payload of an APP11 segment with one packet
+// of a JUMBF superbox.
+func getTestJumbfPacket(instance uint16, sequence uint32, boxSize uint32, content []byte) []byte {
+	b := new(bytes.Buffer)
+	b.Write(jumbfSignature)
+	binary.Write(b, binary.BigEndian, instance)
+	binary.Write(b, binary.BigEndian, sequence)
+
+	// Every packet repeats the superbox header.
+	binary.Write(b, binary.BigEndian, boxSize)
+	b.WriteString("jumb")
+	b.Write(content)
+
+	return b.Bytes()
+}
+
+// getTestJpegWithC2pa returns the test JPEG with a C2PA manifest store split
+// across two APP11 segments, and another JUMBF box that isn't C2PA, after the
+// EXIF. The C2PA segments are returned as well.
+func getTestJpegWithC2pa() (data []byte, c2paSegments [][]byte) {
+	jumd := func(uuid []byte, label string) []byte {
+		b := new(bytes.Buffer)
+		binary.Write(b, binary.BigEndian, uint32(8+len(uuid)+1+len(label)+1))
+		b.WriteString("jumd")
+		b.Write(uuid)
+		b.WriteByte(0x03)
+		b.WriteString(label + "\x00")
+
+		return b.Bytes()
+	}
+
+	c2paJumd := jumd(c2paManifestStoreUuid, "c2pa")
+	manifest := bytes.Repeat([]byte("manifest"), 10)
+	boxSize := uint32(8 + len(c2paJumd) + len(manifest))
+
+	c2paSegments = [][]byte{
+		getTestJpegSegment(jpegMarkerApp11, getTestJumbfPacket(1, 1, boxSize, append(append([]byte{}, c2paJumd...), manifest[:40]...))),
+		getTestJpegSegment(jpegMarkerApp11, getTestJumbfPacket(1, 2, boxSize, manifest[40:])),
+	}
+
+	otherJumd := jumd(bytes.Repeat([]byte{0x11}, 16), "other")
+	other := getTestJpegSegment(jpegMarkerApp11, getTestJumbfPacket(2, 1, uint32(8+len(otherJumd)), otherJumd))
+
+	jpeg := getTestJpegWithExif()
+
+	// After the SOI and EXIF segments.
+	si, err := GetJpegSegmentsInfo(jpeg)
+	log.PanicIf(err)
+
+	end := si.Segments[0].Offset + si.Segments[0].Size
+
+	b := new(bytes.Buffer)
+	b.Write(jpeg[:end])
+	b.Write(c2paSegments[0])
+	b.Write(other)
+	b.Write(c2paSegments[1])
+	b.Write(jpeg[end:])
+
+	return b.Bytes(), c2paSegments
+}
+
+func TestGetC2pa_Jpeg(t *testing.T) {
+	data, c2paSegments := getTestJpegWithC2pa()
+
+	ci, err := GetC2pa(data)
+	log.PanicIf(err)
+
+	if ci.Kind != KindJpeg || len(ci.Segments) != 2 {
+		t.Fatalf("C2PA not found: %s", ci)
+	} else if ci.Size != len(c2paSegments[0])+len(c2paSegments[1]) {
+		t.Fatalf("Size not correct: %s", ci)
+	} else if bytes.HasPrefix(data[ci.Offset:], c2paSegments[0]) != true {
+		t.Fatalf("Offset not correct: %s", ci)
+	}
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	if len(si.Find(JpegSegmentOther)) != 1 {
+		t.Fatalf("Expected the other JUMBF box to not be C2PA: %v", si.Segments)
+	}
+
+	_, err = GetC2pa(getTestJpegWithExif())
+	if err != ErrNoC2pa {
+		t.Fatalf("Expected ErrNoC2pa: %v", err)
+	}
+}
+
+func TestGetC2pa_Heif(t *testing.T) {
+	heic, _, _ := getTestHeic()
+
+	_, err := GetC2pa(heic)
+	if err != ErrNoC2pa {
+		t.Fatalf("Expected ErrNoC2pa: %v", err)
+	}
+
+	uuid := getTestIsoBox("uuid", c2paBmffUuid, []byte{0, 0, 0, 0}, []byte("manifest\x00"))
+	withC2pa := append(append([]byte{}, heic...), uuid...)
+
+	ci, err := GetC2pa(withC2pa)
+	log.PanicIf(err)
+
+	if ci.Kind != KindHeif || ci.Offset != len(heic) || ci.Size != len(uuid) {
+		t.Fatalf("C2PA not correct: %s", ci)
+	}
+}
+
+func TestJpegWriters_PreserveC2pa(t *testing.T) {
+	data, c2paSegments := getTestJpegWithC2pa()
+
+	writers := map[string]func() ([]byte, error){
+		"SetJpegXmp": func() ([]byte, error) {
+			return SetJpegXmp(data, []byte("<x:xmpmeta></x:xmpmeta>"))
+		},
+		"SetJpegIptc": func() ([]byte, error) {
+			return SetJpegIptc(data, []IptcDataset{{Record: IptcRecordApplication, Dataset: IptcDatasetKeywords, Data: []byte("keyword")}})
+		},
+		"patchJpegExif": func() ([]byte, error) {
+			return patchJpegExif(data, func(ep *ExifPatcher) error {
+				return ep.Set(exifcommon.IfdStandard, 0x0112, []uint16{6})
+			})
+		},
+	}
+
+	for name, writer := range writers {
+		updated, err := writer()
+		log.PanicIf(err)
+
+		for _, segment := range c2paSegments {
+			if bytes.Contains(updated, segment) == false {
+				t.Fatalf("%s didn't preserve the C2PA segments.", name)
+			}
+		}
+
+		ci, err := GetC2pa(updated)
+		log.PanicIf(err)
+
+		if len(ci.Segments) != 2 {
+			t.Fatalf("%s: C2PA not found afterward: %s", name, ci)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package exif
+
+import (
+	"hash"
+	"sort"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// canonicalByteOrder is the byte order of the canonical encoding.
+	canonicalByteOrder binary.ByteOrder = binary.BigEndian
+)
+
+const (
+	// canonicalValueAlignment is the value alignment of the canonical
+	// encoding (what TIFF recommends).
+	canonicalValueAlignment = 2
+)
+
+// sortIbTags sorts the tags of the IB, its children, and the rest of its
+// chain by ID. Tags with the same ID stay in the same order.
+func sortIbTags(ib *IfdBuilder) {
+	for ; ib != nil; ib = ib.nextIb {
+		sort.SliceStable(ib.tags, func(i, j int) bool {
+			return ib.tags[i].tagId < ib.tags[j].tagId
+		})
+
+		for _, bt := range ib.tags {
+			if bt.value.IsIb() == true {
+				sortIbTags(bt.value.Ib())
+			}
+		}
+	}
+}
+
+// EncodeCanonicalExif encodes the IFDs in a canonical form, in which the same
+// metadata always produces the same bytes no matter how it was laid out in
+// the file: big-endian, with the tags of each IFD sorted by ID, every value
+// two-byte aligned (with zero padding), and nothing that isn't referenced by
+// an IFD. Re-encoding a file with this package, or with another writer that
+// doesn't change the values, doesn't change the canonical form, so it's what
+// signatures and HMACs over the metadata should be computed over.
+//
+// Tags that can't be re-encoded (see `AddTagsFromExisting()`) are left out.
+func EncodeCanonicalExif(rootIfd *Ifd) (data []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rootIb := newIfdBuilderFromExistingChain(rootIfd, canonicalByteOrder, UnknownTagKeep)
+	sortIbTags(rootIb)
+
+	ibe := NewIfdByteEncoder()
+	ibe.SetValueAlignment(canonicalValueAlignment)
+
+	data, err = ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	return data, nil
+}
+
+// CanonicalExifDigest parses the EXIF data, writes its canonical form (see
+// `EncodeCanonicalExif()`) to the hash, and returns the sum. Pass
+// `sha256.New()` for a digest to sign or `hmac.New()` for an HMAC.
+func CanonicalExifDigest(rawExif []byte, h hash.Hash) (digest []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	data, err := EncodeCanonicalExif(index.RootIfd)
+	log.PanicIf(err)
+
+	_, err = h.Write(data)
+	log.PanicIf(err)
+
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,113 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestCanonicalDigest(rawExif []byte) []byte {
+	digest, err := CanonicalExifDigest(rawExif, sha256.New())
+	log.PanicIf(err)
+
+	return digest
+}
+
+func TestCanonicalExifDigest(t *testing.T) {
+	original, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	// The same metadata in the other byte order and with IFD0 out of order.
+	reordered := exiftest.NewRealisticIfd()
+	reordered.KeepOrder = true
+
+	tags := reordered.Tags
+	for i, j := 0, len(tags)-1; i < j; i, j = i+1, j-1 {
+		tags[i], tags[j] = tags[j], tags[i]
+	}
+
+	swapped, err := exiftest.Build(reordered, binary.BigEndian)
+	log.PanicIf(err)
+
+	// The same metadata re-encoded with different padding.
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), original)
+	log.PanicIf(err)
+
+	ibe := NewIfdByteEncoder()
+	ibe.SetValueAlignment(4)
+
+	reencoded, err := ibe.EncodeToExif(NewIfdBuilderFromExistingChain(index.RootIfd))
+	log.PanicIf(err)
+
+	digest := getTestCanonicalDigest(original)
+
+	if bytes.Equal(getTestCanonicalDigest(swapped), digest) != true {
+		t.Fatalf("Byte order or tag order changed the digest.")
+	} else if bytes.Equal(getTestCanonicalDigest(reencoded), digest) != true {
+		t.Fatalf("Re-encoding changed the digest.")
+	}
+
+	changed := exiftest.NewRealisticIfd()
+	changed.Tags[3].Value = "2020:01:02 03:04:06"
+
+	tampered, err := exiftest.Build(changed, binary.LittleEndian)
+	log.PanicIf(err)
+
+	if bytes.Equal(getTestCanonicalDigest(tampered), digest) == true {
+		t.Fatalf("Changing a value didn't change the digest.")
+	}
+}
+
+func TestCanonicalExifDigest_Hmac(t *testing.T) {
+	rawExif := getTestExifData()
+
+	mac1, err := CanonicalExifDigest(rawExif, hmac.New(sha256.New, []byte("key1")))
+	log.PanicIf(err)
+
+	mac2, err := CanonicalExifDigest(rawExif, hmac.New(sha256.New, []byte("key2")))
+	log.PanicIf(err)
+
+	if len(mac1) != sha256.Size || bytes.Equal(mac1, mac2) == true {
+		t.Fatalf("HMACs not correct.")
+	}
+}
+
+func TestEncodeCanonicalExif(t *testing.T) {
+	original, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), original)
+	log.PanicIf(err)
+
+	canonical, err := EncodeCanonicalExif(index.RootIfd)
+	log.PanicIf(err)
+
+	eh, canonicalIndex, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), canonical)
+	log.PanicIf(err)
+
+	if eh.ByteOrder != binary.BigEndian {
+		t.Fatalf("Byte order not correct: %v", eh.ByteOrder)
+	}
+
+	for _, ifd := range canonicalIndex.Ifds {
+		for i := 1; i < len(ifd.Entries); i++ {
+			if ifd.Entries[i-1].TagId() > ifd.Entries[i].TagId() {
+				t.Fatalf("Tags of [%s] not sorted.", ifd.FqIfdPath)
+			}
+		}
+	}
+
+	dateTime, err := getIfdTagString(canonicalIndex.RootIfd, "DateTime")
+	log.PanicIf(err)
+
+	if dateTime != "2020:01:02 03:04:05" {
+		t.Fatalf("Value not correct: [%s]", dateTime)
+	}
+}
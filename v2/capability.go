@@ -0,0 +1,141 @@
+package exif
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// Capability is a set of the things that can be done with the EXIF of a file.
+type Capability int
+
+const (
+	// CapabilityReadExif means that the EXIF can be extracted.
+	CapabilityReadExif Capability = 1 << iota
+
+	// CapabilityReplaceExif means that the whole EXIF block can be replaced
+	// (see `MediaFormat.ReplaceExif()`).
+	CapabilityReplaceExif
+
+	// CapabilityUpdateTags means that individual tags can be set (see
+	// `UpdateFileTag()`).
+	CapabilityUpdateTags
+)
+
+const (
+	// capabilityAll is every capability.
+	capabilityAll = CapabilityReadExif | CapabilityReplaceExif | CapabilityUpdateTags
+)
+
+var (
+	capabilityNames = []struct {
+		capability Capability
+		name       string
+	}{
+		{CapabilityReadExif, "read-exif"},
+		{CapabilityReplaceExif, "replace-exif"},
+		{CapabilityUpdateTags, "update-tags"},
+	}
+)
+
+// Has returns true if every capability in `other` is in the set.
+func (c Capability) Has(other Capability) bool {
+	return c&other == other
+}
+
+// String returns the names of the capabilities, separated by commas, or
+// "none".
+func (c Capability) String() string {
+	names := make([]string, 0, len(capabilityNames))
+	for _, cn := range capabilityNames {
+		if c.Has(cn.capability) == true {
+			names = append(names, cn.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ",")
+}
+
+// CapabilityError is returned when a container is recognized (or not) but the
+// operation isn't supported for it, or for this particular file. It says what
+// can be done instead, so that tools can adapt (e.g. by only reading the EXIF
+// of files that can't be written). It unwraps to `ErrKindNotWritable`.
+type CapabilityError struct {
+	// Kind is the container, which is `KindUnknown` if it wasn't recognized.
+	Kind Kind
+
+	// Operation is what was attempted.
+	Operation Capability
+
+	// Available is what can be done with the file.
+	Available Capability
+
+	// Reason describes why the operation isn't supported.
+	Reason string
+}
+
+// Error returns a description of the operation, the container, and what's
+// available.
+func (ce *CapabilityError) Error() string {
+	return fmt.Sprintf("%s not supported for [%s] file (available: %s): %s", ce.Operation, ce.Kind, ce.Available, ce.Reason)
+}
+
+// Unwrap returns `ErrKindNotWritable`.
+func (ce *CapabilityError) Unwrap() error {
+	return ErrKindNotWritable
+}
+
+// AsCapabilityError returns the `CapabilityError` in the given error, which
+// may have since been wrapped with a stack.
+func AsCapabilityError(err error) (ce *CapabilityError, found bool) {
+	for err != nil {
+		switch e := err.(type) {
+		case *CapabilityError:
+			return e, true
+		case *errors.Error:
+			err = e.Err
+		default:
+			wrapper, ok := err.(interface{ Unwrap() error })
+			if ok == false {
+				return nil, false
+			}
+
+			err = wrapper.Unwrap()
+		}
+	}
+
+	return nil, false
+}
+
+// CapabilityReporter is implemented by formats that can't do everything with
+// every file. Formats that don't implement it are assumed to be able to read,
+// replace, and update the EXIF of any file that they recognize.
+type CapabilityReporter interface {
+	// Capabilities returns what can be done with the EXIF of the file.
+	Capabilities(data []byte) Capability
+}
+
+// GetCapabilities returns the container of the file and what can be done with
+// its EXIF. A file that isn't recognized has no capabilities.
+func GetCapabilities(data []byte) (kind Kind, capabilities Capability) {
+	mf, err := SniffMediaFormat(data)
+	if err != nil {
+		return KindUnknown, 0
+	}
+
+	return mf.Kind(), getFormatCapabilities(mf, data)
+}
+
+// getFormatCapabilities returns what the format can do with the file.
+func getFormatCapabilities(mf MediaFormat, data []byte) Capability {
+	if cr, ok := mf.(CapabilityReporter); ok == true {
+		return cr.Capabilities(data)
+	}
+
+	return capabilityAll
+}
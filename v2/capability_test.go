@@ -0,0 +1,133 @@
+package exif
+
+import (
+	"testing"
+
+	"io/ioutil"
+	"os"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestCapability_String(t *testing.T) {
+	cases := []struct {
+		capabilities Capability
+		expected     string
+	}{
+		{0, "none"},
+		{CapabilityReadExif, "read-exif"},
+		{CapabilityReadExif | CapabilityUpdateTags, "read-exif,update-tags"},
+		{capabilityAll, "read-exif,replace-exif,update-tags"},
+	}
+
+	for _, c := range cases {
+		if s := c.capabilities.String(); s != c.expected {
+			t.Fatalf("String for (%d) not correct: [%s] != [%s]", c.capabilities, s, c.expected)
+		}
+	}
+
+	if capabilityAll.Has(CapabilityReadExif|CapabilityUpdateTags) != true {
+		t.Fatalf("Has should be true for a subset.")
+	} else if CapabilityReadExif.Has(CapabilityReadExif|CapabilityUpdateTags) != false {
+		t.Fatalf("Has should be false for a superset.")
+	}
+}
+
+func TestCapabilityError(t *testing.T) {
+	ce := &CapabilityError{
+		Kind:      KindCr3,
+		Operation: CapabilityUpdateTags,
+		Available: CapabilityReadExif,
+		Reason:    "some reason",
+	}
+
+	if ce.Error() != "update-tags not supported for [cr3] file (available: read-exif): some reason" {
+		t.Fatalf("Error not correct: [%s]", ce.Error())
+	} else if ce.Unwrap() != ErrKindNotWritable {
+		t.Fatalf("Unwrap not correct: %v", ce.Unwrap())
+	}
+
+	if found, ok := AsCapabilityError(log.Wrap(ce)); ok != true || found != ce {
+		t.Fatalf("CapabilityError not found through the stack.")
+	} else if _, ok := AsCapabilityError(ErrKindNotWritable); ok != false {
+		t.Fatalf("CapabilityError shouldn't be found in a sentinel.")
+	}
+}
+
+func TestGetCapabilities(t *testing.T) {
+	exifData := getTestExifData()
+
+	cases := []struct {
+		data         []byte
+		kind         Kind
+		capabilities Capability
+	}{
+		{exiftest.WrapJpeg(exifData), KindJpeg, capabilityAll},
+		{exifData, KindTiff, CapabilityReadExif | CapabilityUpdateTags},
+		{exiftest.WrapPng(exifData), KindPng, capabilityAll},
+		{getTestWebp(webpExifFlag, exifData), KindWebp, capabilityAll},
+		{getTestHeifWithExif(exifData), KindHeif, capabilityAll},
+		{getTestCr3(exifData), KindCr3, CapabilityReadExif},
+		{[]byte("not media"), KindUnknown, 0},
+	}
+
+	for _, c := range cases {
+		kind, capabilities := GetCapabilities(c.data)
+		if kind != c.kind {
+			t.Fatalf("Kind not correct: [%s] != [%s]", kind, c.kind)
+		} else if capabilities != c.capabilities {
+			t.Fatalf("Capabilities of [%s] not correct: [%s] != [%s]", kind, capabilities, c.capabilities)
+		}
+	}
+}
+
+func TestUpdateFileTag_Cr3(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	original := getTestCr3(getTestExifData())
+	filepath := writeTestFile(tempPath, "image.cr3", original)
+
+	err = UpdateFileTag(filepath, "IFD/Make", "Canon")
+	if ce, found := AsCapabilityError(err); found != true {
+		t.Fatalf("Expected CapabilityError: %v", err)
+	} else if ce.Kind != KindCr3 || ce.Operation != CapabilityUpdateTags || ce.Available.Has(CapabilityReadExif) != true {
+		t.Fatalf("CapabilityError not correct: %v", ce)
+	}
+
+	updated, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	if string(updated) != string(original) {
+		t.Fatalf("File changed by a failed update.")
+	}
+}
+
+func TestUpdateFileTag_Png(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := writeTestFile(tempPath, "image.png", exiftest.WrapPng(getTestExifData()))
+
+	err = UpdateFileTag(filepath, "IFD/Make", "Nikon")
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	rawExif, err := GetPngExif(data)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	if make_, err := getIfdTagString(index.RootIfd, "Make"); err != nil || make_ != "Nikon" {
+		t.Fatalf("Make not correct: [%s] %v", make_, err)
+	}
+}
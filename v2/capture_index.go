@@ -0,0 +1,694 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"encoding/json"
+	"io"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// captureDayLayout is how capture days are keyed. They sort in order.
+	captureDayLayout = "2006-01-02"
+)
+
+var (
+	captureIndexLogger = log.NewLogger("exif.capture_index")
+)
+
+var (
+	// ErrCaptureQueryNotValid means that a capture query couldn't be parsed.
+	ErrCaptureQueryNotValid = errors.New("capture query not valid")
+)
+
+// CaptureRecord is the capture conditions of one file. Values that the file
+// doesn't have are zero.
+type CaptureRecord struct {
+	// Name identifies the file (e.g. its path).
+	Name string `json:"name"`
+
+	// Camera is the make and model.
+	Camera string `json:"camera,omitempty"`
+
+	// Lens is LensModel.
+	Lens string `json:"lens,omitempty"`
+
+	// Iso is the ISO sensitivity (see `GetIso()`).
+	Iso uint32 `json:"iso,omitempty"`
+
+	FNumber float64 `json:"f_number,omitempty"`
+
+	// FocalLength is in millimeters.
+	FocalLength float64 `json:"focal_length,omitempty"`
+
+	// Taken is when the image was taken (see `(*Summary).BestTakenTime()`).
+	Taken time.Time `json:"taken"`
+}
+
+// String returns a descriptive string.
+func (cr CaptureRecord) String() string {
+	return fmt.Sprintf("CaptureRecord<NAME=[%s] CAMERA=[%s] LENS=[%s] ISO=(%d) F-NUMBER=(%.1f) FOCAL-LENGTH=(%.0f) TAKEN=[%s]>", cr.Name, cr.Camera, cr.Lens, cr.Iso, cr.FNumber, cr.FocalLength, cr.Taken)
+}
+
+// NewCaptureRecord reads the capture conditions from an index.
+func NewCaptureRecord(name string, index IfdIndex) (cr CaptureRecord, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	cr.Name = name
+
+	if index.RootIfd != nil {
+		make_, err := getIfdTagString(index.RootIfd, "Make")
+		log.PanicIf(err)
+
+		model, err := getIfdTagString(index.RootIfd, "Model")
+		log.PanicIf(err)
+
+		cr.Camera = cameraName(make_, model)
+	}
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		cr.Lens, err = getIfdTagString(ifds[0], "LensModel")
+		log.PanicIf(err)
+	}
+
+	s := NewSummary(index)
+
+	if iso, err := s.Iso(); err == nil {
+		cr.Iso = uint32(iso)
+	} else if log.Is(err, ErrNoIso) == false {
+		log.Panic(err)
+	}
+
+	if fNumber, err := s.FNumber(); err == nil {
+		cr.FNumber = fNumber
+	} else if log.Is(err, ErrTagNotFound) == false {
+		log.Panic(err)
+	}
+
+	if focalLength, err := s.FocalLength(); err == nil {
+		cr.FocalLength = focalLength
+	} else if log.Is(err, ErrTagNotFound) == false {
+		log.Panic(err)
+	}
+
+	// Unparseable timestamps are common enough that they shouldn't stop the
+	// whole corpus from being indexed.
+	cr.Taken, _ = s.BestTakenTime(nil)
+
+	return cr, nil
+}
+
+// captureFNumberKey rounds an f-number to the tenth of a stop that lenses
+// are marked with.
+func captureFNumberKey(fNumber float64) float64 {
+	return math.Round(fNumber*10) / 10
+}
+
+// captureFocalLengthKey rounds a focal length to the millimeter.
+func captureFocalLengthKey(focalLength float64) float64 {
+	return math.Round(focalLength)
+}
+
+// CaptureIndex is an inverted index of the capture conditions of a corpus
+// of files, so that they can be searched (e.g. every shot at f/1.4 with an
+// 85mm) without a database. Cameras and lenses are indexed by name, ISOs,
+// f-numbers, and focal lengths by value, and capture times by day. It's safe
+// for concurrent use.
+type CaptureIndex struct {
+	records []CaptureRecord
+
+	// byName is the position of the current record of each name. Records
+	// that were replaced are left in place and skipped.
+	byName map[string]int
+
+	cameras      map[string][]int
+	lenses       map[string][]int
+	isos         map[uint32][]int
+	fNumbers     map[float64][]int
+	focalLengths map[float64][]int
+	days         map[string][]int
+
+	m sync.RWMutex
+}
+
+// NewCaptureIndex returns an empty index.
+func NewCaptureIndex() *CaptureIndex {
+	return &CaptureIndex{
+		records:      make([]CaptureRecord, 0),
+		byName:       make(map[string]int),
+		cameras:      make(map[string][]int),
+		lenses:       make(map[string][]int),
+		isos:         make(map[uint32][]int),
+		fNumbers:     make(map[float64][]int),
+		focalLengths: make(map[float64][]int),
+		days:         make(map[string][]int),
+	}
+}
+
+// Add indexes the capture conditions of one file (see `NewCaptureRecord()`).
+func (ci *CaptureIndex) Add(name string, index IfdIndex) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	cr, err := NewCaptureRecord(name, index)
+	log.PanicIf(err)
+
+	ci.AddRecord(cr)
+
+	return nil
+}
+
+// AddRecord indexes a record. A record with the same name replaces the
+// earlier one.
+func (ci *CaptureIndex) AddRecord(cr CaptureRecord) {
+	ci.m.Lock()
+	defer ci.m.Unlock()
+
+	i := len(ci.records)
+
+	ci.records = append(ci.records, cr)
+	ci.byName[cr.Name] = i
+
+	if cr.Camera != "" {
+		key := strings.ToLower(cr.Camera)
+		ci.cameras[key] = append(ci.cameras[key], i)
+	}
+
+	if cr.Lens != "" {
+		key := strings.ToLower(cr.Lens)
+		ci.lenses[key] = append(ci.lenses[key], i)
+	}
+
+	if cr.Iso != 0 {
+		ci.isos[cr.Iso] = append(ci.isos[cr.Iso], i)
+	}
+
+	if cr.FNumber != 0 {
+		key := captureFNumberKey(cr.FNumber)
+		ci.fNumbers[key] = append(ci.fNumbers[key], i)
+	}
+
+	if cr.FocalLength != 0 {
+		key := captureFocalLengthKey(cr.FocalLength)
+		ci.focalLengths[key] = append(ci.focalLengths[key], i)
+	}
+
+	if cr.Taken.IsZero() == false {
+		key := cr.Taken.Format(captureDayLayout)
+		ci.days[key] = append(ci.days[key], i)
+	}
+}
+
+// isCurrent returns true if the record hasn't been replaced.
+func (ci *CaptureIndex) isCurrent(i int) bool {
+	return ci.byName[ci.records[i].Name] == i
+}
+
+// Count returns the number of files indexed.
+func (ci *CaptureIndex) Count() int {
+	ci.m.RLock()
+	defer ci.m.RUnlock()
+
+	return len(ci.byName)
+}
+
+// Records returns every record, in the order that they were added.
+func (ci *CaptureIndex) Records() []CaptureRecord {
+	ci.m.RLock()
+	defer ci.m.RUnlock()
+
+	records := make([]CaptureRecord, 0, len(ci.byName))
+	for i, cr := range ci.records {
+		if ci.isCurrent(i) == true {
+			records = append(records, cr)
+		}
+	}
+
+	return records
+}
+
+// unionPostings returns the positions in any of the lists, in order.
+func unionPostings(lists [][]int) []int {
+	seen := make(map[int]bool)
+	union := make([]int, 0)
+
+	for _, list := range lists {
+		for _, i := range list {
+			if seen[i] == false {
+				seen[i] = true
+				union = append(union, i)
+			}
+		}
+	}
+
+	sort.Ints(union)
+
+	return union
+}
+
+// intersectPostings returns the positions in both of the ordered lists.
+func intersectPostings(a, b []int) []int {
+	intersection := make([]int, 0)
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		if a[i] < b[j] {
+			i++
+		} else if a[i] > b[j] {
+			j++
+		} else {
+			intersection = append(intersection, a[i])
+			i++
+			j++
+		}
+	}
+
+	return intersection
+}
+
+// Search returns the records that match every condition of the query, in
+// the order that they were added.
+func (ci *CaptureIndex) Search(cq CaptureQuery) []CaptureRecord {
+	ci.m.RLock()
+	defer ci.m.RUnlock()
+
+	// candidates is nil until a condition narrows the records down.
+	var candidates []int
+
+	narrow := func(lists [][]int) {
+		matches := unionPostings(lists)
+
+		if candidates == nil {
+			candidates = matches
+		} else {
+			candidates = intersectPostings(candidates, matches)
+		}
+	}
+
+	if cq.Camera != "" {
+		narrow(matchNamePostings(ci.cameras, cq.Camera))
+	}
+
+	if cq.Lens != "" {
+		narrow(matchNamePostings(ci.lenses, cq.Lens))
+	}
+
+	if cq.IsoMin != 0 || cq.IsoMax != 0 {
+		lists := make([][]int, 0)
+		for iso, postings := range ci.isos {
+			if iso >= cq.IsoMin && (cq.IsoMax == 0 || iso <= cq.IsoMax) {
+				lists = append(lists, postings)
+			}
+		}
+
+		narrow(lists)
+	}
+
+	if cq.FNumber != 0 {
+		narrow([][]int{ci.fNumbers[captureFNumberKey(cq.FNumber)]})
+	}
+
+	if cq.FocalLength != 0 {
+		narrow([][]int{ci.focalLengths[captureFocalLengthKey(cq.FocalLength)]})
+	}
+
+	if cq.From.IsZero() == false || cq.To.IsZero() == false {
+		from := ""
+		if cq.From.IsZero() == false {
+			from = cq.From.Format(captureDayLayout)
+		}
+
+		to := ""
+		if cq.To.IsZero() == false {
+			to = cq.To.Format(captureDayLayout)
+		}
+
+		lists := make([][]int, 0)
+		for day, postings := range ci.days {
+			if day >= from && (to == "" || day <= to) {
+				lists = append(lists, postings)
+			}
+		}
+
+		narrow(lists)
+	}
+
+	records := make([]CaptureRecord, 0)
+
+	if candidates == nil {
+		for i, cr := range ci.records {
+			if ci.isCurrent(i) == true {
+				records = append(records, cr)
+			}
+		}
+
+		return records
+	}
+
+	for _, i := range candidates {
+		if ci.isCurrent(i) == true {
+			records = append(records, ci.records[i])
+		}
+	}
+
+	return records
+}
+
+// matchNamePostings returns the postings of the names that contain the
+// phrase, ignoring case.
+func matchNamePostings(index map[string][]int, phrase string) [][]int {
+	phrase = strings.ToLower(phrase)
+
+	lists := make([][]int, 0)
+	for name, postings := range index {
+		if strings.Contains(name, phrase) == true {
+			lists = append(lists, postings)
+		}
+	}
+
+	return lists
+}
+
+// Save writes the records as JSON so that the index can be loaded again
+// with `LoadCaptureIndex()` rather than rebuilt.
+func (ci *CaptureIndex) Save(w io.Writer) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = json.NewEncoder(w).Encode(ci.Records())
+	log.PanicIf(err)
+
+	return nil
+}
+
+// LoadCaptureIndex reads an index written by `(*CaptureIndex).Save()`.
+func LoadCaptureIndex(r io.Reader) (ci *CaptureIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	records := make([]CaptureRecord, 0)
+
+	err = json.NewDecoder(r).Decode(&records)
+	log.PanicIf(err)
+
+	ci = NewCaptureIndex()
+	for _, cr := range records {
+		ci.AddRecord(cr)
+	}
+
+	return ci, nil
+}
+
+// BuildCaptureIndex parses the sources (see `ParseMany()`) and indexes each
+// one. Sources without EXIF, or that fail to parse, aren't indexed. If the
+// options have an `OnResult` callback, it's still called.
+func BuildCaptureIndex(ctx context.Context, sources []ParseSource, opts ParseManyOptions) (ci *CaptureIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ci = NewCaptureIndex()
+
+	onResult := opts.OnResult
+
+	opts.OnResult = func(pr ParseResult) (err error) {
+		if onResult != nil {
+			err := onResult(pr)
+			if err != nil {
+				return err
+			}
+		}
+
+		if pr.Err != nil {
+			if pr.Err != ErrNoExif {
+				captureIndexLogger.Warningf(nil, "Source [%s] not indexed: %v", pr.Name, pr.Err)
+			}
+
+			return nil
+		}
+
+		return ci.Add(pr.Name, pr.Index)
+	}
+
+	_, err = ParseMany(ctx, sources, opts)
+	log.PanicIf(err)
+
+	return ci, nil
+}
+
+// CaptureQuery is the conditions that `(*CaptureIndex).Search()` matches.
+// Zero values match everything.
+type CaptureQuery struct {
+	// Camera and Lens match names that contain them, ignoring case.
+	Camera string
+	Lens   string
+
+	// IsoMin and IsoMax are the inclusive range of ISOs. A zero IsoMax is
+	// unbounded.
+	IsoMin uint32
+	IsoMax uint32
+
+	// FNumber matches to the tenth of a stop.
+	FNumber float64
+
+	// FocalLength matches to the millimeter.
+	FocalLength float64
+
+	// From and To are the inclusive range of days that the images were
+	// taken. Only the dates are considered.
+	From time.Time
+	To   time.Time
+}
+
+// String returns a descriptive string.
+func (cq CaptureQuery) String() string {
+	return fmt.Sprintf("CaptureQuery<CAMERA=[%s] LENS=[%s] ISO=(%d)-(%d) F-NUMBER=(%.1f) FOCAL-LENGTH=(%.0f) FROM=[%s] TO=[%s]>", cq.Camera, cq.Lens, cq.IsoMin, cq.IsoMax, cq.FNumber, cq.FocalLength, cq.From, cq.To)
+}
+
+// splitCaptureQuery splits a query into its terms at whitespace that isn't
+// within double-quotes. The quotes are removed.
+func splitCaptureQuery(phrase string) (terms []string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	terms = make([]string, 0)
+
+	var term strings.Builder
+	inTerm := false
+	quoted := false
+
+	for _, r := range phrase {
+		if r == '"' {
+			quoted = !quoted
+			inTerm = true
+		} else if quoted == false && (r == ' ' || r == '\t') {
+			if inTerm == true {
+				terms = append(terms, term.String())
+				term.Reset()
+				inTerm = false
+			}
+		} else {
+			term.WriteRune(r)
+			inTerm = true
+		}
+	}
+
+	if quoted == true {
+		captureIndexLogger.Warningf(nil, "Capture query [%s] has an unterminated quote.", phrase)
+		log.Panic(ErrCaptureQueryNotValid)
+	}
+
+	if inTerm == true {
+		terms = append(terms, term.String())
+	}
+
+	return terms, nil
+}
+
+// parseCaptureDay parses a year, a month ("2006-01"), or a day
+// ("2006-01-02") and returns the first and last days of it.
+func parseCaptureDay(phrase string) (first, last time.Time, err error) {
+	layouts := []struct {
+		layout string
+		years  int
+		months int
+		days   int
+	}{
+		{"2006", 1, 0, 0},
+		{"2006-01", 0, 1, 0},
+		{captureDayLayout, 0, 0, 1},
+	}
+
+	for _, l := range layouts {
+		first, err := time.Parse(l.layout, phrase)
+		if err != nil {
+			continue
+		}
+
+		last = first.AddDate(l.years, l.months, l.days-1)
+
+		return first, last, nil
+	}
+
+	return first, last, ErrCaptureQueryNotValid
+}
+
+// ParseCaptureQuery parses a query made of whitespace-separated terms, all of
+// which have to match:
+//
+//	f/1.4                           the f-number
+//	85mm                            the focal length
+//	iso:400, iso:100-800, iso:1600- an ISO or a range of them
+//	camera:canon, lens:"EF 85mm"    part of the camera or lens name
+//	date:2020, date:2020-05-03      a year, month, or day
+//	date:2020-01..2020-03           a range of them (either end can be left off)
+func ParseCaptureQuery(phrase string) (cq CaptureQuery, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	terms, err := splitCaptureQuery(phrase)
+	log.PanicIf(err)
+
+	for _, term := range terms {
+		lowered := strings.ToLower(term)
+
+		valid := false
+
+		if strings.HasPrefix(lowered, "camera:") == true {
+			cq.Camera = term[7:]
+			valid = cq.Camera != ""
+		} else if strings.HasPrefix(lowered, "lens:") == true {
+			cq.Lens = term[5:]
+			valid = cq.Lens != ""
+		} else if strings.HasPrefix(lowered, "iso:") == true {
+			valid = parseCaptureIsoRange(lowered[4:], &cq)
+		} else if strings.HasPrefix(lowered, "date:") == true {
+			valid = parseCaptureDateRange(lowered[5:], &cq)
+		} else if strings.HasPrefix(lowered, "f/") == true {
+			cq.FNumber, err = strconv.ParseFloat(lowered[2:], 64)
+			valid = err == nil && cq.FNumber > 0
+		} else if strings.HasSuffix(lowered, "mm") == true {
+			cq.FocalLength, err = strconv.ParseFloat(lowered[:len(lowered)-2], 64)
+			valid = err == nil && cq.FocalLength > 0
+		}
+
+		if valid == false {
+			captureIndexLogger.Warningf(nil, "Capture query term [%s] not valid.", term)
+			log.Panic(ErrCaptureQueryNotValid)
+		}
+	}
+
+	return cq, nil
+}
+
+// parseCaptureIsoRange parses an ISO ("400") or a range of them ("100-800",
+// "1600-", or "-200") into the query.
+func parseCaptureIsoRange(phrase string, cq *CaptureQuery) bool {
+	parts := strings.SplitN(phrase, "-", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+
+	if parts[0] == "" && parts[1] == "" {
+		return false
+	}
+
+	if parts[0] != "" {
+		value, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil {
+			return false
+		}
+
+		cq.IsoMin = uint32(value)
+	}
+
+	if parts[1] != "" {
+		value, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil || uint32(value) < cq.IsoMin {
+			return false
+		}
+
+		cq.IsoMax = uint32(value)
+	}
+
+	return true
+}
+
+// parseCaptureDateRange parses a year, month, or day, or a range of them
+// separated by "..", into the query.
+func parseCaptureDateRange(phrase string, cq *CaptureQuery) bool {
+	parts := strings.SplitN(phrase, "..", 2)
+	if len(parts) == 1 {
+		first, last, err := parseCaptureDay(phrase)
+		if err != nil {
+			return false
+		}
+
+		cq.From, cq.To = first, last
+
+		return true
+	}
+
+	if parts[0] == "" && parts[1] == "" {
+		return false
+	}
+
+	if parts[0] != "" {
+		first, _, err := parseCaptureDay(parts[0])
+		if err != nil {
+			return false
+		}
+
+		cq.From = first
+	}
+
+	if parts[1] != "" {
+		_, last, err := parseCaptureDay(parts[1])
+		if err != nil {
+			return false
+		}
+
+		cq.To = last
+	}
+
+	if cq.From.IsZero() == false && cq.To.IsZero() == false && cq.To.Before(cq.From) == true {
+		return false
+	}
+
+	return true
+}
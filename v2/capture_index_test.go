@@ -0,0 +1,192 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestCaptureExif returns EXIF with the given capture conditions. The
+// f-number is in tenths.
+func getTestCaptureExif(model, lens string, fNumber, focalLength uint32, iso uint16, dateTimeOriginal string) []byte {
+	root := exiftest.NewRealisticIfd()
+	root.Tags[1].Value = model
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = []exiftest.Tag{
+		{Id: 0x829d, Value: []exifcommon.Rational{{Numerator: fNumber, Denominator: 10}}},
+		{Id: 0x8827, Value: []uint16{iso}},
+		{Id: 0x9003, Value: dateTimeOriginal},
+		{Id: 0x920a, Value: []exifcommon.Rational{{Numerator: focalLength, Denominator: 1}}},
+		{Id: 0xa434, Value: lens},
+	}
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return exifData
+}
+
+func getTestCaptureIndex() *CaptureIndex {
+	sources := []ParseSource{
+		BytesSource("a.jpg", getTestCaptureExif("Canon EOS R5", "RF85mm F1.2 L USM", 14, 85, 100, "2020:05:03 10:00:00")),
+		BytesSource("b.jpg", getTestCaptureExif("Canon EOS R5", "RF85mm F1.2 L USM", 28, 85, 800, "2020:06:01 10:00:00")),
+		BytesSource("c.jpg", getTestCaptureExif("Canon EOS R6", "RF35mm F1.8 MACRO IS STM", 14, 35, 3200, "2021:01:01 10:00:00")),
+		BytesSource("d.jpg", []byte("not an image")),
+	}
+
+	ci, err := BuildCaptureIndex(context.Background(), sources, ParseManyOptions{})
+	log.PanicIf(err)
+
+	return ci
+}
+
+func getTestCaptureNames(records []CaptureRecord) []string {
+	names := make([]string, len(records))
+	for i, cr := range records {
+		names[i] = cr.Name
+	}
+
+	return names
+}
+
+func TestNewCaptureRecord(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestCaptureExif("Canon EOS R5", "RF85mm F1.2 L USM", 14, 85, 100, "2020:05:03 10:00:00"))
+	log.PanicIf(err)
+
+	cr, err := NewCaptureRecord("a.jpg", index)
+	log.PanicIf(err)
+
+	expected := CaptureRecord{
+		Name:        "a.jpg",
+		Camera:      "Canon EOS R5",
+		Lens:        "RF85mm F1.2 L USM",
+		Iso:         100,
+		FNumber:     1.4,
+		FocalLength: 85,
+		Taken:       time.Date(2020, 5, 3, 10, 0, 0, 0, time.UTC),
+	}
+
+	if reflect.DeepEqual(cr, expected) != true {
+		t.Fatalf("Record not correct: %s", cr)
+	}
+}
+
+func TestCaptureIndex_Search(t *testing.T) {
+	ci := getTestCaptureIndex()
+
+	if ci.Count() != 3 {
+		t.Fatalf("Count not correct: (%d)", ci.Count())
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"a.jpg", "b.jpg", "c.jpg"}},
+		{"f/1.4 85mm", []string{"a.jpg"}},
+		{"f/1.4", []string{"a.jpg", "c.jpg"}},
+		{"iso:100-800", []string{"a.jpg", "b.jpg"}},
+		{"iso:800-", []string{"b.jpg", "c.jpg"}},
+		{"camera:r5 lens:\"rf85mm f1.2\"", []string{"a.jpg", "b.jpg"}},
+		{"date:2020", []string{"a.jpg", "b.jpg"}},
+		{"date:2020-06..2021-01", []string{"b.jpg", "c.jpg"}},
+		{"date:..2020-05-03", []string{"a.jpg"}},
+		{"camera:nikon", []string{}},
+		{"f/2 50mm", []string{}},
+	}
+
+	for _, test := range tests {
+		cq, err := ParseCaptureQuery(test.query)
+		log.PanicIf(err)
+
+		names := getTestCaptureNames(ci.Search(cq))
+
+		if reflect.DeepEqual(names, test.expected) != true {
+			t.Fatalf("Results for [%s] not correct: %v", test.query, names)
+		}
+	}
+}
+
+func TestCaptureIndex_AddRecord_Replace(t *testing.T) {
+	ci := NewCaptureIndex()
+
+	ci.AddRecord(CaptureRecord{Name: "a.jpg", FocalLength: 85})
+	ci.AddRecord(CaptureRecord{Name: "a.jpg", FocalLength: 35})
+
+	if ci.Count() != 1 {
+		t.Fatalf("Count not correct: (%d)", ci.Count())
+	} else if records := ci.Search(CaptureQuery{FocalLength: 85}); len(records) != 0 {
+		t.Fatalf("Replaced record should not be found: %v", records)
+	} else if records := ci.Search(CaptureQuery{FocalLength: 35}); len(records) != 1 {
+		t.Fatalf("Replacement record not found: %v", records)
+	}
+}
+
+func TestCaptureIndex_SaveAndLoad(t *testing.T) {
+	ci := getTestCaptureIndex()
+
+	b := new(bytes.Buffer)
+
+	err := ci.Save(b)
+	log.PanicIf(err)
+
+	loaded, err := LoadCaptureIndex(b)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(loaded.Records(), ci.Records()) != true {
+		t.Fatalf("Loaded records not correct: %v", loaded.Records())
+	}
+
+	names := getTestCaptureNames(loaded.Search(CaptureQuery{FNumber: 1.4, FocalLength: 85}))
+	if reflect.DeepEqual(names, []string{"a.jpg"}) != true {
+		t.Fatalf("Loaded index not searchable: %v", names)
+	}
+}
+
+func TestParseCaptureQuery(t *testing.T) {
+	cq, err := ParseCaptureQuery("F/1.4  85mm iso:400 camera:\"EOS R5\" date:2020-02")
+	log.PanicIf(err)
+
+	expected := CaptureQuery{
+		Camera:      "EOS R5",
+		IsoMin:      400,
+		IsoMax:      400,
+		FNumber:     1.4,
+		FocalLength: 85,
+		From:        time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+	}
+
+	if reflect.DeepEqual(cq, expected) != true {
+		t.Fatalf("Query not correct: %s", cq)
+	}
+
+	invalid := []string{
+		"aperture:1.4",
+		"f/wide",
+		"iso:800-100",
+		"date:2020-13",
+		"date:2021..2020",
+		"camera:\"EOS",
+		"lens:",
+	}
+
+	for _, phrase := range invalid {
+		if _, err := ParseCaptureQuery(phrase); log.Is(err, ErrCaptureQueryNotValid) != true {
+			t.Fatalf("Expected ErrCaptureQueryNotValid for [%s]: %v", phrase, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"errors"
+	"time"
+
+	"database/sql"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// ErrCatalogNoTarget means that the "sqlite" sink wasn't given a database.
+	ErrCatalogNoTarget = errors.New("catalog needs a database file-path")
+)
+
+var (
+	// CatalogSqlDriverName is the `database/sql` driver that the "sqlite" sink
+	// opens its target with. The driver isn't linked in by this package, so
+	// the program has to import one (e.g. `github.com/mattn/go-sqlite3`,
+	// which registers "sqlite3").
+	CatalogSqlDriverName = "sqlite3"
+)
+
+var (
+	// catalogSchema creates the catalog's tables and indices. There's a row in
+	// "files" for every file, a row in "tags" for every tag, and a row in
+	// "gps" for every file with a position.
+	catalogSchema = []string{
+		`CREATE TABLE IF NOT EXISTS files (
+			path TEXT PRIMARY KEY,
+			error TEXT,
+			byte_order TEXT,
+			make TEXT,
+			model TEXT,
+			date_time_original TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS tags (
+			file_path TEXT NOT NULL REFERENCES files(path),
+			ifd_path TEXT NOT NULL,
+			tag_id INTEGER NOT NULL,
+			tag_name TEXT,
+			tag_type TEXT NOT NULL,
+			value TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS gps (
+			file_path TEXT PRIMARY KEY REFERENCES files(path),
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			altitude INTEGER,
+			timestamp TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS files_make_model ON files (make, model)`,
+		`CREATE INDEX IF NOT EXISTS files_date_time_original ON files (date_time_original)`,
+		`CREATE INDEX IF NOT EXISTS tags_file_path ON tags (file_path)`,
+		`CREATE INDEX IF NOT EXISTS tags_tag_name_value ON tags (tag_name, value)`,
+		`CREATE INDEX IF NOT EXISTS gps_latitude_longitude ON gps (latitude, longitude)`,
+	}
+)
+
+// SqlCatalog writes the metadata of many files into a SQLite database, as a
+// catalog that can be queried (e.g. every file taken with a given camera or
+// within a bounding box). Only `database/sql` is used, so the program
+// chooses the driver.
+type SqlCatalog struct {
+	db *sql.DB
+}
+
+// NewSqlCatalog creates the tables and indices in the database if they don't
+// already exist.
+func NewSqlCatalog(db *sql.DB) (sc *SqlCatalog, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for _, query := range catalogSchema {
+		_, err := db.Exec(query)
+		log.PanicIf(err)
+	}
+
+	sc = &SqlCatalog{
+		db: db,
+	}
+
+	return sc, nil
+}
+
+// Add writes the result of parsing a file. A file that's already in the
+// catalog is replaced.
+func (sc *SqlCatalog) Add(pr ParseResult) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tx, err := sc.db.Begin()
+	log.PanicIf(err)
+
+	committed := false
+
+	defer func() {
+		if committed == false {
+			tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(`DELETE FROM tags WHERE file_path = ?`, pr.Name)
+	log.PanicIf(err)
+
+	_, err = tx.Exec(`DELETE FROM gps WHERE file_path = ?`, pr.Name)
+	log.PanicIf(err)
+
+	if pr.Err != nil {
+		_, err = tx.Exec(`INSERT OR REPLACE INTO files (path, error) VALUES (?, ?)`, pr.Name, pr.Err.Error())
+		log.PanicIf(err)
+
+		err = tx.Commit()
+		log.PanicIf(err)
+
+		committed = true
+
+		return nil
+	}
+
+	rootIfd := pr.Index.RootIfd
+
+	cameraMake, err := getIfdTagString(rootIfd, "Make")
+	log.PanicIf(err)
+
+	model, err := getIfdTagString(rootIfd, "Model")
+	log.PanicIf(err)
+
+	dateTimeOriginal := ""
+	if ifds := pr.Index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		dateTimeOriginal, err = getIfdTagString(ifds[0], "DateTimeOriginal")
+		log.PanicIf(err)
+	}
+
+	_, err = tx.Exec(
+		`INSERT OR REPLACE INTO files (path, error, byte_order, make, model, date_time_original) VALUES (?, NULL, ?, ?, ?, ?)`,
+		pr.Name, pr.Header.ByteOrder.String(), cameraMake, model, dateTimeOriginal)
+
+	log.PanicIf(err)
+
+	for _, ifd := range pr.Index.Ifds {
+		for _, ite := range ifd.Entries {
+			tagName := ""
+			if it, err := ifd.tagIndex.Get(ifd.IfdPath, ite.TagId()); err == nil {
+				tagName = it.Name
+			}
+
+			value, err := ite.Format()
+			log.PanicIf(err)
+
+			_, err = tx.Exec(
+				`INSERT INTO tags (file_path, ifd_path, tag_id, tag_name, tag_type, value) VALUES (?, ?, ?, ?, ?, ?)`,
+				pr.Name, ifd.IfdPath, int64(ite.TagId()), tagName, ite.TagType().String(), value)
+
+			log.PanicIf(err)
+		}
+	}
+
+	if ifds := pr.Index.Lookup[exifcommon.IfdPathStandardGps]; len(ifds) > 0 {
+		if gi, err := ifds[0].GpsInfo(); err == nil {
+			timestamp := ""
+			if gi.Timestamp.IsZero() == false {
+				timestamp = gi.Timestamp.Format(time.RFC3339)
+			}
+
+			_, err = tx.Exec(
+				`INSERT INTO gps (file_path, latitude, longitude, altitude, timestamp) VALUES (?, ?, ?, ?, ?)`,
+				pr.Name, gi.Latitude.Decimal(), gi.Longitude.Decimal(), int64(gi.Altitude), timestamp)
+
+			log.PanicIf(err)
+		}
+	}
+
+	err = tx.Commit()
+	log.PanicIf(err)
+
+	committed = true
+
+	return nil
+}
+
+// catalogSink is the "sqlite" sink, which writes to the catalog in the
+// database at its target.
+type catalogSink struct {
+	target  string
+	db      *sql.DB
+	catalog *SqlCatalog
+}
+
+// newCatalogSink returns a sink that writes to the SQLite database at the
+// target, using the driver named by `CatalogSqlDriverName`.
+func newCatalogSink(target string) (sink Sink, err error) {
+	if target == "" {
+		return nil, ErrCatalogNoTarget
+	}
+
+	return &catalogSink{
+		target: target,
+	}, nil
+}
+
+// Start opens the database and creates the catalog.
+func (cs *catalogSink) Start() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	cs.db, err = sql.Open(CatalogSqlDriverName, cs.target)
+	log.PanicIf(err)
+
+	cs.catalog, err = NewSqlCatalog(cs.db)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// Emit adds the file to the catalog.
+func (cs *catalogSink) Emit(pr ParseResult) (err error) {
+	return cs.catalog.Add(pr)
+}
+
+// Close closes the database.
+func (cs *catalogSink) Close() (err error) {
+	if cs.db == nil {
+		return nil
+	}
+
+	err = cs.db.Close()
+	cs.db = nil
+
+	return err
+}
+
+func init() {
+	RegisterSink("sqlite", newCatalogSink)
+}
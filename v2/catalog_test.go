@@ -0,0 +1,243 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// testSqlStatement is a statement executed against the test driver.
+type testSqlStatement struct {
+	query string
+	args  []driver.Value
+}
+
+// testSqlDriver is a `database/sql` driver that records the statements that
+// are committed, so that the catalog can be tested without SQLite.
+type testSqlDriver struct {
+	m         sync.Mutex
+	committed []testSqlStatement
+	failOn    string
+}
+
+func (tsd *testSqlDriver) Open(name string) (driver.Conn, error) {
+	return &testSqlConn{driver: tsd}, nil
+}
+
+type testSqlConn struct {
+	driver  *testSqlDriver
+	pending []testSqlStatement
+	inTx    bool
+}
+
+func (tsc *testSqlConn) Prepare(query string) (driver.Stmt, error) {
+	return &testSqlStmt{conn: tsc, query: query}, nil
+}
+
+func (tsc *testSqlConn) Close() error {
+	return nil
+}
+
+func (tsc *testSqlConn) Begin() (driver.Tx, error) {
+	tsc.inTx = true
+	tsc.pending = nil
+
+	return tsc, nil
+}
+
+func (tsc *testSqlConn) Commit() error {
+	tsc.driver.m.Lock()
+	defer tsc.driver.m.Unlock()
+
+	tsc.driver.committed = append(tsc.driver.committed, tsc.pending...)
+	tsc.pending = nil
+	tsc.inTx = false
+
+	return nil
+}
+
+func (tsc *testSqlConn) Rollback() error {
+	tsc.pending = nil
+	tsc.inTx = false
+
+	return nil
+}
+
+type testSqlStmt struct {
+	conn  *testSqlConn
+	query string
+}
+
+func (tss *testSqlStmt) Close() error {
+	return nil
+}
+
+func (tss *testSqlStmt) NumInput() int {
+	return -1
+}
+
+func (tss *testSqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if tss.conn.driver.failOn != "" && strings.Contains(tss.query, tss.conn.driver.failOn) == true {
+		return nil, driver.ErrBadConn
+	}
+
+	statement := testSqlStatement{
+		query: tss.query,
+		args:  args,
+	}
+
+	if tss.conn.inTx == true {
+		tss.conn.pending = append(tss.conn.pending, statement)
+	} else {
+		tss.conn.driver.m.Lock()
+		tss.conn.driver.committed = append(tss.conn.driver.committed, statement)
+		tss.conn.driver.m.Unlock()
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (tss *testSqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+var (
+	testSqlDriverInstance = new(testSqlDriver)
+)
+
+func init() {
+	sql.Register("exif-test", testSqlDriverInstance)
+}
+
+func (tsd *testSqlDriver) reset() {
+	tsd.m.Lock()
+	defer tsd.m.Unlock()
+
+	tsd.committed = nil
+	tsd.failOn = ""
+}
+
+func (tsd *testSqlDriver) find(prefix string) []testSqlStatement {
+	tsd.m.Lock()
+	defer tsd.m.Unlock()
+
+	found := make([]testSqlStatement, 0)
+	for _, statement := range tsd.committed {
+		if strings.HasPrefix(statement.query, prefix) == true {
+			found = append(found, statement)
+		}
+	}
+
+	return found
+}
+
+func TestSqlCatalog(t *testing.T) {
+	testSqlDriverInstance.reset()
+
+	originalDriverName := CatalogSqlDriverName
+	CatalogSqlDriverName = "exif-test"
+
+	defer func() {
+		CatalogSqlDriverName = originalDriverName
+	}()
+
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	sink, err := NewSink("sqlite", "catalog.db")
+	log.PanicIf(err)
+
+	err = sink.Start()
+	log.PanicIf(err)
+
+	sources := []ParseSource{
+		BytesSource("good.jpg", exiftest.WrapJpeg(rawExif)),
+		BytesSource("bad.jpg", []byte("not an image")),
+	}
+
+	_, err = ParseMany(context.Background(), sources, ParseManyOptions{Concurrency: 1, OnResult: sink.Emit})
+	log.PanicIf(err)
+
+	err = sink.Close()
+	log.PanicIf(err)
+
+	if len(testSqlDriverInstance.find("CREATE")) != len(catalogSchema) {
+		t.Fatalf("Schema not created.")
+	}
+
+	files := testSqlDriverInstance.find("INSERT OR REPLACE INTO files")
+	if len(files) != 2 {
+		t.Fatalf("File count not correct: (%d)", len(files))
+	}
+
+	good := files[0]
+	bad := files[1]
+
+	if good.args[0] != "good.jpg" || good.args[2] != "Canon" || good.args[4] != "2020:01:02 03:04:05" {
+		t.Fatalf("Good file not correct: %v", good.args)
+	} else if bad.args[0] != "bad.jpg" || bad.args[1] != ErrNoExif.Error() {
+		t.Fatalf("Bad file not correct: %v", bad.args)
+	}
+
+	found := false
+	for _, statement := range testSqlDriverInstance.find("INSERT INTO tags") {
+		if statement.args[3] == "Make" && statement.args[5] == "Canon" {
+			found = true
+		}
+	}
+
+	if found == false {
+		t.Fatalf("Make tag not inserted.")
+	}
+
+	gps := testSqlDriverInstance.find("INSERT INTO gps")
+	if len(gps) != 1 || gps[0].args[0] != "good.jpg" {
+		t.Fatalf("GPS not inserted: %v", gps)
+	}
+}
+
+func TestSqlCatalog_Add_Rollback(t *testing.T) {
+	testSqlDriverInstance.reset()
+
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	db, err := sql.Open("exif-test", "")
+	log.PanicIf(err)
+
+	defer db.Close()
+
+	sc, err := NewSqlCatalog(db)
+	log.PanicIf(err)
+
+	results, err := ParseMany(context.Background(), []ParseSource{BytesSource("good.jpg", exiftest.WrapJpeg(rawExif))}, ParseManyOptions{})
+	log.PanicIf(err)
+
+	testSqlDriverInstance.failOn = "INSERT INTO gps"
+
+	err = sc.Add(results[0])
+	if err == nil {
+		t.Fatalf("Expected error.")
+	} else if len(testSqlDriverInstance.find("INSERT")) != 0 {
+		t.Fatalf("Failed file was committed.")
+	}
+}
+
+func TestNewSink_Sqlite_NoTarget(t *testing.T) {
+	_, err := NewSink("sqlite", "")
+	if log.Is(err, ErrCatalogNoTarget) == false {
+		t.Fatalf("Expected no-target error: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+// This tool serves EXIF parsing, editing, and stripping over HTTP, so that
+// they can be deployed as a sidecar service. It only uses the public API of
+// the package.
+//
+// Example command-line:
+//
+//	exif-server -listen :8080
+//
+// Endpoints (the image is the request body):
+//
+//	POST /parse                                 The EXIF as JSON (a `JsonLinesRecord`).
+//	POST /edit?set=IFD/Artist=Jane&set=...      The JPEG with the tags set.
+//	POST /strip[?kind=exif&kind=xmp...]         The JPEG without the segments (EXIF by default).
+//	GET  /healthz                               "ok".
+//
+// Add "dry_run=true" to /edit and /strip to get what would change (the tags
+// and byte ranges) as JSON instead of the image.
+//
+// Only HTTP is served. gRPC isn't supported since it would require the gRPC
+// module and generated stubs.
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	mainLogger = log.NewLogger("main.main")
+)
+
+var (
+	listenArg            = ":8080"
+	maxBodySizeArg       = int64(0)
+	readHeaderTimeoutArg = time.Duration(0)
+	readTimeoutArg       = time.Duration(0)
+	writeTimeoutArg      = time.Duration(0)
+	printLoggingArg      = false
+)
+
+func main() {
+	defer func() {
+		if state := recover(); state != nil {
+			err := log.Wrap(state.(error))
+			log.PrintErrorf(err, "Program error.")
+			os.Exit(1)
+		}
+	}()
+
+	flag.StringVar(&listenArg, "listen", ":8080", "Address to listen on")
+	flag.Int64Var(&maxBodySizeArg, "max-body-size", defaultMaxBodySize, "Largest image accepted, in bytes")
+	flag.DurationVar(&readHeaderTimeoutArg, "read-header-timeout", defaultReadHeaderTimeout, "How long a client has to send the request headers")
+	flag.DurationVar(&readTimeoutArg, "read-timeout", defaultReadTimeout, "How long a client has to send the whole request")
+	flag.DurationVar(&writeTimeoutArg, "write-timeout", defaultWriteTimeout, "How long a client has to receive the response")
+	flag.BoolVar(&printLoggingArg, "verbose", false, "Print logging")
+
+	flag.Parse()
+
+	if printLoggingArg == true {
+		cla := log.NewConsoleLogAdapter()
+		log.AddAdapter("console", cla)
+
+		scp := log.NewStaticConfigurationProvider()
+		scp.SetLevelName(log.LevelNameDebug)
+
+		log.LoadConfiguration(scp)
+	}
+
+	s := newServer(maxBodySizeArg)
+
+	mainLogger.Infof(nil, "Listening on [%s].", listenArg)
+
+	hs := newHttpServer(listenArg, s.handler(), readHeaderTimeoutArg, readTimeoutArg, writeTimeoutArg)
+
+	err := hs.ListenAndServe()
+	log.PanicIf(err)
+}
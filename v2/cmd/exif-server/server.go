@@ -0,0 +1,254 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+)
+
+const (
+	// defaultMaxBodySize is the largest image accepted by default.
+	defaultMaxBodySize = 64 * 1024 * 1024
+
+	// defaultReadHeaderTimeout, defaultReadTimeout, and defaultWriteTimeout
+	// are how long a client has by default to send the headers, to send the
+	// whole request, and to receive the response.
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = time.Minute
+	defaultWriteTimeout      = 2 * time.Minute
+)
+
+var (
+	errInvalidSet = errors.New("set must be \"<ifd-path>/<tag-name>=<value>\"")
+)
+
+// requestError is an error caused by the request (its parameters or its
+// image). Its message is returned to the client.
+type requestError struct {
+	err error
+}
+
+// Error returns the message of the underlying error.
+func (re requestError) Error() string {
+	return re.err.Error()
+}
+
+// server serves the endpoints.
+type server struct {
+	maxBodySize int64
+}
+
+func newServer(maxBodySize int64) *server {
+	return &server{
+		maxBodySize: maxBodySize,
+	}
+}
+
+// newHttpServer returns the HTTP server for the handler. The timeouts keep
+// slow or stalled clients from holding connections open indefinitely.
+func newHttpServer(address string, handler http.Handler, readHeaderTimeout, readTimeout, writeTimeout time.Duration) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+	}
+}
+
+// handler returns the routes.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/parse", s.post(s.parse))
+	mux.HandleFunc("/edit", s.post(s.edit))
+	mux.HandleFunc("/strip", s.post(s.strip))
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "ok\n")
+	})
+
+	return mux
+}
+
+// post only accepts POSTs and reads the body (up to the maximum size) for the
+// given handler. A `requestError` from the handler is a 400 with its message.
+// Any other error is logged and is a 500 with a generic message, so that
+// internal details aren't returned to the client.
+func (s *server) post(handle func(w http.ResponseWriter, r *http.Request, data []byte) (err error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body := http.MaxBytesReader(w, r.Body, s.maxBodySize)
+
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			http.Error(w, "image too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		err = handle(w, r, data)
+		if re, ok := err.(requestError); ok == true {
+			mainLogger.Warningf(nil, "Request to [%s] not valid: %s", r.URL.Path, re)
+			http.Error(w, re.Error(), http.StatusBadRequest)
+		} else if err != nil {
+			mainLogger.Errorf(nil, err, "Request to [%s] failed.", r.URL.Path)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// parse writes the EXIF as JSON.
+func (s *server) parse(w http.ResponseWriter, r *http.Request, data []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	sources := []exif.ParseSource{
+		exif.BytesSource(r.URL.Query().Get("name"), data),
+	}
+
+	results, err := exif.ParseMany(context.Background(), sources, exif.ParseManyOptions{Concurrency: 1})
+	log.PanicIf(err)
+
+	if results[0].Err != nil {
+		return requestError{results[0].Err}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err = exif.NewJsonLinesEmitter(w).Emit(results[0])
+	log.PanicIf(err)
+
+	return nil
+}
+
+// parseSets returns the tags to set from the "set" parameters.
+func parseSets(sets []string) (tags []exif.TemplateTag, err error) {
+	tags = make([]exif.TemplateTag, 0, len(sets))
+
+	for _, set := range sets {
+		equals := strings.Index(set, "=")
+		if equals == -1 {
+			return nil, errInvalidSet
+		}
+
+		fqTagPath := set[:equals]
+
+		slash := strings.LastIndex(fqTagPath, "/")
+		if slash == -1 {
+			return nil, errInvalidSet
+		}
+
+		tag := exif.TemplateTag{
+			FqIfdPath: fqTagPath[:slash],
+			TagName:   fqTagPath[slash+1:],
+			Value:     set[equals+1:],
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// edit writes the JPEG with the tags in the "set" parameters set. Values can
+// have placeholders (see `exif.TagTemplate`).
+func (s *server) edit(w http.ResponseWriter, r *http.Request, data []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tags, err := parseSets(r.URL.Query()["set"])
+	if err != nil {
+		return requestError{err}
+	}
+
+	tt := exif.TagTemplate{
+		Tags: tags,
+	}
+
+	updated, err := exif.StampJpeg(data, tt, nil)
+	if err != nil {
+		return requestError{err}
+	}
+
+	err = writeImage(w, r, data, updated)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// strip writes the JPEG without the segments of the kinds in the "kind"
+// parameters, or without the EXIF if there aren't any.
+func (s *server) strip(w http.ResponseWriter, r *http.Request, data []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	kinds := r.URL.Query()["kind"]
+	if len(kinds) == 0 {
+		kinds = []string{exif.JpegSegmentExif}
+	}
+
+	updated, err := exif.StripJpegSegments(data, kinds...)
+	if err != nil {
+		return requestError{err}
+	}
+
+	err = writeImage(w, r, data, updated)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// writeImage writes the edited JPEG or, if the "dry_run" parameter is "true",
+// what changed (an `exif.MetadataDiff`) as JSON.
+func writeImage(w http.ResponseWriter, r *http.Request, original, updated []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if r.URL.Query().Get("dry_run") == "true" {
+		md, err := exif.DiffMetadata(original, updated)
+		log.PanicIf(err)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		err = json.NewEncoder(w).Encode(md)
+		log.PanicIf(err)
+
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+
+	_, err = w.Write(updated)
+	log.PanicIf(err)
+
+	return nil
+}
@@ -0,0 +1,200 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestJpeg() []byte {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	return exiftest.WrapJpeg(rawExif)
+}
+
+func doTestRequest(method, url string, body []byte) *httptest.ResponseRecorder {
+	s := newServer(defaultMaxBodySize)
+
+	r := httptest.NewRequest(method, url, bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler().ServeHTTP(w, r)
+
+	return w
+}
+
+func getTestTag(data []byte, tagName string) string {
+	rawExif, err := exif.SearchAndExtractExif(data)
+	log.PanicIf(err)
+
+	tags, err := exif.GetFlatExifData(rawExif)
+	log.PanicIf(err)
+
+	for _, et := range tags {
+		if et.TagName == tagName {
+			return et.Value.(string)
+		}
+	}
+
+	return ""
+}
+
+func TestServer_Parse(t *testing.T) {
+	w := doTestRequest(http.MethodPost, "/parse?name=image.jpg", getTestJpeg())
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status not correct: (%d) %s", w.Code, w.Body)
+	}
+
+	var record exif.JsonLinesRecord
+
+	err := json.Unmarshal(w.Body.Bytes(), &record)
+	log.PanicIf(err)
+
+	found := false
+	for _, et := range record.Tags {
+		if et.TagName == "Make" && et.Value == "Canon" {
+			found = true
+		}
+	}
+
+	if record.Name != "image.jpg" || found == false {
+		t.Fatalf("Record not correct: %v", record)
+	}
+}
+
+func TestServer_Parse_NoExif(t *testing.T) {
+	w := doTestRequest(http.MethodPost, "/parse", []byte("not an image"))
+
+	if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), exif.ErrNoExif.Error()) == false {
+		t.Fatalf("Response not correct: (%d) %s", w.Code, w.Body)
+	}
+}
+
+func TestServer_Edit(t *testing.T) {
+	w := doTestRequest(http.MethodPost, "/edit?set=IFD/Artist=Jane+Doe", getTestJpeg())
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status not correct: (%d) %s", w.Code, w.Body)
+	} else if artist := getTestTag(w.Body.Bytes(), "Artist"); artist != "Jane Doe" {
+		t.Fatalf("Artist not set: [%s]", artist)
+	}
+
+	w = doTestRequest(http.MethodPost, "/edit?set=Artist", getTestJpeg())
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected bad request for an invalid set: (%d)", w.Code)
+	}
+}
+
+func TestServer_Strip(t *testing.T) {
+	w := doTestRequest(http.MethodPost, "/strip", getTestJpeg())
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status not correct: (%d) %s", w.Code, w.Body)
+	}
+
+	_, err := exif.SearchAndExtractExif(w.Body.Bytes())
+	if err != exif.ErrNoExif {
+		t.Fatalf("EXIF not stripped: %v", err)
+	}
+}
+
+func TestServer_Strip_DryRun(t *testing.T) {
+	w := doTestRequest(http.MethodPost, "/strip?dry_run=true", getTestJpeg())
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status not correct: (%d) %s", w.Code, w.Body)
+	}
+
+	var md exif.MetadataDiff
+
+	err := json.Unmarshal(w.Body.Bytes(), &md)
+	log.PanicIf(err)
+
+	removed := 0
+	for _, tc := range md.Tags {
+		if tc.Kind == exif.TagChangeRemoved {
+			removed++
+		}
+	}
+
+	if removed == 0 || removed != len(md.Tags) {
+		t.Fatalf("Expected only removals: %v", md.Tags)
+	} else if len(md.Ranges) == 0 {
+		t.Fatalf("Expected ranges.")
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	if w := doTestRequest(http.MethodGet, "/parse", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected method not allowed: (%d)", w.Code)
+	}
+
+	s := newServer(10)
+
+	r := httptest.NewRequest(http.MethodPost, "/parse", bytes.NewReader(getTestJpeg()))
+	w := httptest.NewRecorder()
+
+	s.handler().ServeHTTP(w, r)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected too large: (%d)", w.Code)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	s := newServer(defaultMaxBodySize)
+
+	handlers := map[int]func(w http.ResponseWriter, r *http.Request, data []byte) (err error){
+		http.StatusBadRequest: func(w http.ResponseWriter, r *http.Request, data []byte) (err error) {
+			return requestError{errors.New("bad value")}
+		},
+		http.StatusInternalServerError: func(w http.ResponseWriter, r *http.Request, data []byte) (err error) {
+			return errors.New("internal detail")
+		},
+	}
+
+	for status, handle := range handlers {
+		r := httptest.NewRequest(http.MethodPost, "/parse", bytes.NewReader(nil))
+		w := httptest.NewRecorder()
+
+		s.post(handle)(w, r)
+
+		if w.Code != status {
+			t.Fatalf("Status not correct: (%d) != (%d)", w.Code, status)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/parse", bytes.NewReader(nil))
+	w := httptest.NewRecorder()
+
+	s.post(handlers[http.StatusInternalServerError])(w, r)
+
+	if strings.Contains(w.Body.String(), "internal detail") == true {
+		t.Fatalf("Internal error returned to the client: %s", w.Body)
+	}
+}
+
+func TestNewHttpServer(t *testing.T) {
+	hs := newHttpServer(":0", http.NewServeMux(), time.Second, 2*time.Second, 3*time.Second)
+
+	if hs.ReadHeaderTimeout != time.Second || hs.ReadTimeout != 2*time.Second || hs.WriteTimeout != 3*time.Second {
+		t.Fatalf("Timeouts not correct: (%s) (%s) (%s)", hs.ReadHeaderTimeout, hs.ReadTimeout, hs.WriteTimeout)
+	}
+}
@@ -0,0 +1,96 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+// This tool writes Go constants and accessor structs for the EXIF tags (see
+// `exif.GenerateTagCode()`), so that code can refer to tags in a way that the
+// compiler checks rather than by IDs and names in literals. It only uses the
+// public API of the package.
+//
+// Example command-line:
+//
+//	exif-tag-gen -package exiftags -output exiftags/tags.go
+//
+// or, from a source file:
+//
+//	//go:generate go run github.com/dsoprea/go-exif/v2/cmd/exif-tag-gen -package exiftags -output tags.go
+//
+// By default, code is generated for every IFD in the standard tag table. Use
+// "-ifd" (e.g. "-ifd IFD,IFD/Exif") to only generate it for some of them.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+)
+
+var (
+	packageNameArg    = ""
+	outputFilepathArg = ""
+	ifdPathsArg       = ""
+)
+
+// generate writes the code to the file, or to STDOUT if no file is given.
+func generate(packageName, ifdPaths, outputFilepath string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	opts := exif.TagCodeOptions{
+		PackageName: packageName,
+	}
+
+	if ifdPaths != "" {
+		opts.IfdPaths = strings.Split(ifdPaths, ",")
+	}
+
+	b := new(bytes.Buffer)
+
+	err = exif.GenerateTagCode(b, exif.NewTagIndex(), opts)
+	log.PanicIf(err)
+
+	if outputFilepath == "" {
+		_, err = os.Stdout.Write(b.Bytes())
+		log.PanicIf(err)
+
+		return nil
+	}
+
+	err = ioutil.WriteFile(outputFilepath, b.Bytes(), 0644)
+	log.PanicIf(err)
+
+	return nil
+}
+
+func main() {
+	defer func() {
+		if state := recover(); state != nil {
+			err := log.Wrap(state.(error))
+			log.PrintErrorf(err, "Program error.")
+			os.Exit(1)
+		}
+	}()
+
+	flag.StringVar(&packageNameArg, "package", "", "Package of the generated code")
+	flag.StringVar(&outputFilepathArg, "output", "", "File to write (STDOUT by default)")
+	flag.StringVar(&ifdPathsArg, "ifd", "", "Comma-separated IFD paths to generate code for (all by default)")
+
+	flag.Parse()
+
+	if packageNameArg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := generate(packageNameArg, ifdPathsArg, outputFilepathArg)
+	log.PanicIf(err)
+}
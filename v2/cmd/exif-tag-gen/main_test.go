@@ -0,0 +1,46 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package main
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"io/ioutil"
+	"os"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestGenerate(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	outputFilepath := path.Join(tempPath, "tags.go")
+
+	err = generate("exiftags", "IFD,IFD/GPSInfo", outputFilepath)
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(outputFilepath)
+	log.PanicIf(err)
+
+	source := string(data)
+
+	if strings.HasPrefix(source, "// Code generated by exif-tag-gen. DO NOT EDIT.\n\npackage exiftags\n") != true {
+		t.Fatalf("Header not correct:\n%s", source[:100])
+	} else if strings.Contains(source, "func ReadGpsTags(") != true {
+		t.Fatalf("GPS tags not generated.")
+	} else if strings.Contains(source, "type ExifTag ") == true {
+		t.Fatalf("Only the given IFDs should be generated.")
+	}
+}
+
+func TestGenerate_Error(t *testing.T) {
+	if err := generate("exiftags", "IFD/Unknown", ""); err == nil {
+		t.Fatalf("Expected error for unknown IFD.")
+	}
+}
@@ -0,0 +1,292 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// WhiteBalanceAuto and WhiteBalanceManual are the modes from the
+	// standard WhiteBalance tag. Maker-notes may provide a more specific
+	// preset name instead (e.g. "Daylight").
+	WhiteBalanceAuto   = "Auto"
+	WhiteBalanceManual = "Manual"
+)
+
+const (
+	canonShotInfoTagId  = 0x0004
+	canonColorDataTagId = 0x4001
+
+	// canonShotInfoWhiteBalanceIndex is the position of the white-balance
+	// preset in the ShotInfo array.
+	canonShotInfoWhiteBalanceIndex = 7
+
+	nikonWhiteBalanceTagId = 0x0005
+	nikonWbRbLevelsTagId   = 0x000c
+
+	sonyWhiteBalanceTagId     = 0x0115
+	sonyColorTemperatureTagId = 0xb021
+)
+
+var (
+	colorBalanceLogger = log.NewLogger("exif.color_balance")
+)
+
+var (
+	// ErrNoColorBalance means that no white-balance information was found.
+	ErrNoColorBalance = errors.New("no color balance")
+)
+
+var (
+	canonWhiteBalanceNames = map[uint16]string{
+		0:  WhiteBalanceAuto,
+		1:  "Daylight",
+		2:  "Cloudy",
+		3:  "Tungsten",
+		4:  "Fluorescent",
+		5:  "Flash",
+		6:  "Custom",
+		8:  "Shade",
+		9:  "Kelvin",
+		17: "Underwater",
+	}
+
+	sonyWhiteBalanceNames = map[int64]string{
+		0:  WhiteBalanceAuto,
+		4:  "Custom",
+		5:  "Daylight",
+		6:  "Cloudy",
+		7:  "Cool White Fluorescent",
+		8:  "Day White Fluorescent",
+		9:  "Daylight Fluorescent",
+		11: "Warm White Fluorescent",
+		14: "Incandescent",
+		15: "Flash",
+		17: "Underwater",
+	}
+)
+
+// canonColorDataLayout gives the positions of the as-shot RGGB levels and
+// color temperature in a Canon ColorData array, which depend on the version
+// of the structure (identified by its length).
+type canonColorDataLayout struct {
+	rggbLevelsIndex       int
+	colorTemperatureIndex int
+}
+
+var (
+	canonColorDataLayout1 = canonColorDataLayout{rggbLevelsIndex: 0x19, colorTemperatureIndex: 0x1d}
+	canonColorDataLayout3 = canonColorDataLayout{rggbLevelsIndex: 0x3f, colorTemperatureIndex: 0x43}
+
+	canonColorDataLayouts = map[int]canonColorDataLayout{
+		582:  canonColorDataLayout1,
+		796:  canonColorDataLayout3,
+		674:  canonColorDataLayout3,
+		692:  canonColorDataLayout3,
+		702:  canonColorDataLayout3,
+		1227: canonColorDataLayout3,
+		1250: canonColorDataLayout3,
+		1251: canonColorDataLayout3,
+		1273: canonColorDataLayout3,
+		1275: canonColorDataLayout3,
+		1312: canonColorDataLayout3,
+		1313: canonColorDataLayout3,
+		1316: canonColorDataLayout3,
+		1337: canonColorDataLayout3,
+		1338: canonColorDataLayout3,
+		1346: canonColorDataLayout3,
+		1353: canonColorDataLayout3,
+		1506: canonColorDataLayout3,
+		1560: canonColorDataLayout3,
+		1592: canonColorDataLayout3,
+		1602: canonColorDataLayout3,
+		1816: canonColorDataLayout3,
+		1820: canonColorDataLayout3,
+		1824: canonColorDataLayout3,
+	}
+)
+
+// ColorBalance is a normalized view of the white balance that was applied
+// (or recorded) at capture time.
+type ColorBalance struct {
+	// Mode is `WhiteBalanceAuto`, `WhiteBalanceManual`, or a vendor preset
+	// name.
+	Mode string
+
+	// LightSource is the value of the standard LightSource tag, or zero.
+	LightSource uint16
+
+	// ColorTemperature is in Kelvin, or zero if it isn't known.
+	ColorTemperature int
+
+	// Multipliers are the red, green, and blue gains, normalized so that
+	// green is one.
+	Multipliers    [3]float64
+	HasMultipliers bool
+
+	// Sources lists where each value came from (e.g. "Exif/WhiteBalance" or
+	// "Canon/ColorData").
+	Sources []string
+}
+
+// String returns a descriptive string.
+func (cb ColorBalance) String() string {
+	return fmt.Sprintf("ColorBalance<MODE=[%s] TEMPERATURE=(%d) MULTIPLIERS=%v SOURCES=%v>", cb.Mode, cb.ColorTemperature, cb.Multipliers, cb.Sources)
+}
+
+func (cb *ColorBalance) setMultipliers(red, green, blue float64, source string) {
+	if red <= 0 || green <= 0 || blue <= 0 {
+		return
+	}
+
+	cb.Multipliers = [3]float64{red / green, 1, blue / green}
+	cb.HasMultipliers = true
+	cb.Sources = append(cb.Sources, source)
+}
+
+// GetColorBalance collects the white balance from the standard tags, the DNG
+// AsShotNeutral tag, and the Canon, Nikon, and Sony maker-notes. Maker-note
+// values take precedence since they're more specific. `ErrNoColorBalance` is
+// returned if nothing was found.
+func GetColorBalance(index IfdIndex) (cb ColorBalance, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	cb.Sources = make([]string, 0)
+
+	rootIfd := index.RootIfd
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd := ifds[0]
+
+		results, err := exifIfd.FindTagWithName("WhiteBalance")
+		if err == nil {
+			value, err := results[0].Value()
+			log.PanicIf(err)
+
+			if values, ok := value.([]uint16); ok == true && len(values) > 0 {
+				if values[0] == 0 {
+					cb.Mode = WhiteBalanceAuto
+				} else {
+					cb.Mode = WhiteBalanceManual
+				}
+
+				cb.Sources = append(cb.Sources, "Exif/WhiteBalance")
+			}
+		} else if log.Is(err, ErrTagNotFound) == false {
+			log.Panic(err)
+		}
+
+		lightSource, err := getIfdTagNumber(exifIfd, "LightSource")
+		log.PanicIf(err)
+
+		if lightSource != 0 {
+			cb.LightSource = uint16(lightSource)
+			cb.Sources = append(cb.Sources, "Exif/LightSource")
+		}
+	}
+
+	results, err := rootIfd.FindTagWithName("AsShotNeutral")
+	if err == nil {
+		value, err := results[0].Value()
+		log.PanicIf(err)
+
+		if neutral, ok := value.([]exifcommon.Rational); ok == true && len(neutral) == 3 {
+			gains := make([]float64, 3)
+			for i, r := range neutral {
+				if r.Numerator != 0 && r.Denominator != 0 {
+					gains[i] = float64(r.Denominator) / float64(r.Numerator)
+				}
+			}
+
+			cb.setMultipliers(gains[0], gains[1], gains[2], "IFD/AsShotNeutral")
+		}
+	} else if log.Is(err, ErrTagNotFound) == false {
+		log.Panic(err)
+	}
+
+	vendor, mni, err := getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	switch vendor {
+	case makerNoteVendorCanon:
+		cb.loadCanon(mni)
+	case makerNoteVendorNikon:
+		cb.loadNikon(mni)
+	case makerNoteVendorSony:
+		cb.loadSony(mni)
+	}
+
+	if len(cb.Sources) == 0 {
+		return cb, ErrNoColorBalance
+	}
+
+	return cb, nil
+}
+
+// loadCanon reads the preset from ShotInfo and the as-shot levels and
+// temperature from ColorData.
+func (cb *ColorBalance) loadCanon(mni *makerNoteIfd) {
+	if shotInfo, found := mni.Uint16s(canonShotInfoTagId); found == true && len(shotInfo) > canonShotInfoWhiteBalanceIndex {
+		if name, found := canonWhiteBalanceNames[shotInfo[canonShotInfoWhiteBalanceIndex]]; found == true {
+			cb.Mode = name
+			cb.Sources = append(cb.Sources, "Canon/ShotInfo")
+		}
+	}
+
+	colorData, found := mni.Uint16s(canonColorDataTagId)
+	if found == false {
+		return
+	}
+
+	layout, found := canonColorDataLayouts[len(colorData)]
+	if found == false {
+		colorBalanceLogger.Warningf(nil, "Canon ColorData with length (%d) not supported.", len(colorData))
+		return
+	}
+
+	rggb := colorData[layout.rggbLevelsIndex : layout.rggbLevelsIndex+4]
+	green := (float64(rggb[1]) + float64(rggb[2])) / 2
+
+	cb.setMultipliers(float64(rggb[0]), green, float64(rggb[3]), "Canon/ColorData")
+
+	if colorTemperature := colorData[layout.colorTemperatureIndex]; colorTemperature != 0 {
+		cb.ColorTemperature = int(colorTemperature)
+		cb.Sources = append(cb.Sources, "Canon/ColorTempAsShot")
+	}
+}
+
+// loadNikon reads the preset and the red and blue levels.
+func (cb *ColorBalance) loadNikon(mni *makerNoteIfd) {
+	if mode, found := mni.String(nikonWhiteBalanceTagId); found == true && mode != "" {
+		cb.Mode = strings.TrimSpace(mode)
+		cb.Sources = append(cb.Sources, "Nikon/WhiteBalance")
+	}
+
+	if levels, found := mni.Floats(nikonWbRbLevelsTagId); found == true && len(levels) >= 2 {
+		cb.setMultipliers(levels[0], 1, levels[1], "Nikon/WB_RBLevels")
+	}
+}
+
+// loadSony reads the preset and the color temperature.
+func (cb *ColorBalance) loadSony(mni *makerNoteIfd) {
+	if whiteBalance, found := mni.Int(sonyWhiteBalanceTagId); found == true {
+		if name, found := sonyWhiteBalanceNames[whiteBalance]; found == true {
+			cb.Mode = name
+			cb.Sources = append(cb.Sources, "Sony/WhiteBalance")
+		}
+	}
+
+	if colorTemperature, found := mni.Int(sonyColorTemperatureTagId); found == true && colorTemperature != 0 {
+		cb.ColorTemperature = int(colorTemperature)
+		cb.Sources = append(cb.Sources, "Sony/ColorTemperature")
+	}
+}
@@ -0,0 +1,119 @@
+package exif
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestColorBalanceIndex(make_ string, makerNote []byte, rootTags ...exiftest.Tag) IfdIndex {
+	whiteBalance := exiftest.Tag{Id: 0xa403, Value: []uint16{1}}
+
+	return getTestMakerNoteIndex(make_, makerNote, rootTags, []exiftest.Tag{whiteBalance})
+}
+
+func TestGetColorBalance_Canon(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	cb, err := GetColorBalance(index)
+	log.PanicIf(err)
+
+	if cb.Mode != WhiteBalanceAuto {
+		t.Fatalf("Mode not correct: [%s]", cb.Mode)
+	} else if cb.ColorTemperature != 4856 {
+		t.Fatalf("Color temperature not correct: (%d)", cb.ColorTemperature)
+	} else if cb.HasMultipliers != true {
+		t.Fatalf("Expected multipliers.")
+	}
+
+	expected := [3]float64{1967.0 / 1024.0, 1, 1739.0 / 1024.0}
+	if cb.Multipliers != expected {
+		t.Fatalf("Multipliers not correct: %v", cb.Multipliers)
+	}
+}
+
+func TestGetColorBalance_Nikon(t *testing.T) {
+	makerNoteIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: nikonWhiteBalanceTagId, Value: "SUNNY       "},
+			{Id: nikonWbRbLevelsTagId, Value: []exifcommon.Rational{{Numerator: 2, Denominator: 1}, {Numerator: 3, Denominator: 2}, {Numerator: 1, Denominator: 1}, {Numerator: 1, Denominator: 1}}},
+		},
+	}
+
+	tiff, err := exiftest.Build(makerNoteIfd, binary.LittleEndian)
+	log.PanicIf(err)
+
+	makerNote := append([]byte("Nikon\x00\x02\x10\x00\x00"), tiff...)
+
+	cb, err := GetColorBalance(getTestColorBalanceIndex("NIKON CORPORATION", makerNote))
+	log.PanicIf(err)
+
+	if cb.Mode != "SUNNY" {
+		t.Fatalf("Mode not correct: [%s]", cb.Mode)
+	} else if cb.Multipliers != [3]float64{2, 1, 1.5} {
+		t.Fatalf("Multipliers not correct: %v", cb.Multipliers)
+	}
+}
+
+func TestGetColorBalance_Sony(t *testing.T) {
+	b := new(bytes.Buffer)
+	b.Write(sonyMakerNoteSignatures[0])
+
+	binary.Write(b, binary.BigEndian, uint16(2))
+
+	binary.Write(b, binary.BigEndian, []uint16{sonyWhiteBalanceTagId, uint16(exifcommon.TypeLong)})
+	binary.Write(b, binary.BigEndian, []uint32{1, 6})
+
+	binary.Write(b, binary.BigEndian, []uint16{sonyColorTemperatureTagId, uint16(exifcommon.TypeLong)})
+	binary.Write(b, binary.BigEndian, []uint32{1, 6500})
+
+	binary.Write(b, binary.BigEndian, uint32(0))
+
+	cb, err := GetColorBalance(getTestColorBalanceIndex("SONY", b.Bytes()))
+	log.PanicIf(err)
+
+	if cb.Mode != "Cloudy" {
+		t.Fatalf("Mode not correct: [%s]", cb.Mode)
+	} else if cb.ColorTemperature != 6500 {
+		t.Fatalf("Color temperature not correct: (%d)", cb.ColorTemperature)
+	} else if cb.HasMultipliers != false {
+		t.Fatalf("Multipliers not expected.")
+	}
+}
+
+func TestGetColorBalance_AsShotNeutral(t *testing.T) {
+	asShotNeutral := exiftest.Tag{
+		Id:    0xc628,
+		Value: []exifcommon.Rational{{Numerator: 1, Denominator: 2}, {Numerator: 1, Denominator: 1}, {Numerator: 2, Denominator: 3}},
+	}
+
+	cb, err := GetColorBalance(getTestColorBalanceIndex("Generic", nil, asShotNeutral))
+	log.PanicIf(err)
+
+	if cb.Mode != WhiteBalanceManual {
+		t.Fatalf("Mode not correct: [%s]", cb.Mode)
+	} else if cb.Multipliers[0] != 2 || math.Abs(cb.Multipliers[2]-1.5) > 1e-9 {
+		t.Fatalf("Multipliers not correct: %v", cb.Multipliers)
+	}
+}
+
+func TestGetColorBalance_None(t *testing.T) {
+	exifData, err := exiftest.Build(&exiftest.Ifd{Tags: []exiftest.Tag{{Id: 0x010f, Value: "Canon"}}}, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	_, err = GetColorBalance(index)
+	if err != ErrNoColorBalance {
+		t.Fatalf("Expected no color balance: %v", err)
+	}
+}
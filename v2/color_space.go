@@ -0,0 +1,244 @@
+package exif
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// ColorSpaceTagId is the ID of the ColorSpace tag in the Exif IFD.
+	ColorSpaceTagId = 0xa001
+
+	// GammaTagId is the ID of the Gamma tag in the Exif IFD.
+	GammaTagId = 0xa500
+)
+
+// The values of the ColorSpace tag. Adobe RGB is officially recorded as
+// uncalibrated (along with an "R03" interoperability index), but some
+// cameras write 2 instead.
+const (
+	ColorSpaceSrgb         uint16 = 1
+	ColorSpaceAdobeRgb     uint16 = 2
+	ColorSpaceUncalibrated uint16 = 0xffff
+)
+
+const (
+	// ColorIntentSrgb is sRGB.
+	ColorIntentSrgb = "srgb"
+
+	// ColorIntentAdobeRgb is Adobe RGB, as described by the DCF optional
+	// color space.
+	ColorIntentAdobeRgb = "adobe-rgb"
+
+	// ColorIntentProfile is anything else (e.g. Display P3), which relies on
+	// an embedded ICC profile.
+	ColorIntentProfile = "profile"
+)
+
+const (
+	interopIndexTagId    = 0x0001
+	interopIndexSrgb     = "R98"
+	interopIndexAdobeRgb = "R03"
+
+	// adobeRgbGamma is the gamma of Adobe RGB (563/256).
+	adobeRgbGamma = 2.19921875
+)
+
+// ColorConsistency describes the color tags of an image and anything about
+// them that doesn't agree.
+type ColorConsistency struct {
+	ColorSpace    uint16
+	HasColorSpace bool
+
+	// Gamma is zero if there's no Gamma tag.
+	Gamma float64
+
+	// InteropIndex is the InteroperabilityIndex ("R98" for sRGB or "R03" for
+	// Adobe RGB).
+	InteropIndex string
+
+	// ProfileDescription is the description of the ICC profile, if one was
+	// given.
+	ProfileDescription string
+	HasProfile         bool
+
+	// Problems describes each inconsistency. It's empty if everything
+	// agrees.
+	Problems []string
+}
+
+// String returns a descriptive string.
+func (cc ColorConsistency) String() string {
+	return fmt.Sprintf("ColorConsistency<COLOR-SPACE=(0x%04x) GAMMA=(%.2f) INTEROP=[%s] PROFILE=[%s] PROBLEMS=(%d)>", cc.ColorSpace, cc.Gamma, cc.InteropIndex, cc.ProfileDescription, len(cc.Problems))
+}
+
+// IsConsistent returns true if no problems were found.
+func (cc ColorConsistency) IsConsistent() bool {
+	return len(cc.Problems) == 0
+}
+
+// Intent returns the color intent that the tags and the profile point to,
+// preferring the profile when they disagree.
+func (cc ColorConsistency) Intent() string {
+	if cc.HasProfile == true {
+		description := strings.ToLower(cc.ProfileDescription)
+
+		if strings.Contains(description, "srgb") == true {
+			return ColorIntentSrgb
+		} else if strings.Contains(description, "adobe rgb") == true {
+			return ColorIntentAdobeRgb
+		}
+
+		return ColorIntentProfile
+	}
+
+	if cc.ColorSpace == ColorSpaceAdobeRgb || cc.InteropIndex == interopIndexAdobeRgb {
+		return ColorIntentAdobeRgb
+	}
+
+	return ColorIntentSrgb
+}
+
+func (cc *ColorConsistency) addProblem(format string, args ...interface{}) {
+	cc.Problems = append(cc.Problems, fmt.Sprintf(format, args...))
+}
+
+// CheckColorConsistency compares the ColorSpace, Gamma, and
+// InteroperabilityIndex tags with each other and with the ICC profile (pass
+// nil if the image doesn't have one; see `GetJpegIccProfile()`).
+func CheckColorConsistency(index IfdIndex, ip *IccProfile) (cc ColorConsistency, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	cc.Problems = make([]string, 0)
+
+	if ip != nil {
+		cc.HasProfile = true
+		cc.ProfileDescription = ip.Description
+	}
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd := ifds[0]
+
+		if _, err := exifIfd.FindTagWithId(ColorSpaceTagId); err == nil {
+			colorSpace, err := getIfdTagNumber(exifIfd, "ColorSpace")
+			log.PanicIf(err)
+
+			cc.ColorSpace = uint16(colorSpace)
+			cc.HasColorSpace = true
+		}
+
+		cc.Gamma, err = getIfdTagNumber(exifIfd, "Gamma")
+		log.PanicIf(err)
+	}
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExifIop]; len(ifds) > 0 {
+		cc.InteropIndex, err = getIfdTagString(ifds[0], "InteroperabilityIndex")
+		log.PanicIf(err)
+	}
+
+	if cc.HasColorSpace == false {
+		cc.addProblem("no ColorSpace tag")
+	}
+
+	switch {
+	case cc.ColorSpace == ColorSpaceSrgb:
+		if cc.HasProfile == true && cc.Intent() != ColorIntentSrgb {
+			cc.addProblem("ColorSpace is sRGB but the ICC profile is [%s]", cc.ProfileDescription)
+		}
+
+		if cc.InteropIndex == interopIndexAdobeRgb {
+			cc.addProblem("ColorSpace is sRGB but InteroperabilityIndex is [%s]", cc.InteropIndex)
+		}
+
+		if cc.Gamma != 0 && math.Abs(cc.Gamma-2.2) > 0.05 {
+			cc.addProblem("ColorSpace is sRGB but Gamma is (%.2f)", cc.Gamma)
+		}
+	case cc.ColorSpace == ColorSpaceAdobeRgb:
+		cc.addProblem("ColorSpace has the non-standard Adobe RGB value (2)")
+	case cc.ColorSpace == ColorSpaceUncalibrated:
+		if cc.HasProfile == false && cc.InteropIndex != interopIndexAdobeRgb {
+			cc.addProblem("ColorSpace is uncalibrated but there's no ICC profile")
+		}
+
+		if cc.InteropIndex == interopIndexSrgb {
+			cc.addProblem("ColorSpace is uncalibrated but InteroperabilityIndex is [%s]", cc.InteropIndex)
+		}
+	case cc.HasColorSpace == true:
+		cc.addProblem("ColorSpace not valid: (0x%04x)", cc.ColorSpace)
+	}
+
+	return cc, nil
+}
+
+// SetColorIntent rewrites ColorSpace, Gamma, and InteroperabilityIndex to
+// describe the given intent (one of the ColorIntent* constants). The Exif IFD
+// is created if necessary, but the interoperability IFD is only updated if
+// it already exists. The ICC profile lives outside of the EXIF, so it's up to
+// the caller to make it agree.
+func SetColorIntent(rootIb *IfdBuilder, intent string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	var colorSpace uint16
+	var interopIndex string
+
+	switch intent {
+	case ColorIntentSrgb:
+		colorSpace = ColorSpaceSrgb
+		interopIndex = interopIndexSrgb
+	case ColorIntentAdobeRgb:
+		colorSpace = ColorSpaceUncalibrated
+		interopIndex = interopIndexAdobeRgb
+	case ColorIntentProfile:
+		colorSpace = ColorSpaceUncalibrated
+	default:
+		log.Panicf("color intent not valid: [%s]", intent)
+	}
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	err = exifIb.SetStandard(ColorSpaceTagId, []uint16{colorSpace})
+	log.PanicIf(err)
+
+	if intent == ColorIntentAdobeRgb {
+		gamma := []exifcommon.Rational{{Numerator: uint32(adobeRgbGamma * 256), Denominator: 256}}
+
+		err = exifIb.SetStandard(GammaTagId, gamma)
+		log.PanicIf(err)
+	} else {
+		_, err = exifIb.DeleteAll(GammaTagId)
+		log.PanicIf(err)
+	}
+
+	iopIb, err := exifIb.ChildWithTagId(exifcommon.IfdIopId)
+	if err != nil {
+		if log.Is(err, ErrChildIbNotFound) == true {
+			return nil
+		}
+
+		log.Panic(err)
+	}
+
+	if interopIndex != "" {
+		err = iopIb.SetStandardWithName("InteroperabilityIndex", interopIndex)
+		log.PanicIf(err)
+	} else {
+		_, err = iopIb.DeleteAll(interopIndexTagId)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getTestColorIndex(intent string) IfdIndex {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	_, index, err := Collect(im, ti, getTestExifData())
+	log.PanicIf(err)
+
+	if intent == "" {
+		return index
+	}
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	err = SetColorIntent(rootIb, intent)
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err = Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestCheckColorConsistency(t *testing.T) {
+	cc, err := CheckColorConsistency(getTestColorIndex(""), nil)
+	log.PanicIf(err)
+
+	if cc.IsConsistent() != true {
+		t.Fatalf("Expected consistent: %v", cc.Problems)
+	} else if cc.ColorSpace != ColorSpaceSrgb || cc.InteropIndex != "R98" {
+		t.Fatalf("Tags not read correctly: %s", cc)
+	} else if cc.Intent() != ColorIntentSrgb {
+		t.Fatalf("Intent not correct: [%s]", cc.Intent())
+	}
+}
+
+func TestCheckColorConsistency_ProfileMismatch(t *testing.T) {
+	ip := &IccProfile{
+		Description: "Display P3",
+	}
+
+	cc, err := CheckColorConsistency(getTestColorIndex(""), ip)
+	log.PanicIf(err)
+
+	if len(cc.Problems) != 1 || cc.Problems[0] != "ColorSpace is sRGB but the ICC profile is [Display P3]" {
+		t.Fatalf("Problems not correct: %v", cc.Problems)
+	} else if cc.Intent() != ColorIntentProfile {
+		t.Fatalf("Intent not correct: [%s]", cc.Intent())
+	}
+}
+
+func TestSetColorIntent_AdobeRgb(t *testing.T) {
+	cc, err := CheckColorConsistency(getTestColorIndex(ColorIntentAdobeRgb), nil)
+	log.PanicIf(err)
+
+	if cc.IsConsistent() != true {
+		t.Fatalf("Expected consistent: %v", cc.Problems)
+	} else if cc.ColorSpace != ColorSpaceUncalibrated || cc.InteropIndex != "R03" {
+		t.Fatalf("Tags not written correctly: %s", cc)
+	} else if cc.Gamma != adobeRgbGamma {
+		t.Fatalf("Gamma not correct: (%f)", cc.Gamma)
+	} else if cc.Intent() != ColorIntentAdobeRgb {
+		t.Fatalf("Intent not correct: [%s]", cc.Intent())
+	}
+}
+
+func TestSetColorIntent_Profile(t *testing.T) {
+	index := getTestColorIndex(ColorIntentProfile)
+
+	cc, err := CheckColorConsistency(index, nil)
+	log.PanicIf(err)
+
+	if len(cc.Problems) != 1 || cc.Problems[0] != "ColorSpace is uncalibrated but there's no ICC profile" {
+		t.Fatalf("Problems not correct: %v", cc.Problems)
+	} else if cc.InteropIndex != "" {
+		t.Fatalf("InteroperabilityIndex not removed: [%s]", cc.InteropIndex)
+	}
+
+	ip := &IccProfile{
+		Description: "Display P3",
+	}
+
+	cc, err = CheckColorConsistency(index, ip)
+	log.PanicIf(err)
+
+	if cc.IsConsistent() != true {
+		t.Fatalf("Expected consistent with a profile: %v", cc.Problems)
+	}
+}
+
+func TestSetColorIntent_Invalid(t *testing.T) {
+	rootIb := NewIfdBuilderFromExistingChain(getTestColorIndex("").RootIfd)
+
+	err := SetColorIntent(rootIb, "cmyk")
+	if err == nil {
+		t.Fatalf("Expected error for invalid intent.")
+	}
+}
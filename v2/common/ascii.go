@@ -0,0 +1,52 @@
+package exifcommon
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrAsciiNotTerminated means that an ASCII value wasn't terminated with
+	// a NUL, which `AsciiPolicyStrict` requires.
+	ErrAsciiNotTerminated = errors.New("ascii not terminated with nul")
+)
+
+// AsciiPolicy determines how the NULs and the padding of ASCII values are
+// handled when they're parsed and encoded.
+type AsciiPolicy int
+
+const (
+	// AsciiPolicyTrim removes all trailing NULs and spaces (writers often pad
+	// strings to a fixed length with either). Values are encoded the same
+	// way, with a single NUL. This is the default.
+	AsciiPolicyTrim AsciiPolicy = iota
+
+	// AsciiPolicyStrict requires that the value be terminated with a NUL and
+	// fails with `ErrAsciiNotTerminated` otherwise. Only the terminating NUL
+	// is removed and values are encoded as they are, with a NUL added.
+	AsciiPolicyStrict
+
+	// AsciiPolicyVerbatim removes the terminating NUL, if there is one, and
+	// nothing else, so embedded NULs, extra NULs, and padding are preserved.
+	// Values are encoded as they are, with a NUL added.
+	AsciiPolicyVerbatim
+)
+
+// String returns the name of the policy.
+func (policy AsciiPolicy) String() string {
+	switch policy {
+	case AsciiPolicyTrim:
+		return "trim"
+	case AsciiPolicyStrict:
+		return "strict"
+	case AsciiPolicyVerbatim:
+		return "verbatim"
+	}
+
+	return ""
+}
+
+// trimAscii removes the trailing NULs and spaces from the given value.
+func trimAscii(value string) string {
+	return strings.TrimRight(value, "\x00 ")
+}
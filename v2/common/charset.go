@@ -0,0 +1,187 @@
+package exifcommon
+
+import (
+	"unicode/utf8"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// Charset is a character set that ASCII values might have actually been
+// written in. Many cameras and editors put UTF-8, Shift-JIS, or GBK into
+// ASCII tags (e.g. Artist and ImageDescription).
+type Charset interface {
+	// Name returns the name of the character set (e.g. "Shift_JIS").
+	Name() string
+
+	// Detect returns true if the bytes are valid in the character set.
+	Detect(raw []byte) bool
+
+	// Decode returns the bytes as a (UTF-8) string.
+	Decode(raw []byte) (value string, err error)
+}
+
+// charset is a `Charset` built from functions.
+type charset struct {
+	name   string
+	detect func(raw []byte) bool
+	decode func(raw []byte) (value string, err error)
+}
+
+// NewCharset returns a `Charset` with the given detection and decoding
+// functions. This is how decoders that aren't built in, like those in
+// golang.org/x/text/encoding, are plugged in:
+//
+//	sjis := exifcommon.NewCharset("Shift_JIS", exifcommon.IsShiftJis, func(raw []byte) (string, error) {
+//	    decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(raw)
+//	    return string(decoded), err
+//	})
+func NewCharset(name string, detect func(raw []byte) bool, decode func(raw []byte) (value string, err error)) Charset {
+	return charset{
+		name:   name,
+		detect: detect,
+		decode: decode,
+	}
+}
+
+func (cs charset) Name() string {
+	return cs.name
+}
+
+func (cs charset) Detect(raw []byte) bool {
+	return cs.detect(raw)
+}
+
+func (cs charset) Decode(raw []byte) (value string, err error) {
+	return cs.decode(raw)
+}
+
+var (
+	// CharsetUtf8 is UTF-8, which doesn't have to be decoded.
+	CharsetUtf8 = NewCharset("UTF-8", utf8.Valid, func(raw []byte) (string, error) {
+		return string(raw), nil
+	})
+
+	// CharsetLatin1 is ISO-8859-1. Every byte is valid, so it should be the
+	// last one tried.
+	CharsetLatin1 = NewCharset("ISO-8859-1", func(raw []byte) bool { return true }, decodeLatin1)
+)
+
+func decodeLatin1(raw []byte) (value string, err error) {
+	runes := make([]rune, len(raw))
+	for i, c := range raw {
+		runes[i] = rune(c)
+	}
+
+	return string(runes), nil
+}
+
+// IsAscii returns true if the bytes are all seven-bit ASCII.
+func IsAscii(raw []byte) bool {
+	for _, c := range raw {
+		if c >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsShiftJis returns true if the bytes are valid Shift-JIS.
+func IsShiftJis(raw []byte) bool {
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+
+		// ASCII (JIS X 0201 Roman) and half-width katakana.
+		if c < 0x80 || (c >= 0xa1 && c <= 0xdf) {
+			continue
+		}
+
+		if (c < 0x81 || c > 0x9f) && (c < 0xe0 || c > 0xfc) {
+			return false
+		}
+
+		i++
+		if i == len(raw) {
+			return false
+		}
+
+		trail := raw[i]
+		if trail < 0x40 || trail > 0xfc || trail == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsGbk returns true if the bytes are valid GBK (which includes GB2312).
+func IsGbk(raw []byte) bool {
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+
+		if c < 0x80 {
+			continue
+		}
+
+		if c < 0x81 || c > 0xfe {
+			return false
+		}
+
+		i++
+		if i == len(raw) {
+			return false
+		}
+
+		trail := raw[i]
+		if trail < 0x40 || trail > 0xfe || trail == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CharsetDecoder decodes ASCII values that aren't actually ASCII with the
+// first of its character sets that they're valid in.
+type CharsetDecoder struct {
+	charsets []Charset
+}
+
+// NewCharsetDecoder returns a decoder that tries the given character sets in
+// order. Since the multibyte character sets overlap, the ones that are the
+// most likely for the images being read should be first. UTF-8 is the least
+// ambiguous and is usually first.
+func NewCharsetDecoder(charsets ...Charset) *CharsetDecoder {
+	return &CharsetDecoder{
+		charsets: charsets,
+	}
+}
+
+// Decode returns the value decoded with the first character set that it's
+// valid in and that character set. Values that are seven-bit ASCII and values
+// that aren't valid in any of the character sets are returned as they are,
+// with a nil character set.
+func (cd *CharsetDecoder) Decode(raw []byte) (value string, cs Charset, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if IsAscii(raw) == true {
+		return string(raw), nil, nil
+	}
+
+	for _, cs := range cd.charsets {
+		if cs.Detect(raw) == false {
+			continue
+		}
+
+		value, err := cs.Decode(raw)
+		log.PanicIf(err)
+
+		return value, cs, nil
+	}
+
+	return string(raw), nil, nil
+}
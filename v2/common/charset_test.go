@@ -0,0 +1,89 @@
+package exifcommon
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// testShiftJis is "日本" in Shift-JIS.
+	testShiftJis = []byte{0x93, 0xfa, 0x96, 0x7b}
+)
+
+func getTestShiftJisCharset() Charset {
+	return NewCharset("Shift_JIS", IsShiftJis, func(raw []byte) (string, error) {
+		if string(raw) == string(testShiftJis) {
+			return "日本", nil
+		}
+
+		return "", ErrWrongType
+	})
+}
+
+func TestIsShiftJis(t *testing.T) {
+	if IsShiftJis(testShiftJis) != true {
+		t.Fatalf("Shift-JIS not detected.")
+	} else if IsShiftJis([]byte("abc\xb1")) != true {
+		t.Fatalf("Half-width katakana not detected.")
+	} else if IsShiftJis([]byte{0x93}) != false {
+		t.Fatalf("Truncated character should not be valid.")
+	} else if IsShiftJis([]byte{0x93, 0x7f}) != false {
+		t.Fatalf("Invalid trail byte should not be valid.")
+	}
+}
+
+func TestIsGbk(t *testing.T) {
+	// "中文" in GBK.
+	if IsGbk([]byte{0xd6, 0xd0, 0xce, 0xc4}) != true {
+		t.Fatalf("GBK not detected.")
+	} else if IsGbk([]byte{0xff, 0x40}) != false {
+		t.Fatalf("Invalid lead byte should not be valid.")
+	} else if IsGbk([]byte{0xd6}) != false {
+		t.Fatalf("Truncated character should not be valid.")
+	}
+}
+
+func TestCharsetDecoder_Decode(t *testing.T) {
+	cd := NewCharsetDecoder(CharsetUtf8, getTestShiftJisCharset(), CharsetLatin1)
+
+	tests := []struct {
+		raw         []byte
+		expected    string
+		charsetName string
+	}{
+		{[]byte("plain"), "plain", ""},
+		{[]byte("caf\xc3\xa9"), "café", "UTF-8"},
+		{testShiftJis, "日本", "Shift_JIS"},
+		{[]byte("caf\xe9"), "café", "ISO-8859-1"},
+	}
+
+	for _, test := range tests {
+		value, cs, err := cd.Decode(test.raw)
+		log.PanicIf(err)
+
+		if value != test.expected {
+			t.Fatalf("Value not correct: [%s] != [%s]", value, test.expected)
+		}
+
+		charsetName := ""
+		if cs != nil {
+			charsetName = cs.Name()
+		}
+
+		if charsetName != test.charsetName {
+			t.Fatalf("Charset for [%s] not correct: [%s]", test.expected, charsetName)
+		}
+	}
+}
+
+func TestCharsetDecoder_Decode_NoMatch(t *testing.T) {
+	cd := NewCharsetDecoder(CharsetUtf8)
+
+	value, cs, err := cd.Decode([]byte("caf\xe9"))
+	log.PanicIf(err)
+
+	if value != "caf\xe9" || cs != nil {
+		t.Fatalf("Value should be returned as it is: [%q] %v", value, cs)
+	}
+}
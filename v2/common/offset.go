@@ -0,0 +1,53 @@
+package exifcommon
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrOffsetOverflow means that an offset or length calculated from the
+	// data doesn't fit in the 32 bits that EXIF addresses with.
+	ErrOffsetOverflow = errors.New("offset overflow")
+)
+
+// CheckedAdd returns the sum of two offsets or lengths. `ErrOffsetOverflow`
+// is returned if it wraps around.
+func CheckedAdd(a, b uint32) (sum uint32, err error) {
+	if a > math.MaxUint32-b {
+		return 0, ErrOffsetOverflow
+	}
+
+	return a + b, nil
+}
+
+// CheckedMultiply returns the byte-length of `unitCount` units of `unitSize`
+// bytes each. `ErrOffsetOverflow` is returned if it doesn't fit in 32 bits.
+func CheckedMultiply(unitCount uint32, unitSize int) (length uint32, err error) {
+	if unitSize < 0 || uint64(unitSize) > math.MaxUint32 {
+		return 0, ErrOffsetOverflow
+	}
+
+	product := uint64(unitCount) * uint64(unitSize)
+	if product > math.MaxUint32 {
+		return 0, ErrOffsetOverflow
+	}
+
+	return uint32(product), nil
+}
+
+// CheckedSlice returns the `length` bytes at `offset`. `ErrOffsetOverflow` is
+// returned if the end wraps around and `ErrNotEnoughData` if it's past the end
+// of the data.
+func CheckedSlice(data []byte, offset, length uint32) (slice []byte, err error) {
+	end, err := CheckedAdd(offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(end) > uint64(len(data)) {
+		return nil, ErrNotEnoughData
+	}
+
+	return data[offset:end], nil
+}
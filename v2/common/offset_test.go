@@ -0,0 +1,64 @@
+package exifcommon
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestCheckedAdd(t *testing.T) {
+	sum, err := CheckedAdd(math.MaxUint32-1, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if sum != math.MaxUint32 {
+		t.Fatalf("Sum not correct: (%d)", sum)
+	}
+
+	_, err = CheckedAdd(math.MaxUint32, 1)
+	if err != ErrOffsetOverflow {
+		t.Fatalf("Expected ErrOffsetOverflow: %v", err)
+	}
+}
+
+func TestCheckedMultiply(t *testing.T) {
+	length, err := CheckedMultiply(3, 8)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if length != 24 {
+		t.Fatalf("Length not correct: (%d)", length)
+	}
+
+	// This wraps around to zero in 32 bits.
+	_, err = CheckedMultiply(0x40000000, 4)
+	if err != ErrOffsetOverflow {
+		t.Fatalf("Expected ErrOffsetOverflow: %v", err)
+	}
+
+	_, err = CheckedMultiply(1, -1)
+	if err != ErrOffsetOverflow {
+		t.Fatalf("Expected ErrOffsetOverflow for negative size: %v", err)
+	}
+}
+
+func TestCheckedSlice(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5}
+
+	slice, err := CheckedSlice(data, 1, 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if bytes.Equal(slice, []byte{2, 3, 4, 5}) != true {
+		t.Fatalf("Slice not correct: %v", slice)
+	}
+
+	_, err = CheckedSlice(data, 2, 4)
+	if err != ErrNotEnoughData {
+		t.Fatalf("Expected ErrNotEnoughData: %v", err)
+	}
+
+	// The end wraps around to a small number, which would otherwise pass a
+	// naive bounds-check.
+	_, err = CheckedSlice(data, 4, math.MaxUint32)
+	if err != ErrOffsetOverflow {
+		t.Fatalf("Expected ErrOffsetOverflow: %v", err)
+	}
+}
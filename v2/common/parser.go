@@ -36,8 +36,8 @@ func (p *Parser) ParseBytes(data []byte, unitCount uint32) (value []uint8, err e
 	return value, nil
 }
 
-// ParseAscii returns a string and auto-strips the trailing NUL character that
-// should be at the end of the encoding.
+// ParseAscii returns a string and auto-strips the trailing NULs and spaces
+// (see `AsciiPolicyTrim`).
 func (p *Parser) ParseAscii(data []byte, unitCount uint32) (value string, err error) {
 	defer func() {
 		if state := recover(); state != nil {
@@ -45,7 +45,20 @@ func (p *Parser) ParseAscii(data []byte, unitCount uint32) (value string, err er
 		}
 	}()
 
-	// TODO(dustin): Add test
+	value, err = p.ParseAsciiWithPolicy(data, unitCount, AsciiPolicyTrim)
+	log.PanicIf(err)
+
+	return value, nil
+}
+
+// ParseAsciiWithPolicy returns a string with its NULs and padding handled
+// according to the given policy.
+func (p *Parser) ParseAsciiWithPolicy(data []byte, unitCount uint32, policy AsciiPolicy) (value string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
 
 	count := int(unitCount)
 
@@ -53,17 +66,28 @@ func (p *Parser) ParseAscii(data []byte, unitCount uint32) (value string, err er
 		log.Panic(ErrNotEnoughData)
 	}
 
-	if len(data) == 0 || data[count-1] != 0 {
+	if count == 0 || data[count-1] != 0 {
 		s := string(data[:count])
-		parserLogger.Warningf(nil, "ascii not terminated with nul as expected: [%v]", s)
+
+		if policy == AsciiPolicyStrict {
+			log.Panic(ErrAsciiNotTerminated)
+		} else if policy == AsciiPolicyTrim {
+			parserLogger.Warningf(nil, "ascii not terminated with nul as expected: [%v]", s)
+			return trimAscii(s), nil
+		}
 
 		return s, nil
-	} else {
-		// Auto-strip the NUL from the end. It serves no purpose outside of
-		// encoding semantics.
+	}
+
+	// Strip the NUL from the end. It serves no purpose outside of encoding
+	// semantics.
+	s := string(data[:count-1])
 
-		return string(data[:count-1]), nil
+	if policy == AsciiPolicyTrim {
+		return trimAscii(s), nil
 	}
+
+	return s, nil
 }
 
 // ParseAsciiNoNul returns a string without any consideration for a trailing NUL
@@ -71,6 +71,47 @@ func TestParser_ParseAscii(t *testing.T) {
 	}
 }
 
+func TestParser_ParseAsciiWithPolicy(t *testing.T) {
+	p := new(Parser)
+
+	tests := []struct {
+		policy   AsciiPolicy
+		encoded  string
+		expected string
+	}{
+		{AsciiPolicyTrim, "abc  \x00\x00", "abc"},
+		{AsciiPolicyTrim, "abc ", "abc"},
+		{AsciiPolicyTrim, "a\x00b\x00", "a\x00b"},
+		{AsciiPolicyStrict, "abc \x00", "abc "},
+		{AsciiPolicyStrict, "a\x00b\x00", "a\x00b"},
+		{AsciiPolicyVerbatim, "abc \x00\x00", "abc \x00"},
+		{AsciiPolicyVerbatim, "abc ", "abc "},
+	}
+
+	for i, test := range tests {
+		value, err := p.ParseAsciiWithPolicy([]byte(test.encoded), uint32(len(test.encoded)), test.policy)
+		log.PanicIf(err)
+
+		if value != test.expected {
+			t.Fatalf("Value (%d) not correct with policy [%s]: [%q]", i, test.policy, value)
+		}
+	}
+}
+
+func TestParser_ParseAsciiWithPolicy_StrictNotTerminated(t *testing.T) {
+	p := new(Parser)
+
+	_, err := p.ParseAsciiWithPolicy([]byte("abc"), 3, AsciiPolicyStrict)
+	if log.Is(err, ErrAsciiNotTerminated) == false {
+		t.Fatalf("Expected ErrAsciiNotTerminated: %v", err)
+	}
+
+	_, err = p.ParseAsciiWithPolicy([]byte{}, 0, AsciiPolicyStrict)
+	if log.Is(err, ErrAsciiNotTerminated) == false {
+		t.Fatalf("Expected ErrAsciiNotTerminated for an empty value: %v", err)
+	}
+}
+
 func TestParser_ParseAsciiNoNul(t *testing.T) {
 	p := new(Parser)
 
@@ -61,6 +61,10 @@ const (
     // TypeSignedRational describes an encoded list of signed rationals.
     TypeSignedRational TagTypePrimitive = 10
 
+    // TypeUtf8 describes an encoded UTF-8 string that is terminated with a
+    // NUL. It was introduced by EXIF 3.0.
+    TypeUtf8 TagTypePrimitive = 129
+
     // TypeAsciiNoNul is just a pseudo-type, for our own purposes.
     TypeAsciiNoNul TagTypePrimitive = 0xf0
 )
@@ -74,7 +78,7 @@ func (typeType TagTypePrimitive) String() string {
 func (tagType TagTypePrimitive) Size() int {
     if tagType == TypeByte {
         return 1
-    } else if tagType == TypeAscii || tagType == TypeAsciiNoNul {
+    } else if tagType == TypeAscii || tagType == TypeAsciiNoNul || tagType == TypeUtf8 {
         return 1
     } else if tagType == TypeShort {
         return 2
@@ -107,7 +111,8 @@ func (tagType TagTypePrimitive) IsValid() bool {
         tagType == TypeRational ||
         tagType == TypeSignedLong ||
         tagType == TypeSignedRational ||
-        tagType == TypeUndefined
+        tagType == TypeUndefined ||
+        tagType == TypeUtf8
 }
 
 var (
@@ -121,6 +126,7 @@ var (
         TypeUndefined:      "UNDEFINED",
         TypeSignedLong:     "SLONG",
         TypeSignedRational: "SRATIONAL",
+        TypeUtf8:           "UTF8",
 
         TypeAsciiNoNul: "_ASCII_NO_NUL",
     }
@@ -128,6 +134,44 @@ var (
     typeNamesR = map[string]TagTypePrimitive{}
 )
 
+const (
+    // FormatFirstMaxLength is the number of characters of a text value that
+    // are kept when only the first value is being formatted.
+    FormatFirstMaxLength = 64
+
+    // formatFirstMaxBytes is the number of bytes of a byte value that are
+    // kept when only the first value is being formatted.
+    formatFirstMaxBytes = 16
+)
+
+// TextValue is implemented by undefined-type values that hold text (e.g.
+// UserComment) so that they can be summarized like ASCII values.
+type TextValue interface {
+    Text() string
+}
+
+// summarizeText returns the first line of the text, truncated to
+// `FormatFirstMaxLength` characters.
+func summarizeText(text string) string {
+    truncated := false
+
+    if i := strings.IndexAny(text, "\r\n"); i != -1 {
+        text = text[:i]
+        truncated = true
+    }
+
+    if runes := []rune(text); len(runes) > FormatFirstMaxLength {
+        text = string(runes[:FormatFirstMaxLength])
+        truncated = true
+    }
+
+    if truncated == true {
+        text += "..."
+    }
+
+    return text
+}
+
 type Rational struct {
     Numerator   uint32
     Denominator uint32
@@ -138,11 +182,80 @@ type SignedRational struct {
     Denominator int32
 }
 
+// FormatOptions controls how numbers are formatted. The zero value formats
+// them the way that `FormatFromType()` does. Numbers are always formatted the
+// same way regardless of locale.
+type FormatOptions struct {
+    // RationalsAsDecimal renders rationals as decimals (e.g. "0.005") rather
+    // than as fractions (e.g. "1/200"). Rationals with a zero denominator are
+    // still rendered as fractions.
+    RationalsAsDecimal bool
+
+    // Precision is the number of decimal places of decimal rationals. Zero
+    // uses as many as are needed.
+    Precision int
+
+    // Units appends the unit of the value (e.g. "mm" or "s"), if the caller
+    // knows it, to every number.
+    Units bool
+
+    // MaxItems truncates lists to this many items followed by "...". Zero
+    // doesn't truncate.
+    MaxItems int
+}
+
+// formatRational formats the given fraction.
+func (fo FormatOptions) formatRational(numerator, denominator int64) string {
+    if fo.RationalsAsDecimal == false || denominator == 0 {
+        return fmt.Sprintf("%d/%d", numerator, denominator)
+    }
+
+    precision := fo.Precision
+    if precision <= 0 {
+        precision = -1
+    }
+
+    return strconv.FormatFloat(float64(numerator)/float64(denominator), 'f', precision, 64)
+}
+
+// formatItems formats the (already-formatted) items of a list.
+func (fo FormatOptions) formatItems(items []string, justFirst bool, unit string) string {
+    if len(items) == 0 {
+        return ""
+    }
+
+    if fo.Units == true && unit != "" {
+        for i := range items {
+            items[i] += " " + unit
+        }
+    }
+
+    if justFirst == true {
+        var valueSuffix string
+        if len(items) > 1 {
+            valueSuffix = "..."
+        }
+
+        return fmt.Sprintf("%v%s", items[0], valueSuffix)
+    }
+
+    if fo.MaxItems > 0 && len(items) > fo.MaxItems {
+        truncated := append(items[:fo.MaxItems:fo.MaxItems], "...")
+        return fmt.Sprintf("%v", truncated)
+    }
+
+    return fmt.Sprintf("%v", items)
+}
+
 // Format returns a stringified value for the given encoding. Automatically
 // parses. Automatically calculates count based on type size. This function
 // also supports undefined-type values (the ones that we support, anyway) by
 // way of the String() method that they all require. We can't be more specific
 // because we're a base package and we can't refer to it.
+//
+// If `justFirst` is true, text is reduced to its first line (and truncated),
+// as are undefined-type values that implement `TextValue`, and bytes are
+// truncated.
 func FormatFromType(value interface{}, justFirst bool) (phrase string, err error) {
     defer func() {
         if state := recover(); state != nil {
@@ -150,106 +263,90 @@ func FormatFromType(value interface{}, justFirst bool) (phrase string, err error
         }
     }()
 
-    // TODO(dustin): !! Add test
+    phrase, err = FormatFromTypeWithOptions(value, justFirst, FormatOptions{}, "")
+    log.PanicIf(err)
 
-    switch t := value.(type) {
-    case []byte:
-        return DumpBytesToString(t), nil
-    case string:
-        return t, nil
-    case []uint16:
-        if len(t) == 0 {
-            return "", nil
+    return phrase, nil
+}
+
+// FormatFromTypeWithOptions is `FormatFromType()` with numbers formatted
+// according to the given options. `unit` is the unit of the value, if known.
+func FormatFromTypeWithOptions(value interface{}, justFirst bool, options FormatOptions, unit string) (phrase string, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
         }
+    }()
 
+    switch t := value.(type) {
+    case []byte:
+        maxBytes := options.MaxItems
         if justFirst == true {
-            var valueSuffix string
-            if len(t) > 1 {
-                valueSuffix = "..."
-            }
-
-            return fmt.Sprintf("%v%s", t[0], valueSuffix), nil
+            maxBytes = formatFirstMaxBytes
         }
 
-        return fmt.Sprintf("%v", t), nil
-    case []uint32:
-        if len(t) == 0 {
-            return "", nil
+        if maxBytes > 0 && len(t) > maxBytes {
+            return DumpBytesToString(t[:maxBytes]) + "...", nil
         }
 
+        return DumpBytesToString(t), nil
+    case string:
         if justFirst == true {
-            var valueSuffix string
-            if len(t) > 1 {
-                valueSuffix = "..."
-            }
-
-            return fmt.Sprintf("%v%s", t[0], valueSuffix), nil
+            return summarizeText(t), nil
         }
 
-        return fmt.Sprintf("%v", t), nil
-    case []Rational:
-        if len(t) == 0 {
-            return "", nil
+        return t, nil
+    case []uint16:
+        items := make([]string, len(t))
+        for i, n := range t {
+            items[i] = strconv.FormatUint(uint64(n), 10)
         }
 
-        parts := make([]string, len(t))
-        for i, r := range t {
-            parts[i] = fmt.Sprintf("%d/%d", r.Numerator, r.Denominator)
-
-            if justFirst == true {
-                break
-            }
+        return options.formatItems(items, justFirst, unit), nil
+    case []uint32:
+        items := make([]string, len(t))
+        for i, n := range t {
+            items[i] = strconv.FormatUint(uint64(n), 10)
         }
 
-        if justFirst == true {
-            var valueSuffix string
-            if len(t) > 1 {
-                valueSuffix = "..."
-            }
-
-            return fmt.Sprintf("%v%s", parts[0], valueSuffix), nil
+        return options.formatItems(items, justFirst, unit), nil
+    case []Rational:
+        items := make([]string, len(t))
+        for i, r := range t {
+            items[i] = options.formatRational(int64(r.Numerator), int64(r.Denominator))
         }
 
-        return fmt.Sprintf("%v", parts), nil
+        return options.formatItems(items, justFirst, unit), nil
     case []int32:
-        if len(t) == 0 {
-            return "", nil
+        items := make([]string, len(t))
+        for i, n := range t {
+            items[i] = strconv.FormatInt(int64(n), 10)
         }
 
-        if justFirst == true {
-            var valueSuffix string
-            if len(t) > 1 {
-                valueSuffix = "..."
-            }
-
-            return fmt.Sprintf("%v%s", t[0], valueSuffix), nil
-        }
-
-        return fmt.Sprintf("%v", t), nil
+        return options.formatItems(items, justFirst, unit), nil
     case []SignedRational:
-        if len(t) == 0 {
-            return "", nil
-        }
-
-        parts := make([]string, len(t))
+        items := make([]string, len(t))
         for i, r := range t {
-            parts[i] = fmt.Sprintf("%d/%d", r.Numerator, r.Denominator)
-
-            if justFirst == true {
-                break
-            }
+            items[i] = options.formatRational(int64(r.Numerator), int64(r.Denominator))
         }
 
+        return options.formatItems(items, justFirst, unit), nil
+    case TextValue:
+        stringer, isStringer := value.(fmt.Stringer)
+
         if justFirst == true {
-            var valueSuffix string
-            if len(t) > 1 {
-                valueSuffix = "..."
+            // Values that have no text (e.g. an empty or undecodable
+            // comment) are described instead.
+            if summary := summarizeText(t.Text()); summary != "" || isStringer == false {
+                return summary, nil
             }
 
-            return fmt.Sprintf("%v%s", parts[0], valueSuffix), nil
+            return stringer.String(), nil
+        } else if isStringer == true {
+            return stringer.String(), nil
         }
 
-        return fmt.Sprintf("%v", parts), nil
+        return t.Text(), nil
     case fmt.Stringer:
         // An undefined value that is documented (or that we otherwise support).
         return t.String(), nil
@@ -293,7 +390,7 @@ func FormatFromBytes(rawBytes []byte, tagType TagTypePrimitive, justFirst bool,
 
         value, err = parser.ParseBytes(rawBytes, unitCount)
         log.PanicIf(err)
-    case TypeAscii:
+    case TypeAscii, TypeUtf8:
         var err error
 
         value, err = parser.ParseAscii(rawBytes, unitCount)
@@ -363,7 +460,7 @@ func TranslateStringToType(tagType TagTypePrimitive, valueString string) (value
         log.PanicIf(err)
 
         return byte(wide), nil
-    } else if tagType == TypeAscii || tagType == TypeAsciiNoNul {
+    } else if tagType == TypeAscii || tagType == TypeAsciiNoNul || tagType == TypeUtf8 {
         // Whether or not we're putting an NUL on the end is only relevant for
         // byte-level encoding. This function really just supports a user
         // interface.
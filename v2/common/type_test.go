@@ -1,6 +1,7 @@
 package exifcommon
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/dsoprea/go-logging"
@@ -342,3 +343,99 @@ func TestTranslateStringToType__InvalidType(t *testing.T) {
 //     log.Panicf("from-string encoding for type not supported; this shouldn't happen: [%s]", tagType.String())
 //     return nil, nil
 // }
+
+type testTextValue string
+
+func (ttv testTextValue) String() string {
+	return "TestTextValue<...>"
+}
+
+func (ttv testTextValue) Text() string {
+	return string(ttv)
+}
+
+func TestFormatFromType_JustFirst_Ascii(t *testing.T) {
+	phrase, err := FormatFromType("first line\nsecond line", true)
+	log.PanicIf(err)
+
+	if phrase != "first line..." {
+		t.Fatalf("Multi-line ASCII not summarized: [%s]", phrase)
+	}
+
+	long := strings.Repeat("a", FormatFirstMaxLength+10)
+
+	phrase, err = FormatFromType(long, true)
+	log.PanicIf(err)
+
+	if phrase != long[:FormatFirstMaxLength]+"..." {
+		t.Fatalf("Long ASCII not truncated: [%s]", phrase)
+	}
+
+	phrase, err = FormatFromType(long, false)
+	log.PanicIf(err)
+
+	if phrase != long {
+		t.Fatalf("ASCII should not be truncated: [%s]", phrase)
+	}
+}
+
+func TestFormatFromType_JustFirst_Bytes(t *testing.T) {
+	value := make([]byte, 20)
+
+	phrase, err := FormatFromType(value, true)
+	log.PanicIf(err)
+
+	if phrase != "00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00..." {
+		t.Fatalf("Bytes not truncated: [%s]", phrase)
+	}
+}
+
+func TestFormatFromType_TextValue(t *testing.T) {
+	value := testTextValue("a comment\r\nwith two lines")
+
+	phrase, err := FormatFromType(value, true)
+	log.PanicIf(err)
+
+	if phrase != "a comment..." {
+		t.Fatalf("Text value not summarized: [%s]", phrase)
+	}
+
+	phrase, err = FormatFromType(value, false)
+	log.PanicIf(err)
+
+	if phrase != "TestTextValue<...>" {
+		t.Fatalf("Text value should be formatted with String(): [%s]", phrase)
+	}
+}
+
+func TestFormatFromTypeWithOptions(t *testing.T) {
+	rationals := []Rational{{Numerator: 1, Denominator: 200}, {Numerator: 28, Denominator: 10}, {Numerator: 1, Denominator: 0}}
+
+	tests := []struct {
+		value     interface{}
+		justFirst bool
+		options   FormatOptions
+		unit      string
+		expected  string
+	}{
+		{rationals, false, FormatOptions{}, "s", "[1/200 28/10 1/0]"},
+		{rationals, false, FormatOptions{RationalsAsDecimal: true}, "", "[0.005 2.8 1/0]"},
+		{rationals, false, FormatOptions{RationalsAsDecimal: true, Precision: 2}, "", "[0.01 2.80 1/0]"},
+		{rationals, true, FormatOptions{RationalsAsDecimal: true, Units: true}, "s", "0.005 s..."},
+		{rationals, false, FormatOptions{MaxItems: 2}, "", "[1/200 28/10 ...]"},
+		{[]SignedRational{{Numerator: -1, Denominator: 3}}, false, FormatOptions{RationalsAsDecimal: true, Precision: 1, Units: true}, "EV", "[-0.3 EV]"},
+		{[]uint16{24, 70}, false, FormatOptions{Units: true}, "mm", "[24 mm 70 mm]"},
+		{[]uint32{1, 2, 3}, false, FormatOptions{MaxItems: 1}, "", "[1 ...]"},
+		{[]byte{1, 2, 3}, false, FormatOptions{MaxItems: 2}, "", "01 02..."},
+		{"text", false, FormatOptions{Units: true}, "mm", "text"},
+	}
+
+	for i, test := range tests {
+		phrase, err := FormatFromTypeWithOptions(test.value, test.justFirst, test.options, test.unit)
+		log.PanicIf(err)
+
+		if phrase != test.expected {
+			t.Fatalf("Phrase (%d) not correct: [%s] != [%s]", i, phrase, test.expected)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package exifcommon
 
 import (
+	"errors"
+	"io"
+
 	"encoding/binary"
 
 	"github.com/dsoprea/go-logging"
@@ -18,6 +21,11 @@ type ValueContext struct {
 	rawValueOffset  []byte
 	addressableData []byte
 
+	// addressableReader and addressableSize are used instead of
+	// addressableData if the data isn't in memory.
+	addressableReader io.ReaderAt
+	addressableSize   int64
+
 	tagType   TagTypePrimitive
 	byteOrder binary.ByteOrder
 
@@ -27,6 +35,9 @@ type ValueContext struct {
 
 	ifdPath string
 	tagId   uint16
+
+	asciiPolicy    AsciiPolicy
+	charsetDecoder *CharsetDecoder
 }
 
 // TODO(dustin): We can update newValueContext() to derive `valueOffset` itself (from `rawValueOffset`).
@@ -47,6 +58,18 @@ func NewValueContext(ifdPath string, tagId uint16, unitCount, valueOffset uint32
 	}
 }
 
+// NewValueContextWithReader returns a new ValueContext that reads the value
+// from the given reader, of the given size, only when it's asked for rather
+// than from data in memory.
+func NewValueContextWithReader(ifdPath string, tagId uint16, unitCount, valueOffset uint32, rawValueOffset []byte, r io.ReaderAt, size int64, tagType TagTypePrimitive, byteOrder binary.ByteOrder) *ValueContext {
+	vc := NewValueContext(ifdPath, tagId, unitCount, valueOffset, rawValueOffset, nil, tagType, byteOrder)
+
+	vc.addressableReader = r
+	vc.addressableSize = size
+
+	return vc
+}
+
 // SetUndefinedValueType sets the effective type if this is an unknown-type tag.
 func (vc *ValueContext) SetUndefinedValueType(tagType TagTypePrimitive) {
 	if vc.tagType != TypeUndefined {
@@ -56,6 +79,18 @@ func (vc *ValueContext) SetUndefinedValueType(tagType TagTypePrimitive) {
 	vc.undefinedValueTagType = tagType
 }
 
+// SetAsciiPolicy determines how ASCII values are read. The default is
+// `AsciiPolicyTrim`.
+func (vc *ValueContext) SetAsciiPolicy(policy AsciiPolicy) {
+	vc.asciiPolicy = policy
+}
+
+// SetCharsetDecoder has ASCII values that aren't actually ASCII decoded with
+// the given decoder. By default, they're returned as they are.
+func (vc *ValueContext) SetCharsetDecoder(cd *CharsetDecoder) {
+	vc.charsetDecoder = cd
+}
+
 // UnitCount returns the embedded unit-count.
 func (vc *ValueContext) UnitCount() uint32 {
 	return vc.unitCount
@@ -73,7 +108,8 @@ func (vc *ValueContext) RawValueOffset() []byte {
 	return vc.rawValueOffset
 }
 
-// AddressableData returns the block of data that we can dereference into.
+// AddressableData returns the block of data that we can dereference into. It's
+// nil if the value context reads from a reader.
 func (vc *ValueContext) AddressableData() []byte {
 	return vc.addressableData
 }
@@ -99,7 +135,13 @@ func (vc *ValueContext) TagId() uint16 {
 func (vc *ValueContext) isEmbedded() bool {
 	tagType := vc.effectiveValueType()
 
-	return (tagType.Size() * int(vc.unitCount)) <= 4
+	byteLength, err := CheckedMultiply(vc.unitCount, tagType.Size())
+	if err != nil {
+		// Too big to be anything but a (bad) reference.
+		return false
+	}
+
+	return byteLength <= 4
 }
 
 // effectiveValueType returns the effective type of the unknown-type tag or, if
@@ -126,16 +168,12 @@ func (vc *ValueContext) readRawEncoded() (rawBytes []byte, err error) {
 		}
 	}()
 
-	tagType := vc.effectiveValueType()
-
-	unitSizeRaw := uint32(tagType.Size())
-
-	if vc.isEmbedded() == true {
-		byteLength := unitSizeRaw * vc.unitCount
-		return vc.rawValueOffset[:byteLength], nil
-	} else {
-		return vc.addressableData[vc.valueOffset : vc.valueOffset+vc.unitCount*unitSizeRaw], nil
+	rawBytes, err = vc.DecodeRaw()
+	if err != nil {
+		log.Panic(Cause(errors.Unwrap(err)))
 	}
+
+	return rawBytes, nil
 }
 
 // ReadRawEncoded returns the encoded bytes for the value that we represent.
@@ -183,7 +221,7 @@ func (vc *ValueContext) FormatFirst() (value string, err error) {
 	rawBytes, err := vc.readRawEncoded()
 	log.PanicIf(err)
 
-	phrase, err := FormatFromBytes(rawBytes, vc.tagType, true, vc.byteOrder)
+	phrase, err := FormatFromBytes(rawBytes, vc.effectiveValueType(), true, vc.byteOrder)
 	log.PanicIf(err)
 
 	return phrase, nil
@@ -207,7 +245,7 @@ func (vc *ValueContext) ReadBytes() (value []byte, err error) {
 }
 
 // ReadAscii parses the encoded NUL-terminated ASCII string from the value-
-// context.
+// context according to its `AsciiPolicy`.
 func (vc *ValueContext) ReadAscii() (value string, err error) {
 	defer func() {
 		if state := recover(); state != nil {
@@ -218,7 +256,30 @@ func (vc *ValueContext) ReadAscii() (value string, err error) {
 	rawValue, err := vc.readRawEncoded()
 	log.PanicIf(err)
 
-	value, err = parser.ParseAscii(rawValue, vc.unitCount)
+	value, err = parser.ParseAsciiWithPolicy(rawValue, vc.unitCount, vc.asciiPolicy)
+	log.PanicIf(err)
+
+	if vc.charsetDecoder != nil {
+		value, _, err = vc.charsetDecoder.Decode([]byte(value))
+		log.PanicIf(err)
+	}
+
+	return value, nil
+}
+
+// ReadUtf8 parses the encoded NUL-terminated UTF-8 string from the value-
+// context according to its `AsciiPolicy`.
+func (vc *ValueContext) ReadUtf8() (value string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rawValue, err := vc.readRawEncoded()
+	log.PanicIf(err)
+
+	value, err = parser.ParseAsciiWithPolicy(rawValue, vc.unitCount, vc.asciiPolicy)
 	log.PanicIf(err)
 
 	return value, nil
@@ -333,8 +394,9 @@ func (vc *ValueContext) ReadSignedRationals() (value []SignedRational, err error
 // (undefined-values aside), so we're named accordingly.
 //
 // Since this method lacks the information to process unknown-type tags (e.g.
-// byte-order, tag-ID, IFD type), it will return an error if attempted. See
-// `Undefined()`.
+// byte-order, tag-ID, IFD type), it will return `ErrUndefinedType` if
+// attempted. See `Undefined()`. This is `Decode()` with the failure wrapped
+// with a stack rather than in a `ValueError`.
 func (vc *ValueContext) Values() (values interface{}, err error) {
 	defer func() {
 		if state := recover(); state != nil {
@@ -342,40 +404,9 @@ func (vc *ValueContext) Values() (values interface{}, err error) {
 		}
 	}()
 
-	if vc.tagType == TypeByte {
-		values, err = vc.ReadBytes()
-		log.PanicIf(err)
-	} else if vc.tagType == TypeAscii {
-		values, err = vc.ReadAscii()
-		log.PanicIf(err)
-	} else if vc.tagType == TypeAsciiNoNul {
-		values, err = vc.ReadAsciiNoNul()
-		log.PanicIf(err)
-	} else if vc.tagType == TypeShort {
-		values, err = vc.ReadShorts()
-		log.PanicIf(err)
-	} else if vc.tagType == TypeLong {
-		values, err = vc.ReadLongs()
-		log.PanicIf(err)
-	} else if vc.tagType == TypeRational {
-		values, err = vc.ReadRationals()
-		log.PanicIf(err)
-	} else if vc.tagType == TypeSignedLong {
-		values, err = vc.ReadSignedLongs()
-		log.PanicIf(err)
-	} else if vc.tagType == TypeSignedRational {
-		values, err = vc.ReadSignedRationals()
-		log.PanicIf(err)
-	} else if vc.tagType == TypeUndefined {
-		log.Panicf("will not parse undefined-type value")
-
-		// Never called.
-		return nil, nil
-	} else {
-		log.Panicf("value of type [%s] is unparseable", vc.tagType)
-
-		// Never called.
-		return nil, nil
+	values, err = vc.Decode()
+	if err != nil {
+		log.Panic(errors.Unwrap(err))
 	}
 
 	return values, nil
@@ -218,6 +218,55 @@ func TestValueContext_readRawEncoded__IsRelative(t *testing.T) {
 	}
 }
 
+func TestValueContext_readRawEncoded__OutOfBounds(t *testing.T) {
+	rawValueOffset := []byte{0, 0, 0, 0}
+	addressableData := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	vc := NewValueContext("aa/bb", 0x1234, 0x40000000, 4, rawValueOffset, addressableData, TypeLong, TestDefaultByteOrder)
+
+	_, err := vc.readRawEncoded()
+	if err == nil {
+		t.Fatalf("Expected error for value past the end of the data.")
+	} else if log.Is(err, ErrNotEnoughData) != true {
+		t.Fatalf("Error not correct: %v", err)
+	}
+}
+
+func TestValueContext_readRawEncoded__Reader(t *testing.T) {
+	rawValueOffset := []byte{0, 0, 0, 4}
+	addressableData := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	r := bytes.NewReader(addressableData)
+
+	vc := NewValueContextWithReader("aa/bb", 0x1234, 5, 4, rawValueOffset, r, int64(len(addressableData)), TypeByte, TestDefaultByteOrder)
+
+	recovered, err := vc.readRawEncoded()
+	log.PanicIf(err)
+
+	if bytes.Equal(recovered, addressableData[4:]) != true {
+		t.Fatalf("Value bytes not read correctly: %v", recovered)
+	} else if vc.AddressableData() != nil {
+		t.Fatalf("Expected no addressable data.")
+	}
+
+	// The size is checked before anything is allocated or read.
+
+	vc = NewValueContextWithReader("aa/bb", 0x1234, 0x40000000, 4, rawValueOffset, r, int64(len(addressableData)), TypeLong, TestDefaultByteOrder)
+
+	_, err = vc.readRawEncoded()
+	if log.Is(err, ErrNotEnoughData) != true {
+		t.Fatalf("Error not correct: %v", err)
+	}
+
+	// The reader is shorter than it claimed.
+
+	vc = NewValueContextWithReader("aa/bb", 0x1234, 8, 4, rawValueOffset, r, 100, TypeByte, TestDefaultByteOrder)
+
+	_, err = vc.readRawEncoded()
+	if log.Is(err, ErrNotEnoughData) != true {
+		t.Fatalf("Error not correct for a short reader: %v", err)
+	}
+}
+
 func TestValueContext_Format__Byte(t *testing.T) {
 	unitCount := uint32(8)
 
@@ -856,3 +905,24 @@ func TestValueContext_Values__SignedRational(t *testing.T) {
 		t.Fatalf("Values not correct (signed rationals): %v", value)
 	}
 }
+
+func TestValueContext_FormatFirst__Undefined(t *testing.T) {
+	unitCount := uint32(8)
+
+	rawValueOffset := []byte{0, 0, 0, 4}
+	valueOffset := uint32(4)
+
+	data := []byte{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h'}
+	addressableData := []byte{0, 0, 0, 0}
+	addressableData = append(addressableData, data...)
+
+	vc := NewValueContext("aa/bb", 0x1234, unitCount, valueOffset, rawValueOffset, addressableData, TypeUndefined, TestDefaultByteOrder)
+	vc.SetUndefinedValueType(TypeByte)
+
+	value, err := vc.FormatFirst()
+	log.PanicIf(err)
+
+	if value != "61 62 63 64 65 66 67 68" {
+		t.Fatalf("FormatFirst not correct for undefined: [%s]", value)
+	}
+}
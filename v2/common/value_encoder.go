@@ -23,7 +23,9 @@ type EncodedData struct {
 }
 
 type ValueEncoder struct {
-    byteOrder binary.ByteOrder
+    byteOrder   binary.ByteOrder
+    asciiPolicy AsciiPolicy
+    utf8        bool
 }
 
 func NewValueEncoder(byteOrder binary.ByteOrder) *ValueEncoder {
@@ -32,6 +34,18 @@ func NewValueEncoder(byteOrder binary.ByteOrder) *ValueEncoder {
     }
 }
 
+// SetAsciiPolicy determines how strings are encoded. The default is
+// `AsciiPolicyTrim`.
+func (ve *ValueEncoder) SetAsciiPolicy(policy AsciiPolicy) {
+    ve.asciiPolicy = policy
+}
+
+// SetUtf8 has strings that aren't seven-bit ASCII encoded with the UTF-8 type
+// of EXIF 3.0 rather than as ASCII.
+func (ve *ValueEncoder) SetUtf8(utf8 bool) {
+    ve.utf8 = utf8
+}
+
 func (ve *ValueEncoder) encodeBytes(value []uint8) (ed EncodedData, err error) {
     ed.Type = TypeByte
     ed.Encoded = []byte(value)
@@ -43,6 +57,14 @@ func (ve *ValueEncoder) encodeBytes(value []uint8) (ed EncodedData, err error) {
 func (ve *ValueEncoder) encodeAscii(value string) (ed EncodedData, err error) {
     ed.Type = TypeAscii
 
+    if ve.asciiPolicy == AsciiPolicyTrim {
+        value = trimAscii(value)
+    }
+
+    if ve.utf8 == true && IsAscii([]byte(value)) == false {
+        ed.Type = TypeUtf8
+    }
+
     ed.Encoded = []byte(value)
     ed.Encoded = append(ed.Encoded, 0)
 
@@ -179,7 +201,7 @@ func (ve *ValueEncoder) encodeSignedRationals(value []SignedRational) (ed Encode
 
 // Encode returns bytes for the given value, infering type from the actual
 // value. This does not support `TypeAsciiNoNull` (all strings are encoded as
-// `TypeAscii`).
+// `TypeAscii`, or as `TypeUtf8` if enabled with `SetUtf8()`).
 func (ve *ValueEncoder) Encode(value interface{}) (ed EncodedData, err error) {
     defer func() {
         if state := recover(); state != nil {
@@ -69,6 +69,64 @@ func TestValueEncoder_encodeAscii__Cycle(t *testing.T) {
     }
 }
 
+func TestValueEncoder_encodeAscii__Policy(t *testing.T) {
+    ve := NewValueEncoder(TestDefaultByteOrder)
+
+    ed, err := ve.encodeAscii("abc \x00 ")
+    log.PanicIf(err)
+
+    if string(ed.Encoded) != "abc\x00" || ed.UnitCount != 4 {
+        t.Fatalf("Trimmed encoding not correct: [%q]", ed.Encoded)
+    }
+
+    for _, policy := range []AsciiPolicy{AsciiPolicyStrict, AsciiPolicyVerbatim} {
+        ve.SetAsciiPolicy(policy)
+
+        ed, err := ve.encodeAscii("a\x00b ")
+        log.PanicIf(err)
+
+        if string(ed.Encoded) != "a\x00b \x00" {
+            t.Fatalf("Encoding with policy [%s] not correct: [%q]", policy, ed.Encoded)
+        }
+
+        // It has to be read back the same way.
+        recovered, err := parser.ParseAsciiWithPolicy(ed.Encoded, ed.UnitCount, policy)
+        log.PanicIf(err)
+
+        if recovered != "a\x00b " {
+            t.Fatalf("Value with policy [%s] not recovered correctly: [%q]", policy, recovered)
+        }
+    }
+}
+
+func TestValueEncoder_encodeAscii__Utf8(t *testing.T) {
+    ve := NewValueEncoder(TestDefaultByteOrder)
+    ve.SetUtf8(true)
+
+    ed, err := ve.encodeAscii("ascii")
+    log.PanicIf(err)
+
+    if ed.Type != TypeAscii {
+        t.Fatalf("ASCII should keep its type: [%s]", ed.Type)
+    }
+
+    ed, err = ve.encodeAscii("café")
+    log.PanicIf(err)
+
+    if ed.Type != TypeUtf8 {
+        t.Fatalf("Type not correct: [%s]", ed.Type)
+    } else if string(ed.Encoded) != "café\x00" || ed.UnitCount != 6 {
+        t.Fatalf("Encoding not correct: [%q]", ed.Encoded)
+    }
+
+    phrase, err := FormatFromBytes(ed.Encoded, ed.Type, false, TestDefaultByteOrder)
+    log.PanicIf(err)
+
+    if phrase != "café" {
+        t.Fatalf("Formatted value not correct: [%s]", phrase)
+    }
+}
+
 func TestValueEncoder_encodeAsciiNoNul__Cycle(t *testing.T) {
     byteOrder := TestDefaultByteOrder
     ve := NewValueEncoder(byteOrder)
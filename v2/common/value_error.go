@@ -0,0 +1,196 @@
+package exifcommon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// ErrTruncatedValue means that a value runs past the end of the data (or
+	// is too long to be addressed at all). It's the same error as
+	// `ErrNotEnoughData`.
+	ErrTruncatedValue = ErrNotEnoughData
+
+	// ErrUnhandledTagType means that the type isn't one that values can be
+	// decoded for.
+	ErrUnhandledTagType = errors.New("tag type not handled")
+
+	// ErrUndefinedType means that an undefined-type value was decoded without
+	// the knowledge of the tag that it needs (see the exifundefined package).
+	ErrUndefinedType = errors.New("undefined-type value can not be decoded without its tag")
+
+	// ErrUndefinedValueTypeNotSet means that the raw bytes of an
+	// undefined-type value were read without `SetUndefinedValueType()`, which
+	// they need in order to be sized.
+	ErrUndefinedValueTypeNotSet = errors.New("undefined-value type not set")
+)
+
+// ValueError is a failure to read or decode a value, along with the tag that
+// the value belongs to.
+type ValueError struct {
+	IfdPath   string
+	TagId     uint16
+	TagType   TagTypePrimitive
+	UnitCount uint32
+
+	// Err is the underlying error (e.g. `ErrTruncatedValue`), without a stack.
+	Err error
+}
+
+// Error returns the tag followed by the underlying error.
+func (ve *ValueError) Error() string {
+	return fmt.Sprintf("value of tag (0x%04x) in IFD [%s] with type [%s] and count (%d): %s", ve.TagId, ve.IfdPath, ve.TagType, ve.UnitCount, ve.Err.Error())
+}
+
+// Unwrap returns the underlying error, for `errors.Is()` and `errors.As()`.
+func (ve *ValueError) Unwrap() error {
+	return ve.Err
+}
+
+// Cause returns the error underneath any stacks that were added to it by
+// go-logging, so that it can be compared with a sentinel error directly or
+// with `errors.Is()`.
+func Cause(err error) error {
+	for err != nil {
+		cause := log.Wrap(err).Err
+		if cause == err {
+			break
+		}
+
+		err = cause
+	}
+
+	return err
+}
+
+// newValueError returns a `ValueError` for the value.
+func (vc *ValueContext) newValueError(err error) *ValueError {
+	err = Cause(err)
+	if err == ErrOffsetOverflow {
+		// A value too long to be addressed is certainly longer than the data.
+		err = ErrTruncatedValue
+	}
+
+	return &ValueError{
+		IfdPath:   vc.ifdPath,
+		TagId:     vc.tagId,
+		TagType:   vc.tagType,
+		UnitCount: vc.unitCount,
+		Err:       err,
+	}
+}
+
+// DecodeRaw returns the encoded bytes of the value, like
+// `ReadRawEncoded()`, but without panicking internally or adding a stack.
+// Failures are returned as a `*ValueError`, so the cause can be checked with
+// `errors.Is()` (e.g. `ErrTruncatedValue`).
+func (vc *ValueContext) DecodeRaw() (rawBytes []byte, err error) {
+	tagType := vc.tagType
+	if tagType == TypeUndefined {
+		tagType = vc.undefinedValueTagType
+
+		if tagType == 0 {
+			return nil, vc.newValueError(ErrUndefinedValueTypeNotSet)
+		}
+	}
+
+	if tagType == TypeUndefined || tagType.IsValid() == false {
+		return nil, vc.newValueError(ErrUnhandledTagType)
+	}
+
+	byteLength, err := CheckedMultiply(vc.unitCount, tagType.Size())
+	if err != nil {
+		return nil, vc.newValueError(err)
+	}
+
+	if byteLength <= 4 {
+		if int(byteLength) > len(vc.rawValueOffset) {
+			return nil, vc.newValueError(ErrTruncatedValue)
+		}
+
+		return vc.rawValueOffset[:byteLength], nil
+	}
+
+	if vc.addressableReader == nil {
+		rawBytes, err = CheckedSlice(vc.addressableData, vc.valueOffset, byteLength)
+		if err != nil {
+			return nil, vc.newValueError(err)
+		}
+
+		return rawBytes, nil
+	}
+
+	// Check the size before allocating anything, since the unit-count might
+	// be garbage.
+	end, err := CheckedAdd(vc.valueOffset, byteLength)
+	if err != nil {
+		return nil, vc.newValueError(err)
+	} else if int64(end) > vc.addressableSize {
+		return nil, vc.newValueError(ErrTruncatedValue)
+	}
+
+	rawBytes = make([]byte, byteLength)
+
+	n, err := vc.addressableReader.ReadAt(rawBytes, int64(vc.valueOffset))
+	if n == len(rawBytes) {
+		return rawBytes, nil
+	} else if err == nil || err == io.EOF {
+		err = ErrTruncatedValue
+	}
+
+	return nil, vc.newValueError(err)
+}
+
+// Decode returns the value, like `Values()`, but without panicking internally
+// or adding a stack. Failures are returned as a `*ValueError`, so the cause
+// can be checked with `errors.Is()`: `ErrTruncatedValue` if the value isn't
+// in the data, `ErrUndefinedType` for undefined-type values, and
+// `ErrUnhandledTagType` for types that can't be decoded.
+func (vc *ValueContext) Decode() (values interface{}, err error) {
+	if vc.tagType == TypeUndefined {
+		return nil, vc.newValueError(ErrUndefinedType)
+	}
+
+	rawValue, err := vc.DecodeRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	switch vc.tagType {
+	case TypeByte:
+		values, err = parser.ParseBytes(rawValue, vc.unitCount)
+	case TypeAscii:
+		var value string
+		value, err = parser.ParseAsciiWithPolicy(rawValue, vc.unitCount, vc.asciiPolicy)
+		if err == nil && vc.charsetDecoder != nil {
+			value, _, err = vc.charsetDecoder.Decode([]byte(value))
+		}
+
+		values = value
+	case TypeAsciiNoNul:
+		values, err = parser.ParseAsciiNoNul(rawValue, vc.unitCount)
+	case TypeUtf8:
+		values, err = parser.ParseAsciiWithPolicy(rawValue, vc.unitCount, vc.asciiPolicy)
+	case TypeShort:
+		values, err = parser.ParseShorts(rawValue, vc.unitCount, vc.byteOrder)
+	case TypeLong:
+		values, err = parser.ParseLongs(rawValue, vc.unitCount, vc.byteOrder)
+	case TypeRational:
+		values, err = parser.ParseRationals(rawValue, vc.unitCount, vc.byteOrder)
+	case TypeSignedLong:
+		values, err = parser.ParseSignedLongs(rawValue, vc.unitCount, vc.byteOrder)
+	case TypeSignedRational:
+		values, err = parser.ParseSignedRationals(rawValue, vc.unitCount, vc.byteOrder)
+	default:
+		err = ErrUnhandledTagType
+	}
+
+	if err != nil {
+		return nil, vc.newValueError(err)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,116 @@
+package exifcommon
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestValueContext_Decode(t *testing.T) {
+	rawValueOffset := []byte{0, 0, 0, 4}
+	addressableData := []byte{0, 0, 0, 0, 0, 1, 0, 2, 0, 3}
+
+	vc := NewValueContext("aa/bb", 0x1234, 3, 4, rawValueOffset, addressableData, TypeShort, TestDefaultByteOrder)
+
+	values, err := vc.Decode()
+	log.PanicIf(err)
+
+	rawBytes, err := vc.DecodeRaw()
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(values, []uint16{1, 2, 3}) != true {
+		t.Fatalf("Values not correct: %v", values)
+	} else if bytes.Equal(rawBytes, addressableData[4:]) != true {
+		t.Fatalf("Raw bytes not correct: %v", rawBytes)
+	}
+}
+
+func TestValueContext_Decode_Errors(t *testing.T) {
+	rawValueOffset := []byte{0, 0, 0, 4}
+	addressableData := []byte{0, 0, 0, 0, 0, 1}
+
+	cases := []struct {
+		name      string
+		unitCount uint32
+		tagType   TagTypePrimitive
+		expected  error
+	}{
+		{name: "truncated", unitCount: 3, tagType: TypeShort, expected: ErrTruncatedValue},
+		{name: "overflow", unitCount: 0x40000000, tagType: TypeRational, expected: ErrTruncatedValue},
+		{name: "undefined", unitCount: 4, tagType: TypeUndefined, expected: ErrUndefinedType},
+		{name: "unknown type", unitCount: 1, tagType: TagTypePrimitive(99), expected: ErrUnhandledTagType},
+	}
+
+	for _, c := range cases {
+		vc := NewValueContext("aa/bb", 0x1234, c.unitCount, 4, rawValueOffset, addressableData, c.tagType, TestDefaultByteOrder)
+
+		_, err := vc.Decode()
+
+		var ve *ValueError
+		if errors.Is(err, c.expected) != true {
+			t.Fatalf("Error not correct for [%s]: %v", c.name, err)
+		} else if errors.As(err, &ve) != true || ve.TagId != 0x1234 || ve.IfdPath != "aa/bb" || ve.UnitCount != c.unitCount {
+			t.Fatalf("Value error not correct for [%s]: %v", c.name, err)
+		}
+
+		// The existing methods fail the same way, with a stack.
+
+		_, err = vc.Values()
+		if log.Is(err, c.expected) != true || Cause(err) != c.expected {
+			t.Fatalf("Values error not correct for [%s]: %v", c.name, err)
+		}
+	}
+}
+
+func TestValueContext_DecodeRaw_Reader(t *testing.T) {
+	rawValueOffset := []byte{0, 0, 0, 4}
+	addressableData := []byte{0, 0, 0, 0, 1, 2, 3, 4, 5}
+
+	r := bytes.NewReader(addressableData)
+
+	vc := NewValueContextWithReader("aa/bb", 0x1234, 5, 4, rawValueOffset, r, int64(len(addressableData)), TypeByte, TestDefaultByteOrder)
+
+	rawBytes, err := vc.DecodeRaw()
+	log.PanicIf(err)
+
+	if bytes.Equal(rawBytes, []byte{1, 2, 3, 4, 5}) != true {
+		t.Fatalf("Raw bytes not correct: %v", rawBytes)
+	}
+
+	// The size claims more than the reader has.
+	vc = NewValueContextWithReader("aa/bb", 0x1234, 8, 4, rawValueOffset, r, 100, TypeByte, TestDefaultByteOrder)
+
+	if _, err := vc.DecodeRaw(); errors.Is(err, ErrTruncatedValue) != true {
+		t.Fatalf("Expected truncated error: %v", err)
+	}
+}
+
+func TestValueContext_DecodeRaw_UndefinedValueTypeNotSet(t *testing.T) {
+	vc := NewValueContext("aa/bb", 0x1234, 4, 0, []byte{1, 2, 3, 4}, nil, TypeUndefined, TestDefaultByteOrder)
+
+	if _, err := vc.DecodeRaw(); errors.Is(err, ErrUndefinedValueTypeNotSet) != true {
+		t.Fatalf("Expected type-not-set error: %v", err)
+	}
+
+	vc.SetUndefinedValueType(TypeByte)
+
+	rawBytes, err := vc.DecodeRaw()
+	log.PanicIf(err)
+
+	if bytes.Equal(rawBytes, []byte{1, 2, 3, 4}) != true {
+		t.Fatalf("Raw bytes not correct: %v", rawBytes)
+	}
+}
+
+func TestCause(t *testing.T) {
+	if Cause(log.Wrap(ErrTruncatedValue)) != ErrTruncatedValue {
+		t.Fatalf("Stack not removed.")
+	} else if Cause(ErrUndefinedType) != ErrUndefinedType {
+		t.Fatalf("Unwrapped error not returned.")
+	} else if Cause(nil) != nil {
+		t.Fatalf("Nil not returned.")
+	}
+}
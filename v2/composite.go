@@ -0,0 +1,339 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// fullFrameDiagonal is the diagonal of a 36x24mm frame, in millimeters.
+	fullFrameDiagonal = 43.266615305567875
+
+	// circleOfConfusionDivisor is the fraction of the diagonal that is taken
+	// as the largest blur that still looks sharp (the same as exiftool).
+	circleOfConfusionDivisor = 1440
+)
+
+var (
+	// ErrCompositeInputMissing means that a tag that a composite value is
+	// calculated from isn't present (or is zero).
+	ErrCompositeInputMissing = errors.New("composite input missing")
+)
+
+var (
+	// focalPlaneUnitMillimeters are the sizes of the FocalPlaneResolutionUnit
+	// units, in millimeters.
+	focalPlaneUnitMillimeters = map[uint16]float64{
+		2: 25.4,
+		3: 10,
+		4: 1,
+		5: 0.001,
+	}
+)
+
+// Composites has the tags that the well-known derived values (the ones
+// exiftool calls "composite" tags) are calculated from. Any of them may be
+// zero if the image didn't have the tag. The methods return
+// `ErrCompositeInputMissing` if one of their inputs is zero.
+type Composites struct {
+	// FocalLength is FocalLength, in millimeters.
+	FocalLength float64
+
+	// FocalLengthIn35mmFilm is FocalLengthIn35mmFilm, in millimeters.
+	FocalLengthIn35mmFilm float64
+
+	// FNumber is FNumber.
+	FNumber float64
+
+	// ExposureTime is ExposureTime, in seconds.
+	ExposureTime float64
+
+	// IsoSpeed is the ISO sensitivity (see `GetIso()`).
+	IsoSpeed float64
+
+	// SubjectDistance is SubjectDistance, in meters.
+	SubjectDistance float64
+
+	// FocalPlaneXResolution and FocalPlaneYResolution are the pixels per
+	// FocalPlaneResolutionUnit on the sensor.
+	FocalPlaneXResolution    float64
+	FocalPlaneYResolution    float64
+	FocalPlaneResolutionUnit uint16
+
+	// Width and Height are PixelXDimension and PixelYDimension or, if those
+	// aren't present, ImageWidth and ImageLength.
+	Width  int
+	Height int
+}
+
+// String returns a descriptive string.
+func (c Composites) String() string {
+	return fmt.Sprintf("Composites<FOCAL-LENGTH=(%.1f) FOCAL-LENGTH-35MM=(%.1f) F-NUMBER=(%.1f) EXPOSURE-TIME=(%f) ISO=(%.0f) SUBJECT-DISTANCE=(%.2f) WIDTH=(%d) HEIGHT=(%d)>", c.FocalLength, c.FocalLengthIn35mmFilm, c.FNumber, c.ExposureTime, c.IsoSpeed, c.SubjectDistance, c.Width, c.Height)
+}
+
+// GetComposites reads the inputs of the composite values from the root and
+// Exif IFDs.
+func GetComposites(index IfdIndex) (c Composites, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd := ifds[0]
+
+		c.FocalLength, err = getIfdTagNumber(exifIfd, "FocalLength")
+		log.PanicIf(err)
+
+		c.FocalLengthIn35mmFilm, err = getIfdTagNumber(exifIfd, "FocalLengthIn35mmFilm")
+		log.PanicIf(err)
+
+		c.FNumber, err = getIfdTagNumber(exifIfd, "FNumber")
+		log.PanicIf(err)
+
+		c.ExposureTime, err = getIfdTagNumber(exifIfd, "ExposureTime")
+		log.PanicIf(err)
+
+		c.SubjectDistance, err = getIfdTagNumber(exifIfd, "SubjectDistance")
+		log.PanicIf(err)
+
+		c.FocalPlaneXResolution, err = getIfdTagNumber(exifIfd, "FocalPlaneXResolution")
+		log.PanicIf(err)
+
+		c.FocalPlaneYResolution, err = getIfdTagNumber(exifIfd, "FocalPlaneYResolution")
+		log.PanicIf(err)
+
+		unit, err := getIfdTagNumber(exifIfd, "FocalPlaneResolutionUnit")
+		log.PanicIf(err)
+
+		c.FocalPlaneResolutionUnit = uint16(unit)
+
+		width, err := getIfdTagNumber(exifIfd, "PixelXDimension")
+		log.PanicIf(err)
+
+		height, err := getIfdTagNumber(exifIfd, "PixelYDimension")
+		log.PanicIf(err)
+
+		c.Width, c.Height = int(width), int(height)
+	}
+
+	iso, err := GetIso(index)
+	if err == nil {
+		c.IsoSpeed = float64(iso.Value)
+	} else if err != ErrNoIso {
+		log.Panic(err)
+	}
+
+	if (c.Width == 0 || c.Height == 0) && index.RootIfd != nil {
+		width, err := getIfdTagNumber(index.RootIfd, "ImageWidth")
+		log.PanicIf(err)
+
+		height, err := getIfdTagNumber(index.RootIfd, "ImageLength")
+		log.PanicIf(err)
+
+		c.Width, c.Height = int(width), int(height)
+	}
+
+	return c, nil
+}
+
+// sensorDiagonal returns the diagonal of the sensor in millimeters, from the
+// image dimensions and the focal-plane resolution.
+func (c Composites) sensorDiagonal() (diagonal float64, err error) {
+	unitMillimeters, found := focalPlaneUnitMillimeters[c.FocalPlaneResolutionUnit]
+	if found == false || c.FocalPlaneXResolution <= 0 || c.FocalPlaneYResolution <= 0 || c.Width <= 0 || c.Height <= 0 {
+		return 0, ErrCompositeInputMissing
+	}
+
+	sensorWidth := float64(c.Width) / c.FocalPlaneXResolution * unitMillimeters
+	sensorHeight := float64(c.Height) / c.FocalPlaneYResolution * unitMillimeters
+
+	return math.Sqrt(sensorWidth*sensorWidth + sensorHeight*sensorHeight), nil
+}
+
+// ScaleFactor35efl returns the crop factor: how much longer the focal length
+// of a lens on a 35mm camera would have to be to give the same field of view.
+// It's FocalLengthIn35mmFilm divided by FocalLength if the camera recorded
+// both and, otherwise, the diagonal of a 35mm frame divided by the diagonal
+// of the sensor, which is calculated from FocalPlaneXResolution,
+// FocalPlaneYResolution, FocalPlaneResolutionUnit, and the image dimensions.
+func (c Composites) ScaleFactor35efl() (scaleFactor float64, err error) {
+	if c.FocalLength > 0 && c.FocalLengthIn35mmFilm > 0 {
+		return c.FocalLengthIn35mmFilm / c.FocalLength, nil
+	}
+
+	diagonal, err := c.sensorDiagonal()
+	if err != nil {
+		return 0, err
+	}
+
+	return fullFrameDiagonal / diagonal, nil
+}
+
+// FocalLength35efl returns the 35mm-equivalent focal length in millimeters.
+// It's FocalLengthIn35mmFilm if the camera recorded it and, otherwise,
+// FocalLength times `ScaleFactor35efl()`.
+func (c Composites) FocalLength35efl() (focalLength float64, err error) {
+	if c.FocalLengthIn35mmFilm > 0 {
+		return c.FocalLengthIn35mmFilm, nil
+	} else if c.FocalLength <= 0 {
+		return 0, ErrCompositeInputMissing
+	}
+
+	scaleFactor, err := c.ScaleFactor35efl()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.FocalLength * scaleFactor, nil
+}
+
+// CircleOfConfusion returns the diameter in millimeters of the largest blur
+// on the sensor that still looks sharp: the sensor diagonal divided by 1440.
+// The sensor diagonal comes from `ScaleFactor35efl()`.
+func (c Composites) CircleOfConfusion() (diameter float64, err error) {
+	scaleFactor, err := c.ScaleFactor35efl()
+	if err != nil {
+		return 0, err
+	}
+
+	return fullFrameDiagonal / scaleFactor / circleOfConfusionDivisor, nil
+}
+
+// HyperfocalDistance returns, in meters, the closest distance that the lens
+// can be focused at while keeping infinity sharp. It's calculated from
+// FocalLength, FNumber, and `CircleOfConfusion()`.
+func (c Composites) HyperfocalDistance() (distance float64, err error) {
+	if c.FocalLength <= 0 || c.FNumber <= 0 {
+		return 0, ErrCompositeInputMissing
+	}
+
+	coc, err := c.CircleOfConfusion()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.FocalLength * c.FocalLength / (c.FNumber * coc) / 1000, nil
+}
+
+// DepthOfField is the range of distances, in meters, that are acceptably
+// sharp.
+type DepthOfField struct {
+	Near float64
+	Far  float64
+
+	// FarIsInfinite is true if everything beyond `Near` is sharp, in which
+	// case `Far` is zero.
+	FarIsInfinite bool
+}
+
+// Depth returns the distance from the near limit to the far limit, or
+// infinity.
+func (dof DepthOfField) Depth() float64 {
+	if dof.FarIsInfinite == true {
+		return math.Inf(1)
+	}
+
+	return dof.Far - dof.Near
+}
+
+// String returns a descriptive string.
+func (dof DepthOfField) String() string {
+	if dof.FarIsInfinite == true {
+		return fmt.Sprintf("%.2f m - inf", dof.Near)
+	}
+
+	return fmt.Sprintf("%.2f m - %.2f m (%.2f m)", dof.Near, dof.Far, dof.Depth())
+}
+
+// DepthOfField returns the near and far limits of sharpness. It's calculated
+// from SubjectDistance, FocalLength, FNumber, and `CircleOfConfusion()`.
+func (c Composites) DepthOfField() (dof DepthOfField, err error) {
+	if c.SubjectDistance <= 0 || c.FocalLength <= 0 || c.FNumber <= 0 {
+		return dof, ErrCompositeInputMissing
+	}
+
+	coc, err := c.CircleOfConfusion()
+	if err != nil {
+		return dof, err
+	}
+
+	// Everything is in millimeters until the end.
+	distance := c.SubjectDistance * 1000
+	focalLengthSquared := c.FocalLength * c.FocalLength
+	spread := coc * c.FNumber * (distance - c.FocalLength)
+
+	dof.Near = distance * focalLengthSquared / (focalLengthSquared + spread) / 1000
+
+	if spread >= focalLengthSquared {
+		dof.FarIsInfinite = true
+	} else {
+		dof.Far = distance * focalLengthSquared / (focalLengthSquared - spread) / 1000
+	}
+
+	return dof, nil
+}
+
+// LightValue returns the brightness of the scene as an exposure value at ISO
+// 100: log2(FNumber^2 / ExposureTime) - log2(ISOSpeedRatings / 100), which is
+// the APEX Bv plus five (see `ApexBvFromExposure()`). It's about 15 in full
+// sun and 5 indoors.
+func (c Composites) LightValue() (lightValue float64, err error) {
+	if c.FNumber <= 0 || c.ExposureTime <= 0 || c.IsoSpeed <= 0 {
+		return 0, ErrCompositeInputMissing
+	}
+
+	return ApexBvFromExposure(c.FNumber, c.ExposureTime, c.IsoSpeed) + ApexSvFromIso(100), nil
+}
+
+// Megapixels returns the number of pixels in millions, from the image
+// dimensions.
+func (c Composites) Megapixels() (megapixels float64, err error) {
+	if c.Width <= 0 || c.Height <= 0 {
+		return 0, ErrCompositeInputMissing
+	}
+
+	return float64(c.Width) * float64(c.Height) / 1e6, nil
+}
+
+// AspectRatio is the ratio of the width to the height, reduced.
+type AspectRatio struct {
+	Width  int
+	Height int
+}
+
+// Value returns the ratio as a number.
+func (ar AspectRatio) Value() float64 {
+	return float64(ar.Width) / float64(ar.Height)
+}
+
+// String returns the ratio as "W:H".
+func (ar AspectRatio) String() string {
+	return fmt.Sprintf("%d:%d", ar.Width, ar.Height)
+}
+
+// AspectRatio returns the aspect ratio of the image, from the image
+// dimensions.
+func (c Composites) AspectRatio() (ar AspectRatio, err error) {
+	if c.Width <= 0 || c.Height <= 0 {
+		return ar, ErrCompositeInputMissing
+	}
+
+	a, b := c.Width, c.Height
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	ar = AspectRatio{
+		Width:  c.Width / a,
+		Height: c.Height / a,
+	}
+
+	return ar, nil
+}
@@ -0,0 +1,125 @@
+package exif
+
+import (
+	"math"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestComposites(exifTags ...exiftest.Tag) Composites {
+	root := exiftest.NewRealisticIfd()
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, exifTags...)
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	c, err := GetComposites(index)
+	log.PanicIf(err)
+
+	return c
+}
+
+func compositeValueEquals(actual, expected float64) bool {
+	return math.Abs(actual-expected) < 0.001*math.Abs(expected)
+}
+
+func TestGetComposites(t *testing.T) {
+	c := getTestComposites(
+		exiftest.Tag{Id: 0xa405, Value: []uint16{75}},
+		exiftest.Tag{Id: 0xa002, Value: []uint32{6000}},
+		exiftest.Tag{Id: 0xa003, Value: []uint32{4000}},
+		exiftest.Tag{Id: 0x9206, Value: []exifcommon.Rational{{Numerator: 5, Denominator: 1}}})
+
+	if c.FocalLength != 50 || c.FocalLengthIn35mmFilm != 75 || c.FNumber != 2.8 || c.IsoSpeed != 400 {
+		t.Fatalf("Inputs not correct: %s", c)
+	} else if c.Width != 6000 || c.Height != 4000 || c.SubjectDistance != 5 {
+		t.Fatalf("Inputs not correct: %s", c)
+	}
+
+	if scaleFactor, err := c.ScaleFactor35efl(); err != nil || scaleFactor != 1.5 {
+		t.Fatalf("ScaleFactor35efl not correct: (%f) %v", scaleFactor, err)
+	} else if focalLength, err := c.FocalLength35efl(); err != nil || focalLength != 75 {
+		t.Fatalf("FocalLength35efl not correct: (%f) %v", focalLength, err)
+	} else if coc, err := c.CircleOfConfusion(); err != nil || compositeValueEquals(coc, 0.020031) == false {
+		t.Fatalf("CircleOfConfusion not correct: (%f) %v", coc, err)
+	} else if hyperfocal, err := c.HyperfocalDistance(); err != nil || compositeValueEquals(hyperfocal, 44.574) == false {
+		t.Fatalf("HyperfocalDistance not correct: (%f) %v", hyperfocal, err)
+	} else if lightValue, err := c.LightValue(); err != nil || compositeValueEquals(lightValue, 8.9366) == false {
+		t.Fatalf("LightValue not correct: (%f) %v", lightValue, err)
+	} else if megapixels, err := c.Megapixels(); err != nil || megapixels != 24 {
+		t.Fatalf("Megapixels not correct: (%f) %v", megapixels, err)
+	} else if ar, err := c.AspectRatio(); err != nil || ar.String() != "3:2" || ar.Value() != 1.5 {
+		t.Fatalf("AspectRatio not correct: [%s] %v", ar, err)
+	}
+
+	dof, err := c.DepthOfField()
+	log.PanicIf(err)
+
+	if compositeValueEquals(dof.Near, 4.5002) == false || compositeValueEquals(dof.Far, 5.6249) == false || dof.FarIsInfinite == true {
+		t.Fatalf("DepthOfField not correct: %s", dof)
+	}
+}
+
+func TestComposites_ScaleFactor35efl_FocalPlane(t *testing.T) {
+	// A 36x24mm sensor.
+	c := getTestComposites(
+		exiftest.Tag{Id: 0xa002, Value: []uint32{6000}},
+		exiftest.Tag{Id: 0xa003, Value: []uint32{4000}},
+		exiftest.Tag{Id: 0xa20e, Value: []exifcommon.Rational{{Numerator: 500, Denominator: 3}}},
+		exiftest.Tag{Id: 0xa20f, Value: []exifcommon.Rational{{Numerator: 500, Denominator: 3}}},
+		exiftest.Tag{Id: 0xa210, Value: []uint16{4}})
+
+	if scaleFactor, err := c.ScaleFactor35efl(); err != nil || compositeValueEquals(scaleFactor, 1) == false {
+		t.Fatalf("ScaleFactor35efl not correct: (%f) %v", scaleFactor, err)
+	} else if focalLength, err := c.FocalLength35efl(); err != nil || compositeValueEquals(focalLength, 50) == false {
+		t.Fatalf("FocalLength35efl not correct: (%f) %v", focalLength, err)
+	}
+}
+
+func TestComposites_DepthOfField_Infinite(t *testing.T) {
+	c := Composites{
+		FocalLength:           50,
+		FocalLengthIn35mmFilm: 50,
+		FNumber:               16,
+		SubjectDistance:       10,
+	}
+
+	dof, err := c.DepthOfField()
+	log.PanicIf(err)
+
+	if dof.FarIsInfinite != true || math.IsInf(dof.Depth(), 1) == false || dof.Near <= 0 || dof.Near >= 10 {
+		t.Fatalf("DepthOfField not correct: %s", dof)
+	}
+}
+
+func TestComposites_InputMissing(t *testing.T) {
+	c := getTestComposites()
+
+	if _, err := c.ScaleFactor35efl(); err != ErrCompositeInputMissing {
+		t.Fatalf("Expected missing input for ScaleFactor35efl: %v", err)
+	} else if _, err := c.HyperfocalDistance(); err != ErrCompositeInputMissing {
+		t.Fatalf("Expected missing input for HyperfocalDistance: %v", err)
+	} else if _, err := c.DepthOfField(); err != ErrCompositeInputMissing {
+		t.Fatalf("Expected missing input for DepthOfField: %v", err)
+	} else if _, err := c.Megapixels(); err != ErrCompositeInputMissing {
+		t.Fatalf("Expected missing input for Megapixels: %v", err)
+	} else if _, err := c.AspectRatio(); err != ErrCompositeInputMissing {
+		t.Fatalf("Expected missing input for AspectRatio: %v", err)
+	}
+
+	// The exposure is in the realistic IFD.
+	if _, err := c.LightValue(); err != nil {
+		t.Fatalf("LightValue should be available: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// ConsumerWebBrowser is the profile of the major web browsers, which only
+	// use the EXIF to orient and size the image.
+	ConsumerWebBrowser = "web-browser"
+
+	// ConsumerApplePhotos is the profile of Apple Photos, which shows the
+	// capture time, the location, and the camera settings.
+	ConsumerApplePhotos = "apple-photos"
+
+	// ConsumerLightroom is the profile of Adobe Lightroom, which reads every
+	// standard tag.
+	ConsumerLightroom = "lightroom"
+
+	// ConsumerStockAgency is the profile of the common requirements of stock
+	// photo agencies, which need the creator and the copyright.
+	ConsumerStockAgency = "stock-agency"
+)
+
+var (
+	// ErrConsumerProfileNotFound means that there's no profile with the given
+	// name.
+	ErrConsumerProfileNotFound = errors.New("consumer profile not found")
+)
+
+// ConsumerProfile describes which tags a consumer of images (e.g. a browser or
+// a photo library) makes use of. Tags are given by fully-qualified tag path,
+// as for `BuilderFromMap()` (e.g. "IFD/Orientation" or
+// "IFD/Exif/DateTimeOriginal").
+type ConsumerProfile struct {
+	Name string
+
+	// Honored are the tags that the consumer reads. The required tags are
+	// honored too, and needn't be repeated.
+	Honored []string
+
+	// HonorsStandard has the consumer read every tag that's in the tag index,
+	// as well as those in `Honored`.
+	HonorsStandard bool
+
+	// Required are the tags that the consumer needs, and that it complains
+	// about or falls back on something else for if they're missing.
+	Required []string
+}
+
+// consumerProfiles are the predefined and registered profiles, by name.
+var consumerProfiles = map[string]ConsumerProfile{
+	ConsumerWebBrowser: {
+		Name: ConsumerWebBrowser,
+		Honored: []string{
+			"IFD/Orientation",
+			"IFD/XResolution",
+			"IFD/YResolution",
+			"IFD/ResolutionUnit",
+			"IFD/Exif/PixelXDimension",
+			"IFD/Exif/PixelYDimension",
+		},
+	},
+	ConsumerApplePhotos: {
+		Name: ConsumerApplePhotos,
+		Honored: []string{
+			"IFD/Orientation",
+			"IFD/Make",
+			"IFD/Model",
+			"IFD/ImageDescription",
+			"IFD/Exif/OffsetTimeOriginal",
+			"IFD/Exif/SubSecTimeOriginal",
+			"IFD/Exif/ExposureTime",
+			"IFD/Exif/FNumber",
+			"IFD/Exif/ISOSpeedRatings",
+			"IFD/Exif/ExposureBiasValue",
+			"IFD/Exif/Flash",
+			"IFD/Exif/FocalLength",
+			"IFD/Exif/FocalLengthIn35mmFilm",
+			"IFD/Exif/LensModel",
+			"IFD/Exif/PixelXDimension",
+			"IFD/Exif/PixelYDimension",
+			"IFD/GPSInfo/GPSLatitudeRef",
+			"IFD/GPSInfo/GPSLatitude",
+			"IFD/GPSInfo/GPSLongitudeRef",
+			"IFD/GPSInfo/GPSLongitude",
+			"IFD/GPSInfo/GPSAltitudeRef",
+			"IFD/GPSInfo/GPSAltitude",
+		},
+		Required: []string{
+			"IFD/Exif/DateTimeOriginal",
+		},
+	},
+	ConsumerLightroom: {
+		Name:           ConsumerLightroom,
+		HonorsStandard: true,
+	},
+	ConsumerStockAgency: {
+		Name: ConsumerStockAgency,
+		Honored: []string{
+			"IFD/ImageDescription",
+			"IFD/Make",
+			"IFD/Model",
+			"IFD/Orientation",
+			"IFD/XPTitle",
+			"IFD/XPKeywords",
+			"IFD/Exif/DateTimeOriginal",
+			"IFD/Exif/LensModel",
+			"IFD/GPSInfo/GPSLatitudeRef",
+			"IFD/GPSInfo/GPSLatitude",
+			"IFD/GPSInfo/GPSLongitudeRef",
+			"IFD/GPSInfo/GPSLongitude",
+		},
+		Required: []string{
+			"IFD/Artist",
+			"IFD/Copyright",
+		},
+	},
+}
+
+// RegisterConsumerProfile adds a profile, or replaces the one with the same
+// name.
+func RegisterConsumerProfile(cp ConsumerProfile) {
+	consumerProfiles[cp.Name] = cp
+}
+
+// GetConsumerProfile returns the profile with the given name.
+func GetConsumerProfile(name string) (cp ConsumerProfile, err error) {
+	cp, found := consumerProfiles[name]
+	if found == false {
+		return ConsumerProfile{}, ErrConsumerProfileNotFound
+	}
+
+	return cp, nil
+}
+
+// ConsumerProfileNames returns the names of the profiles, sorted.
+func ConsumerProfileNames() []string {
+	names := make([]string, 0, len(consumerProfiles))
+	for name := range consumerProfiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// TagAudit describes how a consumer will treat the tags of an image. Tags are
+// given by fully-qualified tag path, with the ID in hex (e.g.
+// "IFD/Exif/0xc8ff") for tags that aren't in the tag index.
+type TagAudit struct {
+	Profile string
+
+	// Honored are the tags that are present and that the consumer reads.
+	Honored []string
+
+	// Ignored are the tags that are present but that the consumer doesn't
+	// read.
+	Ignored []string
+
+	// Missing are the required tags that aren't present.
+	Missing []string
+}
+
+// IsComplete returns true if no required tags are missing.
+func (ta TagAudit) IsComplete() bool {
+	return len(ta.Missing) == 0
+}
+
+// String returns a descriptive string.
+func (ta TagAudit) String() string {
+	return fmt.Sprintf("TagAudit<PROFILE=[%s] HONORED=(%d) IGNORED=(%d) MISSING=%v>", ta.Profile, len(ta.Honored), len(ta.Ignored), ta.Missing)
+}
+
+// AuditTags reports which of the tags in the index the consumer will read or
+// ignore, and which of the tags that it requires are missing. The tags that
+// point to child IFDs are structural and aren't reported.
+func AuditTags(index IfdIndex, cp ConsumerProfile) (ta TagAudit, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	honored := make(map[string]bool)
+	for _, tagPath := range cp.Honored {
+		honored[tagPath] = true
+	}
+
+	for _, tagPath := range cp.Required {
+		honored[tagPath] = true
+	}
+
+	ta = TagAudit{
+		Profile: cp.Name,
+		Honored: make([]string, 0),
+		Ignored: make([]string, 0),
+		Missing: make([]string, 0),
+	}
+
+	present := make(map[string]bool)
+
+	for _, ifd := range index.Ifds {
+		for _, ite := range ifd.Entries {
+			if ite.ChildIfdPath() != "" {
+				continue
+			}
+
+			tagPath := fmt.Sprintf("%s/0x%04x", ifd.FqIfdPath, ite.TagId())
+
+			isStandard := false
+			if it, err := ifd.tagIndex.Get(ifd.IfdPath, ite.TagId()); err == nil {
+				tagPath = fmt.Sprintf("%s/%s", ifd.FqIfdPath, it.Name)
+				isStandard = true
+			} else if log.Is(err, ErrTagNotFound) == false {
+				log.Panic(err)
+			}
+
+			if present[tagPath] == true {
+				continue
+			}
+
+			present[tagPath] = true
+
+			if honored[tagPath] == true || (cp.HonorsStandard == true && isStandard == true) {
+				ta.Honored = append(ta.Honored, tagPath)
+			} else {
+				ta.Ignored = append(ta.Ignored, tagPath)
+			}
+		}
+	}
+
+	for _, tagPath := range cp.Required {
+		if present[tagPath] == false {
+			ta.Missing = append(ta.Missing, tagPath)
+		}
+	}
+
+	sort.Strings(ta.Honored)
+	sort.Strings(ta.Ignored)
+
+	return ta, nil
+}
+
+// AuditExifTags parses the raw EXIF block and calls `AuditTags()` with the
+// named profile.
+func AuditExifTags(rawExif []byte, profileName string) (ta TagAudit, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	cp, err := GetConsumerProfile(profileName)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	ta, err = AuditTags(index, cp)
+	log.PanicIf(err)
+
+	return ta, nil
+}
@@ -0,0 +1,120 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestConsumerProfiles_TagPaths(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	for _, name := range ConsumerProfileNames() {
+		cp, err := GetConsumerProfile(name)
+		log.PanicIf(err)
+
+		for _, tagPath := range append(cp.Honored, cp.Required...) {
+			if _, _, err := resolveTagPath(im, ti, tagPath); err != nil {
+				t.Fatalf("Tag path [%s] of profile [%s] not valid: %v", tagPath, name, err)
+			}
+		}
+	}
+}
+
+func TestAuditExifTags(t *testing.T) {
+	rootIb, err := BuilderFromMap(map[string]interface{}{
+		"IFD/Orientation":           1,
+		"IFD/Make":                  "Canon",
+		"IFD/Exif/DateTimeOriginal": "2020:01:02 03:04:05",
+	})
+	log.PanicIf(err)
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	bt := NewBuilderTag(exifIb.ifdPath, testUnknownTagId, exifcommon.TypeAscii, NewIfdBuilderTagValueFromBytes([]byte("private\x00")), exifIb.byteOrder)
+
+	err = exifIb.Set(bt)
+	log.PanicIf(err)
+
+	rawExif, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	cases := []struct {
+		profile string
+		honored []string
+		ignored []string
+		missing []string
+	}{
+		{
+			profile: ConsumerWebBrowser,
+			honored: []string{"IFD/Orientation"},
+			ignored: []string{"IFD/Exif/0xc8ff", "IFD/Exif/DateTimeOriginal", "IFD/Make"},
+			missing: []string{},
+		},
+		{
+			profile: ConsumerApplePhotos,
+			honored: []string{"IFD/Exif/DateTimeOriginal", "IFD/Make", "IFD/Orientation"},
+			ignored: []string{"IFD/Exif/0xc8ff"},
+			missing: []string{},
+		},
+		{
+			profile: ConsumerLightroom,
+			honored: []string{"IFD/Exif/DateTimeOriginal", "IFD/Make", "IFD/Orientation"},
+			ignored: []string{"IFD/Exif/0xc8ff"},
+			missing: []string{},
+		},
+		{
+			profile: ConsumerStockAgency,
+			honored: []string{"IFD/Exif/DateTimeOriginal", "IFD/Make", "IFD/Orientation"},
+			ignored: []string{"IFD/Exif/0xc8ff"},
+			missing: []string{"IFD/Artist", "IFD/Copyright"},
+		},
+	}
+
+	for _, c := range cases {
+		ta, err := AuditExifTags(rawExif, c.profile)
+		log.PanicIf(err)
+
+		if reflect.DeepEqual(ta.Honored, c.honored) != true {
+			t.Fatalf("Honored tags not correct for [%s]: %v", c.profile, ta.Honored)
+		} else if reflect.DeepEqual(ta.Ignored, c.ignored) != true {
+			t.Fatalf("Ignored tags not correct for [%s]: %v", c.profile, ta.Ignored)
+		} else if reflect.DeepEqual(ta.Missing, c.missing) != true {
+			t.Fatalf("Missing tags not correct for [%s]: %v", c.profile, ta.Missing)
+		} else if ta.IsComplete() != (len(c.missing) == 0) {
+			t.Fatalf("Completeness not correct for [%s].", c.profile)
+		}
+	}
+}
+
+func TestAuditExifTags_ProfileNotFound(t *testing.T) {
+	if _, err := AuditExifTags(nil, "not-a-profile"); log.Is(err, ErrConsumerProfileNotFound) != true {
+		t.Fatalf("Expected profile-not-found error: %v", err)
+	}
+}
+
+func TestRegisterConsumerProfile(t *testing.T) {
+	cp := ConsumerProfile{
+		Name:     "test-consumer",
+		Required: []string{"IFD/Make"},
+	}
+
+	RegisterConsumerProfile(cp)
+
+	defer delete(consumerProfiles, cp.Name)
+
+	found, err := GetConsumerProfile(cp.Name)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(found, cp) != true {
+		t.Fatalf("Profile not registered: %v", found)
+	}
+}
@@ -0,0 +1,409 @@
+package exif
+
+import (
+	"bytes"
+	"fmt"
+
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// ExtractExif identifies the container with the registered formats (see
+// `MediaFormat`) and returns its EXIF block, starting with the TIFF header and
+// ending where the container says it ends. JPEG (APP1), PNG (eXIf), WebP
+// (EXIF), and HEIF (HEIC, AVIF) files are supported, and anything that starts
+// with a TIFF header is returned as it is. `ErrNoExif` is returned if the
+// container doesn't have EXIF or isn't recognized.
+func ExtractExif(data []byte) (rawExif []byte, kind Kind, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	mf, err := SniffMediaFormat(data)
+	if err == ErrMediaFormatNotFound {
+		return nil, KindUnknown, ErrNoExif
+	}
+
+	kind = mf.Kind()
+
+	rawExif, err = mf.ExtractExif(data)
+	if err == ErrNoExif {
+		return nil, kind, err
+	}
+
+	log.PanicIf(err)
+
+	return rawExif, kind, nil
+}
+
+// GetJpegExif returns the EXIF block in the APP1 segment of a JPEG.
+// `ErrNoExif` is returned if there isn't one.
+func GetJpegExif(data []byte) (rawExif []byte, err error) {
+	for _, segment := range jpegSegments(data) {
+		if segment.marker == jpegMarkerApp1 && bytes.HasPrefix(segment.payload, jpegExifPreamble) == true {
+			return segment.payload[len(jpegExifPreamble):], nil
+		}
+	}
+
+	return nil, ErrNoExif
+}
+
+// GetPngExif returns the EXIF block in the eXIf chunk of a PNG. `ErrNoExif`
+// is returned if there isn't one.
+func GetPngExif(data []byte) (rawExif []byte, err error) {
+	if bytes.HasPrefix(data, pngSignature) == false {
+		return nil, ErrNoExif
+	}
+
+	for position := len(pngSignature); position+8 <= len(data); {
+		size := binary.BigEndian.Uint32(data[position:])
+		chunkType := string(data[position+4 : position+8])
+
+		payload, err := exifcommon.CheckedSlice(data, uint32(position+8), size)
+		if err != nil {
+			break
+		}
+
+		switch chunkType {
+		case "eXIf":
+			return payload, nil
+		case "IEND":
+			return nil, ErrNoExif
+		}
+
+		// Length, type, data, and CRC.
+		position += 12 + len(payload)
+	}
+
+	return nil, ErrNoExif
+}
+
+// GetWebpExif returns the EXIF block in the EXIF chunk of a WebP.
+// `ErrNoExif` is returned if there isn't one. Some writers put the JPEG
+// "Exif\x00\x00" preamble in front of the TIFF header, which is removed.
+func GetWebpExif(data []byte) (rawExif []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(data) < 12 || bytes.Equal(data[:4], riffSignature) == false || string(data[8:12]) != "WEBP" {
+		return nil, ErrNoExif
+	}
+
+	// Tolerate a RIFF size that is off, as for WAV files.
+	end := len(data)
+	if size := int(binary.LittleEndian.Uint32(data[4:])); size >= 4 && 8+size < end {
+		end = 8 + size
+	}
+
+	chunks, err := parseRiffChunks(data[12:end])
+	log.PanicIf(err)
+
+	for _, chunk := range chunks {
+		if chunk.id == "EXIF" {
+			return bytes.TrimPrefix(chunk.data, jpegExifPreamble), nil
+		}
+	}
+
+	return nil, ErrNoExif
+}
+
+// GetHeifExif returns the EXIF block in the "Exif" item of a HEIF file that
+// describes the primary image (see `GetHeifItemExif()`). `ErrNoExif` is
+// returned if there isn't one. The item starts with the offset of the TIFF
+// header from the end of the offset itself (usually six, for the JPEG
+// "Exif\x00\x00" preamble, or zero).
+func GetHeifExif(data []byte) (rawExif []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	hm, err := readHeifMeta(data)
+	log.PanicIf(err)
+
+	if hm == nil {
+		return nil, ErrNoExif
+	}
+
+	return getHeifExifItemData(data, hm, hm.exifItemFor(hm.primaryItemId))
+}
+
+// GetHeifItemExif returns the EXIF block that describes the given image item
+// of a HEIF file: the "Exif" item with a "cdsc" reference to it or, failing
+// that, one that doesn't reference any item (which applies to the whole file).
+// This is how the frames of a burst or the images of a collection each have
+// their own EXIF. `ErrNoExif` is returned if there isn't one.
+func GetHeifItemExif(data []byte, itemId uint32) (rawExif []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	hm, err := readHeifMeta(data)
+	log.PanicIf(err)
+
+	if hm == nil {
+		return nil, ErrNoExif
+	}
+
+	return getHeifExifItemData(data, hm, hm.exifItemFor(itemId))
+}
+
+// getHeifExifItemData returns the EXIF block in the given "Exif" item, or
+// `ErrNoExif` if the item ID is zero or the item can't be located.
+func getHeifExifItemData(data []byte, hm *heifMeta, exifItemId uint32) (rawExif []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if exifItemId == 0 {
+		return nil, ErrNoExif
+	}
+
+	hl, found := hm.locations[exifItemId]
+	if found == false || hl.offset < 0 || hl.length < 4 || hl.offset+hl.length > int64(len(data)) {
+		return nil, ErrNoExif
+	}
+
+	item := data[hl.offset : hl.offset+hl.length]
+
+	headerOffset := uint64(binary.BigEndian.Uint32(item)) + 4
+	if headerOffset > uint64(len(item)) {
+		log.Panicf("HEIF EXIF header offset (%d) is past the end of the item (%d)", headerOffset, len(item))
+	}
+
+	return item[headerOffset:], nil
+}
+
+// SetPngExif returns a copy of the PNG with the given raw EXIF block in its
+// eXIf chunk. An existing chunk is replaced. Otherwise, the new one is
+// inserted before the image data, where the specification requires it to be.
+func SetPngExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if bytes.HasPrefix(data, pngSignature) == false {
+		log.Panicf("not a PNG")
+	}
+
+	for position := len(pngSignature); position+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[position:]))
+		chunkType := string(data[position+4 : position+8])
+
+		// Length, type, data, and CRC.
+		end := position + 12 + size
+		if size < 0 || end > len(data) {
+			log.Panicf("PNG chunk [%s] truncated", chunkType)
+		}
+
+		switch chunkType {
+		case "eXIf":
+			updated = append(updated, data[:position]...)
+			updated = appendPngChunk(updated, "eXIf", rawExif)
+			updated = append(updated, data[end:]...)
+
+			return updated, nil
+		case "IDAT", "IEND":
+			updated = append(updated, data[:position]...)
+			updated = appendPngChunk(updated, "eXIf", rawExif)
+			updated = append(updated, data[position:]...)
+
+			return updated, nil
+		}
+
+		position = end
+	}
+
+	ce := &CapabilityError{
+		Kind:      KindPng,
+		Operation: CapabilityReplaceExif,
+		Available: CapabilityReadExif,
+		Reason:    "PNG has no IDAT or IEND chunk to put the EXIF before",
+	}
+
+	log.Panic(ce)
+
+	return nil, nil
+}
+
+// appendPngChunk appends a chunk, with its CRC, to the data.
+func appendPngChunk(data []byte, chunkType string, payload []byte) []byte {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)))
+	copy(header[4:], chunkType)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(payload)
+
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+
+	data = append(data, header...)
+	data = append(data, payload...)
+	data = append(data, footer...)
+
+	return data
+}
+
+// SetWebpExif returns a copy of the WebP with the given raw EXIF block in its
+// EXIF chunk. An existing chunk is replaced. Otherwise, the new one is
+// appended and the EXIF flag of the VP8X chunk is set. A simple WebP, which
+// is just the image data, is converted to the extended format first, with a
+// VP8X chunk that has the size of the image.
+func SetWebpExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(data) < 12 || bytes.Equal(data[:4], riffSignature) == false || string(data[8:12]) != "WEBP" {
+		log.Panicf("not a WebP")
+	}
+
+	// Tolerate a RIFF size that is off, as for WAV files.
+	end := len(data)
+	if size := int(binary.LittleEndian.Uint32(data[4:])); size >= 4 && 8+size < end {
+		end = 8 + size
+	}
+
+	chunks, err := parseRiffChunks(data[12:end])
+	log.PanicIf(err)
+
+	if len(chunks) == 0 {
+		log.Panicf("WebP has no chunks")
+	}
+
+	if chunks[0].id != "VP8X" {
+		vp8x, err := getWebpVp8x(chunks[0])
+		log.PanicIf(err)
+
+		chunks = append([]riffChunk{{id: "VP8X", data: vp8x}}, chunks...)
+	}
+
+	body := new(bytes.Buffer)
+	body.WriteString("WEBP")
+
+	replaced := false
+	for i, chunk := range chunks {
+		payload := chunk.data
+
+		if i == 0 {
+			if len(payload) < 1 {
+				log.Panicf("WebP VP8X chunk truncated")
+			}
+
+			payload = append([]byte{}, payload...)
+			payload[0] |= webpExifFlag
+		} else if chunk.id == "EXIF" {
+			if replaced == true {
+				continue
+			}
+
+			payload = rawExif
+			replaced = true
+		}
+
+		writeRiffChunk(body, chunk.id, payload)
+	}
+
+	if replaced == false {
+		writeRiffChunk(body, "EXIF", rawExif)
+	}
+
+	riff := new(bytes.Buffer)
+	writeRiffChunk(riff, "RIFF", body.Bytes())
+
+	return riff.Bytes(), nil
+}
+
+// getWebpVp8x returns a VP8X chunk for a simple WebP, whose only chunk is the
+// lossy (VP8) or lossless (VP8L) image data, with the size of the image and
+// the alpha flag of a lossless image. A `CapabilityError` is returned if the
+// image data isn't recognized.
+func getWebpVp8x(image riffChunk) (vp8x []byte, err error) {
+	var width, height uint32
+	var hasAlpha bool
+
+	switch {
+	case image.id == "VP8 " && len(image.data) >= 10 && bytes.Equal(image.data[3:6], []byte{0x9d, 0x01, 0x2a}) == true:
+		// The frame tag and start code are followed by the 14-bit width and
+		// height.
+		width = uint32(binary.LittleEndian.Uint16(image.data[6:]) & 0x3fff)
+		height = uint32(binary.LittleEndian.Uint16(image.data[8:]) & 0x3fff)
+	case image.id == "VP8L" && len(image.data) >= 5 && image.data[0] == 0x2f:
+		// The signature is followed by the 14-bit width and height, less
+		// one, and the alpha bit.
+		bits := binary.LittleEndian.Uint32(image.data[1:])
+
+		width = bits&0x3fff + 1
+		height = bits>>14&0x3fff + 1
+		hasAlpha = bits>>28&1 == 1
+	default:
+		return nil, newWebpCapabilityError(fmt.Sprintf("image chunk [%s] of simple WebP not recognized", image.id))
+	}
+
+	if width == 0 || height == 0 {
+		return nil, newWebpCapabilityError("image of simple WebP has no size")
+	}
+
+	// The flags, three reserved bytes, and the 24-bit width and height, less
+	// one.
+	vp8x = make([]byte, 10)
+	if hasAlpha == true {
+		vp8x[0] |= webpAlphaFlag
+	}
+
+	putUint24(vp8x[4:], width-1)
+	putUint24(vp8x[7:], height-1)
+
+	return vp8x, nil
+}
+
+// newWebpCapabilityError returns the error for a WebP that can only be read.
+func newWebpCapabilityError(reason string) *CapabilityError {
+	return &CapabilityError{
+		Kind:      KindWebp,
+		Operation: CapabilityReplaceExif,
+		Available: CapabilityReadExif,
+		Reason:    reason,
+	}
+}
+
+// putUint24 writes a little-endian, 24-bit integer.
+func putUint24(b []byte, value uint32) {
+	b[0] = byte(value)
+	b[1] = byte(value >> 8)
+	b[2] = byte(value >> 16)
+}
+
+// writeRiffChunk writes a chunk, padded to an even size.
+func writeRiffChunk(b *bytes.Buffer, id string, data []byte) {
+	header := make([]byte, 8)
+	copy(header, id)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+
+	b.Write(header)
+	b.Write(data)
+
+	if len(data)%2 == 1 {
+		b.WriteByte(0)
+	}
+}
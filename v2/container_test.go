@@ -0,0 +1,200 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestHeifWithExif returns a HEIC whose only item is the EXIF, with the
+// "Exif\x00\x00" preamble that Apple writes before the TIFF header.
+func getTestHeifWithExif(exifData []byte) []byte {
+	item := append(getTestIsoUints(4, 6), jpegExifPreamble...)
+	item = append(item, exifData...)
+
+	build := func(itemOffset int) []byte {
+		ftyp := getTestIsoBox("ftyp", []byte("heic"), getTestIsoUints(4, 0), []byte("mif1heic"))
+
+		infe := getTestIsoFullBox("infe", 2, 0, getTestIsoUints(2, 1, 0), []byte("Exif\x00"))
+		iinf := getTestIsoFullBox("iinf", 0, 0, getTestIsoUints(2, 1), infe)
+
+		iloc := getTestIsoFullBox(
+			"iloc", 0, 0,
+			[]byte{0x44, 0x00},
+			getTestIsoUints(2, 1),
+			getTestIsoUints(2, 1, 0, 1), getTestIsoUints(4, uint64(itemOffset), uint64(len(item))))
+
+		meta := getTestIsoFullBox("meta", 0, 0, iinf, iloc)
+		mdat := getTestIsoBox("mdat", item)
+
+		return bytes.Join([][]byte{ftyp, meta, mdat}, nil)
+	}
+
+	data := build(0)
+
+	return build(len(data) - len(item))
+}
+
+func TestExtractExif(t *testing.T) {
+	exifData := getTestExifData()
+
+	cases := []struct {
+		name string
+		data []byte
+		kind Kind
+	}{
+		{"jpeg", exiftest.WrapJpeg(exifData), KindJpeg},
+		{"tiff", exifData, KindTiff},
+		{"png", exiftest.WrapPng(exifData), KindPng},
+		{"webp", getTestWebp(webpExifFlag, exifData), KindWebp},
+		{"webp with preamble", getTestWebp(webpExifFlag, append(append([]byte{}, jpegExifPreamble...), exifData...)), KindWebp},
+		{"heif", getTestHeifWithExif(exifData), KindHeif},
+	}
+
+	for _, c := range cases {
+		rawExif, kind, err := ExtractExif(c.data)
+		log.PanicIf(err)
+
+		if kind != c.kind {
+			t.Fatalf("Kind of %s not correct: [%s]", c.name, kind)
+		} else if bytes.Equal(rawExif, exifData) != true {
+			t.Fatalf("EXIF of %s not correct.", c.name)
+		}
+	}
+}
+
+func TestExtractExif_NoExif(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		kind Kind
+	}{
+		{"jpeg", []byte{jpegMarkerPrefix, jpegMarkerSoi, jpegMarkerPrefix, jpegMarkerEoi}, KindJpeg},
+		{"png", exiftest.WrapPng(nil)[:33], KindPng},
+		{"webp", getTestWebp(0, nil), KindWebp},
+		{"heif", getTestHeifWithItemType("hvc1"), KindHeif},
+		{"unknown", []byte("not an image"), KindUnknown},
+	}
+
+	for _, c := range cases {
+		_, kind, err := ExtractExif(c.data)
+		if err != ErrNoExif {
+			t.Fatalf("Expected no EXIF for %s: %v", c.name, err)
+		} else if kind != c.kind {
+			t.Fatalf("Kind of %s not correct: [%s]", c.name, kind)
+		}
+	}
+}
+
+func TestGetHeifExif_HeaderOffsetPastEnd(t *testing.T) {
+	data := getTestHeifWithExif(getTestExifData())
+
+	// The first four bytes of the item are the header offset.
+	i := bytes.Index(data, jpegExifPreamble) - 4
+	data[i] = 0xff
+
+	if _, err := GetHeifExif(data); err == nil {
+		t.Fatalf("Expected error for a header offset past the end of the item.")
+	}
+}
+
+func TestSearchAndExtractExifWithReader_Containers(t *testing.T) {
+	exifData := getTestExifData()
+
+	// The EXIF comes after the image data, which has something that looks
+	// like a TIFF header in it.
+	body := new(bytes.Buffer)
+	body.WriteString("WEBP")
+	writeTestRiffChunk(body, "VP8 ", append([]byte("II*\x00\x08\x00\x00\x00"), make([]byte, 100)...))
+	writeTestRiffChunk(body, "EXIF", exifData)
+
+	webp := new(bytes.Buffer)
+	writeTestRiffChunk(webp, "RIFF", body.Bytes())
+
+	for name, data := range map[string][]byte{"webp": webp.Bytes(), "heif": getTestHeifWithExif(exifData)} {
+		rawExif, err := SearchAndExtractExifWithReader(bytes.NewReader(data))
+		log.PanicIf(err)
+
+		if bytes.Equal(rawExif, exifData) != true {
+			t.Fatalf("EXIF of %s not correct.", name)
+		}
+	}
+
+	if _, err := SearchAndExtractExif(getTestHeifWithItemType("hvc1")); err != ErrNoExif {
+		t.Fatalf("Expected no EXIF: %v", err)
+	}
+}
+
+func TestSetPngExif_NoImageData(t *testing.T) {
+	data := appendPngChunk(append([]byte{}, pngSignature...), "IHDR", make([]byte, 13))
+
+	_, err := SetPngExif(data, getTestExifData())
+	if ce, found := AsCapabilityError(err); found != true {
+		t.Fatalf("Expected CapabilityError: %v", err)
+	} else if ce.Kind != KindPng || ce.Available != CapabilityReadExif {
+		t.Fatalf("CapabilityError not correct: %v", ce)
+	}
+}
+
+func TestSetWebpExif_Simple(t *testing.T) {
+	// A lossy frame of 640x480: the frame tag, the start code, and the size.
+	vp8 := []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a, 0x80, 0x02, 0xe0, 0x01, 0, 0}
+
+	// A lossless image of 3x2 with alpha.
+	vp8l := []byte{0x2f, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(vp8l[1:], 2|1<<14|1<<28)
+
+	cases := []struct {
+		id     string
+		image  []byte
+		flags  byte
+		width  uint32
+		height uint32
+	}{
+		{"VP8 ", vp8, webpExifFlag, 640, 480},
+		{"VP8L", vp8l, webpExifFlag | webpAlphaFlag, 3, 2},
+	}
+
+	exifData := getTestExifData()
+
+	for _, c := range cases {
+		body := new(bytes.Buffer)
+		body.WriteString("WEBP")
+		writeRiffChunk(body, c.id, c.image)
+
+		webp := new(bytes.Buffer)
+		writeRiffChunk(webp, "RIFF", body.Bytes())
+
+		updated, err := SetWebpExif(webp.Bytes(), exifData)
+		log.PanicIf(err)
+
+		chunks, err := parseRiffChunks(updated[12:])
+		log.PanicIf(err)
+
+		if len(chunks) != 3 || chunks[0].id != "VP8X" || chunks[1].id != c.id || chunks[2].id != "EXIF" {
+			t.Fatalf("Chunks of [%s] not correct: %v", c.id, chunks)
+		}
+
+		vp8x := chunks[0].data
+		width := uint32(vp8x[4]) | uint32(vp8x[5])<<8 | uint32(vp8x[6])<<16 + 1
+		height := uint32(vp8x[7]) | uint32(vp8x[8])<<8 | uint32(vp8x[9])<<16 + 1
+
+		if vp8x[0] != c.flags || width != c.width || height != c.height {
+			t.Fatalf("VP8X of [%s] not correct: (0x%02x) %dx%d", c.id, vp8x[0], width, height)
+		}
+
+		rawExif, err := GetWebpExif(updated)
+		log.PanicIf(err)
+
+		if bytes.Equal(rawExif, exifData) != true {
+			t.Fatalf("EXIF of [%s] not correct.", c.id)
+		} else if found, _, err := Probe(bytes.NewReader(updated)); err != nil || found != true {
+			t.Fatalf("EXIF of [%s] not found by Probe: %v", c.id, err)
+		}
+	}
+}
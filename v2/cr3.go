@@ -0,0 +1,91 @@
+package exif
+
+import (
+	"bytes"
+)
+
+var (
+	// cr3CanonUuid is the user type of the "uuid" box in the movie header of a
+	// CR3, which has the metadata boxes.
+	cr3CanonUuid = []byte{
+		0x85, 0xc0, 0xb6, 0x87, 0x82, 0x0f, 0x11, 0xe0,
+		0x81, 0x11, 0xf4, 0xce, 0x46, 0x2b, 0x6a, 0x48,
+	}
+)
+
+const (
+	// cr3Brand is the major brand of the "ftyp" box of a CR3.
+	cr3Brand = "crx "
+)
+
+// isCr3 returns true if the data starts with an "ftyp" box with the CR3 brand.
+func isCr3(data []byte) bool {
+	return len(data) >= 12 && string(data[4:8]) == "ftyp" && string(data[8:12]) == cr3Brand
+}
+
+// GetCr3Exif returns the EXIF block of a Canon CR3, which is the CMT1 box
+// (IFD0) in the movie header. `ErrNoExif` is returned if there isn't one.
+//
+// The other IFDs (Exif, the maker-note, and GPS) are in CMT2 through CMT4,
+// each with its own TIFF header, so they aren't included.
+func GetCr3Exif(data []byte) (rawExif []byte, err error) {
+	if isCr3(data) == false {
+		return nil, ErrNoExif
+	}
+
+	moov, found := findIsoBox(readIsoBoxes(data, 0), "moov")
+	if found == false {
+		return nil, ErrNoExif
+	}
+
+	for _, ib := range readIsoBoxes(moov.payload, moov.offset) {
+		if ib.boxType != "uuid" || bytes.HasPrefix(ib.payload, cr3CanonUuid) == false {
+			continue
+		}
+
+		children := readIsoBoxes(ib.payload[len(cr3CanonUuid):], ib.offset+int64(len(cr3CanonUuid)))
+		if cmt1, found := findIsoBox(children, "CMT1"); found == true {
+			return cmt1.payload, nil
+		}
+	}
+
+	return nil, ErrNoExif
+}
+
+// cr3MediaFormat has the EXIF in the CMT boxes of the movie header. It can
+// only be read.
+type cr3MediaFormat struct{}
+
+// Kind returns `KindCr3`.
+func (cr3MediaFormat) Kind() Kind {
+	return KindCr3
+}
+
+// Sniff looks for the CR3 brand in the "ftyp" box.
+func (cr3MediaFormat) Sniff(head []byte) bool {
+	return isCr3(head)
+}
+
+// ExtractExif returns the EXIF (see `GetCr3Exif()`).
+func (cr3MediaFormat) ExtractExif(data []byte) (rawExif []byte, err error) {
+	return GetCr3Exif(data)
+}
+
+// ReplaceExif returns a `CapabilityError`. The IFDs are split over several
+// boxes of the movie header, whose size changes would move the track data
+// that the movie header points to.
+func (cr3MediaFormat) ReplaceExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	ce := &CapabilityError{
+		Kind:      KindCr3,
+		Operation: CapabilityReplaceExif,
+		Available: CapabilityReadExif,
+		Reason:    "the EXIF is split over the CMT boxes of the movie header",
+	}
+
+	return nil, ce
+}
+
+// Capabilities returns `CapabilityReadExif`.
+func (cr3MediaFormat) Capabilities(data []byte) Capability {
+	return CapabilityReadExif
+}
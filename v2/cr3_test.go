@@ -0,0 +1,63 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// getTestCr3 returns a CR3 with the EXIF in CMT1 and a placeholder for the
+// Exif IFD in CMT2.
+func getTestCr3(exifData []byte) []byte {
+	ftyp := getTestIsoBox("ftyp", []byte(cr3Brand), getTestIsoUints(4, 1), []byte("crx isom"))
+
+	uuid := getTestIsoBox(
+		"uuid",
+		cr3CanonUuid,
+		getTestIsoBox("CNCV", []byte("CanonCR3_001/00.09.00/00.00.00")),
+		getTestIsoBox("CMT1", exifData),
+		getTestIsoBox("CMT2", make([]byte, 8)))
+
+	moov := getTestIsoBox("moov", uuid, getTestIsoBox("trak", make([]byte, 8)))
+	mdat := getTestIsoBox("mdat", make([]byte, 16))
+
+	return bytes.Join([][]byte{ftyp, moov, mdat}, nil)
+}
+
+func TestGetCr3Exif(t *testing.T) {
+	exifData := getTestExifData()
+	data := getTestCr3(exifData)
+
+	rawExif, kind, err := ExtractExif(data)
+	log.PanicIf(err)
+
+	if kind != KindCr3 {
+		t.Fatalf("Kind not correct: [%s]", kind)
+	} else if bytes.Equal(rawExif, exifData) != true {
+		t.Fatalf("EXIF not correct.")
+	}
+
+	heic, _, _ := getTestHeic()
+
+	if _, err := GetCr3Exif(heic); err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif for HEIC: %v", err)
+	} else if _, err := GetCr3Exif(data[:len(data)-len(exifData)]); err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif for truncated CR3: %v", err)
+	}
+}
+
+func TestCr3MediaFormat_ReplaceExif(t *testing.T) {
+	exifData := getTestExifData()
+	data := getTestCr3(exifData)
+
+	mf, err := SniffMediaFormat(data)
+	log.PanicIf(err)
+
+	_, err = mf.ReplaceExif(data, getTestReplacementExif())
+	if ce, found := AsCapabilityError(err); found != true {
+		t.Fatalf("Expected CapabilityError: %v", err)
+	} else if ce.Kind != KindCr3 || ce.Operation != CapabilityReplaceExif || ce.Available != CapabilityReadExif {
+		t.Fatalf("CapabilityError not correct: %v", ce)
+	}
+}
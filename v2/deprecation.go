@@ -0,0 +1,292 @@
+package exif
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+const (
+	gpsVersionIdTagId = 0x0000
+
+	// paddingTagId and offsetSchemaTagId are written by Windows to reserve
+	// space for in-place edits. They aren't in the specification.
+	paddingTagId      = 0xea1c
+	offsetSchemaTagId = 0xea1d
+)
+
+var (
+	// currentGpsVersion is the GPSVersionID of Exif 2.3 and later.
+	currentGpsVersion = []byte{2, 3, 0, 0}
+)
+
+// DeprecatedTag is a tag that's deprecated, superseded, or non-standard, as
+// found by `FindDeprecatedTags()`.
+type DeprecatedTag struct {
+	FqIfdPath string
+	TagId     uint16
+	TagName   string
+
+	// Reason describes what's wrong with the tag.
+	Reason string
+
+	// Migration describes what `MigrateDeprecatedTags()` does about it.
+	Migration string
+}
+
+// String returns a descriptive string.
+func (dt DeprecatedTag) String() string {
+	return fmt.Sprintf("DeprecatedTag<IFD=[%s] ID=(0x%04x) NAME=[%s] REASON=[%s] MIGRATION=[%s]>", dt.FqIfdPath, dt.TagId, dt.TagName, dt.Reason, dt.Migration)
+}
+
+// deprecationRule finds one kind of deprecated tag and rewrites it.
+type deprecationRule struct {
+	// ifdPath is the IFD that the rule applies to, or empty for all of them.
+	ifdPath string
+
+	tagId     uint16
+	tagName   string
+	reason    string
+	migration string
+
+	// check returns true if the IFD has the deprecated tag.
+	check func(ifd *Ifd) bool
+
+	// migrate rewrites the tag in the builder of the IFD, which is in the
+	// chain of the root builder.
+	migrate func(rootIb, ib *IfdBuilder, ifd *Ifd) error
+}
+
+var (
+	deprecationRules = []deprecationRule{
+		{
+			ifdPath:   exifcommon.IfdPathStandardExif,
+			tagId:     isoSpeedRatingsTagId,
+			tagName:   "ISOSpeedRatings",
+			reason:    "ISOSpeedRatings is PhotographicSensitivity since Exif 2.3, which needs SensitivityType to say what it measures",
+			migration: "add SensitivityType (ISO speed) and ISOSpeed, and raise ExifVersion to 2.3",
+			check:     checkIsoSpeedRatings,
+			migrate:   migrateIsoSpeedRatings,
+		},
+		{
+			ifdPath:   exifcommon.IfdPathStandardGps,
+			tagId:     gpsVersionIdTagId,
+			tagName:   "GPSVersionID",
+			reason:    "the GPS IFD is of a version before 2.3",
+			migration: "set GPSVersionID to 2.3.0.0",
+			check:     checkGpsVersionId,
+			migrate:   migrateGpsVersionId,
+		},
+		{
+			tagId:     paddingTagId,
+			tagName:   "Padding",
+			reason:    "Padding is a non-standard tag that only reserves space",
+			migration: "remove it",
+			check:     hasDeprecatedTag(paddingTagId),
+			migrate:   deleteDeprecatedTag(paddingTagId),
+		},
+		{
+			ifdPath:   exifcommon.IfdPathStandardExif,
+			tagId:     offsetSchemaTagId,
+			tagName:   "OffsetSchema",
+			reason:    "OffsetSchema is a non-standard tag that describes the shift of a MakerNote moved by Padding",
+			migration: "remove it",
+			check:     hasDeprecatedTag(offsetSchemaTagId),
+			migrate:   deleteDeprecatedTag(offsetSchemaTagId),
+		},
+	}
+)
+
+// hasDeprecatedTag returns a check for the presence of the tag.
+func hasDeprecatedTag(tagId uint16) func(ifd *Ifd) bool {
+	return func(ifd *Ifd) bool {
+		return len(ifd.EntriesByTagId[tagId]) > 0
+	}
+}
+
+// deleteDeprecatedTag returns a migration that removes the tag.
+func deleteDeprecatedTag(tagId uint16) func(rootIb, ib *IfdBuilder, ifd *Ifd) error {
+	return func(rootIb, ib *IfdBuilder, ifd *Ifd) (err error) {
+		_, err = ib.DeleteAll(tagId)
+		return err
+	}
+}
+
+// checkIsoSpeedRatings returns true if there's an ISOSpeedRatings without a
+// SensitivityType.
+func checkIsoSpeedRatings(ifd *Ifd) bool {
+	return len(ifd.EntriesByTagId[isoSpeedRatingsTagId]) > 0 && len(ifd.EntriesByTagId[sensitivityTypeTagId]) == 0
+}
+
+// migrateIsoSpeedRatings raises the ExifVersion to 2.3 and then sets the ISO
+// again (see `SetIso()`), which adds the SensitivityType and ISOSpeed. The
+// value is kept as PhotographicSensitivity.
+func migrateIsoSpeedRatings(rootIb, ib *IfdBuilder, ifd *Ifd) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	value, err := ifd.EntriesByTagId[isoSpeedRatingsTagId][0].Value()
+	log.PanicIf(err)
+
+	ratings, ok := value.([]uint16)
+	if ok == false || len(ratings) == 0 {
+		log.Panicf("ISOSpeedRatings not valid: %v", value)
+	}
+
+	if getIbExifVersion(ib) < exifVersionSensitivityTags {
+		err := ib.SetStandard(exifVersionTagId, exifundefined.Tag9000ExifVersion{ExifVersion: exifVersionSensitivityTags})
+		log.PanicIf(err)
+	}
+
+	err = SetIso(rootIb, uint32(ratings[0]))
+	log.PanicIf(err)
+
+	return nil
+}
+
+// checkGpsVersionId returns true if the GPSVersionID is before 2.3.
+func checkGpsVersionId(ifd *Ifd) bool {
+	ites := ifd.EntriesByTagId[gpsVersionIdTagId]
+	if len(ites) == 0 {
+		return false
+	}
+
+	version, err := ites[0].GetRawBytes()
+	if err != nil || len(version) != len(currentGpsVersion) {
+		return false
+	}
+
+	return bytes.Compare(version, currentGpsVersion) < 0
+}
+
+// migrateGpsVersionId sets the GPSVersionID to 2.3.0.0, whose fields are a
+// superset of those of 2.2.
+func migrateGpsVersionId(rootIb, ib *IfdBuilder, ifd *Ifd) (err error) {
+	return ib.SetStandard(gpsVersionIdTagId, currentGpsVersion)
+}
+
+// findDeprecations calls the visitor with each IFD and each rule that finds a
+// deprecated tag in it.
+func findDeprecations(index IfdIndex, visitor func(ifd *Ifd, rule deprecationRule) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for _, ifd := range index.Ifds {
+		for _, rule := range deprecationRules {
+			if rule.ifdPath != "" && rule.ifdPath != ifd.IfdPath {
+				continue
+			} else if rule.check(ifd) == false {
+				continue
+			}
+
+			err := visitor(ifd, rule)
+			log.PanicIf(err)
+		}
+	}
+
+	return nil
+}
+
+// FindDeprecatedTags returns the deprecated, superseded, and non-standard
+// tags in the IFDs: an ISOSpeedRatings without the SensitivityType that Exif
+// 2.3 requires, a GPS IFD of a version before 2.3, and the Padding and
+// OffsetSchema tags that Windows adds.
+func FindDeprecatedTags(index IfdIndex) (deprecated []DeprecatedTag) {
+	deprecated = make([]DeprecatedTag, 0)
+
+	findDeprecations(index, func(ifd *Ifd, rule deprecationRule) error {
+		dt := DeprecatedTag{
+			FqIfdPath: ifd.FqIfdPath,
+			TagId:     rule.tagId,
+			TagName:   rule.tagName,
+			Reason:    rule.reason,
+			Migration: rule.migration,
+		}
+
+		deprecated = append(deprecated, dt)
+
+		return nil
+	})
+
+	return deprecated
+}
+
+// MigrateDeprecatedTags rewrites the EXIF with the modern equivalents of the
+// deprecated tags (see `FindDeprecatedTags()`), which are also returned. The
+// original is returned if there aren't any.
+func MigrateDeprecatedTags(rawExif []byte) (migrated []byte, deprecated []DeprecatedTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	deprecated = FindDeprecatedTags(index)
+	if len(deprecated) == 0 {
+		return rawExif, deprecated, nil
+	}
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	err = findDeprecations(index, func(ifd *Ifd, rule deprecationRule) error {
+		ib, err := GetOrCreateIbFromRootIb(rootIb, ifd.FqIfdPath)
+		if err != nil {
+			return err
+		}
+
+		return rule.migrate(rootIb, ib, ifd)
+	})
+
+	log.PanicIf(err)
+
+	migrated, err = NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	return migrated, deprecated, nil
+}
+
+// MigrateJpegDeprecatedTags returns a copy of the JPEG with the deprecated
+// tags of its EXIF migrated (see `MigrateDeprecatedTags()`).
+func MigrateJpegDeprecatedTags(data []byte) (updated []byte, deprecated []DeprecatedTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	segments := si.Find(JpegSegmentExif)
+	if len(segments) == 0 {
+		log.Panic(ErrNoExif)
+	}
+
+	segment := segments[0]
+	rawExif := data[segment.DataOffset : segment.DataOffset+segment.DataSize]
+
+	migrated, deprecated, err := MigrateDeprecatedTags(rawExif)
+	log.PanicIf(err)
+
+	if len(deprecated) == 0 {
+		return data, deprecated, nil
+	}
+
+	updated, err = replaceJpegSegmentData(data, segment, migrated)
+	log.PanicIf(err)
+
+	return updated, deprecated, nil
+}
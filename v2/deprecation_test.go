@@ -0,0 +1,135 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestDeprecatedExif returns realistic EXIF with an ISOSpeedRatings of 400
+// and no SensitivityType, a 2.2 GPS IFD, and Padding in IFD0 and the Exif IFD.
+func getTestDeprecatedExif() []byte {
+	root := exiftest.NewRealisticIfd()
+
+	padding := exiftest.Tag{Id: paddingTagId, Type: exifcommon.TypeUndefined, Raw: make([]byte, 64)}
+	root.Tags = append(root.Tags, padding)
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, padding)
+	exifIfd.Tags = append(exifIfd.Tags, exiftest.Tag{Id: offsetSchemaTagId, Value: []int32{4}})
+
+	gpsIfd := root.Children[1].Ifd
+	gpsIfd.Tags = append(gpsIfd.Tags, exiftest.Tag{Id: gpsVersionIdTagId, Value: []byte{2, 2, 0, 0}})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return rawExif
+}
+
+func TestFindDeprecatedTags(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestDeprecatedExif())
+	log.PanicIf(err)
+
+	deprecated := FindDeprecatedTags(index)
+
+	expected := []struct {
+		fqIfdPath string
+		tagName   string
+	}{
+		{"IFD", "Padding"},
+		{"IFD/Exif", "ISOSpeedRatings"},
+		{"IFD/Exif", "Padding"},
+		{"IFD/Exif", "OffsetSchema"},
+		{"IFD/GPSInfo", "GPSVersionID"},
+	}
+
+	if len(deprecated) != len(expected) {
+		t.Fatalf("Deprecated tags not correct: %v", deprecated)
+	}
+
+	for i, e := range expected {
+		if deprecated[i].FqIfdPath != e.fqIfdPath || deprecated[i].TagName != e.tagName {
+			t.Fatalf("Deprecated tag (%d) not correct: %s", i, deprecated[i])
+		} else if deprecated[i].Reason == "" || deprecated[i].Migration == "" {
+			t.Fatalf("Deprecated tag (%d) not described: %s", i, deprecated[i])
+		}
+	}
+}
+
+func TestMigrateDeprecatedTags(t *testing.T) {
+	migrated, deprecated, err := MigrateDeprecatedTags(getTestDeprecatedExif())
+	log.PanicIf(err)
+
+	if len(deprecated) != 5 {
+		t.Fatalf("Deprecated tags not correct: %v", deprecated)
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), migrated)
+	log.PanicIf(err)
+
+	if remaining := FindDeprecatedTags(index); len(remaining) != 0 {
+		t.Fatalf("Deprecated tags not migrated: %v", remaining)
+	}
+
+	exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+	gpsIfd := index.Lookup[exifcommon.IfdPathStandardGps][0]
+
+	iso, err := GetIso(index)
+	log.PanicIf(err)
+
+	version, err := gpsIfd.EntriesByTagId[gpsVersionIdTagId][0].GetRawBytes()
+	log.PanicIf(err)
+
+	if iso.Value != 400 || iso.SensitivityType != SensitivityTypeIsoSpeed || iso.Source != "Exif/ISOSpeed" {
+		t.Fatalf("ISO not correct: %s", iso)
+	} else if len(exifIfd.EntriesByTagId[isoSpeedRatingsTagId]) != 1 {
+		t.Fatalf("ISOSpeedRatings not kept.")
+	} else if string(version) != string(currentGpsVersion) {
+		t.Fatalf("GPSVersionID not correct: %v", version)
+	} else if len(index.RootIfd.EntriesByTagId[paddingTagId]) != 0 || len(exifIfd.EntriesByTagId[paddingTagId]) != 0 {
+		t.Fatalf("Padding not removed.")
+	}
+
+	if make_, err := getIfdTagString(index.RootIfd, "Make"); err != nil || make_ != "Canon" {
+		t.Fatalf("Make not kept: [%s] %v", make_, err)
+	}
+}
+
+func TestMigrateDeprecatedTags_None(t *testing.T) {
+	rawExif, _, err := MigrateDeprecatedTags(getTestDeprecatedExif())
+	log.PanicIf(err)
+
+	migrated, deprecated, err := MigrateDeprecatedTags(rawExif)
+	log.PanicIf(err)
+
+	if len(deprecated) != 0 {
+		t.Fatalf("Expected no deprecated tags: %v", deprecated)
+	} else if &migrated[0] != &rawExif[0] {
+		t.Fatalf("Expected the original to be returned.")
+	}
+}
+
+func TestMigrateJpegDeprecatedTags(t *testing.T) {
+	data := exiftest.WrapJpeg(getTestDeprecatedExif())
+
+	updated, deprecated, err := MigrateJpegDeprecatedTags(data)
+	log.PanicIf(err)
+
+	rawExif, err := GetJpegExif(updated)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	if len(deprecated) != 5 {
+		t.Fatalf("Deprecated tags not correct: %v", deprecated)
+	} else if remaining := FindDeprecatedTags(index); len(remaining) != 0 {
+		t.Fatalf("Deprecated tags not migrated: %v", remaining)
+	}
+}
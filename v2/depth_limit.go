@@ -0,0 +1,68 @@
+package exif
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// DefaultMaxIfdDepth is how deeply IFDs may be nested by default. The
+	// standard IFDs only go three deep (IFD/Exif/Iop).
+	DefaultMaxIfdDepth = 8
+
+	// DefaultMaxMakerNoteDepth is how deeply maker-note IFDs may be nested by
+	// default, counting the maker-note itself.
+	DefaultMaxMakerNoteDepth = 4
+)
+
+var (
+	// ErrIfdTooDeep means that IFDs are nested more deeply than allowed,
+	// which only happens with damaged or malicious data.
+	ErrIfdTooDeep = errors.New("ifd nested too deeply")
+)
+
+var (
+	// maxMakerNoteDepth is the limit for `DecodeMakerNoteIfd()`.
+	maxMakerNoteDepth int32 = DefaultMaxMakerNoteDepth
+)
+
+// SetMaxIfdDepth sets how deeply `Collect()` and `Scan()` follow child IFDs,
+// with IFD0 at a depth of one (so "IFD/Exif/Iop" is three deep). A child that
+// would be deeper fails the parse with `ErrIfdTooDeep` or, if the enumerator
+// is tolerant, is skipped with a warning. Zero, the default, is
+// `DefaultMaxIfdDepth`.
+func (ie *IfdEnumerate) SetMaxIfdDepth(depth int) {
+	ie.maxIfdDepth = depth
+}
+
+// isIfdTooDeep returns true if the IFD is nested more deeply than allowed.
+func (ie *IfdEnumerate) isIfdTooDeep(fqIfdPath string) bool {
+	maxDepth := ie.maxIfdDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxIfdDepth
+	}
+
+	return ifdDepth(fqIfdPath) > maxDepth
+}
+
+// ifdDepth returns how deeply the IFD is nested, with IFD0 at one.
+func ifdDepth(fqIfdPath string) int {
+	return strings.Count(fqIfdPath, "/") + 1
+}
+
+// SetMaxMakerNoteDepth sets how deeply `DecodeMakerNoteIfd()` follows the
+// IFDs nested in a maker-note, with the maker-note itself at a depth of one.
+// Deeper IFDs are skipped. Zero or less restores `DefaultMaxMakerNoteDepth`.
+func SetMaxMakerNoteDepth(depth int) {
+	if depth <= 0 {
+		depth = DefaultMaxMakerNoteDepth
+	}
+
+	atomic.StoreInt32(&maxMakerNoteDepth, int32(depth))
+}
+
+// getMaxMakerNoteDepth returns the limit set by `SetMaxMakerNoteDepth()`.
+func getMaxMakerNoteDepth() int {
+	return int(atomic.LoadInt32(&maxMakerNoteDepth))
+}
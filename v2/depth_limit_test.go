@@ -0,0 +1,133 @@
+package exif
+
+import (
+	"errors"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// getTestNestedMakerNote returns a maker-note of `count` IFDs, each with one
+// IFD-type entry that points to the next. The last points back to the first.
+func getTestNestedMakerNote(count int) []byte {
+	ifdSize := int(2 + IfdTagEntrySize + 4)
+
+	data := make([]byte, count*ifdSize)
+
+	for i := 0; i < count; i++ {
+		position := i * ifdSize
+		nextOffset := uint32(((i + 1) % count) * ifdSize)
+
+		binary.BigEndian.PutUint16(data[position:], 1)
+		binary.BigEndian.PutUint16(data[position+2:], uint16(0x0100+i))
+		binary.BigEndian.PutUint16(data[position+4:], uint16(subIfdTagTypeIfd))
+		binary.BigEndian.PutUint32(data[position+6:], 1)
+		binary.BigEndian.PutUint32(data[position+10:], nextOffset)
+	}
+
+	return data
+}
+
+// makerNoteDepth returns how many IFDs are nested in the maker-note,
+// counting itself.
+func makerNoteDepth(mn *MakerNote) int {
+	depth := 1
+	for _, mnt := range mn.Tags {
+		for _, child := range mnt.Ifds {
+			if childDepth := makerNoteDepth(child) + 1; childDepth > depth {
+				depth = childDepth
+			}
+		}
+	}
+
+	return depth
+}
+
+func TestIfdEnumerate_SetMaxIfdDepth(t *testing.T) {
+	rawExif := getTestExifData()
+
+	eh, err := ParseExifHeader(rawExif)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, eh.ByteOrder)
+	ie.SetMaxIfdDepth(2)
+
+	_, err = ie.Collect(eh.FirstIfdOffset)
+	if errors.Is(err, ErrIfdTooDeep) == false {
+		t.Fatalf("Expected ErrIfdTooDeep from Collect: %v", err)
+	}
+
+	err = ie.Scan(exifcommon.IfdStandard, eh.FirstIfdOffset, func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) error {
+		return nil
+	})
+
+	if errors.Is(err, ErrIfdTooDeep) == false {
+		t.Fatalf("Expected ErrIfdTooDeep from Scan: %v", err)
+	}
+
+	// The standard IFDs are within the default.
+	ie = NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, eh.ByteOrder)
+
+	_, err = ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+}
+
+func TestIfdEnumerate_SetMaxIfdDepth_Tolerant(t *testing.T) {
+	rawExif := getTestExifData()
+
+	eh, err := ParseExifHeader(rawExif)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, eh.ByteOrder)
+	ie.SetTolerant(true)
+	ie.SetMaxIfdDepth(2)
+
+	index, err := ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+
+	if _, found := index.Lookup["IFD/Exif/Iop"]; found == true {
+		t.Fatalf("Iop IFD should have been skipped.")
+	} else if _, found := index.Lookup["IFD/Exif"]; found == false {
+		t.Fatalf("Exif IFD should have been collected.")
+	} else if len(ie.Warnings()) != 1 || ie.Warnings()[0].Is(ErrIfdTooDeep) == false || ie.Warnings()[0].FqIfdPath != "IFD/Exif/Iop" {
+		t.Fatalf("Warnings not correct: %v", ie.Warnings())
+	}
+}
+
+func TestDecodeMakerNoteIfd_Nested(t *testing.T) {
+	defer SetMaxMakerNoteDepth(DefaultMaxMakerNoteDepth)
+
+	data := getTestNestedMakerNote(10)
+
+	cases := []struct {
+		maxDepth int
+		expected int
+	}{
+		{0, DefaultMaxMakerNoteDepth},
+		{1, 1},
+		{3, 3},
+		{100, 10},
+	}
+
+	for _, c := range cases {
+		SetMaxMakerNoteDepth(c.maxDepth)
+
+		mn, err := DecodeMakerNoteIfd("Test", data, 0, binary.BigEndian, nil)
+		log.PanicIf(err)
+
+		if depth := makerNoteDepth(mn); depth != c.expected {
+			t.Fatalf("Depth for (%d) not correct: (%d) != (%d)", c.maxDepth, depth, c.expected)
+		}
+
+		value, err := mn.Tags[0].Value()
+		log.PanicIf(err)
+
+		if offsets, ok := value.([]uint32); ok != true || len(offsets) != 1 || offsets[0] != 2+IfdTagEntrySize+4 {
+			t.Fatalf("IFD-type value not correct: %v", value)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package exif
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// TagChangeAdded means that the tag is only in the new data.
+	TagChangeAdded = "added"
+
+	// TagChangeRemoved means that the tag is only in the old data.
+	TagChangeRemoved = "removed"
+
+	// TagChangeModified means that the value of the tag changed.
+	TagChangeModified = "modified"
+)
+
+// TagChange is a tag that's different between two versions of a file.
+type TagChange struct {
+	Kind      string `json:"kind"`
+	FqIfdPath string `json:"ifd_path"`
+	TagId     uint16 `json:"tag_id"`
+	TagName   string `json:"tag_name"`
+
+	// Before and After are the formatted values. Before is empty if the tag
+	// was added and After is empty if it was removed.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// String returns a descriptive string.
+func (tc TagChange) String() string {
+	return fmt.Sprintf("TagChange<KIND=[%s] IFD=[%s] TAG-ID=(0x%04x) TAG-NAME=[%s] BEFORE=[%s] AFTER=[%s]>", tc.Kind, tc.FqIfdPath, tc.TagId, tc.TagName, tc.Before, tc.After)
+}
+
+// ByteRange is a range of bytes in the original file.
+type ByteRange struct {
+	Offset int `json:"offset"`
+	Size   int `json:"size"`
+}
+
+// MetadataDiff is how a file's metadata and bytes differ between two
+// versions, such as before and after an edit.
+type MetadataDiff struct {
+	Tags []TagChange `json:"tags"`
+
+	// Ranges are the bytes of the original file that differ. If the sizes are
+	// different, the last range runs from where they stop matching to the
+	// end of the larger of the two.
+	Ranges []ByteRange `json:"ranges"`
+
+	SizeBefore int `json:"size_before"`
+	SizeAfter  int `json:"size_after"`
+}
+
+// IsEmpty returns true if nothing changed.
+func (md MetadataDiff) IsEmpty() bool {
+	return len(md.Tags) == 0 && len(md.Ranges) == 0
+}
+
+// String returns a descriptive string.
+func (md MetadataDiff) String() string {
+	return fmt.Sprintf("MetadataDiff<TAGS=(%d) RANGES=(%d) SIZE-BEFORE=(%d) SIZE-AFTER=(%d)>", len(md.Tags), len(md.Ranges), md.SizeBefore, md.SizeAfter)
+}
+
+// diffTagKey identifies a tag for comparison. Tags that occur more than once
+// in an IFD are told apart by their occurrence.
+type diffTagKey struct {
+	fqIfdPath  string
+	tagId      uint16
+	occurrence int
+}
+
+// diffTags returns the formatted value and name of every tag in the EXIF of
+// the data, if it has any. Pointers to child IFDs and to the thumbnail are
+// left out since their values are only offsets.
+func diffTags(data []byte) (values map[diffTagKey]string, names map[diffTagKey]string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	values = make(map[diffTagKey]string)
+	names = make(map[diffTagKey]string)
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == ErrNoExif {
+		return values, names, nil
+	}
+
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	for _, ifd := range index.Ifds {
+		occurrences := make(map[uint16]int)
+
+		for _, ite := range ifd.Entries {
+			if ite.ChildIfdPath() != "" || ite.TagId() == ThumbnailOffsetTagId {
+				continue
+			}
+
+			key := diffTagKey{
+				fqIfdPath:  ifd.FqIfdPath,
+				tagId:      ite.TagId(),
+				occurrence: occurrences[ite.TagId()],
+			}
+
+			occurrences[ite.TagId()]++
+
+			value, err := ite.Format()
+			log.PanicIf(err)
+
+			values[key] = value
+
+			if it, err := ifd.tagIndex.Get(ifd.IfdPath, ite.TagId()); err == nil {
+				names[key] = it.Name
+			}
+		}
+	}
+
+	return values, names, nil
+}
+
+// diffRanges returns the ranges of bytes that differ.
+func diffRanges(before, after []byte) []ByteRange {
+	ranges := make([]ByteRange, 0)
+
+	common := len(before)
+	if len(after) < common {
+		common = len(after)
+	}
+
+	start := -1
+	for i := 0; i < common; i++ {
+		if before[i] != after[i] {
+			if start == -1 {
+				start = i
+			}
+		} else if start != -1 {
+			ranges = append(ranges, ByteRange{Offset: start, Size: i - start})
+			start = -1
+		}
+	}
+
+	end := len(before)
+	if len(after) > end {
+		end = len(after)
+	}
+
+	if start == -1 && common < end {
+		start = common
+	}
+
+	if start != -1 {
+		ranges = append(ranges, ByteRange{Offset: start, Size: end - start})
+	}
+
+	return ranges
+}
+
+// DiffMetadata returns how the metadata and bytes of a file (any format with
+// EXIF that `SearchAndExtractExif()` finds) differ between two versions.
+func DiffMetadata(before, after []byte) (md MetadataDiff, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	beforeValues, beforeNames, err := diffTags(before)
+	log.PanicIf(err)
+
+	afterValues, afterNames, err := diffTags(after)
+	log.PanicIf(err)
+
+	md = MetadataDiff{
+		Tags:       make([]TagChange, 0),
+		Ranges:     diffRanges(before, after),
+		SizeBefore: len(before),
+		SizeAfter:  len(after),
+	}
+
+	for key, beforeValue := range beforeValues {
+		tc := TagChange{
+			FqIfdPath: key.fqIfdPath,
+			TagId:     key.tagId,
+			TagName:   beforeNames[key],
+			Before:    beforeValue,
+		}
+
+		if afterValue, found := afterValues[key]; found == false {
+			tc.Kind = TagChangeRemoved
+		} else if afterValue != beforeValue {
+			tc.Kind = TagChangeModified
+			tc.After = afterValue
+		} else {
+			continue
+		}
+
+		md.Tags = append(md.Tags, tc)
+	}
+
+	for key, afterValue := range afterValues {
+		if _, found := beforeValues[key]; found == true {
+			continue
+		}
+
+		tc := TagChange{
+			Kind:      TagChangeAdded,
+			FqIfdPath: key.fqIfdPath,
+			TagId:     key.tagId,
+			TagName:   afterNames[key],
+			After:     afterValue,
+		}
+
+		md.Tags = append(md.Tags, tc)
+	}
+
+	sort.Slice(md.Tags, func(i, j int) bool {
+		if md.Tags[i].FqIfdPath != md.Tags[j].FqIfdPath {
+			return md.Tags[i].FqIfdPath < md.Tags[j].FqIfdPath
+		}
+
+		return md.Tags[i].TagId < md.Tags[j].TagId
+	})
+
+	return md, nil
+}
+
+// DryRunFile reports what the given edit would change in the file without
+// writing it. Any of the functions in this package that return an edited
+// copy of a file (e.g. `StampJpeg()`, `SetJpegRights()`, or
+// `Pipeline.Apply()`) can be given as the edit.
+func DryRunFile(filepath string, edit func(data []byte) (updated []byte, err error)) (md MetadataDiff, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	updated, err := edit(data)
+	log.PanicIf(err)
+
+	md, err = DiffMetadata(data, updated)
+	log.PanicIf(err)
+
+	return md, nil
+}
@@ -0,0 +1,128 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestDiffRanges(t *testing.T) {
+	ranges := diffRanges([]byte("abcdefgh"), []byte("aXcdYYghij"))
+
+	expected := []ByteRange{
+		{Offset: 1, Size: 1},
+		{Offset: 4, Size: 2},
+		{Offset: 8, Size: 2},
+	}
+
+	if reflect.DeepEqual(ranges, expected) == false {
+		t.Fatalf("Ranges not correct: %v", ranges)
+	} else if len(diffRanges([]byte("abc"), []byte("abc"))) != 0 {
+		t.Fatalf("Expected no ranges for the same data.")
+	}
+}
+
+func TestDiffMetadata(t *testing.T) {
+	original := getTestPipelineJpeg()
+
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpStripGps},
+			{Op: PipelineOpSetTag, FqIfdPath: "IFD", TagName: "Model", Value: "Other"},
+			{Op: PipelineOpSetTag, FqIfdPath: "IFD", TagName: "Software", Value: "Editor"},
+		},
+	}
+
+	updated, err := p.Apply(original)
+	log.PanicIf(err)
+
+	md, err := DiffMetadata(original, updated)
+	log.PanicIf(err)
+
+	kinds := make(map[string]string)
+	for _, tc := range md.Tags {
+		kinds[tc.FqIfdPath+"/"+tc.TagName] = tc.Kind
+
+		if tc.TagName == "Model" && (tc.Before != "Canon EOS 5D Mark III" || tc.After != "Other") {
+			t.Fatalf("Model change not correct: %s", tc)
+		}
+	}
+
+	if kinds["IFD/Model"] != TagChangeModified || kinds["IFD/Software"] != TagChangeAdded || kinds["IFD/GPSInfo/GPSLatitude"] != TagChangeRemoved {
+		t.Fatalf("Changes not correct: %v", kinds)
+	} else if _, found := kinds["IFD/Make"]; found == true {
+		t.Fatalf("Unchanged tag reported.")
+	} else if len(md.Ranges) == 0 || md.Ranges[0].Offset < 2 {
+		t.Fatalf("Ranges not correct: %v", md.Ranges)
+	} else if md.SizeBefore != len(original) || md.SizeAfter != len(updated) {
+		t.Fatalf("Sizes not correct: %s", md)
+	}
+
+	md, err = DiffMetadata(original, original)
+	log.PanicIf(err)
+
+	if md.IsEmpty() == false {
+		t.Fatalf("Expected no changes: %s", md)
+	}
+}
+
+func TestPipeline_DryRunFile(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+	original := getTestPipelineJpeg()
+
+	err = ioutil.WriteFile(filepath, original, 0644)
+	log.PanicIf(err)
+
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpSetRights, Copyright: "(c) Jane Doe"},
+		},
+	}
+
+	md, err := p.DryRunFile(filepath)
+	log.PanicIf(err)
+
+	found := false
+	for _, tc := range md.Tags {
+		if tc.TagName == "Copyright" && tc.After == "(c) Jane Doe" {
+			found = true
+		}
+	}
+
+	if found == false {
+		t.Fatalf("Copyright change not reported: %v", md.Tags)
+	}
+
+	tt := TagTemplate{
+		Tags: []TemplateTag{
+			{FqIfdPath: "IFD", TagName: "ImageDescription", Value: "{FileBaseName}"},
+		},
+	}
+
+	md, err = DryRunStampJpegFile(filepath, tt)
+	log.PanicIf(err)
+
+	if len(md.Tags) != 1 || md.Tags[0].After != "image" {
+		t.Fatalf("Stamp change not reported: %v", md.Tags)
+	}
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	if bytes.Equal(data, original) == false {
+		t.Fatalf("File changed by a dry-run.")
+	}
+}
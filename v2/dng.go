@@ -0,0 +1,206 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"errors"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// DngVersionTagId is the ID of the DNGVersion tag, which every DNG has in
+	// IFD0.
+	DngVersionTagId = 0xc612
+)
+
+var (
+	// ErrNotDng means that the data isn't a DNG file.
+	ErrNotDng = errors.New("not a dng file")
+
+	// ErrDngTagProtected means that a tag describes the structure of the
+	// image data and can't be changed by `DngEditor`.
+	ErrDngTagProtected = errors.New("dng tag is protected")
+)
+
+var (
+	// dngEditableIfdPaths are the IFDs that `DngEditor` can change. The raw
+	// and preview images are in IFD0 and its SubIFDs, and only IFD0 also has
+	// metadata.
+	dngEditableIfdPaths = map[string]bool{
+		exifcommon.IfdPathStandard:        true,
+		exifcommon.IfdPathStandardExif:    true,
+		exifcommon.IfdPathStandardExifIop: true,
+		exifcommon.IfdPathStandardGps:     true,
+	}
+
+	// dngProtectedTagIds are the IFD0 tags that describe or locate image data
+	// (including the opcode lists and maker-note, which may use absolute
+	// offsets).
+	dngProtectedTagIds = map[uint16]bool{
+		0x00fe: true, // NewSubfileType
+		0x0100: true, // ImageWidth
+		0x0101: true, // ImageLength
+		0x0102: true, // BitsPerSample
+		0x0103: true, // Compression
+		0x0106: true, // PhotometricInterpretation
+		0x0111: true, // StripOffsets
+		0x0115: true, // SamplesPerPixel
+		0x0116: true, // RowsPerStrip
+		0x0117: true, // StripByteCounts
+		0x011c: true, // PlanarConfiguration
+		0x0142: true, // TileWidth
+		0x0143: true, // TileLength
+		0x0144: true, // TileOffsets
+		0x0145: true, // TileByteCounts
+		0x014a: true, // SubIFDs
+		0x0201: true, // JPEGInterchangeFormat
+		0x0202: true, // JPEGInterchangeFormatLength
+		0x927c: true, // MakerNote
+		0xc612: true, // DNGVersion
+		0xc613: true, // DNGBackwardVersion
+		0xc634: true, // DNGPrivateData
+		0xc740: true, // OpcodeList1
+		0xc741: true, // OpcodeList2
+		0xc74e: true, // OpcodeList3
+	}
+)
+
+// DngEditor changes the metadata of a DNG file without touching its image
+// data. The whole file is a TIFF, so it's patched with an `ExifPatcher`: the
+// strips, tiles, SubIFDs, opcode lists, and maker-note keep their original
+// bytes and offsets, and anything that grows is appended to the end of the
+// file. Only IFD0 and the EXIF, Interoperability, and GPS IFDs can be
+// changed, and tags that describe the image data are refused.
+type DngEditor struct {
+	ep *ExifPatcher
+}
+
+// NewDngEditor parses a DNG file. `ErrNotDng` is returned if it doesn't have
+// a DNGVersion tag.
+func NewDngEditor(data []byte) (de *DngEditor, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ep, err := NewExifPatcher(data, NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	if _, found := ep.getIfd(exifcommon.IfdPathStandard).find(DngVersionTagId); found == false {
+		return nil, ErrNotDng
+	}
+
+	de = &DngEditor{
+		ep: ep,
+	}
+
+	return de, nil
+}
+
+// tagId returns the ID of the named tag after checking that it can be
+// changed.
+func (de *DngEditor) tagId(fqIfdPath, tagName string) (tagId uint16, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifdPath, err := de.ep.ifdMapping.StripPathPhraseIndices(fqIfdPath)
+	log.PanicIf(err)
+
+	// Only the first IFD of each kind has metadata. "IFD1" is a sibling
+	// image.
+	if dngEditableIfdPaths[ifdPath] == false || ifdPath != fqIfdPath {
+		log.Panicf("DNG IFD can not be edited: [%s]", fqIfdPath)
+	}
+
+	it, err := de.ep.tagIndex.GetWithName(ifdPath, tagName)
+	log.PanicIf(err)
+
+	if ifdPath == exifcommon.IfdPathStandard && dngProtectedTagIds[it.Id] == true {
+		return 0, ErrDngTagProtected
+	} else if ifdPath == exifcommon.IfdPathStandardExif && it.Id == 0x927c {
+		return 0, ErrDngTagProtected
+	}
+
+	return it.Id, nil
+}
+
+// Set changes or adds a tag. The EXIF and GPS IFDs are added if they're
+// missing. See `ExifPatcher.Set()` for how values are given.
+func (de *DngEditor) Set(fqIfdPath, tagName string, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tagId, err := de.tagId(fqIfdPath, tagName)
+	if err == ErrDngTagProtected {
+		return err
+	}
+
+	log.PanicIf(err)
+
+	if fqIfdPath == exifcommon.IfdPathStandardExifIop {
+		err := de.ep.AddChildIfd(exifcommon.IfdPathStandardExif)
+		log.PanicIf(err)
+	}
+
+	err = de.ep.AddChildIfd(fqIfdPath)
+	log.PanicIf(err)
+
+	err = de.ep.Set(fqIfdPath, tagId, value)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// Delete removes a tag. `ErrTagNotFound` is returned if it's not present.
+func (de *DngEditor) Delete(fqIfdPath, tagName string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tagId, err := de.tagId(fqIfdPath, tagName)
+	if err == ErrDngTagProtected {
+		return err
+	}
+
+	log.PanicIf(err)
+
+	if de.ep.HasIfd(fqIfdPath) == false {
+		return ErrTagNotFound
+	}
+
+	err = de.ep.Delete(fqIfdPath, tagId)
+	if err == ErrTagNotFound {
+		return err
+	}
+
+	log.PanicIf(err)
+
+	return nil
+}
+
+// Encode returns the updated DNG.
+func (de *DngEditor) Encode() (data []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err = de.ep.Encode()
+	log.PanicIf(err)
+
+	return data, nil
+}
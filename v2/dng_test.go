@@ -0,0 +1,120 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestDng returns a minimal DNG: IFD0 has the DNG version and the raw
+// image is in a SubIFD with one strip of pixels, which follows the IFDs.
+func getTestDng() (data []byte, stripOffset uint32) {
+	return getTestTiffWithSubIfd(exiftest.Tag{Id: DngVersionTagId, Value: []byte{1, 4, 0, 0}})
+}
+
+func TestDngEditor(t *testing.T) {
+	original, stripOffset := getTestDng()
+
+	de, err := NewDngEditor(original)
+	log.PanicIf(err)
+
+	err = de.Set(exifcommon.IfdPathStandard, "Make", "Nikon Corporation")
+	log.PanicIf(err)
+
+	err = de.Set(exifcommon.IfdPathStandardExif, "DateTimeOriginal", "2020:01:02 03:04:05")
+	log.PanicIf(err)
+
+	err = de.Set(exifcommon.IfdPathStandardGps, "GPSLatitudeRef", "N")
+	log.PanicIf(err)
+
+	updated, err := de.Encode()
+	log.PanicIf(err)
+
+	if bytes.Equal(updated[stripOffset:stripOffset+uint32(len(testDngPixels))], testDngPixels) != true {
+		t.Fatalf("Pixels moved or changed.")
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "Make"); value != "Nikon Corporation" {
+		t.Fatalf("Make not correct: [%s]", value)
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandardExif, "DateTimeOriginal"); value != "2020:01:02 03:04:05" {
+		t.Fatalf("DateTimeOriginal not correct: [%s]", value)
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandardGps, "GPSLatitudeRef"); value != "N" {
+		t.Fatalf("GPSLatitudeRef not correct: [%s]", value)
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), updated)
+	log.PanicIf(err)
+
+	subIfds, err := getSubIfds(index)
+	log.PanicIf(err)
+
+	if len(subIfds) != 1 {
+		t.Fatalf("SubIFD not found after editing: %v", subIfds)
+	}
+
+	values := readRawIfdUints(updated, subIfds[0].entries[1], subIfds[0].byteOrder)
+	if len(values) != 1 || values[0] != stripOffset {
+		t.Fatalf("StripOffsets changed: %v", values)
+	}
+}
+
+func TestDngEditor_Protected(t *testing.T) {
+	original, _ := getTestDng()
+
+	de, err := NewDngEditor(original)
+	log.PanicIf(err)
+
+	err = de.Set(exifcommon.IfdPathStandard, "StripOffsets", []uint32{0})
+	if err != ErrDngTagProtected {
+		t.Fatalf("Expected ErrDngTagProtected: %v", err)
+	}
+
+	err = de.Delete(exifcommon.IfdPathStandard, "DNGVersion")
+	if err != ErrDngTagProtected {
+		t.Fatalf("Expected ErrDngTagProtected: %v", err)
+	}
+
+	err = de.Set("IFD1", "Make", "Canon")
+	if err == nil {
+		t.Fatalf("Expected error for sibling IFD.")
+	}
+}
+
+func TestDngEditor_Delete(t *testing.T) {
+	original, _ := getTestDng()
+
+	de, err := NewDngEditor(original)
+	log.PanicIf(err)
+
+	err = de.Delete(exifcommon.IfdPathStandard, "Make")
+	log.PanicIf(err)
+
+	err = de.Delete(exifcommon.IfdPathStandard, "Make")
+	if err != ErrTagNotFound {
+		t.Fatalf("Expected ErrTagNotFound: %v", err)
+	}
+
+	err = de.Delete(exifcommon.IfdPathStandardGps, "GPSLatitudeRef")
+	if err != ErrTagNotFound {
+		t.Fatalf("Expected ErrTagNotFound for missing IFD: %v", err)
+	}
+
+	updated, err := de.Encode()
+	log.PanicIf(err)
+
+	if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "Make"); value != "" {
+		t.Fatalf("Make not deleted: [%s]", value)
+	}
+}
+
+func TestNewDngEditor_NotDng(t *testing.T) {
+	_, err := NewDngEditor(getTestExifData())
+	if err != ErrNotDng {
+		t.Fatalf("Expected ErrNotDng: %v", err)
+	}
+}
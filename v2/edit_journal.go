@@ -0,0 +1,421 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	editJournalLogger = log.NewLogger("exif.edit_journal")
+)
+
+var (
+	// ErrJournalFileChanged means that a file was changed by something else
+	// after it was journaled, so rolling it back would lose that change.
+	ErrJournalFileChanged = errors.New("file changed since it was journaled")
+
+	// ErrJournalBackupNotValid means that a journal's backup of a file is
+	// missing or doesn't have the original content.
+	ErrJournalBackupNotValid = errors.New("journal backup not valid")
+)
+
+// JournalEntry records one change to a file.
+type JournalEntry struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+
+	// OriginalSha256 and UpdatedSha256 are the hex-encoded SHA-256 of the
+	// file before and after the change.
+	OriginalSha256 string `json:"original_sha256"`
+	UpdatedSha256  string `json:"updated_sha256"`
+
+	// ModTime is the modification time of the file before the change.
+	ModTime time.Time `json:"mtime"`
+
+	// BackupPath is where the original file was copied.
+	BackupPath string `json:"backup_path"`
+
+	// Changes are the tags that changed (see `DiffMetadata()`).
+	Changes []TagChange `json:"changes,omitempty"`
+}
+
+// String returns a descriptive string.
+func (je JournalEntry) String() string {
+	return fmt.Sprintf("JournalEntry<NAME=[%s] TIME=[%s] ORIGINAL-SHA256=[%s] UPDATED-SHA256=[%s] BACKUP-PATH=[%s] CHANGES=(%d)>", je.Name, je.Time, je.OriginalSha256, je.UpdatedSha256, je.BackupPath, len(je.Changes))
+}
+
+// EditJournal records the changes of a batch edit so that the batch can be
+// undone with `Rollback()`, whether it finished or was interrupted. Before a
+// file is rewritten, the original is copied to the backup directory (named
+// by its SHA-256, so identical files are only copied once) and an entry is
+// appended to the journal as a JSON line and synced to disk. The file is
+// replaced with `RewriteFile()`, so it's always either the original or the
+// update. It's safe for concurrent use.
+type EditJournal struct {
+	f               *os.File
+	backupDirectory string
+
+	m       sync.Mutex
+	entries []JournalEntry
+	encoder *json.Encoder
+}
+
+// editJournalBackupDirectory returns the default backup directory of a
+// journal.
+func editJournalBackupDirectory(journalFilepath string) string {
+	return journalFilepath + ".backups"
+}
+
+// readJournalEntries reads the complete entries of a journal and returns the
+// offset after the last of them. A line that's incomplete or invalid, as the
+// last one might be if the batch was interrupted while writing it, ends the
+// journal.
+func readJournalEntries(r io.Reader, journalFilepath string) (entries []JournalEntry, offset int64, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	entries = make([]JournalEntry, 0)
+
+	br := bufio.NewReader(r)
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+
+		log.PanicIf(err)
+
+		var je JournalEntry
+
+		if err := json.Unmarshal(line, &je); err != nil {
+			editJournalLogger.Warningf(nil, "Ignoring invalid line in journal [%s] at offset (%d): %s", journalFilepath, offset, err)
+			break
+		}
+
+		entries = append(entries, je)
+		offset += int64(len(line))
+	}
+
+	return entries, offset, nil
+}
+
+// OpenEditJournal opens the journal at the given path, loading what was
+// recorded by earlier runs, or creates it. The originals are copied to the
+// backup directory, which defaults to the journal's path with ".backups"
+// appended.
+func OpenEditJournal(filepath, backupDirectory string) (ej *EditJournal, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if backupDirectory == "" {
+		backupDirectory = editJournalBackupDirectory(filepath)
+	}
+
+	f, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE, 0644)
+	log.PanicIf(err)
+
+	entries, offset, err := readJournalEntries(f, filepath)
+	if err != nil {
+		f.Close()
+		log.Panic(err)
+	}
+
+	// Anything after the last complete entry is overwritten.
+
+	err = f.Truncate(offset)
+	log.PanicIf(err)
+
+	_, err = f.Seek(offset, io.SeekStart)
+	log.PanicIf(err)
+
+	ej = &EditJournal{
+		f:               f,
+		backupDirectory: backupDirectory,
+		entries:         entries,
+		encoder:         json.NewEncoder(f),
+	}
+
+	return ej, nil
+}
+
+// Entries returns the entries recorded so far, in order.
+func (ej *EditJournal) Entries() []JournalEntry {
+	ej.m.Lock()
+	defer ej.m.Unlock()
+
+	entries := make([]JournalEntry, len(ej.entries))
+	copy(entries, ej.entries)
+
+	return entries
+}
+
+// backup copies the data to the backup directory, unless it's already there,
+// and returns where.
+func (ej *EditJournal) backup(data []byte, digest string) (backupPath string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	backupPath = path.Join(ej.backupDirectory, digest[:2], digest)
+
+	if _, err := os.Stat(backupPath); err == nil {
+		return backupPath, nil
+	} else if os.IsNotExist(err) == false {
+		log.Panic(err)
+	}
+
+	err = os.MkdirAll(path.Dir(backupPath), 0755)
+	log.PanicIf(err)
+
+	err = replaceFile(backupPath, data)
+	log.PanicIf(err)
+
+	return backupPath, nil
+}
+
+// Rewrite replaces the file's data with the update (see `RewriteFile()`),
+// journaling it first. Nothing is done if the update is the same as the data.
+func (ej *EditJournal) Rewrite(filepath string, data, updated []byte, opts RewriteOptions) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if bytes.Equal(data, updated) == true {
+		return nil
+	}
+
+	fi, err := os.Stat(filepath)
+	log.PanicIf(err)
+
+	md, err := DiffMetadata(data, updated)
+	log.PanicIf(err)
+
+	originalSha256 := sha256.Sum256(data)
+	updatedSha256 := sha256.Sum256(updated)
+
+	je := JournalEntry{
+		Name:           filepath,
+		Time:           time.Now().UTC(),
+		OriginalSha256: hex.EncodeToString(originalSha256[:]),
+		UpdatedSha256:  hex.EncodeToString(updatedSha256[:]),
+		ModTime:        fi.ModTime(),
+		Changes:        md.Tags,
+	}
+
+	je.BackupPath, err = ej.backup(data, je.OriginalSha256)
+	log.PanicIf(err)
+
+	err = ej.record(je)
+	log.PanicIf(err)
+
+	err = RewriteFile(filepath, updated, opts)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// record appends the entry to the journal and syncs it, so that the entry is
+// durable before the file is changed.
+func (ej *EditJournal) record(je JournalEntry) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ej.m.Lock()
+	defer ej.m.Unlock()
+
+	err = ej.encoder.Encode(je)
+	log.PanicIf(err)
+
+	err = ej.f.Sync()
+	log.PanicIf(err)
+
+	ej.entries = append(ej.entries, je)
+
+	return nil
+}
+
+// EditFile applies the edit to the file and journals the change (see
+// `Rewrite()`). Any of the functions in this package that return an edited
+// copy of a file (e.g. `SetJpegRights()` or `Pipeline.Apply()`) can be given
+// as the edit.
+func (ej *EditJournal) EditFile(filepath string, edit func(data []byte) (updated []byte, err error)) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	updated, err := edit(data)
+	log.PanicIf(err)
+
+	err = ej.Rewrite(filepath, data, updated, RewriteOptions{})
+	log.PanicIf(err)
+
+	return nil
+}
+
+// Close closes the journal.
+func (ej *EditJournal) Close() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ej.m.Lock()
+	defer ej.m.Unlock()
+
+	err = ej.f.Close()
+	log.PanicIf(err)
+
+	return nil
+}
+
+// RollbackResult is the outcome of rolling back one journal entry.
+type RollbackResult struct {
+	Entry JournalEntry
+
+	// Restored is true if the file was put back. It's false if the file was
+	// never changed (the batch was interrupted before it got to it) or if
+	// there was an error.
+	Restored bool
+
+	Err error
+}
+
+// String returns a descriptive string.
+func (rr RollbackResult) String() string {
+	return fmt.Sprintf("RollbackResult<NAME=[%s] RESTORED=[%v] ERR=[%v]>", rr.Entry.Name, rr.Restored, rr.Err)
+}
+
+// Rollback undoes the changes recorded in the journal at the given path,
+// latest first, so that a file changed more than once ends up as it was
+// before the first change. Each file is restored from its backup along with
+// its modification time. A file that's been changed by something else since
+// it was journaled is left alone and given `ErrJournalFileChanged`. A failure
+// to restore one file doesn't stop the others; check the `Err` of each
+// result. The journal and the backups are kept, and rolling back again
+// doesn't change anything.
+func Rollback(journalFilepath string) (results []RollbackResult, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	f, err := os.Open(journalFilepath)
+	log.PanicIf(err)
+
+	defer f.Close()
+
+	entries, _, err := readJournalEntries(f, journalFilepath)
+	log.PanicIf(err)
+
+	// firstOriginals is the SHA-256 of each file before its first change. A
+	// file that's back to it has already been rolled back.
+	firstOriginals := make(map[string]string)
+	for _, je := range entries {
+		if _, found := firstOriginals[je.Name]; found == false {
+			firstOriginals[je.Name] = je.OriginalSha256
+		}
+	}
+
+	results = make([]RollbackResult, 0, len(entries))
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		je := entries[i]
+
+		restored, err := rollbackEntry(je, firstOriginals[je.Name])
+		if err != nil {
+			editJournalLogger.Warningf(nil, "Could not roll back [%s]: %s", je.Name, err)
+		}
+
+		rr := RollbackResult{
+			Entry:    je,
+			Restored: restored,
+			Err:      err,
+		}
+
+		results = append(results, rr)
+	}
+
+	return results, nil
+}
+
+// rollbackEntry puts the file back as it was before the entry's change, if
+// it was made and hasn't already been rolled back.
+func rollbackEntry(je JournalEntry, firstOriginalSha256 string) (restored bool, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(je.Name)
+	log.PanicIf(err)
+
+	digest := sha256.Sum256(data)
+	currentSha256 := hex.EncodeToString(digest[:])
+
+	if currentSha256 == je.OriginalSha256 || currentSha256 == firstOriginalSha256 {
+		return false, nil
+	} else if currentSha256 != je.UpdatedSha256 {
+		return false, ErrJournalFileChanged
+	}
+
+	original, err := ioutil.ReadFile(je.BackupPath)
+	if os.IsNotExist(err) == true {
+		return false, ErrJournalBackupNotValid
+	}
+
+	log.PanicIf(err)
+
+	digest = sha256.Sum256(original)
+	if hex.EncodeToString(digest[:]) != je.OriginalSha256 {
+		return false, ErrJournalBackupNotValid
+	}
+
+	err = RewriteFile(je.Name, original, RewriteOptions{})
+	log.PanicIf(err)
+
+	if je.ModTime.IsZero() == false {
+		err = os.Chtimes(je.Name, je.ModTime, je.ModTime)
+		log.PanicIf(err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,241 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"path"
+	"testing"
+	"time"
+
+	"io/ioutil"
+	"os"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func setTestJpegArtist(data []byte) (updated []byte, err error) {
+	return SetJpegRights(data, []string{"Jane Doe"}, "")
+}
+
+func TestEditJournal_Rollback(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	original := getTestJpegWithExif()
+
+	filepath1 := writeTestFile(tempPath, "1.jpg", original)
+	filepath2 := writeTestFile(tempPath, "2.jpg", original)
+
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err = os.Chtimes(filepath1, modTime, modTime)
+	log.PanicIf(err)
+
+	journalFilepath := path.Join(tempPath, "journal.jsonl")
+
+	ej, err := OpenEditJournal(journalFilepath, "")
+	log.PanicIf(err)
+
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpSetRights, Creators: []string{"Jane Doe"}, Copyright: "(c) Jane Doe"},
+		},
+		MtimeFromDateTimeOriginal: true,
+		Journal:                   ej,
+	}
+
+	for _, pr := range p.ApplyFiles([]string{filepath1, filepath2}) {
+		log.PanicIf(pr.Err)
+	}
+
+	// The first file is changed again, outside of the pipeline.
+	err = ej.EditFile(filepath1, func(data []byte) (updated []byte, err error) {
+		return SetJpegRights(data, []string{"John Doe"}, "")
+	})
+
+	log.PanicIf(err)
+
+	err = ej.Close()
+	log.PanicIf(err)
+
+	entries := ej.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("Entry count not correct: (%d)", len(entries))
+	} else if entries[0].Name != filepath1 || entries[1].Name != filepath2 {
+		t.Fatalf("Entries not correct: %v", entries)
+	} else if entries[2].OriginalSha256 != entries[0].UpdatedSha256 {
+		t.Fatalf("Second change should follow the first: %v", entries)
+	} else if len(entries[0].Changes) == 0 {
+		t.Fatalf("Changes not recorded: %s", entries[0])
+	} else if entries[0].BackupPath != entries[1].BackupPath {
+		t.Fatalf("Identical originals should share a backup: %v", entries)
+	}
+
+	results, err := Rollback(journalFilepath)
+	log.PanicIf(err)
+
+	if len(results) != 3 {
+		t.Fatalf("Result count not correct: (%d)", len(results))
+	}
+
+	for _, rr := range results {
+		if rr.Err != nil || rr.Restored != true {
+			t.Fatalf("Entry not rolled back: %s", rr)
+		}
+	}
+
+	for _, filepath := range []string{filepath1, filepath2} {
+		data, err := ioutil.ReadFile(filepath)
+		log.PanicIf(err)
+
+		if bytes.Equal(data, original) != true {
+			t.Fatalf("File not restored: [%s]", filepath)
+		}
+	}
+
+	fi, err := os.Stat(filepath1)
+	log.PanicIf(err)
+
+	if fi.ModTime().Equal(modTime) != true {
+		t.Fatalf("Modification time not restored: [%s]", fi.ModTime())
+	}
+
+	// Rolling back again doesn't do anything.
+	results, err = Rollback(journalFilepath)
+	log.PanicIf(err)
+
+	for _, rr := range results {
+		if rr.Err != nil || rr.Restored != false {
+			t.Fatalf("Entry should already be rolled back: %s", rr)
+		}
+	}
+}
+
+func TestEditJournal_Rollback_Changed(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := writeTestFile(tempPath, "1.jpg", getTestJpegWithExif())
+	journalFilepath := path.Join(tempPath, "journal.jsonl")
+
+	ej, err := OpenEditJournal(journalFilepath, path.Join(tempPath, "backups"))
+	log.PanicIf(err)
+
+	defer ej.Close()
+
+	err = ej.EditFile(filepath, setTestJpegArtist)
+	log.PanicIf(err)
+
+	changed, err := SetJpegRights(getTestJpegWithExif(), []string{"John Doe"}, "")
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(filepath, changed, 0644)
+	log.PanicIf(err)
+
+	results, err := Rollback(journalFilepath)
+	log.PanicIf(err)
+
+	if len(results) != 1 || results[0].Err != ErrJournalFileChanged {
+		t.Fatalf("Expected ErrJournalFileChanged: %v", results)
+	}
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	if bytes.Equal(data, changed) != true {
+		t.Fatalf("Changed file should be left alone.")
+	}
+
+	// A backup that's been tampered with isn't restored.
+	err = ioutil.WriteFile(filepath, changed, 0644)
+	log.PanicIf(err)
+
+	updated, err := setTestJpegArtist(getTestJpegWithExif())
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(filepath, updated, 0644)
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(ej.Entries()[0].BackupPath, []byte("corrupt"), 0644)
+	log.PanicIf(err)
+
+	results, err = Rollback(journalFilepath)
+	log.PanicIf(err)
+
+	if results[0].Err != ErrJournalBackupNotValid {
+		t.Fatalf("Expected ErrJournalBackupNotValid: %v", results)
+	}
+}
+
+func TestOpenEditJournal_Interrupted(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	original := getTestJpegWithExif()
+
+	filepath1 := writeTestFile(tempPath, "1.jpg", original)
+	filepath2 := writeTestFile(tempPath, "2.jpg", original)
+
+	journalFilepath := path.Join(tempPath, "journal.jsonl")
+
+	ej, err := OpenEditJournal(journalFilepath, "")
+	log.PanicIf(err)
+
+	err = ej.EditFile(filepath1, setTestJpegArtist)
+	log.PanicIf(err)
+
+	err = ej.EditFile(filepath2, setTestJpegArtist)
+	log.PanicIf(err)
+
+	err = ej.Close()
+	log.PanicIf(err)
+
+	// The batch was interrupted after the second file was journaled but
+	// before it was written, and while the next entry was being written.
+	err = ioutil.WriteFile(filepath2, original, 0644)
+	log.PanicIf(err)
+
+	f, err := os.OpenFile(journalFilepath, os.O_APPEND|os.O_WRONLY, 0644)
+	log.PanicIf(err)
+
+	_, err = f.Write([]byte(`{"name":"3.j`))
+	log.PanicIf(err)
+
+	f.Close()
+
+	ej, err = OpenEditJournal(journalFilepath, "")
+	log.PanicIf(err)
+
+	if len(ej.Entries()) != 2 {
+		t.Fatalf("Incomplete entry should be ignored: %v", ej.Entries())
+	}
+
+	err = ej.Close()
+	log.PanicIf(err)
+
+	results, err := Rollback(journalFilepath)
+	log.PanicIf(err)
+
+	if len(results) != 2 {
+		t.Fatalf("Result count not correct: (%d)", len(results))
+	} else if results[0].Entry.Name != filepath2 || results[0].Restored != false || results[0].Err != nil {
+		t.Fatalf("Unwritten file should be skipped: %s", results[0])
+	} else if results[1].Entry.Name != filepath1 || results[1].Restored != true || results[1].Err != nil {
+		t.Fatalf("Written file should be restored: %s", results[1])
+	}
+
+	data, err := ioutil.ReadFile(filepath1)
+	log.PanicIf(err)
+
+	if bytes.Equal(data, original) != true {
+		t.Fatalf("File not restored.")
+	}
+}
@@ -0,0 +1,332 @@
+package exif
+
+import (
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// exifEdit is one change made through an `ExifEditor`, kept so that it can be
+// replayed on an `ExifPatcher`.
+type exifEdit struct {
+	fqIfdPath string
+	tagId     uint16
+	value     interface{}
+
+	deleteTag bool
+	deleteIfd bool
+}
+
+// ExifEditor changes the tags of an existing EXIF block (or builds a new one)
+// and writes it back out. It's an `IfdBuilder` seeded from a parse, with tags
+// and IFDs addressed by their fully-qualified paths (e.g. "IFD/Exif" or
+// "IFD/GPSInfo"), so that IFDs are created as needed and the chain of IFD0,
+// Exif, GPS, and Interoperability IFDs is kept consistent when encoded.
+//
+// `Encode()` produces a new, compact EXIF block and `EncodeJpeg()` puts it in
+// a JPEG. A TIFF file is its own EXIF block but also has image data that the
+// builder doesn't carry, so `EncodeTiff()` instead replays the edits on the
+// original file with an `ExifPatcher`.
+type ExifEditor struct {
+	rootIb *IfdBuilder
+	edits  []exifEdit
+}
+
+// NewExifEditor parses a raw EXIF block (starting with the TIFF header), or a
+// whole TIFF file. An empty block starts a new, empty EXIF.
+func NewExifEditor(rawExif []byte) (ee *ExifEditor, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ee = &ExifEditor{
+		edits: make([]exifEdit, 0),
+	}
+
+	if len(rawExif) == 0 {
+		ee.rootIb = NewIfdBuilder(NewIfdMappingWithStandard(), NewTagIndex(), exifcommon.IfdPathStandard, exifcommon.EncodeDefaultByteOrder)
+		return ee, nil
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	ee.rootIb = NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	return ee, nil
+}
+
+// NewExifEditorFromJpeg parses the EXIF of a JPEG, or starts a new one if it
+// doesn't have any.
+func NewExifEditorFromJpeg(data []byte) (ee *ExifEditor, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == ErrNoExif {
+		rawExif = nil
+	} else {
+		log.PanicIf(err)
+	}
+
+	ee, err = NewExifEditor(rawExif)
+	log.PanicIf(err)
+
+	return ee, nil
+}
+
+// RootIb returns the builder for IFD0, for changes that the editor doesn't
+// cover. Those aren't replayed by `EncodeTiff()`.
+func (ee *ExifEditor) RootIb() *IfdBuilder {
+	return ee.rootIb
+}
+
+// findIb returns the builder for the IFD with the given fully-qualified path
+// or nil if there isn't one.
+func (ee *ExifEditor) findIb(fqIfdPath string) *IfdBuilder {
+	for thisIb := ee.rootIb; thisIb != nil; thisIb = thisIb.nextIb {
+		if ib := findChildIb(thisIb, fqIfdPath); ib != nil {
+			return ib
+		}
+	}
+
+	return nil
+}
+
+// findChildIb searches the given builder and its children.
+func findChildIb(ib *IfdBuilder, fqIfdPath string) *IfdBuilder {
+	if ib.fqIfdPath == fqIfdPath {
+		return ib
+	}
+
+	for _, bt := range ib.tags {
+		if bt.value.IsIb() == false {
+			continue
+		}
+
+		if found := findChildIb(bt.value.Ib(), fqIfdPath); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// tagId returns the ID of the named tag in the given IFD.
+func (ee *ExifEditor) tagId(fqIfdPath, tagName string) (tagId uint16, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifdPath, err := ee.rootIb.ifdMapping.StripPathPhraseIndices(fqIfdPath)
+	log.PanicIf(err)
+
+	it, err := ee.rootIb.tagIndex.GetWithName(ifdPath, tagName)
+	log.PanicIf(err)
+
+	return it.Id, nil
+}
+
+// Set changes or adds a tag, adding the IFD if it's missing. Values are given
+// as for `IfdBuilder.SetStandard()`.
+func (ee *ExifEditor) Set(fqIfdPath, tagName string, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tagId, err := ee.tagId(fqIfdPath, tagName)
+	log.PanicIf(err)
+
+	ib, err := GetOrCreateIbFromRootIb(ee.rootIb, fqIfdPath)
+	log.PanicIf(err)
+
+	err = ib.SetStandard(tagId, value)
+	log.PanicIf(err)
+
+	ee.edits = append(ee.edits, exifEdit{fqIfdPath: fqIfdPath, tagId: tagId, value: value})
+
+	return nil
+}
+
+// Delete removes a tag. `ErrTagNotFound` is returned if it's not present.
+func (ee *ExifEditor) Delete(fqIfdPath, tagName string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tagId, err := ee.tagId(fqIfdPath, tagName)
+	log.PanicIf(err)
+
+	ib := ee.findIb(fqIfdPath)
+	if ib == nil {
+		return ErrTagNotFound
+	}
+
+	n, err := ib.DeleteAll(tagId)
+	log.PanicIf(err)
+
+	if n == 0 {
+		return ErrTagNotFound
+	}
+
+	ee.edits = append(ee.edits, exifEdit{fqIfdPath: fqIfdPath, tagId: tagId, deleteTag: true})
+
+	return nil
+}
+
+// DeleteIfd removes a child IFD (e.g. "IFD/GPSInfo" to strip the location)
+// along with its own child IFDs. Nothing is done if it's not present.
+func (ee *ExifEditor) DeleteIfd(fqIfdPath string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ib := ee.findIb(fqIfdPath)
+	if ib == nil {
+		return nil
+	} else if ib.ifdTagId == 0 {
+		log.Panicf("only child IFDs can be deleted: [%s]", fqIfdPath)
+	}
+
+	for thisIb := ee.rootIb; thisIb != nil; thisIb = thisIb.nextIb {
+		parentIb := findParentIb(thisIb, ib)
+		if parentIb == nil {
+			continue
+		}
+
+		_, err := parentIb.DeleteAll(ib.ifdTagId)
+		log.PanicIf(err)
+
+		break
+	}
+
+	ee.edits = append(ee.edits, exifEdit{fqIfdPath: fqIfdPath, deleteIfd: true})
+
+	return nil
+}
+
+// findParentIb returns the builder (under the given one) that has the child
+// builder or nil.
+func findParentIb(ib *IfdBuilder, childIb *IfdBuilder) *IfdBuilder {
+	for _, bt := range ib.tags {
+		if bt.value.IsIb() == false {
+			continue
+		}
+
+		if bt.value.Ib() == childIb {
+			return ib
+		} else if parentIb := findParentIb(bt.value.Ib(), childIb); parentIb != nil {
+			return parentIb
+		}
+	}
+
+	return nil
+}
+
+// Encode returns the new EXIF block, starting with the TIFF header.
+func (ee *ExifEditor) Encode() (rawExif []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rawExif, err = NewIfdByteEncoder().EncodeToExif(ee.rootIb)
+	log.PanicIf(err)
+
+	return rawExif, nil
+}
+
+// EncodeJpeg returns a copy of the JPEG with its EXIF segment replaced by (or,
+// if it didn't have one, added with) the new EXIF block.
+func (ee *ExifEditor) EncodeJpeg(data []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rawExif, err := ee.Encode()
+	log.PanicIf(err)
+
+	updated, err = SetJpegExif(data, rawExif)
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// EncodeTiff returns a copy of the TIFF file (normally the one that the
+// editor was created from) with the edits applied in place (see
+// `ExifPatcher`), so that the image data and its offsets are kept.
+func (ee *ExifEditor) EncodeTiff(data []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ep, err := NewExifPatcher(data, ee.rootIb.ifdMapping, ee.rootIb.tagIndex)
+	log.PanicIf(err)
+
+	for _, edit := range ee.edits {
+		if edit.deleteIfd == true {
+			err := ep.DeleteChildIfd(edit.fqIfdPath)
+			log.PanicIf(err)
+		} else if edit.deleteTag == true {
+			if ep.HasIfd(edit.fqIfdPath) == false {
+				continue
+			}
+
+			err := ep.Delete(edit.fqIfdPath, edit.tagId)
+			if err != nil && err != ErrTagNotFound {
+				log.Panic(err)
+			}
+		} else {
+			err := addPatcherIfdLineage(ep, edit.fqIfdPath)
+			log.PanicIf(err)
+
+			err = ep.Set(edit.fqIfdPath, edit.tagId, edit.value)
+			log.PanicIf(err)
+		}
+	}
+
+	updated, err = ep.Encode()
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// addPatcherIfdLineage adds the given child IFD and any of its parents that
+// are missing.
+func addPatcherIfdLineage(ep *ExifPatcher, fqIfdPath string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for i := range fqIfdPath {
+		if fqIfdPath[i] == '/' {
+			err := ep.AddChildIfd(fqIfdPath[:i])
+			log.PanicIf(err)
+		}
+	}
+
+	err = ep.AddChildIfd(fqIfdPath)
+	log.PanicIf(err)
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestExifEditor_EncodeJpeg(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	data := exiftest.WrapJpeg(rawExif)
+
+	ee, err := NewExifEditorFromJpeg(data)
+	log.PanicIf(err)
+
+	err = ee.Set(exifcommon.IfdPathStandardExif, "DateTimeOriginal", "2021:02:03 04:05:06")
+	log.PanicIf(err)
+
+	err = ee.Set(exifcommon.IfdPathStandardExifIop, "InteroperabilityIndex", "R98")
+	log.PanicIf(err)
+
+	err = ee.Delete(exifcommon.IfdPathStandard, "Make")
+	log.PanicIf(err)
+
+	err = ee.DeleteIfd(exifcommon.IfdPathStandardGps)
+	log.PanicIf(err)
+
+	updated, err := ee.EncodeJpeg(data)
+	log.PanicIf(err)
+
+	updatedExif, err := SearchAndExtractExif(updated)
+	log.PanicIf(err)
+
+	eh, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), updatedExif)
+	log.PanicIf(err)
+
+	if eh.ByteOrder != binary.LittleEndian {
+		t.Fatalf("Byte-order not kept: %v", eh.ByteOrder)
+	} else if _, found := index.Lookup[exifcommon.IfdPathStandardGps]; found == true {
+		t.Fatalf("GPS IFD not deleted.")
+	} else if value := getPatchedTagString(updatedExif, exifcommon.IfdPathStandardExif, "DateTimeOriginal"); value != "2021:02:03 04:05:06" {
+		t.Fatalf("DateTimeOriginal not correct: [%s]", value)
+	} else if value := getPatchedTagString(updatedExif, exifcommon.IfdPathStandardExifIop, "InteroperabilityIndex"); value != "R98" {
+		t.Fatalf("InteroperabilityIndex not correct: [%s]", value)
+	} else if value := getPatchedTagString(updatedExif, exifcommon.IfdPathStandard, "Make"); value != "" {
+		t.Fatalf("Make not deleted: [%s]", value)
+	} else if value := getPatchedTagString(updatedExif, exifcommon.IfdPathStandard, "Model"); value != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not kept: [%s]", value)
+	}
+}
+
+func TestExifEditor_New(t *testing.T) {
+	ee, err := NewExifEditor(nil)
+	log.PanicIf(err)
+
+	err = ee.Set(exifcommon.IfdPathStandardGps, "GPSLatitudeRef", "N")
+	log.PanicIf(err)
+
+	rawExif, err := ee.Encode()
+	log.PanicIf(err)
+
+	if value := getPatchedTagString(rawExif, exifcommon.IfdPathStandardGps, "GPSLatitudeRef"); value != "N" {
+		t.Fatalf("GPSLatitudeRef not correct: [%s]", value)
+	}
+
+	// A JPEG without EXIF gets a new segment.
+	updated, err := ee.EncodeJpeg(getTestPipelineJpeg())
+	log.PanicIf(err)
+
+	if _, err := SearchAndExtractExif(updated); err != nil {
+		t.Fatalf("EXIF not added: %v", err)
+	}
+}
+
+func TestExifEditor_Delete_NotFound(t *testing.T) {
+	ee, err := NewExifEditor(nil)
+	log.PanicIf(err)
+
+	if err := ee.Delete(exifcommon.IfdPathStandard, "Make"); err != ErrTagNotFound {
+		t.Fatalf("Expected tag not found: %v", err)
+	} else if err := ee.Delete(exifcommon.IfdPathStandardGps, "GPSLatitudeRef"); err != ErrTagNotFound {
+		t.Fatalf("Expected tag not found for a missing IFD: %v", err)
+	} else if err := ee.DeleteIfd(exifcommon.IfdPathStandardGps); err != nil {
+		t.Fatalf("Deleting a missing IFD should do nothing: %v", err)
+	}
+}
+
+func TestExifEditor_EncodeTiff(t *testing.T) {
+	original, stripOffset := getTestTiff()
+
+	ee, err := NewExifEditor(original)
+	log.PanicIf(err)
+
+	err = ee.Set(exifcommon.IfdPathStandardExif, "DateTimeOriginal", "2021:02:03 04:05:06")
+	log.PanicIf(err)
+
+	err = ee.Set(exifcommon.IfdPathStandardGps, "GPSLatitudeRef", "N")
+	log.PanicIf(err)
+
+	err = ee.Delete(exifcommon.IfdPathStandard, "Make")
+	log.PanicIf(err)
+
+	err = ee.DeleteIfd(exifcommon.IfdPathStandardGps)
+	log.PanicIf(err)
+
+	updated, err := ee.EncodeTiff(original)
+	log.PanicIf(err)
+
+	if bytes.Equal(updated[stripOffset:stripOffset+uint32(len(testDngPixels))], testDngPixels) != true {
+		t.Fatalf("Strip was moved or changed.")
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), updated)
+	log.PanicIf(err)
+
+	if _, found := index.Lookup[exifcommon.IfdPathStandardGps]; found == true {
+		t.Fatalf("GPS IFD not deleted.")
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandardExif, "DateTimeOriginal"); value != "2021:02:03 04:05:06" {
+		t.Fatalf("DateTimeOriginal not correct: [%s]", value)
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "Make"); value != "" {
+		t.Fatalf("Make not deleted: [%s]", value)
+	}
+}
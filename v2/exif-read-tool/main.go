@@ -1,3 +1,6 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
 // This tool dumps EXIF information from images.
 //
 // Example command-line:
@@ -11,12 +14,43 @@
 //   IFD=[IfdIdentity<PARENT-NAME=[] NAME=[IFD]>] ID=(0x0112) NAME=[Orientation] COUNT=(1) TYPE=[SHORT] VALUE=[1]
 //   IFD=[IfdIdentity<PARENT-NAME=[] NAME=[IFD]>] ID=(0x011a) NAME=[XResolution] COUNT=(1) TYPE=[RATIONAL] VALUE=[72/1]
 //   ...
+//
+// Many files can be written to a sink (e.g. JSON Lines or CSV) instead:
+//
+//   exif-read-tool -sink csv -sink-target tags.csv -filepath <file-path> [<file-path> ...]
+//
+// or cataloged in a SQLite database (this requires building with the "sqlite"
+// tag, which links in a SQLite driver):
+//
+//   exif-read-tool -catalog photos.db -filepath <file-path> [<file-path> ...]
+//
+// Large runs can be made resumable with "-manifest <path>".
+//
+// The cost of parsing a file, with whatever other options are given, can be
+// measured by parsing it repeatedly:
+//
+//   exif-read-tool -bench 1000 -filepath <file-path>
+//
+// What this build supports (containers, tag table, maker-note decoders, and
+// optional features) can be printed without a file:
+//
+//   exif-read-tool -capabilities
+//
+// A file that crashes the parser can be reduced to a small reproducer, which
+// is written to the given directory along with a JSON report of the crash:
+//
+//   exif-read-tool -minimize <directory> -filepath <file-path>
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	"encoding/json"
 	"io/ioutil"
@@ -36,6 +70,25 @@ var (
 	filepathArg     = ""
 	printAsJsonArg  = false
 	printLoggingArg = false
+	heuristicArg    = false
+
+	// These control how the values are formatted.
+	decimalArg   = false
+	precisionArg = 0
+	unitsArg     = false
+	maxItemsArg  = 0
+
+	sinkArg       = ""
+	sinkTargetArg = ""
+	catalogArg    = ""
+	manifestArg   = ""
+
+	searchArg      = ""
+	searchIndexArg = ""
+
+	benchArg        = 0
+	capabilitiesArg = false
+	minimizeArg     = ""
 )
 
 type IfdEntry struct {
@@ -63,9 +116,28 @@ func main() {
 	flag.StringVar(&filepathArg, "filepath", "", "File-path of image")
 	flag.BoolVar(&printAsJsonArg, "json", false, "Print JSON")
 	flag.BoolVar(&printLoggingArg, "verbose", false, "Print logging")
+	flag.BoolVar(&heuristicArg, "heuristic", false, "Find the EXIF in files of unknown formats by scoring every TIFF header")
+	flag.BoolVar(&decimalArg, "decimal", false, "Print rationals as decimals")
+	flag.IntVar(&precisionArg, "precision", 0, "Decimal places of decimal rationals (zero for as many as needed)")
+	flag.BoolVar(&unitsArg, "units", false, "Print the units of values that have one")
+	flag.IntVar(&maxItemsArg, "max-items", 0, "Print this many items of lists rather than just the first")
+	flag.StringVar(&sinkArg, "sink", "", fmt.Sprintf("Write the file-path and any other file-paths given as arguments to this sink (%s)", strings.Join(exif.SinkNames(), ", ")))
+	flag.StringVar(&sinkTargetArg, "sink-target", "", "Where the sink writes to (STDOUT by default for the JSON and CSV sinks)")
+	flag.StringVar(&manifestArg, "manifest", "", "Record the files written to the sink in this manifest and skip the ones recorded by an earlier run that haven't changed")
+	flag.IntVar(&benchArg, "bench", 0, "Parse the file this many times and report the throughput, the allocations, and the time spent in each phase rather than the tags")
+	flag.BoolVar(&capabilitiesArg, "capabilities", false, "Print what this build supports rather than the tags of a file")
+	flag.StringVar(&minimizeArg, "minimize", "", "If parsing the file crashes, write a minimized reproducer and a report of the crash to this directory")
+	flag.StringVar(&catalogArg, "catalog", "", "Write the file-path and any other file-paths given as arguments to the SQLite catalog at this path (the same as \"-sink sqlite -sink-target <path>\")")
+	flag.StringVar(&searchArg, "search", "", "Print the file-path and any other file-paths given as arguments whose capture conditions match this query (e.g. \"f/1.4 85mm iso:100-800 camera:canon date:2020\")")
+	flag.StringVar(&searchIndexArg, "search-index", "", "Search the capture index saved in this file rather than parsing the files, saving it there first if it doesn't exist")
 
 	flag.Parse()
 
+	if capabilitiesArg == true {
+		printCapabilities(exif.Capabilities())
+		return
+	}
+
 	if filepathArg == "" {
 		fmt.Printf("Please provide a file-path for an image.\n")
 		os.Exit(1)
@@ -81,13 +153,78 @@ func main() {
 		log.LoadConfiguration(scp)
 	}
 
+	if catalogArg != "" {
+		sinkArg = "sqlite"
+		sinkTargetArg = catalogArg
+	}
+
+	if searchArg != "" {
+		filepaths := append([]string{filepathArg}, flag.Args()...)
+
+		err := searchFiles(filepaths)
+		log.PanicIf(err)
+
+		return
+	}
+
+	if sinkArg != "" {
+		filepaths := append([]string{filepathArg}, flag.Args()...)
+
+		err := writeToSink(filepaths)
+		log.PanicIf(err)
+
+		return
+	}
+
 	f, err := os.Open(filepathArg)
 	log.PanicIf(err)
 
 	data, err := ioutil.ReadAll(f)
 	log.PanicIf(err)
 
-	rawExif, err := exif.SearchAndExtractExif(data)
+	if minimizeArg != "" {
+		cr, err := exif.MinimizeFuzzFailure(data, exif.MinimizeOptions{})
+		if err == exif.ErrNoFuzzFailure {
+			fmt.Printf("The file doesn't crash the parser.\n")
+			return
+		}
+
+		log.PanicIf(err)
+
+		reproducerFilepath, reportFilepath, err := cr.WriteFiles(minimizeArg)
+		log.PanicIf(err)
+
+		fmt.Printf("%s\n", cr.Message)
+		fmt.Printf("Minimized from (%d) to (%d) bytes in (%d) attempts.\n", cr.OriginalSize, cr.MinimizedSize, cr.Attempts)
+		fmt.Printf("Reproducer: %s\n", reproducerFilepath)
+		fmt.Printf("Report: %s\n", reportFilepath)
+
+		return
+	}
+
+	if benchArg > 0 {
+		report, err := runBenchmark(data, benchArg)
+		if err != nil {
+			if log.Is(err, exif.ErrNoExif) == true {
+				fmt.Printf("No EXIF data.\n")
+				os.Exit(1)
+			}
+
+			log.Panic(err)
+		}
+
+		printBenchmark(report)
+
+		return
+	}
+
+	var rawExif []byte
+	if heuristicArg == true {
+		rawExif, err = exif.SearchAndExtractExifHeuristically(data)
+	} else {
+		rawExif, err = exif.SearchAndExtractExif(data)
+	}
+
 	if err != nil {
 		if err == exif.ErrNoExif {
 			fmt.Printf("No EXIF data.\n")
@@ -140,7 +277,14 @@ func main() {
 			log.Panic(err)
 		}
 
-		valueString, err := ite.FormatFirst()
+		fo := exifcommon.FormatOptions{
+			RationalsAsDecimal: decimalArg,
+			Precision:          precisionArg,
+			Units:              unitsArg,
+			MaxItems:           maxItemsArg,
+		}
+
+		valueString, err := ite.FormatWithOptions(maxItemsArg == 0, fo)
 		log.PanicIf(err)
 
 		entry := IfdEntry{
@@ -175,3 +319,309 @@ func main() {
 		}
 	}
 }
+
+// writeToSink parses the files and writes them to the sink.
+func writeToSink(filepaths []string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	sink, err := exif.NewSink(sinkArg, sinkTargetArg)
+	if err == exif.ErrSinkNotFound {
+		fmt.Printf("Sink not found: [%s]\n", sinkArg)
+		os.Exit(1)
+	}
+
+	log.PanicIf(err)
+
+	sources := make([]exif.ParseSource, len(filepaths))
+	for i, filepath := range filepaths {
+		sources[i] = exif.FileSource(filepath)
+	}
+
+	opts := exif.ParseManyOptions{
+		OnResult: sink.Emit,
+	}
+
+	if manifestArg != "" {
+		bm, err := exif.OpenBatchManifest(manifestArg, false)
+		log.PanicIf(err)
+
+		defer bm.Close()
+
+		sources, err = bm.Pending(sources)
+		log.PanicIf(err)
+
+		opts.OnResult = func(pr exif.ParseResult) (err error) {
+			err = sink.Emit(pr)
+			if err != nil {
+				return err
+			}
+
+			return bm.Record(pr)
+		}
+	}
+
+	err = sink.Start()
+	log.PanicIf(err)
+
+	defer sink.Close()
+
+	_, err = exif.ParseMany(context.Background(), sources, opts)
+	log.PanicIf(err)
+
+	err = sink.Close()
+	log.PanicIf(err)
+
+	return nil
+}
+
+// searchFiles prints the files whose capture conditions match the query.
+func searchFiles(filepaths []string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	cq, err := exif.ParseCaptureQuery(searchArg)
+	if log.Is(err, exif.ErrCaptureQueryNotValid) == true {
+		fmt.Printf("Search query not valid: [%s]\n", searchArg)
+		os.Exit(1)
+	}
+
+	log.PanicIf(err)
+
+	ci, err := loadCaptureIndex(filepaths)
+	log.PanicIf(err)
+
+	records := ci.Search(cq)
+
+	if printAsJsonArg == true {
+		data, err := json.MarshalIndent(records, "", "    ")
+		log.PanicIf(err)
+
+		fmt.Println(string(data))
+	} else {
+		for _, cr := range records {
+			fmt.Printf("%s\n", cr.Name)
+		}
+	}
+
+	return nil
+}
+
+// loadCaptureIndex returns the index saved in the search-index file or, if
+// there isn't one, indexes the files (and saves it there if a file was given).
+func loadCaptureIndex(filepaths []string) (ci *exif.CaptureIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if searchIndexArg != "" {
+		f, err := os.Open(searchIndexArg)
+		if err == nil {
+			defer f.Close()
+
+			ci, err := exif.LoadCaptureIndex(f)
+			log.PanicIf(err)
+
+			return ci, nil
+		} else if os.IsNotExist(err) == false {
+			log.Panic(err)
+		}
+	}
+
+	sources := make([]exif.ParseSource, len(filepaths))
+	for i, filepath := range filepaths {
+		sources[i] = exif.FileSource(filepath)
+	}
+
+	ci, err = exif.BuildCaptureIndex(context.Background(), sources, exif.ParseManyOptions{})
+	log.PanicIf(err)
+
+	if searchIndexArg != "" {
+		f, err := os.Create(searchIndexArg)
+		log.PanicIf(err)
+
+		defer f.Close()
+
+		err = ci.Save(f)
+		log.PanicIf(err)
+	}
+
+	return ci, nil
+}
+
+// benchPhase is the cost of one phase of the parse, summed over every
+// iteration.
+type benchPhase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+	Allocs   uint64        `json:"allocs"`
+	Bytes    uint64        `json:"bytes"`
+}
+
+// benchReport is the result of parsing a file repeatedly.
+type benchReport struct {
+	Iterations int           `json:"iterations"`
+	Size       int           `json:"size"`
+	Duration   time.Duration `json:"duration_ns"`
+	Phases     []benchPhase  `json:"phases"`
+}
+
+// runBenchmark parses the data the given number of times, timing the search
+// for the EXIF, the enumeration of the IFDs, and the decoding of every value
+// separately. Values that can't be decoded are passed over, as they are when
+// printing.
+func runBenchmark(data []byte, iterations int) (report benchReport, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	search := benchPhase{Name: "search"}
+	enumerate := benchPhase{Name: "enumerate"}
+	decode := benchPhase{Name: "decode"}
+
+	im := exif.NewIfdMappingWithStandard()
+	ti := exif.NewTagIndex()
+
+	for i := 0; i < iterations; i++ {
+		var rawExif []byte
+		measurePhase(&search, func() {
+			if heuristicArg == true {
+				rawExif, err = exif.SearchAndExtractExifHeuristically(data)
+			} else {
+				rawExif, err = exif.SearchAndExtractExif(data)
+			}
+		})
+
+		log.PanicIf(err)
+
+		var index exif.IfdIndex
+		measurePhase(&enumerate, func() {
+			_, index, err = exif.Collect(im, ti, rawExif)
+		})
+
+		log.PanicIf(err)
+
+		measurePhase(&decode, func() {
+			for _, ifd := range index.Ifds {
+				for _, ite := range ifd.Entries {
+					ite.Value()
+				}
+			}
+		})
+	}
+
+	report = benchReport{
+		Iterations: iterations,
+		Size:       len(data),
+		Duration:   search.Duration + enumerate.Duration + decode.Duration,
+		Phases:     []benchPhase{search, enumerate, decode},
+	}
+
+	// The hottest phase is first.
+	sort.SliceStable(report.Phases, func(i, j int) bool {
+		return report.Phases[i].Duration > report.Phases[j].Duration
+	})
+
+	return report, nil
+}
+
+// measurePhase runs the function and adds its time and allocations to the
+// phase. Reading the allocations isn't timed.
+func measurePhase(phase *benchPhase, f func()) {
+	before := new(runtime.MemStats)
+	runtime.ReadMemStats(before)
+
+	start := time.Now()
+	f()
+	phase.Duration += time.Since(start)
+
+	after := new(runtime.MemStats)
+	runtime.ReadMemStats(after)
+
+	phase.Allocs += after.Mallocs - before.Mallocs
+	phase.Bytes += after.TotalAlloc - before.TotalAlloc
+}
+
+// printBenchmark prints the report as text or, with "-json", as JSON.
+func printBenchmark(report benchReport) {
+	if printAsJsonArg == true {
+		data, err := json.MarshalIndent(report, "", "    ")
+		log.PanicIf(err)
+
+		fmt.Println(string(data))
+
+		return
+	}
+
+	seconds := report.Duration.Seconds()
+	if seconds == 0 {
+		seconds = 1e-9
+	}
+
+	iterations := uint64(report.Iterations)
+
+	var allocs, bytes uint64
+	for _, phase := range report.Phases {
+		allocs += phase.Allocs
+		bytes += phase.Bytes
+	}
+
+	fmt.Printf("Parsed (%d) times in %s: %.1f parses/s, %.2f MB/s\n", report.Iterations, report.Duration, float64(report.Iterations)/seconds, float64(report.Size)*float64(report.Iterations)/seconds/1e6)
+	fmt.Printf("Per parse: %s, (%d) allocations, (%d) bytes allocated\n", report.Duration/time.Duration(report.Iterations), allocs/iterations, bytes/iterations)
+
+	for _, phase := range report.Phases {
+		share := 0.0
+		if report.Duration > 0 {
+			share = float64(phase.Duration) / float64(report.Duration) * 100
+		}
+
+		fmt.Printf("PHASE=[%s] TIME=[%s] SHARE=(%.1f%%) ALLOCS=(%d) BYTES=(%d)\n", phase.Name, phase.Duration/time.Duration(report.Iterations), share, phase.Allocs/iterations, phase.Bytes/iterations)
+	}
+}
+
+// printCapabilities prints the report as text or, with "-json", as JSON.
+func printCapabilities(bc exif.BuildCapabilities) {
+	if printAsJsonArg == true {
+		data, err := json.MarshalIndent(bc, "", "    ")
+		log.PanicIf(err)
+
+		fmt.Println(string(data))
+
+		return
+	}
+
+	containers := make([]string, len(bc.Containers))
+	for i, kind := range bc.Containers {
+		containers[i] = string(kind)
+	}
+
+	fmt.Printf("EXIF version: %s\n", bc.ExifVersion)
+	fmt.Printf("Tag table: %s\n", bc.TagTable)
+
+	ifdPaths := make([]string, 0, len(bc.TagCounts))
+	for ifdPath := range bc.TagCounts {
+		ifdPaths = append(ifdPaths, ifdPath)
+	}
+
+	sort.Strings(ifdPaths)
+
+	for _, ifdPath := range ifdPaths {
+		fmt.Printf("  IFD=[%s] TAGS=(%d)\n", ifdPath, bc.TagCounts[ifdPath])
+	}
+
+	fmt.Printf("Containers: %s\n", strings.Join(containers, ", "))
+	fmt.Printf("Maker-note decoders: %s\n", strings.Join(bc.MakerNoteDecoders, ", "))
+	fmt.Printf("Quirks: %s\n", strings.Join(bc.Quirks, ", "))
+	fmt.Printf("Features: %s\n", strings.Join(bc.Features, ", "))
+	fmt.Printf("Go version: %s\n", bc.GoVersion)
+}
@@ -1,3 +1,6 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
 package main
 
 import (
@@ -5,14 +8,18 @@ import (
 	"fmt"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
 
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"os/exec"
+	"time"
 
 	"github.com/dsoprea/go-logging"
 
+	"github.com/dsoprea/go-exif/v2"
 	"github.com/dsoprea/go-exif/v2/common"
 )
 
@@ -103,6 +110,185 @@ IFD-PATH=[IFD] ID=(0x0128) NAME=[ResolutionUnit] COUNT=(1) TYPE=[SHORT] VALUE=[2
 	}
 }
 
+func TestMain_FormatOptions(t *testing.T) {
+	cmd := exec.Command(
+		"go", "run", appFilepath,
+		"-filepath", testImageFilepath,
+		"-decimal", "-units")
+
+	b := new(bytes.Buffer)
+	cmd.Stdout = b
+	cmd.Stderr = b
+
+	err := cmd.Run()
+	actual := b.String()
+
+	if err != nil {
+		fmt.Printf(actual)
+		log.Panic(err)
+	}
+
+	expectedLines := []string{
+		"IFD-PATH=[IFD] ID=(0x011a) NAME=[XResolution] COUNT=(1) TYPE=[RATIONAL] VALUE=[72]",
+		"IFD-PATH=[IFD/Exif] ID=(0x829a) NAME=[ExposureTime] COUNT=(1) TYPE=[RATIONAL] VALUE=[0.0015625 s]",
+		"IFD-PATH=[IFD/Exif] ID=(0x920a) NAME=[FocalLength] COUNT=(1) TYPE=[RATIONAL] VALUE=[16 mm]",
+	}
+
+	for _, line := range expectedLines {
+		if strings.Contains(actual, line+"\n") == false {
+			t.Fatalf("Line not found: [%s]\n%s", line, actual)
+		}
+	}
+}
+
+func TestMain_Heuristic(t *testing.T) {
+	cmd := exec.Command(
+		"go", "run", appFilepath,
+		"-filepath", testImageFilepath,
+		"-heuristic")
+
+	b := new(bytes.Buffer)
+	cmd.Stdout = b
+	cmd.Stderr = b
+
+	err := cmd.Run()
+	actual := b.String()
+
+	if err != nil {
+		fmt.Printf(actual)
+		log.Panic(err)
+	}
+
+	line := "IFD-PATH=[IFD] ID=(0x010f) NAME=[Make] COUNT=(6) TYPE=[ASCII] VALUE=[Canon]"
+	if strings.Contains(actual, line+"\n") == false {
+		t.Fatalf("Line not found: [%s]\n%s", line, actual)
+	}
+}
+
+func TestMain_Bench(t *testing.T) {
+	cmd := exec.Command(
+		"go", "run", appFilepath,
+		"-filepath", testImageFilepath,
+		"-bench", "3")
+
+	b := new(bytes.Buffer)
+	cmd.Stdout = b
+	cmd.Stderr = b
+
+	err := cmd.Run()
+	actual := b.String()
+
+	if err != nil {
+		fmt.Printf(actual)
+		log.Panic(err)
+	}
+
+	if strings.HasPrefix(actual, "Parsed (3) times in ") == false {
+		t.Fatalf("Summary not found:\n%s", actual)
+	} else if strings.Contains(actual, "NAME=[Make]") == true {
+		t.Fatalf("Tags should not be printed:\n%s", actual)
+	}
+
+	for _, name := range []string{"search", "enumerate", "decode"} {
+		if strings.Contains(actual, "PHASE=["+name+"] ") == false {
+			t.Fatalf("Phase [%s] not found:\n%s", name, actual)
+		}
+	}
+}
+
+func TestMain_Minimize(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	cmd := exec.Command(
+		"go", "run", appFilepath,
+		"-filepath", testImageFilepath,
+		"-minimize", tempPath)
+
+	b := new(bytes.Buffer)
+	cmd.Stdout = b
+	cmd.Stderr = b
+
+	err = cmd.Run()
+	actual := b.String()
+
+	if err != nil {
+		fmt.Printf(actual)
+		log.Panic(err)
+	}
+
+	if actual != "The file doesn't crash the parser.\n" {
+		t.Fatalf("Output not correct:\n%s", actual)
+	}
+
+	files, err := ioutil.ReadDir(tempPath)
+	log.PanicIf(err)
+
+	if len(files) != 0 {
+		t.Fatalf("Files written for a file that doesn't crash: %v", files)
+	}
+}
+
+func TestMain_Capabilities(t *testing.T) {
+	cmd := exec.Command(
+		"go", "run", appFilepath,
+		"-capabilities")
+
+	b := new(bytes.Buffer)
+	cmd.Stdout = b
+	cmd.Stderr = b
+
+	err := cmd.Run()
+	actual := b.String()
+
+	if err != nil {
+		fmt.Printf(actual)
+		log.Panic(err)
+	}
+
+	if strings.HasPrefix(actual, "EXIF version: 2.32\n") == false {
+		t.Fatalf("EXIF version not found:\n%s", actual)
+	} else if strings.Contains(actual, "Containers: jpeg, png, webp, heif, cr3, tiff\n") == false {
+		t.Fatalf("Containers not found:\n%s", actual)
+	} else if strings.Contains(actual, "  IFD=[IFD/Exif] TAGS=(") == false {
+		t.Fatalf("Tag counts not found:\n%s", actual)
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	data, err := ioutil.ReadFile(testImageFilepath)
+	log.PanicIf(err)
+
+	report, err := runBenchmark(data, 2)
+	log.PanicIf(err)
+
+	if report.Iterations != 2 || report.Size != len(data) || len(report.Phases) != 3 {
+		t.Fatalf("Report not correct: %v", report)
+	}
+
+	var total time.Duration
+	for i, phase := range report.Phases {
+		if phase.Allocs == 0 {
+			t.Fatalf("Phase [%s] has no allocations.", phase.Name)
+		} else if i > 0 && phase.Duration > report.Phases[i-1].Duration {
+			t.Fatalf("Phases not ordered by time: %v", report.Phases)
+		}
+
+		total += phase.Duration
+	}
+
+	if total != report.Duration {
+		t.Fatalf("Duration not correct: (%s) != (%s)", report.Duration, total)
+	}
+
+	_, err = runBenchmark([]byte("not an image"), 1)
+	if log.Is(err, exif.ErrNoExif) == false {
+		t.Fatalf("Expected ErrNoExif: %v", err)
+	}
+}
+
 func TestMainJson(t *testing.T) {
 	cmd := exec.Command(
 		"go", "run", appFilepath,
@@ -145,6 +331,117 @@ func TestMainJson(t *testing.T) {
 	}
 }
 
+func TestMain_Sink(t *testing.T) {
+	cmd := exec.Command(
+		"go", "run", appFilepath,
+		"-filepath", testImageFilepath,
+		"-sink", "csv",
+		testImageFilepath)
+
+	b := new(bytes.Buffer)
+	cmd.Stdout = b
+	cmd.Stderr = b
+
+	err := cmd.Run()
+	actual := b.String()
+
+	if err != nil {
+		fmt.Printf(actual)
+		log.Panic(err)
+	}
+
+	line := testImageFilepath + ",,IFD,0x010f,Make,ASCII,Canon"
+	if strings.HasPrefix(actual, "name,error,ifd_path,tag_id,tag_name,tag_type,value\n") == false {
+		t.Fatalf("Header not found:\n%s", actual)
+	} else if strings.Count(actual, line+"\n") != 2 {
+		t.Fatalf("Line not found for both files: [%s]\n%s", line, actual)
+	}
+}
+
+func TestMain_Manifest(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	manifestFilepath := path.Join(tempPath, "manifest.jsonl")
+	outputFilepath := path.Join(tempPath, "tags.jsonl")
+
+	for i := 0; i < 2; i++ {
+		cmd := exec.Command(
+			"go", "run", appFilepath,
+			"-filepath", testImageFilepath,
+			"-sink", "json",
+			"-sink-target", outputFilepath,
+			"-manifest", manifestFilepath)
+
+		b := new(bytes.Buffer)
+		cmd.Stdout = b
+		cmd.Stderr = b
+
+		err := cmd.Run()
+		if err != nil {
+			fmt.Printf(b.String())
+			log.Panic(err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(outputFilepath)
+	log.PanicIf(err)
+
+	// The second run skips the file.
+	if strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("Expected one record:\n%s", data)
+	}
+}
+
+func TestMain_Search(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	indexFilepath := path.Join(tempPath, "captures.json")
+
+	tests := []struct {
+		query   string
+		matches bool
+	}{
+		{"camera:\"5d mark iii\"", true},
+		{"camera:nikon", false},
+	}
+
+	for _, test := range tests {
+		cmd := exec.Command(
+			"go", "run", appFilepath,
+			"-filepath", testImageFilepath,
+			"-search", test.query,
+			"-search-index", indexFilepath)
+
+		b := new(bytes.Buffer)
+		cmd.Stdout = b
+		cmd.Stderr = b
+
+		err := cmd.Run()
+		actual := b.String()
+
+		if err != nil {
+			fmt.Printf(actual)
+			log.Panic(err)
+		}
+
+		if test.matches == true && actual != testImageFilepath+"\n" {
+			t.Fatalf("Expected the file to match [%s]:\n%s", test.query, actual)
+		} else if test.matches == false && actual != "" {
+			t.Fatalf("Expected no matches for [%s]:\n%s", test.query, actual)
+		}
+	}
+
+	if _, err := os.Stat(indexFilepath); err != nil {
+		t.Fatalf("Index not saved: %v", err)
+	}
+}
+
 func init() {
 	moduleRootPath := exifcommon.GetModuleRootPath()
 	assetsPath = path.Join(moduleRootPath, "assets")
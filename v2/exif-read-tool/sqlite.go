@@ -0,0 +1,14 @@
+//go:build sqlite && !tinygo && !exif_minimal
+// +build sqlite,!tinygo,!exif_minimal
+
+// Building with the "sqlite" tag links in a SQLite driver for the "sqlite"
+// sink and the -catalog flag. It requires cgo and the driver module:
+//
+//   go get github.com/mattn/go-sqlite3
+//   go build -tags sqlite
+
+package main
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
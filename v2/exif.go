@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 
 	"encoding/binary"
 	"io/ioutil"
 
 	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
 )
 
 const (
@@ -34,6 +37,12 @@ const (
 	ExifSignatureLength = 8
 )
 
+const (
+	// ExifMaxApp1Size is the largest EXIF blob that fits in a JPEG APP1
+	// segment, after the segment length and the "Exif\x00\x00" prefix.
+	ExifMaxApp1Size = uint32(0xffff - 2 - 6)
+)
+
 var (
 	exifLogger = log.NewLogger("exif.exif")
 
@@ -71,6 +80,10 @@ func SearchAndExtractExif(data []byte) (rawExif []byte, err error) {
 // SearchAndExtractExifWithReader searches for an EXIF blob using an
 // `io.Reader`. We can't know how much long the EXIF data is without parsing it,
 // so this will likely grab up a lot of the image-data, too.
+//
+// PNG, WebP, and HEIF files are read by their structure instead (see
+// `ExtractExif()`), since their EXIF isn't necessarily near the start and,
+// for HEIF, has to be found through the item locations.
 func SearchAndExtractExifWithReader(r io.Reader) (rawExif []byte, err error) {
 	defer func() {
 		if state := recover(); state != nil {
@@ -78,12 +91,30 @@ func SearchAndExtractExifWithReader(r io.Reader) (rawExif []byte, err error) {
 		}
 	}()
 
+	br := bufio.NewReaderSize(r, probeHeadSize)
+
+	// A short file just gives a short head.
+	head, _ := br.Peek(probeHeadSize)
+
+	switch detectKind(head) {
+	case KindPng, KindWebp, KindHeif:
+		data, err := ioutil.ReadAll(br)
+		log.PanicIf(err)
+
+		rawExif, _, err = ExtractExif(data)
+		if err == ErrNoExif {
+			return nil, err
+		}
+
+		log.PanicIf(err)
+
+		return rawExif, nil
+	}
+
 	// Search for the beginning of the EXIF information. The EXIF is near the
 	// beginning of most JPEGs, so this likely doesn't have a high cost (at
 	// least, again, with JPEGs).
 
-	br := bufio.NewReader(r)
-
 	for {
 		window, err := br.Peek(ExifSignatureLength)
 		if err != nil {
@@ -197,8 +228,12 @@ func Visit(rootIfdName string, ifdMapping *IfdMapping, tagIndex *TagIndex, exifD
 
 	ie := NewIfdEnumerate(ifdMapping, tagIndex, exifData, eh.ByteOrder)
 
+	// Parsing errors are returned as they are so that the `ExifError` can be
+	// unwrapped by `errors.Is()`.
 	err = ie.Scan(rootIfdName, eh.FirstIfdOffset, visitor)
-	log.PanicIf(err)
+	if err != nil {
+		return eh, err
+	}
 
 	return eh, nil
 }
@@ -217,6 +252,149 @@ func Collect(ifdMapping *IfdMapping, tagIndex *TagIndex, exifData []byte) (eh Ex
 	ie := NewIfdEnumerate(ifdMapping, tagIndex, exifData, eh.ByteOrder)
 
 	index, err = ie.Collect(eh.FirstIfdOffset)
+	if err != nil {
+		return eh, index, err
+	}
+
+	return eh, index, nil
+}
+
+// CollectTolerant is like `Collect()` but skips damaged IFDs and entries
+// rather than failing (see `(*IfdEnumerate).SetTolerant()`). What was skipped
+// is in `index.Warnings`. An error is only returned if the header or IFD0
+// can't be read.
+func CollectTolerant(ifdMapping *IfdMapping, tagIndex *TagIndex, exifData []byte) (eh ExifHeader, index IfdIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	eh, err = ParseExifHeader(exifData)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(ifdMapping, tagIndex, exifData, eh.ByteOrder)
+	ie.SetTolerant(true)
+
+	index, err = ie.Collect(eh.FirstIfdOffset)
+	if err != nil {
+		return eh, index, err
+	}
+
+	return eh, index, nil
+}
+
+// CollectWithReader is like `Collect()` but reads the EXIF data from a reader
+// of the given size, only reading what it needs (see
+// `NewIfdEnumerateWithReader()`). For a TIFF or RAW file, this is the file
+// itself. For EXIF within another format, use an `io.SectionReader`.
+func CollectWithReader(ifdMapping *IfdMapping, tagIndex *TagIndex, r io.ReaderAt, size int64) (eh ExifHeader, index IfdIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	header := make([]byte, ExifSignatureLength)
+
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		log.Panic(err)
+	}
+
+	eh, err = ParseExifHeader(header[:n])
+	if err != nil {
+		return eh, index, err
+	}
+
+	ie := NewIfdEnumerateWithReader(ifdMapping, tagIndex, r, size, eh.ByteOrder)
+
+	index, err = ie.Collect(eh.FirstIfdOffset)
+	if err != nil {
+		return eh, index, err
+	}
+
+	return eh, index, nil
+}
+
+// CollectWithReadSeeker is like `CollectWithReader()` for a reader that can
+// only seek (e.g. an `os.File` opened by something else). The size is found
+// by seeking to the end. The reader can't be used by anything else while the
+// index is in use.
+func CollectWithReadSeeker(ifdMapping *IfdMapping, tagIndex *TagIndex, rs io.ReadSeeker) (eh ExifHeader, index IfdIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	size, err := rs.Seek(0, io.SeekEnd)
+	log.PanicIf(err)
+
+	rsa := &readSeekerAt{
+		rs: rs,
+	}
+
+	eh, index, err = CollectWithReader(ifdMapping, tagIndex, rsa, size)
+	if err != nil {
+		return eh, index, err
+	}
+
+	return eh, index, nil
+}
+
+// readSeekerAt reads at offsets by seeking.
+type readSeekerAt struct {
+	rs io.ReadSeeker
+	m  sync.Mutex
+}
+
+// ReadAt reads `len(p)` bytes starting at `off`.
+func (rsa *readSeekerAt) ReadAt(p []byte, off int64) (n int, err error) {
+	rsa.m.Lock()
+	defer rsa.m.Unlock()
+
+	if _, err := rsa.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return io.ReadFull(rsa.rs, p)
+}
+
+// CollectIfds is like `Collect()` but only parses the IFDs with the given
+// fully-qualified paths. See `(*IfdEnumerate).SetFqIfdPaths()`.
+func CollectIfds(ifdMapping *IfdMapping, tagIndex *TagIndex, exifData []byte, fqIfdPaths []string) (eh ExifHeader, index IfdIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	eh, err = ParseExifHeader(exifData)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(ifdMapping, tagIndex, exifData, eh.ByteOrder)
+	ie.SetFqIfdPaths(fqIfdPaths)
+
+	index, err = ie.Collect(eh.FirstIfdOffset)
+	if err != nil {
+		return eh, index, err
+	}
+
+	return eh, index, nil
+}
+
+// CollectShallow only parses IFD0, which is enough for the make, model, and
+// orientation. The Exif, GPS, and Interop IFDs and the thumbnail IFD are
+// skipped.
+func CollectShallow(ifdMapping *IfdMapping, tagIndex *TagIndex, exifData []byte) (eh ExifHeader, index IfdIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	eh, index, err = CollectIfds(ifdMapping, tagIndex, exifData, []string{exifcommon.IfdPathStandard})
 	log.PanicIf(err)
 
 	return eh, index, nil
@@ -0,0 +1,164 @@
+package exif
+
+import (
+	"sort"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// ExifBlockPolicy decides which of several EXIF blocks in a file is the
+// authoritative one.
+type ExifBlockPolicy int
+
+const (
+	// ExifBlockPolicyFirst picks the first block that can be parsed. This is
+	// what most readers do.
+	ExifBlockPolicyFirst ExifBlockPolicy = iota
+
+	// ExifBlockPolicyLast picks the last block that can be parsed. Editors
+	// that don't understand EXIF often leave the original and append their
+	// own.
+	ExifBlockPolicyLast
+
+	// ExifBlockPolicyMostTags picks the block with the most tags, which is
+	// usually the camera's original rather than a stub written by an editor.
+	ExifBlockPolicyMostTags
+
+	// ExifBlockPolicyNewest picks the block with the latest DateTime (when
+	// the file was last changed).
+	ExifBlockPolicyNewest
+)
+
+// ExifBlock is one EXIF block found in a file.
+type ExifBlock struct {
+	// Segment is the JPEG segment that the block is in.
+	Segment SegmentInfo
+
+	// Data is the EXIF data, from the TIFF header.
+	Data []byte
+
+	// TagCount is the number of tags that were parsed and DateTime is the
+	// value of the DateTime tag of IFD0, if any.
+	TagCount int
+	DateTime string
+
+	// Err is why the block couldn't be parsed, or nil.
+	Err error
+}
+
+// GetJpegExifBlocks returns every EXIF block in the JPEG, in file order, with
+// enough about each to decide which one to trust. Blocks that can't be parsed
+// are included, with their errors. `ErrNoExif` is returned if there aren't
+// any.
+func GetJpegExifBlocks(data []byte) (blocks []ExifBlock, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	segments := si.Find(JpegSegmentExif)
+	if len(segments) == 0 {
+		return nil, ErrNoExif
+	}
+
+	blocks = make([]ExifBlock, len(segments))
+
+	for i, segment := range segments {
+		block := ExifBlock{
+			Segment: segment,
+			Data:    data[segment.DataOffset : segment.DataOffset+segment.DataSize],
+		}
+
+		_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), block.Data)
+		if err == nil {
+			for _, ifd := range index.Ifds {
+				block.TagCount += len(ifd.Entries)
+			}
+
+			block.DateTime, err = getIfdTagString(index.RootIfd, "DateTime")
+		}
+
+		block.Err = err
+		blocks[i] = block
+	}
+
+	return blocks, nil
+}
+
+// SelectExifBlock returns the position of the authoritative block according to
+// the policy. Blocks that couldn't be parsed are never picked, so `found` is
+// false if none of them could be.
+func SelectExifBlock(blocks []ExifBlock, policy ExifBlockPolicy) (position int, found bool) {
+	valid := make([]int, 0, len(blocks))
+	for i, block := range blocks {
+		if block.Err == nil {
+			valid = append(valid, i)
+		}
+	}
+
+	if len(valid) == 0 {
+		return -1, false
+	}
+
+	switch policy {
+	case ExifBlockPolicyLast:
+		return valid[len(valid)-1], true
+	case ExifBlockPolicyMostTags:
+		sort.SliceStable(valid, func(i, j int) bool {
+			return blocks[valid[i]].TagCount > blocks[valid[j]].TagCount
+		})
+	case ExifBlockPolicyNewest:
+		// EXIF timestamps sort chronologically as strings.
+		sort.SliceStable(valid, func(i, j int) bool {
+			return blocks[valid[i]].DateTime > blocks[valid[j]].DateTime
+		})
+	}
+
+	return valid[0], true
+}
+
+// RemoveStaleJpegExifBlocks returns a copy of the JPEG with only the
+// authoritative EXIF block, according to the policy, and the number of blocks
+// that were removed. The JPEG is returned as-is if it has fewer than two
+// blocks or none of them can be parsed.
+func RemoveStaleJpegExifBlocks(data []byte, policy ExifBlockPolicy) (updated []byte, removed int, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	blocks, err := GetJpegExifBlocks(data)
+	if err == ErrNoExif {
+		return data, 0, nil
+	}
+
+	log.PanicIf(err)
+
+	keep, found := SelectExifBlock(blocks, policy)
+	if len(blocks) < 2 || found == false {
+		return data, 0, nil
+	}
+
+	updated = make([]byte, 0, len(data))
+	last := 0
+
+	for i, block := range blocks {
+		if i == keep {
+			continue
+		}
+
+		updated = append(updated, data[last:block.Segment.Offset]...)
+		last = block.Segment.Offset + block.Segment.Size
+
+		removed++
+	}
+
+	updated = append(updated, data[last:]...)
+
+	return updated, removed, nil
+}
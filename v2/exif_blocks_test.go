@@ -0,0 +1,123 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestJpegWithExifBlocks returns a JPEG with the camera's EXIF, a block
+// that can't be parsed, and a small block left by an editor, in that order.
+func getTestJpegWithExifBlocks() []byte {
+	cameraExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	editor := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x0131, Value: "Editor"},
+			{Id: 0x0132, Value: "2021:06:07 08:09:10"},
+		},
+	}
+
+	editorExif, err := exiftest.Build(editor, binary.LittleEndian)
+	log.PanicIf(err)
+
+	brokenExif := exiftest.SetFirstIfdOffset(cameraExif, 0xfffffff0)
+
+	b := new(bytes.Buffer)
+	b.Write([]byte{jpegMarkerPrefix, jpegMarkerSoi})
+
+	for _, exifData := range [][]byte{cameraExif, brokenExif, editorExif} {
+		b.Write(getTestJpegSegment(jpegMarkerApp1, append(append([]byte{}, jpegExifPreamble...), exifData...)))
+	}
+
+	b.Write(getTestJpegSegment(jpegMarkerCom, []byte("comment")))
+	b.Write([]byte{jpegMarkerPrefix, jpegMarkerEoi})
+
+	return b.Bytes()
+}
+
+func TestGetJpegExifBlocks(t *testing.T) {
+	blocks, err := GetJpegExifBlocks(getTestJpegWithExifBlocks())
+	log.PanicIf(err)
+
+	if len(blocks) != 3 {
+		t.Fatalf("Expected three blocks: %v", blocks)
+	} else if blocks[0].Err != nil || blocks[0].DateTime != "2020:01:02 03:04:05" || blocks[0].TagCount < 10 {
+		t.Fatalf("Camera block not correct: %v", blocks[0])
+	} else if blocks[1].Err == nil {
+		t.Fatalf("Expected the broken block to fail.")
+	} else if blocks[2].Err != nil || blocks[2].DateTime != "2021:06:07 08:09:10" || blocks[2].TagCount != 2 {
+		t.Fatalf("Editor block not correct: %v", blocks[2])
+	} else if blocks[0].Segment.Offset != 2 {
+		t.Fatalf("Segment offset not correct: (%d)", blocks[0].Segment.Offset)
+	}
+}
+
+func TestGetJpegExifBlocks_NoExif(t *testing.T) {
+	_, err := GetJpegExifBlocks([]byte{jpegMarkerPrefix, jpegMarkerSoi, jpegMarkerPrefix, jpegMarkerEoi})
+	if err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif: %v", err)
+	}
+}
+
+func TestSelectExifBlock(t *testing.T) {
+	blocks, err := GetJpegExifBlocks(getTestJpegWithExifBlocks())
+	log.PanicIf(err)
+
+	expected := map[ExifBlockPolicy]int{
+		ExifBlockPolicyFirst:    0,
+		ExifBlockPolicyLast:     2,
+		ExifBlockPolicyMostTags: 0,
+		ExifBlockPolicyNewest:   2,
+	}
+
+	for policy, expectedPosition := range expected {
+		position, found := SelectExifBlock(blocks, policy)
+		if found != true || position != expectedPosition {
+			t.Fatalf("Policy (%d) selected (%d) instead of (%d).", policy, position, expectedPosition)
+		}
+	}
+
+	if _, found := SelectExifBlock(blocks[1:2], ExifBlockPolicyFirst); found != false {
+		t.Fatalf("Expected a block that can't be parsed to never be selected.")
+	}
+}
+
+func TestRemoveStaleJpegExifBlocks(t *testing.T) {
+	data := getTestJpegWithExifBlocks()
+
+	updated, removed, err := RemoveStaleJpegExifBlocks(data, ExifBlockPolicyNewest)
+	log.PanicIf(err)
+
+	if removed != 2 {
+		t.Fatalf("Expected two blocks to be removed: (%d)", removed)
+	}
+
+	blocks, err := GetJpegExifBlocks(updated)
+	log.PanicIf(err)
+
+	if len(blocks) != 1 || blocks[0].DateTime != "2021:06:07 08:09:10" {
+		t.Fatalf("Wrong block kept: %v", blocks)
+	}
+
+	si, err := GetJpegSegmentsInfo(updated)
+	log.PanicIf(err)
+
+	if si.Has(JpegSegmentComment) != true || bytes.HasSuffix(updated, []byte{jpegMarkerPrefix, jpegMarkerEoi}) != true {
+		t.Fatalf("Rest of the JPEG not preserved.")
+	}
+
+	// There's nothing left to remove.
+	again, removed, err := RemoveStaleJpegExifBlocks(updated, ExifBlockPolicyNewest)
+	log.PanicIf(err)
+
+	if removed != 0 || bytes.Equal(again, updated) != true {
+		t.Fatalf("Expected no change.")
+	}
+}
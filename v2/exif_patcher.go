@@ -0,0 +1,596 @@
+package exif
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// patcherHeaderIfdOffsetPosition is where the TIFF header stores the
+	// offset of the first IFD.
+	patcherHeaderIfdOffsetPosition = 4
+)
+
+// patchIfd is one IFD table in the blob being patched along with where the
+// offset that points to it is stored.
+type patchIfd struct {
+	ifd *Ifd
+
+	// offset is where the table currently lives and allocated is the number
+	// of bytes reserved for it there.
+	offset    uint32
+	allocated uint32
+
+	entries       []rawIfdEntry
+	nextIfdOffset uint32
+
+	// Exactly one of these is set for every IFD other than the first, which
+	// is pointed to by the header.
+	parent   *patchIfd
+	previous *patchIfd
+
+	dirty bool
+}
+
+func (pi *patchIfd) tableSize() uint32 {
+	return rawIfdTableSize(len(pi.entries))
+}
+
+func (pi *patchIfd) find(tagId uint16) (i int, found bool) {
+	i = sort.Search(len(pi.entries), func(j int) bool {
+		return pi.entries[j].tagId >= tagId
+	})
+
+	return i, i < len(pi.entries) && pi.entries[i].tagId == tagId
+}
+
+// ExifPatcher modifies tags in an existing EXIF blob without re-encoding it.
+// Only the IFD tables that change are rewritten, values are updated in place
+// when they fit, and anything that no longer fits (a larger value or a table
+// with more entries) is appended to the end of the blob. Everything else,
+// including maker-notes and thumbnails, keeps its original bytes and
+// offsets, which matters for maker-notes that use absolute offsets.
+//
+// Regions that are abandoned are zeroed but not reclaimed, so the blob never
+// shrinks. Use `IfdBuilder` when a compact result is needed.
+//
+// The blob can also be a whole TIFF (or DNG) file. The strips, tiles, and
+// thumbnail, and the SubIFDs along with their images, are never written to:
+// a value that shares bytes with them is moved rather than overwritten or
+// zeroed.
+type ExifPatcher struct {
+	data       []byte
+	byteOrder  binary.ByteOrder
+	ifdMapping *IfdMapping
+	tagIndex   *TagIndex
+
+	ifds   []*patchIfd
+	byPath map[string]*patchIfd
+
+	// protected are the image-data and thumbnail regions and the tables and
+	// values of the SubIFDs.
+	protected []LayoutRegion
+}
+
+// NewExifPatcher parses the given EXIF blob (starting at the TIFF header).
+// The data is copied and is not modified.
+func NewExifPatcher(exifData []byte, im *IfdMapping, ti *TagIndex) (ep *ExifPatcher, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data := make([]byte, len(exifData))
+	copy(data, exifData)
+
+	_, index, err := Collect(im, ti, data)
+	log.PanicIf(err)
+
+	ep = &ExifPatcher{
+		data:       data,
+		byteOrder:  index.RootIfd.ByteOrder,
+		ifdMapping: im,
+		tagIndex:   ti,
+		ifds:       make([]*patchIfd, 0, len(index.Ifds)),
+		byPath:     make(map[string]*patchIfd),
+	}
+
+	byIfd := make(map[*Ifd]*patchIfd)
+
+	for _, ifd := range index.Ifds {
+		pi, err := ep.readTable(ifd)
+		log.PanicIf(err)
+
+		ep.ifds = append(ep.ifds, pi)
+		ep.byPath[ifd.FqIfdPath] = pi
+		byIfd[ifd] = pi
+	}
+
+	for _, pi := range ep.ifds {
+		if pi.ifd.ParentIfd != nil {
+			pi.parent = byIfd[pi.ifd.ParentIfd]
+		}
+
+		if pi.ifd.NextIfd != nil {
+			if next, found := byIfd[pi.ifd.NextIfd]; found == true {
+				next.previous = pi
+			}
+		}
+	}
+
+	el, err := GetExifLayout(data, index)
+	log.PanicIf(err)
+
+	// The tables and values of the SubIFDs aren't managed here, so they're
+	// protected along with the image data.
+	for _, lr := range el.Regions {
+		if lr.Kind == LayoutRegionImageData || lr.Kind == LayoutRegionThumbnail {
+			ep.protected = append(ep.protected, lr)
+		} else if lr.FqIfdPath != "" {
+			if _, found := ep.byPath[lr.FqIfdPath]; found == false {
+				ep.protected = append(ep.protected, lr)
+			}
+		}
+	}
+
+	return ep, nil
+}
+
+// readTable reads the raw entries of the table for the given IFD.
+func (ep *ExifPatcher) readTable(ifd *Ifd) (pi *patchIfd, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	entries, nextIfdOffset, err := readRawIfdTable(ep.data, ifd.Offset, ep.byteOrder)
+	log.PanicIf(err)
+
+	pi = &patchIfd{
+		ifd:           ifd,
+		offset:        ifd.Offset,
+		allocated:     rawIfdTableSize(len(entries)),
+		entries:       entries,
+		nextIfdOffset: nextIfdOffset,
+	}
+
+	// Writers are supposed to sort the entries but not all of them do. We
+	// keep them sorted so that new entries can be inserted correctly.
+	sort.SliceStable(pi.entries, func(i, j int) bool {
+		return pi.entries[i].tagId < pi.entries[j].tagId
+	})
+
+	return pi, nil
+}
+
+// getIfd returns the IFD with the given fully-qualified path and panics if
+// there isn't one.
+func (ep *ExifPatcher) getIfd(fqIfdPath string) *patchIfd {
+	pi, found := ep.byPath[fqIfdPath]
+	if found == false {
+		log.Panicf("IFD not found: [%s]", fqIfdPath)
+	}
+
+	return pi
+}
+
+// assertNotChildPointer panics if the tag points to a child IFD. Those are
+// managed by the patcher itself.
+func (ep *ExifPatcher) assertNotChildPointer(pi *patchIfd, tagId uint16) {
+	for _, child := range ep.ifds {
+		if child.parent == pi && child.ifd.TagId == tagId {
+			log.Panicf("tag (0x%04x) in IFD [%s] points to a child IFD and can not be changed", tagId, pi.ifd.FqIfdPath)
+		}
+	}
+}
+
+// Set changes the value of a tag, adding it if it's not already present. The
+// type is taken from the tag index, and undefined-type tags require an
+// `exifundefined.EncodeableValue`.
+func (ep *ExifPatcher) Set(fqIfdPath string, tagId uint16, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	pi := ep.getIfd(fqIfdPath)
+	ep.assertNotChildPointer(pi, tagId)
+
+	it, err := ep.tagIndex.Get(pi.ifd.IfdPath, tagId)
+	log.PanicIf(err)
+
+	bt := NewStandardBuilderTag(pi.ifd.IfdPath, it, ep.byteOrder, value)
+
+	ep.setBytes(pi, tagId, it.Type, bt.value.Bytes())
+
+	return nil
+}
+
+// setBytes changes the value of a tag to the given encoded bytes, adding the
+// tag if it's not already present, and panics on failure.
+func (ep *ExifPatcher) setBytes(pi *patchIfd, tagId uint16, tagType exifcommon.TagTypePrimitive, valueBytes []byte) {
+	pe := rawIfdEntry{
+		tagId:   tagId,
+		tagType: tagType,
+	}
+
+	// Undefined-type values are counted in bytes (and `Size()` panics for
+	// them).
+	typeSize := uint32(1)
+	if pe.tagType != exifcommon.TypeUndefined {
+		typeSize = uint32(pe.tagType.Size())
+	}
+
+	pe.unitCount = uint32(len(valueBytes)) / typeSize
+
+	i, found := pi.find(tagId)
+	if found == true {
+		old := pi.entries[i]
+
+		if len(valueBytes) <= 4 {
+			ep.releaseValue(old)
+			copy(pe.valueOffset[:], valueBytes)
+		} else if ep.fitsInPlace(old, uint32(len(valueBytes))) == true {
+			oldSize := old.valueSize()
+			offset := ep.byteOrder.Uint32(old.valueOffset[:])
+
+			copy(ep.data[offset:], valueBytes)
+			ep.clear(offset+uint32(len(valueBytes)), oldSize-uint32(len(valueBytes)))
+
+			pe.valueOffset = old.valueOffset
+		} else {
+			ep.releaseValue(old)
+
+			offset, err := ep.allocate(valueBytes)
+			log.PanicIf(err)
+
+			ep.byteOrder.PutUint32(pe.valueOffset[:], offset)
+		}
+
+		pi.entries[i] = pe
+	} else {
+		if len(valueBytes) <= 4 {
+			copy(pe.valueOffset[:], valueBytes)
+		} else {
+			offset, err := ep.allocate(valueBytes)
+			log.PanicIf(err)
+
+			ep.byteOrder.PutUint32(pe.valueOffset[:], offset)
+		}
+
+		pi.entries = append(pi.entries, rawIfdEntry{})
+		copy(pi.entries[i+1:], pi.entries[i:])
+		pi.entries[i] = pe
+	}
+
+	pi.dirty = true
+}
+
+// Delete removes a tag. `ErrTagNotFound` is returned if it's not present.
+func (ep *ExifPatcher) Delete(fqIfdPath string, tagId uint16) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	pi := ep.getIfd(fqIfdPath)
+	ep.assertNotChildPointer(pi, tagId)
+
+	i, found := pi.find(tagId)
+	if found == false {
+		return ErrTagNotFound
+	}
+
+	ep.releaseValue(pi.entries[i])
+
+	pi.entries = append(pi.entries[:i], pi.entries[i+1:]...)
+	pi.dirty = true
+
+	return nil
+}
+
+// HasIfd returns true if the blob has the IFD with the given fully-qualified
+// path.
+func (ep *ExifPatcher) HasIfd(fqIfdPath string) bool {
+	_, found := ep.byPath[fqIfdPath]
+	return found
+}
+
+// AddChildIfd adds an empty child IFD (e.g. "IFD/GPSInfo") to an existing
+// parent, along with the tag that points to it. Nothing is done if it's
+// already present. The table is appended to the end of the blob when encoded.
+func (ep *ExifPatcher) AddChildIfd(fqIfdPath string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ep.HasIfd(fqIfdPath) == true {
+		return nil
+	}
+
+	i := strings.LastIndex(fqIfdPath, "/")
+	if i == -1 {
+		log.Panicf("only child IFDs can be added: [%s]", fqIfdPath)
+	}
+
+	parent := ep.getIfd(fqIfdPath[:i])
+
+	ifdPath, err := ep.ifdMapping.StripPathPhraseIndices(fqIfdPath)
+	log.PanicIf(err)
+
+	mi, err := ep.ifdMapping.GetWithPath(ifdPath)
+	log.PanicIf(err)
+
+	j, found := parent.find(mi.TagId)
+	if found == true {
+		log.Panicf("tag (0x%04x) in IFD [%s] is already present but isn't a known child IFD", mi.TagId, parent.ifd.FqIfdPath)
+	}
+
+	pointer := rawIfdEntry{
+		tagId:     mi.TagId,
+		tagType:   exifcommon.TypeLong,
+		unitCount: 1,
+	}
+
+	parent.entries = append(parent.entries, rawIfdEntry{})
+	copy(parent.entries[j+1:], parent.entries[j:])
+	parent.entries[j] = pointer
+	parent.dirty = true
+
+	ifd := &Ifd{
+		ByteOrder: ep.byteOrder,
+		Name:      mi.Name,
+		IfdPath:   ifdPath,
+		FqIfdPath: fqIfdPath,
+		TagId:     mi.TagId,
+		ParentIfd: parent.ifd,
+	}
+
+	// With nothing allocated, the table is placed at the end when encoded,
+	// which also sets the pointer.
+	pi := &patchIfd{
+		ifd:     ifd,
+		entries: make([]rawIfdEntry, 0),
+		parent:  parent,
+		dirty:   true,
+	}
+
+	ep.ifds = append(ep.ifds, pi)
+	ep.byPath[fqIfdPath] = pi
+
+	return nil
+}
+
+// DeleteChildIfd removes a child IFD (e.g. "IFD/GPSInfo"), its own child
+// IFDs, and the tag that points to it. Their tables and values are zeroed.
+// Nothing is done if it's not present.
+func (ep *ExifPatcher) DeleteChildIfd(fqIfdPath string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	pi, found := ep.byPath[fqIfdPath]
+	if found == false {
+		return nil
+	} else if pi.parent == nil {
+		log.Panicf("only child IFDs can be deleted: [%s]", fqIfdPath)
+	}
+
+	i, found := pi.parent.find(pi.ifd.TagId)
+	if found == true {
+		pi.parent.entries = append(pi.parent.entries[:i], pi.parent.entries[i+1:]...)
+		pi.parent.dirty = true
+	}
+
+	ifds := make([]*patchIfd, 0, len(ep.ifds))
+
+	for _, other := range ep.ifds {
+		// Descendants have paths under the one being deleted.
+		if other != pi && strings.HasPrefix(other.ifd.FqIfdPath, fqIfdPath+"/") == false {
+			ifds = append(ifds, other)
+			continue
+		}
+
+		for _, pe := range other.entries {
+			ep.releaseValue(pe)
+		}
+
+		if other.allocated > 0 {
+			ep.clear(other.offset, other.allocated)
+		}
+
+		delete(ep.byPath, other.ifd.FqIfdPath)
+	}
+
+	ep.ifds = ifds
+
+	return nil
+}
+
+// fitsInPlace returns true if a value of the given size can be written over
+// the existing out-of-line value of the entry.
+func (ep *ExifPatcher) fitsInPlace(pe rawIfdEntry, size uint32) bool {
+	oldSize := pe.valueSize()
+	if oldSize <= 4 || size > oldSize {
+		return false
+	}
+
+	offset := ep.byteOrder.Uint32(pe.valueOffset[:])
+	if _, err := exifcommon.CheckedSlice(ep.data, offset, oldSize); err != nil {
+		return false
+	}
+
+	return ep.isProtected(offset, oldSize) == false
+}
+
+// releaseValue zeroes the out-of-line value of an entry that is being
+// replaced or removed.
+func (ep *ExifPatcher) releaseValue(pe rawIfdEntry) {
+	size := pe.valueSize()
+	if size <= 4 {
+		return
+	}
+
+	offset := ep.byteOrder.Uint32(pe.valueOffset[:])
+	if _, err := exifcommon.CheckedSlice(ep.data, offset, size); err != nil {
+		return
+	}
+
+	ep.clear(offset, size)
+}
+
+// isProtected returns true if any of the given range is image data, the
+// thumbnail, or part of a SubIFD.
+func (ep *ExifPatcher) isProtected(offset, size uint32) bool {
+	end := uint64(offset) + uint64(size)
+
+	for _, lr := range ep.protected {
+		if uint64(lr.Offset) < end && uint64(offset) < uint64(lr.End()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clear zeroes the given range except for any part of it that's protected.
+func (ep *ExifPatcher) clear(offset, size uint32) {
+	if ep.isProtected(offset, size) == false {
+		region := ep.data[offset : offset+size]
+		for i := range region {
+			region[i] = 0
+		}
+
+		return
+	}
+
+	for i := offset; i < offset+size; i++ {
+		if ep.isProtected(i, 1) == false {
+			ep.data[i] = 0
+		}
+	}
+}
+
+// allocate appends the given bytes to the end of the blob on a word boundary
+// and returns their offset.
+func (ep *ExifPatcher) allocate(value []byte) (offset uint32, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(ep.data)%2 != 0 {
+		ep.data = append(ep.data, 0)
+	}
+
+	if uint64(len(ep.data))+uint64(len(value)) > math.MaxUint32 {
+		log.Panic(exifcommon.ErrOffsetOverflow)
+	}
+
+	offset = uint32(len(ep.data))
+	ep.data = append(ep.data, value...)
+
+	return offset, nil
+}
+
+// setPointer updates whatever points to the given IFD so that it points to
+// its current offset.
+func (ep *ExifPatcher) setPointer(pi *patchIfd) {
+	if pi.parent != nil {
+		i, found := pi.parent.find(pi.ifd.TagId)
+		if found == false {
+			log.Panicf("child-IFD tag (0x%04x) not found in parent IFD [%s]", pi.ifd.TagId, pi.parent.ifd.FqIfdPath)
+		}
+
+		ep.byteOrder.PutUint32(pi.parent.entries[i].valueOffset[:], pi.offset)
+		pi.parent.dirty = true
+	} else if pi.previous != nil {
+		pi.previous.nextIfdOffset = pi.offset
+		pi.previous.dirty = true
+	} else {
+		ep.byteOrder.PutUint32(ep.data[patcherHeaderIfdOffsetPosition:], pi.offset)
+	}
+}
+
+// writeTable writes the table for the given IFD at its current offset and
+// zeroes whatever is left of its allocation.
+func (ep *ExifPatcher) writeTable(pi *patchIfd) {
+	table := ep.data[pi.offset : pi.offset+pi.allocated]
+
+	ep.byteOrder.PutUint16(table, uint16(len(pi.entries)))
+
+	for i, pe := range pi.entries {
+		raw := table[2+uint32(i)*IfdTagEntrySize:]
+
+		ep.byteOrder.PutUint16(raw[0:], pe.tagId)
+		ep.byteOrder.PutUint16(raw[2:], uint16(pe.tagType))
+		ep.byteOrder.PutUint32(raw[4:], pe.unitCount)
+		copy(raw[8:12], pe.valueOffset[:])
+	}
+
+	end := pi.tableSize()
+	ep.byteOrder.PutUint32(table[end-4:], pi.nextIfdOffset)
+
+	for i := end; i < pi.allocated; i++ {
+		table[i] = 0
+	}
+}
+
+// Encode writes the changed tables and returns the patched EXIF blob. Tables
+// that grew are moved to the end; the rest are rewritten in place. The
+// patcher can continue to be used afterwards.
+func (ep *ExifPatcher) Encode() (data []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for _, pi := range ep.ifds {
+		if pi.dirty == false || pi.tableSize() <= pi.allocated {
+			continue
+		}
+
+		ep.clear(pi.offset, pi.allocated)
+
+		size := pi.tableSize()
+
+		offset, err := ep.allocate(make([]byte, size))
+		log.PanicIf(err)
+
+		pi.offset = offset
+		pi.allocated = size
+
+		ep.setPointer(pi)
+	}
+
+	for _, pi := range ep.ifds {
+		if pi.dirty == false {
+			continue
+		}
+
+		ep.writeTable(pi)
+		pi.dirty = false
+	}
+
+	data = make([]byte, len(ep.data))
+	copy(data, ep.data)
+
+	return data, nil
+}
@@ -0,0 +1,485 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func getTestExifPatcher() (ep *ExifPatcher, original []byte) {
+	original = getTestExifData()
+
+	ep, err := NewExifPatcher(original, NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	return ep, original
+}
+
+func getPatchedTagString(data []byte, fqIfdPath, tagName string) string {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), data)
+	log.PanicIf(err)
+
+	ifd, found := index.Lookup[fqIfdPath]
+	if found == false {
+		log.Panicf("IFD not found: [%s]", fqIfdPath)
+	}
+
+	value, err := getIfdTagString(ifd[0], tagName)
+	log.PanicIf(err)
+
+	return value
+}
+
+func countChangedBytes(original, updated []byte) int {
+	changed := 0
+	for i := range original {
+		if original[i] != updated[i] {
+			changed++
+		}
+	}
+
+	return changed
+}
+
+func TestExifPatcher_Set_InPlace(t *testing.T) {
+	ep, original := getTestExifPatcher()
+
+	err := ep.Set(exifcommon.IfdPathStandard, 0x010f, "Nokia")
+	log.PanicIf(err)
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	if len(updated) != len(original) {
+		t.Fatalf("Size changed: (%d) != (%d)", len(updated), len(original))
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "Make"); value != "Nokia" {
+		t.Fatalf("Make not correct: [%s]", value)
+	}
+
+	// The value ("Canon" to "Nokia") is the only thing that should change.
+	if changed := countChangedBytes(original, updated); changed != 5 {
+		t.Fatalf("Wrong number of bytes changed: (%d)", changed)
+	}
+}
+
+func TestExifPatcher_Set_Relocated(t *testing.T) {
+	ep, original := getTestExifPatcher()
+
+	err := ep.Set(exifcommon.IfdPathStandard, 0x010f, "A much longer make than the original")
+	log.PanicIf(err)
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	if len(updated) <= len(original) {
+		t.Fatalf("Value not appended.")
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "Make"); value != "A much longer make than the original" {
+		t.Fatalf("Make not correct: [%s]", value)
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "Model"); value != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not correct: [%s]", value)
+	}
+
+	// The offset and count in the entry and the old value.
+	if changed := countChangedBytes(original, updated); changed > 16 {
+		t.Fatalf("Too many bytes changed: (%d)", changed)
+	}
+}
+
+func TestExifPatcher_Set_Add(t *testing.T) {
+	ep, original := getTestExifPatcher()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), original)
+	log.PanicIf(err)
+
+	exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+	_, err = exifIfd.FindTagWithName("ImageUniqueID")
+	if log.Is(err, ErrTagNotFound) == false {
+		t.Fatalf("Test image should not have ImageUniqueID: %v", err)
+	}
+
+	makerNote, err := exifIfd.FindTagWithName("MakerNote")
+	log.PanicIf(err)
+
+	originalMakerNoteOffset := makerNote[0].getValueOffset()
+
+	originalMakerNote, err := makerNote[0].GetRawBytes()
+	log.PanicIf(err)
+
+	err = ep.Set(exifcommon.IfdPathStandardExif, 0xa420, "0123456789abcdef0123456789abcdef")
+	log.PanicIf(err)
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	if value := getPatchedTagString(updated, exifcommon.IfdPathStandardExif, "ImageUniqueID"); value != "0123456789abcdef0123456789abcdef" {
+		t.Fatalf("ImageUniqueID not correct: [%s]", value)
+	}
+
+	_, index, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), updated)
+	log.PanicIf(err)
+
+	if len(index.Ifds) != 5 {
+		t.Fatalf("IFD count not correct: (%d)", len(index.Ifds))
+	}
+
+	exifIfd = index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+	if len(exifIfd.Entries) != 39 {
+		t.Fatalf("Exif IFD entry count not correct: (%d)", len(exifIfd.Entries))
+	}
+
+	makerNote, err = exifIfd.FindTagWithName("MakerNote")
+	log.PanicIf(err)
+
+	updatedMakerNote, err := makerNote[0].GetRawBytes()
+	log.PanicIf(err)
+
+	if countChangedBytes(originalMakerNote, updatedMakerNote) != 0 {
+		t.Fatalf("Maker-note was changed.")
+	} else if makerNote[0].getValueOffset() != originalMakerNoteOffset {
+		t.Fatalf("Maker-note was moved.")
+	}
+
+	_, err = index.Lookup[exifcommon.IfdPathStandardExifIop][0].FindTagWithName("InteroperabilityIndex")
+	log.PanicIf(err)
+}
+
+func TestExifPatcher_Delete(t *testing.T) {
+	ep, original := getTestExifPatcher()
+
+	err := ep.Delete(exifcommon.IfdPathStandard, 0x0110)
+	log.PanicIf(err)
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	if len(updated) != len(original) {
+		t.Fatalf("Size changed: (%d) != (%d)", len(updated), len(original))
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), updated)
+	log.PanicIf(err)
+
+	_, err = index.RootIfd.FindTagWithName("Model")
+	if log.Is(err, ErrTagNotFound) == false {
+		t.Fatalf("Model not deleted: %v", err)
+	} else if len(index.RootIfd.Entries) != 11 {
+		t.Fatalf("Entry count not correct: (%d)", len(index.RootIfd.Entries))
+	} else if len(index.Ifds) != 5 {
+		t.Fatalf("IFD count not correct: (%d)", len(index.Ifds))
+	}
+
+	err = ep.Delete(exifcommon.IfdPathStandard, 0x0110)
+	if err != ErrTagNotFound {
+		t.Fatalf("Expected ErrTagNotFound: %v", err)
+	}
+}
+
+func TestExifPatcher_Set_ChildIfdPointer(t *testing.T) {
+	ep, _ := getTestExifPatcher()
+
+	err := ep.Set(exifcommon.IfdPathStandard, exifcommon.IfdExifId, uint32(0))
+	if err == nil {
+		t.Fatalf("Expected error for child-IFD pointer.")
+	}
+}
+
+func TestExifPatcher_AddChildIfd(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	original, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	ep, err := NewExifPatcher(original, im, ti)
+	log.PanicIf(err)
+
+	if ep.HasIfd(exifcommon.IfdPathStandardGps) != false {
+		t.Fatalf("GPS IFD should not be present yet.")
+	}
+
+	err = ep.AddChildIfd(exifcommon.IfdPathStandardGps)
+	log.PanicIf(err)
+
+	// Adding it again does nothing.
+	err = ep.AddChildIfd(exifcommon.IfdPathStandardGps)
+	log.PanicIf(err)
+
+	err = ep.Set(exifcommon.IfdPathStandardGps, 0x0001, "S")
+	log.PanicIf(err)
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	if value := getPatchedTagString(updated, exifcommon.IfdPathStandardGps, "GPSLatitudeRef"); value != "S" {
+		t.Fatalf("GPSLatitudeRef not correct: [%s]", value)
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "Make"); value != "Canon" {
+		t.Fatalf("Make not correct: [%s]", value)
+	}
+
+	err = ep.AddChildIfd("IFD/Exif/Iop")
+	if err == nil {
+		t.Fatalf("Expected error for missing parent IFD.")
+	}
+}
+
+func TestExifPatcher_DeleteChildIfd(t *testing.T) {
+	ep, _ := getTestExifPatcher()
+
+	if ep.HasIfd(exifcommon.IfdPathStandardExifIop) != true {
+		t.Fatalf("Interoperability IFD should be present.")
+	}
+
+	err := ep.DeleteChildIfd(exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	// Deleting it again does nothing.
+	err = ep.DeleteChildIfd(exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	if ep.HasIfd(exifcommon.IfdPathStandardExif) != false || ep.HasIfd(exifcommon.IfdPathStandardExifIop) != false {
+		t.Fatalf("Exif IFD and its children should have been deleted.")
+	}
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), updated)
+	log.PanicIf(err)
+
+	if _, found := index.Lookup[exifcommon.IfdPathStandardExif]; found == true {
+		t.Fatalf("Exif IFD still present.")
+	} else if _, found := index.Lookup[exifcommon.IfdPathStandardGps]; found == false {
+		t.Fatalf("GPS IFD should be kept.")
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "Model"); value != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not correct: [%s]", value)
+	}
+
+	err = ep.DeleteChildIfd(exifcommon.IfdPathStandard)
+	if err == nil {
+		t.Fatalf("Expected error for the root IFD.")
+	}
+}
+
+// getTestTiff returns a minimal TIFF with one strip of pixels in IFD0, which
+// follows the IFDs.
+func getTestTiff() (data []byte, stripOffset uint32) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("StripOffsets", []uint32{0})
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("StripByteCounts", []uint32{uint32(len(testDngPixels))})
+	log.PanicIf(err)
+
+	tiff, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	stripOffset = uint32(len(tiff))
+
+	ep, err := NewExifPatcher(append(tiff, testDngPixels...), im, ti)
+	log.PanicIf(err)
+
+	err = ep.Set(exifcommon.IfdPathStandard, 0x0111, []uint32{stripOffset})
+	log.PanicIf(err)
+
+	data, err = ep.Encode()
+	log.PanicIf(err)
+
+	return data, stripOffset
+}
+
+func TestExifPatcher_Tiff_TableGrows(t *testing.T) {
+	original, stripOffset := getTestTiff()
+
+	ep, err := NewExifPatcher(original, NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	tags := map[uint16]string{
+		0x010d: "document",
+		0x010e: "description",
+		0x0131: "software",
+		0x0132: "2020:01:02 03:04:05",
+		0x013b: "artist",
+		0x8298: "copyright",
+	}
+
+	for tagId, value := range tags {
+		err := ep.Set(exifcommon.IfdPathStandard, tagId, value)
+		log.PanicIf(err)
+	}
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	if bytes.Equal(updated[stripOffset:stripOffset+uint32(len(testDngPixels))], testDngPixels) != true {
+		t.Fatalf("Strip was moved or changed.")
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), updated)
+	log.PanicIf(err)
+
+	if index.RootIfd.Offset < uint32(len(original)) {
+		t.Fatalf("Grown IFD should have been moved to the end: (0x%08x)", index.RootIfd.Offset)
+	} else if len(index.RootIfd.Entries) != 3+len(tags) {
+		t.Fatalf("Entry count not correct: (%d)", len(index.RootIfd.Entries))
+	}
+
+	offset, err := getIfdTagNumber(index.RootIfd, "StripOffsets")
+	log.PanicIf(err)
+
+	if uint32(offset) != stripOffset {
+		t.Fatalf("StripOffsets changed: (%d)", uint32(offset))
+	}
+}
+
+func TestExifPatcher_Tiff_SharedValue(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("ImageDescription", string(testDngPixels))
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("StripOffsets", []uint32{0})
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("StripByteCounts", []uint32{uint32(len(testDngPixels))})
+	log.PanicIf(err)
+
+	tiff, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	// Point the strip at the description's value so that they share bytes.
+	_, index, err := Collect(im, ti, tiff)
+	log.PanicIf(err)
+
+	results, err := index.RootIfd.FindTagWithName("ImageDescription")
+	log.PanicIf(err)
+
+	stripOffset := results[0].getValueOffset()
+
+	ep, err := NewExifPatcher(tiff, im, ti)
+	log.PanicIf(err)
+
+	err = ep.Set(exifcommon.IfdPathStandard, 0x0111, []uint32{stripOffset})
+	log.PanicIf(err)
+
+	tiff, err = ep.Encode()
+	log.PanicIf(err)
+
+	ep, err = NewExifPatcher(tiff, im, ti)
+	log.PanicIf(err)
+
+	// This would otherwise be written in place.
+	err = ep.Set(exifcommon.IfdPathStandard, 0x010e, "short")
+	log.PanicIf(err)
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	if bytes.Equal(updated[stripOffset:stripOffset+uint32(len(testDngPixels))], testDngPixels) != true {
+		t.Fatalf("Strip was overwritten.")
+	} else if value := getPatchedTagString(updated, exifcommon.IfdPathStandard, "ImageDescription"); value != "short" {
+		t.Fatalf("ImageDescription not correct: [%s]", value)
+	}
+
+	err = ep.Delete(exifcommon.IfdPathStandard, 0x010e)
+	log.PanicIf(err)
+
+	updated, err = ep.Encode()
+	log.PanicIf(err)
+
+	if bytes.Equal(updated[stripOffset:stripOffset+uint32(len(testDngPixels))], testDngPixels) != true {
+		t.Fatalf("Strip was zeroed.")
+	}
+}
+
+func TestExifPatcher_isProtected_SubIfd(t *testing.T) {
+	original, _ := getTestTiffWithSubIfd()
+
+	ep, err := NewExifPatcher(original, NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), original)
+	log.PanicIf(err)
+
+	subIfds, err := getSubIfds(index)
+	log.PanicIf(err)
+
+	si := subIfds[0]
+	if ep.isProtected(si.offset, rawIfdTableSize(len(si.entries))) != true {
+		t.Fatalf("SubIFD table not protected.")
+	}
+
+	opcodeList, found := si.find(0xc740)
+	if found != true {
+		t.Fatalf("Opcode list not found.")
+	}
+
+	opcodeListOffset := si.byteOrder.Uint32(opcodeList.valueOffset[:])
+	if ep.isProtected(opcodeListOffset, opcodeList.valueSize()) != true {
+		t.Fatalf("SubIFD value not protected.")
+	} else if ep.isProtected(index.RootIfd.Offset, 1) == true {
+		t.Fatalf("IFD0 should not be protected.")
+	}
+}
+
+func TestExifPatcher_Tiff_SubIfdStrip(t *testing.T) {
+	original, stripOffset := getTestTiffWithSubIfd()
+
+	ep, err := NewExifPatcher(original, NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	// Grow IFD0 so that it has to be moved.
+	err = ep.Set(exifcommon.IfdPathStandard, 0x010e, "a description that won't fit in the table")
+	log.PanicIf(err)
+
+	err = ep.Set(exifcommon.IfdPathStandard, 0x0131, "software")
+	log.PanicIf(err)
+
+	updated, err := ep.Encode()
+	log.PanicIf(err)
+
+	if bytes.Equal(updated[stripOffset:stripOffset+uint32(len(testDngPixels))], testDngPixels) != true {
+		t.Fatalf("SubIFD strip was moved or changed.")
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), updated)
+	log.PanicIf(err)
+
+	subIfds, err := getSubIfds(index)
+	log.PanicIf(err)
+
+	if len(subIfds) != 1 {
+		t.Fatalf("SubIFD not found after patching: %v", subIfds)
+	}
+
+	values := readRawIfdUints(updated, subIfds[0].entries[1], subIfds[0].byteOrder)
+	if len(values) != 1 || values[0] != stripOffset {
+		t.Fatalf("SubIFD StripOffsets not correct: %v", values)
+	}
+
+	lc, err := GetLensCorrections(index)
+	log.PanicIf(err)
+
+	if lc.HasDistortion() != true {
+		t.Fatalf("SubIFD opcode list not intact.")
+	}
+}
@@ -0,0 +1,175 @@
+package exif
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// ExifCandidateMinScore is the lowest score that `SearchExifCandidates()`
+	// will accept. A real IFD0 with a few standard tags easily reaches it, and
+	// a stray TIFF signature followed by noise rarely does.
+	ExifCandidateMinScore = 10
+
+	// exifPrefixScore is added when the header follows the "Exif\0\0" prefix
+	// that JPEG (and several other containers) put before it.
+	exifPrefixScore = 10
+
+	// childIfdScore is added for each child IFD that looks like an IFD.
+	childIfdScore = 5
+)
+
+var (
+	exifPrefix = []byte("Exif\x00\x00")
+)
+
+// ExifCandidate is a TIFF header found in data of an unknown format.
+type ExifCandidate struct {
+	// Offset is the position of the header in the data.
+	Offset int
+
+	Header ExifHeader
+
+	// Score says how much the data after the header looks like EXIF. Higher
+	// is more likely.
+	Score int
+}
+
+// scoreExifCandidate scores the EXIF data at the start of `data`, or returns
+// zero if it isn't EXIF at all. IFD0 has to be a complete table. Points are
+// given for each entry with a valid type, for each tag that's a standard one,
+// for values and child IFDs that are where they say they are, for the entries
+// being in order (as the specification requires), and for a sensible link to
+// the next IFD.
+func scoreExifCandidate(data []byte, ti *TagIndex) (eh ExifHeader, score int) {
+	if len(data) < ExifSignatureLength {
+		return eh, 0
+	}
+
+	eh, err := ParseExifHeader(data)
+	if err != nil {
+		return eh, 0
+	}
+
+	if eh.FirstIfdOffset < ExifDefaultFirstIfdOffset {
+		return eh, 0
+	}
+
+	entries, nextIfdOffset, err := readRawIfdTable(data, eh.FirstIfdOffset, eh.ByteOrder)
+	if err != nil || len(entries) == 0 || len(entries) > maxPlausibleIfdEntries {
+		return eh, 0
+	}
+
+	validCount := 0
+	for i, rie := range entries {
+		if rie.tagType.IsValid() == false {
+			continue
+		}
+
+		validCount++
+		score++
+
+		if _, err := ti.Get(exifcommon.IfdPathStandard, rie.tagId); err == nil {
+			score += 2
+		}
+
+		if i > 0 && entries[i-1].tagId < rie.tagId {
+			score++
+		}
+
+		size := rie.valueSize()
+		if size > 4 {
+			if _, err := exifcommon.CheckedSlice(data, eh.ByteOrder.Uint32(rie.valueOffset[:]), size); err == nil {
+				score++
+			}
+		}
+
+		if rie.tagId == exifcommon.IfdExifId || rie.tagId == exifcommon.IfdGpsId {
+			if isPlausibleIfd(data, eh.ByteOrder.Uint32(rie.valueOffset[:]), eh.ByteOrder) == true {
+				score += childIfdScore
+			}
+		}
+	}
+
+	// Noise that happens to follow a signature mostly has invalid types.
+	if validCount*2 < len(entries) {
+		return eh, 0
+	}
+
+	if nextIfdOffset == 0 || isPlausibleIfd(data, nextIfdOffset, eh.ByteOrder) == true {
+		score += 2
+	}
+
+	return eh, score
+}
+
+// SearchExifCandidates finds every TIFF header in the data, at any offset,
+// that's followed by something that looks enough like EXIF and returns them
+// from most to least likely. This is for carving the metadata out of
+// proprietary formats that we can't parse, where `SearchAndExtractExif()`
+// would stop at the first pair of bytes that look like a header.
+func SearchExifCandidates(data []byte) (candidates []ExifCandidate) {
+	ti := NewTagIndex()
+	candidates = make([]ExifCandidate, 0)
+
+	signatures := [][]byte{
+		ExifBigEndianSignature[:],
+		ExifLittleEndianSignature[:],
+	}
+
+	for _, signature := range signatures {
+		for start := 0; start < len(data); {
+			i := bytes.Index(data[start:], signature)
+			if i == -1 {
+				break
+			}
+
+			offset := start + i
+			start = offset + 1
+
+			eh, score := scoreExifCandidate(data[offset:], ti)
+			if score == 0 {
+				continue
+			}
+
+			if offset >= len(exifPrefix) && bytes.Equal(data[offset-len(exifPrefix):offset], exifPrefix) == true {
+				score += exifPrefixScore
+			}
+
+			if score < ExifCandidateMinScore {
+				continue
+			}
+
+			ec := ExifCandidate{
+				Offset: offset,
+				Header: eh,
+				Score:  score,
+			}
+
+			candidates = append(candidates, ec)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+
+		return candidates[i].Offset < candidates[j].Offset
+	})
+
+	return candidates
+}
+
+// SearchAndExtractExifHeuristically returns the data from the most likely
+// EXIF found by `SearchExifCandidates()` to the end, or `ErrNoExif`.
+func SearchAndExtractExifHeuristically(data []byte) (rawExif []byte, err error) {
+	candidates := SearchExifCandidates(data)
+	if len(candidates) == 0 {
+		return nil, ErrNoExif
+	}
+
+	return data[candidates[0].Offset:], nil
+}
@@ -0,0 +1,116 @@
+package exif
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestProprietaryData returns noise with a stray TIFF signature and, after
+// it, real EXIF data at an odd offset, like a camera's raw format.
+func getTestProprietaryData() (data []byte, exifOffset int, exifData []byte) {
+	exifData, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	r := rand.New(rand.NewSource(1))
+
+	noise := make([]byte, 1001)
+	r.Read(noise)
+
+	// A header followed by noise.
+	copy(noise[100:], []byte{'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08})
+
+	b := new(bytes.Buffer)
+	b.Write(noise)
+	b.Write(exifData)
+	b.Write(noise)
+
+	return b.Bytes(), len(noise), exifData
+}
+
+func TestSearchExifCandidates(t *testing.T) {
+	data, exifOffset, _ := getTestProprietaryData()
+
+	candidates := SearchExifCandidates(data)
+	if len(candidates) != 1 {
+		t.Fatalf("Expected one candidate: %v", candidates)
+	}
+
+	ec := candidates[0]
+	if ec.Offset != exifOffset {
+		t.Fatalf("Offset not correct: (%d)", ec.Offset)
+	} else if ec.Header.ByteOrder != binary.LittleEndian {
+		t.Fatalf("Byte order not correct: %v", ec.Header.ByteOrder)
+	} else if ec.Score < ExifCandidateMinScore {
+		t.Fatalf("Score not correct: (%d)", ec.Score)
+	}
+}
+
+func TestSearchExifCandidates_Prefix(t *testing.T) {
+	exifData := getTestExifData()
+
+	b := new(bytes.Buffer)
+	b.WriteString("junk")
+	b.Write(exifData)
+	b.Write(exifPrefix)
+	b.Write(exifData)
+
+	candidates := SearchExifCandidates(b.Bytes())
+	if len(candidates) < 2 {
+		t.Fatalf("Expected both copies: %v", candidates)
+	} else if candidates[0].Offset != 4+len(exifData)+len(exifPrefix) {
+		t.Fatalf("Expected the prefixed copy to be the most likely: %v", candidates)
+	} else if candidates[0].Score != candidates[1].Score+exifPrefixScore {
+		t.Fatalf("Prefix score not correct: %v", candidates)
+	}
+}
+
+func TestSearchExifCandidates_Noise(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	noise := make([]byte, 1<<16)
+	r.Read(noise)
+
+	// Plant signatures throughout.
+	for i := 0; i < len(noise)-8; i += 997 {
+		copy(noise[i:], ExifBigEndianSignature[:])
+		binary.BigEndian.PutUint32(noise[i+4:], 8)
+	}
+
+	if candidates := SearchExifCandidates(noise); len(candidates) != 0 {
+		t.Fatalf("Expected no candidates in noise: %v", candidates)
+	}
+}
+
+func TestSearchAndExtractExifHeuristically(t *testing.T) {
+	data, _, exifData := getTestProprietaryData()
+
+	// The simple search stops at the stray header.
+	rawExif, err := SearchAndExtractExif(data)
+	log.PanicIf(err)
+
+	if bytes.HasPrefix(rawExif, exifData) == true {
+		t.Fatalf("Expected the simple search to find the stray header.")
+	}
+
+	rawExif, err = SearchAndExtractExifHeuristically(data)
+	log.PanicIf(err)
+
+	if bytes.HasPrefix(rawExif, exifData) == false {
+		t.Fatalf("EXIF not found.")
+	}
+
+	_, _, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	_, err = SearchAndExtractExifHeuristically([]byte("no exif here"))
+	if err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif: %v", err)
+	}
+}
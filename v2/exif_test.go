@@ -13,6 +13,7 @@ import (
 	"github.com/dsoprea/go-logging"
 
 	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
 )
 
 func TestVisit(t *testing.T) {
@@ -393,3 +394,231 @@ func ExampleBuildExifHeader() {
 	fmt.Printf("%v\n", eh)
 	// Output: ExifHeader<BYTE-ORDER=[BigEndian] FIRST-IFD-OFFSET=(0x11223344)>
 }
+
+// getTestTagRawValues describes every tag under the IFD with its raw value.
+// The offsets of child IFDs and the thumbnail are skipped since they depend
+// on the layout.
+func getTestTagRawValues(ifd *Ifd) []string {
+	descriptions := make([]string, 0)
+
+	for _, ite := range ifd.DumpTags() {
+		if ite.ChildIfdPath() != "" || ite.TagId() == ThumbnailOffsetTagId {
+			continue
+		}
+
+		rawBytes, err := ite.GetRawBytes()
+		log.PanicIf(err)
+
+		descriptions = append(descriptions, fmt.Sprintf("%s %x", ite, rawBytes))
+	}
+
+	return descriptions
+}
+
+func TestCollectWithReader(t *testing.T) {
+	rawExif, err := SearchFileAndExtractExif(getTestImageFilepath())
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	_, expectedIndex, err := Collect(im, ti, rawExif)
+	log.PanicIf(err)
+
+	expectedTags := getTestTagRawValues(expectedIndex.RootIfd)
+
+	eh, index, err := CollectWithReader(im, ti, bytes.NewReader(rawExif), int64(len(rawExif)))
+	log.PanicIf(err)
+
+	tags := getTestTagRawValues(index.RootIfd)
+
+	if eh.ByteOrder != expectedIndex.RootIfd.ByteOrder {
+		t.Fatalf("Byte order not correct: %v", eh.ByteOrder)
+	} else if reflect.DeepEqual(tags, expectedTags) == false {
+		t.Fatalf("Tags not correct.")
+	}
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	expectedThumbnail, err := expectedIndex.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	if bytes.Equal(thumbnail, expectedThumbnail) == false {
+		t.Fatalf("Thumbnail not correct.")
+	}
+}
+
+func TestCollectWithReader_ReadsLittle(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	// Something large, like the image data of a RAW file, follows the IFDs.
+	data := append(rawExif, make([]byte, 10*1024*1024)...)
+
+	cra := &countingReaderAt{
+		r: bytes.NewReader(data),
+	}
+
+	_, index, err := CollectWithReader(NewIfdMappingWithStandard(), NewTagIndex(), cra, int64(len(data)))
+	log.PanicIf(err)
+
+	model, err := getIfdTagString(index.RootIfd, "Model")
+	log.PanicIf(err)
+
+	if model != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not correct: [%s]", model)
+	} else if cra.count > len(rawExif) {
+		t.Fatalf("Too much read: (%d) > (%d)", cra.count, len(rawExif))
+	}
+
+	_, _, err = CollectWithReader(NewIfdMappingWithStandard(), NewTagIndex(), bytes.NewReader([]byte("abc")), 3)
+	if err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif: %v", err)
+	}
+}
+
+func TestCollectWithReadSeeker(t *testing.T) {
+	f, err := os.Open(getTestImageFilepath())
+	log.PanicIf(err)
+
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	log.PanicIf(err)
+
+	rawExif, err := SearchAndExtractExif(data)
+	log.PanicIf(err)
+
+	_, index, err := CollectWithReadSeeker(NewIfdMappingWithStandard(), NewTagIndex(), bytes.NewReader(rawExif))
+	log.PanicIf(err)
+
+	results, err := index.RootIfd.FindTagWithName("Model")
+	log.PanicIf(err)
+
+	value, err := results[0].Value()
+	log.PanicIf(err)
+
+	if value != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not correct: [%v]", value)
+	}
+}
+
+func TestCollectShallow(t *testing.T) {
+	_, index, err := CollectShallow(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	if len(index.Ifds) != 1 {
+		t.Fatalf("Expected only IFD0: (%d)", len(index.Ifds))
+	} else if index.RootIfd.NextIfd != nil || len(index.RootIfd.Children) != 0 {
+		t.Fatalf("Expected no linked IFDs.")
+	}
+
+	value, err := getIfdTagString(index.RootIfd, "Model")
+	log.PanicIf(err)
+
+	if value != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not correct: [%s]", value)
+	}
+}
+
+func TestCollectIfds(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	// The Exif IFD has to be parsed to reach Iop.
+	_, index, err := CollectIfds(im, ti, getTestExifData(), []string{exifcommon.IfdPathStandardExifIop, "IFD1"})
+	log.PanicIf(err)
+
+	fqIfdPaths := make([]string, len(index.Ifds))
+	for i, ifd := range index.Ifds {
+		fqIfdPaths[i] = ifd.FqIfdPath
+	}
+
+	expected := []string{
+		exifcommon.IfdPathStandard,
+		exifcommon.IfdPathStandardExif,
+		"IFD1",
+		exifcommon.IfdPathStandardExifIop,
+	}
+
+	if reflect.DeepEqual(fqIfdPaths, expected) == false {
+		t.Fatalf("IFDs not correct: %v", fqIfdPaths)
+	} else if _, found := index.Lookup[exifcommon.IfdPathStandardGps]; found == true {
+		t.Fatalf("GPS IFD should have been skipped.")
+	}
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	if len(thumbnail) != 21491 {
+		t.Fatalf("Thumbnail not correct: (%d)", len(thumbnail))
+	}
+
+	// A nil list is the same as `Collect()`.
+	_, index, err = CollectIfds(im, ti, getTestExifData(), nil)
+	log.PanicIf(err)
+
+	if len(index.Ifds) != 5 {
+		t.Fatalf("Expected all IFDs: (%d)", len(index.Ifds))
+	}
+}
+
+func TestCollectIfds_ChainPredecessors(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	exifIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandardExif, exifcommon.TestDefaultByteOrder)
+
+	err := exifIb.SetStandardWithName("DateTimeOriginal", "2019:01:02 03:04:05")
+	log.PanicIf(err)
+
+	err = rootIb.AddChildIb(exifIb)
+	log.PanicIf(err)
+
+	for i := 0; i < 2; i++ {
+		_, err := rootIb.AppendPage()
+		log.PanicIf(err)
+	}
+
+	pageExifIb := NewIfdBuilder(im, ti, "IFD2/Exif", exifcommon.TestDefaultByteOrder)
+
+	err = pageExifIb.SetStandardWithName("DateTimeOriginal", "2020:01:02 03:04:05")
+	log.PanicIf(err)
+
+	err = rootIb.Pages()[2].AddChildIb(pageExifIb)
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	// IFD0 and IFD1 have to be parsed to reach IFD2, but not IFD0's Exif IFD.
+	_, index, err := CollectIfds(im, ti, exifData, []string{"IFD2/Exif"})
+	log.PanicIf(err)
+
+	fqIfdPaths := make([]string, len(index.Ifds))
+	for i, ifd := range index.Ifds {
+		fqIfdPaths[i] = ifd.FqIfdPath
+	}
+
+	expected := []string{
+		exifcommon.IfdPathStandard,
+		"IFD1",
+		"IFD2",
+		"IFD2/Exif",
+	}
+
+	if reflect.DeepEqual(fqIfdPaths, expected) == false {
+		t.Fatalf("IFDs not correct: %v", fqIfdPaths)
+	}
+
+	// A later IFD isn't needed to reach an earlier one.
+	_, index, err = CollectIfds(im, ti, exifData, []string{"IFD1"})
+	log.PanicIf(err)
+
+	if len(index.Ifds) != 2 || index.Ifds[1].FqIfdPath != "IFD1" {
+		t.Fatalf("Expected IFD0 and IFD1: %v", index.Ifds)
+	}
+}
@@ -0,0 +1,319 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/dsoprea/go-logging"
+	goerrors "github.com/go-errors/errors"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// The EXIF versions that can be targeted (see
+	// `IfdByteEncoder.SetTargetExifVersion()`), as they're written in the
+	// ExifVersion tag.
+	ExifVersion221 = "0221"
+	ExifVersion230 = "0230"
+	ExifVersion231 = "0231"
+	ExifVersion232 = "0232"
+	ExifVersion300 = "0300"
+
+	// exifVersionUtf8 is the first EXIF version with the UTF-8 type.
+	exifVersionUtf8 = ExifVersion300
+)
+
+var (
+	// exifVersions are the versions that can be targeted.
+	exifVersions = map[string]bool{
+		ExifVersion221: true,
+		ExifVersion230: true,
+		ExifVersion231: true,
+		ExifVersion232: true,
+		ExifVersion300: true,
+	}
+)
+
+// ExifVersionPolicy determines what's done with tags that are newer than the
+// targeted EXIF version.
+type ExifVersionPolicy int
+
+const (
+	// ExifVersionPolicyDowngrade moves the values of the newer tags into the
+	// older tags that they superseded, if those aren't already set, and then
+	// removes them.
+	ExifVersionPolicyDowngrade ExifVersionPolicy = iota
+
+	// ExifVersionPolicyRefuse fails with an `ExifVersionError` instead.
+	ExifVersionPolicyRefuse
+)
+
+var (
+	// ErrTagNotInExifVersion means that a tag is newer than the targeted EXIF
+	// version.
+	ErrTagNotInExifVersion = errors.New("tag not in EXIF version")
+)
+
+// ExifVersionError describes a tag that's newer than the targeted EXIF
+// version.
+type ExifVersionError struct {
+	// Version is the targeted version and Since is the version that
+	// introduced the tag.
+	Version string
+	Since   string
+
+	IfdPath string
+	TagId   uint16
+	TagName string
+}
+
+// Error returns the tag and the versions.
+func (eve *ExifVersionError) Error() string {
+	return fmt.Sprintf("tag [%s] (0x%04x) in IFD [%s] is from EXIF version [%s], which is newer than [%s]", eve.TagName, eve.TagId, eve.IfdPath, eve.Since, eve.Version)
+}
+
+// Is returns true for `ErrTagNotInExifVersion`.
+func (eve *ExifVersionError) Is(target error) bool {
+	return target == ErrTagNotInExifVersion
+}
+
+// AsExifVersionError returns the `ExifVersionError` in the given error, which
+// may have since been wrapped with a stack.
+func AsExifVersionError(err error) (eve *ExifVersionError, found bool) {
+	for err != nil {
+		switch e := err.(type) {
+		case *ExifVersionError:
+			return e, true
+		case *goerrors.Error:
+			err = e.Err
+		default:
+			wrapper, ok := err.(interface{ Unwrap() error })
+			if ok == false {
+				return nil, false
+			}
+
+			err = wrapper.Unwrap()
+		}
+	}
+
+	return nil, false
+}
+
+// versionedTag is a tag that isn't in every EXIF version.
+type versionedTag struct {
+	ifdPath string
+	tagId   uint16
+	tagName string
+
+	// since is the first version that has the tag.
+	since string
+
+	// supersededTagId is the tag of IFD0 that has the same meaning in the
+	// versions before, if there is one.
+	supersededTagId uint16
+}
+
+var (
+	// versionedTags are the tags that were added after Exif 2.21.
+	versionedTags = []versionedTag{
+		{exifcommon.IfdPathStandardExif, sensitivityTypeTagId, "SensitivityType", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, standardOutputSensitivityTagId, "StandardOutputSensitivity", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, recommendedExposureIndexTagId, "RecommendedExposureIndex", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, isoSpeedTagId, "ISOSpeed", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, isoSpeedLatitudeyyyTagId, "ISOSpeedLatitudeyyy", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, isoSpeedLatitudezzzTagId, "ISOSpeedLatitudezzz", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, 0xa430, "CameraOwnerName", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, 0xa431, "BodySerialNumber", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, 0xa432, "LensSpecification", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, 0xa433, "LensMake", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, 0xa434, "LensModel", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardExif, 0xa435, "LensSerialNumber", ExifVersion230, 0},
+		{exifcommon.IfdPathStandardGps, 0x001f, "GPSHPositioningError", ExifVersion230, 0},
+
+		{exifcommon.IfdPathStandardExif, 0x9010, "OffsetTime", ExifVersion231, 0},
+		{exifcommon.IfdPathStandardExif, 0x9011, "OffsetTimeOriginal", ExifVersion231, 0},
+		{exifcommon.IfdPathStandardExif, 0x9012, "OffsetTimeDigitized", ExifVersion231, 0},
+		{exifcommon.IfdPathStandardExif, 0x9400, "Temperature", ExifVersion231, 0},
+		{exifcommon.IfdPathStandardExif, 0x9401, "Humidity", ExifVersion231, 0},
+		{exifcommon.IfdPathStandardExif, 0x9402, "Pressure", ExifVersion231, 0},
+		{exifcommon.IfdPathStandardExif, 0x9403, "WaterDepth", ExifVersion231, 0},
+		{exifcommon.IfdPathStandardExif, 0x9404, "Acceleration", ExifVersion231, 0},
+		{exifcommon.IfdPathStandardExif, 0x9405, "CameraElevationAngle", ExifVersion231, 0},
+
+		{exifcommon.IfdPathStandardExif, 0xa460, "CompositeImage", ExifVersion232, 0},
+		{exifcommon.IfdPathStandardExif, 0xa461, "SourceImageNumberOfCompositeImage", ExifVersion232, 0},
+		{exifcommon.IfdPathStandardExif, 0xa462, "SourceExposureTimesOfCompositeImage", ExifVersion232, 0},
+
+		{exifcommon.IfdPathStandardExif, 0xa436, "ImageTitle", ExifVersion300, 0x010e},
+		{exifcommon.IfdPathStandardExif, 0xa437, "Photographer", ExifVersion300, 0x013b},
+		{exifcommon.IfdPathStandardExif, 0xa438, "ImageEditor", ExifVersion300, 0},
+		{exifcommon.IfdPathStandardExif, 0xa439, "CameraFirmware", ExifVersion300, 0},
+		{exifcommon.IfdPathStandardExif, 0xa43a, "RAWDevelopingSoftware", ExifVersion300, 0},
+		{exifcommon.IfdPathStandardExif, 0xa43b, "ImageEditingSoftware", ExifVersion300, 0x0131},
+		{exifcommon.IfdPathStandardExif, 0xa43c, "MetadataEditingSoftware", ExifVersion300, 0},
+	}
+)
+
+// NegotiateExifVersion makes the builder conform to the given EXIF version
+// (one of the ExifVersion* constants): it sets the ExifVersion tag, deals with
+// the tags that are newer than the version according to the policy, and
+// writes strings with the UTF-8 type if the version has it (and they aren't
+// seven-bit ASCII) or with the ASCII type if it doesn't. When downgrading, an
+// ISOSpeedRatings is derived from the newer sensitivity tags if there isn't
+// one, and ImageTitle, Photographer, and ImageEditingSoftware are moved to
+// ImageDescription, Artist, and Software. Nothing is changed if the policy
+// refuses a tag.
+func NegotiateExifVersion(rootIb *IfdBuilder, version string, policy ExifVersionPolicy) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if exifVersions[version] == false {
+		log.Panicf("EXIF version not supported: [%s]", version)
+	}
+
+	exifIb, _ := rootIb.ChildWithTagId(exifcommon.IfdExifId)
+	gpsIb, _ := rootIb.ChildWithTagId(exifcommon.IfdGpsId)
+
+	// newer are the tags that are newer than the version, along with the
+	// builders that they're in.
+	type newerTag struct {
+		vt versionedTag
+		ib *IfdBuilder
+	}
+
+	newer := make([]newerTag, 0)
+
+	for _, vt := range versionedTags {
+		if vt.since <= version {
+			continue
+		}
+
+		ib := exifIb
+		if vt.ifdPath == exifcommon.IfdPathStandardGps {
+			ib = gpsIb
+		}
+
+		if ib == nil {
+			continue
+		} else if _, err := ib.FindTag(vt.tagId); err != nil {
+			continue
+		}
+
+		if policy == ExifVersionPolicyRefuse {
+			eve := &ExifVersionError{
+				Version: version,
+				Since:   vt.since,
+				IfdPath: vt.ifdPath,
+				TagId:   vt.tagId,
+				TagName: vt.tagName,
+			}
+
+			return eve
+		}
+
+		newer = append(newer, newerTag{vt: vt, ib: ib})
+	}
+
+	if exifIb == nil {
+		exifIb, err = GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+		log.PanicIf(err)
+	}
+
+	err = exifIb.Set(NewBuilderTag(exifIb.ifdPath, exifVersionTagId, exifcommon.TypeUndefined, NewIfdBuilderTagValueFromBytes([]byte(version)), exifIb.byteOrder))
+	log.PanicIf(err)
+
+	if version < exifVersionSensitivityTags {
+		err := downgradeSensitivity(exifIb)
+		log.PanicIf(err)
+	}
+
+	for _, nt := range newer {
+		if nt.vt.supersededTagId != 0 {
+			if _, err := rootIb.FindTag(nt.vt.supersededTagId); err != nil {
+				bt, err := nt.ib.FindTag(nt.vt.tagId)
+				log.PanicIf(err)
+
+				if bt.value.IsBytes() == true {
+					supersededBt := NewBuilderTag(rootIb.ifdPath, nt.vt.supersededTagId, exifcommon.TypeAscii, NewIfdBuilderTagValueFromBytes(bt.value.Bytes()), rootIb.byteOrder)
+
+					err := rootIb.Set(supersededBt)
+					log.PanicIf(err)
+				}
+			}
+		}
+
+		_, err := nt.ib.DeleteAll(nt.vt.tagId)
+		log.PanicIf(err)
+	}
+
+	negotiateStringTypes(rootIb, version >= exifVersionUtf8)
+
+	return nil
+}
+
+// downgradeSensitivity sets ISOSpeedRatings from ISOSpeed,
+// StandardOutputSensitivity, or RecommendedExposureIndex (the first that's
+// there) if it's not already set. The newer tags are removed separately.
+func downgradeSensitivity(exifIb *IfdBuilder) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if _, err := exifIb.FindTag(isoSpeedRatingsTagId); err == nil {
+		return nil
+	}
+
+	for _, tagId := range []uint16{isoSpeedTagId, standardOutputSensitivityTagId, recommendedExposureIndexTagId} {
+		bt, err := exifIb.FindTag(tagId)
+		if err != nil || bt.typeId != exifcommon.TypeLong || bt.value.IsBytes() == false || len(bt.value.Bytes()) < 4 {
+			continue
+		}
+
+		value := exifIb.byteOrder.Uint32(bt.value.Bytes())
+		if value > isoSpeedRatingsMaximum {
+			value = isoSpeedRatingsMaximum
+		}
+
+		raw := make([]byte, 2)
+		exifIb.byteOrder.PutUint16(raw, uint16(value))
+
+		err = exifIb.Set(NewBuilderTag(exifIb.ifdPath, isoSpeedRatingsTagId, exifcommon.TypeShort, NewIfdBuilderTagValueFromBytes(raw), exifIb.byteOrder))
+		log.PanicIf(err)
+
+		break
+	}
+
+	return nil
+}
+
+// negotiateStringTypes gives the strings of the builder, its children, and
+// the builders chained after it the UTF-8 type if it's allowed and they
+// aren't seven-bit ASCII, or the ASCII type if it isn't.
+func negotiateStringTypes(ib *IfdBuilder, allowUtf8 bool) {
+	for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+		for _, bt := range thisIb.tags {
+			if bt.value.IsIb() == true {
+				negotiateStringTypes(bt.value.Ib(), allowUtf8)
+				continue
+			} else if bt.value.IsBytes() == false {
+				continue
+			}
+
+			if allowUtf8 == false && bt.typeId == exifcommon.TypeUtf8 {
+				bt.typeId = exifcommon.TypeAscii
+			} else if allowUtf8 == true && bt.typeId == exifcommon.TypeAscii {
+				raw := bt.value.Bytes()
+				if exifcommon.IsAscii(raw) == false && utf8.Valid(raw) == true {
+					bt.typeId = exifcommon.TypeUtf8
+				}
+			}
+		}
+	}
+}
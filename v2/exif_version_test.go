@@ -0,0 +1,165 @@
+package exif
+
+import (
+	"errors"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestExifVersionIb returns a builder with Exif 3.0 tags: ISOSpeed,
+// OffsetTime, Photographer, and an ImageDescription with the UTF-8 type.
+func getTestExifVersionIb() *IfdBuilder {
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x010e, Raw: []byte("Caf\xc3\xa9\x00"), Type: exifcommon.TypeUtf8},
+			{Id: 0x010f, Value: "Acme"},
+		},
+		Children: []exiftest.Child{
+			{
+				TagId: exifcommon.IfdExifId,
+				Ifd: &exiftest.Ifd{
+					Tags: []exiftest.Tag{
+						{Id: sensitivityTypeTagId, Value: []uint16{SensitivityTypeIsoSpeed}},
+						{Id: isoSpeedTagId, Value: []uint32{800}},
+						{Id: 0x9010, Value: "+01:00"},
+						{Id: 0xa437, Value: "Jane Doe"},
+					},
+				},
+			},
+		},
+	}
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	return NewIfdBuilderFromExistingChain(index.RootIfd)
+}
+
+// getTestEncodedExifVersion encodes the builder for the version and returns
+// the index of the result.
+func getTestEncodedExifVersion(ib *IfdBuilder, version string) IfdIndex {
+	ibe := NewIfdByteEncoder()
+	ibe.SetTargetExifVersion(version, ExifVersionPolicyDowngrade)
+
+	rawExif, err := ibe.EncodeToExif(ib)
+	log.PanicIf(err)
+
+	if size, err := ibe.EncodedSize(ib); err != nil || size != uint32(len(rawExif)) {
+		log.Panicf("encoded size not correct: (%d) != (%d) %v", size, len(rawExif), err)
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestIfdByteEncoder_SetTargetExifVersion_Downgrade(t *testing.T) {
+	ib := getTestExifVersionIb()
+
+	index := getTestEncodedExifVersion(ib, ExifVersion221)
+	exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+	version, err := exifIfd.EntriesByTagId[exifVersionTagId][0].GetRawBytes()
+	log.PanicIf(err)
+
+	if string(version) != ExifVersion221 {
+		t.Fatalf("ExifVersion not correct: [%s]", version)
+	}
+
+	for _, tagId := range []uint16{sensitivityTypeTagId, isoSpeedTagId, 0x9010, 0xa437} {
+		if len(exifIfd.EntriesByTagId[tagId]) != 0 {
+			t.Fatalf("Tag (0x%04x) not removed.", tagId)
+		}
+	}
+
+	iso, err := GetIso(index)
+	log.PanicIf(err)
+
+	if iso.Value != 800 || iso.Source != "Exif/ISOSpeedRatings" {
+		t.Fatalf("ISO not downgraded: %s", iso)
+	}
+
+	if artist, err := getIfdTagString(index.RootIfd, "Artist"); err != nil || artist != "Jane Doe" {
+		t.Fatalf("Photographer not moved to Artist: [%s] %v", artist, err)
+	} else if ite := index.RootIfd.EntriesByTagId[0x010e][0]; ite.TagType() != exifcommon.TypeAscii {
+		t.Fatalf("UTF-8 string not written as ASCII: [%s]", ite.TagType())
+	}
+
+	// The builder is left as it was.
+
+	exifIb, err := ib.ChildWithTagId(exifcommon.IfdExifId)
+	log.PanicIf(err)
+
+	if _, err := exifIb.FindTag(isoSpeedTagId); err != nil {
+		t.Fatalf("Builder not restored: %v", err)
+	} else if bt, err := ib.FindTag(0x010e); err != nil || bt.typeId != exifcommon.TypeUtf8 {
+		t.Fatalf("Builder type not restored.")
+	} else if _, err := ib.FindTag(0x013b); err == nil {
+		t.Fatalf("Builder not restored: Artist still set.")
+	}
+}
+
+func TestIfdByteEncoder_SetTargetExifVersion_Utf8(t *testing.T) {
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x010e, Raw: []byte("Caf\xc3\xa9\x00"), Type: exifcommon.TypeAscii},
+			{Id: 0x010f, Value: "Acme"},
+		},
+	}
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	index = getTestEncodedExifVersion(NewIfdBuilderFromExistingChain(index.RootIfd), ExifVersion300)
+
+	if ite := index.RootIfd.EntriesByTagId[0x010e][0]; ite.TagType() != exifcommon.TypeUtf8 {
+		t.Fatalf("Non-ASCII string not written as UTF-8: [%s]", ite.TagType())
+	} else if ite := index.RootIfd.EntriesByTagId[0x010f][0]; ite.TagType() != exifcommon.TypeAscii {
+		t.Fatalf("ASCII string not written as ASCII: [%s]", ite.TagType())
+	}
+
+	exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+	version, err := exifIfd.EntriesByTagId[exifVersionTagId][0].GetRawBytes()
+	log.PanicIf(err)
+
+	if string(version) != ExifVersion300 {
+		t.Fatalf("ExifVersion not added: [%s]", version)
+	}
+}
+
+func TestIfdByteEncoder_SetTargetExifVersion_Refuse(t *testing.T) {
+	ibe := NewIfdByteEncoder()
+	ibe.SetTargetExifVersion(ExifVersion230, ExifVersionPolicyRefuse)
+
+	_, err := ibe.EncodeToExif(getTestExifVersionIb())
+
+	eve, found := AsExifVersionError(err)
+	if found != true {
+		t.Fatalf("Expected ExifVersionError: %v", err)
+	} else if errors.Is(eve, ErrTagNotInExifVersion) == false {
+		t.Fatalf("Expected ErrTagNotInExifVersion: %v", err)
+	} else if eve.TagName != "OffsetTime" || eve.Since != ExifVersion231 {
+		t.Fatalf("Error not correct: %v", err)
+	}
+
+	// Everything is in Exif 3.0.
+
+	ibe.SetTargetExifVersion(ExifVersion300, ExifVersionPolicyRefuse)
+
+	_, err = ibe.EncodeToExif(getTestExifVersionIb())
+	log.PanicIf(err)
+}
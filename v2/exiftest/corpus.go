@@ -0,0 +1,164 @@
+package exiftest
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// seedValues has one value of every tag type. They're all long enough to
+	// be stored outside of the entry.
+	seedValues = []struct {
+		name string
+		tag  Tag
+	}{
+		{"byte", Tag{Raw: []byte{1, 2, 3, 4, 5}, Type: exifcommon.TypeByte}},
+		{"ascii", Tag{Value: "seed value"}},
+		{"short", Tag{Value: []uint16{1, 2, 3}}},
+		{"long", Tag{Value: []uint32{1, 2}}},
+		{"rational", Tag{Value: []exifcommon.Rational{{Numerator: 1, Denominator: 2}}}},
+		{"undefined", Tag{Raw: []byte("0230"), Type: exifcommon.TypeUndefined}},
+		{"slong", Tag{Value: []int32{-1, 1}}},
+		{"srational", Tag{Value: []exifcommon.SignedRational{{Numerator: -1, Denominator: 2}}}},
+	}
+)
+
+// SeedCorpus returns inputs for a fuzzer, keyed by a descriptive name. It
+// covers both byte-orders, every tag type, IFD chains, and the TIFF, JPEG,
+// and PNG containers.
+func SeedCorpus() (seeds map[string][]byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	seeds = make(map[string][]byte)
+
+	byteOrders := map[string]binary.ByteOrder{
+		"be": binary.BigEndian,
+		"le": binary.LittleEndian,
+	}
+
+	for byteOrderName, byteOrder := range byteOrders {
+		for _, sv := range seedValues {
+			tag := sv.tag
+			tag.Id = 0x9999
+
+			root := &Ifd{
+				Tags: []Tag{tag},
+			}
+
+			data, err := Build(root, byteOrder)
+			log.PanicIf(err)
+
+			seeds[fmt.Sprintf("tiff-%s-%s", byteOrderName, sv.name)] = data
+		}
+
+		realistic, err := Build(NewRealisticIfd(), byteOrder)
+		log.PanicIf(err)
+
+		seeds[fmt.Sprintf("tiff-%s-realistic", byteOrderName)] = realistic
+
+		root := NewRealisticIfd()
+		root.Next = &Ifd{
+			Tags: []Tag{
+				{Id: 0x0103, Value: []uint16{6}},
+			},
+		}
+
+		chain, err := Build(root, byteOrder)
+		log.PanicIf(err)
+
+		seeds[fmt.Sprintf("tiff-%s-chain", byteOrderName)] = chain
+	}
+
+	realistic := seeds["tiff-be-realistic"]
+
+	seeds["jpeg-realistic"] = WrapJpeg(realistic)
+	seeds["png-realistic"] = WrapPng(realistic)
+
+	return seeds, nil
+}
+
+// WriteSeedCorpus writes the seed corpus to the given directory, one file per
+// input, which is the layout that go-fuzz expects.
+func WriteSeedCorpus(dirpath string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	seeds, err := SeedCorpus()
+	log.PanicIf(err)
+
+	err = os.MkdirAll(dirpath, 0755)
+	log.PanicIf(err)
+
+	for name, data := range seeds {
+		err := ioutil.WriteFile(path.Join(dirpath, name), data, 0644)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// WrapJpeg returns a minimal JPEG stream with the EXIF data in an APP1
+// segment. There's no image data.
+func WrapJpeg(exifData []byte) []byte {
+	segmentLength := 2 + 6 + len(exifData)
+
+	data := []byte{0xff, 0xd8, 0xff, 0xe1, byte(segmentLength >> 8), byte(segmentLength)}
+	data = append(data, 'E', 'x', 'i', 'f', 0, 0)
+	data = append(data, exifData...)
+	data = append(data, 0xff, 0xd9)
+
+	return data
+}
+
+// WrapPng returns a minimal PNG stream with the EXIF data in an eXIf chunk.
+// There's no image data.
+func WrapPng(exifData []byte) []byte {
+	data := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+	ihdr := []byte{
+		0, 0, 0, 1, // Width
+		0, 0, 0, 1, // Height
+		8, 0, 0, 0, 0,
+	}
+
+	data = appendPngChunk(data, "IHDR", ihdr)
+	data = appendPngChunk(data, "eXIf", exifData)
+	data = appendPngChunk(data, "IEND", nil)
+
+	return data
+}
+
+func appendPngChunk(data []byte, chunkType string, payload []byte) []byte {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)))
+	copy(header[4:], chunkType)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(payload)
+
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+
+	data = append(data, header...)
+	data = append(data, payload...)
+	data = append(data, footer...)
+
+	return data
+}
@@ -0,0 +1,47 @@
+package exiftest
+
+import (
+	"testing"
+
+	"io/ioutil"
+	"os"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+)
+
+func TestSeedCorpus(t *testing.T) {
+	seeds, err := SeedCorpus()
+	log.PanicIf(err)
+
+	if len(seeds) != 2*(len(seedValues)+2)+2 {
+		t.Fatalf("Seed count not correct: (%d)", len(seeds))
+	}
+
+	for name, data := range seeds {
+		if exif.ParseBytesForFuzz(data) != 1 {
+			t.Fatalf("Seed [%s] was not parseable.", name)
+		}
+	}
+}
+
+func TestWriteSeedCorpus(t *testing.T) {
+	dirpath, err := ioutil.TempDir("", "exiftest")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(dirpath)
+
+	err = WriteSeedCorpus(dirpath)
+	log.PanicIf(err)
+
+	files, err := ioutil.ReadDir(dirpath)
+	log.PanicIf(err)
+
+	seeds, err := SeedCorpus()
+	log.PanicIf(err)
+
+	if len(files) != len(seeds) {
+		t.Fatalf("File count not correct: (%d)", len(files))
+	}
+}
@@ -0,0 +1,76 @@
+package exiftest
+
+import (
+	"math/rand"
+
+	"encoding/binary"
+)
+
+// byteOrderOf returns the byte-order declared by the header.
+func byteOrderOf(data []byte) binary.ByteOrder {
+	if len(data) >= 2 && data[0] == 'M' && data[1] == 'M' {
+		return binary.BigEndian
+	}
+
+	return binary.LittleEndian
+}
+
+func clone(data []byte) []byte {
+	copied := make([]byte, len(data))
+	copy(copied, data)
+
+	return copied
+}
+
+// Truncate returns a copy of the data cut off at the given length, like a
+// partial download.
+func Truncate(data []byte, length int) []byte {
+	if length > len(data) {
+		length = len(data)
+	}
+
+	return clone(data[:length])
+}
+
+// FlipBits returns a copy of the data with `count` randomly-chosen bits
+// inverted. The same seed always flips the same bits. The header is left
+// alone so that the damage isn't just rejected outright.
+func FlipBits(data []byte, seed int64, count int) []byte {
+	damaged := clone(data)
+	if len(damaged) <= headerSize {
+		return damaged
+	}
+
+	r := rand.New(rand.NewSource(seed))
+
+	for i := 0; i < count; i++ {
+		position := headerSize + r.Intn(len(damaged)-headerSize)
+		damaged[position] ^= 1 << uint(r.Intn(8))
+	}
+
+	return damaged
+}
+
+// PutUint16 returns a copy of the data with the value written at the offset
+// using the data's byte-order.
+func PutUint16(data []byte, offset int, value uint16) []byte {
+	damaged := clone(data)
+	byteOrderOf(data).PutUint16(damaged[offset:], value)
+
+	return damaged
+}
+
+// PutUint32 returns a copy of the data with the value written at the offset
+// using the data's byte-order.
+func PutUint32(data []byte, offset int, value uint32) []byte {
+	damaged := clone(data)
+	byteOrderOf(data).PutUint32(damaged[offset:], value)
+
+	return damaged
+}
+
+// SetFirstIfdOffset returns a copy of the data with the header pointing to a
+// different first IFD.
+func SetFirstIfdOffset(data []byte, offset uint32) []byte {
+	return PutUint32(data, 4, offset)
+}
@@ -0,0 +1,59 @@
+package exiftest
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestTruncate(t *testing.T) {
+	data, err := Build(NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	truncated := Truncate(data, 20)
+	if len(truncated) != 20 {
+		t.Fatalf("Length not correct: (%d)", len(truncated))
+	}
+
+	_, err = collect(truncated)
+	if err == nil {
+		t.Fatalf("Expected error for truncated data.")
+	}
+}
+
+func TestFlipBits(t *testing.T) {
+	data, err := Build(NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	damaged1 := FlipBits(data, 1, 10)
+	damaged2 := FlipBits(data, 1, 10)
+
+	if bytes.Equal(damaged1, damaged2) != true {
+		t.Fatalf("Damage not deterministic.")
+	} else if bytes.Equal(damaged1, data) == true {
+		t.Fatalf("Nothing was damaged.")
+	} else if bytes.Equal(damaged1[:headerSize], data[:headerSize]) != true {
+		t.Fatalf("Header was damaged.")
+	}
+}
+
+func TestSetFirstIfdOffset(t *testing.T) {
+	data, err := Build(NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	damaged := SetFirstIfdOffset(data, 0x1000)
+
+	if binary.LittleEndian.Uint32(damaged[4:]) != 0x1000 {
+		t.Fatalf("Offset not set.")
+	} else if binary.LittleEndian.Uint32(data[4:]) != headerSize {
+		t.Fatalf("Original was modified.")
+	}
+
+	_, err = collect(damaged)
+	if err == nil {
+		t.Fatalf("Expected error for bad offset.")
+	}
+}
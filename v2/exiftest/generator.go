@@ -0,0 +1,272 @@
+// Package exiftest generates EXIF data for tests. Blobs are built from a
+// simple description of the IFDs and tags, so tests can exercise specific
+// byte orders, layouts, and malformations without shipping binary fixtures.
+// The output starts with the TIFF header (what `exif.Collect()` expects).
+package exiftest
+
+import (
+	"sort"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	headerSize = 8
+	entrySize  = 12
+)
+
+// Tag describes one entry in an IFD.
+type Tag struct {
+	// Id is the tag ID.
+	Id uint16
+
+	// Value is any value accepted by `exifcommon.ValueEncoder`. It's ignored
+	// if `Raw` is set.
+	Value interface{}
+
+	// Raw is written verbatim as the value, with `Type` as the type. This
+	// allows values that the encoder won't produce.
+	Raw  []byte
+	Type exifcommon.TagTypePrimitive
+
+	// UnitCount, if not zero, is written in place of the real unit-count.
+	UnitCount uint32
+
+	// ValueOffset, if not zero, is written in place of the real offset of a
+	// value that doesn't fit in the entry. The value itself isn't stored.
+	ValueOffset uint32
+}
+
+// Child attaches a child IFD to its parent via the given tag.
+type Child struct {
+	TagId uint16
+	Ifd   *Ifd
+}
+
+// Ifd describes an IFD.
+type Ifd struct {
+	Tags     []Tag
+	Children []Child
+
+	// Next is the next IFD in the chain (e.g. IFD1 after IFD0).
+	Next *Ifd
+
+	// NextOffset, if not zero, is written in place of the offset of `Next`,
+	// e.g. to point back at an earlier IFD or past the end of the data.
+	NextOffset uint32
+
+	// KeepOrder writes the entries in the order given rather than sorted by
+	// tag ID, as the specification requires.
+	KeepOrder bool
+}
+
+// Build encodes the IFD chain. IFDs are written one after another, each
+// followed by its values and then its children.
+func Build(root *Ifd, byteOrder binary.ByteOrder) (data []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	w := &writer{
+		byteOrder: byteOrder,
+		ve:        exifcommon.NewValueEncoder(byteOrder),
+		data:      make([]byte, headerSize),
+	}
+
+	if byteOrder == binary.BigEndian {
+		copy(w.data, []byte{'M', 'M'})
+	} else {
+		copy(w.data, []byte{'I', 'I'})
+	}
+
+	byteOrder.PutUint16(w.data[2:], 0x002a)
+	byteOrder.PutUint32(w.data[4:], headerSize)
+
+	_, err = w.writeIfd(root)
+	log.PanicIf(err)
+
+	return w.data, nil
+}
+
+type writer struct {
+	byteOrder binary.ByteOrder
+	ve        *exifcommon.ValueEncoder
+	data      []byte
+}
+
+type entry struct {
+	tagId     uint16
+	tagType   exifcommon.TagTypePrimitive
+	unitCount uint32
+	value     []byte
+}
+
+func (w *writer) align() {
+	if len(w.data)%2 != 0 {
+		w.data = append(w.data, 0)
+	}
+}
+
+func (w *writer) writeIfd(ifd *Ifd) (offset uint32, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	w.align()
+
+	offset = uint32(len(w.data))
+
+	entryCount := len(ifd.Tags) + len(ifd.Children)
+	w.data = append(w.data, make([]byte, 2+entryCount*entrySize+4)...)
+
+	entries := make([]entry, 0, entryCount)
+
+	for _, tag := range ifd.Tags {
+		e, err := w.writeTag(tag)
+		log.PanicIf(err)
+
+		entries = append(entries, e)
+	}
+
+	for _, child := range ifd.Children {
+		childOffset, err := w.writeIfd(child.Ifd)
+		log.PanicIf(err)
+
+		value := make([]byte, 4)
+		w.byteOrder.PutUint32(value, childOffset)
+
+		e := entry{
+			tagId:     child.TagId,
+			tagType:   exifcommon.TypeLong,
+			unitCount: 1,
+			value:     value,
+		}
+
+		entries = append(entries, e)
+	}
+
+	if ifd.KeepOrder == false {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].tagId < entries[j].tagId
+		})
+	}
+
+	nextOffset := ifd.NextOffset
+	if nextOffset == 0 && ifd.Next != nil {
+		nextOffset, err = w.writeIfd(ifd.Next)
+		log.PanicIf(err)
+	}
+
+	table := w.data[offset:]
+
+	w.byteOrder.PutUint16(table, uint16(entryCount))
+
+	for i, e := range entries {
+		raw := table[2+i*entrySize:]
+
+		w.byteOrder.PutUint16(raw[0:], e.tagId)
+		w.byteOrder.PutUint16(raw[2:], uint16(e.tagType))
+		w.byteOrder.PutUint32(raw[4:], e.unitCount)
+		copy(raw[8:12], e.value)
+	}
+
+	w.byteOrder.PutUint32(table[2+entryCount*entrySize:], nextOffset)
+
+	return offset, nil
+}
+
+// writeTag stores the value if it doesn't fit in the entry and returns the
+// entry.
+func (w *writer) writeTag(tag Tag) (e entry, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	e.tagId = tag.Id
+
+	var encoded []byte
+	if tag.Raw != nil {
+		encoded = tag.Raw
+		e.tagType = tag.Type
+
+		// Undefined values are counted in bytes.
+		if tag.Type == exifcommon.TypeUndefined {
+			e.unitCount = uint32(len(encoded))
+		} else {
+			e.unitCount = uint32(len(encoded) / tag.Type.Size())
+		}
+	} else {
+		ed, err := w.ve.Encode(tag.Value)
+		log.PanicIf(err)
+
+		encoded = ed.Encoded
+		e.tagType = ed.Type
+		e.unitCount = ed.UnitCount
+	}
+
+	if tag.UnitCount != 0 {
+		e.unitCount = tag.UnitCount
+	}
+
+	e.value = make([]byte, 4)
+
+	if len(encoded) <= 4 {
+		copy(e.value, encoded)
+	} else if tag.ValueOffset != 0 {
+		w.byteOrder.PutUint32(e.value, tag.ValueOffset)
+	} else {
+		w.align()
+
+		w.byteOrder.PutUint32(e.value, uint32(len(w.data)))
+		w.data = append(w.data, encoded...)
+	}
+
+	return e, nil
+}
+
+// NewRealisticIfd returns a description of typical camera EXIF: a root IFD
+// with camera information, an Exif IFD with capture settings, and a GPS IFD.
+// Modify it as needed before calling `Build()`.
+func NewRealisticIfd() *Ifd {
+	exifIfd := &Ifd{
+		Tags: []Tag{
+			{Id: 0x829a, Value: []exifcommon.Rational{{Numerator: 1, Denominator: 250}}},
+			{Id: 0x829d, Value: []exifcommon.Rational{{Numerator: 28, Denominator: 10}}},
+			{Id: 0x8827, Value: []uint16{400}},
+			{Id: 0x9003, Value: "2020:01:02 03:04:05"},
+			{Id: 0x920a, Value: []exifcommon.Rational{{Numerator: 50, Denominator: 1}}},
+		},
+	}
+
+	gpsIfd := &Ifd{
+		Tags: []Tag{
+			{Id: 0x0001, Value: "N"},
+			{Id: 0x0002, Value: []exifcommon.Rational{{Numerator: 26, Denominator: 1}, {Numerator: 35, Denominator: 1}, {Numerator: 12, Denominator: 1}}},
+			{Id: 0x0003, Value: "W"},
+			{Id: 0x0004, Value: []exifcommon.Rational{{Numerator: 80, Denominator: 1}, {Numerator: 3, Denominator: 1}, {Numerator: 13, Denominator: 1}}},
+		},
+	}
+
+	return &Ifd{
+		Tags: []Tag{
+			{Id: 0x010f, Value: "Canon"},
+			{Id: 0x0110, Value: "Canon EOS 5D Mark III"},
+			{Id: 0x0112, Value: []uint16{1}},
+			{Id: 0x0132, Value: "2020:01:02 03:04:05"},
+		},
+		Children: []Child{
+			{TagId: exifcommon.IfdExifId, Ifd: exifIfd},
+			{TagId: exifcommon.IfdGpsId, Ifd: gpsIfd},
+		},
+	}
+}
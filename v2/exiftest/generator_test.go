@@ -0,0 +1,123 @@
+package exiftest
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func collect(data []byte) (index exif.IfdIndex, err error) {
+	im := exif.NewIfdMappingWithStandard()
+	ti := exif.NewTagIndex()
+
+	_, index, err = exif.Collect(im, ti, data)
+	return index, err
+}
+
+func TestBuild(t *testing.T) {
+	for _, byteOrder := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		data, err := Build(NewRealisticIfd(), byteOrder)
+		log.PanicIf(err)
+
+		index, err := collect(data)
+		log.PanicIf(err)
+
+		if index.RootIfd.ByteOrder != byteOrder {
+			t.Fatalf("Byte-order not correct: %v", index.RootIfd.ByteOrder)
+		}
+
+		results, err := index.RootIfd.FindTagWithName("Model")
+		log.PanicIf(err)
+
+		value, err := results[0].Value()
+		log.PanicIf(err)
+
+		if value != "Canon EOS 5D Mark III" {
+			t.Fatalf("Model not correct: [%v]", value)
+		}
+
+		gpsIfd, err := index.RootIfd.ChildWithIfdPath(exifcommon.IfdPathStandardGps)
+		log.PanicIf(err)
+
+		gi, err := gpsIfd.GpsInfo()
+		log.PanicIf(err)
+
+		if gi.Latitude.Degrees != 26 || gi.Longitude.Orientation != 'W' {
+			t.Fatalf("GPS not correct: %s", gi)
+		}
+	}
+}
+
+func TestBuild_Next(t *testing.T) {
+	root := NewRealisticIfd()
+	root.Next = &Ifd{
+		Tags: []Tag{
+			{Id: 0x0103, Value: []uint16{6}},
+		},
+	}
+
+	data, err := Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	index, err := collect(data)
+	log.PanicIf(err)
+
+	if index.RootIfd.NextIfd == nil {
+		t.Fatalf("Next IFD not found.")
+	}
+}
+
+func TestBuild_ValueOffset(t *testing.T) {
+	root := &Ifd{
+		Tags: []Tag{
+			{Id: 0x0110, Value: "a long model name", ValueOffset: 0xfffffff0},
+		},
+	}
+
+	data, err := Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	// The table is at offset 8: the count and then the first entry.
+	valueOffset := binary.BigEndian.Uint32(data[8+2+8:])
+	if valueOffset != 0xfffffff0 {
+		t.Fatalf("Offset not overridden: (0x%08x)", valueOffset)
+	}
+
+	index, err := collect(data)
+	log.PanicIf(err)
+
+	results, err := index.RootIfd.FindTagWithName("Model")
+	log.PanicIf(err)
+
+	_, err = results[0].Value()
+	if err == nil {
+		t.Fatalf("Expected error for bad offset.")
+	}
+}
+
+func TestBuild_Raw(t *testing.T) {
+	root := &Ifd{
+		Tags: []Tag{
+			{Id: 0x0112, Raw: []byte{0, 1, 0, 2}, Type: exifcommon.TypeShort},
+		},
+	}
+
+	data, err := Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	index, err := collect(data)
+	log.PanicIf(err)
+
+	value, err := index.RootIfd.Entries[0].Value()
+	log.PanicIf(err)
+
+	shorts := value.([]uint16)
+	if len(shorts) != 2 || shorts[0] != 1 || shorts[1] != 2 {
+		t.Fatalf("Value not correct: %v", shorts)
+	}
+}
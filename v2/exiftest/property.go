@@ -0,0 +1,261 @@
+package exiftest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// generatedTagIdBase is the first of the tag IDs that generated tags use.
+	// They're unassigned, so the decoder doesn't treat them as known tags.
+	generatedTagIdBase = 0xe000
+
+	// generatedTagIdCount is the number of tag IDs that generated tags use.
+	generatedTagIdCount = 0x1000
+)
+
+var (
+	// GeneratedFqIfdPaths are the IFDs that generated tags are put in.
+	GeneratedFqIfdPaths = []string{
+		exifcommon.IfdPathStandard,
+		exifcommon.IfdPathStandardExif,
+		exifcommon.IfdPathStandardExifIop,
+		exifcommon.IfdPathStandardGps,
+		"IFD1",
+	}
+
+	// GeneratedTypes are the types that generated tags have.
+	GeneratedTypes = []exifcommon.TagTypePrimitive{
+		exifcommon.TypeByte,
+		exifcommon.TypeAscii,
+		exifcommon.TypeShort,
+		exifcommon.TypeLong,
+		exifcommon.TypeRational,
+		exifcommon.TypeUndefined,
+		exifcommon.TypeSignedLong,
+		exifcommon.TypeSignedRational,
+		exifcommon.TypeUtf8,
+	}
+)
+
+// GeneratedTag is a random tag of a `TagSet`.
+type GeneratedTag struct {
+	// FqIfdPath is the IFD that the tag is in (one of `GeneratedFqIfdPaths`).
+	FqIfdPath string
+
+	// Id is unique within the IFD.
+	Id uint16
+
+	Type exifcommon.TagTypePrimitive
+
+	// Value is what the tag decodes to: a string for ASCII and UTF-8, and a
+	// slice of the type's Go type for the others (`[]byte` for undefined).
+	Value interface{}
+}
+
+// String returns a descriptive string.
+func (gt GeneratedTag) String() string {
+	return fmt.Sprintf("GeneratedTag<FQ-IFD-PATH=[%s] ID=(0x%04x) TYPE=[%s] VALUE=[%v]>", gt.FqIfdPath, gt.Id, gt.Type, gt.Value)
+}
+
+// TagSet is a random, valid set of tags along with the byte-order to encode
+// them with. It implements `quick.Generator`, so it can be the argument of a
+// property checked by `testing/quick`. For other property-testing libraries,
+// derive the `*rand.Rand` from a drawn seed and call `NewTagSet()`.
+type TagSet struct {
+	ByteOrder binary.ByteOrder
+	Tags      []GeneratedTag
+}
+
+// String returns a descriptive string.
+func (ts TagSet) String() string {
+	return fmt.Sprintf("TagSet<BYTE-ORDER=[%v] TAGS=(%d)>", ts.ByteOrder, len(ts.Tags))
+}
+
+// NewTagSet returns a random tag set. `size` bounds the number of tags and
+// the number of units of each.
+func NewTagSet(r *rand.Rand, size int) TagSet {
+	if size < 1 {
+		size = 1
+	}
+
+	ts := TagSet{
+		ByteOrder: binary.BigEndian,
+	}
+
+	if r.Intn(2) == 1 {
+		ts.ByteOrder = binary.LittleEndian
+	}
+
+	used := make(map[string]map[uint16]bool)
+
+	count := r.Intn(size) + 1
+	for i := 0; i < count; i++ {
+		fqIfdPath := GeneratedFqIfdPaths[r.Intn(len(GeneratedFqIfdPaths))]
+
+		if used[fqIfdPath] == nil {
+			used[fqIfdPath] = make(map[uint16]bool)
+		}
+
+		tagId := uint16(generatedTagIdBase + r.Intn(generatedTagIdCount))
+		if used[fqIfdPath][tagId] == true {
+			continue
+		}
+
+		used[fqIfdPath][tagId] = true
+
+		tagType := GeneratedTypes[r.Intn(len(GeneratedTypes))]
+
+		gt := GeneratedTag{
+			FqIfdPath: fqIfdPath,
+			Id:        tagId,
+			Type:      tagType,
+			Value:     newGeneratedValue(r, tagType, r.Intn(size)+1),
+		}
+
+		ts.Tags = append(ts.Tags, gt)
+	}
+
+	return ts
+}
+
+// Generate returns a random tag set (see `NewTagSet()`).
+func (TagSet) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(NewTagSet(r, size))
+}
+
+// newGeneratedValue returns a random value of the type with the given number
+// of units (characters, for strings).
+func newGeneratedValue(r *rand.Rand, tagType exifcommon.TagTypePrimitive, count int) interface{} {
+	switch tagType {
+	case exifcommon.TypeByte, exifcommon.TypeUndefined:
+		value := make([]byte, count)
+		r.Read(value)
+
+		return value
+	case exifcommon.TypeAscii:
+		// Trailing spaces are trimmed when strings are decoded, so the last
+		// character isn't one.
+		value := make([]byte, count)
+		for i := range value {
+			value[i] = byte(' ' + r.Intn('~'-' '+1))
+		}
+
+		value[count-1] = byte('!' + r.Intn('~'-'!'+1))
+
+		return string(value)
+	case exifcommon.TypeUtf8:
+		// At least one character isn't ASCII, so that it's encoded as UTF-8.
+		runes := []rune{'é'}
+		for i := 1; i < count; i++ {
+			runes = append(runes, rune('!'+r.Intn(0x2ff-'!')))
+		}
+
+		return string(runes)
+	case exifcommon.TypeShort:
+		value := make([]uint16, count)
+		for i := range value {
+			value[i] = uint16(r.Uint32())
+		}
+
+		return value
+	case exifcommon.TypeLong:
+		value := make([]uint32, count)
+		for i := range value {
+			value[i] = r.Uint32()
+		}
+
+		return value
+	case exifcommon.TypeRational:
+		value := make([]exifcommon.Rational, count)
+		for i := range value {
+			value[i] = exifcommon.Rational{
+				Numerator:   r.Uint32(),
+				Denominator: r.Uint32()/2 + 1,
+			}
+		}
+
+		return value
+	case exifcommon.TypeSignedLong:
+		value := make([]int32, count)
+		for i := range value {
+			value[i] = int32(r.Uint32())
+		}
+
+		return value
+	case exifcommon.TypeSignedRational:
+		value := make([]exifcommon.SignedRational, count)
+		for i := range value {
+			value[i] = exifcommon.SignedRational{
+				Numerator:   int32(r.Uint32()),
+				Denominator: r.Int31n(1<<30) + 1,
+			}
+		}
+
+		return value
+	}
+
+	log.Panicf("type not generated: [%s]", tagType)
+	return nil
+}
+
+// Ifd returns a description of the tags, to encode them with `Build()`
+// rather than with the library's encoder.
+func (ts TagSet) Ifd() *Ifd {
+	ve := exifcommon.NewValueEncoder(ts.ByteOrder)
+	ve.SetUtf8(true)
+
+	ifds := make(map[string]*Ifd)
+	for _, fqIfdPath := range GeneratedFqIfdPaths {
+		ifds[fqIfdPath] = new(Ifd)
+	}
+
+	for _, gt := range ts.Tags {
+		tag := Tag{
+			Id: gt.Id,
+		}
+
+		if gt.Type == exifcommon.TypeUndefined {
+			tag.Raw = gt.Value.([]byte)
+			tag.Type = gt.Type
+		} else if gt.Type == exifcommon.TypeUtf8 {
+			// The encoder picks the type of a string by its content.
+			ed, _ := ve.Encode(gt.Value)
+
+			tag.Raw = ed.Encoded
+			tag.Type = ed.Type
+		} else {
+			tag.Value = gt.Value
+		}
+
+		ifds[gt.FqIfdPath].Tags = append(ifds[gt.FqIfdPath].Tags, tag)
+	}
+
+	root := ifds[exifcommon.IfdPathStandard]
+	exifIfd := ifds[exifcommon.IfdPathStandardExif]
+
+	if len(ifds[exifcommon.IfdPathStandardExifIop].Tags) > 0 {
+		exifIfd.Children = append(exifIfd.Children, Child{TagId: exifcommon.IfdIopId, Ifd: ifds[exifcommon.IfdPathStandardExifIop]})
+	}
+
+	if len(exifIfd.Tags) > 0 || len(exifIfd.Children) > 0 {
+		root.Children = append(root.Children, Child{TagId: exifcommon.IfdExifId, Ifd: exifIfd})
+	}
+
+	if len(ifds[exifcommon.IfdPathStandardGps].Tags) > 0 {
+		root.Children = append(root.Children, Child{TagId: exifcommon.IfdGpsId, Ifd: ifds[exifcommon.IfdPathStandardGps]})
+	}
+
+	if len(ifds["IFD1"].Tags) > 0 {
+		root.Next = ifds["IFD1"]
+	}
+
+	return root
+}
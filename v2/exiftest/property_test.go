@@ -0,0 +1,81 @@
+package exiftest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestNewTagSet(t *testing.T) {
+	ts1 := NewTagSet(rand.New(rand.NewSource(1)), 30)
+	ts2 := NewTagSet(rand.New(rand.NewSource(1)), 30)
+
+	if reflect.DeepEqual(ts1, ts2) != true {
+		t.Fatalf("The same seed should generate the same tags.")
+	} else if len(ts1.Tags) == 0 || len(ts1.Tags) > 30 {
+		t.Fatalf("Tag count not correct: (%d)", len(ts1.Tags))
+	}
+
+	seen := make(map[string]bool)
+
+	for _, gt := range ts1.Tags {
+		key := fmt.Sprintf("%s/%04x", gt.FqIfdPath, gt.Id)
+		if seen[key] == true {
+			t.Fatalf("Tag repeated: %s", gt)
+		}
+
+		seen[key] = true
+
+		ed, err := exifcommon.NewValueEncoder(ts1.ByteOrder).Encode(gt.Value)
+		log.PanicIf(err)
+
+		if gt.Type != exifcommon.TypeUndefined && gt.Type != exifcommon.TypeUtf8 && ed.Type != gt.Type {
+			t.Fatalf("Value not of the type: %s", gt)
+		}
+	}
+}
+
+func TestTagSet_Ifd(t *testing.T) {
+	f := func(ts TagSet) bool {
+		data, err := Build(ts.Ifd(), ts.ByteOrder)
+		log.PanicIf(err)
+
+		index, err := collect(data)
+		log.PanicIf(err)
+
+		for _, gt := range ts.Tags {
+			found := false
+			for _, ifd := range index.Ifds {
+				if ifd.FqIfdPath != gt.FqIfdPath {
+					continue
+				}
+
+				if _, err := ifd.FindTagWithId(gt.Id); err == nil {
+					found = true
+				}
+			}
+
+			if found == false {
+				t.Logf("Tag not found: %s", gt)
+				return false
+			}
+		}
+
+		return true
+	}
+
+	config := &quick.Config{
+		MaxCount: 50,
+		Rand:     rand.New(rand.NewSource(1)),
+	}
+
+	if err := quick.Check(f, config); err != nil {
+		t.Fatalf("Tags not built: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package exifsymmetry checks that the library decodes tags to what they were
+// encoded from, for property tests of the package and of forks of it.
+package exifsymmetry
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// Check asserts that the tags decode to what they were encoded from. They're
+// encoded with the builder and `exif.IfdByteEncoder`, decoded with
+// `exif.Collect()`, and compared, and the decoded chain is encoded again and
+// has to produce the same bytes. The tags are also encoded independently with
+// `exiftest.Build()` and have to decode the same. An error describing the
+// first difference is returned. It's meant to be the property given to
+// `testing/quick` along with `exiftest.TagSet` as the generator:
+//
+//	f := func(ts exiftest.TagSet) bool {
+//		return exifsymmetry.Check(ts) == nil
+//	}
+//
+//	err := quick.Check(f, nil)
+func Check(ts exiftest.TagSet) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	im := exif.NewIfdMappingWithStandard()
+	ti := exif.NewTagIndex()
+
+	rootIb := exif.NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, ts.ByteOrder)
+
+	ve := exifcommon.NewValueEncoder(ts.ByteOrder)
+	ve.SetUtf8(true)
+
+	for _, gt := range ts.Tags {
+		ib, err := exif.GetOrCreateIbFromRootIb(rootIb, gt.FqIfdPath)
+		log.PanicIf(err)
+
+		var encoded []byte
+		if gt.Type == exifcommon.TypeUndefined {
+			encoded = gt.Value.([]byte)
+		} else {
+			ed, err := ve.Encode(gt.Value)
+			log.PanicIf(err)
+
+			if ed.Type != gt.Type {
+				log.Panicf("tag [%s] encoded as type [%s]", gt, ed.Type)
+			}
+
+			encoded = ed.Encoded
+		}
+
+		ifdPath, err := im.StripPathPhraseIndices(gt.FqIfdPath)
+		log.PanicIf(err)
+
+		bt := exif.NewBuilderTag(ifdPath, gt.Id, gt.Type, exif.NewIfdBuilderTagValueFromBytes(encoded), ts.ByteOrder)
+
+		err = ib.Add(bt)
+		log.PanicIf(err)
+	}
+
+	rawExif, err := exif.NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := exif.Collect(im, ti, rawExif)
+	log.PanicIf(err)
+
+	err = checkDecodedTagSet(index, ts)
+	log.PanicIf(err)
+
+	reencoded, err := exif.NewIfdByteEncoder().EncodeToExif(exif.NewIfdBuilderFromExistingChain(index.RootIfd))
+	log.PanicIf(err)
+
+	if bytes.Equal(reencoded, rawExif) != true {
+		log.Panicf("decoded tags encode differently: (%d) != (%d) bytes", len(reencoded), len(rawExif))
+	}
+
+	built, err := exiftest.Build(ts.Ifd(), ts.ByteOrder)
+	log.PanicIf(err)
+
+	_, index, err = exif.Collect(im, ti, built)
+	log.PanicIf(err)
+
+	err = checkDecodedTagSet(index, ts)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// checkDecodedTagSet asserts that every tag is in the index with the same
+// type and value.
+func checkDecodedTagSet(index exif.IfdIndex, ts exiftest.TagSet) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifds := make(map[string]*exif.Ifd)
+	for _, ifd := range index.Ifds {
+		ifds[ifd.FqIfdPath] = ifd
+	}
+
+	for _, gt := range ts.Tags {
+		ifd, found := ifds[gt.FqIfdPath]
+		if found == false {
+			log.Panicf("IFD of tag [%s] not decoded", gt)
+		}
+
+		results, err := ifd.FindTagWithId(gt.Id)
+		if err != nil {
+			log.Panicf("tag [%s] not decoded: %s", gt, err)
+		}
+
+		ite := results[0]
+
+		if ite.TagType() != gt.Type {
+			log.Panicf("tag [%s] decoded with type [%s]", gt, ite.TagType())
+		}
+
+		var value interface{}
+		if gt.Type == exifcommon.TypeUndefined {
+			value, err = ite.GetRawBytes()
+		} else {
+			value, err = ite.Value()
+		}
+
+		log.PanicIf(err)
+
+		if reflect.DeepEqual(value, gt.Value) != true {
+			log.Panicf("tag [%s] decoded as [%v]", gt, value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package exifsymmetry
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestCheck_Quick(t *testing.T) {
+	f := func(ts exiftest.TagSet) bool {
+		if err := Check(ts); err != nil {
+			t.Logf("%s: %s", ts, err)
+			return false
+		}
+
+		return true
+	}
+
+	config := &quick.Config{
+		MaxCount: 200,
+		Rand:     rand.New(rand.NewSource(1)),
+	}
+
+	if err := quick.Check(f, config); err != nil {
+		t.Fatalf("Codec not symmetric: %v", err)
+	}
+}
+
+func TestCheck_EveryType(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for _, byteOrder := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		ts := exiftest.TagSet{
+			ByteOrder: byteOrder,
+		}
+
+		for i, tagType := range exiftest.GeneratedTypes {
+			fqIfdPath := exiftest.GeneratedFqIfdPaths[i%len(exiftest.GeneratedFqIfdPaths)]
+
+			// Take the values from a generated set, with one short enough to
+			// be embedded in the entry and one that isn't.
+			for j, size := range []int{1, 9} {
+				var value interface{}
+				for value == nil {
+					for _, gt := range exiftest.NewTagSet(r, size).Tags {
+						if gt.Type == tagType {
+							value = gt.Value
+							break
+						}
+					}
+				}
+
+				gt := exiftest.GeneratedTag{
+					FqIfdPath: fqIfdPath,
+					Id:        uint16(0xe000 + i*2 + j),
+					Type:      tagType,
+					Value:     value,
+				}
+
+				ts.Tags = append(ts.Tags, gt)
+			}
+		}
+
+		err := Check(ts)
+		log.PanicIf(err)
+	}
+}
+
+func TestCheck_Mismatch(t *testing.T) {
+	ts := exiftest.TagSet{
+		ByteOrder: binary.BigEndian,
+		Tags: []exiftest.GeneratedTag{
+			{FqIfdPath: exifcommon.IfdPathStandard, Id: 0xe000, Type: exifcommon.TypeShort, Value: []uint32{1}},
+		},
+	}
+
+	if err := Check(ts); err == nil {
+		t.Fatalf("Expected error for a value that isn't of the type.")
+	}
+}
@@ -0,0 +1,310 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// exiftoolTagNames maps our tag names to exiftool's where they differ.
+	exiftoolTagNames = map[string]string{
+		"ISOSpeedRatings":       "ISO",
+		"PixelXDimension":       "ExifImageWidth",
+		"PixelYDimension":       "ExifImageHeight",
+		"ImageLength":           "ImageHeight",
+		"DateTime":              "ModifyDate",
+		"DateTimeDigitized":     "CreateDate",
+		"ExposureBiasValue":     "ExposureCompensation",
+		"InteroperabilityIndex": "InteropIndex",
+		"BodySerialNumber":      "SerialNumber",
+		"LensSpecification":     "LensInfo",
+		"CameraOwnerName":       "OwnerName",
+	}
+
+	// exiftoolConvertedTags are reported by exiftool in different units than
+	// they're stored in (e.g. APEX values as seconds and f-numbers) even with
+	// "-n", so they can't be compared directly.
+	exiftoolConvertedTags = map[string]struct{}{
+		"ShutterSpeedValue": {},
+		"ApertureValue":     {},
+		"MaxApertureValue":  {},
+	}
+
+	// exiftoolDecimalGpsTags are reported by exiftool as decimal degrees.
+	exiftoolDecimalGpsTags = map[string]struct{}{
+		"GPSLatitude":      {},
+		"GPSLongitude":     {},
+		"GPSDestLatitude":  {},
+		"GPSDestLongitude": {},
+	}
+)
+
+// ExiftoolMismatch is a tag whose value disagrees with exiftool's.
+type ExiftoolMismatch struct {
+	IfdPath      string
+	TagName      string
+	ExiftoolName string
+
+	Value         interface{}
+	ExiftoolValue interface{}
+}
+
+// String returns a descriptive string.
+func (em ExiftoolMismatch) String() string {
+	return fmt.Sprintf("ExiftoolMismatch<IFD-PATH=[%s] TAG=[%s] EXIFTOOL-TAG=[%s] VALUE=[%v] EXIFTOOL-VALUE=[%v]>", em.IfdPath, em.TagName, em.ExiftoolName, em.Value, em.ExiftoolValue)
+}
+
+// ExiftoolComparison is the result of comparing our decoding of a file with
+// exiftool's.
+type ExiftoolComparison struct {
+	// Matched are the names of the tags that agree.
+	Matched []string
+
+	// Mismatches are the tags that disagree.
+	Mismatches []ExiftoolMismatch
+
+	// Missing are the names of the tags that we decoded but that exiftool
+	// didn't report.
+	Missing []string
+
+	// Skipped are the names of the tags that can't be compared, e.g.
+	// undefined-type tags, which exiftool interprets in its own way.
+	Skipped []string
+}
+
+// ParseExiftoolJson parses the output of `exiftool -j`. There's one record
+// per file.
+func ParseExiftoolJson(data []byte) (records []map[string]interface{}, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+
+	err = decoder.Decode(&records)
+	log.PanicIf(err)
+
+	return records, nil
+}
+
+// CompareWithExiftool compares the given tags (see `GetFlatExifData()`) with
+// one record from `exiftool -j` for the same file. Run exiftool with "-n" so
+// that values aren't converted to descriptions; "-G" group prefixes are
+// allowed. Only the first occurrence of a tag name is compared since exiftool
+// only reports one per group.
+func CompareWithExiftool(exifTags []ExifTag, record map[string]interface{}) (ec ExiftoolComparison, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	// Remove any group prefixes ("EXIF:Make").
+
+	exiftoolValues := make(map[string]interface{})
+	for key, value := range record {
+		if colonAt := strings.LastIndex(key, ":"); colonAt != -1 {
+			key = key[colonAt+1:]
+		}
+
+		if _, found := exiftoolValues[key]; found == false {
+			exiftoolValues[key] = value
+		}
+	}
+
+	ec = ExiftoolComparison{
+		Matched:    make([]string, 0),
+		Mismatches: make([]ExiftoolMismatch, 0),
+		Missing:    make([]string, 0),
+		Skipped:    make([]string, 0),
+	}
+
+	seen := make(map[string]struct{})
+
+	for _, et := range exifTags {
+		if et.TagName == "" {
+			continue
+		} else if _, found := seen[et.TagName]; found == true {
+			continue
+		}
+
+		seen[et.TagName] = struct{}{}
+
+		_, isConverted := exiftoolConvertedTags[et.TagName]
+		if et.ChildIfdPath != "" || et.TagTypeId == exifcommon.TypeUndefined || isConverted == true {
+			ec.Skipped = append(ec.Skipped, et.TagName)
+			continue
+		}
+
+		exiftoolName := et.TagName
+		if alias, found := exiftoolTagNames[et.TagName]; found == true {
+			exiftoolName = alias
+		}
+
+		exiftoolValue, found := exiftoolValues[exiftoolName]
+		if found == false {
+			ec.Missing = append(ec.Missing, et.TagName)
+			continue
+		}
+
+		if exiftoolValueMatches(et, exiftoolValue) == true {
+			ec.Matched = append(ec.Matched, et.TagName)
+		} else {
+			em := ExiftoolMismatch{
+				IfdPath:       et.IfdPath,
+				TagName:       et.TagName,
+				ExiftoolName:  exiftoolName,
+				Value:         et.Value,
+				ExiftoolValue: exiftoolValue,
+			}
+
+			ec.Mismatches = append(ec.Mismatches, em)
+		}
+	}
+
+	return ec, nil
+}
+
+// exiftoolValueMatches compares one of our values to exiftool's.
+func exiftoolValueMatches(et ExifTag, exiftoolValue interface{}) bool {
+	if s, ok := et.Value.(string); ok == true {
+		return strings.TrimSpace(s) == strings.TrimSpace(fmt.Sprintf("%v", exiftoolValue))
+	}
+
+	ours := exiftoolNumbers(et.Value)
+	if ours == nil {
+		return false
+	}
+
+	theirs, ok := exiftoolParseNumbers(exiftoolValue)
+	if ok == false {
+		return false
+	}
+
+	if _, found := exiftoolDecimalGpsTags[et.TagName]; found == true && len(ours) == 3 {
+		decimal := ours[0] + ours[1]/60 + ours[2]/3600
+
+		return len(theirs) == 1 && exiftoolFloatsEqual(decimal, math.Abs(theirs[0])) == true
+	}
+
+	if len(ours) != len(theirs) {
+		return false
+	}
+
+	for i := range ours {
+		if exiftoolFloatsEqual(ours[i], theirs[i]) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+// exiftoolNumbers converts one of our numeric values to floats.
+func exiftoolNumbers(value interface{}) []float64 {
+	var numbers []float64
+
+	switch t := value.(type) {
+	case []byte:
+		for _, n := range t {
+			numbers = append(numbers, float64(n))
+		}
+	case []uint16:
+		for _, n := range t {
+			numbers = append(numbers, float64(n))
+		}
+	case []uint32:
+		for _, n := range t {
+			numbers = append(numbers, float64(n))
+		}
+	case []int32:
+		for _, n := range t {
+			numbers = append(numbers, float64(n))
+		}
+	case []exifcommon.Rational:
+		for _, r := range t {
+			numbers = append(numbers, float64(r.Numerator)/float64(r.Denominator))
+		}
+	case []exifcommon.SignedRational:
+		for _, r := range t {
+			numbers = append(numbers, float64(r.Numerator)/float64(r.Denominator))
+		}
+	}
+
+	return numbers
+}
+
+// exiftoolParseNumbers converts an exiftool value to floats. Lists are
+// space-separated, times are colon-separated, and fractions are allowed
+// (e.g. "1/640").
+func exiftoolParseNumbers(value interface{}) (numbers []float64, ok bool) {
+	switch t := value.(type) {
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return nil, false
+		}
+
+		return []float64{f}, true
+	case float64:
+		return []float64{t}, true
+	case string:
+		fields := strings.FieldsFunc(t, func(r rune) bool {
+			return r == ' ' || r == ':'
+		})
+
+		numbers = make([]float64, 0, len(fields))
+
+		for _, field := range fields {
+			var f float64
+			var err error
+
+			if slashAt := strings.Index(field, "/"); slashAt != -1 {
+				var numerator, denominator float64
+
+				numerator, err = strconv.ParseFloat(field[:slashAt], 64)
+				if err == nil {
+					denominator, err = strconv.ParseFloat(field[slashAt+1:], 64)
+					f = numerator / denominator
+				}
+			} else {
+				f, err = strconv.ParseFloat(field, 64)
+			}
+
+			if err != nil {
+				return nil, false
+			}
+
+			numbers = append(numbers, f)
+		}
+
+		return numbers, true
+	}
+
+	return nil, false
+}
+
+func exiftoolFloatsEqual(a, b float64) bool {
+	if a == b {
+		return true
+	}
+
+	// exiftool rounds what it prints.
+	largest := math.Max(math.Abs(a), math.Abs(b))
+
+	return math.Abs(a-b) <= largest*1e-4
+}
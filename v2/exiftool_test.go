@@ -0,0 +1,94 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// testExiftoolJson is what `exiftool -j -n -G` reports for some of the tags in
+// the test image. FNumber has been changed to force a mismatch.
+var testExiftoolJson = []byte(`[{
+  "SourceFile": "NDM_8901.jpg",
+  "EXIF:Make": "Canon",
+  "EXIF:Model": "Canon EOS 5D Mark III",
+  "EXIF:Orientation": 1,
+  "EXIF:XResolution": 72,
+  "EXIF:ModifyDate": "2017:12:02 08:18:50",
+  "EXIF:ExposureTime": 0.0015625,
+  "EXIF:FNumber": 5.6,
+  "EXIF:ISO": 1600,
+  "EXIF:ShutterSpeedValue": 0.00156249996947241,
+  "EXIF:FocalPlaneXResolution": 2628.33675564682,
+  "EXIF:LensInfo": "16 35 0 0",
+  "EXIF:ExifImageWidth": 3840,
+  "EXIF:GPSVersionID": "2 3 0 0"
+}]`)
+
+func TestParseExiftoolJson(t *testing.T) {
+	records, err := ParseExiftoolJson(testExiftoolJson)
+	log.PanicIf(err)
+
+	if len(records) != 1 {
+		t.Fatalf("Record count not correct: (%d)", len(records))
+	} else if records[0]["EXIF:Make"] != "Canon" {
+		t.Fatalf("Record not correct: %v", records[0])
+	}
+}
+
+func TestCompareWithExiftool(t *testing.T) {
+	exifTags, err := GetFlatExifData(getTestExifData())
+	log.PanicIf(err)
+
+	records, err := ParseExiftoolJson(testExiftoolJson)
+	log.PanicIf(err)
+
+	ec, err := CompareWithExiftool(exifTags, records[0])
+	log.PanicIf(err)
+
+	matched := make(map[string]bool)
+	for _, name := range ec.Matched {
+		matched[name] = true
+	}
+
+	expectedMatched := []string{
+		"Make", "Model", "Orientation", "XResolution", "DateTime",
+		"ExposureTime", "ISOSpeedRatings", "FocalPlaneXResolution",
+		"LensSpecification", "PixelXDimension", "GPSVersionID",
+	}
+
+	for _, name := range expectedMatched {
+		if matched[name] != true {
+			t.Fatalf("Tag [%s] should have matched: %v", name, ec.Matched)
+		}
+	}
+
+	if len(ec.Matched) != len(expectedMatched) {
+		t.Fatalf("Unexpected matches: %v", ec.Matched)
+	}
+
+	if len(ec.Mismatches) != 1 || ec.Mismatches[0].TagName != "FNumber" {
+		t.Fatalf("Mismatches not correct: %v", ec.Mismatches)
+	}
+
+	skipped := make(map[string]bool)
+	for _, name := range ec.Skipped {
+		skipped[name] = true
+	}
+
+	if skipped["ShutterSpeedValue"] != true || skipped["ExifVersion"] != true || skipped["ExifTag"] != true {
+		t.Fatalf("Skipped not correct: %v", ec.Skipped)
+	}
+
+	missing := make(map[string]bool)
+	for _, name := range ec.Missing {
+		missing[name] = true
+	}
+
+	if missing["LensModel"] != true {
+		t.Fatalf("Missing not correct: %v", ec.Missing)
+	}
+}
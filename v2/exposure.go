@@ -0,0 +1,226 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// ExposureSourceRational takes ExposureTime and FNumber as correct and
+	// rewrites ShutterSpeedValue and ApertureValue from them.
+	ExposureSourceRational = "rational"
+
+	// ExposureSourceApex takes ShutterSpeedValue and ApertureValue as correct
+	// and rewrites ExposureTime and FNumber from them.
+	ExposureSourceApex = "apex"
+)
+
+const (
+	// ExposureToleranceStops is how far apart (in stops) a rational value and
+	// its APEX counterpart can be before they're flagged. Cameras round the
+	// APEX values (e.g. 1/250s is written as a Tv of 8, which is 1/256s), so
+	// they rarely agree exactly.
+	ExposureToleranceStops = 1.0 / 6
+
+	exposureTimeTagId      = 0x829a
+	fNumberTagId           = 0x829d
+	shutterSpeedValueTagId = 0x9201
+	apertureValueTagId     = 0x9202
+
+	// exposureApexDenominator is the denominator that the rewritten APEX
+	// values are written with.
+	exposureApexDenominator = 1000
+)
+
+var (
+	// ErrExposureSourceUnknown means that a source isn't one of the
+	// ExposureSource* constants.
+	ErrExposureSourceUnknown = errors.New("unknown exposure source")
+)
+
+// ExposureCheck compares ExposureTime with ShutterSpeedValue and FNumber with
+// ApertureValue. A value is zero if its tag isn't present, and a pair is only
+// compared if both are present.
+type ExposureCheck struct {
+	// ExposureTime is in seconds.
+	ExposureTime float64
+
+	// ShutterSpeedValue is the APEX Tv: -log2(ExposureTime).
+	ShutterSpeedValue float64
+
+	FNumber float64
+
+	// ApertureValue is the APEX Av: 2 * log2(FNumber).
+	ApertureValue float64
+
+	// ShutterDifference and ApertureDifference are how far apart each pair
+	// is, in stops.
+	ShutterDifference  float64
+	ApertureDifference float64
+
+	// ShutterMismatch and ApertureMismatch are true if the difference is
+	// more than `ExposureToleranceStops`.
+	ShutterMismatch  bool
+	ApertureMismatch bool
+}
+
+// IsConsistent returns true if neither pair is mismatched.
+func (ec ExposureCheck) IsConsistent() bool {
+	return ec.ShutterMismatch == false && ec.ApertureMismatch == false
+}
+
+// String returns a descriptive string.
+func (ec ExposureCheck) String() string {
+	return fmt.Sprintf("ExposureCheck<EXPOSURE-TIME=(%f) SHUTTER-SPEED-VALUE=(%.3f) F-NUMBER=(%.1f) APERTURE-VALUE=(%.3f) SHUTTER-MISMATCH=[%v] APERTURE-MISMATCH=[%v]>", ec.ExposureTime, ec.ShutterSpeedValue, ec.FNumber, ec.ApertureValue, ec.ShutterMismatch, ec.ApertureMismatch)
+}
+
+// exposureTimeRational returns an exposure time as a fraction of a second
+// (e.g. 1/256) when it's shorter than a second, and in tenths otherwise.
+func exposureTimeRational(exposureTime float64) exifcommon.Rational {
+	if exposureTime < 1 {
+		return exifcommon.Rational{Numerator: 1, Denominator: uint32(math.Round(1 / exposureTime))}
+	}
+
+	return exifcommon.Rational{Numerator: uint32(math.Round(exposureTime * 10)), Denominator: 10}
+}
+
+// hasShutterSpeedValue returns true if the IFD has a ShutterSpeedValue. A Tv
+// of zero (one second) is valid, so the value can't be used to tell.
+func hasShutterSpeedValue(exifIfd *Ifd) bool {
+	_, err := exifIfd.FindTagWithId(shutterSpeedValueTagId)
+	return err == nil
+}
+
+// checkExposureIfd compares the exposure tags of an Exif IFD.
+func checkExposureIfd(exifIfd *Ifd) (ec ExposureCheck, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ec.ExposureTime, err = getIfdTagNumber(exifIfd, "ExposureTime")
+	log.PanicIf(err)
+
+	ec.ShutterSpeedValue, err = getIfdTagNumber(exifIfd, "ShutterSpeedValue")
+	log.PanicIf(err)
+
+	ec.FNumber, err = getIfdTagNumber(exifIfd, "FNumber")
+	log.PanicIf(err)
+
+	ec.ApertureValue, err = getIfdTagNumber(exifIfd, "ApertureValue")
+	log.PanicIf(err)
+
+	if ec.ExposureTime > 0 && hasShutterSpeedValue(exifIfd) == true {
+		ec.ShutterDifference = math.Abs(ApexTvFromExposureTime(ec.ExposureTime) - ec.ShutterSpeedValue)
+		ec.ShutterMismatch = ec.ShutterDifference > ExposureToleranceStops
+	}
+
+	if ec.FNumber > 0 && ec.ApertureValue > 0 {
+		ec.ApertureDifference = math.Abs(ApexAvFromFNumber(ec.FNumber) - ec.ApertureValue)
+		ec.ApertureMismatch = ec.ApertureDifference > ExposureToleranceStops
+	}
+
+	return ec, nil
+}
+
+// CheckExposure compares ExposureTime (seconds) with ShutterSpeedValue
+// (APEX) and FNumber with ApertureValue (APEX) in the Exif IFD and flags the
+// pairs that disagree, which is common after an editor changed one but not
+// the other. `ErrTagNotFound` is returned if there's no Exif IFD.
+func CheckExposure(index IfdIndex) (ec ExposureCheck, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifds := index.Lookup[exifcommon.IfdPathStandardExif]
+	if len(ifds) == 0 {
+		return ec, ErrTagNotFound
+	}
+
+	ec, err = checkExposureIfd(ifds[0])
+	log.PanicIf(err)
+
+	return ec, nil
+}
+
+// ReconcileExposure rewrites one tag of each pair from the other, depending
+// on the source (one of the ExposureSource* constants). A tag is only written
+// if it's missing or mismatched, and a pair is left alone if its source tag
+// isn't present. The check from before the change is returned.
+func ReconcileExposure(ep *ExifPatcher, source string) (ec ExposureCheck, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if source != ExposureSourceRational && source != ExposureSourceApex {
+		return ec, ErrExposureSourceUnknown
+	}
+
+	pi, found := ep.byPath[exifcommon.IfdPathStandardExif]
+	if found == false {
+		return ec, ErrTagNotFound
+	}
+
+	ec, err = checkExposureIfd(pi.ifd)
+	log.PanicIf(err)
+
+	hasTv := hasShutterSpeedValue(pi.ifd)
+
+	set := func(tagId uint16, value interface{}) {
+		err := ep.Set(exifcommon.IfdPathStandardExif, tagId, value)
+		log.PanicIf(err)
+	}
+
+	if source == ExposureSourceRational {
+		if ec.ExposureTime > 0 && (hasTv == false || ec.ShutterMismatch == true) {
+			tv := ApexTvFromExposureTime(ec.ExposureTime)
+			set(shutterSpeedValueTagId, []exifcommon.SignedRational{{Numerator: int32(math.Round(tv * exposureApexDenominator)), Denominator: exposureApexDenominator}})
+		}
+
+		if ec.FNumber > 0 && (ec.ApertureValue == 0 || ec.ApertureMismatch == true) {
+			av := ApexAvFromFNumber(ec.FNumber)
+			set(apertureValueTagId, []exifcommon.Rational{{Numerator: uint32(math.Round(av * exposureApexDenominator)), Denominator: exposureApexDenominator}})
+		}
+	} else {
+		if hasTv == true && (ec.ExposureTime == 0 || ec.ShutterMismatch == true) {
+			set(exposureTimeTagId, []exifcommon.Rational{exposureTimeRational(ApexExposureTimeFromTv(ec.ShutterSpeedValue))})
+		}
+
+		if ec.ApertureValue > 0 && (ec.FNumber == 0 || ec.ApertureMismatch == true) {
+			fNumber := ApexFNumberFromAv(ec.ApertureValue)
+			set(fNumberTagId, []exifcommon.Rational{{Numerator: uint32(math.Round(fNumber * 10)), Denominator: 10}})
+		}
+	}
+
+	return ec, nil
+}
+
+// ReconcileJpegExposure returns a copy of the JPEG with its exposure tags
+// reconciled (see `ReconcileExposure()`). The JPEG is returned as-is if it
+// has no EXIF.
+func ReconcileJpegExposure(data []byte, source string) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	updated, err = patchJpegExif(data, func(ep *ExifPatcher) (err error) {
+		_, err = ReconcileExposure(ep, source)
+		return err
+	})
+
+	log.PanicIf(err)
+
+	return updated, nil
+}
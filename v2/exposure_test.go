@@ -0,0 +1,147 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestExposureExif returns EXIF with an ExposureTime of 1/250 and an
+// FNumber of 2.8 and the given APEX tags.
+func getTestExposureExif(apexTags ...exiftest.Tag) []byte {
+	root := exiftest.NewRealisticIfd()
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, apexTags...)
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return rawExif
+}
+
+func getTestExposureCheck(rawExif []byte) ExposureCheck {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	ec, err := CheckExposure(index)
+	log.PanicIf(err)
+
+	return ec
+}
+
+func TestCheckExposure_Consistent(t *testing.T) {
+	// A camera would round these to whole stops.
+	rawExif := getTestExposureExif(
+		exiftest.Tag{Id: shutterSpeedValueTagId, Value: []exifcommon.SignedRational{{Numerator: 8, Denominator: 1}}},
+		exiftest.Tag{Id: apertureValueTagId, Value: []exifcommon.Rational{{Numerator: 3, Denominator: 1}}})
+
+	ec := getTestExposureCheck(rawExif)
+
+	if ec.IsConsistent() != true {
+		t.Fatalf("Expected consistent exposure: %s", ec)
+	} else if ec.ShutterDifference == 0 || ec.ApertureDifference == 0 {
+		t.Fatalf("Expected the rounding to show: %s", ec)
+	}
+}
+
+func TestCheckExposure_Mismatch(t *testing.T) {
+	// 1/60s and f/8.
+	rawExif := getTestExposureExif(
+		exiftest.Tag{Id: shutterSpeedValueTagId, Value: []exifcommon.SignedRational{{Numerator: 6, Denominator: 1}}},
+		exiftest.Tag{Id: apertureValueTagId, Value: []exifcommon.Rational{{Numerator: 6, Denominator: 1}}})
+
+	ec := getTestExposureCheck(rawExif)
+
+	if ec.ShutterMismatch != true || ec.ApertureMismatch != true || ec.IsConsistent() != false {
+		t.Fatalf("Expected mismatches: %s", ec)
+	}
+}
+
+func TestCheckExposure_Missing(t *testing.T) {
+	ec := getTestExposureCheck(getTestExposureExif())
+
+	if ec.IsConsistent() != true || ec.ShutterSpeedValue != 0 || ec.ApertureValue != 0 {
+		t.Fatalf("Missing APEX values should not be flagged: %s", ec)
+	}
+}
+
+func TestReconcileExposure_Rational(t *testing.T) {
+	rawExif := getTestExposureExif(
+		exiftest.Tag{Id: shutterSpeedValueTagId, Value: []exifcommon.SignedRational{{Numerator: 6, Denominator: 1}}})
+
+	ep, err := NewExifPatcher(rawExif, NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	before, err := ReconcileExposure(ep, ExposureSourceRational)
+	log.PanicIf(err)
+
+	if before.ShutterMismatch != true {
+		t.Fatalf("Expected a mismatch before: %s", before)
+	}
+
+	patched, err := ep.Encode()
+	log.PanicIf(err)
+
+	after := getTestExposureCheck(patched)
+
+	if after.IsConsistent() != true || after.ExposureTime != 1.0/250 || after.FNumber != 2.8 {
+		t.Fatalf("Rational values should be kept and APEX values rewritten: %s", after)
+	} else if after.ShutterDifference > 0.001 || after.ApertureDifference > 0.001 {
+		t.Fatalf("APEX values not correct: %s", after)
+	}
+}
+
+func TestReconcileExposure_Apex(t *testing.T) {
+	rawExif := getTestExposureExif(
+		exiftest.Tag{Id: shutterSpeedValueTagId, Value: []exifcommon.SignedRational{{Numerator: 6, Denominator: 1}}},
+		exiftest.Tag{Id: apertureValueTagId, Value: []exifcommon.Rational{{Numerator: 6, Denominator: 1}}})
+
+	ep, err := NewExifPatcher(rawExif, NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	_, err = ReconcileExposure(ep, ExposureSourceApex)
+	log.PanicIf(err)
+
+	patched, err := ep.Encode()
+	log.PanicIf(err)
+
+	after := getTestExposureCheck(patched)
+
+	if after.IsConsistent() != true || after.ExposureTime != 1.0/64 || after.FNumber != 8 {
+		t.Fatalf("Rational values should be rewritten: %s", after)
+	} else if after.ShutterSpeedValue != 6 || after.ApertureValue != 6 {
+		t.Fatalf("APEX values should be kept: %s", after)
+	}
+}
+
+func TestReconcileExposure_SourceUnknown(t *testing.T) {
+	ep, err := NewExifPatcher(getTestExposureExif(), NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	if _, err := ReconcileExposure(ep, "other"); err != ErrExposureSourceUnknown {
+		t.Fatalf("Expected unknown source: %v", err)
+	}
+}
+
+func TestReconcileJpegExposure(t *testing.T) {
+	rawExif := getTestExposureExif(
+		exiftest.Tag{Id: apertureValueTagId, Value: []exifcommon.Rational{{Numerator: 6, Denominator: 1}}})
+
+	data := exiftest.WrapJpeg(rawExif)
+
+	updated, err := ReconcileJpegExposure(data, ExposureSourceRational)
+	log.PanicIf(err)
+
+	updatedExif, err := SearchAndExtractExif(updated)
+	log.PanicIf(err)
+
+	if ec := getTestExposureCheck(updatedExif); ec.IsConsistent() != true {
+		t.Fatalf("Exposure not reconciled: %s", ec)
+	}
+}
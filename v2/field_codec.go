@@ -0,0 +1,153 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// protectedFieldHeaderSize is the size of the signature, the original
+	// type, and the original unit-count that start a protected value.
+	protectedFieldHeaderSize = 10
+)
+
+var (
+	// protectedFieldSignature starts the value of a protected tag.
+	protectedFieldSignature = []byte("XPRT")
+)
+
+var (
+	// ErrProtectedFieldNotValid means that a protected value is truncated or
+	// doesn't decode to the type and unit-count that it was written with.
+	ErrProtectedFieldNotValid = errors.New("protected field not valid")
+)
+
+// FieldCodec transforms the values of selected tags when they're written, and
+// reverses it when they're read (e.g. to encrypt or tokenize GPS coordinates
+// and serial numbers). The codec only sees the raw bytes of the value. The
+// protected value is written as UNDEFINED, prefixed with the original type
+// and unit-count so that the tag can be restored as it was.
+type FieldCodec interface {
+	// Protects returns true if the tag's value is transformed.
+	Protects(ifdPath string, tagId uint16) bool
+
+	// Encode returns the protected form of the raw value.
+	Encode(ifdPath string, tagId uint16, raw []byte) (protected []byte, err error)
+
+	// Decode reverses `Encode()`.
+	Decode(ifdPath string, tagId uint16, protected []byte) (raw []byte, err error)
+}
+
+// SetFieldCodec has the encoder protect the values of the tags that the codec
+// selects. The builder itself is left as it was. Values are only protected
+// when a whole block is encoded, not when it's patched in place (see
+// `ExifPatcher`).
+func (ibe *IfdByteEncoder) SetFieldCodec(codec FieldCodec) {
+	ibe.fieldCodec = codec
+}
+
+// SetFieldCodec has the enumerator restore the values of the tags that the
+// codec selects and that were protected with it. Their entries then have the
+// original type, unit-count, and value, although the value is no longer read
+// from the block (so `ValueLocation()` is where the protected value is).
+// Decoding failures (e.g. the wrong key) fail the parse.
+func (ie *IfdEnumerate) SetFieldCodec(codec FieldCodec) {
+	ie.fieldCodec = codec
+}
+
+// protectFields replaces the values of the tags that the codec selects, in
+// the builder, the builders of its child IFDs, and those chained after it.
+func protectFields(ib *IfdBuilder, codec FieldCodec) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+		for _, bt := range thisIb.tags {
+			if bt.value.IsIb() == true {
+				err := protectFields(bt.value.Ib(), codec)
+				log.PanicIf(err)
+
+				continue
+			} else if codec.Protects(thisIb.ifdPath, bt.tagId) == false {
+				continue
+			}
+
+			raw := bt.value.Bytes()
+
+			protected, err := codec.Encode(thisIb.ifdPath, bt.tagId, raw)
+			log.PanicIf(err)
+
+			effectiveType := bt.typeId
+			if effectiveType == exifcommon.TypeUndefined {
+				effectiveType = exifcommon.TypeByte
+			}
+
+			header := make([]byte, protectedFieldHeaderSize)
+			copy(header, protectedFieldSignature)
+			binary.BigEndian.PutUint16(header[4:], uint16(bt.typeId))
+			binary.BigEndian.PutUint32(header[6:], uint32(len(raw)/effectiveType.Size()))
+
+			bt.typeId = exifcommon.TypeUndefined
+			bt.value = NewIfdBuilderTagValueFromBytes(append(header, protected...))
+		}
+	}
+
+	return nil
+}
+
+// unprotectField restores the value of the entry if the codec selects it and
+// it was protected.
+func (ie *IfdEnumerate) unprotectField(ite *IfdTagEntry) {
+	if ie.fieldCodec == nil || ite.tagType != exifcommon.TypeUndefined || ie.fieldCodec.Protects(ite.ifdPath, ite.tagId) == false {
+		return
+	}
+
+	valueContext := ite.getValueContext()
+	valueContext.SetUndefinedValueType(exifcommon.TypeByte)
+
+	value, err := valueContext.ReadRawEncoded()
+	log.PanicIf(err)
+
+	if bytes.HasPrefix(value, protectedFieldSignature) == false {
+		return
+	} else if len(value) < protectedFieldHeaderSize {
+		log.Panic(ErrProtectedFieldNotValid)
+	}
+
+	tagType := exifcommon.TagTypePrimitive(binary.BigEndian.Uint16(value[4:]))
+	unitCount := binary.BigEndian.Uint32(value[6:])
+
+	raw, err := ie.fieldCodec.Decode(ite.ifdPath, ite.tagId, value[protectedFieldHeaderSize:])
+	log.PanicIf(err)
+
+	effectiveType := tagType
+	if effectiveType == exifcommon.TypeUndefined {
+		effectiveType = exifcommon.TypeByte
+	}
+
+	if tagType.IsValid() == false || uint64(len(raw)) != uint64(unitCount)*uint64(effectiveType.Size()) {
+		log.Panic(ErrProtectedFieldNotValid)
+	}
+
+	// The value is read from the restored bytes from now on, either from the
+	// entry itself or from the start of them.
+	rawValueOffset := make([]byte, 4)
+	copy(rawValueOffset, raw)
+
+	ite.tagType = tagType
+	ite.unitCount = unitCount
+	ite.valueOffset = 0
+	ite.rawValueOffset = rawValueOffset
+	ite.addressableData = raw
+	ite.addressableReader = nil
+	ite.addressableSize = 0
+}
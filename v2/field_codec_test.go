@@ -0,0 +1,173 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	bodySerialNumberTagId = 0xa431
+)
+
+// testFieldCodec protects the GPS IFD and the body serial number by XORing
+// them with the key and adding a prefix, so the size changes.
+type testFieldCodec struct {
+	key byte
+}
+
+func (tfc testFieldCodec) Protects(ifdPath string, tagId uint16) bool {
+	return ifdPath == exifcommon.IfdPathStandardGps || (ifdPath == exifcommon.IfdPathStandardExif && tagId == bodySerialNumberTagId)
+}
+
+func (tfc testFieldCodec) Encode(ifdPath string, tagId uint16, raw []byte) (protected []byte, err error) {
+	protected = []byte{'t', 'o', 'k', tfc.key}
+	for _, b := range raw {
+		protected = append(protected, b^tfc.key)
+	}
+
+	return protected, nil
+}
+
+func (tfc testFieldCodec) Decode(ifdPath string, tagId uint16, protected []byte) (raw []byte, err error) {
+	if len(protected) < 4 || protected[3] != tfc.key {
+		return nil, errors.New("wrong key")
+	}
+
+	for _, b := range protected[4:] {
+		raw = append(raw, b^tfc.key)
+	}
+
+	return raw, nil
+}
+
+// getTestFieldCodecIb returns a builder with a make, a GPS latitude, and a
+// body serial number.
+func getTestFieldCodecIb() *IfdBuilder {
+	rootIb := NewIfdBuilder(NewIfdMappingWithStandard(), NewTagIndex(), exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	fields := map[string]interface{}{
+		"IFD/Make":                  "some make",
+		"IFD/GPSInfo/GPSLatitude":   []exifcommon.Rational{{Numerator: 40, Denominator: 1}, {Numerator: 26, Denominator: 1}, {Numerator: 4606, Denominator: 100}},
+		"IFD/Exif/BodySerialNumber": "SN-12345",
+	}
+
+	for address, value := range fields {
+		err := rootIb.SetWithAddress(address, value)
+		log.PanicIf(err)
+	}
+
+	return rootIb
+}
+
+// collectTestFieldCodec collects the EXIF with the codec, if there is one.
+func collectTestFieldCodec(rawExif []byte, codec FieldCodec) (index IfdIndex, err error) {
+	eh, err := ParseExifHeader(rawExif)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, eh.ByteOrder)
+	if codec != nil {
+		ie.SetFieldCodec(codec)
+	}
+
+	return ie.Collect(eh.FirstIfdOffset)
+}
+
+func TestFieldCodec_RoundTrip(t *testing.T) {
+	rootIb := getTestFieldCodecIb()
+	codec := testFieldCodec{key: 0x5a}
+
+	ibe := NewIfdByteEncoder()
+	ibe.SetFieldCodec(codec)
+
+	rawExif, err := ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	size, err := ibe.EncodedSize(rootIb)
+	log.PanicIf(err)
+
+	if size != uint32(len(rawExif)) {
+		t.Fatalf("Encoded size (%d) not correct: (%d)", size, len(rawExif))
+	}
+
+	// The builder is left as it was.
+	bt, err := rootIb.FindTagWithAddress("IFD/GPSInfo/GPSLatitude")
+	log.PanicIf(err)
+
+	if bt.typeId != exifcommon.TypeRational {
+		t.Fatalf("Builder should not have been changed: %s", bt)
+	}
+
+	// Without the codec, the values are protected.
+	index, err := collectTestFieldCodec(rawExif, nil)
+	log.PanicIf(err)
+
+	results, err := index.FindTagWithAddress("IFD/GPSInfo/GPSLatitude")
+	log.PanicIf(err)
+
+	if results[0].TagType() != exifcommon.TypeUndefined {
+		t.Fatalf("GPSLatitude should be protected: %s", results[0])
+	}
+
+	rawBytes, err := results[0].GetRawBytes()
+	log.PanicIf(err)
+
+	if bytes.HasPrefix(rawBytes, protectedFieldSignature) != true {
+		t.Fatalf("Protected value doesn't have the signature: %v", rawBytes)
+	}
+
+	// With it, they're restored.
+	index, err = collectTestFieldCodec(rawExif, codec)
+	log.PanicIf(err)
+
+	expected := map[string]interface{}{
+		"IFD/Make":                  "some make",
+		"IFD/GPSInfo/GPSLatitude":   []exifcommon.Rational{{Numerator: 40, Denominator: 1}, {Numerator: 26, Denominator: 1}, {Numerator: 4606, Denominator: 100}},
+		"IFD/Exif/BodySerialNumber": "SN-12345",
+	}
+
+	for address, expectedValue := range expected {
+		results, err := index.FindTagWithAddress(address)
+		log.PanicIf(err)
+
+		value, err := results[0].Value()
+		log.PanicIf(err)
+
+		if reflect.DeepEqual(value, expectedValue) != true {
+			t.Fatalf("Value of [%s] not correct: %v", address, value)
+		}
+	}
+}
+
+func TestFieldCodec_WrongKey(t *testing.T) {
+	ibe := NewIfdByteEncoder()
+	ibe.SetFieldCodec(testFieldCodec{key: 0x5a})
+
+	rawExif, err := ibe.EncodeToExif(getTestFieldCodecIb())
+	log.PanicIf(err)
+
+	if _, err := collectTestFieldCodec(rawExif, testFieldCodec{key: 0x11}); err == nil {
+		t.Fatalf("Expected error for the wrong key.")
+	}
+}
+
+func TestFieldCodec_NotProtected(t *testing.T) {
+	// A block written without the codec reads the same with it.
+	rawExif, err := NewIfdByteEncoder().EncodeToExif(getTestFieldCodecIb())
+	log.PanicIf(err)
+
+	index, err := collectTestFieldCodec(rawExif, testFieldCodec{key: 0x5a})
+	log.PanicIf(err)
+
+	results, err := index.FindTagWithAddress("IFD/Exif/BodySerialNumber")
+	log.PanicIf(err)
+
+	if value, err := results[0].Value(); err != nil || value != "SN-12345" {
+		t.Fatalf("Value not correct: [%v] %v", value, err)
+	}
+}
@@ -0,0 +1,238 @@
+package exif
+
+import (
+	"errors"
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	fileTagLogger = log.NewLogger("exif.file_tag")
+)
+
+var (
+	// ErrKindNotWritable means that EXIF can't be written to files of that
+	// kind, or not to this one. It's what a `CapabilityError` unwraps to.
+	ErrKindNotWritable = errors.New("file kind not writable")
+)
+
+// UpdateFileTag sets one tag in the file at the given path and writes it back
+// with `RewriteFile()`. The tag is given by its fully-qualified path, as for
+// `BuilderFromMap()` (e.g. "IFD/Exif/UserComment"), and the value is converted
+// the same way. Missing IFDs are added. The path can select a page or, in a
+// HEIF, an image item (e.g. "item:2/IFD/Exif/DateTimeOriginal") whose own EXIF
+// is updated (see `ParseTagAddress()`).
+//
+// The existing EXIF is patched in place (see `ExifPatcher`) so that nothing
+// else in it moves, which also keeps the image data of a TIFF file where it
+// is. For other containers, the patched block is put back with the registered
+// format (see `MediaFormat`), and a file without any EXIF gets a new block
+// with just the one tag. A `CapabilityError` is returned if the container
+// isn't recognized or can't be written.
+func UpdateFileTag(filepath, tagPath string, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ta, err := ParseTagAddress(im, ti, tagPath)
+	log.PanicIf(err)
+
+	fqIfdPath, it := ta.FqIfdPath, ta.Tag
+
+	mf, err := SniffMediaFormat(data)
+	if err == ErrMediaFormatNotFound {
+		ce := &CapabilityError{
+			Kind:      KindUnknown,
+			Operation: CapabilityUpdateTags,
+			Reason:    "the container wasn't recognized",
+		}
+
+		log.Panic(ce)
+	}
+
+	extractExif, replaceExif, err := getAddressedExifAccessors(mf, ta)
+	log.PanicIf(err)
+
+	var updated []byte
+
+	switch mf.Kind() {
+	case KindJpeg:
+		patched := false
+
+		updated, err = patchJpegExif(data, func(ep *ExifPatcher) error {
+			patched = true
+			return setPatcherTag(ep, fqIfdPath, it, value)
+		})
+
+		log.PanicIf(err)
+
+		if patched == false {
+			rawExif, err := newTagExif(im, ti, fqIfdPath, it, value)
+			log.PanicIf(err)
+
+			updated, err = SetJpegExif(data, rawExif)
+			log.PanicIf(err)
+		}
+	case KindTiff:
+		ep, err := NewExifPatcher(data, im, ti)
+		log.PanicIf(err)
+
+		err = setPatcherTag(ep, fqIfdPath, it, value)
+		log.PanicIf(err)
+
+		updated, err = ep.Encode()
+		log.PanicIf(err)
+	default:
+		rawExif, err := extractExif(data)
+		if err == ErrNoExif {
+			rawExif, err = newTagExif(im, ti, fqIfdPath, it, value)
+			log.PanicIf(err)
+		} else {
+			log.PanicIf(err)
+
+			ep, err := NewExifPatcher(rawExif, im, ti)
+			log.PanicIf(err)
+
+			err = setPatcherTag(ep, fqIfdPath, it, value)
+			log.PanicIf(err)
+
+			rawExif, err = ep.Encode()
+			log.PanicIf(err)
+		}
+
+		updated, err = replaceExif(data, rawExif)
+		if ce, found := AsCapabilityError(err); found == true {
+			// Report what was actually asked for.
+			updateCe := *ce
+			updateCe.Operation = CapabilityUpdateTags
+
+			fileTagLogger.Warningf(nil, "Can't write tags to file [%s]: %s", filepath, updateCe.Reason)
+			log.Panic(&updateCe)
+		}
+
+		log.PanicIf(err)
+	}
+
+	err = RewriteFile(filepath, updated, RewriteOptions{})
+	log.PanicIf(err)
+
+	return nil
+}
+
+// ReadFileTag returns the tags at the given path in the file at the given
+// path. The path can select a page or, in a HEIF, an image item (see
+// `ParseTagAddress()`). `ErrNoExif` is returned if there's no EXIF, and
+// `ErrTagNotFound` if the IFD or the tag isn't there.
+func ReadFileTag(filepath, tagPath string) (results []*IfdTagEntry, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ta, err := ParseTagAddress(im, ti, tagPath)
+	log.PanicIf(err)
+
+	mf, err := SniffMediaFormat(data)
+	log.PanicIf(err)
+
+	extractExif, _, err := getAddressedExifAccessors(mf, ta)
+	log.PanicIf(err)
+
+	rawExif, err := extractExif(data)
+	if err != nil {
+		return nil, err
+	}
+
+	_, index, err := Collect(im, ti, rawExif)
+	log.PanicIf(err)
+
+	results, err = index.findTagWithTagAddress(ta)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// getAddressedExifAccessors returns the functions that read and write the
+// EXIF block that the address is in: that of the image item, for a HEIF,
+// or otherwise that of the format. An item can't be selected in other
+// containers.
+func getAddressedExifAccessors(mf MediaFormat, ta TagAddress) (extractExif func(data []byte) ([]byte, error), replaceExif func(data, rawExif []byte) ([]byte, error), err error) {
+	if ta.ItemId == 0 {
+		return mf.ExtractExif, mf.ReplaceExif, nil
+	} else if mf.Kind() != KindHeif {
+		fileTagLogger.Warningf(nil, "Items can only be selected in HEIF files, not [%s].", mf.Kind())
+		return nil, nil, ErrTagPathNotValid
+	}
+
+	extractExif = func(data []byte) ([]byte, error) {
+		return GetHeifItemExif(data, ta.ItemId)
+	}
+
+	replaceExif = func(data, rawExif []byte) ([]byte, error) {
+		return SetHeifItemExif(data, ta.ItemId, rawExif)
+	}
+
+	return extractExif, replaceExif, nil
+}
+
+// newTagExif returns a new EXIF block with just the one tag.
+func newTagExif(im *IfdMapping, ti *TagIndex, fqIfdPath string, it *IndexedTag, value interface{}) (rawExif []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.EncodeDefaultByteOrder)
+
+	err = setBuilderTag(rootIb, fqIfdPath, it, value)
+	log.PanicIf(err)
+
+	rawExif, err = NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	return rawExif, nil
+}
+
+// setPatcherTag sets a tag given by path, adding its IFD if it's missing.
+func setPatcherTag(ep *ExifPatcher, fqIfdPath string, it *IndexedTag, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = addPatcherIfdLineage(ep, fqIfdPath)
+	log.PanicIf(err)
+
+	value, rawBytes := mapTagValue(it, value)
+	if rawBytes != nil {
+		ep.setBytes(ep.getIfd(fqIfdPath), it.Id, exifcommon.TypeUndefined, rawBytes)
+		return nil
+	}
+
+	err = ep.Set(fqIfdPath, it.Id, value)
+	log.PanicIf(err)
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package exif
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+// writeTestFile writes the data to a new file in the given directory.
+func writeTestFile(tempPath, filename string, data []byte) string {
+	filepath := path.Join(tempPath, filename)
+
+	err := ioutil.WriteFile(filepath, data, 0644)
+	log.PanicIf(err)
+
+	return filepath
+}
+
+// getFileTagValue returns the value of the tag in the file.
+func getFileTagValue(filepath, fqIfdPath, tagName string) interface{} {
+	rawExif, err := SearchFileAndExtractExif(filepath)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	results, err := index.Lookup[fqIfdPath][0].FindTagWithName(tagName)
+	log.PanicIf(err)
+
+	value, err := results[0].Value()
+	log.PanicIf(err)
+
+	return value
+}
+
+// getFileUserComment returns the text of the UserComment in the file.
+func getFileUserComment(filepath string) string {
+	value := getFileTagValue(filepath, exifcommon.IfdPathStandardExif, "UserComment")
+	return value.(exifundefined.Tag9286UserComment).Text()
+}
+
+func TestUpdateFileTag_Jpeg(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	original, err := ioutil.ReadFile(getTestImageFilepath())
+	log.PanicIf(err)
+
+	filepath := writeTestFile(tempPath, "image.jpg", original)
+
+	err = UpdateFileTag(filepath, "IFD/Exif/UserComment", "a new comment")
+	log.PanicIf(err)
+
+	err = UpdateFileTag(filepath, "IFD/Exif/FNumber", 2.8)
+	log.PanicIf(err)
+
+	originalExif, err := SearchAndExtractExif(original)
+	log.PanicIf(err)
+
+	updatedExif, err := SearchFileAndExtractExif(filepath)
+	log.PanicIf(err)
+
+	if comment := getFileUserComment(filepath); comment != "a new comment" {
+		t.Fatalf("UserComment not correct: [%s]", comment)
+	} else if value := getFileTagValue(filepath, exifcommon.IfdPathStandardExif, "FNumber"); reflect.DeepEqual(value, []exifcommon.Rational{{Numerator: 14, Denominator: 5}}) != true {
+		t.Fatalf("FNumber not correct: %v", value)
+	} else if value := getPatchedTagString(updatedExif, exifcommon.IfdPathStandard, "Make"); value != getPatchedTagString(originalExif, exifcommon.IfdPathStandard, "Make") {
+		t.Fatalf("Make not kept: [%s]", value)
+	}
+}
+
+func TestUpdateFileTag_JpegWithoutExif(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	original, err := ioutil.ReadFile(getTestImageFilepath())
+	log.PanicIf(err)
+
+	stripped, err := SetJpegExif(original, nil)
+	log.PanicIf(err)
+
+	filepath := writeTestFile(tempPath, "image.jpg", stripped)
+
+	err = UpdateFileTag(filepath, "IFD/Exif/UserComment", "only tag")
+	log.PanicIf(err)
+
+	if comment := getFileUserComment(filepath); comment != "only tag" {
+		t.Fatalf("UserComment not correct: [%s]", comment)
+	}
+}
+
+func TestUpdateFileTag_Tiff(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	original, stripOffset := getTestTiff()
+
+	filepath := writeTestFile(tempPath, "image.tif", original)
+
+	err = UpdateFileTag(filepath, "IFD/Exif/UserComment", "tiff comment")
+	log.PanicIf(err)
+
+	updated, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	if comment := getFileUserComment(filepath); comment != "tiff comment" {
+		t.Fatalf("UserComment not correct: [%s]", comment)
+	} else if bytes.Equal(updated[stripOffset:stripOffset+uint32(len(testDngPixels))], testDngPixels) != true {
+		t.Fatalf("Strip was moved or changed.")
+	}
+}
+
+func TestUpdateFileTag_Errors(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	original, err := ioutil.ReadFile(getTestImageFilepath())
+	log.PanicIf(err)
+
+	jpegFilepath := writeTestFile(tempPath, "image.jpg", original)
+	pngFilepath := writeTestFile(tempPath, "image.png", pngSignature)
+
+	cases := []struct {
+		name     string
+		filepath string
+		tagPath  string
+		value    interface{}
+		expected error
+	}{
+		{name: "bad path", filepath: jpegFilepath, tagPath: "Make", value: "Canon", expected: ErrTagPathNotValid},
+		{name: "bad value", filepath: jpegFilepath, tagPath: "IFD/Orientation", value: 1.5, expected: ErrValueNotConvertible},
+	}
+
+	for _, c := range cases {
+		if err := UpdateFileTag(c.filepath, c.tagPath, c.value); log.Is(err, c.expected) == false {
+			t.Fatalf("Error not correct for [%s]: %v", c.name, err)
+		}
+	}
+
+	// A PNG without any image data to put the EXIF before.
+	err = UpdateFileTag(pngFilepath, "IFD/Make", "Canon")
+	if ce, found := AsCapabilityError(err); found != true {
+		t.Fatalf("Expected CapabilityError for PNG: %v", err)
+	} else if ce.Kind != KindPng || ce.Operation != CapabilityUpdateTags || ce.Available != CapabilityReadExif {
+		t.Fatalf("CapabilityError for PNG not correct: %v", ce)
+	}
+
+	unknownFilepath := writeTestFile(tempPath, "image.bin", []byte("not media"))
+
+	err = UpdateFileTag(unknownFilepath, "IFD/Make", "Canon")
+	if ce, found := AsCapabilityError(err); found != true {
+		t.Fatalf("Expected CapabilityError for unknown file: %v", err)
+	} else if ce.Kind != KindUnknown || ce.Available != 0 {
+		t.Fatalf("CapabilityError for unknown file not correct: %v", ce)
+	}
+
+	updated, err := ioutil.ReadFile(jpegFilepath)
+	log.PanicIf(err)
+
+	if bytes.Equal(updated, original) != true {
+		t.Fatalf("File changed by a failed update.")
+	}
+}
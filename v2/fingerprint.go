@@ -0,0 +1,232 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"crypto/sha1"
+	"encoding/hex"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// FingerprintOptions controls how closely two captures have to agree in order
+// to be considered the same. The zero value requires an exact match.
+type FingerprintOptions struct {
+	// TimestampTolerance is how far apart the capture times can be. Re-exports
+	// often lose subseconds or are shifted slightly by editing software.
+	TimestampTolerance time.Duration
+
+	// GpsTolerance is how far apart the coordinates can be, in decimal
+	// degrees.
+	GpsTolerance float64
+
+	// IgnoreDimensions disregards the image size, which changes when an image
+	// is resized during export.
+	IgnoreDimensions bool
+}
+
+// Fingerprint is a set of capture fields that identify a particular shot.
+// Fields that weren't present in the EXIF are left as zero values.
+type Fingerprint struct {
+	Timestamp time.Time
+
+	Make         string
+	Model        string
+	SerialNumber string
+
+	ExposureTime float64
+	FNumber      float64
+	IsoSpeed     uint32
+
+	Width  uint32
+	Height uint32
+
+	HasGps    bool
+	Latitude  float64
+	Longitude float64
+}
+
+// NewFingerprint pulls the fingerprint fields out of the given index.
+func NewFingerprint(index IfdIndex) (fp Fingerprint, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rootIfd := index.RootIfd
+
+	var exifIfd *Ifd
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd = ifds[0]
+	}
+
+	fp.Make, err = getIfdTagString(rootIfd, "Make")
+	log.PanicIf(err)
+
+	fp.Model, err = getIfdTagString(rootIfd, "Model")
+	log.PanicIf(err)
+
+	if exifIfd != nil {
+		fp.SerialNumber, err = getIfdTagString(exifIfd, "BodySerialNumber")
+		log.PanicIf(err)
+
+		timestampPhrase, err := getIfdTagString(exifIfd, "DateTimeOriginal")
+		log.PanicIf(err)
+
+		if timestampPhrase == "" {
+			timestampPhrase, err = getIfdTagString(rootIfd, "DateTime")
+			log.PanicIf(err)
+		}
+
+		if timestampPhrase != "" {
+			timestamp, err := ParseExifFullTimestamp(timestampPhrase)
+			if err == nil {
+				fp.Timestamp = timestamp
+			}
+		}
+
+		fp.ExposureTime, err = getIfdTagNumber(exifIfd, "ExposureTime")
+		log.PanicIf(err)
+
+		fp.FNumber, err = getIfdTagNumber(exifIfd, "FNumber")
+		log.PanicIf(err)
+
+		isoSpeed, err := getIfdTagNumber(exifIfd, "ISOSpeedRatings")
+		log.PanicIf(err)
+
+		fp.IsoSpeed = uint32(isoSpeed)
+
+		width, err := getIfdTagNumber(exifIfd, "PixelXDimension")
+		log.PanicIf(err)
+
+		height, err := getIfdTagNumber(exifIfd, "PixelYDimension")
+		log.PanicIf(err)
+
+		fp.Width = uint32(width)
+		fp.Height = uint32(height)
+	}
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardGps]; len(ifds) > 0 {
+		gi, err := ifds[0].GpsInfo()
+		if err == nil {
+			fp.HasGps = true
+			fp.Latitude = gi.Latitude.Decimal()
+			fp.Longitude = gi.Longitude.Decimal()
+		}
+	}
+
+	return fp, nil
+}
+
+// Matches returns true if the two fingerprints describe the same capture
+// within the given tolerances. Fields that are missing from either side are
+// not compared.
+func (fp Fingerprint) Matches(other Fingerprint, options FingerprintOptions) bool {
+	if fp.Timestamp.IsZero() == false && other.Timestamp.IsZero() == false {
+		difference := fp.Timestamp.Sub(other.Timestamp)
+		if difference < 0 {
+			difference = -difference
+		}
+
+		if difference > options.TimestampTolerance {
+			return false
+		}
+	}
+
+	if fingerprintStringsDiffer(fp.Make, other.Make) == true ||
+		fingerprintStringsDiffer(fp.Model, other.Model) == true ||
+		fingerprintStringsDiffer(fp.SerialNumber, other.SerialNumber) == true {
+		return false
+	}
+
+	if fingerprintNumbersDiffer(fp.ExposureTime, other.ExposureTime) == true ||
+		fingerprintNumbersDiffer(fp.FNumber, other.FNumber) == true ||
+		fingerprintNumbersDiffer(float64(fp.IsoSpeed), float64(other.IsoSpeed)) == true {
+		return false
+	}
+
+	if options.IgnoreDimensions == false && fp.Width != 0 && other.Width != 0 {
+		// A rotated re-export swaps the dimensions.
+		sameSize := fp.Width == other.Width && fp.Height == other.Height
+		rotated := fp.Width == other.Height && fp.Height == other.Width
+
+		if sameSize == false && rotated == false {
+			return false
+		}
+	}
+
+	if fp.HasGps == true && other.HasGps == true {
+		if math.Abs(fp.Latitude-other.Latitude) > options.GpsTolerance ||
+			math.Abs(fp.Longitude-other.Longitude) > options.GpsTolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+func fingerprintStringsDiffer(a, b string) bool {
+	return a != "" && b != "" && a != b
+}
+
+func fingerprintNumbersDiffer(a, b float64) bool {
+	return a != 0 && b != 0 && a != b
+}
+
+// Key returns a stable hash of the fingerprint that's suitable for bucketing
+// a large library. The timestamp and coordinates are quantized to the
+// tolerances, so near-matches usually share a key. Values that straddle a
+// quantization boundary won't, though, so use `Matches()` to confirm a
+// duplicate or to compare neighboring buckets.
+func (fp Fingerprint) Key(options FingerprintOptions) string {
+	timestamp := fp.Timestamp
+	if options.TimestampTolerance > 0 {
+		timestamp = timestamp.Truncate(options.TimestampTolerance)
+	}
+
+	latitude, longitude := fp.Latitude, fp.Longitude
+	if options.GpsTolerance > 0 {
+		latitude = math.Floor(latitude / options.GpsTolerance)
+		longitude = math.Floor(longitude / options.GpsTolerance)
+	}
+
+	var dimensions string
+	if options.IgnoreDimensions == false {
+		small, large := fp.Width, fp.Height
+		if small > large {
+			small, large = large, small
+		}
+
+		dimensions = fmt.Sprintf("%dx%d", small, large)
+	}
+
+	parts := []string{
+		fmt.Sprintf("%d", timestamp.Unix()),
+		fp.Make,
+		fp.Model,
+		fp.SerialNumber,
+		fmt.Sprintf("%g", fp.ExposureTime),
+		fmt.Sprintf("%g", fp.FNumber),
+		fmt.Sprintf("%d", fp.IsoSpeed),
+		dimensions,
+		fmt.Sprintf("%t,%g,%g", fp.HasGps, latitude, longitude),
+	}
+
+	digest := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+
+	return hex.EncodeToString(digest[:])
+}
+
+// String returns a descriptive string.
+func (fp Fingerprint) String() string {
+	return fmt.Sprintf("Fingerprint<TIME=[%s] MAKE=[%s] MODEL=[%s] SERIAL=[%s] EXPOSURE=(%g) F=(%g) ISO=(%d) SIZE=(%dx%d)>", fp.Timestamp, fp.Make, fp.Model, fp.SerialNumber, fp.ExposureTime, fp.FNumber, fp.IsoSpeed, fp.Width, fp.Height)
+}
@@ -0,0 +1,111 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getTestFingerprint() Fingerprint {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	fp, err := NewFingerprint(index)
+	log.PanicIf(err)
+
+	return fp
+}
+
+func TestNewFingerprint(t *testing.T) {
+	fp := getTestFingerprint()
+
+	if fp.Model != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not correct: [%s]", fp.Model)
+	} else if fp.Timestamp.IsZero() == true {
+		t.Fatalf("Timestamp not found.")
+	} else if fp.ExposureTime == 0 || fp.FNumber == 0 || fp.IsoSpeed == 0 {
+		t.Fatalf("Exposure not found: %s", fp)
+	}
+}
+
+func TestFingerprint_Matches(t *testing.T) {
+	fp := getTestFingerprint()
+
+	options := FingerprintOptions{
+		TimestampTolerance: 2 * time.Second,
+	}
+
+	if fp.Matches(fp, options) != true {
+		t.Fatalf("Fingerprint should match itself.")
+	}
+
+	shifted := fp
+	shifted.Timestamp = fp.Timestamp.Add(time.Second)
+
+	if fp.Matches(shifted, options) != true {
+		t.Fatalf("Fingerprint should match within the tolerance.")
+	} else if fp.Matches(shifted, FingerprintOptions{}) != false {
+		t.Fatalf("Fingerprint should not match exactly.")
+	}
+
+	rotated := fp
+	rotated.Width, rotated.Height = 3000, 2000
+	fp.Width, fp.Height = 2000, 3000
+
+	if fp.Matches(rotated, options) != true {
+		t.Fatalf("Rotated fingerprint should match.")
+	}
+
+	resized := fp
+	resized.Width, resized.Height = 1000, 1500
+
+	if fp.Matches(resized, options) != false {
+		t.Fatalf("Resized fingerprint should not match.")
+	} else if fp.Matches(resized, FingerprintOptions{TimestampTolerance: time.Second, IgnoreDimensions: true}) != true {
+		t.Fatalf("Resized fingerprint should match when ignoring dimensions.")
+	}
+
+	otherCamera := fp
+	otherCamera.SerialNumber = "12345"
+	fp.SerialNumber = "67890"
+
+	if fp.Matches(otherCamera, options) != false {
+		t.Fatalf("Different cameras should not match.")
+	}
+}
+
+func TestFingerprint_Key(t *testing.T) {
+	fp := getTestFingerprint()
+
+	options := FingerprintOptions{
+		TimestampTolerance: time.Minute,
+	}
+
+	key := fp.Key(options)
+
+	if key != getTestFingerprint().Key(options) {
+		t.Fatalf("Key not stable.")
+	}
+
+	// Move it to the start of its minute so that we can't cross into the
+	// next one.
+	fp.Timestamp = fp.Timestamp.Truncate(time.Minute)
+	key = fp.Key(options)
+
+	shifted := fp
+	shifted.Timestamp = fp.Timestamp.Add(30 * time.Second)
+
+	if shifted.Key(options) != key {
+		t.Fatalf("Key should be the same within the tolerance.")
+	}
+
+	shifted.Timestamp = fp.Timestamp.Add(2 * time.Minute)
+
+	if shifted.Key(options) == key {
+		t.Fatalf("Key should be different outside the tolerance.")
+	}
+}
@@ -0,0 +1,248 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"errors"
+	"fmt"
+
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+var (
+	// ErrFlatTagTypeNotValid means that the type of a `FlatTag` read from
+	// JSON isn't one of the type names (e.g. "SHORT").
+	ErrFlatTagTypeNotValid = errors.New("flat tag type not valid")
+)
+
+// FlatTag is one tag of an index along with its decoded value, for exporting
+// all of the EXIF as structured data (see `IfdIndex.Marshal()`). It's written
+// to and read from JSON with the type as its name, and a value read from JSON
+// has the same Go type that it was parsed with (e.g. `[]uint16` for a SHORT
+// and `[]exifcommon.Rational` for a RATIONAL).
+type FlatTag struct {
+	// FqIfdPath is the fully-qualified path of the IFD (e.g. "IFD1" for the
+	// thumbnail IFD, or "IFD/Exif").
+	FqIfdPath string
+
+	// IfdPath is the path of the IFD without indices (e.g. "IFD").
+	IfdPath string
+
+	TagId uint16
+
+	// TagName is empty if the tag isn't known.
+	TagName string
+
+	// TagType is the type that was declared in the entry.
+	TagType   exifcommon.TagTypePrimitive
+	UnitCount uint32
+
+	// Value is the decoded value. For undefined-type tags, it's whatever the
+	// undefined-type codec decoded (or nil if there isn't one that could), and
+	// it comes back from JSON as generic JSON values.
+	Value interface{}
+
+	// Raw is the value as it was stored. It's only set for undefined-type
+	// tags, whose values can't otherwise be reproduced.
+	Raw []byte
+
+	// ChildFqIfdPath is the IFD that the tag points to, if it's a child IFD.
+	ChildFqIfdPath string
+}
+
+// String returns a descriptive string.
+func (ft FlatTag) String() string {
+	return fmt.Sprintf("FlatTag<FQ-IFD-PATH=[%s] TAG-ID=(0x%04x) TAG-NAME=[%s] TAG-TYPE=[%s] UNIT-COUNT=(%d) VALUE=[%v]>", ft.FqIfdPath, ft.TagId, ft.TagName, ft.TagType, ft.UnitCount, ft.Value)
+}
+
+// flatTagJson is how a `FlatTag` is written as JSON.
+type flatTagJson struct {
+	FqIfdPath      string          `json:"fq_ifd_path"`
+	IfdPath        string          `json:"ifd_path"`
+	TagId          uint16          `json:"id"`
+	TagName        string          `json:"name,omitempty"`
+	TagType        string          `json:"type"`
+	UnitCount      uint32          `json:"unit_count"`
+	Value          json.RawMessage `json:"value"`
+	Raw            []byte          `json:"raw,omitempty"`
+	ChildFqIfdPath string          `json:"child_fq_ifd_path,omitempty"`
+}
+
+// MarshalJSON writes the tag as a JSON object.
+func (ft FlatTag) MarshalJSON() (data []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	value, err := json.Marshal(ft.Value)
+	log.PanicIf(err)
+
+	ftj := flatTagJson{
+		FqIfdPath:      ft.FqIfdPath,
+		IfdPath:        ft.IfdPath,
+		TagId:          ft.TagId,
+		TagName:        ft.TagName,
+		TagType:        ft.TagType.String(),
+		UnitCount:      ft.UnitCount,
+		Value:          value,
+		Raw:            ft.Raw,
+		ChildFqIfdPath: ft.ChildFqIfdPath,
+	}
+
+	data, err = json.Marshal(ftj)
+	log.PanicIf(err)
+
+	return data, nil
+}
+
+// UnmarshalJSON reads the tag from a JSON object, decoding the value into
+// the Go type for the tag's type. `ErrFlatTagTypeNotValid` is returned if the
+// type isn't known.
+func (ft *FlatTag) UnmarshalJSON(data []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ftj := flatTagJson{}
+
+	err = json.Unmarshal(data, &ftj)
+	log.PanicIf(err)
+
+	tagType, found := exifcommon.GetTypeByName(ftj.TagType)
+	if found == false {
+		return ErrFlatTagTypeNotValid
+	}
+
+	var value interface{}
+
+	switch tagType {
+	case exifcommon.TypeByte:
+		value = new([]uint8)
+	case exifcommon.TypeAscii, exifcommon.TypeAsciiNoNul, exifcommon.TypeUtf8:
+		value = new(string)
+	case exifcommon.TypeShort:
+		value = new([]uint16)
+	case exifcommon.TypeLong:
+		value = new([]uint32)
+	case exifcommon.TypeRational:
+		value = new([]exifcommon.Rational)
+	case exifcommon.TypeSignedLong:
+		value = new([]int32)
+	case exifcommon.TypeSignedRational:
+		value = new([]exifcommon.SignedRational)
+	default:
+		value = new(interface{})
+	}
+
+	if len(ftj.Value) > 0 {
+		err = json.Unmarshal(ftj.Value, value)
+		log.PanicIf(err)
+	}
+
+	*ft = FlatTag{
+		FqIfdPath:      ftj.FqIfdPath,
+		IfdPath:        ftj.IfdPath,
+		TagId:          ftj.TagId,
+		TagName:        ftj.TagName,
+		TagType:        tagType,
+		UnitCount:      ftj.UnitCount,
+		Raw:            ftj.Raw,
+		ChildFqIfdPath: ftj.ChildFqIfdPath,
+	}
+
+	switch v := value.(type) {
+	case *[]uint8:
+		ft.Value = *v
+	case *string:
+		ft.Value = *v
+	case *[]uint16:
+		ft.Value = *v
+	case *[]uint32:
+		ft.Value = *v
+	case *[]exifcommon.Rational:
+		ft.Value = *v
+	case *[]int32:
+		ft.Value = *v
+	case *[]exifcommon.SignedRational:
+		ft.Value = *v
+	case *interface{}:
+		ft.Value = *v
+	}
+
+	return nil
+}
+
+// Marshal returns every tag in the index, including those of the child IFDs
+// and the thumbnail IFD, in the order that the IFDs were parsed. Tags with
+// undefined-type values that can't be decoded are still included (see
+// `FlatTag.Value`).
+func (index IfdIndex) Marshal() (flatTags []FlatTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	flatTags = make([]FlatTag, 0)
+
+	for _, ifd := range index.Ifds {
+		for _, ite := range ifd.Entries {
+			ft, err := newFlatTag(ifd, ite)
+			log.PanicIf(err)
+
+			flatTags = append(flatTags, ft)
+		}
+	}
+
+	return flatTags, nil
+}
+
+// newFlatTag builds the flat representation of the given entry of the IFD.
+func newFlatTag(ifd *Ifd, ite *IfdTagEntry) (ft FlatTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ft = FlatTag{
+		FqIfdPath:      ifd.FqIfdPath,
+		IfdPath:        ifd.IfdPath,
+		TagId:          ite.TagId(),
+		TagType:        ite.TagType(),
+		UnitCount:      ite.UnitCount(),
+		ChildFqIfdPath: ite.ChildFqIfdPath(),
+	}
+
+	if it, err := ifd.tagIndex.Get(ifd.IfdPath, ft.TagId); err == nil {
+		ft.TagName = it.Name
+	} else if log.Is(err, ErrTagNotFound) == false {
+		log.Panic(err)
+	}
+
+	if ft.TagType == exifcommon.TypeUndefined {
+		ft.Raw, err = ite.rawView()
+		log.PanicIf(err)
+	}
+
+	ft.Value, err = ite.Value()
+	if err != nil {
+		if err != exifcommon.ErrUnhandledUndefinedTypedTag && err != exifundefined.ErrUnparseableValue {
+			log.Panic(err)
+		}
+
+		ft.Value = nil
+	}
+
+	return ft, nil
+}
@@ -0,0 +1,114 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestFlatTags() []FlatTag {
+	root := exiftest.NewRealisticIfd()
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(
+		exifIfd.Tags,
+		exiftest.Tag{Id: exifVersionTagId, Type: exifcommon.TypeUndefined, Raw: []byte("0230")},
+		exiftest.Tag{Id: 0x9204, Value: []exifcommon.SignedRational{{Numerator: -2, Denominator: 3}}})
+
+	root.Next = &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x0103, Value: []uint16{6}},
+		},
+	}
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	flatTags, err := index.Marshal()
+	log.PanicIf(err)
+
+	return flatTags
+}
+
+func TestIfdIndex_Marshal(t *testing.T) {
+	flatTags := getTestFlatTags()
+
+	byPath := make(map[string]map[uint16]FlatTag)
+	for _, ft := range flatTags {
+		if byPath[ft.FqIfdPath] == nil {
+			byPath[ft.FqIfdPath] = make(map[uint16]FlatTag)
+		}
+
+		byPath[ft.FqIfdPath][ft.TagId] = ft
+	}
+
+	make_ := byPath["IFD"][0x010f]
+	compression := byPath["IFD1"][0x0103]
+	gpsPointer := byPath["IFD"][exifcommon.IfdGpsId]
+	exifVersion := byPath["IFD/Exif"][exifVersionTagId]
+	latitude := byPath["IFD/GPSInfo"][0x0002]
+
+	if len(byPath) != 4 {
+		t.Fatalf("IFDs not correct: %v", byPath)
+	} else if make_.TagName != "Make" || make_.TagType != exifcommon.TypeAscii || make_.UnitCount != 6 || make_.Value != "Canon" || make_.Raw != nil {
+		t.Fatalf("Make not correct: %s", make_)
+	} else if compression.IfdPath != exifcommon.IfdPathStandard || reflect.DeepEqual(compression.Value, []uint16{6}) != true {
+		t.Fatalf("Thumbnail IFD tag not correct: %s", compression)
+	} else if gpsPointer.ChildFqIfdPath != "IFD/GPSInfo" {
+		t.Fatalf("Child IFD not correct: %s", gpsPointer)
+	} else if bytes.Equal(exifVersion.Raw, []byte("0230")) != true || exifVersion.Value == nil {
+		t.Fatalf("Undefined tag not correct: %s", exifVersion)
+	} else if len(latitude.Value.([]exifcommon.Rational)) != 3 {
+		t.Fatalf("Latitude not correct: %s", latitude)
+	}
+}
+
+func TestFlatTag_Json_RoundTrip(t *testing.T) {
+	flatTags := getTestFlatTags()
+
+	data, err := json.Marshal(flatTags)
+	log.PanicIf(err)
+
+	recovered := make([]FlatTag, 0)
+
+	err = json.Unmarshal(data, &recovered)
+	log.PanicIf(err)
+
+	if len(recovered) != len(flatTags) {
+		t.Fatalf("Tag count not correct: (%d) != (%d)", len(recovered), len(flatTags))
+	}
+
+	for i, ft := range flatTags {
+		// Undefined values come back as generic JSON but keep the raw bytes.
+		if ft.TagType == exifcommon.TypeUndefined {
+			ft.Value = recovered[i].Value
+		}
+
+		if reflect.DeepEqual(recovered[i], ft) != true {
+			t.Fatalf("Tag not recovered: %s != %s", recovered[i], ft)
+		}
+	}
+}
+
+func TestFlatTag_UnmarshalJSON_TypeNotValid(t *testing.T) {
+	ft := FlatTag{}
+
+	err := json.Unmarshal([]byte(`{"id": 1, "type": "QUAD", "value": [1]}`), &ft)
+	if log.Is(err, ErrFlatTagTypeNotValid) == false {
+		t.Fatalf("Expected type error: %v", err)
+	}
+}
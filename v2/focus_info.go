@@ -0,0 +1,281 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	canonAfInfo2TagId       = 0x0026
+	nikonAfInfoTagId        = 0x0088
+	sonyFocusLocationTagId  = 0x2027
+	canonAfInfo2HeaderCount = 8
+)
+
+var (
+	focusInfoLogger = log.NewLogger("exif.focus_info")
+)
+
+var (
+	// ErrNoFocusInfo means that no focus information was found.
+	ErrNoFocusInfo = errors.New("no focus info")
+)
+
+var (
+	canonAfAreaModeNames = map[uint16]string{
+		0:  "Off (Manual Focus)",
+		2:  "Single-point AF",
+		4:  "Auto",
+		5:  "Face Detect AF",
+		6:  "Face + Tracking",
+		7:  "Zone AF",
+		8:  "AF Point Expansion (4 point)",
+		9:  "Spot AF",
+		10: "AF Point Expansion (8 point)",
+		11: "Flexizone Multi",
+		13: "Flexizone Single",
+		14: "Large Zone AF",
+	}
+
+	nikonAfAreaModeNames = map[byte]string{
+		0: "Single Area",
+		1: "Dynamic Area",
+		2: "Dynamic Area (closest subject)",
+		3: "Group Dynamic",
+		4: "Single Area (wide)",
+		5: "Dynamic Area (wide)",
+	}
+
+	// nikonAfPointNames are the points of the 11-point layout that the
+	// AFInfo tag describes.
+	nikonAfPointNames = []string{
+		"Center",
+		"Top",
+		"Bottom",
+		"Mid-left",
+		"Mid-right",
+		"Upper-left",
+		"Upper-right",
+		"Lower-left",
+		"Lower-right",
+		"Far Left",
+		"Far Right",
+	}
+)
+
+// FocusPoint is one AF point. The position is only available if
+// `HasPosition` is true, in which case it's the rectangle of the point in
+// the coordinate system of the `FocusInfo` (origin at the top-left).
+type FocusPoint struct {
+	Index int
+	Name  string
+
+	HasPosition bool
+	X, Y        int
+	Width       int
+	Height      int
+
+	Selected bool
+	InFocus  bool
+}
+
+// String returns a descriptive string.
+func (fp FocusPoint) String() string {
+	return fmt.Sprintf("FocusPoint<INDEX=(%d) NAME=[%s] X=(%d) Y=(%d) W=(%d) H=(%d) SELECTED=[%v] IN-FOCUS=[%v]>", fp.Index, fp.Name, fp.X, fp.Y, fp.Width, fp.Height, fp.Selected, fp.InFocus)
+}
+
+// FocusInfo is a normalized view of the autofocus information.
+type FocusInfo struct {
+	AreaMode string
+
+	// Points has every AF point that the camera described, whether or not it
+	// was used.
+	Points []FocusPoint
+
+	// ImageWidth and ImageHeight are the dimensions that the point positions
+	// are relative to. These may differ from the stored image (e.g. if it
+	// was cropped or resized in camera), so positions should be scaled.
+	ImageWidth  int
+	ImageHeight int
+
+	// FocusDistance is in meters.
+	FocusDistance    float64
+	HasFocusDistance bool
+
+	Sources []string
+}
+
+// SelectedPoints returns the points that were selected for focusing.
+func (fi FocusInfo) SelectedPoints() []FocusPoint {
+	points := make([]FocusPoint, 0)
+
+	for _, fp := range fi.Points {
+		if fp.Selected == true {
+			points = append(points, fp)
+		}
+	}
+
+	return points
+}
+
+// InFocusPoints returns the points that achieved focus.
+func (fi FocusInfo) InFocusPoints() []FocusPoint {
+	points := make([]FocusPoint, 0)
+
+	for _, fp := range fi.Points {
+		if fp.InFocus == true {
+			points = append(points, fp)
+		}
+	}
+
+	return points
+}
+
+// GetFocusInfo collects the AF points from the Canon (AFInfo2), Nikon
+// (AFInfo), and Sony (FocusLocation) maker-notes and the focus distance from
+// the standard SubjectDistance tag. Nikon point positions aren't known, so
+// only their names are given. `ErrNoFocusInfo` is returned if nothing was
+// found.
+func GetFocusInfo(index IfdIndex) (fi FocusInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fi.Points = make([]FocusPoint, 0)
+	fi.Sources = make([]string, 0)
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		subjectDistance, err := getIfdTagNumber(ifds[0], "SubjectDistance")
+		log.PanicIf(err)
+
+		if subjectDistance > 0 {
+			fi.FocusDistance = subjectDistance
+			fi.HasFocusDistance = true
+			fi.Sources = append(fi.Sources, "Exif/SubjectDistance")
+		}
+	}
+
+	vendor, mni, err := getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	switch vendor {
+	case makerNoteVendorCanon:
+		fi.loadCanon(mni)
+	case makerNoteVendorNikon:
+		fi.loadNikon(mni)
+	case makerNoteVendorSony:
+		fi.loadSony(mni)
+	}
+
+	if len(fi.Sources) == 0 {
+		return fi, ErrNoFocusInfo
+	}
+
+	return fi, nil
+}
+
+// loadCanon reads AFInfo2. After an eight-value header come the widths,
+// heights, x-positions, and y-positions of each point and then the in-focus
+// and selected bitmasks. Positions are signed, relative to the center of the
+// image, and y increases upwards.
+func (fi *FocusInfo) loadCanon(mni *makerNoteIfd) {
+	afInfo, found := mni.Uint16s(canonAfInfo2TagId)
+	if found == false || len(afInfo) < canonAfInfo2HeaderCount {
+		return
+	}
+
+	pointCount := int(afInfo[2])
+	maskCount := (pointCount + 15) / 16
+
+	if len(afInfo) < canonAfInfo2HeaderCount+pointCount*4+maskCount*2 {
+		focusInfoLogger.Warningf(nil, "Canon AFInfo2 is too short for (%d) points.", pointCount)
+		return
+	}
+
+	fi.AreaMode = canonAfAreaModeNames[afInfo[1]]
+	fi.ImageWidth = int(afInfo[6])
+	fi.ImageHeight = int(afInfo[7])
+
+	widths := afInfo[canonAfInfo2HeaderCount:]
+	heights := widths[pointCount:]
+	xPositions := heights[pointCount:]
+	yPositions := xPositions[pointCount:]
+	inFocus := yPositions[pointCount:]
+	selected := inFocus[maskCount:]
+
+	for i := 0; i < pointCount; i++ {
+		width := int(widths[i])
+		height := int(heights[i])
+
+		fp := FocusPoint{
+			Index:       i,
+			HasPosition: true,
+			X:           fi.ImageWidth/2 + int(int16(xPositions[i])) - width/2,
+			Y:           fi.ImageHeight/2 - int(int16(yPositions[i])) - height/2,
+			Width:       width,
+			Height:      height,
+			InFocus:     inFocus[i/16]&(1<<uint(i%16)) != 0,
+			Selected:    selected[i/16]&(1<<uint(i%16)) != 0,
+		}
+
+		fi.Points = append(fi.Points, fp)
+	}
+
+	fi.Sources = append(fi.Sources, "Canon/AFInfo2")
+}
+
+// loadNikon reads AFInfo, which has the area mode, the primary point, and a
+// bitmask of the points that were in focus.
+func (fi *FocusInfo) loadNikon(mni *makerNoteIfd) {
+	entry, found := mni.entries[nikonAfInfoTagId]
+	if found == false || len(entry.value) < 4 {
+		return
+	}
+
+	afInfo := entry.value
+
+	fi.AreaMode = nikonAfAreaModeNames[afInfo[0]]
+	primary := int(afInfo[1])
+	inFocus := mni.byteOrder.Uint16(afInfo[2:])
+
+	for i, name := range nikonAfPointNames {
+		fp := FocusPoint{
+			Index:    i,
+			Name:     name,
+			Selected: i == primary,
+			InFocus:  inFocus&(1<<uint(i)) != 0,
+		}
+
+		fi.Points = append(fi.Points, fp)
+	}
+
+	fi.Sources = append(fi.Sources, "Nikon/AFInfo")
+}
+
+// loadSony reads FocusLocation, which is the image size followed by the
+// center of the focus point. It's reported as a point with no size.
+func (fi *FocusInfo) loadSony(mni *makerNoteIfd) {
+	focusLocation, found := mni.Uint16s(sonyFocusLocationTagId)
+	if found == false || len(focusLocation) < 4 {
+		return
+	}
+
+	fi.ImageWidth = int(focusLocation[0])
+	fi.ImageHeight = int(focusLocation[1])
+
+	fp := FocusPoint{
+		HasPosition: true,
+		X:           int(focusLocation[2]),
+		Y:           int(focusLocation[3]),
+		Selected:    true,
+	}
+
+	fi.Points = append(fi.Points, fp)
+	fi.Sources = append(fi.Sources, "Sony/FocusLocation")
+}
@@ -0,0 +1,118 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestGetFocusInfo_Canon(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	fi, err := GetFocusInfo(index)
+	log.PanicIf(err)
+
+	if len(fi.Points) != 61 {
+		t.Fatalf("Point count not correct: (%d)", len(fi.Points))
+	} else if fi.AreaMode != "Single-point AF" {
+		t.Fatalf("Area mode not correct: [%s]", fi.AreaMode)
+	} else if fi.ImageWidth != 3840 || fi.ImageHeight != 2560 {
+		t.Fatalf("Dimensions not correct: (%d) (%d)", fi.ImageWidth, fi.ImageHeight)
+	}
+
+	selected := fi.SelectedPoints()
+	if len(selected) != 1 {
+		t.Fatalf("Selected count not correct: %v", selected)
+	}
+
+	// The center point.
+	fp := selected[0]
+	centerX := fp.X + fp.Width/2
+	centerY := fp.Y + fp.Height/2
+
+	if fp.Index != 30 || fp.InFocus != true {
+		t.Fatalf("Selected point not correct: %s", fp)
+	} else if centerX != 1920 || centerY != 1280 {
+		t.Fatalf("Selected point not centered: (%d) (%d)", centerX, centerY)
+	}
+}
+
+func TestGetFocusInfo_Nikon(t *testing.T) {
+	makerNoteIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: nikonAfInfoTagId, Raw: []byte{1, 3, 0, 0x18}, Type: exifcommon.TypeUndefined},
+		},
+	}
+
+	tiff, err := exiftest.Build(makerNoteIfd, binary.BigEndian)
+	log.PanicIf(err)
+
+	makerNote := append([]byte("Nikon\x00\x02\x10\x00\x00"), tiff...)
+	subjectDistance := exiftest.Tag{Id: 0x9206, Value: []exifcommon.Rational{{Numerator: 5, Denominator: 2}}}
+
+	fi, err := GetFocusInfo(getTestMakerNoteIndex("NIKON CORPORATION", makerNote, nil, []exiftest.Tag{subjectDistance}))
+	log.PanicIf(err)
+
+	if fi.AreaMode != "Dynamic Area" {
+		t.Fatalf("Area mode not correct: [%s]", fi.AreaMode)
+	} else if fi.HasFocusDistance != true || fi.FocusDistance != 2.5 {
+		t.Fatalf("Focus distance not correct: (%f)", fi.FocusDistance)
+	}
+
+	selected := fi.SelectedPoints()
+	inFocus := fi.InFocusPoints()
+
+	if len(selected) != 1 || selected[0].Name != "Mid-left" || selected[0].HasPosition != false {
+		t.Fatalf("Selected points not correct: %v", selected)
+	} else if len(inFocus) != 2 || inFocus[0].Name != "Mid-left" || inFocus[1].Name != "Mid-right" {
+		t.Fatalf("In-focus points not correct: %v", inFocus)
+	}
+}
+
+func TestGetFocusInfo_Sony(t *testing.T) {
+	b := new(bytes.Buffer)
+	b.Write(sonyMakerNoteSignatures[0])
+
+	binary.Write(b, binary.BigEndian, uint16(1))
+	binary.Write(b, binary.BigEndian, []uint16{sonyFocusLocationTagId, uint16(exifcommon.TypeShort)})
+	binary.Write(b, binary.BigEndian, uint32(4))
+
+	// The values don't fit in the entry so they follow the IFD. Offsets are
+	// relative to the EXIF data, so we fix this up once we know where the
+	// maker-note landed.
+	binary.Write(b, binary.BigEndian, uint32(0))
+	binary.Write(b, binary.BigEndian, uint32(0))
+	binary.Write(b, binary.BigEndian, []uint16{6000, 4000, 1500, 1000})
+
+	makerNote := b.Bytes()
+	index := getTestMakerNoteIndex("SONY", makerNote, nil, nil)
+
+	ite, err := getMakerNoteTag(index)
+	log.PanicIf(err)
+
+	valueOffset := ite.getValueOffset() + uint32(len(makerNote)) - 8
+	binary.BigEndian.PutUint32(ite.addressableData[ite.getValueOffset()+12+2+8:], valueOffset)
+
+	fi, err := GetFocusInfo(index)
+	log.PanicIf(err)
+
+	if fi.ImageWidth != 6000 || fi.ImageHeight != 4000 {
+		t.Fatalf("Dimensions not correct: (%d) (%d)", fi.ImageWidth, fi.ImageHeight)
+	} else if len(fi.Points) != 1 || fi.Points[0].X != 1500 || fi.Points[0].Y != 1000 {
+		t.Fatalf("Points not correct: %v", fi.Points)
+	}
+}
+
+func TestGetFocusInfo_None(t *testing.T) {
+	_, err := GetFocusInfo(getTestMakerNoteIndex("Acme", nil, nil, nil))
+	if err != ErrNoFocusInfo {
+		t.Fatalf("Expected no focus info: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package exif
+
+import (
+	"runtime"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// ParseBytesForFuzz is an entry-point for fuzzers. It searches the data for
+// EXIF, parses it, and decodes every value and thumbnail. It follows the
+// go-fuzz convention and returns (1) if the data had parseable EXIF and (0)
+// otherwise.
+//
+// Errors from malformed data are expected and ignored. However, since the
+// parser recovers panics and returns them as errors, a runtime error (e.g. an
+// out-of-bounds index) would otherwise go unnoticed. Those are panicked again
+// so that the fuzzer records a crash.
+func ParseBytesForFuzz(data []byte) int {
+	rawExif, err := SearchAndExtractExif(data)
+	if err != nil {
+		panicIfRuntimeError(err)
+		return 0
+	}
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	_, index, err := Collect(im, ti, rawExif)
+	if err != nil {
+		panicIfRuntimeError(err)
+		return 0
+	}
+
+	for _, ifd := range index.Ifds {
+		for _, ite := range ifd.Entries {
+			_, err := ite.Value()
+			panicIfRuntimeError(err)
+
+			_, err = ite.Format()
+			panicIfRuntimeError(err)
+		}
+
+		_, err := ifd.Thumbnail()
+		panicIfRuntimeError(err)
+	}
+
+	return 1
+}
+
+// panicIfRuntimeError panics if the error was caused by a runtime error
+// rather than being raised deliberately.
+func panicIfRuntimeError(err error) {
+	if err == nil {
+		return
+	}
+
+	if _, ok := log.Wrap(err).Err.(runtime.Error); ok == true {
+		panic(err)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build go1.18
+// +build go1.18
+
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// Run with `go test -fuzz FuzzParseBytes`.
+func FuzzParseBytes(f *testing.F) {
+	seeds, err := exiftest.SeedCorpus()
+	log.PanicIf(err)
+
+	for _, data := range seeds {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseBytesForFuzz(data)
+	})
+}
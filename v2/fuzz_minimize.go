@@ -0,0 +1,436 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"runtime/debug"
+
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+	goerrors "github.com/go-errors/errors"
+)
+
+const (
+	// minimizeEntrySize is the size of an IFD entry.
+	minimizeEntrySize = int(IfdTagEntrySize)
+)
+
+const (
+	// DefaultMinimizeMaxAttempts is how many times, by default, the input is
+	// tried while it's minimized.
+	DefaultMinimizeMaxAttempts = 10000
+)
+
+var (
+	// ErrNoFuzzFailure means that the input given to `MinimizeFuzzFailure()`
+	// doesn't fail.
+	ErrNoFuzzFailure = errors.New("input does not fail")
+)
+
+var (
+	// minimizeDigitsRe matches the numbers in a failure's message, which
+	// change as the input is minimized (e.g. the length in "index out of
+	// range [8] with length 4").
+	minimizeDigitsRe = regexp.MustCompile(`[0-9]+`)
+
+	// minimizeIfdTagIds are the tags whose values point to child IFDs.
+	minimizeIfdTagIds = map[uint16]struct{}{
+		0x8769: {},
+		0x8825: {},
+		0xa005: {},
+		0x014a: {},
+	}
+)
+
+// MinimizeOptions control `MinimizeFuzzFailure()`.
+type MinimizeOptions struct {
+	// Check is given each candidate input and panics if it fails. By default,
+	// it's `ParseBytesForFuzz()`.
+	Check func(data []byte)
+
+	// MaxAttempts is how many candidates are tried at most. Zero is
+	// `DefaultMinimizeMaxAttempts`.
+	MaxAttempts int
+}
+
+// CrashReport describes a failure and has the smallest input that was found
+// to reproduce it.
+type CrashReport struct {
+	// Signature identifies the failure: the type of the error and its message
+	// without any numbers. Every reduction of the input kept it the same.
+	Signature string `json:"signature"`
+
+	// Message, Position, and Stack are from the minimized input. Position is
+	// where the parser was (see `ExifError`), if it was parsing an IFD.
+	Message  string `json:"message"`
+	Position string `json:"position,omitempty"`
+	Stack    string `json:"stack,omitempty"`
+
+	OriginalSize   int    `json:"original_size"`
+	OriginalSha256 string `json:"original_sha256"`
+
+	MinimizedSize   int    `json:"minimized_size"`
+	MinimizedSha256 string `json:"minimized_sha256"`
+
+	// Attempts is how many candidates were tried.
+	Attempts int `json:"attempts"`
+
+	// Reproducer is the minimized input.
+	Reproducer []byte `json:"-"`
+}
+
+// String returns a descriptive string.
+func (cr CrashReport) String() string {
+	return fmt.Sprintf("CrashReport<SIGNATURE=[%s] ORIGINAL-SIZE=(%d) MINIMIZED-SIZE=(%d) ATTEMPTS=(%d)>", cr.Signature, cr.OriginalSize, cr.MinimizedSize, cr.Attempts)
+}
+
+// WriteFiles writes the reproducer and the report, as JSON, to the directory.
+// They're named after the SHA-256 of the reproducer so that the same failure
+// found twice is written once.
+func (cr CrashReport) WriteFiles(dirpath string) (reproducerFilepath, reportFilepath string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	reproducerFilepath = path.Join(dirpath, cr.MinimizedSha256+".bin")
+	reportFilepath = path.Join(dirpath, cr.MinimizedSha256+".json")
+
+	err = ioutil.WriteFile(reproducerFilepath, cr.Reproducer, 0644)
+	log.PanicIf(err)
+
+	data, err := json.MarshalIndent(cr, "", "    ")
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(reportFilepath, data, 0644)
+	log.PanicIf(err)
+
+	return reproducerFilepath, reportFilepath, nil
+}
+
+// fuzzFailure is the outcome of one try of the input.
+type fuzzFailure struct {
+	signature string
+	message   string
+	position  string
+	stack     string
+}
+
+// fuzzMinimizer tries candidates, keeping the smallest that fails the same
+// way.
+type fuzzMinimizer struct {
+	check       func(data []byte)
+	maxAttempts int
+	attempts    int
+
+	signature string
+	data      []byte
+	failure   fuzzFailure
+}
+
+// try runs the check and returns the failure, or nil if it passed.
+func (fm *fuzzMinimizer) try(data []byte) (failure *fuzzFailure) {
+	fm.attempts++
+
+	defer func() {
+		state := recover()
+		if state == nil {
+			return
+		}
+
+		failure = describeFuzzFailure(state, debug.Stack())
+	}()
+
+	fm.check(data)
+
+	return nil
+}
+
+// accept makes the candidate the current input if it fails the same way.
+func (fm *fuzzMinimizer) accept(candidate []byte) bool {
+	if fm.attempts >= fm.maxAttempts {
+		return false
+	}
+
+	failure := fm.try(candidate)
+	if failure == nil || failure.signature != fm.signature {
+		return false
+	}
+
+	fm.data = candidate
+	fm.failure = *failure
+
+	return true
+}
+
+// describeFuzzFailure describes the recovered value.
+func describeFuzzFailure(state interface{}, stack []byte) *fuzzFailure {
+	err, ok := state.(error)
+	if ok == false {
+		err = fmt.Errorf("%v", state)
+	}
+
+	failure := &fuzzFailure{
+		message: err.Error(),
+		stack:   string(stack),
+	}
+
+	if ee, found := AsExifError(err); found == true {
+		failure.position = ee.Position()
+	}
+
+	// Find the underlying error, preferring the stack of where it was first
+	// wrapped since that's closer to where it happened.
+
+	for {
+		if e, ok := err.(*goerrors.Error); ok == true {
+			failure.stack = string(e.Stack())
+			err = e.Err
+		} else if wrapper, ok := err.(interface{ Unwrap() error }); ok == true && wrapper.Unwrap() != nil {
+			err = wrapper.Unwrap()
+		} else {
+			break
+		}
+	}
+
+	failure.signature = fmt.Sprintf("%T: %s", err, minimizeDigitsRe.ReplaceAllString(err.Error(), "N"))
+
+	return failure
+}
+
+// MinimizeFuzzFailure reduces an input that fails (e.g. one that a user
+// reported, or that a fuzzer found) to a small one that fails the same way,
+// and reports the failure. The reductions are tried in a fixed order, so the
+// result is always the same for the same input: the container around the EXIF
+// is dropped, then IFDs are unlinked, entries are removed, the data is
+// truncated, and what's left is zeroed in ever smaller chunks, until nothing
+// more can be removed. `ErrNoFuzzFailure` is returned if the input doesn't
+// fail.
+func MinimizeFuzzFailure(data []byte, opts MinimizeOptions) (cr CrashReport, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fm := &fuzzMinimizer{
+		check:       opts.Check,
+		maxAttempts: opts.MaxAttempts,
+	}
+
+	if fm.check == nil {
+		fm.check = func(data []byte) {
+			ParseBytesForFuzz(data)
+		}
+	}
+
+	if fm.maxAttempts == 0 {
+		fm.maxAttempts = DefaultMinimizeMaxAttempts
+	}
+
+	failure := fm.try(data)
+	if failure == nil {
+		return cr, ErrNoFuzzFailure
+	}
+
+	fm.signature = failure.signature
+	fm.data = data
+	fm.failure = *failure
+
+	if rawExif, err := SearchAndExtractExif(data); err == nil && len(rawExif) < len(data) {
+		fm.accept(append([]byte{}, rawExif...))
+	}
+
+	for {
+		previous := fm.data
+
+		fm.unlinkIfds()
+		fm.removeEntries()
+		fm.truncate()
+		fm.zero()
+
+		if fm.attempts >= fm.maxAttempts || bytes.Equal(fm.data, previous) == true {
+			break
+		}
+	}
+
+	originalSum := sha256.Sum256(data)
+	minimizedSum := sha256.Sum256(fm.data)
+
+	cr = CrashReport{
+		Signature:       fm.signature,
+		Message:         fm.failure.message,
+		Position:        fm.failure.position,
+		Stack:           fm.failure.stack,
+		OriginalSize:    len(data),
+		OriginalSha256:  hex.EncodeToString(originalSum[:]),
+		MinimizedSize:   len(fm.data),
+		MinimizedSha256: hex.EncodeToString(minimizedSum[:]),
+		Attempts:        fm.attempts,
+		Reproducer:      fm.data,
+	}
+
+	return cr, nil
+}
+
+// minimizeIfd is an IFD that was found in the input.
+type minimizeIfd struct {
+	offset     uint32
+	entryCount int
+}
+
+// findIfds returns the IFDs that can be reached from the header, as far as
+// they can be read. The data may be damaged, so nothing is assumed about it.
+func (fm *fuzzMinimizer) findIfds() (ifds []minimizeIfd, byteOrder binary.ByteOrder) {
+	eh, err := ParseExifHeader(fm.data)
+	if err != nil {
+		return nil, nil
+	}
+
+	byteOrder = eh.ByteOrder
+
+	ifds = make([]minimizeIfd, 0)
+	visited := make(map[uint32]struct{})
+	queue := []uint32{eh.FirstIfdOffset}
+
+	for len(queue) > 0 {
+		offset := queue[0]
+		queue = queue[1:]
+
+		if _, found := visited[offset]; found == true || offset == 0 || int(offset)+2 > len(fm.data) {
+			continue
+		}
+
+		visited[offset] = struct{}{}
+
+		entryCount := int(byteOrder.Uint16(fm.data[offset:]))
+		if available := (len(fm.data) - int(offset) - 2) / minimizeEntrySize; entryCount > available {
+			entryCount = available
+		}
+
+		ifds = append(ifds, minimizeIfd{offset: offset, entryCount: entryCount})
+
+		for i := 0; i < entryCount; i++ {
+			entryOffset := int(offset) + 2 + i*minimizeEntrySize
+			tagId := byteOrder.Uint16(fm.data[entryOffset:])
+
+			if _, found := minimizeIfdTagIds[tagId]; found == true {
+				queue = append(queue, byteOrder.Uint32(fm.data[entryOffset+8:]))
+			}
+		}
+
+		nextOffset := int(offset) + 2 + entryCount*minimizeEntrySize
+		if nextOffset+4 <= len(fm.data) {
+			queue = append(queue, byteOrder.Uint32(fm.data[nextOffset:]))
+		}
+	}
+
+	return ifds, byteOrder
+}
+
+// unlinkIfds tries clearing the next-IFD offset of each IFD.
+func (fm *fuzzMinimizer) unlinkIfds() {
+	ifds, byteOrder := fm.findIfds()
+
+	for _, ifd := range ifds {
+		nextOffset := int(ifd.offset) + 2 + ifd.entryCount*minimizeEntrySize
+		if nextOffset+4 > len(fm.data) || byteOrder.Uint32(fm.data[nextOffset:]) == 0 {
+			continue
+		}
+
+		candidate := append([]byte{}, fm.data...)
+		byteOrder.PutUint32(candidate[nextOffset:], 0)
+
+		fm.accept(candidate)
+	}
+}
+
+// removeEntries tries removing each entry of each IFD, from the last. The
+// entries after it, and the next-IFD offset, are moved up.
+func (fm *fuzzMinimizer) removeEntries() {
+	ifds, byteOrder := fm.findIfds()
+
+	for _, ifd := range ifds {
+		entryCount := ifd.entryCount
+
+		for i := entryCount - 1; i >= 0; i-- {
+			start := int(ifd.offset) + 2 + i*minimizeEntrySize
+
+			end := int(ifd.offset) + 2 + entryCount*minimizeEntrySize + 4
+			if end > len(fm.data) {
+				end = len(fm.data)
+			}
+
+			candidate := append([]byte{}, fm.data...)
+			copy(candidate[start:], candidate[start+minimizeEntrySize:end])
+
+			for j := end - minimizeEntrySize; j < end; j++ {
+				candidate[j] = 0
+			}
+
+			byteOrder.PutUint16(candidate[ifd.offset:], uint16(entryCount-1))
+
+			if fm.accept(candidate) == true {
+				entryCount--
+			}
+		}
+	}
+}
+
+// truncate tries removing the end of the data, in ever smaller amounts.
+func (fm *fuzzMinimizer) truncate() {
+	for step := len(fm.data) / 2; step > 0; {
+		if step >= len(fm.data) {
+			step = len(fm.data) / 2
+		} else if fm.accept(fm.data[:len(fm.data)-step]) == false {
+			step /= 2
+		}
+	}
+}
+
+// zero tries zeroing the data in ever smaller chunks, skipping those that are
+// already zero.
+func (fm *fuzzMinimizer) zero() {
+	for size := len(fm.data) / 2; size > 0; size /= 2 {
+		for start := 0; start < len(fm.data); start += size {
+			end := start + size
+			if end > len(fm.data) {
+				end = len(fm.data)
+			}
+
+			if isZero(fm.data[start:end]) == true {
+				continue
+			}
+
+			candidate := append([]byte{}, fm.data...)
+			for i := start; i < end; i++ {
+				candidate[i] = 0
+			}
+
+			fm.accept(candidate)
+		}
+	}
+}
+
+// isZero returns true if every byte is zero.
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,169 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+const (
+	testMinimizeTagId = 0xfe00
+)
+
+// getTestMinimizeJpeg returns a JPEG whose EXIF has the tag that
+// `checkTestMinimize()` fails on.
+func getTestMinimizeJpeg() []byte {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: testMinimizeTagId, Value: []uint16{1, 2}})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return exiftest.WrapJpeg(rawExif)
+}
+
+// checkTestMinimize has a bug: it indexes out of range if the first IFD has
+// the test tag.
+func checkTestMinimize(data []byte) {
+	rawExif, err := SearchAndExtractExif(data)
+	if err != nil {
+		return
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	if err != nil {
+		return
+	}
+
+	if _, found := index.RootIfd.EntriesByTagId[testMinimizeTagId]; found == true {
+		values := make([]int, 1)
+		_ = values[len(data)]
+	}
+}
+
+func TestMinimizeFuzzFailure(t *testing.T) {
+	data := getTestMinimizeJpeg()
+
+	cr, err := MinimizeFuzzFailure(data, MinimizeOptions{Check: checkTestMinimize})
+	log.PanicIf(err)
+
+	if cr.Signature != "runtime.boundsError: runtime error: index out of range [N] with length N" {
+		t.Fatalf("Signature not correct: [%s]", cr.Signature)
+	} else if cr.OriginalSize != len(data) || cr.MinimizedSize != len(cr.Reproducer) {
+		t.Fatalf("Sizes not correct: %s", cr)
+	} else if cr.MinimizedSize >= len(data)/4 {
+		t.Fatalf("Input not minimized: %s", cr)
+	} else if strings.Contains(cr.Stack, "checkTestMinimize") == false {
+		t.Fatalf("Stack not correct:\n%s", cr.Stack)
+	}
+
+	// The reproducer fails, and has only the one entry.
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), cr.Reproducer)
+	log.PanicIf(err)
+
+	if len(index.Ifds) != 1 || len(index.RootIfd.Entries) != 1 || index.RootIfd.Entries[0].TagId() != testMinimizeTagId {
+		t.Fatalf("Reproducer not minimal: %v", index.RootIfd.Entries)
+	}
+
+	func() {
+		defer func() {
+			if state := recover(); state == nil {
+				t.Fatalf("Reproducer doesn't fail.")
+			}
+		}()
+
+		checkTestMinimize(cr.Reproducer)
+	}()
+
+	// The result is the same every time.
+
+	again, err := MinimizeFuzzFailure(data, MinimizeOptions{Check: checkTestMinimize})
+	log.PanicIf(err)
+
+	if bytes.Equal(again.Reproducer, cr.Reproducer) != true || again.Attempts != cr.Attempts {
+		t.Fatalf("Minimization not deterministic: %s != %s", again, cr)
+	}
+}
+
+func TestMinimizeFuzzFailure_SameFailure(t *testing.T) {
+	data := getTestMinimizeJpeg()
+
+	// Smaller inputs fail differently, which mustn't be mistaken for the
+	// original failure.
+	check := func(data []byte) {
+		if len(data) >= 100 {
+			checkTestMinimize(data)
+		} else if _, err := ParseExifHeader(data); err == nil {
+			log.Panicf("a different failure")
+		}
+	}
+
+	cr, err := MinimizeFuzzFailure(data, MinimizeOptions{Check: check})
+	log.PanicIf(err)
+
+	if cr.MinimizedSize < 100 {
+		t.Fatalf("Minimized to a different failure: %s", cr)
+	} else if strings.HasPrefix(cr.Signature, "runtime.boundsError: ") == false {
+		t.Fatalf("Signature not correct: [%s]", cr.Signature)
+	}
+}
+
+func TestMinimizeFuzzFailure_MaxAttempts(t *testing.T) {
+	cr, err := MinimizeFuzzFailure(getTestMinimizeJpeg(), MinimizeOptions{Check: checkTestMinimize, MaxAttempts: 5})
+	log.PanicIf(err)
+
+	if cr.Attempts != 5 {
+		t.Fatalf("Attempts not limited: (%d)", cr.Attempts)
+	}
+}
+
+func TestMinimizeFuzzFailure_NoFailure(t *testing.T) {
+	if _, err := MinimizeFuzzFailure(getTestExifData(), MinimizeOptions{}); err != ErrNoFuzzFailure {
+		t.Fatalf("Expected ErrNoFuzzFailure: %v", err)
+	}
+}
+
+func TestCrashReport_WriteFiles(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	cr, err := MinimizeFuzzFailure(getTestMinimizeJpeg(), MinimizeOptions{Check: checkTestMinimize})
+	log.PanicIf(err)
+
+	reproducerFilepath, reportFilepath, err := cr.WriteFiles(tempPath)
+	log.PanicIf(err)
+
+	reproducer, err := ioutil.ReadFile(reproducerFilepath)
+	log.PanicIf(err)
+
+	if bytes.Equal(reproducer, cr.Reproducer) != true {
+		t.Fatalf("Reproducer not written.")
+	}
+
+	data, err := ioutil.ReadFile(reportFilepath)
+	log.PanicIf(err)
+
+	written := CrashReport{}
+
+	err = json.Unmarshal(data, &written)
+	log.PanicIf(err)
+
+	if written.Signature != cr.Signature || written.MinimizedSha256 != cr.MinimizedSha256 || written.OriginalSize != cr.OriginalSize {
+		t.Fatalf("Report not written: %s", written)
+	}
+}
@@ -0,0 +1,30 @@
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestParseBytesForFuzz(t *testing.T) {
+	if ParseBytesForFuzz(getTestExifData()) != 1 {
+		t.Fatalf("Test EXIF should have been parseable.")
+	}
+
+	if ParseBytesForFuzz([]byte("no exif here")) != 0 {
+		t.Fatalf("Non-EXIF data should not have been parseable.")
+	}
+
+	seeds, err := exiftest.SeedCorpus()
+	log.PanicIf(err)
+
+	// Damaged data is expected to fail to parse but it mustn't cause a
+	// runtime error.
+	for _, data := range seeds {
+		for seed := int64(0); seed < 20; seed++ {
+			ParseBytesForFuzz(exiftest.FlipBits(data, seed, 4))
+		}
+	}
+}
@@ -7,6 +7,7 @@ go 1.13
 
 require (
 	github.com/dsoprea/go-logging v0.0.0-20200502201358-170ff607885f
+	github.com/go-errors/errors v1.0.2
 	github.com/golang/geo v0.0.0-20190916061304-5b978397cfec
 	gopkg.in/yaml.v2 v2.2.7
 )
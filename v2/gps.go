@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/dsoprea/go-logging"
-	"github.com/golang/geo/s2"
 
 	"github.com/dsoprea/go-exif/v2/common"
 )
@@ -100,18 +99,3 @@ func (gi *GpsInfo) String() string {
 	return fmt.Sprintf("GpsInfo<LAT=(%.05f) LON=(%.05f) ALT=(%d) TIME=[%s]>",
 		gi.Latitude.Decimal(), gi.Longitude.Decimal(), gi.Altitude, gi.Timestamp)
 }
-
-// S2CellId returns the cell-ID of the geographic location on the earth.
-func (gi *GpsInfo) S2CellId() s2.CellID {
-	latitude := gi.Latitude.Decimal()
-	longitude := gi.Longitude.Decimal()
-
-	ll := s2.LatLngFromDegrees(latitude, longitude)
-	cellId := s2.CellIDFromLatLng(ll)
-
-	if cellId.IsValid() == false {
-		panic(ErrGpsCoordinatesNotValid)
-	}
-
-	return cellId
-}
@@ -0,0 +1,27 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"github.com/golang/geo/s2"
+)
+
+// S2CellId returns the cell-ID of the geographic location on the earth.
+func (gi *GpsInfo) S2CellId() s2.CellID {
+	latitude := gi.Latitude.Decimal()
+	longitude := gi.Longitude.Decimal()
+
+	ll := s2.LatLngFromDegrees(latitude, longitude)
+	cellId := s2.CellIDFromLatLng(ll)
+
+	if cellId.IsValid() == false {
+		panic(ErrGpsCoordinatesNotValid)
+	}
+
+	return cellId
+}
+
+func init() {
+	buildFeatures = append(buildFeatures, "s2-cell-id")
+}
@@ -0,0 +1,521 @@
+package exif
+
+import (
+	"bytes"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// heifBrands are the "ftyp" brands of HEIF files (HEIC and AVIF
+	// included).
+	heifBrands = map[string]bool{
+		"heic": true,
+		"heix": true,
+		"heim": true,
+		"heis": true,
+		"hevc": true,
+		"mif1": true,
+		"msf1": true,
+		"avif": true,
+	}
+)
+
+// isoBox is one box of an ISO base media file (HEIF, MP4).
+type isoBox struct {
+	boxType string
+	payload []byte
+
+	// offset is the position of the payload in the data that was parsed.
+	offset int64
+
+	// headerSize is the size of the box header, which comes just before the
+	// payload.
+	headerSize int64
+}
+
+// readIsoBoxes returns the boxes in the given data, which is at the given
+// position of the file. Parsing stops at the first box that's truncated.
+func readIsoBoxes(data []byte, offset int64) []isoBox {
+	boxes := make([]isoBox, 0)
+
+	for position := uint64(0); position+8 <= uint64(len(data)); {
+		size := uint64(binary.BigEndian.Uint32(data[position:]))
+		boxType := string(data[position+4 : position+8])
+		headerSize := uint64(8)
+
+		if size == 1 {
+			if position+16 > uint64(len(data)) {
+				break
+			}
+
+			size = binary.BigEndian.Uint64(data[position+8:])
+			headerSize = 16
+		} else if size == 0 {
+			size = uint64(len(data)) - position
+		}
+
+		if size < headerSize || size > uint64(len(data))-position {
+			break
+		}
+
+		ib := isoBox{
+			boxType:    boxType,
+			payload:    data[position+headerSize : position+size],
+			offset:     offset + int64(position+headerSize),
+			headerSize: int64(headerSize),
+		}
+
+		boxes = append(boxes, ib)
+		position += size
+	}
+
+	return boxes
+}
+
+// findIsoBox returns the first box of the given type.
+func findIsoBox(boxes []isoBox, boxType string) (ib isoBox, found bool) {
+	for _, ib := range boxes {
+		if ib.boxType == boxType {
+			return ib, true
+		}
+	}
+
+	return ib, false
+}
+
+// isoReader reads the big-endian fields of a box. Reading past the end
+// panics with `ErrNotEnoughData`.
+type isoReader struct {
+	data     []byte
+	position int
+}
+
+func (ir *isoReader) bytes(count int) []byte {
+	if count < 0 || count > len(ir.data)-ir.position {
+		log.Panic(exifcommon.ErrNotEnoughData)
+	}
+
+	b := ir.data[ir.position : ir.position+count]
+	ir.position += count
+
+	return b
+}
+
+// uint reads an unsigned integer of the given number of bytes (zero, one,
+// two, four, or eight).
+func (ir *isoReader) uint(size int) uint64 {
+	b := ir.bytes(size)
+
+	value := uint64(0)
+	for _, c := range b {
+		value = value<<8 | uint64(c)
+	}
+
+	return value
+}
+
+// fullBoxHeader reads the version and flags of a full box.
+func (ir *isoReader) fullBoxHeader() (version byte, flags uint32) {
+	b := ir.bytes(4)
+	return b[0], uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// string reads a NUL-terminated string.
+func (ir *isoReader) string() string {
+	remaining := ir.data[ir.position:]
+
+	i := bytes.IndexByte(remaining, 0)
+	if i == -1 {
+		log.Panic(exifcommon.ErrNotEnoughData)
+	}
+
+	ir.position += i + 1
+
+	return string(remaining[:i])
+}
+
+// heifReference is one item reference: the "from" item refers to the "to"
+// items (e.g. an auxiliary image to the image that it belongs to).
+type heifReference struct {
+	referenceType string
+	fromItemId    uint32
+	toItemIds     []uint32
+}
+
+// heifLocation is where the data of an item is. Only items that are stored
+// in the file in one piece have an offset.
+type heifLocation struct {
+	offset int64
+	length int64
+}
+
+// heifGroup is one entity group (e.g. a "brst" burst): the items that belong
+// together, in order.
+type heifGroup struct {
+	groupType string
+	groupId   uint32
+	entityIds []uint32
+}
+
+// heifMeta is the item information of a HEIF file.
+type heifMeta struct {
+	primaryItemId uint32
+	itemTypes     map[uint32]string
+	references    []heifReference
+	locations     map[uint32]heifLocation
+	groups        []heifGroup
+
+	// properties are the boxes in the property container and associations
+	// are the (one-based) indices of the properties of each item.
+	properties   []isoBox
+	associations map[uint32][]int
+}
+
+// exifItemFor returns the "Exif" item that describes the given image item: the
+// one with a "cdsc" reference to it or, failing that, one that doesn't
+// reference any item. For the primary item, any "Exif" item (the lowest ID) is
+// used as a last resort, since many writers don't add the reference. Zero is
+// returned if there isn't one.
+func (hm *heifMeta) exifItemFor(imageItemId uint32) uint32 {
+	describes := make(map[uint32][]uint32)
+	for _, hr := range hm.references {
+		if hr.referenceType == "cdsc" {
+			describes[hr.fromItemId] = append(describes[hr.fromItemId], hr.toItemIds...)
+		}
+	}
+
+	var referenced, unreferenced, fallback uint32
+	for itemId, itemType := range hm.itemTypes {
+		if itemType != "Exif" {
+			continue
+		}
+
+		if fallback == 0 || itemId < fallback {
+			fallback = itemId
+		}
+
+		toItemIds, found := describes[itemId]
+		if found == false {
+			if unreferenced == 0 || itemId < unreferenced {
+				unreferenced = itemId
+			}
+
+			continue
+		}
+
+		for _, toItemId := range toItemIds {
+			if toItemId == imageItemId && (referenced == 0 || itemId < referenced) {
+				referenced = itemId
+			}
+		}
+	}
+
+	if referenced != 0 {
+		return referenced
+	} else if unreferenced != 0 {
+		return unreferenced
+	} else if imageItemId == hm.primaryItemId {
+		return fallback
+	}
+
+	return 0
+}
+
+// describes returns true if the item has a "cdsc" reference to the other
+// item.
+func (hm *heifMeta) describes(fromItemId, toItemId uint32) bool {
+	for _, hr := range hm.references {
+		if hr.referenceType != "cdsc" || hr.fromItemId != fromItemId {
+			continue
+		}
+
+		for _, itemId := range hr.toItemIds {
+			if itemId == toItemId {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// property returns the first property of the given type that's associated
+// with the item.
+func (hm *heifMeta) property(itemId uint32, boxType string) (ib isoBox, found bool) {
+	for _, i := range hm.associations[itemId] {
+		if i < 1 || i > len(hm.properties) {
+			continue
+		}
+
+		if ib := hm.properties[i-1]; ib.boxType == boxType {
+			return ib, true
+		}
+	}
+
+	return ib, false
+}
+
+// isHeif returns true if the data starts with an "ftyp" box with a HEIF
+// brand.
+func isHeif(data []byte) bool {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+
+	boxes := readIsoBoxes(data, 0)
+	if len(boxes) == 0 || len(boxes[0].payload) < 4 {
+		return false
+	}
+
+	payload := boxes[0].payload
+	if heifBrands[string(payload[:4])] == true {
+		return true
+	}
+
+	// The compatible brands follow the major brand and the minor version.
+	for i := 8; i+4 <= len(payload); i += 4 {
+		if heifBrands[string(payload[i:i+4])] == true {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readHeifMeta reads the item information from the "meta" box of a HEIF
+// file. Nil is returned if the data isn't HEIF.
+func readHeifMeta(data []byte) (hm *heifMeta, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if isHeif(data) == false {
+		return nil, nil
+	}
+
+	meta, found := findIsoBox(readIsoBoxes(data, 0), "meta")
+	if found == false || len(meta.payload) < 4 {
+		return nil, nil
+	}
+
+	hm = &heifMeta{
+		itemTypes:    make(map[uint32]string),
+		references:   make([]heifReference, 0),
+		locations:    make(map[uint32]heifLocation),
+		groups:       make([]heifGroup, 0),
+		properties:   make([]isoBox, 0),
+		associations: make(map[uint32][]int),
+	}
+
+	// "meta" is a full box.
+	for _, ib := range readIsoBoxes(meta.payload[4:], meta.offset+4) {
+		ir := &isoReader{data: ib.payload}
+
+		switch ib.boxType {
+		case "pitm":
+			version, _ := ir.fullBoxHeader()
+			hm.primaryItemId = uint32(ir.uint(heifItemIdSize(version, 1)))
+		case "iinf":
+			hm.readItemInfo(ib)
+		case "iref":
+			hm.readItemReferences(ib)
+		case "iprp":
+			hm.readItemProperties(ib)
+		case "iloc":
+			hm.readItemLocations(ib)
+		case "grpl":
+			hm.readGroups(ib)
+		}
+	}
+
+	return hm, nil
+}
+
+// heifItemIdSize returns the size of the item IDs (and of some counts) in a
+// box, which are 32-bit from the given version onward and 16-bit before it.
+func heifItemIdSize(version, largeFromVersion byte) int {
+	if version >= largeFromVersion {
+		return 4
+	}
+
+	return 2
+}
+
+func (hm *heifMeta) readItemInfo(ib isoBox) {
+	ir := &isoReader{data: ib.payload}
+
+	version, _ := ir.fullBoxHeader()
+
+	// The entry count isn't needed since the entries are boxes.
+	ir.uint(heifItemIdSize(version, 1))
+
+	for _, infe := range readIsoBoxes(ib.payload[ir.position:], 0) {
+		if infe.boxType != "infe" {
+			continue
+		}
+
+		ir := &isoReader{data: infe.payload}
+
+		version, _ := ir.fullBoxHeader()
+
+		// Older versions don't have an item type.
+		if version < 2 {
+			continue
+		}
+
+		itemId := uint32(ir.uint(heifItemIdSize(version, 3)))
+
+		// Skip the protection index.
+		ir.uint(2)
+
+		hm.itemTypes[itemId] = string(ir.bytes(4))
+	}
+}
+
+func (hm *heifMeta) readItemReferences(ib isoBox) {
+	ir := &isoReader{data: ib.payload}
+
+	version, _ := ir.fullBoxHeader()
+	itemIdSize := heifItemIdSize(version, 1)
+
+	for _, reference := range readIsoBoxes(ib.payload[ir.position:], 0) {
+		ir := &isoReader{data: reference.payload}
+
+		hr := heifReference{
+			referenceType: reference.boxType,
+			fromItemId:    uint32(ir.uint(itemIdSize)),
+		}
+
+		count := int(ir.uint(2))
+
+		hr.toItemIds = make([]uint32, count)
+		for i := range hr.toItemIds {
+			hr.toItemIds[i] = uint32(ir.uint(itemIdSize))
+		}
+
+		hm.references = append(hm.references, hr)
+	}
+}
+
+func (hm *heifMeta) readItemProperties(ib isoBox) {
+	boxes := readIsoBoxes(ib.payload, ib.offset)
+
+	if ipco, found := findIsoBox(boxes, "ipco"); found == true {
+		hm.properties = readIsoBoxes(ipco.payload, ipco.offset)
+	}
+
+	for _, ipma := range boxes {
+		if ipma.boxType != "ipma" {
+			continue
+		}
+
+		ir := &isoReader{data: ipma.payload}
+
+		version, flags := ir.fullBoxHeader()
+		entryCount := ir.uint(4)
+
+		for i := uint64(0); i < entryCount; i++ {
+			itemId := uint32(ir.uint(heifItemIdSize(version, 1)))
+			associationCount := int(ir.uint(1))
+
+			for j := 0; j < associationCount; j++ {
+				// The high bit is the "essential" flag.
+				var index int
+				if flags&1 == 1 {
+					index = int(ir.uint(2) & 0x7fff)
+				} else {
+					index = int(ir.uint(1) & 0x7f)
+				}
+
+				hm.associations[itemId] = append(hm.associations[itemId], index)
+			}
+		}
+	}
+}
+
+func (hm *heifMeta) readItemLocations(ib isoBox) {
+	ir := &isoReader{data: ib.payload}
+
+	version, _ := ir.fullBoxHeader()
+
+	sizes := ir.uint(2)
+	offsetSize := int(sizes >> 12)
+	lengthSize := int(sizes >> 8 & 0xf)
+	baseOffsetSize := int(sizes >> 4 & 0xf)
+
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0xf)
+	}
+
+	itemIdSize := heifItemIdSize(version, 2)
+	itemCount := ir.uint(itemIdSize)
+
+	for i := uint64(0); i < itemCount; i++ {
+		itemId := uint32(ir.uint(itemIdSize))
+
+		constructionMethod := uint64(0)
+		if version == 1 || version == 2 {
+			constructionMethod = ir.uint(2) & 0xf
+		}
+
+		dataReferenceIndex := ir.uint(2)
+		baseOffset := ir.uint(baseOffsetSize)
+		extentCount := int(ir.uint(2))
+
+		var offset, length uint64
+
+		for j := 0; j < extentCount; j++ {
+			ir.uint(indexSize)
+
+			extentOffset := ir.uint(offsetSize)
+			extentLength := ir.uint(lengthSize)
+
+			if j == 0 {
+				offset = baseOffset + extentOffset
+			}
+
+			length += extentLength
+		}
+
+		// Only data that's in the file itself, in one piece, can be
+		// addressed.
+		if constructionMethod != 0 || dataReferenceIndex != 0 || extentCount != 1 {
+			continue
+		}
+
+		hm.locations[itemId] = heifLocation{
+			offset: int64(offset),
+			length: int64(length),
+		}
+	}
+}
+
+func (hm *heifMeta) readGroups(ib isoBox) {
+	for _, group := range readIsoBoxes(ib.payload, ib.offset) {
+		ir := &isoReader{data: group.payload}
+
+		ir.fullBoxHeader()
+
+		hg := heifGroup{
+			groupType: group.boxType,
+			groupId:   uint32(ir.uint(4)),
+		}
+
+		count := int(ir.uint(4))
+
+		hg.entityIds = make([]uint32, count)
+		for i := range hg.entityIds {
+			hg.entityIds[i] = uint32(ir.uint(4))
+		}
+
+		hm.groups = append(hm.groups, hg)
+	}
+}
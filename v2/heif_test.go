@@ -0,0 +1,156 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	testHeifGainMap   = []byte("gain-map-pixels")
+	testHeifThumbnail = []byte("thumbnail-pixels")
+)
+
+func getTestIsoBox(boxType string, payload ...[]byte) []byte {
+	joined := bytes.Join(payload, nil)
+
+	b := make([]byte, 8, 8+len(joined))
+	binary.BigEndian.PutUint32(b, uint32(8+len(joined)))
+	copy(b[4:], boxType)
+
+	return append(b, joined...)
+}
+
+func getTestIsoFullBox(boxType string, version byte, flags uint32, payload ...[]byte) []byte {
+	header := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return getTestIsoBox(boxType, append([][]byte{header}, payload...)...)
+}
+
+func getTestIsoUints(size int, values ...uint64) []byte {
+	b := make([]byte, 0, size*len(values))
+
+	for _, value := range values {
+		for i := size - 1; i >= 0; i-- {
+			b = append(b, byte(value>>(8*uint(i))))
+		}
+	}
+
+	return b
+}
+
+// getTestHeic returns a HEIC whose primary item (1) has a gain map (2) and a
+// thumbnail (3), both of which are in the "mdat" box.
+func getTestHeic() (data []byte, gainMapOffset, thumbnailOffset int64) {
+	build := func(gainMapOffset, thumbnailOffset int64) []byte {
+		ftyp := getTestIsoBox("ftyp", []byte("heic"), getTestIsoUints(4, 0), []byte("mif1heic"))
+
+		infes := make([][]byte, 0)
+		for itemId := uint64(1); itemId <= 3; itemId++ {
+			infe := getTestIsoFullBox("infe", 2, 0, getTestIsoUints(2, itemId, 0), []byte("hvc1\x00"))
+			infes = append(infes, infe)
+		}
+
+		iinf := getTestIsoFullBox("iinf", 0, 0, getTestIsoUints(2, 3), bytes.Join(infes, nil))
+
+		iref := getTestIsoFullBox(
+			"iref", 0, 0,
+			getTestIsoBox("auxl", getTestIsoUints(2, 2, 1, 1)),
+			getTestIsoBox("thmb", getTestIsoUints(2, 3, 1, 1)))
+
+		auxC := getTestIsoFullBox("auxC", 0, 0, []byte("urn:com:apple:photo:2020:aux:hdrgainmap\x00"))
+
+		// The gain map's only property is essential.
+		ipma := getTestIsoFullBox("ipma", 0, 0, getTestIsoUints(4, 1), getTestIsoUints(2, 2), []byte{1, 0x81})
+
+		iprp := getTestIsoBox("iprp", getTestIsoBox("ipco", auxC), ipma)
+
+		// Four-byte offsets and lengths and no base offset.
+		iloc := getTestIsoFullBox(
+			"iloc", 0, 0,
+			[]byte{0x44, 0x00},
+			getTestIsoUints(2, 2),
+			getTestIsoUints(2, 2, 0, 1), getTestIsoUints(4, uint64(gainMapOffset), uint64(len(testHeifGainMap))),
+			getTestIsoUints(2, 3, 0, 1), getTestIsoUints(4, uint64(thumbnailOffset), uint64(len(testHeifThumbnail))))
+
+		pitm := getTestIsoFullBox("pitm", 0, 0, getTestIsoUints(2, 1))
+		hdlr := getTestIsoFullBox("hdlr", 0, 0, getTestIsoUints(4, 0), []byte("pict"), make([]byte, 13))
+
+		meta := getTestIsoFullBox("meta", 0, 0, hdlr, pitm, iinf, iref, iprp, iloc)
+		mdat := getTestIsoBox("mdat", testHeifGainMap, testHeifThumbnail)
+
+		return append(append(ftyp, meta...), mdat...)
+	}
+
+	// The offsets have a fixed size, so they can be filled in afterward.
+	data = build(0, 0)
+
+	gainMapOffset = int64(len(data) - len(testHeifGainMap) - len(testHeifThumbnail))
+	thumbnailOffset = gainMapOffset + int64(len(testHeifGainMap))
+
+	return build(gainMapOffset, thumbnailOffset), gainMapOffset, thumbnailOffset
+}
+
+func TestReadHeifMeta(t *testing.T) {
+	data, gainMapOffset, _ := getTestHeic()
+
+	hm, err := readHeifMeta(data)
+	log.PanicIf(err)
+
+	if hm.primaryItemId != 1 {
+		t.Fatalf("Primary item not correct: (%d)", hm.primaryItemId)
+	} else if len(hm.itemTypes) != 3 || hm.itemTypes[2] != "hvc1" {
+		t.Fatalf("Item types not correct: %v", hm.itemTypes)
+	} else if len(hm.references) != 2 || hm.references[0].referenceType != "auxl" || hm.references[0].fromItemId != 2 || hm.references[0].toItemIds[0] != 1 {
+		t.Fatalf("References not correct: %v", hm.references)
+	}
+
+	if _, found := hm.property(2, "auxC"); found != true {
+		t.Fatalf("auxC not associated with the gain map.")
+	} else if _, found := hm.property(1, "auxC"); found == true {
+		t.Fatalf("auxC should not be associated with the primary item.")
+	}
+
+	hl, found := hm.locations[2]
+	if found != true || hl.offset != gainMapOffset || hl.length != int64(len(testHeifGainMap)) {
+		t.Fatalf("Gain-map location not correct: %v", hl)
+	}
+}
+
+func TestReadHeifMeta_NotHeif(t *testing.T) {
+	hm, err := readHeifMeta(getTestJpegWithExif())
+	log.PanicIf(err)
+
+	if hm != nil {
+		t.Fatalf("Expected nil for a JPEG.")
+	}
+}
+
+func TestReadHeifMeta_Truncated(t *testing.T) {
+	data, _, _ := getTestHeic()
+
+	// Claim more associations than there are.
+	i := bytes.Index(data, []byte("ipma"))
+	truncated := append([]byte{}, data...)
+
+	binary.BigEndian.PutUint32(truncated[i+8:], 5)
+
+	_, err := readHeifMeta(truncated)
+	if err == nil {
+		t.Fatalf("Expected error for truncated box.")
+	}
+}
+
+func TestReadIsoBoxes_Invalid(t *testing.T) {
+	// The second box claims to be larger than the data.
+	data := append(getTestIsoBox("free", []byte{1, 2, 3}), 0, 0, 0, 0xff, 'f', 'r', 'e', 'e')
+
+	boxes := readIsoBoxes(data, 100)
+	if len(boxes) != 1 {
+		t.Fatalf("Expected one box: %v", boxes)
+	} else if boxes[0].boxType != "free" || boxes[0].offset != 108 || bytes.Equal(boxes[0].payload, []byte{1, 2, 3}) != true {
+		t.Fatalf("Box not correct: %v", boxes[0])
+	}
+}
@@ -0,0 +1,531 @@
+package exif
+
+import (
+	"bytes"
+	"math"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// heifExtent is one piece of the data of an item.
+type heifExtent struct {
+	index  uint64
+	offset uint64
+	length uint64
+}
+
+// heifItemLocation is one entry of the "iloc" box, as it's written.
+type heifItemLocation struct {
+	itemId uint32
+
+	// constructionMethod is the whole field, with the reserved bits.
+	constructionMethod uint64
+
+	dataReferenceIndex uint64
+	baseOffset         uint64
+	extents            []heifExtent
+}
+
+// isInFile returns true if the item's data is at absolute offsets in the file
+// itself (rather than in the "idat" box or another file).
+func (hil *heifItemLocation) isInFile() bool {
+	return hil.constructionMethod&0xf == 0 && hil.dataReferenceIndex == 0
+}
+
+// shift moves the parts of the item's data that are at or after the given
+// position by the given amount.
+func (hil *heifItemLocation) shift(from uint64, delta int64) {
+	if hil.isInFile() == false || delta == 0 {
+		return
+	}
+
+	if hil.baseOffset != 0 && hil.baseOffset >= from {
+		hil.baseOffset = uint64(int64(hil.baseOffset) + delta)
+		return
+	}
+
+	for i, he := range hil.extents {
+		if hil.baseOffset+he.offset >= from {
+			hil.extents[i].offset = uint64(int64(he.offset) + delta)
+		}
+	}
+}
+
+// heifItemLocations is the content of the "iloc" box.
+type heifItemLocations struct {
+	version   byte
+	indexSize int
+	items     []*heifItemLocation
+}
+
+// readHeifItemLocations reads all of the entries of the "iloc" box, including
+// the ones that `readHeifMeta()` doesn't keep.
+func readHeifItemLocations(payload []byte) (hils *heifItemLocations) {
+	ir := &isoReader{data: payload}
+
+	version, _ := ir.fullBoxHeader()
+
+	sizes := ir.uint(2)
+	offsetSize := int(sizes >> 12)
+	lengthSize := int(sizes >> 8 & 0xf)
+	baseOffsetSize := int(sizes >> 4 & 0xf)
+
+	hils = &heifItemLocations{
+		version: version,
+	}
+
+	if version == 1 || version == 2 {
+		hils.indexSize = int(sizes & 0xf)
+	}
+
+	itemIdSize := heifItemIdSize(version, 2)
+	itemCount := ir.uint(itemIdSize)
+
+	for i := uint64(0); i < itemCount; i++ {
+		hil := &heifItemLocation{
+			itemId: uint32(ir.uint(itemIdSize)),
+		}
+
+		if version == 1 || version == 2 {
+			hil.constructionMethod = ir.uint(2)
+		}
+
+		hil.dataReferenceIndex = ir.uint(2)
+		hil.baseOffset = ir.uint(baseOffsetSize)
+
+		extentCount := int(ir.uint(2))
+		hil.extents = make([]heifExtent, extentCount)
+
+		for j := range hil.extents {
+			hil.extents[j] = heifExtent{
+				index:  ir.uint(hils.indexSize),
+				offset: ir.uint(offsetSize),
+				length: ir.uint(lengthSize),
+			}
+		}
+
+		hils.items = append(hils.items, hil)
+	}
+
+	return hils
+}
+
+// encode returns the "iloc" box with offsets, lengths, and base offsets of
+// the given size. The version is raised if the item IDs need it.
+func (hils *heifItemLocations) encode(fieldSize int) []byte {
+	version := hils.version
+	for _, hil := range hils.items {
+		if hil.itemId > math.MaxUint16 && version < 2 {
+			version = 2
+		}
+	}
+
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = hils.indexSize
+	}
+
+	itemIdSize := heifItemIdSize(version, 2)
+
+	b := new(bytes.Buffer)
+	b.Write([]byte{version, 0, 0, 0})
+	writeIsoUint(b, 2, uint64(fieldSize<<12|fieldSize<<8|fieldSize<<4|indexSize))
+	writeIsoUint(b, itemIdSize, uint64(len(hils.items)))
+
+	for _, hil := range hils.items {
+		writeIsoUint(b, itemIdSize, uint64(hil.itemId))
+
+		if version == 1 || version == 2 {
+			writeIsoUint(b, 2, hil.constructionMethod)
+		}
+
+		writeIsoUint(b, 2, hil.dataReferenceIndex)
+		writeIsoUint(b, fieldSize, hil.baseOffset)
+		writeIsoUint(b, 2, uint64(len(hil.extents)))
+
+		for _, he := range hil.extents {
+			writeIsoUint(b, indexSize, he.index)
+			writeIsoUint(b, fieldSize, he.offset)
+			writeIsoUint(b, fieldSize, he.length)
+		}
+	}
+
+	return appendIsoBox(nil, "iloc", b.Bytes())
+}
+
+// clone returns a copy that can be changed without changing the original.
+func (hils *heifItemLocations) clone() *heifItemLocations {
+	copied := &heifItemLocations{
+		version:   hils.version,
+		indexSize: hils.indexSize,
+		items:     make([]*heifItemLocation, len(hils.items)),
+	}
+
+	for i, hil := range hils.items {
+		hilCopy := *hil
+		hilCopy.extents = append([]heifExtent{}, hil.extents...)
+
+		copied.items[i] = &hilCopy
+	}
+
+	return copied
+}
+
+// findHeifItemLocation returns the location of the item or nil.
+func findHeifItemLocation(hils *heifItemLocations, itemId uint32) *heifItemLocation {
+	for _, hil := range hils.items {
+		if hil.itemId == itemId {
+			return hil
+		}
+	}
+
+	return nil
+}
+
+// writeIsoUint writes an unsigned, big-endian integer of the given number of
+// bytes.
+func writeIsoUint(b *bytes.Buffer, size int, value uint64) {
+	for i := size - 1; i >= 0; i-- {
+		b.WriteByte(byte(value >> (8 * uint(i))))
+	}
+}
+
+// appendIsoBox appends a box with the given payload to the data.
+func appendIsoBox(data []byte, boxType string, payload []byte) []byte {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(8+len(payload)))
+	copy(header[4:], boxType)
+
+	data = append(data, header...)
+	return append(data, payload...)
+}
+
+// getHeifExifItem returns the content of an "Exif" item: the offset of the
+// TIFF header from the end of the offset itself, the JPEG preamble that Apple
+// writes, and the EXIF block.
+func getHeifExifItem(rawExif []byte) []byte {
+	item := make([]byte, 4)
+	binary.BigEndian.PutUint32(item, uint32(len(jpegExifPreamble)))
+	item = append(item, jpegExifPreamble...)
+
+	return append(item, rawExif...)
+}
+
+// SetHeifExif returns a copy of the HEIF (HEIC, AVIF) file with the given raw
+// EXIF block in the "Exif" item of the primary image (see
+// `SetHeifItemExif()`).
+func SetHeifExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	hm, err := readHeifMeta(data)
+	log.PanicIf(err)
+
+	if hm == nil {
+		log.Panicf("not a HEIF")
+	}
+
+	updated, err = setHeifItemExif(data, hm, hm.primaryItemId, rawExif)
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// SetHeifItemExif returns a copy of the HEIF (HEIC, AVIF) file with the given
+// raw EXIF block in the "Exif" item that describes the given image item (see
+// `GetHeifItemExif()`), so that each frame of a burst can have its own. If the
+// block fits in the existing item, it's overwritten in place (with the rest of
+// the item zeroed). Otherwise, the item is written to a new "mdat" box at the
+// end of the file and the "meta" box is rewritten to point to it, adding the
+// item, and a reference from it to the image item, if there wasn't one. The
+// data of every other item is left where it is, with its offsets updated if
+// the "meta" box changed size. A `CapabilityError` is returned for image
+// sequences, whose track offsets would also have to be updated, if the block
+// doesn't fit in place.
+func SetHeifItemExif(data []byte, itemId uint32, rawExif []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	hm, err := readHeifMeta(data)
+	log.PanicIf(err)
+
+	if hm == nil {
+		log.Panicf("not a HEIF")
+	} else if itemType, found := hm.itemTypes[itemId]; found == false || itemType == "Exif" {
+		log.Panicf("HEIF item (%d) is not an image", itemId)
+	}
+
+	updated, err = setHeifItemExif(data, hm, itemId, rawExif)
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// setHeifItemExif writes the EXIF for the image item. An "Exif" item that
+// applies to the whole file is only reused for the primary item, so that
+// setting the EXIF of another frame doesn't change that of the others.
+func setHeifItemExif(data []byte, hm *heifMeta, imageItemId uint32, rawExif []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	item := getHeifExifItem(rawExif)
+
+	exifItemId := hm.exifItemFor(imageItemId)
+	if exifItemId != 0 && imageItemId != hm.primaryItemId && hm.describes(exifItemId, imageItemId) == false {
+		exifItemId = 0
+	}
+
+	if hl, found := hm.locations[exifItemId]; exifItemId != 0 && found == true && hl.offset+hl.length <= int64(len(data)) && int64(len(item)) <= hl.length {
+		updated = append([]byte{}, data...)
+
+		region := updated[hl.offset : hl.offset+hl.length]
+		n := copy(region, item)
+
+		for i := n; i < len(region); i++ {
+			region[i] = 0
+		}
+
+		return updated, nil
+	}
+
+	updated, err = rewriteHeifMeta(data, hm, exifItemId, imageItemId, item)
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// rewriteHeifMeta appends the item in a new "mdat" box and rewrites the
+// "meta" box to locate it there. A zero item ID means that the item has to be
+// added, with a reference to the image item that it describes.
+func rewriteHeifMeta(data []byte, hm *heifMeta, exifItemId, imageItemId uint32, item []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	boxes := readIsoBoxes(data, 0)
+
+	metaIndex := -1
+	for i, ib := range boxes {
+		if ib.boxType == "meta" {
+			metaIndex = i
+			break
+		}
+	}
+
+	meta := boxes[metaIndex]
+	metaStart := meta.offset - meta.headerSize
+	metaEnd := meta.offset + int64(len(meta.payload))
+
+	children := readIsoBoxes(meta.payload[4:], meta.offset+4)
+
+	var hils *heifItemLocations
+	for _, ib := range children {
+		if ib.boxType == "iloc" {
+			hils = readHeifItemLocations(ib.payload)
+		}
+	}
+
+	if hils == nil {
+		hils = &heifItemLocations{}
+	}
+
+	isNew := exifItemId == 0
+	if isNew == true {
+		for itemId := range hm.itemTypes {
+			if itemId > exifItemId {
+				exifItemId = itemId
+			}
+		}
+
+		for _, hil := range hils.items {
+			if hil.itemId > exifItemId {
+				exifItemId = hil.itemId
+			}
+		}
+
+		exifItemId++
+	}
+
+	if findHeifItemLocation(hils, exifItemId) == nil {
+		hils.items = append(hils.items, &heifItemLocation{itemId: exifItemId})
+	}
+
+	// The offsets all have the same size, which is big enough for the file
+	// with the new item, so the size of the "meta" box doesn't depend on
+	// them.
+	fieldSize := 4
+	if uint64(len(data))+uint64(len(item))+uint64(len(meta.payload))+1024 > math.MaxUint32 {
+		fieldSize = 8
+	}
+
+	build := func(delta int64) []byte {
+		shifted := hils.clone()
+
+		for _, hil := range shifted.items {
+			if hil.itemId != exifItemId {
+				hil.shift(uint64(metaEnd), delta)
+				continue
+			}
+
+			hil.constructionMethod = 0
+			hil.dataReferenceIndex = 0
+			hil.baseOffset = 0
+			hil.extents = []heifExtent{{
+				offset: uint64(int64(len(data)) + delta + 8),
+				length: uint64(len(item)),
+			}}
+		}
+
+		payload := append([]byte{}, meta.payload[:4]...)
+		hasIref := false
+
+		for _, ib := range children {
+			switch {
+			case ib.boxType == "iloc":
+				payload = append(payload, shifted.encode(fieldSize)...)
+			case ib.boxType == "iinf" && isNew == true:
+				payload = append(payload, addHeifItemInfo(ib, exifItemId)...)
+			case ib.boxType == "iref" && isNew == true:
+				payload = append(payload, addHeifItemReference(ib, exifItemId, imageItemId)...)
+				hasIref = true
+			default:
+				start := ib.offset - ib.headerSize - meta.offset
+				payload = append(payload, meta.payload[start:start+ib.headerSize+int64(len(ib.payload))]...)
+			}
+		}
+
+		if isNew == true && hasIref == false && imageItemId != 0 {
+			payload = append(payload, addHeifItemReference(isoBox{}, exifItemId, imageItemId)...)
+		}
+
+		if findIsoBoxIndex(children, "iloc") == -1 {
+			payload = append(payload, shifted.encode(fieldSize)...)
+		}
+
+		return appendIsoBox(nil, "meta", payload)
+	}
+
+	delta := int64(len(build(0))) - (metaEnd - metaStart)
+
+	if delta != 0 {
+		for _, ib := range boxes[metaIndex+1:] {
+			if ib.boxType == "moov" {
+				log.Panic(newHeifCapabilityError("the EXIF of an image sequence can only be replaced with a block that fits in the existing item"))
+			}
+		}
+	}
+
+	newMeta := build(delta)
+
+	updated = make([]byte, 0, int64(len(data))+delta+8+int64(len(item)))
+	updated = append(updated, data[:metaStart]...)
+	updated = append(updated, newMeta...)
+	updated = append(updated, data[metaEnd:]...)
+	updated = appendIsoBox(updated, "mdat", item)
+
+	return updated, nil
+}
+
+// newHeifCapabilityError returns the error for a HEIF whose EXIF can only be
+// read.
+func newHeifCapabilityError(reason string) *CapabilityError {
+	return &CapabilityError{
+		Kind:      KindHeif,
+		Operation: CapabilityReplaceExif,
+		Available: CapabilityReadExif,
+		Reason:    reason,
+	}
+}
+
+// findIsoBoxIndex returns the index of the first box of the given type or
+// (-1).
+func findIsoBoxIndex(boxes []isoBox, boxType string) int {
+	for i, ib := range boxes {
+		if ib.boxType == boxType {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// addHeifItemInfo returns the "iinf" box with an "infe" entry for a new
+// "Exif" item.
+func addHeifItemInfo(iinf isoBox, itemId uint32) []byte {
+	ir := &isoReader{data: iinf.payload}
+
+	version, _ := ir.fullBoxHeader()
+	countSize := heifItemIdSize(version, 1)
+	count := ir.uint(countSize)
+
+	if countSize == 2 && count+1 > math.MaxUint16 {
+		log.Panic(newHeifCapabilityError("there are too many items to add one"))
+	}
+
+	infeVersion := byte(2)
+	infeIdSize := 2
+	if itemId > math.MaxUint16 {
+		infeVersion = 3
+		infeIdSize = 4
+	}
+
+	infe := new(bytes.Buffer)
+	infe.Write([]byte{infeVersion, 0, 0, 0})
+	writeIsoUint(infe, infeIdSize, uint64(itemId))
+
+	// The protection index, the item type, and an empty name.
+	writeIsoUint(infe, 2, 0)
+	infe.WriteString("Exif\x00")
+
+	b := new(bytes.Buffer)
+	b.Write(iinf.payload[:4])
+	writeIsoUint(b, countSize, count+1)
+	b.Write(iinf.payload[ir.position:])
+	b.Write(appendIsoBox(nil, "infe", infe.Bytes()))
+
+	return appendIsoBox(nil, "iinf", b.Bytes())
+}
+
+// addHeifItemReference returns the "iref" box (or a new one, if the given box
+// is empty) with a "cdsc" reference from the EXIF item to the image item,
+// which is how readers find the metadata of an image.
+func addHeifItemReference(iref isoBox, fromItemId, toItemId uint32) []byte {
+	version := byte(0)
+	if iref.payload != nil {
+		version = iref.payload[0]
+	} else if fromItemId > math.MaxUint16 || toItemId > math.MaxUint16 {
+		version = 1
+	}
+
+	itemIdSize := heifItemIdSize(version, 1)
+	if itemIdSize == 2 && (fromItemId > math.MaxUint16 || toItemId > math.MaxUint16) {
+		log.Panic(newHeifCapabilityError("the item references are too small for the new item"))
+	}
+
+	cdsc := new(bytes.Buffer)
+	writeIsoUint(cdsc, itemIdSize, uint64(fromItemId))
+	writeIsoUint(cdsc, 2, 1)
+	writeIsoUint(cdsc, itemIdSize, uint64(toItemId))
+
+	payload := iref.payload
+	if payload == nil {
+		payload = []byte{version, 0, 0, 0}
+	}
+
+	payload = append(append([]byte{}, payload...), appendIsoBox(nil, "cdsc", cdsc.Bytes())...)
+
+	return appendIsoBox(nil, "iref", payload)
+}
@@ -0,0 +1,100 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestSetHeifExif_InPlace(t *testing.T) {
+	data := getTestHeifWithExif(getTestExifData())
+	replacement := getTestReplacementExif()
+
+	updated, err := SetHeifExif(data, replacement)
+	log.PanicIf(err)
+
+	rawExif, err := GetHeifExif(updated)
+	log.PanicIf(err)
+
+	if len(updated) != len(data) {
+		t.Fatalf("File should not have changed size: (%d) != (%d)", len(updated), len(data))
+	} else if bytes.HasPrefix(rawExif, replacement) != true {
+		t.Fatalf("EXIF not replaced.")
+	}
+}
+
+func TestSetHeifExif_Grow(t *testing.T) {
+	exifData := getTestExifData()
+	data := getTestHeifWithExif(exifData)
+
+	larger := append(append([]byte{}, exifData...), make([]byte, 100)...)
+
+	updated, err := SetHeifExif(data, larger)
+	log.PanicIf(err)
+
+	rawExif, err := GetHeifExif(updated)
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, larger) != true {
+		t.Fatalf("EXIF not replaced.")
+	}
+}
+
+func TestSetHeifExif_AddItem(t *testing.T) {
+	data, _, _ := getTestHeic()
+	exifData := getTestExifData()
+
+	updated, err := SetHeifExif(data, exifData)
+	log.PanicIf(err)
+
+	rawExif, err := GetHeifExif(updated)
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, exifData) != true {
+		t.Fatalf("EXIF not added.")
+	}
+
+	hm, err := readHeifMeta(updated)
+	log.PanicIf(err)
+
+	if hm.itemTypes[4] != "Exif" {
+		t.Fatalf("EXIF item not added: %v", hm.itemTypes)
+	}
+
+	// The other items moved with the "mdat" box.
+	expected := map[uint32][]byte{
+		2: testHeifGainMap,
+		3: testHeifThumbnail,
+	}
+
+	for itemId, content := range expected {
+		hl := hm.locations[itemId]
+		if bytes.Equal(updated[hl.offset:hl.offset+hl.length], content) != true {
+			t.Fatalf("Item (%d) not located correctly.", itemId)
+		}
+	}
+
+	found := false
+	for _, hr := range hm.references {
+		if hr.referenceType == "cdsc" && hr.fromItemId == 4 && len(hr.toItemIds) == 1 && hr.toItemIds[0] == 1 {
+			found = true
+		}
+	}
+
+	if found != true {
+		t.Fatalf("EXIF item not referenced: %v", hm.references)
+	}
+}
+
+func TestSetHeifExif_Sequence(t *testing.T) {
+	data, _, _ := getTestHeic()
+	data = append(data, getTestIsoBox("moov", make([]byte, 8))...)
+
+	_, err := SetHeifExif(data, getTestExifData())
+	if ce, found := AsCapabilityError(err); found != true {
+		t.Fatalf("Expected CapabilityError: %v", err)
+	} else if ce.Kind != KindHeif || ce.Operation != CapabilityReplaceExif || ce.Available != CapabilityReadExif {
+		t.Fatalf("CapabilityError not correct: %v", ce)
+	}
+}
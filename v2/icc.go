@@ -0,0 +1,237 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	iccHeaderSize        = 128
+	iccTagEntrySize      = 12
+	iccChunkHeaderLength = 2
+)
+
+var (
+	iccLogger = log.NewLogger("exif.icc")
+)
+
+var (
+	// ErrNoIccProfile means that there's no ICC profile.
+	ErrNoIccProfile = errors.New("no icc profile")
+)
+
+var (
+	iccProfileFileSignature = []byte("acsp")
+	iccDescriptionTag       = []byte("desc")
+)
+
+// IccProfile is an ICC color profile and the basic fields from its header.
+type IccProfile struct {
+	// Data is the whole profile.
+	Data []byte
+
+	// Version is the profile version (e.g. "2.1.0" or "4.3.0").
+	Version string
+
+	// DeviceClass is the profile class signature (e.g. "mntr" or "scnr").
+	DeviceClass string
+
+	// ColorSpace is the data color space signature (e.g. "RGB " or "GRAY").
+	// ConnectionSpace is the profile connection space ("XYZ " or "Lab ").
+	ColorSpace      string
+	ConnectionSpace string
+
+	// Description is the profile's description (e.g. "sRGB IEC61966-2.1" or
+	// "Display P3"), or empty if it doesn't have one we can read.
+	Description string
+}
+
+// String returns a descriptive string.
+func (ip IccProfile) String() string {
+	return fmt.Sprintf("IccProfile<SIZE=(%d) VERSION=[%s] CLASS=[%s] COLOR-SPACE=[%s] DESCRIPTION=[%s]>", len(ip.Data), ip.Version, ip.DeviceClass, ip.ColorSpace, ip.Description)
+}
+
+// ParseIccProfile reads the header and the description of an ICC profile.
+func ParseIccProfile(data []byte) (ip IccProfile, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(data) < iccHeaderSize+4 {
+		log.Panicf("ICC profile too short: (%d)", len(data))
+	} else if bytes.Equal(data[36:40], iccProfileFileSignature) == false {
+		log.Panicf("ICC profile signature not found")
+	}
+
+	ip = IccProfile{
+		Data:            data,
+		Version:         fmt.Sprintf("%d.%d.%d", data[8], data[9]>>4, data[9]&0x0f),
+		DeviceClass:     string(data[12:16]),
+		ColorSpace:      string(data[16:20]),
+		ConnectionSpace: string(data[20:24]),
+	}
+
+	tagCount := int(binary.BigEndian.Uint32(data[iccHeaderSize:]))
+
+	for i := 0; i < tagCount; i++ {
+		entryOffset := iccHeaderSize + 4 + i*iccTagEntrySize
+		if entryOffset+iccTagEntrySize > len(data) {
+			break
+		}
+
+		entry := data[entryOffset : entryOffset+iccTagEntrySize]
+		if bytes.Equal(entry[:4], iccDescriptionTag) == false {
+			continue
+		}
+
+		offset := uint64(binary.BigEndian.Uint32(entry[4:]))
+		size := uint64(binary.BigEndian.Uint32(entry[8:]))
+
+		if offset+size > uint64(len(data)) {
+			iccLogger.Warningf(nil, "ICC description is out of bounds.")
+			break
+		}
+
+		ip.Description = parseIccText(data[offset : offset+size])
+		break
+	}
+
+	return ip, nil
+}
+
+// parseIccText decodes the textDescriptionType (ICC v2) and
+// multiLocalizedUnicodeType (ICC v4) tag types. For the latter, the first
+// record is used.
+func parseIccText(value []byte) string {
+	if len(value) < 12 {
+		return ""
+	}
+
+	switch string(value[:4]) {
+	case "desc":
+		length := uint64(binary.BigEndian.Uint32(value[8:]))
+		if 12+length > uint64(len(value)) {
+			return ""
+		}
+
+		return strings.TrimRight(string(value[12:12+length]), "\x00")
+	case "mluc":
+		if len(value) < 28 || binary.BigEndian.Uint32(value[8:]) == 0 {
+			return ""
+		}
+
+		length := uint64(binary.BigEndian.Uint32(value[20:]))
+		offset := uint64(binary.BigEndian.Uint32(value[24:]))
+
+		if offset+length > uint64(len(value)) {
+			return ""
+		}
+
+		raw := value[offset : offset+length]
+
+		units := make([]uint16, len(raw)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(raw[i*2:])
+		}
+
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	case "text":
+		return strings.TrimRight(string(value[8:]), "\x00")
+	}
+
+	return ""
+}
+
+// jpegIccProfile reassembles the ICC profile from the APP2 chunks of a JPEG.
+// Each chunk has a sequence number (starting from one) and the total number
+// of chunks, and the chunks aren't required to be in order. nil is returned
+// if there are no chunks.
+func jpegIccProfile(data []byte) (profile []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	type iccChunk struct {
+		sequence int
+		data     []byte
+	}
+
+	chunks := make([]iccChunk, 0)
+	count := 0
+
+	for _, segment := range jpegSegments(data) {
+		if segment.marker != jpegMarkerApp2 || bytes.HasPrefix(segment.payload, iccProfileSignature) == false {
+			continue
+		}
+
+		chunk := segment.payload[len(iccProfileSignature):]
+		if len(chunk) < iccChunkHeaderLength {
+			log.Panicf("ICC chunk too short")
+		}
+
+		if count == 0 {
+			count = int(chunk[1])
+		} else if int(chunk[1]) != count {
+			log.Panicf("ICC chunks disagree on the chunk count: (%d) != (%d)", chunk[1], count)
+		}
+
+		chunks = append(chunks, iccChunk{
+			sequence: int(chunk[0]),
+			data:     chunk[iccChunkHeaderLength:],
+		})
+	}
+
+	if len(chunks) == 0 {
+		return nil, nil
+	} else if len(chunks) != count {
+		log.Panicf("ICC chunk count not correct: (%d) != (%d)", len(chunks), count)
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].sequence < chunks[j].sequence
+	})
+
+	for i, chunk := range chunks {
+		if chunk.sequence != i+1 {
+			log.Panicf("ICC chunk (%d) missing", i+1)
+		}
+
+		profile = append(profile, chunk.data...)
+	}
+
+	return profile, nil
+}
+
+// GetJpegIccProfile returns the ICC profile of a JPEG, reassembled from its
+// APP2 chunks. `ErrNoIccProfile` is returned if there isn't one.
+func GetJpegIccProfile(data []byte) (ip IccProfile, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	profile, err := jpegIccProfile(data)
+	log.PanicIf(err)
+
+	if profile == nil {
+		return ip, ErrNoIccProfile
+	}
+
+	ip, err = ParseIccProfile(profile)
+	log.PanicIf(err)
+
+	return ip, nil
+}
@@ -0,0 +1,175 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// getTestIccProfile returns a minimal v4 profile with an mluc description.
+func getTestIccProfile(description string) []byte {
+	mluc := new(bytes.Buffer)
+	mluc.WriteString("mluc")
+	mluc.Write([]byte{0, 0, 0, 0})
+
+	units := make([]uint16, 0)
+	for _, r := range description {
+		units = append(units, uint16(r))
+	}
+
+	for _, value := range []uint32{1, 12} {
+		err := binary.Write(mluc, binary.BigEndian, value)
+		log.PanicIf(err)
+	}
+
+	mluc.WriteString("enUS")
+
+	for _, value := range []uint32{uint32(len(units) * 2), 28} {
+		err := binary.Write(mluc, binary.BigEndian, value)
+		log.PanicIf(err)
+	}
+
+	err := binary.Write(mluc, binary.BigEndian, units)
+	log.PanicIf(err)
+
+	header := make([]byte, iccHeaderSize)
+	header[8] = 4
+	header[9] = 0x30
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	copy(header[36:], "acsp")
+
+	tagTableSize := 4 + iccTagEntrySize
+	size := iccHeaderSize + tagTableSize + mluc.Len()
+
+	binary.BigEndian.PutUint32(header, uint32(size))
+
+	b := new(bytes.Buffer)
+	b.Write(header)
+
+	for _, value := range []uint32{1} {
+		err := binary.Write(b, binary.BigEndian, value)
+		log.PanicIf(err)
+	}
+
+	b.WriteString("desc")
+
+	for _, value := range []uint32{uint32(iccHeaderSize + tagTableSize), uint32(mluc.Len())} {
+		err := binary.Write(b, binary.BigEndian, value)
+		log.PanicIf(err)
+	}
+
+	b.Write(mluc.Bytes())
+
+	return b.Bytes()
+}
+
+// getTestJpegWithIcc returns the test JPEG with the profile split across two
+// APP2 segments, stored out of order.
+func getTestJpegWithIcc(profile []byte) []byte {
+	data := getTestJpegWithExif()
+
+	half := len(profile) / 2
+	chunks := [][]byte{profile[half:], profile[:half]}
+	sequences := []byte{2, 1}
+
+	b := new(bytes.Buffer)
+	b.Write(data[:2])
+
+	for i, chunk := range chunks {
+		payloadLength := len(iccProfileSignature) + iccChunkHeaderLength + len(chunk)
+
+		b.Write([]byte{jpegMarkerPrefix, jpegMarkerApp2, byte((2 + payloadLength) >> 8), byte(2 + payloadLength)})
+		b.Write(iccProfileSignature)
+		b.Write([]byte{sequences[i], 2})
+		b.Write(chunk)
+	}
+
+	b.Write(data[2:])
+
+	return b.Bytes()
+}
+
+func TestParseIccProfile(t *testing.T) {
+	ip, err := ParseIccProfile(getTestIccProfile("Display P3"))
+	log.PanicIf(err)
+
+	if ip.Version != "4.3.0" {
+		t.Fatalf("Version not correct: [%s]", ip.Version)
+	} else if ip.DeviceClass != "mntr" {
+		t.Fatalf("Device class not correct: [%s]", ip.DeviceClass)
+	} else if ip.ColorSpace != "RGB " || ip.ConnectionSpace != "XYZ " {
+		t.Fatalf("Color spaces not correct: %s", ip)
+	} else if ip.Description != "Display P3" {
+		t.Fatalf("Description not correct: [%s]", ip.Description)
+	}
+}
+
+func TestParseIccProfile_Invalid(t *testing.T) {
+	_, err := ParseIccProfile(make([]byte, 200))
+	if err == nil {
+		t.Fatalf("Expected error for missing signature.")
+	}
+}
+
+func TestParseIccText_Desc(t *testing.T) {
+	value := []byte("desc\x00\x00\x00\x00\x00\x00\x00\x12sRGB IEC61966-2.1\x00")
+
+	if text := parseIccText(value); text != "sRGB IEC61966-2.1" {
+		t.Fatalf("Description not correct: [%s]", text)
+	}
+}
+
+func TestGetJpegIccProfile(t *testing.T) {
+	profile := getTestIccProfile("Display P3")
+	data := getTestJpegWithIcc(profile)
+
+	ip, err := GetJpegIccProfile(data)
+	log.PanicIf(err)
+
+	if bytes.Equal(ip.Data, profile) != true {
+		t.Fatalf("Profile not reassembled correctly.")
+	} else if ip.Description != "Display P3" {
+		t.Fatalf("Description not correct: [%s]", ip.Description)
+	}
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	if len(si.Find(JpegSegmentIcc)) != 2 {
+		t.Fatalf("ICC segments not found.")
+	} else if si.IccProfile == nil || si.IccProfile.Description != "Display P3" {
+		t.Fatalf("ICC profile not included in segments info.")
+	}
+}
+
+func TestGetJpegIccProfile_MissingChunk(t *testing.T) {
+	data := getTestJpegWithIcc(getTestIccProfile("Display P3"))
+
+	// Corrupt the chunk count of the first chunk so the chunks disagree.
+	i := bytes.Index(data, iccProfileSignature)
+	data[i+len(iccProfileSignature)+1] = 3
+
+	_, err := GetJpegIccProfile(data)
+	if err == nil {
+		t.Fatalf("Expected error for inconsistent chunks.")
+	}
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	if si.IccProfile != nil {
+		t.Fatalf("Broken profile should not be included.")
+	}
+}
+
+func TestGetJpegIccProfile_None(t *testing.T) {
+	_, err := GetJpegIccProfile(getTestJpegWithExif())
+	if err != ErrNoIccProfile {
+		t.Fatalf("Expected ErrNoIccProfile: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package exif
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/dsoprea/go-logging"
@@ -193,11 +194,16 @@ func (im *IfdMapping) ResolvePath(pathPhrase string) (lineage []IfdTagIdAndIndex
 	ptr := im.rootNode
 	empty := IfdTagIdAndIndex{}
 	for i, name := range path {
-		indexByte := name[len(name)-1]
+		// The index (e.g. of "IFD12") can have more than one digit.
+		digits := len(name)
+		for digits > 0 && name[digits-1] >= '0' && name[digits-1] <= '9' {
+			digits--
+		}
+
 		index := 0
-		if indexByte >= '0' && indexByte <= '9' {
-			index = int(indexByte - '0')
-			name = name[:len(name)-1]
+		if digits < len(name) {
+			index, _ = strconv.Atoi(name[digits:])
+			name = name[:digits]
 		}
 
 		itii := IfdTagIdAndIndex{}
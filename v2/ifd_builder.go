@@ -156,6 +156,9 @@ func (bt *BuilderTag) SetValue(byteOrder binary.ByteOrder, value interface{}) (e
 
 	// TODO(dustin): !! Add test.
 
+	value, err = CoerceValue(value, bt.typeId)
+	log.PanicIf(err)
+
 	var ed exifcommon.EncodedData
 	if bt.typeId == exifcommon.TypeUndefined {
 		encodeable := value.(exifundefined.EncodeableValue)
@@ -185,6 +188,18 @@ func (bt *BuilderTag) SetValue(byteOrder binary.ByteOrder, value interface{}) (e
 // NewStandardBuilderTag constructs a `BuilderTag` instance. The type is looked
 // up. `ii` is the type of IFD that owns this tag.
 func NewStandardBuilderTag(ifdPath string, it *IndexedTag, byteOrder binary.ByteOrder, value interface{}) *BuilderTag {
+	return newStandardBuilderTag(ifdPath, it, byteOrder, value, exifcommon.NewValueEncoder(byteOrder))
+}
+
+// newStandardBuilderTag is `NewStandardBuilderTag()` with the value encoded by
+// the given encoder. The value is first converted to the type in the index
+// (see `CoerceValue()`). A string that's encoded as UTF-8 gets that type
+// rather than the one in the index.
+func newStandardBuilderTag(ifdPath string, it *IndexedTag, byteOrder binary.ByteOrder, value interface{}, ve *exifcommon.ValueEncoder) *BuilderTag {
+	tagType := it.Type
+
+	value = coerceValue(value, tagType)
+
 	var rawBytes []byte
 	if it.Type == exifcommon.TypeUndefined {
 		encodeable := value.(exifundefined.EncodeableValue)
@@ -194,12 +209,14 @@ func NewStandardBuilderTag(ifdPath string, it *IndexedTag, byteOrder binary.Byte
 		rawBytes, _, err = exifundefined.Encode(encodeable, byteOrder)
 		log.PanicIf(err)
 	} else {
-		ve := exifcommon.NewValueEncoder(byteOrder)
-
 		ed, err := ve.Encode(value)
 		log.PanicIf(err)
 
 		rawBytes = ed.Encoded
+
+		if ed.Type == exifcommon.TypeUtf8 {
+			tagType = ed.Type
+		}
 	}
 
 	tagValue := NewIfdBuilderTagValueFromBytes(rawBytes)
@@ -207,7 +224,7 @@ func NewStandardBuilderTag(ifdPath string, it *IndexedTag, byteOrder binary.Byte
 	return NewBuilderTag(
 		ifdPath,
 		it.Id,
-		it.Type,
+		tagType,
 		tagValue,
 		byteOrder)
 }
@@ -246,6 +263,24 @@ type IfdBuilder struct {
 
 	ifdMapping *IfdMapping
 	tagIndex   *TagIndex
+
+	// asciiPolicy and utf8 determine how the strings that are set are
+	// encoded.
+	asciiPolicy exifcommon.AsciiPolicy
+	utf8        bool
+
+	// unknownTagPolicy determines whether tags that aren't in the index are
+	// copied from existing IFDs.
+	unknownTagPolicy UnknownTagPolicy
+
+	// listeners are notified when tags are added, replaced, or deleted.
+	listeners []IfdBuilderListener
+
+	// history has the earlier values of the tags that were replaced, by tag
+	// ID, if historyEnabled is true (see `SetHistoryEnabled()`).
+	historyEnabled bool
+	history        map[uint16][]*BuilderTag
+	reverting      bool
 }
 
 func NewIfdBuilder(ifdMapping *IfdMapping, tagIndex *TagIndex, fqIfdPath string, byteOrder binary.ByteOrder) (ib *IfdBuilder) {
@@ -319,10 +354,24 @@ func NewIfdBuilderWithExistingIfd(ifd *Ifd) (ib *IfdBuilder) {
 // NewIfdBuilderFromExistingChain creates a chain of IB instances from an
 // IFD chain generated from real data.
 func NewIfdBuilderFromExistingChain(rootIfd *Ifd) (firstIb *IfdBuilder) {
+	return newIfdBuilderFromExistingChain(rootIfd, nil, UnknownTagKeep)
+}
+
+// newIfdBuilderFromExistingChain creates the chain of IBs in the given byte
+// order or, if nil, the byte order of each IFD, with the given policy for
+// unknown tags.
+func newIfdBuilderFromExistingChain(rootIfd *Ifd, byteOrder binary.ByteOrder, policy UnknownTagPolicy) (firstIb *IfdBuilder) {
 	var lastIb *IfdBuilder
 	i := 0
 	for thisExistingIfd := rootIfd; thisExistingIfd != nil; thisExistingIfd = thisExistingIfd.NextIfd {
-		newIb := NewIfdBuilder(rootIfd.ifdMapping, rootIfd.tagIndex, rootIfd.FqIfdPath, thisExistingIfd.ByteOrder)
+		ibByteOrder := byteOrder
+		if ibByteOrder == nil {
+			ibByteOrder = thisExistingIfd.ByteOrder
+		}
+
+		newIb := NewIfdBuilder(rootIfd.ifdMapping, rootIfd.tagIndex, rootIfd.FqIfdPath, ibByteOrder)
+		newIb.unknownTagPolicy = policy
+
 		if firstIb == nil {
 			firstIb = newIb
 		} else {
@@ -398,8 +447,13 @@ func getOrCreateIbFromRootIbInner(rootIb *IfdBuilder, parentIb *IfdBuilder, curr
 
 	expectedFqRootIfdPath := ""
 	if parentLineage != nil {
-		expectedLineage := append(parentLineage, currentItii)
-		expectedFqRootIfdPath = thisIb.ifdMapping.PathPhraseFromLineage(expectedLineage)
+		// We're at the first sibling until we seek, below, and the parent
+		// might be a later page (e.g. "IFD2/Exif").
+		firstItii := currentItii
+		firstItii.Index = 0
+
+		expectedLineage := append(parentLineage, firstItii)
+		expectedFqRootIfdPath = thisIb.ifdMapping.FqPathPhraseFromLineage(expectedLineage)
 	} else {
 		expectedFqRootIfdPath = thisIb.ifdMapping.PathPhraseFromLineage(currentLineage[:1])
 	}
@@ -423,6 +477,7 @@ func getOrCreateIbFromRootIbInner(rootIb *IfdBuilder, parentIb *IfdBuilder, curr
 			}
 
 			thisIb.nextIb = NewIfdBuilder(thisIb.ifdMapping, thisIb.tagIndex, siblingFqIfdPath, thisIb.byteOrder)
+			thisIb.nextIb.inheritSettings(thisIb)
 		}
 
 		thisIb = thisIb.nextIb
@@ -464,6 +519,7 @@ func getOrCreateIbFromRootIbInner(rootIb *IfdBuilder, parentIb *IfdBuilder, curr
 		fqIfdChildPath := thisIb.ifdMapping.FqPathPhraseFromLineage(childLineage)
 
 		foundChild = NewIfdBuilder(thisIb.ifdMapping, thisIb.tagIndex, fqIfdChildPath, thisIb.byteOrder)
+		foundChild.inheritSettings(thisIb)
 
 		err = thisIb.AddChildIb(foundChild)
 		log.PanicIf(err)
@@ -510,6 +566,95 @@ func (ib *IfdBuilder) Tags() (tags []*BuilderTag) {
 	return ib.tags
 }
 
+// EncodedSize returns the exact number of bytes that `EncodeToExif()` will
+// produce for this IB, its child IBs, and the IBs chained after it, including
+// the header. This assumes the default encoder options, which pack values
+// without alignment. Use `IfdByteEncoder.EncodedSize()` for an encoder with
+// other options. Nothing is encoded, so this is cheap enough to call before
+// every change (e.g. to decide whether a thumbnail still fits within
+// `ExifMaxApp1Size`).
+func (ib *IfdBuilder) EncodedSize() (size uint32, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	size, err = NewIfdByteEncoder().EncodedSize(ib)
+	log.PanicIf(err)
+
+	return size, nil
+}
+
+// SetAsciiPolicy determines how the strings that are set on this builder are
+// encoded. The default is `exifcommon.AsciiPolicyTrim`. The builders that are
+// created from this one by `GetOrCreateIbFromRootIb()` and `AppendPage()`
+// inherit it.
+func (ib *IfdBuilder) SetAsciiPolicy(policy exifcommon.AsciiPolicy) {
+	ib.asciiPolicy = policy
+}
+
+// SetUtf8 has the strings that are set on this builder and that aren't
+// seven-bit ASCII written with the UTF-8 type of EXIF 3.0. It's inherited the
+// same way as the ASCII policy.
+func (ib *IfdBuilder) SetUtf8(utf8 bool) {
+	ib.utf8 = utf8
+}
+
+// newValueEncoder returns an encoder for the values that are set on this
+// builder.
+func (ib *IfdBuilder) newValueEncoder() *exifcommon.ValueEncoder {
+	ve := exifcommon.NewValueEncoder(ib.byteOrder)
+	ve.SetAsciiPolicy(ib.asciiPolicy)
+	ve.SetUtf8(ib.utf8)
+
+	return ve
+}
+
+// ReencodeStrings decodes the ASCII values of this builder and its children
+// that aren't actually ASCII with the given decoder and sets them again, as
+// UTF-8. Enable `SetUtf8()` first to have them written with the UTF-8 type.
+func (ib *IfdBuilder) ReencodeStrings(cd *exifcommon.CharsetDecoder) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	parser := new(exifcommon.Parser)
+
+	for i, bt := range ib.tags {
+		if bt.value.IsIb() == true {
+			err := bt.value.Ib().ReencodeStrings(cd)
+			log.PanicIf(err)
+
+			continue
+		} else if bt.typeId != exifcommon.TypeAscii {
+			continue
+		}
+
+		raw := bt.value.Bytes()
+
+		value, err := parser.ParseAsciiWithPolicy(raw, uint32(len(raw)), exifcommon.AsciiPolicyVerbatim)
+		log.PanicIf(err)
+
+		decoded, cs, err := cd.Decode([]byte(value))
+		log.PanicIf(err)
+
+		if cs == nil {
+			continue
+		}
+
+		ed, err := ib.newValueEncoder().Encode(decoded)
+		log.PanicIf(err)
+
+		reencodedBt := NewBuilderTag(bt.ifdPath, bt.tagId, ed.Type, NewIfdBuilderTagValueFromBytes(ed.Encoded), bt.byteOrder)
+		ib.replaceTag(i, reencodedBt)
+	}
+
+	return nil
+}
+
 // SetThumbnail sets thumbnail data.
 //
 // NOTES:
@@ -532,8 +677,6 @@ func (ib *IfdBuilder) SetThumbnail(data []byte) (err error) {
 		log.Panicf("thumbnails can only go into a root Ifd (and only the second one)")
 	}
 
-	// TODO(dustin): !! Add a test for this function.
-
 	if data == nil || len(data) == 0 {
 		log.Panic("thumbnail is empty")
 	}
@@ -563,6 +706,32 @@ func (ib *IfdBuilder) SetThumbnail(data []byte) (err error) {
 	return nil
 }
 
+// RemoveThumbnail removes the thumbnail data along with the tags that point to
+// it, so that nothing refers to a thumbnail that is no longer written. Like
+// `SetThumbnail()`, this has to be called on the second root IFD. The IFD
+// stays in the chain; use `SetNextIb()` on the first one to drop it.
+func (ib *IfdBuilder) RemoveThumbnail() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ib.ifdPath != exifcommon.IfdPathStandard {
+		log.Panicf("thumbnails can only be in a root Ifd")
+	}
+
+	ib.thumbnailData = nil
+
+	_, err = ib.DeleteAll(ThumbnailOffsetTagId)
+	log.PanicIf(err)
+
+	_, err = ib.DeleteAll(ThumbnailSizeTagId)
+	log.PanicIf(err)
+
+	return nil
+}
+
 func (ib *IfdBuilder) Thumbnail() []byte {
 	return ib.thumbnailData
 }
@@ -742,6 +911,51 @@ func (ib *IfdBuilder) SetNextIb(nextIb *IfdBuilder) (err error) {
 	return nil
 }
 
+// AppendPage adds an empty root-level IFD to the end of the chain, such as
+// the next page of a multi-page TIFF, and returns its builder. The new IFD
+// is named after its position (e.g. "IFD2" for the third page). This must be
+// called on the builder of the first IFD.
+func (ib *IfdBuilder) AppendPage() (pageIb *IfdBuilder, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ib.fqIfdPath != exifcommon.IfdStandard {
+		log.Panicf("pages can only be appended via the first root IFD: [%s]", ib.fqIfdPath)
+	}
+
+	lastIb := ib
+	count := 1
+
+	for lastIb.nextIb != nil {
+		lastIb = lastIb.nextIb
+		count++
+	}
+
+	fqIfdPath := fmt.Sprintf("%s%d", exifcommon.IfdStandard, count)
+
+	pageIb = NewIfdBuilder(ib.ifdMapping, ib.tagIndex, fqIfdPath, ib.byteOrder)
+	pageIb.inheritSettings(ib)
+
+	err = lastIb.SetNextIb(pageIb)
+	log.PanicIf(err)
+
+	return pageIb, nil
+}
+
+// Pages returns the builders of this IFD and every IFD chained after it.
+func (ib *IfdBuilder) Pages() []*IfdBuilder {
+	pages := make([]*IfdBuilder, 0)
+
+	for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+		pages = append(pages, thisIb)
+	}
+
+	return pages
+}
+
 func (ib *IfdBuilder) DeleteN(tagId uint16, n int) (err error) {
 	defer func() {
 		if state := recover(); state != nil {
@@ -766,7 +980,7 @@ func (ib *IfdBuilder) DeleteN(tagId uint16, n int) (err error) {
 			log.Panic(ErrTagEntryNotFound)
 		}
 
-		ib.tags = append(ib.tags[:j], ib.tags[j+1:]...)
+		ib.removeTag(j)
 		n--
 	}
 
@@ -820,7 +1034,7 @@ func (ib *IfdBuilder) ReplaceAt(position int, bt *BuilderTag) (err error) {
 		log.Panicf("replacement position does not exist")
 	}
 
-	ib.tags[position] = bt
+	ib.replaceTag(position, bt)
 
 	return nil
 }
@@ -835,7 +1049,7 @@ func (ib *IfdBuilder) Replace(tagId uint16, bt *BuilderTag) (err error) {
 	position, err := ib.Find(tagId)
 	log.PanicIf(err)
 
-	ib.tags[position] = bt
+	ib.replaceTag(position, bt)
 
 	return nil
 }
@@ -850,7 +1064,7 @@ func (ib *IfdBuilder) Set(bt *BuilderTag) (err error) {
 
 	position, err := ib.Find(bt.tagId)
 	if err == nil {
-		ib.tags[position] = bt
+		ib.replaceTag(position, bt)
 	} else if log.Is(err, ErrTagEntryNotFound) == true {
 		err = ib.add(bt)
 		log.PanicIf(err)
@@ -955,7 +1169,7 @@ func (ib *IfdBuilder) add(bt *BuilderTag) (err error) {
 		log.Panicf("BuilderTag value is not set: %s", bt)
 	}
 
-	ib.tags = append(ib.tags, bt)
+	ib.appendTag(bt)
 	return nil
 }
 
@@ -1000,7 +1214,7 @@ func (ib *IfdBuilder) AddChildIb(childIb *IfdBuilder) (err error) {
 	}
 
 	bt := ib.NewBuilderTagFromBuilder(childIb)
-	ib.tags = append(ib.tags, bt)
+	ib.appendTag(bt)
 
 	return nil
 }
@@ -1025,7 +1239,8 @@ func (ib *IfdBuilder) NewBuilderTagFromBuilder(childIb *IfdBuilder) (bt *Builder
 
 // AddTagsFromExisting does a verbatim copy of the entries in `ifd` to this
 // builder. It excludes child IFDs. These must be added explicitly via
-// `AddChildIb()`.
+// `AddChildIb()`. Tags that aren't in the tag index are handled according to
+// `SetUnknownTagPolicy()`.
 func (ib *IfdBuilder) AddTagsFromExisting(ifd *Ifd, includeTagIds []uint16, excludeTagIds []uint16) (err error) {
 	defer func() {
 		if state := recover(); state != nil {
@@ -1107,14 +1322,23 @@ func (ib *IfdBuilder) AddTagsFromExisting(ifd *Ifd, includeTagIds []uint16, excl
 				log.Panicf("could not find child IFD for child ITE: IFD-PATH=[%s] TAG-ID=(0x%04x) CURRENT-TAG-POSITION=(%d) CHILDREN=%v", ite.IfdPath(), ite.TagId(), i, childTagIds)
 			}
 
-			childIb := NewIfdBuilderFromExistingChain(childIfd)
+			// The child IFD might have been in a different byte order, but
+			// it'll be written in ours.
+			childIb := newIfdBuilderFromExistingChain(childIfd, ib.byteOrder, ib.unknownTagPolicy)
 			bt = ib.NewBuilderTagFromBuilder(childIb)
 		} else {
 			// Non-IFD tag.
 
+			if ib.keepExistingTag(ite) == false {
+				continue
+			}
+
 			rawBytes, err := ite.GetRawBytes()
 			log.PanicIf(err)
 
+			rawBytes, err = convertRawByteOrder(ite, rawBytes, ib.byteOrder)
+			log.PanicIf(err)
+
 			value := NewIfdBuilderTagValueFromBytes(rawBytes)
 
 			bt = NewBuilderTag(
@@ -1144,7 +1368,7 @@ func (ib *IfdBuilder) AddStandard(tagId uint16, value interface{}) (err error) {
 	it, err := ib.tagIndex.Get(ib.ifdPath, tagId)
 	log.PanicIf(err)
 
-	bt := NewStandardBuilderTag(ib.ifdPath, it, ib.byteOrder, value)
+	bt := newStandardBuilderTag(ib.ifdPath, it, ib.byteOrder, value, ib.newValueEncoder())
 
 	err = ib.add(bt)
 	log.PanicIf(err)
@@ -1165,7 +1389,7 @@ func (ib *IfdBuilder) AddStandardWithName(tagName string, value interface{}) (er
 	it, err := ib.tagIndex.GetWithName(ib.ifdPath, tagName)
 	log.PanicIf(err)
 
-	bt := NewStandardBuilderTag(ib.ifdPath, it, ib.byteOrder, value)
+	bt := newStandardBuilderTag(ib.ifdPath, it, ib.byteOrder, value, ib.newValueEncoder())
 
 	err = ib.add(bt)
 	log.PanicIf(err)
@@ -1187,7 +1411,7 @@ func (ib *IfdBuilder) SetStandard(tagId uint16, value interface{}) (err error) {
 	it, err := ib.tagIndex.Get(ib.ifdPath, tagId)
 	log.PanicIf(err)
 
-	bt := NewStandardBuilderTag(ib.ifdPath, it, ib.byteOrder, value)
+	bt := newStandardBuilderTag(ib.ifdPath, it, ib.byteOrder, value, ib.newValueEncoder())
 
 	i, err := ib.Find(tagId)
 	if err != nil {
@@ -1195,14 +1419,34 @@ func (ib *IfdBuilder) SetStandard(tagId uint16, value interface{}) (err error) {
 			log.Panic(err)
 		}
 
-		ib.tags = append(ib.tags, bt)
+		ib.appendTag(bt)
 	} else {
-		ib.tags[i] = bt
+		ib.replaceTag(i, bt)
 	}
 
 	return nil
 }
 
+// ResetOrientation sets the Orientation tag to the default (normal)
+// orientation. Call this after the orientation has been applied to the image
+// data itself (see `ApplyOrientation()`). Only valid on the root IFD.
+func (ib *IfdBuilder) ResetOrientation() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ib.ifdPath != exifcommon.IfdStandard {
+		log.Panicf("orientation can only be set on the root IFD: [%s]", ib.fqIfdPath)
+	}
+
+	err = ib.SetStandard(OrientationTagId, []uint16{OrientationTopLeft})
+	log.PanicIf(err)
+
+	return nil
+}
+
 // SetStandardWithName quickly and easily composes and adds or replaces the
 // tag using the information already known about a tag (using the name). Only
 // works with standard tags.
@@ -1218,7 +1462,7 @@ func (ib *IfdBuilder) SetStandardWithName(tagName string, value interface{}) (er
 	it, err := ib.tagIndex.GetWithName(ib.ifdPath, tagName)
 	log.PanicIf(err)
 
-	bt := NewStandardBuilderTag(ib.ifdPath, it, ib.byteOrder, value)
+	bt := newStandardBuilderTag(ib.ifdPath, it, ib.byteOrder, value, ib.newValueEncoder())
 
 	i, err := ib.Find(bt.tagId)
 	if err != nil {
@@ -1226,9 +1470,9 @@ func (ib *IfdBuilder) SetStandardWithName(tagName string, value interface{}) (er
 			log.Panic(err)
 		}
 
-		ib.tags = append(ib.tags, bt)
+		ib.appendTag(bt)
 	} else {
-		ib.tags[i] = bt
+		ib.replaceTag(i, bt)
 	}
 
 	return nil
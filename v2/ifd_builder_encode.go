@@ -3,6 +3,8 @@ package exif
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 
 	"encoding/binary"
@@ -17,6 +19,19 @@ const (
 	IfdTagEntrySize = uint32(2 + 2 + 4 + 4)
 )
 
+// ThumbnailPlacement determines where the encoder writes the thumbnail.
+type ThumbnailPlacement int
+
+const (
+	// ThumbnailPlacementInline writes the thumbnail with the other values of
+	// its IFD. This is the default.
+	ThumbnailPlacementInline ThumbnailPlacement = iota
+
+	// ThumbnailPlacementEnd writes the thumbnail after everything else, so
+	// that it can be found (or truncated) without walking the IFDs.
+	ThumbnailPlacementEnd
+)
+
 type ByteWriter struct {
 	b         *bytes.Buffer
 	byteOrder binary.ByteOrder
@@ -90,22 +105,77 @@ func (bw ByteWriter) WriteFourBytes(value []byte) (err error) {
 // keeping track of where the offsets start, the data that has been added, and
 // bumping the offset *when* the data is added.
 type ifdDataAllocator struct {
-	offset uint32
-	b      bytes.Buffer
+	offset    uint32
+	alignment uint32
+	b         bytes.Buffer
+
+	// asciiOffsets are the offsets of the ASCII values that can be shared,
+	// if they're being deduplicated. New ones are also recorded in
+	// publishedAsciiOffsets, if it's set, for the IFDs encoded later.
+	asciiOffsets          map[string]uint32
+	publishedAsciiOffsets map[string]uint32
 }
 
 func newIfdDataAllocator(ifdDataAddressableOffset uint32) *ifdDataAllocator {
 	return &ifdDataAllocator{
-		offset: ifdDataAddressableOffset,
+		offset:    ifdDataAddressableOffset,
+		alignment: 1,
+	}
+}
+
+// Align pads the data so that the next offset is a multiple of the alignment.
+func (ida *ifdDataAllocator) Align() {
+	if ida.alignment <= 1 {
+		return
+	}
+
+	padding := (ida.alignment - ida.offset%ida.alignment) % ida.alignment
+	if padding == 0 {
+		return
 	}
+
+	ida.b.Write(make([]byte, padding))
+	ida.offset += padding
 }
 
 func (ida *ifdDataAllocator) Allocate(value []byte) (offset uint32, err error) {
+	ida.Align()
+
+	if uint64(len(value)) > math.MaxUint32 {
+		log.Panic(exifcommon.ErrOffsetOverflow)
+	}
+
+	nextOffset, err := exifcommon.CheckedAdd(ida.offset, uint32(len(value)))
+	log.PanicIf(err)
+
 	_, err = ida.b.Write(value)
 	log.PanicIf(err)
 
 	offset = ida.offset
-	ida.offset += uint32(len(value))
+	ida.offset = nextOffset
+
+	return offset, nil
+}
+
+// AllocateAscii allocates an ASCII value, or returns the offset of the same
+// value if it was already allocated and values are being deduplicated.
+func (ida *ifdDataAllocator) AllocateAscii(value []byte) (offset uint32, err error) {
+	if ida.asciiOffsets == nil {
+		return ida.Allocate(value)
+	}
+
+	if offset, found := ida.asciiOffsets[string(value)]; found == true {
+		return offset, nil
+	}
+
+	offset, err = ida.Allocate(value)
+	log.PanicIf(err)
+
+	ida.asciiOffsets[string(value)] = offset
+
+	if ida.publishedAsciiOffsets != nil {
+		ida.publishedAsciiOffsets[string(value)] = offset
+	}
 
 	return offset, nil
 }
@@ -124,14 +194,96 @@ func (ida *ifdDataAllocator) Bytes() []byte {
 type IfdByteEncoder struct {
 	// journal holds a list of actions taken while encoding.
 	journal [][3]string
+
+	valueAlignment     uint32
+	thumbnailPlacement ThumbnailPlacement
+
+	// thumbnailData and thumbnailOffset are used to defer the thumbnail to
+	// the end when `ThumbnailPlacementEnd` is set.
+	thumbnailData   []byte
+	thumbnailOffset uint32
+
+	slack []SlackRegion
+
+	// pinnedValues and pinnedIfds are the offsets that values and child IFDs
+	// are pinned to (see `PinValue()` and `PinIfd()`), and pins are the ones
+	// that were found while encoding.
+	pinnedValues map[pinnedTag]uint32
+	pinnedIfds   map[string]uint32
+	pins         []encoderPin
+
+	// deduplicateAscii is true if identical ASCII values share storage, and
+	// asciiOffsets are the values written so far.
+	deduplicateAscii bool
+	asciiOffsets     map[string]uint32
+
+	// targetExifVersion is the EXIF version that's negotiated before encoding
+	// (see `SetTargetExifVersion()`), or empty to write the builder as it is.
+	targetExifVersion string
+	exifVersionPolicy ExifVersionPolicy
+
+	// fieldCodec, if not nil, protects the values of the tags that it selects
+	// (see `SetFieldCodec()`).
+	fieldCodec FieldCodec
 }
 
 func NewIfdByteEncoder() (ibe *IfdByteEncoder) {
 	return &IfdByteEncoder{
-		journal: make([][3]string, 0),
+		journal:        make([][3]string, 0),
+		valueAlignment: 1,
 	}
 }
 
+// SetValueAlignment makes every allocated value, and every IFD, start at a
+// multiple of the given number of bytes. Values are packed by default. TIFF
+// recommends (2), and (4) additionally word-aligns everything, which some
+// strict readers require.
+func (ibe *IfdByteEncoder) SetValueAlignment(alignment uint32) {
+	if alignment != 1 && alignment != 2 && alignment != 4 {
+		log.Panicf("value alignment must be (1), (2), or (4): (%d)", alignment)
+	}
+
+	ibe.valueAlignment = alignment
+}
+
+// SetSlack sets unreferenced data from the original EXIF block (see
+// `ExifLayout.Slack()`) to be carried over. Each region is written at its
+// original offset if that's still past everything else, which keeps any
+// absolute offsets into it valid. Otherwise, it's appended to the end.
+func (ibe *IfdByteEncoder) SetSlack(slack []SlackRegion) {
+	ibe.slack = make([]SlackRegion, len(slack))
+	copy(ibe.slack, slack)
+
+	sort.SliceStable(ibe.slack, func(i, j int) bool {
+		return ibe.slack[i].Offset < ibe.slack[j].Offset
+	})
+}
+
+// SetDeduplicateAscii makes tags with identical ASCII values (e.g. DateTime
+// and DateTimeOriginal) point to the same data instead of each having a copy.
+// Note that `EncodedSize()` has to encode everything when this is set.
+func (ibe *IfdByteEncoder) SetDeduplicateAscii(deduplicate bool) {
+	ibe.deduplicateAscii = deduplicate
+}
+
+// SetThumbnailPlacement determines where the thumbnail is written.
+func (ibe *IfdByteEncoder) SetThumbnailPlacement(placement ThumbnailPlacement) {
+	ibe.thumbnailPlacement = placement
+}
+
+// SetTargetExifVersion has every builder that's encoded made to conform to
+// the given EXIF version (one of the ExifVersion* constants) first (see
+// `NegotiateExifVersion()`). The builder is restored afterward, so only the
+// encoded data reflects it.
+func (ibe *IfdByteEncoder) SetTargetExifVersion(version string, policy ExifVersionPolicy) {
+	if exifVersions[version] == false {
+		log.Panicf("EXIF version not supported: [%s]", version)
+	}
+
+	ibe.targetExifVersion = version
+	ibe.exifVersionPolicy = policy
+}
+
 func (ibe *IfdByteEncoder) Journal() [][3]string {
 	return ibe.journal
 }
@@ -248,8 +400,36 @@ func (ibe *IfdByteEncoder) encodeTagToBytes(ib *IfdBuilder, bt *BuilderTag, bw *
 
 		// Write four-byte value/offset.
 
-		if len_ > 4 {
-			offset, err := ida.Allocate(valueBytes)
+		if ibe.thumbnailPlacement == ThumbnailPlacementEnd && bt.tagId == ThumbnailOffsetTagId && ib.ifdPath == exifcommon.IfdPathStandard {
+			// The offset isn't known until everything else has been
+			// encoded. See `EncodeToExifPayload()`.
+
+			ibe.thumbnailData = valueBytes
+
+			err = bw.WriteUint32(ibe.thumbnailOffset)
+			log.PanicIf(err)
+		} else if pinnedOffset, found := ibe.getPinnedValueOffset(ib, bt); found == true && len_ > 4 {
+			// It's written after everything else. See `encodeTail()`.
+
+			if nextIfdOffsetToWrite > 0 {
+				ibe.addPin(encoderPin{
+					offset:      pinnedOffset,
+					description: fmt.Sprintf("value of tag (0x%04x) in [%s]", bt.tagId, ib.ifdPath),
+					data:        valueBytes,
+				})
+			}
+
+			err = bw.WriteUint32(pinnedOffset)
+			log.PanicIf(err)
+		} else if len_ > 4 {
+			var offset uint32
+
+			if bt.typeId == exifcommon.TypeAscii {
+				offset, err = ida.AllocateAscii(valueBytes)
+			} else {
+				offset, err = ida.Allocate(valueBytes)
+			}
+
 			log.PanicIf(err)
 
 			err = bw.WriteUint32(offset)
@@ -270,7 +450,20 @@ func (ibe *IfdByteEncoder) encodeTagToBytes(ib *IfdBuilder, bt *BuilderTag, bw *
 		err = bw.WriteUint32(1)
 		log.PanicIf(err)
 
-		if nextIfdOffsetToWrite > 0 {
+		if pinnedOffset, found := ibe.getPinnedIfdOffset(bt.value.Ib()); found == true {
+			// It's written after everything else. See `encodeTail()`.
+
+			if nextIfdOffsetToWrite > 0 {
+				ibe.addPin(encoderPin{
+					offset:      pinnedOffset,
+					description: fmt.Sprintf("IFD [%s]", bt.value.Ib().ifdPath),
+					ib:          bt.value.Ib(),
+				})
+			}
+
+			err = bw.WriteUint32(pinnedOffset)
+			log.PanicIf(err)
+		} else if nextIfdOffsetToWrite > 0 {
 			var err error
 
 			ibe.pushToJournal("encodeTagToBytes", ">", "[%s]->[%s]", ib.ifdPath, bt.value.Ib().ifdPath)
@@ -328,6 +521,23 @@ func (ibe *IfdByteEncoder) encodeIfdToBytes(ib *IfdBuilder, ifdAddressableOffset
 	log.PanicIf(err)
 
 	ida := newIfdDataAllocator(ifdAddressableOffset)
+	ida.alignment = ibe.valueAlignment
+
+	if ibe.deduplicateAscii == true {
+		// Only values from IFDs that were written before this one started can
+		// be shared, so that sizing this IFD and then writing it allocate the
+		// same data even though its child IFDs are written in between. New
+		// values are only published when this IFD is really being written.
+
+		ida.asciiOffsets = make(map[string]uint32, len(ibe.asciiOffsets))
+		for value, offset := range ibe.asciiOffsets {
+			ida.asciiOffsets[value] = offset
+		}
+
+		if nextIfdOffsetToWrite > 0 {
+			ida.publishedAsciiOffsets = ibe.asciiOffsets
+		}
+	}
 
 	childIfdBlocks := make([][]byte, 0)
 
@@ -346,11 +556,17 @@ func (ibe *IfdByteEncoder) encodeIfdToBytes(ib *IfdBuilder, ifdAddressableOffset
 				log.Panicf("no IFD offset provided for child-IFDs; no new child-IFDs permitted")
 			}
 
-			nextIfdOffsetToWrite += uint32(len(childIfdBlock))
+			nextIfdOffsetToWrite, err = exifcommon.CheckedAdd(nextIfdOffsetToWrite, uint32(len(childIfdBlock)))
+			log.PanicIf(err)
+
 			childIfdBlocks = append(childIfdBlocks, childIfdBlock)
 		}
 	}
 
+	// Make sure that whatever comes after us (child or sibling IFDs) is
+	// aligned.
+	ida.Align()
+
 	dataBytes := ida.Bytes()
 	dataSize = uint32(len(dataBytes))
 
@@ -430,13 +646,18 @@ func (ibe *IfdByteEncoder) encodeAndAttachIfd(ib *IfdBuilder, ifdAddressableOffs
 
 		ibe.pushToJournal("encodeAndAttachIfd", ">", "Calculating size: (%d) [%s]", i, thisIb.ifdPath)
 
-		_, tableSize, allocatedDataSize, _, err := ibe.encodeIfdToBytes(thisIb, ifdAddressableOffset, 0, false)
+		// The data has to be sized from where it'll actually be allocated
+		// since that determines the alignment padding.
+		_, tableSize, allocatedDataSize, _, err := ibe.encodeIfdToBytes(thisIb, ifdAddressableOffset+ibe.TableSize(len(thisIb.tags)), 0, false)
 		log.PanicIf(err)
 
 		ibe.pushToJournal("encodeAndAttachIfd", "<", "Finished calculating size: (%d) [%s]", i, thisIb.ifdPath)
 
-		ifdAddressableOffset += tableSize
-		nextIfdOffsetToWrite := ifdAddressableOffset + allocatedDataSize
+		ifdAddressableOffset, err = exifcommon.CheckedAdd(ifdAddressableOffset, tableSize)
+		log.PanicIf(err)
+
+		nextIfdOffsetToWrite, err := exifcommon.CheckedAdd(ifdAddressableOffset, allocatedDataSize)
+		log.PanicIf(err)
 
 		ibe.pushToJournal("encodeAndAttachIfd", ">", "Next IFD will be written at offset (0x%08x)", nextIfdOffsetToWrite)
 
@@ -462,7 +683,8 @@ func (ibe *IfdByteEncoder) encodeAndAttachIfd(ib *IfdBuilder, ifdAddressableOffs
 
 		totalChildIfdSize := uint32(0)
 		for _, childIfdSize := range childIfdSizes {
-			totalChildIfdSize += childIfdSize
+			totalChildIfdSize, err = exifcommon.CheckedAdd(totalChildIfdSize, childIfdSize)
+			log.PanicIf(err)
 		}
 
 		if len(tableAndAllocated) != int(tableSize+allocatedDataSize+totalChildIfdSize) {
@@ -476,7 +698,11 @@ func (ibe *IfdByteEncoder) encodeAndAttachIfd(ib *IfdBuilder, ifdAddressableOffs
 
 		// Advance past what we've allocated, thus far.
 
-		ifdAddressableOffset += allocatedDataSize + totalChildIfdSize
+		ifdAddressableOffset, err = exifcommon.CheckedAdd(ifdAddressableOffset, allocatedDataSize)
+		log.PanicIf(err)
+
+		ifdAddressableOffset, err = exifcommon.CheckedAdd(ifdAddressableOffset, totalChildIfdSize)
+		log.PanicIf(err)
 
 		ibe.pushToJournal("encodeAndAttachIfd", "<", "Finishing encoding process: (%d) [%s] [FINAL:] NEXT-IFD-OFFSET-TO-WRITE=(0x%08x)", i, ib.ifdPath, nextIfdOffsetToWrite)
 
@@ -497,12 +723,150 @@ func (ibe *IfdByteEncoder) EncodeToExifPayload(ib *IfdBuilder) (data []byte, err
 		}
 	}()
 
+	if ibe.targetExifVersion != "" || ibe.fieldCodec != nil {
+		snapshot := ib.Snapshot()
+
+		defer func() {
+			err := ib.Restore(snapshot)
+			log.PanicIf(err)
+		}()
+
+		if ibe.targetExifVersion != "" {
+			err := NegotiateExifVersion(ib, ibe.targetExifVersion, ibe.exifVersionPolicy)
+			log.PanicIf(err)
+		}
+
+		if ibe.fieldCodec != nil {
+			err := protectFields(ib, ibe.fieldCodec)
+			log.PanicIf(err)
+		}
+	}
+
+	ibe.thumbnailData = nil
+	ibe.thumbnailOffset = 0
+	ibe.asciiOffsets = make(map[string]uint32)
+	ibe.pins = nil
+
 	data, err = ibe.encodeAndAttachIfd(ib, ExifDefaultFirstIfdOffset)
 	log.PanicIf(err)
 
+	tail := ibe.encodeTail(ExifDefaultFirstIfdOffset + uint32(len(data)))
+
+	if ibe.thumbnailData != nil {
+		// Now that we know where everything else ends, encode again with the
+		// real thumbnail offset (the size doesn't change) and append the
+		// thumbnail.
+
+		thumbnailOffset := ExifDefaultFirstIfdOffset + uint32(len(data)) + uint32(len(tail))
+		padding := (ibe.valueAlignment - thumbnailOffset%ibe.valueAlignment) % ibe.valueAlignment
+
+		ibe.thumbnailOffset = thumbnailOffset + padding
+		ibe.asciiOffsets = make(map[string]uint32)
+		ibe.pins = nil
+
+		data, err = ibe.encodeAndAttachIfd(ib, ExifDefaultFirstIfdOffset)
+		log.PanicIf(err)
+
+		tail = ibe.encodeTail(ExifDefaultFirstIfdOffset + uint32(len(data)))
+
+		data = append(data, tail...)
+		data = append(data, make([]byte, padding)...)
+		data = append(data, ibe.thumbnailData...)
+	} else {
+		data = append(data, tail...)
+	}
+
 	return data, nil
 }
 
+// EncodedSize returns the exact number of bytes that `EncodeToExif()` will
+// produce for the given IB, its child IBs, and the IBs chained after it,
+// including the header, the alignment padding, the slack, the pinned values
+// and IFDs, and the thumbnail. Nothing is encoded unless ASCII values are
+// deduplicated, anything is pinned, an EXIF version is targeted, or values are
+// protected.
+func (ibe *IfdByteEncoder) EncodedSize(ib *IfdBuilder) (size uint32, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ibe.deduplicateAscii == true || ibe.hasPins() == true || ibe.targetExifVersion != "" || ibe.fieldCodec != nil {
+		// Which values are shared, how big the pinned IFDs are, which tags
+		// the EXIF version keeps, and how big the protected values are, is
+		// only known by encoding.
+
+		data, err := ibe.EncodeToExif(ib)
+		log.PanicIf(err)
+
+		return uint32(len(data)), nil
+	}
+
+	var thumbnailSize uint64
+
+	end := ibe.encodedChainEnd(ib, uint64(ExifDefaultFirstIfdOffset), &thumbnailSize)
+	if end > math.MaxUint32 {
+		log.Panic(exifcommon.ErrOffsetOverflow)
+	}
+
+	end += uint64(len(ibe.encodeTail(uint32(end))))
+
+	if thumbnailSize > 0 {
+		end = ibe.alignedOffset(end) + thumbnailSize
+	}
+
+	if end > math.MaxUint32 {
+		log.Panic(exifcommon.ErrOffsetOverflow)
+	}
+
+	return uint32(end), nil
+}
+
+// alignedOffset returns the next offset on a value boundary.
+func (ibe *IfdByteEncoder) alignedOffset(offset uint64) uint64 {
+	alignment := uint64(ibe.valueAlignment)
+	return offset + (alignment-offset%alignment)%alignment
+}
+
+// encodedChainEnd returns where the tables, allocated values, and child IFDs
+// of the given IB and every IB after it will end if the first table is
+// written at the given offset. This mirrors `encodeAndAttachIfd()`. The size
+// of a thumbnail that's deferred to the end is returned separately.
+func (ibe *IfdByteEncoder) encodedChainEnd(ib *IfdBuilder, offset uint64, thumbnailSize *uint64) uint64 {
+	for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+		offset += uint64(ibe.TableSize(len(thisIb.tags)))
+
+		childIbs := make([]*IfdBuilder, 0)
+
+		for _, bt := range thisIb.tags {
+			if bt.value.IsIb() == true {
+				childIbs = append(childIbs, bt.value.Ib())
+				continue
+			} else if bt.value.IsBytes() == false {
+				continue
+			}
+
+			len_ := uint64(len(bt.value.Bytes()))
+
+			if ibe.thumbnailPlacement == ThumbnailPlacementEnd && bt.tagId == ThumbnailOffsetTagId && thisIb.ifdPath == exifcommon.IfdPathStandard {
+				*thumbnailSize = len_
+			} else if len_ > 4 {
+				offset = ibe.alignedOffset(offset) + len_
+			}
+		}
+
+		// The data is padded so that whatever follows is aligned.
+		offset = ibe.alignedOffset(offset)
+
+		for _, childIb := range childIbs {
+			offset = ibe.encodedChainEnd(childIb, offset, thumbnailSize)
+		}
+	}
+
+	return offset
+}
+
 // EncodeToExif calls EncodeToExifPayload and then packages the result into a
 // complete EXIF block.
 func (ibe *IfdByteEncoder) EncodeToExif(ib *IfdBuilder) (data []byte, err error) {
@@ -0,0 +1,152 @@
+package exif
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// ErrPinnedOffsetNotAvailable means that a pinned value or IFD can't be
+	// written at its offset because something else is already there.
+	ErrPinnedOffsetNotAvailable = errors.New("pinned offset not available")
+)
+
+// pinnedTag identifies a tag whose value is pinned.
+type pinnedTag struct {
+	ifdPath string
+	tagId   uint16
+}
+
+// encoderPin is a value or an IFD that was found while encoding and has to be
+// written at a pinned offset.
+type encoderPin struct {
+	offset uint32
+
+	// description says what it is, for errors.
+	description string
+
+	// data is the value, or the encoded IFD once `ib` has been encoded.
+	data []byte
+	ib   *IfdBuilder
+}
+
+// PinValue makes the value of the tag with the given ID in the IFD with the
+// given path (e.g. "IFD/Exif") be written at the given offset from the start
+// of the EXIF data, for readers (e.g. camera firmware) that expect it at a
+// fixed position, such as a maker note at its original offset. Pinned values
+// and IFDs are written after everything else, with padding in between, so the
+// offset has to be past the end of the other IFDs and values. Otherwise,
+// encoding fails with `ErrPinnedOffsetNotAvailable`. Values of four bytes or
+// less are stored in their entries and can't be pinned.
+func (ibe *IfdByteEncoder) PinValue(ifdPath string, tagId uint16, offset uint32) {
+	if ibe.pinnedValues == nil {
+		ibe.pinnedValues = make(map[pinnedTag]uint32)
+	}
+
+	ibe.pinnedValues[pinnedTag{ifdPath: ifdPath, tagId: tagId}] = offset
+}
+
+// PinIfd makes the child IFD with the given path (e.g. "IFD/Exif") be written
+// at the given offset, along with its values and its own child IFDs, as for
+// `PinValue()`.
+func (ibe *IfdByteEncoder) PinIfd(ifdPath string, offset uint32) {
+	if ibe.pinnedIfds == nil {
+		ibe.pinnedIfds = make(map[string]uint32)
+	}
+
+	ibe.pinnedIfds[ifdPath] = offset
+}
+
+// hasPins returns true if any value or IFD is pinned.
+func (ibe *IfdByteEncoder) hasPins() bool {
+	return len(ibe.pinnedValues) > 0 || len(ibe.pinnedIfds) > 0
+}
+
+// getPinnedValueOffset returns the offset that the value of the tag is pinned
+// to, if it is.
+func (ibe *IfdByteEncoder) getPinnedValueOffset(ib *IfdBuilder, bt *BuilderTag) (offset uint32, found bool) {
+	offset, found = ibe.pinnedValues[pinnedTag{ifdPath: ib.ifdPath, tagId: bt.tagId}]
+	return offset, found
+}
+
+// getPinnedIfdOffset returns the offset that the child IFD is pinned to, if it
+// is.
+func (ibe *IfdByteEncoder) getPinnedIfdOffset(childIb *IfdBuilder) (offset uint32, found bool) {
+	offset, found = ibe.pinnedIfds[childIb.ifdPath]
+	return offset, found
+}
+
+// addPin records a pinned value or IFD that was encountered while writing
+// (rather than sizing) an IFD.
+func (ibe *IfdByteEncoder) addPin(pin encoderPin) {
+	ibe.pins = append(ibe.pins, pin)
+}
+
+// encodePinnedIfds encodes the pinned IFDs that were encountered, including
+// any that are pinned within them.
+func (ibe *IfdByteEncoder) encodePinnedIfds() {
+	for i := 0; i < len(ibe.pins); i++ {
+		if ibe.pins[i].ib == nil {
+			continue
+		}
+
+		data, err := ibe.encodeAndAttachIfd(ibe.pins[i].ib, ibe.pins[i].offset)
+		log.PanicIf(err)
+
+		ibe.pins[i].data = data
+	}
+}
+
+// encodeTail returns the bytes to append after the IFDs, given the offset
+// where they'll start: the slack (see `SetSlack()`) and the pinned values and
+// IFDs, in order of offset.
+func (ibe *IfdByteEncoder) encodeTail(start uint32) []byte {
+	ibe.encodePinnedIfds()
+
+	type tailItem struct {
+		offset      uint32
+		data        []byte
+		description string
+
+		// isPinned is false for slack, which is appended instead if its
+		// offset is taken.
+		isPinned bool
+	}
+
+	items := make([]tailItem, 0, len(ibe.slack)+len(ibe.pins))
+
+	for _, sr := range ibe.slack {
+		items = append(items, tailItem{offset: sr.Offset, data: sr.Data})
+	}
+
+	for _, pin := range ibe.pins {
+		items = append(items, tailItem{offset: pin.offset, data: pin.data, description: pin.description, isPinned: true})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].offset < items[j].offset
+	})
+
+	b := make([]byte, 0)
+	offset := start
+
+	for _, item := range items {
+		if item.offset >= offset {
+			b = append(b, make([]byte, item.offset-offset)...)
+			offset = item.offset
+		} else if item.isPinned == true {
+			ifdBuilderLogger.Warningf(nil, "%s is pinned at (0x%08x) but what comes before it ends at (0x%08x).", item.description, item.offset, offset)
+			log.Panic(ErrPinnedOffsetNotAvailable)
+		} else if padding := (ibe.valueAlignment - offset%ibe.valueAlignment) % ibe.valueAlignment; padding > 0 {
+			b = append(b, make([]byte, padding)...)
+			offset += padding
+		}
+
+		b = append(b, item.data...)
+		offset += uint32(len(item.data))
+	}
+
+	return b
+}
@@ -0,0 +1,113 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	testMakerNoteTagId = 0x927c
+)
+
+func getTestPinnedOffset(rootIb *IfdBuilder) uint32 {
+	size, err := NewIfdByteEncoder().EncodedSize(rootIb)
+	log.PanicIf(err)
+
+	// Somewhere past everything else.
+	return size + 0x100
+}
+
+func getTestMakerNoteEntry(index IfdIndex) *IfdTagEntry {
+	results, err := index.Lookup[exifcommon.IfdPathStandardExif][0].FindTagWithId(testMakerNoteTagId)
+	log.PanicIf(err)
+
+	return results[0]
+}
+
+func TestIfdByteEncoder_PinValue(t *testing.T) {
+	rootIb, originalThumbnail := getTestRealIb()
+
+	_, originalIndex, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	originalMakerNote, err := getTestMakerNoteEntry(originalIndex).rawView()
+	log.PanicIf(err)
+
+	pinnedOffset := getTestPinnedOffset(rootIb)
+
+	ibe := NewIfdByteEncoder()
+	ibe.PinValue(exifcommon.IfdPathStandardExif, testMakerNoteTagId, pinnedOffset)
+
+	encoded, err := ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	size, err := ibe.EncodedSize(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), encoded)
+	log.PanicIf(err)
+
+	makerNote := getTestMakerNoteEntry(index)
+
+	raw, err := makerNote.rawView()
+	log.PanicIf(err)
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	if makerNote.ValueLocation() != pinnedOffset {
+		t.Fatalf("Maker note not at the pinned offset: (0x%08x) != (0x%08x)", makerNote.ValueLocation(), pinnedOffset)
+	} else if bytes.Equal(raw, originalMakerNote) != true {
+		t.Fatalf("Maker note not correct.")
+	} else if uint32(len(encoded)) != pinnedOffset+uint32(len(originalMakerNote)) || size != uint32(len(encoded)) {
+		t.Fatalf("Size not correct: (%d) (%d)", len(encoded), size)
+	} else if bytes.Equal(thumbnail, originalThumbnail) != true {
+		t.Fatalf("Thumbnail not correct.")
+	}
+}
+
+func TestIfdByteEncoder_PinIfd(t *testing.T) {
+	rootIb, _ := getTestRealIb()
+
+	pinnedOffset := getTestPinnedOffset(rootIb)
+
+	ibe := NewIfdByteEncoder()
+	ibe.PinIfd(exifcommon.IfdPathStandardExif, pinnedOffset)
+
+	encoded, err := ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), encoded)
+	log.PanicIf(err)
+
+	exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+	if exifIfd.Offset != pinnedOffset {
+		t.Fatalf("Exif IFD not at the pinned offset: (0x%08x) != (0x%08x)", exifIfd.Offset, pinnedOffset)
+	} else if len(index.Ifds) != 5 {
+		t.Fatalf("IFD count not correct: (%d)", len(index.Ifds))
+	}
+
+	// Its values and its own child IFD come with it.
+
+	if getTestMakerNoteEntry(index).ValueLocation() < pinnedOffset {
+		t.Fatalf("Maker note not after the pinned IFD.")
+	} else if iopIfd := index.Lookup[exifcommon.IfdPathStandardExifIop][0]; iopIfd.Offset < pinnedOffset {
+		t.Fatalf("Interoperability IFD not after the pinned IFD: (0x%08x)", iopIfd.Offset)
+	}
+}
+
+func TestIfdByteEncoder_PinValue_NotAvailable(t *testing.T) {
+	rootIb, _ := getTestRealIb()
+
+	ibe := NewIfdByteEncoder()
+	ibe.PinValue(exifcommon.IfdPathStandardExif, testMakerNoteTagId, ExifDefaultFirstIfdOffset)
+
+	if _, err := ibe.EncodeToExif(rootIb); log.Is(err, ErrPinnedOffsetNotAvailable) == false {
+		t.Fatalf("Expected pinned-offset error: %v", err)
+	}
+}
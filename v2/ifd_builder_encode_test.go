@@ -892,3 +892,237 @@ func ExampleIfdByteEncoder_EncodeToExif() {
 	// 4: IfdTagEntry<TAG-IFD-PATH=[IFD] TAG-ID=(0x013e) TAG-TYPE=[RATIONAL] UNIT-COUNT=(1)> [[{286335522 858997828}]]
 	// 5: IfdTagEntry<TAG-IFD-PATH=[IFD] TAG-ID=(0x9201) TAG-TYPE=[SRATIONAL] UNIT-COUNT=(1)> [[{286335522 858997828}]]
 }
+
+func getTestRealIb() (rootIb *IfdBuilder, originalThumbnail []byte) {
+	rawExif, err := SearchFileAndExtractExif(getTestImageFilepath())
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	originalThumbnail, err = index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	rootIb = NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	return rootIb, originalThumbnail
+}
+
+func Test_IfdByteEncoder_SetValueAlignment(t *testing.T) {
+	for _, alignment := range []uint32{2, 4} {
+		rootIb, originalThumbnail := getTestRealIb()
+
+		ibe := NewIfdByteEncoder()
+		ibe.SetValueAlignment(alignment)
+
+		encoded, err := ibe.EncodeToExif(rootIb)
+		log.PanicIf(err)
+
+		_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), encoded)
+		log.PanicIf(err)
+
+		if len(index.Ifds) != 5 {
+			t.Fatalf("IFD count not correct with alignment (%d): (%d)", alignment, len(index.Ifds))
+		}
+
+		for _, ifd := range index.Ifds {
+			if ifd.Offset%alignment != 0 {
+				t.Fatalf("IFD [%s] not aligned to (%d): (0x%08x)", ifd.FqIfdPath, alignment, ifd.Offset)
+			}
+
+			for _, ite := range ifd.Entries {
+				tagType := ite.TagType()
+				if tagType == exifcommon.TypeUndefined {
+					tagType = exifcommon.TypeByte
+				}
+
+				if uint32(tagType.Size())*ite.UnitCount() <= 4 {
+					continue
+				}
+
+				if ite.getValueOffset()%alignment != 0 {
+					t.Fatalf("Tag (0x%04x) in [%s] not aligned to (%d): (0x%08x)", ite.TagId(), ifd.FqIfdPath, alignment, ite.getValueOffset())
+				}
+			}
+		}
+
+		thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+		log.PanicIf(err)
+
+		if bytes.Equal(thumbnail, originalThumbnail) == false {
+			t.Fatalf("Thumbnail not correct with alignment (%d).", alignment)
+		}
+	}
+}
+
+func Test_IfdByteEncoder_SetValueAlignment_Invalid(t *testing.T) {
+	defer func() {
+		if state := recover(); state == nil {
+			t.Fatalf("Expected panic for invalid alignment.")
+		}
+	}()
+
+	NewIfdByteEncoder().SetValueAlignment(3)
+}
+
+func Test_IfdByteEncoder_SetThumbnailPlacement_End(t *testing.T) {
+	rootIb, originalThumbnail := getTestRealIb()
+
+	ibe := NewIfdByteEncoder()
+	ibe.SetValueAlignment(2)
+	ibe.SetThumbnailPlacement(ThumbnailPlacementEnd)
+
+	encoded, err := ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	if bytes.HasSuffix(encoded, originalThumbnail) == false {
+		t.Fatalf("Thumbnail not at the end.")
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), encoded)
+	log.PanicIf(err)
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	if bytes.Equal(thumbnail, originalThumbnail) == false {
+		t.Fatalf("Thumbnail not correct.")
+	}
+}
+
+func Test_IfdByteEncoder_EncodedSize(t *testing.T) {
+	for _, alignment := range []uint32{1, 2, 4} {
+		for _, placement := range []ThumbnailPlacement{ThumbnailPlacementInline, ThumbnailPlacementEnd} {
+			rootIb, _ := getTestRealIb()
+
+			ibe := NewIfdByteEncoder()
+			ibe.SetValueAlignment(alignment)
+			ibe.SetThumbnailPlacement(placement)
+
+			size, err := ibe.EncodedSize(rootIb)
+			log.PanicIf(err)
+
+			encoded, err := ibe.EncodeToExif(rootIb)
+			log.PanicIf(err)
+
+			if size != uint32(len(encoded)) {
+				t.Fatalf("Encoded size not correct with alignment (%d) and placement (%d): (%d) != (%d)", alignment, placement, size, len(encoded))
+			}
+		}
+	}
+}
+
+func Test_IfdByteEncoder_SetDeduplicateAscii(t *testing.T) {
+	for _, alignment := range []uint32{1, 4} {
+		for _, placement := range []ThumbnailPlacement{ThumbnailPlacementInline, ThumbnailPlacementEnd} {
+			rootIb, _ := getTestRealIb()
+
+			ibe := NewIfdByteEncoder()
+			ibe.SetValueAlignment(alignment)
+			ibe.SetThumbnailPlacement(placement)
+
+			plain, err := ibe.EncodeToExif(rootIb)
+			log.PanicIf(err)
+
+			ibe.SetDeduplicateAscii(true)
+
+			size, err := ibe.EncodedSize(rootIb)
+			log.PanicIf(err)
+
+			deduplicated, err := ibe.EncodeToExif(rootIb)
+			log.PanicIf(err)
+
+			md, err := DiffMetadata(plain, deduplicated)
+			log.PanicIf(err)
+
+			if size != uint32(len(deduplicated)) {
+				t.Fatalf("Encoded size not correct with alignment (%d) and placement (%d): (%d) != (%d)", alignment, placement, size, len(deduplicated))
+			} else if len(deduplicated) >= len(plain) {
+				t.Fatalf("Nothing deduplicated with alignment (%d) and placement (%d): (%d) >= (%d)", alignment, placement, len(deduplicated), len(plain))
+			} else if len(md.Tags) != 0 {
+				t.Fatalf("Values changed with alignment (%d) and placement (%d): %v", alignment, placement, md.Tags)
+			}
+		}
+	}
+}
+
+func Test_IfdByteEncoder_EncodedSize_Slack(t *testing.T) {
+	rawExif, _, _ := getTestExifDataWithSlack()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	el, err := GetExifLayout(rawExif, index)
+	log.PanicIf(err)
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	ibe := NewIfdByteEncoder()
+	ibe.SetValueAlignment(4)
+	ibe.SetSlack(el.Slack(rawExif))
+
+	size, err := ibe.EncodedSize(rootIb)
+	log.PanicIf(err)
+
+	encoded, err := ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	if size != uint32(len(encoded)) {
+		t.Fatalf("Encoded size not correct with slack: (%d) != (%d)", size, len(encoded))
+	}
+}
+
+func Test_IfdByteEncoder_SetSlack(t *testing.T) {
+	rawExif, slackOffset, slackData := getTestExifDataWithSlack()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	el, err := GetExifLayout(rawExif, index)
+	log.PanicIf(err)
+
+	slack := el.Slack(rawExif)
+
+	// Encoded on its own, the IFD is small enough for the slack to keep its
+	// original offset.
+
+	ib := NewIfdBuilder(NewIfdMappingWithStandard(), NewTagIndex(), exifcommon.IfdPathStandard, index.RootIfd.ByteOrder)
+
+	err = ib.AddStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	ibe := NewIfdByteEncoder()
+	ibe.SetSlack(slack)
+
+	encoded, err := ibe.EncodeToExif(ib)
+	log.PanicIf(err)
+
+	if bytes.Equal(encoded[slackOffset:slackOffset+uint32(len(slackData))], slackData) == false {
+		t.Fatalf("Slack not at its original offset.")
+	}
+
+	_, _, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), encoded)
+	log.PanicIf(err)
+
+	// The full chain is larger than the original offset, so the slack is
+	// appended.
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	ibe = NewIfdByteEncoder()
+	ibe.SetSlack(slack)
+
+	encoded, err = ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	if bytes.HasSuffix(encoded, slack[0].Data) == false {
+		t.Fatalf("Slack not appended.")
+	}
+
+	_, recoveredIndex, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), encoded)
+	log.PanicIf(err)
+
+	if len(recoveredIndex.Ifds) != 5 {
+		t.Fatalf("IFD count not correct: (%d)", len(recoveredIndex.Ifds))
+	}
+}
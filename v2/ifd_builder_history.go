@@ -0,0 +1,108 @@
+package exif
+
+import (
+	"errors"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// ErrNoTagHistory means that a tag has no earlier value to revert to.
+	ErrNoTagHistory = errors.New("tag has no history")
+)
+
+// SetHistoryEnabled determines whether the values of tags that are replaced
+// (e.g. by `Set()`, `SetStandard()`, or `Replace()`) are kept so that they can
+// be reverted with `RevertTag()`. It's off by default. It applies to this
+// builder, the builders of its child IFDs and of the IFDs chained after it,
+// and the builders that are created from them later. Disabling it drops the
+// history.
+func (ib *IfdBuilder) SetHistoryEnabled(enabled bool) {
+	for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+		thisIb.historyEnabled = enabled
+
+		if enabled == false {
+			thisIb.history = nil
+		}
+
+		for _, bt := range thisIb.tags {
+			if bt.value.IsIb() == true {
+				bt.value.Ib().SetHistoryEnabled(enabled)
+			}
+		}
+	}
+}
+
+// TagHistory returns the earlier values of the tag, oldest first. They're
+// copies of the tags as they were when they were replaced.
+func (ib *IfdBuilder) TagHistory(tagId uint16) []*BuilderTag {
+	history := ib.history[tagId]
+
+	tags := make([]*BuilderTag, len(history))
+	copy(tags, history)
+
+	return tags
+}
+
+// RevertTag sets the tag back to its most recent earlier value, which is then
+// dropped from the history, and returns it. The tag is added again if it was
+// deleted since. `ErrNoTagHistory` is returned if there's no earlier value.
+func (ib *IfdBuilder) RevertTag(tagId uint16) (bt *BuilderTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	history := ib.history[tagId]
+	if len(history) == 0 {
+		return nil, ErrNoTagHistory
+	}
+
+	previous := *history[len(history)-1]
+	bt = &previous
+
+	ib.history[tagId] = history[:len(history)-1]
+
+	// The value that's reverted from isn't kept, so that reverting repeatedly
+	// walks back through the history.
+
+	ib.reverting = true
+	defer func() {
+		ib.reverting = false
+	}()
+
+	position, err := ib.Find(tagId)
+	if err == nil {
+		ib.replaceTag(position, bt)
+	} else if log.Is(err, ErrTagEntryNotFound) == true {
+		ib.appendTag(bt)
+	} else {
+		log.Panic(err)
+	}
+
+	return bt, nil
+}
+
+// ClearTagHistory drops the earlier values of every tag (e.g. once the
+// changes are saved).
+func (ib *IfdBuilder) ClearTagHistory() {
+	ib.history = nil
+}
+
+// recordHistory keeps a copy of a tag that's being replaced, if history is
+// enabled.
+func (ib *IfdBuilder) recordHistory(oldBt *BuilderTag) {
+	if ib.historyEnabled == false || ib.reverting == true {
+		return
+	}
+
+	if ib.history == nil {
+		ib.history = make(map[uint16][]*BuilderTag)
+	}
+
+	// `(*BuilderTag).SetValue()` changes tags in place, so the tag is copied.
+	previous := *oldBt
+
+	ib.history[oldBt.tagId] = append(ib.history[oldBt.tagId], &previous)
+}
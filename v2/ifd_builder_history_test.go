@@ -0,0 +1,142 @@
+package exif
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// getTestBuilderTagString returns the value of an ASCII builder tag.
+func getTestBuilderTagString(bt *BuilderTag) string {
+	return strings.TrimRight(string(bt.Value().Bytes()), "\x00")
+}
+
+func TestIfdBuilder_SetHistoryEnabled(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ib := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+	ib.SetHistoryEnabled(true)
+
+	for _, make_ := range []string{"Canon", "Nikon", "Sony"} {
+		err := ib.SetStandardWithName("Make", make_)
+		log.PanicIf(err)
+	}
+
+	history := ib.TagHistory(0x010f)
+	if len(history) != 2 || getTestBuilderTagString(history[0]) != "Canon" || getTestBuilderTagString(history[1]) != "Nikon" {
+		t.Fatalf("History not correct: %v", history)
+	}
+
+	bt, err := ib.RevertTag(0x010f)
+	log.PanicIf(err)
+
+	if getTestBuilderTagString(bt) != "Nikon" {
+		t.Fatalf("Reverted tag not correct: %v", bt)
+	} else if current, err := ib.FindTagWithName("Make"); err != nil || getTestBuilderTagString(current) != "Nikon" {
+		t.Fatalf("Tag not reverted: %v", current)
+	} else if len(ib.TagHistory(0x010f)) != 1 {
+		t.Fatalf("Reverting shouldn't add to the history: %v", ib.TagHistory(0x010f))
+	}
+
+	_, err = ib.RevertTag(0x010f)
+	log.PanicIf(err)
+
+	if current, err := ib.FindTagWithName("Make"); err != nil || getTestBuilderTagString(current) != "Canon" {
+		t.Fatalf("Tag not reverted to the first value: %v", current)
+	} else if _, err := ib.RevertTag(0x010f); err != ErrNoTagHistory {
+		t.Fatalf("Expected ErrNoTagHistory: %v", err)
+	}
+}
+
+func TestIfdBuilder_SetHistoryEnabled_Disabled(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ib := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := ib.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	err = ib.SetStandardWithName("Make", "Nikon")
+	log.PanicIf(err)
+
+	if len(ib.TagHistory(0x010f)) != 0 {
+		t.Fatalf("History kept by default.")
+	}
+
+	ib.SetHistoryEnabled(true)
+
+	err = ib.SetStandardWithName("Make", "Sony")
+	log.PanicIf(err)
+
+	ib.SetHistoryEnabled(false)
+
+	if len(ib.TagHistory(0x010f)) != 0 {
+		t.Fatalf("History not dropped when disabled.")
+	}
+}
+
+func TestIfdBuilder_RevertTag_Deleted(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ib := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+	ib.SetHistoryEnabled(true)
+
+	err := ib.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	err = ib.SetStandardWithName("Make", "Nikon")
+	log.PanicIf(err)
+
+	err = ib.DeleteFirst(0x010f)
+	log.PanicIf(err)
+
+	_, err = ib.RevertTag(0x010f)
+	log.PanicIf(err)
+
+	if current, err := ib.FindTagWithName("Make"); err != nil || getTestBuilderTagString(current) != "Canon" {
+		t.Fatalf("Deleted tag not restored: %v", current)
+	}
+
+	ib.ClearTagHistory()
+
+	if _, err := ib.RevertTag(0x010f); err != ErrNoTagHistory {
+		t.Fatalf("Expected ErrNoTagHistory after clearing: %v", err)
+	}
+}
+
+func TestIfdBuilder_SetHistoryEnabled_Children(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, "IFD/Exif")
+	log.PanicIf(err)
+
+	rootIb.SetHistoryEnabled(true)
+
+	gpsIb, err := GetOrCreateIbFromRootIb(rootIb, "IFD/GPSInfo")
+	log.PanicIf(err)
+
+	for _, ib := range []*IfdBuilder{exifIb, gpsIb} {
+		if ib.historyEnabled != true {
+			t.Fatalf("History not enabled for [%s].", ib.fqIfdPath)
+		}
+	}
+
+	err = exifIb.SetStandardWithName("ISOSpeedRatings", []uint16{100})
+	log.PanicIf(err)
+
+	err = exifIb.SetStandardWithName("ISOSpeedRatings", []uint16{200})
+	log.PanicIf(err)
+
+	if len(exifIb.TagHistory(0x8827)) != 1 {
+		t.Fatalf("Child history not kept: %v", exifIb.TagHistory(0x8827))
+	}
+}
@@ -0,0 +1,139 @@
+package exif
+
+// BuilderChange is the kind of change that was made to the tags of a builder.
+type BuilderChange int
+
+const (
+	// BuilderChangeAdd means that a tag was added.
+	BuilderChangeAdd BuilderChange = iota
+
+	// BuilderChangeReplace means that a tag was replaced with another.
+	BuilderChangeReplace
+
+	// BuilderChangeDelete means that a tag was removed.
+	BuilderChangeDelete
+)
+
+// String returns the name of the change.
+func (bc BuilderChange) String() string {
+	switch bc {
+	case BuilderChangeAdd:
+		return "add"
+	case BuilderChangeReplace:
+		return "replace"
+	case BuilderChangeDelete:
+		return "delete"
+	}
+
+	return ""
+}
+
+// BuilderEvent describes a change to the tags of a builder.
+type BuilderEvent struct {
+	Change BuilderChange
+
+	// Ib is the builder that was changed and Position is the index of the tag
+	// in it. For deletes, this is where the tag was before it was removed.
+	Ib       *IfdBuilder
+	Position int
+
+	// OldTag is the tag that was replaced or removed (nil for adds). NewTag is
+	// the tag that was added or that replaced it (nil for deletes).
+	OldTag *BuilderTag
+	NewTag *BuilderTag
+}
+
+// TagId returns the ID of the tag that was changed.
+func (be BuilderEvent) TagId() uint16 {
+	if be.NewTag != nil {
+		return be.NewTag.tagId
+	}
+
+	return be.OldTag.tagId
+}
+
+// IfdBuilderListener is notified after the tags of a builder are changed.
+type IfdBuilderListener interface {
+	TagChanged(event BuilderEvent)
+}
+
+// IfdBuilderListenerFunc is a function that can be used as an
+// `IfdBuilderListener`.
+type IfdBuilderListenerFunc func(event BuilderEvent)
+
+// TagChanged calls the function.
+func (f IfdBuilderListenerFunc) TagChanged(event BuilderEvent) {
+	f(event)
+}
+
+// AddListener has the given listener notified whenever a tag is added,
+// replaced, or deleted. It's added to this builder, the builders of its child
+// IFDs and of the IFDs chained after it, and the builders that are created
+// from them later by `GetOrCreateIbFromRootIb()` and `AppendPage()`.
+func (ib *IfdBuilder) AddListener(listener IfdBuilderListener) {
+	for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+		thisIb.listeners = append(thisIb.listeners, listener)
+
+		for _, bt := range thisIb.tags {
+			if bt.value.IsIb() == true {
+				bt.value.Ib().AddListener(listener)
+			}
+		}
+	}
+}
+
+// inheritSettings copies the settings of the given builder, which this one
+// was created from.
+func (ib *IfdBuilder) inheritSettings(fromIb *IfdBuilder) {
+	ib.asciiPolicy = fromIb.asciiPolicy
+	ib.utf8 = fromIb.utf8
+	ib.unknownTagPolicy = fromIb.unknownTagPolicy
+	ib.historyEnabled = fromIb.historyEnabled
+
+	ib.listeners = make([]IfdBuilderListener, len(fromIb.listeners))
+	copy(ib.listeners, fromIb.listeners)
+}
+
+// notify passes the change to the listeners.
+func (ib *IfdBuilder) notify(change BuilderChange, position int, oldBt, newBt *BuilderTag) {
+	if len(ib.listeners) == 0 {
+		return
+	}
+
+	event := BuilderEvent{
+		Change:   change,
+		Ib:       ib,
+		Position: position,
+		OldTag:   oldBt,
+		NewTag:   newBt,
+	}
+
+	for _, listener := range ib.listeners {
+		listener.TagChanged(event)
+	}
+}
+
+// appendTag adds the tag to the end of the builder's tags. All changes to the
+// tags go through appendTag, replaceTag, and removeTag so that the listeners
+// see every one.
+func (ib *IfdBuilder) appendTag(bt *BuilderTag) {
+	ib.tags = append(ib.tags, bt)
+	ib.notify(BuilderChangeAdd, len(ib.tags)-1, nil, bt)
+}
+
+// replaceTag replaces the tag at the given position.
+func (ib *IfdBuilder) replaceTag(position int, bt *BuilderTag) {
+	oldBt := ib.tags[position]
+	ib.tags[position] = bt
+
+	ib.recordHistory(oldBt)
+	ib.notify(BuilderChangeReplace, position, oldBt, bt)
+}
+
+// removeTag removes the tag at the given position.
+func (ib *IfdBuilder) removeTag(position int) {
+	oldBt := ib.tags[position]
+	ib.tags = append(ib.tags[:position], ib.tags[position+1:]...)
+
+	ib.notify(BuilderChangeDelete, position, oldBt, nil)
+}
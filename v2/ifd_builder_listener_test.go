@@ -0,0 +1,135 @@
+package exif
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestBuilderChange_String(t *testing.T) {
+	if BuilderChangeAdd.String() != "add" {
+		t.Fatalf("Add name not correct: [%s]", BuilderChangeAdd)
+	} else if BuilderChangeReplace.String() != "replace" {
+		t.Fatalf("Replace name not correct: [%s]", BuilderChangeReplace)
+	} else if BuilderChangeDelete.String() != "delete" {
+		t.Fatalf("Delete name not correct: [%s]", BuilderChangeDelete)
+	}
+}
+
+func TestIfdBuilder_AddListener(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	events := make([]string, 0)
+	rootIb.AddListener(IfdBuilderListenerFunc(func(event BuilderEvent) {
+		events = append(events, fmt.Sprintf("%s %s 0x%04x (%d)", event.Change, event.Ib.fqIfdPath, event.TagId(), event.Position))
+	}))
+
+	err := rootIb.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("Model", "EOS")
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("Make", "Nikon")
+	log.PanicIf(err)
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, "IFD/Exif")
+	log.PanicIf(err)
+
+	err = exifIb.AddStandardWithName("ISOSpeedRatings", []uint16{100})
+	log.PanicIf(err)
+
+	err = rootIb.DeleteFirst(0x010f)
+	log.PanicIf(err)
+
+	expected := []string{
+		"add IFD 0x010f (0)",
+		"add IFD 0x0110 (1)",
+		"replace IFD 0x010f (0)",
+		"add IFD 0x8769 (2)",
+		"add IFD/Exif 0x8827 (0)",
+		"delete IFD 0x010f (0)",
+	}
+
+	if reflect.DeepEqual(events, expected) != true {
+		t.Fatalf("Events not correct: %v", events)
+	}
+}
+
+func TestIfdBuilder_AddListener_ExistingChildren(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, "IFD/Exif")
+	log.PanicIf(err)
+
+	pageIb, err := rootIb.AppendPage()
+	log.PanicIf(err)
+
+	var events []BuilderEvent
+	rootIb.AddListener(IfdBuilderListenerFunc(func(event BuilderEvent) {
+		events = append(events, event)
+	}))
+
+	err = exifIb.SetStandardWithName("ISOSpeedRatings", []uint16{100})
+	log.PanicIf(err)
+
+	err = pageIb.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	if len(events) != 2 {
+		t.Fatalf("Expected two events: %v", events)
+	} else if events[0].Ib != exifIb || events[0].Change != BuilderChangeAdd || events[0].OldTag != nil {
+		t.Fatalf("Child event not correct: %v", events[0])
+	} else if events[1].Ib != pageIb || events[1].NewTag.tagId != 0x010f {
+		t.Fatalf("Page event not correct: %v", events[1])
+	}
+}
+
+func TestIfdBuilder_AddListener_Replace(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ib := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := ib.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	originalBt, err := ib.FindTagWithName("Make")
+	log.PanicIf(err)
+
+	var events []BuilderEvent
+	ib.AddListener(IfdBuilderListenerFunc(func(event BuilderEvent) {
+		events = append(events, event)
+	}))
+
+	it, err := ti.GetWithName(exifcommon.IfdPathStandard, "Make")
+	log.PanicIf(err)
+
+	replacementBt := NewStandardBuilderTag(exifcommon.IfdPathStandard, it, exifcommon.TestDefaultByteOrder, "Nikon")
+
+	err = ib.Replace(0x010f, replacementBt)
+	log.PanicIf(err)
+
+	_, err = ib.DeleteAll(0x010f)
+	log.PanicIf(err)
+
+	if len(events) != 2 {
+		t.Fatalf("Expected two events: %v", events)
+	} else if events[0].Change != BuilderChangeReplace || events[0].OldTag != originalBt || events[0].NewTag != replacementBt {
+		t.Fatalf("Replace event not correct: %v", events[0])
+	} else if events[1].Change != BuilderChangeDelete || events[1].OldTag != replacementBt || events[1].NewTag != nil {
+		t.Fatalf("Delete event not correct: %v", events[1])
+	} else if events[1].TagId() != 0x010f {
+		t.Fatalf("Delete event tag not correct: (0x%04x)", events[1].TagId())
+	}
+}
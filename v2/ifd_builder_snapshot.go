@@ -0,0 +1,147 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// ErrSnapshotNotForBuilder means that a snapshot was restored to a
+	// different builder than the one that it was taken of.
+	ErrSnapshotNotForBuilder = errors.New("snapshot not taken of this builder")
+)
+
+// ibState is the state of one builder in a snapshot.
+type ibState struct {
+	ib *IfdBuilder
+
+	tags []*BuilderTag
+
+	// tagValues are copies of the tags, since `(*BuilderTag).SetValue()`
+	// changes them in place.
+	tagValues []BuilderTag
+
+	nextIb        *IfdBuilder
+	thumbnailData []byte
+}
+
+// IfdBuilderSnapshot is the state of a builder, the builders of its child
+// IFDs, and the builders of the IFDs chained after it at some point.
+type IfdBuilderSnapshot struct {
+	ib     *IfdBuilder
+	states []ibState
+}
+
+// Snapshot records the tags of this builder, of the builders of its child
+// IFDs, and of the builders of the IFDs chained after it so that they can be
+// restored with `Restore()`.
+func (ib *IfdBuilder) Snapshot() *IfdBuilderSnapshot {
+	snapshot := &IfdBuilderSnapshot{
+		ib:     ib,
+		states: make([]ibState, 0),
+	}
+
+	snapshot.add(ib)
+
+	return snapshot
+}
+
+func (snapshot *IfdBuilderSnapshot) add(ib *IfdBuilder) {
+	for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+		state := ibState{
+			ib:            thisIb,
+			tags:          make([]*BuilderTag, len(thisIb.tags)),
+			tagValues:     make([]BuilderTag, len(thisIb.tags)),
+			nextIb:        thisIb.nextIb,
+			thumbnailData: thisIb.thumbnailData,
+		}
+
+		copy(state.tags, thisIb.tags)
+
+		for i, bt := range thisIb.tags {
+			state.tagValues[i] = *bt
+		}
+
+		snapshot.states = append(snapshot.states, state)
+
+		for _, bt := range thisIb.tags {
+			if bt.value.IsIb() == true {
+				snapshot.add(bt.value.Ib())
+			}
+		}
+	}
+}
+
+// Restore returns this builder, the builders of its child IFDs, and the
+// builders of the IFDs chained after it to how they were when the snapshot
+// was taken. Child IFDs and chained IFDs that were added since are dropped.
+// The listeners are not notified.
+func (ib *IfdBuilder) Restore(snapshot *IfdBuilderSnapshot) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if snapshot.ib != ib {
+		log.Panic(ErrSnapshotNotForBuilder)
+	}
+
+	for _, state := range snapshot.states {
+		thisIb := state.ib
+
+		thisIb.tags = make([]*BuilderTag, len(state.tags))
+		copy(thisIb.tags, state.tags)
+
+		for i, bt := range state.tags {
+			*bt = state.tagValues[i]
+		}
+
+		thisIb.nextIb = state.nextIb
+		thisIb.thumbnailData = state.thumbnailData
+	}
+
+	return nil
+}
+
+// Transaction calls the given function to edit the builder and, if it returns
+// an error or panics, restores the builder (and its child and chained
+// builders) to how it was before. This is useful for applying a batch of
+// edits, any of which might fail validation, all-or-nothing.
+func (ib *IfdBuilder) Transaction(edit func(ib *IfdBuilder) error) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	snapshot := ib.Snapshot()
+
+	defer func() {
+		state := recover()
+		if state == nil && err == nil {
+			return
+		}
+
+		restoreErr := ib.Restore(snapshot)
+		log.PanicIf(restoreErr)
+
+		if state != nil {
+			stateErr, ok := state.(error)
+			if ok == false {
+				stateErr = fmt.Errorf("%v", state)
+			}
+
+			log.Panic(stateErr)
+		}
+	}()
+
+	err = edit(ib)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package exif
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func getTestSnapshotIb() *IfdBuilder {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("Model", "EOS")
+	log.PanicIf(err)
+
+	return rootIb
+}
+
+func TestIfdBuilder_Restore(t *testing.T) {
+	rootIb := getTestSnapshotIb()
+
+	originalDump := rootIb.DumpToStrings()
+
+	snapshot := rootIb.Snapshot()
+
+	err := rootIb.SetStandardWithName("Make", "Nikon")
+	log.PanicIf(err)
+
+	err = rootIb.DeleteFirst(0x0110)
+	log.PanicIf(err)
+
+	makeBt, err := rootIb.FindTagWithName("Make")
+	log.PanicIf(err)
+
+	err = makeBt.SetValue(exifcommon.TestDefaultByteOrder, "Fuji")
+	log.PanicIf(err)
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, "IFD/Exif")
+	log.PanicIf(err)
+
+	err = exifIb.SetStandardWithName("ISOSpeedRatings", []uint16{100})
+	log.PanicIf(err)
+
+	_, err = rootIb.AppendPage()
+	log.PanicIf(err)
+
+	err = rootIb.Restore(snapshot)
+	log.PanicIf(err)
+
+	if dump := rootIb.DumpToStrings(); len(dump) != len(originalDump) {
+		t.Fatalf("Builder not restored: %v", dump)
+	} else if rootIb.nextIb != nil {
+		t.Fatalf("Appended page not dropped.")
+	}
+
+	bt, err := rootIb.FindTagWithName("Make")
+	log.PanicIf(err)
+
+	if string(bt.Value().Bytes()) != "Canon\x00" {
+		t.Fatalf("Make not restored: %v", bt.Value().Bytes())
+	}
+
+	_, err = rootIb.FindTagWithName("Model")
+	log.PanicIf(err)
+}
+
+func TestIfdBuilder_Restore_ChildEdits(t *testing.T) {
+	rootIb := getTestSnapshotIb()
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, "IFD/Exif")
+	log.PanicIf(err)
+
+	err = exifIb.SetStandardWithName("ISOSpeedRatings", []uint16{100})
+	log.PanicIf(err)
+
+	snapshot := rootIb.Snapshot()
+
+	err = exifIb.SetStandardWithName("ISOSpeedRatings", []uint16{200})
+	log.PanicIf(err)
+
+	err = exifIb.SetStandardWithName("ExposureProgram", []uint16{2})
+	log.PanicIf(err)
+
+	err = rootIb.Restore(snapshot)
+	log.PanicIf(err)
+
+	if len(exifIb.Tags()) != 1 {
+		t.Fatalf("Child builder not restored: %v", exifIb.Tags())
+	}
+
+	bt, err := exifIb.FindTagWithName("ISOSpeedRatings")
+	log.PanicIf(err)
+
+	if string(bt.Value().Bytes()) != string([]byte{0, 100}) {
+		t.Fatalf("ISOSpeedRatings not restored: %v", bt.Value().Bytes())
+	}
+}
+
+func TestIfdBuilder_Restore_WrongBuilder(t *testing.T) {
+	rootIb := getTestSnapshotIb()
+	otherIb := getTestSnapshotIb()
+
+	err := otherIb.Restore(rootIb.Snapshot())
+	if log.Is(err, ErrSnapshotNotForBuilder) == false {
+		t.Fatalf("Expected ErrSnapshotNotForBuilder: %v", err)
+	}
+}
+
+func TestIfdBuilder_Transaction_Commit(t *testing.T) {
+	rootIb := getTestSnapshotIb()
+
+	err := rootIb.Transaction(func(ib *IfdBuilder) error {
+		err := ib.SetStandardWithName("Make", "Nikon")
+		log.PanicIf(err)
+
+		return ib.SetStandardWithName("Artist", "Someone")
+	})
+
+	log.PanicIf(err)
+
+	if len(rootIb.Tags()) != 3 {
+		t.Fatalf("Edits not applied: %v", rootIb.Tags())
+	}
+
+	bt, err := rootIb.FindTagWithName("Make")
+	log.PanicIf(err)
+
+	if string(bt.Value().Bytes()) != "Nikon\x00" {
+		t.Fatalf("Make not set: %v", bt.Value().Bytes())
+	}
+}
+
+func TestIfdBuilder_Transaction_Rollback(t *testing.T) {
+	rootIb := getTestSnapshotIb()
+
+	err := rootIb.Transaction(func(ib *IfdBuilder) error {
+		err := ib.SetStandardWithName("Make", "Nikon")
+		log.PanicIf(err)
+
+		err = ib.SetStandardWithName("Artist", "Someone")
+		log.PanicIf(err)
+
+		// Not a tag.
+		return ib.SetStandardWithName("NoSuchTag", "value")
+	})
+
+	if err == nil {
+		t.Fatalf("Expected error.")
+	} else if len(rootIb.Tags()) != 2 {
+		t.Fatalf("Edits not rolled back: %v", rootIb.Tags())
+	}
+
+	bt, err := rootIb.FindTagWithName("Make")
+	log.PanicIf(err)
+
+	if string(bt.Value().Bytes()) != "Canon\x00" {
+		t.Fatalf("Make not rolled back: %v", bt.Value().Bytes())
+	}
+}
+
+func TestIfdBuilder_Transaction_Panic(t *testing.T) {
+	rootIb := getTestSnapshotIb()
+
+	errEdit := errors.New("edit failed")
+
+	err := rootIb.Transaction(func(ib *IfdBuilder) error {
+		err := ib.DeleteFirst(0x010f)
+		log.PanicIf(err)
+
+		log.Panic(errEdit)
+		return nil
+	})
+
+	if log.Is(err, errEdit) == false {
+		t.Fatalf("Expected the edit's error: %v", err)
+	} else if len(rootIb.Tags()) != 2 {
+		t.Fatalf("Edits not rolled back: %v", rootIb.Tags())
+	}
+}
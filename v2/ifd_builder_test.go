@@ -2289,3 +2289,337 @@ func TestGetOrCreateIbFromRootIb__Child(t *testing.T) {
 		t.Fatalf("Constructed IFDs not correct.")
 	}
 }
+
+func TestIfdBuilder_ResetOrientation(t *testing.T) {
+	rawExif, err := SearchFileAndExtractExif(getTestImageFilepath())
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	_, index, err := Collect(im, ti, rawExif)
+	log.PanicIf(err)
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	err = rootIb.SetStandard(OrientationTagId, []uint16{OrientationRightTop})
+	log.PanicIf(err)
+
+	err = rootIb.ResetOrientation()
+	log.PanicIf(err)
+
+	bt, err := rootIb.FindTag(OrientationTagId)
+	log.PanicIf(err)
+
+	value := bt.Value().Bytes()
+	if value[0] != 1 || value[1] != 0 {
+		t.Fatalf("Orientation not reset: %v", value)
+	}
+
+	exifIb, err := rootIb.ChildWithTagId(exifcommon.IfdExifId)
+	log.PanicIf(err)
+
+	err = exifIb.ResetOrientation()
+	if err == nil {
+		t.Fatalf("Expected error for non-root IFD.")
+	}
+}
+
+func TestIfdBuilder_EncodedSize(t *testing.T) {
+	rawExif, err := SearchFileAndExtractExif(getTestImageFilepath())
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	_, index, err := Collect(im, ti, rawExif)
+	log.PanicIf(err)
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	size, err := rootIb.EncodedSize()
+	log.PanicIf(err)
+
+	ibe := NewIfdByteEncoder()
+
+	encoded, err := ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	if size != uint32(len(encoded)) {
+		t.Fatalf("Encoded size not correct: (%d) != (%d)", size, len(encoded))
+	}
+
+	// Small values are stored in the entry and don't add to the size.
+
+	err = rootIb.SetStandardWithName("Make", "ABC")
+	log.PanicIf(err)
+
+	smallerSize, err := rootIb.EncodedSize()
+	log.PanicIf(err)
+
+	if smallerSize != size-6 {
+		t.Fatalf("Encoded size not correct after update: (%d) != (%d)", smallerSize, size-6)
+	}
+
+	encoded, err = ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	if smallerSize != uint32(len(encoded)) {
+		t.Fatalf("Encoded size not correct after encoding: (%d) != (%d)", smallerSize, len(encoded))
+	}
+}
+
+func TestIfdBuilder_EncodedSize_Empty(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ib := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	size, err := ib.EncodedSize()
+	log.PanicIf(err)
+
+	if size != ExifDefaultFirstIfdOffset+6 {
+		t.Fatalf("Encoded size not correct: (%d)", size)
+	}
+}
+
+func TestIfdBuilder_AppendPage(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("ImageDescription", "page 1")
+	log.PanicIf(err)
+
+	for _, description := range []string{"page 2", "page 3"} {
+		pageIb, err := rootIb.AppendPage()
+		log.PanicIf(err)
+
+		err = pageIb.SetStandardWithName("ImageDescription", description)
+		log.PanicIf(err)
+	}
+
+	if len(rootIb.Pages()) != 3 {
+		t.Fatalf("Page count not correct: (%d)", len(rootIb.Pages()))
+	}
+
+	// Pages can have their own child IFDs.
+	exifIb := NewIfdBuilder(im, ti, "IFD2/Exif", exifcommon.TestDefaultByteOrder)
+
+	err = exifIb.SetStandardWithName("DateTimeOriginal", "2020:01:02 03:04:05")
+	log.PanicIf(err)
+
+	err = rootIb.Pages()[2].AddChildIb(exifIb)
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	pages := index.Pages()
+	if len(pages) != 3 {
+		t.Fatalf("Collected page count not correct: (%d)", len(pages))
+	}
+
+	for i, ifd := range pages {
+		expectedFqIfdPath := "IFD"
+		if i > 0 {
+			expectedFqIfdPath = fmt.Sprintf("IFD%d", i)
+		}
+
+		description, err := getIfdTagString(ifd, "ImageDescription")
+		log.PanicIf(err)
+
+		if ifd.FqIfdPath != expectedFqIfdPath {
+			t.Fatalf("Page (%d) path not correct: [%s]", i, ifd.FqIfdPath)
+		} else if description != fmt.Sprintf("page %d", i+1) {
+			t.Fatalf("Page (%d) description not correct: [%s]", i, description)
+		}
+	}
+
+	pageIfds := index.PageIfds(2)
+	if len(pageIfds) != 2 || pageIfds[1].IfdPath != exifcommon.IfdPathStandardExif {
+		t.Fatalf("Third page IFDs not correct: %v", pageIfds)
+	} else if len(index.PageIfds(1)) != 1 {
+		t.Fatalf("Second page should not have child IFDs.")
+	} else if index.PageIfds(3) != nil {
+		t.Fatalf("Expected nil for a page that doesn't exist.")
+	}
+}
+
+func TestIfdBuilder_AppendPage_NotFirst(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	pageIb, err := rootIb.AppendPage()
+	log.PanicIf(err)
+
+	_, err = pageIb.AppendPage()
+	if err == nil {
+		t.Fatalf("Expected error when appending via a later page.")
+	}
+}
+
+func TestIfdBuilder_SetAsciiPolicy(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("Make", "Canon  ")
+	log.PanicIf(err)
+
+	bt, err := rootIb.FindTagWithName("Make")
+	log.PanicIf(err)
+
+	if string(bt.value.Bytes()) != "Canon\x00" {
+		t.Fatalf("Default encoding not correct: [%q]", bt.value.Bytes())
+	}
+
+	rootIb.SetAsciiPolicy(exifcommon.AsciiPolicyVerbatim)
+
+	// Children inherit it.
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, "IFD/Exif")
+	log.PanicIf(err)
+
+	err = exifIb.SetStandardWithName("LensModel", "Lens ")
+	log.PanicIf(err)
+
+	bt, err = exifIb.FindTagWithName("LensModel")
+	log.PanicIf(err)
+
+	if string(bt.value.Bytes()) != "Lens \x00" {
+		t.Fatalf("Verbatim encoding not correct: [%q]", bt.value.Bytes())
+	}
+}
+
+func TestIfdBuilder_ReencodeStrings(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	// Written in ISO-8859-1 into an ASCII tag.
+	err := rootIb.SetStandardWithName("Artist", "caf\xe9")
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	rootIb.SetUtf8(true)
+
+	cd := exifcommon.NewCharsetDecoder(exifcommon.CharsetUtf8, exifcommon.CharsetLatin1)
+
+	err = rootIb.ReencodeStrings(cd)
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	expected := map[string]struct {
+		tagType exifcommon.TagTypePrimitive
+		value   string
+	}{
+		"Artist": {exifcommon.TypeUtf8, "café"},
+		"Make":   {exifcommon.TypeAscii, "Canon"},
+	}
+
+	for tagName, e := range expected {
+		results, err := index.RootIfd.FindTagWithName(tagName)
+		log.PanicIf(err)
+
+		value, err := results[0].Value()
+		log.PanicIf(err)
+
+		if results[0].TagType() != e.tagType {
+			t.Fatalf("Type of [%s] not correct: [%s]", tagName, results[0].TagType())
+		} else if value.(string) != e.value {
+			t.Fatalf("Value of [%s] not correct: [%s]", tagName, value)
+		}
+	}
+}
+
+func TestIfdBuilder_SetThumbnail_Replace(t *testing.T) {
+	rootIb, originalThumbnail := getTestRealIb()
+
+	ifd1Ib, err := rootIb.NextIb()
+	log.PanicIf(err)
+
+	replacement := []byte{0xff, 0xd8, 0x01, 0x02, 0x03, 0xff, 0xd9}
+
+	err = ifd1Ib.SetThumbnail(replacement)
+	log.PanicIf(err)
+
+	encoded, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), encoded)
+	log.PanicIf(err)
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	if bytes.Equal(thumbnail, replacement) != true {
+		t.Fatalf("Thumbnail not replaced: %v", thumbnail)
+	} else if len(encoded) >= len(originalThumbnail) {
+		t.Fatalf("Original thumbnail still written: (%d)", len(encoded))
+	}
+
+	// The tags were replaced rather than added.
+
+	for _, tagId := range []uint16{ThumbnailOffsetTagId, ThumbnailSizeTagId} {
+		if results, err := ifd1Ib.FindN(tagId, 2); err != nil || len(results) != 1 {
+			t.Fatalf("Tag (0x%04x) not replaced: %v %v", tagId, results, err)
+		}
+	}
+}
+
+func TestIfdBuilder_RemoveThumbnail(t *testing.T) {
+	rootIb, _ := getTestRealIb()
+
+	ifd1Ib, err := rootIb.NextIb()
+	log.PanicIf(err)
+
+	err = ifd1Ib.RemoveThumbnail()
+	log.PanicIf(err)
+
+	if ifd1Ib.Thumbnail() != nil {
+		t.Fatalf("Thumbnail not removed from builder.")
+	}
+
+	encoded, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), encoded)
+	log.PanicIf(err)
+
+	if _, err := index.RootIfd.NextIfd.Thumbnail(); log.Is(err, ErrNoThumbnail) == false {
+		t.Fatalf("Expected no thumbnail: %v", err)
+	} else if _, err := index.RootIfd.NextIfd.FindTagWithId(ThumbnailSizeTagId); log.Is(err, ErrTagNotFound) == false {
+		t.Fatalf("Thumbnail length tag not removed: %v", err)
+	}
+
+	// It's fine to do again.
+
+	err = ifd1Ib.RemoveThumbnail()
+	log.PanicIf(err)
+}
+
+func TestIfdBuilder_RemoveThumbnail_NotRoot(t *testing.T) {
+	rootIb, _ := getTestRealIb()
+
+	exifIb, err := rootIb.ChildWithTagId(exifcommon.IfdExifId)
+	log.PanicIf(err)
+
+	if err := exifIb.RemoveThumbnail(); err == nil {
+		t.Fatalf("Expected error for a child IFD.")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -32,6 +34,10 @@ var (
 
 	// ErrOffsetInvalid means that the file offset is not valid.
 	ErrOffsetInvalid = errors.New("file offset invalid")
+
+	// ErrIfdCycle means that an IFD refers back to one that was already
+	// parsed.
+	ErrIfdCycle = errors.New("ifd cycle")
 )
 
 var (
@@ -77,16 +83,22 @@ type IfdTagEnumerator struct {
 	addressableData []byte
 	ifdOffset       uint32
 	buffer          *bytes.Buffer
+
+	// windowOffset is where `addressableData` starts, if it's only the part
+	// of the data with the IFD (see `NewIfdEnumerateWithReader()`).
+	windowOffset uint32
 }
 
 func NewIfdTagEnumerator(addressableData []byte, byteOrder binary.ByteOrder, ifdOffset uint32) (enumerator *IfdTagEnumerator, err error) {
-	if ifdOffset >= uint32(len(addressableData)) {
+	// At least the tag-count has to be there.
+	if _, err := exifcommon.CheckedSlice(addressableData, ifdOffset, 2); err != nil {
 		return nil, ErrOffsetInvalid
 	}
 
 	enumerator = &IfdTagEnumerator{
 		addressableData: addressableData,
 		byteOrder:       byteOrder,
+		ifdOffset:       ifdOffset,
 		buffer:          bytes.NewBuffer(addressableData[ifdOffset:]),
 	}
 
@@ -97,19 +109,15 @@ func NewIfdTagEnumerator(addressableData []byte, byteOrder binary.ByteOrder, ifd
 // accumulator (which allows us to know how far to seek to the beginning of the
 // next IFD when it's time to jump).
 func (ife *IfdTagEnumerator) getUint16() (value uint16, raw []byte, err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
-
 	needBytes := 2
 	offset := 0
 	raw = make([]byte, needBytes)
 
 	for offset < needBytes {
 		n, err := ife.buffer.Read(raw[offset:])
-		log.PanicIf(err)
+		if err != nil {
+			return 0, nil, err
+		}
 
 		offset += n
 	}
@@ -123,19 +131,15 @@ func (ife *IfdTagEnumerator) getUint16() (value uint16, raw []byte, err error) {
 // accumulator (which allows us to know how far to seek to the beginning of the
 // next IFD when it's time to jump).
 func (ife *IfdTagEnumerator) getUint32() (value uint32, raw []byte, err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
-
 	needBytes := 4
 	offset := 0
 	raw = make([]byte, needBytes)
 
 	for offset < needBytes {
 		n, err := ife.buffer.Read(raw[offset:])
-		log.PanicIf(err)
+		if err != nil {
+			return 0, nil, err
+		}
 
 		offset += n
 	}
@@ -152,6 +156,66 @@ type IfdEnumerate struct {
 	currentOffset uint32
 	tagIndex      *TagIndex
 	ifdMapping    *IfdMapping
+
+	// fqIfdPaths, if not nil, are the only IFDs that `Collect()` parses
+	// (along with the IFDs that lead to them).
+	fqIfdPaths map[string]struct{}
+
+	// position is where we are, for the errors that we return.
+	position      parsePosition
+	recoverPanics bool
+
+	asciiPolicy    exifcommon.AsciiPolicy
+	charsetDecoder *exifcommon.CharsetDecoder
+
+	// ifdByteOrders and detectIfdByteOrder allow IFDs to be in a different
+	// byte order than the file.
+	ifdByteOrders      map[string]binary.ByteOrder
+	detectIfdByteOrder bool
+
+	// counters are what we've had to work around.
+	counters ParseCounters
+
+	// tolerant skips damaged data rather than failing, and records what was
+	// skipped in `warnings`.
+	tolerant bool
+	warnings []*ExifError
+
+	// reader and readerSize are read from instead of `exifData` if the data
+	// isn't in memory.
+	reader     io.ReaderAt
+	readerSize int64
+
+	// decodeConcurrency, if not zero, is how many IFDs `Collect()` decodes
+	// the values of at once.
+	decodeConcurrency int
+
+	// maxIfdDepth, if not zero, is how deeply child IFDs are followed.
+	maxIfdDepth int
+
+	// quirks are those that apply to the camera, which is identified by
+	// quirkMake and quirkModel once they're read (see `Quirk`). They're chosen
+	// from quirkCandidates, if not nil, rather than the registered quirks.
+	quirks          []Quirk
+	quirkCandidates []Quirk
+	quirksSelected  bool
+	quirksDisabled  bool
+	quirkMake       string
+	quirkModel      string
+
+	// unknownTagReporter, if not nil, is called with the entries whose tags
+	// aren't in the index (see `SetUnknownTagReporter()`).
+	unknownTagReporter   UnknownTagReporter
+	unknownTagSampleSize int
+
+	// fieldCodec, if not nil, restores the protected values of the tags that
+	// it selects (see `SetFieldCodec()`).
+	fieldCodec FieldCodec
+
+	// valueMiddleware transforms decoded values. It's the registered
+	// middleware unless `SetValueMiddleware()` was called.
+	valueMiddleware         []ValueMiddleware
+	valueMiddlewareSelected bool
 }
 
 func NewIfdEnumerate(ifdMapping *IfdMapping, tagIndex *TagIndex, exifData []byte, byteOrder binary.ByteOrder) *IfdEnumerate {
@@ -161,40 +225,211 @@ func NewIfdEnumerate(ifdMapping *IfdMapping, tagIndex *TagIndex, exifData []byte
 		byteOrder:  byteOrder,
 		ifdMapping: ifdMapping,
 		tagIndex:   tagIndex,
+
+		recoverPanics: true,
 	}
 }
 
-func (ie *IfdEnumerate) getTagEnumerator(ifdOffset uint32) (enumerator *IfdTagEnumerator, err error) {
+// NewIfdEnumerateWithReader returns an enumerator that reads the EXIF data
+// (starting with the TIFF header, like the data given to `NewIfdEnumerate()`)
+// from a reader of the given size instead of from memory. Only the IFD tables
+// and the thumbnail are read while enumerating, and values are only read when
+// they're asked for, so a few tags can be pulled from a large TIFF or RAW
+// file without reading all of it. The reader has to stay usable for as long
+// as the entries are.
+//
+// Whatever reads vendor maker-notes or SubIFDs from the index (e.g.
+// `GetFocusInfo()`) needs the data in memory and fails for entries that were
+// read this way.
+func NewIfdEnumerateWithReader(ifdMapping *IfdMapping, tagIndex *TagIndex, r io.ReaderAt, size int64, byteOrder binary.ByteOrder) *IfdEnumerate {
+	ie := NewIfdEnumerate(ifdMapping, tagIndex, nil, byteOrder)
+
+	ie.reader = r
+	ie.readerSize = size
+
+	return ie
+}
+
+// dataSize returns the size of the addressable data.
+func (ie *IfdEnumerate) dataSize() uint32 {
+	if ie.reader != nil {
+		if ie.readerSize > math.MaxUint32 {
+			return math.MaxUint32
+		}
+
+		return uint32(ie.readerSize)
+	}
+
+	return uint32(len(ie.exifData)) - ExifAddressableAreaStart
+}
+
+// readWindow returns up to `length` bytes at the offset from the reader. Less
+// is returned if the data ends first.
+func (ie *IfdEnumerate) readWindow(offset uint32, length uint32) (window []byte, err error) {
 	defer func() {
 		if state := recover(); state != nil {
 			err = log.Wrap(state.(error))
 		}
 	}()
 
-	enumerator, err =
-		NewIfdTagEnumerator(
-			ie.exifData[ExifAddressableAreaStart:],
-			ie.byteOrder,
-			ifdOffset)
+	available := ie.dataSize()
+	if offset >= available {
+		return nil, ErrOffsetInvalid
+	} else if length > available-offset {
+		length = available - offset
+	}
+
+	window = make([]byte, length)
 
+	n, err := ie.reader.ReadAt(window, int64(offset))
+	if err != nil && err != io.EOF {
+		log.Panic(err)
+	}
+
+	return window[:n], nil
+}
+
+// SetFqIfdPaths limits `Collect()` to the IFDs with the given fully-qualified
+// paths (e.g. "IFD" for IFD0, "IFD1", or "IFD/Exif") and the IFDs that have to
+// be parsed in order to reach them: their ancestors and, since later IFDs in
+// a chain are reached through the ones before them, their predecessors (e.g.
+// "IFD2" also parses IFD0 and IFD1). Everything else is skipped without being
+// read. A nil list parses everything.
+func (ie *IfdEnumerate) SetFqIfdPaths(fqIfdPaths []string) {
+	if fqIfdPaths == nil {
+		ie.fqIfdPaths = nil
+		return
+	}
+
+	ie.fqIfdPaths = make(map[string]struct{})
+	for _, fqIfdPath := range fqIfdPaths {
+		ie.fqIfdPaths[fqIfdPath] = struct{}{}
+	}
+}
+
+// SetAsciiPolicy determines how the ASCII values of the tags that are parsed
+// are read. The default is `exifcommon.AsciiPolicyTrim`.
+func (ie *IfdEnumerate) SetAsciiPolicy(policy exifcommon.AsciiPolicy) {
+	ie.asciiPolicy = policy
+}
+
+// SetCharsetDecoder has the ASCII values of the tags that are parsed that
+// aren't actually ASCII decoded with the given decoder. By default, they're
+// returned as they are.
+func (ie *IfdEnumerate) SetCharsetDecoder(cd *exifcommon.CharsetDecoder) {
+	ie.charsetDecoder = cd
+}
+
+// splitFqIfdPathPart splits one part of a fully-qualified IFD path into the
+// IFD name and its index in the chain (e.g. "IFD1" into "IFD" and (1)).
+func splitFqIfdPathPart(part string) (name string, index int) {
+	i := len(part)
+	for i > 0 && part[i-1] >= '0' && part[i-1] <= '9' {
+		i--
+	}
+
+	index, err := strconv.Atoi(part[i:])
 	if err != nil {
-		if err == ErrOffsetInvalid {
-			return nil, err
+		return part, 0
+	}
+
+	return part[:i], index
+}
+
+// isFqIfdPathWanted returns true if the IFD was requested or has to be parsed
+// in order to reach one that was, as either an ancestor or an earlier IFD in
+// the same chain.
+func (ie *IfdEnumerate) isFqIfdPathWanted(fqIfdPath string) bool {
+	if ie.fqIfdPaths == nil {
+		return true
+	}
+
+	if _, found := ie.fqIfdPaths[fqIfdPath]; found == true {
+		return true
+	}
+
+	parts := strings.Split(fqIfdPath, "/")
+	lastName, lastIndex := splitFqIfdPathPart(parts[len(parts)-1])
+
+	for wanted := range ie.fqIfdPaths {
+		wantedParts := strings.Split(wanted, "/")
+		if len(wantedParts) < len(parts) {
+			continue
 		}
 
-		log.Panic(err)
+		// Everything above this IFD has to be the same IFD.
+		isReachable := true
+		for i := 0; i < len(parts)-1; i++ {
+			if parts[i] != wantedParts[i] {
+				isReachable = false
+				break
+			}
+		}
+
+		if isReachable == false {
+			continue
+		}
+
+		// This IFD has to be the one on the way to the wanted IFD or come
+		// before it in the chain.
+		wantedName, wantedIndex := splitFqIfdPathPart(wantedParts[len(parts)-1])
+		if wantedName == lastName && lastIndex <= wantedIndex {
+			return true
+		}
 	}
 
-	return enumerator, nil
+	return false
 }
 
-func (ie *IfdEnumerate) parseTag(fqIfdPath string, tagPosition int, enumerator *IfdTagEnumerator) (ite *IfdTagEntry, err error) {
+func (ie *IfdEnumerate) getTagEnumerator(fqIfdPath string, ifdOffset uint32) (enumerator *IfdTagEnumerator, err error) {
+	if _, err := exifcommon.CheckedAdd(ExifAddressableAreaStart, ifdOffset); err != nil {
+		return nil, ErrOffsetInvalid
+	}
+
+	if ie.reader != nil {
+		return ie.getReaderTagEnumerator(fqIfdPath, ifdOffset)
+	}
+
+	return NewIfdTagEnumerator(
+		ie.exifData[ExifAddressableAreaStart:],
+		ie.ifdByteOrder(fqIfdPath, ifdOffset),
+		ifdOffset)
+}
+
+// getReaderTagEnumerator returns an enumerator over just the IFD table, read
+// from the reader.
+func (ie *IfdEnumerate) getReaderTagEnumerator(fqIfdPath string, ifdOffset uint32) (enumerator *IfdTagEnumerator, err error) {
 	defer func() {
 		if state := recover(); state != nil {
 			err = log.Wrap(state.(error))
 		}
 	}()
 
+	byteOrder := ie.ifdByteOrder(fqIfdPath, ifdOffset)
+
+	rawTagCount, err := ie.readWindow(ifdOffset, 2)
+	if err != nil || len(rawTagCount) < 2 {
+		return nil, ErrOffsetInvalid
+	}
+
+	tagCount := byteOrder.Uint16(rawTagCount)
+
+	// A short table is caught by `parseIfd()`.
+	table, err := ie.readWindow(ifdOffset, rawIfdTableSize(int(tagCount)))
+	log.PanicIf(err)
+
+	enumerator = &IfdTagEnumerator{
+		addressableData: table,
+		byteOrder:       byteOrder,
+		ifdOffset:       ifdOffset,
+		buffer:          bytes.NewBuffer(table),
+		windowOffset:    ifdOffset,
+	}
+
+	return enumerator, nil
+}
+
+func (ie *IfdEnumerate) parseTag(fqIfdPath string, tagPosition int, enumerator *IfdTagEnumerator) (ite *IfdTagEntry, err error) {
 	tagId, _, err := enumerator.getUint16()
 	log.PanicIf(err)
 
@@ -209,8 +444,10 @@ func (ie *IfdEnumerate) parseTag(fqIfdPath string, tagPosition int, enumerator *
 	valueOffset, rawValueOffset, err := enumerator.getUint32()
 	log.PanicIf(err)
 
+	ie.position.readTag(tagId, tagType, unitCount)
+
 	if tagType.IsValid() == false {
-		log.Panic(ErrTagTypeNotValid)
+		return nil, ErrTagTypeNotValid
 	}
 
 	ifdPath, err := ie.ifdMapping.StripPathPhraseIndices(fqIfdPath)
@@ -225,7 +462,21 @@ func (ie *IfdEnumerate) parseTag(fqIfdPath string, tagPosition int, enumerator *
 		valueOffset,
 		rawValueOffset,
 		ie.exifData[ExifAddressableAreaStart:],
-		ie.byteOrder)
+		enumerator.byteOrder)
+
+	ite.entryOffset = enumerator.ifdOffset + 2 + uint32(tagPosition)*IfdTagEntrySize
+	ite.asciiPolicy = ie.asciiPolicy
+	ite.charsetDecoder = ie.charsetDecoder
+
+	ie.fixQuirkEntry(ite, enumerator)
+	ie.countTag(ifdPath, tagId, ite.tagType)
+
+	if ie.reader != nil {
+		ite.addressableReader = ie.reader
+		ite.addressableSize = ie.readerSize
+	}
+
+	ie.reportUnknownTag(fqIfdPath, ifdPath, ite)
 
 	// If it's an IFD but not a standard one, it'll just be seen as a LONG
 	// (the standard IFD tag type), later, unless we skip it because it's
@@ -252,33 +503,71 @@ type TagVisitorFn func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) (err er
 // ParseIfd decodes the IFD block that we're currently sitting on the first
 // byte of.
 func (ie *IfdEnumerate) ParseIfd(fqIfdPath string, ifdIndex int, enumerator *IfdTagEnumerator, visitor TagVisitorFn, doDescend bool) (nextIfdOffset uint32, entries []*IfdTagEntry, thumbnailData []byte, err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
+	defer ie.recoverParse(&err)
+
+	nextIfdOffset, entries, thumbnailData = ie.parseIfd(fqIfdPath, ifdIndex, enumerator, visitor, doDescend)
+	return nextIfdOffset, entries, thumbnailData, nil
+}
+
+// parseIfd is `ParseIfd()` without the recovery. Failures panic.
+func (ie *IfdEnumerate) parseIfd(fqIfdPath string, ifdIndex int, enumerator *IfdTagEnumerator, visitor TagVisitorFn, doDescend bool) (nextIfdOffset uint32, entries []*IfdTagEntry, thumbnailData []byte) {
+	ie.position.enterIfd(fqIfdPath, enumerator.ifdOffset)
 
 	tagCount, _, err := enumerator.getUint16()
 	log.PanicIf(err)
 
 	ifdEnumerateLogger.Debugf(nil, "Current IFD tag-count: (%d)", tagCount)
 
+	// Make sure that the whole table is there before reading any of it.
+	tableOffset := enumerator.ifdOffset - enumerator.windowOffset
+	_, err = exifcommon.CheckedSlice(enumerator.addressableData, tableOffset, rawIfdTableSize(int(tagCount)))
+
+	truncated := false
+	if err != nil && ie.tolerant == true {
+		// Read the entries that are there. There's no next-IFD offset.
+		available := uint32(len(enumerator.addressableData)) - tableOffset
+		tagCount = uint16((available - 2) / IfdTagEntrySize)
+		truncated = true
+
+		ie.warn(err)
+	} else {
+		log.PanicIf(err)
+	}
+
 	entries = make([]*IfdTagEntry, 0)
 
 	var enumeratorThumbnailOffset *IfdTagEntry
 	var enumeratorThumbnailSize *IfdTagEntry
 
 	for i := 0; i < int(tagCount); i++ {
+		// Descending into a child moves the position, so it's set for every
+		// entry.
+		ie.position.enterIfd(fqIfdPath, enumerator.ifdOffset)
+		ie.position.enterTag(i)
+
 		ite, err := ie.parseTag(fqIfdPath, i, enumerator)
 		if err != nil {
 			if log.Is(err, ErrTagTypeNotValid) == true {
 				ifdEnumerateLogger.Warningf(nil, "Tag in IFD [%s] at position (%d) has invalid type and will be skipped.", fqIfdPath, i)
+				ie.counters.SkippedEntries++
+
 				continue
 			}
 
 			log.Panic(err)
 		}
 
+		if ie.tolerant == true && ie.isValueInBounds(ite) == false {
+			ie.warn(exifcommon.ErrNotEnoughData)
+			ie.counters.SkippedEntries++
+
+			continue
+		}
+
+		ie.unprotectField(ite)
+		ie.normalizeQuirkEntry(fqIfdPath, ite)
+		ie.transformEntryValue(ite)
+
 		tagId := ite.TagId()
 		if tagId == ThumbnailOffsetTagId {
 			enumeratorThumbnailOffset = ite
@@ -301,17 +590,21 @@ func (ie *IfdEnumerate) ParseIfd(fqIfdPath string, ifdIndex int, enumerator *Ifd
 			if doDescend == true {
 				ifdEnumerateLogger.Debugf(nil, "Descending to IFD [%s].", ite.ChildIfdPath())
 
-				err := ie.scan(ite.ChildFqIfdPath(), ite.getValueOffset(), visitor)
-				log.PanicIf(err)
+				ie.scan(ite.ChildFqIfdPath(), ite.getValueOffset(), visitor)
 			}
 		}
 
 		entries = append(entries, ite)
 	}
 
+	ie.position.enterIfd(fqIfdPath, enumerator.ifdOffset)
+
 	if enumeratorThumbnailOffset != nil && enumeratorThumbnailSize != nil {
-		thumbnailData, err = ie.parseThumbnail(enumeratorThumbnailOffset, enumeratorThumbnailSize)
-		log.PanicIf(err)
+		thumbnailData = ie.parseThumbnail(enumeratorThumbnailOffset, enumeratorThumbnailSize)
+	}
+
+	if truncated == true {
+		return 0, entries, thumbnailData
 	}
 
 	nextIfdOffset, _, err = enumerator.getUint32()
@@ -319,25 +612,46 @@ func (ie *IfdEnumerate) ParseIfd(fqIfdPath string, ifdIndex int, enumerator *Ifd
 
 	ifdEnumerateLogger.Debugf(nil, "Next IFD at offset: (%08x)", nextIfdOffset)
 
-	return nextIfdOffset, entries, thumbnailData, nil
+	return nextIfdOffset, entries, thumbnailData
 }
 
-func (ie *IfdEnumerate) parseThumbnail(offsetIte, lengthIte *IfdTagEntry) (thumbnailData []byte, err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
-
+func (ie *IfdEnumerate) parseThumbnail(offsetIte, lengthIte *IfdTagEntry) (thumbnailData []byte) {
 	vRaw, err := lengthIte.Value()
 	log.PanicIf(err)
 
-	vList := vRaw.([]uint32)
-	if len(vList) != 1 {
-		log.Panicf("not exactly one long: (%d)", len(vList))
+	var length uint32
+
+	// The length is officially a LONG, but some writers use a SHORT.
+	switch vList := vRaw.(type) {
+	case []uint32:
+		if len(vList) != 1 {
+			log.Panicf("not exactly one long: (%d)", len(vList))
+		}
+
+		length = vList[0]
+	case []uint16:
+		if len(vList) != 1 {
+			log.Panicf("not exactly one short: (%d)", len(vList))
+		}
+
+		length = uint32(vList[0])
+	default:
+		log.Panicf("thumbnail length not an integer: [%s]", lengthIte.TagType())
 	}
 
-	length := vList[0]
+	if length == 0 {
+		return nil
+	}
+
+	// Truncated files often still claim the whole thumbnail.
+	available := ie.dataSize()
+	if offset := offsetIte.getValueOffset(); offset >= available {
+		ie.counters.ClampedOffsets++
+		return nil
+	} else if length > available-offset {
+		ie.counters.ClampedOffsets++
+		length = available - offset
+	}
 
 	// The tag is official a LONG type, but it's actually an offset to a blob of bytes.
 	offsetIte.updateTagType(exifcommon.TypeByte)
@@ -346,54 +660,73 @@ func (ie *IfdEnumerate) parseThumbnail(offsetIte, lengthIte *IfdTagEntry) (thumb
 	thumbnailData, err = offsetIte.GetRawBytes()
 	log.PanicIf(err)
 
-	return thumbnailData, nil
+	return thumbnailData
 }
 
-// Scan enumerates the different EXIF's IFD blocks.
-func (ie *IfdEnumerate) scan(fqIfdName string, ifdOffset uint32, visitor TagVisitorFn) (err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
+// scan enumerates the different EXIF's IFD blocks. Failures panic.
+func (ie *IfdEnumerate) scan(fqIfdName string, ifdOffset uint32, visitor TagVisitorFn) {
+	// Each child chain is scanned by a recursive call, so a child pointer
+	// that leads back to an ancestor would otherwise recurse forever.
+	if ie.isIfdTooDeep(fqIfdName) == true {
+		if ie.tolerant == true {
+			ie.position.enterIfd(fqIfdName, ifdOffset)
+			ie.warn(ErrIfdTooDeep)
+
+			return
 		}
-	}()
+
+		log.Panic(ErrIfdTooDeep)
+	}
+
+	seenOffsets := make(map[uint32]struct{})
 
 	for ifdIndex := 0; ; ifdIndex++ {
 		ifdEnumerateLogger.Debugf(nil, "Parsing IFD [%s] (%d) at offset (%04x) (scan).", fqIfdName, ifdIndex, ifdOffset)
 
-		enumerator, err := ie.getTagEnumerator(ifdOffset)
+		if _, found := seenOffsets[ifdOffset]; found == true {
+			if ie.tolerant == true {
+				ie.position.enterIfd(fqIfdName, ifdOffset)
+				ie.warn(ErrIfdCycle)
+
+				break
+			}
+
+			log.Panic(ErrIfdCycle)
+		}
+
+		seenOffsets[ifdOffset] = struct{}{}
+
+		enumerator, err := ie.getTagEnumerator(fqIfdName, ifdOffset)
 		if err != nil {
 			if err == ErrOffsetInvalid {
 				ifdEnumerateLogger.Errorf(nil, nil, "IFD [%s] (%d) at offset (%04x) is unreachable. Terminating scan.", fqIfdName, ifdIndex, ifdOffset)
+
+				if ie.tolerant == true {
+					ie.position.enterIfd(fqIfdName, ifdOffset)
+					ie.warn(err)
+				}
+
 				break
 			}
 
 			log.Panic(err)
 		}
 
-		nextIfdOffset, _, _, err := ie.ParseIfd(fqIfdName, ifdIndex, enumerator, visitor, true)
-		log.PanicIf(err)
-
+		nextIfdOffset, _, _ := ie.parseIfd(fqIfdName, ifdIndex, enumerator, visitor, true)
 		if nextIfdOffset == 0 {
 			break
 		}
 
 		ifdOffset = nextIfdOffset
 	}
-
-	return nil
 }
 
 // Scan enumerates the different EXIF blocks (called IFDs). `rootIfdName` will
 // be "IFD" in the TIFF standard.
 func (ie *IfdEnumerate) Scan(rootIfdName string, ifdOffset uint32, visitor TagVisitorFn) (err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
+	defer ie.recoverParse(&err)
 
-	err = ie.scan(rootIfdName, ifdOffset, visitor)
-	log.PanicIf(err)
+	ie.scan(rootIfdName, ifdOffset, visitor)
 
 	return nil
 }
@@ -521,6 +854,11 @@ func (ifd *Ifd) String() string {
 	return fmt.Sprintf("Ifd<ID=(%d) IFD-PATH=[%s] INDEX=(%d) COUNT=(%d) OFF=(0x%04x) CHILDREN=(%d) PARENT=(0x%04x) NEXT-IFD=(0x%04x)>", ifd.Id, ifd.IfdPath, ifd.Index, len(ifd.Entries), ifd.Offset, len(ifd.Children), parentOffset, ifd.NextIfdOffset)
 }
 
+// Thumbnail returns the thumbnail that the JPEGInterchangeFormat and
+// JPEGInterchangeFormatLength tags of this IFD (normally IFD1) point to.
+// `ErrNoThumbnail` is returned if either tag is missing, if the length is zero,
+// or if the offset is past the end of the data. A thumbnail that runs past the
+// end of the data is cut short there (see `ParseCounters.ClampedOffsets`).
 func (ifd *Ifd) Thumbnail() (data []byte, err error) {
 	defer func() {
 		if state := recover(); state != nil {
@@ -750,6 +1088,53 @@ func (ifd *Ifd) DumpTree() []string {
 	return ifd.dumpTree(nil, 0)
 }
 
+func (ifd *Ifd) writeTree(b *strings.Builder, level int) {
+	indent := strings.Repeat("  ", level)
+
+	fmt.Fprintf(b, "%sIFD [%s] OFFSET=(0x%08x) ENTRIES=(%d)\n", indent, ifd.FqIfdPath, ifd.Offset, len(ifd.Entries))
+
+	for _, ite := range ifd.Entries {
+		tagName := "?"
+		if it, err := ifd.tagIndex.Get(ifd.IfdPath, ite.TagId()); err == nil {
+			tagName = it.Name
+		}
+
+		childIfdPath := ite.ChildIfdPath()
+		if childIfdPath != "" {
+			fmt.Fprintf(b, "%s  - %s (0x%04x) %s\n", indent, tagName, ite.TagId(), ite.TagType())
+
+			if childIfd, found := ifd.ChildIfdIndex[childIfdPath]; found == true {
+				childIfd.writeTree(b, level+2)
+			}
+
+			continue
+		}
+
+		valuePhrase, err := ite.FormatFirst()
+		if err != nil {
+			valuePhrase = fmt.Sprintf("!ERROR: %s", err)
+		}
+
+		fmt.Fprintf(b, "%s  - %s (0x%04x) %s [%s]\n", indent, tagName, ite.TagId(), ite.TagType(), valuePhrase)
+	}
+}
+
+// TreeString returns an indented rendering of the IFD hierarchy with the
+// name, type, and a short preview of the value of every tag. Child IFDs are
+// nested under the tags that point to them and sibling IFDs (e.g. IFD1) are
+// listed at the same level as the IFD that links to them. This is meant for
+// debugging and display; values that can't be read are shown with an error
+// rather than failing the whole render.
+func (index IfdIndex) TreeString() string {
+	b := new(strings.Builder)
+
+	for ifd := index.RootIfd; ifd != nil; ifd = ifd.NextIfd {
+		ifd.writeTree(b, 0)
+	}
+
+	return b.String()
+}
+
 // GpsInfo parses and consolidates the GPS info. This can only be called on the
 // GPS IFD.
 func (ifd *Ifd) GpsInfo() (gi *GpsInfo, err error) {
@@ -939,15 +1324,92 @@ type IfdIndex struct {
 	Ifds    []*Ifd
 	Tree    map[int]*Ifd
 	Lookup  map[string][]*Ifd
+
+	// Counters are what the parser had to work around.
+	Counters ParseCounters
+
+	// Warnings are what a tolerant parse skipped (see
+	// `(*IfdEnumerate).SetTolerant()`).
+	Warnings []*ExifError
+}
+
+// ifdsWithPath returns the IFDs at the given path. Either an IFD-path (e.g.
+// "IFD/GPSInfo", which matches every IFD in the chain) or a fully-qualified
+// one (e.g. "IFD1") can be given.
+func (index IfdIndex) ifdsWithPath(ifdPath string) []*Ifd {
+	if ifds, found := index.Lookup[ifdPath]; found == true {
+		return ifds
+	}
+
+	for _, ifd := range index.Ifds {
+		if ifd.FqIfdPath == ifdPath {
+			return []*Ifd{ifd}
+		}
+	}
+
+	return nil
+}
+
+// Has returns true if the given IFD was found. See `HasTag()` for the paths
+// that are accepted.
+func (index IfdIndex) Has(ifdPath string) bool {
+	return len(index.ifdsWithPath(ifdPath)) > 0
+}
+
+// HasTag returns true if the given IFD has a tag with the given name. The
+// path can be an IFD-path (e.g. "IFD/Exif") or a fully-qualified IFD-path
+// (e.g. "IFD1"). Names that aren't known for that IFD are never found.
+func (index IfdIndex) HasTag(ifdPath string, tagName string) bool {
+	for _, ifd := range index.ifdsWithPath(ifdPath) {
+		if _, err := ifd.FindTagWithName(tagName); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Pages returns the root-level IFDs in the order they're chained. In a
+// multi-page TIFF, each one is a page. In the EXIF of a JPEG, the second one
+// is the thumbnail.
+func (index IfdIndex) Pages() []*Ifd {
+	pages := make([]*Ifd, 0)
+
+	for ifd := index.RootIfd; ifd != nil; ifd = ifd.NextIfd {
+		pages = append(pages, ifd)
+	}
+
+	return pages
+}
+
+// PageIfds returns the IFDs that belong to the given page (zero-based): the
+// page's own IFD followed by its child IFDs, depth-first. Nil is returned if
+// there's no such page.
+func (index IfdIndex) PageIfds(page int) []*Ifd {
+	pages := index.Pages()
+	if page < 0 || page >= len(pages) {
+		return nil
+	}
+
+	ifds := make([]*Ifd, 0)
+
+	var add func(ifd *Ifd)
+	add = func(ifd *Ifd) {
+		ifds = append(ifds, ifd)
+
+		for _, child := range ifd.Children {
+			add(child)
+		}
+	}
+
+	add(pages[page])
+
+	return ifds
 }
 
 // Scan enumerates the different EXIF blocks (called IFDs).
 func (ie *IfdEnumerate) Collect(rootIfdOffset uint32) (index IfdIndex, err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
+	defer ie.recoverParse(&err)
 
 	tree := make(map[int]*Ifd)
 	ifds := make([]*Ifd, 0)
@@ -967,6 +1429,7 @@ func (ie *IfdEnumerate) Collect(rootIfdOffset uint32) (index IfdIndex, err error
 	}
 
 	edges := make(map[uint32]*Ifd)
+	seenOffsets := make(map[uint32]struct{})
 
 	for {
 		if len(queue) == 0 {
@@ -987,17 +1450,53 @@ func (ie *IfdEnumerate) Collect(rootIfdOffset uint32) (index IfdIndex, err error
 
 		ifdEnumerateLogger.Debugf(nil, "Parsing IFD [%s] (%d) at offset (%04x) (Collect).", ifdPath, currentIndex, offset)
 
-		enumerator, err := ie.getTagEnumerator(offset)
+		// A chain or child pointer that leads back to an IFD that we've
+		// already parsed would otherwise have us parse it forever.
+		if _, found := seenOffsets[offset]; found == true {
+			if ie.tolerant == true {
+				ie.position.enterIfd(fqIfdPath, offset)
+				ie.warn(ErrIfdCycle)
+
+				continue
+			}
+
+			log.Panic(ErrIfdCycle)
+		}
+
+		seenOffsets[offset] = struct{}{}
+
+		if ie.isIfdTooDeep(fqIfdPath) == true {
+			if ie.tolerant == true {
+				ie.position.enterIfd(fqIfdPath, offset)
+				ie.warn(ErrIfdTooDeep)
+
+				continue
+			}
+
+			log.Panic(ErrIfdTooDeep)
+		}
+
+		enumerator, err := ie.getTagEnumerator(fqIfdPath, offset)
 		if err != nil {
 			if err == ErrOffsetInvalid {
+				if ie.tolerant == true {
+					ie.position.enterIfd(fqIfdPath, offset)
+					ie.warn(err)
+
+					continue
+				}
+
 				return index, err
 			}
 
 			log.Panic(err)
 		}
 
-		nextIfdOffset, entries, thumbnailData, err := ie.ParseIfd(fqIfdPath, currentIndex, enumerator, nil, false)
-		log.PanicIf(err)
+		nextIfdOffset, entries, thumbnailData, err := ie.parseIfdTolerantly(fqIfdPath, currentIndex, enumerator)
+		if err != nil {
+			ie.warn(err)
+			continue
+		}
 
 		id := len(ifds)
 
@@ -1014,7 +1513,7 @@ func (ie *IfdEnumerate) Collect(rootIfdOffset uint32) (index IfdIndex, err error
 		}
 
 		ifd := &Ifd{
-			ByteOrder: ie.byteOrder,
+			ByteOrder: enumerator.byteOrder,
 
 			Name:      name,
 			IfdPath:   ifdPath,
@@ -1074,6 +1573,8 @@ func (ie *IfdEnumerate) Collect(rootIfdOffset uint32) (index IfdIndex, err error
 		for i, ite := range entries {
 			if ite.ChildIfdPath() == "" {
 				continue
+			} else if ie.isFqIfdPathWanted(ite.ChildFqIfdPath()) == false {
+				continue
 			}
 
 			qi := QueuedIfd{
@@ -1093,9 +1594,6 @@ func (ie *IfdEnumerate) Collect(rootIfdOffset uint32) (index IfdIndex, err error
 
 		// If there's another IFD in the chain.
 		if nextIfdOffset != 0 {
-			// Allow the next link to know what the previous link was.
-			edges[nextIfdOffset] = ifd
-
 			siblingIndex := currentIndex + 1
 
 			var fqIfdPath string
@@ -1105,6 +1603,13 @@ func (ie *IfdEnumerate) Collect(rootIfdOffset uint32) (index IfdIndex, err error
 				fqIfdPath = fmt.Sprintf("%s%d", name, siblingIndex)
 			}
 
+			if ie.isFqIfdPathWanted(fqIfdPath) == false {
+				continue
+			}
+
+			// Allow the next link to know what the previous link was.
+			edges[nextIfdOffset] = ifd
+
 			qi := QueuedIfd{
 				Name:      name,
 				IfdPath:   ifdPath,
@@ -1118,24 +1623,49 @@ func (ie *IfdEnumerate) Collect(rootIfdOffset uint32) (index IfdIndex, err error
 		}
 	}
 
+	if ie.tolerant == true && len(ifds) == 0 {
+		// Not even IFD0 could be read.
+		return index, ie.warnings[0]
+	}
+
 	index.RootIfd = tree[0]
 	index.Ifds = ifds
 	index.Tree = tree
 	index.Lookup = lookup
+	index.Counters = ie.counters
+	index.Warnings = ie.warnings
 
-	err = ie.setChildrenIndex(index.RootIfd)
-	log.PanicIf(err)
+	ie.setChildrenIndex(index.RootIfd)
+
+	if ie.decodeConcurrency > 0 {
+		decodeValues(ifds, ie.decodeConcurrency)
+	}
 
 	return index, nil
 }
 
-func (ie *IfdEnumerate) setChildrenIndex(ifd *Ifd) (err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
+// parseIfdTolerantly is `parseIfd()` for `Collect()`. If the enumerator is
+// tolerant, a failure is returned rather than panicking so that the IFD can be
+// skipped.
+func (ie *IfdEnumerate) parseIfdTolerantly(fqIfdPath string, ifdIndex int, enumerator *IfdTagEnumerator) (nextIfdOffset uint32, entries []*IfdTagEntry, thumbnailData []byte, err error) {
+	if ie.tolerant == true {
+		defer func() {
+			if state := recover(); state != nil {
+				stateErr, ok := state.(error)
+				if ok == false {
+					stateErr = fmt.Errorf("%v", state)
+				}
 
+				err = stateErr
+			}
+		}()
+	}
+
+	nextIfdOffset, entries, thumbnailData = ie.parseIfd(fqIfdPath, ifdIndex, enumerator, nil, false)
+	return nextIfdOffset, entries, thumbnailData, nil
+}
+
+func (ie *IfdEnumerate) setChildrenIndex(ifd *Ifd) {
 	childIfdIndex := make(map[string]*Ifd)
 	for _, childIfd := range ifd.Children {
 		childIfdIndex[childIfd.IfdPath] = childIfd
@@ -1144,23 +1674,15 @@ func (ie *IfdEnumerate) setChildrenIndex(ifd *Ifd) (err error) {
 	ifd.ChildIfdIndex = childIfdIndex
 
 	for _, childIfd := range ifd.Children {
-		err := ie.setChildrenIndex(childIfd)
-		log.PanicIf(err)
+		ie.setChildrenIndex(childIfd)
 	}
-
-	return nil
 }
 
 // ParseOneIfd is a hack to use an IE to parse a raw IFD block. Can be used for
 // testing.
 func ParseOneIfd(ifdMapping *IfdMapping, tagIndex *TagIndex, fqIfdPath, ifdPath string, byteOrder binary.ByteOrder, ifdBlock []byte, visitor TagVisitorFn) (nextIfdOffset uint32, entries []*IfdTagEntry, err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
-
 	ie := NewIfdEnumerate(ifdMapping, tagIndex, make([]byte, 0), byteOrder)
+	defer ie.recoverParse(&err)
 
 	enumerator, err := NewIfdTagEnumerator(ifdBlock, byteOrder, 0)
 	if err != nil {
@@ -1171,21 +1693,15 @@ func ParseOneIfd(ifdMapping *IfdMapping, tagIndex *TagIndex, fqIfdPath, ifdPath
 		log.Panic(err)
 	}
 
-	nextIfdOffset, entries, _, err = ie.ParseIfd(fqIfdPath, 0, enumerator, visitor, true)
-	log.PanicIf(err)
+	nextIfdOffset, entries, _ = ie.parseIfd(fqIfdPath, 0, enumerator, visitor, true)
 
 	return nextIfdOffset, entries, nil
 }
 
 // ParseOneTag is a hack to use an IE to parse a raw tag block.
 func ParseOneTag(ifdMapping *IfdMapping, tagIndex *TagIndex, fqIfdPath, ifdPath string, byteOrder binary.ByteOrder, tagBlock []byte) (tag *IfdTagEntry, err error) {
-	defer func() {
-		if state := recover(); state != nil {
-			err = log.Wrap(state.(error))
-		}
-	}()
-
 	ie := NewIfdEnumerate(ifdMapping, tagIndex, make([]byte, 0), byteOrder)
+	defer ie.recoverParse(&err)
 
 	enumerator, err := NewIfdTagEnumerator(tagBlock, byteOrder, 0)
 	if err != nil {
@@ -1196,6 +1712,10 @@ func ParseOneTag(ifdMapping *IfdMapping, tagIndex *TagIndex, fqIfdPath, ifdPath
 		log.Panic(err)
 	}
 
+	// The block is just the one entry.
+	ie.position.enterIfd(fqIfdPath, 0)
+	ie.position.tagPosition = 0
+
 	tag, err = ie.parseTag(fqIfdPath, 0, enumerator)
 	log.PanicIf(err)
 
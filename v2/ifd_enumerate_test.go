@@ -2,16 +2,20 @@ package exif
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
 
+	"encoding/binary"
 	"io/ioutil"
 
 	"github.com/dsoprea/go-logging"
 
 	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
 )
 
 func TestIfdTagEntry_RawBytes_RealData(t *testing.T) {
@@ -227,6 +231,50 @@ func TestIfd_Thumbnail(t *testing.T) {
 	}
 }
 
+func TestIfd_Thumbnail_Bounds(t *testing.T) {
+	cases := []struct {
+		name      string
+		size      interface{}
+		offset    uint32
+		expectedN int
+	}{
+		{name: "short length", size: []uint16{16}, offset: 8, expectedN: 16},
+		{name: "zero length", size: []uint32{0}, offset: 8},
+		{name: "past the end", size: []uint32{4}, offset: 0xfff0},
+	}
+
+	for _, c := range cases {
+		root := &exiftest.Ifd{
+			Tags: []exiftest.Tag{
+				{Id: 0x0112, Value: []uint16{1}},
+			},
+			Next: &exiftest.Ifd{
+				Tags: []exiftest.Tag{
+					{Id: ThumbnailOffsetTagId, Value: []uint32{c.offset}},
+					{Id: ThumbnailSizeTagId, Value: c.size},
+				},
+			},
+		}
+
+		exifData, err := exiftest.Build(root, binary.BigEndian)
+		log.PanicIf(err)
+
+		_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+		log.PanicIf(err)
+
+		thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+		if c.expectedN == 0 {
+			if log.Is(err, ErrNoThumbnail) == false {
+				t.Fatalf("Expected no thumbnail for [%s]: %v", c.name, err)
+			}
+		} else if err != nil {
+			t.Fatalf("Thumbnail not read for [%s]: %v", c.name, err)
+		} else if bytes.Equal(thumbnail, exifData[c.offset:c.offset+uint32(c.expectedN)]) != true {
+			t.Fatalf("Thumbnail not correct for [%s]: %v", c.name, thumbnail)
+		}
+	}
+}
+
 func TestIfd_GpsInfo(t *testing.T) {
 	defer func() {
 		if state := recover(); state != nil {
@@ -579,3 +627,198 @@ func ExampleIfd_FindTagWithName() {
 	// Output:
 	// Canon EOS 5D Mark III
 }
+
+func TestIfdIndex_TreeString(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	tree := index.TreeString()
+	lines := strings.Split(strings.TrimRight(tree, "\n"), "\n")
+
+	expected := map[int]string{
+		0:  "IFD [IFD] OFFSET=(0x00000008) ENTRIES=(12)",
+		1:  "  - Make (0x010f) ASCII [Canon]",
+		2:  "  - Model (0x0110) ASCII [Canon EOS 5D Mark III]",
+		11: "  - ExifTag (0x8769) LONG",
+		12: "    IFD [IFD/Exif] OFFSET=(0x00000168) ENTRIES=(38)",
+		13: "      - ExposureTime (0x829a) RATIONAL [1/640]",
+	}
+
+	for i, line := range expected {
+		if lines[i] != line {
+			t.Fatalf("Line (%d) not correct: [%s] != [%s]\n%s", i, lines[i], line, tree)
+		}
+	}
+
+	if strings.Contains(tree, "\nIFD [IFD1] OFFSET=(0x00002c54) ENTRIES=(4)\n") == false {
+		t.Fatalf("Sibling IFD not rendered at the top level:\n%s", tree)
+	} else if strings.Contains(tree, "\n        IFD [IFD/Exif/Iop] ") == false {
+		t.Fatalf("Grandchild IFD not nested:\n%s", tree)
+	}
+}
+
+func TestIfdIndex_Has(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	if index.Has(exifcommon.IfdPathStandardGps) != true {
+		t.Fatalf("GPS IFD not found.")
+	} else if index.Has("IFD1") != true {
+		t.Fatalf("IFD1 not found by fully-qualified path.")
+	} else if index.Has("IFD2") != false {
+		t.Fatalf("IFD2 should not be found.")
+	}
+
+	_, index, err = CollectShallow(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	if index.Has(exifcommon.IfdPathStandardGps) != false {
+		t.Fatalf("GPS IFD should not be found in a shallow collection.")
+	}
+}
+
+func TestIfdIndex_HasTag(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	if index.HasTag(exifcommon.IfdPathStandardExif, "DateTimeOriginal") != true {
+		t.Fatalf("DateTimeOriginal not found.")
+	} else if index.HasTag(exifcommon.IfdPathStandardGps, "GPSLatitude") != false {
+		t.Fatalf("GPSLatitude should not be found.")
+	} else if index.HasTag(exifcommon.IfdPathStandardGps, "GPSVersionID") != true {
+		t.Fatalf("GPSVersionID not found.")
+	} else if index.HasTag("IFD1", "Compression") != true {
+		t.Fatalf("Compression not found in IFD1.")
+	} else if index.HasTag(exifcommon.IfdPathStandard, "NotATag") != false {
+		t.Fatalf("Unknown tag should not be found.")
+	} else if index.HasTag("IFD/Unknown", "Make") != false {
+		t.Fatalf("Tag in a missing IFD should not be found.")
+	}
+}
+
+func TestIfdIndex_Pages(t *testing.T) {
+	rawExif := getTestExifData()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	pages := index.Pages()
+	if len(pages) != 2 || pages[0] != index.RootIfd || pages[1].FqIfdPath != "IFD1" {
+		t.Fatalf("Pages not correct: %v", pages)
+	}
+
+	paths := make([]string, 0)
+	for _, ifd := range index.PageIfds(0) {
+		paths = append(paths, ifd.FqIfdPath)
+	}
+
+	expected := []string{"IFD", "IFD/Exif", "IFD/Exif/Iop", "IFD/GPSInfo"}
+	if reflect.DeepEqual(paths, expected) != true {
+		t.Fatalf("First page IFDs not correct: %v", paths)
+	} else if len(index.PageIfds(1)) != 1 {
+		t.Fatalf("Thumbnail IFD should not have child IFDs.")
+	}
+}
+
+func TestIfdEnumerate_Collect_Cycle(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	// Point the next-IFD link of IFD0 back at itself.
+	nextIfdPosition := ExifDefaultFirstIfdOffset + 2 + IfdTagEntrySize
+	exifcommon.TestDefaultByteOrder.PutUint32(exifData[nextIfdPosition:], ExifDefaultFirstIfdOffset)
+
+	_, _, err = Collect(im, ti, exifData)
+	if errors.Is(err, ErrIfdCycle) == false {
+		t.Fatalf("Expected ErrIfdCycle: %v", err)
+	}
+
+	_, err = Visit(exifcommon.IfdStandard, im, ti, exifData, func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) error {
+		return nil
+	})
+
+	if errors.Is(err, ErrIfdCycle) == false {
+		t.Fatalf("Expected ErrIfdCycle from Visit: %v", err)
+	}
+}
+
+func TestIfdEnumerate_SetAsciiPolicy(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+	rootIb.SetAsciiPolicy(exifcommon.AsciiPolicyVerbatim)
+
+	err := rootIb.SetStandardWithName("Make", "Canon  ")
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	eh, err := ParseExifHeader(exifData)
+	log.PanicIf(err)
+
+	expected := map[exifcommon.AsciiPolicy]string{
+		exifcommon.AsciiPolicyTrim:     "Canon",
+		exifcommon.AsciiPolicyVerbatim: "Canon  ",
+	}
+
+	for policy, expectedValue := range expected {
+		ie := NewIfdEnumerate(im, ti, exifData, eh.ByteOrder)
+		ie.SetAsciiPolicy(policy)
+
+		index, err := ie.Collect(eh.FirstIfdOffset)
+		log.PanicIf(err)
+
+		results, err := index.RootIfd.FindTagWithName("Make")
+		log.PanicIf(err)
+
+		value, err := results[0].Value()
+		log.PanicIf(err)
+
+		if value.(string) != expectedValue {
+			t.Fatalf("Value with policy [%s] not correct: [%q]", policy, value)
+		}
+	}
+}
+
+func TestIfdEnumerate_SetCharsetDecoder(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	// Written in ISO-8859-1 into an ASCII tag.
+	err := rootIb.SetStandardWithName("Artist", "caf\xe9")
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	eh, err := ParseExifHeader(exifData)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(im, ti, exifData, eh.ByteOrder)
+	ie.SetCharsetDecoder(exifcommon.NewCharsetDecoder(exifcommon.CharsetUtf8, exifcommon.CharsetLatin1))
+
+	index, err := ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+
+	results, err := index.RootIfd.FindTagWithName("Artist")
+	log.PanicIf(err)
+
+	value, err := results[0].Value()
+	log.PanicIf(err)
+
+	if value.(string) != "café" {
+		t.Fatalf("Value not decoded: [%q]", value)
+	}
+}
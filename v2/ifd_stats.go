@@ -0,0 +1,88 @@
+package exif
+
+import (
+	"fmt"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// IfdStats summarizes one IFD. It's cheap to compute from an index and is
+// meant for catching anomalous files (e.g. thousands of entries or very
+// large values) before doing any real work with them.
+type IfdStats struct {
+	FqIfdPath string
+	ByteOrder binary.ByteOrder
+
+	Offset        uint32
+	NextIfdOffset uint32
+
+	// EntryCount is the number of parsed entries. The thumbnail tags aren't
+	// counted.
+	EntryCount int
+
+	// ChildCount is the number of child IFDs.
+	ChildCount int
+
+	// ValueBytes is the total size of the values stored outside of the
+	// entries, and LargestValueBytes is the largest of them.
+	ValueBytes        uint64
+	LargestValueBytes uint64
+
+	// ThumbnailBytes is the size of the thumbnail, if the IFD has one.
+	ThumbnailBytes int
+}
+
+// String returns a descriptive string.
+func (is IfdStats) String() string {
+	return fmt.Sprintf("IfdStats<IFD=[%s] BYTE-ORDER=[%v] OFFSET=(0x%08x) ENTRIES=(%d) CHILDREN=(%d) VALUE-BYTES=(%d) LARGEST-VALUE-BYTES=(%d)>", is.FqIfdPath, is.ByteOrder, is.Offset, is.EntryCount, is.ChildCount, is.ValueBytes, is.LargestValueBytes)
+}
+
+// Stats returns the statistics for this IFD.
+func (ifd *Ifd) Stats() IfdStats {
+	is := IfdStats{
+		FqIfdPath:      ifd.FqIfdPath,
+		ByteOrder:      ifd.ByteOrder,
+		Offset:         ifd.Offset,
+		NextIfdOffset:  ifd.NextIfdOffset,
+		EntryCount:     len(ifd.Entries),
+		ChildCount:     len(ifd.Children),
+		ThumbnailBytes: len(ifd.thumbnailData),
+	}
+
+	for _, ite := range ifd.Entries {
+		tagType := ite.TagType()
+		if tagType == exifcommon.TypeUndefined {
+			tagType = exifcommon.TypeByte
+		}
+
+		if tagType.IsValid() == false {
+			continue
+		}
+
+		size := uint64(tagType.Size()) * uint64(ite.UnitCount())
+		if size <= 4 {
+			continue
+		}
+
+		is.ValueBytes += size
+
+		if size > is.LargestValueBytes {
+			is.LargestValueBytes = size
+		}
+	}
+
+	return is
+}
+
+// Stats returns the statistics for every IFD in the index, in the order that
+// they were parsed.
+func (index IfdIndex) Stats() []IfdStats {
+	stats := make([]IfdStats, len(index.Ifds))
+	for i, ifd := range index.Ifds {
+		stats[i] = ifd.Stats()
+	}
+
+	return stats
+}
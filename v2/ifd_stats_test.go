@@ -0,0 +1,44 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestIfdIndex_Stats(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	stats := index.Stats()
+	if len(stats) != 5 {
+		t.Fatalf("Stats count not correct: (%d)", len(stats))
+	}
+
+	rootStats := stats[0]
+	if rootStats.FqIfdPath != exifcommon.IfdPathStandard {
+		t.Fatalf("First IFD not correct: [%s]", rootStats.FqIfdPath)
+	} else if rootStats.ByteOrder != binary.LittleEndian {
+		t.Fatalf("Byte-order not correct: [%v]", rootStats.ByteOrder)
+	} else if rootStats.Offset != 8 || rootStats.NextIfdOffset != 11348 {
+		t.Fatalf("Offsets not correct: %s", rootStats)
+	} else if rootStats.EntryCount != 12 || rootStats.ChildCount != 2 {
+		t.Fatalf("Counts not correct: %s", rootStats)
+	} else if rootStats.ValueBytes != 6+22+8+8+20 || rootStats.LargestValueBytes != 22 {
+		t.Fatalf("Value sizes not correct: %s", rootStats)
+	}
+
+	exifStats := index.Lookup[exifcommon.IfdPathStandardExif][0].Stats()
+	if exifStats.EntryCount != 38 || exifStats.LargestValueBytes != 8152 {
+		t.Fatalf("Exif stats not correct: %s", exifStats)
+	}
+
+	thumbnailStats := stats[3]
+	if thumbnailStats.FqIfdPath != "IFD1" || thumbnailStats.ThumbnailBytes != 21491 {
+		t.Fatalf("Thumbnail stats not correct: %s", thumbnailStats)
+	}
+}
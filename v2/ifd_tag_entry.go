@@ -2,6 +2,7 @@ package exif
 
 import (
 	"fmt"
+	"io"
 
 	"encoding/binary"
 
@@ -15,6 +16,23 @@ var (
 	iteLogger = log.NewLogger("exif.ifd_tag_entry")
 )
 
+var (
+	// tagUnits are the units of the tags that have a fixed one, for
+	// `FormatOptions.Units`.
+	tagUnits = map[string]map[uint16]string{
+		exifcommon.IfdPathStandardExif: {
+			0x829a: "s",  // ExposureTime
+			0x9204: "EV", // ExposureBiasValue
+			0x9206: "m",  // SubjectDistance
+			0x920a: "mm", // FocalLength
+			0xa405: "mm", // FocalLengthIn35mmFilm
+		},
+		exifcommon.IfdPathStandardGps: {
+			0x0006: "m", // GPSAltitude
+		},
+	}
+)
+
 // IfdTagEntry refers to a tag in the loaded EXIF block.
 type IfdTagEntry struct {
 	tagId          uint16
@@ -24,6 +42,9 @@ type IfdTagEntry struct {
 	valueOffset    uint32
 	rawValueOffset []byte
 
+	// entryOffset is where the 12-byte entry is in the addressable data.
+	entryOffset uint32
+
 	// childIfdName is the right most atom in the IFD-path. We need this to
 	// construct the fully-qualified IFD-path.
 	childIfdName string
@@ -45,6 +66,20 @@ type IfdTagEntry struct {
 
 	addressableData []byte
 	byteOrder       binary.ByteOrder
+
+	// addressableReader and addressableSize are used instead of
+	// addressableData if the entry was parsed from a reader (see
+	// `NewIfdEnumerateWithReader()`).
+	addressableReader io.ReaderAt
+	addressableSize   int64
+
+	// asciiPolicy and charsetDecoder determine how ASCII values are read.
+	asciiPolicy    exifcommon.AsciiPolicy
+	charsetDecoder *exifcommon.CharsetDecoder
+
+	// decoded is the value if it was decoded during the parse (see
+	// `SetDecodeConcurrency()`).
+	decoded *decodedValue
 }
 
 func newIfdTagEntry(ifdPath string, tagId uint16, tagIndex int, tagType exifcommon.TagTypePrimitive, unitCount uint32, valueOffset uint32, rawValueOffset []byte, addressableData []byte, byteOrder binary.ByteOrder) *IfdTagEntry {
@@ -104,6 +139,52 @@ func (ite *IfdTagEntry) getValueOffset() uint32 {
 	return ite.valueOffset
 }
 
+// encodedTagType returns the type that the size of the value is calculated
+// with. Undefined values are counted in bytes.
+func (ite *IfdTagEntry) encodedTagType() exifcommon.TagTypePrimitive {
+	if ite.tagType == exifcommon.TypeUndefined {
+		return exifcommon.TypeByte
+	}
+
+	return ite.tagType
+}
+
+// EncodedSize returns the size of the value as it's stored, in bytes (the
+// unit-count times the size of the type).
+func (ite *IfdTagEntry) EncodedSize() (size uint32, err error) {
+	size, err = exifcommon.CheckedMultiply(ite.unitCount, ite.encodedTagType().Size())
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// IsEmbedded returns true if the value is stored in the last four bytes of
+// the entry rather than elsewhere in the data.
+func (ite *IfdTagEntry) IsEmbedded() bool {
+	size, err := ite.EncodedSize()
+	return err == nil && size <= 4
+}
+
+// EntryOffset returns where the 12-byte entry is, from the start of the EXIF
+// data (the TIFF header). For entries from `ParseOneIfd()`, it's from the
+// start of the IFD block instead.
+func (ite *IfdTagEntry) EntryOffset() uint32 {
+	return ExifAddressableAreaStart + ite.entryOffset
+}
+
+// ValueLocation returns where the value is, from the start of the EXIF data
+// (the TIFF header). This is in the entry itself (eight bytes after
+// `EntryOffset()`) if the value is embedded. See `EncodedSize()` for its size.
+func (ite *IfdTagEntry) ValueLocation() uint32 {
+	if ite.IsEmbedded() == true {
+		return ite.EntryOffset() + 8
+	}
+
+	return ExifAddressableAreaStart + ite.valueOffset
+}
+
 // RawBytes renders a specific list of bytes from the value in this tag.
 func (ite *IfdTagEntry) GetRawBytes() (rawBytes []byte, err error) {
 	valueContext := ite.getValueContext()
@@ -113,6 +194,15 @@ func (ite *IfdTagEntry) GetRawBytes() (rawBytes []byte, err error) {
 		if err != nil {
 			if err == exifcommon.ErrUnhandledUndefinedTypedTag {
 				ite.setIsUnhandledUnknown(true)
+
+				// There's no decoder to round-trip it through, so the
+				// bytes are returned as they are (e.g. Padding).
+				valueContext.SetUndefinedValueType(exifcommon.TypeByte)
+
+				rawBytes, err = valueContext.ReadRawEncoded()
+				log.PanicIf(err)
+
+				return rawBytes, nil
 			} else if err == exifundefined.ErrUnparseableValue {
 				return nil, err
 			} else {
@@ -143,6 +233,10 @@ func (ite *IfdTagEntry) Value() (value interface{}, err error) {
 		}
 	}()
 
+	if ite.decoded != nil {
+		return ite.decoded.value, ite.decoded.err
+	}
+
 	valueContext := ite.getValueContext()
 
 	if ite.tagType == exifcommon.TypeUndefined {
@@ -174,25 +268,33 @@ func (ite *IfdTagEntry) Format() (phrase string, err error) {
 		}
 	}()
 
-	value, err := ite.Value()
-	if err != nil {
-		if err == exifcommon.ErrUnhandledUndefinedTypedTag {
-			return exifundefined.UnparseableUnknownTagValuePlaceholder, nil
-		} else if err == exifundefined.ErrUnparseableValue {
-			return exifundefined.UnparseableHandledTagValuePlaceholder, nil
-		}
+	phrase, err = ite.FormatWithOptions(false, exifcommon.FormatOptions{})
+	log.PanicIf(err)
 
-		log.Panic(err)
-	}
+	return phrase, nil
+}
 
-	phrase, err = exifcommon.FormatFromType(value, false)
+// FormatFirst returns the same as Format() but only the first item. Text
+// (including undefined-type values that hold text, like UserComment) is
+// reduced to a one-line summary.
+func (ite *IfdTagEntry) FormatFirst() (phrase string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	phrase, err = ite.FormatWithOptions(true, exifcommon.FormatOptions{})
 	log.PanicIf(err)
 
 	return phrase, nil
 }
 
-// FormatFirst returns the same as Format() but only the first item.
-func (ite *IfdTagEntry) FormatFirst() (phrase string, err error) {
+// FormatWithOptions returns the same as Format() (or FormatFirst(), if
+// `justFirst` is true) with numbers formatted according to the given options.
+// The units are known for the tags that always have the same one (e.g.
+// FocalLength and ExposureTime).
+func (ite *IfdTagEntry) FormatWithOptions(justFirst bool, options exifcommon.FormatOptions) (phrase string, err error) {
 	defer func() {
 		if state := recover(); state != nil {
 			err = log.Wrap(state.(error))
@@ -203,12 +305,16 @@ func (ite *IfdTagEntry) FormatFirst() (phrase string, err error) {
 	if err != nil {
 		if err == exifcommon.ErrUnhandledUndefinedTypedTag {
 			return exifundefined.UnparseableUnknownTagValuePlaceholder, nil
+		} else if err == exifundefined.ErrUnparseableValue {
+			return exifundefined.UnparseableHandledTagValuePlaceholder, nil
 		}
 
 		log.Panic(err)
 	}
 
-	phrase, err = exifcommon.FormatFromType(value, true)
+	unit := tagUnits[ite.ifdPath][ite.tagId]
+
+	phrase, err = exifcommon.FormatFromTypeWithOptions(value, justFirst, options, unit)
 	log.PanicIf(err)
 
 	return phrase, nil
@@ -243,13 +349,33 @@ func (ite *IfdTagEntry) ChildFqIfdPath() string {
 }
 
 func (ite *IfdTagEntry) getValueContext() *exifcommon.ValueContext {
-	return exifcommon.NewValueContext(
-		ite.ifdPath,
-		ite.tagId,
-		ite.unitCount,
-		ite.valueOffset,
-		ite.rawValueOffset,
-		ite.addressableData,
-		ite.tagType,
-		ite.byteOrder)
+	var vc *exifcommon.ValueContext
+
+	if ite.addressableReader != nil {
+		vc = exifcommon.NewValueContextWithReader(
+			ite.ifdPath,
+			ite.tagId,
+			ite.unitCount,
+			ite.valueOffset,
+			ite.rawValueOffset,
+			ite.addressableReader,
+			ite.addressableSize,
+			ite.tagType,
+			ite.byteOrder)
+	} else {
+		vc = exifcommon.NewValueContext(
+			ite.ifdPath,
+			ite.tagId,
+			ite.unitCount,
+			ite.valueOffset,
+			ite.rawValueOffset,
+			ite.addressableData,
+			ite.tagType,
+			ite.byteOrder)
+	}
+
+	vc.SetAsciiPolicy(ite.asciiPolicy)
+	vc.SetCharsetDecoder(ite.charsetDecoder)
+
+	return vc
 }
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"testing"
 
+	"encoding/binary"
+
 	"github.com/dsoprea/go-logging"
 
 	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
 )
 
 func TestIfdTagEntry_RawBytes_Allocated(t *testing.T) {
@@ -81,3 +84,66 @@ func TestIfdTagEntry_String(t *testing.T) {
 		t.Fatalf("string representation not expected: [%s] != [%s]", ite.String(), expected)
 	}
 }
+
+func TestIfdTagEntry_Layout(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	cases := []struct {
+		ifdPath  string
+		tagId    uint16
+		embedded bool
+		size     uint32
+		raw      []byte
+	}{
+		{exifcommon.IfdPathStandard, 0x010f, false, 6, []byte("Canon\x00")},
+		{exifcommon.IfdPathStandard, OrientationTagId, true, 2, []byte{0x00, 0x01}},
+		{exifcommon.IfdPathStandardExif, 0x829d, false, 8, []byte{0, 0, 0, 28, 0, 0, 0, 10}},
+		{exifcommon.IfdPathStandardGps, 0x0001, true, 2, []byte("N\x00")},
+	}
+
+	for _, c := range cases {
+		ifd := index.Lookup[c.ifdPath][0]
+
+		results, err := ifd.FindTagWithId(c.tagId)
+		log.PanicIf(err)
+
+		ite := results[0]
+
+		size, err := ite.EncodedSize()
+		log.PanicIf(err)
+
+		entryOffset := ifd.Offset + 2 + uint32(ite.tagIndex)*IfdTagEntrySize
+		if ite.EntryOffset() != entryOffset || binary.BigEndian.Uint16(rawExif[entryOffset:]) != c.tagId {
+			t.Fatalf("Entry offset for (0x%04x) not correct: (%d)", c.tagId, ite.EntryOffset())
+		} else if ite.IsEmbedded() != c.embedded || size != c.size {
+			t.Fatalf("Layout for (0x%04x) not correct: [%v] (%d)", c.tagId, ite.IsEmbedded(), size)
+		} else if c.embedded == true && ite.ValueLocation() != entryOffset+8 {
+			t.Fatalf("Embedded value for (0x%04x) not in the entry: (%d)", c.tagId, ite.ValueLocation())
+		} else if bytes.Equal(rawExif[ite.ValueLocation():ite.ValueLocation()+size], c.raw) != true {
+			t.Fatalf("Value location for (0x%04x) not correct: %v", c.tagId, rawExif[ite.ValueLocation():ite.ValueLocation()+size])
+		}
+	}
+}
+
+func TestIfdTagEntry_EncodedSize_Overflow(t *testing.T) {
+	ite := newIfdTagEntry(
+		exifcommon.IfdPathStandard,
+		0x1,
+		0,
+		exifcommon.TypeRational,
+		0x40000000,
+		0,
+		nil,
+		nil,
+		exifcommon.TestDefaultByteOrder)
+
+	if _, err := ite.EncodedSize(); err == nil {
+		t.Fatalf("Expected overflow error.")
+	} else if ite.IsEmbedded() != false {
+		t.Fatalf("Overflowing value should not be embedded.")
+	}
+}
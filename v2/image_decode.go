@@ -0,0 +1,183 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"image"
+	"io"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// States of the JPEG segment scanner.
+const (
+	captureStateSoi = iota
+	captureStateMarkerPrefix
+	captureStateMarker
+	captureStateLength
+	captureStatePayload
+	captureStateDone
+)
+
+// ExifCaptureReader wraps the reader of a JPEG stream and captures the EXIF
+// APP1 segment as it's read by someone else (usually an image decoder). The
+// data passes through unchanged. Scanning stops at the start of the
+// compressed image data, so the cost is limited to the headers.
+type ExifCaptureReader struct {
+	r io.Reader
+
+	state int
+
+	marker       byte
+	lengthBytes  []byte
+	remaining    int
+	payload      []byte
+	capturingApp bool
+
+	rawExif []byte
+}
+
+// NewExifCaptureReader returns a new ExifCaptureReader.
+func NewExifCaptureReader(r io.Reader) *ExifCaptureReader {
+	return &ExifCaptureReader{
+		r: r,
+	}
+}
+
+// RawExif returns the captured EXIF data (starting with the TIFF header), or
+// nil if none has been seen yet.
+func (ecr *ExifCaptureReader) RawExif() []byte {
+	return ecr.rawExif
+}
+
+// IsDone returns true once the scanner has reached the image data (or
+// decided that the stream isn't a JPEG). No more EXIF will be captured.
+func (ecr *ExifCaptureReader) IsDone() bool {
+	return ecr.state == captureStateDone
+}
+
+// Read reads from the underlying reader and scans what passes through.
+func (ecr *ExifCaptureReader) Read(p []byte) (n int, err error) {
+	n, err = ecr.r.Read(p)
+
+	if ecr.state != captureStateDone {
+		ecr.scan(p[:n])
+	}
+
+	return n, err
+}
+
+func (ecr *ExifCaptureReader) scan(data []byte) {
+	for i := 0; i < len(data) && ecr.state != captureStateDone; i++ {
+		b := data[i]
+
+		switch ecr.state {
+		case captureStateSoi:
+			// We're looking at the first two bytes, which must be SOI.
+			if ecr.marker == 0 {
+				if b != jpegMarkerPrefix {
+					ecr.state = captureStateDone
+				}
+
+				ecr.marker = b
+			} else if b != jpegMarkerSoi {
+				ecr.state = captureStateDone
+			} else {
+				ecr.state = captureStateMarkerPrefix
+			}
+
+		case captureStateMarkerPrefix:
+			if b != jpegMarkerPrefix {
+				// Not well-formed. Don't try to make sense of it.
+				ecr.state = captureStateDone
+			} else {
+				ecr.state = captureStateMarker
+			}
+
+		case captureStateMarker:
+			if b == jpegMarkerPrefix {
+				// Fill byte.
+				continue
+			}
+
+			ecr.marker = b
+
+			if b == jpegMarkerSos || b == jpegMarkerEoi {
+				ecr.state = captureStateDone
+			} else if b == jpegMarkerTem || (b >= jpegMarkerRst0 && b <= jpegMarkerRst7) {
+				// These don't have a length.
+				ecr.state = captureStateMarkerPrefix
+			} else {
+				ecr.lengthBytes = ecr.lengthBytes[:0]
+				ecr.state = captureStateLength
+			}
+
+		case captureStateLength:
+			ecr.lengthBytes = append(ecr.lengthBytes, b)
+			if len(ecr.lengthBytes) < 2 {
+				continue
+			}
+
+			length := int(ecr.lengthBytes[0])<<8 | int(ecr.lengthBytes[1])
+			if length < 2 {
+				ecr.state = captureStateDone
+				continue
+			}
+
+			ecr.remaining = length - 2
+			ecr.capturingApp = ecr.marker == jpegMarkerApp1 && ecr.rawExif == nil
+			ecr.payload = ecr.payload[:0]
+
+			if ecr.remaining == 0 {
+				ecr.state = captureStateMarkerPrefix
+			} else {
+				ecr.state = captureStatePayload
+			}
+
+		case captureStatePayload:
+			// Consume as much of the segment as we have in one go.
+			count := ecr.remaining
+			if available := len(data) - i; available < count {
+				count = available
+			}
+
+			if ecr.capturingApp == true {
+				ecr.payload = append(ecr.payload, data[i:i+count]...)
+			}
+
+			ecr.remaining -= count
+			i += count - 1
+
+			if ecr.remaining == 0 {
+				if ecr.capturingApp == true && bytes.HasPrefix(ecr.payload, jpegExifPreamble) == true {
+					ecr.rawExif = make([]byte, len(ecr.payload)-len(jpegExifPreamble))
+					copy(ecr.rawExif, ecr.payload[len(jpegExifPreamble):])
+				}
+
+				ecr.state = captureStateMarkerPrefix
+			}
+		}
+	}
+}
+
+// DecodeImage decodes an image with `image.Decode()` while capturing its
+// EXIF, so the file doesn't have to be read twice. The decoders for the
+// formats that you expect have to be registered as usual (e.g. by importing
+// "image/jpeg"). EXIF is currently only captured from JPEGs. `rawExif` is nil
+// if there wasn't any.
+func DecodeImage(r io.Reader) (img image.Image, format string, rawExif []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ecr := NewExifCaptureReader(r)
+
+	img, format, err = image.Decode(ecr)
+	log.PanicIf(err)
+
+	return img, format, ecr.RawExif(), nil
+}
@@ -0,0 +1,77 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestExifCaptureReader_Read(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	ecr := NewExifCaptureReader(bytes.NewReader(data))
+
+	// Read in small, odd-sized pieces to exercise the state across calls.
+	buffer := make([]byte, 13)
+	output := new(bytes.Buffer)
+
+	for {
+		n, err := ecr.Read(buffer)
+		output.Write(buffer[:n])
+
+		if err != nil {
+			break
+		}
+	}
+
+	if bytes.Equal(output.Bytes(), data) != true {
+		t.Fatalf("Data was not passed through unchanged.")
+	} else if ecr.IsDone() != true {
+		t.Fatalf("Scanner did not finish.")
+	} else if bytes.Equal(ecr.RawExif(), getTestExifData()) != true {
+		t.Fatalf("Captured EXIF not correct.")
+	}
+}
+
+func TestExifCaptureReader_Read_NotJpeg(t *testing.T) {
+	ecr := NewExifCaptureReader(bytes.NewReader([]byte("not a jpeg")))
+
+	_, err := ecr.Read(make([]byte, 100))
+	log.PanicIf(err)
+
+	if ecr.IsDone() != true {
+		t.Fatalf("Scanner should have given up.")
+	} else if ecr.RawExif() != nil {
+		t.Fatalf("Should not have captured anything.")
+	}
+}
+
+func TestDecodeImage(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	img, format, rawExif, err := DecodeImage(bytes.NewReader(data))
+	log.PanicIf(err)
+
+	if format != "jpeg" {
+		t.Fatalf("Format not correct: [%s]", format)
+	} else if img.Bounds().Dx() != 16 {
+		t.Fatalf("Image not correct: %v", img.Bounds())
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	results, err := index.RootIfd.FindTagWithName("Model")
+	log.PanicIf(err)
+
+	value, err := results[0].Value()
+	log.PanicIf(err)
+
+	if value != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not correct: [%v]", value)
+	}
+}
@@ -0,0 +1,328 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// jfifDensityUnitInch and jfifDensityUnitCentimeter are the JFIF density
+	// units, which correspond to the ResolutionUnit values of 2 and 3.
+	jfifDensityUnitInch       = 1
+	jfifDensityUnitCentimeter = 2
+
+	resolutionUnitInch       = 2
+	resolutionUnitCentimeter = 3
+)
+
+var (
+	// ErrNoImageDimensions means that the dimensions of the image couldn't be
+	// read from its header, or that the container isn't a JPEG or PNG.
+	ErrNoImageDimensions = errors.New("image dimensions not found")
+)
+
+// ImageHeader is what the header of the image data says about its size.
+type ImageHeader struct {
+	Kind Kind
+
+	// Width and Height are from the SOF segment of a JPEG or the IHDR chunk of
+	// a PNG.
+	Width  int
+	Height int
+
+	// XDensity, YDensity, and DensityUnit (one of the ResolutionUnit values,
+	// or zero if there isn't one) are from the JFIF segment of a JPEG.
+	XDensity    int
+	YDensity    int
+	DensityUnit uint16
+}
+
+// String returns a descriptive string.
+func (ih ImageHeader) String() string {
+	return fmt.Sprintf("ImageHeader<KIND=[%s] WIDTH=(%d) HEIGHT=(%d) X-DENSITY=(%d) Y-DENSITY=(%d) DENSITY-UNIT=(%d)>", ih.Kind, ih.Width, ih.Height, ih.XDensity, ih.YDensity, ih.DensityUnit)
+}
+
+// GetImageHeader reads the dimensions of the image from its header, without
+// decoding it: the SOF segment of a JPEG (and the JFIF segment, if there is
+// one), or the IHDR chunk of a PNG. `ErrNoImageDimensions` is returned for
+// other containers or if the header isn't there.
+func GetImageHeader(data []byte) (ih ImageHeader, err error) {
+	if bytes.HasPrefix(data, pngSignature) == true {
+		ih.Kind = KindPng
+
+		chunks := getPngChunks(data, "IHDR")
+		if len(chunks) == 0 || len(chunks[0]) < 8 {
+			return ih, ErrNoImageDimensions
+		}
+
+		ih.Width = int(binary.BigEndian.Uint32(chunks[0][0:]))
+		ih.Height = int(binary.BigEndian.Uint32(chunks[0][4:]))
+
+		return ih, nil
+	}
+
+	segments := jpegSegments(data)
+	if len(segments) == 0 {
+		return ih, ErrNoImageDimensions
+	}
+
+	ih.Kind = KindJpeg
+
+	found := false
+	for _, segment := range segments {
+		if isJpegSof(segment.marker) == true && len(segment.payload) >= 5 {
+			ih.Height = int(binary.BigEndian.Uint16(segment.payload[1:]))
+			ih.Width = int(binary.BigEndian.Uint16(segment.payload[3:]))
+			found = true
+		} else if segment.marker == jpegMarkerApp0 && bytes.HasPrefix(segment.payload, []byte("JFIF\x00")) == true && len(segment.payload) >= 12 {
+			switch segment.payload[7] {
+			case jfifDensityUnitInch:
+				ih.DensityUnit = resolutionUnitInch
+			case jfifDensityUnitCentimeter:
+				ih.DensityUnit = resolutionUnitCentimeter
+			}
+
+			ih.XDensity = int(binary.BigEndian.Uint16(segment.payload[8:]))
+			ih.YDensity = int(binary.BigEndian.Uint16(segment.payload[10:]))
+		}
+	}
+
+	if found == false {
+		return ih, ErrNoImageDimensions
+	}
+
+	return ih, nil
+}
+
+// isJpegSof returns true if the marker starts a frame (SOF0 through SOF15,
+// other than DHT, JPG, and DAC, which share the range).
+func isJpegSof(marker byte) bool {
+	return marker >= 0xc0 && marker <= 0xcf && marker != 0xc4 && marker != 0xc8 && marker != 0xcc
+}
+
+// DimensionMismatch is a tag that doesn't agree with the image header.
+type DimensionMismatch struct {
+	// TagPath is the fully-qualified path of the tag (e.g.
+	// "IFD/Exif/PixelXDimension").
+	TagPath string
+
+	// Expected is what the image header says, and Actual is what the tag
+	// says. Missing is true if the tag isn't there.
+	Expected float64
+	Actual   float64
+	Missing  bool
+}
+
+// String returns a descriptive string.
+func (dm DimensionMismatch) String() string {
+	if dm.Missing == true {
+		return fmt.Sprintf("DimensionMismatch<TAG=[%s] EXPECTED=(%g) MISSING>", dm.TagPath, dm.Expected)
+	}
+
+	return fmt.Sprintf("DimensionMismatch<TAG=[%s] EXPECTED=(%g) ACTUAL=(%g)>", dm.TagPath, dm.Expected, dm.Actual)
+}
+
+// DimensionReport compares the image header to the EXIF.
+type DimensionReport struct {
+	Header     ImageHeader
+	Mismatches []DimensionMismatch
+}
+
+// IsConsistent returns true if the EXIF agrees with the image header.
+func (dr DimensionReport) IsConsistent() bool {
+	return len(dr.Mismatches) == 0
+}
+
+// dimensionCheck is a tag that's compared to the image header.
+type dimensionCheck struct {
+	tagPath  string
+	expected float64
+
+	// required is true if the tag should be there. Otherwise, it's only
+	// compared if it is.
+	required bool
+}
+
+// getDimensionChecks returns the tags to compare to the header.
+func getDimensionChecks(ih ImageHeader) []dimensionCheck {
+	checks := []dimensionCheck{
+		{"IFD/Exif/PixelXDimension", float64(ih.Width), true},
+		{"IFD/Exif/PixelYDimension", float64(ih.Height), true},
+		{"IFD/ImageWidth", float64(ih.Width), false},
+		{"IFD/ImageLength", float64(ih.Height), false},
+	}
+
+	if ih.DensityUnit != 0 {
+		checks = append(
+			checks,
+			dimensionCheck{"IFD/XResolution", float64(ih.XDensity), false},
+			dimensionCheck{"IFD/YResolution", float64(ih.YDensity), false},
+			dimensionCheck{"IFD/ResolutionUnit", float64(ih.DensityUnit), false},
+		)
+	}
+
+	return checks
+}
+
+// VerifyImageDimensions compares the dimensions in the header of a JPEG or PNG
+// to PixelXDimension and PixelYDimension (which ExifTool calls ExifImageWidth
+// and ExifImageHeight), to ImageWidth and ImageLength if they're present, and,
+// if a JPEG has a JFIF segment with a density, to XResolution, YResolution,
+// and ResolutionUnit. `ErrNoExif` is returned if there's no EXIF.
+func VerifyImageDimensions(data []byte) (dr DimensionReport, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	dr.Header, err = GetImageHeader(data)
+	if err != nil {
+		return dr, err
+	}
+
+	mf, err := GetMediaFormat(dr.Header.Kind)
+	log.PanicIf(err)
+
+	rawExif, err := mf.ExtractExif(data)
+	if err != nil {
+		return dr, err
+	}
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	_, index, err := Collect(im, ti, rawExif)
+	log.PanicIf(err)
+
+	dr.Mismatches = make([]DimensionMismatch, 0)
+
+	for _, check := range getDimensionChecks(dr.Header) {
+		actual, found, err := getDimensionTagValue(im, ti, index, check.tagPath)
+		log.PanicIf(err)
+
+		if found == false {
+			if check.required == true {
+				dm := DimensionMismatch{
+					TagPath:  check.tagPath,
+					Expected: check.expected,
+					Missing:  true,
+				}
+
+				dr.Mismatches = append(dr.Mismatches, dm)
+			}
+
+			continue
+		}
+
+		if actual != check.expected {
+			dm := DimensionMismatch{
+				TagPath:  check.tagPath,
+				Expected: check.expected,
+				Actual:   actual,
+			}
+
+			dr.Mismatches = append(dr.Mismatches, dm)
+		}
+	}
+
+	return dr, nil
+}
+
+// getDimensionTagValue returns the first value of the tag as a number.
+func getDimensionTagValue(im *IfdMapping, ti *TagIndex, index IfdIndex, tagPath string) (value float64, found bool, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fqIfdPath, it, err := resolveTagPath(im, ti, tagPath)
+	log.PanicIf(err)
+
+	ifd, found := index.Lookup[fqIfdPath]
+	if found == false || len(ifd) == 0 {
+		return 0, false, nil
+	}
+
+	if _, found := ifd[0].EntriesByTagId[it.Id]; found == false {
+		return 0, false, nil
+	}
+
+	value, err = getIfdTagNumber(ifd[0], it.Name)
+	log.PanicIf(err)
+
+	return value, true, nil
+}
+
+// FixImageDimensions returns a copy of the JPEG or PNG with the mismatched
+// tags (see `VerifyImageDimensions()`) set to what the image header says,
+// along with the report from before they were fixed. The EXIF is patched in
+// place (see `ExifPatcher`) so that nothing else moves. The data is returned
+// as it is if nothing needed fixing.
+func FixImageDimensions(data []byte) (updated []byte, dr DimensionReport, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	dr, err = VerifyImageDimensions(data)
+	if err != nil {
+		return nil, dr, err
+	}
+
+	if dr.IsConsistent() == true {
+		return data, dr, nil
+	}
+
+	mf, err := GetMediaFormat(dr.Header.Kind)
+	log.PanicIf(err)
+
+	rawExif, err := mf.ExtractExif(data)
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ep, err := NewExifPatcher(rawExif, im, ti)
+	log.PanicIf(err)
+
+	for _, dm := range dr.Mismatches {
+		fqIfdPath, it, err := resolveTagPath(im, ti, dm.TagPath)
+		log.PanicIf(err)
+
+		err = setPatcherTag(ep, fqIfdPath, it, getDimensionValue(it, dm.Expected))
+		log.PanicIf(err)
+	}
+
+	rawExif, err = ep.Encode()
+	log.PanicIf(err)
+
+	updated, err = mf.ReplaceExif(data, rawExif)
+	log.PanicIf(err)
+
+	return updated, dr, nil
+}
+
+// getDimensionValue returns the value for the tag, in its type.
+func getDimensionValue(it *IndexedTag, value float64) interface{} {
+	switch it.Type {
+	case exifcommon.TypeShort:
+		return []uint16{uint16(value)}
+	case exifcommon.TypeRational:
+		return []exifcommon.Rational{{Numerator: uint32(value), Denominator: 1}}
+	}
+
+	return []uint32{uint32(value)}
+}
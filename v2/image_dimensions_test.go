@@ -0,0 +1,137 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestDimensionsJpeg returns a JPEG with a 640x480 frame, a JFIF segment
+// that says 72 DPI, and EXIF that has the wrong PixelYDimension and
+// XResolution.
+func getTestDimensionsJpeg() []byte {
+	root := exiftest.NewRealisticIfd()
+
+	root.Tags = append(
+		root.Tags,
+		exiftest.Tag{Id: 0x011a, Value: []exifcommon.Rational{{Numerator: 300, Denominator: 1}}},
+		exiftest.Tag{Id: 0x011b, Value: []exifcommon.Rational{{Numerator: 72, Denominator: 1}}},
+		exiftest.Tag{Id: 0x0128, Value: []uint16{2}})
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(
+		exifIfd.Tags,
+		exiftest.Tag{Id: 0xa002, Value: []uint32{640}},
+		exiftest.Tag{Id: 0xa003, Value: []uint32{100}})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	jpeg := exiftest.WrapJpeg(rawExif)
+
+	jfif := []byte{0xff, 0xe0, 0, 16, 'J', 'F', 'I', 'F', 0, 1, 2, 1, 0, 72, 0, 72, 0, 0}
+	sof := []byte{0xff, 0xc0, 0, 11, 8, 0x01, 0xe0, 0x02, 0x80, 1, 1, 0x11, 0}
+
+	data := append([]byte{}, jpeg[:2]...)
+	data = append(data, jfif...)
+	data = append(data, jpeg[2:len(jpeg)-2]...)
+	data = append(data, sof...)
+	data = append(data, jpeg[len(jpeg)-2:]...)
+
+	return data
+}
+
+func TestGetImageHeader(t *testing.T) {
+	ih, err := GetImageHeader(getTestDimensionsJpeg())
+	log.PanicIf(err)
+
+	if ih.Kind != KindJpeg || ih.Width != 640 || ih.Height != 480 {
+		t.Fatalf("JPEG header not correct: %s", ih)
+	} else if ih.XDensity != 72 || ih.YDensity != 72 || ih.DensityUnit != resolutionUnitInch {
+		t.Fatalf("JFIF density not correct: %s", ih)
+	}
+
+	ih, err = GetImageHeader(exiftest.WrapPng(getTestExifData()))
+	log.PanicIf(err)
+
+	if ih.Kind != KindPng || ih.Width != 1 || ih.Height != 1 || ih.DensityUnit != 0 {
+		t.Fatalf("PNG header not correct: %s", ih)
+	}
+
+	// There's no frame.
+	if _, err := GetImageHeader(exiftest.WrapJpeg(getTestExifData())); err != ErrNoImageDimensions {
+		t.Fatalf("Expected ErrNoImageDimensions: %v", err)
+	}
+}
+
+func TestVerifyImageDimensions(t *testing.T) {
+	dr, err := VerifyImageDimensions(getTestDimensionsJpeg())
+	log.PanicIf(err)
+
+	if dr.IsConsistent() == true {
+		t.Fatalf("Expected mismatches.")
+	} else if len(dr.Mismatches) != 2 {
+		t.Fatalf("Expected two mismatches: %v", dr.Mismatches)
+	} else if dm := dr.Mismatches[0]; dm.TagPath != "IFD/Exif/PixelYDimension" || dm.Expected != 480 || dm.Actual != 100 || dm.Missing != false {
+		t.Fatalf("Height mismatch not correct: %s", dm)
+	} else if dm := dr.Mismatches[1]; dm.TagPath != "IFD/XResolution" || dm.Expected != 72 || dm.Actual != 300 {
+		t.Fatalf("Resolution mismatch not correct: %s", dm)
+	}
+}
+
+// getTestDimensionsPng returns a 1x1 PNG with EXIF that doesn't have
+// PixelXDimension or PixelYDimension.
+func getTestDimensionsPng() []byte {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	return exiftest.WrapPng(rawExif)
+}
+
+func TestVerifyImageDimensions_Missing(t *testing.T) {
+	dr, err := VerifyImageDimensions(getTestDimensionsPng())
+	log.PanicIf(err)
+
+	if len(dr.Mismatches) != 2 {
+		t.Fatalf("Expected two mismatches: %v", dr.Mismatches)
+	} else if dm := dr.Mismatches[0]; dm.TagPath != "IFD/Exif/PixelXDimension" || dm.Missing != true || dm.Expected != 1 {
+		t.Fatalf("Missing width not correct: %s", dm)
+	} else if dm := dr.Mismatches[1]; dm.TagPath != "IFD/Exif/PixelYDimension" || dm.Missing != true {
+		t.Fatalf("Missing height not correct: %s", dm)
+	}
+}
+
+func TestFixImageDimensions(t *testing.T) {
+	for _, data := range [][]byte{getTestDimensionsJpeg(), getTestDimensionsPng()} {
+		updated, dr, err := FixImageDimensions(data)
+		log.PanicIf(err)
+
+		if dr.IsConsistent() == true {
+			t.Fatalf("Expected the report from before the fix.")
+		}
+
+		dr, err = VerifyImageDimensions(updated)
+		log.PanicIf(err)
+
+		if dr.IsConsistent() != true {
+			t.Fatalf("Still not consistent after the fix: %v", dr.Mismatches)
+		}
+
+		// Nothing is left to fix.
+
+		again, _, err := FixImageDimensions(updated)
+		log.PanicIf(err)
+
+		if &again[0] != &updated[0] {
+			t.Fatalf("Data changed without a mismatch.")
+		}
+	}
+}
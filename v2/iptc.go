@@ -0,0 +1,399 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// IptcRecordEnvelope is the IPTC envelope record.
+	IptcRecordEnvelope = 1
+
+	// IptcRecordApplication is the IPTC application record, which has the
+	// descriptive datasets.
+	IptcRecordApplication = 2
+)
+
+// Datasets in the envelope and application records.
+const (
+	IptcDatasetCodedCharacterSet = 90
+
+	IptcDatasetRecordVersion   = 0
+	IptcDatasetKeywords        = 25
+	IptcDatasetByline          = 80
+	IptcDatasetCopyrightNotice = 116
+)
+
+const (
+	iptcTagMarker = 0x1c
+
+	photoshopIptcResourceId = 0x0404
+)
+
+var (
+	// ErrNoIptc means that there's no IPTC data.
+	ErrNoIptc = errors.New("no iptc data")
+)
+
+var (
+	photoshopSignature         = []byte("Photoshop 3.0\x00")
+	photoshopResourceSignature = []byte("8BIM")
+
+	// iptcUtf8 is the value of the CodedCharacterSet dataset for UTF-8.
+	iptcUtf8 = []byte("\x1b%G")
+)
+
+// IptcDataset is one IPTC-IIM dataset.
+type IptcDataset struct {
+	Record  byte
+	Dataset byte
+	Data    []byte
+}
+
+// String returns a descriptive string.
+func (id IptcDataset) String() string {
+	return fmt.Sprintf("IptcDataset<ID=(%d:%02d) SIZE=(%d)>", id.Record, id.Dataset, len(id.Data))
+}
+
+// ParseIptc parses IPTC-IIM data into its datasets.
+func ParseIptc(data []byte) (datasets []IptcDataset, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	datasets = make([]IptcDataset, 0)
+
+	for i := 0; i < len(data); {
+		if data[i] != iptcTagMarker {
+			// Trailing padding is common.
+			if bytes.Count(data[i:], []byte{0}) == len(data)-i {
+				break
+			}
+
+			log.Panicf("IPTC tag marker not found at (%d)", i)
+		} else if i+5 > len(data) {
+			log.Panicf("IPTC dataset header truncated at (%d)", i)
+		}
+
+		record := data[i+1]
+		dataset := data[i+2]
+		length := int(binary.BigEndian.Uint16(data[i+3:]))
+
+		i += 5
+
+		if length&0x8000 != 0 {
+			// Extended dataset: the low bits are the size of the length.
+			lengthSize := length & 0x7fff
+			if lengthSize > 4 || i+lengthSize > len(data) {
+				log.Panicf("IPTC extended length not valid at (%d)", i)
+			}
+
+			length = 0
+			for _, b := range data[i : i+lengthSize] {
+				length = length<<8 | int(b)
+			}
+
+			i += lengthSize
+		}
+
+		if i+length > len(data) {
+			log.Panicf("IPTC dataset (%d:%02d) truncated", record, dataset)
+		}
+
+		datasets = append(datasets, IptcDataset{
+			Record:  record,
+			Dataset: dataset,
+			Data:    data[i : i+length],
+		})
+
+		i += length
+	}
+
+	return datasets, nil
+}
+
+// EncodeIptc encodes the datasets as IPTC-IIM data.
+func EncodeIptc(datasets []IptcDataset) []byte {
+	b := new(bytes.Buffer)
+
+	for _, id := range datasets {
+		b.Write([]byte{iptcTagMarker, id.Record, id.Dataset})
+
+		if len(id.Data) < 0x8000 {
+			binary.Write(b, binary.BigEndian, uint16(len(id.Data)))
+		} else {
+			binary.Write(b, binary.BigEndian, uint16(0x8004))
+			binary.Write(b, binary.BigEndian, uint32(len(id.Data)))
+		}
+
+		b.Write(id.Data)
+	}
+
+	return b.Bytes()
+}
+
+// IptcStrings returns the values of every instance of the given dataset.
+func IptcStrings(datasets []IptcDataset, record, dataset byte) []string {
+	values := make([]string, 0)
+
+	for _, id := range datasets {
+		if id.Record == record && id.Dataset == dataset {
+			values = append(values, string(id.Data))
+		}
+	}
+
+	return values
+}
+
+// SetIptcStrings returns a copy of the datasets with every instance of the
+// given dataset replaced by the given values. The new instances take the
+// place of the first old one, or are appended if there wasn't one. The
+// CodedCharacterSet is set to UTF-8 if it isn't already.
+func SetIptcStrings(datasets []IptcDataset, record, dataset byte, values []string) []IptcDataset {
+	replacements := make([]IptcDataset, len(values))
+	for i, value := range values {
+		replacements[i] = IptcDataset{
+			Record:  record,
+			Dataset: dataset,
+			Data:    []byte(value),
+		}
+	}
+
+	updated := make([]IptcDataset, 0, len(datasets)+len(values)+1)
+	hasCharacterSet := false
+	replaced := false
+
+	for _, id := range datasets {
+		if id.Record == IptcRecordEnvelope && id.Dataset == IptcDatasetCodedCharacterSet {
+			hasCharacterSet = true
+		}
+
+		if id.Record == record && id.Dataset == dataset {
+			if replaced == false {
+				updated = append(updated, replacements...)
+				replaced = true
+			}
+
+			continue
+		}
+
+		updated = append(updated, id)
+	}
+
+	if replaced == false {
+		updated = append(updated, replacements...)
+	}
+
+	if hasCharacterSet == false {
+		characterSet := IptcDataset{
+			Record:  IptcRecordEnvelope,
+			Dataset: IptcDatasetCodedCharacterSet,
+			Data:    iptcUtf8,
+		}
+
+		updated = append([]IptcDataset{characterSet}, updated...)
+	}
+
+	return updated
+}
+
+// photoshopResource is one image resource block in an APP13 segment.
+type photoshopResource struct {
+	id   uint16
+	name []byte
+	data []byte
+}
+
+// parsePhotoshopResources parses the image resource blocks that follow the
+// "Photoshop 3.0" signature.
+func parsePhotoshopResources(data []byte) (resources []photoshopResource, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	resources = make([]photoshopResource, 0)
+
+	for i := 0; i+len(photoshopResourceSignature)+2 < len(data); {
+		if bytes.Equal(data[i:i+4], photoshopResourceSignature) == false {
+			log.Panicf("image resource signature not found at (%d)", i)
+		}
+
+		id := binary.BigEndian.Uint16(data[i+4:])
+		i += 6
+
+		// The name is a Pascal string padded to an even size.
+		nameLength := int(data[i])
+		nameSize := 1 + nameLength
+		if nameSize%2 == 1 {
+			nameSize++
+		}
+
+		if i+nameSize+4 > len(data) {
+			log.Panicf("image resource (0x%04x) truncated", id)
+		}
+
+		name := data[i+1 : i+1+nameLength]
+		i += nameSize
+
+		size := int(binary.BigEndian.Uint32(data[i:]))
+		i += 4
+
+		if size < 0 || i+size > len(data) {
+			log.Panicf("image resource (0x%04x) data truncated", id)
+		}
+
+		resources = append(resources, photoshopResource{
+			id:   id,
+			name: name,
+			data: data[i : i+size],
+		})
+
+		i += size
+		if size%2 == 1 {
+			i++
+		}
+	}
+
+	return resources, nil
+}
+
+// encodePhotoshopResources encodes image resource blocks (without the
+// "Photoshop 3.0" signature).
+func encodePhotoshopResources(resources []photoshopResource) []byte {
+	b := new(bytes.Buffer)
+
+	for _, pr := range resources {
+		b.Write(photoshopResourceSignature)
+		binary.Write(b, binary.BigEndian, pr.id)
+
+		b.WriteByte(byte(len(pr.name)))
+		b.Write(pr.name)
+
+		if (1+len(pr.name))%2 == 1 {
+			b.WriteByte(0)
+		}
+
+		binary.Write(b, binary.BigEndian, uint32(len(pr.data)))
+		b.Write(pr.data)
+
+		if len(pr.data)%2 == 1 {
+			b.WriteByte(0)
+		}
+	}
+
+	return b.Bytes()
+}
+
+// GetJpegIptc returns the IPTC datasets from the Photoshop APP13 segment of a
+// JPEG. `ErrNoIptc` is returned if there aren't any.
+func GetJpegIptc(data []byte) (datasets []IptcDataset, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for _, segment := range jpegSegments(data) {
+		if segment.marker != jpegMarkerApp13 || bytes.HasPrefix(segment.payload, photoshopSignature) == false {
+			continue
+		}
+
+		resources, err := parsePhotoshopResources(segment.payload[len(photoshopSignature):])
+		log.PanicIf(err)
+
+		for _, pr := range resources {
+			if pr.id == photoshopIptcResourceId {
+				datasets, err := ParseIptc(pr.data)
+				log.PanicIf(err)
+
+				return datasets, nil
+			}
+		}
+	}
+
+	return nil, ErrNoIptc
+}
+
+// SetJpegIptc returns a copy of the JPEG with the given IPTC datasets. The
+// other image resources in an existing Photoshop segment are preserved.
+// Otherwise, a new segment is inserted after any leading APP0 through APP2
+// segments.
+func SetJpegIptc(data []byte, datasets []IptcDataset) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(data) < 2 || data[0] != jpegMarkerPrefix || data[1] != jpegMarkerSoi {
+		log.Panic(ErrNotJpeg)
+	}
+
+	resources := make([]photoshopResource, 0)
+
+	// Where the new segment goes and how much of the original it replaces.
+	start := 2
+	end := 2
+
+	for _, s := range jpegSegments(data) {
+		segmentStart := s.offset - 4
+		segmentEnd := s.offset + len(s.payload)
+
+		if s.marker == jpegMarkerApp13 && bytes.HasPrefix(s.payload, photoshopSignature) == true {
+			resources, err = parsePhotoshopResources(s.payload[len(photoshopSignature):])
+			log.PanicIf(err)
+
+			start, end = segmentStart, segmentEnd
+			break
+		} else if s.marker >= jpegMarkerApp0 && s.marker <= jpegMarkerApp2 {
+			start, end = segmentEnd, segmentEnd
+		} else {
+			break
+		}
+	}
+
+	iptc := photoshopResource{
+		id:   photoshopIptcResourceId,
+		data: EncodeIptc(datasets),
+	}
+
+	found := false
+	for i, pr := range resources {
+		if pr.id == photoshopIptcResourceId {
+			resources[i] = iptc
+			found = true
+
+			break
+		}
+	}
+
+	if found == false {
+		resources = append(resources, iptc)
+	}
+
+	payload := append([]byte{}, photoshopSignature...)
+	payload = append(payload, encodePhotoshopResources(resources)...)
+
+	if 2+len(payload) > 0xffff {
+		log.Panicf("IPTC too large for one segment: (%d)", len(payload))
+	}
+
+	segment := []byte{jpegMarkerPrefix, jpegMarkerApp13, byte((2 + len(payload)) >> 8), byte(2 + len(payload))}
+	segment = append(segment, payload...)
+
+	updated = make([]byte, 0, len(data)+len(segment))
+	updated = append(updated, data[:start]...)
+	updated = append(updated, segment...)
+	updated = append(updated, data[end:]...)
+
+	return updated, nil
+}
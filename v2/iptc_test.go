@@ -0,0 +1,128 @@
+package exif
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestParseIptc(t *testing.T) {
+	datasets := []IptcDataset{
+		{Record: IptcRecordApplication, Dataset: IptcDatasetKeywords, Data: []byte("beach")},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetKeywords, Data: []byte("sunset")},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetByline, Data: bytes.Repeat([]byte("a"), 0x9000)},
+	}
+
+	encoded := EncodeIptc(datasets)
+
+	// Padding is allowed at the end.
+	encoded = append(encoded, 0, 0)
+
+	parsed, err := ParseIptc(encoded)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(parsed, datasets) != true {
+		t.Fatalf("Datasets not correct: %v", parsed)
+	}
+
+	keywords := IptcStrings(parsed, IptcRecordApplication, IptcDatasetKeywords)
+	if reflect.DeepEqual(keywords, []string{"beach", "sunset"}) != true {
+		t.Fatalf("Keywords not correct: %v", keywords)
+	}
+}
+
+func TestParseIptc_Truncated(t *testing.T) {
+	encoded := EncodeIptc([]IptcDataset{{Record: 2, Dataset: 80, Data: []byte("Jane Doe")}})
+
+	_, err := ParseIptc(encoded[:len(encoded)-1])
+	if err == nil {
+		t.Fatalf("Expected error for truncated dataset.")
+	}
+}
+
+func TestSetIptcStrings(t *testing.T) {
+	datasets := []IptcDataset{
+		{Record: IptcRecordApplication, Dataset: IptcDatasetRecordVersion, Data: []byte{0, 4}},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetKeywords, Data: []byte("old")},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetByline, Data: []byte("Jane Doe")},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetKeywords, Data: []byte("older")},
+	}
+
+	updated := SetIptcStrings(datasets, IptcRecordApplication, IptcDatasetKeywords, []string{"new1", "new2"})
+
+	expected := []IptcDataset{
+		{Record: IptcRecordEnvelope, Dataset: IptcDatasetCodedCharacterSet, Data: iptcUtf8},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetRecordVersion, Data: []byte{0, 4}},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetKeywords, Data: []byte("new1")},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetKeywords, Data: []byte("new2")},
+		{Record: IptcRecordApplication, Dataset: IptcDatasetByline, Data: []byte("Jane Doe")},
+	}
+
+	if reflect.DeepEqual(updated, expected) != true {
+		t.Fatalf("Datasets not correct: %v", updated)
+	}
+}
+
+func TestSetJpegIptc(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	_, err := GetJpegIptc(data)
+	if err != ErrNoIptc {
+		t.Fatalf("Expected ErrNoIptc: %v", err)
+	}
+
+	datasets := []IptcDataset{
+		{Record: IptcRecordApplication, Dataset: IptcDatasetByline, Data: []byte("Jane Doe")},
+	}
+
+	updated, err := SetJpegIptc(data, datasets)
+	log.PanicIf(err)
+
+	si, err := GetJpegSegmentsInfo(updated)
+	log.PanicIf(err)
+
+	if len(si.Segments) < 2 || si.Segments[0].Kind != JpegSegmentExif || si.Segments[1].Kind != JpegSegmentPhotoshop {
+		t.Fatalf("Photoshop segment not placed after the EXIF: %v", si.Segments)
+	}
+
+	// Replace it, keeping the other resources.
+
+	photoshop := si.Segments[1]
+
+	resources := []photoshopResource{
+		{id: 0x03ed, name: []byte("abc"), data: []byte{1, 2, 3}},
+		{id: photoshopIptcResourceId, data: EncodeIptc(datasets)},
+	}
+
+	payload := append([]byte{}, photoshopSignature...)
+	payload = append(payload, encodePhotoshopResources(resources)...)
+
+	updated, err = replaceJpegSegmentData(updated, photoshop, payload[len(photoshopSignature):])
+	log.PanicIf(err)
+
+	datasets[0].Data = []byte("John Doe")
+
+	updated, err = SetJpegIptc(updated, datasets)
+	log.PanicIf(err)
+
+	recovered, err := GetJpegIptc(updated)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(recovered, datasets) != true {
+		t.Fatalf("IPTC not correct: %v", recovered)
+	}
+
+	si, err = GetJpegSegmentsInfo(updated)
+	log.PanicIf(err)
+
+	photoshop = si.Find(JpegSegmentPhotoshop)[0]
+
+	parsed, err := parsePhotoshopResources(updated[photoshop.DataOffset : photoshop.DataOffset+photoshop.DataSize])
+	log.PanicIf(err)
+
+	if len(parsed) != 2 || parsed[0].id != 0x03ed || string(parsed[0].name) != "abc" || bytes.Equal(parsed[0].data, []byte{1, 2, 3}) != true {
+		t.Fatalf("Other resources not preserved: %v", parsed)
+	}
+}
@@ -0,0 +1,194 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// These are the values of SensitivityType, which says what ISOSpeedRatings
+// means and which of the EXIF 2.3 sensitivity tags were recorded.
+const (
+	SensitivityTypeUnknown        = 0
+	SensitivityTypeSos            = 1
+	SensitivityTypeRei            = 2
+	SensitivityTypeIsoSpeed       = 3
+	SensitivityTypeSosRei         = 4
+	SensitivityTypeSosIsoSpeed    = 5
+	SensitivityTypeReiIsoSpeed    = 6
+	SensitivityTypeSosReiIsoSpeed = 7
+)
+
+const (
+	isoSpeedRatingsTagId           = 0x8827
+	sensitivityTypeTagId           = 0x8830
+	standardOutputSensitivityTagId = 0x8831
+	recommendedExposureIndexTagId  = 0x8832
+	isoSpeedTagId                  = 0x8833
+	isoSpeedLatitudeyyyTagId       = 0x8834
+	isoSpeedLatitudezzzTagId       = 0x8835
+	exifVersionTagId               = 0x9000
+
+	// isoSpeedRatingsMaximum is the largest ISOSpeedRatings, which is a SHORT.
+	// It means "this or more".
+	isoSpeedRatingsMaximum = 0xffff
+
+	// exifVersionSensitivityTags is the first EXIF version with the
+	// SensitivityType, StandardOutputSensitivity, RecommendedExposureIndex,
+	// and ISOSpeed tags.
+	exifVersionSensitivityTags = "0230"
+)
+
+var (
+	// ErrNoIso means that none of the ISO tags were found.
+	ErrNoIso = errors.New("no iso")
+)
+
+// Iso is the ISO sensitivity of an image along with the tag that it was read
+// from (e.g. "Exif/ISOSpeed").
+type Iso struct {
+	Value  uint32
+	Source string
+
+	// SensitivityType is the SensitivityType tag, or
+	// `SensitivityTypeUnknown` if it's not present.
+	SensitivityType uint16
+}
+
+// String returns a descriptive string.
+func (iso Iso) String() string {
+	return fmt.Sprintf("Iso<VALUE=(%d) SOURCE=[%s] SENSITIVITY-TYPE=(%d)>", iso.Value, iso.Source, iso.SensitivityType)
+}
+
+// GetIso returns the ISO sensitivity. EXIF 2.2 only has ISOSpeedRatings,
+// which is a SHORT and can't go past 65535. EXIF 2.3 added
+// StandardOutputSensitivity, RecommendedExposureIndex, and ISOSpeed, with
+// SensitivityType saying which of them were recorded and which one
+// ISOSpeedRatings (renamed PhotographicSensitivity) has. The tag that
+// SensitivityType names is preferred, then ISOSpeedRatings unless it's
+// saturated, and then whichever of the EXIF 2.3 tags is present. `ErrNoIso`
+// is returned if there isn't one.
+func GetIso(index IfdIndex) (iso Iso, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifds := index.Lookup[exifcommon.IfdPathStandardExif]
+	if len(ifds) == 0 {
+		return iso, ErrNoIso
+	}
+
+	exifIfd := ifds[0]
+
+	sensitivityType, err := getIfdTagNumber(exifIfd, "SensitivityType")
+	log.PanicIf(err)
+
+	iso.SensitivityType = uint16(sensitivityType)
+
+	// The tags to try, in order.
+	tagNames := make([]string, 0)
+
+	switch iso.SensitivityType {
+	case SensitivityTypeSos, SensitivityTypeSosRei:
+		tagNames = append(tagNames, "StandardOutputSensitivity")
+	case SensitivityTypeRei:
+		tagNames = append(tagNames, "RecommendedExposureIndex")
+	case SensitivityTypeIsoSpeed, SensitivityTypeSosIsoSpeed, SensitivityTypeReiIsoSpeed, SensitivityTypeSosReiIsoSpeed:
+		tagNames = append(tagNames, "ISOSpeed")
+	}
+
+	ratings, err := getIfdTagNumber(exifIfd, "ISOSpeedRatings")
+	log.PanicIf(err)
+
+	if ratings > 0 && ratings < isoSpeedRatingsMaximum {
+		tagNames = append(tagNames, "ISOSpeedRatings")
+	}
+
+	tagNames = append(tagNames, "ISOSpeed", "StandardOutputSensitivity", "RecommendedExposureIndex")
+
+	for _, tagName := range tagNames {
+		value, err := getIfdTagNumber(exifIfd, tagName)
+		log.PanicIf(err)
+
+		if value > 0 {
+			iso.Value = uint32(value)
+			iso.Source = "Exif/" + tagName
+
+			return iso, nil
+		}
+	}
+
+	if ratings > 0 {
+		iso.Value = uint32(ratings)
+		iso.Source = "Exif/ISOSpeedRatings"
+
+		return iso, nil
+	}
+
+	return iso, ErrNoIso
+}
+
+// SetIso writes the ISO sensitivity into the Exif IFD, creating it if
+// necessary. ISOSpeedRatings is always written (as 65535 if the value doesn't
+// fit). If the ExifVersion is 2.3 or later, or the value doesn't fit in
+// ISOSpeedRatings, the value is also written to ISOSpeed, with a
+// SensitivityType that says so. The other sensitivity tags are removed so
+// that nothing disagrees with the new value.
+func SetIso(rootIb *IfdBuilder, value uint32) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	ratings := value
+	if ratings > isoSpeedRatingsMaximum {
+		ratings = isoSpeedRatingsMaximum
+	}
+
+	err = exifIb.SetStandard(isoSpeedRatingsTagId, []uint16{uint16(ratings)})
+	log.PanicIf(err)
+
+	staleTagIds := []uint16{
+		standardOutputSensitivityTagId,
+		recommendedExposureIndexTagId,
+		isoSpeedLatitudeyyyTagId,
+		isoSpeedLatitudezzzTagId,
+	}
+
+	if getIbExifVersion(exifIb) >= exifVersionSensitivityTags || value > isoSpeedRatingsMaximum {
+		err := exifIb.SetStandard(sensitivityTypeTagId, []uint16{SensitivityTypeIsoSpeed})
+		log.PanicIf(err)
+
+		err = exifIb.SetStandard(isoSpeedTagId, []uint32{value})
+		log.PanicIf(err)
+	} else {
+		staleTagIds = append(staleTagIds, sensitivityTypeTagId, isoSpeedTagId)
+	}
+
+	for _, tagId := range staleTagIds {
+		_, err := exifIb.DeleteAll(tagId)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// getIbExifVersion returns the ExifVersion (e.g. "0230") of the Exif IFD in
+// a builder, or an empty string if it's not present.
+func getIbExifVersion(exifIb *IfdBuilder) string {
+	bt, err := exifIb.FindTag(exifVersionTagId)
+	if err != nil || bt.value.IsBytes() == false {
+		return ""
+	}
+
+	return string(bt.value.Bytes())
+}
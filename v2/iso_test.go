@@ -0,0 +1,179 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+func getTestIsoIndex(exifTags ...exiftest.Tag) IfdIndex {
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x010f, Value: "Acme"},
+		},
+		Children: []exiftest.Child{
+			{TagId: exifcommon.IfdExifId, Ifd: &exiftest.Ifd{Tags: exifTags}},
+		},
+	}
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestGetIso(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	iso, err := GetIso(index)
+	log.PanicIf(err)
+
+	if iso.Value != 1600 || iso.Source != "Exif/RecommendedExposureIndex" || iso.SensitivityType != SensitivityTypeRei {
+		t.Fatalf("ISO not correct: %s", iso)
+	}
+}
+
+func TestGetIso_Versions(t *testing.T) {
+	cases := []struct {
+		name   string
+		tags   []exiftest.Tag
+		value  uint32
+		source string
+	}{
+		{
+			"exif 2.2",
+			[]exiftest.Tag{{Id: isoSpeedRatingsTagId, Value: []uint16{800}}},
+			800,
+			"Exif/ISOSpeedRatings",
+		},
+		{
+			"sensitivity type names the tag",
+			[]exiftest.Tag{
+				{Id: isoSpeedRatingsTagId, Value: []uint16{200}},
+				{Id: sensitivityTypeTagId, Value: []uint16{SensitivityTypeSosRei}},
+				{Id: standardOutputSensitivityTagId, Value: []uint32{200}},
+				{Id: recommendedExposureIndexTagId, Value: []uint32{250}},
+			},
+			200,
+			"Exif/StandardOutputSensitivity",
+		},
+		{
+			"saturated ratings",
+			[]exiftest.Tag{
+				{Id: isoSpeedRatingsTagId, Value: []uint16{65535}},
+				{Id: isoSpeedTagId, Value: []uint32{102400}},
+			},
+			102400,
+			"Exif/ISOSpeed",
+		},
+		{
+			"saturated ratings only",
+			[]exiftest.Tag{{Id: isoSpeedRatingsTagId, Value: []uint16{65535}}},
+			65535,
+			"Exif/ISOSpeedRatings",
+		},
+		{
+			"sensitivity type without its tag",
+			[]exiftest.Tag{
+				{Id: isoSpeedRatingsTagId, Value: []uint16{100}},
+				{Id: sensitivityTypeTagId, Value: []uint16{SensitivityTypeIsoSpeed}},
+			},
+			100,
+			"Exif/ISOSpeedRatings",
+		},
+	}
+
+	for _, c := range cases {
+		iso, err := GetIso(getTestIsoIndex(c.tags...))
+		log.PanicIf(err)
+
+		if iso.Value != c.value || iso.Source != c.source {
+			t.Fatalf("ISO for %s not correct: %s", c.name, iso)
+		}
+	}
+}
+
+func TestGetIso_None(t *testing.T) {
+	if _, err := GetIso(getTestIsoIndex(exiftest.Tag{Id: 0x829d, Value: []exifcommon.Rational{{Numerator: 4, Denominator: 1}}})); err != ErrNoIso {
+		t.Fatalf("Expected no ISO: %v", err)
+	}
+}
+
+func TestSetIso(t *testing.T) {
+	cases := []struct {
+		name            string
+		exifVersion     string
+		value           uint32
+		ratings         float64
+		sensitivityType float64
+		isoSpeed        float64
+	}{
+		{"exif 2.2", "0220", 800, 800, 0, 0},
+		{"exif 2.3", "0230", 800, 800, SensitivityTypeIsoSpeed, 800},
+		{"exif 2.2 too large", "0220", 204800, 65535, SensitivityTypeIsoSpeed, 204800},
+	}
+
+	for _, c := range cases {
+		im := NewIfdMappingWithStandard()
+		ti := NewTagIndex()
+
+		rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+		exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+		log.PanicIf(err)
+
+		err = exifIb.SetStandard(exifVersionTagId, exifundefined.Tag9000ExifVersion{ExifVersion: c.exifVersion})
+		log.PanicIf(err)
+
+		// Stale values that have to go.
+		err = exifIb.SetStandard(recommendedExposureIndexTagId, []uint32{100})
+		log.PanicIf(err)
+
+		err = exifIb.SetStandard(sensitivityTypeTagId, []uint16{SensitivityTypeRei})
+		log.PanicIf(err)
+
+		err = SetIso(rootIb, c.value)
+		log.PanicIf(err)
+
+		exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+		log.PanicIf(err)
+
+		_, index, err := Collect(im, ti, exifData)
+		log.PanicIf(err)
+
+		exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+		ratings, err := getIfdTagNumber(exifIfd, "ISOSpeedRatings")
+		log.PanicIf(err)
+
+		sensitivityType, err := getIfdTagNumber(exifIfd, "SensitivityType")
+		log.PanicIf(err)
+
+		isoSpeed, err := getIfdTagNumber(exifIfd, "ISOSpeed")
+		log.PanicIf(err)
+
+		rei, err := getIfdTagNumber(exifIfd, "RecommendedExposureIndex")
+		log.PanicIf(err)
+
+		if ratings != c.ratings || sensitivityType != c.sensitivityType || isoSpeed != c.isoSpeed || rei != 0 {
+			t.Fatalf("Tags for %s not correct: (%f) (%f) (%f) (%f)", c.name, ratings, sensitivityType, isoSpeed, rei)
+		}
+
+		iso, err := GetIso(index)
+		log.PanicIf(err)
+
+		if iso.Value != c.value {
+			t.Fatalf("ISO for %s not correct: %s", c.name, iso)
+		}
+	}
+}
@@ -0,0 +1,596 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	jpegMarkerPrefix = 0xff
+	jpegMarkerSoi    = 0xd8
+	jpegMarkerEoi    = 0xd9
+	jpegMarkerSos    = 0xda
+	jpegMarkerApp0   = 0xe0
+	jpegMarkerApp1   = 0xe1
+	jpegMarkerApp2   = 0xe2
+	jpegMarkerApp11  = 0xeb
+	jpegMarkerApp13  = 0xed
+	jpegMarkerApp14  = 0xee
+	jpegMarkerApp15  = 0xef
+	jpegMarkerCom    = 0xfe
+	jpegMarkerTem    = 0x01
+	jpegMarkerRst0   = 0xd0
+	jpegMarkerRst7   = 0xd7
+)
+
+const (
+	// JpegSegmentExif is an APP1 segment with EXIF.
+	JpegSegmentExif = "exif"
+
+	// JpegSegmentXmp is an APP1 segment with the main XMP packet.
+	JpegSegmentXmp = "xmp"
+
+	// JpegSegmentXmpExtended is an APP1 segment with part of the extended
+	// XMP.
+	JpegSegmentXmpExtended = "xmp-extended"
+
+	// JpegSegmentIcc is an APP2 segment with one chunk of an ICC profile.
+	JpegSegmentIcc = "icc"
+
+	// JpegSegmentMpf is an APP2 segment with the MPF (CIPA DC-007) index.
+	JpegSegmentMpf = "mpf"
+
+	// JpegSegmentJfif is the APP0 JFIF (or JFXX) segment.
+	JpegSegmentJfif = "jfif"
+
+	// JpegSegmentPhotoshop is an APP13 segment with Photoshop image resources
+	// (which is where IPTC is stored).
+	JpegSegmentPhotoshop = "photoshop"
+
+	// JpegSegmentAdobe is the APP14 Adobe segment that describes the color
+	// transform.
+	JpegSegmentAdobe = "adobe"
+
+	// JpegSegmentC2pa is an APP11 segment with one packet of a C2PA
+	// (Content Credentials) manifest store.
+	JpegSegmentC2pa = "c2pa"
+
+	// JpegSegmentComment is a COM segment.
+	JpegSegmentComment = "comment"
+
+	// JpegSegmentOther is any other APPn segment.
+	JpegSegmentOther = "other"
+)
+
+var (
+	// ErrNoXmp means that there's no XMP.
+	ErrNoXmp = errors.New("no xmp data")
+
+	// ErrNotJpeg means that the data doesn't start with a SOI marker.
+	ErrNotJpeg = errors.New("not a jpeg")
+)
+
+var (
+	// jpegExifPreamble prefixes the EXIF data in an APP1 segment.
+	jpegExifPreamble = []byte{'E', 'x', 'i', 'f', 0, 0}
+
+	// xmpNamespace prefixes the XMP data in an APP1 segment.
+	xmpNamespace = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+	// iccProfileSignature prefixes each chunk of an ICC profile in an APP2
+	// segment.
+	iccProfileSignature = []byte("ICC_PROFILE\x00")
+
+	// xmpExtendedNamespace prefixes the extended-XMP data in an APP1
+	// segment.
+	xmpExtendedNamespace = []byte("http://ns.adobe.com/xmp/extension/\x00")
+
+	xmpPacketStart = []byte("<x:xmpmeta")
+	xmpPacketEnd   = []byte("</x:xmpmeta>")
+)
+
+var (
+	// jpegSegmentSignatures identifies the metadata segments by marker and
+	// payload prefix. The data for each kind starts after its prefix.
+	jpegSegmentSignatures = []struct {
+		marker byte
+		prefix []byte
+		kind   string
+	}{
+		{jpegMarkerApp0, []byte("JFIF\x00"), JpegSegmentJfif},
+		{jpegMarkerApp0, []byte("JFXX\x00"), JpegSegmentJfif},
+		{jpegMarkerApp1, jpegExifPreamble, JpegSegmentExif},
+		{jpegMarkerApp1, xmpNamespace, JpegSegmentXmp},
+		{jpegMarkerApp1, xmpExtendedNamespace, JpegSegmentXmpExtended},
+		{jpegMarkerApp2, iccProfileSignature, JpegSegmentIcc},
+		{jpegMarkerApp2, mpfSignature, JpegSegmentMpf},
+		{jpegMarkerApp13, photoshopSignature, JpegSegmentPhotoshop},
+		{jpegMarkerApp14, []byte("Adobe"), JpegSegmentAdobe},
+	}
+)
+
+// jpegSegment is one marker segment in a JPEG.
+type jpegSegment struct {
+	marker byte
+
+	// offset is the position of the payload in the file.
+	offset int
+
+	payload []byte
+}
+
+// jpegSegments returns the segments that precede the image data. Parsing
+// stops quietly at the first thing that isn't well-formed.
+func jpegSegments(data []byte) []jpegSegment {
+	segments := make([]jpegSegment, 0)
+
+	if len(data) < 2 || data[0] != jpegMarkerPrefix || data[1] != jpegMarkerSoi {
+		return segments
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != jpegMarkerPrefix {
+			break
+		}
+
+		marker := data[i+1]
+		if marker == jpegMarkerPrefix {
+			// Fill byte.
+			i++
+			continue
+		} else if marker == jpegMarkerSos || marker == jpegMarkerEoi {
+			break
+		} else if marker == jpegMarkerTem || (marker >= jpegMarkerRst0 && marker <= jpegMarkerRst7) {
+			i += 2
+			continue
+		}
+
+		length := uint32(data[i+2])<<8 | uint32(data[i+3])
+		if length < 2 {
+			break
+		}
+
+		// The length includes itself but not the marker. Slice relative to
+		// the marker so that the offset math stays small.
+		payload, err := exifcommon.CheckedSlice(data[i:], 4, length-2)
+		if err != nil {
+			break
+		}
+
+		segment := jpegSegment{
+			marker:  marker,
+			offset:  i + 4,
+			payload: payload,
+		}
+
+		segments = append(segments, segment)
+
+		i += 2 + int(length)
+	}
+
+	return segments
+}
+
+// jpegXmpPackets returns the XMP from the APP1 segments of a JPEG.
+func jpegXmpPackets(data []byte) [][]byte {
+	packets := make([][]byte, 0)
+
+	for _, segment := range jpegSegments(data) {
+		if segment.marker == jpegMarkerApp1 && bytes.HasPrefix(segment.payload, xmpNamespace) == true {
+			packets = append(packets, segment.payload[len(xmpNamespace):])
+		}
+	}
+
+	return packets
+}
+
+// findXmpPacket returns the first XMP packet found anywhere in the data, or
+// nil. This works for containers like HEIC that store XMP as an item rather
+// than in a segment.
+func findXmpPacket(data []byte) []byte {
+	start := bytes.Index(data, xmpPacketStart)
+	if start == -1 {
+		return nil
+	}
+
+	xmp := data[start:]
+	if end := bytes.Index(xmp, xmpPacketEnd); end != -1 {
+		xmp = xmp[:end+len(xmpPacketEnd)]
+	}
+
+	return xmp
+}
+
+// GetJpegXmp returns the XMP packet from a JPEG. `ErrNoXmp` is returned if
+// there isn't one. Extended XMP (split across several segments) isn't
+// supported.
+func GetJpegXmp(data []byte) (xmp []byte, err error) {
+	packets := jpegXmpPackets(data)
+	if len(packets) == 0 {
+		return nil, ErrNoXmp
+	}
+
+	return packets[0], nil
+}
+
+// SetJpegXmp returns a copy of the JPEG with the given XMP packet. An existing
+// XMP segment is replaced. Otherwise, the new one is inserted after any
+// leading APP0/APP1 segments (JFIF and EXIF), where readers expect it.
+func SetJpegXmp(data []byte, xmp []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	segments := jpegSegments(data)
+	if len(segments) == 0 && (len(data) < 2 || data[0] != jpegMarkerPrefix || data[1] != jpegMarkerSoi) {
+		log.Panicf("not a JPEG")
+	}
+
+	payloadLength := len(xmpNamespace) + len(xmp)
+	if 2+payloadLength > 0xffff {
+		log.Panicf("XMP too large for one segment: (%d)", len(xmp))
+	}
+
+	segment := []byte{jpegMarkerPrefix, jpegMarkerApp1, byte((2 + payloadLength) >> 8), byte(2 + payloadLength)}
+	segment = append(segment, xmpNamespace...)
+	segment = append(segment, xmp...)
+
+	// Where the new segment goes and how much of the original it replaces.
+	start := 2
+	end := 2
+
+	for _, s := range segments {
+		segmentStart := s.offset - 4
+		segmentEnd := s.offset + len(s.payload)
+
+		if s.marker == jpegMarkerApp1 && bytes.HasPrefix(s.payload, xmpNamespace) == true {
+			start, end = segmentStart, segmentEnd
+			break
+		} else if s.marker == jpegMarkerApp0 || s.marker == jpegMarkerApp1 {
+			start, end = segmentEnd, segmentEnd
+		} else {
+			break
+		}
+	}
+
+	updated = make([]byte, 0, len(data)+len(segment))
+	updated = append(updated, data[:start]...)
+	updated = append(updated, segment...)
+	updated = append(updated, data[end:]...)
+
+	return updated, nil
+}
+
+// SetJpegExif returns a copy of the JPEG with the given raw EXIF block (the
+// TIFF header and IFDs, as returned by `SearchAndExtractExif()`). The first
+// existing EXIF segment is replaced, wherever it is among the other metadata
+// segments, and any others are removed. Otherwise, the new one is inserted
+// after any leading JFIF segment. An empty block removes the EXIF.
+func SetJpegExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(data) < 2 || data[0] != jpegMarkerPrefix || data[1] != jpegMarkerSoi {
+		log.Panicf("not a JPEG")
+	}
+
+	if len(rawExif) == 0 {
+		updated, err = StripJpegSegments(data, JpegSegmentExif)
+		log.PanicIf(err)
+
+		return updated, nil
+	}
+
+	payloadLength := len(jpegExifPreamble) + len(rawExif)
+	if 2+payloadLength > 0xffff {
+		log.Panicf("EXIF too large for one segment: (%d)", len(rawExif))
+	}
+
+	segment := []byte{jpegMarkerPrefix, jpegMarkerApp1, byte((2 + payloadLength) >> 8), byte(2 + payloadLength)}
+	segment = append(segment, jpegExifPreamble...)
+	segment = append(segment, rawExif...)
+
+	// Where the new segment goes if there's no EXIF segment to replace.
+	insertAt := 2
+	leading := true
+
+	// The EXIF segments, wherever they are among the others (e.g. after an
+	// XMP or ICC segment). The first is replaced and the rest are removed so
+	// that only one is left.
+	exifRanges := make([][2]int, 0)
+
+	for _, s := range jpegSegments(data) {
+		segmentStart := s.offset - 4
+		segmentEnd := s.offset + len(s.payload)
+
+		if s.marker == jpegMarkerApp1 && bytes.HasPrefix(s.payload, jpegExifPreamble) == true {
+			exifRanges = append(exifRanges, [2]int{segmentStart, segmentEnd})
+		} else if s.marker == jpegMarkerApp0 && leading == true {
+			insertAt = segmentEnd
+			continue
+		}
+
+		leading = false
+	}
+
+	updated = make([]byte, 0, len(data)+len(segment))
+
+	if len(exifRanges) == 0 {
+		updated = append(updated, data[:insertAt]...)
+		updated = append(updated, segment...)
+		updated = append(updated, data[insertAt:]...)
+
+		return updated, nil
+	}
+
+	last := 0
+	for i, exifRange := range exifRanges {
+		updated = append(updated, data[last:exifRange[0]]...)
+
+		if i == 0 {
+			updated = append(updated, segment...)
+		}
+
+		last = exifRange[1]
+	}
+
+	updated = append(updated, data[last:]...)
+
+	return updated, nil
+}
+
+// StripJpegSegments returns a copy of the JPEG without the metadata segments
+// of the given kinds (the JpegSegment* constants, e.g. `JpegSegmentExif`).
+// Note that removing anything invalidates a C2PA manifest store (see
+// `GetC2pa()`).
+func StripJpegSegments(data []byte, kinds ...string) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	strip := make(map[string]bool)
+	for _, kind := range kinds {
+		strip[kind] = true
+	}
+
+	updated = make([]byte, 0, len(data))
+	last := 0
+
+	for _, segment := range si.Segments {
+		if strip[segment.Kind] == false {
+			continue
+		}
+
+		updated = append(updated, data[last:segment.Offset]...)
+		last = segment.Offset + segment.Size
+	}
+
+	updated = append(updated, data[last:]...)
+
+	return updated, nil
+}
+
+// SegmentInfo describes one metadata segment in a JPEG.
+type SegmentInfo struct {
+	// Kind is one of the JpegSegment* constants.
+	Kind   string
+	Marker byte
+
+	// Offset is the position of the marker in the file and Size is the size
+	// of the whole segment, including the marker and the length.
+	Offset int
+	Size   int
+
+	// DataOffset is the position of the data following the segment's
+	// signature (e.g. the TIFF header for EXIF, or the chunk number and count
+	// that precede each piece of an ICC profile), and DataSize is its size.
+	DataOffset int
+	DataSize   int
+}
+
+// String returns a descriptive string.
+func (si SegmentInfo) String() string {
+	return fmt.Sprintf("SegmentInfo<KIND=[%s] MARKER=(0x%02x) OFFSET=(%d) SIZE=(%d) DATA-OFFSET=(%d) DATA-SIZE=(%d)>", si.Kind, si.Marker, si.Offset, si.Size, si.DataOffset, si.DataSize)
+}
+
+// SegmentsInfo lists the metadata segments of a JPEG in file order.
+type SegmentsInfo struct {
+	Segments []SegmentInfo
+
+	// IccProfile is the profile reassembled from the ICC segments, or nil if
+	// there isn't one or it couldn't be read.
+	IccProfile *IccProfile
+}
+
+// Has returns true if there's at least one segment of the given kind.
+func (si SegmentsInfo) Has(kind string) bool {
+	return len(si.Find(kind)) > 0
+}
+
+// Find returns the segments of the given kind, in file order. Some kinds
+// (ICC and extended XMP) are normally split across several segments.
+func (si SegmentsInfo) Find(kind string) []SegmentInfo {
+	found := make([]SegmentInfo, 0)
+
+	for _, segment := range si.Segments {
+		if segment.Kind == kind {
+			found = append(found, segment)
+		}
+	}
+
+	return found
+}
+
+// GetJpegSegmentsInfo returns the APPn and COM segments that precede the
+// image data, along with which standard each belongs to, and the ICC profile
+// if there is one. `ErrNotJpeg` is returned if the data doesn't start with a
+// SOI marker.
+func GetJpegSegmentsInfo(data []byte) (si SegmentsInfo, err error) {
+	if len(data) < 2 || data[0] != jpegMarkerPrefix || data[1] != jpegMarkerSoi {
+		return si, ErrNotJpeg
+	}
+
+	si.Segments = make([]SegmentInfo, 0)
+
+	segments := jpegSegments(data)
+	c2paInstances := jpegC2paInstances(segments)
+
+	for _, segment := range segments {
+		isApp := segment.marker >= jpegMarkerApp0 && segment.marker <= jpegMarkerApp15
+		if isApp == false && segment.marker != jpegMarkerCom {
+			continue
+		}
+
+		info := SegmentInfo{
+			Kind:       JpegSegmentOther,
+			Marker:     segment.marker,
+			Offset:     segment.offset - 4,
+			Size:       4 + len(segment.payload),
+			DataOffset: segment.offset,
+			DataSize:   len(segment.payload),
+		}
+
+		if segment.marker == jpegMarkerCom {
+			info.Kind = JpegSegmentComment
+		}
+
+		for _, signature := range jpegSegmentSignatures {
+			if segment.marker == signature.marker && bytes.HasPrefix(segment.payload, signature.prefix) == true {
+				info.Kind = signature.kind
+				info.DataOffset += len(signature.prefix)
+				info.DataSize -= len(signature.prefix)
+
+				break
+			}
+		}
+
+		if isJpegC2paSegment(segment, c2paInstances) == true {
+			info.Kind = JpegSegmentC2pa
+			info.DataOffset += jumbfPacketHeaderSize
+			info.DataSize -= jumbfPacketHeaderSize
+		}
+
+		si.Segments = append(si.Segments, info)
+	}
+
+	if si.Has(JpegSegmentIcc) == true {
+		ip, err := GetJpegIccProfile(data)
+		if err == nil {
+			si.IccProfile = &ip
+		} else {
+			iccLogger.Warningf(nil, "Could not read ICC profile: %s", err)
+		}
+	}
+
+	return si, nil
+}
+
+// replaceJpegSegmentData returns a copy of the JPEG with the data of the given
+// segment (the part after its signature) replaced.
+func replaceJpegSegmentData(data []byte, segment SegmentInfo, segmentData []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	signature := data[segment.Offset+4 : segment.DataOffset]
+
+	length := 2 + len(signature) + len(segmentData)
+	if length > 0xffff {
+		log.Panicf("data too large for one segment: (%d)", len(segmentData))
+	}
+
+	updated = make([]byte, 0, len(data)-segment.DataSize+len(segmentData))
+	updated = append(updated, data[:segment.Offset]...)
+	updated = append(updated, jpegMarkerPrefix, segment.Marker, byte(length>>8), byte(length))
+	updated = append(updated, signature...)
+	updated = append(updated, segmentData...)
+	updated = append(updated, data[segment.Offset+segment.Size:]...)
+
+	return updated, nil
+}
+
+// readJpegMetadata returns the EXIF index, the XMP packet, and the IPTC
+// datasets of a JPEG. Whichever of them the JPEG doesn't have is empty.
+func readJpegMetadata(data []byte) (index IfdIndex, xmp []byte, iptc []IptcDataset, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	if segments := si.Find(JpegSegmentExif); len(segments) > 0 {
+		rawExif := data[segments[0].DataOffset : segments[0].DataOffset+segments[0].DataSize]
+
+		_, index, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+		log.PanicIf(err)
+	}
+
+	xmp, err = GetJpegXmp(data)
+	if err != nil && err != ErrNoXmp {
+		log.Panic(err)
+	}
+
+	iptc, err = GetJpegIptc(data)
+	if err != nil && err != ErrNoIptc {
+		log.Panic(err)
+	}
+
+	return index, xmp, iptc, nil
+}
+
+// patchJpegExif returns a copy of the JPEG with its EXIF modified by the given
+// function (see `ExifPatcher`). The JPEG is returned as-is if it doesn't have
+// any EXIF.
+func patchJpegExif(data []byte, patch func(ep *ExifPatcher) error) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	segments := si.Find(JpegSegmentExif)
+	if len(segments) == 0 {
+		return data, nil
+	}
+
+	segment := segments[0]
+	rawExif := data[segment.DataOffset : segment.DataOffset+segment.DataSize]
+
+	ep, err := NewExifPatcher(rawExif, NewIfdMappingWithStandard(), NewTagIndex())
+	log.PanicIf(err)
+
+	err = patch(ep)
+	log.PanicIf(err)
+
+	patched, err := ep.Encode()
+	log.PanicIf(err)
+
+	updated, err = replaceJpegSegmentData(data, segment, patched)
+	log.PanicIf(err)
+
+	return updated, nil
+}
@@ -0,0 +1,264 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	jpegStreamLogger = log.NewLogger("exif.jpeg_stream")
+)
+
+var (
+	// errJpegStreamLostSync means that something other than a marker was
+	// found where one was expected.
+	errJpegStreamLostSync = errors.New("lost sync with jpeg markers")
+)
+
+// StreamImage is one image found by a `JpegStreamScanner`.
+type StreamImage struct {
+	// Offset is the position of the SOI marker in the stream.
+	Offset int64
+
+	// Size is the number of bytes from the SOI marker through the EOI marker.
+	Size int64
+
+	// RawExif is the EXIF block from the first EXIF APP1 segment (starting at
+	// the byte-order), or nil if the image doesn't have one.
+	RawExif []byte
+}
+
+// String returns a descriptive string.
+func (si StreamImage) String() string {
+	return fmt.Sprintf("StreamImage<OFFSET=(%d) SIZE=(%d) EXIF-SIZE=(%d)>", si.Offset, si.Size, len(si.RawExif))
+}
+
+// JpegStreamScanner finds the JPEGs in a stream that contains several of them
+// back-to-back (e.g. MJPEG, or files that were concatenated or piped
+// together) and returns the EXIF of each. It's used like a `bufio.Scanner`:
+//
+//	jss := NewJpegStreamScanner(r)
+//	for jss.Scan() == true {
+//	    si := jss.Image()
+//	    // ...
+//	}
+//
+//	if err := jss.Err(); err != nil {
+//	    // ...
+//	}
+//
+// Only the EXIF is kept in memory. The image data is read through until the
+// EOI marker and then the search for the next SOI marker resumes. Anything
+// between images (e.g. multipart boundaries) is skipped, as is an image
+// whose markers stop making sense. An image that is cut off by the start of
+// another is dropped.
+type JpegStreamScanner struct {
+	br       *bufio.Reader
+	position int64
+
+	image StreamImage
+	err   error
+}
+
+// NewJpegStreamScanner returns a scanner that reads from the given stream.
+func NewJpegStreamScanner(r io.Reader) *JpegStreamScanner {
+	return &JpegStreamScanner{
+		br: bufio.NewReader(r),
+	}
+}
+
+// Scan advances to the next image. It returns false at the end of the stream
+// or after an error.
+func (jss *JpegStreamScanner) Scan() bool {
+	if jss.err != nil {
+		return false
+	}
+
+	image, found, err := jss.next()
+	if err != nil {
+		jss.err = err
+		return false
+	}
+
+	jss.image = image
+
+	return found
+}
+
+// Image returns the image found by the last call to `Scan()`.
+func (jss *JpegStreamScanner) Image() StreamImage {
+	return jss.image
+}
+
+// Err returns the first error that was encountered. The stream ending in the
+// middle of an image is reported as `io.ErrUnexpectedEOF`. The end of the
+// stream otherwise isn't an error.
+func (jss *JpegStreamScanner) Err() error {
+	return jss.err
+}
+
+func (jss *JpegStreamScanner) readByte() byte {
+	b, err := jss.br.ReadByte()
+	if err == io.EOF {
+		log.Panic(io.ErrUnexpectedEOF)
+	}
+
+	log.PanicIf(err)
+
+	jss.position++
+
+	return b
+}
+
+func (jss *JpegStreamScanner) readFull(p []byte) {
+	n, err := io.ReadFull(jss.br, p)
+	jss.position += int64(n)
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		log.Panic(io.ErrUnexpectedEOF)
+	}
+
+	log.PanicIf(err)
+}
+
+func (jss *JpegStreamScanner) discard(n int) {
+	discarded, err := jss.br.Discard(n)
+	jss.position += int64(discarded)
+
+	if err == io.EOF {
+		log.Panic(io.ErrUnexpectedEOF)
+	}
+
+	log.PanicIf(err)
+}
+
+// findSoi reads up to and including the next SOI marker and returns its
+// position. `found` is false if the stream ended first.
+func (jss *JpegStreamScanner) findSoi() (offset int64, found bool, err error) {
+	previous := byte(0)
+	for {
+		b, err := jss.br.ReadByte()
+		if err == io.EOF {
+			return 0, false, nil
+		} else if err != nil {
+			return 0, false, err
+		}
+
+		jss.position++
+
+		if previous == jpegMarkerPrefix && b == jpegMarkerSoi {
+			return jss.position - 2, true, nil
+		}
+
+		previous = b
+	}
+}
+
+// next returns the next complete image in the stream.
+func (jss *JpegStreamScanner) next() (image StreamImage, found bool, err error) {
+	for {
+		offset, found, err := jss.findSoi()
+		if err != nil {
+			return image, false, err
+		} else if found == false {
+			return image, false, nil
+		}
+
+		image, err = jss.readImage(offset)
+		if err == errJpegStreamLostSync {
+			jpegStreamLogger.Warningf(nil, "Skipping malformed image at offset (%d).", offset)
+			continue
+		} else if err != nil {
+			return image, false, err
+		}
+
+		return image, true, nil
+	}
+}
+
+// readImage reads the rest of the image whose SOI marker has just been read.
+func (jss *JpegStreamScanner) readImage(offset int64) (image StreamImage, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+
+			// These are expected and are returned as-is so they can be
+			// compared against.
+			if log.Is(err, io.ErrUnexpectedEOF) == true {
+				err = io.ErrUnexpectedEOF
+			} else if log.Is(err, errJpegStreamLostSync) == true {
+				err = errJpegStreamLostSync
+			}
+		}
+	}()
+
+	image.Offset = offset
+
+	// We're in entropy-coded data once a SOS marker has been read. Markers
+	// can still appear between scans (e.g. progressive JPEGs).
+	inScan := false
+
+	for {
+		b := jss.readByte()
+		if b != jpegMarkerPrefix {
+			if inScan == true {
+				continue
+			}
+
+			log.Panic(errJpegStreamLostSync)
+		}
+
+		marker := jss.readByte()
+		for marker == jpegMarkerPrefix {
+			marker = jss.readByte()
+		}
+
+		if marker == 0x00 && inScan == true {
+			// A stuffed 0xff in the entropy-coded data.
+			continue
+		} else if marker == jpegMarkerTem || (marker >= jpegMarkerRst0 && marker <= jpegMarkerRst7) {
+			continue
+		} else if marker == jpegMarkerEoi {
+			image.Size = jss.position - offset
+			return image, nil
+		} else if marker == jpegMarkerSoi {
+			// The image we were reading was cut short and another one
+			// starts here.
+			offset = jss.position - 2
+			image = StreamImage{
+				Offset: offset,
+			}
+
+			inScan = false
+			continue
+		} else if marker == 0x00 {
+			log.Panic(errJpegStreamLostSync)
+		}
+
+		lengthBytes := make([]byte, 2)
+		jss.readFull(lengthBytes)
+
+		length := int(lengthBytes[0])<<8 | int(lengthBytes[1])
+		if length < 2 {
+			log.Panic(errJpegStreamLostSync)
+		}
+
+		if marker == jpegMarkerApp1 && image.RawExif == nil {
+			payload := make([]byte, length-2)
+			jss.readFull(payload)
+
+			if bytes.HasPrefix(payload, jpegExifPreamble) == true {
+				image.RawExif = payload[len(jpegExifPreamble):]
+			}
+		} else {
+			jss.discard(length - 2)
+		}
+
+		inScan = marker == jpegMarkerSos
+	}
+}
@@ -0,0 +1,120 @@
+package exif
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getTestJpegData() []byte {
+	data, err := ioutil.ReadFile(getTestImageFilepath())
+	log.PanicIf(err)
+
+	return data
+}
+
+func TestJpegStreamScanner_Scan(t *testing.T) {
+	jpegData := getTestJpegData()
+
+	// A JPEG without any EXIF.
+	plainJpeg := []byte{
+		0xff, 0xd8,
+		0xff, 0xe0, 0x00, 0x04, 0x00, 0x00,
+		0xff, 0xda, 0x00, 0x02,
+		0x12, 0xff, 0x00, 0x34, 0xff, 0xd0, 0x56,
+		0xff, 0xd9,
+	}
+
+	b := new(bytes.Buffer)
+	b.WriteString("--boundary\r\n\r\n")
+	b.Write(jpegData)
+	b.WriteString("\r\n--boundary\r\n\r\n")
+	b.Write(plainJpeg)
+	b.Write(jpegData)
+	b.WriteString("\r\n--boundary--\r\n")
+
+	jss := NewJpegStreamScanner(b)
+
+	images := make([]StreamImage, 0)
+	for jss.Scan() == true {
+		images = append(images, jss.Image())
+	}
+
+	err := jss.Err()
+	log.PanicIf(err)
+
+	if len(images) != 3 {
+		t.Fatalf("Image count not correct: (%d)", len(images))
+	}
+
+	expectedExif := getTestExifData()
+
+	first := images[0]
+	if first.Offset != 14 || first.Size != int64(len(jpegData)) {
+		t.Fatalf("First image not correct: %s", first)
+	} else if bytes.Equal(first.RawExif, expectedExif) != true {
+		t.Fatalf("First image EXIF not correct.")
+	}
+
+	second := images[1]
+	if second.Offset != first.Offset+first.Size+16 || second.Size != int64(len(plainJpeg)) {
+		t.Fatalf("Second image not correct: %s", second)
+	} else if second.RawExif != nil {
+		t.Fatalf("Second image should not have EXIF.")
+	}
+
+	third := images[2]
+	if third.Offset != second.Offset+second.Size || third.Size != int64(len(jpegData)) {
+		t.Fatalf("Third image not correct: %s", third)
+	} else if bytes.Equal(third.RawExif, expectedExif) != true {
+		t.Fatalf("Third image EXIF not correct.")
+	}
+
+	_, _, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), third.RawExif)
+	log.PanicIf(err)
+}
+
+func TestJpegStreamScanner_Scan_Truncated(t *testing.T) {
+	jpegData := getTestJpegData()
+
+	b := new(bytes.Buffer)
+	b.Write(jpegData)
+	b.Write(jpegData[:len(jpegData)/2])
+
+	jss := NewJpegStreamScanner(b)
+
+	if jss.Scan() != true {
+		t.Fatalf("First image not found: %v", jss.Err())
+	} else if jss.Scan() != false {
+		t.Fatalf("Truncated image should not be returned.")
+	} else if jss.Err() != io.ErrUnexpectedEOF {
+		t.Fatalf("Expected io.ErrUnexpectedEOF: %v", jss.Err())
+	}
+}
+
+func TestJpegStreamScanner_Scan_Interrupted(t *testing.T) {
+	jpegData := getTestJpegData()
+
+	b := new(bytes.Buffer)
+	b.Write(jpegData[:len(jpegData)/2])
+	b.Write(jpegData)
+
+	jss := NewJpegStreamScanner(b)
+
+	if jss.Scan() != true {
+		t.Fatalf("Image not found: %v", jss.Err())
+	}
+
+	si := jss.Image()
+	if si.Offset != int64(len(jpegData)/2) || si.Size != int64(len(jpegData)) {
+		t.Fatalf("Image not correct: %s", si)
+	} else if jss.Scan() != false {
+		t.Fatalf("Expected only one image.")
+	} else if jss.Err() != nil {
+		t.Fatalf("Expected no error: %v", jss.Err())
+	}
+}
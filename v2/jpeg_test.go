@@ -0,0 +1,235 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestJpegSegments(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	segments := jpegSegments(data)
+	if len(segments) == 0 {
+		t.Fatalf("No segments found.")
+	} else if segments[0].marker != jpegMarkerApp1 || bytes.HasPrefix(segments[0].payload, jpegExifPreamble) != true {
+		t.Fatalf("First segment not correct.")
+	} else if segments[0].offset != 6 {
+		t.Fatalf("Offset not correct: (%d)", segments[0].offset)
+	}
+}
+
+func TestSetJpegXmp(t *testing.T) {
+	data := []byte{jpegMarkerPrefix, jpegMarkerSoi, jpegMarkerPrefix, jpegMarkerEoi}
+
+	_, err := GetJpegXmp(data)
+	if err != ErrNoXmp {
+		t.Fatalf("Expected ErrNoXmp: %v", err)
+	}
+
+	updated, err := SetJpegXmp(data, []byte("<x:xmpmeta/>"))
+	log.PanicIf(err)
+
+	xmp, err := GetJpegXmp(updated)
+	log.PanicIf(err)
+
+	if string(xmp) != "<x:xmpmeta/>" {
+		t.Fatalf("XMP not correct: [%s]", xmp)
+	} else if bytes.HasSuffix(updated, []byte{jpegMarkerPrefix, jpegMarkerEoi}) != true {
+		t.Fatalf("Rest of the image not preserved.")
+	}
+}
+
+func TestSetJpegXmp_NotJpeg(t *testing.T) {
+	_, err := SetJpegXmp([]byte("not a jpeg"), []byte("<x:xmpmeta/>"))
+	if err == nil {
+		t.Fatalf("Expected error for non-JPEG.")
+	}
+}
+
+func TestStripJpegSegments(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	data, err := SetJpegXmp(data, []byte("<x:xmpmeta/>"))
+	log.PanicIf(err)
+
+	stripped, err := StripJpegSegments(data, JpegSegmentExif, JpegSegmentXmp)
+	log.PanicIf(err)
+
+	si, err := GetJpegSegmentsInfo(stripped)
+	log.PanicIf(err)
+
+	if si.Has(JpegSegmentExif) == true || si.Has(JpegSegmentXmp) == true {
+		t.Fatalf("Segments not stripped: %v", si.Segments)
+	} else if bytes.Equal(stripped[:2], data[:2]) == false {
+		t.Fatalf("SOI not preserved.")
+	} else if bytes.HasSuffix(stripped, data[len(data)-100:]) == false {
+		t.Fatalf("Image data not preserved.")
+	}
+
+	_, err = SearchAndExtractExif(stripped)
+	if err != ErrNoExif {
+		t.Fatalf("Expected no EXIF: %v", err)
+	}
+
+	unchanged, err := StripJpegSegments(data, JpegSegmentIcc)
+	log.PanicIf(err)
+
+	if bytes.Equal(unchanged, data) == false {
+		t.Fatalf("Data changed without matching segments.")
+	}
+}
+
+func TestSetJpegExif(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	rawExif, err := SearchAndExtractExif(data)
+	log.PanicIf(err)
+
+	stripped, err := SetJpegExif(data, nil)
+	log.PanicIf(err)
+
+	_, err = SearchAndExtractExif(stripped)
+	if err != ErrNoExif {
+		t.Fatalf("Expected no EXIF: %v", err)
+	}
+
+	restored, err := SetJpegExif(stripped, rawExif)
+	log.PanicIf(err)
+
+	recovered, err := SearchAndExtractExif(restored)
+	log.PanicIf(err)
+
+	if bytes.HasPrefix(recovered, rawExif) == false {
+		t.Fatalf("EXIF not inserted.")
+	}
+
+	replaced, err := SetJpegExif(restored, rawExif)
+	log.PanicIf(err)
+
+	if bytes.Equal(replaced, restored) == false {
+		t.Fatalf("EXIF not replaced in place.")
+	}
+
+	_, err = SetJpegExif([]byte("not a jpeg"), rawExif)
+	if err == nil {
+		t.Fatalf("Expected error for non-JPEG.")
+	}
+}
+
+func TestSetJpegExif_AfterOtherSegments(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	rawExif, err := SearchAndExtractExif(data)
+	log.PanicIf(err)
+
+	stripped, err := SetJpegExif(data, nil)
+	log.PanicIf(err)
+
+	withXmp, err := SetJpegXmp(stripped, []byte("<x:xmpmeta/>"))
+	log.PanicIf(err)
+
+	si, err := GetJpegSegmentsInfo(withXmp)
+	log.PanicIf(err)
+
+	xmpSegment := si.Find(JpegSegmentXmp)[0]
+	insertAt := xmpSegment.Offset + xmpSegment.Size
+
+	// Two EXIF segments that both follow the XMP.
+	withExif, err := SetJpegExif(stripped, rawExif)
+	log.PanicIf(err)
+
+	si, err = GetJpegSegmentsInfo(withExif)
+	log.PanicIf(err)
+
+	exifSegment := si.Find(JpegSegmentExif)[0]
+	segment := withExif[exifSegment.Offset : exifSegment.Offset+exifSegment.Size]
+
+	original := make([]byte, 0)
+	original = append(original, withXmp[:insertAt]...)
+	original = append(original, segment...)
+	original = append(original, segment...)
+	original = append(original, withXmp[insertAt:]...)
+
+	updatedExif := getExifSimpleTestIbBytes()
+
+	updated, err := SetJpegExif(original, updatedExif)
+	log.PanicIf(err)
+
+	si, err = GetJpegSegmentsInfo(updated)
+	log.PanicIf(err)
+
+	if len(si.Find(JpegSegmentExif)) != 1 {
+		t.Fatalf("Expected one EXIF segment: (%d)", len(si.Find(JpegSegmentExif)))
+	} else if len(si.Find(JpegSegmentXmp)) != 1 {
+		t.Fatalf("XMP segment not kept.")
+	} else if si.Find(JpegSegmentExif)[0].Offset != insertAt {
+		t.Fatalf("EXIF not replaced in place: (%d) != (%d)", si.Find(JpegSegmentExif)[0].Offset, insertAt)
+	}
+
+	recovered, err := SearchAndExtractExif(updated)
+	log.PanicIf(err)
+
+	if bytes.HasPrefix(recovered, updatedExif) == false {
+		t.Fatalf("EXIF not updated.")
+	} else if len(updated) != len(withXmp)+len(jpegExifPreamble)+len(updatedExif)+4 {
+		t.Fatalf("Size not correct: (%d)", len(updated))
+	}
+}
+
+func TestGetJpegSegmentsInfo(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	data, err := SetJpegXmp(data, []byte("<x:xmpmeta/>"))
+	log.PanicIf(err)
+
+	iccPayload := append([]byte("ICC_PROFILE\x00\x01\x01"), make([]byte, 10)...)
+	iccSegment := []byte{jpegMarkerPrefix, jpegMarkerApp2, 0, byte(2 + len(iccPayload))}
+	iccSegment = append(iccSegment, iccPayload...)
+
+	updated := make([]byte, 0)
+	updated = append(updated, data[:2]...)
+	updated = append(updated, iccSegment...)
+	updated = append(updated, data[2:]...)
+
+	si, err := GetJpegSegmentsInfo(updated)
+	log.PanicIf(err)
+
+	if si.Has(JpegSegmentExif) != true {
+		t.Fatalf("EXIF segment not found.")
+	} else if si.Has(JpegSegmentXmp) != true {
+		t.Fatalf("XMP segment not found.")
+	} else if si.Has(JpegSegmentPhotoshop) != false {
+		t.Fatalf("Photoshop segment should not be found.")
+	}
+
+	icc := si.Find(JpegSegmentIcc)
+	if len(icc) != 1 {
+		t.Fatalf("ICC segment not found.")
+	} else if icc[0].Offset != 2 || icc[0].Size != len(iccSegment) {
+		t.Fatalf("ICC segment not correct: %s", icc[0])
+	}
+
+	exifSegment := si.Find(JpegSegmentExif)[0]
+	if exifSegment.Offset != 2+len(iccSegment) || exifSegment.Marker != jpegMarkerApp1 {
+		t.Fatalf("EXIF segment not correct: %s", exifSegment)
+	}
+
+	rawExif := updated[exifSegment.DataOffset : exifSegment.DataOffset+exifSegment.DataSize]
+	if bytes.Equal(rawExif, getTestExifData()) != true {
+		t.Fatalf("EXIF data offset not correct: %s", exifSegment)
+	}
+
+	xmpSegment := si.Find(JpegSegmentXmp)[0]
+	if string(updated[xmpSegment.DataOffset:xmpSegment.DataOffset+xmpSegment.DataSize]) != "<x:xmpmeta/>" {
+		t.Fatalf("XMP data offset not correct: %s", xmpSegment)
+	}
+}
+
+func TestGetJpegSegmentsInfo_NotJpeg(t *testing.T) {
+	_, err := GetJpegSegmentsInfo([]byte("not a jpeg"))
+	if err != ErrNotJpeg {
+		t.Fatalf("Expected ErrNotJpeg: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"io"
+	"sync"
+
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// JsonLinesRecord is the object written for each file.
+type JsonLinesRecord struct {
+	// Name identifies the file (e.g. its path).
+	Name string `json:"name"`
+
+	// Error is the error encountered while parsing the file, if any.
+	Error string `json:"error,omitempty"`
+
+	// ByteOrder is "BigEndian" or "LittleEndian".
+	ByteOrder string `json:"byte_order,omitempty"`
+
+	Tags []ExifTag `json:"tags,omitempty"`
+}
+
+// JsonLinesEmitter writes one JSON object per file, each on its own line
+// (JSON Lines, or ndjson), as the files are parsed, so that the output can be
+// streamed into jq or loaded into BigQuery and the like without waiting for a
+// batch to finish. It's safe to emit from more than one goroutine.
+type JsonLinesEmitter struct {
+	m       sync.Mutex
+	encoder *json.Encoder
+	count   int
+}
+
+// NewJsonLinesEmitter returns an emitter that writes to the given writer.
+func NewJsonLinesEmitter(w io.Writer) *JsonLinesEmitter {
+	return &JsonLinesEmitter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// Count returns the number of records that were written.
+func (jle *JsonLinesEmitter) Count() int {
+	jle.m.Lock()
+	defer jle.m.Unlock()
+
+	return jle.count
+}
+
+// EmitRecord writes the record as a single line.
+func (jle *JsonLinesEmitter) EmitRecord(record JsonLinesRecord) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	jle.m.Lock()
+	defer jle.m.Unlock()
+
+	// The encoder terminates every value with a newline and never writes one
+	// within it.
+	err = jle.encoder.Encode(record)
+	log.PanicIf(err)
+
+	jle.count++
+
+	return nil
+}
+
+// Emit writes the result of `ParseMany()`. It can be given as
+// `ParseManyOptions.OnResult` to write each result as soon as it's ready.
+func (jle *JsonLinesEmitter) Emit(pr ParseResult) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	record := JsonLinesRecord{
+		Name: pr.Name,
+	}
+
+	if pr.Err != nil {
+		record.Error = pr.Err.Error()
+	} else {
+		record.ByteOrder = pr.Header.ByteOrder.String()
+
+		record.Tags, err = pr.Index.flatTags()
+		log.PanicIf(err)
+	}
+
+	err = jle.EmitRecord(record)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// EmitArchiveEntry writes the result for an image in an archive. It can be
+// called from an `ArchiveEntryHandlerFn`.
+func (jle *JsonLinesEmitter) EmitArchiveEntry(ae ArchiveEntry) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	record := JsonLinesRecord{
+		Name: ae.Name,
+	}
+
+	if ae.Err != nil {
+		record.Error = ae.Err.Error()
+	} else {
+		record.ByteOrder = ae.Header.ByteOrder.String()
+		record.Tags = ae.Tags
+	}
+
+	err = jle.EmitRecord(record)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// flatTags returns the flat representation of every tag in the index, IFD by
+// IFD in the order that they were parsed. Tags whose values can't be parsed
+// are skipped.
+func (index IfdIndex) flatTags() (exifTags []ExifTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	exifTags = make([]ExifTag, 0)
+
+	for _, ifd := range index.Ifds {
+		for _, ite := range ifd.Entries {
+			et, ok, err := newExifTag(ifd.tagIndex, ifd.IfdPath, ite)
+			log.PanicIf(err)
+
+			if ok == true {
+				exifTags = append(exifTags, et)
+			}
+		}
+	}
+
+	return exifTags, nil
+}
@@ -0,0 +1,111 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestJsonLinesEmitter_Emit(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	sources := []ParseSource{
+		BytesSource("good.jpg", exiftest.WrapJpeg(rawExif)),
+		BytesSource("bad.jpg", []byte("not an image")),
+	}
+
+	b := new(bytes.Buffer)
+	jle := NewJsonLinesEmitter(b)
+
+	_, err = ParseMany(context.Background(), sources, ParseManyOptions{OnResult: jle.Emit})
+	log.PanicIf(err)
+
+	if jle.Count() != 2 {
+		t.Fatalf("Record count not correct: (%d)", jle.Count())
+	}
+
+	records := make(map[string]JsonLinesRecord)
+
+	scanner := bufio.NewScanner(b)
+	for scanner.Scan() == true {
+		var record JsonLinesRecord
+
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		log.PanicIf(err)
+
+		records[record.Name] = record
+	}
+
+	good := records["good.jpg"]
+	bad := records["bad.jpg"]
+
+	if len(records) != 2 {
+		t.Fatalf("Line count not correct: (%d)", len(records))
+	} else if good.Error != "" || good.ByteOrder != "BigEndian" {
+		t.Fatalf("Good record not correct: %v", good)
+	} else if bad.Error != ErrNoExif.Error() || len(bad.Tags) != 0 {
+		t.Fatalf("Bad record not correct: %v", bad)
+	}
+
+	names := make(map[string]string)
+	for _, et := range good.Tags {
+		names[et.IfdPath+"/"+et.TagName] = et.TagTypeName
+	}
+
+	if names["IFD/Make"] != "ASCII" || names["IFD/Exif/DateTimeOriginal"] != "ASCII" || names["IFD/GPSInfo/GPSLatitude"] != "RATIONAL" {
+		t.Fatalf("Tags not correct: %v", names)
+	}
+}
+
+func TestJsonLinesEmitter_EmitArchiveEntry(t *testing.T) {
+	b := new(bytes.Buffer)
+	jle := NewJsonLinesEmitter(b)
+
+	err := jle.EmitArchiveEntry(ArchiveEntry{Name: "a/b.jpg", Err: ErrNoExif})
+	log.PanicIf(err)
+
+	if b.String() != "{\"name\":\"a/b.jpg\",\"error\":\"no exif data\"}\n" {
+		t.Fatalf("Line not correct: %q", b.String())
+	}
+}
+
+func TestParseMany_OnResultError(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	sources := make([]ParseSource, 10)
+	for i := range sources {
+		sources[i] = BytesSource("image.jpg", exiftest.WrapJpeg(rawExif))
+	}
+
+	calls := 0
+	handlerErr := errors.New("write failed")
+
+	opts := ParseManyOptions{
+		Concurrency: 1,
+		OnResult: func(pr ParseResult) (err error) {
+			calls++
+			return handlerErr
+		},
+	}
+
+	_, err = ParseMany(context.Background(), sources, opts)
+	if err != handlerErr {
+		t.Fatalf("Expected handler error: %v", err)
+	} else if calls != 1 {
+		t.Fatalf("Handler called after it failed: (%d)", calls)
+	}
+}
@@ -0,0 +1,239 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// XpKeywordsTagId is the ID of the Windows XPKeywords tag in IFD0.
+	XpKeywordsTagId = 0x9c9e
+)
+
+// KeywordsInfo is the keywords from each of the places they're commonly
+// stored.
+type KeywordsInfo struct {
+	// Xp is XPKeywords, which Windows stores as semicolon-separated UCS-2.
+	Xp []string
+
+	// Xmp is dc:subject.
+	Xmp []string
+
+	// Iptc is Keywords (2:25).
+	Iptc []string
+}
+
+// String returns a descriptive string.
+func (ki KeywordsInfo) String() string {
+	return fmt.Sprintf("KeywordsInfo<XP=%v XMP=%v IPTC=%v>", ki.Xp, ki.Xmp, ki.Iptc)
+}
+
+// All returns the keywords from every source, with duplicates removed. Two
+// keywords are duplicates if they only differ in case or surrounding
+// whitespace, in which case the first spelling is kept. XMP is taken first,
+// then IPTC, and then XPKeywords.
+func (ki KeywordsInfo) All() []string {
+	all := make([]string, 0, len(ki.Xmp)+len(ki.Iptc)+len(ki.Xp))
+	all = append(all, ki.Xmp...)
+	all = append(all, ki.Iptc...)
+	all = append(all, ki.Xp...)
+
+	return dedupeKeywords(all)
+}
+
+// dedupeKeywords returns the keywords without blanks or duplicates, in their
+// original order.
+func dedupeKeywords(keywords []string) []string {
+	deduped := make([]string, 0, len(keywords))
+	seen := make(map[string]struct{})
+
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			continue
+		}
+
+		key := strings.ToLower(keyword)
+		if _, found := seen[key]; found == true {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		deduped = append(deduped, keyword)
+	}
+
+	return deduped
+}
+
+// decodeXpString decodes the NUL-terminated UTF-16LE that the Windows XP*
+// tags use.
+func decodeXpString(raw []byte) string {
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+
+	for i, unit := range units {
+		if unit == 0 {
+			units = units[:i]
+			break
+		}
+	}
+
+	return string(utf16.Decode(units))
+}
+
+// encodeXpString encodes the string the way the Windows XP* tags expect.
+func encodeXpString(s string) []byte {
+	units := utf16.Encode([]rune(s))
+
+	raw := make([]byte, len(units)*2+2)
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(raw[i*2:], unit)
+	}
+
+	return raw
+}
+
+// GetKeywords collects the keywords from XPKeywords, the XMP packet, and the
+// IPTC datasets (either of the last two may be nil).
+func GetKeywords(index IfdIndex, xmp []byte, iptc []IptcDataset) (ki KeywordsInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ki.Xp = make([]string, 0)
+
+	if index.RootIfd != nil {
+		results, err := index.RootIfd.FindTagWithId(XpKeywordsTagId)
+		if err == nil {
+			raw, err := results[0].GetRawBytes()
+			log.PanicIf(err)
+
+			ki.Xp = dedupeKeywords(strings.Split(decodeXpString(raw), ";"))
+		} else if log.Is(err, ErrTagNotFound) == false {
+			log.Panic(err)
+		}
+	}
+
+	ki.Xmp = xmpArrayItems(xmp, "dc:subject")
+	ki.Iptc = IptcStrings(iptc, IptcRecordApplication, IptcDatasetKeywords)
+
+	return ki, nil
+}
+
+// GetJpegKeywords is a convenience function that reads the EXIF, XMP, and
+// IPTC from a JPEG and calls `GetKeywords()`.
+func GetJpegKeywords(data []byte) (ki KeywordsInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	index, xmp, iptc, err := readJpegMetadata(data)
+	log.PanicIf(err)
+
+	ki, err = GetKeywords(index, xmp, iptc)
+	log.PanicIf(err)
+
+	return ki, nil
+}
+
+// SetXpKeywords writes the XPKeywords tag into IFD0. The tag is removed if
+// there are no keywords.
+func SetXpKeywords(rootIb *IfdBuilder, keywords []string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if rootIb.ifdPath != exifcommon.IfdStandard {
+		log.Panicf("keywords can only be set on the root IFD: [%s]", rootIb.fqIfdPath)
+	}
+
+	keywords = dedupeKeywords(keywords)
+
+	if len(keywords) == 0 {
+		_, err := rootIb.DeleteAll(XpKeywordsTagId)
+		log.PanicIf(err)
+
+		return nil
+	}
+
+	err = rootIb.SetStandard(XpKeywordsTagId, encodeXpString(strings.Join(keywords, ";")))
+	log.PanicIf(err)
+
+	return nil
+}
+
+// MergeKeywordsIntoXmp returns the XMP packet with dc:subject set to the
+// keywords (removing it if there aren't any).
+func MergeKeywordsIntoXmp(xmp []byte, keywords []string) []byte {
+	return mergeXmpArray(xmp, "dc:subject", "Bag", DcNamespace, dedupeKeywords(keywords))
+}
+
+// MergeKeywordsIntoIptc returns a copy of the datasets with Keywords set to
+// the keywords (removing them if there aren't any).
+func MergeKeywordsIntoIptc(iptc []IptcDataset, keywords []string) []IptcDataset {
+	return SetIptcStrings(iptc, IptcRecordApplication, IptcDatasetKeywords, dedupeKeywords(keywords))
+}
+
+// SetJpegKeywords returns a copy of the JPEG with the keywords written to
+// XPKeywords, dc:subject, and the IPTC Keywords. XPKeywords is only written if
+// the JPEG already has EXIF (see `SetJpegRights()`). Use
+// `(KeywordsInfo).All()` to merge the existing keywords with new ones first.
+func SetJpegKeywords(data []byte, keywords []string) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	keywords = dedupeKeywords(keywords)
+
+	updated, err = patchJpegExif(data, func(ep *ExifPatcher) error {
+		err := ep.Delete(exifcommon.IfdPathStandard, XpKeywordsTagId)
+		if err != nil && err != ErrTagNotFound {
+			return err
+		}
+
+		if len(keywords) == 0 {
+			return nil
+		}
+
+		return ep.Set(exifcommon.IfdPathStandard, XpKeywordsTagId, encodeXpString(strings.Join(keywords, ";")))
+	})
+	log.PanicIf(err)
+
+	xmp, err := GetJpegXmp(updated)
+	if err != nil && err != ErrNoXmp {
+		log.Panic(err)
+	}
+
+	updated, err = SetJpegXmp(updated, MergeKeywordsIntoXmp(xmp, keywords))
+	log.PanicIf(err)
+
+	iptc, err := GetJpegIptc(updated)
+	if err != nil && err != ErrNoIptc {
+		log.Panic(err)
+	}
+
+	updated, err = SetJpegIptc(updated, MergeKeywordsIntoIptc(iptc, keywords))
+	log.PanicIf(err)
+
+	return updated, nil
+}
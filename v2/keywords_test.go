@@ -0,0 +1,145 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestKeywordsInfo_All(t *testing.T) {
+	ki := KeywordsInfo{
+		Xp:   []string{"beach", "Sunset", "dog"},
+		Xmp:  []string{"sunset", "Beach "},
+		Iptc: []string{"", "vacation", "BEACH"},
+	}
+
+	all := ki.All()
+
+	expected := []string{"sunset", "Beach", "vacation", "dog"}
+	if reflect.DeepEqual(all, expected) != true {
+		t.Fatalf("Keywords not correct: %v", all)
+	}
+}
+
+func TestXpString(t *testing.T) {
+	raw := encodeXpString("café;日本")
+
+	if len(raw) != 7*2+2 {
+		t.Fatalf("Encoding not the right size: (%d)", len(raw))
+	} else if raw[len(raw)-1] != 0 || raw[len(raw)-2] != 0 {
+		t.Fatalf("Encoding not terminated: %v", raw)
+	}
+
+	decoded := decodeXpString(raw)
+	if decoded != "café;日本" {
+		t.Fatalf("Decoding not correct: [%s]", decoded)
+	}
+}
+
+func TestGetKeywords(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := SetXpKeywords(rootIb, []string{"beach", "sunset", "Beach"})
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	xmp := MergeKeywordsIntoXmp(nil, []string{"sunset", "dog"})
+	iptc := MergeKeywordsIntoIptc(nil, []string{"vacation"})
+
+	ki, err := GetKeywords(index, xmp, iptc)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(ki.Xp, []string{"beach", "sunset"}) != true {
+		t.Fatalf("XP keywords not correct: %v", ki.Xp)
+	} else if reflect.DeepEqual(ki.Xmp, []string{"sunset", "dog"}) != true {
+		t.Fatalf("XMP keywords not correct: %v", ki.Xmp)
+	} else if reflect.DeepEqual(ki.Iptc, []string{"vacation"}) != true {
+		t.Fatalf("IPTC keywords not correct: %v", ki.Iptc)
+	}
+
+	expected := []string{"sunset", "dog", "vacation", "beach"}
+	if reflect.DeepEqual(ki.All(), expected) != true {
+		t.Fatalf("All keywords not correct: %v", ki.All())
+	}
+}
+
+func TestSetXpKeywords_Remove(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := SetXpKeywords(rootIb, []string{"beach"})
+	log.PanicIf(err)
+
+	err = SetXpKeywords(rootIb, nil)
+	log.PanicIf(err)
+
+	_, err = rootIb.FindTag(XpKeywordsTagId)
+	if log.Is(err, ErrTagEntryNotFound) == false {
+		t.Fatalf("Expected XPKeywords to be removed: %v", err)
+	}
+}
+
+func TestSetJpegKeywords(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	ki, err := GetJpegKeywords(data)
+	log.PanicIf(err)
+
+	if len(ki.All()) != 0 {
+		t.Fatalf("Test image should not have keywords: %s", ki)
+	}
+
+	updated, err := SetJpegKeywords(data, []string{"beach", "sunset", "SUNSET"})
+	log.PanicIf(err)
+
+	ki, err = GetJpegKeywords(updated)
+	log.PanicIf(err)
+
+	expected := []string{"beach", "sunset"}
+
+	if reflect.DeepEqual(ki.Xp, expected) != true {
+		t.Fatalf("XP keywords not correct: %v", ki.Xp)
+	} else if reflect.DeepEqual(ki.Xmp, expected) != true {
+		t.Fatalf("XMP keywords not correct: %v", ki.Xmp)
+	} else if reflect.DeepEqual(ki.Iptc, expected) != true {
+		t.Fatalf("IPTC keywords not correct: %v", ki.Iptc)
+	}
+
+	// Replacing them drops the old ones everywhere.
+	updated, err = SetJpegKeywords(updated, []string{"dog"})
+	log.PanicIf(err)
+
+	ki, err = GetJpegKeywords(updated)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(ki.All(), []string{"dog"}) != true {
+		t.Fatalf("Replaced keywords not correct: %s", ki)
+	}
+
+	// As does clearing them.
+	updated, err = SetJpegKeywords(updated, nil)
+	log.PanicIf(err)
+
+	ki, err = GetJpegKeywords(updated)
+	log.PanicIf(err)
+
+	if len(ki.All()) != 0 {
+		t.Fatalf("Keywords not cleared: %s", ki)
+	}
+}
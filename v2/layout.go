@@ -0,0 +1,429 @@
+package exif
+
+import (
+	"fmt"
+	"sort"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// LayoutRegionHeader is the TIFF header.
+	LayoutRegionHeader = "header"
+
+	// LayoutRegionIfd is an IFD table: the entry count, the entries, and the
+	// next-IFD offset.
+	LayoutRegionIfd = "ifd"
+
+	// LayoutRegionValue is a value that was too large to be stored in its
+	// entry.
+	LayoutRegionValue = "value"
+
+	// LayoutRegionThumbnail is the thumbnail referred to by IFD1.
+	LayoutRegionThumbnail = "thumbnail"
+
+	// LayoutRegionImageData is a strip or tile of image data (in a TIFF or a
+	// DNG, where the whole file is the block).
+	LayoutRegionImageData = "image-data"
+
+	// LayoutRegionGap is a range of bytes that nothing refers to.
+	LayoutRegionGap = "gap"
+)
+
+// rawIfdEntry is one IFD entry exactly as stored. The value-offset is kept as
+// raw bytes since it holds the value itself when the value fits in four
+// bytes.
+type rawIfdEntry struct {
+	tagId       uint16
+	tagType     exifcommon.TagTypePrimitive
+	unitCount   uint32
+	valueOffset [4]byte
+}
+
+// valueSize returns the number of bytes that the value occupies, or zero if
+// the type is invalid or the size can't be addressed.
+func (rie rawIfdEntry) valueSize() uint32 {
+	tagType := rie.tagType
+	if tagType == exifcommon.TypeUndefined {
+		tagType = exifcommon.TypeByte
+	}
+
+	if tagType.IsValid() == false {
+		return 0
+	}
+
+	size, err := exifcommon.CheckedMultiply(rie.unitCount, tagType.Size())
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// rawIfdTableSize returns the size of an IFD table with the given number of
+// entries.
+func rawIfdTableSize(entryCount int) uint32 {
+	return 2 + uint32(entryCount)*IfdTagEntrySize + 4
+}
+
+// readRawIfdTable reads every entry of the IFD table at the given offset,
+// including those that the enumerator hides or skips (e.g. the thumbnail
+// tags and entries with invalid types).
+func readRawIfdTable(data []byte, offset uint32, byteOrder binary.ByteOrder) (entries []rawIfdEntry, nextIfdOffset uint32, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	raw, err := exifcommon.CheckedSlice(data, offset, 2)
+	if err != nil {
+		log.Panicf("IFD table offset (0x%08x) is out of bounds", offset)
+	}
+
+	count := int(byteOrder.Uint16(raw))
+
+	table, err := exifcommon.CheckedSlice(data, offset, rawIfdTableSize(count))
+	if err != nil {
+		log.Panicf("IFD table at (0x%08x) is truncated", offset)
+	}
+
+	entries = make([]rawIfdEntry, count)
+
+	for i := range entries {
+		raw := table[2+uint32(i)*IfdTagEntrySize:]
+
+		entries[i] = rawIfdEntry{
+			tagId:     byteOrder.Uint16(raw[0:]),
+			tagType:   exifcommon.TagTypePrimitive(byteOrder.Uint16(raw[2:])),
+			unitCount: byteOrder.Uint32(raw[4:]),
+		}
+
+		copy(entries[i].valueOffset[:], raw[8:12])
+	}
+
+	nextIfdOffset = byteOrder.Uint32(table[2+uint32(count)*IfdTagEntrySize:])
+
+	return entries, nextIfdOffset, nil
+}
+
+var (
+	// layoutImageDataTagIds are the pairs of tags that locate image data:
+	// StripOffsets and StripByteCounts, and TileOffsets and TileByteCounts.
+	layoutImageDataTagIds = [][2]uint16{
+		{0x0111, 0x0117},
+		{0x0144, 0x0145},
+	}
+)
+
+// readRawIfdUints returns the values of a SHORT or LONG entry, or nil if the
+// entry has another type or its value is out of bounds.
+func readRawIfdUints(data []byte, rie rawIfdEntry, byteOrder binary.ByteOrder) []uint32 {
+	if rie.tagType != exifcommon.TypeShort && rie.tagType != exifcommon.TypeLong {
+		return nil
+	}
+
+	size, err := exifcommon.CheckedMultiply(rie.unitCount, rie.tagType.Size())
+	if err != nil {
+		return nil
+	}
+
+	raw := rie.valueOffset[:]
+	if size > 4 {
+		raw, err = exifcommon.CheckedSlice(data, byteOrder.Uint32(rie.valueOffset[:]), size)
+		if err != nil {
+			return nil
+		}
+	}
+
+	values := make([]uint32, rie.unitCount)
+	for i := range values {
+		if rie.tagType == exifcommon.TypeShort {
+			values[i] = uint32(byteOrder.Uint16(raw[i*2:]))
+		} else {
+			values[i] = byteOrder.Uint32(raw[i*4:])
+		}
+	}
+
+	return values
+}
+
+// LayoutRegion is one contiguous range of bytes in an EXIF block.
+type LayoutRegion struct {
+	// Kind is one of the LayoutRegion* constants.
+	Kind string
+
+	Offset uint32
+	Size   uint32
+
+	// FqIfdPath is the IFD that the region belongs to. It's empty for the
+	// header and for gaps.
+	FqIfdPath string
+
+	// TagId is the tag that refers to the region, for values and thumbnails.
+	TagId uint16
+}
+
+// End returns the offset just past the region.
+func (lr LayoutRegion) End() uint32 {
+	return lr.Offset + lr.Size
+}
+
+// String returns a descriptive string.
+func (lr LayoutRegion) String() string {
+	return fmt.Sprintf("LayoutRegion<KIND=[%s] OFFSET=(0x%08x) SIZE=(%d) IFD=[%s] TAG-ID=(0x%04x)>", lr.Kind, lr.Offset, lr.Size, lr.FqIfdPath, lr.TagId)
+}
+
+// ExifLayout is the physical layout of an EXIF block.
+type ExifLayout struct {
+	// Size is the size of the whole block.
+	Size uint32
+
+	// Regions covers the whole block, in order of offset. Regions can
+	// overlap if the data is unusual (e.g. two tags sharing one value).
+	Regions []LayoutRegion
+}
+
+// Gaps returns the regions that nothing refers to. Small gaps are usually
+// alignment padding; large ones may be hidden or orphaned data.
+func (el ExifLayout) Gaps() []LayoutRegion {
+	gaps := make([]LayoutRegion, 0)
+
+	for _, lr := range el.Regions {
+		if lr.Kind == LayoutRegionGap {
+			gaps = append(gaps, lr)
+		}
+	}
+
+	return gaps
+}
+
+// SlackRegion is unreferenced data and where it was found.
+type SlackRegion struct {
+	Offset uint32
+	Data   []byte
+}
+
+// String returns a descriptive string.
+func (sr SlackRegion) String() string {
+	return fmt.Sprintf("SlackRegion<OFFSET=(0x%08x) SIZE=(%d)>", sr.Offset, len(sr.Data))
+}
+
+// Slack returns the gaps that have data in them ("slack space"). Gaps that
+// are all zeroes are padding or released space and aren't included. Some
+// vendors keep calibration data here, so it can be carried over when
+// rewriting using `(*IfdByteEncoder).SetSlack()` (`ExifPatcher` leaves it
+// where it is). `rawExif` must be the block that the layout was taken from.
+func (el ExifLayout) Slack(rawExif []byte) []SlackRegion {
+	slack := make([]SlackRegion, 0)
+
+	for _, lr := range el.Gaps() {
+		data, err := exifcommon.CheckedSlice(rawExif, lr.Offset, lr.Size)
+		if err != nil {
+			continue
+		}
+
+		for _, b := range data {
+			if b != 0 {
+				sr := SlackRegion{
+					Offset: lr.Offset,
+					Data:   make([]byte, len(data)),
+				}
+
+				copy(sr.Data, data)
+				slack = append(slack, sr)
+
+				break
+			}
+		}
+	}
+
+	return slack
+}
+
+// RegionsAt returns the regions that contain the given offset.
+func (el ExifLayout) RegionsAt(offset uint32) []LayoutRegion {
+	regions := make([]LayoutRegion, 0)
+
+	for _, lr := range el.Regions {
+		if lr.Offset <= offset && offset < lr.End() {
+			regions = append(regions, lr)
+		}
+	}
+
+	return regions
+}
+
+// GetExifLayout returns the physical layout of the EXIF block that the index
+// was collected from, including any SubIFDs and the image data of each IFD.
+// Values whose offsets fall outside of the block are ignored. Offsets inside of maker-notes aren't followed, so the maker-note
+// is reported as a single value.
+func GetExifLayout(rawExif []byte, index IfdIndex) (el ExifLayout, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if uint64(len(rawExif)) > 0xffffffff {
+		log.Panicf("EXIF block is too large: (%d)", len(rawExif))
+	}
+
+	size := uint32(len(rawExif))
+
+	regions := []LayoutRegion{
+		{
+			Kind: LayoutRegionHeader,
+			Size: ExifDefaultFirstIfdOffset,
+		},
+	}
+
+	addRegion := func(lr LayoutRegion) {
+		if lr.Size == 0 {
+			return
+		} else if _, err := exifcommon.CheckedSlice(rawExif, lr.Offset, lr.Size); err != nil {
+			return
+		}
+
+		regions = append(regions, lr)
+	}
+
+	// addIfd adds the table, values, and image data of an IFD. IFD0 and IFD1
+	// have the thumbnail tags. A SubIFD may use the same tags for a JPEG
+	// preview, which is image data like any other.
+	addIfd := func(fqIfdPath string, hasThumbnail, isSubIfd bool, offset uint32, entries []rawIfdEntry, byteOrder binary.ByteOrder) {
+		addRegion(LayoutRegion{
+			Kind:      LayoutRegionIfd,
+			Offset:    offset,
+			Size:      rawIfdTableSize(len(entries)),
+			FqIfdPath: fqIfdPath,
+		})
+
+		var thumbnailOffset, thumbnailSize uint32
+
+		for _, rie := range entries {
+			if hasThumbnail == true && rie.unitCount == 1 {
+				if rie.tagId == ThumbnailOffsetTagId {
+					thumbnailOffset = byteOrder.Uint32(rie.valueOffset[:])
+					continue
+				} else if rie.tagId == ThumbnailSizeTagId {
+					thumbnailSize = byteOrder.Uint32(rie.valueOffset[:])
+					continue
+				}
+			}
+
+			valueSize := rie.valueSize()
+			if valueSize <= 4 || valueSize > size {
+				continue
+			}
+
+			addRegion(LayoutRegion{
+				Kind:      LayoutRegionValue,
+				Offset:    byteOrder.Uint32(rie.valueOffset[:]),
+				Size:      valueSize,
+				FqIfdPath: fqIfdPath,
+				TagId:     rie.tagId,
+			})
+		}
+
+		byTagId := make(map[uint16]rawIfdEntry, len(entries))
+		for _, rie := range entries {
+			byTagId[rie.tagId] = rie
+		}
+
+		imageDataTagIds := layoutImageDataTagIds
+		if isSubIfd == true {
+			imageDataTagIds = append(imageDataTagIds, [2]uint16{ThumbnailOffsetTagId, ThumbnailSizeTagId})
+		}
+
+		for _, pair := range imageDataTagIds {
+			offsetsEntry, found := byTagId[pair[0]]
+			if found == false {
+				continue
+			}
+
+			countsEntry, found := byTagId[pair[1]]
+			if found == false {
+				continue
+			}
+
+			offsets := readRawIfdUints(rawExif, offsetsEntry, byteOrder)
+			counts := readRawIfdUints(rawExif, countsEntry, byteOrder)
+
+			for i := 0; i < len(offsets) && i < len(counts); i++ {
+				addRegion(LayoutRegion{
+					Kind:      LayoutRegionImageData,
+					Offset:    offsets[i],
+					Size:      counts[i],
+					FqIfdPath: fqIfdPath,
+					TagId:     pair[0],
+				})
+			}
+		}
+
+		if thumbnailOffset != 0 && thumbnailSize != 0 {
+			addRegion(LayoutRegion{
+				Kind:      LayoutRegionThumbnail,
+				Offset:    thumbnailOffset,
+				Size:      thumbnailSize,
+				FqIfdPath: fqIfdPath,
+				TagId:     ThumbnailOffsetTagId,
+			})
+		}
+	}
+
+	for _, ifd := range index.Ifds {
+		entries, _, err := readRawIfdTable(rawExif, ifd.Offset, ifd.ByteOrder)
+		log.PanicIf(err)
+
+		addIfd(ifd.FqIfdPath, ifd.IfdPath == exifcommon.IfdPathStandard, false, ifd.Offset, entries, ifd.ByteOrder)
+
+		// SubIFDs (e.g. the raw image of a DNG) aren't collected, so they're
+		// read here.
+		for _, si := range readSubIfds(rawExif, ifd.ByteOrder, ifd.FqIfdPath, entries) {
+			addIfd(si.fqIfdPath, false, true, si.offset, si.entries, si.byteOrder)
+		}
+	}
+
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].Offset < regions[j].Offset
+	})
+
+	el = ExifLayout{
+		Size:    size,
+		Regions: make([]LayoutRegion, 0, len(regions)),
+	}
+
+	// Fill in the gaps.
+
+	covered := uint32(0)
+	for _, lr := range regions {
+		if lr.Offset > covered {
+			el.Regions = append(el.Regions, LayoutRegion{
+				Kind:   LayoutRegionGap,
+				Offset: covered,
+				Size:   lr.Offset - covered,
+			})
+		}
+
+		el.Regions = append(el.Regions, lr)
+
+		if lr.End() > covered {
+			covered = lr.End()
+		}
+	}
+
+	if covered < size {
+		el.Regions = append(el.Regions, LayoutRegion{
+			Kind:   LayoutRegionGap,
+			Offset: covered,
+			Size:   size - covered,
+		})
+	}
+
+	return el, nil
+}
@@ -0,0 +1,202 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func getTestExifLayout() ExifLayout {
+	rawExif := getTestExifData()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	el, err := GetExifLayout(rawExif, index)
+	log.PanicIf(err)
+
+	return el
+}
+
+func TestGetExifLayout(t *testing.T) {
+	el := getTestExifLayout()
+
+	if el.Size != 32936 {
+		t.Fatalf("Size not correct: (%d)", el.Size)
+	} else if el.Regions[0].Kind != LayoutRegionHeader {
+		t.Fatalf("First region not the header: %s", el.Regions[0])
+	}
+
+	// The regions should cover everything, in order.
+
+	covered := uint32(0)
+	for _, lr := range el.Regions {
+		if lr.Offset > covered {
+			t.Fatalf("Region doesn't follow the previous one: %s", lr)
+		}
+
+		if lr.End() > covered {
+			covered = lr.End()
+		}
+	}
+
+	if covered != el.Size {
+		t.Fatalf("Regions don't cover the block: (%d) != (%d)", covered, el.Size)
+	}
+
+	ifdCount := 0
+	for _, lr := range el.Regions {
+		if lr.Kind == LayoutRegionIfd {
+			ifdCount++
+		}
+	}
+
+	if ifdCount != 5 {
+		t.Fatalf("IFD count not correct: (%d)", ifdCount)
+	}
+
+	last := el.Regions[len(el.Regions)-2]
+	if last.Kind != LayoutRegionThumbnail || last.Offset != 0x2cb4 || last.Size != 21491 {
+		t.Fatalf("Thumbnail region not correct: %s", last)
+	}
+}
+
+func TestExifLayout_Gaps(t *testing.T) {
+	el := getTestExifLayout()
+
+	gaps := el.Gaps()
+	if len(gaps) != 9 {
+		t.Fatalf("Gap count not correct: (%d)", len(gaps))
+	}
+
+	largest := gaps[0]
+	for _, lr := range gaps {
+		if lr.Size > largest.Size {
+			largest = lr
+		}
+	}
+
+	if largest.Offset != 0x2564 || largest.Size != 1776 {
+		t.Fatalf("Largest gap not correct: %s", largest)
+	}
+}
+
+func TestExifLayout_RegionsAt(t *testing.T) {
+	el := getTestExifLayout()
+
+	regions := el.RegionsAt(0x38e + 100)
+	if len(regions) != 1 {
+		t.Fatalf("Region count not correct: (%d)", len(regions))
+	}
+
+	lr := regions[0]
+	if lr.Kind != LayoutRegionValue || lr.FqIfdPath != exifcommon.IfdPathStandardExif || lr.TagId != 0x927c || lr.Size != 8152 {
+		t.Fatalf("Maker-note region not correct: %s", lr)
+	}
+
+	if regions := el.RegionsAt(el.Size); len(regions) != 0 {
+		t.Fatalf("Expected no regions past the end: %v", regions)
+	}
+}
+
+func TestReadRawIfdTable_Truncated(t *testing.T) {
+	rawExif := getTestExifData()
+
+	_, _, err := readRawIfdTable(rawExif[:20], ExifDefaultFirstIfdOffset, exifcommon.TestDefaultByteOrder)
+	if err == nil {
+		t.Fatalf("Expected error for truncated table.")
+	}
+}
+
+func getTestExifDataWithSlack() (rawExif []byte, slackOffset uint32, slackData []byte) {
+	rawExif = getTestExifData()
+
+	// There's a large, zeroed gap after the GPS IFD.
+	slackOffset = 0x2564
+	slackData = []byte("calibration data")
+
+	copy(rawExif[slackOffset:], slackData)
+
+	return rawExif, slackOffset, slackData
+}
+
+func TestExifLayout_Slack(t *testing.T) {
+	el := getTestExifLayout()
+
+	if slack := el.Slack(getTestExifData()); len(slack) != 0 {
+		t.Fatalf("Expected no slack: %v", slack)
+	}
+
+	rawExif, slackOffset, slackData := getTestExifDataWithSlack()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	el, err = GetExifLayout(rawExif, index)
+	log.PanicIf(err)
+
+	slack := el.Slack(rawExif)
+	if len(slack) != 1 {
+		t.Fatalf("Slack count not correct: (%d)", len(slack))
+	}
+
+	sr := slack[0]
+	if sr.Offset != slackOffset || len(sr.Data) != 1776 || bytes.HasPrefix(sr.Data, slackData) == false {
+		t.Fatalf("Slack not correct: %s", sr)
+	}
+}
+
+func TestGetExifLayout_ImageData(t *testing.T) {
+	data, stripOffset := getTestTiff()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), data)
+	log.PanicIf(err)
+
+	el, err := GetExifLayout(data, index)
+	log.PanicIf(err)
+
+	regions := el.RegionsAt(stripOffset)
+	if len(regions) != 1 {
+		t.Fatalf("Expected one region at the strip: %v", regions)
+	}
+
+	lr := regions[0]
+	if lr.Kind != LayoutRegionImageData || lr.Size != uint32(len(testDngPixels)) || lr.TagId != 0x0111 {
+		t.Fatalf("Strip region not correct: %s", lr)
+	} else if len(el.Gaps()) != 0 {
+		t.Fatalf("Strip should not be reported as a gap: %v", el.Gaps())
+	}
+}
+
+func TestGetExifLayout_SubIfd(t *testing.T) {
+	data, stripOffset := getTestTiffWithSubIfd()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), data)
+	log.PanicIf(err)
+
+	el, err := GetExifLayout(data, index)
+	log.PanicIf(err)
+
+	if len(el.Gaps()) != 0 {
+		t.Fatalf("SubIFD should be fully accounted for: %v", el.Gaps())
+	}
+
+	regions := el.RegionsAt(stripOffset)
+	if len(regions) != 1 || regions[0].Kind != LayoutRegionImageData || regions[0].FqIfdPath != "IFD/SubIFD0" {
+		t.Fatalf("Strip region not correct: %v", regions)
+	}
+
+	kinds := make(map[string]bool)
+	for _, lr := range el.Regions {
+		if lr.FqIfdPath == "IFD/SubIFD0" {
+			kinds[lr.Kind] = true
+		}
+	}
+
+	if kinds[LayoutRegionIfd] != true || kinds[LayoutRegionValue] != true {
+		t.Fatalf("SubIFD table or opcode list not found: %v", kinds)
+	}
+}
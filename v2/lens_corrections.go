@@ -0,0 +1,370 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// DNG opcode IDs (DNG specification, chapter 7).
+	DngOpcodeWarpRectilinear   = 1
+	DngOpcodeWarpFisheye       = 2
+	DngOpcodeFixVignetteRadial = 3
+	DngOpcodeGainMap           = 9
+)
+
+const (
+	// Maker-note tags with the camera's own correction settings.
+	canonVignettingCorr2TagId  = 0x4016
+	nikonVignetteControlTagId  = 0x002a
+	nikonDistortInfoTagId      = 0x002b
+	canonVignettingCorr2Length = 8
+)
+
+var (
+	// dngOpcodeListTagIds are OpcodeList1, OpcodeList2, and OpcodeList3, in
+	// the order in which they're applied.
+	dngOpcodeListTagIds = []uint16{0xc740, 0xc741, 0xc74e}
+)
+
+var (
+	// ErrNoLensCorrections means that no correction data was found.
+	ErrNoLensCorrections = errors.New("no lens corrections")
+
+	// ErrDngOpcodeListNotValid means that an opcode list is truncated or
+	// otherwise malformed.
+	ErrDngOpcodeListNotValid = errors.New("dng opcode list not valid")
+)
+
+// DngOpcode is one entry in a DNG opcode list. The parameters are always
+// big-endian, regardless of the byte order of the file.
+type DngOpcode struct {
+	Id         uint32
+	DngVersion uint32
+	Flags      uint32
+	Parameters []byte
+}
+
+// IsOptional returns true if a reader may skip the opcode if it doesn't
+// support it.
+func (do DngOpcode) IsOptional() bool {
+	return do.Flags&1 != 0
+}
+
+// String returns a descriptive string.
+func (do DngOpcode) String() string {
+	return fmt.Sprintf("DngOpcode<ID=(%d) VERSION=(0x%08x) FLAGS=(0x%x) PARAMETER-BYTES=(%d)>", do.Id, do.DngVersion, do.Flags, len(do.Parameters))
+}
+
+// ParseDngOpcodeList parses the raw value of an OpcodeList tag.
+func ParseDngOpcodeList(raw []byte) (opcodes []DngOpcode, err error) {
+	if len(raw) < 4 {
+		return nil, ErrDngOpcodeListNotValid
+	}
+
+	count := binary.BigEndian.Uint32(raw)
+	raw = raw[4:]
+
+	opcodes = make([]DngOpcode, 0)
+
+	for i := uint32(0); i < count; i++ {
+		if len(raw) < 16 {
+			return nil, ErrDngOpcodeListNotValid
+		}
+
+		parameterSize := uint64(binary.BigEndian.Uint32(raw[12:]))
+		if 16+parameterSize > uint64(len(raw)) {
+			return nil, ErrDngOpcodeListNotValid
+		}
+
+		do := DngOpcode{
+			Id:         binary.BigEndian.Uint32(raw),
+			DngVersion: binary.BigEndian.Uint32(raw[4:]),
+			Flags:      binary.BigEndian.Uint32(raw[8:]),
+			Parameters: raw[16 : 16+parameterSize],
+		}
+
+		opcodes = append(opcodes, do)
+		raw = raw[16+parameterSize:]
+	}
+
+	return opcodes, nil
+}
+
+// PlaneDistortion has the warp coefficients for one color plane. For the
+// rectilinear model the radial coefficients are kr0-kr3 and the tangential
+// coefficients are kt0-kt1. For the fisheye model only kr0-kr3 are used.
+type PlaneDistortion struct {
+	Radial     [4]float64
+	Tangential [2]float64
+}
+
+// LensCorrections is a normalized view of the lens corrections stored with
+// an image. Centers are relative to the image, where (0.5, 0.5) is the
+// middle.
+type LensCorrections struct {
+	// Distortion has one entry per color plane, or a single entry if it
+	// applies to all planes.
+	Distortion       []PlaneDistortion
+	IsFisheye        bool
+	DistortionCenter [2]float64
+
+	// Vignetting has the k0-k4 coefficients of the radial vignetting model,
+	// or is nil.
+	Vignetting       []float64
+	VignettingCenter [2]float64
+
+	// HasVignettingGainMap indicates that vignetting is corrected with a
+	// GainMap opcode, which isn't decoded here.
+	HasVignettingGainMap bool
+
+	// Vendor is the maker-note vendor that the in-camera settings were read
+	// from, or empty.
+	Vendor string
+
+	// InCameraDistortion, InCameraVignetting, and InCameraChromaticAberration
+	// are whether the camera says that it corrected the image itself before
+	// saving it. Each is nil if the maker-note doesn't record it.
+	InCameraDistortion          *bool
+	InCameraVignetting          *bool
+	InCameraChromaticAberration *bool
+}
+
+// HasDistortion returns true if there are distortion coefficients.
+func (lc LensCorrections) HasDistortion() bool {
+	return len(lc.Distortion) > 0
+}
+
+// HasChromaticAberration returns true if the distortion differs between
+// color planes, which is how DNG represents lateral chromatic aberration.
+func (lc LensCorrections) HasChromaticAberration() bool {
+	for i := 1; i < len(lc.Distortion); i++ {
+		if lc.Distortion[i] != lc.Distortion[0] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasVignetting returns true if there's vignetting correction of either kind.
+func (lc LensCorrections) HasVignetting() bool {
+	return lc.Vignetting != nil || lc.HasVignettingGainMap == true
+}
+
+// HasInCameraSettings returns true if any of the camera's own correction
+// settings were found.
+func (lc LensCorrections) HasInCameraSettings() bool {
+	return lc.InCameraDistortion != nil || lc.InCameraVignetting != nil || lc.InCameraChromaticAberration != nil
+}
+
+// readDngDoubles reads big-endian doubles from opcode parameters.
+func readDngDoubles(parameters []byte, count int) (values []float64, err error) {
+	if len(parameters) < count*8 {
+		return nil, ErrDngOpcodeListNotValid
+	}
+
+	values = make([]float64, count)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.BigEndian.Uint64(parameters[i*8:]))
+	}
+
+	return values, nil
+}
+
+// applyWarp loads a WarpRectilinear or WarpFisheye opcode.
+func (lc *LensCorrections) applyWarp(do DngOpcode) (err error) {
+	coefficientCount := 6
+	if do.Id == DngOpcodeWarpFisheye {
+		coefficientCount = 4
+	}
+
+	if len(do.Parameters) < 4 {
+		return ErrDngOpcodeListNotValid
+	}
+
+	planeCount := int(binary.BigEndian.Uint32(do.Parameters))
+	if planeCount > 4 {
+		return ErrDngOpcodeListNotValid
+	}
+
+	values, err := readDngDoubles(do.Parameters[4:], planeCount*coefficientCount+2)
+	if err != nil {
+		return err
+	}
+
+	lc.Distortion = make([]PlaneDistortion, planeCount)
+	for i := range lc.Distortion {
+		coefficients := values[i*coefficientCount:]
+
+		pd := &lc.Distortion[i]
+		copy(pd.Radial[:], coefficients[:4])
+
+		if do.Id == DngOpcodeWarpRectilinear {
+			copy(pd.Tangential[:], coefficients[4:6])
+		}
+	}
+
+	lc.IsFisheye = do.Id == DngOpcodeWarpFisheye
+	lc.DistortionCenter = [2]float64{values[planeCount*coefficientCount], values[planeCount*coefficientCount+1]}
+
+	return nil
+}
+
+// LoadDngOpcodes adds the corrections described by the opcodes. Opcodes that
+// aren't lens corrections are ignored.
+func (lc *LensCorrections) LoadDngOpcodes(opcodes []DngOpcode) (err error) {
+	for _, do := range opcodes {
+		switch do.Id {
+		case DngOpcodeWarpRectilinear, DngOpcodeWarpFisheye:
+			err := lc.applyWarp(do)
+			if err != nil {
+				return err
+			}
+		case DngOpcodeFixVignetteRadial:
+			values, err := readDngDoubles(do.Parameters, 7)
+			if err != nil {
+				return err
+			}
+
+			lc.Vignetting = values[:5]
+			lc.VignettingCenter = [2]float64{values[5], values[6]}
+		case DngOpcodeGainMap:
+			lc.HasVignettingGainMap = true
+		}
+	}
+
+	return nil
+}
+
+// loadDngOpcodeList adds the corrections in the raw value of an OpcodeList
+// tag.
+func (lc *LensCorrections) loadDngOpcodeList(raw []byte) (err error) {
+	opcodes, err := ParseDngOpcodeList(raw)
+	if err != nil {
+		return err
+	}
+
+	return lc.LoadDngOpcodes(opcodes)
+}
+
+// loadCanon reads VignettingCorr2, whose first value is its length in bytes
+// and whose sixth through eighth are the peripheral-lighting,
+// chromatic-aberration, and distortion settings.
+func (lc *LensCorrections) loadCanon(mni *makerNoteIfd) {
+	entry, found := mni.entries[canonVignettingCorr2TagId]
+	if found == false || entry.tagType != exifcommon.TypeSignedLong || entry.unitCount < canonVignettingCorr2Length {
+		return
+	}
+
+	setting := func(i int) *bool {
+		enabled := int32(mni.byteOrder.Uint32(entry.value[i*4:])) != 0
+		return &enabled
+	}
+
+	lc.InCameraVignetting = setting(5)
+	lc.InCameraChromaticAberration = setting(6)
+	lc.InCameraDistortion = setting(7)
+}
+
+// loadNikon reads VignetteControl (zero is off; otherwise it's the strength)
+// and the AutoDistortionControl byte of DistortInfo, which follows a
+// four-byte version.
+func (lc *LensCorrections) loadNikon(mni *makerNoteIfd) {
+	if vignetteControl, found := mni.Int(nikonVignetteControlTagId); found == true {
+		enabled := vignetteControl != 0
+		lc.InCameraVignetting = &enabled
+	}
+
+	if entry, found := mni.entries[nikonDistortInfoTagId]; found == true && len(entry.value) >= 5 {
+		enabled := entry.value[4] != 0
+		lc.InCameraDistortion = &enabled
+	}
+}
+
+// GetLensCorrections collects the lens corrections from the DNG opcode lists
+// in any IFD, including the SubIFDs (where DNGs keep the raw image), and the
+// camera's own correction settings from Canon and Nikon maker-notes.
+// `ErrNoLensCorrections` is returned if there's nothing.
+func GetLensCorrections(index IfdIndex) (lc LensCorrections, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	subIfds, err := getSubIfds(index)
+	log.PanicIf(err)
+
+	for _, tagId := range dngOpcodeListTagIds {
+		for _, ifd := range index.Ifds {
+			results, err := ifd.FindTagWithId(tagId)
+			if err != nil {
+				if log.Is(err, ErrTagNotFound) == true {
+					continue
+				}
+
+				log.Panic(err)
+			}
+
+			valueContext := results[0].getValueContext()
+			valueContext.SetUndefinedValueType(exifcommon.TypeByte)
+
+			raw, err := valueContext.ReadRawEncoded()
+			log.PanicIf(err)
+
+			err = lc.loadDngOpcodeList(raw)
+			if err == ErrDngOpcodeListNotValid {
+				return lc, err
+			}
+
+			log.PanicIf(err)
+		}
+
+		for _, si := range subIfds {
+			rie, found := si.find(tagId)
+			if found == false {
+				continue
+			}
+
+			raw, err := readRawIfdValue(si.data, rie, si.byteOrder)
+			if err != nil {
+				return lc, ErrDngOpcodeListNotValid
+			}
+
+			err = lc.loadDngOpcodeList(raw)
+			if err == ErrDngOpcodeListNotValid {
+				return lc, err
+			}
+
+			log.PanicIf(err)
+		}
+	}
+
+	vendor, mni, err := getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	switch vendor {
+	case makerNoteVendorCanon:
+		lc.loadCanon(mni)
+	case makerNoteVendorNikon:
+		lc.loadNikon(mni)
+	}
+
+	if lc.HasInCameraSettings() == true {
+		lc.Vendor = vendor
+	}
+
+	if lc.HasDistortion() == false && lc.HasVignetting() == false && lc.HasInCameraSettings() == false {
+		return lc, ErrNoLensCorrections
+	}
+
+	return lc, nil
+}
@@ -0,0 +1,206 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestDngOpcode(b *bytes.Buffer, id uint32, parameters ...interface{}) {
+	p := new(bytes.Buffer)
+	for _, parameter := range parameters {
+		binary.Write(p, binary.BigEndian, parameter)
+	}
+
+	binary.Write(b, binary.BigEndian, []uint32{id, 0x01030000, 1, uint32(p.Len())})
+	b.Write(p.Bytes())
+}
+
+func getTestDngOpcodeList() []byte {
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.BigEndian, uint32(3))
+
+	// Three planes where red and blue differ slightly from green.
+	getTestDngOpcode(b, DngOpcodeWarpRectilinear,
+		uint32(3),
+		[]float64{1.001, -0.02, 0.003, 0, 0, 0},
+		[]float64{1, -0.02, 0.003, 0, 0, 0},
+		[]float64{0.999, -0.02, 0.003, 0, 0, 0},
+		[]float64{0.5, 0.5})
+
+	getTestDngOpcode(b, DngOpcodeFixVignetteRadial, []float64{0.3, 0.1, 0, 0, 0, 0.5, 0.5})
+
+	// An opcode that isn't a lens correction.
+	getTestDngOpcode(b, 4, []uint32{0, 0})
+
+	return b.Bytes()
+}
+
+func TestParseDngOpcodeList(t *testing.T) {
+	opcodes, err := ParseDngOpcodeList(getTestDngOpcodeList())
+	log.PanicIf(err)
+
+	if len(opcodes) != 3 {
+		t.Fatalf("Opcode count not correct: (%d)", len(opcodes))
+	} else if opcodes[0].Id != DngOpcodeWarpRectilinear || opcodes[2].Id != 4 {
+		t.Fatalf("Opcodes not correct: %v", opcodes)
+	} else if opcodes[1].IsOptional() != true {
+		t.Fatalf("Expected opcode to be optional.")
+	}
+}
+
+func TestParseDngOpcodeList_Truncated(t *testing.T) {
+	raw := getTestDngOpcodeList()
+
+	_, err := ParseDngOpcodeList(raw[:len(raw)-1])
+	if err != ErrDngOpcodeListNotValid {
+		t.Fatalf("Expected not-valid error: %v", err)
+	}
+}
+
+func TestLensCorrections_LoadDngOpcodes(t *testing.T) {
+	opcodes, err := ParseDngOpcodeList(getTestDngOpcodeList())
+	log.PanicIf(err)
+
+	lc := LensCorrections{}
+
+	err = lc.LoadDngOpcodes(opcodes)
+	log.PanicIf(err)
+
+	if len(lc.Distortion) != 3 {
+		t.Fatalf("Plane count not correct: (%d)", len(lc.Distortion))
+	} else if lc.Distortion[1].Radial != [4]float64{1, -0.02, 0.003, 0} {
+		t.Fatalf("Radial coefficients not correct: %v", lc.Distortion[1].Radial)
+	} else if lc.DistortionCenter != [2]float64{0.5, 0.5} {
+		t.Fatalf("Distortion center not correct: %v", lc.DistortionCenter)
+	} else if lc.HasChromaticAberration() != true {
+		t.Fatalf("Expected chromatic aberration.")
+	} else if len(lc.Vignetting) != 5 || lc.Vignetting[0] != 0.3 {
+		t.Fatalf("Vignetting not correct: %v", lc.Vignetting)
+	} else if lc.IsFisheye != false || lc.HasVignettingGainMap != false {
+		t.Fatalf("Flags not correct.")
+	}
+}
+
+func TestGetLensCorrections(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+
+	opcodeListTag := exiftest.Tag{
+		Id:   0xc741,
+		Raw:  getTestDngOpcodeList(),
+		Type: exifcommon.TypeUndefined,
+	}
+
+	// As in a DNG, the opcode lists are with the raw image in a SubIFD.
+	rawIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x00fe, Value: []uint32{0}},
+			opcodeListTag,
+		},
+	}
+
+	root.Children = append(root.Children, exiftest.Child{TagId: SubIfdsTagId, Ifd: rawIfd})
+
+	exifData, err := exiftest.Build(root, binary.LittleEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	lc, err := GetLensCorrections(index)
+	log.PanicIf(err)
+
+	if lc.HasDistortion() != true || lc.HasVignetting() != true {
+		t.Fatalf("Corrections not found: %v", lc)
+	}
+}
+
+func TestGetLensCorrections_None(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	_, err = GetLensCorrections(index)
+	if err != ErrNoLensCorrections {
+		t.Fatalf("Expected no corrections: %v", err)
+	}
+}
+
+func TestGetLensCorrections_Nikon(t *testing.T) {
+	makerNoteIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: nikonVignetteControlTagId, Value: []uint16{3}},
+			{Id: nikonDistortInfoTagId, Raw: []byte("0100\x01\x00\x00\x00"), Type: exifcommon.TypeUndefined},
+		},
+	}
+
+	tiff, err := exiftest.Build(makerNoteIfd, binary.LittleEndian)
+	log.PanicIf(err)
+
+	makerNote := append([]byte("Nikon\x00\x02\x10\x00\x00"), tiff...)
+
+	lc, err := GetLensCorrections(getTestMakerNoteIndex("NIKON CORPORATION", makerNote, nil, nil))
+	log.PanicIf(err)
+
+	if lc.Vendor != makerNoteVendorNikon {
+		t.Fatalf("Vendor not correct: [%s]", lc.Vendor)
+	} else if lc.InCameraVignetting == nil || *lc.InCameraVignetting != true {
+		t.Fatalf("Vignetting setting not correct.")
+	} else if lc.InCameraDistortion == nil || *lc.InCameraDistortion != true {
+		t.Fatalf("Distortion setting not correct.")
+	} else if lc.InCameraChromaticAberration != nil {
+		t.Fatalf("Chromatic-aberration setting not expected.")
+	} else if lc.HasDistortion() != false {
+		t.Fatalf("Coefficients not expected.")
+	}
+}
+
+func TestGetLensCorrections_Canon(t *testing.T) {
+	b := new(bytes.Buffer)
+
+	vignettingCorr2 := []int32{32, 0, 0, 0, 0, 1, 0, 1}
+
+	binary.Write(b, binary.BigEndian, uint16(1))
+	binary.Write(b, binary.BigEndian, []uint16{canonVignettingCorr2TagId, uint16(exifcommon.TypeSignedLong)})
+	binary.Write(b, binary.BigEndian, []uint32{uint32(len(vignettingCorr2)), 0})
+	binary.Write(b, binary.BigEndian, uint32(0))
+
+	makerNote := b.Bytes()
+
+	// Canon value offsets are relative to the EXIF data rather than the
+	// maker-note, so the values follow a marker and the offset is patched in
+	// once the EXIF has been built.
+	marker := []byte("VIGNETTINGCORR2:")
+	makerNote = append(makerNote, marker...)
+
+	values := new(bytes.Buffer)
+	binary.Write(values, binary.BigEndian, vignettingCorr2)
+	makerNote = append(makerNote, values.Bytes()...)
+
+	index := getTestMakerNoteIndex("Canon", makerNote, nil, nil)
+
+	ite, err := getMakerNoteTag(index)
+	log.PanicIf(err)
+
+	data := ite.getValueContext().AddressableData()
+	valueOffset := uint32(bytes.Index(data, marker) + len(marker))
+	binary.BigEndian.PutUint32(data[ite.getValueOffset()+2+8:], valueOffset)
+
+	lc, err := GetLensCorrections(index)
+	log.PanicIf(err)
+
+	if lc.Vendor != makerNoteVendorCanon {
+		t.Fatalf("Vendor not correct: [%s]", lc.Vendor)
+	} else if lc.InCameraVignetting == nil || *lc.InCameraVignetting != true {
+		t.Fatalf("Vignetting setting not correct.")
+	} else if lc.InCameraChromaticAberration == nil || *lc.InCameraChromaticAberration != false {
+		t.Fatalf("Chromatic-aberration setting not correct.")
+	} else if lc.InCameraDistortion == nil || *lc.InCameraDistortion != true {
+		t.Fatalf("Distortion setting not correct.")
+	}
+}
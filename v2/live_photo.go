@@ -0,0 +1,209 @@
+package exif
+
+import (
+	"errors"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// appleContentIdentifierTagId is the maker-note tag shared by a Live
+	// Photo still and its video.
+	appleContentIdentifierTagId = 0x0011
+
+	appleBurstUuidTagId       = 0x000b
+	applePhotoIdentifierTagId = 0x002b
+
+	// quickTimeContentIdentifier is the metadata key of the content
+	// identifier in the video.
+	quickTimeContentIdentifier = "com.apple.quicktime.content.identifier"
+)
+
+var (
+	livePhotoLogger = log.NewLogger("exif.live_photo")
+)
+
+var (
+	// ErrNoLivePhoto indicates that no pairing identifier was found.
+	ErrNoLivePhoto = errors.New("no live-photo identifier")
+)
+
+// LivePhotoInfo has the identifiers that Apple devices use to associate
+// related assets. A Live Photo still and its video have the same
+// ContentIdentifier. Photos from the same burst have the same BurstUuid.
+type LivePhotoInfo struct {
+	ContentIdentifier string
+	BurstUuid         string
+	PhotoIdentifier   string
+}
+
+// IsPairedWith returns true if the two assets belong to the same Live Photo.
+func (lpi LivePhotoInfo) IsPairedWith(other LivePhotoInfo) bool {
+	return lpi.ContentIdentifier != "" && lpi.ContentIdentifier == other.ContentIdentifier
+}
+
+// GetLivePhotoInfo returns the pairing identifiers from an image (JPEG or
+// HEIC). The Apple maker-note is checked first and the XMP is used as a
+// fallback for the content identifier. `ErrNoLivePhoto` is returned if there
+// is no content identifier.
+func GetLivePhotoInfo(data []byte) (lpi LivePhotoInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == nil {
+		_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+		if err == nil {
+			amn, err := getAppleMakerNote(index)
+			log.PanicIf(err)
+
+			if amn != nil {
+				lpi.ContentIdentifier, _ = amn.String(appleContentIdentifierTagId)
+				lpi.BurstUuid, _ = amn.String(appleBurstUuidTagId)
+				lpi.PhotoIdentifier, _ = amn.String(applePhotoIdentifierTagId)
+			}
+		} else {
+			livePhotoLogger.Warningf(nil, "Could not parse EXIF while looking for maker-note: %s", err)
+		}
+	} else if err != ErrNoExif {
+		log.Panic(err)
+	}
+
+	if lpi.ContentIdentifier == "" {
+		if xmp := findXmpPacket(data); xmp != nil {
+			if value := findXmpPropertyValue(xmp, ":ContentIdentifier"); value != nil {
+				lpi.ContentIdentifier = string(value)
+			}
+		}
+	}
+
+	if lpi.ContentIdentifier == "" {
+		return lpi, ErrNoLivePhoto
+	}
+
+	return lpi, nil
+}
+
+// quickTimeAtom is a box in a QuickTime/MP4 file.
+type quickTimeAtom struct {
+	atomType string
+	payload  []byte
+}
+
+// quickTimeAtoms returns the atoms at one level. A truncated final atom is
+// ignored.
+func quickTimeAtoms(data []byte) []quickTimeAtom {
+	atoms := make([]quickTimeAtom, 0)
+
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		atomType := string(data[4:8])
+		headerSize := uint64(8)
+
+		if size == 1 {
+			if len(data) < 16 {
+				break
+			}
+
+			size = binary.BigEndian.Uint64(data[8:])
+			headerSize = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+
+		if size < headerSize || size > uint64(len(data)) {
+			break
+		}
+
+		atoms = append(atoms, quickTimeAtom{
+			atomType: atomType,
+			payload:  data[headerSize:size],
+		})
+
+		data = data[size:]
+	}
+
+	return atoms
+}
+
+// findQuickTimeAtom returns the first child atom of the given type.
+func findQuickTimeAtom(data []byte, atomType string) (payload []byte, found bool) {
+	for _, atom := range quickTimeAtoms(data) {
+		if atom.atomType == atomType {
+			return atom.payload, true
+		}
+	}
+
+	return nil, false
+}
+
+// GetQuickTimeContentIdentifier returns the Live Photo content identifier
+// from the metadata of a QuickTime (MOV) file. This is the counterpart of
+// `LivePhotoInfo.ContentIdentifier`. `ErrNoLivePhoto` is returned if it isn't
+// present.
+func GetQuickTimeContentIdentifier(data []byte) (contentIdentifier string, err error) {
+	moov, found := findQuickTimeAtom(data, "moov")
+	if found == false {
+		return "", ErrNoLivePhoto
+	}
+
+	meta, found := findQuickTimeAtom(moov, "meta")
+	if found == false {
+		return "", ErrNoLivePhoto
+	}
+
+	keys, found := findQuickTimeAtom(meta, "keys")
+	if found == false || len(keys) < 8 {
+		return "", ErrNoLivePhoto
+	}
+
+	// The keys are numbered from one and come after the version/flags and
+	// the count.
+	keyIndex := uint32(0)
+	entries := keys[8:]
+
+	for i := uint32(1); len(entries) >= 8; i++ {
+		size := binary.BigEndian.Uint32(entries)
+		if size < 8 || uint64(size) > uint64(len(entries)) {
+			break
+		}
+
+		if string(entries[8:size]) == quickTimeContentIdentifier {
+			keyIndex = i
+			break
+		}
+
+		entries = entries[size:]
+	}
+
+	if keyIndex == 0 {
+		return "", ErrNoLivePhoto
+	}
+
+	ilst, found := findQuickTimeAtom(meta, "ilst")
+	if found == false {
+		return "", ErrNoLivePhoto
+	}
+
+	// Item atoms are typed by their key index rather than a name.
+	for _, item := range quickTimeAtoms(ilst) {
+		if binary.BigEndian.Uint32([]byte(item.atomType)) != keyIndex {
+			continue
+		}
+
+		value, found := findQuickTimeAtom(item.payload, "data")
+		if found == false || len(value) < 8 {
+			break
+		}
+
+		// Skip the type indicator and the locale.
+		return string(value[8:]), nil
+	}
+
+	return "", ErrNoLivePhoto
+}
@@ -0,0 +1,162 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+const (
+	testContentIdentifier = "6F9C6B5A-0A51-4E5C-9B0E-3F4D2C1A8E77"
+	testBurstUuid         = "0D2E4C55-1F44-4B6C-8C65-2B2F8C5D9A10"
+)
+
+func getTestLivePhotoMakerNote() []byte {
+	values := []string{testContentIdentifier + "\x00", testBurstUuid + "\x00"}
+	tagIds := []uint16{appleContentIdentifierTagId, appleBurstUuidTagId}
+
+	b := new(bytes.Buffer)
+
+	b.Write(appleMakerNoteSignature)
+	b.Write([]byte{0, 1, 'M', 'M'})
+
+	binary.Write(b, binary.BigEndian, uint16(len(values)))
+
+	valueOffset := 14 + 2 + len(values)*12 + 4
+	for i, value := range values {
+		binary.Write(b, binary.BigEndian, tagIds[i])
+		binary.Write(b, binary.BigEndian, uint16(exifcommon.TypeAscii))
+		binary.Write(b, binary.BigEndian, uint32(len(value)))
+		binary.Write(b, binary.BigEndian, uint32(valueOffset))
+
+		valueOffset += len(value)
+	}
+
+	binary.Write(b, binary.BigEndian, uint32(0))
+
+	for _, value := range values {
+		b.WriteString(value)
+	}
+
+	return b.Bytes()
+}
+
+func getTestLivePhotoJpeg() []byte {
+	root := exiftest.NewRealisticIfd()
+	exifIfd := root.Children[0].Ifd
+
+	makerNoteTag := exiftest.Tag{
+		Id:   0x927c,
+		Raw:  getTestLivePhotoMakerNote(),
+		Type: exifcommon.TypeUndefined,
+	}
+
+	exifIfd.Tags = append(exifIfd.Tags, makerNoteTag)
+
+	exifData, err := exiftest.Build(root, binary.LittleEndian)
+	log.PanicIf(err)
+
+	return exiftest.WrapJpeg(exifData)
+}
+
+func getTestQuickTimeAtom(atomType string, payload []byte) []byte {
+	atom := make([]byte, 8)
+	binary.BigEndian.PutUint32(atom, uint32(8+len(payload)))
+	copy(atom[4:], atomType)
+
+	return append(atom, payload...)
+}
+
+func getTestQuickTimeMovie(contentIdentifier string) []byte {
+	otherKey := "com.apple.quicktime.make"
+
+	keys := []byte{0, 0, 0, 0, 0, 0, 0, 2}
+	keys = append(keys, getTestQuickTimeAtom("mdta", []byte(otherKey))...)
+	keys = append(keys, getTestQuickTimeAtom("mdta", []byte(quickTimeContentIdentifier))...)
+
+	itemType := make([]byte, 4)
+	binary.BigEndian.PutUint32(itemType, 2)
+
+	data := append([]byte{0, 0, 0, 1, 0, 0, 0, 0}, contentIdentifier...)
+	ilst := getTestQuickTimeAtom(string(itemType), getTestQuickTimeAtom("data", data))
+
+	meta := getTestQuickTimeAtom("hdlr", make([]byte, 24))
+	meta = append(meta, getTestQuickTimeAtom("keys", keys)...)
+	meta = append(meta, getTestQuickTimeAtom("ilst", ilst)...)
+
+	movie := getTestQuickTimeAtom("ftyp", []byte("qt  \x00\x00\x00\x00qt  "))
+	movie = append(movie, getTestQuickTimeAtom("moov", getTestQuickTimeAtom("meta", meta))...)
+	movie = append(movie, getTestQuickTimeAtom("mdat", make([]byte, 16))...)
+
+	return movie
+}
+
+func TestGetLivePhotoInfo_MakerNote(t *testing.T) {
+	lpi, err := GetLivePhotoInfo(getTestLivePhotoJpeg())
+	log.PanicIf(err)
+
+	if lpi.ContentIdentifier != testContentIdentifier {
+		t.Fatalf("Content identifier not correct: [%s]", lpi.ContentIdentifier)
+	} else if lpi.BurstUuid != testBurstUuid {
+		t.Fatalf("Burst UUID not correct: [%s]", lpi.BurstUuid)
+	} else if lpi.PhotoIdentifier != "" {
+		t.Fatalf("Photo identifier not expected: [%s]", lpi.PhotoIdentifier)
+	}
+}
+
+func TestGetLivePhotoInfo_Xmp(t *testing.T) {
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:Description apple-fi:ContentIdentifier="` + testContentIdentifier + `"/></x:xmpmeta>`
+
+	data, err := SetJpegXmp(getTestJpegWithExif(), []byte(xmp))
+	log.PanicIf(err)
+
+	lpi, err := GetLivePhotoInfo(data)
+	log.PanicIf(err)
+
+	if lpi.ContentIdentifier != testContentIdentifier {
+		t.Fatalf("Content identifier not correct: [%s]", lpi.ContentIdentifier)
+	}
+}
+
+func TestGetLivePhotoInfo_None(t *testing.T) {
+	_, err := GetLivePhotoInfo(getTestJpegWithExif())
+	if err != ErrNoLivePhoto {
+		t.Fatalf("Expected no live-photo: %v", err)
+	}
+}
+
+func TestGetQuickTimeContentIdentifier(t *testing.T) {
+	contentIdentifier, err := GetQuickTimeContentIdentifier(getTestQuickTimeMovie(testContentIdentifier))
+	log.PanicIf(err)
+
+	if contentIdentifier != testContentIdentifier {
+		t.Fatalf("Content identifier not correct: [%s]", contentIdentifier)
+	}
+
+	_, err = GetQuickTimeContentIdentifier([]byte("not a movie"))
+	if err != ErrNoLivePhoto {
+		t.Fatalf("Expected no live-photo: %v", err)
+	}
+}
+
+func TestLivePhotoInfo_IsPairedWith(t *testing.T) {
+	still, err := GetLivePhotoInfo(getTestLivePhotoJpeg())
+	log.PanicIf(err)
+
+	contentIdentifier, err := GetQuickTimeContentIdentifier(getTestQuickTimeMovie(testContentIdentifier))
+	log.PanicIf(err)
+
+	video := LivePhotoInfo{ContentIdentifier: contentIdentifier}
+
+	if still.IsPairedWith(video) != true {
+		t.Fatalf("Expected still and video to be paired.")
+	} else if still.IsPairedWith(LivePhotoInfo{}) != false {
+		t.Fatalf("Expected no pairing with an empty identifier.")
+	}
+}
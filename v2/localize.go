@@ -0,0 +1,276 @@
+package exif
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// metersPerFoot converts lengths for `UnitsImperial`.
+	metersPerFoot = 0.3048
+)
+
+// MessageCatalog supplies the text that's shown to users, by key. Enum labels
+// are keyed by the name of the tag and the value (e.g. "MeteringMode.2") and
+// units by "unit." and the symbol (e.g. "unit.m" or "unit.ft").
+type MessageCatalog interface {
+	// Message returns the text for the key, if there is any.
+	Message(key string) (text string, found bool)
+}
+
+// MapCatalog is a `MessageCatalog` held in a map.
+type MapCatalog map[string]string
+
+// Message returns the text for the key.
+func (mc MapCatalog) Message(key string) (text string, found bool) {
+	text, found = mc[key]
+	return text, found
+}
+
+// DefaultCatalog is the English text. It's used for anything that the catalog
+// given to a `Localizer` doesn't have.
+var DefaultCatalog = MapCatalog{
+	"Orientation.1": "Horizontal (normal)",
+	"Orientation.2": "Mirror horizontal",
+	"Orientation.3": "Rotate 180",
+	"Orientation.4": "Mirror vertical",
+	"Orientation.5": "Mirror horizontal and rotate 270 CW",
+	"Orientation.6": "Rotate 90 CW",
+	"Orientation.7": "Mirror horizontal and rotate 90 CW",
+	"Orientation.8": "Rotate 270 CW",
+
+	"ResolutionUnit.1": "None",
+	"ResolutionUnit.2": "inches",
+	"ResolutionUnit.3": "cm",
+
+	"ExposureProgram.0": "Not defined",
+	"ExposureProgram.1": "Manual",
+	"ExposureProgram.2": "Program AE",
+	"ExposureProgram.3": "Aperture-priority AE",
+	"ExposureProgram.4": "Shutter speed priority AE",
+	"ExposureProgram.5": "Creative (slow speed)",
+	"ExposureProgram.6": "Action (high speed)",
+	"ExposureProgram.7": "Portrait",
+	"ExposureProgram.8": "Landscape",
+	"ExposureProgram.9": "Bulb",
+
+	"MeteringMode.0":   "Unknown",
+	"MeteringMode.1":   "Average",
+	"MeteringMode.2":   "Center-weighted average",
+	"MeteringMode.3":   "Spot",
+	"MeteringMode.4":   "Multi-spot",
+	"MeteringMode.5":   "Multi-segment",
+	"MeteringMode.6":   "Partial",
+	"MeteringMode.255": "Other",
+
+	"LightSource.0":   "Unknown",
+	"LightSource.1":   "Daylight",
+	"LightSource.2":   "Fluorescent",
+	"LightSource.3":   "Tungsten (incandescent)",
+	"LightSource.4":   "Flash",
+	"LightSource.9":   "Fine weather",
+	"LightSource.10":  "Cloudy",
+	"LightSource.11":  "Shade",
+	"LightSource.17":  "Standard light A",
+	"LightSource.18":  "Standard light B",
+	"LightSource.19":  "Standard light C",
+	"LightSource.20":  "D55",
+	"LightSource.21":  "D65",
+	"LightSource.22":  "D75",
+	"LightSource.23":  "D50",
+	"LightSource.24":  "ISO studio tungsten",
+	"LightSource.255": "Other",
+
+	"ColorSpace.1":     "sRGB",
+	"ColorSpace.65535": "Uncalibrated",
+
+	"ExposureMode.0": "Auto",
+	"ExposureMode.1": "Manual",
+	"ExposureMode.2": "Auto bracket",
+
+	"WhiteBalance.0": "Auto",
+	"WhiteBalance.1": "Manual",
+
+	"SceneCaptureType.0": "Standard",
+	"SceneCaptureType.1": "Landscape",
+	"SceneCaptureType.2": "Portrait",
+	"SceneCaptureType.3": "Night",
+
+	"GPSAltitudeRef.0": "Above sea level",
+	"GPSAltitudeRef.1": "Below sea level",
+
+	"unit.s":  "s",
+	"unit.EV": "EV",
+	"unit.m":  "m",
+	"unit.mm": "mm",
+	"unit.ft": "ft",
+}
+
+// enumTagNames are the tags whose values are labeled, by IFD path and tag ID.
+// The name is the prefix of their keys in the catalog.
+var enumTagNames = map[string]map[uint16]string{
+	exifcommon.IfdPathStandard: {
+		0x0112: "Orientation",
+		0x0128: "ResolutionUnit",
+	},
+	exifcommon.IfdPathStandardExif: {
+		0x8822: "ExposureProgram",
+		0x9207: "MeteringMode",
+		0x9208: "LightSource",
+		0xa001: "ColorSpace",
+		0xa402: "ExposureMode",
+		0xa403: "WhiteBalance",
+		0xa406: "SceneCaptureType",
+	},
+	exifcommon.IfdPathStandardGps: {
+		0x0005: "GPSAltitudeRef",
+	},
+}
+
+// UnitSystem determines the units that lengths are shown in.
+type UnitSystem int
+
+const (
+	// UnitsMetric shows lengths as they're stored, in meters. This is the
+	// default.
+	UnitsMetric UnitSystem = iota
+
+	// UnitsImperial shows lengths that are stored in meters (e.g. GPSAltitude
+	// and SubjectDistance) in feet.
+	UnitsImperial
+)
+
+// Localizer formats tag values for users, with enum values labeled and units
+// shown from a message catalog, and lengths in the chosen unit system.
+type Localizer struct {
+	catalog MessageCatalog
+	units   UnitSystem
+}
+
+// NewLocalizer returns a localizer with the given catalog, which may be nil
+// for `DefaultCatalog`.
+func NewLocalizer(catalog MessageCatalog, units UnitSystem) *Localizer {
+	if catalog == nil {
+		catalog = DefaultCatalog
+	}
+
+	return &Localizer{
+		catalog: catalog,
+		units:   units,
+	}
+}
+
+// Message returns the text for the key from the catalog or, failing that, from
+// `DefaultCatalog`.
+func (l *Localizer) Message(key string) (text string, found bool) {
+	if text, found := l.catalog.Message(key); found == true {
+		return text, true
+	}
+
+	return DefaultCatalog.Message(key)
+}
+
+// FormatTag returns the value of the tag for users. A single value of an enum
+// tag (e.g. MeteringMode) is shown as its label, if the catalog has one, and
+// the values of tags with a unit (e.g. FocalLength) are shown with it. Other
+// tags are formatted as for `Format()`.
+func (l *Localizer) FormatTag(ite *IfdTagEntry) (phrase string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	name, isEnum := enumTagNames[ite.ifdPath][ite.tagId]
+	unit := tagUnits[ite.ifdPath][ite.tagId]
+
+	if isEnum == false && unit == "" {
+		phrase, err = ite.Format()
+		log.PanicIf(err)
+
+		return phrase, nil
+	}
+
+	value, err := ite.Value()
+	log.PanicIf(err)
+
+	n, isSingle := enumValue(value)
+	if isEnum == true && isSingle == true {
+		if label, found := l.Message(fmt.Sprintf("%s.%d", name, n)); found == true {
+			return label, nil
+		}
+	}
+
+	options := exifcommon.FormatOptions{}
+
+	var unitText string
+	if unit != "" {
+		if rationals, ok := value.([]exifcommon.Rational); ok == true && unit == "m" && l.units == UnitsImperial {
+			value = rationalsToFeet(rationals)
+			unit = "ft"
+
+			options.RationalsAsDecimal = true
+			options.Precision = 1
+		}
+
+		var found bool
+		if unitText, found = l.Message("unit." + unit); found == false {
+			unitText = unit
+		}
+
+		options.Units = true
+	}
+
+	// A single number is shown on its own rather than as a list.
+	justFirst := isSingle
+	if rationals, ok := value.([]exifcommon.Rational); ok == true && len(rationals) == 1 {
+		justFirst = true
+	} else if signedRationals, ok := value.([]exifcommon.SignedRational); ok == true && len(signedRationals) == 1 {
+		justFirst = true
+	}
+
+	phrase, err = exifcommon.FormatFromTypeWithOptions(value, justFirst, options, unitText)
+	log.PanicIf(err)
+
+	return phrase, nil
+}
+
+// enumValue returns the value if it's a single integer.
+func enumValue(value interface{}) (n uint32, ok bool) {
+	switch v := value.(type) {
+	case []uint8:
+		if len(v) == 1 {
+			return uint32(v[0]), true
+		}
+	case []uint16:
+		if len(v) == 1 {
+			return uint32(v[0]), true
+		}
+	case []uint32:
+		if len(v) == 1 {
+			return v[0], true
+		}
+	}
+
+	return 0, false
+}
+
+// rationalsToFeet converts lengths in meters to feet, in tenths.
+func rationalsToFeet(rationals []exifcommon.Rational) []exifcommon.Rational {
+	feet := make([]exifcommon.Rational, len(rationals))
+	for i, r := range rationals {
+		if r.Denominator == 0 {
+			feet[i] = r
+			continue
+		}
+
+		tenths := math.Min(math.Round(float64(r.Numerator)/float64(r.Denominator)/metersPerFoot*10), math.MaxUint32)
+		feet[i] = exifcommon.Rational{Numerator: uint32(tenths), Denominator: 10}
+	}
+
+	return feet
+}
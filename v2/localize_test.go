@@ -0,0 +1,87 @@
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getTestLocalizedIndex() IfdIndex {
+	rootIb, err := BuilderFromMap(map[string]interface{}{
+		"IFD/Orientation":            6,
+		"IFD/Make":                   "Canon",
+		"IFD/Exif/MeteringMode":      5,
+		"IFD/Exif/LightSource":       99,
+		"IFD/Exif/FocalLength":       50,
+		"IFD/Exif/ExposureTime":      "1/250",
+		"IFD/Exif/ExposureBiasValue": -0.5,
+		"IFD/GPSInfo/GPSAltitudeRef": 0,
+		"IFD/GPSInfo/GPSAltitude":    100,
+	})
+	log.PanicIf(err)
+
+	rawExif, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestLocalizer_FormatTag(t *testing.T) {
+	index := getTestLocalizedIndex()
+
+	german := MapCatalog{
+		"MeteringMode.5":   "Mehrfeldmessung",
+		"GPSAltitudeRef.0": "Über dem Meeresspiegel",
+		"unit.m":           "Meter",
+	}
+
+	cases := []struct {
+		catalog   MessageCatalog
+		units     UnitSystem
+		fqIfdPath string
+		tagName   string
+		expected  string
+	}{
+		{nil, UnitsMetric, "IFD", "Orientation", "Rotate 90 CW"},
+		{nil, UnitsMetric, "IFD", "Make", "Canon"},
+		{nil, UnitsMetric, "IFD/Exif", "MeteringMode", "Multi-segment"},
+		{nil, UnitsMetric, "IFD/Exif", "LightSource", "99"},
+		{nil, UnitsMetric, "IFD/Exif", "FocalLength", "50/1 mm"},
+		{nil, UnitsMetric, "IFD/Exif", "ExposureTime", "1/250 s"},
+		{nil, UnitsMetric, "IFD/Exif", "ExposureBiasValue", "-1/2 EV"},
+		{nil, UnitsMetric, "IFD/GPSInfo", "GPSAltitudeRef", "Above sea level"},
+		{nil, UnitsMetric, "IFD/GPSInfo", "GPSAltitude", "100/1 m"},
+		{nil, UnitsImperial, "IFD/GPSInfo", "GPSAltitude", "328.1 ft"},
+		{german, UnitsMetric, "IFD/Exif", "MeteringMode", "Mehrfeldmessung"},
+		{german, UnitsMetric, "IFD/GPSInfo", "GPSAltitudeRef", "Über dem Meeresspiegel"},
+		{german, UnitsMetric, "IFD/GPSInfo", "GPSAltitude", "100/1 Meter"},
+		{german, UnitsMetric, "IFD", "Orientation", "Rotate 90 CW"},
+	}
+
+	for _, c := range cases {
+		results, err := index.Lookup[c.fqIfdPath][0].FindTagWithName(c.tagName)
+		log.PanicIf(err)
+
+		phrase, err := NewLocalizer(c.catalog, c.units).FormatTag(results[0])
+		log.PanicIf(err)
+
+		if phrase != c.expected {
+			t.Fatalf("Phrase for [%s] [%s] not correct: [%s] != [%s]", c.fqIfdPath, c.tagName, phrase, c.expected)
+		}
+	}
+}
+
+func TestLocalizer_Message(t *testing.T) {
+	l := NewLocalizer(MapCatalog{"MeteringMode.3": "Spot (local)"}, UnitsMetric)
+
+	if text, found := l.Message("MeteringMode.3"); found != true || text != "Spot (local)" {
+		t.Fatalf("Catalog message not correct: [%s]", text)
+	} else if text, found := l.Message("MeteringMode.2"); found != true || text != "Center-weighted average" {
+		t.Fatalf("Default message not correct: [%s]", text)
+	} else if _, found := l.Message("not-a-key"); found != false {
+		t.Fatalf("Expected no message.")
+	}
+}
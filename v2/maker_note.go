@@ -0,0 +1,417 @@
+package exif
+
+import (
+	"bytes"
+	"strings"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+const (
+	makerNoteVendorApple  = "Apple"
+	makerNoteVendorCanon  = "Canon"
+	makerNoteVendorNikon  = "Nikon"
+	makerNoteVendorSony   = "Sony"
+	makerNoteVendorPentax = "Pentax"
+)
+
+const (
+	sonyMakerNoteHeaderSize    = 12
+	nikonMakerNoteTiffPosition = 10
+	pentaxMakerNoteHeaderSize  = 6
+)
+
+var (
+	appleMakerNoteSignature = []byte("Apple iOS\x00")
+	nikonMakerNoteSignature = []byte("Nikon\x00")
+
+	pentaxMakerNoteSignature = []byte("AOC\x00")
+
+	sonyMakerNoteSignatures = [][]byte{
+		[]byte("SONY DSC \x00\x00\x00"),
+		[]byte("SONY CAM \x00\x00\x00"),
+	}
+)
+
+// makerNoteByteOrders are the byte orders that have been registered for the
+// maker-notes of vendors.
+var makerNoteByteOrders = make(map[string]binary.ByteOrder)
+
+// RegisterMakerNoteByteOrder sets the byte order that the maker-notes of the
+// given vendor ("Canon" or "Sony") are read in. These don't have a header
+// that says, and, by default, they're read in the byte order of the file
+// unless they only make sense in the other one.
+func RegisterMakerNoteByteOrder(vendor string, byteOrder binary.ByteOrder) {
+	makerNoteByteOrders[vendor] = byteOrder
+}
+
+// makerNoteByteOrder returns the byte order to read the maker-note IFD of the
+// given vendor at the given offset with.
+func makerNoteByteOrder(vendor string, data []byte, ifdOffset int, byteOrder binary.ByteOrder) binary.ByteOrder {
+	if registered, found := makerNoteByteOrders[vendor]; found == true {
+		return registered
+	} else if ifdOffset < 0 {
+		return byteOrder
+	}
+
+	return DetectIfdByteOrder(data, uint32(ifdOffset), byteOrder)
+}
+
+// makerNoteEntry is one tag from a maker-note along with its value bytes.
+type makerNoteEntry struct {
+	tagType   exifcommon.TagTypePrimitive
+	unitCount uint32
+	value     []byte
+}
+
+// makerNoteIfd is a maker-note that is laid out as an IFD. Most vendors do
+// this, though they differ in the header that precedes the IFD and in what
+// the value offsets are relative to.
+type makerNoteIfd struct {
+	byteOrder binary.ByteOrder
+	entries   map[uint16]makerNoteEntry
+}
+
+// parseMakerNoteIfd reads the IFD at the given offset. Value offsets are
+// relative to the start of `data`. Entries whose values are out of bounds or
+// whose types aren't valid are skipped.
+func parseMakerNoteIfd(data []byte, ifdOffset int, byteOrder binary.ByteOrder) *makerNoteIfd {
+	mni := &makerNoteIfd{
+		byteOrder: byteOrder,
+		entries:   make(map[uint16]makerNoteEntry),
+	}
+
+	if ifdOffset < 0 || ifdOffset+2 > len(data) {
+		return mni
+	}
+
+	tagCount := int(byteOrder.Uint16(data[ifdOffset:]))
+
+	for i := 0; i < tagCount; i++ {
+		entryOffset := ifdOffset + 2 + i*int(IfdTagEntrySize)
+		if entryOffset+int(IfdTagEntrySize) > len(data) {
+			break
+		}
+
+		entry := data[entryOffset:]
+
+		tagId := byteOrder.Uint16(entry[0:])
+		tagType := exifcommon.TagTypePrimitive(byteOrder.Uint16(entry[2:]))
+		unitCount := byteOrder.Uint32(entry[4:])
+
+		if tagType.IsValid() == false {
+			continue
+		}
+
+		var byteCount uint64
+		if tagType == exifcommon.TypeUndefined {
+			byteCount = uint64(unitCount)
+		} else {
+			byteCount = uint64(tagType.Size()) * uint64(unitCount)
+		}
+
+		var value []byte
+		if byteCount <= 4 {
+			value = entry[8 : 8+byteCount]
+		} else {
+			valueOffset := uint64(byteOrder.Uint32(entry[8:]))
+			if valueOffset+byteCount > uint64(len(data)) {
+				continue
+			}
+
+			value = data[valueOffset : valueOffset+byteCount]
+		}
+
+		mni.entries[tagId] = makerNoteEntry{
+			tagType:   tagType,
+			unitCount: unitCount,
+			value:     value,
+		}
+	}
+
+	return mni
+}
+
+// String returns the value of an ASCII tag.
+func (mni *makerNoteIfd) String(tagId uint16) (value string, found bool) {
+	entry, found := mni.entries[tagId]
+	if found == false || entry.tagType != exifcommon.TypeAscii {
+		return "", false
+	}
+
+	return string(bytes.TrimRight(entry.value, "\x00")), true
+}
+
+// Int returns the first value of an integer tag.
+func (mni *makerNoteIfd) Int(tagId uint16) (value int64, found bool) {
+	entry, found := mni.entries[tagId]
+	if found == false || entry.unitCount == 0 {
+		return 0, false
+	}
+
+	switch entry.tagType {
+	case exifcommon.TypeShort:
+		return int64(mni.byteOrder.Uint16(entry.value)), true
+	case exifcommon.TypeLong:
+		return int64(mni.byteOrder.Uint32(entry.value)), true
+	case exifcommon.TypeSignedLong:
+		return int64(int32(mni.byteOrder.Uint32(entry.value))), true
+	}
+
+	return 0, false
+}
+
+// Uint16s returns all of the values of a SHORT tag.
+func (mni *makerNoteIfd) Uint16s(tagId uint16) (values []uint16, found bool) {
+	entry, found := mni.entries[tagId]
+	if found == false || entry.tagType != exifcommon.TypeShort {
+		return nil, false
+	}
+
+	values = make([]uint16, entry.unitCount)
+	for i := range values {
+		values[i] = mni.byteOrder.Uint16(entry.value[i*2:])
+	}
+
+	return values, true
+}
+
+// Floats returns all of the values of a rational tag.
+func (mni *makerNoteIfd) Floats(tagId uint16) (values []float64, found bool) {
+	entry, found := mni.entries[tagId]
+	if found == false {
+		return nil, false
+	}
+
+	values = make([]float64, 0, entry.unitCount)
+
+	for i := 0; i < int(entry.unitCount); i++ {
+		raw := entry.value[i*8:]
+
+		switch entry.tagType {
+		case exifcommon.TypeRational:
+			numerator := mni.byteOrder.Uint32(raw)
+			denominator := mni.byteOrder.Uint32(raw[4:])
+
+			if denominator == 0 {
+				return nil, false
+			}
+
+			values = append(values, float64(numerator)/float64(denominator))
+		case exifcommon.TypeSignedRational:
+			numerator := int32(mni.byteOrder.Uint32(raw))
+			denominator := int32(mni.byteOrder.Uint32(raw[4:]))
+
+			if denominator == 0 {
+				return nil, false
+			}
+
+			values = append(values, float64(numerator)/float64(denominator))
+		default:
+			return nil, false
+		}
+	}
+
+	return values, true
+}
+
+// Float returns the first value of a rational tag.
+func (mni *makerNoteIfd) Float(tagId uint16) (value float64, found bool) {
+	values, found := mni.Floats(tagId)
+	if found == false || len(values) == 0 {
+		return 0, false
+	}
+
+	return values[0], true
+}
+
+// getMakerNoteTag returns the MakerNote entry from the Exif IFD or nil if
+// there isn't one.
+func getMakerNoteTag(index IfdIndex) (ite *IfdTagEntry, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifds := index.Lookup[exifcommon.IfdPathStandardExif]
+	if len(ifds) == 0 {
+		return nil, nil
+	}
+
+	results, err := ifds[0].FindTagWithName("MakerNote")
+	if err != nil {
+		if log.Is(err, ErrTagNotFound) == true {
+			return nil, nil
+		}
+
+		log.Panic(err)
+	}
+
+	return results[0], nil
+}
+
+// getAppleMakerNote returns the Apple maker-note from the Exif IFD or nil if
+// there isn't one.
+func getAppleMakerNote(index IfdIndex) (mni *makerNoteIfd, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ite, err := getMakerNoteTag(index)
+	log.PanicIf(err)
+
+	if ite == nil {
+		return nil, nil
+	}
+
+	value, err := ite.Value()
+	log.PanicIf(err)
+
+	makerNote, ok := value.(exifundefined.Tag927CMakerNote)
+	if ok == false {
+		return nil, nil
+	}
+
+	return parseAppleMakerNote(makerNote.MakerNoteBytes), nil
+}
+
+// parseAppleMakerNote returns nil if the data isn't an Apple maker-note. It
+// has a short header followed by an IFD (usually big-endian) whose offsets
+// are relative to the start of the maker-note.
+func parseAppleMakerNote(raw []byte) *makerNoteIfd {
+	if bytes.HasPrefix(raw, appleMakerNoteSignature) == false || len(raw) < 16 {
+		return nil
+	}
+
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if raw[12] == 'I' {
+		byteOrder = binary.LittleEndian
+	}
+
+	return parseMakerNoteIfd(raw, 14, byteOrder)
+}
+
+// parseNikonMakerNote returns nil if the data isn't a (type 3) Nikon
+// maker-note, which embeds its own TIFF header after the signature. Offsets
+// are relative to that header.
+func parseNikonMakerNote(raw []byte) *makerNoteIfd {
+	if bytes.HasPrefix(raw, nikonMakerNoteSignature) == false || len(raw) < nikonMakerNoteTiffPosition+8 {
+		return nil
+	}
+
+	tiff := raw[nikonMakerNoteTiffPosition:]
+
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if tiff[0] == 'I' {
+		byteOrder = binary.LittleEndian
+	}
+
+	return parseMakerNoteIfd(tiff, int(byteOrder.Uint32(tiff[4:])), byteOrder)
+}
+
+// parseSonyMakerNote returns nil if the maker-note isn't a Sony one. It has a
+// 12-byte header before the IFD, and offsets are relative to the EXIF data.
+func parseSonyMakerNote(ite *IfdTagEntry, byteOrder binary.ByteOrder) *makerNoteIfd {
+	offset := int(ite.getValueOffset())
+	data := ite.addressableData
+
+	for _, signature := range sonyMakerNoteSignatures {
+		if offset+len(signature) <= len(data) && bytes.Equal(data[offset:offset+len(signature)], signature) == true {
+			ifdOffset := offset + sonyMakerNoteHeaderSize
+			byteOrder = makerNoteByteOrder(makerNoteVendorSony, data, ifdOffset, byteOrder)
+
+			return parseMakerNoteIfd(data, ifdOffset, byteOrder)
+		}
+	}
+
+	return nil
+}
+
+// parsePentaxMakerNote returns nil if the maker-note isn't a Pentax one. It
+// has a 6-byte header ("AOC\0" and, usually, a byte-order mark) before the
+// IFD, and offsets are relative to the EXIF data.
+func parsePentaxMakerNote(ite *IfdTagEntry, byteOrder binary.ByteOrder) *makerNoteIfd {
+	offset := int(ite.getValueOffset())
+	data := ite.addressableData
+
+	if offset+pentaxMakerNoteHeaderSize > len(data) || bytes.HasPrefix(data[offset:], pentaxMakerNoteSignature) == false {
+		return nil
+	}
+
+	switch string(data[offset+4 : offset+6]) {
+	case "MM":
+		byteOrder = binary.BigEndian
+	case "II":
+		byteOrder = binary.LittleEndian
+	}
+
+	return parseMakerNoteIfd(data, offset+pentaxMakerNoteHeaderSize, byteOrder)
+}
+
+// getVendorMakerNote identifies and parses the maker-note based on the Make
+// tag. Canon maker-notes have no header and their offsets are relative to
+// the EXIF data. An empty vendor is returned if there's no maker-note or it's
+// not one that we understand.
+func getVendorMakerNote(index IfdIndex) (vendor string, mni *makerNoteIfd, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ite, err := getMakerNoteTag(index)
+	log.PanicIf(err)
+
+	if ite == nil {
+		return "", nil, nil
+	}
+
+	make_, err := getIfdTagString(index.RootIfd, "Make")
+	log.PanicIf(err)
+
+	byteOrder := index.RootIfd.ByteOrder
+	lowered := strings.ToLower(make_)
+
+	if strings.HasPrefix(lowered, "canon") == true {
+		ifdOffset := int(ite.getValueOffset())
+		byteOrder = makerNoteByteOrder(makerNoteVendorCanon, ite.addressableData, ifdOffset, byteOrder)
+
+		return makerNoteVendorCanon, parseMakerNoteIfd(ite.addressableData, ifdOffset, byteOrder), nil
+	} else if strings.HasPrefix(lowered, "sony") == true {
+		if mni := parseSonyMakerNote(ite, byteOrder); mni != nil {
+			return makerNoteVendorSony, mni, nil
+		}
+
+		return "", nil, nil
+	} else if strings.HasPrefix(lowered, "pentax") == true || strings.HasPrefix(lowered, "ricoh") == true {
+		if mni := parsePentaxMakerNote(ite, byteOrder); mni != nil {
+			return makerNoteVendorPentax, mni, nil
+		}
+
+		return "", nil, nil
+	}
+
+	value, err := ite.Value()
+	log.PanicIf(err)
+
+	makerNote, ok := value.(exifundefined.Tag927CMakerNote)
+	if ok == false {
+		return "", nil, nil
+	}
+
+	if mni := parseNikonMakerNote(makerNote.MakerNoteBytes); mni != nil {
+		return makerNoteVendorNikon, mni, nil
+	} else if mni := parseAppleMakerNote(makerNote.MakerNoteBytes); mni != nil {
+		return makerNoteVendorApple, mni, nil
+	}
+
+	return "", nil, nil
+}
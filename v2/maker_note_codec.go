@@ -0,0 +1,492 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// MakerNoteIfdPath is the IFD-path that the value contexts of maker-note
+	// tags report.
+	MakerNoteIfdPath = "IFD/Exif/MakerNote"
+)
+
+var (
+	// ErrNoMakerNote means that there's no maker-note or that no codec
+	// understands it.
+	ErrNoMakerNote = errors.New("no maker-note")
+)
+
+var (
+	canonMakerNoteTagNames = map[uint16]string{
+		canonCameraSettingsTagId:       "CameraSettings",
+		0x0002:                         "FocalLength",
+		canonShotInfoTagId:             "ShotInfo",
+		0x0006:                         "ImageType",
+		0x0007:                         "FirmwareVersion",
+		0x0008:                         "FileNumber",
+		0x0009:                         "OwnerName",
+		canonSerialNumberTagId:         "SerialNumber",
+		0x000d:                         "CameraInfo",
+		0x0010:                         "CanonModelID",
+		canonAfInfo2TagId:              "AFInfo2",
+		0x0035:                         "TimeInfo",
+		0x0093:                         "FileInfo",
+		0x0095:                         "LensModel",
+		canonInternalSerialNumberTagId: "InternalSerialNumber",
+		0x0099:                         "CustomFunctions2",
+		0x009a:                         "AspectInfo",
+		0x00a0:                         "ProcessingInfo",
+		0x00e0:                         "SensorInfo",
+		canonColorDataTagId:            "ColorData",
+		canonVignettingCorr2TagId:      "VignettingCorr2",
+	}
+
+	nikonMakerNoteTagNames = map[uint16]string{
+		0x0001:                    "MakerNoteVersion",
+		0x0002:                    "ISO",
+		0x0004:                    "Quality",
+		nikonWhiteBalanceTagId:    "WhiteBalance",
+		nikonWbRbLevelsTagId:      "WB_RBLevels",
+		nikonSerialNumberTagId:    "SerialNumber",
+		nikonVignetteControlTagId: "VignetteControl",
+		nikonDistortInfoTagId:     "DistortInfo",
+		0x0084:                    "Lens",
+		nikonAfInfoTagId:          "AFInfo",
+		nikonShootingModeTagId:    "ShootingMode",
+		0x0098:                    "LensData",
+		nikonShutterCountTagId:    "ShutterCount",
+	}
+
+	sonyMakerNoteTagNames = map[uint16]string{
+		0x0102:                    "Quality",
+		0x0104:                    "FlashExposureComp",
+		sonyWhiteBalanceTagId:     "WhiteBalance",
+		sonyFocusLocationTagId:    "FocusLocation",
+		sonyTag9050TagId:          "Tag9050",
+		0xb001:                    "SonyModelID",
+		0xb020:                    "CreativeStyle",
+		sonyColorTemperatureTagId: "ColorTemperature",
+		0xb027:                    "LensType",
+		sonyReleaseModeTagId:      "ReleaseMode",
+		sonySequenceNumberTagId:   "SequenceNumber",
+	}
+)
+
+// MakerNoteData is what a `MakerNoteCodec` decodes.
+type MakerNoteData struct {
+	// Make is the Make tag of the image.
+	Make string
+
+	// Raw is the value of the MakerNote tag.
+	Raw []byte
+
+	// ExifData is the whole EXIF block (starting with the TIFF header) and
+	// Offset is where `Raw` is in it. Many vendors use offsets that are
+	// relative to the EXIF block rather than to the maker-note.
+	ExifData []byte
+	Offset   uint32
+
+	// ByteOrder is the byte order of the EXIF block.
+	ByteOrder binary.ByteOrder
+}
+
+// MakerNoteTag is one tag of a decoded maker-note. Its value is read through
+// the same `ValueContext` as a standard tag.
+type MakerNoteTag struct {
+	TagId     uint16
+	TagName   string
+	TagType   exifcommon.TagTypePrimitive
+	UnitCount uint32
+
+	// Ifds are the IFDs that the tag points to, if it has the IFD type. The
+	// tag itself then reads as the offsets of those IFDs.
+	Ifds []*MakerNote
+
+	vc *exifcommon.ValueContext
+}
+
+// ValueContext returns the context that the value is read with.
+// Undefined-type values are read as bytes.
+func (mnt *MakerNoteTag) ValueContext() *exifcommon.ValueContext {
+	return mnt.vc
+}
+
+// Value returns the decoded value (see `ValueContext.Values()`).
+func (mnt *MakerNoteTag) Value() (value interface{}, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if mnt.TagType == exifcommon.TypeUndefined {
+		value, err = mnt.vc.ReadBytes()
+		log.PanicIf(err)
+
+		return value, nil
+	}
+
+	value, err = mnt.vc.Values()
+	log.PanicIf(err)
+
+	return value, nil
+}
+
+// Format returns the value as a string. ASCII values end at the first NUL.
+func (mnt *MakerNoteTag) Format() (phrase string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if mnt.TagType == exifcommon.TypeAscii {
+		phrase, err = mnt.vc.ReadAscii()
+		log.PanicIf(err)
+
+		// Cameras leave junk in the padding after the NUL.
+		if i := strings.IndexByte(phrase, 0); i != -1 {
+			phrase = phrase[:i]
+		}
+
+		return phrase, nil
+	}
+
+	phrase, err = mnt.vc.Format()
+	log.PanicIf(err)
+
+	return phrase, nil
+}
+
+// String returns a descriptive string.
+func (mnt *MakerNoteTag) String() string {
+	return fmt.Sprintf("MakerNoteTag<TAG-ID=(0x%04x) TAG-NAME=[%s] TAG-TYPE=[%s] UNIT-COUNT=(%d)>", mnt.TagId, mnt.TagName, mnt.TagType, mnt.UnitCount)
+}
+
+// MakerNote is a decoded maker-note.
+type MakerNote struct {
+	Vendor    string
+	ByteOrder binary.ByteOrder
+	Tags      []*MakerNoteTag
+}
+
+// String returns a descriptive string.
+func (mn *MakerNote) String() string {
+	return fmt.Sprintf("MakerNote<VENDOR=[%s] TAGS=(%d)>", mn.Vendor, len(mn.Tags))
+}
+
+// FindTagWithId returns the tag with the given ID. `ErrTagNotFound` is
+// returned if it's not present.
+func (mn *MakerNote) FindTagWithId(tagId uint16) (mnt *MakerNoteTag, err error) {
+	for _, mnt := range mn.Tags {
+		if mnt.TagId == tagId {
+			return mnt, nil
+		}
+	}
+
+	return nil, ErrTagNotFound
+}
+
+// FindTagWithName returns the tag with the given name. `ErrTagNotFound` is
+// returned if it's not present or the codec doesn't know its name.
+func (mn *MakerNote) FindTagWithName(tagName string) (mnt *MakerNoteTag, err error) {
+	for _, mnt := range mn.Tags {
+		if mnt.TagName == tagName {
+			return mnt, nil
+		}
+	}
+
+	return nil, ErrTagNotFound
+}
+
+// MakerNoteCodec decodes the maker-notes of one vendor. `Decode()` should
+// return `ErrNoMakerNote` if it doesn't recognize the data.
+type MakerNoteCodec interface {
+	Decode(mnd MakerNoteData) (mn *MakerNote, err error)
+}
+
+// registeredMakerNoteCodec is a codec and the Make prefix that it's used for.
+type registeredMakerNoteCodec struct {
+	makePrefix string
+	codec      MakerNoteCodec
+}
+
+var (
+	makerNoteCodecs      = make([]registeredMakerNoteCodec, 0)
+	makerNoteCodecsMutex sync.RWMutex
+)
+
+// RegisterMakerNoteCodec sets the codec for images whose Make starts with the
+// given prefix (ignoring case). Codecs registered later are tried first, so
+// the built-in Canon, Nikon, and Sony codecs can be replaced.
+func RegisterMakerNoteCodec(makePrefix string, codec MakerNoteCodec) {
+	makerNoteCodecsMutex.Lock()
+	defer makerNoteCodecsMutex.Unlock()
+
+	rmnc := registeredMakerNoteCodec{
+		makePrefix: strings.ToLower(makePrefix),
+		codec:      codec,
+	}
+
+	makerNoteCodecs = append([]registeredMakerNoteCodec{rmnc}, makerNoteCodecs...)
+}
+
+// findMakerNoteCodec returns the codec for the given Make or nil.
+func findMakerNoteCodec(make_ string) MakerNoteCodec {
+	makerNoteCodecsMutex.RLock()
+	defer makerNoteCodecsMutex.RUnlock()
+
+	lowered := strings.ToLower(make_)
+
+	for _, rmnc := range makerNoteCodecs {
+		if strings.HasPrefix(lowered, rmnc.makePrefix) == true {
+			return rmnc.codec
+		}
+	}
+
+	return nil
+}
+
+// DecodeMakerNote decodes the maker-note with the codec registered for the
+// image's Make. `ErrNoMakerNote` is returned if there's no maker-note, no
+// codec for the Make, or the codec doesn't recognize it. The index must have
+// been collected from a byte slice.
+func DecodeMakerNote(index IfdIndex) (mn *MakerNote, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ite, err := getMakerNoteTag(index)
+	log.PanicIf(err)
+
+	if ite == nil || ite.addressableData == nil {
+		return nil, ErrNoMakerNote
+	}
+
+	make_, err := getIfdTagString(index.RootIfd, "Make")
+	log.PanicIf(err)
+
+	codec := findMakerNoteCodec(make_)
+	if codec == nil {
+		return nil, ErrNoMakerNote
+	}
+
+	raw, err := ite.GetRawBytes()
+	log.PanicIf(err)
+
+	mnd := MakerNoteData{
+		Make:      make_,
+		Raw:       raw,
+		ExifData:  ite.addressableData,
+		Offset:    ite.getValueOffset(),
+		ByteOrder: index.RootIfd.ByteOrder,
+	}
+
+	mn, err = codec.Decode(mnd)
+	if err == ErrNoMakerNote {
+		return nil, err
+	}
+
+	log.PanicIf(err)
+
+	return mn, nil
+}
+
+// DecodeMakerNoteIfd decodes a maker-note that is laid out as an IFD at the
+// given offset in `data`, with value offsets relative to the start of
+// `data`. Entries whose types aren't valid or whose values are out of bounds
+// are skipped. Entries with the IFD type have the IFDs that they point to
+// decoded into `Ifds`, down to the depth set by `SetMaxMakerNoteDepth()`;
+// deeper IFDs, and IFDs that were already decoded, are skipped. Codecs can
+// use this once they've found the IFD.
+func DecodeMakerNoteIfd(vendor string, data []byte, ifdOffset uint32, byteOrder binary.ByteOrder, tagNames map[uint16]string) (mn *MakerNote, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	seenOffsets := map[uint32]struct{}{
+		ifdOffset: {},
+	}
+
+	mn, err = decodeMakerNoteIfd(vendor, data, ifdOffset, byteOrder, tagNames, 1, seenOffsets)
+	log.PanicIf(err)
+
+	return mn, nil
+}
+
+// decodeMakerNoteIfd decodes the maker-note IFD at the given depth, and the
+// IFDs nested in it.
+func decodeMakerNoteIfd(vendor string, data []byte, ifdOffset uint32, byteOrder binary.ByteOrder, tagNames map[uint16]string, depth int, seenOffsets map[uint32]struct{}) (mn *MakerNote, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	entries, _, err := readRawIfdTable(data, ifdOffset, byteOrder)
+	log.PanicIf(err)
+
+	mn = &MakerNote{
+		Vendor:    vendor,
+		ByteOrder: byteOrder,
+		Tags:      make([]*MakerNoteTag, 0, len(entries)),
+	}
+
+	for _, pe := range entries {
+		isIfd := pe.tagType == subIfdTagTypeIfd
+		if isIfd == true {
+			pe.tagType = exifcommon.TypeLong
+		} else if pe.tagType.IsValid() == false {
+			continue
+		}
+
+		size := pe.valueSize()
+		valueOffset := byteOrder.Uint32(pe.valueOffset[:])
+
+		if size > 4 {
+			if _, err := exifcommon.CheckedSlice(data, valueOffset, size); err != nil {
+				continue
+			}
+		}
+
+		rawValueOffset := make([]byte, 4)
+		copy(rawValueOffset, pe.valueOffset[:])
+
+		vc := exifcommon.NewValueContext(MakerNoteIfdPath, pe.tagId, pe.unitCount, valueOffset, rawValueOffset, data, pe.tagType, byteOrder)
+
+		if pe.tagType == exifcommon.TypeUndefined {
+			vc.SetUndefinedValueType(exifcommon.TypeByte)
+		}
+
+		mnt := &MakerNoteTag{
+			TagId:     pe.tagId,
+			TagName:   tagNames[pe.tagId],
+			TagType:   pe.tagType,
+			UnitCount: pe.unitCount,
+			vc:        vc,
+		}
+
+		if isIfd == true {
+			mnt.Ifds = decodeNestedMakerNoteIfds(vendor, data, pe, byteOrder, depth, seenOffsets)
+		}
+
+		mn.Tags = append(mn.Tags, mnt)
+	}
+
+	return mn, nil
+}
+
+// decodeNestedMakerNoteIfds decodes the IFDs that an IFD-type entry of a
+// maker-note at the given depth points to. IFDs that are too deep, that were
+// already decoded, or that can't be read are skipped.
+func decodeNestedMakerNoteIfds(vendor string, data []byte, pe rawIfdEntry, byteOrder binary.ByteOrder, depth int, seenOffsets map[uint32]struct{}) []*MakerNote {
+	ifds := make([]*MakerNote, 0)
+
+	for i, offset := range readRawIfdUints(data, pe, byteOrder) {
+		if _, found := seenOffsets[offset]; found == true {
+			continue
+		} else if depth >= getMaxMakerNoteDepth() {
+			ifdEnumerateLogger.Warningf(nil, "Maker-note IFD (%d) of tag (0x%04x) at offset (0x%08x) is nested too deeply.", i, pe.tagId, offset)
+			continue
+		}
+
+		seenOffsets[offset] = struct{}{}
+
+		mn, err := decodeMakerNoteIfd(vendor, data, offset, byteOrder, nil, depth+1, seenOffsets)
+		if err != nil {
+			ifdEnumerateLogger.Warningf(nil, "Maker-note IFD (%d) of tag (0x%04x) at offset (0x%08x) could not be read: %s", i, pe.tagId, offset, err)
+			continue
+		}
+
+		ifds = append(ifds, mn)
+	}
+
+	return ifds
+}
+
+// canonMakerNoteCodec decodes Canon maker-notes, which are an IFD with no
+// header and offsets relative to the EXIF block.
+type canonMakerNoteCodec struct{}
+
+// Decode decodes the maker-note.
+func (canonMakerNoteCodec) Decode(mnd MakerNoteData) (mn *MakerNote, err error) {
+	byteOrder := makerNoteByteOrder(makerNoteVendorCanon, mnd.ExifData, int(mnd.Offset), mnd.ByteOrder)
+
+	mn, err = DecodeMakerNoteIfd(makerNoteVendorCanon, mnd.ExifData, mnd.Offset, byteOrder, canonMakerNoteTagNames)
+	if err != nil {
+		return nil, ErrNoMakerNote
+	}
+
+	return mn, nil
+}
+
+// nikonMakerNoteCodec decodes (type 3) Nikon maker-notes, which embed their
+// own TIFF header after a signature. Offsets are relative to that header.
+type nikonMakerNoteCodec struct{}
+
+// Decode decodes the maker-note.
+func (nikonMakerNoteCodec) Decode(mnd MakerNoteData) (mn *MakerNote, err error) {
+	raw := mnd.Raw
+	if bytes.HasPrefix(raw, nikonMakerNoteSignature) == false || len(raw) < nikonMakerNoteTiffPosition+8 {
+		return nil, ErrNoMakerNote
+	}
+
+	tiff := raw[nikonMakerNoteTiffPosition:]
+
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if tiff[0] == 'I' {
+		byteOrder = binary.LittleEndian
+	}
+
+	mn, err = DecodeMakerNoteIfd(makerNoteVendorNikon, tiff, byteOrder.Uint32(tiff[4:]), byteOrder, nikonMakerNoteTagNames)
+	if err != nil {
+		return nil, ErrNoMakerNote
+	}
+
+	return mn, nil
+}
+
+// sonyMakerNoteCodec decodes Sony maker-notes, which have a 12-byte header
+// before the IFD and offsets relative to the EXIF block.
+type sonyMakerNoteCodec struct{}
+
+// Decode decodes the maker-note.
+func (sonyMakerNoteCodec) Decode(mnd MakerNoteData) (mn *MakerNote, err error) {
+	for _, signature := range sonyMakerNoteSignatures {
+		if bytes.HasPrefix(mnd.Raw, signature) == false {
+			continue
+		}
+
+		ifdOffset := mnd.Offset + sonyMakerNoteHeaderSize
+		byteOrder := makerNoteByteOrder(makerNoteVendorSony, mnd.ExifData, int(ifdOffset), mnd.ByteOrder)
+
+		mn, err = DecodeMakerNoteIfd(makerNoteVendorSony, mnd.ExifData, ifdOffset, byteOrder, sonyMakerNoteTagNames)
+		if err != nil {
+			return nil, ErrNoMakerNote
+		}
+
+		return mn, nil
+	}
+
+	return nil, ErrNoMakerNote
+}
+
+func init() {
+	RegisterMakerNoteCodec("canon", canonMakerNoteCodec{})
+	RegisterMakerNoteCodec("nikon", nikonMakerNoteCodec{})
+	RegisterMakerNoteCodec("sony", sonyMakerNoteCodec{})
+}
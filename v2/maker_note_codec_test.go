@@ -0,0 +1,159 @@
+package exif
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestDecodeMakerNote_Canon(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	mn, err := DecodeMakerNote(index)
+	log.PanicIf(err)
+
+	if mn.Vendor != makerNoteVendorCanon {
+		t.Fatalf("Vendor not correct: [%s]", mn.Vendor)
+	}
+
+	mnt, err := mn.FindTagWithName("LensModel")
+	log.PanicIf(err)
+
+	phrase, err := mnt.Format()
+	log.PanicIf(err)
+
+	if phrase != "EF16-35mm f/4L IS USM" {
+		t.Fatalf("LensModel not correct: [%s]", phrase)
+	}
+
+	mnt, err = mn.FindTagWithId(canonInternalSerialNumberTagId)
+	log.PanicIf(err)
+
+	value, err := mnt.Value()
+	log.PanicIf(err)
+
+	if value != "AD0413895" {
+		t.Fatalf("InternalSerialNumber not correct: [%v]", value)
+	} else if mnt.ValueContext().IfdPath() != MakerNoteIfdPath {
+		t.Fatalf("IFD-path not correct: [%s]", mnt.ValueContext().IfdPath())
+	}
+
+	// Undefined-type values are read as bytes.
+	mnt, err = mn.FindTagWithName("CameraInfo")
+	log.PanicIf(err)
+
+	value, err = mnt.Value()
+	log.PanicIf(err)
+
+	if raw, ok := value.([]byte); ok == false || len(raw) != int(mnt.UnitCount) {
+		t.Fatalf("CameraInfo not read as bytes: %s", mnt)
+	}
+}
+
+func TestDecodeMakerNote_Nikon(t *testing.T) {
+	makerNoteIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: nikonSerialNumberTagId, Value: "3001234"},
+			{Id: 0x0084, Value: []exifcommon.Rational{{Numerator: 24, Denominator: 1}, {Numerator: 70, Denominator: 1}, {Numerator: 28, Denominator: 10}, {Numerator: 28, Denominator: 10}}},
+		},
+	}
+
+	tiff, err := exiftest.Build(makerNoteIfd, binary.LittleEndian)
+	log.PanicIf(err)
+
+	makerNote := append([]byte("Nikon\x00\x02\x10\x00\x00"), tiff...)
+
+	mn, err := DecodeMakerNote(getTestMakerNoteIndex("NIKON CORPORATION", makerNote, nil, nil))
+	log.PanicIf(err)
+
+	if mn.Vendor != makerNoteVendorNikon || mn.ByteOrder != binary.LittleEndian || len(mn.Tags) != 2 {
+		t.Fatalf("Maker-note not correct: %s", mn)
+	}
+
+	mnt, err := mn.FindTagWithName("SerialNumber")
+	log.PanicIf(err)
+
+	if value, err := mnt.Value(); err != nil || value != "3001234" {
+		t.Fatalf("SerialNumber not correct: [%v] %v", value, err)
+	}
+
+	mnt, err = mn.FindTagWithName("Lens")
+	log.PanicIf(err)
+
+	value, err := mnt.Value()
+	log.PanicIf(err)
+
+	expected := []exifcommon.Rational{{Numerator: 24, Denominator: 1}, {Numerator: 70, Denominator: 1}, {Numerator: 28, Denominator: 10}, {Numerator: 28, Denominator: 10}}
+	if reflect.DeepEqual(value, expected) != true {
+		t.Fatalf("Lens not correct: %v", value)
+	}
+}
+
+func TestDecodeMakerNote_Sony(t *testing.T) {
+	b := new(bytes.Buffer)
+	b.Write(sonyMakerNoteSignatures[0])
+
+	binary.Write(b, binary.BigEndian, uint16(1))
+	binary.Write(b, binary.BigEndian, []uint16{0xb027, uint16(exifcommon.TypeLong)})
+	binary.Write(b, binary.BigEndian, []uint32{1, 32790})
+	binary.Write(b, binary.BigEndian, uint32(0))
+
+	mn, err := DecodeMakerNote(getTestMakerNoteIndex("SONY", b.Bytes(), nil, nil))
+	log.PanicIf(err)
+
+	mnt, err := mn.FindTagWithName("LensType")
+	log.PanicIf(err)
+
+	if value, err := mnt.Value(); err != nil || reflect.DeepEqual(value, []uint32{32790}) != true {
+		t.Fatalf("LensType not correct: %v %v", value, err)
+	}
+}
+
+func TestDecodeMakerNote_NotSupported(t *testing.T) {
+	if _, err := DecodeMakerNote(getTestMakerNoteIndex("Acme", []byte("ACME\x00\x01\x02\x03"), nil, nil)); err != ErrNoMakerNote {
+		t.Fatalf("Expected no maker-note for an unknown Make: %v", err)
+	} else if _, err := DecodeMakerNote(getTestMakerNoteIndex("SONY", []byte("ACME\x00\x01\x02\x03"), nil, nil)); err != ErrNoMakerNote {
+		t.Fatalf("Expected no maker-note for an unrecognized format: %v", err)
+	} else if _, err := DecodeMakerNote(getTestMakerNoteIndex("Canon", nil, nil, nil)); err != ErrNoMakerNote {
+		t.Fatalf("Expected no maker-note when there isn't one: %v", err)
+	}
+}
+
+// testMakerNoteCodec reads the whole maker-note as one tag.
+type testMakerNoteCodec struct{}
+
+func (testMakerNoteCodec) Decode(mnd MakerNoteData) (mn *MakerNote, err error) {
+	if bytes.HasPrefix(mnd.Raw, []byte("ACME")) == false {
+		return nil, ErrNoMakerNote
+	}
+
+	mn = &MakerNote{
+		Vendor:    "Acme",
+		ByteOrder: mnd.ByteOrder,
+	}
+
+	return mn, nil
+}
+
+func TestRegisterMakerNoteCodec(t *testing.T) {
+	RegisterMakerNoteCodec("ACME", testMakerNoteCodec{})
+
+	defer func() {
+		makerNoteCodecs = makerNoteCodecs[1:]
+	}()
+
+	mn, err := DecodeMakerNote(getTestMakerNoteIndex("Acme Cameras", []byte("ACME\x00\x01\x02\x03"), nil, nil))
+	log.PanicIf(err)
+
+	if mn.Vendor != "Acme" {
+		t.Fatalf("Vendor not correct: [%s]", mn.Vendor)
+	}
+}
@@ -0,0 +1,371 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// MakerNoteChangeBlockAdded means that an IFD of the maker-note is only in
+	// the new file.
+	MakerNoteChangeBlockAdded = "block-added"
+
+	// MakerNoteChangeBlockRemoved means that an IFD of the maker-note is only
+	// in the old file.
+	MakerNoteChangeBlockRemoved = "block-removed"
+
+	// MakerNoteChangeTagAdded means that the tag is only in the new file.
+	MakerNoteChangeTagAdded = "tag-added"
+
+	// MakerNoteChangeTagRemoved means that the tag is only in the old file.
+	MakerNoteChangeTagRemoved = "tag-removed"
+
+	// MakerNoteChangeTagResized means that the type or unit-count of the tag
+	// changed.
+	MakerNoteChangeTagResized = "tag-resized"
+
+	// MakerNoteChangeTagMoved means that the offset of the tag's value
+	// changed.
+	MakerNoteChangeTagMoved = "tag-moved"
+)
+
+const (
+	// makerNoteDiffRootBlock is the block of the maker-note's own IFD.
+	makerNoteDiffRootBlock = "MakerNote"
+)
+
+var (
+	// ErrMakerNoteVendorMismatch means that the maker-notes that were
+	// compared aren't from the same vendor.
+	ErrMakerNoteVendorMismatch = errors.New("maker-note vendors don't match")
+)
+
+// MakerNoteChange is a structural difference between two maker-notes.
+type MakerNoteChange struct {
+	Kind string `json:"kind"`
+
+	// Block is the IFD of the maker-note that the change is in: "MakerNote"
+	// for its own IFD, and the parent's block followed by the tag and the
+	// index of the IFD for those nested in it (e.g.
+	// "MakerNote/0x0011[0]").
+	Block string `json:"block"`
+
+	// TagId and TagName are empty for changes to whole blocks.
+	TagId   uint16 `json:"tag_id,omitempty"`
+	TagName string `json:"tag_name,omitempty"`
+
+	// Before and After describe the type and unit-count of resized tags
+	// (e.g. "SHORT[4]"), the offsets of moved tags, and the number of tags in
+	// added and removed blocks. Before is empty for additions and After for
+	// removals.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// String returns a descriptive string.
+func (mnc MakerNoteChange) String() string {
+	return fmt.Sprintf("MakerNoteChange<KIND=[%s] BLOCK=[%s] TAG-ID=(0x%04x) TAG-NAME=[%s] BEFORE=[%s] AFTER=[%s]>", mnc.Kind, mnc.Block, mnc.TagId, mnc.TagName, mnc.Before, mnc.After)
+}
+
+// MakerNoteDiff is how the structure of the maker-note changed between two
+// files from the same camera, such as before and after a firmware update.
+type MakerNoteDiff struct {
+	Vendor string `json:"vendor"`
+
+	// OffsetBefore and OffsetAfter are where the maker-note is in the EXIF,
+	// and SizeBefore and SizeAfter are its sizes.
+	OffsetBefore uint32 `json:"offset_before"`
+	OffsetAfter  uint32 `json:"offset_after"`
+	SizeBefore   uint32 `json:"size_before"`
+	SizeAfter    uint32 `json:"size_after"`
+
+	Changes []MakerNoteChange `json:"changes"`
+}
+
+// IsEmpty returns true if the structure didn't change.
+func (mnd MakerNoteDiff) IsEmpty() bool {
+	return len(mnd.Changes) == 0
+}
+
+// String returns a descriptive string.
+func (mnd MakerNoteDiff) String() string {
+	return fmt.Sprintf("MakerNoteDiff<VENDOR=[%s] OFFSET-BEFORE=(0x%08x) OFFSET-AFTER=(0x%08x) SIZE-BEFORE=(%d) SIZE-AFTER=(%d) CHANGES=(%d)>", mnd.Vendor, mnd.OffsetBefore, mnd.OffsetAfter, mnd.SizeBefore, mnd.SizeAfter, len(mnd.Changes))
+}
+
+// makerNoteDiffTag is a tag of a maker-note block. Tags that occur more than
+// once in a block are told apart by their occurrence.
+type makerNoteDiffTag struct {
+	block      string
+	tagId      uint16
+	occurrence int
+}
+
+// makerNoteLayout is the structure of a decoded maker-note.
+type makerNoteLayout struct {
+	vendor string
+	offset uint32
+	size   uint32
+
+	// blocks has the number of tags in each block.
+	blocks map[string]int
+
+	tags  map[makerNoteDiffTag]*MakerNoteTag
+	order []makerNoteDiffTag
+}
+
+// readMakerNoteLayout decodes the maker-note of the file and flattens its
+// structure.
+func readMakerNoteLayout(data []byte) (mnl *makerNoteLayout, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == ErrNoExif {
+		return nil, ErrNoMakerNote
+	}
+
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	mn, err := DecodeMakerNote(index)
+	if err == ErrNoMakerNote {
+		return nil, err
+	}
+
+	log.PanicIf(err)
+
+	ite, err := getMakerNoteTag(index)
+	log.PanicIf(err)
+
+	mnl = &makerNoteLayout{
+		vendor: mn.Vendor,
+		offset: ite.getValueOffset(),
+		size:   ite.UnitCount(),
+		blocks: make(map[string]int),
+		tags:   make(map[makerNoteDiffTag]*MakerNoteTag),
+	}
+
+	mnl.add(makerNoteDiffRootBlock, mn)
+
+	return mnl, nil
+}
+
+// add adds the block and those nested in it.
+func (mnl *makerNoteLayout) add(block string, mn *MakerNote) {
+	mnl.blocks[block] = len(mn.Tags)
+
+	occurrences := make(map[uint16]int)
+
+	for _, mnt := range mn.Tags {
+		key := makerNoteDiffTag{
+			block:      block,
+			tagId:      mnt.TagId,
+			occurrence: occurrences[mnt.TagId],
+		}
+
+		occurrences[mnt.TagId]++
+
+		mnl.tags[key] = mnt
+		mnl.order = append(mnl.order, key)
+
+		for i, childMn := range mnt.Ifds {
+			childBlock := fmt.Sprintf("%s/0x%04x[%d]", block, mnt.TagId, i)
+			mnl.add(childBlock, childMn)
+		}
+	}
+}
+
+// describeMakerNoteTagSize returns the type and unit-count of the tag.
+func describeMakerNoteTagSize(mnt *MakerNoteTag) string {
+	return fmt.Sprintf("%s[%d]", mnt.TagType, mnt.UnitCount)
+}
+
+// makerNoteTagValueOffset returns the offset of the tag's value, and false if
+// the value is in the entry itself.
+func makerNoteTagValueOffset(mnt *MakerNoteTag) (offset uint32, found bool) {
+	size := mnt.TagType.Size() * int(mnt.UnitCount)
+	if size <= 4 {
+		return 0, false
+	}
+
+	return mnt.vc.ValueOffset(), true
+}
+
+// DiffMakerNotes returns how the structure of the maker-note changed between
+// two files from the same camera (any format with EXIF that
+// `SearchAndExtractExif()` finds): the IFDs nested in it that were added or
+// removed, and the tags that were added, removed, resized, or moved. It's
+// meant for tracking how a vendor's layout changes (e.g. with a firmware
+// update), so values aren't compared. Offsets are as they're stored, which is
+// relative to the EXIF for some vendors and to the maker-note for others.
+// Tags that only moved because the whole maker-note moved in the EXIF aren't
+// reported. `ErrNoMakerNote` is returned if either file doesn't have a
+// maker-note that a registered codec decodes, and
+// `ErrMakerNoteVendorMismatch` if they aren't from the same vendor.
+func DiffMakerNotes(before, after []byte) (mnd MakerNoteDiff, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	beforeMnl, err := readMakerNoteLayout(before)
+	if err == ErrNoMakerNote {
+		return mnd, err
+	}
+
+	log.PanicIf(err)
+
+	afterMnl, err := readMakerNoteLayout(after)
+	if err == ErrNoMakerNote {
+		return mnd, err
+	}
+
+	log.PanicIf(err)
+
+	if beforeMnl.vendor != afterMnl.vendor {
+		return mnd, ErrMakerNoteVendorMismatch
+	}
+
+	mnd = MakerNoteDiff{
+		Vendor:       beforeMnl.vendor,
+		OffsetBefore: beforeMnl.offset,
+		OffsetAfter:  afterMnl.offset,
+		SizeBefore:   beforeMnl.size,
+		SizeAfter:    afterMnl.size,
+		Changes:      make([]MakerNoteChange, 0),
+	}
+
+	for block, count := range beforeMnl.blocks {
+		if _, found := afterMnl.blocks[block]; found == false {
+			mnc := MakerNoteChange{
+				Kind:   MakerNoteChangeBlockRemoved,
+				Block:  block,
+				Before: fmt.Sprintf("%d", count),
+			}
+
+			mnd.Changes = append(mnd.Changes, mnc)
+		}
+	}
+
+	for block, count := range afterMnl.blocks {
+		if _, found := beforeMnl.blocks[block]; found == false {
+			mnc := MakerNoteChange{
+				Kind:  MakerNoteChangeBlockAdded,
+				Block: block,
+				After: fmt.Sprintf("%d", count),
+			}
+
+			mnd.Changes = append(mnd.Changes, mnc)
+		}
+	}
+
+	// The shift of the whole maker-note, which offsets relative to the EXIF
+	// move by.
+	shift := afterMnl.offset - beforeMnl.offset
+
+	for _, key := range beforeMnl.order {
+		beforeMnt := beforeMnl.tags[key]
+
+		mnc := MakerNoteChange{
+			Block:   key.block,
+			TagId:   key.tagId,
+			TagName: beforeMnt.TagName,
+		}
+
+		afterMnt, found := afterMnl.tags[key]
+		if found == false {
+			// The tags of removed blocks are reported with the block.
+			if _, found := afterMnl.blocks[key.block]; found == false {
+				continue
+			}
+
+			mnc.Kind = MakerNoteChangeTagRemoved
+			mnc.Before = describeMakerNoteTagSize(beforeMnt)
+		} else if beforeMnt.TagType != afterMnt.TagType || beforeMnt.UnitCount != afterMnt.UnitCount {
+			mnc.Kind = MakerNoteChangeTagResized
+			mnc.Before = describeMakerNoteTagSize(beforeMnt)
+			mnc.After = describeMakerNoteTagSize(afterMnt)
+		} else {
+			beforeOffset, isOffset := makerNoteTagValueOffset(beforeMnt)
+			if isOffset == false {
+				continue
+			}
+
+			afterOffset, _ := makerNoteTagValueOffset(afterMnt)
+			if afterOffset == beforeOffset || afterOffset-beforeOffset == shift {
+				continue
+			}
+
+			mnc.Kind = MakerNoteChangeTagMoved
+			mnc.Before = fmt.Sprintf("0x%08x", beforeOffset)
+			mnc.After = fmt.Sprintf("0x%08x", afterOffset)
+		}
+
+		mnd.Changes = append(mnd.Changes, mnc)
+	}
+
+	for _, key := range afterMnl.order {
+		if _, found := beforeMnl.tags[key]; found == true {
+			continue
+		} else if _, found := beforeMnl.blocks[key.block]; found == false {
+			continue
+		}
+
+		afterMnt := afterMnl.tags[key]
+
+		mnc := MakerNoteChange{
+			Kind:    MakerNoteChangeTagAdded,
+			Block:   key.block,
+			TagId:   key.tagId,
+			TagName: afterMnt.TagName,
+			After:   describeMakerNoteTagSize(afterMnt),
+		}
+
+		mnd.Changes = append(mnd.Changes, mnc)
+	}
+
+	sort.SliceStable(mnd.Changes, func(i, j int) bool {
+		if mnd.Changes[i].Block != mnd.Changes[j].Block {
+			return mnd.Changes[i].Block < mnd.Changes[j].Block
+		}
+
+		return mnd.Changes[i].TagId < mnd.Changes[j].TagId
+	})
+
+	return mnd, nil
+}
+
+// DiffMakerNoteFiles returns how the structure of the maker-note changed
+// between the two files (see `DiffMakerNotes()`).
+func DiffMakerNoteFiles(beforeFilepath, afterFilepath string) (mnd MakerNoteDiff, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	before, err := ioutil.ReadFile(beforeFilepath)
+	log.PanicIf(err)
+
+	after, err := ioutil.ReadFile(afterFilepath)
+	log.PanicIf(err)
+
+	mnd, err = DiffMakerNotes(before, after)
+	if err == ErrNoMakerNote || err == ErrMakerNoteVendorMismatch {
+		return mnd, err
+	}
+
+	log.PanicIf(err)
+
+	return mnd, nil
+}
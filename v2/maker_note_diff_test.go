@@ -0,0 +1,146 @@
+package exif
+
+import (
+	"reflect"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+const (
+	testNikonPreviewIfdTagId = 0x0011
+)
+
+// getTestNikonMakerNoteExif returns EXIF for the model with a Nikon
+// maker-note that has the given tags and, if `previewTags` isn't nil, a
+// nested IFD with them.
+func getTestNikonMakerNoteExif(model string, tags, previewTags []exiftest.Tag) []byte {
+	makerNoteIfd := &exiftest.Ifd{
+		Tags:      tags,
+		KeepOrder: true,
+	}
+
+	if previewTags != nil {
+		child := exiftest.Child{
+			TagId: testNikonPreviewIfdTagId,
+			Ifd:   &exiftest.Ifd{Tags: previewTags},
+		}
+
+		makerNoteIfd.Children = append(makerNoteIfd.Children, child)
+	}
+
+	tiff, err := exiftest.Build(makerNoteIfd, binary.LittleEndian)
+	log.PanicIf(err)
+
+	// The nested IFD is pointed to by an IFD-type entry, which is the last.
+	if previewTags != nil {
+		entry := 8 + 2 + len(tags)*12
+		binary.LittleEndian.PutUint16(tiff[entry+2:], uint16(subIfdTagTypeIfd))
+	}
+
+	makerNote := append([]byte("Nikon\x00\x02\x10\x00\x00"), tiff...)
+
+	root := exiftest.NewRealisticIfd()
+	root.Tags[0].Value = "NIKON CORPORATION"
+	root.Tags[1].Value = model
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, exiftest.Tag{Id: 0x927c, Raw: makerNote, Type: exifcommon.TypeUndefined})
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return exifData
+}
+
+func TestDiffMakerNotes(t *testing.T) {
+	before := getTestNikonMakerNoteExif(
+		"NIKON D850",
+		[]exiftest.Tag{
+			{Id: 0x0084, Value: []exifcommon.Rational{{Numerator: 24, Denominator: 1}, {Numerator: 70, Denominator: 1}}},
+			{Id: nikonSerialNumberTagId, Value: "3001234"},
+			{Id: 0x0002, Value: []uint16{0, 100}},
+		},
+		[]exiftest.Tag{
+			{Id: 0x0201, Value: []uint32{1000}},
+		})
+
+	after := getTestNikonMakerNoteExif(
+		"NIKON D850",
+		[]exiftest.Tag{
+			{Id: 0x0084, Value: []exifcommon.Rational{{Numerator: 24, Denominator: 1}, {Numerator: 70, Denominator: 1}, {Numerator: 28, Denominator: 10}}},
+			{Id: nikonSerialNumberTagId, Value: "3001234"},
+			{Id: 0x0004, Value: "FINE"},
+		},
+		nil)
+
+	mnd, err := DiffMakerNotes(before, after)
+	log.PanicIf(err)
+
+	if mnd.Vendor != makerNoteVendorNikon {
+		t.Fatalf("Vendor not correct: [%s]", mnd.Vendor)
+	}
+
+	// The serial number moved since the IFD lost an entry and the value before
+	// it grew.
+	expected := []MakerNoteChange{
+		{Kind: MakerNoteChangeTagRemoved, Block: "MakerNote", TagId: 0x0002, TagName: "ISO", Before: "SHORT[2]"},
+		{Kind: MakerNoteChangeTagAdded, Block: "MakerNote", TagId: 0x0004, TagName: "Quality", After: "ASCII[5]"},
+		{Kind: MakerNoteChangeTagRemoved, Block: "MakerNote", TagId: 0x0011, Before: "LONG[1]"},
+		{Kind: MakerNoteChangeTagMoved, Block: "MakerNote", TagId: nikonSerialNumberTagId, TagName: "SerialNumber", Before: "0x0000004e", After: "0x0000004a"},
+		{Kind: MakerNoteChangeTagResized, Block: "MakerNote", TagId: 0x0084, TagName: "Lens", Before: "RATIONAL[2]", After: "RATIONAL[3]"},
+		{Kind: MakerNoteChangeBlockRemoved, Block: "MakerNote/0x0011[0]", Before: "1"},
+	}
+
+	if reflect.DeepEqual(mnd.Changes, expected) != true {
+		for _, mnc := range mnd.Changes {
+			t.Logf("%s", mnc)
+		}
+
+		t.Fatalf("Changes not correct.")
+	}
+}
+
+func TestDiffMakerNotes_Unchanged(t *testing.T) {
+	tags := []exiftest.Tag{
+		{Id: nikonSerialNumberTagId, Value: "3001234"},
+	}
+
+	previewTags := []exiftest.Tag{
+		{Id: 0x0201, Value: []uint32{1000}},
+	}
+
+	before := getTestNikonMakerNoteExif("NIKON D850", tags, previewTags)
+
+	// A longer model moves the whole maker-note.
+	after := getTestNikonMakerNoteExif("NIKON D850 with a much longer name", tags, previewTags)
+
+	mnd, err := DiffMakerNotes(before, after)
+	log.PanicIf(err)
+
+	if mnd.OffsetAfter == mnd.OffsetBefore {
+		t.Fatalf("Maker-note should have moved: %s", mnd)
+	} else if mnd.IsEmpty() != true {
+		t.Fatalf("Expected no changes: %v", mnd.Changes)
+	}
+}
+
+func TestDiffMakerNotes_VendorMismatch(t *testing.T) {
+	nikon := getTestNikonMakerNoteExif("NIKON D850", []exiftest.Tag{{Id: nikonSerialNumberTagId, Value: "3001234"}}, nil)
+
+	if _, err := DiffMakerNotes(getTestExifData(), nikon); err != ErrMakerNoteVendorMismatch {
+		t.Fatalf("Expected ErrMakerNoteVendorMismatch: %v", err)
+	}
+
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	if _, err := DiffMakerNotes(rawExif, nikon); err != ErrNoMakerNote {
+		t.Fatalf("Expected ErrNoMakerNote: %v", err)
+	}
+}
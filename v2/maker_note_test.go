@@ -0,0 +1,128 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestMakerNoteIndex returns the index for a realistic IFD with the given
+// Make, maker-note, and extra tags.
+func getTestMakerNoteIndex(make_ string, makerNote []byte, rootTags, exifTags []exiftest.Tag) IfdIndex {
+	root := exiftest.NewRealisticIfd()
+	root.Tags[0].Value = make_
+	root.Tags = append(root.Tags, rootTags...)
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, exifTags...)
+
+	if makerNote != nil {
+		makerNoteTag := exiftest.Tag{
+			Id:   0x927c,
+			Raw:  makerNote,
+			Type: exifcommon.TypeUndefined,
+		}
+
+		exifIfd.Tags = append(exifIfd.Tags, makerNoteTag)
+	}
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestParseAppleMakerNote(t *testing.T) {
+	amn := parseAppleMakerNote(getTestAppleMakerNote())
+	if amn == nil {
+		t.Fatalf("Maker-note not parsed.")
+	}
+
+	if hdrImageType, found := amn.Int(appleHdrImageTypeTagId); found != true || hdrImageType != 3 {
+		t.Fatalf("Int value not correct: (%d) %v", hdrImageType, found)
+	} else if headroom, found := amn.Float(appleHdrHeadroomTagId); found != true || headroom != 1.7 {
+		t.Fatalf("Float value not correct: (%f) %v", headroom, found)
+	} else if _, found := amn.String(appleHdrImageTypeTagId); found != false {
+		t.Fatalf("Expected String to reject a non-ASCII tag.")
+	}
+}
+
+func TestParseAppleMakerNote_NotApple(t *testing.T) {
+	if amn := parseAppleMakerNote([]byte("Nikon\x00\x02\x10\x00\x00")); amn != nil {
+		t.Fatalf("Expected nil for a non-Apple maker-note.")
+	}
+}
+
+func TestParseAppleMakerNote_OutOfBounds(t *testing.T) {
+	raw := getTestLivePhotoMakerNote()
+	raw = raw[:len(raw)-10]
+
+	amn := parseAppleMakerNote(raw)
+
+	if _, found := amn.String(appleContentIdentifierTagId); found != true {
+		t.Fatalf("Expected in-bounds value to be read.")
+	} else if _, found := amn.String(appleBurstUuidTagId); found != false {
+		t.Fatalf("Expected out-of-bounds value to be skipped.")
+	}
+}
+
+func TestGetVendorMakerNote(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	vendor, mni, err := getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	if vendor != makerNoteVendorCanon {
+		t.Fatalf("Vendor not correct: [%s]", vendor)
+	} else if _, found := mni.Uint16s(canonColorDataTagId); found != true {
+		t.Fatalf("Expected Canon ColorData.")
+	}
+}
+
+func TestGetVendorMakerNote_Apple(t *testing.T) {
+	vendor, mni, err := getVendorMakerNote(getTestMakerNoteIndex("Apple", getTestAppleMakerNote(), nil, nil))
+	log.PanicIf(err)
+
+	if vendor != makerNoteVendorApple || mni == nil {
+		t.Fatalf("Vendor not correct: [%s]", vendor)
+	}
+}
+
+func TestGetVendorMakerNote_Unknown(t *testing.T) {
+	vendor, mni, err := getVendorMakerNote(getTestMakerNoteIndex("Acme", []byte("ACME\x00\x01\x02\x03"), nil, nil))
+	log.PanicIf(err)
+
+	if vendor != "" || mni != nil {
+		t.Fatalf("Expected no vendor: [%s]", vendor)
+	}
+}
+
+func TestParsePentaxMakerNote(t *testing.T) {
+	b := new(bytes.Buffer)
+	b.Write(pentaxMakerNoteSignature)
+	b.WriteString("MM")
+
+	binary.Write(b, binary.BigEndian, uint16(1))
+	binary.Write(b, binary.BigEndian, []uint16{0x0001, uint16(exifcommon.TypeShort)})
+	binary.Write(b, binary.BigEndian, []uint32{1, 0x00020000})
+	binary.Write(b, binary.BigEndian, uint32(0))
+
+	vendor, mni, err := getVendorMakerNote(getTestMakerNoteIndex("RICOH IMAGING COMPANY, LTD.", b.Bytes(), nil, nil))
+	log.PanicIf(err)
+
+	if vendor != makerNoteVendorPentax {
+		t.Fatalf("Vendor not correct: [%s]", vendor)
+	} else if value, found := mni.Int(0x0001); found != true || value != 2 {
+		t.Fatalf("Value not correct: (%d)", value)
+	}
+}
@@ -0,0 +1,248 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	manifestLogger = log.NewLogger("exif.manifest")
+)
+
+// ManifestEntry records that a source was processed, and what it was when it
+// was.
+type ManifestEntry struct {
+	Name string `json:"name"`
+
+	// Size and ModTime (in nanoseconds since the epoch) are from the source's
+	// `Stat`, if it has one.
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"`
+
+	// Sha256 is the hex-encoded SHA-256 of the data, if the manifest compares
+	// content.
+	Sha256 string `json:"sha256,omitempty"`
+
+	// NoExif is true if the source didn't have any EXIF.
+	NoExif bool `json:"no_exif,omitempty"`
+}
+
+// String returns a descriptive string.
+func (me ManifestEntry) String() string {
+	return fmt.Sprintf("ManifestEntry<NAME=[%s] SIZE=(%d) MTIME=(%d) SHA256=[%s] NO-EXIF=[%v]>", me.Name, me.Size, me.ModTime, me.Sha256, me.NoExif)
+}
+
+// matches returns true if the entry is of the same data as the other.
+func (me ManifestEntry) matches(other ManifestEntry) bool {
+	return me.Name == other.Name && me.Size == other.Size && me.ModTime == other.ModTime && me.Sha256 == other.Sha256
+}
+
+// BatchManifest is a checkpoint for a large batch run, so that a run that's
+// interrupted can be resumed without processing the same files again. Each
+// source is recorded as soon as it's processed, as a JSON line appended to
+// the manifest file. Sources are identified by their name, size, and
+// modification time or, if `compareContent` is true, by their name and the
+// SHA-256 of their data (which is slower since it reads all of them, but
+// doesn't trust timestamps).
+//
+// Sources that failed (other than for not having EXIF) aren't recorded, so
+// that they're tried again when the run is resumed.
+type BatchManifest struct {
+	f              *os.File
+	compareContent bool
+
+	m          sync.Mutex
+	entries    map[string]ManifestEntry
+	identities map[string]ManifestEntry
+	encoder    *json.Encoder
+}
+
+// OpenBatchManifest opens the manifest at the given path, loading what was
+// recorded by earlier runs, or creates it.
+func OpenBatchManifest(filepath string, compareContent bool) (bm *BatchManifest, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	f, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE, 0644)
+	log.PanicIf(err)
+
+	bm = &BatchManifest{
+		f:              f,
+		compareContent: compareContent,
+		entries:        make(map[string]ManifestEntry),
+		identities:     make(map[string]ManifestEntry),
+	}
+
+	// If the last run was interrupted while writing, the last line might be
+	// incomplete. Only complete lines count, and the rest is overwritten.
+
+	br := bufio.NewReader(f)
+	var offset int64
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+
+		log.PanicIf(err)
+
+		var me ManifestEntry
+
+		if err := json.Unmarshal(line, &me); err != nil {
+			manifestLogger.Warningf(nil, "Ignoring invalid line in manifest [%s] at offset (%d): %s", filepath, offset, err)
+			break
+		}
+
+		bm.entries[me.Name] = me
+		offset += int64(len(line))
+	}
+
+	err = f.Truncate(offset)
+	log.PanicIf(err)
+
+	_, err = f.Seek(offset, io.SeekStart)
+	log.PanicIf(err)
+
+	bm.encoder = json.NewEncoder(f)
+
+	return bm, nil
+}
+
+// Count returns the number of sources that have been recorded.
+func (bm *BatchManifest) Count() int {
+	bm.m.Lock()
+	defer bm.m.Unlock()
+
+	return len(bm.entries)
+}
+
+// identify returns the entry that identifies the source as it is now.
+func (bm *BatchManifest) identify(source ParseSource) (me ManifestEntry, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	me = ManifestEntry{
+		Name: source.Name,
+	}
+
+	if source.Stat != nil {
+		size, modTime, err := source.Stat()
+		log.PanicIf(err)
+
+		me.Size = size
+
+		if modTime.IsZero() == false {
+			me.ModTime = modTime.UnixNano()
+		}
+	}
+
+	if bm.compareContent == true {
+		rc, err := source.Open()
+		log.PanicIf(err)
+
+		defer rc.Close()
+
+		h := sha256.New()
+
+		_, err = io.Copy(h, rc)
+		log.PanicIf(err)
+
+		me.Sha256 = hex.EncodeToString(h.Sum(nil))
+
+		// The content is what identifies it.
+		me.ModTime = 0
+	}
+
+	return me, nil
+}
+
+// Pending returns the sources that haven't been processed, or that have
+// changed since they were. Sources that can't be identified (e.g. that can't
+// be stat'd) are always pending, so that their errors are reported when
+// they're processed.
+func (bm *BatchManifest) Pending(sources []ParseSource) (pending []ParseSource, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	pending = make([]ParseSource, 0)
+
+	bm.m.Lock()
+	defer bm.m.Unlock()
+
+	for _, source := range sources {
+		me, err := bm.identify(source)
+		if err != nil {
+			pending = append(pending, source)
+			continue
+		}
+
+		bm.identities[source.Name] = me
+
+		if recorded, found := bm.entries[source.Name]; found == true && recorded.matches(me) == true {
+			continue
+		}
+
+		pending = append(pending, source)
+	}
+
+	return pending, nil
+}
+
+// Record records the result of processing a source that was returned by
+// `Pending()`. It can be given as `ParseManyOptions.OnResult`, or called from
+// it after the result has been written somewhere.
+func (bm *BatchManifest) Record(pr ParseResult) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if pr.Err != nil && pr.Err != ErrNoExif {
+		return nil
+	}
+
+	bm.m.Lock()
+	defer bm.m.Unlock()
+
+	me, found := bm.identities[pr.Name]
+	if found == false {
+		return nil
+	}
+
+	me.NoExif = pr.Err == ErrNoExif
+
+	err = bm.encoder.Encode(me)
+	log.PanicIf(err)
+
+	bm.entries[me.Name] = me
+
+	return nil
+}
+
+// Close closes the manifest.
+func (bm *BatchManifest) Close() (err error) {
+	return bm.f.Close()
+}
@@ -0,0 +1,162 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestManifestFiles(tempPath string) (sources []ParseSource) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	files := map[string][]byte{
+		"a.jpg": exiftest.WrapJpeg(rawExif),
+		"b.jpg": exiftest.WrapJpeg(rawExif),
+		"c.txt": []byte("not an image"),
+	}
+
+	for _, name := range []string{"a.jpg", "b.jpg", "c.txt"} {
+		filepath := path.Join(tempPath, name)
+
+		err := ioutil.WriteFile(filepath, files[name], 0644)
+		log.PanicIf(err)
+
+		sources = append(sources, FileSource(filepath))
+	}
+
+	return sources
+}
+
+func runTestManifest(manifestFilepath string, compareContent bool, sources []ParseSource) (processed int) {
+	bm, err := OpenBatchManifest(manifestFilepath, compareContent)
+	log.PanicIf(err)
+
+	defer bm.Close()
+
+	pending, err := bm.Pending(sources)
+	log.PanicIf(err)
+
+	_, err = ParseMany(context.Background(), pending, ParseManyOptions{OnResult: bm.Record})
+	log.PanicIf(err)
+
+	return len(pending)
+}
+
+func TestBatchManifest(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	sources := getTestManifestFiles(tempPath)
+	manifestFilepath := path.Join(tempPath, "manifest.jsonl")
+
+	if processed := runTestManifest(manifestFilepath, false, sources); processed != 3 {
+		t.Fatalf("First run didn't process everything: (%d)", processed)
+	} else if processed := runTestManifest(manifestFilepath, false, sources); processed != 0 {
+		t.Fatalf("Second run processed files again: (%d)", processed)
+	}
+
+	// A changed file and a missing file are processed again.
+
+	err = ioutil.WriteFile(sources[1].Name, []byte("changed"), 0644)
+	log.PanicIf(err)
+
+	sources = append(sources, FileSource(path.Join(tempPath, "missing.jpg")))
+
+	bm, err := OpenBatchManifest(manifestFilepath, false)
+	log.PanicIf(err)
+
+	defer bm.Close()
+
+	pending, err := bm.Pending(sources)
+	log.PanicIf(err)
+
+	if bm.Count() != 3 {
+		t.Fatalf("Recorded count not correct: (%d)", bm.Count())
+	} else if len(pending) != 2 || pending[0].Name != sources[1].Name || pending[1].Name != sources[3].Name {
+		t.Fatalf("Pending not correct: %v", pending)
+	}
+}
+
+func TestBatchManifest_Failed(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	manifestFilepath := path.Join(tempPath, "manifest.jsonl")
+
+	sources := []ParseSource{
+		BytesSource("broken.jpg", []byte{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x20, 'E', 'x', 'i', 'f', 0x00, 0x00, 'M', 'M', 0x00, 0x2a, 0xff, 0xff, 0xff, 0xff}),
+	}
+
+	// Sources that fail are tried again.
+
+	bm, err := OpenBatchManifest(manifestFilepath, false)
+	log.PanicIf(err)
+
+	defer bm.Close()
+
+	pending, err := bm.Pending(sources)
+	log.PanicIf(err)
+
+	results, err := ParseMany(context.Background(), pending, ParseManyOptions{OnResult: bm.Record})
+	log.PanicIf(err)
+
+	if results[0].Err == nil || results[0].Err == ErrNoExif {
+		t.Fatalf("Expected parse error: %v", results[0].Err)
+	} else if bm.Count() != 0 {
+		t.Fatalf("Failed source was recorded.")
+	}
+}
+
+func TestBatchManifest_Interrupted(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	sources := getTestManifestFiles(tempPath)
+	manifestFilepath := path.Join(tempPath, "manifest.jsonl")
+
+	runTestManifest(manifestFilepath, true, sources)
+
+	// Cut the last line short, as if the run was killed while writing it.
+
+	data, err := ioutil.ReadFile(manifestFilepath)
+	log.PanicIf(err)
+
+	err = ioutil.WriteFile(manifestFilepath, data[:len(data)-5], 0644)
+	log.PanicIf(err)
+
+	if processed := runTestManifest(manifestFilepath, true, sources); processed != 1 {
+		t.Fatalf("Expected the interrupted source to be processed: (%d)", processed)
+	} else if processed := runTestManifest(manifestFilepath, true, sources); processed != 0 {
+		t.Fatalf("Third run processed files again: (%d)", processed)
+	}
+
+	// Content is compared, so touching a file doesn't matter.
+
+	touchedTime := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err = os.Chtimes(sources[0].Name, touchedTime, touchedTime)
+	log.PanicIf(err)
+
+	if processed := runTestManifest(manifestFilepath, true, sources); processed != 0 {
+		t.Fatalf("Touched file processed again: (%d)", processed)
+	}
+}
@@ -0,0 +1,308 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// ErrMediaFormatNotFound means that no registered format recognized the
+	// data.
+	ErrMediaFormatNotFound = errors.New("media format not found")
+)
+
+// MediaFormat reads and writes the EXIF of one kind of container. Formats are
+// registered with `RegisterMediaFormat()`, which lets `Open()` and
+// `ExtractExif()` handle containers that this package doesn't know about.
+type MediaFormat interface {
+	// Kind returns the kind of container.
+	Kind() Kind
+
+	// Sniff returns true if the data, which may be just the start of the
+	// file, is this kind of container.
+	Sniff(head []byte) bool
+
+	// ExtractExif returns the EXIF block (starting with the TIFF header).
+	// `ErrNoExif` is returned if there isn't one.
+	ExtractExif(data []byte) (rawExif []byte, err error)
+
+	// ReplaceExif returns a copy of the data with the given EXIF block in
+	// place of the existing one, or added if there isn't one. A
+	// `CapabilityError` is returned if the container, or this file, can't
+	// take it.
+	ReplaceExif(data []byte, rawExif []byte) (updated []byte, err error)
+}
+
+var (
+	mediaFormats      = make([]MediaFormat, 0)
+	mediaFormatsMutex sync.RWMutex
+)
+
+// RegisterMediaFormat adds a format. Formats registered later are sniffed
+// first, so the built-in JPEG, TIFF, PNG, WebP, HEIF, and CR3 formats can be
+// replaced.
+func RegisterMediaFormat(mf MediaFormat) {
+	mediaFormatsMutex.Lock()
+	defer mediaFormatsMutex.Unlock()
+
+	mediaFormats = append([]MediaFormat{mf}, mediaFormats...)
+}
+
+// GetMediaFormat returns the format registered for the kind.
+// `ErrMediaFormatNotFound` is returned if there isn't one.
+func GetMediaFormat(kind Kind) (mf MediaFormat, err error) {
+	mediaFormatsMutex.RLock()
+	defer mediaFormatsMutex.RUnlock()
+
+	for _, mf := range mediaFormats {
+		if mf.Kind() == kind {
+			return mf, nil
+		}
+	}
+
+	return nil, ErrMediaFormatNotFound
+}
+
+// SniffMediaFormat returns the first registered format that recognizes the
+// data. `ErrMediaFormatNotFound` is returned if none do.
+func SniffMediaFormat(head []byte) (mf MediaFormat, err error) {
+	mediaFormatsMutex.RLock()
+	defer mediaFormatsMutex.RUnlock()
+
+	for _, mf := range mediaFormats {
+		if mf.Sniff(head) == true {
+			return mf, nil
+		}
+	}
+
+	return nil, ErrMediaFormatNotFound
+}
+
+// MediaFile is a file that was read by `Open()`, with the format that
+// recognized it.
+type MediaFile struct {
+	Filepath string
+	Format   MediaFormat
+	Data     []byte
+}
+
+// Open reads the file and finds its format. `ErrMediaFormatNotFound` is
+// returned if no registered format recognizes it.
+func Open(filepath string) (mf *MediaFile, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	format, err := SniffMediaFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mf = &MediaFile{
+		Filepath: filepath,
+		Format:   format,
+		Data:     data,
+	}
+
+	return mf, nil
+}
+
+// Exif returns the EXIF block of the file. `ErrNoExif` is returned if there
+// isn't one.
+func (mf *MediaFile) Exif() (rawExif []byte, err error) {
+	return mf.Format.ExtractExif(mf.Data)
+}
+
+// ReplaceExif puts the EXIF block in the data of the file. Nothing is written
+// until `Save()` is called.
+func (mf *MediaFile) ReplaceExif(rawExif []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	updated, err := mf.Format.ReplaceExif(mf.Data, rawExif)
+	log.PanicIf(err)
+
+	mf.Data = updated
+
+	return nil
+}
+
+// Save writes the data back to the file with `RewriteFile()`.
+func (mf *MediaFile) Save() (err error) {
+	return RewriteFile(mf.Filepath, mf.Data, RewriteOptions{})
+}
+
+// jpegMediaFormat has the EXIF in an APP1 segment.
+type jpegMediaFormat struct{}
+
+// Kind returns `KindJpeg`.
+func (jpegMediaFormat) Kind() Kind {
+	return KindJpeg
+}
+
+// Sniff looks for the SOI marker.
+func (jpegMediaFormat) Sniff(head []byte) bool {
+	return len(head) >= 2 && head[0] == jpegMarkerPrefix && head[1] == jpegMarkerSoi
+}
+
+// ExtractExif returns the EXIF (see `GetJpegExif()`).
+func (jpegMediaFormat) ExtractExif(data []byte) (rawExif []byte, err error) {
+	return GetJpegExif(data)
+}
+
+// ReplaceExif replaces or inserts the EXIF segment (see `SetJpegExif()`).
+func (jpegMediaFormat) ReplaceExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	return SetJpegExif(data, rawExif)
+}
+
+// tiffMediaFormat is anything that starts with a TIFF header.
+type tiffMediaFormat struct{}
+
+// Kind returns `KindTiff`.
+func (tiffMediaFormat) Kind() Kind {
+	return KindTiff
+}
+
+// Sniff looks for a TIFF header.
+func (tiffMediaFormat) Sniff(head []byte) bool {
+	_, err := ParseExifHeader(head)
+	return err == nil
+}
+
+// ExtractExif returns the data, which is the EXIF block.
+func (tiffMediaFormat) ExtractExif(data []byte) (rawExif []byte, err error) {
+	return data, nil
+}
+
+// ReplaceExif returns a `CapabilityError`. The EXIF is the file itself, so
+// replacing it would lose the image data that its IFDs point to (see
+// `ExifPatcher` instead).
+func (tiffMediaFormat) ReplaceExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	ce := &CapabilityError{
+		Kind:      KindTiff,
+		Operation: CapabilityReplaceExif,
+		Available: tiffMediaFormat{}.Capabilities(data),
+		Reason:    "the EXIF is the file itself, so its tags have to be updated instead",
+	}
+
+	return nil, ce
+}
+
+// Capabilities returns everything but replacing the EXIF.
+func (tiffMediaFormat) Capabilities(data []byte) Capability {
+	return CapabilityReadExif | CapabilityUpdateTags
+}
+
+// pngMediaFormat has the EXIF in an eXIf chunk.
+type pngMediaFormat struct{}
+
+// Kind returns `KindPng`.
+func (pngMediaFormat) Kind() Kind {
+	return KindPng
+}
+
+// Sniff looks for the PNG signature.
+func (pngMediaFormat) Sniff(head []byte) bool {
+	return bytes.HasPrefix(head, pngSignature) == true
+}
+
+// ExtractExif returns the EXIF (see `GetPngExif()`).
+func (pngMediaFormat) ExtractExif(data []byte) (rawExif []byte, err error) {
+	return GetPngExif(data)
+}
+
+// ReplaceExif replaces or inserts the eXIf chunk (see `SetPngExif()`).
+func (pngMediaFormat) ReplaceExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	return SetPngExif(data, rawExif)
+}
+
+// webpMediaFormat has the EXIF in an EXIF chunk.
+type webpMediaFormat struct{}
+
+// Kind returns `KindWebp`.
+func (webpMediaFormat) Kind() Kind {
+	return KindWebp
+}
+
+// Sniff looks for a RIFF header with the WEBP form type.
+func (webpMediaFormat) Sniff(head []byte) bool {
+	return len(head) >= 12 && bytes.Equal(head[:4], riffSignature) == true && string(head[8:12]) == "WEBP"
+}
+
+// ExtractExif returns the EXIF (see `GetWebpExif()`).
+func (webpMediaFormat) ExtractExif(data []byte) (rawExif []byte, err error) {
+	return GetWebpExif(data)
+}
+
+// ReplaceExif replaces or adds the EXIF chunk (see `SetWebpExif()`).
+func (webpMediaFormat) ReplaceExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	return SetWebpExif(data, rawExif)
+}
+
+// Capabilities returns everything unless the file is a simple WebP whose image
+// data isn't recognized, which can only be read.
+func (webpMediaFormat) Capabilities(data []byte) Capability {
+	if len(data) < 12 {
+		return CapabilityReadExif
+	}
+
+	chunks, err := parseRiffChunks(data[12:])
+	if err != nil || len(chunks) == 0 {
+		return CapabilityReadExif
+	} else if chunks[0].id == "VP8X" {
+		return capabilityAll
+	}
+
+	if _, err := getWebpVp8x(chunks[0]); err != nil {
+		return CapabilityReadExif
+	}
+
+	return capabilityAll
+}
+
+// heifMediaFormat has the EXIF in an "Exif" item.
+type heifMediaFormat struct{}
+
+// Kind returns `KindHeif`.
+func (heifMediaFormat) Kind() Kind {
+	return KindHeif
+}
+
+// Sniff looks for a HEIF brand in the "ftyp" box.
+func (heifMediaFormat) Sniff(head []byte) bool {
+	return isHeif(head)
+}
+
+// ExtractExif returns the EXIF (see `GetHeifExif()`).
+func (heifMediaFormat) ExtractExif(data []byte) (rawExif []byte, err error) {
+	return GetHeifExif(data)
+}
+
+// ReplaceExif replaces or adds the "Exif" item (see `SetHeifExif()`).
+func (heifMediaFormat) ReplaceExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	return SetHeifExif(data, rawExif)
+}
+
+func init() {
+	// The TIFF format is sniffed last since a TIFF header is the least
+	// distinctive signature.
+	RegisterMediaFormat(tiffMediaFormat{})
+	RegisterMediaFormat(cr3MediaFormat{})
+	RegisterMediaFormat(heifMediaFormat{})
+	RegisterMediaFormat(webpMediaFormat{})
+	RegisterMediaFormat(pngMediaFormat{})
+	RegisterMediaFormat(jpegMediaFormat{})
+}
@@ -0,0 +1,232 @@
+package exif
+
+import (
+	"bytes"
+	"path"
+	"testing"
+
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// testMediaFormat is a container that's just a signature followed by the
+// EXIF.
+type testMediaFormat struct{}
+
+var (
+	testMediaFormatSignature = []byte("TESTEXIF")
+)
+
+func (testMediaFormat) Kind() Kind {
+	return Kind("test")
+}
+
+func (testMediaFormat) Sniff(head []byte) bool {
+	return bytes.HasPrefix(head, testMediaFormatSignature)
+}
+
+func (testMediaFormat) ExtractExif(data []byte) (rawExif []byte, err error) {
+	return data[len(testMediaFormatSignature):], nil
+}
+
+func (testMediaFormat) ReplaceExif(data []byte, rawExif []byte) (updated []byte, err error) {
+	return append(append([]byte{}, testMediaFormatSignature...), rawExif...), nil
+}
+
+// getTestReplacementExif returns an EXIF block that's smaller than the one
+// from `getTestExifData()`.
+func getTestReplacementExif() []byte {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	return rawExif
+}
+
+func TestSniffMediaFormat(t *testing.T) {
+	exifData := getTestExifData()
+
+	cases := []struct {
+		data []byte
+		kind Kind
+	}{
+		{exiftest.WrapJpeg(exifData), KindJpeg},
+		{exifData, KindTiff},
+		{exiftest.WrapPng(exifData), KindPng},
+		{getTestWebp(webpExifFlag, exifData), KindWebp},
+		{getTestHeifWithExif(exifData), KindHeif},
+	}
+
+	for _, c := range cases {
+		mf, err := SniffMediaFormat(c.data)
+		log.PanicIf(err)
+
+		if mf.Kind() != c.kind {
+			t.Fatalf("Kind not correct: [%s] != [%s]", mf.Kind(), c.kind)
+		}
+	}
+
+	if _, err := SniffMediaFormat([]byte("not media")); err != ErrMediaFormatNotFound {
+		t.Fatalf("Expected ErrMediaFormatNotFound: %v", err)
+	}
+}
+
+func TestMediaFormat_ReplaceExif(t *testing.T) {
+	exifData := getTestExifData()
+	replacement := getTestReplacementExif()
+
+	pngWithoutExif := append([]byte{}, pngSignature...)
+	pngWithoutExif = appendPngChunk(pngWithoutExif, "IHDR", make([]byte, 13))
+	pngWithoutExif = appendPngChunk(pngWithoutExif, "IDAT", []byte{1, 2, 3})
+	pngWithoutExif = appendPngChunk(pngWithoutExif, "IEND", nil)
+
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"jpeg", exiftest.WrapJpeg(exifData)},
+		{"png", exiftest.WrapPng(exifData)},
+		{"png without exif", pngWithoutExif},
+		{"webp", getTestWebp(webpExifFlag, exifData)},
+		{"webp without exif", getTestWebp(0, nil)},
+		{"heif", getTestHeifWithExif(exifData)},
+		{"heif without exif", getTestHeifWithItemType("mime")},
+	}
+
+	for _, c := range cases {
+		mf, err := SniffMediaFormat(c.data)
+		log.PanicIf(err)
+
+		updated, err := mf.ReplaceExif(c.data, replacement)
+		log.PanicIf(err)
+
+		rawExif, kind, err := ExtractExif(updated)
+		log.PanicIf(err)
+
+		if kind != mf.Kind() {
+			t.Fatalf("Kind of %s not correct: [%s]", c.name, kind)
+		} else if bytes.HasPrefix(rawExif, replacement) != true {
+			t.Fatalf("EXIF of %s not replaced.", c.name)
+		}
+
+		_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+		log.PanicIf(err)
+
+		if make_, err := getIfdTagString(index.RootIfd, "Make"); err != nil || make_ != "Canon" {
+			t.Fatalf("Make of %s not correct: [%s] %v", c.name, make_, err)
+		}
+	}
+}
+
+func TestMediaFormat_ReplaceExif_NotWritable(t *testing.T) {
+	exifData := getTestExifData()
+
+	// A simple WebP whose image data isn't recognized.
+	body := new(bytes.Buffer)
+	body.WriteString("WEBP")
+	writeRiffChunk(body, "VP8 ", make([]byte, 100))
+
+	simpleWebp := new(bytes.Buffer)
+	writeRiffChunk(simpleWebp, "RIFF", body.Bytes())
+
+	cases := []struct {
+		name      string
+		data      []byte
+		available Capability
+	}{
+		{"tiff", exifData, CapabilityReadExif | CapabilityUpdateTags},
+		{"simple webp", simpleWebp.Bytes(), CapabilityReadExif},
+	}
+
+	for _, c := range cases {
+		mf, err := SniffMediaFormat(c.data)
+		log.PanicIf(err)
+
+		_, err = mf.ReplaceExif(c.data, exifData)
+		if ce, found := AsCapabilityError(err); found != true {
+			t.Fatalf("Expected CapabilityError for %s: %v", c.name, err)
+		} else if ce.Kind != mf.Kind() || ce.Available != c.available {
+			t.Fatalf("CapabilityError for %s not correct: %v", c.name, ce)
+		} else if _, capabilities := GetCapabilities(c.data); capabilities != c.available {
+			t.Fatalf("Capabilities of %s not correct: %s", c.name, capabilities)
+		}
+	}
+}
+
+func TestRegisterMediaFormat(t *testing.T) {
+	original := mediaFormats
+	defer func() {
+		mediaFormats = original
+	}()
+
+	RegisterMediaFormat(testMediaFormat{})
+
+	exifData := getTestExifData()
+	data := append(append([]byte{}, testMediaFormatSignature...), exifData...)
+
+	rawExif, kind, err := ExtractExif(data)
+	log.PanicIf(err)
+
+	if kind != "test" {
+		t.Fatalf("Kind not correct: [%s]", kind)
+	} else if bytes.Equal(rawExif, exifData) != true {
+		t.Fatalf("EXIF not correct.")
+	}
+
+	mf, err := GetMediaFormat("test")
+	log.PanicIf(err)
+
+	if _, ok := mf.(testMediaFormat); ok != true {
+		t.Fatalf("Format not correct: %v", mf)
+	} else if _, err := GetMediaFormat("not-a-kind"); err != ErrMediaFormatNotFound {
+		t.Fatalf("Expected ErrMediaFormatNotFound: %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.png")
+
+	err = ioutil.WriteFile(filepath, exiftest.WrapPng(getTestExifData()), 0644)
+	log.PanicIf(err)
+
+	mf, err := Open(filepath)
+	log.PanicIf(err)
+
+	if mf.Format.Kind() != KindPng {
+		t.Fatalf("Format not correct: [%s]", mf.Format.Kind())
+	}
+
+	replacement := getTestReplacementExif()
+
+	err = mf.ReplaceExif(replacement)
+	log.PanicIf(err)
+
+	err = mf.Save()
+	log.PanicIf(err)
+
+	mf, err = Open(filepath)
+	log.PanicIf(err)
+
+	rawExif, err := mf.Exif()
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, replacement) != true {
+		t.Fatalf("EXIF not saved.")
+	}
+
+	err = ioutil.WriteFile(filepath, []byte("not media"), 0644)
+	log.PanicIf(err)
+
+	if _, err := Open(filepath); err != ErrMediaFormatNotFound {
+		t.Fatalf("Expected ErrMediaFormatNotFound: %v", err)
+	}
+}
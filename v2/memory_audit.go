@@ -0,0 +1,138 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+
+	"hash/crc32"
+)
+
+const (
+	// guardSize is the size of each canary around a guarded value.
+	guardSize = 16
+
+	// guardCanary fills the canaries.
+	guardCanary = 0xa5
+
+	// guardPoison fills a guarded value once it's released.
+	guardPoison = 0xdd
+)
+
+var (
+	// ErrMemoryAuditFailed is returned by `ValueSource.Close()` in audit mode
+	// if any of its references were misused (see `AuditViolations()`).
+	ErrMemoryAuditFailed = errors.New("memory audit failed")
+)
+
+// MemoryAuditEnabled returns true if the library was built with the
+// `exif_audit` tag. Then, the bytes of every `ValueRef` are a copy of the
+// value in a guarded buffer instead of a view of the source. The buffer has
+// canaries on either side, to detect writes past the value (e.g. by
+// appending to it), and is checksummed, to detect writes to the value
+// itself. When the reference is released, the value is poisoned, so that
+// reading bytes that were kept past then returns garbage rather than
+// silently working until the source is unmapped, and writes to it after
+// then are detected too. This is slower and copies every value, so it's only for
+// validating an integration before using zero-copy mode in production.
+func MemoryAuditEnabled() bool {
+	return memoryAuditBuild
+}
+
+// guardedBuffer is a copy of a value between two canaries.
+type guardedBuffer struct {
+	buffer   []byte
+	size     int
+	checksum uint32
+	poisoned bool
+}
+
+// newGuardedBuffer copies the value into a new guarded buffer.
+func newGuardedBuffer(raw []byte) *guardedBuffer {
+	buffer := make([]byte, guardSize+len(raw)+guardSize)
+
+	for i := range buffer {
+		buffer[i] = guardCanary
+	}
+
+	copy(buffer[guardSize:], raw)
+
+	gb := &guardedBuffer{
+		buffer:   buffer,
+		size:     len(raw),
+		checksum: crc32.ChecksumIEEE(raw),
+	}
+
+	return gb
+}
+
+// value returns the value. Its capacity extends into the trailing canary, so
+// that appending to it in place is detected.
+func (gb *guardedBuffer) value() []byte {
+	return gb.buffer[guardSize : guardSize+gb.size : guardSize+gb.size+guardSize]
+}
+
+// check returns a description of how the buffer was misused, or an empty
+// string if it wasn't.
+func (gb *guardedBuffer) check() string {
+	for i := 0; i < guardSize; i++ {
+		if gb.buffer[i] != guardCanary {
+			return "written before the start of the value"
+		} else if gb.buffer[guardSize+gb.size+i] != guardCanary {
+			return "written past the end of the value"
+		}
+	}
+
+	value := gb.buffer[guardSize : guardSize+gb.size]
+
+	if gb.poisoned == true {
+		for _, b := range value {
+			if b != guardPoison {
+				return "written after being released"
+			}
+		}
+	} else if crc32.ChecksumIEEE(value) != gb.checksum {
+		return "modified"
+	}
+
+	return ""
+}
+
+// poison overwrites the value, so that stale views of it read garbage.
+func (gb *guardedBuffer) poison() {
+	value := gb.buffer[guardSize : guardSize+gb.size]
+	for i := range value {
+		value[i] = guardPoison
+	}
+
+	gb.poisoned = true
+}
+
+// auditViolation records a misuse of the reference. The source's lock must
+// be held.
+func (vr *ValueRef) auditViolation(description string) {
+	vs := vr.source
+
+	violation := fmt.Errorf("value reference (%d) of (%d) bytes %s", vr.auditId, len(vr.data), description)
+	vs.violations = append(vs.violations, violation)
+}
+
+// auditCheck checks the reference's buffer and records how it was misused.
+// The source's lock must be held.
+func (vr *ValueRef) auditCheck() {
+	if description := vr.guarded.check(); description != "" {
+		vr.auditViolation(description)
+	}
+}
+
+// AuditViolations returns how the references of the source have been misused
+// so far, in audit mode (see `MemoryAuditEnabled()`). Writes are found when a
+// reference is released and when the source is closed.
+func (vs *ValueSource) AuditViolations() []error {
+	vs.m.Lock()
+	defer vs.m.Unlock()
+
+	violations := make([]error, len(vs.violations))
+	copy(violations, vs.violations)
+
+	return violations
+}
@@ -0,0 +1,9 @@
+//go:build exif_audit
+// +build exif_audit
+
+package exif
+
+const (
+	// memoryAuditBuild is true when built with the `exif_audit` tag.
+	memoryAuditBuild = true
+)
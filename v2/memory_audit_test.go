@@ -0,0 +1,130 @@
+package exif
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestAuditedValueRef returns a source in audit mode and a reference to
+// the Make.
+func getTestAuditedValueRef() (vs *ValueSource, vr *ValueRef, rawExif []byte) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	vs = NewValueSource(rawExif, nil)
+	vs.audit = true
+
+	index := getTestValueSourceIndex(vs)
+
+	results, err := index.RootIfd.FindTagWithName("Make")
+	log.PanicIf(err)
+
+	vr, err = vs.Ref(results[0])
+	log.PanicIf(err)
+
+	return vs, vr, rawExif
+}
+
+// checkTestAuditViolation asserts that the source has one violation with the
+// description.
+func checkTestAuditViolation(t *testing.T, vs *ValueSource, description string) {
+	violations := vs.AuditViolations()
+	if len(violations) != 1 {
+		t.Fatalf("Expected one violation: %v", violations)
+	} else if strings.HasSuffix(violations[0].Error(), description) != true {
+		t.Fatalf("Violation not correct: %v", violations[0])
+	}
+}
+
+func TestValueRef_Audit(t *testing.T) {
+	vs, vr, rawExif := getTestAuditedValueRef()
+
+	view, err := vr.Bytes()
+	log.PanicIf(err)
+
+	if string(view) != "Canon\x00" {
+		t.Fatalf("View not correct: %q", view)
+	}
+
+	// The view is a copy.
+	original := make([]byte, len(rawExif))
+	copy(original, rawExif)
+
+	vr.Release()
+
+	if bytes.Equal(rawExif, original) != true {
+		t.Fatalf("Source should not have changed.")
+	} else if bytes.Equal(view, bytes.Repeat([]byte{guardPoison}, len(view))) != true {
+		t.Fatalf("Released view not poisoned: %v", view)
+	}
+
+	err = vs.Close()
+	log.PanicIf(err)
+
+	if violations := vs.AuditViolations(); len(violations) != 0 {
+		t.Fatalf("Expected no violations: %v", violations)
+	}
+}
+
+func TestValueRef_Audit_WritePastEnd(t *testing.T) {
+	vs, vr, _ := getTestAuditedValueRef()
+
+	view, err := vr.Bytes()
+	log.PanicIf(err)
+
+	// This appends in place, over the canary.
+	_ = append(view, 'x')
+
+	vr.Release()
+
+	checkTestAuditViolation(t, vs, "written past the end of the value")
+
+	if err := vs.Close(); err != ErrMemoryAuditFailed {
+		t.Fatalf("Expected ErrMemoryAuditFailed: %v", err)
+	}
+}
+
+func TestValueRef_Audit_Modified(t *testing.T) {
+	vs, vr, _ := getTestAuditedValueRef()
+
+	view, err := vr.Bytes()
+	log.PanicIf(err)
+
+	view[0] = 'c'
+
+	// It's found when the source is closed, even if it's not released.
+	if err := vs.Close(); err != ErrMemoryAuditFailed {
+		t.Fatalf("Expected ErrMemoryAuditFailed: %v", err)
+	}
+
+	checkTestAuditViolation(t, vs, "modified")
+}
+
+func TestValueRef_Audit_UseAfterRelease(t *testing.T) {
+	vs, vr, _ := getTestAuditedValueRef()
+
+	view, err := vr.Bytes()
+	log.PanicIf(err)
+
+	vr.Release()
+
+	// The view that was kept reads garbage, and writing to it is found.
+	if view[0] != guardPoison {
+		t.Fatalf("Released view not poisoned: %v", view)
+	}
+
+	view[0] = 'c'
+
+	if err := vs.Close(); err != ErrMemoryAuditFailed {
+		t.Fatalf("Expected ErrMemoryAuditFailed: %v", err)
+	}
+
+	checkTestAuditViolation(t, vs, "written after being released")
+}
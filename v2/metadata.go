@@ -0,0 +1,413 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// pngXmpKeyword is the keyword of the iTXt chunk with the XMP packet.
+	pngXmpKeyword = []byte("XML:com.adobe.xmp")
+)
+
+// Metadata is everything that could be read from a file: the EXIF, XMP, IPTC,
+// ICC profile, and thumbnails. Each source is read on its own, so a missing
+// or broken one doesn't keep the others from being returned; its accessor
+// returns the error instead (e.g. `ErrNoXmp` if there isn't any XMP).
+type Metadata struct {
+	// Kind is the container.
+	Kind Kind
+
+	index   IfdIndex
+	exifErr error
+
+	xmp    []byte
+	xmpErr error
+
+	iptc    []IptcDataset
+	iptcErr error
+
+	iccProfile IccProfile
+	iccErr     error
+
+	thumbnails [][]byte
+}
+
+// Exif returns the parsed EXIF. `ErrNoExif` is returned if there isn't any.
+func (md *Metadata) Exif() (index IfdIndex, err error) {
+	return md.index, md.exifErr
+}
+
+// Summary returns the common values of the EXIF (see `NewSummary()`).
+func (md *Metadata) Summary() (s *Summary, err error) {
+	if md.exifErr != nil {
+		return nil, md.exifErr
+	}
+
+	return NewSummary(md.index), nil
+}
+
+// Xmp returns the XMP packet. `ErrNoXmp` is returned if there isn't one.
+func (md *Metadata) Xmp() (xmp []byte, err error) {
+	return md.xmp, md.xmpErr
+}
+
+// Iptc returns the IPTC datasets. `ErrNoIptc` is returned if there aren't
+// any.
+func (md *Metadata) Iptc() (datasets []IptcDataset, err error) {
+	return md.iptc, md.iptcErr
+}
+
+// IccProfile returns the ICC profile. `ErrNoIccProfile` is returned if there
+// isn't one.
+func (md *Metadata) IccProfile() (ip IccProfile, err error) {
+	return md.iccProfile, md.iccErr
+}
+
+// Thumbnails returns the thumbnails in the EXIF, in the order of their IFDs.
+// It's empty if there aren't any.
+func (md *Metadata) Thumbnails() [][]byte {
+	return md.thumbnails
+}
+
+// ReadMetadata reads all of the metadata of a file that's already in memory.
+// `ErrMediaFormatNotFound` is returned if no registered format recognizes it.
+func ReadMetadata(data []byte) (md *Metadata, err error) {
+	mf, err := SniffMediaFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return readMetadata(mf, data, nil), nil
+}
+
+// ReadMetadataWithCache is `ReadMetadata()` but takes the EXIF from the cache
+// if the content was read before, and adds it otherwise (see
+// `ReadCachedMetadata()`).
+func ReadMetadataWithCache(data []byte, cache MetadataCache) (md *Metadata, err error) {
+	mf, err := SniffMediaFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return readMetadata(mf, data, cache), nil
+}
+
+// OpenMetadata reads the file and all of its metadata, which is what most
+// applications need (see `Open()` to write it back).
+func OpenMetadata(filepath string) (md *Metadata, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	mf, err := Open(filepath)
+	if err == ErrMediaFormatNotFound {
+		return nil, err
+	}
+
+	log.PanicIf(err)
+
+	return mf.Metadata(), nil
+}
+
+// OpenMetadataWithCache is `OpenMetadata()` but takes the EXIF from the cache
+// if the content was read before, and adds it otherwise (see
+// `ReadCachedMetadata()`). The file is still read, to hash it.
+func OpenMetadataWithCache(filepath string, cache MetadataCache) (md *Metadata, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	mf, err := Open(filepath)
+	if err == ErrMediaFormatNotFound {
+		return nil, err
+	}
+
+	log.PanicIf(err)
+
+	return mf.MetadataWithCache(cache), nil
+}
+
+// Metadata reads all of the metadata of the file.
+func (mf *MediaFile) Metadata() *Metadata {
+	return readMetadata(mf.Format, mf.Data, nil)
+}
+
+// MetadataWithCache reads all of the metadata of the file, taking the EXIF
+// from the cache if the content was read before.
+func (mf *MediaFile) MetadataWithCache(cache MetadataCache) *Metadata {
+	return readMetadata(mf.Format, mf.Data, cache)
+}
+
+// readMetadata reads each source with the format's EXIF and the container's
+// XMP, IPTC, and ICC profile. The EXIF comes from the cache if there is one.
+func readMetadata(mf MediaFormat, data []byte, cache MetadataCache) *Metadata {
+	md := &Metadata{
+		Kind:       mf.Kind(),
+		thumbnails: make([][]byte, 0),
+	}
+
+	if cache != nil {
+		md.exifErr = md.readCachedExif(data, cache)
+	} else {
+		md.exifErr = md.readExif(mf, data)
+	}
+
+	md.xmp, md.xmpErr = getContainerXmp(md.Kind, data)
+	md.iptc, md.iptcErr = getContainerIptc(md.Kind, data)
+	md.iccProfile, md.iccErr = getContainerIccProfile(md.Kind, data)
+
+	return md
+}
+
+// readExif parses the EXIF and collects the thumbnails.
+func (md *Metadata) readExif(mf MediaFormat, data []byte) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rawExif, err := mf.ExtractExif(data)
+	if err == ErrNoExif {
+		return err
+	}
+
+	log.PanicIf(err)
+
+	_, md.index, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	md.readThumbnails()
+
+	return nil
+}
+
+// readCachedExif takes the parsed EXIF from the cache, adding it if it's not
+// there, and collects the thumbnails.
+func (md *Metadata) readCachedExif(data []byte, cache MetadataCache) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	cm, err := ReadCachedMetadata(data, cache)
+	log.PanicIf(err)
+
+	md.index, err = cm.Exif()
+	if err == ErrNoExif {
+		return err
+	}
+
+	log.PanicIf(err)
+
+	md.readThumbnails()
+
+	return nil
+}
+
+// readThumbnails collects the thumbnails of the parsed EXIF.
+func (md *Metadata) readThumbnails() {
+	for _, ifd := range md.index.Ifds {
+		if thumbnail, err := ifd.Thumbnail(); err == nil {
+			md.thumbnails = append(md.thumbnails, thumbnail)
+		}
+	}
+}
+
+// getContainerXmp returns the XMP packet of a JPEG (APP1), PNG (iTXt), or
+// WebP ("XMP " chunk). `ErrNoXmp` is returned for other containers.
+func getContainerXmp(kind Kind, data []byte) (xmp []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	switch kind {
+	case KindJpeg:
+		return GetJpegXmp(data)
+	case KindPng:
+		for _, payload := range getPngChunks(data, "iTXt") {
+			if xmp, found := parsePngXmp(payload); found == true {
+				return xmp, nil
+			}
+		}
+	case KindWebp:
+		if payload, found := getWebpChunk(data, "XMP "); found == true {
+			return payload, nil
+		}
+	}
+
+	return nil, ErrNoXmp
+}
+
+// getContainerIptc returns the IPTC datasets of a JPEG. `ErrNoIptc` is
+// returned for other containers.
+func getContainerIptc(kind Kind, data []byte) (datasets []IptcDataset, err error) {
+	if kind == KindJpeg {
+		return GetJpegIptc(data)
+	}
+
+	return nil, ErrNoIptc
+}
+
+// getContainerIccProfile returns the ICC profile of a JPEG (APP2), PNG
+// (iCCP), or WebP ("ICCP" chunk). `ErrNoIccProfile` is returned for other
+// containers.
+func getContainerIccProfile(kind Kind, data []byte) (ip IccProfile, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	var profile []byte
+
+	switch kind {
+	case KindJpeg:
+		return GetJpegIccProfile(data)
+	case KindPng:
+		if chunks := getPngChunks(data, "iCCP"); len(chunks) > 0 {
+			// The profile name, the compression method, and then the
+			// compressed profile.
+			nul := bytes.IndexByte(chunks[0], 0)
+			if nul == -1 || nul+2 > len(chunks[0]) {
+				log.Panicf("iCCP chunk not valid")
+			}
+
+			profile, err = inflatePng(chunks[0][nul+2:])
+			log.PanicIf(err)
+		}
+	case KindWebp:
+		profile, _ = getWebpChunk(data, "ICCP")
+	}
+
+	if profile == nil {
+		return ip, ErrNoIccProfile
+	}
+
+	ip, err = ParseIccProfile(profile)
+	log.PanicIf(err)
+
+	return ip, nil
+}
+
+// getPngChunks returns the payloads of the chunks of the given type, up to the
+// IEND chunk.
+func getPngChunks(data []byte, chunkType string) (payloads [][]byte) {
+	payloads = make([][]byte, 0)
+
+	if bytes.HasPrefix(data, pngSignature) == false {
+		return payloads
+	}
+
+	for position := len(pngSignature); position+8 <= len(data); {
+		size := binary.BigEndian.Uint32(data[position:])
+		currentType := string(data[position+4 : position+8])
+
+		payload, err := exifcommon.CheckedSlice(data, uint32(position+8), size)
+		if err != nil || currentType == "IEND" {
+			break
+		} else if currentType == chunkType {
+			payloads = append(payloads, payload)
+		}
+
+		// Length, type, data, and CRC.
+		position += 12 + len(payload)
+	}
+
+	return payloads
+}
+
+// parsePngXmp returns the text of an iTXt chunk if it's the XMP packet.
+func parsePngXmp(payload []byte) (xmp []byte, found bool) {
+	if bytes.HasPrefix(payload, append(pngXmpKeyword, 0)) == false {
+		return nil, false
+	}
+
+	// The compression flag and method follow the keyword, and then the
+	// language tag and the translated keyword, which are NUL-terminated.
+	rest := payload[len(pngXmpKeyword)+1:]
+	if len(rest) < 2 {
+		return nil, false
+	}
+
+	compressed := rest[0] == 1
+
+	rest = rest[2:]
+	for i := 0; i < 2; i++ {
+		nul := bytes.IndexByte(rest, 0)
+		if nul == -1 {
+			return nil, false
+		}
+
+		rest = rest[nul+1:]
+	}
+
+	if compressed == false {
+		return rest, true
+	}
+
+	xmp, err := inflatePng(rest)
+	if err != nil {
+		return nil, false
+	}
+
+	return xmp, true
+}
+
+// inflatePng decompresses the zlib stream of a PNG chunk.
+func inflatePng(data []byte) (inflated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	log.PanicIf(err)
+
+	defer r.Close()
+
+	inflated, err = ioutil.ReadAll(r)
+	log.PanicIf(err)
+
+	return inflated, nil
+}
+
+// getWebpChunk returns the payload of the first chunk of a WebP with the
+// given ID.
+func getWebpChunk(data []byte, id string) (payload []byte, found bool) {
+	if len(data) < 12 {
+		return nil, false
+	}
+
+	chunks, err := parseRiffChunks(data[12:])
+	if err != nil {
+		return nil, false
+	}
+
+	for _, chunk := range chunks {
+		if chunk.id == id {
+			return chunk.data, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,427 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"container/list"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+const (
+	// DefaultMemoryMetadataCacheEntries is the most entries that a
+	// `MemoryMetadataCache` keeps by default.
+	DefaultMemoryMetadataCacheEntries = 10000
+)
+
+var (
+	metadataCacheLogger = log.NewLogger("exif.metadata_cache")
+)
+
+var (
+	// ErrMetadataCacheKeyNotValid means that a key given to a cache isn't a
+	// hex-encoded hash (see `MetadataCacheKey()`).
+	ErrMetadataCacheKeyNotValid = errors.New("metadata cache key not valid")
+)
+
+// CachedMetadata is what's cached for the content of a file: its container,
+// its EXIF block, and the flattened tags of it, so that they can be had
+// without reading the container or parsing the IFDs again.
+type CachedMetadata struct {
+	// Kind is the container.
+	Kind Kind `json:"kind"`
+
+	// RawExif is the EXIF block. It's empty if there isn't one.
+	RawExif []byte `json:"raw_exif,omitempty"`
+
+	// Tags are the tags of the EXIF (see `IfdIndex.Marshal()`).
+	Tags []FlatTag `json:"tags,omitempty"`
+
+	// exifOnce makes sure that the EXIF is only parsed once, however many
+	// times it's asked for.
+	exifOnce sync.Once
+	index    IfdIndex
+	exifErr  error
+}
+
+// String returns a descriptive string.
+func (cm *CachedMetadata) String() string {
+	return fmt.Sprintf("CachedMetadata<KIND=[%s] EXIF-SIZE=(%d) TAGS=(%d)>", cm.Kind, len(cm.RawExif), len(cm.Tags))
+}
+
+// Exif returns the parsed EXIF block. It's only parsed the first time, and
+// the same index is returned after that, so it shouldn't be modified.
+// `ErrNoExif` is returned if there isn't one.
+func (cm *CachedMetadata) Exif() (index IfdIndex, err error) {
+	cm.exifOnce.Do(func() {
+		cm.index, cm.exifErr = cm.parseExif()
+	})
+
+	return cm.index, cm.exifErr
+}
+
+// parseExif parses the EXIF block.
+func (cm *CachedMetadata) parseExif() (index IfdIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(cm.RawExif) == 0 {
+		return index, ErrNoExif
+	}
+
+	_, index, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), cm.RawExif)
+	log.PanicIf(err)
+
+	return index, nil
+}
+
+// decodeUndefinedValues decodes the values of the undefined-type tags from
+// their raw bytes, since JSON doesn't keep their types.
+func (cm *CachedMetadata) decodeUndefinedValues() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(cm.RawExif) == 0 {
+		return nil
+	}
+
+	eh, err := ParseExifHeader(cm.RawExif)
+	log.PanicIf(err)
+
+	for i, ft := range cm.Tags {
+		if ft.TagType != exifcommon.TypeUndefined || ft.Raw == nil {
+			continue
+		}
+
+		rawValueOffset := make([]byte, 4)
+		copy(rawValueOffset, ft.Raw)
+
+		valueContext := exifcommon.NewValueContext(ft.IfdPath, ft.TagId, ft.UnitCount, 0, rawValueOffset, ft.Raw, ft.TagType, eh.ByteOrder)
+
+		value, err := exifundefined.Decode(valueContext)
+		if err != nil {
+			if err != exifcommon.ErrUnhandledUndefinedTypedTag && err != exifundefined.ErrUnparseableValue {
+				log.Panic(err)
+			}
+
+			value = nil
+		}
+
+		cm.Tags[i].Value = value
+	}
+
+	return nil
+}
+
+// MetadataCache stores what was read from files by the hash of their content
+// (see `MetadataCacheKey()`), so that scanning a large library again only
+// parses the files that changed. Implementations have to be safe for
+// concurrent use. What's returned by `Get()` is shared and shouldn't be
+// modified.
+type MetadataCache interface {
+	// Get returns what was stored for the key. `found` is false if nothing
+	// was.
+	Get(key string) (cm *CachedMetadata, found bool, err error)
+
+	// Put stores the metadata for the key, replacing anything that was.
+	Put(key string, cm *CachedMetadata) (err error)
+}
+
+// MetadataCacheKey returns the key of the content in a `MetadataCache`, which
+// is the hex-encoded SHA-256 of it.
+func MetadataCacheKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadCachedMetadata returns the metadata of a file that's already in memory
+// from the cache or, if it's not there, reads it and adds it. Files without
+// EXIF are cached too, but files that fail to parse aren't, so that their
+// errors are reported each time. `ErrMediaFormatNotFound` is returned if no
+// registered format recognizes the file.
+func ReadCachedMetadata(data []byte, cache MetadataCache) (cm *CachedMetadata, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	key := MetadataCacheKey(data)
+
+	cm, found, err := cache.Get(key)
+	log.PanicIf(err)
+
+	if found == true {
+		return cm, nil
+	}
+
+	mf, err := SniffMediaFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cm = &CachedMetadata{
+		Kind: mf.Kind(),
+	}
+
+	rawExif, err := mf.ExtractExif(data)
+	if err != nil && err != ErrNoExif {
+		log.Panic(err)
+	}
+
+	if err == nil {
+		_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+		log.PanicIf(err)
+
+		cm.Tags, err = index.Marshal()
+		log.PanicIf(err)
+
+		cm.RawExif = rawExif
+
+		// We've already parsed it.
+		cm.exifOnce.Do(func() {
+			cm.index = index
+		})
+	}
+
+	err = cache.Put(key, cm)
+	log.PanicIf(err)
+
+	return cm, nil
+}
+
+// OpenCachedMetadata reads the file and returns its metadata from the cache
+// (see `ReadCachedMetadata()`). The file is still read, to hash it.
+func OpenCachedMetadata(filepath string, cache MetadataCache) (cm *CachedMetadata, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	cm, err = ReadCachedMetadata(data, cache)
+	if err == ErrMediaFormatNotFound {
+		return nil, err
+	}
+
+	log.PanicIf(err)
+
+	return cm, nil
+}
+
+// MemoryMetadataCache is a `MetadataCache` that's kept in memory, for the
+// life of the process. It's bounded: once it's full, the entry that was used
+// least recently is evicted to make room for a new one.
+type MemoryMetadataCache struct {
+	maxEntries int
+
+	m sync.Mutex
+
+	// recent has the keys, from the most recently used to the least.
+	recent  *list.List
+	entries map[string]*list.Element
+}
+
+// memoryMetadataCacheEntry is an element of `MemoryMetadataCache.recent`.
+type memoryMetadataCacheEntry struct {
+	key string
+	cm  *CachedMetadata
+}
+
+// NewMemoryMetadataCache returns an empty cache that keeps at most the given
+// number of entries (`DefaultMemoryMetadataCacheEntries` if it's zero or
+// less).
+func NewMemoryMetadataCache(maxEntries int) *MemoryMetadataCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMemoryMetadataCacheEntries
+	}
+
+	return &MemoryMetadataCache{
+		maxEntries: maxEntries,
+		recent:     list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns what was stored for the key.
+func (mmc *MemoryMetadataCache) Get(key string) (cm *CachedMetadata, found bool, err error) {
+	mmc.m.Lock()
+	defer mmc.m.Unlock()
+
+	e, found := mmc.entries[key]
+	if found == false {
+		return nil, false, nil
+	}
+
+	mmc.recent.MoveToFront(e)
+
+	return e.Value.(memoryMetadataCacheEntry).cm, true, nil
+}
+
+// Put stores the metadata for the key, evicting the least-recently-used entry
+// if the cache is full.
+func (mmc *MemoryMetadataCache) Put(key string, cm *CachedMetadata) (err error) {
+	mmc.m.Lock()
+	defer mmc.m.Unlock()
+
+	if e, found := mmc.entries[key]; found == true {
+		e.Value = memoryMetadataCacheEntry{key: key, cm: cm}
+		mmc.recent.MoveToFront(e)
+
+		return nil
+	}
+
+	if mmc.recent.Len() >= mmc.maxEntries {
+		oldest := mmc.recent.Back()
+
+		mmc.recent.Remove(oldest)
+		delete(mmc.entries, oldest.Value.(memoryMetadataCacheEntry).key)
+	}
+
+	mmc.entries[key] = mmc.recent.PushFront(memoryMetadataCacheEntry{key: key, cm: cm})
+
+	return nil
+}
+
+// Count returns the number of entries.
+func (mmc *MemoryMetadataCache) Count() int {
+	mmc.m.Lock()
+	defer mmc.m.Unlock()
+
+	return mmc.recent.Len()
+}
+
+// DiskMetadataCache is a `MetadataCache` that's kept in a directory, so that
+// it lasts between runs. Each entry is a JSON file named for its key, under a
+// subdirectory named for the first two characters of it. Entries are written
+// to a temporary file and renamed, so an interrupted write never leaves a
+// partial one, and one that can't be read is treated as missing.
+type DiskMetadataCache struct {
+	dirpath string
+}
+
+// NewDiskMetadataCache returns a cache in the given directory, which is
+// created if it doesn't exist.
+func NewDiskMetadataCache(dirpath string) (dmc *DiskMetadataCache, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = os.MkdirAll(dirpath, 0755)
+	log.PanicIf(err)
+
+	dmc = &DiskMetadataCache{
+		dirpath: dirpath,
+	}
+
+	return dmc, nil
+}
+
+// entryFilepath returns the path of the entry for the key.
+func (dmc *DiskMetadataCache) entryFilepath(key string) (filepath string, err error) {
+	if len(key) < 2 {
+		return "", ErrMetadataCacheKeyNotValid
+	} else if _, err := hex.DecodeString(key); err != nil {
+		return "", ErrMetadataCacheKeyNotValid
+	}
+
+	return path.Join(dmc.dirpath, key[:2], key+".json"), nil
+}
+
+// Get returns what was stored for the key.
+func (dmc *DiskMetadataCache) Get(key string) (cm *CachedMetadata, found bool, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	filepath, err := dmc.entryFilepath(key)
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	if os.IsNotExist(err) == true {
+		return nil, false, nil
+	}
+
+	log.PanicIf(err)
+
+	cm = new(CachedMetadata)
+
+	if err := json.Unmarshal(data, cm); err != nil {
+		metadataCacheLogger.Warningf(nil, "Ignoring invalid cache entry [%s]: %s", filepath, err)
+		return nil, false, nil
+	}
+
+	err = cm.decodeUndefinedValues()
+	log.PanicIf(err)
+
+	return cm, true, nil
+}
+
+// Put stores the metadata for the key.
+func (dmc *DiskMetadataCache) Put(key string, cm *CachedMetadata) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	filepath, err := dmc.entryFilepath(key)
+	log.PanicIf(err)
+
+	data, err := json.Marshal(cm)
+	log.PanicIf(err)
+
+	parentPath := path.Dir(filepath)
+
+	err = os.MkdirAll(parentPath, 0755)
+	log.PanicIf(err)
+
+	f, err := ioutil.TempFile(parentPath, key+".*.tmp")
+	log.PanicIf(err)
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), filepath)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+		log.Panic(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,281 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// countingMetadataCache counts the entries that are stored in a cache.
+type countingMetadataCache struct {
+	MetadataCache
+
+	puts int
+}
+
+func (cmc *countingMetadataCache) Put(key string, cm *CachedMetadata) (err error) {
+	cmc.puts++
+	return cmc.MetadataCache.Put(key, cm)
+}
+
+func TestReadCachedMetadata_Memory(t *testing.T) {
+	data := getTestJpegWithExif()
+	cache := &countingMetadataCache{MetadataCache: NewMemoryMetadataCache(0)}
+
+	cm, err := ReadCachedMetadata(data, cache)
+	log.PanicIf(err)
+
+	if cm.Kind != KindJpeg {
+		t.Fatalf("Kind not correct: [%s]", cm.Kind)
+	} else if len(cm.Tags) == 0 {
+		t.Fatalf("Expected tags.")
+	}
+
+	index, err := cm.Exif()
+	log.PanicIf(err)
+
+	expectedTags, err := index.Marshal()
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(cm.Tags, expectedTags) != true {
+		t.Fatalf("Tags not correct.")
+	}
+
+	// The second read is from the cache.
+	cached, err := ReadCachedMetadata(data, cache)
+	log.PanicIf(err)
+
+	if cached != cm {
+		t.Fatalf("Expected the cached entry.")
+	} else if cache.puts != 1 {
+		t.Fatalf("Expected one entry to be stored: (%d)", cache.puts)
+	}
+}
+
+func TestReadCachedMetadata_NoExif(t *testing.T) {
+	data, _, _ := getTestHeic()
+	cache := NewMemoryMetadataCache(0)
+
+	cm, err := ReadCachedMetadata(data, cache)
+	log.PanicIf(err)
+
+	if cm.Kind != KindHeif || len(cm.RawExif) != 0 || len(cm.Tags) != 0 {
+		t.Fatalf("Entry not correct: %s", cm)
+	} else if cache.Count() != 1 {
+		t.Fatalf("A file without EXIF should be cached too.")
+	}
+
+	if _, err := cm.Exif(); err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif: %v", err)
+	}
+}
+
+func TestReadCachedMetadata_NotRecognized(t *testing.T) {
+	cache := NewMemoryMetadataCache(0)
+
+	if _, err := ReadCachedMetadata([]byte("not an image"), cache); err != ErrMediaFormatNotFound {
+		t.Fatalf("Expected ErrMediaFormatNotFound: %v", err)
+	} else if cache.Count() != 0 {
+		t.Fatalf("Nothing should have been cached.")
+	}
+}
+
+func TestOpenCachedMetadata_Disk(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	data := getTestJpegWithExif()
+	filepath := writeTestFile(tempPath, "image.jpg", data)
+
+	cache, err := NewDiskMetadataCache(path.Join(tempPath, "cache"))
+	log.PanicIf(err)
+
+	cm, err := OpenCachedMetadata(filepath, cache)
+	log.PanicIf(err)
+
+	// A new cache in the same directory has the entry.
+	cache, err = NewDiskMetadataCache(path.Join(tempPath, "cache"))
+	log.PanicIf(err)
+
+	cached, found, err := cache.Get(MetadataCacheKey(data))
+	log.PanicIf(err)
+
+	if found != true {
+		t.Fatalf("Entry not stored.")
+	} else if cached.Kind != cm.Kind || reflect.DeepEqual(cached.RawExif, cm.RawExif) != true {
+		t.Fatalf("Entry not correct: %s", cached)
+	} else if reflect.DeepEqual(cached.Tags, cm.Tags) != true {
+		t.Fatalf("Tags not correct.")
+	}
+}
+
+func TestDiskMetadataCache_Get_Invalid(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	cache, err := NewDiskMetadataCache(tempPath)
+	log.PanicIf(err)
+
+	key := MetadataCacheKey([]byte("content"))
+
+	err = os.MkdirAll(path.Join(tempPath, key[:2]), 0755)
+	log.PanicIf(err)
+
+	writeTestFile(path.Join(tempPath, key[:2]), key+".json", []byte("{\"kind\":"))
+
+	if _, found, err := cache.Get(key); err != nil || found != false {
+		t.Fatalf("An invalid entry should be missing: %v %v", found, err)
+	}
+
+	if _, _, err := cache.Get("../escape"); log.Is(err, ErrMetadataCacheKeyNotValid) != true {
+		t.Fatalf("Expected ErrMetadataCacheKeyNotValid: %v", err)
+	} else if err := cache.Put("", &CachedMetadata{}); log.Is(err, ErrMetadataCacheKeyNotValid) != true {
+		t.Fatalf("Expected ErrMetadataCacheKeyNotValid: %v", err)
+	}
+}
+
+func TestCachedMetadata_Exif_ParsedOnce(t *testing.T) {
+	rawExif, err := SearchAndExtractExif(getTestJpegWithExif())
+	log.PanicIf(err)
+
+	cm := &CachedMetadata{
+		Kind:    KindJpeg,
+		RawExif: rawExif,
+	}
+
+	index1, err := cm.Exif()
+	log.PanicIf(err)
+
+	index2, err := cm.Exif()
+	log.PanicIf(err)
+
+	if index1.RootIfd == nil || index1.RootIfd != index2.RootIfd {
+		t.Fatalf("EXIF parsed again.")
+	}
+}
+
+func TestMemoryMetadataCache_Eviction(t *testing.T) {
+	cache := NewMemoryMetadataCache(2)
+
+	a := &CachedMetadata{Kind: KindJpeg}
+	b := &CachedMetadata{Kind: KindPng}
+	c := &CachedMetadata{Kind: KindHeif}
+
+	err := cache.Put("a", a)
+	log.PanicIf(err)
+
+	err = cache.Put("b", b)
+	log.PanicIf(err)
+
+	// "a" is now the most recently used, so "b" is evicted.
+	if cm, found, _ := cache.Get("a"); found != true || cm != a {
+		t.Fatalf("Entry not found.")
+	}
+
+	err = cache.Put("c", c)
+	log.PanicIf(err)
+
+	if cache.Count() != 2 {
+		t.Fatalf("Count not correct: (%d)", cache.Count())
+	} else if _, found, _ := cache.Get("b"); found == true {
+		t.Fatalf("Least-recently-used entry not evicted.")
+	} else if cm, found, _ := cache.Get("a"); found != true || cm != a {
+		t.Fatalf("Recently-used entry evicted.")
+	} else if cm, found, _ := cache.Get("c"); found != true || cm != c {
+		t.Fatalf("New entry not stored.")
+	}
+
+	if NewMemoryMetadataCache(0).maxEntries != DefaultMemoryMetadataCacheEntries {
+		t.Fatalf("Default size not correct.")
+	}
+}
+
+func TestReadMetadataWithCache(t *testing.T) {
+	data := getTestJpegWithExif()
+	cache := &countingMetadataCache{MetadataCache: NewMemoryMetadataCache(0)}
+
+	md1, err := ReadMetadataWithCache(data, cache)
+	log.PanicIf(err)
+
+	md2, err := ReadMetadataWithCache(data, cache)
+	log.PanicIf(err)
+
+	index1, err := md1.Exif()
+	log.PanicIf(err)
+
+	index2, err := md2.Exif()
+	log.PanicIf(err)
+
+	expected, err := ReadMetadata(data)
+	log.PanicIf(err)
+
+	if cache.puts != 1 {
+		t.Fatalf("Expected one entry to be stored: (%d)", cache.puts)
+	} else if index1.RootIfd != index2.RootIfd {
+		t.Fatalf("EXIF parsed again.")
+	} else if md1.Kind != expected.Kind || reflect.DeepEqual(md1.Thumbnails(), expected.Thumbnails()) != true {
+		t.Fatalf("Metadata not correct.")
+	}
+}
+
+func TestOpenMetadataWithCache(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	data, _, _ := getTestHeic()
+	filepath := writeTestFile(tempPath, "image.heic", data)
+
+	cache := NewMemoryMetadataCache(0)
+
+	md, err := OpenMetadataWithCache(filepath, cache)
+	log.PanicIf(err)
+
+	if _, err := md.Exif(); err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif: %v", err)
+	} else if cache.Count() != 1 {
+		t.Fatalf("Entry not stored.")
+	}
+}
+
+func TestParseMany_Cache(t *testing.T) {
+	data := getTestJpegWithExif()
+	cache := &countingMetadataCache{MetadataCache: NewMemoryMetadataCache(0)}
+
+	sources := []ParseSource{
+		BytesSource("first", data),
+		BytesSource("second", data),
+		BytesSource("empty", []byte("not an image")),
+	}
+
+	results, err := ParseMany(context.Background(), sources, ParseManyOptions{Concurrency: 1, Cache: cache})
+	log.PanicIf(err)
+
+	expected, err := ParseMany(context.Background(), sources[:1], ParseManyOptions{})
+	log.PanicIf(err)
+
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Fatalf("Sources failed: %v %v", results[0].Err, results[1].Err)
+	} else if results[2].Err != ErrNoExif {
+		t.Fatalf("Expected no-EXIF error: %v", results[2].Err)
+	} else if cache.puts != 1 {
+		t.Fatalf("Expected one entry to be stored: (%d)", cache.puts)
+	} else if results[0].Index.RootIfd != results[1].Index.RootIfd {
+		t.Fatalf("EXIF parsed again.")
+	} else if results[0].Header != expected[0].Header || len(results[0].Index.Ifds) != len(expected[0].Index.Ifds) {
+		t.Fatalf("Result not correct: %v", results[0].Header)
+	}
+}
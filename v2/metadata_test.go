@@ -0,0 +1,191 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"path"
+	"testing"
+
+	"compress/zlib"
+	"io/ioutil"
+	"os"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestCompressed returns the data as a zlib stream.
+func getTestCompressed(data []byte) []byte {
+	b := new(bytes.Buffer)
+
+	w := zlib.NewWriter(b)
+
+	_, err := w.Write(data)
+	log.PanicIf(err)
+
+	err = w.Close()
+	log.PanicIf(err)
+
+	return b.Bytes()
+}
+
+func TestReadMetadata_Jpeg(t *testing.T) {
+	profile := getTestIccProfile("Display P3")
+	data := getTestJpegWithIcc(profile)
+
+	data, err := SetJpegXmp(data, []byte("<x:xmpmeta/>"))
+	log.PanicIf(err)
+
+	datasets := []IptcDataset{
+		{Record: IptcRecordApplication, Dataset: IptcDatasetByline, Data: []byte("Jane Doe")},
+	}
+
+	data, err = SetJpegIptc(data, datasets)
+	log.PanicIf(err)
+
+	md, err := ReadMetadata(data)
+	log.PanicIf(err)
+
+	index, err := md.Exif()
+	log.PanicIf(err)
+
+	xmp, err := md.Xmp()
+	log.PanicIf(err)
+
+	iptc, err := md.Iptc()
+	log.PanicIf(err)
+
+	ip, err := md.IccProfile()
+	log.PanicIf(err)
+
+	s, err := md.Summary()
+	log.PanicIf(err)
+
+	if md.Kind != KindJpeg {
+		t.Fatalf("Kind not correct: [%s]", md.Kind)
+	} else if make_, err := getIfdTagString(index.RootIfd, "Make"); err != nil || make_ != "Canon" {
+		t.Fatalf("Make not correct: [%s] %v", make_, err)
+	} else if string(xmp) != "<x:xmpmeta/>" {
+		t.Fatalf("XMP not correct: [%s]", xmp)
+	} else if len(iptc) != 1 || string(iptc[0].Data) != "Jane Doe" {
+		t.Fatalf("IPTC not correct: %v", iptc)
+	} else if ip.Description != "Display P3" {
+		t.Fatalf("ICC profile not correct: %s", ip)
+	} else if s == nil {
+		t.Fatalf("Summary not returned.")
+	}
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	if thumbnails := md.Thumbnails(); len(thumbnails) != 1 || bytes.Equal(thumbnails[0], thumbnail) != true {
+		t.Fatalf("Thumbnails not correct: (%d)", len(thumbnails))
+	}
+}
+
+func TestReadMetadata_Png(t *testing.T) {
+	profile := getTestIccProfile("sRGB")
+
+	xmpChunk := append(append([]byte{}, pngXmpKeyword...), 0, 1, 0, 0, 0)
+	xmpChunk = append(xmpChunk, getTestCompressed([]byte("<x:xmpmeta/>"))...)
+
+	iccpChunk := append([]byte("icc\x00\x00"), getTestCompressed(profile)...)
+
+	data := append([]byte{}, pngSignature...)
+	data = appendPngChunk(data, "IHDR", make([]byte, 13))
+	data = appendPngChunk(data, "iCCP", iccpChunk)
+	data = appendPngChunk(data, "iTXt", xmpChunk)
+	data = appendPngChunk(data, "eXIf", getTestExifData())
+	data = appendPngChunk(data, "IDAT", []byte{1, 2, 3})
+	data = appendPngChunk(data, "IEND", nil)
+
+	md, err := ReadMetadata(data)
+	log.PanicIf(err)
+
+	xmp, err := md.Xmp()
+	log.PanicIf(err)
+
+	ip, err := md.IccProfile()
+	log.PanicIf(err)
+
+	if _, err := md.Exif(); err != nil {
+		t.Fatalf("EXIF not read: %v", err)
+	} else if string(xmp) != "<x:xmpmeta/>" {
+		t.Fatalf("XMP not correct: [%s]", xmp)
+	} else if ip.Description != "sRGB" {
+		t.Fatalf("ICC profile not correct: %s", ip)
+	} else if _, err := md.Iptc(); err != ErrNoIptc {
+		t.Fatalf("Expected ErrNoIptc: %v", err)
+	}
+}
+
+func TestReadMetadata_Webp(t *testing.T) {
+	body := new(bytes.Buffer)
+	body.WriteString("WEBP")
+
+	writeTestRiffChunk(body, "VP8X", make([]byte, 10))
+	writeTestRiffChunk(body, "ICCP", getTestIccProfile("sRGB"))
+	writeTestRiffChunk(body, "VP8 ", make([]byte, 100))
+	writeTestRiffChunk(body, "XMP ", []byte("<x:xmpmeta/>"))
+
+	webp := new(bytes.Buffer)
+	writeTestRiffChunk(webp, "RIFF", body.Bytes())
+
+	md, err := ReadMetadata(webp.Bytes())
+	log.PanicIf(err)
+
+	xmp, err := md.Xmp()
+	log.PanicIf(err)
+
+	ip, err := md.IccProfile()
+	log.PanicIf(err)
+
+	if md.Kind != KindWebp {
+		t.Fatalf("Kind not correct: [%s]", md.Kind)
+	} else if string(xmp) != "<x:xmpmeta/>" {
+		t.Fatalf("XMP not correct: [%s]", xmp)
+	} else if ip.Description != "sRGB" {
+		t.Fatalf("ICC profile not correct: %s", ip)
+	} else if _, err := md.Exif(); err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif: %v", err)
+	} else if _, err := md.Summary(); err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif from Summary: %v", err)
+	} else if len(md.Thumbnails()) != 0 {
+		t.Fatalf("Expected no thumbnails.")
+	}
+}
+
+func TestOpenMetadata(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.png")
+
+	err = ioutil.WriteFile(filepath, exiftest.WrapPng(getTestExifData()), 0644)
+	log.PanicIf(err)
+
+	md, err := OpenMetadata(filepath)
+	log.PanicIf(err)
+
+	if md.Kind != KindPng {
+		t.Fatalf("Kind not correct: [%s]", md.Kind)
+	} else if _, err := md.Exif(); err != nil {
+		t.Fatalf("EXIF not read: %v", err)
+	} else if _, err := md.Xmp(); err != ErrNoXmp {
+		t.Fatalf("Expected ErrNoXmp: %v", err)
+	} else if _, err := md.IccProfile(); err != ErrNoIccProfile {
+		t.Fatalf("Expected ErrNoIccProfile: %v", err)
+	}
+
+	err = ioutil.WriteFile(filepath, []byte("not media"), 0644)
+	log.PanicIf(err)
+
+	if _, err := OpenMetadata(filepath); err != ErrMediaFormatNotFound {
+		t.Fatalf("Expected ErrMediaFormatNotFound: %v", err)
+	}
+}
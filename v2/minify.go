@@ -0,0 +1,149 @@
+package exif
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// makerNoteTagId is the MakerNote tag in the EXIF IFD.
+	makerNoteTagId = 0x927c
+)
+
+// MinifyOptions changes what `MinifyExif()` removes.
+type MinifyOptions struct {
+	// PruneMakerNote removes the MakerNote, which is often the largest part
+	// of the EXIF but is only useful to the camera vendor's software.
+	PruneMakerNote bool
+}
+
+// MinifyResult describes what `MinifyExif()` did.
+type MinifyResult struct {
+	SizeBefore int
+	SizeAfter  int
+
+	EmptyTagsRemoved int
+	MakerNoteRemoved bool
+}
+
+// BytesSaved returns how much smaller the EXIF is.
+func (mr MinifyResult) BytesSaved() int {
+	return mr.SizeBefore - mr.SizeAfter
+}
+
+// String returns a descriptive string.
+func (mr MinifyResult) String() string {
+	return fmt.Sprintf("MinifyResult<SIZE-BEFORE=(%d) SIZE-AFTER=(%d) EMPTY-TAGS-REMOVED=(%d) MAKER-NOTE-REMOVED=[%v]>", mr.SizeBefore, mr.SizeAfter, mr.EmptyTagsRemoved, mr.MakerNoteRemoved)
+}
+
+// isEmptyBuilderTagValue returns true if the tag has no value, or is an ASCII
+// value of only NULs and spaces.
+func isEmptyBuilderTagValue(bt *BuilderTag) bool {
+	if bt.value.IsBytes() == false {
+		return false
+	}
+
+	value := bt.value.Bytes()
+	if len(value) == 0 {
+		return true
+	}
+
+	return bt.typeId == exifcommon.TypeAscii && len(bytes.Trim(value, "\x00 ")) == 0
+}
+
+// minifyIb removes the empty tags (and, if requested, the MakerNote) from the
+// given IB, its child IBs, and the IBs chained after it.
+func minifyIb(ib *IfdBuilder, opts MinifyOptions, mr *MinifyResult) {
+	for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+		tags := make([]*BuilderTag, 0, len(thisIb.tags))
+
+		for _, bt := range thisIb.tags {
+			if bt.value.IsIb() == true {
+				minifyIb(bt.value.Ib(), opts, mr)
+			} else if opts.PruneMakerNote == true && thisIb.ifdPath == exifcommon.IfdPathStandardExif && bt.tagId == makerNoteTagId {
+				mr.MakerNoteRemoved = true
+				continue
+			} else if isEmptyBuilderTagValue(bt) == true {
+				mr.EmptyTagsRemoved++
+				continue
+			}
+
+			tags = append(tags, bt)
+		}
+
+		thisIb.tags = tags
+	}
+}
+
+// MinifyExif rewrites a raw EXIF block as small as it can be without losing
+// information: the data is packed without padding or unreferenced space,
+// identical ASCII values are stored once, and empty tags are dropped. The
+// MakerNote can also be dropped (see `MinifyOptions`), which is useful to
+// meet a size budget when delivering images on the web. The original is
+// returned if the result isn't smaller.
+func MinifyExif(rawExif []byte, opts MinifyOptions) (minified []byte, mr MinifyResult, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	mr = MinifyResult{
+		SizeBefore: len(rawExif),
+	}
+
+	minifyIb(rootIb, opts, &mr)
+
+	ibe := NewIfdByteEncoder()
+	ibe.SetDeduplicateAscii(true)
+
+	minified, err = ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	if len(minified) >= len(rawExif) {
+		return rawExif, MinifyResult{SizeBefore: len(rawExif), SizeAfter: len(rawExif)}, nil
+	}
+
+	mr.SizeAfter = len(minified)
+
+	return minified, mr, nil
+}
+
+// MinifyJpegExif returns a copy of the JPEG with its EXIF minified (see
+// `MinifyExif()`). The sizes in the result are of the EXIF, so
+// `BytesSaved()` is also how much smaller the JPEG is.
+func MinifyJpegExif(data []byte, opts MinifyOptions) (updated []byte, mr MinifyResult, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	segments := si.Find(JpegSegmentExif)
+	if len(segments) == 0 {
+		log.Panic(ErrNoExif)
+	}
+
+	segment := segments[0]
+	rawExif := data[segment.DataOffset : segment.DataOffset+segment.DataSize]
+
+	minified, mr, err := MinifyExif(rawExif, opts)
+	log.PanicIf(err)
+
+	updated, err = replaceJpegSegmentData(data, segment, minified)
+	log.PanicIf(err)
+
+	return updated, mr, nil
+}
@@ -0,0 +1,102 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestMinifiableExif() []byte {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: 0x0131, Value: "      "})
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, exiftest.Tag{Id: makerNoteTagId, Raw: bytes.Repeat([]byte{0xaa}, 100), Type: exifcommon.TypeUndefined})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return rawExif
+}
+
+func TestMinifyExif(t *testing.T) {
+	rawExif := getTestMinifiableExif()
+
+	minified, mr, err := MinifyExif(rawExif, MinifyOptions{})
+	log.PanicIf(err)
+
+	if mr.EmptyTagsRemoved != 1 || mr.MakerNoteRemoved == true {
+		t.Fatalf("Result not correct: %s", mr)
+	} else if mr.SizeBefore != len(rawExif) || mr.SizeAfter != len(minified) || mr.BytesSaved() <= 0 {
+		t.Fatalf("Sizes not correct: %s", mr)
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), minified)
+	log.PanicIf(err)
+
+	dateTime, err := index.RootIfd.FindTagWithName("DateTime")
+	log.PanicIf(err)
+
+	exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+	dateTimeOriginal, err := exifIfd.FindTagWithName("DateTimeOriginal")
+	log.PanicIf(err)
+
+	if dateTime[0].getValueOffset() != dateTimeOriginal[0].getValueOffset() {
+		t.Fatalf("Identical ASCII values not shared.")
+	}
+
+	md, err := DiffMetadata(rawExif, minified)
+	log.PanicIf(err)
+
+	if len(md.Tags) != 1 || md.Tags[0].TagName != "Software" || md.Tags[0].Kind != TagChangeRemoved {
+		t.Fatalf("Only the empty tag should have been removed: %v", md.Tags)
+	}
+}
+
+func TestMinifyExif_PruneMakerNote(t *testing.T) {
+	rawExif := getTestMinifiableExif()
+
+	kept, _, err := MinifyExif(rawExif, MinifyOptions{})
+	log.PanicIf(err)
+
+	pruned, mr, err := MinifyExif(rawExif, MinifyOptions{PruneMakerNote: true})
+	log.PanicIf(err)
+
+	if mr.MakerNoteRemoved == false {
+		t.Fatalf("MakerNote not removed: %s", mr)
+	} else if len(kept)-len(pruned) < 100 {
+		t.Fatalf("MakerNote data not removed: (%d) (%d)", len(kept), len(pruned))
+	}
+}
+
+func TestMinifyExif_AlreadyMinimal(t *testing.T) {
+	rawExif := getTestMinifiableExif()
+
+	minified, _, err := MinifyExif(rawExif, MinifyOptions{PruneMakerNote: true})
+	log.PanicIf(err)
+
+	again, mr, err := MinifyExif(minified, MinifyOptions{PruneMakerNote: true})
+	log.PanicIf(err)
+
+	if bytes.Equal(again, minified) == false || mr.BytesSaved() != 0 {
+		t.Fatalf("Minimal EXIF changed: %s", mr)
+	}
+}
+
+func TestMinifyJpegExif(t *testing.T) {
+	data := exiftest.WrapJpeg(getTestMinifiableExif())
+
+	updated, mr, err := MinifyJpegExif(data, MinifyOptions{PruneMakerNote: true})
+	log.PanicIf(err)
+
+	if len(data)-len(updated) != mr.BytesSaved() {
+		t.Fatalf("JPEG size not correct: (%d) (%d) %s", len(data), len(updated), mr)
+	}
+}
@@ -0,0 +1,195 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strconv"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// MotionPhotoGoogleMicroVideo is the original Google format, described by
+	// the GCamera:MicroVideo* XMP properties.
+	MotionPhotoGoogleMicroVideo = "google-microvideo"
+
+	// MotionPhotoGoogle is the current Google/Android format, described by
+	// GCamera:MotionPhoto and an XMP container directory.
+	MotionPhotoGoogle = "google-motionphoto"
+
+	// MotionPhotoSamsung is a Samsung image with the video in the SEF trailer.
+	MotionPhotoSamsung = "samsung-sef"
+)
+
+var (
+	// ErrNoMotionPhoto means that there's no embedded video.
+	ErrNoMotionPhoto = errors.New("not a motion photo")
+)
+
+var (
+	samsungTrailerSignature   = []byte("SEFT")
+	samsungDirectorySignature = []byte("SEFH")
+	samsungMotionPhotoName    = []byte("MotionPhoto_Data")
+
+	microVideoOffsetRe   = regexp.MustCompile(`GCamera:MicroVideoOffset(?:="|>)(\d+)`)
+	containerItemRe      = regexp.MustCompile(`<Container:Item\b[^>]*>`)
+	itemSemanticVideoRe  = regexp.MustCompile(`Item:Semantic="MotionPhoto"`)
+	itemLengthRe         = regexp.MustCompile(`Item:Length="(\d+)"`)
+	motionPhotoPresentRe = regexp.MustCompile(`GCamera:MotionPhoto(?:="|>)1`)
+)
+
+// MotionPhoto describes the video embedded in a still image.
+type MotionPhoto struct {
+	// Kind is one of the MotionPhoto* constants.
+	Kind string
+
+	// VideoOffset is the position of the video from the start of the file.
+	VideoOffset int64
+
+	// VideoLength is the size of the video.
+	VideoLength int64
+}
+
+// Video returns the embedded video from the file data.
+func (mp MotionPhoto) Video(data []byte) []byte {
+	return data[mp.VideoOffset : mp.VideoOffset+mp.VideoLength]
+}
+
+// DetectMotionPhoto looks for a video embedded in the given JPEG file data
+// and returns where it is. `ErrNoMotionPhoto` is returned if there isn't one.
+// Google's formats are described in the XMP while Samsung's is described in
+// a trailer at the end of the file. In all cases, the video is checked to
+// start with an MP4 header.
+func DetectMotionPhoto(data []byte) (mp MotionPhoto, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fileSize := int64(len(data))
+
+	for _, xmp := range jpegXmpPackets(data) {
+		if matches := microVideoOffsetRe.FindSubmatch(xmp); matches != nil {
+			length, err := strconv.ParseInt(string(matches[1]), 10, 64)
+			if err == nil && length > 0 && length <= fileSize {
+				mp = MotionPhoto{
+					Kind:        MotionPhotoGoogleMicroVideo,
+					VideoOffset: fileSize - length,
+					VideoLength: length,
+				}
+
+				if isMp4(data, mp) == true {
+					return mp, nil
+				}
+			}
+		}
+
+		if motionPhotoPresentRe.Match(xmp) == true {
+			for _, item := range containerItemRe.FindAll(xmp, -1) {
+				if itemSemanticVideoRe.Match(item) == false {
+					continue
+				}
+
+				matches := itemLengthRe.FindSubmatch(item)
+				if matches == nil {
+					continue
+				}
+
+				length, err := strconv.ParseInt(string(matches[1]), 10, 64)
+				if err != nil || length <= 0 || length > fileSize {
+					continue
+				}
+
+				mp = MotionPhoto{
+					Kind:        MotionPhotoGoogle,
+					VideoOffset: fileSize - length,
+					VideoLength: length,
+				}
+
+				if isMp4(data, mp) == true {
+					return mp, nil
+				}
+			}
+		}
+	}
+
+	mp, found := findSamsungMotionPhoto(data)
+	if found == true && isMp4(data, mp) == true {
+		return mp, nil
+	}
+
+	return MotionPhoto{}, ErrNoMotionPhoto
+}
+
+// findSamsungMotionPhoto reads the SEF trailer, which is a directory of
+// blocks that precede it. The trailer ends with the directory size and
+// "SEFT". Each directory entry has the distance back from the directory to
+// the block and the size of the block. The block has a short header and the
+// name, and then the data.
+func findSamsungMotionPhoto(data []byte) (mp MotionPhoto, found bool) {
+	fileSize := int64(len(data))
+	if fileSize < 8 || bytes.Equal(data[fileSize-4:], samsungTrailerSignature) == false {
+		return mp, false
+	}
+
+	directorySize := int64(binary.LittleEndian.Uint32(data[fileSize-8:]))
+	directoryOffset := fileSize - 8 - directorySize
+
+	if directoryOffset < 0 || directorySize < 12 || bytes.Equal(data[directoryOffset:directoryOffset+4], samsungDirectorySignature) == false {
+		return mp, false
+	}
+
+	entryCount := int64(binary.LittleEndian.Uint32(data[directoryOffset+8:]))
+
+	for i := int64(0); i < entryCount; i++ {
+		entryOffset := directoryOffset + 12 + i*12
+		if entryOffset+12 > fileSize-8 {
+			break
+		}
+
+		distance := int64(binary.LittleEndian.Uint32(data[entryOffset+4:]))
+		blockSize := int64(binary.LittleEndian.Uint32(data[entryOffset+8:]))
+		blockOffset := directoryOffset - distance
+
+		if blockOffset < 0 || blockSize < 8 || blockOffset+blockSize > directoryOffset {
+			continue
+		}
+
+		nameLength := int64(binary.LittleEndian.Uint32(data[blockOffset+4:]))
+		if 8+nameLength > blockSize {
+			continue
+		}
+
+		name := data[blockOffset+8 : blockOffset+8+nameLength]
+		if bytes.Equal(name, samsungMotionPhotoName) == false {
+			continue
+		}
+
+		mp = MotionPhoto{
+			Kind:        MotionPhotoSamsung,
+			VideoOffset: blockOffset + 8 + nameLength,
+			VideoLength: blockSize - 8 - nameLength,
+		}
+
+		return mp, true
+	}
+
+	return mp, false
+}
+
+// isMp4 returns true if the video looks like it starts with an MP4 "ftyp"
+// box.
+func isMp4(data []byte, mp MotionPhoto) bool {
+	if mp.VideoLength < 8 || mp.VideoOffset+8 > int64(len(data)) {
+		return false
+	}
+
+	return bytes.Equal(data[mp.VideoOffset+4:mp.VideoOffset+8], []byte("ftyp"))
+}
@@ -0,0 +1,128 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	testMotionPhotoVideo = append([]byte{0, 0, 0, 0x18, 'f', 't', 'y', 'p', 'm', 'p', '4', '2'}, bytes.Repeat([]byte{0xaa}, 100)...)
+)
+
+// getTestMotionPhotoJpeg returns a JPEG with the given XMP followed by the
+// given trailer.
+func getTestMotionPhotoJpeg(xmp string, trailer []byte) []byte {
+	b := new(bytes.Buffer)
+
+	b.Write([]byte{jpegMarkerPrefix, jpegMarkerSoi})
+
+	payload := append([]byte{}, xmpNamespace...)
+	payload = append(payload, []byte(xmp)...)
+
+	b.Write([]byte{jpegMarkerPrefix, jpegMarkerApp1})
+
+	err := binary.Write(b, binary.BigEndian, uint16(2+len(payload)))
+	log.PanicIf(err)
+
+	b.Write(payload)
+
+	b.Write([]byte{jpegMarkerPrefix, jpegMarkerSos, 0, 2})
+	b.Write([]byte{0x11, 0x22, 0x33})
+	b.Write([]byte{jpegMarkerPrefix, jpegMarkerEoi})
+
+	b.Write(trailer)
+
+	return b.Bytes()
+}
+
+func TestDetectMotionPhoto_MicroVideo(t *testing.T) {
+	xmp := `<rdf:Description GCamera:MicroVideo="1" GCamera:MicroVideoVersion="1" GCamera:MicroVideoOffset="112"/>`
+
+	data := getTestMotionPhotoJpeg(xmp, testMotionPhotoVideo)
+
+	mp, err := DetectMotionPhoto(data)
+	log.PanicIf(err)
+
+	if mp.Kind != MotionPhotoGoogleMicroVideo {
+		t.Fatalf("Kind not correct: [%s]", mp.Kind)
+	} else if bytes.Equal(mp.Video(data), testMotionPhotoVideo) != true {
+		t.Fatalf("Video not correct: %v", mp)
+	}
+}
+
+func TestDetectMotionPhoto_MotionPhoto(t *testing.T) {
+	xmp := `<rdf:Description GCamera:MotionPhoto="1" GCamera:MotionPhotoVersion="1">
+<Container:Directory><rdf:Seq>
+<rdf:li rdf:parseType="Resource"><Container:Item Item:Mime="image/jpeg" Item:Semantic="Primary" Item:Length="0" Item:Padding="0"/></rdf:li>
+<rdf:li rdf:parseType="Resource"><Container:Item Item:Mime="video/mp4" Item:Semantic="MotionPhoto" Item:Length="112"/></rdf:li>
+</rdf:Seq></Container:Directory></rdf:Description>`
+
+	data := getTestMotionPhotoJpeg(xmp, testMotionPhotoVideo)
+
+	mp, err := DetectMotionPhoto(data)
+	log.PanicIf(err)
+
+	if mp.Kind != MotionPhotoGoogle {
+		t.Fatalf("Kind not correct: [%s]", mp.Kind)
+	} else if bytes.Equal(mp.Video(data), testMotionPhotoVideo) != true {
+		t.Fatalf("Video not correct: %v", mp)
+	}
+}
+
+func TestDetectMotionPhoto_Samsung(t *testing.T) {
+	trailer := new(bytes.Buffer)
+
+	// The data block.
+
+	blockSize := 8 + len(samsungMotionPhotoName) + len(testMotionPhotoVideo)
+
+	trailer.Write([]byte{0, 0, 0x30, 0x0a})
+	binary.Write(trailer, binary.LittleEndian, uint32(len(samsungMotionPhotoName)))
+	trailer.Write(samsungMotionPhotoName)
+	trailer.Write(testMotionPhotoVideo)
+
+	// The directory.
+
+	directory := new(bytes.Buffer)
+	directory.Write(samsungDirectorySignature)
+	binary.Write(directory, binary.LittleEndian, uint32(106))
+	binary.Write(directory, binary.LittleEndian, uint32(1))
+
+	directory.Write([]byte{0, 0, 0x30, 0x0a})
+	binary.Write(directory, binary.LittleEndian, uint32(blockSize))
+	binary.Write(directory, binary.LittleEndian, uint32(blockSize))
+
+	trailer.Write(directory.Bytes())
+	binary.Write(trailer, binary.LittleEndian, uint32(directory.Len()))
+	trailer.Write(samsungTrailerSignature)
+
+	data := getTestMotionPhotoJpeg("<rdf:Description/>", trailer.Bytes())
+
+	mp, err := DetectMotionPhoto(data)
+	log.PanicIf(err)
+
+	if mp.Kind != MotionPhotoSamsung {
+		t.Fatalf("Kind not correct: [%s]", mp.Kind)
+	} else if bytes.Equal(mp.Video(data), testMotionPhotoVideo) != true {
+		t.Fatalf("Video not correct: %v", mp)
+	}
+}
+
+func TestDetectMotionPhoto_NotMotionPhoto(t *testing.T) {
+	// The XMP claims a video but there isn't one.
+	xmp := `<rdf:Description GCamera:MicroVideo="1" GCamera:MicroVideoOffset="10"/>`
+
+	data := getTestMotionPhotoJpeg(xmp, nil)
+
+	_, err := DetectMotionPhoto(data)
+	if err != ErrNoMotionPhoto {
+		t.Fatalf("Expected ErrNoMotionPhoto: %v", err)
+	}
+}
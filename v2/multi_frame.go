@@ -0,0 +1,380 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+	"sort"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// webpAnimationFlag is the bit of the VP8X flags that says that the image
+	// is animated.
+	webpAnimationFlag = 0x02
+)
+
+var (
+	// heifMetadataItemTypes are the item types that aren't images.
+	heifMetadataItemTypes = map[string]bool{
+		"Exif": true,
+		"mime": true,
+		"uri ": true,
+	}
+)
+
+// FrameScope says which frames of a file an EXIF block applies to.
+type FrameScope int
+
+const (
+	// FrameScopeAllFrames means that the EXIF applies to the file as a whole,
+	// and so to every frame (e.g. the EXIF chunk of an animated WebP, which
+	// can't say anything about the frames individually).
+	FrameScopeAllFrames FrameScope = iota
+
+	// FrameScopeItems means that the EXIF applies to specific image items of
+	// a HEIF file (those that its "Exif" item has a "cdsc" reference to).
+	FrameScopeItems
+)
+
+// String returns the name of the scope.
+func (fs FrameScope) String() string {
+	switch fs {
+	case FrameScopeAllFrames:
+		return "all-frames"
+	case FrameScopeItems:
+		return "items"
+	}
+
+	return fmt.Sprintf("FrameScope(%d)", int(fs))
+}
+
+// FrameExif is one EXIF block of a file and what it applies to.
+type FrameExif struct {
+	Scope FrameScope
+
+	// ExifItemId is the HEIF "Exif" item that has the EXIF, and ItemIds are
+	// the image items that it describes. Both are zero for other containers.
+	ExifItemId uint32
+	ItemIds    []uint32
+
+	RawExif []byte
+}
+
+// String returns a descriptive string.
+func (fe FrameExif) String() string {
+	return fmt.Sprintf("FrameExif<SCOPE=[%s] EXIF-ITEM=(%d) ITEMS=%v SIZE=(%d)>", fe.Scope, fe.ExifItemId, fe.ItemIds, len(fe.RawExif))
+}
+
+// FrameLayout is how the frames of a file are laid out and which of them each
+// EXIF block applies to.
+type FrameLayout struct {
+	Kind Kind
+
+	// FrameCount is how many frames there are: those of an animated WebP or
+	// PNG, the samples of the image sequence of a HEIF (e.g. an AVIF
+	// animation), or otherwise the image items of a HEIF. It's one for a
+	// still image.
+	FrameCount int
+
+	// IsAnimated is true if the frames are played in sequence (an animated
+	// WebP or PNG, or a HEIF with an image sequence) rather than being
+	// separate images.
+	IsAnimated bool
+
+	// PrimaryItemId and ItemIds are the primary image item and the image
+	// items of a HEIF that are shown on their own (not thumbnails, auxiliary
+	// images, or the tiles of a grid), in the order of their IDs.
+	PrimaryItemId uint32
+	ItemIds       []uint32
+
+	// BurstGroups are the image items of each "brst" group of a HEIF (a
+	// burst), in the order of the burst.
+	BurstGroups [][]uint32
+
+	// Exif are the EXIF blocks. A HEIF can have one per item, in the order
+	// of their item IDs, and other containers have at most one.
+	Exif []FrameExif
+}
+
+// ExifFor returns the EXIF block that applies to the image item of a HEIF or,
+// for other containers, to every frame. A block that applies to the whole
+// file is returned if none describes the item specifically.
+func (fl FrameLayout) ExifFor(itemId uint32) (fe FrameExif, found bool) {
+	for _, fe := range fl.Exif {
+		if fe.Scope != FrameScopeItems {
+			continue
+		}
+
+		for _, describedItemId := range fe.ItemIds {
+			if describedItemId == itemId {
+				return fe, true
+			}
+		}
+	}
+
+	for _, fe := range fl.Exif {
+		if fe.Scope == FrameScopeAllFrames {
+			return fe, true
+		}
+	}
+
+	return fe, false
+}
+
+// GetFrameLayout returns the frames of the file and which of them each EXIF
+// block applies to, so that the frames of a burst or an animation aren't
+// flattened into a single, ambiguous set of tags. The "Exif" items of a HEIF
+// each describe the image items that they have a "cdsc" reference to (or the
+// whole file, if they don't have one), and `GetHeifItemExif()` and
+// `SetHeifItemExif()` read and write them. The EXIF of an animated WebP or PNG
+// always applies to every frame, since those containers have nowhere to put
+// any other. A file without EXIF has an empty `Exif`, and
+// `ErrMediaFormatNotFound` is returned if the container isn't recognized.
+func GetFrameLayout(data []byte) (fl FrameLayout, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	mf, err := SniffMediaFormat(data)
+	if err != nil {
+		return fl, err
+	}
+
+	fl = FrameLayout{
+		Kind:        mf.Kind(),
+		FrameCount:  1,
+		ItemIds:     make([]uint32, 0),
+		BurstGroups: make([][]uint32, 0),
+		Exif:        make([]FrameExif, 0),
+	}
+
+	switch fl.Kind {
+	case KindHeif:
+		err := getHeifFrameLayout(data, &fl)
+		log.PanicIf(err)
+
+		return fl, nil
+	case KindWebp:
+		err := getWebpFrameLayout(data, &fl)
+		log.PanicIf(err)
+	case KindPng:
+		// The "acTL" chunk of an animated PNG has the number of frames.
+		if chunks := getPngChunks(data, "acTL"); len(chunks) > 0 && len(chunks[0]) >= 4 {
+			fl.FrameCount = int(binary.BigEndian.Uint32(chunks[0]))
+			fl.IsAnimated = true
+		}
+	}
+
+	rawExif, err := mf.ExtractExif(data)
+	if err != nil {
+		if log.Is(err, ErrNoExif) == true {
+			return fl, nil
+		}
+
+		log.Panic(err)
+	}
+
+	fe := FrameExif{
+		Scope:   FrameScopeAllFrames,
+		RawExif: rawExif,
+	}
+
+	fl.Exif = append(fl.Exif, fe)
+
+	return fl, nil
+}
+
+// getWebpFrameLayout counts the "ANMF" chunks of an animated WebP.
+func getWebpFrameLayout(data []byte, fl *FrameLayout) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if len(data) < 12 {
+		return nil
+	}
+
+	chunks, err := parseRiffChunks(data[12:])
+	log.PanicIf(err)
+
+	frameCount := 0
+	for _, chunk := range chunks {
+		switch chunk.id {
+		case "VP8X":
+			if len(chunk.data) > 0 && chunk.data[0]&webpAnimationFlag != 0 {
+				fl.IsAnimated = true
+			}
+		case "ANIM":
+			fl.IsAnimated = true
+		case "ANMF":
+			frameCount++
+		}
+	}
+
+	if frameCount > 0 {
+		fl.FrameCount = frameCount
+	}
+
+	return nil
+}
+
+// getHeifFrameLayout reads the image items, bursts, image sequence, and
+// "Exif" items of a HEIF.
+func getHeifFrameLayout(data []byte, fl *FrameLayout) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	hm, err := readHeifMeta(data)
+	log.PanicIf(err)
+
+	if hm == nil {
+		return nil
+	}
+
+	fl.PrimaryItemId = hm.primaryItemId
+
+	// Thumbnails and auxiliary images refer to their image, and a derived
+	// image (e.g. a grid) refers to its inputs.
+	hidden := make(map[uint32]bool)
+	for _, hr := range hm.references {
+		switch hr.referenceType {
+		case "thmb", "auxl":
+			hidden[hr.fromItemId] = true
+		case "dimg":
+			for _, itemId := range hr.toItemIds {
+				hidden[itemId] = true
+			}
+		}
+	}
+
+	for itemId, itemType := range hm.itemTypes {
+		if heifMetadataItemTypes[itemType] == false && hidden[itemId] == false {
+			fl.ItemIds = append(fl.ItemIds, itemId)
+		}
+	}
+
+	sortItemIds(fl.ItemIds)
+
+	if len(fl.ItemIds) > 0 {
+		fl.FrameCount = len(fl.ItemIds)
+	}
+
+	for _, hg := range hm.groups {
+		if hg.groupType == "brst" {
+			fl.BurstGroups = append(fl.BurstGroups, hg.entityIds)
+		}
+	}
+
+	if sampleCount, found := getIsoImageSequenceSampleCount(data); found == true {
+		fl.FrameCount = sampleCount
+		fl.IsAnimated = true
+	}
+
+	exifItemIds := make([]uint32, 0)
+	for itemId, itemType := range hm.itemTypes {
+		if itemType == "Exif" {
+			exifItemIds = append(exifItemIds, itemId)
+		}
+	}
+
+	sortItemIds(exifItemIds)
+
+	for _, exifItemId := range exifItemIds {
+		rawExif, err := getHeifExifItemData(data, hm, exifItemId)
+		if err != nil {
+			if log.Is(err, ErrNoExif) == true {
+				continue
+			}
+
+			log.Panic(err)
+		}
+
+		fe := FrameExif{
+			Scope:      FrameScopeAllFrames,
+			ExifItemId: exifItemId,
+			RawExif:    rawExif,
+		}
+
+		for _, hr := range hm.references {
+			if hr.referenceType == "cdsc" && hr.fromItemId == exifItemId {
+				fe.Scope = FrameScopeItems
+				fe.ItemIds = append(fe.ItemIds, hr.toItemIds...)
+			}
+		}
+
+		fl.Exif = append(fl.Exif, fe)
+	}
+
+	return nil
+}
+
+// getIsoImageSequenceSampleCount returns the number of samples of the first
+// image-sequence ("pict") track of the "moov" box.
+func getIsoImageSequenceSampleCount(data []byte) (sampleCount int, found bool) {
+	moov, found := findIsoBox(readIsoBoxes(data, 0), "moov")
+	if found == false {
+		return 0, false
+	}
+
+	for _, trak := range readIsoBoxes(moov.payload, moov.offset) {
+		if trak.boxType != "trak" {
+			continue
+		}
+
+		mdia, found := findIsoBox(readIsoBoxes(trak.payload, trak.offset), "mdia")
+		if found == false {
+			continue
+		}
+
+		mdiaBoxes := readIsoBoxes(mdia.payload, mdia.offset)
+
+		// The handler type follows the full-box header and a reserved field.
+		hdlr, found := findIsoBox(mdiaBoxes, "hdlr")
+		if found == false || len(hdlr.payload) < 12 || string(hdlr.payload[8:12]) != "pict" {
+			continue
+		}
+
+		stsz, found := findIsoBoxPath(mdiaBoxes, "minf", "stbl", "stsz")
+		if found == false || len(stsz.payload) < 12 {
+			continue
+		}
+
+		// The full-box header and the default sample size come first.
+		return int(binary.BigEndian.Uint32(stsz.payload[8:])), true
+	}
+
+	return 0, false
+}
+
+// findIsoBoxPath returns the box at the given path of nested boxes.
+func findIsoBoxPath(boxes []isoBox, boxTypes ...string) (ib isoBox, found bool) {
+	for i, boxType := range boxTypes {
+		ib, found = findIsoBox(boxes, boxType)
+		if found == false {
+			return ib, false
+		} else if i < len(boxTypes)-1 {
+			boxes = readIsoBoxes(ib.payload, ib.offset)
+		}
+	}
+
+	return ib, found
+}
+
+// sortItemIds sorts the item IDs in place.
+func sortItemIds(itemIds []uint32) {
+	sort.Slice(itemIds, func(i, j int) bool {
+		return itemIds[i] < itemIds[j]
+	})
+}
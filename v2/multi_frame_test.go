@@ -0,0 +1,222 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestGetFrameLayout_HeifBurst(t *testing.T) {
+	exif1 := getTestMultiFrameExif("first")
+	exif2 := getTestMultiFrameExif("second")
+
+	fl, err := GetFrameLayout(getTestHeifBurst(exif1, exif2))
+	log.PanicIf(err)
+
+	if fl.Kind != KindHeif {
+		t.Fatalf("Kind not correct: [%s]", fl.Kind)
+	} else if fl.FrameCount != 2 || fl.IsAnimated != false {
+		t.Fatalf("Frames not correct: (%d) %v", fl.FrameCount, fl.IsAnimated)
+	} else if fl.PrimaryItemId != 1 || reflect.DeepEqual(fl.ItemIds, []uint32{1, 2}) != true {
+		t.Fatalf("Items not correct: (%d) %v", fl.PrimaryItemId, fl.ItemIds)
+	} else if reflect.DeepEqual(fl.BurstGroups, [][]uint32{{1, 2}}) != true {
+		t.Fatalf("Burst groups not correct: %v", fl.BurstGroups)
+	} else if len(fl.Exif) != 2 {
+		t.Fatalf("Expected two EXIF blocks: %v", fl.Exif)
+	}
+
+	expected := map[uint32][]byte{
+		1: exif1,
+		2: exif2,
+	}
+
+	for itemId, exifData := range expected {
+		fe, found := fl.ExifFor(itemId)
+		if found != true {
+			t.Fatalf("No EXIF for item (%d).", itemId)
+		} else if fe.Scope != FrameScopeItems || reflect.DeepEqual(fe.ItemIds, []uint32{itemId}) != true {
+			t.Fatalf("Scope of the EXIF of item (%d) not correct: %s", itemId, fe)
+		} else if bytes.Equal(fe.RawExif, exifData) != true {
+			t.Fatalf("EXIF of item (%d) not correct.", itemId)
+		}
+	}
+}
+
+func TestGetHeifItemExif(t *testing.T) {
+	exif1 := getTestMultiFrameExif("first")
+	exif2 := getTestMultiFrameExif("second")
+
+	data := getTestHeifBurst(exif1, exif2)
+
+	rawExif, err := GetHeifItemExif(data, 2)
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, exif2) != true {
+		t.Fatalf("EXIF of the second frame not correct.")
+	}
+
+	// The primary item's is what's read without an item.
+	rawExif, err = GetHeifExif(data)
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, exif1) != true {
+		t.Fatalf("EXIF of the primary item not correct.")
+	}
+}
+
+func TestGetHeifItemExif_NoExif(t *testing.T) {
+	data, _, _ := getTestHeic()
+
+	_, err := GetHeifItemExif(data, 1)
+	if err != ErrNoExif {
+		t.Fatalf("Expected ErrNoExif: %v", err)
+	}
+}
+
+func TestSetHeifItemExif(t *testing.T) {
+	exif1 := getTestMultiFrameExif("first")
+	exif2 := getTestMultiFrameExif("second")
+
+	data := getTestHeifBurst(exif1, exif2)
+
+	// This doesn't fit in the existing item.
+	replacement := getTestMultiFrameExif("a much longer replacement")
+
+	updated, err := SetHeifItemExif(data, 2, replacement)
+	log.PanicIf(err)
+
+	rawExif, err := GetHeifItemExif(updated, 2)
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, replacement) != true {
+		t.Fatalf("EXIF of the second frame not replaced.")
+	}
+
+	rawExif, err = GetHeifItemExif(updated, 1)
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, exif1) != true {
+		t.Fatalf("EXIF of the first frame should not have changed.")
+	}
+}
+
+func TestSetHeifItemExif_AddItem(t *testing.T) {
+	primaryExif := getTestMultiFrameExif("primary")
+	thumbnailExif := getTestMultiFrameExif("thumbnail")
+
+	data, _, _ := getTestHeic()
+
+	updated, err := SetHeifExif(data, primaryExif)
+	log.PanicIf(err)
+
+	// The primary item's block is referenced to it, so the thumbnail gets one
+	// of its own.
+	updated, err = SetHeifItemExif(updated, 3, thumbnailExif)
+	log.PanicIf(err)
+
+	hm, err := readHeifMeta(updated)
+	log.PanicIf(err)
+
+	if hm.itemTypes[5] != "Exif" || hm.describes(5, 3) != true {
+		t.Fatalf("EXIF item for the thumbnail not added: %v %v", hm.itemTypes, hm.references)
+	}
+
+	rawExif, err := GetHeifItemExif(updated, 3)
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, thumbnailExif) != true {
+		t.Fatalf("EXIF of the thumbnail not correct.")
+	}
+
+	rawExif, err = GetHeifExif(updated)
+	log.PanicIf(err)
+
+	if bytes.Equal(rawExif, primaryExif) != true {
+		t.Fatalf("EXIF of the primary item should not have changed.")
+	}
+}
+
+func TestSetHeifItemExif_NotImage(t *testing.T) {
+	data := getTestHeifBurst(getTestMultiFrameExif("first"), getTestMultiFrameExif("second"))
+
+	_, err := SetHeifItemExif(data, 3, getTestMultiFrameExif("third"))
+	if err == nil {
+		t.Fatalf("Expected error for an EXIF item.")
+	}
+}
+
+func TestGetFrameLayout_ImageSequence(t *testing.T) {
+	data, _, _ := getTestHeic()
+
+	hdlr := getTestIsoFullBox("hdlr", 0, 0, getTestIsoUints(4, 0), []byte("pict"), make([]byte, 13))
+	stsz := getTestIsoFullBox("stsz", 0, 0, getTestIsoUints(4, 0, 5))
+	minf := getTestIsoBox("minf", getTestIsoBox("stbl", stsz))
+	trak := getTestIsoBox("trak", getTestIsoBox("mdia", hdlr, minf))
+
+	data = append(data, getTestIsoBox("moov", trak)...)
+
+	fl, err := GetFrameLayout(data)
+	log.PanicIf(err)
+
+	// The thumbnail and the gain map aren't frames of their own.
+	if fl.FrameCount != 5 || fl.IsAnimated != true {
+		t.Fatalf("Frames not correct: (%d) %v", fl.FrameCount, fl.IsAnimated)
+	} else if reflect.DeepEqual(fl.ItemIds, []uint32{1}) != true {
+		t.Fatalf("Items not correct: %v", fl.ItemIds)
+	} else if len(fl.Exif) != 0 {
+		t.Fatalf("Expected no EXIF: %v", fl.Exif)
+	}
+}
+
+func TestGetFrameLayout_AnimatedWebp(t *testing.T) {
+	exifData := getTestExifData()
+
+	body := new(bytes.Buffer)
+	body.WriteString("WEBP")
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpAnimationFlag | webpExifFlag
+
+	writeTestRiffChunk(body, "VP8X", vp8x)
+	writeTestRiffChunk(body, "ANIM", make([]byte, 6))
+
+	for i := 0; i < 3; i++ {
+		writeTestRiffChunk(body, "ANMF", make([]byte, 16))
+	}
+
+	writeTestRiffChunk(body, "EXIF", exifData)
+
+	webp := new(bytes.Buffer)
+	writeTestRiffChunk(webp, "RIFF", body.Bytes())
+
+	fl, err := GetFrameLayout(webp.Bytes())
+	log.PanicIf(err)
+
+	if fl.Kind != KindWebp {
+		t.Fatalf("Kind not correct: [%s]", fl.Kind)
+	} else if fl.FrameCount != 3 || fl.IsAnimated != true {
+		t.Fatalf("Frames not correct: (%d) %v", fl.FrameCount, fl.IsAnimated)
+	} else if len(fl.Exif) != 1 || fl.Exif[0].Scope != FrameScopeAllFrames || bytes.Equal(fl.Exif[0].RawExif, exifData) != true {
+		t.Fatalf("EXIF not correct: %v", fl.Exif)
+	}
+
+	if fe, found := fl.ExifFor(0); found != true || fe.Scope != FrameScopeAllFrames {
+		t.Fatalf("EXIF should apply to every frame.")
+	}
+}
+
+func TestGetFrameLayout_Still(t *testing.T) {
+	fl, err := GetFrameLayout(getTestJpegWithExif())
+	log.PanicIf(err)
+
+	if fl.Kind != KindJpeg || fl.FrameCount != 1 || fl.IsAnimated != false {
+		t.Fatalf("Layout not correct: %v", fl)
+	} else if len(fl.Exif) != 1 || fl.Exif[0].Scope != FrameScopeAllFrames {
+		t.Fatalf("EXIF not correct: %v", fl.Exif)
+	}
+}
@@ -0,0 +1,56 @@
+package exif
+
+import (
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// OrientationTagId is the ID of the Orientation tag in IFD0.
+	OrientationTagId = 0x0112
+)
+
+// The values of the Orientation tag. The name describes where the first row
+// and column of the stored image should be displayed.
+const (
+	OrientationTopLeft     uint16 = 1
+	OrientationTopRight    uint16 = 2
+	OrientationBottomRight uint16 = 3
+	OrientationBottomLeft  uint16 = 4
+	OrientationLeftTop     uint16 = 5
+	OrientationRightTop    uint16 = 6
+	OrientationRightBottom uint16 = 7
+	OrientationLeftBottom  uint16 = 8
+)
+
+// OrientationRotation returns how to display an image with the given
+// Orientation: mirror it horizontally if `mirrored` is true and then rotate it
+// clockwise by `degrees` (0, 90, 180, or 270).
+func OrientationRotation(orientation uint16) (degrees int, mirrored bool, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	switch orientation {
+	case OrientationTopLeft:
+		return 0, false, nil
+	case OrientationTopRight:
+		return 0, true, nil
+	case OrientationBottomRight:
+		return 180, false, nil
+	case OrientationBottomLeft:
+		return 180, true, nil
+	case OrientationLeftTop:
+		return 270, true, nil
+	case OrientationRightTop:
+		return 90, false, nil
+	case OrientationRightBottom:
+		return 90, true, nil
+	case OrientationLeftBottom:
+		return 270, false, nil
+	}
+
+	log.Panicf("orientation not valid: (%d)", orientation)
+	return 0, false, nil
+}
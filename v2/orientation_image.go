@@ -0,0 +1,68 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"image"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// ApplyOrientation returns the image as it's meant to be displayed given the
+// value of its Orientation tag. The image is returned as-is if no change is
+// required. Otherwise, a new image is returned. Orientations 5 through 8 swap
+// the width and height.
+func ApplyOrientation(img image.Image, orientation uint16) (oriented image.Image, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if orientation == OrientationTopLeft {
+		return img, nil
+	} else if orientation < OrientationTopLeft || orientation > OrientationLeftBottom {
+		log.Panicf("orientation not valid: (%d)", orientation)
+	}
+
+	bounds := img.Bounds()
+	w := bounds.Dx()
+	h := bounds.Dy()
+
+	// Maps a position in the output to a position in the original.
+	var source func(x, y int) (int, int)
+
+	switch orientation {
+	case OrientationTopRight:
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case OrientationBottomRight:
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case OrientationBottomLeft:
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case OrientationLeftTop:
+		source = func(x, y int) (int, int) { return y, x }
+	case OrientationRightTop:
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case OrientationRightBottom:
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case OrientationLeftBottom:
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+
+	outputW, outputH := w, h
+	if orientation >= OrientationLeftTop {
+		outputW, outputH = h, w
+	}
+
+	output := image.NewRGBA64(image.Rect(0, 0, outputW, outputH))
+
+	for y := 0; y < outputH; y++ {
+		for x := 0; x < outputW; x++ {
+			sourceX, sourceY := source(x, y)
+			output.Set(x, y, img.At(bounds.Min.X+sourceX, bounds.Min.Y+sourceY))
+		}
+	}
+
+	return output, nil
+}
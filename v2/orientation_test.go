@@ -0,0 +1,122 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+	"image"
+	"testing"
+
+	"image/color"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// getOrientationTestImage returns a 3x2 image where every pixel is unique:
+//
+//	0 1 2
+//	3 4 5
+func getOrientationTestImage() image.Image {
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+
+	for i := 0; i < 6; i++ {
+		img.SetGray(i%3, i/3, color.Gray{Y: uint8(i)})
+	}
+
+	return img
+}
+
+func getOrientationTestPixels(img image.Image) [][]uint8 {
+	bounds := img.Bounds()
+	rows := make([][]uint8, bounds.Dy())
+
+	for y := 0; y < bounds.Dy(); y++ {
+		rows[y] = make([]uint8, bounds.Dx())
+
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+			rows[y][x] = c.Y
+		}
+	}
+
+	return rows
+}
+
+func TestApplyOrientation(t *testing.T) {
+	expected := map[uint16][][]uint8{
+		OrientationTopLeft:     {{0, 1, 2}, {3, 4, 5}},
+		OrientationTopRight:    {{2, 1, 0}, {5, 4, 3}},
+		OrientationBottomRight: {{5, 4, 3}, {2, 1, 0}},
+		OrientationBottomLeft:  {{3, 4, 5}, {0, 1, 2}},
+		OrientationLeftTop:     {{0, 3}, {1, 4}, {2, 5}},
+		OrientationRightTop:    {{3, 0}, {4, 1}, {5, 2}},
+		OrientationRightBottom: {{5, 2}, {4, 1}, {3, 0}},
+		OrientationLeftBottom:  {{2, 5}, {1, 4}, {0, 3}},
+	}
+
+	img := getOrientationTestImage()
+
+	for orientation, rows := range expected {
+		oriented, err := ApplyOrientation(img, orientation)
+		log.PanicIf(err)
+
+		actual := getOrientationTestPixels(oriented)
+
+		if len(actual) != len(rows) {
+			t.Fatalf("Orientation (%d) height not correct: (%d)", orientation, len(actual))
+		}
+
+		for y, row := range rows {
+			for x, value := range row {
+				if actual[y][x] != value {
+					t.Fatalf("Orientation (%d) not correct: %v != %v", orientation, actual, rows)
+				}
+			}
+		}
+	}
+}
+
+func TestApplyOrientation_Invalid(t *testing.T) {
+	_, err := ApplyOrientation(getOrientationTestImage(), 9)
+	if err == nil {
+		t.Fatalf("Expected error for invalid orientation.")
+	}
+}
+
+func TestOrientationRotation(t *testing.T) {
+	// Rotating clockwise by these is the same as applying them.
+	rotations := map[int]uint16{
+		0:   OrientationTopLeft,
+		90:  OrientationRightTop,
+		180: OrientationBottomRight,
+		270: OrientationLeftBottom,
+	}
+
+	img := getOrientationTestImage()
+
+	for orientation := OrientationTopLeft; orientation <= OrientationLeftBottom; orientation++ {
+		degrees, mirrored, err := OrientationRotation(orientation)
+		log.PanicIf(err)
+
+		displayed := img
+		if mirrored == true {
+			displayed, err = ApplyOrientation(displayed, OrientationTopRight)
+			log.PanicIf(err)
+		}
+
+		displayed, err = ApplyOrientation(displayed, rotations[degrees])
+		log.PanicIf(err)
+
+		expected, err := ApplyOrientation(img, orientation)
+		log.PanicIf(err)
+
+		if fmt.Sprintf("%v", getOrientationTestPixels(displayed)) != fmt.Sprintf("%v", getOrientationTestPixels(expected)) {
+			t.Fatalf("Rotation for orientation (%d) not correct: (%d) [%v]", orientation, degrees, mirrored)
+		}
+	}
+
+	if _, _, err := OrientationRotation(0); err == nil {
+		t.Fatalf("Expected error for invalid orientation.")
+	}
+}
@@ -0,0 +1,235 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// GPanoNamespace is the XMP namespace of the Google Photo Sphere
+	// properties.
+	GPanoNamespace = "http://ns.google.com/photos/1.0/panorama/"
+
+	// ProjectionEquirectangular is the only projection-type that viewers
+	// generally support.
+	ProjectionEquirectangular = "equirectangular"
+)
+
+var (
+	panoramaLogger = log.NewLogger("exif.panorama")
+)
+
+var (
+	// ErrNoPanorama means that there are no GPano properties.
+	ErrNoPanorama = errors.New("no panorama metadata")
+)
+
+var (
+	gpanoPropertyRe   = regexp.MustCompile(`GPano:(\w+)(?:="([^"]*)"|>([^<]*)</GPano:\w+>)`)
+	gpanoAttributeRe  = regexp.MustCompile(`\s+GPano:\w+="[^"]*"`)
+	gpanoElementRe    = regexp.MustCompile(`\s*<GPano:(\w+)>[^<]*</GPano:\w+>`)
+	gpanoNamespaceRe  = regexp.MustCompile(`\s+xmlns:GPano="[^"]*"`)
+	rdfDescriptionRe  = regexp.MustCompile(`<rdf:Description\b`)
+	xmpPacketTemplate = "<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n<rdf:Description rdf:about=\"\"%s/>\n</rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>"
+)
+
+// PanoramaInfo is the Photo Sphere (GPano) metadata that tells 360° viewers
+// how to display an image. The cropped-area fields describe where the image
+// sits within the full panorama when it doesn't cover the whole sphere.
+type PanoramaInfo struct {
+	UsePanoramaViewer bool
+	ProjectionType    string
+
+	FullPanoWidthPixels  int
+	FullPanoHeightPixels int
+
+	CroppedAreaImageWidthPixels  int
+	CroppedAreaImageHeightPixels int
+	CroppedAreaLeftPixels        int
+	CroppedAreaTopPixels         int
+
+	// PoseHeadingDegrees is the compass heading of the center of the image.
+	// Only valid if `HasPoseHeading` is true.
+	PoseHeadingDegrees float64
+	HasPoseHeading     bool
+}
+
+// String returns a descriptive string.
+func (pi PanoramaInfo) String() string {
+	return fmt.Sprintf("PanoramaInfo<PROJECTION=[%s] FULL=(%dx%d) CROPPED=(%dx%d) AT=(%d,%d)>", pi.ProjectionType, pi.FullPanoWidthPixels, pi.FullPanoHeightPixels, pi.CroppedAreaImageWidthPixels, pi.CroppedAreaImageHeightPixels, pi.CroppedAreaLeftPixels, pi.CroppedAreaTopPixels)
+}
+
+// ParsePanoramaInfo reads the GPano properties from an XMP packet. Both the
+// attribute and element forms are supported. `ErrNoPanorama` is returned if
+// there aren't any.
+func ParsePanoramaInfo(xmp []byte) (pi PanoramaInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	matches := gpanoPropertyRe.FindAllSubmatch(xmp, -1)
+	if len(matches) == 0 {
+		return pi, ErrNoPanorama
+	}
+
+	for _, match := range matches {
+		name := string(match[1])
+
+		value := string(match[2])
+		if match[3] != nil {
+			value = string(match[3])
+		}
+
+		switch name {
+		case "UsePanoramaViewer":
+			pi.UsePanoramaViewer = value == "True" || value == "true"
+		case "ProjectionType":
+			pi.ProjectionType = value
+		case "FullPanoWidthPixels":
+			pi.FullPanoWidthPixels = gpanoInt(name, value)
+		case "FullPanoHeightPixels":
+			pi.FullPanoHeightPixels = gpanoInt(name, value)
+		case "CroppedAreaImageWidthPixels":
+			pi.CroppedAreaImageWidthPixels = gpanoInt(name, value)
+		case "CroppedAreaImageHeightPixels":
+			pi.CroppedAreaImageHeightPixels = gpanoInt(name, value)
+		case "CroppedAreaLeftPixels":
+			pi.CroppedAreaLeftPixels = gpanoInt(name, value)
+		case "CroppedAreaTopPixels":
+			pi.CroppedAreaTopPixels = gpanoInt(name, value)
+		case "PoseHeadingDegrees":
+			heading, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				panoramaLogger.Warningf(nil, "GPano property [%s] not valid: [%s]", name, value)
+				continue
+			}
+
+			pi.PoseHeadingDegrees = heading
+			pi.HasPoseHeading = true
+		}
+	}
+
+	return pi, nil
+}
+
+func gpanoInt(name, value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		panoramaLogger.Warningf(nil, "GPano property [%s] not valid: [%s]", name, value)
+		return 0
+	}
+
+	return n
+}
+
+// GetPanoramaInfo reads the GPano properties from the XMP in a JPEG.
+func GetPanoramaInfo(data []byte) (pi PanoramaInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	xmp, err := GetJpegXmp(data)
+	if err != nil {
+		if err == ErrNoXmp {
+			return pi, ErrNoPanorama
+		}
+
+		log.Panic(err)
+	}
+
+	pi, err = ParsePanoramaInfo(xmp)
+	if err != nil {
+		if err == ErrNoPanorama {
+			return pi, err
+		}
+
+		log.Panic(err)
+	}
+
+	return pi, nil
+}
+
+// attributes renders the properties as XMP attributes (with a leading
+// space).
+func (pi PanoramaInfo) attributes() string {
+	b := new(bytes.Buffer)
+
+	fmt.Fprintf(b, "\n    xmlns:GPano=\"%s\"", GPanoNamespace)
+
+	usePanoramaViewer := "False"
+	if pi.UsePanoramaViewer == true {
+		usePanoramaViewer = "True"
+	}
+
+	fmt.Fprintf(b, "\n    GPano:UsePanoramaViewer=\"%s\"", usePanoramaViewer)
+	fmt.Fprintf(b, "\n    GPano:ProjectionType=\"%s\"", pi.ProjectionType)
+	fmt.Fprintf(b, "\n    GPano:FullPanoWidthPixels=\"%d\"", pi.FullPanoWidthPixels)
+	fmt.Fprintf(b, "\n    GPano:FullPanoHeightPixels=\"%d\"", pi.FullPanoHeightPixels)
+	fmt.Fprintf(b, "\n    GPano:CroppedAreaImageWidthPixels=\"%d\"", pi.CroppedAreaImageWidthPixels)
+	fmt.Fprintf(b, "\n    GPano:CroppedAreaImageHeightPixels=\"%d\"", pi.CroppedAreaImageHeightPixels)
+	fmt.Fprintf(b, "\n    GPano:CroppedAreaLeftPixels=\"%d\"", pi.CroppedAreaLeftPixels)
+	fmt.Fprintf(b, "\n    GPano:CroppedAreaTopPixels=\"%d\"", pi.CroppedAreaTopPixels)
+
+	if pi.HasPoseHeading == true {
+		fmt.Fprintf(b, "\n    GPano:PoseHeadingDegrees=\"%s\"", strconv.FormatFloat(pi.PoseHeadingDegrees, 'f', -1, 64))
+	}
+
+	return b.String()
+}
+
+// MergeIntoXmp returns the XMP packet with the GPano properties replaced by
+// these. Other properties are left alone. A new packet is created if `xmp` is
+// empty or has no description.
+func (pi PanoramaInfo) MergeIntoXmp(xmp []byte) []byte {
+	location := rdfDescriptionRe.FindIndex(xmp)
+	if location == nil {
+		return []byte(fmt.Sprintf(xmpPacketTemplate, pi.attributes()))
+	}
+
+	// Remove the existing properties.
+
+	cleaned := gpanoElementRe.ReplaceAll(xmp, nil)
+	cleaned = gpanoAttributeRe.ReplaceAll(cleaned, nil)
+	cleaned = gpanoNamespaceRe.ReplaceAll(cleaned, nil)
+
+	location = rdfDescriptionRe.FindIndex(cleaned)
+
+	merged := make([]byte, 0, len(cleaned)+512)
+	merged = append(merged, cleaned[:location[1]]...)
+	merged = append(merged, pi.attributes()...)
+	merged = append(merged, cleaned[location[1]:]...)
+
+	return merged
+}
+
+// SetPanoramaInfo returns a copy of the JPEG with the GPano properties set in
+// its XMP. Any other XMP properties are preserved.
+func SetPanoramaInfo(data []byte, pi PanoramaInfo) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	xmp, err := GetJpegXmp(data)
+	if err != nil && err != ErrNoXmp {
+		log.Panic(err)
+	}
+
+	updated, err = SetJpegXmp(data, pi.MergeIntoXmp(xmp))
+	log.PanicIf(err)
+
+	return updated, nil
+}
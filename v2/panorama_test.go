@@ -0,0 +1,126 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func getTestPanoramaInfo() PanoramaInfo {
+	return PanoramaInfo{
+		UsePanoramaViewer:            true,
+		ProjectionType:               ProjectionEquirectangular,
+		FullPanoWidthPixels:          8000,
+		FullPanoHeightPixels:         4000,
+		CroppedAreaImageWidthPixels:  8000,
+		CroppedAreaImageHeightPixels: 2000,
+		CroppedAreaLeftPixels:        0,
+		CroppedAreaTopPixels:         1000,
+		PoseHeadingDegrees:           90.5,
+		HasPoseHeading:               true,
+	}
+}
+
+func TestParsePanoramaInfo_Elements(t *testing.T) {
+	xmp := []byte(`<rdf:Description rdf:about="">
+<GPano:ProjectionType>equirectangular</GPano:ProjectionType>
+<GPano:FullPanoWidthPixels>4096</GPano:FullPanoWidthPixels>
+<GPano:UsePanoramaViewer>True</GPano:UsePanoramaViewer>
+</rdf:Description>`)
+
+	pi, err := ParsePanoramaInfo(xmp)
+	log.PanicIf(err)
+
+	if pi.ProjectionType != ProjectionEquirectangular || pi.FullPanoWidthPixels != 4096 || pi.UsePanoramaViewer != true {
+		t.Fatalf("Panorama not correct: %s", pi)
+	} else if pi.HasPoseHeading != false {
+		t.Fatalf("Heading should not be present.")
+	}
+}
+
+func TestParsePanoramaInfo_None(t *testing.T) {
+	_, err := ParsePanoramaInfo([]byte(`<rdf:Description rdf:about=""/>`))
+	if err != ErrNoPanorama {
+		t.Fatalf("Expected ErrNoPanorama: %v", err)
+	}
+}
+
+func TestPanoramaInfo_MergeIntoXmp(t *testing.T) {
+	original := []byte(`<x:xmpmeta><rdf:RDF><rdf:Description rdf:about="" xmlns:GPano="` + GPanoNamespace + `" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="5" GPano:ProjectionType="cylindrical">
+<GPano:FullPanoWidthPixels>10</GPano:FullPanoWidthPixels>
+</rdf:Description></rdf:RDF></x:xmpmeta>`)
+
+	expected := getTestPanoramaInfo()
+	merged := expected.MergeIntoXmp(original)
+
+	if bytes.Contains(merged, []byte(`xmp:Rating="5"`)) != true {
+		t.Fatalf("Other properties were not preserved: %s", merged)
+	} else if bytes.Contains(merged, []byte("cylindrical")) == true || bytes.Contains(merged, []byte(">10<")) == true {
+		t.Fatalf("Old properties were not removed: %s", merged)
+	} else if bytes.Count(merged, []byte("xmlns:GPano")) != 1 {
+		t.Fatalf("Namespace not declared once: %s", merged)
+	}
+
+	pi, err := ParsePanoramaInfo(merged)
+	log.PanicIf(err)
+
+	if pi != expected {
+		t.Fatalf("Merged panorama not correct: %s", pi)
+	}
+}
+
+func TestSetPanoramaInfo(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	_, err := GetPanoramaInfo(data)
+	if err != ErrNoPanorama {
+		t.Fatalf("Expected ErrNoPanorama: %v", err)
+	}
+
+	expected := getTestPanoramaInfo()
+
+	updated, err := SetPanoramaInfo(data, expected)
+	log.PanicIf(err)
+
+	pi, err := GetPanoramaInfo(updated)
+	log.PanicIf(err)
+
+	if pi != expected {
+		t.Fatalf("Panorama not correct: %s", pi)
+	}
+
+	// The EXIF must still be there and the XMP must come after it.
+
+	rawExif, err := SearchAndExtractExif(updated)
+	log.PanicIf(err)
+
+	_, _, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	segments := jpegSegments(updated)
+	if bytes.HasPrefix(segments[0].payload, jpegExifPreamble) != true || bytes.HasPrefix(segments[1].payload, xmpNamespace) != true {
+		t.Fatalf("Segments not in the right order.")
+	}
+
+	// Setting it again replaces the segment rather than adding another.
+
+	expected.FullPanoWidthPixels = 9000
+
+	updated, err = SetPanoramaInfo(updated, expected)
+	log.PanicIf(err)
+
+	if len(jpegXmpPackets(updated)) != 1 {
+		t.Fatalf("XMP segment was duplicated.")
+	}
+
+	pi, err = GetPanoramaInfo(updated)
+	log.PanicIf(err)
+
+	if pi.FullPanoWidthPixels != 9000 {
+		t.Fatalf("Panorama not updated: %s", pi)
+	}
+}
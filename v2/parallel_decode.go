@@ -0,0 +1,61 @@
+package exif
+
+import (
+	"sync"
+)
+
+// SetDecodeConcurrency has `Collect()` decode the values of every tag once
+// the IFDs have been walked, with up to the given number of IFDs decoded at
+// once, each in its own goroutine. This helps with files that have thousands
+// of tags (e.g. raw files with large maker notes). `Value()` then returns the
+// decoded value, or the failure to decode it, without reading it again. The
+// index is assembled by the walk, before any values are decoded, so it's the
+// same regardless of the order in which the IFDs finish. Zero, the default,
+// leaves values to be decoded when they're asked for.
+func (ie *IfdEnumerate) SetDecodeConcurrency(concurrency int) {
+	ie.decodeConcurrency = concurrency
+}
+
+// decodedValue is the result of decoding the value of an entry ahead of time.
+type decodedValue struct {
+	value interface{}
+	err   error
+}
+
+// decodeValues decodes the values of the tags in the given IFDs, up to
+// `concurrency` IFDs at a time. Each entry only has its own result written, so
+// the results don't depend on scheduling.
+func decodeValues(ifds []*Ifd, concurrency int) {
+	if concurrency > len(ifds) {
+		concurrency = len(ifds)
+	}
+
+	queue := make(chan *Ifd)
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for ifd := range queue {
+				for _, ite := range ifd.Entries {
+					value, err := ite.Value()
+
+					ite.decoded = &decodedValue{
+						value: value,
+						err:   err,
+					}
+				}
+			}
+		}()
+	}
+
+	for _, ifd := range ifds {
+		queue <- ifd
+	}
+
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,86 @@
+package exif
+
+import (
+	"reflect"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// collectDecoded parses the EXIF with the values decoded during the parse.
+func collectDecoded(rawExif []byte, concurrency int) IfdIndex {
+	eh, err := ParseExifHeader(rawExif)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, eh.ByteOrder)
+	ie.SetDecodeConcurrency(concurrency)
+
+	index, err := ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestIfdEnumerate_SetDecodeConcurrency(t *testing.T) {
+	rawExif := getTestExifData()
+
+	_, expected, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	for _, concurrency := range []int{1, 3, 100} {
+		index := collectDecoded(rawExif, concurrency)
+
+		if len(index.Ifds) != len(expected.Ifds) {
+			t.Fatalf("IFD count not correct for (%d): (%d) != (%d)", concurrency, len(index.Ifds), len(expected.Ifds))
+		}
+
+		for i, ifd := range index.Ifds {
+			expectedIfd := expected.Ifds[i]
+
+			if ifd.FqIfdPath != expectedIfd.FqIfdPath || len(ifd.Entries) != len(expectedIfd.Entries) {
+				t.Fatalf("IFD (%d) not correct for (%d): %s != %s", i, concurrency, ifd, expectedIfd)
+			}
+
+			for j, ite := range ifd.Entries {
+				if ite.decoded == nil {
+					t.Fatalf("Value of %s not decoded for (%d).", ite, concurrency)
+				}
+
+				value, err := ite.Value()
+				expectedValue, expectedErr := expectedIfd.Entries[j].Value()
+
+				if reflect.DeepEqual(value, expectedValue) != true {
+					t.Fatalf("Value of %s not correct for (%d): %v != %v", ite, concurrency, value, expectedValue)
+				} else if (err == nil) != (expectedErr == nil) {
+					t.Fatalf("Error of %s not correct for (%d): %v != %v", ite, concurrency, err, expectedErr)
+				}
+			}
+		}
+	}
+}
+
+func TestIfdEnumerate_SetDecodeConcurrency_Failure(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: 0x013b, Value: "Jane Doe", ValueOffset: 0x00fffff0})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	// A value that can't be decoded doesn't fail the parse, but it still
+	// fails when it's asked for.
+	index := collectDecoded(rawExif, 2)
+
+	results, err := index.RootIfd.FindTagWithId(0x013b)
+	log.PanicIf(err)
+
+	if _, err := results[0].Value(); log.Is(err, exifcommon.ErrTruncatedValue) != true {
+		t.Fatalf("Expected truncated-value error: %v", err)
+	} else if make_, err := getIfdTagString(index.RootIfd, "Make"); err != nil || make_ != "Canon" {
+		t.Fatalf("Make not correct: [%s] %v", make_, err)
+	}
+}
@@ -0,0 +1,69 @@
+package exif
+
+import (
+	"fmt"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// ParseCounters counts what the parser had to work around in one file. Files
+// from a given camera or pipeline tend to have the same counts, so an
+// ingestion service can keep the distribution of each and flag the files that
+// stand out (possibly crafted, corrupted, or from something that we haven't
+// seen before) for manual review.
+type ParseCounters struct {
+	// SkippedEntries is the number of entries that were dropped because
+	// their type isn't valid.
+	SkippedEntries int
+
+	// CoercedTypes is the number of entries of known tags whose type isn't
+	// the one that the tag is defined with, so their values have to be
+	// converted by whatever uses them. A SHORT where a LONG is defined is
+	// allowed by the specification and isn't counted.
+	CoercedTypes int
+
+	// ClampedOffsets is the number of values that extended past the end of
+	// the data and were cut short (e.g. a thumbnail in a truncated file).
+	ClampedOffsets int
+
+	// UnknownTags is the number of entries whose tags aren't in the tag
+	// index.
+	UnknownTags int
+
+	// QuirkedEntries is the number of entries that were adjusted by a quirk
+	// of the camera (see `Quirk`).
+	QuirkedEntries int
+}
+
+// String returns a descriptive string.
+func (pc ParseCounters) String() string {
+	return fmt.Sprintf("ParseCounters<SKIPPED-ENTRIES=(%d) COERCED-TYPES=(%d) CLAMPED-OFFSETS=(%d) UNKNOWN-TAGS=(%d) QUIRKED-ENTRIES=(%d)>", pc.SkippedEntries, pc.CoercedTypes, pc.ClampedOffsets, pc.UnknownTags, pc.QuirkedEntries)
+}
+
+// Total returns the sum of the counters. Zero means that nothing unusual was
+// found.
+func (pc ParseCounters) Total() int {
+	return pc.SkippedEntries + pc.CoercedTypes + pc.ClampedOffsets + pc.UnknownTags + pc.QuirkedEntries
+}
+
+// Counters returns the counts of what the parser has had to work around so
+// far. They accumulate across calls, so use one enumerator per file.
+func (ie *IfdEnumerate) Counters() ParseCounters {
+	return ie.counters
+}
+
+// countTag counts the entry if its tag isn't known or its type isn't the
+// tag's.
+func (ie *IfdEnumerate) countTag(ifdPath string, tagId uint16, tagType exifcommon.TagTypePrimitive) {
+	it, err := ie.tagIndex.Get(ifdPath, tagId)
+	if err != nil {
+		ie.counters.UnknownTags++
+		return
+	}
+
+	if tagType == it.Type || (tagType == exifcommon.TypeShort && it.Type == exifcommon.TypeLong) {
+		return
+	}
+
+	ie.counters.CoercedTypes++
+}
@@ -0,0 +1,83 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestIfdEnumerate_Counters(t *testing.T) {
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			// A SHORT where a LONG is defined is fine.
+			{Id: 0x0100, Value: []uint16{100}},
+
+			// A LONG where a SHORT is defined.
+			{Id: 0x0112, Value: []uint32{1}},
+
+			// Not a known tag.
+			{Id: 0x9999, Value: []uint16{1}},
+
+			// Its type is replaced below.
+			{Id: 0xfffe, Raw: []byte{1, 2, 3, 4}, Type: exifcommon.TypeUndefined},
+		},
+		Next: &exiftest.Ifd{
+			Tags: []exiftest.Tag{
+				// A thumbnail that runs past the end.
+				{Id: ThumbnailOffsetTagId, Value: []uint32{8}},
+				{Id: ThumbnailSizeTagId, Value: []uint32{1 << 20}},
+			},
+		},
+	}
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	// The type of the last entry of IFD0.
+	exifData = exiftest.PutUint16(exifData, 8+2+3*12+2, 0x00ff)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	expected := ParseCounters{
+		SkippedEntries: 1,
+		CoercedTypes:   1,
+		ClampedOffsets: 1,
+		UnknownTags:    1,
+	}
+
+	if index.Counters != expected {
+		t.Fatalf("Counters not correct: %s", index.Counters)
+	} else if index.Counters.Total() != 4 {
+		t.Fatalf("Total not correct: (%d)", index.Counters.Total())
+	}
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	if len(thumbnail) != len(exifData)-8 {
+		t.Fatalf("Thumbnail not clamped: (%d)", len(thumbnail))
+	}
+}
+
+func TestIfdEnumerate_Counters_Clean(t *testing.T) {
+	exifData, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	eh, err := ParseExifHeader(exifData)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), exifData, eh.ByteOrder)
+
+	_, err = ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+
+	if counters := ie.Counters(); counters.Total() != 0 {
+		t.Fatalf("Expected no counts: %s", counters)
+	}
+}
@@ -0,0 +1,189 @@
+package exif
+
+import (
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+	"github.com/go-errors/errors"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// parsePosition is where the enumerator is in the data. The offsets are
+// relative to the start of the addressable data (the TIFF header).
+type parsePosition struct {
+	fqIfdPath string
+	ifdOffset uint32
+
+	// tagPosition is the index of the entry in the IFD, or (-1) if we're not
+	// on an entry.
+	tagPosition int
+	tagOffset   uint32
+
+	// hasTag is false until the entry has been read.
+	hasTag    bool
+	tagId     uint16
+	tagType   exifcommon.TagTypePrimitive
+	unitCount uint32
+}
+
+// enterIfd records that the IFD at the given offset is being parsed.
+func (pp *parsePosition) enterIfd(fqIfdPath string, ifdOffset uint32) {
+	*pp = parsePosition{
+		fqIfdPath:   fqIfdPath,
+		ifdOffset:   ifdOffset,
+		tagPosition: -1,
+	}
+}
+
+// enterTag records that the entry at the given position of the current IFD is
+// being parsed.
+func (pp *parsePosition) enterTag(tagPosition int) {
+	pp.tagPosition = tagPosition
+	pp.tagOffset = pp.ifdOffset + 2 + uint32(tagPosition)*IfdTagEntrySize
+	pp.hasTag = false
+}
+
+// readTag records the entry that's being parsed.
+func (pp *parsePosition) readTag(tagId uint16, tagType exifcommon.TagTypePrimitive, unitCount uint32) {
+	pp.hasTag = true
+	pp.tagId = tagId
+	pp.tagType = tagType
+	pp.unitCount = unitCount
+}
+
+// ExifError is returned by `IfdEnumerate` (and so by `Collect()` and
+// `Visit()`) when parsing fails. It describes where in the data the failure
+// happened so that it can be reported along with a bug.
+//
+// Use `errors.Is()` or `AsExifError()` to get at the underlying error.
+type ExifError struct {
+	// FqIfdPath is the IFD that was being parsed. The offsets are from the
+	// start of the EXIF data (the TIFF header).
+	FqIfdPath string
+	IfdOffset uint32
+
+	// TagPosition is the index of the entry that was being parsed, or (-1) if
+	// the failure wasn't in an entry. The rest of the fields describe the
+	// entry.
+	TagPosition int
+	TagOffset   uint32
+
+	// HasTag is false if the entry couldn't be read (its ID, type, and count
+	// are zero).
+	HasTag    bool
+	TagId     uint16
+	TagType   exifcommon.TagTypePrimitive
+	UnitCount uint32
+
+	// Err is the underlying error.
+	Err error
+}
+
+// newExifError returns an `ExifError` for the given position.
+func newExifError(pp parsePosition, err error) *ExifError {
+	return &ExifError{
+		FqIfdPath:   pp.fqIfdPath,
+		IfdOffset:   ExifAddressableAreaStart + pp.ifdOffset,
+		TagPosition: pp.tagPosition,
+		TagOffset:   ExifAddressableAreaStart + pp.tagOffset,
+		HasTag:      pp.hasTag,
+		TagId:       pp.tagId,
+		TagType:     pp.tagType,
+		UnitCount:   pp.unitCount,
+		Err:         err,
+	}
+}
+
+// Position returns a description like "IFD [IFD/Exif] at offset (0x0000001a)
+// tag (0x9286) type [UNDEFINED] count (8) entry (3) at offset (0x00000040)".
+func (ee *ExifError) Position() string {
+	description := fmt.Sprintf("IFD [%s] at offset (0x%08x)", ee.FqIfdPath, ee.IfdOffset)
+
+	if ee.TagPosition < 0 {
+		return description
+	}
+
+	if ee.HasTag == true {
+		tagTypeName := ee.TagType.String()
+		if tagTypeName == "" {
+			tagTypeName = fmt.Sprintf("0x%04x", uint16(ee.TagType))
+		}
+
+		description += fmt.Sprintf(" tag (0x%04x) type [%s] count (%d)", ee.TagId, tagTypeName, ee.UnitCount)
+	}
+
+	return description + fmt.Sprintf(" entry (%d) at offset (0x%08x)", ee.TagPosition, ee.TagOffset)
+}
+
+// Error returns the position followed by the underlying error.
+func (ee *ExifError) Error() string {
+	return fmt.Sprintf("%s: %s", ee.Position(), ee.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (ee *ExifError) Unwrap() error {
+	return ee.Err
+}
+
+// Is returns true if the underlying error is the given error, including when
+// it was wrapped with a stack.
+func (ee *ExifError) Is(target error) bool {
+	return log.Is(ee.Err, target)
+}
+
+// AsExifError returns the `ExifError` in the given error, which may have
+// since been wrapped with a stack.
+func AsExifError(err error) (ee *ExifError, found bool) {
+	for err != nil {
+		switch e := err.(type) {
+		case *ExifError:
+			return e, true
+		case *errors.Error:
+			err = e.Err
+		default:
+			wrapper, ok := err.(interface{ Unwrap() error })
+			if ok == false {
+				return nil, false
+			}
+
+			err = wrapper.Unwrap()
+		}
+	}
+
+	return nil, false
+}
+
+// SetRecoverPanics determines whether the exported methods recover from a
+// failure while parsing and return it as an error, which is the default. If
+// disabled, the panic propagates to the caller with its original stack, which
+// is more useful when debugging.
+func (ie *IfdEnumerate) SetRecoverPanics(recoverPanics bool) {
+	ie.recoverPanics = recoverPanics
+}
+
+// recoverParse is deferred by the exported methods, which are the only places
+// that recover. The error is returned as an `ExifError` for the position that
+// we were at when it happened.
+func (ie *IfdEnumerate) recoverParse(err *error) {
+	if ie.recoverPanics == false {
+		return
+	}
+
+	state := recover()
+	if state == nil {
+		return
+	}
+
+	stateErr, ok := state.(error)
+	if ok == false {
+		stateErr = fmt.Errorf("%v", state)
+	}
+
+	if ie.position.fqIfdPath == "" {
+		*err = log.Wrap(stateErr)
+		return
+	}
+
+	*err = newExifError(ie.position, log.Wrap(stateErr))
+}
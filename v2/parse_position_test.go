@@ -0,0 +1,152 @@
+package exif
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func getTestRecoverIfdEnumerate() (ie *IfdEnumerate, firstIfdOffset uint32) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("Make", "Canon")
+	log.PanicIf(err)
+
+	err = rootIb.SetStandardWithName("Model", "EOS")
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	eh, err := ParseExifHeader(exifData)
+	log.PanicIf(err)
+
+	return NewIfdEnumerate(im, ti, exifData, eh.ByteOrder), eh.FirstIfdOffset
+}
+
+func TestExifError_Position(t *testing.T) {
+	pp := parsePosition{}
+	pp.enterIfd("IFD/Exif", 0x1a)
+
+	ee := newExifError(pp, nil)
+	if ee.Position() != "IFD [IFD/Exif] at offset (0x0000001a)" {
+		t.Fatalf("IFD position not correct: [%s]", ee.Position())
+	}
+
+	pp.enterTag(3)
+
+	ee = newExifError(pp, nil)
+	if ee.Position() != "IFD [IFD/Exif] at offset (0x0000001a) entry (3) at offset (0x00000040)" {
+		t.Fatalf("Entry position not correct: [%s]", ee.Position())
+	}
+
+	pp.readTag(0x9286, exifcommon.TypeUndefined, 8)
+
+	ee = newExifError(pp, nil)
+	if ee.Position() != "IFD [IFD/Exif] at offset (0x0000001a) tag (0x9286) type [UNDEFINED] count (8) entry (3) at offset (0x00000040)" {
+		t.Fatalf("Tag position not correct: [%s]", ee.Position())
+	}
+}
+
+func TestIfdEnumerate_Scan_ErrorContext(t *testing.T) {
+	ie, firstIfdOffset := getTestRecoverIfdEnumerate()
+
+	errVisitor := errors.New("visitor failed")
+
+	err := ie.Scan(exifcommon.IfdStandard, firstIfdOffset, func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) error {
+		if ite.TagId() == 0x0110 {
+			return errVisitor
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errVisitor) == false {
+		t.Fatalf("Expected the visitor's error: %v", err)
+	}
+
+	// Model is the second entry of IFD0.
+	expected := "IFD [IFD] at offset (0x00000008) tag (0x0110) type [ASCII] count (4) entry (1) at offset (0x00000016): visitor failed"
+	if err.Error() != expected {
+		t.Fatalf("Error not correct: [%s]", err)
+	}
+
+	ee, found := AsExifError(log.Wrap(err))
+	if found != true {
+		t.Fatalf("ExifError not found: %v", err)
+	} else if ee.FqIfdPath != "IFD" || ee.TagPosition != 1 || ee.TagOffset != 0x16 || ee.TagId != 0x0110 || ee.TagType != exifcommon.TypeAscii || ee.UnitCount != 4 {
+		t.Fatalf("ExifError not correct: %v", ee)
+	}
+}
+
+func TestIfdEnumerate_Collect_ErrorContext(t *testing.T) {
+	ie, firstIfdOffset := getTestRecoverIfdEnumerate()
+
+	// Point the next-IFD link of IFD0 back at itself.
+	nextIfdPosition := ExifAddressableAreaStart + firstIfdOffset + 2 + 2*IfdTagEntrySize
+	exifcommon.TestDefaultByteOrder.PutUint32(ie.exifData[nextIfdPosition:], firstIfdOffset)
+
+	_, err := ie.Collect(firstIfdOffset)
+	if errors.Is(err, ErrIfdCycle) == false {
+		t.Fatalf("Expected ErrIfdCycle: %v", err)
+	} else if strings.HasPrefix(err.Error(), "IFD [IFD] at offset (0x00000008): ") == false {
+		t.Fatalf("Error does not have the position: [%s]", err)
+	}
+
+	ee, found := AsExifError(err)
+	if found != true {
+		t.Fatalf("ExifError not found: %v", err)
+	} else if ee.TagPosition != -1 || ee.HasTag != false {
+		t.Fatalf("ExifError should not describe an entry: %v", ee)
+	}
+}
+
+func TestIfdEnumerate_SetRecoverPanics(t *testing.T) {
+	ie, firstIfdOffset := getTestRecoverIfdEnumerate()
+	ie.SetRecoverPanics(false)
+
+	errVisitor := errors.New("visitor failed")
+
+	defer func() {
+		state := recover()
+		if state == nil {
+			t.Fatalf("Expected panic.")
+		} else if err, ok := state.(error); ok == false || log.Is(err, errVisitor) == false {
+			t.Fatalf("Expected the visitor's error: %v", state)
+		}
+	}()
+
+	ie.Scan(exifcommon.IfdStandard, firstIfdOffset, func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) error {
+		return errVisitor
+	})
+}
+
+func TestIfdEnumerate_recoverParse_NotError(t *testing.T) {
+	ie, _ := getTestRecoverIfdEnumerate()
+	ie.position.enterIfd("IFD", 8)
+
+	err := func() (err error) {
+		defer ie.recoverParse(&err)
+
+		panic("not an error")
+	}()
+
+	if err == nil || err.Error() != "IFD [IFD] at offset (0x00000008): not an error" {
+		t.Fatalf("Error not correct: %v", err)
+	}
+}
+
+func TestAsExifError_NotFound(t *testing.T) {
+	if _, found := AsExifError(log.Wrap(ErrIfdCycle)); found != false {
+		t.Fatalf("Expected no ExifError.")
+	} else if _, found := AsExifError(nil); found != false {
+		t.Fatalf("Expected no ExifError for nil.")
+	}
+}
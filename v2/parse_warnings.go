@@ -0,0 +1,62 @@
+package exif
+
+import (
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// SetTolerant determines whether `Collect()` and `Scan()` recover from damage
+// that would otherwise fail the whole parse, which is useful for files from
+// old cameras and careless editors. When enabled:
+//
+//   - an IFD whose table is truncated has the entries that are there
+//   - an entry whose value isn't in the data is skipped
+//   - an IFD (child or next in the chain) at an offset that isn't in the data,
+//     or that was already parsed, is skipped
+//   - an IFD that fails for any other reason is skipped (`Collect()` only)
+//
+// Each of these is recorded as a warning, with where it happened (see
+// `Warnings()`). Whatever else could be read is returned. The default is to
+// fail.
+func (ie *IfdEnumerate) SetTolerant(tolerant bool) {
+	ie.tolerant = tolerant
+}
+
+// Warnings returns what a tolerant parse skipped, in the order that it was
+// found. The `Err` of each is the reason (e.g. `ErrOffsetInvalid` or
+// `exifcommon.ErrNotEnoughData`).
+func (ie *IfdEnumerate) Warnings() []*ExifError {
+	return ie.warnings
+}
+
+// warn records a warning at the current position.
+func (ie *IfdEnumerate) warn(err error) {
+	ee := newExifError(ie.position, err)
+
+	ifdEnumerateLogger.Warningf(nil, "Skipping damaged data: %s", ee)
+	ie.warnings = append(ie.warnings, ee)
+}
+
+// isValueInBounds returns true if the value of the entry is in the data (or
+// in the entry itself).
+func (ie *IfdEnumerate) isValueInBounds(ite *IfdTagEntry) bool {
+	// Undefined-type values are counted in bytes (and `Size()` panics for
+	// them).
+	typeSize := 1
+	if ite.TagType() != exifcommon.TypeUndefined {
+		typeSize = ite.TagType().Size()
+	}
+
+	byteLength, err := exifcommon.CheckedMultiply(ite.UnitCount(), typeSize)
+	if err != nil {
+		return false
+	} else if byteLength <= 4 {
+		return true
+	}
+
+	end, err := exifcommon.CheckedAdd(ite.getValueOffset(), byteLength)
+	if err != nil {
+		return false
+	}
+
+	return end <= ie.dataSize()
+}
@@ -0,0 +1,147 @@
+package exif
+
+import (
+	"errors"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestCollectTolerant_ValueOutOfBounds(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: 0x013b, Value: "Jane Doe", ValueOffset: 0x00fffff0})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := CollectTolerant(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	if _, err := index.RootIfd.FindTagWithId(0x013b); log.Is(err, ErrTagNotFound) == false {
+		t.Fatalf("Entry with a value out of bounds should be skipped: %v", err)
+	} else if make_, err := getIfdTagString(index.RootIfd, "Make"); err != nil || make_ != "Canon" {
+		t.Fatalf("Make not correct: [%s] %v", make_, err)
+	} else if len(index.Warnings) != 1 || index.Counters.SkippedEntries != 1 {
+		t.Fatalf("Expected one warning: %v", index.Warnings)
+	}
+
+	ee := index.Warnings[0]
+	if ee.Is(exifcommon.ErrNotEnoughData) == false || ee.FqIfdPath != exifcommon.IfdPathStandard || ee.HasTag != true || ee.TagId != 0x013b {
+		t.Fatalf("Warning not correct: %s", ee)
+	}
+}
+
+func TestCollectTolerant_ChildIfdOutOfBounds(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+
+	// Replace the GPS IFD with a pointer to nowhere.
+	root.Children = root.Children[:1]
+	root.Tags = append(root.Tags, exiftest.Tag{Id: exifcommon.IfdGpsId, Value: []uint32{0x00fffff0}})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	if _, _, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif); errors.Is(err, ErrOffsetInvalid) == false {
+		t.Fatalf("Expected offset error when not tolerant: %v", err)
+	}
+
+	_, index, err := CollectTolerant(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	if index.Has(exifcommon.IfdPathStandardExif) != true {
+		t.Fatalf("Exif IFD not recovered.")
+	} else if index.Has(exifcommon.IfdPathStandardGps) == true {
+		t.Fatalf("GPS IFD not expected.")
+	} else if len(index.Warnings) != 1 || index.Warnings[0].Is(ErrOffsetInvalid) == false || index.Warnings[0].FqIfdPath != exifcommon.IfdPathStandardGps {
+		t.Fatalf("Warnings not correct: %v", index.Warnings)
+	}
+}
+
+func TestCollectTolerant_TruncatedTable(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	// The first three entries of IFD0 and part of the fourth. Only the
+	// orientation has its value in the entry.
+	truncated := exiftest.Truncate(rawExif, int(ExifDefaultFirstIfdOffset+2+3*IfdTagEntrySize+5))
+
+	if _, _, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), truncated); err == nil {
+		t.Fatalf("Expected error when not tolerant.")
+	}
+
+	_, index, err := CollectTolerant(NewIfdMappingWithStandard(), NewTagIndex(), truncated)
+	log.PanicIf(err)
+
+	if len(index.Ifds) != 1 || len(index.RootIfd.Entries) != 1 || index.RootIfd.Entries[0].TagId() != OrientationTagId {
+		t.Fatalf("Entries not correct: %v", index.RootIfd.Entries)
+	} else if len(index.Warnings) != 3 {
+		t.Fatalf("Expected three warnings: %v", index.Warnings)
+	}
+
+	// The table first, then the two entries whose values are gone.
+	if ee := index.Warnings[0]; ee.Is(exifcommon.ErrNotEnoughData) == false || ee.TagPosition != -1 {
+		t.Fatalf("Table warning not correct: %s", ee)
+	}
+}
+
+func TestCollectTolerant_Cycle(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+	root.NextOffset = ExifDefaultFirstIfdOffset
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	if _, _, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif); errors.Is(err, ErrIfdCycle) == false {
+		t.Fatalf("Expected cycle error when not tolerant: %v", err)
+	}
+
+	_, index, err := CollectTolerant(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	if len(index.Ifds) != 3 || index.RootIfd.NextIfd != nil {
+		t.Fatalf("IFDs not correct: %v", index.Ifds)
+	} else if len(index.Warnings) != 1 || index.Warnings[0].Is(ErrIfdCycle) == false || index.Warnings[0].FqIfdPath != "IFD1" {
+		t.Fatalf("Warnings not correct: %v", index.Warnings)
+	}
+}
+
+func TestCollectTolerant_RootUnreadable(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	_, _, err = CollectTolerant(NewIfdMappingWithStandard(), NewTagIndex(), exiftest.SetFirstIfdOffset(rawExif, 0x00fffff0))
+	if errors.Is(err, ErrOffsetInvalid) == false {
+		t.Fatalf("Expected offset error: %v", err)
+	}
+}
+
+func TestIfdEnumerate_Scan_Tolerant(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+	root.NextOffset = ExifDefaultFirstIfdOffset
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, binary.BigEndian)
+	ie.SetTolerant(true)
+
+	count := 0
+	visitor := func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) error {
+		count++
+		return nil
+	}
+
+	err = ie.Scan(exifcommon.IfdStandard, ExifDefaultFirstIfdOffset, visitor)
+	log.PanicIf(err)
+
+	if count != 15 {
+		t.Fatalf("Not every tag visited: (%d)", count)
+	} else if len(ie.Warnings()) != 1 || ie.Warnings()[0].Is(ErrIfdCycle) == false {
+		t.Fatalf("Warnings not correct: %v", ie.Warnings())
+	}
+}
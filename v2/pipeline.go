@@ -0,0 +1,517 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"time"
+
+	"encoding/json"
+	"image/jpeg"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// PipelineOpStripGps removes the GPS IFD.
+	PipelineOpStripGps = "strip-gps"
+
+	// PipelineOpShiftTime adds `Shift` to DateTime, DateTimeOriginal, and
+	// DateTimeDigitized (e.g. to correct a camera's clock). Their SubSecTime
+	// tags are shifted too, with more digits if `Shift` needs them.
+	PipelineOpShiftTime = "shift-time"
+
+	// PipelineOpSetRights sets `Creators` and `Copyright` in the EXIF, XMP,
+	// and IPTC (see `SetJpegRights()`).
+	PipelineOpSetRights = "set-rights"
+
+	// PipelineOpSetTag sets `TagName` in `FqIfdPath` to `Value`, which can
+	// have placeholders (see `TagTemplate`).
+	PipelineOpSetTag = "set-tag"
+
+	// PipelineOpStripSegments removes the segments of the `Kinds` (see
+	// `StripJpegSegments()`).
+	PipelineOpStripSegments = "strip-segments"
+
+	// PipelineOpRegenerateThumbnail replaces the thumbnail with one scaled
+	// from the image so that its longest side is `MaxSize` (160 by default).
+	PipelineOpRegenerateThumbnail = "regenerate-thumbnail"
+
+	// PipelineOpMinifyExif makes the EXIF as small as it can be, also
+	// removing the MakerNote if `PruneMakerNote` is true (see
+	// `MinifyExif()`).
+	PipelineOpMinifyExif = "minify-exif"
+
+	// PipelineOpReconcileExposure rewrites ShutterSpeedValue and
+	// ApertureValue from ExposureTime and FNumber, or the other way around,
+	// depending on `Source` (see `ReconcileExposure()`).
+	PipelineOpReconcileExposure = "reconcile-exposure"
+)
+
+const (
+	// pipelineThumbnailMaxSize is the default longest side of a regenerated
+	// thumbnail.
+	pipelineThumbnailMaxSize = 160
+)
+
+var (
+	// ErrPipelineOpUnknown means that an operation isn't one of the
+	// PipelineOp* constants.
+	ErrPipelineOpUnknown = errors.New("unknown pipeline operation")
+)
+
+// PipelineOperation is one step of a pipeline. Which fields are used depends
+// on `Op`, which is one of the PipelineOp* constants.
+type PipelineOperation struct {
+	Op string `json:"op" yaml:"op"`
+
+	// Shift is a duration (e.g. "-1h30m").
+	Shift string `json:"shift,omitempty" yaml:"shift,omitempty"`
+
+	Creators  []string `json:"creators,omitempty" yaml:"creators,omitempty"`
+	Copyright string   `json:"copyright,omitempty" yaml:"copyright,omitempty"`
+
+	FqIfdPath string `json:"ifd_path,omitempty" yaml:"ifd_path,omitempty"`
+	TagName   string `json:"tag_name,omitempty" yaml:"tag_name,omitempty"`
+	Value     string `json:"value,omitempty" yaml:"value,omitempty"`
+
+	Kinds []string `json:"kinds,omitempty" yaml:"kinds,omitempty"`
+
+	MaxSize int `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+
+	PruneMakerNote bool `json:"prune_maker_note,omitempty" yaml:"prune_maker_note,omitempty"`
+
+	// Source is one of the ExposureSource* constants.
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// String returns a descriptive string.
+func (po PipelineOperation) String() string {
+	return fmt.Sprintf("PipelineOperation<OP=[%s]>", po.Op)
+}
+
+// Pipeline is a sequence of operations on a JPEG's metadata, expressed as data
+// so that a workflow (e.g. strip GPS, shift the time, set the copyright, and
+// regenerate the thumbnail) can be stored, reviewed, and reused. The
+// operations are applied to each file in memory, in order, and the file is
+// only changed if they all succeed.
+type Pipeline struct {
+	Name       string              `json:"name" yaml:"name"`
+	Operations []PipelineOperation `json:"operations" yaml:"operations"`
+
+	// Backup, if not empty, is the format (one of the BackupFormat*
+	// constants) that `ApplyFile()` backs-up each file's EXIF in before
+	// changing it (see `BackupJpegExif()`).
+	Backup string `json:"backup,omitempty" yaml:"backup,omitempty"`
+
+	// MtimeFromDateTimeOriginal sets the modification time of each file that
+	// `ApplyFile()` changes to its DateTimeOriginal (see `RewriteOptions`).
+	MtimeFromDateTimeOriginal bool `json:"mtime_from_date_time_original,omitempty" yaml:"mtime_from_date_time_original,omitempty"`
+
+	// Journal, if not nil, records each file that `ApplyFile()` changes so
+	// that the batch can be undone (see `Rollback()`).
+	Journal *EditJournal `json:"-" yaml:"-"`
+}
+
+// ParsePipelineJson parses a pipeline from JSON and checks its operations.
+func ParsePipelineJson(data []byte) (p Pipeline, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = json.Unmarshal(data, &p)
+	log.PanicIf(err)
+
+	err = p.Validate()
+	log.PanicIf(err)
+
+	return p, nil
+}
+
+// Validate checks that every operation is known and has what it needs.
+func (p Pipeline) Validate() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if p.Backup != "" {
+		if _, found := backupSidecarSuffixes[p.Backup]; found == false {
+			return ErrBackupFormatUnknown
+		}
+	}
+
+	for i, po := range p.Operations {
+		switch po.Op {
+		case PipelineOpStripGps, PipelineOpSetRights, PipelineOpRegenerateThumbnail, PipelineOpMinifyExif:
+		case PipelineOpShiftTime:
+			_, err := time.ParseDuration(po.Shift)
+			if err != nil {
+				log.Panicf("operation (%d) has an invalid shift: [%s]", i, po.Shift)
+			}
+		case PipelineOpSetTag:
+			if po.FqIfdPath == "" || po.TagName == "" {
+				log.Panicf("operation (%d) needs an IFD path and a tag name", i)
+			}
+		case PipelineOpStripSegments:
+			if len(po.Kinds) == 0 {
+				log.Panicf("operation (%d) needs segment kinds", i)
+			}
+		case PipelineOpReconcileExposure:
+			if po.Source != ExposureSourceRational && po.Source != ExposureSourceApex {
+				log.Panicf("operation (%d) has an invalid exposure source: [%s]", i, po.Source)
+			}
+		default:
+			return ErrPipelineOpUnknown
+		}
+	}
+
+	return nil
+}
+
+// Apply returns a copy of the JPEG with every operation applied.
+func (p Pipeline) Apply(data []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = p.Validate()
+	log.PanicIf(err)
+
+	updated = data
+
+	for i, po := range p.Operations {
+		updated, err = po.apply(updated)
+		if err != nil {
+			log.Panicf("operation (%d) [%s] failed: %s", i, po.Op, err)
+		}
+	}
+
+	return updated, nil
+}
+
+// apply returns a copy of the JPEG with the operation applied.
+func (po PipelineOperation) apply(data []byte) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	switch po.Op {
+	case PipelineOpStripGps:
+		updated, err = rebuildJpegExif(data, func(rootIb *IfdBuilder) (err error) {
+			_, err = rootIb.DeleteAll(exifcommon.IfdGpsId)
+			return err
+		})
+	case PipelineOpShiftTime:
+		updated, err = shiftJpegTimes(data, po.Shift)
+	case PipelineOpSetRights:
+		updated, err = SetJpegRights(data, po.Creators, po.Copyright)
+	case PipelineOpSetTag:
+		tt := TagTemplate{
+			Tags: []TemplateTag{
+				{FqIfdPath: po.FqIfdPath, TagName: po.TagName, Value: po.Value},
+			},
+		}
+
+		updated, err = StampJpeg(data, tt, nil)
+	case PipelineOpStripSegments:
+		updated, err = StripJpegSegments(data, po.Kinds...)
+	case PipelineOpRegenerateThumbnail:
+		updated, err = regenerateJpegThumbnail(data, po.MaxSize)
+	case PipelineOpMinifyExif:
+		updated, _, err = MinifyJpegExif(data, MinifyOptions{PruneMakerNote: po.PruneMakerNote})
+	case PipelineOpReconcileExposure:
+		updated, err = ReconcileJpegExposure(data, po.Source)
+	default:
+		log.Panic(ErrPipelineOpUnknown)
+	}
+
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// rebuildJpegExif returns a copy of the JPEG with its EXIF rebuilt from an
+// `IfdBuilder` modified by the given function. Unlike `patchJpegExif()`, the
+// IFDs can be added and removed.
+func rebuildJpegExif(data []byte, rebuild func(rootIb *IfdBuilder) error) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	si, err := GetJpegSegmentsInfo(data)
+	log.PanicIf(err)
+
+	segments := si.Find(JpegSegmentExif)
+	if len(segments) == 0 {
+		log.Panic(ErrNoExif)
+	}
+
+	segment := segments[0]
+	rawExif := data[segment.DataOffset : segment.DataOffset+segment.DataSize]
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	rootIb := NewIfdBuilderFromExistingChain(index.RootIfd)
+
+	err = rebuild(rootIb)
+	log.PanicIf(err)
+
+	rebuilt, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	updated, err = replaceJpegSegmentData(data, segment, rebuilt)
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// shiftJpegTimes returns a copy of the JPEG with its capture and modification
+// times shifted by the given duration.
+func shiftJpegTimes(data []byte, shift string) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	duration, err := time.ParseDuration(shift)
+	log.PanicIf(err)
+
+	index, _, _, err := readJpegMetadata(data)
+	log.PanicIf(err)
+
+	if index.RootIfd == nil {
+		log.Panic(ErrNoExif)
+	}
+
+	// SubSecTime is kept, with more digits if the shift needs them.
+	fraction := duration % time.Second
+	if fraction < 0 {
+		fraction = -fraction
+	}
+
+	shiftPrecision := subSecPrecision(int(fraction))
+
+	shifted := make(map[string]time.Time)
+	precisions := make(map[string]int)
+
+	for _, tagName := range []string{"DateTime", "DateTimeOriginal", "DateTimeDigitized"} {
+		timestamp, precision, err := GetTimestamp(index, tagName)
+		if err == ErrTagNotFound {
+			continue
+		}
+
+		log.PanicIf(err)
+
+		if shiftPrecision > precision {
+			precision = shiftPrecision
+		}
+
+		shifted[tagName] = timestamp.Add(duration)
+		precisions[tagName] = precision
+	}
+
+	updated, err = patchJpegExif(data, func(ep *ExifPatcher) error {
+		for tagName, timestamp := range shifted {
+			tt := timestampTags[tagName]
+
+			err := ep.Set(tt.fqIfdPath, tt.tagId, ExifFullTimestampString(timestamp))
+			if err != nil {
+				return err
+			}
+
+			subSec := ExifSubSecTimeString(timestamp, precisions[tagName])
+			if subSec == "" {
+				continue
+			}
+
+			err = addPatcherIfdLineage(ep, exifcommon.IfdPathStandardExif)
+			if err != nil {
+				return err
+			}
+
+			err = ep.Set(exifcommon.IfdPathStandardExif, tt.subSecTagId, subSec)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// regenerateJpegThumbnail returns a copy of the JPEG with a thumbnail scaled
+// from the image.
+func regenerateJpegThumbnail(data []byte, maxSize int) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if maxSize <= 0 {
+		maxSize = pipelineThumbnailMaxSize
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	log.PanicIf(err)
+
+	thumbnail := scaleImage(img, maxSize)
+
+	b := new(bytes.Buffer)
+
+	err = jpeg.Encode(b, thumbnail, nil)
+	log.PanicIf(err)
+
+	updated, err = rebuildJpegExif(data, func(rootIb *IfdBuilder) (err error) {
+		defer func() {
+			if state := recover(); state != nil {
+				err = log.Wrap(state.(error))
+			}
+		}()
+
+		thumbnailIb, err := rootIb.NextIb()
+		log.PanicIf(err)
+
+		if thumbnailIb == nil {
+			thumbnailIb = NewIfdBuilder(rootIb.ifdMapping, rootIb.tagIndex, exifcommon.IfdPathStandard, rootIb.byteOrder)
+
+			err := rootIb.SetNextIb(thumbnailIb)
+			log.PanicIf(err)
+		}
+
+		err = thumbnailIb.SetThumbnail(b.Bytes())
+		log.PanicIf(err)
+
+		return nil
+	})
+
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// scaleImage returns the image scaled (nearest-neighbor) so that its longest
+// side is no more than the given size.
+func scaleImage(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	if width <= maxSize && height <= maxSize {
+		return img
+	}
+
+	scaledWidth, scaledHeight := maxSize, maxSize
+	if width > height {
+		scaledHeight = height * maxSize / width
+	} else {
+		scaledWidth = width * maxSize / height
+	}
+
+	if scaledWidth < 1 {
+		scaledWidth = 1
+	}
+
+	if scaledHeight < 1 {
+		scaledHeight = 1
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+
+	for y := 0; y < scaledHeight; y++ {
+		for x := 0; x < scaledWidth; x++ {
+			scaled.Set(x, y, img.At(bounds.Min.X+x*width/scaledWidth, bounds.Min.Y+y*height/scaledHeight))
+		}
+	}
+
+	return scaled
+}
+
+// PipelineResult is the outcome of applying a pipeline to one file.
+type PipelineResult struct {
+	Filepath string
+	Err      error
+}
+
+// ApplyFile applies the pipeline to a JPEG file, replacing it (see
+// `RewriteFile()`). The file is left unchanged if any operation fails. If
+// `Backup` is set, the original EXIF is written to a sidecar first, and if
+// `Journal` is set, the change is journaled first.
+func (p Pipeline) ApplyFile(filepath string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	updated, err := p.Apply(data)
+	log.PanicIf(err)
+
+	if p.Backup != "" {
+		_, err := backupJpegExifData(filepath, data, p.Backup)
+		log.PanicIf(err)
+	}
+
+	opts := RewriteOptions{
+		MtimeFromDateTimeOriginal: p.MtimeFromDateTimeOriginal,
+	}
+
+	if p.Journal != nil {
+		err := p.Journal.Rewrite(filepath, data, updated, opts)
+		log.PanicIf(err)
+
+		return nil
+	}
+
+	err = RewriteFile(filepath, updated, opts)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// DryRunFile reports what `ApplyFile()` would change in the file without
+// writing it.
+func (p Pipeline) DryRunFile(filepath string) (md MetadataDiff, err error) {
+	return DryRunFile(filepath, p.Apply)
+}
+
+// ApplyFiles applies the pipeline to each JPEG file. A failure doesn't stop
+// the others; check the `Err` of each result.
+func (p Pipeline) ApplyFiles(filepaths []string) []PipelineResult {
+	results := make([]PipelineResult, len(filepaths))
+
+	for i, filepath := range filepaths {
+		results[i] = PipelineResult{
+			Filepath: filepath,
+			Err:      p.ApplyFile(filepath),
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,235 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"encoding/binary"
+	"image/jpeg"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestPipelineIndex(data []byte) IfdIndex {
+	rawExif, err := SearchAndExtractExif(data)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestParsePipelineJson(t *testing.T) {
+	p, err := ParsePipelineJson([]byte(`{"name": "publish", "operations": [{"op": "strip-gps"}, {"op": "shift-time", "shift": "-1h"}]}`))
+	log.PanicIf(err)
+
+	if p.Name != "publish" || len(p.Operations) != 2 || p.Operations[1].Shift != "-1h" {
+		t.Fatalf("Pipeline not correct: %v", p)
+	}
+
+	_, err = ParsePipelineJson([]byte(`{"operations": [{"op": "recolor"}]}`))
+	if log.Is(err, ErrPipelineOpUnknown) == false {
+		t.Fatalf("Expected unknown-operation error: %v", err)
+	}
+
+	_, err = ParsePipelineJson([]byte(`{"operations": [{"op": "shift-time", "shift": "soon"}]}`))
+	if err == nil {
+		t.Fatalf("Expected error for invalid shift.")
+	}
+}
+
+func TestPipeline_Apply(t *testing.T) {
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpStripGps},
+			{Op: PipelineOpShiftTime, Shift: "-1h30m"},
+			{Op: PipelineOpSetRights, Creators: []string{"Jane Doe"}, Copyright: "(c) Jane Doe"},
+			{Op: PipelineOpSetTag, FqIfdPath: "IFD", TagName: "Software", Value: "pipeline for {Model}"},
+			{Op: PipelineOpRegenerateThumbnail, MaxSize: 80},
+		},
+	}
+
+	updated, err := p.Apply(getTestPipelineJpeg())
+	log.PanicIf(err)
+
+	index := getTestPipelineIndex(updated)
+
+	copyright, err := getIfdTagString(index.RootIfd, "Copyright")
+	log.PanicIf(err)
+
+	software, err := getIfdTagString(index.RootIfd, "Software")
+	log.PanicIf(err)
+
+	dateTimeOriginal, err := getIfdTagString(index.Lookup[exifcommon.IfdPathStandardExif][0], "DateTimeOriginal")
+	log.PanicIf(err)
+
+	if index.Has(exifcommon.IfdPathStandardGps) == true {
+		t.Fatalf("GPS not stripped.")
+	} else if copyright != "(c) Jane Doe" {
+		t.Fatalf("Copyright not set: [%s]", copyright)
+	} else if software != "pipeline for Canon EOS 5D Mark III" {
+		t.Fatalf("Software not set: [%s]", software)
+	} else if dateTimeOriginal != "2020:01:02 01:34:05" {
+		t.Fatalf("Time not shifted: [%s]", dateTimeOriginal)
+	} else if index.RootIfd.NextIfd == nil {
+		t.Fatalf("No thumbnail IFD.")
+	}
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(thumbnail))
+	log.PanicIf(err)
+
+	if config.Width != 80 || config.Height != 60 {
+		t.Fatalf("Thumbnail size not correct: (%d)x(%d)", config.Width, config.Height)
+	}
+}
+
+func TestPipeline_Apply_ShiftTimeSubSec(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, exiftest.Tag{Id: 0x9291, Value: "25"})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	cases := []struct {
+		shift            string
+		dateTimeOriginal string
+		subSec           string
+	}{
+		{"1h", "2020:01:02 04:04:05", "25"},
+		{"1.5s", "2020:01:02 03:04:06", "75"},
+		{"-250ms", "2020:01:02 03:04:05", "00"},
+		{"1.125s", "2020:01:02 03:04:06", "375"},
+	}
+
+	for _, c := range cases {
+		p := Pipeline{
+			Operations: []PipelineOperation{
+				{Op: PipelineOpShiftTime, Shift: c.shift},
+			},
+		}
+
+		updated, err := p.Apply(exiftest.WrapJpeg(rawExif))
+		log.PanicIf(err)
+
+		exifIfd := getTestPipelineIndex(updated).Lookup[exifcommon.IfdPathStandardExif][0]
+
+		dateTimeOriginal, err := getIfdTagString(exifIfd, "DateTimeOriginal")
+		log.PanicIf(err)
+
+		subSec, err := getIfdTagString(exifIfd, "SubSecTimeOriginal")
+		log.PanicIf(err)
+
+		if dateTimeOriginal != c.dateTimeOriginal || subSec != c.subSec {
+			t.Fatalf("Time not shifted correctly by [%s]: [%s] [%s]", c.shift, dateTimeOriginal, subSec)
+		}
+	}
+}
+
+func TestPipeline_ApplyFile_Atomic(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+	original := getTestPipelineJpeg()
+
+	err = ioutil.WriteFile(filepath, original, 0644)
+	log.PanicIf(err)
+
+	// The second operation fails, so the first mustn't be written either.
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpStripGps},
+			{Op: PipelineOpSetTag, FqIfdPath: "IFD", TagName: "Software", Value: "{Undefined}"},
+		},
+	}
+
+	results := p.ApplyFiles([]string{filepath})
+	if results[0].Err == nil {
+		t.Fatalf("Expected error.")
+	}
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	if bytes.Equal(data, original) == false {
+		t.Fatalf("File changed after a failure.")
+	}
+
+	p.Operations = p.Operations[:1]
+
+	err = p.ApplyFile(filepath)
+	log.PanicIf(err)
+
+	data, err = ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	if getTestPipelineIndex(data).Has(exifcommon.IfdPathStandardGps) == true {
+		t.Fatalf("File not updated.")
+	}
+}
+
+func TestPipeline_Apply_MinifyExif(t *testing.T) {
+	data := exiftest.WrapJpeg(getTestMinifiableExif())
+
+	updated, _, err := MinifyJpegExif(data, MinifyOptions{PruneMakerNote: true})
+	log.PanicIf(err)
+
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpMinifyExif, PruneMakerNote: true},
+		},
+	}
+
+	applied, err := p.Apply(data)
+	log.PanicIf(err)
+
+	if bytes.Equal(applied, updated) == false {
+		t.Fatalf("Pipeline result not correct.")
+	}
+}
+
+func TestPipeline_Apply_ReconcileExposure(t *testing.T) {
+	rawExif := getTestExposureExif(
+		exiftest.Tag{Id: apertureValueTagId, Value: []exifcommon.Rational{{Numerator: 6, Denominator: 1}}})
+
+	data := exiftest.WrapJpeg(rawExif)
+
+	updated, err := ReconcileJpegExposure(data, ExposureSourceRational)
+	log.PanicIf(err)
+
+	p := Pipeline{
+		Operations: []PipelineOperation{
+			{Op: PipelineOpReconcileExposure, Source: ExposureSourceRational},
+		},
+	}
+
+	applied, err := p.Apply(data)
+	log.PanicIf(err)
+
+	if bytes.Equal(applied, updated) == false {
+		t.Fatalf("Pipeline result not correct.")
+	}
+
+	p.Operations[0].Source = ""
+
+	if err := p.Validate(); err == nil {
+		t.Fatalf("Expected a missing source to be invalid.")
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"github.com/dsoprea/go-logging"
+	"gopkg.in/yaml.v2"
+)
+
+// ParsePipelineYaml parses a pipeline from YAML and checks its operations.
+// It's not available in reduced-footprint builds.
+func ParsePipelineYaml(data []byte) (p Pipeline, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = yaml.Unmarshal(data, &p)
+	log.PanicIf(err)
+
+	err = p.Validate()
+	log.PanicIf(err)
+
+	return p, nil
+}
+
+func init() {
+	buildFeatures = append(buildFeatures, "pipeline-yaml")
+}
@@ -0,0 +1,38 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestParsePipelineYaml(t *testing.T) {
+	data := `
+name: publish
+operations:
+  - op: strip-segments
+    kinds: [xmp, comment]
+  - op: set-rights
+    creators: [Jane Doe]
+    copyright: (c) Jane Doe
+`
+
+	p, err := ParsePipelineYaml([]byte(data))
+	log.PanicIf(err)
+
+	if p.Name != "publish" || len(p.Operations) != 2 {
+		t.Fatalf("Pipeline not correct: %v", p)
+	} else if len(p.Operations[0].Kinds) != 2 || p.Operations[0].Kinds[1] != JpegSegmentComment {
+		t.Fatalf("Kinds not correct: %v", p.Operations[0].Kinds)
+	} else if p.Operations[1].Copyright != "(c) Jane Doe" {
+		t.Fatalf("Copyright not correct: [%s]", p.Operations[1].Copyright)
+	}
+
+	_, err = ParsePipelineYaml([]byte("operations:\n  - op: strip-segments\n"))
+	if err == nil {
+		t.Fatalf("Expected error for missing kinds.")
+	}
+}
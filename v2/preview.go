@@ -0,0 +1,444 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+// PreviewSource is where a preview was found.
+type PreviewSource int
+
+const (
+	// PreviewSourceThumbnail is the thumbnail of an IFD in the main chain
+	// (normally IFD1).
+	PreviewSourceThumbnail PreviewSource = iota
+
+	// PreviewSourceSubIfd is a reduced-resolution JPEG in a SubIFD (e.g. the
+	// previews of a DNG).
+	PreviewSourceSubIfd
+
+	// PreviewSourceMpf is a preview image listed in the MPF index of a JPEG.
+	PreviewSourceMpf
+
+	// PreviewSourceMakerNote is a preview that the maker-note points to.
+	PreviewSourceMakerNote
+
+	// PreviewSourceHeif is a thumbnail item of a HEIF file.
+	PreviewSourceHeif
+)
+
+// String returns the name of the source.
+func (ps PreviewSource) String() string {
+	switch ps {
+	case PreviewSourceThumbnail:
+		return "thumbnail"
+	case PreviewSourceSubIfd:
+		return "sub-ifd"
+	case PreviewSourceMpf:
+		return "mpf"
+	case PreviewSourceMakerNote:
+		return "maker-note"
+	case PreviewSourceHeif:
+		return "heif"
+	}
+
+	return fmt.Sprintf("PreviewSource(%d)", int(ps))
+}
+
+const (
+	// newSubfileTypeTagId is the NewSubfileType tag, whose first bit marks a
+	// reduced-resolution image.
+	newSubfileTypeTagId = 0x00fe
+
+	compressionTagId     = 0x0103
+	stripOffsetsTagId    = 0x0111
+	stripByteCountsTagId = 0x0117
+
+	// compressionOldJpeg and compressionJpeg are the Compression values of a
+	// JPEG stream.
+	compressionOldJpeg = 6
+	compressionJpeg    = 7
+
+	// Nikon maker-note tags. The preview IFD has the JPEGInterchangeFormat
+	// tags, and its offsets are relative to the maker-note's TIFF header.
+	nikonPreviewIfdTagId = 0x0011
+
+	// Pentax maker-note tags. The offset is relative to the EXIF data.
+	pentaxPreviewLengthTagId = 0x0003
+	pentaxPreviewStartTagId  = 0x0004
+)
+
+var (
+	// ErrNoPreview means that there's no preview, or none that's large
+	// enough.
+	ErrNoPreview = errors.New("no preview found")
+)
+
+// Preview is an embedded preview image along with where it came from.
+type Preview struct {
+	Source PreviewSource
+
+	// FqIfdPath is the IFD of a thumbnail or SubIFD preview (e.g. "IFD1" or
+	// "IFD/SubIFD0").
+	FqIfdPath string
+
+	// Vendor is the maker-note vendor of a maker-note preview (e.g. "Nikon").
+	Vendor string
+
+	// ItemId is the position of an MPF preview in the MPF index or the item
+	// ID of a HEIF thumbnail.
+	ItemId uint32
+
+	// Width and Height are from the header of the image, or zero if they
+	// couldn't be read.
+	Width  int
+	Height int
+
+	// Data is the encoded image (usually a JPEG).
+	Data []byte
+}
+
+// String returns a descriptive string.
+func (p Preview) String() string {
+	return fmt.Sprintf("Preview<SOURCE=[%s] IFD-PATH=[%s] VENDOR=[%s] ITEM-ID=(%d) WIDTH=(%d) HEIGHT=(%d) SIZE=(%d)>", p.Source, p.FqIfdPath, p.Vendor, p.ItemId, p.Width, p.Height, len(p.Data))
+}
+
+// isLarger returns true if the preview has more pixels than the other one or,
+// if they're the same, more data.
+func (p Preview) isLarger(other Preview) bool {
+	area := p.Width * p.Height
+	otherArea := other.Width * other.Height
+
+	if area != otherArea {
+		return area > otherArea
+	}
+
+	return len(p.Data) > len(other.Data)
+}
+
+// newPreview returns a preview of the image, with the dimensions from its
+// header if it has one.
+func newPreview(source PreviewSource, image []byte) Preview {
+	p := Preview{
+		Source: source,
+		Data:   image,
+	}
+
+	if ih, err := GetImageHeader(image); err == nil {
+		p.Width = ih.Width
+		p.Height = ih.Height
+	}
+
+	return p
+}
+
+// GetPreviews returns all of the embedded previews of a JPEG, TIFF, DNG, or
+// HEIF file: the thumbnails of the IFD chain, JPEG previews in SubIFDs,
+// previews in the MPF index, previews that a Nikon or Pentax maker-note
+// points to, and HEIF thumbnail items. They're in that order, and sources
+// that can't be read are skipped. The list is empty if there aren't any.
+func GetPreviews(data []byte) (previews []Preview, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	previews = make([]Preview, 0)
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == nil {
+		exifPreviews, err := getExifPreviews(rawExif)
+		log.PanicIf(err)
+
+		previews = append(previews, exifPreviews...)
+	} else if err != ErrNoExif {
+		log.Panic(err)
+	}
+
+	mpfImages, err := getMpfImages(data)
+	log.PanicIf(err)
+
+	for i, ai := range mpfImages {
+		if ai.Kind != AuxiliaryPreview {
+			continue
+		}
+
+		p := newPreview(PreviewSourceMpf, data[ai.Offset:ai.Offset+ai.Length])
+		p.ItemId = uint32(i + 1)
+
+		previews = append(previews, p)
+	}
+
+	heifPreviews, err := getHeifPreviews(data)
+	log.PanicIf(err)
+
+	previews = append(previews, heifPreviews...)
+
+	return previews, nil
+}
+
+// getExifPreviews returns the previews that the EXIF has or points to.
+func getExifPreviews(rawExif []byte) (previews []Preview, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	previews = make([]Preview, 0)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	if err != nil {
+		ifdEnumerateLogger.Warningf(nil, "Could not parse EXIF while looking for previews: %s", err)
+		return previews, nil
+	}
+
+	for _, ifd := range index.Ifds {
+		if thumbnail, err := ifd.Thumbnail(); err == nil {
+			p := newPreview(PreviewSourceThumbnail, thumbnail)
+			p.FqIfdPath = ifd.FqIfdPath
+
+			previews = append(previews, p)
+		}
+	}
+
+	subIfds, err := getSubIfds(index)
+	log.PanicIf(err)
+
+	for _, si := range subIfds {
+		if image := getSubIfdPreview(si); image != nil {
+			p := newPreview(PreviewSourceSubIfd, image)
+			p.FqIfdPath = si.fqIfdPath
+
+			previews = append(previews, p)
+		}
+	}
+
+	vendor, image, err := getMakerNotePreview(index)
+	log.PanicIf(err)
+
+	if image != nil {
+		p := newPreview(PreviewSourceMakerNote, image)
+		p.Vendor = vendor
+
+		previews = append(previews, p)
+	}
+
+	return previews, nil
+}
+
+// getSubIfdPreview returns the JPEG of a reduced-resolution SubIFD or nil if
+// it's not one. The JPEG is either where the JPEGInterchangeFormat tags point
+// or the only strip of a JPEG-compressed image.
+func getSubIfdPreview(si subIfd) []byte {
+	getUint := func(tagId uint16) (value uint32, found bool) {
+		rie, found := si.find(tagId)
+		if found == false {
+			return 0, false
+		}
+
+		values := readRawIfdUints(si.data, rie, si.byteOrder)
+		if len(values) != 1 {
+			return 0, false
+		}
+
+		return values[0], true
+	}
+
+	if subfileType, found := getUint(newSubfileTypeTagId); found == false || subfileType&1 == 0 {
+		return nil
+	}
+
+	offset, hasOffset := getUint(ThumbnailOffsetTagId)
+	size, hasSize := getUint(ThumbnailSizeTagId)
+
+	if hasOffset == false || hasSize == false {
+		if compression, found := getUint(compressionTagId); found == false || (compression != compressionJpeg && compression != compressionOldJpeg) {
+			return nil
+		}
+
+		offset, hasOffset = getUint(stripOffsetsTagId)
+		size, hasSize = getUint(stripByteCountsTagId)
+
+		if hasOffset == false || hasSize == false {
+			return nil
+		}
+	}
+
+	image, err := exifcommon.CheckedSlice(si.data, offset, size)
+	if err != nil || size == 0 {
+		return nil
+	}
+
+	return image
+}
+
+// getMakerNotePreview returns the preview that a Nikon or Pentax maker-note
+// points to or nil if there isn't one.
+func getMakerNotePreview(index IfdIndex) (vendor string, image []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	vendor, mni, err := getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	var data []byte
+	var start, length int64
+
+	switch vendor {
+	case makerNoteVendorNikon:
+		previewIfdOffset, found := mni.Int(nikonPreviewIfdTagId)
+		if found == false {
+			return "", nil, nil
+		}
+
+		ite, err := getMakerNoteTag(index)
+		log.PanicIf(err)
+
+		value, err := ite.Value()
+		log.PanicIf(err)
+
+		data = value.(exifundefined.Tag927CMakerNote).MakerNoteBytes[nikonMakerNoteTiffPosition:]
+
+		previewIfd := parseMakerNoteIfd(data, int(previewIfdOffset), mni.byteOrder)
+
+		start, _ = previewIfd.Int(ThumbnailOffsetTagId)
+		length, _ = previewIfd.Int(ThumbnailSizeTagId)
+	case makerNoteVendorPentax:
+		ite, err := getMakerNoteTag(index)
+		log.PanicIf(err)
+
+		data = ite.addressableData
+
+		start, _ = mni.Int(pentaxPreviewStartTagId)
+		length, _ = mni.Int(pentaxPreviewLengthTagId)
+	default:
+		return "", nil, nil
+	}
+
+	if length <= 0 || start < 0 || start+length > int64(len(data)) {
+		return "", nil, nil
+	}
+
+	return vendor, data[start : start+length], nil
+}
+
+// getHeifPreviews returns the thumbnail items of a HEIF file. Their dimensions
+// are from their "ispe" properties.
+func getHeifPreviews(data []byte) (previews []Preview, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	previews = make([]Preview, 0)
+
+	hm, err := readHeifMeta(data)
+	if err != nil || hm == nil {
+		return previews, nil
+	}
+
+	for _, hr := range hm.references {
+		if hr.referenceType != "thmb" {
+			continue
+		}
+
+		hl, found := hm.locations[hr.fromItemId]
+		if found == false || hl.length == 0 || hl.offset+hl.length > int64(len(data)) {
+			continue
+		}
+
+		p := Preview{
+			Source: PreviewSourceHeif,
+			ItemId: hr.fromItemId,
+			Data:   data[hl.offset : hl.offset+hl.length],
+		}
+
+		// The "ispe" property has the version and flags, and then the width
+		// and height.
+		if ispe, found := hm.property(hr.fromItemId, "ispe"); found == true && len(ispe.payload) >= 12 {
+			p.Width = int(binary.BigEndian.Uint32(ispe.payload[4:]))
+			p.Height = int(binary.BigEndian.Uint32(ispe.payload[8:]))
+		}
+
+		previews = append(previews, p)
+	}
+
+	return previews, nil
+}
+
+// LargestPreview returns the embedded preview with the most pixels (see
+// `GetPreviews()`). Previews whose dimensions couldn't be read are only chosen
+// if none could, in which case the one with the most data is. `ErrNoPreview`
+// is returned if there aren't any.
+func LargestPreview(data []byte) (p Preview, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	previews, err := GetPreviews(data)
+	log.PanicIf(err)
+
+	if len(previews) == 0 {
+		return p, ErrNoPreview
+	}
+
+	p = previews[0]
+	for _, candidate := range previews[1:] {
+		if candidate.isLarger(p) == true {
+			p = candidate
+		}
+	}
+
+	return p, nil
+}
+
+// PreviewAtLeast returns the smallest embedded preview that's at least the
+// given width and height (see `GetPreviews()`), which is the cheapest one to
+// scale down for display. Previews whose dimensions couldn't be read aren't
+// considered. `ErrNoPreview` is returned if none is large enough.
+func PreviewAtLeast(data []byte, width, height int) (p Preview, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	previews, err := GetPreviews(data)
+	log.PanicIf(err)
+
+	candidates := make([]Preview, 0, len(previews))
+	for _, candidate := range previews {
+		if candidate.Width > 0 && candidate.Height > 0 && candidate.Width >= width && candidate.Height >= height {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return p, ErrNoPreview
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[j].isLarger(candidates[i])
+	})
+
+	return candidates[0], nil
+}
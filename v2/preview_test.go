@@ -0,0 +1,196 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+	"io/ioutil"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestPreviewImage returns a JPEG with nothing but a frame of the given
+// size.
+func getTestPreviewImage(width, height int) []byte {
+	sof := []byte{8, 0, 0, 0, 0, 1, 1, 0x11, 0}
+	binary.BigEndian.PutUint16(sof[1:], uint16(height))
+	binary.BigEndian.PutUint16(sof[3:], uint16(width))
+
+	image := []byte{jpegMarkerPrefix, jpegMarkerSoi}
+	image = append(image, getTestJpegSegment(0xc0, sof)...)
+	image = append(image, jpegMarkerPrefix, jpegMarkerEoi)
+
+	return image
+}
+
+// getTestNikonPreviewMakerNote returns a Nikon maker-note whose preview IFD
+// points to the image, which follows it.
+func getTestNikonPreviewMakerNote(image []byte) []byte {
+	b := new(bytes.Buffer)
+
+	b.Write(nikonMakerNoteSignature)
+	b.Write([]byte{2, 0x10, 0, 0})
+	b.Write([]byte{'M', 'M', 0, 0x2a, 0, 0, 0, 8})
+
+	previewIfdOffset := 8 + 2 + 12 + 4
+	imageOffset := previewIfdOffset + 2 + 2*12 + 4
+
+	binary.Write(b, binary.BigEndian, uint16(1))
+	binary.Write(b, binary.BigEndian, []uint16{nikonPreviewIfdTagId, uint16(exifcommon.TypeLong)})
+	binary.Write(b, binary.BigEndian, []uint32{1, uint32(previewIfdOffset), 0})
+
+	binary.Write(b, binary.BigEndian, uint16(2))
+	binary.Write(b, binary.BigEndian, []uint16{ThumbnailOffsetTagId, uint16(exifcommon.TypeLong)})
+	binary.Write(b, binary.BigEndian, []uint32{1, uint32(imageOffset)})
+	binary.Write(b, binary.BigEndian, []uint16{ThumbnailSizeTagId, uint16(exifcommon.TypeLong)})
+	binary.Write(b, binary.BigEndian, []uint32{1, uint32(len(image)), 0})
+
+	b.Write(image)
+
+	return b.Bytes()
+}
+
+// getTestPreviewsJpeg returns a JPEG with a 640x480 preview in a Nikon
+// maker-note and a 1920x1080 one in the MPF index.
+func getTestPreviewsJpeg() []byte {
+	root := exiftest.NewRealisticIfd()
+	root.Tags[0].Value = "NIKON CORPORATION"
+
+	makerNoteTag := exiftest.Tag{
+		Id:   0x927c,
+		Raw:  getTestNikonPreviewMakerNote(getTestPreviewImage(640, 480)),
+		Type: exifcommon.TypeUndefined,
+	}
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, makerNoteTag)
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return getTestMpfJpegWith(exifData, getTestPreviewImage(1920, 1080), 0x010002)
+}
+
+func TestGetPreviews(t *testing.T) {
+	previews, err := GetPreviews(getTestPreviewsJpeg())
+	log.PanicIf(err)
+
+	if len(previews) != 2 {
+		t.Fatalf("Expected two previews: %v", previews)
+	} else if p := previews[0]; p.Source != PreviewSourceMakerNote || p.Vendor != makerNoteVendorNikon || p.Width != 640 || p.Height != 480 {
+		t.Fatalf("Maker-note preview not correct: %s", p)
+	} else if bytes.Equal(p.Data, getTestPreviewImage(640, 480)) != true {
+		t.Fatalf("Maker-note preview data not correct.")
+	} else if p := previews[1]; p.Source != PreviewSourceMpf || p.ItemId != 1 || p.Width != 1920 || p.Height != 1080 {
+		t.Fatalf("MPF preview not correct: %s", p)
+	}
+}
+
+func TestGetPreviews_Thumbnail(t *testing.T) {
+	data, err := ioutil.ReadFile(getTestImageFilepath())
+	log.PanicIf(err)
+
+	previews, err := GetPreviews(data)
+	log.PanicIf(err)
+
+	if len(previews) != 1 {
+		t.Fatalf("Expected one preview: %v", previews)
+	} else if p := previews[0]; p.Source != PreviewSourceThumbnail || p.FqIfdPath != "IFD1" || p.Width != 160 || p.Height != 120 {
+		t.Fatalf("Thumbnail not correct: %s", p)
+	}
+}
+
+func TestGetPreviews_SubIfd(t *testing.T) {
+	image := getTestPreviewImage(1024, 768)
+
+	previewIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: newSubfileTypeTagId, Value: []uint32{1}},
+			{Id: compressionTagId, Value: []uint16{compressionJpeg}},
+			{Id: stripOffsetsTagId, Value: []uint32{0}},
+			{Id: stripByteCountsTagId, Value: []uint32{uint32(len(image))}},
+		},
+	}
+
+	rawIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: newSubfileTypeTagId, Value: []uint32{0}},
+			{Id: compressionTagId, Value: []uint16{compressionJpeg}},
+		},
+	}
+
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{{Id: 0x010f, Value: "Canon"}},
+		Children: []exiftest.Child{
+			{TagId: SubIfdsTagId, Ifd: rawIfd},
+			{TagId: SubIfdsTagId, Ifd: previewIfd},
+		},
+	}
+
+	tiff, err := exiftest.Build(root, binary.LittleEndian)
+	log.PanicIf(err)
+
+	// The strip offset is stored in the entry itself, so setting it doesn't
+	// change the size.
+	previewIfd.Tags[2].Value = []uint32{uint32(len(tiff))}
+
+	tiff, err = exiftest.Build(root, binary.LittleEndian)
+	log.PanicIf(err)
+
+	previews, err := GetPreviews(append(tiff, image...))
+	log.PanicIf(err)
+
+	if len(previews) != 1 {
+		t.Fatalf("Expected one preview: %v", previews)
+	} else if p := previews[0]; p.Source != PreviewSourceSubIfd || p.Width != 1024 || p.Height != 768 {
+		t.Fatalf("SubIFD preview not correct: %s", p)
+	}
+}
+
+func TestGetPreviews_Heif(t *testing.T) {
+	data, _, _ := getTestHeic()
+
+	previews, err := GetPreviews(data)
+	log.PanicIf(err)
+
+	if len(previews) != 1 {
+		t.Fatalf("Expected one preview: %v", previews)
+	} else if p := previews[0]; p.Source != PreviewSourceHeif || p.ItemId != 3 || bytes.Equal(p.Data, testHeifThumbnail) != true {
+		t.Fatalf("HEIF thumbnail not correct: %s", p)
+	}
+}
+
+func TestLargestPreview(t *testing.T) {
+	p, err := LargestPreview(getTestPreviewsJpeg())
+	log.PanicIf(err)
+
+	if p.Source != PreviewSourceMpf || p.Width != 1920 {
+		t.Fatalf("Largest preview not correct: %s", p)
+	}
+
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	if _, err := LargestPreview(exiftest.WrapJpeg(rawExif)); err != ErrNoPreview {
+		t.Fatalf("Expected ErrNoPreview: %v", err)
+	}
+}
+
+func TestPreviewAtLeast(t *testing.T) {
+	data := getTestPreviewsJpeg()
+
+	if p, err := PreviewAtLeast(data, 600, 400); err != nil || p.Source != PreviewSourceMakerNote {
+		t.Fatalf("Smallest large-enough preview not correct: %s %v", p, err)
+	} else if p, err := PreviewAtLeast(data, 1000, 400); err != nil || p.Source != PreviewSourceMpf {
+		t.Fatalf("Wider preview not correct: %s %v", p, err)
+	} else if _, err := PreviewAtLeast(data, 4000, 3000); err != ErrNoPreview {
+		t.Fatalf("Expected ErrNoPreview: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package exif
+
+import (
+	"bytes"
+	"io"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// Kind is the container that `Probe()` found.
+type Kind string
+
+const (
+	// KindUnknown means that the container wasn't recognized.
+	KindUnknown Kind = ""
+
+	// KindJpeg is a JPEG, with the EXIF in an APP1 segment.
+	KindJpeg Kind = "jpeg"
+
+	// KindTiff is anything that starts with a TIFF header (TIFF, DNG and
+	// most raw formats, and bare EXIF blocks).
+	KindTiff Kind = "tiff"
+
+	// KindPng is a PNG, with the EXIF in an eXIf chunk.
+	KindPng Kind = "png"
+
+	// KindHeif is a HEIF (HEIC, AVIF), with the EXIF in an "Exif" item.
+	KindHeif Kind = "heif"
+
+	// KindWebp is a WebP, with the EXIF in an "EXIF" chunk.
+	KindWebp Kind = "webp"
+
+	// KindCr3 is a Canon CR3, with the EXIF in the CMT boxes of the movie
+	// header. It can only be read.
+	KindCr3 Kind = "cr3"
+)
+
+const (
+	// probeHeadSize is how much is read from the start of the file up front.
+	// It's enough to identify the container and, usually, to find the EXIF
+	// without reading anything else.
+	probeHeadSize = 4096
+
+	// probeMaxSegments is the most JPEG segments or PNG chunks that are
+	// looked at before giving up.
+	probeMaxSegments = 256
+
+	// probeMaxMetaSize is the largest HEIF "meta" box that will be read.
+	probeMaxMetaSize = 1 << 20
+
+	// webpExifFlag is the bit of the VP8X flags that says that there's EXIF.
+	webpExifFlag = 0x08
+
+	// webpAlphaFlag is the bit of the VP8X flags that says that the image has
+	// an alpha channel.
+	webpAlphaFlag = 0x10
+)
+
+var (
+	pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+)
+
+// probeReader reads small pieces of a file, from the head that was already
+// read when possible.
+type probeReader struct {
+	r    io.ReaderAt
+	head []byte
+}
+
+// read returns `size` bytes at the given offset, or nil if the file isn't
+// that long.
+func (pr *probeReader) read(offset int64, size int) []byte {
+	if offset < 0 || size < 0 {
+		return nil
+	}
+
+	if offset+int64(size) <= int64(len(pr.head)) {
+		return pr.head[offset : offset+int64(size)]
+	}
+
+	data := make([]byte, size)
+
+	n, err := pr.r.ReadAt(data, offset)
+	if n == size {
+		return data
+	} else if err == io.EOF {
+		return nil
+	}
+
+	log.PanicIf(err)
+
+	return nil
+}
+
+// Probe says whether the file has EXIF and what container it's in, reading
+// only the start of the file and the headers of whatever comes before the
+// EXIF (a few KB, usually), so that a large number of files can be triaged
+// quickly. The EXIF isn't read or validated. The kind is `KindUnknown` if the
+// container isn't recognized, in which case `found` is false.
+func Probe(r io.ReaderAt) (found bool, kind Kind, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	head := make([]byte, probeHeadSize)
+
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		log.Panic(err)
+	}
+
+	pr := &probeReader{
+		r:    r,
+		head: head[:n],
+	}
+
+	kind = detectKind(pr.head)
+
+	switch kind {
+	case KindJpeg:
+		return probeJpeg(pr), kind, nil
+	case KindPng:
+		return probePng(pr), kind, nil
+	case KindWebp:
+		return probeWebp(pr), kind, nil
+	case KindHeif:
+		return probeHeif(pr), kind, nil
+	case KindTiff:
+		return true, kind, nil
+	}
+
+	return false, KindUnknown, nil
+}
+
+// detectKind identifies the container from the start of the file.
+func detectKind(head []byte) Kind {
+	switch {
+	case len(head) >= 2 && head[0] == jpegMarkerPrefix && head[1] == jpegMarkerSoi:
+		return KindJpeg
+	case bytes.HasPrefix(head, pngSignature) == true:
+		return KindPng
+	case len(head) >= 12 && bytes.Equal(head[:4], riffSignature) == true && string(head[8:12]) == "WEBP":
+		return KindWebp
+	case isHeif(head) == true:
+		return KindHeif
+	}
+
+	if _, err := ParseExifHeader(head); err == nil {
+		return KindTiff
+	}
+
+	return KindUnknown
+}
+
+// probeJpeg walks the segments, without reading their payloads, until it
+// finds an EXIF APP1 segment or the image data.
+func probeJpeg(pr *probeReader) bool {
+	position := int64(2)
+
+	for i := 0; i < probeMaxSegments; i++ {
+		header := pr.read(position, 4)
+		if header == nil || header[0] != jpegMarkerPrefix {
+			return false
+		}
+
+		marker := header[1]
+
+		if marker == jpegMarkerPrefix {
+			// Fill byte.
+			position++
+			continue
+		} else if marker == jpegMarkerSos || marker == jpegMarkerEoi {
+			return false
+		} else if marker == jpegMarkerTem || (marker >= jpegMarkerRst0 && marker <= jpegMarkerRst7) {
+			position += 2
+			continue
+		}
+
+		length := int64(binary.BigEndian.Uint16(header[2:]))
+		if length < 2 {
+			return false
+		}
+
+		if marker == jpegMarkerApp1 && length >= 2+int64(len(jpegExifPreamble)) {
+			preamble := pr.read(position+4, len(jpegExifPreamble))
+			if bytes.Equal(preamble, jpegExifPreamble) == true {
+				return true
+			}
+		}
+
+		position += 2 + length
+	}
+
+	return false
+}
+
+// probePng walks the chunks until it finds an eXIf chunk or the image data,
+// which the eXIf chunk has to come before.
+func probePng(pr *probeReader) bool {
+	position := int64(len(pngSignature))
+
+	for i := 0; i < probeMaxSegments; i++ {
+		header := pr.read(position, 8)
+		if header == nil {
+			return false
+		}
+
+		switch string(header[4:]) {
+		case "eXIf":
+			return true
+		case "IDAT", "IEND":
+			return false
+		}
+
+		// Length, type, data, and CRC.
+		position += 12 + int64(binary.BigEndian.Uint32(header))
+	}
+
+	return false
+}
+
+// probeWebp checks the flags of the VP8X chunk, which has to be first when
+// there's any metadata.
+func probeWebp(pr *probeReader) bool {
+	header := pr.read(12, 9)
+	if header == nil || string(header[:4]) != "VP8X" {
+		return false
+	}
+
+	return header[8]&webpExifFlag != 0
+}
+
+// probeHeif reads the "meta" box and looks for an "Exif" item.
+func probeHeif(pr *probeReader) bool {
+	ftyp := readIsoBoxes(pr.head, 0)[0]
+	ftypSize := ftyp.offset + int64(len(ftyp.payload))
+
+	for position, i := int64(0), 0; i < probeMaxSegments; i++ {
+		header := pr.read(position, 8)
+		if header == nil {
+			return false
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		if string(header[4:]) == "meta" {
+			if size < 8 || size > probeMaxMetaSize {
+				return false
+			}
+
+			meta := pr.read(position, int(size))
+			if meta == nil {
+				return false
+			}
+
+			// `readHeifMeta()` wants the "ftyp" box first.
+			data := append(append([]byte{}, pr.head[:ftypSize]...), meta...)
+
+			hm, err := readHeifMeta(data)
+			if err != nil || hm == nil {
+				return false
+			}
+
+			for _, itemType := range hm.itemTypes {
+				if itemType == "Exif" {
+					return true
+				}
+			}
+
+			return false
+		} else if size < 8 {
+			// Sizes of zero (to the end) and one (64-bit) don't happen
+			// before "meta".
+			return false
+		}
+
+		position += size
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+package exif
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// countingReaderAt counts the bytes that are read.
+type countingReaderAt struct {
+	r     io.ReaderAt
+	count int
+}
+
+func (cra *countingReaderAt) ReadAt(p []byte, offset int64) (n int, err error) {
+	n, err = cra.r.ReadAt(p, offset)
+	cra.count += n
+
+	return n, err
+}
+
+func getTestWebp(flags byte, exifData []byte) []byte {
+	body := new(bytes.Buffer)
+	body.WriteString("WEBP")
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = flags
+
+	writeTestRiffChunk(body, "VP8X", vp8x)
+	writeTestRiffChunk(body, "VP8 ", make([]byte, 100))
+
+	if exifData != nil {
+		writeTestRiffChunk(body, "EXIF", exifData)
+	}
+
+	webp := new(bytes.Buffer)
+	writeTestRiffChunk(webp, "RIFF", body.Bytes())
+
+	return webp.Bytes()
+}
+
+func getTestHeifWithItemType(itemType string) []byte {
+	ftyp := getTestIsoBox("ftyp", []byte("heic"), getTestIsoUints(4, 0), []byte("mif1heic"))
+
+	infe := getTestIsoFullBox("infe", 2, 0, getTestIsoUints(2, 1, 0), []byte(itemType+"\x00"))
+	iinf := getTestIsoFullBox("iinf", 0, 0, getTestIsoUints(2, 1), infe)
+
+	meta := getTestIsoFullBox("meta", 0, 0, iinf)
+	mdat := getTestIsoBox("mdat", make([]byte, 100))
+
+	return bytes.Join([][]byte{ftyp, meta, mdat}, nil)
+}
+
+func TestProbe(t *testing.T) {
+	exifData := getTestExifData()
+
+	cases := []struct {
+		name  string
+		data  []byte
+		found bool
+		kind  Kind
+	}{
+		{"jpeg", exiftest.WrapJpeg(exifData), true, KindJpeg},
+		{"jpeg without exif", []byte{jpegMarkerPrefix, jpegMarkerSoi, jpegMarkerPrefix, jpegMarkerEoi}, false, KindJpeg},
+		{"tiff", exifData, true, KindTiff},
+		{"png", exiftest.WrapPng(exifData), true, KindPng},
+		{"webp", getTestWebp(webpExifFlag, exifData), true, KindWebp},
+		{"webp without exif", getTestWebp(0, nil), false, KindWebp},
+		{"heif", getTestHeifWithItemType("Exif"), true, KindHeif},
+		{"heif without exif", getTestHeifWithItemType("hvc1"), false, KindHeif},
+		{"unknown", []byte("not an image"), false, KindUnknown},
+		{"empty", []byte{}, false, KindUnknown},
+	}
+
+	for _, c := range cases {
+		found, kind, err := Probe(bytes.NewReader(c.data))
+		log.PanicIf(err)
+
+		if found != c.found || kind != c.kind {
+			t.Fatalf("Probe of %s not correct: (%v) [%s]", c.name, found, kind)
+		}
+	}
+}
+
+func TestProbe_MinimalRead(t *testing.T) {
+	// A large profile before the EXIF, and a lot of image data after it.
+	b := new(bytes.Buffer)
+	b.Write([]byte{jpegMarkerPrefix, jpegMarkerSoi})
+	b.Write(getTestJpegSegment(jpegMarkerApp2, make([]byte, 60000)))
+	b.Write(getTestJpegSegment(jpegMarkerApp1, append(append([]byte{}, jpegExifPreamble...), getTestExifData()...)))
+	b.Write(getTestJpegSegment(jpegMarkerSos, make([]byte, 10)))
+	b.Write(make([]byte, 1<<20))
+
+	cra := &countingReaderAt{
+		r: bytes.NewReader(b.Bytes()),
+	}
+
+	found, kind, err := Probe(cra)
+	log.PanicIf(err)
+
+	if found != true || kind != KindJpeg {
+		t.Fatalf("EXIF not found: (%v) [%s]", found, kind)
+	} else if cra.count > probeHeadSize+16 {
+		t.Fatalf("Too much was read: (%d)", cra.count)
+	}
+}
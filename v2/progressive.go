@@ -0,0 +1,460 @@
+package exif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// progressiveReadChunkSize is how much we'll read from the stream before
+	// trying to make progress.
+	progressiveReadChunkSize = 4096
+
+	// DefaultProgressiveMaxSize is how much data a `ProgressiveParser`
+	// buffers by default (see `SetMaxSize()`).
+	DefaultProgressiveMaxSize = 16 * 1024 * 1024
+)
+
+var (
+	progressiveLogger = log.NewLogger("exif.progressive")
+)
+
+// progressiveIfd describes an IFD that we know about but whose table has not
+// necessarily arrived yet.
+type progressiveIfd struct {
+	name            string
+	ifdPath         string
+	fqIfdPath       string
+	parentFqIfdPath string
+	index           int
+	offset          uint32
+}
+
+// progressiveTag is a tag whose entry has been read but whose value might not
+// have arrived yet.
+type progressiveTag struct {
+	fqIfdPath string
+	ifdIndex  int
+	ite       *IfdTagEntry
+}
+
+// ProgressiveParser decodes EXIF data as it arrives. Data is provided via
+// `Write()` (so it can be the target of an `io.Copy()`) and the visitor is
+// called for each tag as soon as both its IFD entry and its value are
+// available. This allows callers reading from a network stream to act on tags
+// like DateTime or Orientation before the whole image has arrived.
+//
+// The first byte written must be the first byte of the EXIF header. See
+// `ParseProgressively()` to search for the header in an arbitrary stream.
+//
+// Tags are not necessarily visited in the same order as `Visit()` since IFDs
+// and values can be stored anywhere in the block.
+//
+// IFDs and values that can't be addressed, or that are beyond the most that
+// the parser will buffer (see `SetMaxSize()`), are unreachable: they're
+// logged and dropped rather than waited for.
+type ProgressiveParser struct {
+	ifdMapping *IfdMapping
+	tagIndex   *TagIndex
+	visitor    TagVisitorFn
+
+	data    []byte
+	maxSize int
+
+	// unreachable is the number of IFDs and tags that were dropped.
+	unreachable int
+
+	haveHeader bool
+	eh         ExifHeader
+
+	pendingIfds []progressiveIfd
+	pendingTags []progressiveTag
+
+	// seenOffsets prevents us from parsing the same IFD twice when an IFD
+	// chain loops on itself.
+	seenOffsets map[uint32]struct{}
+}
+
+// NewProgressiveParser returns a new ProgressiveParser.
+func NewProgressiveParser(ifdMapping *IfdMapping, tagIndex *TagIndex, visitor TagVisitorFn) *ProgressiveParser {
+	return &ProgressiveParser{
+		ifdMapping:  ifdMapping,
+		tagIndex:    tagIndex,
+		visitor:     visitor,
+		data:        make([]byte, 0),
+		maxSize:     DefaultProgressiveMaxSize,
+		pendingIfds: make([]progressiveIfd, 0),
+		pendingTags: make([]progressiveTag, 0),
+		seenOffsets: make(map[uint32]struct{}),
+	}
+}
+
+// SetMaxSize sets the most data that will be buffered. IFDs and values that
+// end beyond it are unreachable, and data written beyond it is ignored.
+func (pp *ProgressiveParser) SetMaxSize(maxSize int) {
+	pp.maxSize = maxSize
+}
+
+// Unreachable returns the number of IFDs and tags that were dropped because
+// they can't be addressed or are beyond the maximum size.
+func (pp *ProgressiveParser) Unreachable() int {
+	return pp.unreachable
+}
+
+// isReachable returns true if the range ends within the maximum size.
+func (pp *ProgressiveParser) isReachable(offset uint32, size uint64) bool {
+	return uint64(offset)+size <= uint64(pp.maxSize)
+}
+
+// Header returns the EXIF header. This is only valid once `HaveHeader()`
+// returns true.
+func (pp *ProgressiveParser) Header() ExifHeader {
+	return pp.eh
+}
+
+// HaveHeader returns true if enough data has arrived to parse the header.
+func (pp *ProgressiveParser) HaveHeader() bool {
+	return pp.haveHeader
+}
+
+// IsDone returns true if every IFD and tag that is reachable has been
+// visited. No more data needs to be written.
+func (pp *ProgressiveParser) IsDone() bool {
+	return pp.haveHeader == true && len(pp.pendingIfds) == 0 && len(pp.pendingTags) == 0
+}
+
+// Write adds more data and visits whatever tags have become available as a
+// result. Data beyond the maximum size (see `SetMaxSize()`) or after the
+// parser is done is accepted but not kept, since nothing can need it.
+func (pp *ProgressiveParser) Write(p []byte) (n int, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	n = len(p)
+
+	if pp.IsDone() == true {
+		return n, nil
+	}
+
+	if remaining := pp.maxSize - len(pp.data); len(p) > remaining {
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		p = p[:remaining]
+	}
+
+	pp.data = append(pp.data, p...)
+
+	err = pp.process()
+	if err != nil {
+		if err == ErrNoExif {
+			return 0, err
+		}
+
+		log.Panic(err)
+	}
+
+	return n, nil
+}
+
+func (pp *ProgressiveParser) process() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if pp.haveHeader == false {
+		if len(pp.data) < ExifSignatureLength {
+			return nil
+		}
+
+		eh, err := ParseExifHeader(pp.data)
+		if err != nil {
+			if err == ErrNoExif {
+				return err
+			}
+
+			log.Panic(err)
+		}
+
+		pp.eh = eh
+		pp.haveHeader = true
+
+		pi := progressiveIfd{
+			name:      exifcommon.IfdStandard,
+			ifdPath:   exifcommon.IfdStandard,
+			fqIfdPath: exifcommon.IfdStandard,
+			offset:    eh.FirstIfdOffset,
+		}
+
+		pp.pendingIfds = append(pp.pendingIfds, pi)
+	}
+
+	// Parsing an IFD can queue both tags and more IFDs, so keep going until
+	// we can't do anything else with what we have.
+
+	for {
+		progress := false
+
+		// Parsing an IFD will queue its children and siblings directly onto
+		// `pendingIfds`, so iterate over what we had coming in.
+		currentIfds := pp.pendingIfds
+		pp.pendingIfds = make([]progressiveIfd, 0)
+
+		for _, pi := range currentIfds {
+			parsed, err := pp.tryParseIfd(pi)
+			log.PanicIf(err)
+
+			if parsed == true {
+				progress = true
+			} else {
+				pp.pendingIfds = append(pp.pendingIfds, pi)
+			}
+		}
+
+		currentTags := pp.pendingTags
+		pp.pendingTags = make([]progressiveTag, 0)
+
+		for _, pt := range currentTags {
+			available, reachable := pp.isValueAvailable(pt.ite)
+			if reachable == false {
+				progressiveLogger.Warningf(nil, "Value of tag (0x%04x) in IFD [%s] is unreachable and will be skipped.", pt.ite.TagId(), pt.fqIfdPath)
+				pp.unreachable++
+
+				continue
+			} else if available == false {
+				pp.pendingTags = append(pp.pendingTags, pt)
+				continue
+			}
+
+			// The entry was created with whatever data we had at the time.
+			// Point it at the current data so the value can be read.
+			pt.ite.addressableData = pp.data
+
+			if pp.visitor != nil {
+				err := pp.visitor(pt.fqIfdPath, pt.ifdIndex, pt.ite)
+				log.PanicIf(err)
+			}
+
+			progress = true
+		}
+
+		if progress == false {
+			break
+		}
+	}
+
+	return nil
+}
+
+// isValueAvailable returns true if all of the bytes for the tag's value have
+// arrived and, if they haven't, whether they ever can.
+func (pp *ProgressiveParser) isValueAvailable(ite *IfdTagEntry) (available, reachable bool) {
+	tagType := ite.tagType
+	if tagType == exifcommon.TypeUndefined {
+		tagType = exifcommon.TypeByte
+	}
+
+	byteCount, err := exifcommon.CheckedMultiply(ite.unitCount, tagType.Size())
+	if err != nil {
+		// It can't be addressed, so it'll never arrive.
+		return false, false
+	} else if byteCount <= 4 {
+		return true, true
+	} else if pp.isReachable(ite.valueOffset, uint64(byteCount)) == false {
+		return false, false
+	}
+
+	_, err = exifcommon.CheckedSlice(pp.data, ite.valueOffset, byteCount)
+	return err == nil, true
+}
+
+// tryParseIfd parses the given IFD if its whole table has arrived.
+func (pp *ProgressiveParser) tryParseIfd(pi progressiveIfd) (parsed bool, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if _, found := pp.seenOffsets[pi.offset]; found == true {
+		progressiveLogger.Warningf(nil, "IFD [%s] at offset (0x%04x) was already parsed. Skipping.", pi.fqIfdPath, pi.offset)
+		return true, nil
+	}
+
+	if pp.isReachable(pi.offset, 2) == false {
+		progressiveLogger.Warningf(nil, "IFD [%s] at offset (0x%04x) is unreachable and will be skipped.", pi.fqIfdPath, pi.offset)
+		pp.unreachable++
+
+		return true, nil
+	}
+
+	rawTagCount, err := exifcommon.CheckedSlice(pp.data, pi.offset, 2)
+	if err == exifcommon.ErrNotEnoughData {
+		return false, nil
+	}
+
+	log.PanicIf(err)
+
+	tagCount := pp.eh.ByteOrder.Uint16(rawTagCount)
+
+	if pp.isReachable(pi.offset, uint64(rawIfdTableSize(int(tagCount)))) == false {
+		progressiveLogger.Warningf(nil, "Table of IFD [%s] at offset (0x%04x) is unreachable and will be skipped.", pi.fqIfdPath, pi.offset)
+		pp.unreachable++
+
+		return true, nil
+	}
+
+	_, err = exifcommon.CheckedSlice(pp.data, pi.offset, rawIfdTableSize(int(tagCount)))
+	if err == exifcommon.ErrNotEnoughData {
+		return false, nil
+	}
+
+	log.PanicIf(err)
+
+	pp.seenOffsets[pi.offset] = struct{}{}
+
+	ie := NewIfdEnumerate(pp.ifdMapping, pp.tagIndex, pp.data, pp.eh.ByteOrder)
+
+	enumerator, err := ie.getTagEnumerator(pi.fqIfdPath, pi.offset)
+	log.PanicIf(err)
+
+	// Skip the tag-count, which we've already read.
+	_, _, err = enumerator.getUint16()
+	log.PanicIf(err)
+
+	for i := 0; i < int(tagCount); i++ {
+		ite, err := ie.parseTag(pi.fqIfdPath, i, enumerator)
+		if err != nil {
+			if log.Is(err, ErrTagTypeNotValid) == true {
+				progressiveLogger.Warningf(nil, "Tag in IFD [%s] at position (%d) has invalid type and will be skipped.", pi.fqIfdPath, i)
+				continue
+			}
+
+			log.Panic(err)
+		}
+
+		// As with `ParseIfd()`, the thumbnail tags aren't visited.
+		if tagId := ite.TagId(); tagId == ThumbnailOffsetTagId || tagId == ThumbnailSizeTagId {
+			continue
+		}
+
+		pt := progressiveTag{
+			fqIfdPath: pi.fqIfdPath,
+			ifdIndex:  pi.index,
+			ite:       ite,
+		}
+
+		pp.pendingTags = append(pp.pendingTags, pt)
+
+		if ite.ChildIfdPath() != "" {
+			childPi := progressiveIfd{
+				name:            ite.ChildIfdName(),
+				ifdPath:         ite.ChildIfdPath(),
+				fqIfdPath:       ite.ChildFqIfdPath(),
+				parentFqIfdPath: pi.fqIfdPath,
+				offset:          ite.getValueOffset(),
+			}
+
+			pp.pendingIfds = append(pp.pendingIfds, childPi)
+		}
+	}
+
+	nextIfdOffset, _, err := enumerator.getUint32()
+	log.PanicIf(err)
+
+	if nextIfdOffset != 0 {
+		siblingIndex := pi.index + 1
+
+		var fqIfdPath string
+		if pi.parentFqIfdPath != "" {
+			fqIfdPath = fmt.Sprintf("%s/%s%d", pi.parentFqIfdPath, pi.name, siblingIndex)
+		} else {
+			fqIfdPath = fmt.Sprintf("%s%d", pi.name, siblingIndex)
+		}
+
+		siblingPi := progressiveIfd{
+			name:            pi.name,
+			ifdPath:         pi.ifdPath,
+			fqIfdPath:       fqIfdPath,
+			parentFqIfdPath: pi.parentFqIfdPath,
+			index:           siblingIndex,
+			offset:          nextIfdOffset,
+		}
+
+		pp.pendingIfds = append(pp.pendingIfds, siblingPi)
+	}
+
+	return true, nil
+}
+
+// ParseProgressively searches the stream for the EXIF header and then feeds
+// a `ProgressiveParser` until every reachable tag has been visited. It stops
+// reading as soon as that happens, so the rest of the stream (e.g. the image
+// data) is not consumed. IFDs or values that were never reached before the
+// stream ended, or that are unreachable (see `ProgressiveParser`), are logged
+// and otherwise ignored. At most `DefaultProgressiveMaxSize` bytes are read
+// after the header.
+func ParseProgressively(r io.Reader, ifdMapping *IfdMapping, tagIndex *TagIndex, visitor TagVisitorFn) (eh ExifHeader, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	br := bufio.NewReaderSize(r, progressiveReadChunkSize)
+
+	for {
+		window, err := br.Peek(ExifSignatureLength)
+		if err != nil {
+			if err == io.EOF {
+				return eh, ErrNoExif
+			}
+
+			log.Panic(err)
+		}
+
+		_, err = ParseExifHeader(window)
+		if err == nil {
+			break
+		} else if log.Is(err, ErrNoExif) == false {
+			log.Panic(err)
+		}
+
+		_, err = br.Discard(1)
+		log.PanicIf(err)
+	}
+
+	pp := NewProgressiveParser(ifdMapping, tagIndex, visitor)
+	chunk := make([]byte, progressiveReadChunkSize)
+
+	for pp.IsDone() == false {
+		n, err := br.Read(chunk)
+		if n > 0 {
+			_, err := pp.Write(chunk[:n])
+			log.PanicIf(err)
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	if pp.IsDone() == false {
+		progressiveLogger.Warningf(nil, "Stream ended with (%d) IFDs and (%d) tags unreachable.", len(pp.pendingIfds), len(pp.pendingTags))
+	}
+
+	return pp.Header(), nil
+}
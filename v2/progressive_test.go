@@ -0,0 +1,188 @@
+package exif
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"encoding/binary"
+	"io"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getVisitedTagsForTest(rawExif []byte) []string {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	visited := make([]string, 0)
+	visitor := func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) (err error) {
+		visited = append(visited, fmt.Sprintf("%s (0x%04x)", ite.IfdPath(), ite.TagId()))
+		return nil
+	}
+
+	_, err := Visit(exifcommon.IfdStandard, im, ti, rawExif, visitor)
+	log.PanicIf(err)
+
+	sort.Strings(visited)
+
+	return visited
+}
+
+func TestProgressiveParser_Write_Chunked(t *testing.T) {
+	rawExif := getTestExifData()
+	expected := getVisitedTagsForTest(rawExif)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	actual := make([]string, 0)
+	visitor := func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) (err error) {
+		// Make sure that the value is actually readable when we're told about
+		// it.
+		_, err = ite.GetRawBytes()
+		log.PanicIf(err)
+
+		actual = append(actual, fmt.Sprintf("%s (0x%04x)", ite.IfdPath(), ite.TagId()))
+		return nil
+	}
+
+	pp := NewProgressiveParser(im, ti, visitor)
+
+	for i := 0; i < len(rawExif) && pp.IsDone() == false; i += 7 {
+		end := i + 7
+		if end > len(rawExif) {
+			end = len(rawExif)
+		}
+
+		_, err := pp.Write(rawExif[i:end])
+		log.PanicIf(err)
+	}
+
+	if pp.IsDone() != true {
+		t.Fatalf("Parser not done.")
+	}
+
+	sort.Strings(actual)
+
+	if reflect.DeepEqual(actual, expected) != true {
+		t.Fatalf("Visited tags not correct:\nACTUAL: %v\nEXPECTED: %v", actual, expected)
+	}
+}
+
+func TestProgressiveParser_Write_NoExif(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	pp := NewProgressiveParser(im, ti, nil)
+
+	_, err := pp.Write([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07})
+	if err != ErrNoExif {
+		t.Fatalf("Expected no-EXIF error: %v", err)
+	}
+}
+
+func TestParseProgressively(t *testing.T) {
+	rawExif := getTestExifData()
+	expected := getVisitedTagsForTest(rawExif)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	actual := make([]string, 0)
+	visitor := func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) (err error) {
+		actual = append(actual, fmt.Sprintf("%s (0x%04x)", ite.IfdPath(), ite.TagId()))
+		return nil
+	}
+
+	// Put some garbage in front of the EXIF data, as we'd see in a real image.
+	data := append([]byte{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x00}, rawExif...)
+
+	eh, err := ParseProgressively(bytes.NewBuffer(data), im, ti, visitor)
+	log.PanicIf(err)
+
+	if eh.FirstIfdOffset != 8 {
+		t.Fatalf("Header not correct: %s", eh)
+	}
+
+	sort.Strings(actual)
+
+	if reflect.DeepEqual(actual, expected) != true {
+		t.Fatalf("Visited tags not correct:\nACTUAL: %v\nEXPECTED: %v", actual, expected)
+	}
+}
+
+// countingReader counts the bytes read from the reader.
+type countingReader struct {
+	r     io.Reader
+	count int
+}
+
+func (cr *countingReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+	cr.count += n
+
+	return n, err
+}
+
+func TestParseProgressively_Unreachable(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	// The size of DateTimeOriginal can't be addressed.
+	ite := index.Lookup[exifcommon.IfdPathStandardExif][0].EntriesByTagId[0x9003][0]
+
+	broken := exiftest.PutUint16(rawExif, int(ite.EntryOffset())+2, uint16(exifcommon.TypeLong))
+	broken = exiftest.PutUint32(broken, int(ite.EntryOffset())+4, 0x40000001)
+
+	visited := 0
+	visitor := func(fqIfdPath string, ifdIndex int, ite *IfdTagEntry) (err error) {
+		visited++
+		return nil
+	}
+
+	cr := &countingReader{
+		r: io.MultiReader(bytes.NewReader(broken), bytes.NewReader(make([]byte, 20*1024*1024))),
+	}
+
+	_, err = ParseProgressively(cr, NewIfdMappingWithStandard(), NewTagIndex(), visitor)
+	log.PanicIf(err)
+
+	if visited != len(getVisitedTagsForTest(rawExif))-1 {
+		t.Fatalf("Visited count not correct: (%d)", visited)
+	} else if cr.count > len(broken)+2*progressiveReadChunkSize {
+		t.Fatalf("Too much read: (%d)", cr.count)
+	}
+}
+
+func TestProgressiveParser_SetMaxSize(t *testing.T) {
+	rawExif := getTestExifData()
+
+	pp := NewProgressiveParser(NewIfdMappingWithStandard(), NewTagIndex(), nil)
+	pp.SetMaxSize(512)
+
+	for i := 0; i < 16 && pp.IsDone() == false; i++ {
+		n, err := pp.Write(rawExif)
+		log.PanicIf(err)
+
+		if n != len(rawExif) {
+			t.Fatalf("Write count not correct: (%d)", n)
+		}
+	}
+
+	if pp.IsDone() != true {
+		t.Fatalf("Parser not done.")
+	} else if len(pp.data) > 512 {
+		t.Fatalf("Too much buffered: (%d)", len(pp.data))
+	} else if pp.Unreachable() == 0 {
+		t.Fatalf("Unreachable IFDs and tags not counted.")
+	}
+}
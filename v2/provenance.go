@@ -0,0 +1,298 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+const (
+	// ProvenanceUnknown means that there wasn't enough evidence either way.
+	ProvenanceUnknown = "unknown"
+
+	// ProvenanceCamera means that the image appears to be straight out of
+	// the camera.
+	ProvenanceCamera = "camera"
+
+	// ProvenanceEdited means that the image appears to have been modified by
+	// editing software.
+	ProvenanceEdited = "edited"
+
+	// ProvenanceScreenshot means that the image appears to be a screen
+	// capture.
+	ProvenanceScreenshot = "screenshot"
+)
+
+var (
+	// provenanceEditors are substrings of the Software tag written by
+	// editing applications (matched case-insensitively).
+	provenanceEditors = []string{
+		"photoshop",
+		"lightroom",
+		"camera raw",
+		"gimp",
+		"snapseed",
+		"affinity",
+		"pixelmator",
+		"capture one",
+		"darktable",
+		"rawtherapee",
+		"paint.net",
+		"acdsee",
+		"luminar",
+		"picasa",
+		"instagram",
+	}
+
+	// provenanceScreenshotters are substrings of the Software tag written
+	// by screen-capture tools.
+	provenanceScreenshotters = []string{
+		"screenshot",
+		"snipping tool",
+		"greenshot",
+		"shottr",
+	}
+
+	xmpHistoryActionRe       = regexp.MustCompile(`stEvt:action(?:="|>)(saved|converted|derived|edited|produced)`)
+	xmpCreatorToolRe         = regexp.MustCompile(`xmp:CreatorTool(?:="|>)([^"<]+)`)
+	xmpScreenshotCommentRe   = regexp.MustCompile(`(?i)UserComment(?:="|>)\s*(?:<rdf:Alt>\s*<rdf:li[^>]*>)?\s*Screenshot`)
+	provenanceFirmwareLikeRe = regexp.MustCompile(`^(?i)(ver(sion)?\.?\s*)?[0-9][0-9.]*[a-z]?$|^iOS [0-9.]+$|^Android `)
+)
+
+// ProvenanceEvidence is one observation and the conclusion that it supports.
+type ProvenanceEvidence struct {
+	// Conclusion is one of the Provenance* constants.
+	Conclusion string
+
+	// Source is where the evidence came from (e.g. "IFD/Software" or
+	// "XMP/History").
+	Source string
+
+	Detail string
+}
+
+// String returns a descriptive string.
+func (pe ProvenanceEvidence) String() string {
+	return fmt.Sprintf("ProvenanceEvidence<CONCLUSION=[%s] SOURCE=[%s] DETAIL=[%s]>", pe.Conclusion, pe.Source, pe.Detail)
+}
+
+// ProvenanceReport is the result of classifying an image.
+type ProvenanceReport struct {
+	// Classification is one of the Provenance* constants.
+	Classification string
+
+	Evidence []ProvenanceEvidence
+}
+
+// EvidenceFor returns the evidence supporting the given conclusion.
+func (pr ProvenanceReport) EvidenceFor(conclusion string) []ProvenanceEvidence {
+	evidence := make([]ProvenanceEvidence, 0)
+
+	for _, pe := range pr.Evidence {
+		if pe.Conclusion == conclusion {
+			evidence = append(evidence, pe)
+		}
+	}
+
+	return evidence
+}
+
+func (pr *ProvenanceReport) add(conclusion, source, format string, args ...interface{}) {
+	pe := ProvenanceEvidence{
+		Conclusion: conclusion,
+		Source:     source,
+		Detail:     fmt.Sprintf(format, args...),
+	}
+
+	pr.Evidence = append(pr.Evidence, pe)
+}
+
+// ClassifyProvenance inspects the EXIF and (optional) XMP for signs of how the
+// image was produced. Screenshot evidence takes precedence over editing
+// evidence, which takes precedence over camera evidence. These are
+// heuristics: metadata is easily stripped or forged, so the evidence should
+// be weighed rather than the classification trusted outright.
+func ClassifyProvenance(index IfdIndex, xmp []byte) (pr ProvenanceReport, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	pr.Evidence = make([]ProvenanceEvidence, 0)
+
+	if index.RootIfd != nil {
+		err := pr.inspectExif(index)
+		log.PanicIf(err)
+	}
+
+	if xmp != nil {
+		pr.inspectXmp(xmp)
+	}
+
+	if len(pr.EvidenceFor(ProvenanceScreenshot)) > 0 {
+		pr.Classification = ProvenanceScreenshot
+	} else if len(pr.EvidenceFor(ProvenanceEdited)) > 0 {
+		pr.Classification = ProvenanceEdited
+	} else if len(pr.EvidenceFor(ProvenanceCamera)) > 0 {
+		pr.Classification = ProvenanceCamera
+	} else {
+		pr.Classification = ProvenanceUnknown
+	}
+
+	return pr, nil
+}
+
+func (pr *ProvenanceReport) inspectExif(index IfdIndex) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rootIfd := index.RootIfd
+
+	var exifIfd *Ifd
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd = ifds[0]
+	}
+
+	software, err := getIfdTagString(rootIfd, "Software")
+	log.PanicIf(err)
+
+	if software != "" {
+		lowered := strings.ToLower(software)
+
+		if provenanceMatchesAny(lowered, provenanceScreenshotters) == true {
+			pr.add(ProvenanceScreenshot, "IFD/Software", "written by a screen-capture tool: [%s]", software)
+		} else if provenanceMatchesAny(lowered, provenanceEditors) == true {
+			pr.add(ProvenanceEdited, "IFD/Software", "written by an editor: [%s]", software)
+		} else if provenanceFirmwareLikeRe.MatchString(software) == true {
+			pr.add(ProvenanceCamera, "IFD/Software", "looks like a firmware version: [%s]", software)
+		}
+	}
+
+	processingSoftware, err := getIfdTagString(rootIfd, "ProcessingSoftware")
+	log.PanicIf(err)
+
+	if processingSoftware != "" {
+		pr.add(ProvenanceEdited, "IFD/ProcessingSoftware", "processed by [%s]", processingSoftware)
+	}
+
+	make_, err := getIfdTagString(rootIfd, "Make")
+	log.PanicIf(err)
+
+	model, err := getIfdTagString(rootIfd, "Model")
+	log.PanicIf(err)
+
+	if exifIfd == nil {
+		return nil
+	}
+
+	exposureTime, err := getIfdTagNumber(exifIfd, "ExposureTime")
+	log.PanicIf(err)
+
+	if make_ != "" && model != "" && exposureTime != 0 {
+		pr.add(ProvenanceCamera, "IFD/Make", "capture settings recorded by [%s %s]", make_, model)
+	}
+
+	dateTime, err := getIfdTagString(rootIfd, "DateTime")
+	log.PanicIf(err)
+
+	dateTimeOriginal, err := getIfdTagString(exifIfd, "DateTimeOriginal")
+	log.PanicIf(err)
+
+	if dateTime != "" && dateTimeOriginal != "" && dateTime != dateTimeOriginal {
+		pr.add(ProvenanceEdited, "IFD/DateTime", "modified [%s] after capture [%s]", dateTime, dateTimeOriginal)
+	}
+
+	results, err := exifIfd.FindTagWithName("UserComment")
+	if err == nil {
+		value, err := results[0].Value()
+		log.PanicIf(err)
+
+		if uc, ok := value.(exifundefined.Tag9286UserComment); ok == true {
+			comment := strings.TrimSpace(strings.Trim(string(uc.EncodingBytes), "\x00"))
+			if strings.EqualFold(comment, "Screenshot") == true {
+				pr.add(ProvenanceScreenshot, "Exif/UserComment", "marked as a screenshot")
+			}
+		}
+	} else if log.Is(err, ErrTagNotFound) == false {
+		log.Panic(err)
+	}
+
+	amn, err := getAppleMakerNote(index)
+	log.PanicIf(err)
+
+	if amn != nil {
+		pr.add(ProvenanceCamera, "Exif/MakerNote", "Apple maker-note present (editors usually drop it)")
+	} else if _, err := exifIfd.FindTagWithName("MakerNote"); err == nil {
+		pr.add(ProvenanceCamera, "Exif/MakerNote", "maker-note present")
+	}
+
+	return nil
+}
+
+func (pr *ProvenanceReport) inspectXmp(xmp []byte) {
+	for _, matches := range xmpHistoryActionRe.FindAllSubmatch(xmp, -1) {
+		pr.add(ProvenanceEdited, "XMP/History", "history action [%s]", matches[1])
+	}
+
+	if matches := xmpCreatorToolRe.FindSubmatch(xmp); matches != nil {
+		creatorTool := string(matches[1])
+		lowered := strings.ToLower(creatorTool)
+
+		if provenanceMatchesAny(lowered, provenanceScreenshotters) == true {
+			pr.add(ProvenanceScreenshot, "XMP/CreatorTool", "created by a screen-capture tool: [%s]", creatorTool)
+		} else if provenanceMatchesAny(lowered, provenanceEditors) == true {
+			pr.add(ProvenanceEdited, "XMP/CreatorTool", "created by an editor: [%s]", creatorTool)
+		}
+	}
+
+	if xmpScreenshotCommentRe.Match(xmp) == true {
+		pr.add(ProvenanceScreenshot, "XMP/UserComment", "marked as a screenshot")
+	}
+}
+
+func provenanceMatchesAny(lowered string, substrings []string) bool {
+	for _, s := range substrings {
+		if strings.Contains(lowered, s) == true {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetProvenance classifies an image file using its EXIF and XMP. Images with
+// neither are reported as unknown.
+func GetProvenance(data []byte) (pr ProvenanceReport, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	var index IfdIndex
+
+	rawExif, err := SearchAndExtractExif(data)
+	if err == nil {
+		_, index, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+		log.PanicIf(err)
+	} else if err != ErrNoExif {
+		log.Panic(err)
+	}
+
+	pr, err = ClassifyProvenance(index, findXmpPacket(data))
+	log.PanicIf(err)
+
+	return pr, nil
+}
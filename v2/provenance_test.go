@@ -0,0 +1,121 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestProvenanceIndex(software string) IfdIndex {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: 0x0131, Value: software})
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestClassifyProvenance_Camera(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	pr, err := ClassifyProvenance(index, nil)
+	log.PanicIf(err)
+
+	if pr.Classification != ProvenanceCamera {
+		t.Fatalf("Classification not correct: [%s] %v", pr.Classification, pr.Evidence)
+	} else if len(pr.EvidenceFor(ProvenanceCamera)) != 2 {
+		t.Fatalf("Evidence not correct: %v", pr.Evidence)
+	}
+}
+
+func TestClassifyProvenance_FirmwareSoftware(t *testing.T) {
+	pr, err := ClassifyProvenance(getTestProvenanceIndex("Ver.1.1.3"), nil)
+	log.PanicIf(err)
+
+	if pr.Classification != ProvenanceCamera {
+		t.Fatalf("Classification not correct: [%s] %v", pr.Classification, pr.Evidence)
+	}
+
+	evidence := pr.EvidenceFor(ProvenanceCamera)
+	if evidence[0].Source != "IFD/Software" {
+		t.Fatalf("Evidence not correct: %v", evidence)
+	}
+}
+
+func TestClassifyProvenance_Edited(t *testing.T) {
+	pr, err := ClassifyProvenance(getTestProvenanceIndex("Adobe Photoshop 24.0 (Macintosh)"), nil)
+	log.PanicIf(err)
+
+	if pr.Classification != ProvenanceEdited {
+		t.Fatalf("Classification not correct: [%s] %v", pr.Classification, pr.Evidence)
+	}
+
+	evidence := pr.EvidenceFor(ProvenanceEdited)
+	if len(evidence) != 1 || evidence[0].Source != "IFD/Software" {
+		t.Fatalf("Evidence not correct: %v", evidence)
+	}
+
+	// The camera evidence is still reported.
+	if len(pr.EvidenceFor(ProvenanceCamera)) == 0 {
+		t.Fatalf("Expected camera evidence too: %v", pr.Evidence)
+	}
+}
+
+func TestClassifyProvenance_XmpHistory(t *testing.T) {
+	xmp := []byte(`<x:xmpmeta><rdf:li stEvt:action="derived"/><rdf:li stEvt:action="saved"/></x:xmpmeta>`)
+
+	pr, err := ClassifyProvenance(IfdIndex{}, xmp)
+	log.PanicIf(err)
+
+	if pr.Classification != ProvenanceEdited {
+		t.Fatalf("Classification not correct: [%s]", pr.Classification)
+	} else if len(pr.EvidenceFor(ProvenanceEdited)) != 2 {
+		t.Fatalf("Evidence not correct: %v", pr.Evidence)
+	}
+}
+
+func TestClassifyProvenance_Screenshot(t *testing.T) {
+	xmp := []byte(`<x:xmpmeta><exif:UserComment><rdf:Alt><rdf:li xml:lang="x-default">Screenshot</rdf:li></rdf:Alt></exif:UserComment></x:xmpmeta>`)
+
+	pr, err := ClassifyProvenance(IfdIndex{}, xmp)
+	log.PanicIf(err)
+
+	if pr.Classification != ProvenanceScreenshot {
+		t.Fatalf("Classification not correct: [%s]", pr.Classification)
+	}
+}
+
+func TestGetProvenance(t *testing.T) {
+	xmp := `<x:xmpmeta xmp:CreatorTool="GIMP 2.10"></x:xmpmeta>`
+
+	data, err := SetJpegXmp(getTestJpegWithExif(), []byte(xmp))
+	log.PanicIf(err)
+
+	pr, err := GetProvenance(data)
+	log.PanicIf(err)
+
+	if pr.Classification != ProvenanceEdited {
+		t.Fatalf("Classification not correct: [%s] %v", pr.Classification, pr.Evidence)
+	}
+}
+
+func TestGetProvenance_Unknown(t *testing.T) {
+	pr, err := GetProvenance([]byte("no metadata here"))
+	log.PanicIf(err)
+
+	if pr.Classification != ProvenanceUnknown || len(pr.Evidence) != 0 {
+		t.Fatalf("Expected unknown: %v", pr)
+	}
+}
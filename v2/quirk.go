@@ -0,0 +1,308 @@
+package exif
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	makeTagId        = 0x010f
+	modelTagId       = 0x0110
+	xResolutionTagId = 0x011a
+	yResolutionTagId = 0x011b
+
+	// defaultResolution is what a missing or bogus resolution means,
+	// according to the specification.
+	defaultResolution = 72
+)
+
+// QuirkEntry is an entry as a quirk sees it while it's parsed, before its
+// value is read. Changes to the type, the count, and the value offset are
+// kept.
+type QuirkEntry struct {
+	IfdPath     string
+	TagId       uint16
+	TagType     exifcommon.TagTypePrimitive
+	UnitCount   uint32
+	ValueOffset uint32
+}
+
+// Quirk adjusts the parse of the files from some cameras, which write
+// something that's known to be wrong.
+type Quirk struct {
+	// Name identifies the quirk.
+	Name string
+
+	// Make and Model select the cameras: each is matched against the start of
+	// the tag, ignoring case and surrounding spaces. An empty one matches
+	// anything.
+	Make  string
+	Model string
+
+	// TagIds, if not empty, are the only tags that the quirk is given.
+	TagIds []uint16
+
+	// FixEntry, if not nil, adjusts the entry and returns true if it changed
+	// anything.
+	FixEntry func(qe *QuirkEntry) bool
+
+	// Normalize, if not nil, returns the value that should be decoded for the
+	// entry instead and true, or false to leave it as it is.
+	Normalize func(ifdPath string, tagId uint16, value interface{}) (normalized interface{}, changed bool)
+}
+
+// matches returns true if the quirk applies to the camera.
+func (q Quirk) matches(make_, model string) bool {
+	return quirkPrefixMatches(make_, q.Make) && quirkPrefixMatches(model, q.Model)
+}
+
+// hasTag returns true if the quirk is given the tag.
+func (q Quirk) hasTag(tagId uint16) bool {
+	if len(q.TagIds) == 0 {
+		return true
+	}
+
+	for _, id := range q.TagIds {
+		if id == tagId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quirkPrefixMatches returns true if the value starts with the prefix,
+// ignoring case and surrounding spaces.
+func quirkPrefixMatches(value, prefix string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+
+	return strings.HasPrefix(value, prefix)
+}
+
+var (
+	quirks      = make([]Quirk, 0)
+	quirksMutex sync.RWMutex
+)
+
+// RegisterQuirk adds a quirk. It applies to whatever is parsed afterward.
+func RegisterQuirk(q Quirk) {
+	quirksMutex.Lock()
+	defer quirksMutex.Unlock()
+
+	quirks = append(quirks, q)
+}
+
+// GetQuirks returns the quirks that apply to the camera, in the order that
+// they were registered.
+func GetQuirks(make_, model string) []Quirk {
+	quirksMutex.RLock()
+	defer quirksMutex.RUnlock()
+
+	return matchQuirks(quirks, make_, model)
+}
+
+// getRegisteredQuirks returns a copy of the registered quirks.
+func getRegisteredQuirks() []Quirk {
+	quirksMutex.RLock()
+	defer quirksMutex.RUnlock()
+
+	return append([]Quirk{}, quirks...)
+}
+
+// matchQuirks returns the quirks that apply to the camera.
+func matchQuirks(candidates []Quirk, make_, model string) []Quirk {
+	matched := make([]Quirk, 0)
+	for _, q := range candidates {
+		if q.matches(make_, model) == true {
+			matched = append(matched, q)
+		}
+	}
+
+	return matched
+}
+
+// SetQuirksEnabled determines whether the registered quirks are applied, which
+// they are by default. The Make and Model of the first IFD select them, so the
+// entries of that IFD that come before them (the image structure tags, in the
+// usual order) are only given the quirks that apply to any camera.
+func (ie *IfdEnumerate) SetQuirksEnabled(enabled bool) {
+	ie.quirksDisabled = (enabled == false)
+}
+
+// SetQuirks has the enumerator choose from the given quirks rather than the
+// registered ones (e.g. those of a `Registry`).
+func (ie *IfdEnumerate) SetQuirks(candidates []Quirk) {
+	ie.quirkCandidates = candidates
+	ie.quirksSelected = false
+}
+
+// selectQuirks chooses the quirks for the camera.
+func (ie *IfdEnumerate) selectQuirks() {
+	ie.quirksSelected = true
+
+	if ie.quirksDisabled == true {
+		ie.quirks = nil
+		return
+	}
+
+	if ie.quirkCandidates != nil {
+		ie.quirks = matchQuirks(ie.quirkCandidates, ie.quirkMake, ie.quirkModel)
+		return
+	}
+
+	ie.quirks = GetQuirks(ie.quirkMake, ie.quirkModel)
+}
+
+// fixQuirkEntry applies the quirks to the entry before its value is read.
+func (ie *IfdEnumerate) fixQuirkEntry(ite *IfdTagEntry, enumerator *IfdTagEnumerator) {
+	if ie.quirksSelected == false {
+		ie.selectQuirks()
+	}
+
+	qe := QuirkEntry{
+		IfdPath:     ite.ifdPath,
+		TagId:       ite.tagId,
+		TagType:     ite.tagType,
+		UnitCount:   ite.unitCount,
+		ValueOffset: ite.valueOffset,
+	}
+
+	changed := false
+	for _, q := range ie.quirks {
+		if q.FixEntry != nil && q.hasTag(qe.TagId) == true && q.FixEntry(&qe) == true {
+			changed = true
+		}
+	}
+
+	if changed == false {
+		return
+	}
+
+	ie.counters.QuirkedEntries++
+
+	ite.tagType = qe.TagType
+	ite.unitCount = qe.UnitCount
+
+	if qe.ValueOffset != ite.valueOffset {
+		ite.valueOffset = qe.ValueOffset
+
+		ite.rawValueOffset = make([]byte, 4)
+		enumerator.byteOrder.PutUint32(ite.rawValueOffset, qe.ValueOffset)
+	}
+}
+
+// normalizeQuirkEntry applies the quirks to the value of the entry, and
+// selects the quirks again once the Make or Model of the first IFD is read.
+func (ie *IfdEnumerate) normalizeQuirkEntry(fqIfdPath string, ite *IfdTagEntry) {
+	if fqIfdPath == exifcommon.IfdPathStandard && (ite.tagId == makeTagId || ite.tagId == modelTagId) {
+		if value, err := ite.Value(); err == nil {
+			if s, ok := value.(string); ok == true {
+				if ite.tagId == makeTagId {
+					ie.quirkMake = s
+				} else {
+					ie.quirkModel = s
+				}
+
+				ie.selectQuirks()
+			}
+		}
+	}
+
+	var value interface{}
+	decoded := false
+	changed := false
+
+	for _, q := range ie.quirks {
+		if q.Normalize == nil || q.hasTag(ite.tagId) == false {
+			continue
+		}
+
+		if decoded == false {
+			var err error
+
+			value, err = ite.Value()
+			if err != nil {
+				return
+			}
+
+			decoded = true
+		}
+
+		if normalized, normalizedChanged := q.Normalize(ite.ifdPath, ite.tagId, value); normalizedChanged == true {
+			value = normalized
+			changed = true
+		}
+	}
+
+	if changed == false {
+		return
+	}
+
+	ie.counters.QuirkedEntries++
+
+	ite.decoded = &decodedValue{
+		value: value,
+	}
+}
+
+// fixMakerNoteType counts a MakerNote that's written with a numeric type in
+// bytes, as UNDEFINED, so that it can be read like any other.
+func fixMakerNoteType(qe *QuirkEntry) bool {
+	if qe.IfdPath != exifcommon.IfdPathStandardExif || qe.TagType == exifcommon.TypeUndefined || qe.TagType == exifcommon.TypeByte {
+		return false
+	}
+
+	size, err := exifcommon.CheckedMultiply(qe.UnitCount, qe.TagType.Size())
+	if err != nil {
+		return false
+	}
+
+	qe.TagType = exifcommon.TypeUndefined
+	qe.UnitCount = size
+
+	return true
+}
+
+// normalizeResolution replaces a resolution that's zero or has a zero
+// denominator with the default.
+func normalizeResolution(ifdPath string, tagId uint16, value interface{}) (normalized interface{}, changed bool) {
+	rationals, ok := value.([]exifcommon.Rational)
+	if ok == false || len(rationals) != 1 || ifdPath != exifcommon.IfdPathStandard {
+		return nil, false
+	} else if rationals[0].Numerator != 0 && rationals[0].Denominator != 0 {
+		return nil, false
+	}
+
+	return []exifcommon.Rational{{Numerator: defaultResolution, Denominator: 1}}, true
+}
+
+// StandardQuirks returns the quirks for mistakes that many cameras make: a
+// MakerNote written with a numeric type is read as UNDEFINED
+// ("maker-note-numeric-type"), and a resolution of zero (or with a zero
+// denominator) is decoded as the default of 72 ("zero-resolution"). They
+// change what the file says, so they aren't registered by default; use
+// `RegisterStandardQuirks()` or `(*IfdEnumerate).SetQuirks()`.
+func StandardQuirks() []Quirk {
+	return []Quirk{
+		{
+			Name:     "maker-note-numeric-type",
+			TagIds:   []uint16{makerNoteTagId},
+			FixEntry: fixMakerNoteType,
+		},
+		{
+			Name:      "zero-resolution",
+			TagIds:    []uint16{xResolutionTagId, yResolutionTagId},
+			Normalize: normalizeResolution,
+		},
+	}
+}
+
+// RegisterStandardQuirks registers the quirks of `StandardQuirks()`.
+func RegisterStandardQuirks() {
+	for _, q := range StandardQuirks() {
+		RegisterQuirk(q)
+	}
+}
@@ -0,0 +1,174 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// collectTestQuirks parses the EXIF with quirks enabled or not. The standard
+// quirks are applied along with the registered ones.
+func collectTestQuirks(rawExif []byte, enabled bool) (index IfdIndex, counters ParseCounters) {
+	eh, err := ParseExifHeader(rawExif)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, eh.ByteOrder)
+	ie.SetQuirks(append(getRegisteredQuirks(), StandardQuirks()...))
+	ie.SetQuirksEnabled(enabled)
+
+	index, err = ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+
+	return index, ie.Counters()
+}
+
+// getTestQuirkValue returns the value of the first tag with the ID in the IFD.
+func getTestQuirkValue(ifd *Ifd, tagId uint16) interface{} {
+	value, err := ifd.EntriesByTagId[tagId][0].Value()
+	log.PanicIf(err)
+
+	return value
+}
+
+func TestGetQuirks(t *testing.T) {
+	original := quirks
+	defer func() {
+		quirks = original
+	}()
+
+	RegisterStandardQuirks()
+
+	RegisterQuirk(Quirk{
+		Name:  "acme",
+		Make:  "ACME",
+		Model: "Roadrunner",
+	})
+
+	hasQuirk := func(quirks []Quirk, name string) bool {
+		for _, q := range quirks {
+			if q.Name == name {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if quirks := GetQuirks("Acme Corp.  ", "Roadrunner 2"); hasQuirk(quirks, "acme") != true {
+		t.Fatalf("Quirk not selected: %v", quirks)
+	} else if hasQuirk(quirks, "zero-resolution") != true {
+		t.Fatalf("Quirk for any camera not selected: %v", quirks)
+	} else if quirks := GetQuirks("Acme Corp.", "Coyote"); hasQuirk(quirks, "acme") != false {
+		t.Fatalf("Quirk selected for another model: %v", quirks)
+	} else if quirks := GetQuirks("Canon", "Roadrunner"); hasQuirk(quirks, "acme") != false {
+		t.Fatalf("Quirk selected for another make: %v", quirks)
+	}
+}
+
+func TestQuirk_ZeroResolution(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: xResolutionTagId, Value: []exifcommon.Rational{{Numerator: 0, Denominator: 0}}})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	index, counters := collectTestQuirks(rawExif, true)
+
+	value := getTestQuirkValue(index.RootIfd, xResolutionTagId).([]exifcommon.Rational)
+	if value[0].Numerator != 72 || value[0].Denominator != 1 {
+		t.Fatalf("Resolution not normalized: %v", value)
+	} else if counters.QuirkedEntries != 1 {
+		t.Fatalf("Quirked entries not counted: %s", counters)
+	}
+
+	index, counters = collectTestQuirks(rawExif, false)
+
+	value = getTestQuirkValue(index.RootIfd, xResolutionTagId).([]exifcommon.Rational)
+	if value[0].Numerator != 0 || value[0].Denominator != 0 {
+		t.Fatalf("Resolution normalized with quirks disabled: %v", value)
+	} else if counters.QuirkedEntries != 0 {
+		t.Fatalf("Quirked entries counted with quirks disabled: %s", counters)
+	}
+
+	// The standard quirks aren't applied unless they're asked for.
+	_, index, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	value = getTestQuirkValue(index.RootIfd, xResolutionTagId).([]exifcommon.Rational)
+	if value[0].Numerator != 0 || value[0].Denominator != 0 {
+		t.Fatalf("Resolution normalized by default: %v", value)
+	}
+}
+
+func TestQuirk_MakerNoteType(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, exiftest.Tag{Id: makerNoteTagId, Value: []uint32{0x01020304, 0x05060708}})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	index, _ := collectTestQuirks(rawExif, true)
+
+	ite := index.Lookup[exifcommon.IfdPathStandardExif][0].EntriesByTagId[makerNoteTagId][0]
+
+	rawBytes, err := ite.GetRawBytes()
+	log.PanicIf(err)
+
+	if ite.TagType() != exifcommon.TypeUndefined || ite.UnitCount() != 8 {
+		t.Fatalf("MakerNote type not fixed: %s", ite)
+	} else if string(rawBytes) != "\x01\x02\x03\x04\x05\x06\x07\x08" {
+		t.Fatalf("MakerNote not correct: %v", rawBytes)
+	}
+}
+
+func TestRegisterQuirk_OffByOne(t *testing.T) {
+	original := quirks
+	defer func() {
+		quirks = original
+	}()
+
+	// A camera that writes the offset of DateTimeOriginal one short.
+	RegisterQuirk(Quirk{
+		Name:   "acme-offset",
+		Make:   "Acme",
+		Model:  "Roadrunner",
+		TagIds: []uint16{0x9003},
+		FixEntry: func(qe *QuirkEntry) bool {
+			qe.ValueOffset++
+			return true
+		},
+	})
+
+	root := exiftest.NewRealisticIfd()
+	root.Tags[0].Value = "Acme"
+	root.Tags[1].Value = "Roadrunner 2"
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	index, _ := collectTestQuirks(rawExif, false)
+	ite := index.Lookup[exifcommon.IfdPathStandardExif][0].EntriesByTagId[0x9003][0]
+
+	broken := exiftest.PutUint32(rawExif, int(ite.EntryOffset())+8, ite.getValueOffset()-1)
+
+	index, counters := collectTestQuirks(broken, true)
+
+	if value := getTestQuirkValue(index.Lookup[exifcommon.IfdPathStandardExif][0], 0x9003).(string); value != "2020:01:02 03:04:05" {
+		t.Fatalf("Offset not fixed: [%s]", value)
+	} else if counters.QuirkedEntries != 1 {
+		t.Fatalf("Quirked entries not counted: %s", counters)
+	}
+
+	index, _ = collectTestQuirks(broken, false)
+
+	if value := getTestQuirkValue(index.Lookup[exifcommon.IfdPathStandardExif][0], 0x9003).(string); value == "2020:01:02 03:04:05" {
+		t.Fatalf("Offset fixed with quirks disabled.")
+	}
+}
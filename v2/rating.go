@@ -0,0 +1,177 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// RatingTagId is the ID of the Rating tag (0-5 stars) in IFD0.
+	RatingTagId = 0x4746
+
+	// RatingPercentTagId is the ID of the RatingPercent tag in IFD0.
+	RatingPercentTagId = 0x4749
+
+	// XmpNamespace is the namespace of the basic XMP properties (e.g.
+	// xmp:Rating and xmp:CreatorTool).
+	XmpNamespace = "http://ns.adobe.com/xap/1.0/"
+)
+
+const (
+	// RatingRejected marks a rejected image. Only XMP can record it.
+	RatingRejected = -1
+
+	// RatingUnrated is an image that hasn't been rated.
+	RatingUnrated = 0
+
+	// RatingMax is the highest number of stars.
+	RatingMax = 5
+)
+
+var (
+	// ErrNoRating means that no rating was found.
+	ErrNoRating = errors.New("no rating")
+)
+
+var (
+	// ratingPercents is the RatingPercent that Windows writes for each number
+	// of stars.
+	ratingPercents = []int{0, 1, 25, 50, 75, 99}
+)
+
+// Rating is a star rating and where it was read from ("IFD/Rating",
+// "IFD/RatingPercent", or "XMP/Rating").
+type Rating struct {
+	// Stars is from zero (unrated) to five, or `RatingRejected`.
+	Stars int
+
+	// Percent is the RatingPercent, or the Windows equivalent of `Stars` if
+	// the tag isn't present.
+	Percent int
+
+	Source string
+}
+
+// String returns a descriptive string.
+func (r Rating) String() string {
+	return fmt.Sprintf("Rating<STARS=(%d) PERCENT=(%d) SOURCE=[%s]>", r.Stars, r.Percent, r.Source)
+}
+
+// starsFromPercent converts a RatingPercent to stars the same way Windows
+// does.
+func starsFromPercent(percent int) int {
+	switch {
+	case percent <= 0:
+		return 0
+	case percent < 13:
+		return 1
+	case percent < 38:
+		return 2
+	case percent < 63:
+		return 3
+	case percent < 88:
+		return 4
+	}
+
+	return 5
+}
+
+// GetRating returns the rating from the Rating tag, then the RatingPercent
+// tag, and then the xmp:Rating property of the XMP packet (which may be nil).
+// `ErrNoRating` is returned if none of them are present.
+func GetRating(index IfdIndex, xmp []byte) (r Rating, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	r.Percent = -1
+
+	if index.RootIfd != nil {
+		if _, err := index.RootIfd.FindTagWithId(RatingPercentTagId); err == nil {
+			percent, err := getIfdTagNumber(index.RootIfd, "RatingPercent")
+			log.PanicIf(err)
+
+			r.Percent = int(percent)
+			r.Stars = starsFromPercent(r.Percent)
+			r.Source = "IFD/RatingPercent"
+		}
+
+		if _, err := index.RootIfd.FindTagWithId(RatingTagId); err == nil {
+			stars, err := getIfdTagNumber(index.RootIfd, "Rating")
+			log.PanicIf(err)
+
+			r.Stars = int(stars)
+			r.Source = "IFD/Rating"
+		}
+	}
+
+	if r.Source == "" && xmp != nil {
+		if value := findXmpPropertyValue(xmp, "xmp:Rating"); value != nil {
+			if stars, err := strconv.ParseFloat(strings.TrimSpace(string(value)), 64); err == nil {
+				r.Stars = int(stars)
+				r.Source = "XMP/Rating"
+			}
+		}
+	}
+
+	if r.Source == "" {
+		return r, ErrNoRating
+	}
+
+	if r.Stars > RatingMax {
+		r.Stars = RatingMax
+	} else if r.Stars < RatingRejected {
+		r.Stars = RatingRejected
+	}
+
+	if r.Percent == -1 && r.Stars >= 0 {
+		r.Percent = ratingPercents[r.Stars]
+	} else if r.Percent == -1 {
+		r.Percent = 0
+	}
+
+	return r, nil
+}
+
+// SetRating writes the Rating and RatingPercent tags into IFD0.
+// `RatingRejected` can't be represented in EXIF, so the tags are removed
+// instead (use `MergeRatingIntoXmp()` to record it).
+func SetRating(rootIb *IfdBuilder, stars int) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if rootIb.ifdPath != exifcommon.IfdStandard {
+		log.Panicf("rating can only be set on the root IFD: [%s]", rootIb.fqIfdPath)
+	} else if stars < RatingRejected || stars > RatingMax {
+		log.Panicf("rating not valid: (%d)", stars)
+	}
+
+	if stars == RatingRejected {
+		_, err := rootIb.DeleteAll(RatingTagId)
+		log.PanicIf(err)
+
+		_, err = rootIb.DeleteAll(RatingPercentTagId)
+		log.PanicIf(err)
+
+		return nil
+	}
+
+	err = rootIb.SetStandard(RatingTagId, []uint16{uint16(stars)})
+	log.PanicIf(err)
+
+	err = rootIb.SetStandard(RatingPercentTagId, []uint16{uint16(ratingPercents[stars])})
+	log.PanicIf(err)
+
+	return nil
+}
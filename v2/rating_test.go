@@ -0,0 +1,94 @@
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestGetRating(t *testing.T) {
+	rootTags := []exiftest.Tag{
+		{Id: RatingTagId, Value: []uint16{4}},
+	}
+
+	r, err := GetRating(getTestMakerNoteIndex("Acme", nil, rootTags, nil), nil)
+	log.PanicIf(err)
+
+	if r.Stars != 4 || r.Percent != 75 || r.Source != "IFD/Rating" {
+		t.Fatalf("Rating not correct: %s", r)
+	}
+}
+
+func TestGetRating_Percent(t *testing.T) {
+	rootTags := []exiftest.Tag{
+		{Id: RatingPercentTagId, Value: []uint16{50}},
+	}
+
+	r, err := GetRating(getTestMakerNoteIndex("Acme", nil, rootTags, nil), nil)
+	log.PanicIf(err)
+
+	if r.Stars != 3 || r.Percent != 50 || r.Source != "IFD/RatingPercent" {
+		t.Fatalf("Rating not correct: %s", r)
+	}
+}
+
+func TestGetRating_Xmp(t *testing.T) {
+	index := getTestMakerNoteIndex("Acme", nil, nil, nil)
+
+	r, err := GetRating(index, []byte(`<rdf:Description xmp:Rating="-1"/>`))
+	log.PanicIf(err)
+
+	if r.Stars != RatingRejected || r.Percent != 0 || r.Source != "XMP/Rating" {
+		t.Fatalf("Rating not correct: %s", r)
+	}
+
+	r, err = GetRating(index, []byte(`<xmp:Rating>2.0</xmp:Rating>`))
+	log.PanicIf(err)
+
+	if r.Stars != 2 || r.Percent != 25 {
+		t.Fatalf("Element rating not correct: %s", r)
+	}
+
+	_, err = GetRating(index, nil)
+	if err != ErrNoRating {
+		t.Fatalf("Expected ErrNoRating: %v", err)
+	}
+}
+
+func TestSetRating(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := SetRating(rootIb, 5)
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	r, err := GetRating(index, nil)
+	log.PanicIf(err)
+
+	if r.Stars != 5 || r.Percent != 99 {
+		t.Fatalf("Rating not correct: %s", r)
+	}
+
+	err = SetRating(rootIb, RatingRejected)
+	log.PanicIf(err)
+
+	if len(rootIb.Tags()) != 0 {
+		t.Fatalf("Rating tags not removed.")
+	}
+
+	err = SetRating(rootIb, 6)
+	if err == nil {
+		t.Fatalf("Expected error for invalid rating.")
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	xmpRatingAttributeRe = regexp.MustCompile(`\s+xmp:Rating="[^"]*"`)
+	xmpRatingElementRe   = regexp.MustCompile(`\s*<xmp:Rating>[^<]*</xmp:Rating>`)
+	xmpNamespaceRe       = regexp.MustCompile(`xmlns:xmp="`)
+)
+
+// MergeRatingIntoXmp returns the XMP packet with xmp:Rating set to the given
+// number of stars. Other properties are left alone. A new packet is created
+// if `xmp` is empty or has no description.
+func MergeRatingIntoXmp(xmp []byte, stars int) []byte {
+	attribute := fmt.Sprintf(" xmp:Rating=\"%d\"", stars)
+
+	location := rdfDescriptionRe.FindIndex(xmp)
+	if location == nil {
+		return []byte(fmt.Sprintf(xmpPacketTemplate, fmt.Sprintf(" xmlns:xmp=\"%s\"%s", XmpNamespace, attribute)))
+	}
+
+	cleaned := xmpRatingElementRe.ReplaceAll(xmp, nil)
+	cleaned = xmpRatingAttributeRe.ReplaceAll(cleaned, nil)
+
+	if xmpNamespaceRe.Match(cleaned) == false {
+		attribute = fmt.Sprintf(" xmlns:xmp=\"%s\"%s", XmpNamespace, attribute)
+	}
+
+	location = rdfDescriptionRe.FindIndex(cleaned)
+
+	merged := make([]byte, 0, len(cleaned)+len(attribute))
+	merged = append(merged, cleaned[:location[1]]...)
+	merged = append(merged, attribute...)
+	merged = append(merged, cleaned[location[1]:]...)
+
+	return merged
+}
@@ -0,0 +1,30 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestMergeRatingIntoXmp(t *testing.T) {
+	xmp := MergeRatingIntoXmp(nil, 3)
+
+	r, err := GetRating(IfdIndex{}, xmp)
+	log.PanicIf(err)
+
+	if r.Stars != 3 {
+		t.Fatalf("Rating not correct in new packet: %s", r)
+	}
+
+	original := []byte(`<x:xmpmeta><rdf:RDF><rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="1" xmp:CreatorTool="Acme"/></rdf:RDF></x:xmpmeta>`)
+
+	merged := MergeRatingIntoXmp(original, 4)
+
+	expected := `<x:xmpmeta><rdf:RDF><rdf:Description xmp:Rating="4" rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:CreatorTool="Acme"/></rdf:RDF></x:xmpmeta>`
+	if string(merged) != expected {
+		t.Fatalf("Merged XMP not correct: [%s]", merged)
+	}
+}
@@ -0,0 +1,179 @@
+package exif
+
+import (
+	"fmt"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// RawEntry is an IFD entry exactly as it's stored, with no knowledge of what
+// the tag is. The offsets are from the start of the EXIF data (the TIFF
+// header; see `ParseExifHeader()`).
+type RawEntry struct {
+	TagId     uint16
+	TagType   exifcommon.TagTypePrimitive
+	UnitCount uint32
+
+	// ValueOffset is the last four bytes of the entry, which are the value
+	// itself if it fits (see `IsEmbedded()`) and otherwise its offset.
+	ValueOffset [4]byte
+
+	// Offset is where the 12-byte entry is.
+	Offset uint32
+}
+
+// String returns a descriptive string.
+func (re RawEntry) String() string {
+	return fmt.Sprintf("RawEntry<TAG-ID=(0x%04x) TAG-TYPE=[%s] UNIT-COUNT=(%d) VALUE-OFFSET=%v OFFSET=(0x%08x)>", re.TagId, re.TagType, re.UnitCount, re.ValueOffset, re.Offset)
+}
+
+// raw returns the entry as the internal readers take it.
+func (re RawEntry) raw() rawIfdEntry {
+	return rawIfdEntry{
+		tagId:       re.TagId,
+		tagType:     re.TagType,
+		unitCount:   re.UnitCount,
+		valueOffset: re.ValueOffset,
+	}
+}
+
+// ValueSize returns the size of the value in bytes, or zero if the type isn't
+// valid or the size overflows.
+func (re RawEntry) ValueSize() uint32 {
+	return re.raw().valueSize()
+}
+
+// IsEmbedded returns true if the value is in the entry itself.
+func (re RawEntry) IsEmbedded() bool {
+	return re.ValueSize() <= 4
+}
+
+// ValueLocation returns where the value is: eight bytes into the entry if it's
+// embedded, and otherwise wherever `ValueOffset` points.
+func (re RawEntry) ValueLocation(byteOrder binary.ByteOrder) uint32 {
+	if re.IsEmbedded() == true {
+		return re.Offset + 8
+	}
+
+	return byteOrder.Uint32(re.ValueOffset[:])
+}
+
+// Value returns the raw bytes of the value. `exifcommon.ErrNotEnoughData` is
+// returned if it isn't in the data.
+func (re RawEntry) Value(data []byte, byteOrder binary.ByteOrder) (value []byte, err error) {
+	return readRawIfdValue(data, re.raw(), byteOrder)
+}
+
+// Uints returns the values of a SHORT or LONG entry (e.g. the offsets of
+// child IFDs or of image data), or nil if it has another type or its value
+// isn't in the data.
+func (re RawEntry) Uints(data []byte, byteOrder binary.ByteOrder) []uint32 {
+	return readRawIfdUints(data, re.raw(), byteOrder)
+}
+
+// RawIfd is an IFD table exactly as it's stored: the entries, in the order
+// that they're in, and the offset of the next IFD in the chain.
+type RawIfd struct {
+	Offset    uint32
+	ByteOrder binary.ByteOrder
+
+	Entries []RawEntry
+
+	// NextIfdOffset is zero if this is the last IFD in the chain.
+	NextIfdOffset uint32
+}
+
+// String returns a descriptive string.
+func (ri RawIfd) String() string {
+	return fmt.Sprintf("RawIfd<OFFSET=(0x%08x) ENTRIES=(%d) NEXT-IFD-OFFSET=(0x%08x)>", ri.Offset, len(ri.Entries), ri.NextIfdOffset)
+}
+
+// Size returns the size of the table: the entry count, the entries, and the
+// next-IFD offset.
+func (ri RawIfd) Size() uint32 {
+	return rawIfdTableSize(len(ri.Entries))
+}
+
+// Find returns the first entry with the given tag ID.
+func (ri RawIfd) Find(tagId uint16) (re RawEntry, found bool) {
+	for _, re := range ri.Entries {
+		if re.TagId == tagId {
+			return re, true
+		}
+	}
+
+	return re, false
+}
+
+// ReadRawIfd reads the IFD table at the given offset. Every entry is
+// returned, including ones with types that aren't valid, and nothing is
+// looked up or decoded. This is the same reader that the rest of the package
+// is built on, for parsing vendor-specific structures that the tag index
+// doesn't describe.
+func ReadRawIfd(data []byte, offset uint32, byteOrder binary.ByteOrder) (ri RawIfd, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	entries, nextIfdOffset, err := readRawIfdTable(data, offset, byteOrder)
+	log.PanicIf(err)
+
+	ri = RawIfd{
+		Offset:        offset,
+		ByteOrder:     byteOrder,
+		Entries:       make([]RawEntry, len(entries)),
+		NextIfdOffset: nextIfdOffset,
+	}
+
+	for i, rie := range entries {
+		ri.Entries[i] = RawEntry{
+			TagId:       rie.tagId,
+			TagType:     rie.tagType,
+			UnitCount:   rie.unitCount,
+			ValueOffset: rie.valueOffset,
+			Offset:      offset + 2 + uint32(i)*IfdTagEntrySize,
+		}
+	}
+
+	return ri, nil
+}
+
+// ReadRawIfdChain reads IFD0 (from the header) and every IFD after it in the
+// chain (e.g. IFD1). Child IFDs aren't followed, since which entries point to
+// them depends on what the tags are; use `RawEntry.Uints()` and
+// `ReadRawIfd()`. `ErrIfdCycle` is returned if the chain loops.
+func ReadRawIfdChain(data []byte) (eh ExifHeader, ifds []RawIfd, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	eh, err = ParseExifHeader(data)
+	log.PanicIf(err)
+
+	ifds = make([]RawIfd, 0)
+	seen := make(map[uint32]struct{})
+
+	for offset := eh.FirstIfdOffset; offset != 0; {
+		if _, found := seen[offset]; found == true {
+			log.Panic(ErrIfdCycle)
+		}
+
+		seen[offset] = struct{}{}
+
+		ri, err := ReadRawIfd(data, offset, eh.ByteOrder)
+		log.PanicIf(err)
+
+		ifds = append(ifds, ri)
+		offset = ri.NextIfdOffset
+	}
+
+	return eh, ifds, nil
+}
@@ -0,0 +1,125 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestRawIfdExif() []byte {
+	root := exiftest.NewRealisticIfd()
+
+	// A tag that isn't known. It's the last entry of IFD0.
+	root.Tags = append(root.Tags, exiftest.Tag{Id: 0xc000, Value: []uint32{1}})
+
+	root.Next = &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x0103, Value: []uint16{6}},
+		},
+	}
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	// Give it a type that isn't valid, which is still read.
+	return exiftest.PutUint16(rawExif, int(ExifDefaultFirstIfdOffset+2+6*IfdTagEntrySize+2), 99)
+}
+
+func TestReadRawIfdChain(t *testing.T) {
+	rawExif := getTestRawIfdExif()
+
+	eh, ifds, err := ReadRawIfdChain(rawExif)
+	log.PanicIf(err)
+
+	if eh.ByteOrder != binary.BigEndian || len(ifds) != 2 {
+		t.Fatalf("Chain not correct: %s %v", eh, ifds)
+	}
+
+	ifd0 := ifds[0]
+	if ifd0.Offset != ExifDefaultFirstIfdOffset || ifd0.NextIfdOffset != ifds[1].Offset || ifds[1].NextIfdOffset != 0 {
+		t.Fatalf("Offsets not correct: %v", ifds)
+	} else if len(ifd0.Entries) != 7 || ifd0.Size() != 2+7*IfdTagEntrySize+4 {
+		t.Fatalf("IFD0 entries not correct: %v", ifd0.Entries)
+	}
+
+	unknown, found := ifd0.Find(0xc000)
+	if found != true || unknown.TagType != 99 || unknown.ValueSize() != 0 {
+		t.Fatalf("Entry with an invalid type not correct: %s", unknown)
+	}
+
+	for i, re := range ifd0.Entries {
+		if binary.BigEndian.Uint16(rawExif[re.Offset:]) != re.TagId || re.Offset != ifd0.Offset+2+uint32(i)*IfdTagEntrySize {
+			t.Fatalf("Entry offset not correct: %s", re)
+		}
+	}
+}
+
+func TestRawEntry_Value(t *testing.T) {
+	rawExif := getTestRawIfdExif()
+
+	ri, err := ReadRawIfd(rawExif, ExifDefaultFirstIfdOffset, binary.BigEndian)
+	log.PanicIf(err)
+
+	make_, _ := ri.Find(0x010f)
+	orientation, _ := ri.Find(OrientationTagId)
+
+	value, err := make_.Value(rawExif, ri.ByteOrder)
+	log.PanicIf(err)
+
+	if make_.IsEmbedded() != false || bytes.Equal(value, []byte("Canon\x00")) != true {
+		t.Fatalf("External value not correct: %v", value)
+	} else if bytes.Equal(rawExif[make_.ValueLocation(ri.ByteOrder):][:6], value) != true {
+		t.Fatalf("External value location not correct: (%d)", make_.ValueLocation(ri.ByteOrder))
+	}
+
+	value, err = orientation.Value(rawExif, ri.ByteOrder)
+	log.PanicIf(err)
+
+	if orientation.IsEmbedded() != true || orientation.ValueLocation(ri.ByteOrder) != orientation.Offset+8 || bytes.Equal(value, []byte{0, 1}) != true {
+		t.Fatalf("Embedded value not correct: %v", value)
+	}
+
+	// Follow a child IFD.
+
+	exifPointer, found := ri.Find(exifcommon.IfdExifId)
+	if found != true {
+		t.Fatalf("Exif IFD pointer not found.")
+	}
+
+	offsets := exifPointer.Uints(rawExif, ri.ByteOrder)
+	if len(offsets) != 1 {
+		t.Fatalf("Exif IFD pointer not correct: %v", offsets)
+	}
+
+	exifIfd, err := ReadRawIfd(rawExif, offsets[0], ri.ByteOrder)
+	log.PanicIf(err)
+
+	if _, found := exifIfd.Find(0x829a); found != true {
+		t.Fatalf("ExposureTime not found in the Exif IFD: %v", exifIfd.Entries)
+	}
+
+	// A value that isn't in the data.
+
+	make_.ValueOffset = [4]byte{0x00, 0xff, 0xff, 0xf0}
+	if _, err := make_.Value(rawExif, ri.ByteOrder); err != exifcommon.ErrNotEnoughData {
+		t.Fatalf("Expected not-enough-data error: %v", err)
+	}
+}
+
+func TestReadRawIfdChain_Cycle(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+	root.NextOffset = ExifDefaultFirstIfdOffset
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	if _, _, err := ReadRawIfdChain(rawExif); log.Is(err, ErrIfdCycle) == false {
+		t.Fatalf("Expected cycle error: %v", err)
+	}
+}
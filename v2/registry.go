@@ -0,0 +1,460 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"sync/atomic"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// ErrPolicyNotFound means that a registry doesn't have a policy with the
+	// given name.
+	ErrPolicyNotFound = errors.New("policy not found")
+)
+
+var (
+	registryLogger = log.NewLogger("exif.registry")
+)
+
+// RegistryFiles are the files that a `Registry` is loaded from. They're all
+// JSON, so that they can be loaded by every build, and any of them can be
+// empty:
+//
+// TagsFilepath has the tags to add to the standard ones, by IFD path (e.g.
+// `{"IFD": [{"id": 50000, "name": "Custom", "type_name": "ASCII"}]}`).
+//
+// QuirksFilepath has a list of `QuirkRule`, which are applied after the
+// registered quirks.
+//
+// PoliciesFilepath has a list of `Pipeline`, by which redaction and other
+// policies are looked-up by name.
+type RegistryFiles struct {
+	TagsFilepath     string
+	QuirksFilepath   string
+	PoliciesFilepath string
+}
+
+// filepaths returns the files that aren't empty.
+func (rf RegistryFiles) filepaths() []string {
+	filepaths := make([]string, 0, 3)
+	for _, filepath := range []string{rf.TagsFilepath, rf.QuirksFilepath, rf.PoliciesFilepath} {
+		if filepath != "" {
+			filepaths = append(filepaths, filepath)
+		}
+	}
+
+	return filepaths
+}
+
+// QuirkRule is a quirk that's expressed as data. It sets the type, and
+// possibly the count, of the entries that it's given.
+type QuirkRule struct {
+	Name  string `json:"name"`
+	Make  string `json:"make"`
+	Model string `json:"model"`
+
+	// IfdPath, if not empty, is the only IFD whose entries are fixed.
+	IfdPath string `json:"ifd_path,omitempty"`
+
+	TagIds []uint16 `json:"tag_ids,omitempty"`
+
+	// TypeName is the type that the entries are read as (e.g. "UNDEFINED").
+	TypeName string `json:"type_name"`
+
+	// UnitCount, if not zero, is the count that the entries are read with.
+	// Otherwise, the count is changed so that the size of the value stays the
+	// same.
+	UnitCount uint32 `json:"unit_count,omitempty"`
+}
+
+// String returns a descriptive string.
+func (qr QuirkRule) String() string {
+	return fmt.Sprintf("QuirkRule<NAME=[%s] MAKE=[%s] MODEL=[%s] IFD-PATH=[%s] TYPE=[%s]>", qr.Name, qr.Make, qr.Model, qr.IfdPath, qr.TypeName)
+}
+
+// Quirk returns the rule as a `Quirk`.
+func (qr QuirkRule) Quirk() (q Quirk, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if qr.Name == "" {
+		log.Panicf("quirk rule has no name")
+	}
+
+	tagType, found := exifcommon.GetTypeByName(qr.TypeName)
+	if found == false {
+		log.Panicf("quirk rule [%s] has an invalid type: [%s]", qr.Name, qr.TypeName)
+	}
+
+	fixEntry := func(qe *QuirkEntry) bool {
+		if qr.IfdPath != "" && qe.IfdPath != qr.IfdPath {
+			return false
+		}
+
+		unitCount := qr.UnitCount
+		if unitCount == 0 {
+			unitCount = qe.UnitCount * uint32(qe.TagType.Size()) / uint32(tagType.Size())
+		}
+
+		if qe.TagType == tagType && qe.UnitCount == unitCount {
+			return false
+		}
+
+		qe.TagType = tagType
+		qe.UnitCount = unitCount
+
+		return true
+	}
+
+	q = Quirk{
+		Name:     qr.Name,
+		Make:     qr.Make,
+		Model:    qr.Model,
+		TagIds:   qr.TagIds,
+		FixEntry: fixEntry,
+	}
+
+	return q, nil
+}
+
+// Registry is the tags, quirks, and policies that a long-running service
+// parses with. It's loaded from files (see `RegistryFiles`) and isn't changed
+// once it's loaded, so it can be used by any number of goroutines while a
+// `RegistryReloader` loads the next one.
+type Registry struct {
+	ifdMapping *IfdMapping
+	tagIndex   *TagIndex
+	quirks     []Quirk
+	policies   map[string]Pipeline
+}
+
+// LoadRegistry loads a registry from the files. The standard tags and the
+// registered quirks are always included.
+func LoadRegistry(files RegistryFiles) (r *Registry, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	r = &Registry{
+		ifdMapping: NewIfdMappingWithStandard(),
+		tagIndex:   NewTagIndex(),
+		quirks:     getRegisteredQuirks(),
+		policies:   make(map[string]Pipeline),
+	}
+
+	// The standard tags are loaded now rather than on first use so that the
+	// index isn't changed while it's shared.
+
+	err = LoadStandardTags(r.tagIndex)
+	log.PanicIf(err)
+
+	if files.TagsFilepath != "" {
+		encodedIfds := make(map[string][]encodedTag)
+
+		err := loadRegistryFile(files.TagsFilepath, &encodedIfds)
+		log.PanicIf(err)
+
+		for ifdPath, tags := range encodedIfds {
+			for _, tagInfo := range tags {
+				tagType, found := exifcommon.GetTypeByName(tagInfo.TypeName)
+				if found == false {
+					log.Panicf("type [%s] for [%s] not valid", tagInfo.TypeName, tagInfo.Name)
+				}
+
+				it := &IndexedTag{
+					IfdPath: ifdPath,
+					Id:      uint16(tagInfo.Id),
+					Name:    tagInfo.Name,
+					Type:    tagType,
+				}
+
+				err := r.tagIndex.Add(it)
+				log.PanicIf(err)
+			}
+		}
+	}
+
+	if files.QuirksFilepath != "" {
+		rules := make([]QuirkRule, 0)
+
+		err := loadRegistryFile(files.QuirksFilepath, &rules)
+		log.PanicIf(err)
+
+		for _, qr := range rules {
+			q, err := qr.Quirk()
+			log.PanicIf(err)
+
+			r.quirks = append(r.quirks, q)
+		}
+	}
+
+	if files.PoliciesFilepath != "" {
+		pipelines := make([]Pipeline, 0)
+
+		err := loadRegistryFile(files.PoliciesFilepath, &pipelines)
+		log.PanicIf(err)
+
+		for _, p := range pipelines {
+			if p.Name == "" {
+				log.Panicf("policy has no name")
+			} else if _, found := r.policies[p.Name]; found == true {
+				log.Panicf("policy defined more than once: [%s]", p.Name)
+			}
+
+			err := p.Validate()
+			log.PanicIf(err)
+
+			r.policies[p.Name] = p
+		}
+	}
+
+	return r, nil
+}
+
+// loadRegistryFile decodes the JSON file into the value.
+func loadRegistryFile(filepath string, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	err = json.Unmarshal(data, value)
+	if err != nil {
+		log.Panicf("registry file [%s] not valid: %s", filepath, err)
+	}
+
+	return nil
+}
+
+// IfdMapping returns the IFD mapping to parse with.
+func (r *Registry) IfdMapping() *IfdMapping {
+	return r.ifdMapping
+}
+
+// TagIndex returns the standard tags and those from the tags file.
+func (r *Registry) TagIndex() *TagIndex {
+	return r.tagIndex
+}
+
+// Quirks returns the registered quirks and those from the quirks file.
+func (r *Registry) Quirks() []Quirk {
+	return r.quirks
+}
+
+// Policy returns the policy with the given name. `ErrPolicyNotFound` is
+// returned if there isn't one.
+func (r *Registry) Policy(name string) (p Pipeline, err error) {
+	p, found := r.policies[name]
+	if found == false {
+		return p, ErrPolicyNotFound
+	}
+
+	return p, nil
+}
+
+// PolicyNames returns the names of the policies, sorted.
+func (r *Registry) PolicyNames() []string {
+	names := make([]string, 0, len(r.policies))
+	for name := range r.policies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NewIfdEnumerate returns an enumerator that parses with the tags and quirks
+// of the registry.
+func (r *Registry) NewIfdEnumerate(exifData []byte, byteOrder binary.ByteOrder) *IfdEnumerate {
+	ie := NewIfdEnumerate(r.ifdMapping, r.tagIndex, exifData, byteOrder)
+	ie.SetQuirks(r.quirks)
+
+	return ie
+}
+
+// Collect is like `Collect()` but parses with the tags and quirks of the
+// registry.
+func (r *Registry) Collect(exifData []byte) (eh ExifHeader, index IfdIndex, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	eh, err = ParseExifHeader(exifData)
+	log.PanicIf(err)
+
+	ie := r.NewIfdEnumerate(exifData, eh.ByteOrder)
+
+	index, err = ie.Collect(eh.FirstIfdOffset)
+	if err != nil {
+		return eh, index, err
+	}
+
+	return eh, index, nil
+}
+
+// RegistryReloader holds the current registry of a long-running service and
+// replaces it when its files change. The replacement is atomic: callers that
+// got the registry before a reload keep using the one that they have, and a
+// reload that fails leaves the current registry as it is.
+type RegistryReloader struct {
+	files RegistryFiles
+
+	current atomic.Value
+
+	// m serializes reloads. modTimes are those of the files that the current
+	// registry was loaded from.
+	m        sync.Mutex
+	modTimes map[string]time.Time
+}
+
+// NewRegistryReloader loads the registry from the files, which has to succeed.
+func NewRegistryReloader(files RegistryFiles) (rr *RegistryReloader, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rr = &RegistryReloader{
+		files: files,
+	}
+
+	err = rr.Reload()
+	log.PanicIf(err)
+
+	return rr, nil
+}
+
+// Registry returns the current registry.
+func (rr *RegistryReloader) Registry() *Registry {
+	return rr.current.Load().(*Registry)
+}
+
+// Reload loads the registry from the files again and, if it succeeds, makes
+// it the current one.
+func (rr *RegistryReloader) Reload() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rr.m.Lock()
+	defer rr.m.Unlock()
+
+	// The times are read first so that a file that changes while it's loaded
+	// is loaded again next time.
+
+	modTimes, err := rr.getModTimes()
+	log.PanicIf(err)
+
+	r, err := LoadRegistry(rr.files)
+	log.PanicIf(err)
+
+	rr.current.Store(r)
+	rr.modTimes = modTimes
+
+	registryLogger.Debugf(nil, "Registry loaded: (%d) quirks, (%d) policies", len(r.quirks), len(r.policies))
+
+	return nil
+}
+
+// ReloadIfChanged reloads the registry if any of the files have been modified
+// since it was loaded.
+func (rr *RegistryReloader) ReloadIfChanged() (reloaded bool, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	modTimes, err := rr.getModTimes()
+	log.PanicIf(err)
+
+	rr.m.Lock()
+
+	changed := false
+	for filepath, modTime := range modTimes {
+		if rr.modTimes[filepath].Equal(modTime) == false {
+			changed = true
+			break
+		}
+	}
+
+	rr.m.Unlock()
+
+	if changed == false {
+		return false, nil
+	}
+
+	err = rr.Reload()
+	log.PanicIf(err)
+
+	return true, nil
+}
+
+// getModTimes returns the modification time of each file.
+func (rr *RegistryReloader) getModTimes() (modTimes map[string]time.Time, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	modTimes = make(map[string]time.Time)
+	for _, filepath := range rr.files.filepaths() {
+		fi, err := os.Stat(filepath)
+		log.PanicIf(err)
+
+		modTimes[filepath] = fi.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+// Watch checks the files at the interval, reloading the registry when they
+// change, until the context is done. Errors, after which the current registry
+// is kept, are given to `onError` if it isn't nil.
+func (rr *RegistryReloader) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := rr.ReloadIfChanged(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+const (
+	testRegistryTagId = 0xfe00
+
+	testRegistryTags = `{"IFD": [{"id": 65024, "name": "AcmeCalibration", "type_name": "SHORT"}]}`
+
+	testRegistryQuirks = `[{"name": "acme-calibration", "make": "Canon", "ifd_path": "IFD", "tag_ids": [65024], "type_name": "BYTE"}]`
+
+	testRegistryPolicies = `[{"name": "redact-location", "operations": [{"op": "strip-gps"}]}]`
+)
+
+// writeTestRegistryFiles writes the registry files to the directory.
+func writeTestRegistryFiles(tempPath string) RegistryFiles {
+	return RegistryFiles{
+		TagsFilepath:     writeTestFile(tempPath, "tags.json", []byte(testRegistryTags)),
+		QuirksFilepath:   writeTestFile(tempPath, "quirks.json", []byte(testRegistryQuirks)),
+		PoliciesFilepath: writeTestFile(tempPath, "policies.json", []byte(testRegistryPolicies)),
+	}
+}
+
+// getTestRegistryExif returns EXIF with the tag that the registry files add.
+func getTestRegistryExif() []byte {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: testRegistryTagId, Value: []uint16{0x0102, 0x0304}})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return rawExif
+}
+
+func TestLoadRegistry(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	r, err := LoadRegistry(writeTestRegistryFiles(tempPath))
+	log.PanicIf(err)
+
+	it, err := r.TagIndex().Get("IFD", testRegistryTagId)
+	log.PanicIf(err)
+
+	if it.Name != "AcmeCalibration" {
+		t.Fatalf("Tag not loaded: %s", it)
+	} else if len(r.Quirks()) != len(getRegisteredQuirks())+1 {
+		t.Fatalf("Quirks not loaded: (%d)", len(r.Quirks()))
+	} else if names := r.PolicyNames(); len(names) != 1 || names[0] != "redact-location" {
+		t.Fatalf("Policies not loaded: %v", names)
+	} else if _, err := r.Policy("not-a-policy"); err != ErrPolicyNotFound {
+		t.Fatalf("Expected ErrPolicyNotFound: %v", err)
+	}
+
+	_, index, err := r.Collect(getTestRegistryExif())
+	log.PanicIf(err)
+
+	results, err := index.RootIfd.FindTagWithName("AcmeCalibration")
+	log.PanicIf(err)
+
+	value, err := results[0].Value()
+	log.PanicIf(err)
+
+	if bytes, ok := value.([]uint8); ok != true || len(bytes) != 4 || bytes[0] != 0x01 || bytes[3] != 0x04 {
+		t.Fatalf("Quirk rule not applied: %v", value)
+	}
+
+	// The registered quirks and tags aren't affected.
+
+	_, index, err = Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestRegistryExif())
+	log.PanicIf(err)
+
+	if value := getTestQuirkValue(index.RootIfd, testRegistryTagId); len(value.([]uint16)) != 2 {
+		t.Fatalf("Quirk rule applied without the registry: %v", value)
+	}
+}
+
+func TestLoadRegistry_Invalid(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	cases := []RegistryFiles{
+		{TagsFilepath: writeTestFile(tempPath, "tags.json", []byte(`{"IFD": [{"id": 271, "name": "Make", "type_name": "ASCII"}]}`))},
+		{QuirksFilepath: writeTestFile(tempPath, "quirks.json", []byte(`[{"name": "bad-type", "type_name": "NOT-A-TYPE"}]`))},
+		{PoliciesFilepath: writeTestFile(tempPath, "policies.json", []byte(`[{"name": "bad-op", "operations": [{"op": "not-an-op"}]}]`))},
+		{PoliciesFilepath: writeTestFile(tempPath, "unnamed.json", []byte(`[{"operations": []}]`))},
+		{PoliciesFilepath: writeTestFile(tempPath, "not-json.json", []byte(`policies`))},
+		{PoliciesFilepath: "/does/not/exist.json"},
+	}
+
+	for i, files := range cases {
+		if _, err := LoadRegistry(files); err == nil {
+			t.Fatalf("Expected error for case (%d).", i)
+		}
+	}
+}
+
+func TestRegistryReloader(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	files := writeTestRegistryFiles(tempPath)
+
+	rr, err := NewRegistryReloader(files)
+	log.PanicIf(err)
+
+	original := rr.Registry()
+
+	if reloaded, err := rr.ReloadIfChanged(); err != nil || reloaded != false {
+		t.Fatalf("Reloaded without a change: (%v) %v", reloaded, err)
+	}
+
+	// setContent writes the policies and moves their modification time
+	// forward, since it might otherwise not change.
+	modTime := time.Now()
+	setContent := func(content string) {
+		err := ioutil.WriteFile(files.PoliciesFilepath, []byte(content), 0644)
+		log.PanicIf(err)
+
+		modTime = modTime.Add(time.Second)
+
+		err = os.Chtimes(files.PoliciesFilepath, modTime, modTime)
+		log.PanicIf(err)
+	}
+
+	setContent(`[{"name": "redact-location", "operations": [{"op": "strip-gps"}]}, {"name": "redact-all", "operations": [{"op": "strip-segments", "kinds": ["exif", "xmp"]}]}]`)
+
+	if reloaded, err := rr.ReloadIfChanged(); err != nil || reloaded != true {
+		t.Fatalf("Not reloaded after a change: (%v) %v", reloaded, err)
+	} else if names := rr.Registry().PolicyNames(); len(names) != 2 {
+		t.Fatalf("New policies not loaded: %v", names)
+	} else if len(original.PolicyNames()) != 1 {
+		t.Fatalf("Earlier registry changed: %v", original.PolicyNames())
+	}
+
+	// A bad file leaves the current registry in place.
+
+	current := rr.Registry()
+
+	setContent(`not json`)
+
+	if _, err := rr.ReloadIfChanged(); err == nil {
+		t.Fatalf("Expected error for an invalid file.")
+	} else if rr.Registry() != current {
+		t.Fatalf("Registry replaced after a failed reload.")
+	}
+}
+
+func TestRegistryReloader_Watch(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	files := writeTestRegistryFiles(tempPath)
+
+	rr, err := NewRegistryReloader(files)
+	log.PanicIf(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := make(chan error, 100)
+	done := make(chan struct{})
+
+	go func() {
+		rr.Watch(ctx, time.Millisecond, func(err error) {
+			errs <- err
+		})
+
+		close(done)
+	}()
+
+	err = os.Remove(files.QuirksFilepath)
+	log.PanicIf(err)
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Error not reported.")
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch didn't return after the context was canceled.")
+	}
+
+	if len(rr.Registry().Quirks()) != len(getRegisteredQuirks())+1 {
+		t.Fatalf("Registry not kept after a failed reload.")
+	}
+}
@@ -0,0 +1,233 @@
+package exifremote
+
+import (
+	"io"
+	"sync"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+)
+
+// BlockSource is random-access storage with a known size, such as a local
+// file, an HTTP resource, or an object in a cloud bucket.
+type BlockSource interface {
+	io.ReaderAt
+
+	// Size returns the total number of bytes available.
+	Size() int64
+}
+
+const (
+	// DefaultMaxCachedBlocks is the default number of blocks that a
+	// FetcherSource keeps. With `DefaultBlockSize`, this is 4MiB.
+	DefaultMaxCachedBlocks = 64
+)
+
+// RangeFetcher returns `length` bytes starting at `offset`. It may only
+// return fewer bytes if the range runs past the end of the data. Object-store
+// SDKs generally take an HTTP-style range for this (e.g. "bytes=0-65535").
+type RangeFetcher func(offset, length int64) (data []byte, err error)
+
+// FetcherSource is a BlockSource that reads through a RangeFetcher in
+// fixed-size blocks and caches them. This is the adapter to use for object
+// storage: implement a RangeFetcher over the SDK's ranged-get call. At most
+// `DefaultMaxCachedBlocks` blocks are kept (see `SetMaxCachedBlocks()`), and
+// the least recently used one is dropped to make room. It's safe for
+// concurrent use.
+type FetcherSource struct {
+	fetcher   RangeFetcher
+	size      int64
+	blockSize int64
+
+	blocks       map[int64][]byte
+	requestCount int
+
+	// maxCachedBlocks is the most blocks that are kept and recent has the
+	// indices of the cached blocks from least to most recently used.
+	maxCachedBlocks int
+	recent          []int64
+
+	m sync.Mutex
+}
+
+// NewFetcherSource returns a new FetcherSource. If `blockSize` is zero,
+// `DefaultBlockSize` is used.
+func NewFetcherSource(fetcher RangeFetcher, size int64, blockSize int) *FetcherSource {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+
+	return &FetcherSource{
+		fetcher:         fetcher,
+		size:            size,
+		blockSize:       int64(blockSize),
+		blocks:          make(map[int64][]byte),
+		maxCachedBlocks: DefaultMaxCachedBlocks,
+		recent:          make([]int64, 0),
+	}
+}
+
+// SetMaxCachedBlocks sets the most blocks that are kept. Zero or less keeps
+// every block, which is only appropriate for small sources or short-lived
+// readers.
+func (fs *FetcherSource) SetMaxCachedBlocks(maxCachedBlocks int) {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	fs.maxCachedBlocks = maxCachedBlocks
+	fs.evict()
+}
+
+// CachedBlockCount returns the number of blocks currently cached.
+func (fs *FetcherSource) CachedBlockCount() int {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	return len(fs.blocks)
+}
+
+// Size returns the total size of the data.
+func (fs *FetcherSource) Size() int64 {
+	return fs.size
+}
+
+// RequestCount returns the number of times the fetcher has been called.
+func (fs *FetcherSource) RequestCount() int {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	return fs.requestCount
+}
+
+// ReadAt reads `len(p)` bytes starting at `off`.
+func (fs *FetcherSource) ReadAt(p []byte, off int64) (n int, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if off < 0 {
+		log.Panicf("offset can not be negative: (%d)", off)
+	}
+
+	for n < len(p) {
+		current := off + int64(n)
+		if current >= fs.size {
+			return n, io.EOF
+		}
+
+		blockIndex := current / fs.blockSize
+
+		block, err := fs.getBlock(blockIndex)
+		log.PanicIf(err)
+
+		blockOffset := current - blockIndex*fs.blockSize
+		if blockOffset >= int64(len(block)) {
+			log.Panicf("fetcher returned a short block: (%d) < (%d)", len(block), blockOffset+1)
+		}
+
+		n += copy(p[n:], block[blockOffset:])
+	}
+
+	return n, nil
+}
+
+// setBlock primes the cache with a block that was already retrieved.
+func (fs *FetcherSource) setBlock(blockIndex int64, block []byte) {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	fs.cache(blockIndex, block)
+}
+
+// cache stores a block as the most recently used one. The lock must be held.
+func (fs *FetcherSource) cache(blockIndex int64, block []byte) {
+	fs.blocks[blockIndex] = block
+	fs.touch(blockIndex)
+	fs.evict()
+}
+
+// touch marks a cached block as the most recently used one. The lock must be
+// held.
+func (fs *FetcherSource) touch(blockIndex int64) {
+	for i, cachedIndex := range fs.recent {
+		if cachedIndex == blockIndex {
+			fs.recent = append(fs.recent[:i], fs.recent[i+1:]...)
+			break
+		}
+	}
+
+	fs.recent = append(fs.recent, blockIndex)
+}
+
+// evict drops the least recently used blocks until there are no more than
+// the maximum. The lock must be held.
+func (fs *FetcherSource) evict() {
+	if fs.maxCachedBlocks <= 0 {
+		return
+	}
+
+	for len(fs.recent) > fs.maxCachedBlocks {
+		delete(fs.blocks, fs.recent[0])
+		fs.recent = fs.recent[1:]
+	}
+}
+
+func (fs *FetcherSource) getBlock(blockIndex int64) (block []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	if block, found := fs.blocks[blockIndex]; found == true {
+		fs.touch(blockIndex)
+		return block, nil
+	}
+
+	start := blockIndex * fs.blockSize
+	length := fs.blockSize
+
+	if start+length > fs.size {
+		length = fs.size - start
+	}
+
+	fs.requestCount++
+
+	block, err = fs.fetcher(start, length)
+	log.PanicIf(err)
+
+	fs.cache(blockIndex, block)
+
+	return block, nil
+}
+
+// ParseSourceProgressively visits the EXIF tags stored in the given source.
+// The source is read from the beginning and reading stops as soon as every
+// tag has been visited, so only the part of the source that holds the EXIF
+// data is fetched.
+func ParseSourceProgressively(bs BlockSource, ifdMapping *exif.IfdMapping, tagIndex *exif.TagIndex, visitor exif.TagVisitorFn) (eh exif.ExifHeader, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	sr := io.NewSectionReader(bs, 0, bs.Size())
+
+	eh, err = exif.ParseProgressively(sr, ifdMapping, tagIndex, visitor)
+	if err != nil {
+		if err == exif.ErrNoExif {
+			return eh, err
+		}
+
+		log.Panic(err)
+	}
+
+	return eh, nil
+}
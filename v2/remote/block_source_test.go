@@ -0,0 +1,271 @@
+package exifremote
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+)
+
+func TestFetcherSource_ReadAt(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	fetcher := func(offset, length int64) (data2 []byte, err error) {
+		return data[offset : offset+length], nil
+	}
+
+	fs := NewFetcherSource(fetcher, int64(len(data)), 100)
+
+	buffer := make([]byte, 150)
+
+	n, err := fs.ReadAt(buffer, 950)
+	if err != io.EOF {
+		t.Fatalf("Expected EOF: %v", err)
+	} else if n != 50 {
+		t.Fatalf("Read count not correct: (%d)", n)
+	} else if bytes.Equal(buffer[:n], data[950:]) != true {
+		t.Fatalf("Data not correct.")
+	}
+
+	n, err = fs.ReadAt(buffer, 120)
+	log.PanicIf(err)
+
+	if n != 150 {
+		t.Fatalf("Read count not correct: (%d)", n)
+	} else if bytes.Equal(buffer, data[120:270]) != true {
+		t.Fatalf("Data not correct.")
+	}
+
+	if fs.RequestCount() != 3 {
+		t.Fatalf("Request count not correct: (%d)", fs.RequestCount())
+	}
+}
+
+func TestFetcherSource_SetMaxCachedBlocks(t *testing.T) {
+	data := make([]byte, 1000)
+
+	fetcher := func(offset, length int64) (data2 []byte, err error) {
+		return data[offset : offset+length], nil
+	}
+
+	fs := NewFetcherSource(fetcher, int64(len(data)), 100)
+	fs.SetMaxCachedBlocks(2)
+
+	buffer := make([]byte, 1)
+
+	for _, offset := range []int64{0, 100, 0, 200} {
+		_, err := fs.ReadAt(buffer, offset)
+		log.PanicIf(err)
+	}
+
+	if fs.CachedBlockCount() != 2 {
+		t.Fatalf("Cached block count not correct: (%d)", fs.CachedBlockCount())
+	} else if fs.RequestCount() != 3 {
+		t.Fatalf("Request count not correct: (%d)", fs.RequestCount())
+	}
+
+	// The first block was used more recently than the second, so the second
+	// was the one dropped.
+	_, err := fs.ReadAt(buffer, 0)
+	log.PanicIf(err)
+
+	if fs.RequestCount() != 3 {
+		t.Fatalf("First block should still be cached: (%d)", fs.RequestCount())
+	}
+
+	_, err = fs.ReadAt(buffer, 100)
+	log.PanicIf(err)
+
+	if fs.RequestCount() != 4 {
+		t.Fatalf("Second block should have been dropped: (%d)", fs.RequestCount())
+	}
+
+	// Without a maximum, everything is kept.
+	fs.SetMaxCachedBlocks(0)
+
+	for offset := int64(0); offset < 1000; offset += 100 {
+		_, err := fs.ReadAt(buffer, offset)
+		log.PanicIf(err)
+	}
+
+	if fs.CachedBlockCount() != 10 {
+		t.Fatalf("Expected every block to be cached: (%d)", fs.CachedBlockCount())
+	}
+}
+
+func TestFetcherSource_ReadAt_ShortBlock(t *testing.T) {
+	fetcher := func(offset, length int64) (data []byte, err error) {
+		return make([]byte, 10), nil
+	}
+
+	fs := NewFetcherSource(fetcher, 1000, 100)
+
+	_, err := fs.ReadAt(make([]byte, 1), 50)
+	if err == nil {
+		t.Fatalf("Expected error for short block.")
+	}
+}
+
+func TestParseSourceProgressively(t *testing.T) {
+	imageData := getTestImageData()
+
+	fetcher := func(offset, length int64) (data []byte, err error) {
+		return imageData[offset : offset+length], nil
+	}
+
+	fs := NewFetcherSource(fetcher, int64(len(imageData)), 0)
+
+	im := exif.NewIfdMappingWithStandard()
+	ti := exif.NewTagIndex()
+
+	tagCount := 0
+	visitor := func(fqIfdPath string, ifdIndex int, ite *exif.IfdTagEntry) (err error) {
+		tagCount++
+		return nil
+	}
+
+	_, err := ParseSourceProgressively(fs, im, ti, visitor)
+	log.PanicIf(err)
+
+	if tagCount == 0 {
+		t.Fatalf("No tags were visited.")
+	} else if fs.RequestCount() != 1 {
+		t.Fatalf("Expected the EXIF to be in the first block: (%d)", fs.RequestCount())
+	}
+}
+
+// getObjectInput and getObjectOutput are shaped like the request and
+// response of an object-store SDK's download call (e.g. `s3.GetObjectInput`
+// and `s3.GetObjectOutput` in the AWS SDK).
+type getObjectInput struct {
+	Bucket *string
+	Key    *string
+
+	// Range is an HTTP-style range (e.g. "bytes=0-65535").
+	Range *string
+}
+
+type getObjectOutput struct {
+	Body          io.ReadCloser
+	ContentLength *int64
+}
+
+type headObjectInput struct {
+	Bucket *string
+	Key    *string
+}
+
+type headObjectOutput struct {
+	ContentLength *int64
+}
+
+// objectStoreClient stands in for an object-store SDK client.
+type objectStoreClient struct {
+	objects map[string][]byte
+}
+
+func (osc *objectStoreClient) GetObject(input *getObjectInput) (output *getObjectOutput, err error) {
+	data := osc.objects[*input.Bucket+"/"+*input.Key]
+
+	if input.Range != nil {
+		var start, end int64
+
+		_, err = fmt.Sscanf(*input.Range, "bytes=%d-%d", &start, &end)
+		if err != nil {
+			return nil, err
+		}
+
+		data = data[start : end+1]
+	}
+
+	length := int64(len(data))
+
+	output = &getObjectOutput{
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: &length,
+	}
+
+	return output, nil
+}
+
+func (osc *objectStoreClient) HeadObject(input *headObjectInput) (output *headObjectOutput, err error) {
+	length := int64(len(osc.objects[*input.Bucket+"/"+*input.Key]))
+
+	output = &headObjectOutput{
+		ContentLength: &length,
+	}
+
+	return output, nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// Reading EXIF straight out of object storage. The fetcher makes a ranged
+// download and reads the body, and the size comes from the object's
+// metadata. With the AWS SDK, `client` would be an `*s3.S3` and the calls
+// would be the same. With the GCS client, the fetcher would read from
+// `NewRangeReader(ctx, offset, length)` and the size would come from
+// `Attrs()`.
+func ExampleNewFetcherSource() {
+	client := &objectStoreClient{
+		objects: map[string][]byte{
+			"uploads/image.jpg": getTestImageData(),
+		},
+	}
+
+	bucket := stringPtr("uploads")
+	key := stringPtr("image.jpg")
+
+	hoo, err := client.HeadObject(&headObjectInput{Bucket: bucket, Key: key})
+	log.PanicIf(err)
+
+	fetcher := func(offset, length int64) (data []byte, err error) {
+		goi := &getObjectInput{
+			Bucket: bucket,
+			Key:    key,
+			Range:  stringPtr(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+		}
+
+		goo, err := client.GetObject(goi)
+		if err != nil {
+			return nil, err
+		}
+
+		defer goo.Body.Close()
+
+		return ioutil.ReadAll(goo.Body)
+	}
+
+	fs := NewFetcherSource(fetcher, *hoo.ContentLength, 0)
+
+	im := exif.NewIfdMappingWithStandard()
+	ti := exif.NewTagIndex()
+
+	visitor := func(fqIfdPath string, ifdIndex int, ite *exif.IfdTagEntry) (err error) {
+		// Model
+		if ite.TagId() == 0x0110 {
+			value, err := ite.Format()
+			log.PanicIf(err)
+
+			fmt.Printf("%s\n", value)
+		}
+
+		return nil
+	}
+
+	_, err = ParseSourceProgressively(fs, im, ti, visitor)
+	log.PanicIf(err)
+
+	// Output:
+	// Canon EOS 5D Mark III
+}
@@ -0,0 +1,159 @@
+package exifremote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"io/ioutil"
+	"net/http"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// DefaultBlockSize is the default number of bytes requested at a time.
+	DefaultBlockSize = 64 * 1024
+)
+
+var (
+	httpRangeReaderLogger = log.NewLogger("exifremote.http_range_reader")
+)
+
+var (
+	// ErrRangeNotSupported means that the server did not honor a range
+	// request.
+	ErrRangeNotSupported = errors.New("server does not support range requests")
+)
+
+// HttpRangeReader is a BlockSource over a remote resource that is read using
+// HTTP range requests. Data is requested and cached in fixed-size blocks so
+// that the many small reads done while parsing turn into a few requests. It's
+// safe for concurrent use.
+type HttpRangeReader struct {
+	*FetcherSource
+
+	ctx    context.Context
+	client *http.Client
+	url    string
+}
+
+// NewHttpRangeReader returns a new HttpRangeReader. The first block is
+// requested immediately in order to determine the size of the resource. Every
+// request, including the ones made by later reads, is made with `ctx`, so
+// canceling it stops the reader. If `client` is nil, `http.DefaultClient` is
+// used. If `blockSize` is zero, `DefaultBlockSize` is used.
+func NewHttpRangeReader(ctx context.Context, client *http.Client, url string, blockSize int) (hrr *HttpRangeReader, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize
+	} else if blockSize < 0 {
+		log.Panicf("block-size must be positive: (%d)", blockSize)
+	}
+
+	hrr = &HttpRangeReader{
+		ctx:    ctx,
+		client: client,
+		url:    url,
+	}
+
+	firstBlock, size, err := hrr.fetchRange(0, int64(blockSize))
+	if err != nil {
+		if err == ErrRangeNotSupported {
+			return nil, err
+		}
+
+		log.Panic(err)
+	}
+
+	hrr.FetcherSource = NewFetcherSource(hrr.fetch, size, blockSize)
+	hrr.FetcherSource.setBlock(0, firstBlock)
+	hrr.FetcherSource.requestCount = 1
+
+	return hrr, nil
+}
+
+func (hrr *HttpRangeReader) fetch(offset, length int64) (data []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	data, _, err = hrr.fetchRange(offset, length)
+	log.PanicIf(err)
+
+	return data, nil
+}
+
+// fetchRange does a single range request and returns the data along with the
+// total size of the resource. No more than `length` bytes are read, even if
+// the server sends more.
+func (hrr *HttpRangeReader) fetchRange(offset, length int64) (data []byte, size int64, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	end := offset + length - 1
+
+	httpRangeReaderLogger.Debugf(nil, "Requesting range (%d)-(%d): [%s]", offset, end, hrr.url)
+
+	req, err := http.NewRequestWithContext(hrr.ctx, "GET", hrr.url, nil)
+	log.PanicIf(err)
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, end))
+
+	resp, err := hrr.client.Do(req)
+	log.PanicIf(err)
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		// The server ignored the range and is sending the whole thing.
+		return nil, 0, ErrRangeNotSupported
+	} else if resp.StatusCode != http.StatusPartialContent {
+		log.Panicf("range request failed: (%d) [%s]", resp.StatusCode, hrr.url)
+	}
+
+	size, err = parseContentRangeSize(resp.Header.Get("Content-Range"))
+	log.PanicIf(err)
+
+	data, err = ioutil.ReadAll(io.LimitReader(resp.Body, length))
+	log.PanicIf(err)
+
+	return data, size, nil
+}
+
+// parseContentRangeSize returns the total size from a Content-Range header
+// like "bytes 0-99/1234".
+func parseContentRangeSize(contentRange string) (size int64, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	slashAt := strings.LastIndex(contentRange, "/")
+	if slashAt == -1 || contentRange[slashAt+1:] == "*" {
+		log.Panicf("content-range does not have a size: [%s]", contentRange)
+	}
+
+	size, err = strconv.ParseInt(contentRange[slashAt+1:], 10, 64)
+	log.PanicIf(err)
+
+	return size, nil
+}
@@ -0,0 +1,201 @@
+package exifremote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2"
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func newTestServer(data []byte) *httptest.Server {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "image.jpg", time.Time{}, bytes.NewReader(data))
+	}
+
+	return httptest.NewServer(http.HandlerFunc(hf))
+}
+
+func getTestImageData() []byte {
+	filepath := path.Join(exifcommon.GetModuleRootPath(), "assets", "NDM_8901.jpg")
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	return data
+}
+
+func TestHttpRangeReader_ReadAt(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	ts := newTestServer(data)
+	defer ts.Close()
+
+	hrr, err := NewHttpRangeReader(context.Background(), nil, ts.URL, 64)
+	log.PanicIf(err)
+
+	if hrr.Size() != 1000 {
+		t.Fatalf("Size not correct: (%d)", hrr.Size())
+	}
+
+	// Spans three blocks.
+	buffer := make([]byte, 100)
+
+	n, err := hrr.ReadAt(buffer, 100)
+	log.PanicIf(err)
+
+	if n != 100 {
+		t.Fatalf("Read count not correct: (%d)", n)
+	} else if bytes.Equal(buffer, data[100:200]) != true {
+		t.Fatalf("Data not correct.")
+	}
+
+	// The first block was read by the constructor and blocks one through
+	// three by the read.
+	if hrr.RequestCount() != 4 {
+		t.Fatalf("Request count not correct: (%d)", hrr.RequestCount())
+	}
+
+	// Everything here is cached.
+	_, err = hrr.ReadAt(buffer[:10], 150)
+	log.PanicIf(err)
+
+	if hrr.RequestCount() != 4 {
+		t.Fatalf("Cached read made a request: (%d)", hrr.RequestCount())
+	}
+}
+
+func TestHttpRangeReader_ReadAt_Eof(t *testing.T) {
+	data := make([]byte, 100)
+
+	ts := newTestServer(data)
+	defer ts.Close()
+
+	hrr, err := NewHttpRangeReader(context.Background(), nil, ts.URL, 64)
+	log.PanicIf(err)
+
+	buffer := make([]byte, 50)
+
+	n, err := hrr.ReadAt(buffer, 80)
+	if err != io.EOF {
+		t.Fatalf("Expected EOF: %v", err)
+	} else if n != 20 {
+		t.Fatalf("Read count not correct: (%d)", n)
+	}
+}
+
+func TestNewHttpRangeReader_RangeNotSupported(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("abc"))
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(hf))
+	defer ts.Close()
+
+	_, err := NewHttpRangeReader(context.Background(), nil, ts.URL, 64)
+	if err != ErrRangeNotSupported {
+		t.Fatalf("Expected ErrRangeNotSupported: %v", err)
+	}
+}
+
+func TestHttpRangeReader_ReadAt_LongResponse(t *testing.T) {
+	// The server claims a partial response but sends the whole resource.
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-63/1000")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(hf))
+	defer ts.Close()
+
+	hrr, err := NewHttpRangeReader(context.Background(), nil, ts.URL, 64)
+	log.PanicIf(err)
+
+	if cached := hrr.CachedBlockCount(); cached != 1 {
+		t.Fatalf("Cached block count not correct: (%d)", cached)
+	}
+
+	buffer := make([]byte, 100)
+
+	n, err := hrr.ReadAt(buffer, 0)
+	log.PanicIf(err)
+
+	// Only the requested block is kept from each response, so the second
+	// block is the start of the resource again.
+	if n != 100 {
+		t.Fatalf("Read count not correct: (%d)", n)
+	} else if bytes.Equal(buffer[:64], data[:64]) != true || bytes.Equal(buffer[64:], data[:36]) != true {
+		t.Fatalf("Data not correct.")
+	}
+}
+
+func TestNewHttpRangeReader_Canceled(t *testing.T) {
+	ts := newTestServer(make([]byte, 100))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewHttpRangeReader(ctx, nil, ts.URL, 64)
+	if err == nil || strings.Contains(err.Error(), context.Canceled.Error()) != true {
+		t.Fatalf("Expected cancellation: %v", err)
+	}
+}
+
+func TestHttpRangeReader_ParseProgressively(t *testing.T) {
+	imageData := getTestImageData()
+
+	// Make the image artificially large so that it's obvious if we download
+	// it.
+	padded := make([]byte, len(imageData)+10*1024*1024)
+	copy(padded, imageData)
+
+	ts := newTestServer(padded)
+	defer ts.Close()
+
+	hrr, err := NewHttpRangeReader(context.Background(), nil, ts.URL, 0)
+	log.PanicIf(err)
+
+	im := exif.NewIfdMappingWithStandard()
+	ti := exif.NewTagIndex()
+
+	tagCount := 0
+	visitor := func(fqIfdPath string, ifdIndex int, ite *exif.IfdTagEntry) (err error) {
+		tagCount++
+		return nil
+	}
+
+	sr := io.NewSectionReader(hrr, 0, hrr.Size())
+
+	_, err = exif.ParseProgressively(sr, im, ti, visitor)
+	log.PanicIf(err)
+
+	if tagCount == 0 {
+		t.Fatalf("No tags were visited.")
+	}
+
+	fetched := int64(hrr.RequestCount()) * DefaultBlockSize
+	if fetched >= int64(len(imageData)) {
+		t.Fatalf("Too much was fetched: (%d) >= (%d)", fetched, len(imageData))
+	}
+}
@@ -0,0 +1,3 @@
+// Package exifremote provides sources that read EXIF data from remote
+// locations without downloading the whole file.
+package exifremote
@@ -0,0 +1,154 @@
+package exif
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	rewriteLogger = log.NewLogger("exif.rewrite")
+)
+
+// RewriteOptions changes how `RewriteFile()` writes a file.
+type RewriteOptions struct {
+	// MtimeFromDateTimeOriginal sets the modification time of the file to
+	// the DateTimeOriginal of the new data, taken as local time, instead of
+	// keeping the old one. The old one is kept if there isn't one.
+	MtimeFromDateTimeOriginal bool
+}
+
+// RewriteFile replaces the file with the data so that a crash or a failure
+// at any point leaves either the old file or the new one, never a mix of the
+// two. The data is written to a temporary file in the same directory, synced
+// to disk, and then renamed over the file. If the path is a symlink, the file
+// that it points to is replaced and the link is kept. The mode, access and
+// modification times, and (on Linux) the owner and extended attributes of
+// the old file are carried over. The owner is only carried over if we're
+// allowed to set it (e.g. as root); otherwise, the new file is owned by us.
+// A file that doesn't exist is created with mode 0644.
+func RewriteFile(targetFilepath string, data []byte, opts RewriteOptions) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fi, err := os.Stat(targetFilepath)
+	if err != nil && os.IsNotExist(err) == false {
+		log.Panic(err)
+	}
+
+	if fi != nil {
+		targetFilepath, err = filepath.EvalSymlinks(targetFilepath)
+		log.PanicIf(err)
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(targetFilepath), "."+filepath.Base(targetFilepath)+".")
+	log.PanicIf(err)
+
+	tempFilepath := f.Name()
+
+	defer os.Remove(tempFilepath)
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		log.Panic(err)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		log.Panic(err)
+	}
+
+	err = f.Close()
+	log.PanicIf(err)
+
+	mode := os.FileMode(0644)
+	if fi != nil {
+		mode = fi.Mode()
+
+		copyOwner(fi, tempFilepath)
+		copyXattrs(targetFilepath, tempFilepath)
+	}
+
+	err = os.Chmod(tempFilepath, mode)
+	log.PanicIf(err)
+
+	mtime := time.Time{}
+	atime := time.Time{}
+
+	if fi != nil {
+		mtime = fi.ModTime()
+		atime = fileAccessTime(fi)
+	}
+
+	if opts.MtimeFromDateTimeOriginal == true {
+		if dateTimeOriginal, found := findDateTimeOriginal(data); found == true {
+			mtime = dateTimeOriginal
+
+			if atime.IsZero() == true {
+				atime = dateTimeOriginal
+			}
+		}
+	}
+
+	if mtime.IsZero() == false {
+		err = os.Chtimes(tempFilepath, atime, mtime)
+		log.PanicIf(err)
+	}
+
+	err = os.Rename(tempFilepath, targetFilepath)
+	log.PanicIf(err)
+
+	// The rename itself is only durable once the directory is synced.
+	syncDir(filepath.Dir(targetFilepath))
+
+	return nil
+}
+
+// replaceFile rewrites the file with the default options.
+func replaceFile(targetFilepath string, data []byte) (err error) {
+	return RewriteFile(targetFilepath, data, RewriteOptions{})
+}
+
+// findDateTimeOriginal returns the DateTimeOriginal in the EXIF of the data,
+// as local time.
+func findDateTimeOriginal(data []byte) (dateTimeOriginal time.Time, found bool) {
+	rawExif, err := SearchAndExtractExif(data)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	ifds := index.Lookup[exifcommon.IfdPathStandardExif]
+	if len(ifds) == 0 {
+		return time.Time{}, false
+	}
+
+	value, err := getIfdTagString(ifds[0], "DateTimeOriginal")
+	if err != nil || value == "" {
+		return time.Time{}, false
+	}
+
+	t, err := ParseExifFullTimestamp(value)
+	if err != nil {
+		rewriteLogger.Warningf(nil, "Could not parse DateTimeOriginal [%s]: %s", value, err)
+		return time.Time{}, false
+	}
+
+	dateTimeOriginal = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+
+	return dateTimeOriginal, true
+}
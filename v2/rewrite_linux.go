@@ -0,0 +1,95 @@
+//go:build linux && !tinygo
+// +build linux,!tinygo
+
+package exif
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileAccessTime returns the access time of the file.
+func fileAccessTime(fi os.FileInfo) time.Time {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if ok == false {
+		return fi.ModTime()
+	}
+
+	return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+}
+
+// copyOwner gives the file the owner and group in the info. A failure is only
+// logged since only root can give a file to another user.
+func copyOwner(fi os.FileInfo, toFilepath string) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if ok == false {
+		return
+	}
+
+	err := os.Chown(toFilepath, int(st.Uid), int(st.Gid))
+	if err != nil {
+		rewriteLogger.Warningf(nil, "Could not set the owner of [%s] to (%d:%d): %s", toFilepath, st.Uid, st.Gid, err)
+	}
+}
+
+// copyXattrs copies the extended attributes of one file to another. Failures
+// are only logged since many filesystems don't support them or only allow
+// some of them to be set (e.g. "security.*" needs privileges).
+func copyXattrs(fromFilepath, toFilepath string) {
+	size, err := syscall.Listxattr(fromFilepath, nil)
+	if err != nil || size == 0 {
+		return
+	}
+
+	names := make([]byte, size)
+
+	size, err = syscall.Listxattr(fromFilepath, names)
+	if err != nil {
+		rewriteLogger.Warningf(nil, "Could not list the extended attributes of [%s]: %s", fromFilepath, err)
+		return
+	}
+
+	for _, name := range bytes.Split(names[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+
+		attr := string(name)
+
+		valueSize, err := syscall.Getxattr(fromFilepath, attr, nil)
+		if err != nil {
+			rewriteLogger.Warningf(nil, "Could not read extended attribute [%s] of [%s]: %s", attr, fromFilepath, err)
+			continue
+		}
+
+		value := make([]byte, valueSize)
+
+		valueSize, err = syscall.Getxattr(fromFilepath, attr, value)
+		if err != nil {
+			rewriteLogger.Warningf(nil, "Could not read extended attribute [%s] of [%s]: %s", attr, fromFilepath, err)
+			continue
+		}
+
+		err = syscall.Setxattr(toFilepath, attr, value[:valueSize], 0)
+		if err != nil {
+			rewriteLogger.Warningf(nil, "Could not copy extended attribute [%s] of [%s]: %s", attr, fromFilepath, err)
+		}
+	}
+}
+
+// syncDir syncs a directory so that renames in it are durable.
+func syncDir(dirPath string) {
+	d, err := os.Open(dirPath)
+	if err != nil {
+		rewriteLogger.Warningf(nil, "Could not open directory [%s] to sync it: %s", dirPath, err)
+		return
+	}
+
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		rewriteLogger.Warningf(nil, "Could not sync directory [%s]: %s", dirPath, err)
+	}
+}
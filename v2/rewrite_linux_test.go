@@ -0,0 +1,104 @@
+//go:build linux && !tinygo
+// +build linux,!tinygo
+
+package exif
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestRewriteFile_Xattrs(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+
+	err = ioutil.WriteFile(filepath, []byte("old"), 0644)
+	log.PanicIf(err)
+
+	err = syscall.Setxattr(filepath, "user.test", []byte("value"), 0)
+	if err != nil {
+		t.Skipf("Extended attributes not supported: %s", err)
+	}
+
+	err = RewriteFile(filepath, []byte("new"), RewriteOptions{})
+	log.PanicIf(err)
+
+	value := make([]byte, 100)
+
+	size, err := syscall.Getxattr(filepath, "user.test", value)
+	log.PanicIf(err)
+
+	if string(value[:size]) != "value" {
+		t.Fatalf("Extended attribute not preserved: [%s]", value[:size])
+	}
+}
+
+func TestRewriteFile_Symlink(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	targetFilepath := path.Join(tempPath, "image.jpg")
+	linkFilepath := path.Join(tempPath, "link.jpg")
+
+	err = ioutil.WriteFile(targetFilepath, []byte("old"), 0644)
+	log.PanicIf(err)
+
+	err = os.Symlink("image.jpg", linkFilepath)
+	log.PanicIf(err)
+
+	err = RewriteFile(linkFilepath, []byte("new"), RewriteOptions{})
+	log.PanicIf(err)
+
+	fi, err := os.Lstat(linkFilepath)
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(targetFilepath)
+	log.PanicIf(err)
+
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Symlink replaced.")
+	} else if string(data) != "new" {
+		t.Fatalf("Target not rewritten: [%s]", data)
+	}
+}
+
+func TestRewriteFile_Owner(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skipf("Only root can give a file to another user.")
+	}
+
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+
+	err = ioutil.WriteFile(filepath, []byte("old"), 0644)
+	log.PanicIf(err)
+
+	err = os.Chown(filepath, 1234, 5678)
+	log.PanicIf(err)
+
+	err = RewriteFile(filepath, []byte("new"), RewriteOptions{})
+	log.PanicIf(err)
+
+	fi, err := os.Stat(filepath)
+	log.PanicIf(err)
+
+	st := fi.Sys().(*syscall.Stat_t)
+	if st.Uid != 1234 || st.Gid != 5678 {
+		t.Fatalf("Owner not preserved: (%d:%d)", st.Uid, st.Gid)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !linux || tinygo
+// +build !linux tinygo
+
+package exif
+
+import (
+	"os"
+	"time"
+)
+
+// fileAccessTime returns the modification time, since the access time isn't
+// available portably.
+func fileAccessTime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
+
+// copyOwner does nothing on this platform, so the new file is owned by us.
+func copyOwner(fi os.FileInfo, toFilepath string) {
+}
+
+// copyXattrs does nothing on this platform.
+func copyXattrs(fromFilepath, toFilepath string) {
+}
+
+// syncDir does nothing on this platform, where directories can't always be
+// opened and synced.
+func syncDir(dirPath string) {
+}
@@ -0,0 +1,109 @@
+package exif
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestRewriteFile(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+
+	err = ioutil.WriteFile(filepath, []byte("old"), 0600)
+	log.PanicIf(err)
+
+	mtime := time.Date(2015, 6, 7, 8, 9, 10, 0, time.UTC)
+
+	err = os.Chtimes(filepath, mtime, mtime)
+	log.PanicIf(err)
+
+	err = RewriteFile(filepath, []byte("new"), RewriteOptions{})
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	fi, err := os.Stat(filepath)
+	log.PanicIf(err)
+
+	if bytes.Equal(data, []byte("new")) == false {
+		t.Fatalf("Data not written: [%s]", data)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Fatalf("Mode not preserved: %s", fi.Mode())
+	} else if fi.ModTime().Equal(mtime) == false {
+		t.Fatalf("Modification time not preserved: %s", fi.ModTime())
+	}
+
+	files, err := ioutil.ReadDir(tempPath)
+	log.PanicIf(err)
+
+	if len(files) != 1 {
+		t.Fatalf("Temporary file left behind: (%d)", len(files))
+	}
+}
+
+func TestRewriteFile_New(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "new.bin")
+
+	err = RewriteFile(filepath, []byte("data"), RewriteOptions{})
+	log.PanicIf(err)
+
+	fi, err := os.Stat(filepath)
+	log.PanicIf(err)
+
+	if fi.Mode().Perm() != 0644 {
+		t.Fatalf("Mode not correct: %s", fi.Mode())
+	}
+}
+
+func TestRewriteFile_MtimeFromDateTimeOriginal(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "image.jpg")
+	data := getTestPipelineJpeg()
+
+	err = ioutil.WriteFile(filepath, data, 0644)
+	log.PanicIf(err)
+
+	err = RewriteFile(filepath, data, RewriteOptions{MtimeFromDateTimeOriginal: true})
+	log.PanicIf(err)
+
+	fi, err := os.Stat(filepath)
+	log.PanicIf(err)
+
+	expected := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+
+	if fi.ModTime().Equal(expected) == false {
+		t.Fatalf("Modification time not correct: %s", fi.ModTime())
+	}
+
+	// Without a DateTimeOriginal, the old time is kept.
+
+	err = RewriteFile(filepath, []byte("no exif"), RewriteOptions{MtimeFromDateTimeOriginal: true})
+	log.PanicIf(err)
+
+	fi, err = os.Stat(filepath)
+	log.PanicIf(err)
+
+	if fi.ModTime().Equal(expected) == false {
+		t.Fatalf("Modification time not kept: %s", fi.ModTime())
+	}
+}
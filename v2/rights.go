@@ -0,0 +1,265 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// ArtistTagId is the ID of the Artist tag in IFD0.
+	ArtistTagId = 0x013b
+
+	// CopyrightTagId is the ID of the Copyright tag in IFD0.
+	CopyrightTagId = 0x8298
+)
+
+// RightsInfo is the creator and copyright as recorded by each of the EXIF,
+// XMP, and IPTC families, and whether they agree.
+type RightsInfo struct {
+	// Artist and Copyright are the EXIF tags.
+	Artist    string
+	Copyright string
+
+	// XmpCreators is dc:creator and XmpRights is the default dc:rights.
+	XmpCreators []string
+	XmpRights   string
+
+	// IptcBylines is By-line (2:80) and IptcCopyright is Copyright Notice
+	// (2:116).
+	IptcBylines   []string
+	IptcCopyright string
+
+	// Problems describes each disagreement between the families. Families
+	// that don't have a value aren't compared.
+	Problems []string
+}
+
+// String returns a descriptive string.
+func (ri RightsInfo) String() string {
+	return fmt.Sprintf("RightsInfo<ARTIST=[%s] COPYRIGHT=[%s] XMP-CREATORS=%v XMP-RIGHTS=[%s] IPTC-BYLINES=%v IPTC-COPYRIGHT=[%s]>", ri.Artist, ri.Copyright, ri.XmpCreators, ri.XmpRights, ri.IptcBylines, ri.IptcCopyright)
+}
+
+// IsConsistent returns true if no problems were found.
+func (ri RightsInfo) IsConsistent() bool {
+	return len(ri.Problems) == 0
+}
+
+// Artists returns the semicolon-separated names in the Artist tag.
+func (ri RightsInfo) Artists() []string {
+	return SplitAsciiList(ri.Artist)
+}
+
+// Creator returns the first creator found, preferring EXIF, then XMP, and
+// then IPTC.
+func (ri RightsInfo) Creator() string {
+	if artists := ri.Artists(); len(artists) > 0 {
+		return artists[0]
+	} else if len(ri.XmpCreators) > 0 {
+		return ri.XmpCreators[0]
+	} else if len(ri.IptcBylines) > 0 {
+		return ri.IptcBylines[0]
+	}
+
+	return ""
+}
+
+// check compares the families with each other.
+func (ri *RightsInfo) check() {
+	ri.Problems = make([]string, 0)
+
+	creators := map[string][]string{
+		"EXIF": ri.Artists(),
+		"XMP":  ri.XmpCreators,
+		"IPTC": ri.IptcBylines,
+	}
+
+	// EXIF can have separate photographer and editor copyrights. Only the
+	// photographer's is compared.
+	copyright, _ := SplitCopyright(ri.Copyright)
+
+	copyrights := map[string]string{
+		"EXIF": copyright,
+		"XMP":  ri.XmpRights,
+		"IPTC": ri.IptcCopyright,
+	}
+
+	families := []string{"EXIF", "XMP", "IPTC"}
+
+	for i, family1 := range families {
+		for _, family2 := range families[i+1:] {
+			creators1 := creators[family1]
+			creators2 := creators[family2]
+
+			if len(creators1) > 0 && len(creators2) > 0 && JoinAsciiList(creators1) != JoinAsciiList(creators2) {
+				ri.Problems = append(ri.Problems, fmt.Sprintf("%s creator %v doesn't match %s creator %v", family1, creators1, family2, creators2))
+			}
+
+			copyright1 := copyrights[family1]
+			copyright2 := copyrights[family2]
+
+			if copyright1 != "" && copyright2 != "" && copyright1 != copyright2 {
+				ri.Problems = append(ri.Problems, fmt.Sprintf("%s copyright [%s] doesn't match %s copyright [%s]", family1, copyright1, family2, copyright2))
+			}
+		}
+	}
+}
+
+// GetRightsInfo collects the creator and copyright from the EXIF, the XMP
+// packet, and the IPTC datasets (either of the last two may be nil), and
+// checks that they agree.
+func GetRightsInfo(index IfdIndex, xmp []byte, iptc []IptcDataset) (ri RightsInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if index.RootIfd != nil {
+		ri.Artist, err = getIfdTagString(index.RootIfd, "Artist")
+		log.PanicIf(err)
+
+		ri.Copyright, err = getIfdTagString(index.RootIfd, "Copyright")
+		log.PanicIf(err)
+	}
+
+	ri.XmpCreators = xmpArrayItems(xmp, "dc:creator")
+
+	if rights := xmpArrayItems(xmp, "dc:rights"); len(rights) > 0 {
+		ri.XmpRights = rights[0]
+	}
+
+	ri.IptcBylines = IptcStrings(iptc, IptcRecordApplication, IptcDatasetByline)
+
+	if copyrights := IptcStrings(iptc, IptcRecordApplication, IptcDatasetCopyrightNotice); len(copyrights) > 0 {
+		ri.IptcCopyright = copyrights[0]
+	}
+
+	ri.check()
+
+	return ri, nil
+}
+
+// GetJpegRightsInfo is a convenience function that reads the EXIF, XMP, and
+// IPTC from a JPEG and calls `GetRightsInfo()`.
+func GetJpegRightsInfo(data []byte) (ri RightsInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	index, xmp, iptc, err := readJpegMetadata(data)
+	log.PanicIf(err)
+
+	ri, err = GetRightsInfo(index, xmp, iptc)
+	log.PanicIf(err)
+
+	return ri, nil
+}
+
+// SetRights writes the Artist and Copyright tags into IFD0. Multiple
+// creators are joined with semicolons (see `JoinAsciiList()`). Empty values
+// are left alone.
+func SetRights(rootIb *IfdBuilder, creators []string, copyright string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if rootIb.ifdPath != exifcommon.IfdStandard {
+		log.Panicf("rights can only be set on the root IFD: [%s]", rootIb.fqIfdPath)
+	}
+
+	if len(creators) > 0 {
+		err := SetAsciiList(rootIb, ArtistTagId, creators)
+		log.PanicIf(err)
+	}
+
+	if copyright != "" {
+		err := rootIb.SetStandard(CopyrightTagId, copyright)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// MergeRightsIntoXmp returns the XMP packet with dc:creator and dc:rights
+// set. Empty values are left alone.
+func MergeRightsIntoXmp(xmp []byte, creators []string, copyright string) []byte {
+	if len(creators) > 0 {
+		xmp = mergeXmpArray(xmp, "dc:creator", "Seq", DcNamespace, creators)
+	}
+
+	if copyright != "" {
+		xmp = mergeXmpArray(xmp, "dc:rights", "Alt", DcNamespace, []string{copyright})
+	}
+
+	return xmp
+}
+
+// MergeRightsIntoIptc returns a copy of the datasets with By-line and
+// Copyright Notice set. Empty values are left alone.
+func MergeRightsIntoIptc(iptc []IptcDataset, creators []string, copyright string) []IptcDataset {
+	if len(creators) > 0 {
+		iptc = SetIptcStrings(iptc, IptcRecordApplication, IptcDatasetByline, creators)
+	}
+
+	if copyright != "" {
+		iptc = SetIptcStrings(iptc, IptcRecordApplication, IptcDatasetCopyrightNotice, []string{copyright})
+	}
+
+	return iptc
+}
+
+// SetJpegRights returns a copy of the JPEG with the creator and copyright
+// stamped into the EXIF, XMP, and IPTC. The EXIF is patched in place (see
+// `ExifPatcher`), so a JPEG without EXIF doesn't get any. Empty values are
+// left alone.
+func SetJpegRights(data []byte, creators []string, copyright string) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	updated, err = patchJpegExif(data, func(ep *ExifPatcher) error {
+		if len(creators) > 0 {
+			err := ep.Set(exifcommon.IfdPathStandard, ArtistTagId, JoinAsciiList(creators))
+			if err != nil {
+				return err
+			}
+		}
+
+		if copyright != "" {
+			return ep.Set(exifcommon.IfdPathStandard, CopyrightTagId, copyright)
+		}
+
+		return nil
+	})
+	log.PanicIf(err)
+
+	xmp, err := GetJpegXmp(updated)
+	if err != nil && err != ErrNoXmp {
+		log.Panic(err)
+	}
+
+	updated, err = SetJpegXmp(updated, MergeRightsIntoXmp(xmp, creators, copyright))
+	log.PanicIf(err)
+
+	iptc, err := GetJpegIptc(updated)
+	if err != nil && err != ErrNoIptc {
+		log.Panic(err)
+	}
+
+	updated, err = SetJpegIptc(updated, MergeRightsIntoIptc(iptc, creators, copyright))
+	log.PanicIf(err)
+
+	return updated, nil
+}
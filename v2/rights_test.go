@@ -0,0 +1,117 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestGetRightsInfo(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := SetRights(rootIb, []string{"Jane Doe"}, "(c) Jane Doe")
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	xmp := MergeRightsIntoXmp(nil, []string{"Jane Doe"}, "(c) Jane Doe")
+	iptc := MergeRightsIntoIptc(nil, []string{"John Doe"}, "(c) Jane Doe")
+
+	ri, err := GetRightsInfo(index, xmp, iptc)
+	log.PanicIf(err)
+
+	if ri.Artist != "Jane Doe" || ri.Copyright != "(c) Jane Doe" {
+		t.Fatalf("EXIF rights not correct: %s", ri)
+	} else if reflect.DeepEqual(ri.XmpCreators, []string{"Jane Doe"}) != true || ri.XmpRights != "(c) Jane Doe" {
+		t.Fatalf("XMP rights not correct: %s", ri)
+	} else if reflect.DeepEqual(ri.IptcBylines, []string{"John Doe"}) != true || ri.IptcCopyright != "(c) Jane Doe" {
+		t.Fatalf("IPTC rights not correct: %s", ri)
+	} else if ri.Creator() != "Jane Doe" {
+		t.Fatalf("Creator not correct: [%s]", ri.Creator())
+	}
+
+	expected := []string{
+		"EXIF creator [Jane Doe] doesn't match IPTC creator [John Doe]",
+		"XMP creator [Jane Doe] doesn't match IPTC creator [John Doe]",
+	}
+
+	if reflect.DeepEqual(ri.Problems, expected) != true {
+		t.Fatalf("Problems not correct: %v", ri.Problems)
+	}
+}
+
+func TestSetJpegRights(t *testing.T) {
+	data := getTestJpegWithExif()
+
+	ri, err := GetJpegRightsInfo(data)
+	log.PanicIf(err)
+
+	if ri.Creator() != "" {
+		t.Fatalf("Test image should not have a creator: %s", ri)
+	}
+
+	updated, err := SetJpegRights(data, []string{"Jane Doe", "John Doe"}, "(c) 2020 Jane Doe")
+	log.PanicIf(err)
+
+	ri, err = GetJpegRightsInfo(updated)
+	log.PanicIf(err)
+
+	if ri.IsConsistent() != true {
+		t.Fatalf("Rights not consistent: %v", ri.Problems)
+	} else if ri.Artist != "Jane Doe; John Doe" || ri.Copyright != "(c) 2020 Jane Doe" {
+		t.Fatalf("EXIF rights not correct: %s", ri)
+	} else if reflect.DeepEqual(ri.XmpCreators, []string{"Jane Doe", "John Doe"}) != true || ri.XmpRights != "(c) 2020 Jane Doe" {
+		t.Fatalf("XMP rights not correct: %s", ri)
+	} else if reflect.DeepEqual(ri.IptcBylines, []string{"Jane Doe", "John Doe"}) != true || ri.IptcCopyright != "(c) 2020 Jane Doe" {
+		t.Fatalf("IPTC rights not correct: %s", ri)
+	}
+
+	// The rest of the EXIF is untouched.
+	_, err = GetJpegRightsInfo(updated)
+	log.PanicIf(err)
+
+	rawExif, err := SearchAndExtractExif(updated)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	model, err := getIfdTagString(index.RootIfd, "Model")
+	log.PanicIf(err)
+
+	if model != "Canon EOS 5D Mark III" {
+		t.Fatalf("Model not preserved: [%s]", model)
+	}
+}
+
+func TestRightsInfo_Artists(t *testing.T) {
+	ri := RightsInfo{
+		Artist:      "Jane Doe;John Doe",
+		Copyright:   "(c) Jane Doe\x00(c) John Doe",
+		XmpCreators: []string{"Jane Doe", "John Doe"},
+		XmpRights:   "(c) Jane Doe",
+	}
+
+	ri.check()
+
+	if reflect.DeepEqual(ri.Artists(), []string{"Jane Doe", "John Doe"}) != true {
+		t.Fatalf("Artists not correct: %v", ri.Artists())
+	} else if ri.Creator() != "Jane Doe" {
+		t.Fatalf("Creator not correct: [%s]", ri.Creator())
+	} else if ri.IsConsistent() != true {
+		t.Fatalf("Rights should be consistent: %v", ri.Problems)
+	}
+}
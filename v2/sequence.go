@@ -0,0 +1,175 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// CompositeImage values (EXIF 2.31).
+	CompositeImageUnknown       = 0
+	CompositeImageNone          = 1
+	CompositeImageGeneral       = 2
+	CompositeImageWhileShooting = 3
+)
+
+const (
+	canonCameraSettingsTagId           = 0x0001
+	canonCameraSettingsContinuousDrive = 5
+	canonShotInfoSequenceNumberIndex   = 9
+	nikonShootingModeTagId             = 0x0089
+	nikonShootingModeContinuousBit     = 0x0001
+	sonyReleaseModeTagId               = 0xb049
+	sonySequenceNumberTagId            = 0xb04a
+	sonyReleaseModeContinuous          = 2
+)
+
+var (
+	// ErrNoSequenceInfo means that no burst or sequence information was
+	// found.
+	ErrNoSequenceInfo = errors.New("no sequence info")
+)
+
+var (
+	canonContinuousDriveNames = map[uint16]string{
+		0:  "Single",
+		1:  "Continuous",
+		2:  "Movie",
+		3:  "Continuous, Speed Priority",
+		4:  "Continuous, Low",
+		5:  "Continuous, High",
+		6:  "Silent Single",
+		9:  "Single, Silent",
+		10: "Continuous, Silent",
+	}
+)
+
+// SequenceInfo describes how a shot relates to the others taken around it.
+type SequenceInfo struct {
+	// BurstId is shared by every shot in a burst. Only some cameras record
+	// one (e.g. Apple's BurstUUID).
+	BurstId string
+
+	// SequenceNumber is the position of the shot in a continuous sequence.
+	SequenceNumber    int
+	HasSequenceNumber bool
+
+	// IsContinuous is true if the camera was in a continuous drive mode.
+	IsContinuous bool
+	DriveMode    string
+
+	// CompositeImage is one of the CompositeImage* constants, and
+	// SourceImageCount is the number of images that went into it.
+	CompositeImage   uint16
+	SourceImageCount int
+
+	Sources []string
+}
+
+// String returns a descriptive string.
+func (si SequenceInfo) String() string {
+	return fmt.Sprintf("SequenceInfo<BURST-ID=[%s] SEQUENCE-NUMBER=(%d) CONTINUOUS=[%v] DRIVE-MODE=[%s] COMPOSITE=(%d) SOURCES=%v>", si.BurstId, si.SequenceNumber, si.IsContinuous, si.DriveMode, si.CompositeImage, si.Sources)
+}
+
+// IsBurst returns true if the shot was part of a burst rather than a single
+// exposure.
+func (si SequenceInfo) IsBurst() bool {
+	return si.BurstId != "" || si.IsContinuous == true || si.SequenceNumber > 0
+}
+
+// GetSequenceInfo collects the burst and sequence information from the
+// standard CompositeImage tags and the Apple, Canon, Nikon, and Sony
+// maker-notes. `ErrNoSequenceInfo` is returned if nothing was found.
+//
+// Only Apple records a burst identifier. For other cameras, shots from the
+// same burst have to be grouped by body (see `GetSerialNumbers()`) and
+// capture time, using the sequence number to order them.
+func GetSequenceInfo(index IfdIndex) (si SequenceInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	si.Sources = make([]string, 0)
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd := ifds[0]
+
+		compositeImage, err := getIfdTagNumber(exifIfd, "CompositeImage")
+		log.PanicIf(err)
+
+		if compositeImage != 0 {
+			si.CompositeImage = uint16(compositeImage)
+			si.Sources = append(si.Sources, "Exif/CompositeImage")
+		}
+
+		// The first value is the total number of source images and the
+		// second is the number that were used.
+		sourceImageCount, err := getIfdTagNumber(exifIfd, "SourceImageNumberOfCompositeImage")
+		log.PanicIf(err)
+
+		if sourceImageCount != 0 {
+			si.SourceImageCount = int(sourceImageCount)
+			si.Sources = append(si.Sources, "Exif/SourceImageNumberOfCompositeImage")
+		}
+	}
+
+	vendor, mni, err := getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	switch vendor {
+	case makerNoteVendorApple:
+		if burstUuid, found := mni.String(appleBurstUuidTagId); found == true && burstUuid != "" {
+			si.BurstId = burstUuid
+			si.IsContinuous = true
+			si.Sources = append(si.Sources, "Apple/BurstUUID")
+		}
+	case makerNoteVendorCanon:
+		si.loadCanon(mni)
+	case makerNoteVendorNikon:
+		if shootingMode, found := mni.Int(nikonShootingModeTagId); found == true {
+			si.IsContinuous = shootingMode&nikonShootingModeContinuousBit != 0
+			si.Sources = append(si.Sources, "Nikon/ShootingMode")
+		}
+	case makerNoteVendorSony:
+		if releaseMode, found := mni.Int(sonyReleaseModeTagId); found == true {
+			si.IsContinuous = releaseMode == sonyReleaseModeContinuous
+			si.Sources = append(si.Sources, "Sony/ReleaseMode")
+		}
+
+		if sequenceNumber, found := mni.Int(sonySequenceNumberTagId); found == true {
+			si.SequenceNumber = int(sequenceNumber)
+			si.HasSequenceNumber = true
+			si.Sources = append(si.Sources, "Sony/SequenceNumber")
+		}
+	}
+
+	if len(si.Sources) == 0 {
+		return si, ErrNoSequenceInfo
+	}
+
+	return si, nil
+}
+
+// loadCanon reads the drive mode from CameraSettings and the sequence number
+// from ShotInfo.
+func (si *SequenceInfo) loadCanon(mni *makerNoteIfd) {
+	if cameraSettings, found := mni.Uint16s(canonCameraSettingsTagId); found == true && len(cameraSettings) > canonCameraSettingsContinuousDrive {
+		drive := cameraSettings[canonCameraSettingsContinuousDrive]
+
+		si.DriveMode = canonContinuousDriveNames[drive]
+		si.IsContinuous = drive == 1 || drive == 3 || drive == 4 || drive == 5 || drive == 10
+		si.Sources = append(si.Sources, "Canon/ContinuousDrive")
+	}
+
+	if shotInfo, found := mni.Uint16s(canonShotInfoTagId); found == true && len(shotInfo) > canonShotInfoSequenceNumberIndex {
+		si.SequenceNumber = int(shotInfo[canonShotInfoSequenceNumberIndex])
+		si.HasSequenceNumber = true
+		si.Sources = append(si.Sources, "Canon/SequenceNumber")
+	}
+}
@@ -0,0 +1,374 @@
+package exif
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// SequenceAnomalyKind is the kind of inconsistency between the frames of a
+// sequence.
+type SequenceAnomalyKind int
+
+const (
+	// SequenceAnomalyClockJump means that the capture time went backward, or
+	// forward by much more than the usual interval.
+	SequenceAnomalyClockJump SequenceAnomalyKind = iota
+
+	// SequenceAnomalyCameraChange means that the body serial number is
+	// different from that of the frame before.
+	SequenceAnomalyCameraChange
+
+	// SequenceAnomalyMissingFrames means that the frame number skipped ahead.
+	SequenceAnomalyMissingFrames
+
+	// SequenceAnomalyGpsTeleport means that the location moved faster than
+	// anything could have carried the camera.
+	SequenceAnomalyGpsTeleport
+)
+
+// String returns the name of the kind.
+func (sak SequenceAnomalyKind) String() string {
+	switch sak {
+	case SequenceAnomalyClockJump:
+		return "clock-jump"
+	case SequenceAnomalyCameraChange:
+		return "camera-change"
+	case SequenceAnomalyMissingFrames:
+		return "missing-frames"
+	case SequenceAnomalyGpsTeleport:
+		return "gps-teleport"
+	}
+
+	return fmt.Sprintf("SequenceAnomalyKind(%d)", int(sak))
+}
+
+const (
+	// sequenceClockJumpFactor is how many times longer than the median
+	// interval a gap has to be to be a clock jump.
+	sequenceClockJumpFactor = 10
+
+	// sequenceMinClockJump is the shortest gap that's a clock jump, so that
+	// the pauses of a fast sequence aren't.
+	sequenceMinClockJump = time.Minute
+
+	// DefaultSequenceMaxSpeed is the fastest, in meters per second, that the
+	// camera is taken to move by default (about that of an airliner).
+	DefaultSequenceMaxSpeed = 300.0
+
+	// earthRadius is the mean radius of the Earth, in meters.
+	earthRadius = 6371008.8
+
+	// imageNumberTagId is the ImageNumber tag of IFD0 (TIFF/EP).
+	imageNumberTagId = 0x9211
+)
+
+// SequenceFrame is one file of a sequence.
+type SequenceFrame struct {
+	// Name identifies the frame (e.g. its file-path).
+	Name string
+
+	// Index is the parsed EXIF. A frame without EXIF has a zero index.
+	Index IfdIndex
+}
+
+// SequenceCheckOptions control `CheckSequence()`.
+type SequenceCheckOptions struct {
+	// MaxClockGap is the longest that the capture time can move forward
+	// between frames. Zero is ten times the median interval, and at least a
+	// minute.
+	MaxClockGap time.Duration
+
+	// MaxSpeed is the fastest, in meters per second, that the location can
+	// move between frames. Zero is `DefaultSequenceMaxSpeed`.
+	MaxSpeed float64
+}
+
+// SequenceAnomaly is an inconsistency between a frame and the one before it.
+type SequenceAnomaly struct {
+	Kind SequenceAnomalyKind
+
+	// Position is the index of the frame, and Name its name. PreviousName is
+	// the frame that it was compared to.
+	Position     int
+	Name         string
+	PreviousName string
+
+	// Description says what was found (e.g. "capture time went back 1h0m0s").
+	Description string
+}
+
+// String returns a descriptive string.
+func (sa SequenceAnomaly) String() string {
+	return fmt.Sprintf("SequenceAnomaly<KIND=[%s] POSITION=(%d) NAME=[%s] PREVIOUS=[%s] DESCRIPTION=[%s]>", sa.Kind, sa.Position, sa.Name, sa.PreviousName, sa.Description)
+}
+
+// sequenceFrameInfo is what's compared between frames.
+type sequenceFrameInfo struct {
+	captureTime    time.Time
+	hasCaptureTime bool
+
+	serialNumber string
+
+	number    int
+	hasNumber bool
+
+	latitude, longitude float64
+	hasLocation         bool
+}
+
+// getSequenceFrameInfo reads what's compared from the frame. Whatever's
+// missing isn't compared.
+func getSequenceFrameInfo(index IfdIndex) (sfi sequenceFrameInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if index.RootIfd == nil {
+		return sfi, nil
+	}
+
+	for _, tagName := range []string{"DateTimeOriginal", "DateTime"} {
+		if t, _, err := GetTimestamp(index, tagName); err == nil {
+			sfi.captureTime = t
+			sfi.hasCaptureTime = true
+
+			break
+		}
+	}
+
+	if sn, err := GetSerialNumbers(index); err == nil {
+		sfi.serialNumber = sn.Body
+	}
+
+	if results, err := index.RootIfd.FindTagWithId(imageNumberTagId); err == nil {
+		if value, err := results[0].Value(); err == nil {
+			if numbers, ok := value.([]uint32); ok == true && len(numbers) > 0 {
+				sfi.number = int(numbers[0])
+				sfi.hasNumber = true
+			}
+		}
+	}
+
+	if sfi.hasNumber == false {
+		if si, err := GetSequenceInfo(index); err == nil && si.HasSequenceNumber == true {
+			sfi.number = si.SequenceNumber
+			sfi.hasNumber = true
+		}
+	}
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardGps]; len(ifds) > 0 {
+		if gi, err := ifds[0].GpsInfo(); err == nil {
+			sfi.latitude = gi.Latitude.Decimal()
+			sfi.longitude = gi.Longitude.Decimal()
+			sfi.hasLocation = true
+		}
+	}
+
+	return sfi, nil
+}
+
+// gpsDistance returns the distance, in meters, between two coordinates along
+// the surface of the Earth.
+func gpsDistance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	toRadians := func(degrees float64) float64 {
+		return degrees * math.Pi / 180
+	}
+
+	deltaLatitude := toRadians(latitude2 - latitude1)
+	deltaLongitude := toRadians(longitude2 - longitude1)
+
+	a := math.Sin(deltaLatitude/2)*math.Sin(deltaLatitude/2) +
+		math.Cos(toRadians(latitude1))*math.Cos(toRadians(latitude2))*math.Sin(deltaLongitude/2)*math.Sin(deltaLongitude/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// CheckSequence compares each frame of a sequence (e.g. a time-lapse, or the
+// files of a shoot) to the one before it, in the order given, and returns the
+// outliers: the capture time jumping, the body serial number changing, the
+// frame number skipping ahead (by ImageNumber or the maker-note's sequence
+// number), and the location moving impossibly fast. What a frame doesn't have
+// isn't compared, and the anomalies are in the order of the frames.
+func CheckSequence(frames []SequenceFrame, opts SequenceCheckOptions) (anomalies []SequenceAnomaly, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if opts.MaxSpeed == 0 {
+		opts.MaxSpeed = DefaultSequenceMaxSpeed
+	}
+
+	infos := make([]sequenceFrameInfo, len(frames))
+	for i, frame := range frames {
+		infos[i], err = getSequenceFrameInfo(frame.Index)
+		log.PanicIf(err)
+	}
+
+	maxClockGap := opts.MaxClockGap
+	if maxClockGap == 0 {
+		maxClockGap = getSequenceMaxClockGap(infos)
+	}
+
+	anomalies = make([]SequenceAnomaly, 0)
+
+	add := func(kind SequenceAnomalyKind, i, previous int, format string, args ...interface{}) {
+		sa := SequenceAnomaly{
+			Kind:         kind,
+			Position:     i,
+			Name:         frames[i].Name,
+			PreviousName: frames[previous].Name,
+			Description:  fmt.Sprintf(format, args...),
+		}
+
+		anomalies = append(anomalies, sa)
+	}
+
+	// Each frame is compared to the last one before it that had each thing,
+	// so that a frame without it doesn't hide a change.
+
+	lastTime, lastSerial, lastNumber, lastLocation := -1, -1, -1, -1
+
+	for i, sfi := range infos {
+		if sfi.hasCaptureTime == true {
+			if lastTime >= 0 {
+				gap := sfi.captureTime.Sub(infos[lastTime].captureTime)
+
+				if gap < 0 {
+					add(SequenceAnomalyClockJump, i, lastTime, "capture time went back %s", -gap)
+				} else if gap > maxClockGap {
+					add(SequenceAnomalyClockJump, i, lastTime, "capture time went forward %s", gap)
+				}
+			}
+
+			lastTime = i
+		}
+
+		if sfi.serialNumber != "" {
+			if lastSerial >= 0 && sfi.serialNumber != infos[lastSerial].serialNumber {
+				add(SequenceAnomalyCameraChange, i, lastSerial, "body serial number changed from [%s] to [%s]", infos[lastSerial].serialNumber, sfi.serialNumber)
+			}
+
+			lastSerial = i
+		}
+
+		if sfi.hasNumber == true {
+			// A number that doesn't go up is a new sequence (e.g. the next
+			// burst), not a missing frame.
+			if lastNumber >= 0 {
+				if skipped := sfi.number - infos[lastNumber].number - 1; skipped > 0 {
+					add(SequenceAnomalyMissingFrames, i, lastNumber, "(%d) frames missing between (%d) and (%d)", skipped, infos[lastNumber].number, sfi.number)
+				}
+			}
+
+			lastNumber = i
+		}
+
+		if sfi.hasLocation == true {
+			if lastLocation >= 0 {
+				previous := infos[lastLocation]
+				distance := gpsDistance(previous.latitude, previous.longitude, sfi.latitude, sfi.longitude)
+
+				// Without capture times, or if they're the same, the frames
+				// are taken to be a second apart.
+				elapsed := time.Second
+				if sfi.hasCaptureTime == true && previous.hasCaptureTime == true {
+					if gap := sfi.captureTime.Sub(previous.captureTime); gap > elapsed {
+						elapsed = gap
+					} else if -gap > elapsed {
+						elapsed = -gap
+					}
+				}
+
+				if speed := distance / elapsed.Seconds(); speed > opts.MaxSpeed {
+					add(SequenceAnomalyGpsTeleport, i, lastLocation, "moved (%.0f) meters in %s", distance, elapsed)
+				}
+			}
+
+			lastLocation = i
+		}
+	}
+
+	return anomalies, nil
+}
+
+// CheckSequenceFiles reads the files and checks them as a sequence (see
+// `CheckSequence()`), using their file-paths as their names. Files without
+// EXIF aren't compared.
+func CheckSequenceFiles(filepaths []string, opts SequenceCheckOptions) (anomalies []SequenceAnomaly, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	frames := make([]SequenceFrame, len(filepaths))
+	for i, filepath := range filepaths {
+		frames[i].Name = filepath
+
+		rawExif, err := SearchFileAndExtractExif(filepath)
+		if err != nil {
+			if log.Is(err, ErrNoExif) == true {
+				continue
+			}
+
+			log.Panic(err)
+		}
+
+		_, frames[i].Index, err = Collect(im, ti, rawExif)
+		log.PanicIf(err)
+	}
+
+	anomalies, err = CheckSequence(frames, opts)
+	log.PanicIf(err)
+
+	return anomalies, nil
+}
+
+// getSequenceMaxClockGap returns the default largest gap: ten times the median
+// interval between capture times, and at least a minute.
+func getSequenceMaxClockGap(infos []sequenceFrameInfo) time.Duration {
+	intervals := make([]time.Duration, 0, len(infos))
+
+	var last *sequenceFrameInfo
+	for i := range infos {
+		if infos[i].hasCaptureTime == false {
+			continue
+		}
+
+		if last != nil {
+			if interval := infos[i].captureTime.Sub(last.captureTime); interval > 0 {
+				intervals = append(intervals, interval)
+			}
+		}
+
+		last = &infos[i]
+	}
+
+	maxClockGap := sequenceMinClockJump
+	if len(intervals) == 0 {
+		return maxClockGap
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i] < intervals[j]
+	})
+
+	if gap := intervals[len(intervals)/2] * sequenceClockJumpFactor; gap > maxClockGap {
+		maxClockGap = gap
+	}
+
+	return maxClockGap
+}
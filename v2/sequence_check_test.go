@@ -0,0 +1,180 @@
+package exif
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// testSequenceStart is the capture time of the first test frame.
+var (
+	testSequenceStart = time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)
+)
+
+// getTestSequenceExif returns the EXIF of a frame with the given frame number,
+// capture time, serial number, and latitude (in whole degrees).
+func getTestSequenceExif(number uint32, captureTime time.Time, serialNumber string, latitude uint32) []byte {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: imageNumberTagId, Value: []uint32{number}})
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags[3].Value = ExifFullTimestampString(captureTime)
+	exifIfd.Tags = append(exifIfd.Tags, exiftest.Tag{Id: 0xa431, Value: serialNumber})
+
+	gpsIfd := root.Children[1].Ifd
+	gpsIfd.Tags[1].Value = []exifcommon.Rational{{Numerator: latitude, Denominator: 1}, {Numerator: 0, Denominator: 1}, {Numerator: 0, Denominator: 1}}
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return rawExif
+}
+
+// getTestSequenceFrame returns a frame (see `getTestSequenceExif()`).
+func getTestSequenceFrame(number uint32, captureTime time.Time, serialNumber string, latitude uint32) SequenceFrame {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestSequenceExif(number, captureTime, serialNumber, latitude))
+	log.PanicIf(err)
+
+	return SequenceFrame{
+		Name:  fmt.Sprintf("IMG_%04d.JPG", number),
+		Index: index,
+	}
+}
+
+// getTestSequenceAnomalyKinds returns the position and kind of each anomaly.
+func getTestSequenceAnomalyKinds(anomalies []SequenceAnomaly) []string {
+	kinds := make([]string, len(anomalies))
+	for i, sa := range anomalies {
+		kinds[i] = fmt.Sprintf("(%d) %s", sa.Position, sa.Kind)
+	}
+
+	return kinds
+}
+
+func TestCheckSequence(t *testing.T) {
+	frames := []SequenceFrame{
+		getTestSequenceFrame(1, testSequenceStart, "1234", 26),
+		getTestSequenceFrame(2, testSequenceStart.Add(10*time.Second), "1234", 26),
+		getTestSequenceFrame(3, testSequenceStart.Add(20*time.Second), "1234", 26),
+
+		// Two frames are missing.
+		getTestSequenceFrame(6, testSequenceStart.Add(50*time.Second), "1234", 26),
+
+		// The clock was set back an hour.
+		getTestSequenceFrame(7, testSequenceStart.Add(-time.Hour), "1234", 26),
+		getTestSequenceFrame(8, testSequenceStart.Add(-time.Hour+10*time.Second), "1234", 26),
+
+		// Another camera, a thousand kilometers away.
+		getTestSequenceFrame(9, testSequenceStart.Add(-time.Hour+20*time.Second), "5678", 35),
+
+		// Without EXIF.
+		{Name: "IMG_0010.JPG"},
+
+		// The clock jumps ahead a day.
+		getTestSequenceFrame(11, testSequenceStart.Add(23*time.Hour), "5678", 35),
+	}
+
+	anomalies, err := CheckSequence(frames, SequenceCheckOptions{})
+	log.PanicIf(err)
+
+	expected := []string{
+		"(3) missing-frames",
+		"(4) clock-jump",
+		"(6) camera-change",
+		"(6) gps-teleport",
+		"(8) clock-jump",
+		"(8) missing-frames",
+	}
+
+	if kinds := getTestSequenceAnomalyKinds(anomalies); reflect.DeepEqual(kinds, expected) != true {
+		t.Fatalf("Anomalies not correct: %v", anomalies)
+	}
+
+	if sa := anomalies[0]; sa.Name != "IMG_0006.JPG" || sa.PreviousName != "IMG_0003.JPG" || sa.Description != "(2) frames missing between (3) and (6)" {
+		t.Fatalf("Missing-frames anomaly not correct: %s", sa)
+	} else if sa := anomalies[1]; sa.Description != "capture time went back 1h0m50s" {
+		t.Fatalf("Clock-jump anomaly not correct: %s", sa)
+	} else if sa := anomalies[2]; sa.Description != "body serial number changed from [1234] to [5678]" {
+		t.Fatalf("Camera-change anomaly not correct: %s", sa)
+	} else if sa := anomalies[4]; sa.PreviousName != "IMG_0009.JPG" {
+		t.Fatalf("Frame without EXIF should have been skipped: %s", sa)
+	}
+}
+
+func TestCheckSequence_Consistent(t *testing.T) {
+	frames := make([]SequenceFrame, 5)
+	for i := range frames {
+		frames[i] = getTestSequenceFrame(uint32(i+1), testSequenceStart.Add(time.Duration(i)*5*time.Minute), "1234", 26)
+	}
+
+	anomalies, err := CheckSequence(frames, SequenceCheckOptions{})
+	log.PanicIf(err)
+
+	if len(anomalies) != 0 {
+		t.Fatalf("Expected no anomalies: %v", anomalies)
+	}
+
+	// The same sequence, with a gap that's smaller than the default.
+
+	anomalies, err = CheckSequence(frames, SequenceCheckOptions{MaxClockGap: time.Minute})
+	log.PanicIf(err)
+
+	if len(anomalies) != 4 || anomalies[0].Kind != SequenceAnomalyClockJump {
+		t.Fatalf("Expected clock jumps: %v", anomalies)
+	}
+}
+
+func TestCheckSequence_NewSequence(t *testing.T) {
+	// A frame number that doesn't go up starts a new sequence.
+	frames := []SequenceFrame{
+		getTestSequenceFrame(9998, testSequenceStart, "1234", 26),
+		getTestSequenceFrame(9999, testSequenceStart.Add(time.Second), "1234", 26),
+		getTestSequenceFrame(1, testSequenceStart.Add(2*time.Second), "1234", 26),
+	}
+
+	anomalies, err := CheckSequence(frames, SequenceCheckOptions{})
+	log.PanicIf(err)
+
+	if len(anomalies) != 0 {
+		t.Fatalf("Expected no anomalies: %v", anomalies)
+	}
+}
+
+func TestCheckSequenceFiles(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepaths := []string{
+		writeTestFile(tempPath, "1.jpg", exiftest.WrapJpeg(getTestSequenceExif(1, testSequenceStart, "1234", 26))),
+		writeTestFile(tempPath, "2.jpg", []byte("not an image")),
+		writeTestFile(tempPath, "3.jpg", exiftest.WrapJpeg(getTestSequenceExif(3, testSequenceStart.Add(time.Second), "1234", 26))),
+	}
+
+	anomalies, err := CheckSequenceFiles(filepaths, SequenceCheckOptions{})
+	log.PanicIf(err)
+
+	if len(anomalies) != 1 || anomalies[0].Kind != SequenceAnomalyMissingFrames || anomalies[0].Name != filepaths[2] {
+		t.Fatalf("Anomalies not correct: %v", anomalies)
+	}
+}
+
+func TestGpsDistance(t *testing.T) {
+	// One degree of latitude is about 111km.
+	if distance := gpsDistance(26, -80, 27, -80); distance < 111000 || distance > 111400 {
+		t.Fatalf("Distance not correct: (%f)", distance)
+	} else if distance := gpsDistance(26, -80, 26, -80); distance != 0 {
+		t.Fatalf("Distance to the same place not zero: (%f)", distance)
+	}
+}
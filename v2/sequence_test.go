@@ -0,0 +1,82 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestGetSequenceInfo_Canon(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	si, err := GetSequenceInfo(index)
+	log.PanicIf(err)
+
+	if si.DriveMode != "Continuous, Low" || si.IsContinuous != true {
+		t.Fatalf("Drive mode not correct: %s", si)
+	} else if si.HasSequenceNumber != true || si.SequenceNumber != 0 {
+		t.Fatalf("Sequence number not correct: %s", si)
+	} else if si.IsBurst() != true {
+		t.Fatalf("Expected burst.")
+	}
+}
+
+func TestGetSequenceInfo_Apple(t *testing.T) {
+	si, err := GetSequenceInfo(getTestMakerNoteIndex("Apple", getTestLivePhotoMakerNote(), nil, nil))
+	log.PanicIf(err)
+
+	if si.BurstId != testBurstUuid {
+		t.Fatalf("Burst ID not correct: [%s]", si.BurstId)
+	} else if si.IsBurst() != true {
+		t.Fatalf("Expected burst.")
+	}
+}
+
+func TestGetSequenceInfo_Sony(t *testing.T) {
+	b := new(bytes.Buffer)
+	b.Write(sonyMakerNoteSignatures[0])
+
+	binary.Write(b, binary.BigEndian, uint16(2))
+
+	binary.Write(b, binary.BigEndian, []uint16{sonyReleaseModeTagId, uint16(exifcommon.TypeShort), 0, 1, sonyReleaseModeContinuous, 0})
+	binary.Write(b, binary.BigEndian, []uint16{sonySequenceNumberTagId, uint16(exifcommon.TypeShort), 0, 1, 7, 0})
+
+	binary.Write(b, binary.BigEndian, uint32(0))
+
+	si, err := GetSequenceInfo(getTestMakerNoteIndex("SONY", b.Bytes(), nil, nil))
+	log.PanicIf(err)
+
+	if si.IsContinuous != true || si.SequenceNumber != 7 {
+		t.Fatalf("Sequence info not correct: %s", si)
+	}
+}
+
+func TestGetSequenceInfo_Composite(t *testing.T) {
+	exifTags := []exiftest.Tag{
+		{Id: 0xa460, Value: []uint16{CompositeImageWhileShooting}},
+		{Id: 0xa461, Value: []uint16{5, 3}},
+	}
+
+	si, err := GetSequenceInfo(getTestMakerNoteIndex("Acme", nil, nil, exifTags))
+	log.PanicIf(err)
+
+	if si.CompositeImage != CompositeImageWhileShooting || si.SourceImageCount != 5 {
+		t.Fatalf("Composite info not correct: %s", si)
+	} else if si.IsBurst() != false {
+		t.Fatalf("Composite alone isn't a burst.")
+	}
+}
+
+func TestGetSequenceInfo_None(t *testing.T) {
+	_, err := GetSequenceInfo(getTestMakerNoteIndex("Acme", nil, nil, nil))
+	if err != ErrNoSequenceInfo {
+		t.Fatalf("Expected no sequence info: %v", err)
+	}
+}
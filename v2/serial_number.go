@@ -0,0 +1,137 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	canonSerialNumberTagId         = 0x000c
+	canonInternalSerialNumberTagId = 0x0096
+	nikonSerialNumberTagId         = 0x001d
+	pentaxSerialNumberTagId        = 0x0229
+)
+
+var (
+	// ErrNoSerialNumber means that neither a body nor a lens serial number was
+	// found.
+	ErrNoSerialNumber = errors.New("no serial number")
+)
+
+// SerialNumbers has the body and lens serial numbers along with the tag that
+// each was read from (e.g. "Exif/BodySerialNumber" or
+// "Nikon/SerialNumber").
+type SerialNumbers struct {
+	Body       string
+	BodySource string
+
+	Lens       string
+	LensSource string
+}
+
+// String returns a descriptive string.
+func (sn SerialNumbers) String() string {
+	return fmt.Sprintf("SerialNumbers<BODY=[%s] BODY-SOURCE=[%s] LENS=[%s] LENS-SOURCE=[%s]>", sn.Body, sn.BodySource, sn.Lens, sn.LensSource)
+}
+
+// GetSerialNumbers returns the body and lens serial numbers. The standard
+// EXIF 2.3 tags are preferred, then the DNG CameraSerialNumber tag, and then
+// the Canon, Nikon, and Pentax maker-notes. Note that Canon's maker-note
+// records the internal serial number, which doesn't always match the one
+// printed on the body. `ErrNoSerialNumber` is returned if nothing was found.
+func GetSerialNumbers(index IfdIndex) (sn SerialNumbers, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd := ifds[0]
+
+		sn.Body, err = getIfdTagString(exifIfd, "BodySerialNumber")
+		log.PanicIf(err)
+
+		if sn.Body != "" {
+			sn.BodySource = "Exif/BodySerialNumber"
+		}
+
+		sn.Lens, err = getIfdTagString(exifIfd, "LensSerialNumber")
+		log.PanicIf(err)
+
+		if sn.Lens != "" {
+			sn.LensSource = "Exif/LensSerialNumber"
+		}
+	}
+
+	if sn.Body == "" {
+		sn.Body, err = getIfdTagString(index.RootIfd, "CameraSerialNumber")
+		log.PanicIf(err)
+
+		if sn.Body != "" {
+			sn.BodySource = "IFD/CameraSerialNumber"
+		}
+	}
+
+	if sn.Body == "" {
+		vendor, mni, err := getVendorMakerNote(index)
+		log.PanicIf(err)
+
+		switch vendor {
+		case makerNoteVendorCanon:
+			if serial, found := mni.String(canonInternalSerialNumberTagId); found == true && serial != "" {
+				sn.Body = serial
+				sn.BodySource = "Canon/InternalSerialNumber"
+			} else if serial, found := mni.Int(canonSerialNumberTagId); found == true && serial != 0 {
+				sn.Body = fmt.Sprintf("%d", serial)
+				sn.BodySource = "Canon/SerialNumber"
+			}
+		case makerNoteVendorNikon:
+			if serial, found := mni.String(nikonSerialNumberTagId); found == true && serial != "" {
+				sn.Body = serial
+				sn.BodySource = "Nikon/SerialNumber"
+			}
+		case makerNoteVendorPentax:
+			if serial, found := mni.String(pentaxSerialNumberTagId); found == true && serial != "" {
+				sn.Body = serial
+				sn.BodySource = "Pentax/SerialNumber"
+			}
+		}
+	}
+
+	if sn.Body == "" && sn.Lens == "" {
+		return sn, ErrNoSerialNumber
+	}
+
+	return sn, nil
+}
+
+// SetSerialNumbers writes the standard BodySerialNumber and LensSerialNumber
+// tags into the Exif IFD, creating it if necessary. Empty values are left
+// alone. Maker-note variants aren't modified.
+func SetSerialNumbers(rootIb *IfdBuilder, body, lens string) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	if body != "" {
+		err := exifIb.SetStandardWithName("BodySerialNumber", body)
+		log.PanicIf(err)
+	}
+
+	if lens != "" {
+		err := exifIb.SetStandardWithName("LensSerialNumber", lens)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
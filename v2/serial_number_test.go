@@ -0,0 +1,99 @@
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestGetSerialNumbers(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	sn, err := GetSerialNumbers(index)
+	log.PanicIf(err)
+
+	if sn.Body != "063024020097" || sn.BodySource != "Exif/BodySerialNumber" {
+		t.Fatalf("Body serial not correct: %s", sn)
+	} else if sn.Lens != "2400001068" || sn.LensSource != "Exif/LensSerialNumber" {
+		t.Fatalf("Lens serial not correct: %s", sn)
+	}
+}
+
+func TestGetSerialNumbers_MakerNote(t *testing.T) {
+	makerNoteIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: nikonSerialNumberTagId, Value: "3012345"},
+		},
+	}
+
+	tiff, err := exiftest.Build(makerNoteIfd, exifcommon.TestDefaultByteOrder)
+	log.PanicIf(err)
+
+	makerNote := append([]byte("Nikon\x00\x02\x10\x00\x00"), tiff...)
+
+	sn, err := GetSerialNumbers(getTestMakerNoteIndex("NIKON CORPORATION", makerNote, nil, nil))
+	log.PanicIf(err)
+
+	if sn.Body != "3012345" || sn.BodySource != "Nikon/SerialNumber" {
+		t.Fatalf("Body serial not correct: %s", sn)
+	} else if sn.Lens != "" {
+		t.Fatalf("Lens serial not expected: %s", sn)
+	}
+}
+
+func TestGetSerialNumbers_None(t *testing.T) {
+	_, err := GetSerialNumbers(getTestMakerNoteIndex("Acme", nil, nil, nil))
+	if err != ErrNoSerialNumber {
+		t.Fatalf("Expected no serial number: %v", err)
+	}
+}
+
+func TestSetSerialNumbers(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("Make", "Acme")
+	log.PanicIf(err)
+
+	err = SetSerialNumbers(rootIb, "B123", "L456")
+	log.PanicIf(err)
+
+	ibe := NewIfdByteEncoder()
+
+	exifData, err := ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	sn, err := GetSerialNumbers(index)
+	log.PanicIf(err)
+
+	if sn.Body != "B123" || sn.Lens != "L456" {
+		t.Fatalf("Serial numbers not correct: %s", sn)
+	}
+
+	// Empty values leave the existing tags alone.
+
+	err = SetSerialNumbers(rootIb, "B789", "")
+	log.PanicIf(err)
+
+	exifData, err = ibe.EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err = Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	sn, err = GetSerialNumbers(index)
+	log.PanicIf(err)
+
+	if sn.Body != "B789" || sn.Lens != "L456" {
+		t.Fatalf("Serial numbers not correct after update: %s", sn)
+	}
+}
@@ -0,0 +1,179 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// ShutterCountConfidenceHigh means that the count is stored plainly in a
+	// documented tag.
+	ShutterCountConfidenceHigh = "high"
+
+	// ShutterCountConfidenceMedium means that the count had to be deciphered
+	// or read from a structure whose layout varies between models.
+	ShutterCountConfidenceMedium = "medium"
+)
+
+const (
+	nikonShutterCountTagId = 0x00a7
+
+	sonyTag9050TagId              = 0x9050
+	sonyTag9050ShutterCountOffset = 0x3a
+
+	pentaxDateTagId         = 0x0006
+	pentaxTimeTagId         = 0x0007
+	pentaxShutterCountTagId = 0x005d
+)
+
+var (
+	// ErrNoShutterCount means that the shutter count isn't recorded, or is
+	// recorded somewhere that we don't know how to read.
+	ErrNoShutterCount = errors.New("no shutter count")
+)
+
+var (
+	// sonyDecipherTable reverses the substitution cipher that Sony applies to
+	// some maker-note blocks, where each byte b below 249 is stored as
+	// b^3 mod 249.
+	sonyDecipherTable [256]byte
+)
+
+// ShutterCountResult is the number of actuations along with where it came
+// from.
+type ShutterCountResult struct {
+	Count uint32
+
+	// Source is the vendor block the count was read from (e.g.
+	// "Nikon/ShutterCount").
+	Source string
+
+	// Confidence is one of the ShutterCountConfidence* constants.
+	Confidence string
+}
+
+// String returns a descriptive string.
+func (scr ShutterCountResult) String() string {
+	return fmt.Sprintf("ShutterCountResult<COUNT=(%d) SOURCE=[%s] CONFIDENCE=[%s]>", scr.Count, scr.Source, scr.Confidence)
+}
+
+// ShutterCount returns the shutter actuation count from the maker-note.
+// Nikon (ShutterCount), Sony (Tag9050), and Pentax (ShutterCount) are
+// supported. Olympus and Canon bodies don't record the count in the image
+// (or only in model-specific structures) and return `ErrNoShutterCount`.
+func ShutterCount(index IfdIndex) (scr ShutterCountResult, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	vendor, mni, err := getVendorMakerNote(index)
+	log.PanicIf(err)
+
+	found := false
+
+	switch vendor {
+	case makerNoteVendorNikon:
+		scr, found = nikonShutterCount(mni)
+	case makerNoteVendorSony:
+		scr, found = sonyShutterCount(mni)
+	case makerNoteVendorPentax:
+		scr, found = pentaxShutterCount(mni)
+	}
+
+	if found == false {
+		return scr, ErrNoShutterCount
+	}
+
+	return scr, nil
+}
+
+// nikonShutterCount reads the ShutterCount tag. Nikon also uses it as part of
+// the key for the encrypted blocks, so it's stored in the clear.
+func nikonShutterCount(mni *makerNoteIfd) (scr ShutterCountResult, found bool) {
+	count, found := mni.Int(nikonShutterCountTagId)
+	if found == false {
+		return scr, false
+	}
+
+	scr = ShutterCountResult{
+		Count:      uint32(count),
+		Source:     "Nikon/ShutterCount",
+		Confidence: ShutterCountConfidenceHigh,
+	}
+
+	return scr, true
+}
+
+// sonyShutterCount deciphers Tag9050 and reads the 24-bit count from it.
+func sonyShutterCount(mni *makerNoteIfd) (scr ShutterCountResult, found bool) {
+	entry, found := mni.entries[sonyTag9050TagId]
+	if found == false || len(entry.value) < sonyTag9050ShutterCountOffset+4 {
+		return scr, false
+	}
+
+	raw := make([]byte, 4)
+	for i := range raw {
+		raw[i] = sonyDecipherTable[entry.value[sonyTag9050ShutterCountOffset+i]]
+	}
+
+	count := mni.byteOrder.Uint32(raw) & 0x00ffffff
+	if count == 0 {
+		return scr, false
+	}
+
+	scr = ShutterCountResult{
+		Count:      count,
+		Source:     "Sony/Tag9050",
+		Confidence: ShutterCountConfidenceMedium,
+	}
+
+	return scr, true
+}
+
+// pentaxShutterCount reads ShutterCount, which is XORed with the Date and
+// Time tags (and then inverted).
+func pentaxShutterCount(mni *makerNoteIfd) (scr ShutterCountResult, found bool) {
+	countEntry, found := mni.entries[pentaxShutterCountTagId]
+	if found == false || len(countEntry.value) != 4 {
+		return scr, false
+	}
+
+	dateEntry, found := mni.entries[pentaxDateTagId]
+	if found == false || len(dateEntry.value) != 4 {
+		return scr, false
+	}
+
+	timeEntry, found := mni.entries[pentaxTimeTagId]
+	if found == false || len(timeEntry.value) < 3 {
+		return scr, false
+	}
+
+	timeValue := make([]byte, 4)
+	copy(timeValue, timeEntry.value)
+
+	key := binary.BigEndian.Uint32(dateEntry.value) ^ ^binary.BigEndian.Uint32(timeValue)
+
+	scr = ShutterCountResult{
+		Count:      binary.BigEndian.Uint32(countEntry.value) ^ key,
+		Source:     "Pentax/ShutterCount",
+		Confidence: ShutterCountConfidenceMedium,
+	}
+
+	return scr, true
+}
+
+func init() {
+	for i := 0; i < 256; i++ {
+		sonyDecipherTable[i] = byte(i)
+	}
+
+	for i := 0; i < 249; i++ {
+		sonyDecipherTable[(i*i*i)%249] = byte(i)
+	}
+}
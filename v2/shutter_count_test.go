@@ -0,0 +1,99 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestMakerNoteIfd builds a standalone maker-note IFD whose offsets are
+// relative to the start of the returned data.
+func getTestMakerNoteIfd(byteOrder binary.ByteOrder, tags []exiftest.Tag) *makerNoteIfd {
+	data, err := exiftest.Build(&exiftest.Ifd{Tags: tags}, byteOrder)
+	log.PanicIf(err)
+
+	return parseMakerNoteIfd(data, int(byteOrder.Uint32(data[4:])), byteOrder)
+}
+
+func TestShutterCount_Nikon(t *testing.T) {
+	makerNoteIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: nikonShutterCountTagId, Value: []uint32{48213}},
+		},
+	}
+
+	tiff, err := exiftest.Build(makerNoteIfd, binary.BigEndian)
+	log.PanicIf(err)
+
+	makerNote := append([]byte("Nikon\x00\x02\x10\x00\x00"), tiff...)
+
+	scr, err := ShutterCount(getTestMakerNoteIndex("NIKON CORPORATION", makerNote, nil, nil))
+	log.PanicIf(err)
+
+	if scr.Count != 48213 {
+		t.Fatalf("Count not correct: (%d)", scr.Count)
+	} else if scr.Source != "Nikon/ShutterCount" || scr.Confidence != ShutterCountConfidenceHigh {
+		t.Fatalf("Source not correct: %s", scr)
+	}
+}
+
+func TestShutterCount_Canon(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	_, err = ShutterCount(index)
+	if err != ErrNoShutterCount {
+		t.Fatalf("Expected no shutter count: %v", err)
+	}
+}
+
+func TestSonyShutterCount(t *testing.T) {
+	plain := make([]byte, 0x40)
+	binary.LittleEndian.PutUint32(plain[sonyTag9050ShutterCountOffset:], 0x7a000000|21007)
+
+	enciphered := make([]byte, len(plain))
+	for i, b := range plain {
+		enciphered[i] = byte((int(b) * int(b) * int(b)) % 249)
+	}
+
+	mni := getTestMakerNoteIfd(binary.LittleEndian, []exiftest.Tag{
+		{Id: sonyTag9050TagId, Raw: enciphered, Type: exifcommon.TypeUndefined},
+	})
+
+	scr, found := sonyShutterCount(mni)
+	if found != true {
+		t.Fatalf("Shutter count not found.")
+	} else if scr.Count != 21007 {
+		t.Fatalf("Count not correct: (%d)", scr.Count)
+	} else if scr.Confidence != ShutterCountConfidenceMedium {
+		t.Fatalf("Confidence not correct: [%s]", scr.Confidence)
+	}
+}
+
+func TestPentaxShutterCount(t *testing.T) {
+	date := []byte{0x07, 0xe4, 0x01, 0x02}
+	time := []byte{0x03, 0x04, 0x05}
+
+	key := binary.BigEndian.Uint32(date) ^ ^binary.BigEndian.Uint32(append(time, 0))
+
+	count := make([]byte, 4)
+	binary.BigEndian.PutUint32(count, 12345^key)
+
+	mni := getTestMakerNoteIfd(binary.BigEndian, []exiftest.Tag{
+		{Id: pentaxDateTagId, Raw: date, Type: exifcommon.TypeUndefined},
+		{Id: pentaxTimeTagId, Raw: time, Type: exifcommon.TypeUndefined},
+		{Id: pentaxShutterCountTagId, Raw: count, Type: exifcommon.TypeUndefined},
+	})
+
+	scr, found := pentaxShutterCount(mni)
+	if found != true {
+		t.Fatalf("Shutter count not found.")
+	} else if scr.Count != 12345 {
+		t.Fatalf("Count not correct: (%d)", scr.Count)
+	}
+}
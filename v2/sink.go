@@ -0,0 +1,267 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"encoding/csv"
+
+	"github.com/dsoprea/go-logging"
+)
+
+var (
+	// ErrSinkNotFound means that no sink was registered with the given name.
+	ErrSinkNotFound = errors.New("sink not found")
+)
+
+// Sink is a destination for the results of parsing many files (e.g. the
+// results of `ParseMany()`). `Start()` is called before the first result and
+// `Close()` after the last. `Emit()` is never called concurrently.
+type Sink interface {
+	Start() (err error)
+	Emit(pr ParseResult) (err error)
+	Close() (err error)
+}
+
+// SinkFactory returns a new sink that writes to the given target. What the
+// target is depends on the sink (e.g. a file-path).
+type SinkFactory func(target string) (sink Sink, err error)
+
+var (
+	sinkFactories = make(map[string]SinkFactory)
+)
+
+// RegisterSink registers a sink under the given name so that tools can load
+// it with `NewSink()`. The sink-names in this package are "json", "csv", and
+// "sqlite" (see `SqlCatalog`).
+// It's not safe to call concurrently and should be called from `init()`.
+func RegisterSink(name string, factory SinkFactory) {
+	sinkFactories[name] = factory
+}
+
+// NewSink returns a new sink of the given name. `ErrSinkNotFound` is returned
+// if there isn't one.
+func NewSink(name, target string) (sink Sink, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	factory, found := sinkFactories[name]
+	if found == false {
+		return nil, ErrSinkNotFound
+	}
+
+	sink, err = factory(target)
+	log.PanicIf(err)
+
+	return sink, nil
+}
+
+// SinkNames returns the names of the registered sinks, sorted.
+func SinkNames() []string {
+	names := make([]string, 0, len(sinkFactories))
+	for name := range sinkFactories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// sinkOutput opens the file that a sink writes to. An empty target or "-" is
+// STDOUT, which isn't closed. Files are appended to, so that a run that's
+// resumed (see `BatchManifest`) adds to what was written before.
+type sinkOutput struct {
+	target string
+	w      io.Writer
+	f      *os.File
+
+	// appending is true if the file already had data.
+	appending bool
+}
+
+func (so *sinkOutput) open() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if so.target == "" || so.target == "-" {
+		so.w = os.Stdout
+		return nil
+	}
+
+	so.f, err = os.OpenFile(so.target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	log.PanicIf(err)
+
+	fi, err := so.f.Stat()
+	log.PanicIf(err)
+
+	so.w = so.f
+	so.appending = fi.Size() > 0
+
+	return nil
+}
+
+func (so *sinkOutput) close() (err error) {
+	if so.f == nil {
+		return nil
+	}
+
+	err = so.f.Close()
+	so.f = nil
+
+	return err
+}
+
+// JsonSink writes a JSON Lines record (see `JsonLinesEmitter`) for each file.
+type JsonSink struct {
+	output  sinkOutput
+	emitter *JsonLinesEmitter
+}
+
+// NewJsonSink returns a sink that appends to the file at the target, or writes
+// to STDOUT if the target is empty or "-".
+func NewJsonSink(target string) (sink Sink, err error) {
+	return &JsonSink{
+		output: sinkOutput{
+			target: target,
+		},
+	}, nil
+}
+
+// Start opens the output.
+func (js *JsonSink) Start() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = js.output.open()
+	log.PanicIf(err)
+
+	js.emitter = NewJsonLinesEmitter(js.output.w)
+
+	return nil
+}
+
+// Emit writes the record for the file.
+func (js *JsonSink) Emit(pr ParseResult) (err error) {
+	return js.emitter.Emit(pr)
+}
+
+// Close closes the output.
+func (js *JsonSink) Close() (err error) {
+	return js.output.close()
+}
+
+var (
+	// csvSinkHeader are the columns written by `CsvSink`.
+	csvSinkHeader = []string{"name", "error", "ifd_path", "tag_id", "tag_name", "tag_type", "value"}
+)
+
+// CsvSink writes a row for each tag of each file, with a header row. A file
+// that couldn't be parsed gets one row with the error.
+type CsvSink struct {
+	output sinkOutput
+	writer *csv.Writer
+}
+
+// NewCsvSink returns a sink that appends to the file at the target, or writes
+// to STDOUT if the target is empty or "-".
+func NewCsvSink(target string) (sink Sink, err error) {
+	return &CsvSink{
+		output: sinkOutput{
+			target: target,
+		},
+	}, nil
+}
+
+// Start opens the output and writes the header, unless the output already
+// has data.
+func (cs *CsvSink) Start() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = cs.output.open()
+	log.PanicIf(err)
+
+	cs.writer = csv.NewWriter(cs.output.w)
+
+	if cs.output.appending == false {
+		err := cs.writer.Write(csvSinkHeader)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// Emit writes the rows for the file.
+func (cs *CsvSink) Emit(pr ParseResult) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if pr.Err != nil {
+		err := cs.writer.Write([]string{pr.Name, pr.Err.Error(), "", "", "", "", ""})
+		log.PanicIf(err)
+	} else {
+		for _, ifd := range pr.Index.Ifds {
+			for _, ite := range ifd.Entries {
+				tagName := ""
+				if it, err := ifd.tagIndex.Get(ifd.IfdPath, ite.TagId()); err == nil {
+					tagName = it.Name
+				}
+
+				value, err := ite.Format()
+				log.PanicIf(err)
+
+				record := []string{
+					pr.Name,
+					"",
+					ifd.IfdPath,
+					fmt.Sprintf("0x%04x", ite.TagId()),
+					tagName,
+					ite.TagType().String(),
+					value,
+				}
+
+				err = cs.writer.Write(record)
+				log.PanicIf(err)
+			}
+		}
+	}
+
+	cs.writer.Flush()
+
+	err = cs.writer.Error()
+	log.PanicIf(err)
+
+	return nil
+}
+
+// Close closes the output.
+func (cs *CsvSink) Close() (err error) {
+	return cs.output.close()
+}
+
+func init() {
+	RegisterSink("json", NewJsonSink)
+	RegisterSink("csv", NewCsvSink)
+}
@@ -0,0 +1,198 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"encoding/binary"
+	"encoding/csv"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+type testSink struct {
+	started bool
+	names   []string
+	closed  bool
+}
+
+func (ts *testSink) Start() (err error) {
+	ts.started = true
+	return nil
+}
+
+func (ts *testSink) Emit(pr ParseResult) (err error) {
+	ts.names = append(ts.names, pr.Name)
+	return nil
+}
+
+func (ts *testSink) Close() (err error) {
+	ts.closed = true
+	return nil
+}
+
+func TestNewSink(t *testing.T) {
+	ts := new(testSink)
+
+	RegisterSink("test", func(target string) (sink Sink, err error) {
+		return ts, nil
+	})
+
+	defer delete(sinkFactories, "test")
+
+	sink, err := NewSink("test", "")
+	log.PanicIf(err)
+
+	if sink != ts {
+		t.Fatalf("Registered sink not returned.")
+	} else if reflect.DeepEqual(SinkNames(), []string{"csv", "json", "sqlite", "test"}) == false {
+		t.Fatalf("Sink names not correct: %v", SinkNames())
+	}
+
+	_, err = NewSink("invalid", "")
+	if err != ErrSinkNotFound {
+		t.Fatalf("Expected not-found error: %v", err)
+	}
+}
+
+func TestCsvSink(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "tags.csv")
+
+	sink, err := NewSink("csv", filepath)
+	log.PanicIf(err)
+
+	err = sink.Start()
+	log.PanicIf(err)
+
+	sources := []ParseSource{
+		BytesSource("good.jpg", exiftest.WrapJpeg(rawExif)),
+		BytesSource("bad.jpg", []byte("not an image")),
+	}
+
+	_, err = ParseMany(context.Background(), sources, ParseManyOptions{Concurrency: 1, OnResult: sink.Emit})
+	log.PanicIf(err)
+
+	err = sink.Close()
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	log.PanicIf(err)
+
+	found := false
+	for _, record := range records[1:] {
+		if reflect.DeepEqual(record, []string{"good.jpg", "", "IFD", "0x010f", "Make", "ASCII", "Canon"}) == true {
+			found = true
+		}
+	}
+
+	last := records[len(records)-1]
+
+	if reflect.DeepEqual(records[0], csvSinkHeader) == false {
+		t.Fatalf("Header not correct: %v", records[0])
+	} else if found == false {
+		t.Fatalf("Make row not found: %v", records)
+	} else if last[0] != "bad.jpg" || last[1] != ErrNoExif.Error() {
+		t.Fatalf("Error row not correct: %v", last)
+	}
+}
+
+func TestJsonSink(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "tags.jsonl")
+
+	sink, err := NewSink("json", filepath)
+	log.PanicIf(err)
+
+	err = sink.Start()
+	log.PanicIf(err)
+
+	sources := []ParseSource{
+		BytesSource("first.jpg", exiftest.WrapJpeg(rawExif)),
+		BytesSource("second.jpg", exiftest.WrapJpeg(rawExif)),
+	}
+
+	_, err = ParseMany(context.Background(), sources, ParseManyOptions{OnResult: sink.Emit})
+	log.PanicIf(err)
+
+	err = sink.Close()
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Line count not correct: (%d)", len(lines))
+	}
+}
+
+func TestCsvSink_Append(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := path.Join(tempPath, "tags.csv")
+
+	for _, name := range []string{"first.jpg", "second.jpg"} {
+		sink, err := NewSink("csv", filepath)
+		log.PanicIf(err)
+
+		err = sink.Start()
+		log.PanicIf(err)
+
+		_, err = ParseMany(context.Background(), []ParseSource{BytesSource(name, exiftest.WrapJpeg(rawExif))}, ParseManyOptions{OnResult: sink.Emit})
+		log.PanicIf(err)
+
+		err = sink.Close()
+		log.PanicIf(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	log.PanicIf(err)
+
+	names := make(map[string]int)
+	for _, record := range records {
+		names[record[0]]++
+	}
+
+	if names["name"] != 1 {
+		t.Fatalf("Header not written once: (%d)", names["name"])
+	} else if names["first.jpg"] == 0 || names["first.jpg"] != names["second.jpg"] {
+		t.Fatalf("Both runs not written: %v", names)
+	}
+}
@@ -0,0 +1,207 @@
+package exif
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// HistogramBucket is one value in a histogram and how often it occurred.
+type HistogramBucket struct {
+	Value float64
+	Count int
+}
+
+// NamedCount is a name and how often it occurred.
+type NamedCount struct {
+	Name  string
+	Count int
+}
+
+// LibraryStats aggregates the capture settings of many images, e.g. to show
+// which focal lengths a photographer actually uses. Call `Add()` with the
+// index of each image. It's safe for concurrent use.
+type LibraryStats struct {
+	// ImageCount is the number of indexes that have been added.
+	ImageCount int
+
+	// FocalLengths counts images by focal length (in mm).
+	FocalLengths map[float64]int
+
+	// IsoSpeeds counts images by ISO speed.
+	IsoSpeeds map[uint32]int
+
+	// Cameras counts images by make and model.
+	Cameras map[string]int
+
+	// Lenses counts images by lens model.
+	Lenses map[string]int
+
+	// Earliest and Latest are the range of capture times. They're zero if no
+	// image had a timestamp.
+	Earliest time.Time
+	Latest   time.Time
+
+	m sync.Mutex
+}
+
+// NewLibraryStats returns a new LibraryStats.
+func NewLibraryStats() *LibraryStats {
+	return &LibraryStats{
+		FocalLengths: make(map[float64]int),
+		IsoSpeeds:    make(map[uint32]int),
+		Cameras:      make(map[string]int),
+		Lenses:       make(map[string]int),
+	}
+}
+
+// Add includes one image in the statistics. Tags that are missing from the
+// image are skipped.
+func (ls *LibraryStats) Add(index IfdIndex) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rootIfd := index.RootIfd
+
+	make_, err := getIfdTagString(rootIfd, "Make")
+	log.PanicIf(err)
+
+	model, err := getIfdTagString(rootIfd, "Model")
+	log.PanicIf(err)
+
+	var focalLength, isoSpeed float64
+	var lensModel, timestampPhrase string
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd := ifds[0]
+
+		focalLength, err = getIfdTagNumber(exifIfd, "FocalLength")
+		log.PanicIf(err)
+
+		isoSpeed, err = getIfdTagNumber(exifIfd, "ISOSpeedRatings")
+		log.PanicIf(err)
+
+		lensModel, err = getIfdTagString(exifIfd, "LensModel")
+		log.PanicIf(err)
+
+		timestampPhrase, err = getIfdTagString(exifIfd, "DateTimeOriginal")
+		log.PanicIf(err)
+	}
+
+	if timestampPhrase == "" {
+		timestampPhrase, err = getIfdTagString(rootIfd, "DateTime")
+		log.PanicIf(err)
+	}
+
+	var timestamp time.Time
+	if timestampPhrase != "" {
+		// Unparseable timestamps are common enough that they shouldn't stop
+		// the whole aggregation.
+		timestamp, _ = ParseExifFullTimestamp(timestampPhrase)
+	}
+
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	ls.ImageCount++
+
+	if focalLength != 0 {
+		ls.FocalLengths[focalLength]++
+	}
+
+	if isoSpeed != 0 {
+		ls.IsoSpeeds[uint32(isoSpeed)]++
+	}
+
+	if camera := cameraName(make_, model); camera != "" {
+		ls.Cameras[camera]++
+	}
+
+	if lensModel != "" {
+		ls.Lenses[lensModel]++
+	}
+
+	if timestamp.IsZero() == false {
+		if ls.Earliest.IsZero() == true || timestamp.Before(ls.Earliest) == true {
+			ls.Earliest = timestamp
+		}
+
+		if ls.Latest.IsZero() == true || timestamp.After(ls.Latest) == true {
+			ls.Latest = timestamp
+		}
+	}
+
+	return nil
+}
+
+// cameraName returns the make and model as one name. The make is often
+// repeated at the start of the model, in which case it's not added again.
+func cameraName(make_, model string) string {
+	if make_ != "" && strings.HasPrefix(strings.ToLower(model), strings.ToLower(make_)) == false {
+		return strings.TrimSpace(make_ + " " + model)
+	}
+
+	return model
+}
+
+// FocalLengthHistogram returns the focal-length counts ordered by focal
+// length.
+func (ls *LibraryStats) FocalLengthHistogram() []HistogramBucket {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	buckets := make([]HistogramBucket, 0, len(ls.FocalLengths))
+	for value, count := range ls.FocalLengths {
+		buckets = append(buckets, HistogramBucket{Value: value, Count: count})
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Value < buckets[j].Value
+	})
+
+	return buckets
+}
+
+// IsoHistogram returns the ISO counts ordered by ISO speed.
+func (ls *LibraryStats) IsoHistogram() []HistogramBucket {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	buckets := make([]HistogramBucket, 0, len(ls.IsoSpeeds))
+	for value, count := range ls.IsoSpeeds {
+		buckets = append(buckets, HistogramBucket{Value: float64(value), Count: count})
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Value < buckets[j].Value
+	})
+
+	return buckets
+}
+
+// SortCounts returns the counts ordered from most to least common. Ties are
+// ordered by name.
+func SortCounts(counts map[string]int) []NamedCount {
+	sorted := make([]NamedCount, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, NamedCount{Name: name, Count: count})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
@@ -0,0 +1,51 @@
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+)
+
+func TestLibraryStats_Add(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	ls := NewLibraryStats()
+
+	for i := 0; i < 3; i++ {
+		err := ls.Add(index)
+		log.PanicIf(err)
+	}
+
+	if ls.ImageCount != 3 {
+		t.Fatalf("Image count not correct: (%d)", ls.ImageCount)
+	} else if ls.Cameras["Canon EOS 5D Mark III"] != 3 {
+		t.Fatalf("Camera counts not correct: %v", ls.Cameras)
+	} else if ls.Earliest.IsZero() == true || ls.Earliest.Equal(ls.Latest) != true {
+		t.Fatalf("Date range not correct: [%s] [%s]", ls.Earliest, ls.Latest)
+	}
+
+	focalLengths := ls.FocalLengthHistogram()
+	if len(focalLengths) != 1 || focalLengths[0].Count != 3 {
+		t.Fatalf("Focal-length histogram not correct: %v", focalLengths)
+	}
+
+	isoSpeeds := ls.IsoHistogram()
+	if len(isoSpeeds) != 1 || isoSpeeds[0].Count != 3 {
+		t.Fatalf("ISO histogram not correct: %v", isoSpeeds)
+	}
+}
+
+func TestSortCounts(t *testing.T) {
+	counts := map[string]int{
+		"b": 2,
+		"a": 2,
+		"c": 5,
+	}
+
+	sorted := SortCounts(counts)
+
+	if sorted[0].Name != "c" || sorted[1].Name != "a" || sorted[2].Name != "b" {
+		t.Fatalf("Order not correct: %v", sorted)
+	}
+}
@@ -0,0 +1,138 @@
+package exif
+
+import (
+	"fmt"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// SubIfdsTagId is the ID of the SubIFDs tag, which lists the offsets of
+	// additional IFDs. DNGs keep the raw image (and its opcode lists) in one
+	// of these.
+	SubIfdsTagId = 0x014a
+
+	// subIfdTagTypeIfd is the IFD type, which newer files use for SubIFDs in
+	// place of LONG. It's read the same way.
+	subIfdTagTypeIfd = exifcommon.TagTypePrimitive(13)
+)
+
+// subIfd is an IFD that's reached through a SubIFDs tag. These aren't in the
+// standard IFD mapping (the tag can list any number of them), so `Collect()`
+// doesn't descend into them and they're read raw.
+type subIfd struct {
+	// fqIfdPath names the IFD after its parent and its position in the
+	// parent's list (e.g. "IFD/SubIFD0").
+	fqIfdPath string
+
+	offset  uint32
+	entries []rawIfdEntry
+
+	data      []byte
+	byteOrder binary.ByteOrder
+}
+
+// find returns the entry with the given tag ID.
+func (si subIfd) find(tagId uint16) (rie rawIfdEntry, found bool) {
+	for _, rie := range si.entries {
+		if rie.tagId == tagId {
+			return rie, true
+		}
+	}
+
+	return rie, false
+}
+
+// readRawIfdValue returns the bytes of the entry's value, which are in the
+// entry itself if they fit.
+func readRawIfdValue(data []byte, rie rawIfdEntry, byteOrder binary.ByteOrder) (value []byte, err error) {
+	size := rie.valueSize()
+	if size <= 4 {
+		return rie.valueOffset[:size], nil
+	}
+
+	return exifcommon.CheckedSlice(data, byteOrder.Uint32(rie.valueOffset[:]), size)
+}
+
+// readSubIfds returns the IFDs listed by the SubIFDs entry among the given
+// entries, followed by any that they list in turn. Tables that are out of
+// bounds, that were already read, or that are nested more deeply than
+// `DefaultMaxIfdDepth` are skipped.
+func readSubIfds(data []byte, byteOrder binary.ByteOrder, parentFqIfdPath string, entries []rawIfdEntry) []subIfd {
+	return readSubIfdsRecursively(data, byteOrder, parentFqIfdPath, entries, make(map[uint32]struct{}))
+}
+
+func readSubIfdsRecursively(data []byte, byteOrder binary.ByteOrder, parentFqIfdPath string, entries []rawIfdEntry, seenOffsets map[uint32]struct{}) []subIfd {
+	subIfds := make([]subIfd, 0)
+
+	for _, rie := range entries {
+		if rie.tagId != SubIfdsTagId {
+			continue
+		} else if rie.tagType == subIfdTagTypeIfd {
+			rie.tagType = exifcommon.TypeLong
+		}
+
+		for i, offset := range readRawIfdUints(data, rie, byteOrder) {
+			if _, found := seenOffsets[offset]; found == true {
+				continue
+			} else if ifdDepth(parentFqIfdPath) >= DefaultMaxIfdDepth {
+				ifdEnumerateLogger.Warningf(nil, "SubIFD (%d) of [%s] at offset (0x%08x) is nested too deeply.", i, parentFqIfdPath, offset)
+				continue
+			}
+
+			seenOffsets[offset] = struct{}{}
+
+			childEntries, _, err := readRawIfdTable(data, offset, byteOrder)
+			if err != nil {
+				ifdEnumerateLogger.Warningf(nil, "SubIFD (%d) of [%s] at offset (0x%08x) could not be read: %s", i, parentFqIfdPath, offset, err)
+				continue
+			}
+
+			si := subIfd{
+				fqIfdPath: fmt.Sprintf("%s/SubIFD%d", parentFqIfdPath, i),
+				offset:    offset,
+				entries:   childEntries,
+				data:      data,
+				byteOrder: byteOrder,
+			}
+
+			subIfds = append(subIfds, si)
+			subIfds = append(subIfds, readSubIfdsRecursively(data, byteOrder, si.fqIfdPath, childEntries, seenOffsets)...)
+		}
+	}
+
+	return subIfds
+}
+
+// getSubIfds returns the SubIFDs of all of the IFDs in the index.
+func getSubIfds(index IfdIndex) (subIfds []subIfd, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	subIfds = make([]subIfd, 0)
+
+	for _, ifd := range index.Ifds {
+		// The tag may have been skipped by the enumerator if it has the IFD
+		// type, so the table is read again.
+		if len(ifd.Entries) == 0 {
+			continue
+		}
+
+		// The offsets are relative to the data that the IFD was read from.
+		data := ifd.Entries[0].getValueContext().AddressableData()
+
+		entries, _, err := readRawIfdTable(data, ifd.Offset, ifd.ByteOrder)
+		log.PanicIf(err)
+
+		subIfds = append(subIfds, readSubIfds(data, ifd.ByteOrder, ifd.FqIfdPath, entries)...)
+	}
+
+	return subIfds, nil
+}
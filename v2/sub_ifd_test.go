@@ -0,0 +1,138 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestTiffWithSubIfd returns a TIFF that keeps its image in a SubIFD, as
+// DNGs do, along with an opcode list. IFD0 has the given tags. The strip of
+// pixels follows the IFDs.
+func getTestTiffWithSubIfd(rootTags ...exiftest.Tag) (data []byte, stripOffset uint32) {
+	rawIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x00fe, Value: []uint32{0}},
+			{Id: 0x0111, Value: []uint32{0}},
+			{Id: 0x0117, Value: []uint32{uint32(len(testDngPixels))}},
+			{Id: 0xc740, Raw: getTestDngOpcodeList(), Type: exifcommon.TypeUndefined},
+		},
+	}
+
+	root := &exiftest.Ifd{
+		Tags: append([]exiftest.Tag{{Id: 0x010f, Value: "Canon"}}, rootTags...),
+		Children: []exiftest.Child{
+			{TagId: SubIfdsTagId, Ifd: rawIfd},
+		},
+	}
+
+	tiff, err := exiftest.Build(root, binary.LittleEndian)
+	log.PanicIf(err)
+
+	// The strip offset is stored in the entry itself, so setting it doesn't
+	// change the size.
+	stripOffset = uint32(len(tiff))
+	rawIfd.Tags[1].Value = []uint32{stripOffset}
+
+	tiff, err = exiftest.Build(root, binary.LittleEndian)
+	log.PanicIf(err)
+
+	return append(tiff, testDngPixels...), stripOffset
+}
+
+func TestGetSubIfds(t *testing.T) {
+	nestedIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x00fe, Value: []uint32{2}},
+		},
+	}
+
+	rawIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x00fe, Value: []uint32{0}},
+			{Id: 0x0100, Value: []uint32{4000}},
+		},
+		Children: []exiftest.Child{
+			{TagId: SubIfdsTagId, Ifd: nestedIfd},
+		},
+	}
+
+	root := exiftest.NewRealisticIfd()
+	root.Children = append(root.Children, exiftest.Child{TagId: SubIfdsTagId, Ifd: rawIfd})
+
+	exifData, err := exiftest.Build(root, binary.LittleEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	subIfds, err := getSubIfds(index)
+	log.PanicIf(err)
+
+	if len(subIfds) != 2 {
+		t.Fatalf("Expected two SubIFDs: %v", subIfds)
+	} else if subIfds[0].fqIfdPath != "IFD/SubIFD0" || subIfds[1].fqIfdPath != "IFD/SubIFD0/SubIFD0" {
+		t.Fatalf("Paths not correct: [%s] [%s]", subIfds[0].fqIfdPath, subIfds[1].fqIfdPath)
+	}
+
+	rie, found := subIfds[0].find(0x0100)
+	if found != true {
+		t.Fatalf("ImageWidth not found.")
+	}
+
+	value, err := readRawIfdValue(subIfds[0].data, rie, subIfds[0].byteOrder)
+	log.PanicIf(err)
+
+	if binary.LittleEndian.Uint32(value) != 4000 {
+		t.Fatalf("ImageWidth not correct: %v", value)
+	}
+}
+
+func TestReadSubIfds_IfdTypeAndCycle(t *testing.T) {
+	rawIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x00fe, Value: []uint32{0}},
+			{Id: SubIfdsTagId, Value: []uint32{0}},
+		},
+	}
+
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x010f, Value: "Canon"},
+		},
+		Children: []exiftest.Child{
+			{TagId: SubIfdsTagId, Ifd: rawIfd},
+		},
+	}
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	entries, _, err := readRawIfdTable(exifData, ExifDefaultFirstIfdOffset, binary.BigEndian)
+	log.PanicIf(err)
+
+	// Have the raw image list itself as a SubIFD. The size doesn't change.
+	rawIfdOffset := binary.BigEndian.Uint32(entries[1].valueOffset[:])
+	rawIfd.Tags[1].Value = []uint32{rawIfdOffset}
+
+	exifData, err = exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	entries, _, err = readRawIfdTable(exifData, ExifDefaultFirstIfdOffset, binary.BigEndian)
+	log.PanicIf(err)
+
+	// Newer files use the IFD type.
+	entries[1].tagType = subIfdTagTypeIfd
+
+	subIfds := readSubIfds(exifData, binary.BigEndian, exifcommon.IfdStandard, entries)
+	if len(subIfds) != 1 {
+		t.Fatalf("Expected the cycle to be cut: %v", subIfds)
+	} else if subIfds[0].offset != rawIfdOffset {
+		t.Fatalf("Offset not correct: (0x%08x)", subIfds[0].offset)
+	}
+}
@@ -0,0 +1,236 @@
+package exif
+
+import (
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// bestTakenTimeTagNames are the timestamps that `BestTakenTime()` tries,
+	// in order.
+	bestTakenTimeTagNames = []string{
+		"DateTimeOriginal",
+		"DateTimeDigitized",
+		"DateTime",
+	}
+)
+
+// Summary reads the tags that most applications want from an index as
+// ready-to-use values, so that they don't each have to convert GPS rationals,
+// combine timestamps with their SubSecTime and OffsetTime tags, and so on.
+type Summary struct {
+	index IfdIndex
+}
+
+// NewSummary returns a summary of the given index.
+func NewSummary(index IfdIndex) *Summary {
+	return &Summary{
+		index: index,
+	}
+}
+
+// GpsInfo returns the GPS info (see `Ifd.GpsInfo()`). `ErrNoGpsTags` is
+// returned if there isn't a GPS IFD or it doesn't have a position.
+func (s *Summary) GpsInfo() (gi *GpsInfo, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifds := s.index.Lookup[exifcommon.IfdPathStandardGps]
+	if len(ifds) == 0 {
+		return nil, ErrNoGpsTags
+	}
+
+	gi, err = ifds[0].GpsInfo()
+	log.PanicIf(err)
+
+	return gi, nil
+}
+
+// GpsDegrees returns the latitude and longitude in decimal degrees, which are
+// negative in the south and west.
+func (s *Summary) GpsDegrees() (latitude, longitude float64, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	gi, err := s.GpsInfo()
+	log.PanicIf(err)
+
+	return gi.Latitude.Decimal(), gi.Longitude.Decimal(), nil
+}
+
+// BestTakenTime returns when the image was taken, from DateTimeOriginal or,
+// if that isn't present, DateTimeDigitized or DateTime. The SubSecTime tag is
+// included and the OffsetTime tag, if present, gives the zone. Otherwise, the
+// timestamp is taken to be in the given location (UTC if nil), since EXIF
+// timestamps are local to wherever the camera was set up. `ErrTagNotFound` is
+// returned if none of them are present.
+func (s *Summary) BestTakenTime(location *time.Location) (t time.Time, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for _, tagName := range bestTakenTimeTagNames {
+		wallClock, _, err := GetTimestamp(s.index, tagName)
+		if err != nil {
+			if log.Is(err, ErrTagNotFound) == true {
+				continue
+			}
+
+			log.Panic(err)
+		}
+
+		if zone := s.offsetTime(getTimestampTag(tagName)); zone != nil {
+			location = zone
+		} else if location == nil {
+			location = time.UTC
+		}
+
+		t = time.Date(
+			wallClock.Year(), wallClock.Month(), wallClock.Day(),
+			wallClock.Hour(), wallClock.Minute(), wallClock.Second(), wallClock.Nanosecond(),
+			location)
+
+		return t, nil
+	}
+
+	return t, ErrTagNotFound
+}
+
+// offsetTime returns the zone from the OffsetTime tag that goes with the
+// timestamp, or nil if it isn't present or isn't valid.
+func (s *Summary) offsetTime(tt timestampTag) *time.Location {
+	ifds := s.index.Lookup[exifcommon.IfdPathStandardExif]
+	if len(ifds) == 0 {
+		return nil
+	}
+
+	results, err := ifds[0].FindTagWithId(tt.offsetTagId)
+	if err != nil {
+		return nil
+	}
+
+	value, err := results[0].Value()
+	if err != nil {
+		return nil
+	}
+
+	phrase, _ := value.(string)
+
+	zone, err := ParseExifOffsetTime(phrase)
+	if err != nil {
+		return nil
+	}
+
+	return zone
+}
+
+// Orientation returns the Orientation tag, or `OrientationTopLeft` (as the
+// standard says) if it isn't present.
+func (s *Summary) Orientation() (orientation uint16, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if s.index.RootIfd == nil {
+		return OrientationTopLeft, nil
+	}
+
+	value, err := getIfdTagNumber(s.index.RootIfd, "Orientation")
+	log.PanicIf(err)
+
+	if value == 0 {
+		return OrientationTopLeft, nil
+	}
+
+	return uint16(value), nil
+}
+
+// Rotation returns how to display the image (see `OrientationRotation()`).
+func (s *Summary) Rotation() (degrees int, mirrored bool, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	orientation, err := s.Orientation()
+	log.PanicIf(err)
+
+	degrees, mirrored, err = OrientationRotation(orientation)
+	log.PanicIf(err)
+
+	return degrees, mirrored, nil
+}
+
+// ExposureTime returns the ExposureTime, in seconds.
+func (s *Summary) ExposureTime() (seconds float64, err error) {
+	return s.exifNumber("ExposureTime")
+}
+
+// FNumber returns the FNumber.
+func (s *Summary) FNumber() (fNumber float64, err error) {
+	return s.exifNumber("FNumber")
+}
+
+// FocalLength returns the FocalLength, in millimeters.
+func (s *Summary) FocalLength() (millimeters float64, err error) {
+	return s.exifNumber("FocalLength")
+}
+
+// ExposureBias returns the ExposureBiasValue, in stops.
+func (s *Summary) ExposureBias() (stops float64, err error) {
+	return s.exifNumber("ExposureBiasValue")
+}
+
+// Iso returns the ISO sensitivity (see `GetIso()`). `ErrNoIso` is returned
+// if it isn't present.
+func (s *Summary) Iso() (iso float64, err error) {
+	value, err := GetIso(s.index)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(value.Value), nil
+}
+
+// exifNumber returns the first value of a numeric tag in the Exif IFD.
+// `ErrTagNotFound` is returned if it isn't present.
+func (s *Summary) exifNumber(tagName string) (value float64, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ifds := s.index.Lookup[exifcommon.IfdPathStandardExif]
+	if len(ifds) == 0 {
+		return 0, ErrTagNotFound
+	}
+
+	_, err = ifds[0].FindTagWithName(tagName)
+	if err != nil {
+		if log.Is(err, ErrTagNotFound) == true {
+			return 0, ErrTagNotFound
+		}
+
+		log.Panic(err)
+	}
+
+	value, err = getIfdTagNumber(ifds[0], tagName)
+	log.PanicIf(err)
+
+	return value, nil
+}
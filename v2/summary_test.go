@@ -0,0 +1,122 @@
+package exif
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestSummary(root *exiftest.Ifd) *Summary {
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	return NewSummary(index)
+}
+
+func TestSummary(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+	root.Tags[2] = exiftest.Tag{Id: OrientationTagId, Value: []uint16{OrientationRightTop}}
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(
+		exifIfd.Tags,
+		exiftest.Tag{Id: 0x9011, Value: "+09:00"},
+		exiftest.Tag{Id: 0x9204, Value: []exifcommon.SignedRational{{Numerator: -2, Denominator: 3}}},
+		exiftest.Tag{Id: 0x9291, Value: "25"})
+
+	s := getTestSummary(root)
+
+	latitude, longitude, err := s.GpsDegrees()
+	log.PanicIf(err)
+
+	if math.Abs(latitude-(26+35.0/60+12.0/3600)) > 1e-9 || math.Abs(longitude+(80+3.0/60+13.0/3600)) > 1e-9 {
+		t.Fatalf("GPS not correct: (%f) (%f)", latitude, longitude)
+	}
+
+	takenTime, err := s.BestTakenTime(time.UTC)
+	log.PanicIf(err)
+
+	expectedTime := time.Date(2020, 1, 2, 3, 4, 5, 250000000, time.FixedZone("", 9*60*60))
+	if takenTime.Equal(expectedTime) != true {
+		t.Fatalf("Time not correct: [%s]", takenTime)
+	}
+
+	degrees, mirrored, err := s.Rotation()
+	log.PanicIf(err)
+
+	if degrees != 90 || mirrored != false {
+		t.Fatalf("Rotation not correct: (%d) [%v]", degrees, mirrored)
+	}
+
+	exposureTime, err := s.ExposureTime()
+	log.PanicIf(err)
+
+	fNumber, err := s.FNumber()
+	log.PanicIf(err)
+
+	focalLength, err := s.FocalLength()
+	log.PanicIf(err)
+
+	exposureBias, err := s.ExposureBias()
+	log.PanicIf(err)
+
+	iso, err := s.Iso()
+	log.PanicIf(err)
+
+	if exposureTime != 0.004 || fNumber != 2.8 || focalLength != 50 || math.Abs(exposureBias+2.0/3) > 1e-9 || iso != 400 {
+		t.Fatalf("Exposure not correct: (%f) (%f) (%f) (%f) (%f)", exposureTime, fNumber, focalLength, exposureBias, iso)
+	}
+}
+
+func TestSummary_BestTakenTime_Fallback(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+
+	// Without DateTimeOriginal.
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags[:3], exifIfd.Tags[4:]...)
+
+	location, err := time.LoadLocation("America/New_York")
+	log.PanicIf(err)
+
+	takenTime, err := getTestSummary(root).BestTakenTime(location)
+	log.PanicIf(err)
+
+	if takenTime.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, location)) != true || takenTime.Location() != location {
+		t.Fatalf("Time not correct: [%s]", takenTime)
+	}
+}
+
+func TestSummary_Empty(t *testing.T) {
+	s := getTestSummary(&exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x010f, Value: "Acme"},
+		},
+	})
+
+	if _, _, err := s.GpsDegrees(); log.Is(err, ErrNoGpsTags) == false {
+		t.Fatalf("Expected no GPS: %v", err)
+	} else if _, err := s.BestTakenTime(nil); log.Is(err, ErrTagNotFound) == false {
+		t.Fatalf("Expected no time: %v", err)
+	} else if _, err := s.ExposureTime(); log.Is(err, ErrTagNotFound) == false {
+		t.Fatalf("Expected no exposure time: %v", err)
+	} else if _, err := s.Iso(); err != ErrNoIso {
+		t.Fatalf("Expected no ISO: %v", err)
+	}
+
+	orientation, err := s.Orientation()
+	log.PanicIf(err)
+
+	if orientation != OrientationTopLeft {
+		t.Fatalf("Orientation not correct: (%d)", orientation)
+	}
+}
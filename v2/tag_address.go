@@ -0,0 +1,235 @@
+package exif
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+)
+
+const (
+	// tagAddressPagePrefix and tagAddressItemPrefix start the selectors of a
+	// tag address.
+	tagAddressPagePrefix = "page:"
+	tagAddressItemPrefix = "item:"
+)
+
+// TagAddress is a tag path along with the page and the HEIF item that it's
+// in (see `ParseTagAddress()`).
+type TagAddress struct {
+	// Page is the root IFD (zero-based) that the tag is under. In a
+	// multi-page TIFF, each one is a page.
+	Page int
+
+	// ItemId is the HEIF image item whose EXIF has the tag, or zero for the
+	// primary item (and for other containers).
+	ItemId uint32
+
+	// FqIfdPath is the fully-qualified path of the IFD, with the page applied
+	// (e.g. "IFD2/Exif").
+	FqIfdPath string
+
+	Tag *IndexedTag
+}
+
+// String returns a descriptive string.
+func (ta TagAddress) String() string {
+	return fmt.Sprintf("TagAddress<PAGE=(%d) ITEM=(%d) FQ-IFD-PATH=[%s] TAG=[%s]>", ta.Page, ta.ItemId, ta.FqIfdPath, ta.Tag.Name)
+}
+
+// ParseTagAddress resolves a tag path that can start with selectors for the
+// page and the HEIF item, in either order, such as
+// "page:2/IFD/Exif/DateTimeOriginal" or "item:3/IFD/Make". The page is
+// zero-based and replaces the index of the root IFD, so
+// "page:2/IFD/Exif/DateTimeOriginal" is the same as
+// "IFD2/Exif/DateTimeOriginal". A page that disagrees with an index already in
+// the path, or a selector that's repeated or not a number, is
+// `ErrTagPathNotValid`.
+func ParseTagAddress(im *IfdMapping, ti *TagIndex, address string) (ta TagAddress, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	hasPage, hasItem := false, false
+	tagPath := address
+
+	for {
+		i := strings.Index(tagPath, "/")
+		if i == -1 {
+			break
+		}
+
+		selector := tagPath[:i]
+
+		isPage := strings.HasPrefix(selector, tagAddressPagePrefix) == true && hasPage == false
+		isItem := strings.HasPrefix(selector, tagAddressItemPrefix) == true && hasItem == false
+
+		if isPage == false && isItem == false {
+			break
+		}
+
+		number, err := strconv.ParseUint(selector[strings.Index(selector, ":")+1:], 10, 32)
+		if err != nil {
+			builderMapLogger.Warningf(nil, "Selector [%s] of tag address [%s] not valid.", selector, address)
+			log.Panic(ErrTagPathNotValid)
+		}
+
+		if isPage == true {
+			ta.Page = int(number)
+			hasPage = true
+		} else {
+			ta.ItemId = uint32(number)
+			hasItem = true
+		}
+
+		tagPath = tagPath[i+1:]
+	}
+
+	ta.FqIfdPath, ta.Tag, err = splitTagPath(im, ti, tagPath)
+	log.PanicIf(err)
+
+	lineage, err := im.ResolvePath(ta.FqIfdPath)
+	log.PanicIf(err)
+
+	if hasPage == true {
+		if lineage[0].Index != 0 && lineage[0].Index != ta.Page {
+			builderMapLogger.Warningf(nil, "Page of tag address [%s] disagrees with its path.", address)
+			log.Panic(ErrTagPathNotValid)
+		}
+
+		lineage[0].Index = ta.Page
+		ta.FqIfdPath = im.FqPathPhraseFromLineage(lineage)
+	} else {
+		ta.Page = lineage[0].Index
+	}
+
+	return ta, nil
+}
+
+// FindTagWithAddress returns the tags at the address (see
+// `ParseTagAddress()`), such as "page:1/IFD/Exif/DateTimeOriginal".
+// `ErrTagNotFound` is returned if the IFD or the tag isn't there, and
+// `ErrTagPathNotValid` if the address has an item, which has to be selected
+// from the file (see `ReadFileTag()`).
+func (index IfdIndex) FindTagWithAddress(address string) (results []*IfdTagEntry, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if index.RootIfd == nil {
+		log.Panic(ErrTagNotFound)
+	}
+
+	ta, err := ParseTagAddress(index.RootIfd.ifdMapping, index.RootIfd.tagIndex, address)
+	log.PanicIf(err)
+
+	if ta.ItemId != 0 {
+		log.Panic(ErrTagPathNotValid)
+	}
+
+	results, err = index.findTagWithTagAddress(ta)
+	log.PanicIf(err)
+
+	return results, nil
+}
+
+// findTagWithTagAddress returns the tags in the IFD of the address. The item
+// is ignored.
+func (index IfdIndex) findTagWithTagAddress(ta TagAddress) (results []*IfdTagEntry, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	for _, ifd := range index.Ifds {
+		if ifd.FqIfdPath != ta.FqIfdPath {
+			continue
+		}
+
+		results, err := ifd.FindTagWithId(ta.Tag.Id)
+		log.PanicIf(err)
+
+		return results, nil
+	}
+
+	log.Panic(ErrTagNotFound)
+	return nil, nil
+}
+
+// FindTagWithAddress returns the tag at the address (see
+// `ParseTagAddress()`), looking from this root builder. `ErrTagNotFound` is
+// returned if the IFD or the tag isn't there.
+func (ib *IfdBuilder) FindTagWithAddress(address string) (bt *BuilderTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ta, err := ParseTagAddress(ib.ifdMapping, ib.tagIndex, address)
+	log.PanicIf(err)
+
+	if ta.ItemId != 0 {
+		log.Panic(ErrTagPathNotValid)
+	}
+
+	thisIb, err := findIbWithFqIfdPath(ib, ta.FqIfdPath)
+	log.PanicIf(err)
+
+	bt, err = thisIb.FindTag(ta.Tag.Id)
+	log.PanicIf(err)
+
+	return bt, nil
+}
+
+// SetWithAddress sets the tag at the address (see `ParseTagAddress()`) from
+// this root builder, converting the value as for `BuilderFromMap()`. Missing
+// pages and IFDs are added.
+func (ib *IfdBuilder) SetWithAddress(address string, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	err = setMapTag(ib, address, value)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// findIbWithFqIfdPath returns the builder of the IFD, without adding it.
+func findIbWithFqIfdPath(rootIb *IfdBuilder, fqIfdPath string) (ib *IfdBuilder, err error) {
+	var find func(ib *IfdBuilder) *IfdBuilder
+	find = func(ib *IfdBuilder) *IfdBuilder {
+		for thisIb := ib; thisIb != nil; thisIb = thisIb.nextIb {
+			if thisIb.fqIfdPath == fqIfdPath {
+				return thisIb
+			}
+
+			for _, bt := range thisIb.tags {
+				if bt.value.IsIb() == false {
+					continue
+				}
+
+				if found := find(bt.value.Ib()); found != nil {
+					return found
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if ib = find(rootIb); ib == nil {
+		return nil, ErrTagNotFound
+	}
+
+	return ib, nil
+}
@@ -0,0 +1,179 @@
+package exif
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestParseTagAddress(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	cases := []struct {
+		address   string
+		page      int
+		itemId    uint32
+		fqIfdPath string
+		tagName   string
+	}{
+		{"IFD/Make", 0, 0, "IFD", "Make"},
+		{"IFD1/Compression", 1, 0, "IFD1", "Compression"},
+		{"page:2/IFD/Exif/DateTimeOriginal", 2, 0, "IFD2/Exif", "DateTimeOriginal"},
+		{"page:12/IFD/Exif/DateTimeOriginal", 12, 0, "IFD12/Exif", "DateTimeOriginal"},
+		{"page:1/IFD1/Make", 1, 0, "IFD1", "Make"},
+		{"item:3/IFD/Make", 0, 3, "IFD", "Make"},
+		{"item:3/page:1/IFD/Exif/0x9003", 1, 3, "IFD1/Exif", "DateTimeOriginal"},
+	}
+
+	for _, c := range cases {
+		ta, err := ParseTagAddress(im, ti, c.address)
+		log.PanicIf(err)
+
+		if ta.Page != c.page || ta.ItemId != c.itemId || ta.FqIfdPath != c.fqIfdPath || ta.Tag.Name != c.tagName {
+			t.Fatalf("Address [%s] not parsed correctly: %s", c.address, ta)
+		}
+	}
+}
+
+func TestParseTagAddress_NotValid(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	addresses := []string{
+		"page:x/IFD/Make",
+		"page:-1/IFD/Make",
+		"page:2/IFD1/Make",
+		"page:1/page:1/IFD/Make",
+		"item:1/item:2/IFD/Make",
+		"page:1/Make",
+	}
+
+	for _, address := range addresses {
+		if _, err := ParseTagAddress(im, ti, address); err == nil {
+			t.Fatalf("Expected error for address [%s].", address)
+		}
+	}
+}
+
+func TestIfdBuilder_SetWithAddress(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetWithAddress("IFD/Make", "first page")
+	log.PanicIf(err)
+
+	err = rootIb.SetWithAddress("page:2/IFD/Exif/DateTimeOriginal", "2020:06:01 12:00:00")
+	log.PanicIf(err)
+
+	if pages := rootIb.Pages(); len(pages) != 3 {
+		t.Fatalf("Expected three pages: (%d)", len(pages))
+	}
+
+	bt, err := rootIb.FindTagWithAddress("page:2/IFD/Exif/DateTimeOriginal")
+	log.PanicIf(err)
+
+	if bt.tagId != 0x9003 {
+		t.Fatalf("Wrong tag found: %s", bt)
+	}
+
+	if _, err := rootIb.FindTagWithAddress("page:1/IFD/Exif/DateTimeOriginal"); log.Is(err, ErrTagNotFound) != true {
+		t.Fatalf("Expected ErrTagNotFound for a page without the tag: %v", err)
+	}
+
+	rawExif, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, rawExif)
+	log.PanicIf(err)
+
+	results, err := index.FindTagWithAddress("page:2/IFD/Exif/DateTimeOriginal")
+	log.PanicIf(err)
+
+	value, err := results[0].Value()
+	log.PanicIf(err)
+
+	if value != "2020:06:01 12:00:00" {
+		t.Fatalf("Value not correct: [%v]", value)
+	}
+
+	if _, err := index.FindTagWithAddress("IFD/Exif/DateTimeOriginal"); log.Is(err, ErrTagNotFound) != true {
+		t.Fatalf("Expected ErrTagNotFound for the first page: %v", err)
+	} else if _, err := index.FindTagWithAddress("item:1/IFD/Make"); log.Is(err, ErrTagPathNotValid) != true {
+		t.Fatalf("Expected ErrTagPathNotValid for an item: %v", err)
+	}
+}
+
+func TestBuilderFromMap_Page(t *testing.T) {
+	fields := map[string]interface{}{
+		"IFD/Make":                "first",
+		"page:1/IFD/Make":         "second",
+		"page:1/IFD/Exif/FNumber": 2.8,
+	}
+
+	rootIb, err := BuilderFromMap(fields)
+	log.PanicIf(err)
+
+	bt, err := rootIb.FindTagWithAddress("page:1/IFD/Make")
+	log.PanicIf(err)
+
+	if value := bt.Value().Bytes(); string(value) != "second\x00" {
+		t.Fatalf("Make of the second page not correct: %v", value)
+	}
+
+	if _, err := rootIb.FindTagWithAddress("page:1/IFD/Exif/FNumber"); err != nil {
+		t.Fatalf("FNumber of the second page not found: %v", err)
+	}
+}
+
+func TestReadFileTag_HeifItem(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	data := getTestHeifBurst(getTestMultiFrameExif("first"), getTestMultiFrameExif("second"))
+	filepath := writeTestFile(tempPath, "burst.heic", data)
+
+	err = UpdateFileTag(filepath, "item:2/IFD/Make", "updated second")
+	log.PanicIf(err)
+
+	expected := map[string]string{
+		"IFD/Make":        "first",
+		"item:1/IFD/Make": "first",
+		"item:2/IFD/Make": "updated second",
+	}
+
+	for address, cameraMake := range expected {
+		results, err := ReadFileTag(filepath, address)
+		log.PanicIf(err)
+
+		value, err := results[0].Value()
+		log.PanicIf(err)
+
+		if value != cameraMake {
+			t.Fatalf("Make at [%s] not correct: [%v]", address, value)
+		}
+	}
+}
+
+func TestReadFileTag_ItemNotHeif(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	filepath := writeTestFile(tempPath, "image.jpg", getTestJpegWithExif())
+
+	if _, err := ReadFileTag(filepath, "item:2/IFD/Make"); log.Is(err, ErrTagPathNotValid) != true {
+		t.Fatalf("Expected ErrTagPathNotValid: %v", err)
+	} else if err := UpdateFileTag(filepath, "item:2/IFD/Make", "x"); log.Is(err, ErrTagPathNotValid) != true {
+		t.Fatalf("Expected ErrTagPathNotValid: %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go/format"
+	"go/token"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// tagCodeExifImportPath and tagCodeCommonImportPath are the packages that
+	// generated code uses.
+	tagCodeExifImportPath   = "github.com/dsoprea/go-exif/v2"
+	tagCodeCommonImportPath = "github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// tagCodeIfdNames are the identifiers of the standard IFDs in generated
+	// code. Other IFDs are named after their paths.
+	tagCodeIfdNames = map[string]string{
+		exifcommon.IfdPathStandard:        "Ifd",
+		exifcommon.IfdPathStandardExif:    "Exif",
+		exifcommon.IfdPathStandardExifIop: "Iop",
+		exifcommon.IfdPathStandardGps:     "Gps",
+	}
+)
+
+// TagCodeOptions configures `GenerateTagCode()`.
+type TagCodeOptions struct {
+	// PackageName is the package of the generated code. It can't be "exif".
+	PackageName string
+
+	// IfdPaths, if not empty, are the only IFDs (e.g. "IFD/GPSInfo") to
+	// generate code for. By default, every IFD in the index is.
+	IfdPaths []string
+}
+
+// tagCodeIdentifier returns an exported identifier for the name, dropping
+// any characters that can't be in one.
+func tagCodeIdentifier(name string) string {
+	b := new(strings.Builder)
+
+	for _, c := range name {
+		if c == '_' || unicode.IsLetter(c) == true || unicode.IsDigit(c) == true {
+			b.WriteRune(c)
+		}
+	}
+
+	identifier := b.String()
+	if identifier == "" || unicode.IsDigit(rune(identifier[0])) == true {
+		return "Tag" + identifier
+	}
+
+	return strings.ToUpper(identifier[:1]) + identifier[1:]
+}
+
+// tagCodeIfdName returns the identifier of the IFD in generated code.
+func tagCodeIfdName(ifdPath string) string {
+	if name, found := tagCodeIfdNames[ifdPath]; found == true {
+		return name
+	}
+
+	parts := strings.Split(ifdPath, "/")
+	for i, part := range parts {
+		parts[i] = tagCodeIdentifier(part)
+	}
+
+	return strings.Join(parts, "")
+}
+
+// tagCodeValueType returns the Go type of the values of the tag-type, as
+// `IfdTagEntry.Value()` returns them.
+func tagCodeValueType(tagType exifcommon.TagTypePrimitive) string {
+	switch tagType {
+	case exifcommon.TypeByte:
+		return "[]byte"
+	case exifcommon.TypeAscii, exifcommon.TypeAsciiNoNul, exifcommon.TypeUtf8:
+		return "string"
+	case exifcommon.TypeShort:
+		return "[]uint16"
+	case exifcommon.TypeLong:
+		return "[]uint32"
+	case exifcommon.TypeRational:
+		return "[]exifcommon.Rational"
+	case exifcommon.TypeSignedLong:
+		return "[]int32"
+	case exifcommon.TypeSignedRational:
+		return "[]exifcommon.SignedRational"
+	}
+
+	// Undefined-type values are decoded to whatever the tag's codec returns.
+	return "interface{}"
+}
+
+// tagCodeIfd is the code generated for one IFD.
+type tagCodeIfd struct {
+	ifdPath string
+	name    string
+	tags    []*IndexedTag
+	fields  []string
+}
+
+// GenerateTagCode writes Go source to the writer that gives downstream code
+// compile-time-checked references to the tags in the index (the standard
+// tags if it's empty), rather than IDs and names in literals. For each IFD
+// (e.g. "Exif" for "IFD/Exif") it declares:
+//
+//	ExifTag           a uint16 type with `Name()`, `IfdPath()`, and `String()`
+//	ExifFNumber, ...  a constant of that type for each tag
+//	ExifTags          a struct with a field for each tag, of its value's type
+//	ReadExifTags      a function that fills that struct from an `*exif.Ifd`
+//
+// The IFD0 tags are prefixed with "Ifd" and the GPS tags with "Gps". Names
+// are sanitized to be exported identifiers. A clash between them is an
+// error.
+func GenerateTagCode(w io.Writer, ti *TagIndex, opts TagCodeOptions) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if token.IsIdentifier(opts.PackageName) == false || opts.PackageName == "exif" {
+		log.Panicf("package name not valid: [%s]", opts.PackageName)
+	}
+
+	if len(ti.tagsByIfd) == 0 {
+		err := LoadStandardTags(ti)
+		log.PanicIf(err)
+	}
+
+	ifdPaths := opts.IfdPaths
+	if len(ifdPaths) == 0 {
+		ifdPaths = make([]string, 0, len(ti.tagsByIfd))
+		for ifdPath := range ti.tagsByIfd {
+			ifdPaths = append(ifdPaths, ifdPath)
+		}
+
+		sort.Strings(ifdPaths)
+	}
+
+	// Every top-level identifier is checked for clashes.
+	identifiers := make(map[string]string)
+
+	declare := func(identifier, what string) {
+		if previous, found := identifiers[identifier]; found == true {
+			log.Panicf("identifier [%s] for %s clashes with %s", identifier, what, previous)
+		}
+
+		identifiers[identifier] = what
+	}
+
+	ifds := make([]tagCodeIfd, len(ifdPaths))
+	usesCommon := false
+
+	for i, ifdPath := range ifdPaths {
+		family := ti.tagsByIfd[ifdPath]
+		if len(family) == 0 {
+			log.Panicf("no tags for IFD [%s]", ifdPath)
+		}
+
+		tci := tagCodeIfd{
+			ifdPath: ifdPath,
+			name:    tagCodeIfdName(ifdPath),
+			tags:    make([]*IndexedTag, 0, len(family)),
+		}
+
+		for _, it := range family {
+			tci.tags = append(tci.tags, it)
+		}
+
+		sort.Slice(tci.tags, func(i, j int) bool {
+			return tci.tags[i].Id < tci.tags[j].Id
+		})
+
+		what := fmt.Sprintf("IFD [%s]", ifdPath)
+		declare(tci.name+"Tag", what)
+		declare(tci.name+"Tags", what)
+		declare("Read"+tci.name+"Tags", what)
+		declare(strings.ToLower(tci.name[:1])+tci.name[1:]+"TagNames", what)
+
+		fields := make(map[string]string)
+		tci.fields = make([]string, len(tci.tags))
+
+		for j, it := range tci.tags {
+			field := tagCodeIdentifier(it.Name)
+			tci.fields[j] = field
+
+			declare(tci.name+field, it.String())
+
+			if previous, found := fields[field]; found == true {
+				log.Panicf("field [%s] for %s clashes with %s", field, it, previous)
+			}
+
+			fields[field] = it.String()
+
+			if strings.Contains(tagCodeValueType(it.Type), "exifcommon.") == true {
+				usesCommon = true
+			}
+		}
+
+		ifds[i] = tci
+	}
+
+	b := new(bytes.Buffer)
+
+	fmt.Fprintf(b, "// Code generated by exif-tag-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "package %s\n\n", opts.PackageName)
+	fmt.Fprintf(b, "import (\n\"fmt\"\n\n%q\n", tagCodeExifImportPath)
+
+	if usesCommon == true {
+		fmt.Fprintf(b, "%q\n", tagCodeCommonImportPath)
+	}
+
+	fmt.Fprintf(b, ")\n\n")
+
+	for _, tci := range ifds {
+		writeTagCodeIfd(b, tci)
+	}
+
+	source, err := format.Source(b.Bytes())
+	log.PanicIf(err)
+
+	_, err = w.Write(source)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// writeTagCodeIfd writes the declarations for one IFD. It's formatted
+// afterward.
+func writeTagCodeIfd(b *bytes.Buffer, tci tagCodeIfd) {
+	tagType := tci.name + "Tag"
+	names := strings.ToLower(tci.name[:1]) + tci.name[1:] + "TagNames"
+
+	fmt.Fprintf(b, "// %s is the ID of a tag in the [%s] IFD.\n", tagType, tci.ifdPath)
+	fmt.Fprintf(b, "type %s uint16\n\n", tagType)
+
+	fmt.Fprintf(b, "const (\n")
+	for i, it := range tci.tags {
+		fmt.Fprintf(b, "%s%s %s = 0x%04x\n", tci.name, tci.fields[i], tagType, it.Id)
+	}
+	fmt.Fprintf(b, ")\n\n")
+
+	fmt.Fprintf(b, "var %s = map[%s]string{\n", names, tagType)
+	for i, it := range tci.tags {
+		fmt.Fprintf(b, "%s%s: %q,\n", tci.name, tci.fields[i], it.Name)
+	}
+	fmt.Fprintf(b, "}\n\n")
+
+	fmt.Fprintf(b, "// IfdPath returns the path of the IFD that the tag is in.\n")
+	fmt.Fprintf(b, "func (%s) IfdPath() string {\nreturn %q\n}\n\n", tagType, tci.ifdPath)
+
+	fmt.Fprintf(b, "// Name returns the name of the tag, or an empty string if it's not known.\n")
+	fmt.Fprintf(b, "func (t %s) Name() string {\nreturn %s[t]\n}\n\n", tagType, names)
+
+	fmt.Fprintf(b, "// String returns the name of the tag, or its ID if it's not known.\n")
+	fmt.Fprintf(b, "func (t %s) String() string {\n", tagType)
+	fmt.Fprintf(b, "if name, found := %s[t]; found {\nreturn name\n}\n\n", names)
+	fmt.Fprintf(b, "return fmt.Sprintf(\"%s(0x%%04x)\", uint16(t))\n}\n\n", tagType)
+
+	fmt.Fprintf(b, "// %sTags has the values of the tags in a [%s] IFD.\n", tci.name, tci.ifdPath)
+	fmt.Fprintf(b, "type %sTags struct {\n", tci.name)
+	for i, it := range tci.tags {
+		fmt.Fprintf(b, "%s %s\n", tci.fields[i], tagCodeValueType(it.Type))
+	}
+	fmt.Fprintf(b, "}\n\n")
+
+	fmt.Fprintf(b, "// Read%sTags reads the values of the tags in the IFD. Tags that aren't there,\n", tci.name)
+	fmt.Fprintf(b, "// can't be decoded, or have a different type than expected are left as zero\n// values.\n")
+	fmt.Fprintf(b, "func Read%sTags(ifd *exif.Ifd) (tags %sTags) {\n", tci.name, tci.name)
+	fmt.Fprintf(b, "for _, ite := range ifd.Entries {\n")
+	fmt.Fprintf(b, "value, err := ite.Value()\nif err != nil {\ncontinue\n}\n\n")
+	fmt.Fprintf(b, "switch %s(ite.TagId()) {\n", tagType)
+	for i, it := range tci.tags {
+		fmt.Fprintf(b, "case %s%s:\n", tci.name, tci.fields[i])
+
+		valueType := tagCodeValueType(it.Type)
+		if valueType == "interface{}" {
+			fmt.Fprintf(b, "tags.%s = value\n", tci.fields[i])
+		} else {
+			fmt.Fprintf(b, "tags.%s, _ = value.(%s)\n", tci.fields[i], valueType)
+		}
+	}
+	fmt.Fprintf(b, "}\n}\n\nreturn tags\n}\n\n")
+}
@@ -0,0 +1,154 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go/ast"
+	"go/parser"
+	"go/token"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// parseTestTagCode generates code for the index and returns the names of its
+// top-level declarations.
+func parseTestTagCode(ti *TagIndex, opts TagCodeOptions) (source string, declarations map[string]struct{}) {
+	b := new(bytes.Buffer)
+
+	err := GenerateTagCode(b, ti, opts)
+	log.PanicIf(err)
+
+	f, err := parser.ParseFile(token.NewFileSet(), "tags.go", b.Bytes(), 0)
+	log.PanicIf(err)
+
+	declarations = make(map[string]struct{})
+	for name := range f.Scope.Objects {
+		declarations[name] = struct{}{}
+	}
+
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok == true && fd.Recv != nil {
+			receiver := fd.Recv.List[0].Type.(*ast.Ident).Name
+			declarations[receiver+"."+fd.Name.Name] = struct{}{}
+		}
+	}
+
+	return b.String(), declarations
+}
+
+func TestGenerateTagCode(t *testing.T) {
+	source, declarations := parseTestTagCode(NewTagIndex(), TagCodeOptions{PackageName: "exiftags"})
+
+	expected := []string{
+		"IfdTag", "IfdTags", "ReadIfdTags", "IfdMake", "IfdModel",
+		"ExifTag", "ExifTags", "ReadExifTags", "ExifFNumber", "ExifISOSpeedRatings",
+		"GpsTag", "GpsTags", "ReadGpsTags", "GpsGPSLatitude",
+		"IopTag", "IopTags", "ReadIopTags", "IopInteroperabilityIndex",
+		"ExifTag.Name", "ExifTag.IfdPath", "ExifTag.String",
+	}
+
+	for _, name := range expected {
+		if _, found := declarations[name]; found == false {
+			t.Fatalf("Declaration [%s] not generated.", name)
+		}
+	}
+
+	// Whitespace is collapsed so that the alignment doesn't matter.
+	collapsed := strings.Join(strings.Fields(source), " ")
+
+	fragments := []string{
+		"ExifFNumber ExifTag = 0x829d",
+		"FNumber []exifcommon.Rational",
+		"Make string",
+		"case ExifFNumber: tags.FNumber, _ = value.([]exifcommon.Rational)",
+		"return \"IFD/GPSInfo\"",
+	}
+
+	for _, fragment := range fragments {
+		if strings.Contains(collapsed, fragment) == false {
+			t.Fatalf("Code not generated: [%s]", fragment)
+		}
+	}
+
+	// The output is deterministic.
+	again, _ := parseTestTagCode(NewTagIndex(), TagCodeOptions{PackageName: "exiftags"})
+	if again != source {
+		t.Fatalf("Generated code not deterministic.")
+	}
+}
+
+func TestGenerateTagCode_CustomTags(t *testing.T) {
+	ti := NewTagIndex()
+
+	tags := []*IndexedTag{
+		{IfdPath: "IFD/Exif/MakerNotes", Id: 0x0002, Name: "Shutter-Count", Type: exifcommon.TypeLong},
+		{IfdPath: "IFD/Exif/MakerNotes", Id: 0x0001, Name: "3dMode", Type: exifcommon.TypeUndefined},
+	}
+
+	for _, it := range tags {
+		err := ti.Add(it)
+		log.PanicIf(err)
+	}
+
+	source, declarations := parseTestTagCode(ti, TagCodeOptions{PackageName: "exiftags"})
+
+	names := make([]string, 0)
+	for name := range declarations {
+		if strings.HasPrefix(name, "IFDExifMakerNotes") == true {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	expected := []string{
+		"IFDExifMakerNotesShutterCount",
+		"IFDExifMakerNotesTag",
+		"IFDExifMakerNotesTag.IfdPath",
+		"IFDExifMakerNotesTag.Name",
+		"IFDExifMakerNotesTag.String",
+		"IFDExifMakerNotesTag3dMode",
+		"IFDExifMakerNotesTags",
+	}
+
+	if reflect.DeepEqual(names, expected) != true {
+		t.Fatalf("Declarations not correct: %v", names)
+	} else if strings.Contains(source, "\"github.com/dsoprea/go-exif/v2/common\"") == true {
+		t.Fatalf("Unused import generated.")
+	} else if strings.Contains(source, "tags.Tag3dMode = value\n") == false {
+		t.Fatalf("Undefined-type tag not generated.")
+	}
+}
+
+func TestGenerateTagCode_Errors(t *testing.T) {
+	ti := NewTagIndex()
+
+	tags := []*IndexedTag{
+		{IfdPath: exifcommon.IfdPathStandard, Id: 0x0001, Name: "Shutter-Count", Type: exifcommon.TypeLong},
+		{IfdPath: exifcommon.IfdPathStandard, Id: 0x0002, Name: "ShutterCount", Type: exifcommon.TypeLong},
+	}
+
+	for _, it := range tags {
+		err := ti.Add(it)
+		log.PanicIf(err)
+	}
+
+	b := new(bytes.Buffer)
+
+	if err := GenerateTagCode(b, ti, TagCodeOptions{PackageName: "exiftags"}); err == nil || strings.Contains(err.Error(), "clashes") == false {
+		t.Fatalf("Expected clash: %v", err)
+	} else if err := GenerateTagCode(b, NewTagIndex(), TagCodeOptions{PackageName: "exif"}); err == nil {
+		t.Fatalf("Expected error for package name.")
+	} else if err := GenerateTagCode(b, NewTagIndex(), TagCodeOptions{PackageName: "exiftags", IfdPaths: []string{"IFD/Unknown"}}); err == nil {
+		t.Fatalf("Expected error for unknown IFD.")
+	}
+}
@@ -0,0 +1,369 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+var (
+	// ErrTagValueNotValid means that a value doesn't satisfy the constraints
+	// of its tag. It's matched by every `ValidationError`.
+	ErrTagValueNotValid = errors.New("tag value not valid")
+)
+
+// TagConstraint describes the values that the specification allows for a
+// tag, beyond its type.
+type TagConstraint struct {
+	// Count is the number of items that the value must have, or zero for any.
+	// For ASCII values, this includes the terminating NUL.
+	Count uint32
+
+	// Min and Max are the range that every item of an integer value must be
+	// in. They only apply if Max is nonzero.
+	Min int64
+	Max int64
+
+	// Values are the only integers that the items may be, if given.
+	Values []int64
+
+	// Strings are the only strings that an ASCII value may be, if given.
+	Strings []string
+}
+
+// tagConstraints are the constraints of the standard tags, by IFD path and
+// tag ID.
+var tagConstraints = map[string]map[uint16]TagConstraint{
+	exifcommon.IfdPathStandard: {
+		// Compression
+		0x0103: {Count: 1},
+
+		// Orientation
+		0x0112: {Count: 1, Min: 1, Max: 8},
+
+		// XResolution, YResolution
+		0x011a: {Count: 1},
+		0x011b: {Count: 1},
+
+		// ResolutionUnit
+		0x0128: {Count: 1, Values: []int64{1, 2, 3}},
+
+		// DateTime
+		0x0132: {Count: 20},
+
+		// YCbCrPositioning
+		0x0213: {Count: 1, Values: []int64{1, 2}},
+	},
+	exifcommon.IfdPathStandardExif: {
+		// ExposureTime, FNumber
+		0x829a: {Count: 1},
+		0x829d: {Count: 1},
+
+		// ExposureProgram
+		0x8822: {Count: 1, Min: 0, Max: 9},
+
+		// DateTimeOriginal, DateTimeDigitized
+		0x9003: {Count: 20},
+		0x9004: {Count: 20},
+
+		// OffsetTime, OffsetTimeOriginal, OffsetTimeDigitized
+		0x9010: {Count: 7},
+		0x9011: {Count: 7},
+		0x9012: {Count: 7},
+
+		// MeteringMode
+		0x9207: {Count: 1, Values: []int64{0, 1, 2, 3, 4, 5, 6, 255}},
+
+		// Flash
+		0x9209: {Count: 1},
+
+		// FocalLength
+		0x920a: {Count: 1},
+
+		// SubSecTime, SubSecTimeOriginal, and SubSecTimeDigitized have no
+		// fixed count.
+
+		// ColorSpace
+		0xa001: {Count: 1, Values: []int64{1, 0xffff}},
+
+		// PixelXDimension, PixelYDimension
+		0xa002: {Count: 1},
+		0xa003: {Count: 1},
+
+		// FocalPlaneResolutionUnit
+		0xa210: {Count: 1, Values: []int64{1, 2, 3}},
+
+		// SensingMethod
+		0xa217: {Count: 1, Min: 1, Max: 8},
+
+		// CustomRendered
+		0xa401: {Count: 1, Min: 0, Max: 1},
+
+		// ExposureMode
+		0xa402: {Count: 1, Min: 0, Max: 2},
+
+		// WhiteBalance
+		0xa403: {Count: 1, Min: 0, Max: 1},
+
+		// FocalLengthIn35mmFilm
+		0xa405: {Count: 1},
+
+		// SceneCaptureType
+		0xa406: {Count: 1, Min: 0, Max: 3},
+
+		// GainControl
+		0xa407: {Count: 1, Min: 0, Max: 4},
+
+		// Contrast, Saturation, Sharpness
+		0xa408: {Count: 1, Min: 0, Max: 2},
+		0xa409: {Count: 1, Min: 0, Max: 2},
+		0xa40a: {Count: 1, Min: 0, Max: 2},
+
+		// SubjectDistanceRange
+		0xa40c: {Count: 1, Min: 0, Max: 3},
+	},
+	exifcommon.IfdPathStandardGps: {
+		// GPSVersionID
+		0x0000: {Count: 4},
+
+		// GPSLatitudeRef, GPSLatitude
+		0x0001: {Count: 2, Strings: []string{"N", "S"}},
+		0x0002: {Count: 3},
+
+		// GPSLongitudeRef, GPSLongitude
+		0x0003: {Count: 2, Strings: []string{"E", "W"}},
+		0x0004: {Count: 3},
+
+		// GPSAltitudeRef, GPSAltitude
+		0x0005: {Count: 1, Min: 0, Max: 1},
+		0x0006: {Count: 1},
+
+		// GPSTimeStamp
+		0x0007: {Count: 3},
+
+		// GPSStatus, GPSMeasureMode
+		0x0009: {Count: 2, Strings: []string{"A", "V"}},
+		0x000a: {Count: 2, Strings: []string{"2", "3"}},
+
+		// GPSSpeedRef
+		0x000c: {Count: 2, Strings: []string{"K", "M", "N"}},
+
+		// GPSImgDirectionRef
+		0x0010: {Count: 2, Strings: []string{"T", "M"}},
+
+		// GPSDateStamp
+		0x001d: {Count: 11},
+	},
+}
+
+// AddTagConstraint sets the constraint of a tag, such as a private tag or a
+// standard tag that has none, for `ValidateTagValue()`.
+func AddTagConstraint(ifdPath string, tagId uint16, tc TagConstraint) {
+	constraints, found := tagConstraints[ifdPath]
+	if found == false {
+		constraints = make(map[uint16]TagConstraint)
+		tagConstraints[ifdPath] = constraints
+	}
+
+	constraints[tagId] = tc
+}
+
+// GetTagConstraint returns the constraint of a tag.
+func GetTagConstraint(ifdPath string, tagId uint16) (tc TagConstraint, found bool) {
+	tc, found = tagConstraints[ifdPath][tagId]
+	return tc, found
+}
+
+// ValidationError describes why a value isn't valid for a tag.
+type ValidationError struct {
+	IfdPath string
+	TagId   uint16
+	TagName string
+
+	// Reason says which constraint wasn't satisfied.
+	Reason string
+}
+
+// Error returns the tag and the reason.
+func (ve *ValidationError) Error() string {
+	return fmt.Sprintf("value not valid for tag [%s] (0x%04x) in IFD [%s]: %s", ve.TagName, ve.TagId, ve.IfdPath, ve.Reason)
+}
+
+// Is returns true for `ErrTagValueNotValid`.
+func (ve *ValidationError) Is(target error) bool {
+	return target == ErrTagValueNotValid
+}
+
+// valueTypes are the types of the values that can be set for each tag type.
+var valueTypes = map[exifcommon.TagTypePrimitive]reflect.Type{
+	exifcommon.TypeByte:           reflect.TypeOf([]byte{}),
+	exifcommon.TypeAscii:          reflect.TypeOf(""),
+	exifcommon.TypeAsciiNoNul:     reflect.TypeOf(""),
+	exifcommon.TypeShort:          reflect.TypeOf([]uint16{}),
+	exifcommon.TypeLong:           reflect.TypeOf([]uint32{}),
+	exifcommon.TypeRational:       reflect.TypeOf([]exifcommon.Rational{}),
+	exifcommon.TypeSignedLong:     reflect.TypeOf([]int32{}),
+	exifcommon.TypeSignedRational: reflect.TypeOf([]exifcommon.SignedRational{}),
+}
+
+// ValidateTagValue checks that the value can be set for the tag: that it's
+// the right type for the tag and satisfies the tag's constraint (its count,
+// range, and allowed values), if it has one. A `*ValidationError` is returned
+// if not.
+func ValidateTagValue(it *IndexedTag, value interface{}) error {
+	reason := validateTagValue(it, value)
+	if reason == "" {
+		return nil
+	}
+
+	ve := &ValidationError{
+		IfdPath: it.IfdPath,
+		TagId:   it.Id,
+		TagName: it.Name,
+		Reason:  reason,
+	}
+
+	return ve
+}
+
+// validateTagValue returns the reason that the value isn't valid, or an empty
+// string if it is.
+func validateTagValue(it *IndexedTag, value interface{}) string {
+	if value == nil {
+		return "value is nil"
+	}
+
+	if it.Type == exifcommon.TypeUndefined {
+		if _, ok := value.(exifundefined.EncodeableValue); ok == false {
+			return fmt.Sprintf("value type [%s] is not an encodeable undefined value", reflect.TypeOf(value))
+		}
+
+		return ""
+	}
+
+	expectedType, found := valueTypes[it.Type]
+	if found == false {
+		return fmt.Sprintf("tag type [%s] can not be set", it.Type)
+	} else if reflect.TypeOf(value) != expectedType {
+		return fmt.Sprintf("value type [%s] does not match tag type [%s] (expected [%s])", reflect.TypeOf(value), it.Type, expectedType)
+	}
+
+	tc, found := GetTagConstraint(it.IfdPath, it.Id)
+	if found == false {
+		return ""
+	}
+
+	if s, ok := value.(string); ok == true {
+		if tc.Count != 0 && uint32(len(s))+1 != tc.Count {
+			return fmt.Sprintf("string has (%d) characters but must have (%d)", len(s), tc.Count-1)
+		}
+
+		if tc.Strings != nil {
+			for _, allowed := range tc.Strings {
+				if s == allowed {
+					return ""
+				}
+			}
+
+			return fmt.Sprintf("string [%s] must be one of [%s]", s, strings.Join(tc.Strings, ", "))
+		}
+
+		return ""
+	}
+
+	rv := reflect.ValueOf(value)
+	count := rv.Len()
+
+	if tc.Count != 0 && uint32(count) != tc.Count {
+		return fmt.Sprintf("value has (%d) items but must have (%d)", count, tc.Count)
+	}
+
+	for i := 0; i < count; i++ {
+		item := rv.Index(i)
+
+		var n int64
+		switch item.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			n = int64(item.Uint())
+		case reflect.Int32:
+			n = item.Int()
+		default:
+			// Rationals aren't constrained.
+			continue
+		}
+
+		if tc.Max != 0 && (n < tc.Min || n > tc.Max) {
+			return fmt.Sprintf("item (%d) is (%d) but must be from (%d) to (%d)", i, n, tc.Min, tc.Max)
+		}
+
+		if tc.Values != nil {
+			allowed := false
+			for _, v := range tc.Values {
+				if n == v {
+					allowed = true
+					break
+				}
+			}
+
+			if allowed == false {
+				return fmt.Sprintf("item (%d) is (%d) but must be one of %v", i, n, tc.Values)
+			}
+		}
+	}
+
+	return ""
+}
+
+// SetStandardValidated is `SetStandard()` except that the value is checked
+// with `ValidateTagValue()` first. If it's not valid, the builder isn't
+// changed and the `*ValidationError` is returned.
+func (ib *IfdBuilder) SetStandardValidated(tagId uint16, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	it, err := ib.tagIndex.Get(ib.ifdPath, tagId)
+	log.PanicIf(err)
+
+	err = ValidateTagValue(it, value)
+	if err != nil {
+		return err
+	}
+
+	err = ib.SetStandard(tagId, value)
+	log.PanicIf(err)
+
+	return nil
+}
+
+// SetValidated is `SetStandardWithName()` except that the value is checked
+// with `ValidateTagValue()` first. If it's not valid, the builder isn't
+// changed and the `*ValidationError` is returned.
+func (ib *IfdBuilder) SetValidated(tagName string, value interface{}) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	it, err := ib.tagIndex.GetWithName(ib.ifdPath, tagName)
+	log.PanicIf(err)
+
+	err = ValidateTagValue(it, value)
+	if err != nil {
+		return err
+	}
+
+	err = ib.SetStandardWithName(tagName, value)
+	log.PanicIf(err)
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package exif
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestValidateTagValue(t *testing.T) {
+	ti := NewTagIndex()
+
+	cases := []struct {
+		ifdPath string
+		tagName string
+		value   interface{}
+		reason  string
+	}{
+		{exifcommon.IfdPathStandard, "Orientation", []uint16{6}, ""},
+		{exifcommon.IfdPathStandard, "Orientation", []uint16{9}, "item (0) is (9) but must be from (1) to (8)"},
+		{exifcommon.IfdPathStandard, "Orientation", []uint16{1, 1}, "value has (2) items but must have (1)"},
+		{exifcommon.IfdPathStandard, "Orientation", []uint32{1}, "value type [[]uint32] does not match tag type [SHORT] (expected [[]uint16])"},
+		{exifcommon.IfdPathStandard, "ResolutionUnit", []uint16{4}, "item (0) is (4) but must be one of [1 2 3]"},
+		{exifcommon.IfdPathStandard, "DateTime", "2020:01:02 03:04:05", ""},
+		{exifcommon.IfdPathStandard, "DateTime", "2020:01:02", "string has (10) characters but must have (19)"},
+		{exifcommon.IfdPathStandard, "Make", "Canon", ""},
+		{exifcommon.IfdPathStandard, "Make", nil, "value is nil"},
+		{exifcommon.IfdPathStandardExif, "ColorSpace", []uint16{0xffff}, ""},
+		{exifcommon.IfdPathStandardGps, "GPSLatitudeRef", "N", ""},
+		{exifcommon.IfdPathStandardGps, "GPSLatitudeRef", "E", "string [E] must be one of [N, S]"},
+		{exifcommon.IfdPathStandardGps, "GPSVersionID", []byte{2, 3, 0, 0}, ""},
+		{exifcommon.IfdPathStandardExif, "ExifVersion", "0232", "value type [string] is not an encodeable undefined value"},
+	}
+
+	for _, c := range cases {
+		it, err := ti.GetWithName(c.ifdPath, c.tagName)
+		log.PanicIf(err)
+
+		err = ValidateTagValue(it, c.value)
+		if c.reason == "" {
+			if err != nil {
+				t.Fatalf("Value for [%s] should be valid: %v", c.tagName, err)
+			}
+
+			continue
+		}
+
+		ve, ok := err.(*ValidationError)
+		if ok == false {
+			t.Fatalf("Expected ValidationError for [%s]: %v", c.tagName, err)
+		} else if ve.Reason != c.reason {
+			t.Fatalf("Reason for [%s] not correct: [%s]", c.tagName, ve.Reason)
+		} else if ve.TagName != c.tagName || ve.TagId != it.Id || ve.IfdPath != c.ifdPath {
+			t.Fatalf("Tag not correct: %v", ve)
+		}
+	}
+}
+
+func TestAddTagConstraint(t *testing.T) {
+	ti := NewTagIndex()
+
+	it, err := ti.GetWithName(exifcommon.IfdPathStandard, "Rating")
+	log.PanicIf(err)
+
+	original, hadOriginal := GetTagConstraint(it.IfdPath, it.Id)
+
+	defer func() {
+		if hadOriginal == true {
+			AddTagConstraint(it.IfdPath, it.Id, original)
+		} else {
+			delete(tagConstraints[it.IfdPath], it.Id)
+		}
+	}()
+
+	AddTagConstraint(it.IfdPath, it.Id, TagConstraint{Count: 1, Min: 0, Max: 5})
+
+	if err := ValidateTagValue(it, []uint16{6}); errors.Is(err, ErrTagValueNotValid) == false {
+		t.Fatalf("Expected ErrTagValueNotValid: %v", err)
+	}
+}
+
+func TestIfdBuilder_SetValidated(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	ib := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := ib.SetValidated("Orientation", []uint16{6})
+	log.PanicIf(err)
+
+	err = ib.SetValidated("Orientation", []uint16{9})
+	if errors.Is(err, ErrTagValueNotValid) == false {
+		t.Fatalf("Expected ErrTagValueNotValid: %v", err)
+	} else if strings.HasPrefix(err.Error(), "value not valid for tag [Orientation] (0x0112) in IFD [IFD]: ") == false {
+		t.Fatalf("Error not correct: [%s]", err)
+	}
+
+	bt, err := ib.FindTagWithName("Orientation")
+	log.PanicIf(err)
+
+	if string(bt.Value().Bytes()) != string([]byte{0, 6}) {
+		t.Fatalf("Invalid value was set: %v", bt.Value().Bytes())
+	}
+
+	err = ib.SetStandardValidated(0x0128, []uint16{7})
+	if _, ok := err.(*ValidationError); ok == false {
+		t.Fatalf("Expected ValidationError: %v", err)
+	} else if len(ib.Tags()) != 1 {
+		t.Fatalf("Invalid value was added: %v", ib.Tags())
+	}
+
+	err = ib.SetValidated("NoSuchTag", "value")
+	if err == nil {
+		t.Fatalf("Expected error for unknown tag.")
+	}
+}
@@ -0,0 +1,142 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// TagProvenance is exactly where a value was read from, so that a claim made
+// from it (e.g. when or where a photo was taken) can be traced back to the
+// bytes in the file. The offsets are from the start of the EXIF data (the
+// TIFF header); see `Absolute()`.
+type TagProvenance struct {
+	FqIfdPath string
+	TagId     uint16
+	TagName   string
+
+	// EntryOffset is the position of the 12-byte IFD entry.
+	EntryOffset uint32
+
+	// ValueOffset and ValueSize are where the raw (undecoded) value is. It's
+	// in the entry itself (and ValueOffset is EntryOffset+8) if it's four
+	// bytes or less.
+	ValueOffset uint32
+	ValueSize   uint32
+
+	// Sha256 is the hex-encoded SHA-256 of the raw value.
+	Sha256 string
+}
+
+// String returns a descriptive string.
+func (tp TagProvenance) String() string {
+	return fmt.Sprintf("TagProvenance<IFD=[%s] TAG-ID=(0x%04x) TAG-NAME=[%s] ENTRY-OFFSET=(0x%08x) VALUE-OFFSET=(0x%08x) VALUE-SIZE=(%d) SHA256=[%s]>", tp.FqIfdPath, tp.TagId, tp.TagName, tp.EntryOffset, tp.ValueOffset, tp.ValueSize, tp.Sha256)
+}
+
+// IsInline returns true if the value is stored in the entry.
+func (tp TagProvenance) IsInline() bool {
+	return tp.ValueOffset == tp.EntryOffset+8
+}
+
+// Absolute returns the provenance with the offsets moved to be from the
+// start of the file, given the position of the EXIF data in it (e.g.
+// `SegmentInfo.DataOffset` for a JPEG).
+func (tp TagProvenance) Absolute(exifOffset uint32) TagProvenance {
+	tp.EntryOffset += exifOffset
+	tp.ValueOffset += exifOffset
+
+	return tp
+}
+
+// TagProvenance returns where the value of the given entry of this IFD was
+// read from.
+func (ifd *Ifd) TagProvenance(ite *IfdTagEntry) (tp TagProvenance, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tp = TagProvenance{
+		FqIfdPath:   ifd.FqIfdPath,
+		TagId:       ite.TagId(),
+		EntryOffset: ite.EntryOffset(),
+		ValueOffset: ite.ValueLocation(),
+	}
+
+	if it, err := ifd.tagIndex.Get(ifd.IfdPath, tp.TagId); err == nil {
+		tp.TagName = it.Name
+	}
+
+	raw, err := ite.rawView()
+	log.PanicIf(err)
+
+	tp.ValueSize = uint32(len(raw))
+
+	digest := sha256.Sum256(raw)
+	tp.Sha256 = hex.EncodeToString(digest[:])
+
+	return tp, nil
+}
+
+// Provenance returns the provenance of every value in the index, IFD by IFD
+// in the order that they were parsed.
+func (index IfdIndex) Provenance() (provenance []TagProvenance, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	provenance = make([]TagProvenance, 0)
+
+	for _, ifd := range index.Ifds {
+		for _, ite := range ifd.Entries {
+			tp, err := ifd.TagProvenance(ite)
+			log.PanicIf(err)
+
+			provenance = append(provenance, tp)
+		}
+	}
+
+	return provenance, nil
+}
+
+// FindProvenance returns the provenance of the values of the tag with the
+// given name. See `HasTag()` for the paths that are accepted.
+// `ErrTagNotFound` is returned if there aren't any.
+func (index IfdIndex) FindProvenance(ifdPath, tagName string) (provenance []TagProvenance, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	provenance = make([]TagProvenance, 0)
+
+	for _, ifd := range index.ifdsWithPath(ifdPath) {
+		results, err := ifd.FindTagWithName(tagName)
+		if err != nil {
+			continue
+		}
+
+		for _, ite := range results {
+			tp, err := ifd.TagProvenance(ite)
+			log.PanicIf(err)
+
+			provenance = append(provenance, tp)
+		}
+	}
+
+	if len(provenance) == 0 {
+		return nil, ErrTagNotFound
+	}
+
+	return provenance, nil
+}
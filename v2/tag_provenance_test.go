@@ -0,0 +1,90 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestIfdIndex_FindProvenance(t *testing.T) {
+	exifData, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	provenance, err := index.FindProvenance(exifcommon.IfdPathStandardExif, "DateTimeOriginal")
+	log.PanicIf(err)
+
+	tp := provenance[0]
+
+	raw := []byte("2020:01:02 03:04:05\x00")
+	digest := sha256.Sum256(raw)
+
+	if len(provenance) != 1 {
+		t.Fatalf("Expected one value: %v", provenance)
+	} else if tp.FqIfdPath != exifcommon.IfdPathStandardExif || tp.TagId != 0x9003 || tp.TagName != "DateTimeOriginal" {
+		t.Fatalf("Tag not correct: %s", tp)
+	} else if tp.IsInline() != false || bytes.Equal(exifData[tp.ValueOffset:tp.ValueOffset+tp.ValueSize], raw) != true {
+		t.Fatalf("Value offset not correct: %s", tp)
+	} else if binary.BigEndian.Uint16(exifData[tp.EntryOffset:]) != 0x9003 {
+		t.Fatalf("Entry offset not correct: %s", tp)
+	} else if tp.Sha256 != hex.EncodeToString(digest[:]) {
+		t.Fatalf("Hash not correct: %s", tp)
+	}
+
+	_, err = index.FindProvenance(exifcommon.IfdPathStandardExif, "Artist")
+	if err != ErrTagNotFound {
+		t.Fatalf("Expected ErrTagNotFound: %v", err)
+	}
+}
+
+func TestIfdIndex_Provenance(t *testing.T) {
+	exifData, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.LittleEndian)
+	log.PanicIf(err)
+
+	jpeg := exiftest.WrapJpeg(exifData)
+	exifOffset := uint32(bytes.Index(jpeg, exifData))
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), exifData)
+	log.PanicIf(err)
+
+	provenance, err := index.Provenance()
+	log.PanicIf(err)
+
+	count := 0
+	for _, ifd := range index.Ifds {
+		count += len(ifd.Entries)
+	}
+
+	if len(provenance) != count {
+		t.Fatalf("Expected every value: (%d) != (%d)", len(provenance), count)
+	}
+
+	for _, tp := range provenance {
+		absolute := tp.Absolute(exifOffset)
+		entry := jpeg[absolute.EntryOffset:]
+		value := jpeg[absolute.ValueOffset : absolute.ValueOffset+absolute.ValueSize]
+
+		digest := sha256.Sum256(value)
+
+		if binary.LittleEndian.Uint16(entry) != tp.TagId {
+			t.Fatalf("Entry not at the absolute offset: %s", absolute)
+		} else if hex.EncodeToString(digest[:]) != tp.Sha256 {
+			t.Fatalf("Value not at the absolute offset: %s", absolute)
+		} else if tp.ValueSize <= 4 && tp.IsInline() != true {
+			t.Fatalf("Expected the value to be inline: %s", tp)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/dsoprea/go-logging"
-	"gopkg.in/yaml.v2"
 
 	"github.com/dsoprea/go-exif/v2/common"
 )
@@ -50,9 +49,9 @@ var (
 type encodedTag struct {
 	// id is signed, here, because YAML doesn't have enough information to
 	// support unsigned.
-	Id       int    `yaml:"id"`
-	Name     string `yaml:"name"`
-	TypeName string `yaml:"type_name"`
+	Id       int    `yaml:"id" json:"id"`
+	Name     string `yaml:"name" json:"name"`
+	TypeName string `yaml:"type_name" json:"type_name"`
 }
 
 // Indexing structures.
@@ -184,11 +183,10 @@ func LoadStandardTags(ti *TagIndex) (err error) {
 		}
 	}()
 
-	// Read static data.
+	// Read static data. Where this comes from depends on the build (see
+	// tags_definitions.go and tags_definitions_minimal.go).
 
-	encodedIfds := make(map[string][]encodedTag)
-
-	err = yaml.Unmarshal([]byte(tagsYaml), encodedIfds)
+	encodedIfds, err := loadStandardTagDefinitions()
 	log.PanicIf(err)
 
 	// Load structure.
@@ -1,3 +1,6 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
 package exif
 
 var (
@@ -59,6 +62,15 @@ IFD/Exif:
 - id: 0x9004
   name: DateTimeDigitized
   type_name: ASCII
+- id: 0x9010
+  name: OffsetTime
+  type_name: ASCII
+- id: 0x9011
+  name: OffsetTimeOriginal
+  type_name: ASCII
+- id: 0x9012
+  name: OffsetTimeDigitized
+  type_name: ASCII
 - id: 0x9101
   name: ComponentsConfiguration
   type_name: UNDEFINED
@@ -221,6 +233,18 @@ IFD/Exif:
 - id: 0xa435
   name: LensSerialNumber
   type_name: ASCII
+- id: 0xa460
+  name: CompositeImage
+  type_name: SHORT
+- id: 0xa461
+  name: SourceImageNumberOfCompositeImage
+  type_name: SHORT
+- id: 0xa462
+  name: SourceExposureTimesOfCompositeImage
+  type_name: UNDEFINED
+- id: 0xa500
+  name: Gamma
+  type_name: RATIONAL
 IFD/GPSInfo:
 - id: 0x0000
   name: GPSVersionID
@@ -0,0 +1,30 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"github.com/dsoprea/go-logging"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// tagTableName identifies the tag table (see `BuildCapabilities`).
+	tagTableName = "full"
+)
+
+// loadStandardTagDefinitions decodes the complete, embedded tag table.
+func loadStandardTagDefinitions() (encodedIfds map[string][]encodedTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	encodedIfds = make(map[string][]encodedTag)
+
+	err = yaml.Unmarshal([]byte(tagsYaml), encodedIfds)
+	log.PanicIf(err)
+
+	return encodedIfds, nil
+}
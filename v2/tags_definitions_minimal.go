@@ -0,0 +1,136 @@
+//go:build tinygo || exif_minimal
+// +build tinygo exif_minimal
+
+package exif
+
+// This is the reduced-footprint tag table used by TinyGo builds and by builds
+// with the "exif_minimal" tag. It's a static literal so that we don't need
+// the YAML decoder (and all of its reflection) or the full table in the
+// binary. It covers what on-device metadata stamping typically needs
+// (including the tags that EXIF 2.3 requires), the baseline TIFF tags for
+// thumbnails and strips, and the tags that the helpers in this package look
+// up by name (ratings, serial numbers, color balance, etc.), which
+// `TestMinimalTagDefinitions` checks. Anything else can still be registered
+// via `TagIndex.Add()`.
+
+var (
+	minimalTagDefinitions = map[string][]encodedTag{
+		"IFD": {
+			{Id: 0x000b, Name: "ProcessingSoftware", TypeName: "ASCII"},
+			{Id: 0x00fe, Name: "NewSubfileType", TypeName: "LONG"},
+			{Id: 0x00ff, Name: "SubfileType", TypeName: "SHORT"},
+			{Id: 0x0100, Name: "ImageWidth", TypeName: "LONG"},
+			{Id: 0x0101, Name: "ImageLength", TypeName: "LONG"},
+			{Id: 0x0102, Name: "BitsPerSample", TypeName: "SHORT"},
+			{Id: 0x0103, Name: "Compression", TypeName: "SHORT"},
+			{Id: 0x0106, Name: "PhotometricInterpretation", TypeName: "SHORT"},
+			{Id: 0x010d, Name: "DocumentName", TypeName: "ASCII"},
+			{Id: 0x010e, Name: "ImageDescription", TypeName: "ASCII"},
+			{Id: 0x010f, Name: "Make", TypeName: "ASCII"},
+			{Id: 0x0110, Name: "Model", TypeName: "ASCII"},
+			{Id: 0x0111, Name: "StripOffsets", TypeName: "LONG"},
+			{Id: 0x0112, Name: "Orientation", TypeName: "SHORT"},
+			{Id: 0x0115, Name: "SamplesPerPixel", TypeName: "SHORT"},
+			{Id: 0x0116, Name: "RowsPerStrip", TypeName: "LONG"},
+			{Id: 0x0117, Name: "StripByteCounts", TypeName: "LONG"},
+			{Id: 0x011a, Name: "XResolution", TypeName: "RATIONAL"},
+			{Id: 0x011b, Name: "YResolution", TypeName: "RATIONAL"},
+			{Id: 0x011c, Name: "PlanarConfiguration", TypeName: "SHORT"},
+			{Id: 0x0128, Name: "ResolutionUnit", TypeName: "SHORT"},
+			{Id: 0x0131, Name: "Software", TypeName: "ASCII"},
+			{Id: 0x0132, Name: "DateTime", TypeName: "ASCII"},
+			{Id: 0x013b, Name: "Artist", TypeName: "ASCII"},
+			{Id: 0x0201, Name: "JPEGInterchangeFormat", TypeName: "LONG"},
+			{Id: 0x0202, Name: "JPEGInterchangeFormatLength", TypeName: "LONG"},
+			{Id: 0x0213, Name: "YCbCrPositioning", TypeName: "SHORT"},
+			{Id: 0x4746, Name: "Rating", TypeName: "SHORT"},
+			{Id: 0x4749, Name: "RatingPercent", TypeName: "SHORT"},
+			{Id: 0x8298, Name: "Copyright", TypeName: "ASCII"},
+			{Id: 0x8769, Name: "ExifTag", TypeName: "LONG"},
+			{Id: 0x8825, Name: "GPSTag", TypeName: "LONG"},
+			{Id: 0xc628, Name: "AsShotNeutral", TypeName: "SHORT"},
+			{Id: 0xc62f, Name: "CameraSerialNumber", TypeName: "ASCII"},
+		},
+		"IFD/Exif": {
+			{Id: 0x829a, Name: "ExposureTime", TypeName: "RATIONAL"},
+			{Id: 0x829d, Name: "FNumber", TypeName: "RATIONAL"},
+			{Id: 0x8822, Name: "ExposureProgram", TypeName: "SHORT"},
+			{Id: 0x8827, Name: "ISOSpeedRatings", TypeName: "SHORT"},
+			{Id: 0x8830, Name: "SensitivityType", TypeName: "SHORT"},
+			{Id: 0x8831, Name: "StandardOutputSensitivity", TypeName: "LONG"},
+			{Id: 0x8832, Name: "RecommendedExposureIndex", TypeName: "LONG"},
+			{Id: 0x8833, Name: "ISOSpeed", TypeName: "LONG"},
+			{Id: 0x8834, Name: "ISOSpeedLatitudeyyy", TypeName: "LONG"},
+			{Id: 0x8835, Name: "ISOSpeedLatitudezzz", TypeName: "LONG"},
+			{Id: 0x9000, Name: "ExifVersion", TypeName: "UNDEFINED"},
+			{Id: 0x9003, Name: "DateTimeOriginal", TypeName: "ASCII"},
+			{Id: 0x9004, Name: "DateTimeDigitized", TypeName: "ASCII"},
+			{Id: 0x9010, Name: "OffsetTime", TypeName: "ASCII"},
+			{Id: 0x9011, Name: "OffsetTimeOriginal", TypeName: "ASCII"},
+			{Id: 0x9012, Name: "OffsetTimeDigitized", TypeName: "ASCII"},
+			{Id: 0x9101, Name: "ComponentsConfiguration", TypeName: "UNDEFINED"},
+			{Id: 0x9201, Name: "ShutterSpeedValue", TypeName: "SRATIONAL"},
+			{Id: 0x9202, Name: "ApertureValue", TypeName: "RATIONAL"},
+			{Id: 0x9204, Name: "ExposureBiasValue", TypeName: "SRATIONAL"},
+			{Id: 0x9206, Name: "SubjectDistance", TypeName: "RATIONAL"},
+			{Id: 0x9207, Name: "MeteringMode", TypeName: "SHORT"},
+			{Id: 0x9208, Name: "LightSource", TypeName: "SHORT"},
+			{Id: 0x9209, Name: "Flash", TypeName: "SHORT"},
+			{Id: 0x920a, Name: "FocalLength", TypeName: "RATIONAL"},
+			{Id: 0x927c, Name: "MakerNote", TypeName: "UNDEFINED"},
+			{Id: 0x9286, Name: "UserComment", TypeName: "UNDEFINED"},
+			{Id: 0x9290, Name: "SubSecTime", TypeName: "ASCII"},
+			{Id: 0x9291, Name: "SubSecTimeOriginal", TypeName: "ASCII"},
+			{Id: 0x9292, Name: "SubSecTimeDigitized", TypeName: "ASCII"},
+			{Id: 0xa000, Name: "FlashpixVersion", TypeName: "UNDEFINED"},
+			{Id: 0xa001, Name: "ColorSpace", TypeName: "SHORT"},
+			{Id: 0xa002, Name: "PixelXDimension", TypeName: "LONG"},
+			{Id: 0xa003, Name: "PixelYDimension", TypeName: "LONG"},
+			{Id: 0xa004, Name: "RelatedSoundFile", TypeName: "ASCII"},
+			{Id: 0xa005, Name: "InteroperabilityTag", TypeName: "LONG"},
+			{Id: 0xa20e, Name: "FocalPlaneXResolution", TypeName: "RATIONAL"},
+			{Id: 0xa20f, Name: "FocalPlaneYResolution", TypeName: "RATIONAL"},
+			{Id: 0xa210, Name: "FocalPlaneResolutionUnit", TypeName: "SHORT"},
+			{Id: 0xa401, Name: "CustomRendered", TypeName: "SHORT"},
+			{Id: 0xa402, Name: "ExposureMode", TypeName: "SHORT"},
+			{Id: 0xa403, Name: "WhiteBalance", TypeName: "SHORT"},
+			{Id: 0xa405, Name: "FocalLengthIn35mmFilm", TypeName: "SHORT"},
+			{Id: 0xa406, Name: "SceneCaptureType", TypeName: "SHORT"},
+			{Id: 0xa420, Name: "ImageUniqueID", TypeName: "ASCII"},
+			{Id: 0xa430, Name: "CameraOwnerName", TypeName: "ASCII"},
+			{Id: 0xa431, Name: "BodySerialNumber", TypeName: "ASCII"},
+			{Id: 0xa432, Name: "LensSpecification", TypeName: "RATIONAL"},
+			{Id: 0xa434, Name: "LensModel", TypeName: "ASCII"},
+			{Id: 0xa435, Name: "LensSerialNumber", TypeName: "ASCII"},
+			{Id: 0xa460, Name: "CompositeImage", TypeName: "SHORT"},
+			{Id: 0xa461, Name: "SourceImageNumberOfCompositeImage", TypeName: "SHORT"},
+			{Id: 0xa500, Name: "Gamma", TypeName: "RATIONAL"},
+		},
+		"IFD/GPSInfo": {
+			{Id: 0x0000, Name: "GPSVersionID", TypeName: "BYTE"},
+			{Id: 0x0001, Name: "GPSLatitudeRef", TypeName: "ASCII"},
+			{Id: 0x0002, Name: "GPSLatitude", TypeName: "RATIONAL"},
+			{Id: 0x0003, Name: "GPSLongitudeRef", TypeName: "ASCII"},
+			{Id: 0x0004, Name: "GPSLongitude", TypeName: "RATIONAL"},
+			{Id: 0x0005, Name: "GPSAltitudeRef", TypeName: "BYTE"},
+			{Id: 0x0006, Name: "GPSAltitude", TypeName: "RATIONAL"},
+			{Id: 0x0007, Name: "GPSTimeStamp", TypeName: "RATIONAL"},
+			{Id: 0x0012, Name: "GPSMapDatum", TypeName: "ASCII"},
+			{Id: 0x001d, Name: "GPSDateStamp", TypeName: "ASCII"},
+		},
+		"IFD/Exif/Iop": {
+			{Id: 0x0001, Name: "InteroperabilityIndex", TypeName: "ASCII"},
+			{Id: 0x0002, Name: "InteroperabilityVersion", TypeName: "UNDEFINED"},
+		},
+	}
+)
+
+const (
+	// tagTableName identifies the tag table (see `BuildCapabilities`).
+	tagTableName = "minimal"
+)
+
+// loadStandardTagDefinitions returns the reduced tag table.
+func loadStandardTagDefinitions() (encodedIfds map[string][]encodedTag, err error) {
+	return minimalTagDefinitions, nil
+}
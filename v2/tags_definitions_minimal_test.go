@@ -0,0 +1,107 @@
+//go:build tinygo || exif_minimal
+// +build tinygo exif_minimal
+
+package exif
+
+import (
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// These only run in reduced-footprint builds:
+//
+//   go test -tags exif_minimal ./...
+
+func init() {
+	// The original encoder tests use a few tags that nothing else
+	// needs. Add them so that those tests run against this table too.
+	minimalTagDefinitions[exifcommon.IfdPathStandard] = append(
+		minimalTagDefinitions[exifcommon.IfdPathStandard],
+		encodedTag{Id: 0x013e, Name: "WhitePoint", TypeName: "RATIONAL"},
+		encodedTag{Id: 0x0150, Name: "DotRange", TypeName: "BYTE"},
+		encodedTag{Id: 0x9201, Name: "ShutterSpeedValue", TypeName: "SRATIONAL"})
+}
+
+func TestMinimalTagDefinitions(t *testing.T) {
+	// The tags that the helpers look up by name, and the baseline TIFF tags.
+	required := map[string][]string{
+		exifcommon.IfdPathStandard: {
+			"Artist", "AsShotNeutral", "BitsPerSample", "CameraSerialNumber",
+			"Compression", "Copyright", "DateTime", "ImageLength", "ImageWidth",
+			"Make", "Model", "NewSubfileType", "Orientation",
+			"PhotometricInterpretation", "PlanarConfiguration",
+			"ProcessingSoftware", "Rating", "RatingPercent", "RowsPerStrip",
+			"SamplesPerPixel", "Software", "StripByteCounts", "StripOffsets",
+			"SubfileType",
+		},
+		exifcommon.IfdPathStandardExif: {
+			"ApertureValue", "BodySerialNumber", "ColorSpace",
+			"ComponentsConfiguration", "CompositeImage", "ExifVersion",
+			"FlashpixVersion",
+			"DateTimeDigitized", "DateTimeOriginal", "ExposureBiasValue",
+			"ExposureTime", "FNumber", "FocalLength",
+			"FocalLengthIn35mmFilm", "FocalPlaneResolutionUnit",
+			"FocalPlaneXResolution", "FocalPlaneYResolution",
+			"Gamma", "ImageUniqueID", "ISOSpeed", "ISOSpeedLatitudeyyy", "ISOSpeedLatitudezzz",
+			"ISOSpeedRatings", "LensModel", "LensSerialNumber",
+			"LightSource", "MakerNote", "OffsetTime", "OffsetTimeDigitized",
+			"OffsetTimeOriginal", "PixelXDimension", "PixelYDimension",
+			"RecommendedExposureIndex", "RelatedSoundFile", "SensitivityType",
+			"ShutterSpeedValue", "SourceImageNumberOfCompositeImage",
+			"StandardOutputSensitivity", "SubjectDistance", "UserComment",
+			"WhiteBalance",
+		},
+		exifcommon.IfdPathStandardExifIop: {
+			"InteroperabilityIndex",
+		},
+	}
+
+	ti := NewTagIndex()
+
+	for ifdPath, tagNames := range required {
+		for _, tagName := range tagNames {
+			if _, err := ti.GetWithName(ifdPath, tagName); err != nil {
+				t.Fatalf("Tag [%s] not in the minimal table for [%s]: %v", tagName, ifdPath, err)
+			}
+		}
+	}
+}
+
+func TestMinimalTagDefinitions_Helpers(t *testing.T) {
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+	err := rootIb.SetStandardWithName("Make", "Acme")
+	log.PanicIf(err)
+
+	err = SetRating(rootIb, 4)
+	log.PanicIf(err)
+
+	err = SetSerialNumbers(rootIb, "B123", "L456")
+	log.PanicIf(err)
+
+	exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+	log.PanicIf(err)
+
+	_, index, err := Collect(im, ti, exifData)
+	log.PanicIf(err)
+
+	r, err := GetRating(index, nil)
+	log.PanicIf(err)
+
+	if r.Stars != 4 {
+		t.Fatalf("Rating not correct: %s", r)
+	}
+
+	sn, err := GetSerialNumbers(index)
+	log.PanicIf(err)
+
+	if sn.Body != "B123" || sn.Lens != "L456" {
+		t.Fatalf("Serial numbers not correct: %s", sn)
+	}
+}
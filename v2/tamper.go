@@ -0,0 +1,371 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+// TamperSeverity is how strongly an indicator suggests tampering.
+type TamperSeverity int
+
+const (
+	// TamperSeverityNone is the severity of a report without indicators.
+	TamperSeverityNone TamperSeverity = iota
+
+	// TamperSeverityLow is something unusual that ordinary software also
+	// does.
+	TamperSeverityLow
+
+	// TamperSeverityMedium is something that a camera wouldn't do but that a
+	// careless editor might.
+	TamperSeverityMedium
+
+	// TamperSeverityHigh is something that's hard to explain other than by
+	// deliberate modification or hidden data.
+	TamperSeverityHigh
+)
+
+// String returns the name of the severity.
+func (ts TamperSeverity) String() string {
+	switch ts {
+	case TamperSeverityNone:
+		return "none"
+	case TamperSeverityLow:
+		return "low"
+	case TamperSeverityMedium:
+		return "medium"
+	case TamperSeverityHigh:
+		return "high"
+	}
+
+	return fmt.Sprintf("TamperSeverity(%d)", int(ts))
+}
+
+const (
+	// tamperMaxUtcOffset and tamperMinUtcOffset are the range of time zones.
+	// The capture time is local and the GPS time is UTC, so they can't be
+	// further apart than this (plus the age of the fix) without a time zone
+	// or clock having been changed.
+	tamperMaxUtcOffset = 14 * time.Hour
+	tamperMinUtcOffset = -12 * time.Hour
+
+	// tamperGpsFixAge is how stale a GPS fix can reasonably be.
+	tamperGpsFixAge = time.Hour
+
+	// tamperLargeSlackSize is the size at which unreferenced data is more
+	// than vendors normally leave behind.
+	tamperLargeSlackSize = 1024
+)
+
+var (
+	// tamperMakerNoteVendors identify the vendor that wrote a maker-note by
+	// its signature and the words that the Make (or Software) of that
+	// vendor's devices contain.
+	tamperMakerNoteVendors = []struct {
+		vendor     string
+		signatures [][]byte
+		makes      []string
+	}{
+		{makerNoteVendorApple, [][]byte{appleMakerNoteSignature}, []string{"apple"}},
+		{makerNoteVendorNikon, [][]byte{nikonMakerNoteSignature}, []string{"nikon"}},
+		{makerNoteVendorSony, sonyMakerNoteSignatures, []string{"sony"}},
+		{makerNoteVendorPentax, [][]byte{pentaxMakerNoteSignature}, []string{"pentax", "ricoh"}},
+		{"Olympus", [][]byte{[]byte("OLYMPUS\x00"), []byte("OM SYSTEM\x00")}, []string{"olympus", "om digital"}},
+		{"Fujifilm", [][]byte{[]byte("FUJIFILM")}, []string{"fujifilm"}},
+		{"Panasonic", [][]byte{[]byte("Panasonic\x00")}, []string{"panasonic"}},
+	}
+
+	// tamperPayloadSignatures are the signatures of files that might be
+	// hidden in slack space.
+	tamperPayloadSignatures = []struct {
+		name      string
+		signature []byte
+	}{
+		{"JPEG", []byte{0xff, 0xd8, 0xff}},
+		{"PNG", pngSignature},
+		{"GIF", []byte("GIF8")},
+		{"ZIP", []byte("PK\x03\x04")},
+		{"PDF", []byte("%PDF")},
+		{"ELF", []byte("\x7fELF")},
+		{"gzip", []byte{0x1f, 0x8b, 0x08}},
+	}
+)
+
+// TamperIndicator is one sign of tampering.
+type TamperIndicator struct {
+	Severity TamperSeverity
+
+	// Source is what the indicator was found in (e.g. "IFD/DateTime" or
+	// "slack").
+	Source string
+
+	// Rationale explains why it's suspicious.
+	Rationale string
+}
+
+// String returns a descriptive string.
+func (ti TamperIndicator) String() string {
+	return fmt.Sprintf("TamperIndicator<SEVERITY=[%s] SOURCE=[%s] RATIONALE=[%s]>", ti.Severity, ti.Source, ti.Rationale)
+}
+
+// TamperReport is the result of `AnalyzeTampering()`.
+type TamperReport struct {
+	Indicators []TamperIndicator
+}
+
+// Severity returns the highest severity of the indicators.
+func (tr TamperReport) Severity() TamperSeverity {
+	severity := TamperSeverityNone
+
+	for _, ti := range tr.Indicators {
+		if ti.Severity > severity {
+			severity = ti.Severity
+		}
+	}
+
+	return severity
+}
+
+func (tr *TamperReport) add(severity TamperSeverity, source, format string, args ...interface{}) {
+	ti := TamperIndicator{
+		Severity:  severity,
+		Source:    source,
+		Rationale: fmt.Sprintf(format, args...),
+	}
+
+	tr.Indicators = append(tr.Indicators, ti)
+}
+
+// AnalyzeTampering looks for signs that the EXIF has been tampered with or is
+// hiding something: tag combinations that a camera wouldn't write, a capture
+// time that's too far from the GPS time, a maker-note from a different
+// vendor than the Make or Software, and data that nothing refers to. Like
+// `ClassifyProvenance()`, these are heuristics to direct a closer look, not
+// proof.
+func AnalyzeTampering(rawExif []byte) (tr TamperReport, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	tr.Indicators = make([]TamperIndicator, 0)
+
+	err = tr.inspectCombinations(index)
+	log.PanicIf(err)
+
+	err = tr.inspectMakerNote(index)
+	log.PanicIf(err)
+
+	el, err := GetExifLayout(rawExif, index)
+	log.PanicIf(err)
+
+	tr.inspectSlack(el.Slack(rawExif))
+
+	return tr, nil
+}
+
+// inspectCombinations checks the tags that have to agree with each other.
+func (tr *TamperReport) inspectCombinations(index IfdIndex) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	rootIfd := index.RootIfd
+
+	orientation, err := getIfdTagNumber(rootIfd, "Orientation")
+	log.PanicIf(err)
+
+	if orientation != 0 && (orientation < 1 || orientation > 8) {
+		tr.add(TamperSeverityLow, "IFD/Orientation", "orientation (%v) isn't one of the eight defined values", orientation)
+	}
+
+	dateTime, err := getIfdTagString(rootIfd, "DateTime")
+	log.PanicIf(err)
+
+	var exifIfd *Ifd
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		exifIfd = ifds[0]
+	}
+
+	var captured time.Time
+	if exifIfd != nil {
+		dateTimeOriginal, err := getIfdTagString(exifIfd, "DateTimeOriginal")
+		log.PanicIf(err)
+
+		dateTimeDigitized, err := getIfdTagString(exifIfd, "DateTimeDigitized")
+		log.PanicIf(err)
+
+		// EXIF timestamps sort chronologically as strings.
+		if dateTime != "" && dateTimeOriginal != "" && dateTime < dateTimeOriginal {
+			tr.add(TamperSeverityMedium, "IFD/DateTime", "modified [%s] before it was captured [%s]", dateTime, dateTimeOriginal)
+		}
+
+		if dateTimeDigitized != "" && dateTimeOriginal != "" && dateTimeDigitized < dateTimeOriginal {
+			tr.add(TamperSeverityMedium, "Exif/DateTimeDigitized", "digitized [%s] before it was captured [%s]", dateTimeDigitized, dateTimeOriginal)
+		}
+
+		if dateTimeOriginal != "" {
+			captured, _ = ParseExifFullTimestamp(dateTimeOriginal)
+		}
+	}
+
+	gpsIfds := index.Lookup[exifcommon.IfdPathStandardGps]
+	if len(gpsIfds) == 0 {
+		return nil
+	}
+
+	gpsIfd := gpsIfds[0]
+
+	pairs := [][2]uint16{
+		{TagLatitudeId, TagLatitudeRefId},
+		{TagLongitudeId, TagLongitudeRefId},
+	}
+
+	for _, pair := range pairs {
+		_, hasValue := gpsIfd.EntriesByTagId[pair[0]]
+		_, hasRef := gpsIfd.EntriesByTagId[pair[1]]
+
+		if hasValue != hasRef {
+			tr.add(TamperSeverityMedium, "GPSInfo", "tag (0x%04x) and its reference (0x%04x) aren't both present", pair[0], pair[1])
+		}
+	}
+
+	gi, err := gpsIfd.GpsInfo()
+	if err != nil {
+		return nil
+	}
+
+	if latitude := gi.Latitude.Decimal(); latitude < -90 || latitude > 90 {
+		tr.add(TamperSeverityHigh, "GPSInfo/GPSLatitude", "latitude (%f) is out of range", latitude)
+	}
+
+	if longitude := gi.Longitude.Decimal(); longitude < -180 || longitude > 180 {
+		tr.add(TamperSeverityHigh, "GPSInfo/GPSLongitude", "longitude (%f) is out of range", longitude)
+	}
+
+	if gi.Timestamp.IsZero() == true || captured.IsZero() == true {
+		return nil
+	}
+
+	// The capture time is parsed as UTC, so this is its UTC offset plus how
+	// old the fix was.
+	difference := captured.Sub(gi.Timestamp)
+
+	if difference > tamperMaxUtcOffset+tamperGpsFixAge {
+		tr.add(TamperSeverityMedium, "Exif/DateTimeOriginal", "captured [%s] too long after the GPS fix [%s] for any time zone", captured.Format(time.RFC3339), gi.Timestamp.Format(time.RFC3339))
+	} else if difference < tamperMinUtcOffset {
+		tr.add(TamperSeverityMedium, "Exif/DateTimeOriginal", "captured [%s] before the GPS fix [%s] by more than any time zone", captured.Format(time.RFC3339), gi.Timestamp.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// inspectMakerNote checks that the maker-note was written by the vendor named
+// by the Make and Software.
+func (tr *TamperReport) inspectMakerNote(index IfdIndex) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	ite, err := getMakerNoteTag(index)
+	log.PanicIf(err)
+
+	if ite == nil {
+		return nil
+	}
+
+	value, err := ite.Value()
+	log.PanicIf(err)
+
+	makerNote, ok := value.(exifundefined.Tag927CMakerNote)
+	if ok == false {
+		return nil
+	}
+
+	vendorIndex := -1
+	for i, tmv := range tamperMakerNoteVendors {
+		for _, signature := range tmv.signatures {
+			if bytes.HasPrefix(makerNote.MakerNoteBytes, signature) == true {
+				vendorIndex = i
+			}
+		}
+	}
+
+	if vendorIndex == -1 {
+		return nil
+	}
+
+	vendor := tamperMakerNoteVendors[vendorIndex]
+
+	make_, err := getIfdTagString(index.RootIfd, "Make")
+	log.PanicIf(err)
+
+	software, err := getIfdTagString(index.RootIfd, "Software")
+	log.PanicIf(err)
+
+	lowered := strings.ToLower(make_)
+
+	if make_ == "" {
+		tr.add(TamperSeverityLow, "IFD/Make", "there's a %s maker-note but no Make", vendor.vendor)
+	} else if provenanceMatchesAny(lowered, vendor.makes) == false {
+		tr.add(TamperSeverityHigh, "IFD/Make", "Make [%s] doesn't match the %s maker-note", make_, vendor.vendor)
+	}
+
+	lowered = strings.ToLower(software)
+
+	for i, other := range tamperMakerNoteVendors {
+		if i != vendorIndex && provenanceMatchesAny(lowered, other.makes) == true {
+			tr.add(TamperSeverityMedium, "IFD/Software", "Software [%s] is from %s but the maker-note is from %s", software, other.vendor, vendor.vendor)
+		}
+	}
+
+	if strings.HasPrefix(software, "iOS ") == true && vendor.vendor != makerNoteVendorApple {
+		tr.add(TamperSeverityMedium, "IFD/Software", "Software [%s] is from an iPhone but the maker-note is from %s", software, vendor.vendor)
+	}
+
+	return nil
+}
+
+// inspectSlack checks the data that nothing refers to.
+func (tr *TamperReport) inspectSlack(slack []SlackRegion) {
+	for _, sr := range slack {
+		found := false
+
+		for _, tps := range tamperPayloadSignatures {
+			if i := bytes.Index(sr.Data, tps.signature); i != -1 {
+				tr.add(TamperSeverityHigh, "slack", "%s signature at offset (0x%08x) in (%d) unreferenced bytes", tps.name, sr.Offset+uint32(i), len(sr.Data))
+				found = true
+
+				break
+			}
+		}
+
+		if found == true {
+			continue
+		}
+
+		if len(sr.Data) >= tamperLargeSlackSize {
+			tr.add(TamperSeverityMedium, "slack", "(%d) unreferenced bytes at offset (0x%08x)", len(sr.Data), sr.Offset)
+		} else {
+			tr.add(TamperSeverityLow, "slack", "(%d) unreferenced bytes at offset (0x%08x), which some vendors use for calibration data", len(sr.Data), sr.Offset)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func TestAnalyzeTampering_Clean(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	tr, err := AnalyzeTampering(rawExif)
+	log.PanicIf(err)
+
+	if len(tr.Indicators) != 0 || tr.Severity() != TamperSeverityNone {
+		t.Fatalf("Expected no indicators: %v", tr.Indicators)
+	}
+}
+
+func TestAnalyzeTampering(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+
+	// Modified before it was captured, with a Nikon maker-note but a Canon
+	// Make and Sony Software.
+	root.Tags[3].Value = "2019:12:31 00:00:00"
+	root.Tags = append(root.Tags, exiftest.Tag{Id: 0x0131, Value: "Sony Imaging Edge"})
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, exiftest.Tag{Id: 0x927c, Raw: []byte("Nikon\x00\x02\x10\x00\x00"), Type: exifcommon.TypeUndefined})
+
+	// A GPS fix more than a day before the capture.
+	gpsIfd := root.Children[1].Ifd
+	gpsIfd.Tags = append(gpsIfd.Tags,
+		exiftest.Tag{Id: 0x0007, Value: []exifcommon.Rational{{Numerator: 1, Denominator: 1}, {Numerator: 0, Denominator: 1}, {Numerator: 0, Denominator: 1}}},
+		exiftest.Tag{Id: 0x001d, Value: "2019:12:31"})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	// A ZIP file that nothing refers to.
+	rawExif = append(rawExif, []byte("PK\x03\x04hidden")...)
+
+	tr, err := AnalyzeTampering(rawExif)
+	log.PanicIf(err)
+
+	expected := map[string]TamperSeverity{
+		"IFD/DateTime":          TamperSeverityMedium,
+		"Exif/DateTimeOriginal": TamperSeverityMedium,
+		"IFD/Make":              TamperSeverityHigh,
+		"IFD/Software":          TamperSeverityMedium,
+		"slack":                 TamperSeverityHigh,
+	}
+
+	actual := make(map[string]TamperSeverity)
+	for _, ti := range tr.Indicators {
+		actual[ti.Source] = ti.Severity
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("Indicators not correct: %v", tr.Indicators)
+	}
+
+	for source, severity := range expected {
+		if actual[source] != severity {
+			t.Fatalf("Indicator for [%s] not correct: %v", source, tr.Indicators)
+		}
+	}
+
+	if tr.Severity() != TamperSeverityHigh {
+		t.Fatalf("Severity not correct: [%s]", tr.Severity())
+	}
+}
+
+func TestAnalyzeTampering_GpsRef(t *testing.T) {
+	root := exiftest.NewRealisticIfd()
+
+	// Drop GPSLatitudeRef.
+	gpsIfd := root.Children[1].Ifd
+	gpsIfd.Tags = gpsIfd.Tags[1:]
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	tr, err := AnalyzeTampering(rawExif)
+	log.PanicIf(err)
+
+	if len(tr.Indicators) != 1 || tr.Indicators[0].Source != "GPSInfo" {
+		t.Fatalf("Indicators not correct: %v", tr.Indicators)
+	}
+}
@@ -0,0 +1,384 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	templatePlaceholderRe = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+)
+
+// TemplateTag is one tag to stamp. `Value` may have placeholders like
+// "{Model}" or "{FileBaseName}" (see `TagTemplate`). Numeric values with
+// more than one component are separated by commas and rationals are written
+// as "numerator/denominator".
+type TemplateTag struct {
+	// FqIfdPath is the IFD to write to (e.g. "IFD" or "IFD/Exif"). The IFD
+	// must already exist.
+	FqIfdPath string
+
+	TagName string
+	Value   string
+}
+
+// String returns a descriptive string.
+func (tt TemplateTag) String() string {
+	return fmt.Sprintf("TemplateTag<IFD=[%s] TAG=[%s] VALUE=[%s]>", tt.FqIfdPath, tt.TagName, tt.Value)
+}
+
+// TagTemplate is a set of tags to stamp onto many images, such as the
+// copyright, photographer, and job ID applied when importing a shoot.
+//
+// Placeholders are resolved, in order of precedence, from `Variables`, from
+// the file ("FileName", "FileBaseName", "FileDir", "FileSize", and
+// "FileModifyDate", which is in EXIF's date format), and from the existing
+// tags in IFD0 and the EXIF IFD (by name, e.g. "{DateTimeOriginal}"). An
+// unresolved placeholder is an error.
+type TagTemplate struct {
+	Tags      []TemplateTag
+	Variables map[string]string
+
+	// KeepExisting leaves tags that are already present alone.
+	KeepExisting bool
+
+	// Backup, if not empty, is the format (one of the BackupFormat*
+	// constants) that `StampJpegFile()` backs-up each file's EXIF in before
+	// changing it (see `BackupJpegExif()`).
+	Backup string
+
+	// MtimeFromDateTimeOriginal sets the modification time of each file that
+	// `StampJpegFile()` changes to its DateTimeOriginal (see
+	// `RewriteOptions`).
+	MtimeFromDateTimeOriginal bool
+}
+
+// templateMetadataVariables returns the values of the tags in IFD0 and the
+// EXIF IFD by name.
+func templateMetadataVariables(index IfdIndex) (variables map[string]string, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	variables = make(map[string]string)
+
+	for _, ifdPath := range []string{exifcommon.IfdPathStandard, exifcommon.IfdPathStandardExif} {
+		ifds, found := index.Lookup[ifdPath]
+		if found == false {
+			continue
+		}
+
+		ifd := ifds[0]
+
+		for _, ite := range ifd.Entries {
+			if ite.ChildIfdPath() != "" {
+				continue
+			}
+
+			it, err := ifd.tagIndex.Get(ite.IfdPath(), ite.TagId())
+			if err != nil {
+				continue
+			}
+
+			if ite.TagType() == exifcommon.TypeAscii || ite.TagType() == exifcommon.TypeAsciiNoNul {
+				value, err := ite.Value()
+				if err != nil {
+					continue
+				}
+
+				variables[it.Name] = strings.TrimSpace(value.(string))
+			} else if ite.TagType() != exifcommon.TypeUndefined {
+				phrase, err := ite.FormatFirst()
+				if err != nil {
+					continue
+				}
+
+				variables[it.Name] = phrase
+			}
+		}
+	}
+
+	return variables, nil
+}
+
+// templateFileVariables returns the variables that describe the file.
+func templateFileVariables(filepath string, fi os.FileInfo) map[string]string {
+	filename := path.Base(filepath)
+
+	return map[string]string{
+		"FileName":       filename,
+		"FileBaseName":   strings.TrimSuffix(filename, path.Ext(filename)),
+		"FileDir":        path.Dir(filepath),
+		"FileSize":       strconv.FormatInt(fi.Size(), 10),
+		"FileModifyDate": ExifFullTimestampString(fi.ModTime()),
+	}
+}
+
+// Resolve returns the tags with their placeholders replaced. `Variables` takes
+// precedence over the given variables.
+func (tt TagTemplate) Resolve(variables map[string]string) (resolved []TemplateTag, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	resolved = make([]TemplateTag, len(tt.Tags))
+
+	for i, tag := range tt.Tags {
+		var missing []string
+
+		value := templatePlaceholderRe.ReplaceAllStringFunc(tag.Value, func(placeholder string) string {
+			name := placeholder[1 : len(placeholder)-1]
+
+			if value, found := tt.Variables[name]; found == true {
+				return value
+			} else if value, found := variables[name]; found == true {
+				return value
+			}
+
+			missing = append(missing, name)
+			return placeholder
+		})
+
+		if len(missing) > 0 {
+			log.Panicf("template value for [%s] has unresolved placeholders: %v", tag.TagName, missing)
+		}
+
+		tag.Value = value
+		resolved[i] = tag
+	}
+
+	return resolved, nil
+}
+
+// templateValue converts the string to the type that the tag expects.
+func templateValue(tagType exifcommon.TagTypePrimitive, valueString string) (value interface{}, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	if tagType == exifcommon.TypeAscii || tagType == exifcommon.TypeAsciiNoNul {
+		return valueString, nil
+	}
+
+	parts := strings.Split(valueString, ",")
+
+	switch tagType {
+	case exifcommon.TypeByte:
+		values := make([]byte, len(parts))
+		for i, part := range parts {
+			n, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+			log.PanicIf(err)
+
+			values[i] = byte(n)
+		}
+
+		return values, nil
+	case exifcommon.TypeShort:
+		values := make([]uint16, len(parts))
+		for i, part := range parts {
+			n, err := exifcommon.TranslateStringToType(tagType, strings.TrimSpace(part))
+			log.PanicIf(err)
+
+			values[i] = n.(uint16)
+		}
+
+		return values, nil
+	case exifcommon.TypeLong:
+		values := make([]uint32, len(parts))
+		for i, part := range parts {
+			n, err := exifcommon.TranslateStringToType(tagType, strings.TrimSpace(part))
+			log.PanicIf(err)
+
+			values[i] = n.(uint32)
+		}
+
+		return values, nil
+	case exifcommon.TypeRational:
+		values := make([]exifcommon.Rational, len(parts))
+		for i, part := range parts {
+			n, err := exifcommon.TranslateStringToType(tagType, strings.TrimSpace(part))
+			log.PanicIf(err)
+
+			values[i] = n.(exifcommon.Rational)
+		}
+
+		return values, nil
+	case exifcommon.TypeSignedLong:
+		values := make([]int32, len(parts))
+		for i, part := range parts {
+			n, err := exifcommon.TranslateStringToType(tagType, strings.TrimSpace(part))
+			log.PanicIf(err)
+
+			values[i] = n.(int32)
+		}
+
+		return values, nil
+	case exifcommon.TypeSignedRational:
+		values := make([]exifcommon.SignedRational, len(parts))
+		for i, part := range parts {
+			n, err := exifcommon.TranslateStringToType(tagType, strings.TrimSpace(part))
+			log.PanicIf(err)
+
+			values[i] = n.(exifcommon.SignedRational)
+		}
+
+		return values, nil
+	}
+
+	log.Panicf("tags of type [%s] can not be stamped from a template", tagType)
+	return nil, nil
+}
+
+// StampJpeg returns a copy of the JPEG with the template applied to its EXIF.
+// `variables` are additional placeholder values, such as those describing the
+// file. The EXIF is patched in place (see
+// `ExifPatcher`), and a JPEG without EXIF is an error.
+func StampJpeg(data []byte, tt TagTemplate, variables map[string]string) (updated []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	index, _, _, err := readJpegMetadata(data)
+	log.PanicIf(err)
+
+	if index.RootIfd == nil {
+		log.Panic(ErrNoExif)
+	}
+
+	allVariables, err := templateMetadataVariables(index)
+	log.PanicIf(err)
+
+	for name, value := range variables {
+		allVariables[name] = value
+	}
+
+	resolved, err := tt.Resolve(allVariables)
+	log.PanicIf(err)
+
+	im := NewIfdMappingWithStandard()
+	ti := NewTagIndex()
+
+	updated, err = patchJpegExif(data, func(ep *ExifPatcher) (err error) {
+		defer func() {
+			if state := recover(); state != nil {
+				err = log.Wrap(state.(error))
+			}
+		}()
+
+		for _, tag := range resolved {
+			ifdPath, err := im.StripPathPhraseIndices(tag.FqIfdPath)
+			log.PanicIf(err)
+
+			it, err := ti.GetWithName(ifdPath, tag.TagName)
+			log.PanicIf(err)
+
+			if tt.KeepExisting == true {
+				if _, found := ep.getIfd(tag.FqIfdPath).find(it.Id); found == true {
+					continue
+				}
+			}
+
+			value, err := templateValue(it.Type, tag.Value)
+			log.PanicIf(err)
+
+			err = ep.Set(tag.FqIfdPath, it.Id, value)
+			log.PanicIf(err)
+		}
+
+		return nil
+	})
+	log.PanicIf(err)
+
+	return updated, nil
+}
+
+// StampJpegFile applies the template to a JPEG file, replacing it (see
+// `RewriteFile()`).
+func StampJpegFile(filepath string, tt TagTemplate) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fi, err := os.Stat(filepath)
+	log.PanicIf(err)
+
+	data, err := ioutil.ReadFile(filepath)
+	log.PanicIf(err)
+
+	updated, err := StampJpeg(data, tt, templateFileVariables(filepath, fi))
+	log.PanicIf(err)
+
+	if tt.Backup != "" {
+		_, err := backupJpegExifData(filepath, data, tt.Backup)
+		log.PanicIf(err)
+	}
+
+	err = RewriteFile(filepath, updated, RewriteOptions{MtimeFromDateTimeOriginal: tt.MtimeFromDateTimeOriginal})
+	log.PanicIf(err)
+
+	return nil
+}
+
+// DryRunStampJpegFile reports what `StampJpegFile()` would change in the
+// file without writing it.
+func DryRunStampJpegFile(filepath string, tt TagTemplate) (md MetadataDiff, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	fi, err := os.Stat(filepath)
+	log.PanicIf(err)
+
+	md, err = DryRunFile(filepath, func(data []byte) (updated []byte, err error) {
+		return StampJpeg(data, tt, templateFileVariables(filepath, fi))
+	})
+
+	log.PanicIf(err)
+
+	return md, nil
+}
+
+// StampResult is the outcome of stamping one file.
+type StampResult struct {
+	Filepath string
+	Err      error
+}
+
+// StampJpegFiles applies the template to each JPEG file. A failure doesn't
+// stop the others; check the `Err` of each result.
+func StampJpegFiles(filepaths []string, tt TagTemplate) []StampResult {
+	results := make([]StampResult, len(filepaths))
+
+	for i, filepath := range filepaths {
+		results[i] = StampResult{
+			Filepath: filepath,
+			Err:      StampJpegFile(filepath, tt),
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,191 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func getTestTemplate() TagTemplate {
+	return TagTemplate{
+		Tags: []TemplateTag{
+			{FqIfdPath: "IFD", TagName: "Artist", Value: "{Photographer}"},
+			{FqIfdPath: "IFD", TagName: "Copyright", Value: "(c) {Photographer}"},
+			{FqIfdPath: "IFD/Exif", TagName: "ImageUniqueID", Value: "{JobId}-{FileBaseName}"},
+			{FqIfdPath: "IFD", TagName: "ImageDescription", Value: "Shot on a {Make}"},
+		},
+		Variables: map[string]string{
+			"Photographer": "Jane Doe",
+			"JobId":        "J1234",
+		},
+	}
+}
+
+func TestTagTemplate_Resolve(t *testing.T) {
+	tt := getTestTemplate()
+
+	resolved, err := tt.Resolve(map[string]string{
+		"FileBaseName": "IMG_0001",
+		"Make":         "Canon",
+		"JobId":        "overridden",
+	})
+	log.PanicIf(err)
+
+	values := make([]string, len(resolved))
+	for i, tag := range resolved {
+		values[i] = tag.Value
+	}
+
+	expected := []string{"Jane Doe", "(c) Jane Doe", "J1234-IMG_0001", "Shot on a Canon"}
+	if reflect.DeepEqual(values, expected) != true {
+		t.Fatalf("Resolved values not correct: %v", values)
+	}
+
+	// The template itself is unchanged.
+	if tt.Tags[0].Value != "{Photographer}" {
+		t.Fatalf("Template was modified: %s", tt.Tags[0])
+	}
+}
+
+func TestTagTemplate_Resolve_Unresolved(t *testing.T) {
+	tt := getTestTemplate()
+
+	_, err := tt.Resolve(nil)
+	if err == nil {
+		t.Fatalf("Expected error for unresolved placeholders.")
+	}
+}
+
+func TestTemplateValue(t *testing.T) {
+	value, err := templateValue(exifcommon.TypeShort, "1, 2")
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(value, []uint16{1, 2}) != true {
+		t.Fatalf("SHORT value not correct: %v", value)
+	}
+
+	value, err = templateValue(exifcommon.TypeRational, "563/256")
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(value, []exifcommon.Rational{{Numerator: 563, Denominator: 256}}) != true {
+		t.Fatalf("RATIONAL value not correct: %v", value)
+	}
+
+	value, err = templateValue(exifcommon.TypeAscii, "a, b")
+	log.PanicIf(err)
+
+	if value != "a, b" {
+		t.Fatalf("ASCII value not correct: %v", value)
+	}
+
+	_, err = templateValue(exifcommon.TypeShort, "abc")
+	if err == nil {
+		t.Fatalf("Expected error for invalid number.")
+	}
+
+	_, err = templateValue(exifcommon.TypeUndefined, "abc")
+	if err == nil {
+		t.Fatalf("Expected error for undefined type.")
+	}
+}
+
+func TestStampJpeg(t *testing.T) {
+	tt := getTestTemplate()
+
+	updated, err := StampJpeg(getTestJpegWithExif(), tt, map[string]string{"FileBaseName": "IMG_0001"})
+	log.PanicIf(err)
+
+	index, _, _, err := readJpegMetadata(updated)
+	log.PanicIf(err)
+
+	artist, err := getIfdTagString(index.RootIfd, "Artist")
+	log.PanicIf(err)
+
+	description, err := getIfdTagString(index.RootIfd, "ImageDescription")
+	log.PanicIf(err)
+
+	uniqueId, err := getIfdTagString(index.Lookup[exifcommon.IfdPathStandardExif][0], "ImageUniqueID")
+	log.PanicIf(err)
+
+	if artist != "Jane Doe" {
+		t.Fatalf("Artist not correct: [%s]", artist)
+	} else if description != "Shot on a Canon" {
+		t.Fatalf("ImageDescription not correct: [%s]", description)
+	} else if uniqueId != "J1234-IMG_0001" {
+		t.Fatalf("ImageUniqueID not correct: [%s]", uniqueId)
+	}
+
+	// Existing values are kept if requested.
+	tt.Tags[0].Value = "John Doe"
+	tt.KeepExisting = true
+
+	restamped, err := StampJpeg(updated, tt, map[string]string{"FileBaseName": "IMG_0002"})
+	log.PanicIf(err)
+
+	index, _, _, err = readJpegMetadata(restamped)
+	log.PanicIf(err)
+
+	artist, err = getIfdTagString(index.RootIfd, "Artist")
+	log.PanicIf(err)
+
+	if artist != "Jane Doe" {
+		t.Fatalf("Existing artist was not kept: [%s]", artist)
+	}
+}
+
+func TestStampJpegFiles(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "")
+	log.PanicIf(err)
+
+	defer os.RemoveAll(tempPath)
+
+	goodFilepath := path.Join(tempPath, "IMG_0001.jpg")
+
+	err = ioutil.WriteFile(goodFilepath, getTestJpegWithExif(), 0644)
+	log.PanicIf(err)
+
+	badFilepath := path.Join(tempPath, "notes.txt")
+
+	err = ioutil.WriteFile(badFilepath, []byte("not an image"), 0644)
+	log.PanicIf(err)
+
+	results := StampJpegFiles([]string{badFilepath, goodFilepath}, getTestTemplate())
+
+	if len(results) != 2 {
+		t.Fatalf("Result count not correct: (%d)", len(results))
+	} else if results[0].Err == nil {
+		t.Fatalf("Expected error for non-JPEG.")
+	} else if results[1].Err != nil {
+		t.Fatalf("Stamping failed: %v", results[1].Err)
+	}
+
+	data, err := ioutil.ReadFile(goodFilepath)
+	log.PanicIf(err)
+
+	index, _, _, err := readJpegMetadata(data)
+	log.PanicIf(err)
+
+	uniqueId, err := getIfdTagString(index.Lookup[exifcommon.IfdPathStandardExif][0], "ImageUniqueID")
+	log.PanicIf(err)
+
+	if uniqueId != "J1234-IMG_0001" {
+		t.Fatalf("ImageUniqueID not correct: [%s]", uniqueId)
+	}
+
+	// No temporary files are left behind.
+	files, err := ioutil.ReadDir(tempPath)
+	log.PanicIf(err)
+
+	if len(files) != 2 {
+		t.Fatalf("Unexpected files left behind: (%d)", len(files))
+	}
+}
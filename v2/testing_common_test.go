@@ -1,15 +1,20 @@
 package exif
 
 import (
+	"bytes"
+	"image"
 	"path"
 	"reflect"
 	"testing"
 
+	"encoding/binary"
+	"image/jpeg"
 	"io/ioutil"
 
 	"github.com/dsoprea/go-logging"
 
 	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
 )
 
 var (
@@ -18,6 +23,8 @@ var (
 	testGpsImageFilepath = ""
 
 	testExifData = make([]byte, 0)
+
+	testDngPixels = []byte("0123456789abcdef")
 )
 
 func getExifSimpleTestIb() *IfdBuilder {
@@ -197,3 +204,113 @@ func getTestGpsImageFilepath() string {
 
 	return testGpsImageFilepath
 }
+
+// getTestJpegWithExif returns a small JPEG with the test EXIF data inserted
+// right after the SOI marker.
+func getTestJpegWithExif() []byte {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+
+	encoded := new(bytes.Buffer)
+
+	err := jpeg.Encode(encoded, img, nil)
+	log.PanicIf(err)
+
+	exifData := getTestExifData()
+
+	b := new(bytes.Buffer)
+
+	// SOI
+	b.Write(encoded.Bytes()[:2])
+
+	// APP1
+	b.Write([]byte{jpegMarkerPrefix, jpegMarkerApp1})
+
+	err = binary.Write(b, binary.BigEndian, uint16(2+len(jpegExifPreamble)+len(exifData)))
+	log.PanicIf(err)
+
+	b.Write(jpegExifPreamble)
+	b.Write(exifData)
+
+	b.Write(encoded.Bytes()[2:])
+
+	return b.Bytes()
+}
+
+// getTestPipelineJpeg returns a 320x240 JPEG with realistic EXIF (including
+// GPS) and no thumbnail.
+func getTestPipelineJpeg() []byte {
+	encoded := new(bytes.Buffer)
+
+	err := jpeg.Encode(encoded, image.NewGray(image.Rect(0, 0, 320, 240)), nil)
+	log.PanicIf(err)
+
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	b := new(bytes.Buffer)
+	b.Write(encoded.Bytes()[:2])
+	b.Write(getTestJpegSegment(jpegMarkerApp1, append(append([]byte{}, jpegExifPreamble...), rawExif...)))
+	b.Write(encoded.Bytes()[2:])
+
+	return b.Bytes()
+}
+
+// getTestHeifBurst returns a HEIC burst of two images (1 and 2, with 1 the
+// primary) that each have their own "Exif" item (3 and 4).
+func getTestHeifBurst(exif1, exif2 []byte) []byte {
+	item1 := getHeifExifItem(exif1)
+	item2 := getHeifExifItem(exif2)
+
+	build := func(itemOffset int) []byte {
+		ftyp := getTestIsoBox("ftyp", []byte("heic"), getTestIsoUints(4, 0), []byte("mif1heic"))
+
+		infes := make([][]byte, 0)
+		for _, itemType := range []string{"hvc1", "hvc1", "Exif", "Exif"} {
+			itemId := uint64(len(infes) + 1)
+			infe := getTestIsoFullBox("infe", 2, 0, getTestIsoUints(2, itemId, 0), []byte(itemType+"\x00"))
+			infes = append(infes, infe)
+		}
+
+		iinf := getTestIsoFullBox("iinf", 0, 0, getTestIsoUints(2, 4), bytes.Join(infes, nil))
+
+		iref := getTestIsoFullBox(
+			"iref", 0, 0,
+			getTestIsoBox("cdsc", getTestIsoUints(2, 3, 1, 1)),
+			getTestIsoBox("cdsc", getTestIsoUints(2, 4, 1, 2)))
+
+		iloc := getTestIsoFullBox(
+			"iloc", 0, 0,
+			[]byte{0x44, 0x00},
+			getTestIsoUints(2, 2),
+			getTestIsoUints(2, 3, 0, 1), getTestIsoUints(4, uint64(itemOffset), uint64(len(item1))),
+			getTestIsoUints(2, 4, 0, 1), getTestIsoUints(4, uint64(itemOffset+len(item1)), uint64(len(item2))))
+
+		brst := getTestIsoFullBox("brst", 0, 0, getTestIsoUints(4, 100, 2, 1, 2))
+		grpl := getTestIsoBox("grpl", brst)
+
+		pitm := getTestIsoFullBox("pitm", 0, 0, getTestIsoUints(2, 1))
+
+		meta := getTestIsoFullBox("meta", 0, 0, pitm, iinf, iref, iloc, grpl)
+		mdat := getTestIsoBox("mdat", item1, item2)
+
+		return bytes.Join([][]byte{ftyp, meta, mdat}, nil)
+	}
+
+	data := build(0)
+
+	return build(len(data) - len(item1) - len(item2))
+}
+
+// getTestMultiFrameExif returns a block with only the given Make.
+func getTestMultiFrameExif(cameraMake string) []byte {
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x010f, Value: cameraMake},
+		},
+	}
+
+	exifData, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return exifData
+}
@@ -0,0 +1,235 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// SubSecPrecisionAuto has `SetTimestamp()` use as many SubSecTime digits
+	// as the time needs (none for a whole second).
+	SubSecPrecisionAuto = -1
+
+	// subSecMaxPrecision is the most SubSecTime digits (nanoseconds).
+	subSecMaxPrecision = 9
+)
+
+var (
+	// ErrSubSecTimeNotValid means that a SubSecTime value isn't just digits.
+	ErrSubSecTimeNotValid = errors.New("subsectime not valid")
+
+	// ErrOffsetTimeNotValid means that an OffsetTime value isn't "+HH:MM" or
+	// "-HH:MM".
+	ErrOffsetTimeNotValid = errors.New("offsettime not valid")
+)
+
+// timestampTag is a timestamp tag and the SubSecTime and OffsetTime tags
+// that go with it.
+type timestampTag struct {
+	fqIfdPath   string
+	tagId       uint16
+	subSecTagId uint16
+	offsetTagId uint16
+}
+
+var (
+	// timestampTags are the timestamp tags, by name. SubSecTime and
+	// OffsetTime are in the Exif IFD even though DateTime is in IFD0.
+	timestampTags = map[string]timestampTag{
+		"DateTime":          {exifcommon.IfdPathStandard, 0x0132, 0x9290, 0x9010},
+		"DateTimeOriginal":  {exifcommon.IfdPathStandardExif, 0x9003, 0x9291, 0x9011},
+		"DateTimeDigitized": {exifcommon.IfdPathStandardExif, 0x9004, 0x9292, 0x9012},
+	}
+)
+
+// ExifSubSecTimeString returns the SubSecTime value for the fraction of a
+// second in the time, with the given number of digits (up to nine). The
+// fraction is truncated rather than rounded, so that it never carries into
+// the seconds. An empty string is returned for a precision of zero, in which
+// case the tag should be left out. `SubSecPrecisionAuto` uses as many digits
+// as the time needs.
+func ExifSubSecTimeString(t time.Time, precision int) string {
+	if precision == SubSecPrecisionAuto {
+		precision = subSecPrecision(t.Nanosecond())
+	}
+
+	if precision <= 0 {
+		return ""
+	} else if precision > subSecMaxPrecision {
+		precision = subSecMaxPrecision
+	}
+
+	return fmt.Sprintf("%09d", t.Nanosecond())[:precision]
+}
+
+// subSecPrecision returns the number of digits needed to write the given
+// nanoseconds exactly.
+func subSecPrecision(nanoseconds int) int {
+	if nanoseconds == 0 {
+		return 0
+	}
+
+	return len(strings.TrimRight(fmt.Sprintf("%09d", nanoseconds), "0"))
+}
+
+// ParseExifSubSecTime parses a SubSecTime value into nanoseconds and the
+// number of digits that it had. Surrounding spaces are ignored and digits
+// beyond the ninth are dropped. An empty value is zero with no precision.
+// `ErrSubSecTimeNotValid` is returned if the value has anything but digits.
+func ParseExifSubSecTime(phrase string) (nanoseconds int, precision int, err error) {
+	phrase = strings.TrimSpace(phrase)
+
+	for _, c := range phrase {
+		if c < '0' || c > '9' {
+			return 0, 0, ErrSubSecTimeNotValid
+		}
+	}
+
+	if len(phrase) > subSecMaxPrecision {
+		phrase = phrase[:subSecMaxPrecision]
+	}
+
+	precision = len(phrase)
+	if precision == 0 {
+		return 0, 0, nil
+	}
+
+	nanoseconds, err = strconv.Atoi(phrase + strings.Repeat("0", subSecMaxPrecision-precision))
+	if err != nil {
+		return 0, 0, ErrSubSecTimeNotValid
+	}
+
+	return nanoseconds, precision, nil
+}
+
+// ParseExifOffsetTime parses an OffsetTime value (e.g. "+09:00"), which is
+// the offset from UTC of the timestamp that it goes with, into a fixed zone.
+// A value that is empty or blank (spaces and a colon, which the standard uses
+// for unknown) returns a nil zone. `ErrOffsetTimeNotValid` is returned if the
+// value is anything else.
+func ParseExifOffsetTime(phrase string) (location *time.Location, err error) {
+	phrase = strings.TrimRight(phrase, "\x00")
+
+	if strings.Trim(phrase, " :") == "" {
+		return nil, nil
+	} else if len(phrase) != 6 || (phrase[0] != '+' && phrase[0] != '-') || phrase[3] != ':' {
+		return nil, ErrOffsetTimeNotValid
+	}
+
+	hours, err := strconv.ParseUint(phrase[1:3], 10, 8)
+	if err != nil || hours > 23 {
+		return nil, ErrOffsetTimeNotValid
+	}
+
+	minutes, err := strconv.ParseUint(phrase[4:6], 10, 8)
+	if err != nil || minutes > 59 {
+		return nil, ErrOffsetTimeNotValid
+	}
+
+	seconds := int(hours)*60*60 + int(minutes)*60
+	if phrase[0] == '-' {
+		seconds = -seconds
+	}
+
+	return time.FixedZone(phrase, seconds), nil
+}
+
+// getTimestampTag returns the tag with the given name or panics.
+func getTimestampTag(tagName string) timestampTag {
+	tt, found := timestampTags[tagName]
+	if found == false {
+		log.Panicf("not a timestamp tag: [%s]", tagName)
+	}
+
+	return tt
+}
+
+// SetTimestamp writes DateTime, DateTimeOriginal, or DateTimeDigitized (as
+// for `ExifFullTimestampString()`) along with its SubSecTime tag, which has
+// the given number of digits (see `ExifSubSecTimeString()`). The SubSecTime
+// tag is removed if the precision is zero. IFDs are created as necessary.
+func SetTimestamp(rootIb *IfdBuilder, tagName string, t time.Time, precision int) (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tt := getTimestampTag(tagName)
+
+	ib, err := GetOrCreateIbFromRootIb(rootIb, tt.fqIfdPath)
+	log.PanicIf(err)
+
+	err = ib.SetStandard(tt.tagId, ExifFullTimestampString(t))
+	log.PanicIf(err)
+
+	exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+	log.PanicIf(err)
+
+	if subSec := ExifSubSecTimeString(t, precision); subSec != "" {
+		err := exifIb.SetStandard(tt.subSecTagId, subSec)
+		log.PanicIf(err)
+	} else {
+		_, err := exifIb.DeleteAll(tt.subSecTagId)
+		log.PanicIf(err)
+	}
+
+	return nil
+}
+
+// GetTimestamp reads DateTime, DateTimeOriginal, or DateTimeDigitized along
+// with its SubSecTime tag, and returns the time (in UTC, as for
+// `ParseExifFullTimestamp()`) and the number of SubSecTime digits.
+// `ErrTagNotFound` is returned if the timestamp isn't present. A SubSecTime
+// that isn't valid is ignored.
+func GetTimestamp(index IfdIndex, tagName string) (t time.Time, precision int, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	tt := getTimestampTag(tagName)
+
+	ifds := index.Lookup[tt.fqIfdPath]
+	if len(ifds) == 0 {
+		return t, 0, ErrTagNotFound
+	}
+
+	phrase, err := getIfdTagString(ifds[0], tagName)
+	log.PanicIf(err)
+
+	if phrase == "" {
+		return t, 0, ErrTagNotFound
+	}
+
+	t, err = ParseExifFullTimestamp(phrase)
+	log.PanicIf(err)
+
+	if ifds := index.Lookup[exifcommon.IfdPathStandardExif]; len(ifds) > 0 {
+		results, err := ifds[0].FindTagWithId(tt.subSecTagId)
+		if err == nil {
+			value, err := results[0].Value()
+			log.PanicIf(err)
+
+			subSec, _ := value.(string)
+
+			nanoseconds, subSecPrecision, err := ParseExifSubSecTime(subSec)
+			if err == nil {
+				t = t.Add(time.Duration(nanoseconds))
+				precision = subSecPrecision
+			}
+		} else if log.Is(err, ErrTagNotFound) == false {
+			log.Panic(err)
+		}
+	}
+
+	return t, precision, nil
+}
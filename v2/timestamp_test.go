@@ -0,0 +1,171 @@
+package exif
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestExifSubSecTimeString(t *testing.T) {
+	timestamp := time.Date(2020, 1, 2, 3, 4, 5, 123450000, time.UTC)
+
+	cases := []struct {
+		timestamp time.Time
+		precision int
+		expected  string
+	}{
+		{timestamp, 0, ""},
+		{timestamp, 1, "1"},
+		{timestamp, 3, "123"},
+		{timestamp, 6, "123450"},
+		{timestamp, 12, "123450000"},
+		{timestamp, SubSecPrecisionAuto, "12345"},
+		{timestamp.Truncate(time.Second), SubSecPrecisionAuto, ""},
+		{timestamp.Truncate(time.Second), 2, "00"},
+
+		// Truncated, not rounded.
+		{time.Date(2020, 1, 2, 3, 4, 5, 999000000, time.UTC), 2, "99"},
+	}
+
+	for _, c := range cases {
+		if subSec := ExifSubSecTimeString(c.timestamp, c.precision); subSec != c.expected {
+			t.Fatalf("SubSecTime for (%d) digits not correct: [%s] != [%s]", c.precision, subSec, c.expected)
+		}
+	}
+}
+
+func TestParseExifSubSecTime(t *testing.T) {
+	cases := []struct {
+		phrase      string
+		nanoseconds int
+		precision   int
+	}{
+		{"", 0, 0},
+		{"5", 500000000, 1},
+		{"05", 50000000, 2},
+		{" 123 ", 123000000, 3},
+		{"1234567891", 123456789, 9},
+	}
+
+	for _, c := range cases {
+		nanoseconds, precision, err := ParseExifSubSecTime(c.phrase)
+		log.PanicIf(err)
+
+		if nanoseconds != c.nanoseconds || precision != c.precision {
+			t.Fatalf("SubSecTime [%s] not parsed correctly: (%d) (%d)", c.phrase, nanoseconds, precision)
+		}
+	}
+
+	for _, phrase := range []string{"1.5", "-12", "12a"} {
+		if _, _, err := ParseExifSubSecTime(phrase); err != ErrSubSecTimeNotValid {
+			t.Fatalf("Expected [%s] to not be valid: %v", phrase, err)
+		}
+	}
+}
+
+func TestParseExifOffsetTime(t *testing.T) {
+	cases := []struct {
+		phrase  string
+		seconds int
+	}{
+		{"+09:00", 9 * 60 * 60},
+		{"-05:30", -(5*60*60 + 30*60)},
+		{"+00:00", 0},
+	}
+
+	for _, c := range cases {
+		zone, err := ParseExifOffsetTime(c.phrase)
+		log.PanicIf(err)
+
+		if _, offset := time.Date(2020, 1, 2, 3, 4, 5, 0, zone).Zone(); offset != c.seconds {
+			t.Fatalf("OffsetTime [%s] not parsed correctly: (%d)", c.phrase, offset)
+		}
+	}
+
+	for _, phrase := range []string{"", "   :  "} {
+		if zone, err := ParseExifOffsetTime(phrase); err != nil || zone != nil {
+			t.Fatalf("Expected [%s] to be unknown: %v", phrase, err)
+		}
+	}
+
+	for _, phrase := range []string{"09:00", "+9:00", "+24:00", "+09:60", "+0a:00"} {
+		if _, err := ParseExifOffsetTime(phrase); err != ErrOffsetTimeNotValid {
+			t.Fatalf("Expected [%s] to not be valid: %v", phrase, err)
+		}
+	}
+}
+
+func TestSetTimestamp_RoundTrip(t *testing.T) {
+	timestamp := time.Date(2020, 1, 2, 3, 4, 5, 120000000, time.UTC)
+
+	cases := []struct {
+		precision int
+		subSec    string
+		expected  time.Time
+	}{
+		{0, "", timestamp.Truncate(time.Second)},
+		{1, "1", timestamp.Truncate(100 * time.Millisecond)},
+		{3, "120", timestamp},
+		{SubSecPrecisionAuto, "12", timestamp},
+	}
+
+	for _, c := range cases {
+		im := NewIfdMappingWithStandard()
+		ti := NewTagIndex()
+
+		rootIb := NewIfdBuilder(im, ti, exifcommon.IfdPathStandard, exifcommon.TestDefaultByteOrder)
+
+		// Set it twice to show that a shorter or absent SubSecTime replaces
+		// the previous one.
+		err := SetTimestamp(rootIb, "DateTimeOriginal", timestamp, 9)
+		log.PanicIf(err)
+
+		err = SetTimestamp(rootIb, "DateTimeOriginal", timestamp, c.precision)
+		log.PanicIf(err)
+
+		err = SetTimestamp(rootIb, "DateTime", timestamp, 0)
+		log.PanicIf(err)
+
+		exifData, err := NewIfdByteEncoder().EncodeToExif(rootIb)
+		log.PanicIf(err)
+
+		_, index, err := Collect(im, ti, exifData)
+		log.PanicIf(err)
+
+		exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+		if _, err := exifIfd.FindTagWithName("SubSecTimeOriginal"); (c.subSec == "") != log.Is(err, ErrTagNotFound) {
+			t.Fatalf("SubSecTimeOriginal presence for (%d) digits not correct: %v", c.precision, err)
+		} else if subSec, err := getIfdTagString(exifIfd, "SubSecTimeOriginal"); err != nil || subSec != c.subSec {
+			t.Fatalf("SubSecTimeOriginal for (%d) digits not correct: [%s] %v", c.precision, subSec, err)
+		} else if _, err := exifIfd.FindTagWithName("SubSecTime"); log.Is(err, ErrTagNotFound) == false {
+			t.Fatalf("SubSecTime not expected: %v", err)
+		}
+
+		recovered, precision, err := GetTimestamp(index, "DateTimeOriginal")
+		log.PanicIf(err)
+
+		if recovered.Equal(c.expected) != true || precision != len(c.subSec) {
+			t.Fatalf("Timestamp for (%d) digits not correct: [%s] (%d)", c.precision, recovered, precision)
+		}
+	}
+}
+
+func TestGetTimestamp(t *testing.T) {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), getTestExifData())
+	log.PanicIf(err)
+
+	timestamp, precision, err := GetTimestamp(index, "DateTimeOriginal")
+	log.PanicIf(err)
+
+	if ExifFullTimestampString(timestamp) != "2017:12:02 08:18:50" || precision != 2 {
+		t.Fatalf("Timestamp not correct: [%s] (%d)", timestamp, precision)
+	}
+
+	if _, _, err := GetTimestamp(getTestMakerNoteIndex("Acme", nil, nil, nil), "DateTimeDigitized"); err != ErrTagNotFound {
+		t.Fatalf("Expected not-found: %v", err)
+	}
+}
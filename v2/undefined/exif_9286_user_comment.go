@@ -3,6 +3,8 @@ package exifundefined
 import (
     "bytes"
     "fmt"
+    "strings"
+    "unicode/utf16"
 
     "encoding/binary"
 
@@ -63,6 +65,63 @@ func (uc Tag9286UserComment) String() string {
     return fmt.Sprintf("UserComment<SIZE=(%d) ENCODING=[%s] V=%v LEN=(%d)>", len(uc.EncodingBytes), TagUndefinedType_9286_UserComment_Encoding_Names[uc.EncodingType], valuePhrase, len(uc.EncodingBytes))
 }
 
+// Text returns the comment as text. ASCII comments are returned as they are
+// and Unicode comments are decoded from UTF-16. JIS and undefined comments
+// can't be decoded, so nothing is returned for them. Trailing NULs and
+// spaces, which cameras often use to fill the tag, are removed.
+func (uc Tag9286UserComment) Text() string {
+    var text string
+
+    if uc.EncodingType == TagUndefinedType_9286_UserComment_Encoding_ASCII {
+        text = string(uc.EncodingBytes)
+    } else if uc.EncodingType == TagUndefinedType_9286_UserComment_Encoding_UNICODE {
+        text = decodeUtf16(uc.EncodingBytes)
+    }
+
+    return strings.TrimRight(text, "\x00 ")
+}
+
+// decodeUtf16 decodes UTF-16 text. The specification says that the byte
+// order is that of the EXIF block, which we don't know here, so we use the
+// BOM if there is one and otherwise guess from where the zero bytes are
+// (mostly-ASCII text has a zero in every high byte).
+func decodeUtf16(raw []byte) string {
+    var byteOrder binary.ByteOrder = binary.BigEndian
+
+    if len(raw) >= 2 && raw[0] == 0xff && raw[1] == 0xfe {
+        byteOrder = binary.LittleEndian
+        raw = raw[2:]
+    } else if len(raw) >= 2 && raw[0] == 0xfe && raw[1] == 0xff {
+        raw = raw[2:]
+    } else {
+        evenZeroes := 0
+        oddZeroes := 0
+
+        for i, b := range raw {
+            if b != 0 {
+                continue
+            }
+
+            if i%2 == 0 {
+                evenZeroes++
+            } else {
+                oddZeroes++
+            }
+        }
+
+        if oddZeroes > evenZeroes {
+            byteOrder = binary.LittleEndian
+        }
+    }
+
+    units := make([]uint16, len(raw)/2)
+    for i := range units {
+        units[i] = byteOrder.Uint16(raw[i*2:])
+    }
+
+    return string(utf16.Decode(units))
+}
+
 type Codec9286UserComment struct {
 }
 
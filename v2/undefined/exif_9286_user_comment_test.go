@@ -92,3 +92,80 @@ func TestCodec9286UserComment_Decode(t *testing.T) {
 		t.Fatalf("Decoded struct not correct.")
 	}
 }
+
+func TestTag9286UserComment_Text(t *testing.T) {
+	ut := Tag9286UserComment{
+		EncodingType:  TagUndefinedType_9286_UserComment_Encoding_ASCII,
+		EncodingBytes: []byte("some comment\x00\x00  "),
+	}
+
+	if text := ut.Text(); text != "some comment" {
+		t.Fatalf("ASCII text not correct: [%s]", text)
+	}
+
+	// Little-endian without a BOM.
+	ut = Tag9286UserComment{
+		EncodingType:  TagUndefinedType_9286_UserComment_Encoding_UNICODE,
+		EncodingBytes: []byte{'h', 0, 'i', 0, 0xe9, 0, 0, 0},
+	}
+
+	if text := ut.Text(); text != "hié" {
+		t.Fatalf("Little-endian Unicode text not correct: [%s]", text)
+	}
+
+	// Big-endian with a BOM.
+	ut = Tag9286UserComment{
+		EncodingType:  TagUndefinedType_9286_UserComment_Encoding_UNICODE,
+		EncodingBytes: []byte{0xfe, 0xff, 0, 'h', 0, 'i'},
+	}
+
+	if text := ut.Text(); text != "hi" {
+		t.Fatalf("Big-endian Unicode text not correct: [%s]", text)
+	}
+
+	ut = Tag9286UserComment{
+		EncodingType:  TagUndefinedType_9286_UserComment_Encoding_UNDEFINED,
+		EncodingBytes: []byte("not decoded"),
+	}
+
+	if text := ut.Text(); text != "" {
+		t.Fatalf("Undefined text not correct: [%s]", text)
+	}
+
+	ut = Tag9286UserComment{
+		EncodingType:  TagUndefinedType_9286_UserComment_Encoding_JIS,
+		EncodingBytes: []byte{0x1b, 0x24, 0x42, 0x46, 0x7c},
+	}
+
+	if text := ut.Text(); text != "" {
+		t.Fatalf("JIS text not correct: [%s]", text)
+	}
+}
+
+func TestTag9286UserComment_FormatFirst(t *testing.T) {
+	ut := Tag9286UserComment{
+		EncodingType:  TagUndefinedType_9286_UserComment_Encoding_ASCII,
+		EncodingBytes: []byte("line one\nline two"),
+	}
+
+	phrase, err := exifcommon.FormatFromType(ut, true)
+	log.PanicIf(err)
+
+	if phrase != "line one..." {
+		t.Fatalf("FormatFirst not correct: [%s]", phrase)
+	}
+}
+
+func TestTag9286UserComment_FormatFirst_NoText(t *testing.T) {
+	ut := Tag9286UserComment{
+		EncodingType:  TagUndefinedType_9286_UserComment_Encoding_UNDEFINED,
+		EncodingBytes: make([]byte, 256),
+	}
+
+	phrase, err := exifcommon.FormatFromType(ut, true)
+	log.PanicIf(err)
+
+	if phrase != ut.String() {
+		t.Fatalf("FormatFirst should describe a comment without text: [%s]", phrase)
+	}
+}
@@ -1,6 +1,8 @@
 package exifundefined
 
 import (
+	"strings"
+
 	"encoding/binary"
 
 	"github.com/dsoprea/go-logging"
@@ -20,6 +22,11 @@ func (gpm Tag001BGPSProcessingMethod) String() string {
 	return gpm.string
 }
 
+// Text returns the processing method (e.g. "GPS" or "NETWORK").
+func (gpm Tag001BGPSProcessingMethod) Text() string {
+	return strings.TrimRight(gpm.string, "\x00 ")
+}
+
 type Codec001BGPSProcessingMethod struct {
 }
 
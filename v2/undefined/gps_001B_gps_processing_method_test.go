@@ -62,3 +62,11 @@ func TestCodec001BGPSProcessingMethod_Decode(t *testing.T) {
 		t.Fatalf("Decoded value not correct: %s\n", value)
 	}
 }
+
+func TestTag001BGPSProcessingMethod_Text(t *testing.T) {
+	gpm := Tag001BGPSProcessingMethod{"NETWORK\x00"}
+
+	if text := gpm.Text(); text != "NETWORK" {
+		t.Fatalf("Text not correct: [%s]", text)
+	}
+}
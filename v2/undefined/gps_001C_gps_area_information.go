@@ -1,6 +1,8 @@
 package exifundefined
 
 import (
+	"strings"
+
 	"encoding/binary"
 
 	"github.com/dsoprea/go-logging"
@@ -20,6 +22,11 @@ func (gai Tag001CGPSAreaInformation) String() string {
 	return gai.string
 }
 
+// Text returns the name of the area.
+func (gai Tag001CGPSAreaInformation) Text() string {
+	return strings.TrimRight(gai.string, "\x00 ")
+}
+
 type Codec001CGPSAreaInformation struct {
 }
 
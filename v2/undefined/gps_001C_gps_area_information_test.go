@@ -62,3 +62,11 @@ func TestCodec001CGPSAreaInformation_Decode(t *testing.T) {
 		t.Fatalf("Decoded value not correct: %s\n", value)
 	}
 }
+
+func TestTag001CGPSAreaInformation_Text(t *testing.T) {
+	gai := Tag001CGPSAreaInformation{"Downtown  "}
+
+	if text := gai.Text(); text != "Downtown" {
+		t.Fatalf("Text not correct: [%s]", text)
+	}
+}
@@ -0,0 +1,75 @@
+package exif
+
+import (
+	"github.com/dsoprea/go-logging"
+)
+
+// UnknownTagPolicy determines what happens to the tags that aren't in the tag
+// index (e.g. private or vendor tags) when a builder is loaded from existing
+// IFDs.
+type UnknownTagPolicy int
+
+const (
+	// UnknownTagKeep copies unknown tags verbatim, like any other tag. This is
+	// the default.
+	UnknownTagKeep UnknownTagPolicy = iota
+
+	// UnknownTagDrop leaves unknown tags out.
+	UnknownTagDrop
+
+	// UnknownTagWarn copies unknown tags verbatim but logs a warning for each
+	// one.
+	UnknownTagWarn
+)
+
+// String returns the name of the policy.
+func (policy UnknownTagPolicy) String() string {
+	switch policy {
+	case UnknownTagKeep:
+		return "keep"
+	case UnknownTagDrop:
+		return "drop"
+	case UnknownTagWarn:
+		return "warn"
+	}
+
+	return "unknown"
+}
+
+// SetUnknownTagPolicy determines what `AddTagsFromExisting()` does with tags
+// that aren't in the tag index. The default is `UnknownTagKeep`. The builders
+// that are created for child IFDs while loading, and those created by
+// `GetOrCreateIbFromRootIb()` and `AppendPage()`, inherit it. Use
+// `NewIfdBuilderFromExistingChainWithPolicy()` to load a whole chain with it.
+func (ib *IfdBuilder) SetUnknownTagPolicy(policy UnknownTagPolicy) {
+	ib.unknownTagPolicy = policy
+}
+
+// NewIfdBuilderFromExistingChainWithPolicy is `NewIfdBuilderFromExistingChain()`
+// with the given policy for unknown tags, which the builders keep.
+func NewIfdBuilderFromExistingChainWithPolicy(rootIfd *Ifd, policy UnknownTagPolicy) (firstIb *IfdBuilder) {
+	return newIfdBuilderFromExistingChain(rootIfd, nil, policy)
+}
+
+// keepExistingTag returns whether an existing tag is copied under the
+// builder's unknown-tag policy.
+func (ib *IfdBuilder) keepExistingTag(ite *IfdTagEntry) bool {
+	if ib.unknownTagPolicy == UnknownTagKeep {
+		return true
+	}
+
+	_, err := ib.tagIndex.Get(ite.IfdPath(), ite.TagId())
+	if err == nil {
+		return true
+	} else if log.Is(err, ErrTagNotFound) == false {
+		log.Panic(err)
+	}
+
+	if ib.unknownTagPolicy == UnknownTagDrop {
+		return false
+	}
+
+	ifdBuilderLogger.Warningf(nil, "Keeping unknown tag (0x%04x) in IFD [%s].", ite.TagId(), ib.fqIfdPath)
+
+	return true
+}
@@ -0,0 +1,100 @@
+package exif
+
+import (
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+const (
+	testUnknownTagId = 0xc8ff
+)
+
+// getTestIfdWithUnknownTags returns a parse of an EXIF block with an unknown
+// tag in both IFD0 and the Exif IFD.
+func getTestIfdWithUnknownTags() *Ifd {
+	exifIfd := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x829d, Value: []exifcommon.Rational{{Numerator: 28, Denominator: 10}}},
+			{Id: testUnknownTagId, Value: []uint16{2}},
+		},
+	}
+
+	root := &exiftest.Ifd{
+		Tags: []exiftest.Tag{
+			{Id: 0x010f, Value: "Canon"},
+			{Id: testUnknownTagId, Value: "private"},
+		},
+		Children: []exiftest.Child{
+			{TagId: exifcommon.IfdExifId, Ifd: exifIfd},
+		},
+	}
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	return index.RootIfd
+}
+
+func TestUnknownTagPolicy(t *testing.T) {
+	rootIfd := getTestIfdWithUnknownTags()
+
+	cases := []struct {
+		policy UnknownTagPolicy
+		kept   bool
+	}{
+		{UnknownTagKeep, true},
+		{UnknownTagDrop, false},
+		{UnknownTagWarn, true},
+	}
+
+	for _, c := range cases {
+		rootIb := NewIfdBuilderFromExistingChainWithPolicy(rootIfd, c.policy)
+
+		exifIb, err := GetOrCreateIbFromRootIb(rootIb, exifcommon.IfdPathStandardExif)
+		log.PanicIf(err)
+
+		for _, ib := range []*IfdBuilder{rootIb, exifIb} {
+			_, err := ib.FindTag(testUnknownTagId)
+			if kept := err == nil; kept != c.kept {
+				t.Fatalf("Unknown tag in [%s] not handled correctly for policy [%s]: %v", ib.fqIfdPath, c.policy, err)
+			}
+		}
+
+		if _, err := rootIb.FindTagWithName("Make"); err != nil {
+			t.Fatalf("Known tag dropped for policy [%s]: %v", c.policy, err)
+		} else if _, err := exifIb.FindTagWithName("FNumber"); err != nil {
+			t.Fatalf("Known child tag dropped for policy [%s]: %v", c.policy, err)
+		}
+	}
+}
+
+func TestIfdBuilder_SetUnknownTagPolicy(t *testing.T) {
+	rootIfd := getTestIfdWithUnknownTags()
+
+	ib := NewIfdBuilderWithExistingIfd(rootIfd)
+	ib.SetUnknownTagPolicy(UnknownTagDrop)
+
+	err := ib.AddTagsFromExisting(rootIfd, nil, nil)
+	log.PanicIf(err)
+
+	rawExif, err := NewIfdByteEncoder().EncodeToExif(ib)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	if _, err := index.RootIfd.FindTagWithId(testUnknownTagId); log.Is(err, ErrTagNotFound) != true {
+		t.Fatalf("Unknown tag not dropped: %v", err)
+	} else if _, err := index.Lookup[exifcommon.IfdPathStandardExif][0].FindTagWithId(testUnknownTagId); log.Is(err, ErrTagNotFound) != true {
+		t.Fatalf("Unknown child tag not dropped: %v", err)
+	}
+}
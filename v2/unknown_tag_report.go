@@ -0,0 +1,228 @@
+package exif
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+const (
+	// DefaultUnknownTagSampleSize is how many bytes of the value of an
+	// unknown tag are reported by default.
+	DefaultUnknownTagSampleSize = 32
+
+	// DefaultUnknownTagMaxSamples is how many distinct samples an
+	// `UnknownTagCollector` keeps for each tag by default.
+	DefaultUnknownTagMaxSamples = 8
+)
+
+// UnknownTag is an entry whose tag isn't in the tag index.
+type UnknownTag struct {
+	FqIfdPath string
+	TagId     uint16
+	TagType   exifcommon.TagTypePrimitive
+	UnitCount uint32
+
+	// Sample is the start of the raw value, or nil if it couldn't be read.
+	Sample []byte
+}
+
+// String returns a descriptive string.
+func (ut UnknownTag) String() string {
+	return fmt.Sprintf("UnknownTag<IFD=[%s] ID=(0x%04x) TYPE=[%s] UNIT-COUNT=(%d) SAMPLE=(%d)>", ut.FqIfdPath, ut.TagId, ut.TagType, ut.UnitCount, len(ut.Sample))
+}
+
+// UnknownTagReporter is called with each unknown tag as it's parsed.
+type UnknownTagReporter func(ut UnknownTag)
+
+// SetUnknownTagReporter has the enumerator call the reporter with each entry
+// whose tag isn't in the tag index, along with up to `sampleSize` bytes of its
+// value (zero is `DefaultUnknownTagSampleSize`, and less than zero reports no
+// value). This is opt-in, and the reports only go to the reporter: nothing is
+// sent anywhere else. An `UnknownTagCollector` aggregates them, so that the
+// tags that come up often can be added to a registry (see `LoadRegistry()`).
+func (ie *IfdEnumerate) SetUnknownTagReporter(reporter UnknownTagReporter, sampleSize int) {
+	if sampleSize == 0 {
+		sampleSize = DefaultUnknownTagSampleSize
+	}
+
+	ie.unknownTagReporter = reporter
+	ie.unknownTagSampleSize = sampleSize
+}
+
+// reportUnknownTag calls the reporter if the entry's tag isn't known.
+func (ie *IfdEnumerate) reportUnknownTag(fqIfdPath, ifdPath string, ite *IfdTagEntry) {
+	if ie.unknownTagReporter == nil {
+		return
+	} else if _, err := ie.tagIndex.Get(ifdPath, ite.TagId()); err == nil {
+		return
+	}
+
+	ut := UnknownTag{
+		FqIfdPath: fqIfdPath,
+		TagId:     ite.TagId(),
+		TagType:   ite.TagType(),
+		UnitCount: ite.UnitCount(),
+	}
+
+	if ie.unknownTagSampleSize > 0 {
+		ut.Sample = sampleUnknownTagValue(ite, ie.unknownTagSampleSize)
+	}
+
+	ie.unknownTagReporter(ut)
+}
+
+// sampleUnknownTagValue returns a copy of the start of the raw value, or nil
+// if it can't be read.
+func sampleUnknownTagValue(ite *IfdTagEntry, sampleSize int) (sample []byte) {
+	defer func() {
+		if state := recover(); state != nil {
+			sample = nil
+		}
+	}()
+
+	valueContext := ite.getValueContext()
+
+	if ite.TagType() == exifcommon.TypeUndefined {
+		valueContext.SetUndefinedValueType(exifcommon.TypeByte)
+	}
+
+	raw, err := valueContext.ReadRawEncoded()
+	if err != nil {
+		return nil
+	}
+
+	if len(raw) > sampleSize {
+		raw = raw[:sampleSize]
+	}
+
+	sample = make([]byte, len(raw))
+	copy(sample, raw)
+
+	return sample
+}
+
+// UnknownTagObservation is what an `UnknownTagCollector` has seen of one tag
+// in one IFD.
+type UnknownTagObservation struct {
+	FqIfdPath string
+	TagId     uint16
+
+	// Count is how many times the tag was seen.
+	Count int
+
+	// TagTypes are the distinct types that it had, in the order seen.
+	TagTypes []exifcommon.TagTypePrimitive
+
+	// Samples are the distinct samples of its value, in the order seen.
+	Samples [][]byte
+}
+
+// String returns a descriptive string.
+func (uto UnknownTagObservation) String() string {
+	return fmt.Sprintf("UnknownTagObservation<IFD=[%s] ID=(0x%04x) COUNT=(%d) TYPES=%v SAMPLES=(%d)>", uto.FqIfdPath, uto.TagId, uto.Count, uto.TagTypes, len(uto.Samples))
+}
+
+// unknownTagKey identifies a tag in an IFD.
+type unknownTagKey struct {
+	fqIfdPath string
+	tagId     uint16
+}
+
+// UnknownTagCollector aggregates the unknown tags reported across any number
+// of files (see `SetUnknownTagReporter()`). It's safe to share between
+// goroutines.
+type UnknownTagCollector struct {
+	maxSamples int
+
+	observations map[unknownTagKey]*UnknownTagObservation
+	mutex        sync.Mutex
+}
+
+// NewUnknownTagCollector returns a collector that keeps up to `maxSamples`
+// distinct samples of each tag. Zero is `DefaultUnknownTagMaxSamples`.
+func NewUnknownTagCollector(maxSamples int) *UnknownTagCollector {
+	if maxSamples <= 0 {
+		maxSamples = DefaultUnknownTagMaxSamples
+	}
+
+	return &UnknownTagCollector{
+		maxSamples:   maxSamples,
+		observations: make(map[unknownTagKey]*UnknownTagObservation),
+	}
+}
+
+// Report records the tag. It's an `UnknownTagReporter`.
+func (utc *UnknownTagCollector) Report(ut UnknownTag) {
+	utc.mutex.Lock()
+	defer utc.mutex.Unlock()
+
+	key := unknownTagKey{fqIfdPath: ut.FqIfdPath, tagId: ut.TagId}
+
+	uto, found := utc.observations[key]
+	if found == false {
+		uto = &UnknownTagObservation{
+			FqIfdPath: ut.FqIfdPath,
+			TagId:     ut.TagId,
+			TagTypes:  make([]exifcommon.TagTypePrimitive, 0),
+			Samples:   make([][]byte, 0),
+		}
+
+		utc.observations[key] = uto
+	}
+
+	uto.Count++
+
+	hasType := false
+	for _, tagType := range uto.TagTypes {
+		if tagType == ut.TagType {
+			hasType = true
+			break
+		}
+	}
+
+	if hasType == false {
+		uto.TagTypes = append(uto.TagTypes, ut.TagType)
+	}
+
+	if ut.Sample == nil || len(uto.Samples) >= utc.maxSamples {
+		return
+	}
+
+	for _, sample := range uto.Samples {
+		if bytes.Equal(sample, ut.Sample) == true {
+			return
+		}
+	}
+
+	uto.Samples = append(uto.Samples, ut.Sample)
+}
+
+// Observations returns copies of what's been collected, ordered by IFD and
+// then by tag ID.
+func (utc *UnknownTagCollector) Observations() []UnknownTagObservation {
+	utc.mutex.Lock()
+	defer utc.mutex.Unlock()
+
+	observations := make([]UnknownTagObservation, 0, len(utc.observations))
+	for _, uto := range utc.observations {
+		copied := *uto
+		copied.TagTypes = append([]exifcommon.TagTypePrimitive{}, uto.TagTypes...)
+		copied.Samples = append([][]byte{}, uto.Samples...)
+
+		observations = append(observations, copied)
+	}
+
+	sort.Slice(observations, func(i, j int) bool {
+		if observations[i].FqIfdPath != observations[j].FqIfdPath {
+			return observations[i].FqIfdPath < observations[j].FqIfdPath
+		}
+
+		return observations[i].TagId < observations[j].TagId
+	})
+
+	return observations
+}
@@ -0,0 +1,105 @@
+package exif
+
+import (
+	"bytes"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestUnknownTagExif returns EXIF with an unknown SHORT tag in IFD0 and an
+// unknown, 64-byte UNDEFINED tag in the Exif IFD.
+func getTestUnknownTagExif(value uint16) []byte {
+	root := exiftest.NewRealisticIfd()
+	root.Tags = append(root.Tags, exiftest.Tag{Id: 0xfe01, Value: []uint16{value, 2}})
+
+	exifIfd := root.Children[0].Ifd
+	exifIfd.Tags = append(exifIfd.Tags, exiftest.Tag{Id: 0xfe02, Raw: bytes.Repeat([]byte{0xab}, 64), Type: exifcommon.TypeUndefined})
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return rawExif
+}
+
+// collectTestUnknownTags collects the EXIF with the reporter.
+func collectTestUnknownTags(rawExif []byte, reporter UnknownTagReporter, sampleSize int) {
+	eh, err := ParseExifHeader(rawExif)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, eh.ByteOrder)
+	ie.SetUnknownTagReporter(reporter, sampleSize)
+
+	_, err = ie.Collect(eh.FirstIfdOffset)
+	log.PanicIf(err)
+}
+
+func TestIfdEnumerate_SetUnknownTagReporter(t *testing.T) {
+	reported := make([]UnknownTag, 0)
+
+	collectTestUnknownTags(getTestUnknownTagExif(1), func(ut UnknownTag) {
+		reported = append(reported, ut)
+	}, 0)
+
+	if len(reported) != 2 {
+		t.Fatalf("Expected two unknown tags: %v", reported)
+	}
+
+	ut := reported[0]
+	if ut.FqIfdPath != "IFD" || ut.TagId != 0xfe01 || ut.TagType != exifcommon.TypeShort || ut.UnitCount != 2 {
+		t.Fatalf("IFD0 tag not correct: %s", ut)
+	} else if bytes.Equal(ut.Sample, []byte{0, 1, 0, 2}) != true {
+		t.Fatalf("IFD0 sample not correct: %v", ut.Sample)
+	}
+
+	ut = reported[1]
+	if ut.FqIfdPath != "IFD/Exif" || ut.TagId != 0xfe02 || ut.TagType != exifcommon.TypeUndefined || ut.UnitCount != 64 {
+		t.Fatalf("Exif tag not correct: %s", ut)
+	} else if len(ut.Sample) != DefaultUnknownTagSampleSize || ut.Sample[0] != 0xab {
+		t.Fatalf("Exif sample not truncated: %v", ut.Sample)
+	}
+
+	// Without a sample.
+
+	reported = reported[:0]
+
+	collectTestUnknownTags(getTestUnknownTagExif(1), func(ut UnknownTag) {
+		reported = append(reported, ut)
+	}, -1)
+
+	if len(reported) != 2 || reported[0].Sample != nil || reported[1].Sample != nil {
+		t.Fatalf("Expected no samples: %v", reported)
+	}
+}
+
+func TestUnknownTagCollector(t *testing.T) {
+	utc := NewUnknownTagCollector(2)
+
+	for _, value := range []uint16{1, 1, 2, 3} {
+		collectTestUnknownTags(getTestUnknownTagExif(value), utc.Report, 0)
+	}
+
+	observations := utc.Observations()
+	if len(observations) != 2 {
+		t.Fatalf("Expected two observations: %v", observations)
+	}
+
+	uto := observations[0]
+	if uto.FqIfdPath != "IFD" || uto.TagId != 0xfe01 || uto.Count != 4 {
+		t.Fatalf("IFD0 observation not correct: %s", uto)
+	} else if len(uto.TagTypes) != 1 || uto.TagTypes[0] != exifcommon.TypeShort {
+		t.Fatalf("Types not correct: %v", uto.TagTypes)
+	} else if len(uto.Samples) != 2 || uto.Samples[0][1] != 1 || uto.Samples[1][1] != 2 {
+		t.Fatalf("Samples not distinct or not limited: %v", uto.Samples)
+	}
+
+	uto = observations[1]
+	if uto.FqIfdPath != "IFD/Exif" || uto.TagId != 0xfe02 || uto.Count != 4 || len(uto.Samples) != 1 {
+		t.Fatalf("Exif observation not correct: %s", uto)
+	}
+}
@@ -113,49 +113,12 @@ func GetFlatExifData(exifData []byte) (exifTags []ExifTag, err error) {
         var ifd *Ifd
         ifd, q = q[0], q[1:]
 
-        ti := NewTagIndex()
         for _, ite := range ifd.Entries {
-            tagName := ""
-
-            it, err := ti.Get(ifd.IfdPath, ite.TagId())
-            if err != nil {
-                // If it's a non-standard tag, just leave the name blank.
-                if log.Is(err, ErrTagNotFound) != true {
-                    log.PanicIf(err)
-                }
-            } else {
-                tagName = it.Name
-            }
-
-            valueBytes, err := ite.GetRawBytes()
-            if err != nil {
-                if err == exifundefined.ErrUnparseableValue {
-                    continue
-                }
+            et, ok, err := newExifTag(ti, ifd.IfdPath, ite)
+            log.PanicIf(err)
 
-                log.Panic(err)
-            }
-
-            value, err := ite.Value()
-            if err != nil {
-                if err == exifcommon.ErrUnhandledUndefinedTypedTag {
-                    value = exifundefined.UnparseableUnknownTagValuePlaceholder
-                } else {
-                    log.Panic(err)
-                }
-            }
-
-            tagType := ite.TagType()
-
-            et := ExifTag{
-                IfdPath:      ifd.IfdPath,
-                TagId:        ite.TagId(),
-                TagName:      tagName,
-                TagTypeId:    tagType,
-                TagTypeName:  tagType.String(),
-                Value:        value,
-                ValueBytes:   valueBytes,
-                ChildIfdPath: ite.ChildIfdPath(),
+            if ok == false {
+                continue
             }
 
             exifTags = append(exifTags, et)
@@ -173,6 +136,132 @@ func GetFlatExifData(exifData []byte) (exifTags []ExifTag, err error) {
     return exifTags, nil
 }
 
+// newExifTag builds the flat representation of the given entry. `ok` is false
+// if the value can not be parsed and the tag should be skipped.
+func newExifTag(ti *TagIndex, ifdPath string, ite *IfdTagEntry) (et ExifTag, ok bool, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    tagName := ""
+
+    it, err := ti.Get(ifdPath, ite.TagId())
+    if err != nil {
+        // If it's a non-standard tag, just leave the name blank.
+        if log.Is(err, ErrTagNotFound) != true {
+            log.PanicIf(err)
+        }
+    } else {
+        tagName = it.Name
+    }
+
+    valueBytes, err := ite.GetRawBytes()
+    if err != nil {
+        if err == exifundefined.ErrUnparseableValue {
+            return et, false, nil
+        }
+
+        log.Panic(err)
+    }
+
+    value, err := ite.Value()
+    if err != nil {
+        if err == exifcommon.ErrUnhandledUndefinedTypedTag {
+            value = exifundefined.UnparseableUnknownTagValuePlaceholder
+        } else {
+            log.Panic(err)
+        }
+    }
+
+    tagType := ite.TagType()
+
+    et = ExifTag{
+        IfdPath:      ifdPath,
+        TagId:        ite.TagId(),
+        TagName:      tagName,
+        TagTypeId:    tagType,
+        TagTypeName:  tagType.String(),
+        Value:        value,
+        ValueBytes:   valueBytes,
+        ChildIfdPath: ite.ChildIfdPath(),
+    }
+
+    return et, true, nil
+}
+
+// getIfdTagString returns the value of an ASCII tag or an empty string if
+// the tag isn't present.
+func getIfdTagString(ifd *Ifd, tagName string) (value string, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    results, err := ifd.FindTagWithName(tagName)
+    if err != nil {
+        if log.Is(err, ErrTagNotFound) == true {
+            return "", nil
+        }
+
+        log.Panic(err)
+    }
+
+    raw, err := results[0].Value()
+    log.PanicIf(err)
+
+    if s, ok := raw.(string); ok == true {
+        return strings.TrimSpace(s), nil
+    }
+
+    return "", nil
+}
+
+// getIfdTagNumber returns the first value of a numeric tag as a float or
+// zero if the tag isn't present.
+func getIfdTagNumber(ifd *Ifd, tagName string) (value float64, err error) {
+    defer func() {
+        if state := recover(); state != nil {
+            err = log.Wrap(state.(error))
+        }
+    }()
+
+    results, err := ifd.FindTagWithName(tagName)
+    if err != nil {
+        if log.Is(err, ErrTagNotFound) == true {
+            return 0, nil
+        }
+
+        log.Panic(err)
+    }
+
+    raw, err := results[0].Value()
+    log.PanicIf(err)
+
+    switch t := raw.(type) {
+    case []uint16:
+        if len(t) > 0 {
+            return float64(t[0]), nil
+        }
+    case []uint32:
+        if len(t) > 0 {
+            return float64(t[0]), nil
+        }
+    case []exifcommon.Rational:
+        if len(t) > 0 && t[0].Denominator != 0 {
+            return float64(t[0].Numerator) / float64(t[0].Denominator), nil
+        }
+    case []exifcommon.SignedRational:
+        if len(t) > 0 && t[0].Denominator != 0 {
+            return float64(t[0].Numerator) / float64(t[0].Denominator), nil
+        }
+    }
+
+    return 0, nil
+}
+
 func GpsDegreesEquals(gi1, gi2 GpsDegrees) bool {
     if gi2.Orientation != gi1.Orientation {
         return false
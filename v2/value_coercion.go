@@ -0,0 +1,256 @@
+package exif
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+const (
+	// coercionMaxDenominator is the largest denominator used when a fraction
+	// is converted to a RATIONAL, which is plenty for an exposure time of
+	// 1/8000 or an aperture of 2.8.
+	coercionMaxDenominator = 1000000
+
+	// coercionTolerance is how far (relative to the value) the rational for
+	// a fraction can be from it before the conversion is considered lossy.
+	coercionTolerance = 1e-9
+)
+
+var (
+	valueCoercionLogger = log.NewLogger("exif.value_coercion")
+)
+
+var (
+	// ErrValueNotConvertible means that a value can't be converted to the type
+	// of its tag without losing something (e.g. a fraction for a SHORT, or a
+	// number that's out of range). The reason is logged.
+	ErrValueNotConvertible = errors.New("value not convertible")
+)
+
+// CoerceValue converts a Go-native value to what values of the given type are
+// encoded from (e.g. []uint16 for SHORT). The builder does this for every
+// value that's set, so this is only needed to check a value ahead of time.
+// These are the rules:
+//
+//   - ASCII tags take a string, bytes, or a `time.Time`, which is written as for
+//     `ExifFullTimestampString()`.
+//   - BYTE, SHORT, LONG, and SLONG tags take integers of any Go type, floats
+//     that are whole numbers, or numeric strings, that are in range for the
+//     type.
+//   - RATIONAL and SRATIONAL tags take `Rational` or `SignedRational` values,
+//     strings like "1/250", or numbers, which become the closest fraction with
+//     a denominator of up to a million (e.g. 0.004 is 1/250). Unsigned ones
+//     can't be negative.
+//   - Numeric tags take either one value or a slice or array of them.
+//   - Undefined-type tags only take an `exifundefined.EncodeableValue`.
+//
+// `ErrValueNotConvertible` is returned, and the reason logged, for anything
+// else, including a conversion that would lose something.
+func CoerceValue(value interface{}, tagType exifcommon.TagTypePrimitive) (coerced interface{}, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	coerced = coerceValue(value, tagType)
+	return coerced, nil
+}
+
+// notConvertible logs why the value can't be converted and panics.
+func notConvertible(value interface{}, tagType exifcommon.TagTypePrimitive, reason string) {
+	valueCoercionLogger.Warningf(nil, "Value [%v] (%T) can't be converted to [%s]: %s", value, value, tagType, reason)
+	log.Panic(ErrValueNotConvertible)
+}
+
+// coerceValue is `CoerceValue()` but panics.
+func coerceValue(value interface{}, tagType exifcommon.TagTypePrimitive) interface{} {
+	switch tagType {
+	case exifcommon.TypeAscii, exifcommon.TypeAsciiNoNul, exifcommon.TypeUtf8:
+		switch v := value.(type) {
+		case string:
+			return v
+		case []byte:
+			return string(v)
+		case time.Time:
+			return ExifFullTimestampString(v)
+		}
+
+		notConvertible(value, tagType, "not a string or a time")
+	case exifcommon.TypeUndefined:
+		if _, ok := value.(exifundefined.EncodeableValue); ok == false {
+			notConvertible(value, tagType, "not an undefined-type value")
+		}
+
+		return value
+	}
+
+	if isCoercedType(value, tagType) == true {
+		// Already the right type.
+		return value
+	}
+
+	items := coercionItems(value)
+	if len(items) == 0 {
+		notConvertible(value, tagType, "no values")
+	}
+
+	switch tagType {
+	case exifcommon.TypeByte:
+		byteValues := make([]byte, len(items))
+		for i, item := range items {
+			byteValues[i] = byte(coerceInteger(item, tagType, 0, math.MaxUint8))
+		}
+
+		return byteValues
+	case exifcommon.TypeShort:
+		shorts := make([]uint16, len(items))
+		for i, item := range items {
+			shorts[i] = uint16(coerceInteger(item, tagType, 0, math.MaxUint16))
+		}
+
+		return shorts
+	case exifcommon.TypeLong:
+		longs := make([]uint32, len(items))
+		for i, item := range items {
+			longs[i] = uint32(coerceInteger(item, tagType, 0, math.MaxUint32))
+		}
+
+		return longs
+	case exifcommon.TypeSignedLong:
+		signedLongs := make([]int32, len(items))
+		for i, item := range items {
+			signedLongs[i] = int32(coerceInteger(item, tagType, math.MinInt32, math.MaxInt32))
+		}
+
+		return signedLongs
+	case exifcommon.TypeRational:
+		rationals := make([]exifcommon.Rational, len(items))
+		for i, item := range items {
+			numerator, denominator := coerceRational(item, tagType)
+			if numerator < 0 || denominator < 0 {
+				notConvertible(item, tagType, "negative")
+			} else if numerator > math.MaxUint32 || denominator > math.MaxUint32 {
+				notConvertible(item, tagType, "out of range")
+			}
+
+			rationals[i] = exifcommon.Rational{Numerator: uint32(numerator), Denominator: uint32(denominator)}
+		}
+
+		return rationals
+	case exifcommon.TypeSignedRational:
+		signedRationals := make([]exifcommon.SignedRational, len(items))
+		for i, item := range items {
+			numerator, denominator := coerceRational(item, tagType)
+			if numerator < math.MinInt32 || numerator > math.MaxInt32 || denominator < math.MinInt32 || denominator > math.MaxInt32 {
+				notConvertible(item, tagType, "out of range")
+			}
+
+			signedRationals[i] = exifcommon.SignedRational{Numerator: int32(numerator), Denominator: int32(denominator)}
+		}
+
+		return signedRationals
+	}
+
+	notConvertible(value, tagType, "type not handled")
+	return nil
+}
+
+// coerceInteger returns the item as an integer if it's a whole number within
+// the given range, and otherwise panics.
+func coerceInteger(item interface{}, tagType exifcommon.TagTypePrimitive, minimum, maximum int64) int64 {
+	f, ok := coercionNumber(item)
+	if ok == false {
+		notConvertible(item, tagType, "not a number")
+	} else if f != math.Trunc(f) {
+		notConvertible(item, tagType, "not a whole number")
+	} else if f < float64(minimum) || f > float64(maximum) {
+		notConvertible(item, tagType, "out of range")
+	}
+
+	return int64(f)
+}
+
+// coerceRational returns the item as a fraction, and otherwise panics. It can
+// be a rational, a string like "1/250", or a number.
+func coerceRational(item interface{}, tagType exifcommon.TagTypePrimitive) (numerator, denominator int64) {
+	switch v := item.(type) {
+	case exifcommon.Rational:
+		return int64(v.Numerator), int64(v.Denominator)
+	case exifcommon.SignedRational:
+		return int64(v.Numerator), int64(v.Denominator)
+	case string:
+		if parts := strings.SplitN(v, "/", 2); len(parts) == 2 {
+			numerator, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+			if err != nil {
+				notConvertible(item, tagType, "numerator not a whole number")
+			}
+
+			denominator, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+			if err != nil {
+				notConvertible(item, tagType, "denominator not a whole number")
+			}
+
+			return numerator, denominator
+		}
+	}
+
+	f, ok := coercionNumber(item)
+	if ok == false {
+		notConvertible(item, tagType, "not a number or a fraction")
+	} else if math.IsNaN(f) == true || math.IsInf(f, 0) == true || math.Abs(f) > math.MaxUint32 {
+		notConvertible(item, tagType, "out of range")
+	}
+
+	numerator, denominator = rationalFromFloat(f, coercionMaxDenominator)
+	if math.Abs(float64(numerator)/float64(denominator)-f) > coercionTolerance*math.Max(1, math.Abs(f)) {
+		notConvertible(item, tagType, "no close enough fraction")
+	}
+
+	return numerator, denominator
+}
+
+// rationalFromFloat returns the closest fraction to the value whose
+// denominator is at most `maxDenominator`, from its continued fraction.
+func rationalFromFloat(f float64, maxDenominator int64) (numerator, denominator int64) {
+	sign := int64(1)
+	if f < 0 {
+		sign = -1
+		f = -f
+	}
+
+	// The convergents, h/k, start from 0/1 and 1/0.
+	h0, h1 := int64(0), int64(1)
+	k0, k1 := int64(1), int64(0)
+
+	x := f
+	for {
+		a := math.Floor(x)
+
+		h2 := int64(a)*h1 + h0
+		k2 := int64(a)*k1 + k0
+		if k2 > maxDenominator {
+			break
+		}
+
+		h0, h1 = h1, h2
+		k0, k1 = k1, k2
+
+		remainder := x - a
+		if remainder < 1e-9 || math.Abs(float64(h1)/float64(k1)-f) < 1e-12 {
+			break
+		}
+
+		x = 1 / remainder
+	}
+
+	return sign * h1, k1
+}
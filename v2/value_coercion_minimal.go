@@ -0,0 +1,152 @@
+//go:build tinygo || exif_minimal
+// +build tinygo exif_minimal
+
+package exif
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// These are the reduced-footprint versions of those in
+// value_coercion_reflect.go. They don't use reflection, so they only know the
+// builtin numeric types and slices of them (not arrays or named types).
+
+// isCoercedType returns true if the value is already what `coerceValue()`
+// returns for the type.
+func isCoercedType(value interface{}, tagType exifcommon.TagTypePrimitive) bool {
+	switch value.(type) {
+	case []byte:
+		return tagType == exifcommon.TypeByte
+	case []uint16:
+		return tagType == exifcommon.TypeShort
+	case []uint32:
+		return tagType == exifcommon.TypeLong
+	case []exifcommon.Rational:
+		return tagType == exifcommon.TypeRational
+	case []int32:
+		return tagType == exifcommon.TypeSignedLong
+	case []exifcommon.SignedRational:
+		return tagType == exifcommon.TypeSignedRational
+	}
+
+	return false
+}
+
+// coercionItems returns the items of a slice, or the value itself as the only
+// item.
+func coercionItems(value interface{}) []interface{} {
+	items := make([]interface{}, 0)
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	case []string:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []int:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []int8:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []int16:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []int32:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []int64:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []uint:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []uint8:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []uint16:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []uint32:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []uint64:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []float32:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []float64:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []exifcommon.Rational:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []exifcommon.SignedRational:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	default:
+		items = append(items, value)
+	}
+
+	return items
+}
+
+// coercionNumber returns a number of a builtin numeric type, or a numeric
+// string, as a float.
+func coercionNumber(item interface{}) (f float64, ok bool) {
+	switch v := item.(type) {
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		// Use the shortest decimal for it, so that 2.8 is still 2.8 rather
+		// than what it is in binary.
+		f, err := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+		return f, err == nil
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,82 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// These use reflection so that a value of any numeric type, or a slice or
+// array of them, can be coerced. See value_coercion_minimal.go for the
+// reduced-footprint build.
+
+var (
+	// coercedTypes are what `coerceValue()` returns for each numeric type.
+	coercedTypes = map[exifcommon.TagTypePrimitive]reflect.Type{
+		exifcommon.TypeByte:           reflect.TypeOf([]byte{}),
+		exifcommon.TypeShort:          reflect.TypeOf([]uint16{}),
+		exifcommon.TypeLong:           reflect.TypeOf([]uint32{}),
+		exifcommon.TypeRational:       reflect.TypeOf([]exifcommon.Rational{}),
+		exifcommon.TypeSignedLong:     reflect.TypeOf([]int32{}),
+		exifcommon.TypeSignedRational: reflect.TypeOf([]exifcommon.SignedRational{}),
+	}
+)
+
+// isCoercedType returns true if the value is already what `coerceValue()`
+// returns for the type.
+func isCoercedType(value interface{}, tagType exifcommon.TagTypePrimitive) bool {
+	coercedType, found := coercedTypes[tagType]
+	return found == true && value != nil && reflect.TypeOf(value) == coercedType
+}
+
+// coercionItems returns the items of a slice or array, or the value itself as
+// the only item.
+func coercionItems(value interface{}) []interface{} {
+	if value == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+
+	return items
+}
+
+// coercionNumber returns a number of any Go type, or a numeric string, as a
+// float.
+func coercionNumber(item interface{}) (f float64, ok bool) {
+	if s, ok := item.(string); ok == true {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return f, err == nil
+	}
+
+	v := reflect.ValueOf(item)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32:
+		// Use the shortest decimal for it, so that 2.8 is still 2.8 rather
+		// than what it is in binary.
+		f, err := strconv.ParseFloat(strconv.FormatFloat(v.Float(), 'g', -1, 32), 64)
+		return f, err == nil
+	case reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,22 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+func TestCoerceValue_Array(t *testing.T) {
+	coerced, err := CoerceValue([2]int{2, 3}, exifcommon.TypeByte)
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(coerced, []byte{2, 3}) != true {
+		t.Fatalf("Value not correct: %v", coerced)
+	}
+}
@@ -0,0 +1,132 @@
+package exif
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/undefined"
+)
+
+func TestCoerceValue(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    interface{}
+		tagType  exifcommon.TagTypePrimitive
+		expected interface{}
+	}{
+		{"int for SHORT", 6, exifcommon.TypeShort, []uint16{6}},
+		{"ints for LONG", []int{1, 2}, exifcommon.TypeLong, []uint32{1, 2}},
+		{"whole float for SHORT", 400.0, exifcommon.TypeShort, []uint16{400}},
+		{"string for SLONG", "-3", exifcommon.TypeSignedLong, []int32{-3}},
+		{"shorts for LONG", []uint16{7}, exifcommon.TypeLong, []uint32{7}},
+		{"float for RATIONAL", 0.004, exifcommon.TypeRational, []exifcommon.Rational{{Numerator: 1, Denominator: 250}}},
+		{"float32 for RATIONAL", float32(2.8), exifcommon.TypeRational, []exifcommon.Rational{{Numerator: 14, Denominator: 5}}},
+		{"fraction string for RATIONAL", "28/10", exifcommon.TypeRational, []exifcommon.Rational{{Numerator: 28, Denominator: 10}}},
+		{"negative float for SRATIONAL", -0.5, exifcommon.TypeSignedRational, []exifcommon.SignedRational{{Numerator: -1, Denominator: 2}}},
+		{"rational for SRATIONAL", exifcommon.Rational{Numerator: 1, Denominator: 3}, exifcommon.TypeSignedRational, []exifcommon.SignedRational{{Numerator: 1, Denominator: 3}}},
+		{"time for ASCII", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), exifcommon.TypeAscii, "2020:01:02 03:04:05"},
+		{"bytes for ASCII", []byte("Canon"), exifcommon.TypeAscii, "Canon"},
+		{"exact", []uint16{1, 2}, exifcommon.TypeShort, []uint16{1, 2}},
+	}
+
+	for _, c := range cases {
+		coerced, err := CoerceValue(c.value, c.tagType)
+		log.PanicIf(err)
+
+		if reflect.DeepEqual(coerced, c.expected) != true {
+			t.Fatalf("Value not correct for [%s]: %v != %v", c.name, coerced, c.expected)
+		}
+	}
+}
+
+func TestCoerceValue_NotConvertible(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   interface{}
+		tagType exifcommon.TagTypePrimitive
+	}{
+		{"fraction for SHORT", 1.5, exifcommon.TypeShort},
+		{"out of range for SHORT", 70000, exifcommon.TypeShort},
+		{"negative for LONG", -1, exifcommon.TypeLong},
+		{"negative for RATIONAL", -2.8, exifcommon.TypeRational},
+		{"lossy RATIONAL", 1e-7, exifcommon.TypeRational},
+		{"word for SHORT", "six", exifcommon.TypeShort},
+		{"number for ASCII", 1, exifcommon.TypeAscii},
+		{"empty", []int{}, exifcommon.TypeShort},
+		{"nil", nil, exifcommon.TypeLong},
+		{"bytes for UNDEFINED", []byte{1}, exifcommon.TypeUndefined},
+	}
+
+	for _, c := range cases {
+		if _, err := CoerceValue(c.value, c.tagType); log.Is(err, ErrValueNotConvertible) == false {
+			t.Fatalf("Expected not-convertible error for [%s]: %v", c.name, err)
+		}
+	}
+
+	// Undefined-type values are left alone.
+
+	value := exifundefined.Tag9000ExifVersion{ExifVersion: "0230"}
+
+	coerced, err := CoerceValue(value, exifcommon.TypeUndefined)
+	log.PanicIf(err)
+
+	if coerced != value {
+		t.Fatalf("Undefined-type value not correct: %v", coerced)
+	}
+}
+
+func TestIfdBuilder_SetStandard_Coerced(t *testing.T) {
+	ib := NewIfdBuilder(NewIfdMappingWithStandard(), NewTagIndex(), exifcommon.IfdPathStandard, exifcommon.EncodeDefaultByteOrder)
+
+	err := ib.SetStandardWithName("Orientation", 8)
+	log.PanicIf(err)
+
+	err = ib.SetStandardWithName("XResolution", 72)
+	log.PanicIf(err)
+
+	rawExif, err := NewIfdByteEncoder().EncodeToExif(ib)
+	log.PanicIf(err)
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	orientation, err := index.RootIfd.FindTagWithName("Orientation")
+	log.PanicIf(err)
+
+	value, err := orientation[0].Value()
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(value, []uint16{8}) != true {
+		t.Fatalf("Orientation not correct: %v", value)
+	}
+
+	if err := ib.SetStandardWithName("Orientation", 8.5); log.Is(err, ErrValueNotConvertible) == false {
+		t.Fatalf("Expected not-convertible error: %v", err)
+	}
+}
+
+func TestRationalFromFloat(t *testing.T) {
+	cases := []struct {
+		value       float64
+		numerator   int64
+		denominator int64
+	}{
+		{0, 0, 1},
+		{3, 3, 1},
+		{0.004, 1, 250},
+		{2.8, 14, 5},
+		{-0.3333333333, -1, 3},
+		{0.000125, 1, 8000},
+	}
+
+	for _, c := range cases {
+		numerator, denominator := rationalFromFloat(c.value, coercionMaxDenominator)
+		if numerator != c.numerator || denominator != c.denominator {
+			t.Fatalf("Fraction for (%f) not correct: (%d)/(%d)", c.value, numerator, denominator)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package exif
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/dsoprea/go-logging"
+)
+
+// ValueMiddleware observes or changes the decoded values of tags as they're
+// parsed, so that a policy (e.g. normalizing whitespace, redacting, or
+// converting units) applies to every caller without each of them
+// post-processing the index. It sees the value after the field codec and the
+// quirks. Only decoded values are affected: the raw bytes (e.g.
+// `GetRawBytes()`, and builders made from the index) are as they were. The
+// tags that give the structure (pointers to child IFDs, and the offset and
+// size of the thumbnail) aren't given to it.
+type ValueMiddleware struct {
+	// Name identifies the middleware.
+	Name string
+
+	// IfdPath, if not empty, is the only IFD (e.g. "IFD/GPSInfo") whose tags
+	// the middleware is given.
+	IfdPath string
+
+	// TagIds, if not empty, are the only tags that the middleware is given.
+	TagIds []uint16
+
+	// Transform returns the value that the entry should be decoded as. It
+	// returns the value as it is to only observe it. An error fails the
+	// parse.
+	Transform func(ifdPath string, tagId uint16, value interface{}) (transformed interface{}, err error)
+}
+
+// hasTag returns true if the middleware is given the tag.
+func (vm ValueMiddleware) hasTag(ifdPath string, tagId uint16) bool {
+	if vm.IfdPath != "" && vm.IfdPath != ifdPath {
+		return false
+	} else if len(vm.TagIds) == 0 {
+		return true
+	}
+
+	for _, id := range vm.TagIds {
+		if id == tagId {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	valueMiddleware      = make([]ValueMiddleware, 0)
+	valueMiddlewareMutex sync.RWMutex
+)
+
+// RegisterValueMiddleware adds a middleware, after those already registered.
+// It applies to whatever is parsed afterward.
+func RegisterValueMiddleware(vm ValueMiddleware) {
+	valueMiddlewareMutex.Lock()
+	defer valueMiddlewareMutex.Unlock()
+
+	valueMiddleware = append(valueMiddleware, vm)
+}
+
+// getRegisteredValueMiddleware returns a copy of the registered middleware.
+func getRegisteredValueMiddleware() []ValueMiddleware {
+	valueMiddlewareMutex.RLock()
+	defer valueMiddlewareMutex.RUnlock()
+
+	return append([]ValueMiddleware{}, valueMiddleware...)
+}
+
+// SetValueMiddleware has the enumerator apply the given middleware, in order,
+// rather than the registered middleware. An empty list applies none.
+func (ie *IfdEnumerate) SetValueMiddleware(middleware []ValueMiddleware) {
+	ie.valueMiddleware = middleware
+	ie.valueMiddlewareSelected = middleware != nil
+}
+
+// transformEntryValue applies the middleware to the value of the entry.
+// Values that can't be decoded are left alone.
+func (ie *IfdEnumerate) transformEntryValue(ite *IfdTagEntry) {
+	if ite.ChildIfdPath() != "" || ite.tagId == ThumbnailOffsetTagId || ite.tagId == ThumbnailSizeTagId {
+		return
+	} else if ie.valueMiddlewareSelected == false {
+		ie.valueMiddleware = getRegisteredValueMiddleware()
+		ie.valueMiddlewareSelected = true
+	}
+
+	var value interface{}
+	decoded := false
+
+	for _, vm := range ie.valueMiddleware {
+		if vm.Transform == nil || vm.hasTag(ite.ifdPath, ite.tagId) == false {
+			continue
+		}
+
+		if decoded == false {
+			var err error
+
+			value, err = ite.Value()
+			if err != nil {
+				return
+			}
+
+			decoded = true
+		}
+
+		transformed, err := vm.Transform(ite.ifdPath, ite.tagId, value)
+		if err != nil {
+			ifdEnumerateLogger.Warningf(nil, "Value middleware [%s] failed for tag (0x%04x) in IFD [%s].", vm.Name, ite.tagId, ite.ifdPath)
+			log.Panic(err)
+		}
+
+		value = transformed
+	}
+
+	if decoded == false {
+		return
+	}
+
+	ite.decoded = &decodedValue{
+		value: value,
+	}
+}
+
+// NewWhitespaceMiddleware returns a middleware that trims the string values
+// and collapses each run of whitespace within them into a single space.
+func NewWhitespaceMiddleware() ValueMiddleware {
+	return ValueMiddleware{
+		Name: "whitespace",
+		Transform: func(ifdPath string, tagId uint16, value interface{}) (transformed interface{}, err error) {
+			if s, ok := value.(string); ok == true {
+				return strings.Join(strings.Fields(s), " "), nil
+			}
+
+			return value, nil
+		},
+	}
+}
+
+// NewRedactMiddleware returns a middleware that replaces the values of the
+// tags in the IFD (all of them, if no tags are given) with zero values of the
+// same type. Lists keep their length, so code that expects a number of
+// values still gets them. Only decoded values are redacted (see
+// `ValueMiddleware`); use `ExifEditor.Delete()` to remove them from the file.
+func NewRedactMiddleware(ifdPath string, tagIds ...uint16) ValueMiddleware {
+	return ValueMiddleware{
+		Name:    "redact",
+		IfdPath: ifdPath,
+		TagIds:  tagIds,
+		Transform: func(ifdPath string, tagId uint16, value interface{}) (transformed interface{}, err error) {
+			return redactValue(value), nil
+		},
+	}
+}
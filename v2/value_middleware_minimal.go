@@ -0,0 +1,33 @@
+//go:build tinygo || exif_minimal
+// +build tinygo exif_minimal
+
+package exif
+
+import (
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+// redactValue returns the zero value of the value's type. Slices keep their
+// length. This is the reduced-footprint version of the one in
+// value_middleware_reflect.go: it doesn't use reflection, so the values of
+// undefined-type tags are redacted to nil.
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return ""
+	case []byte:
+		return make([]byte, len(v))
+	case []uint16:
+		return make([]uint16, len(v))
+	case []uint32:
+		return make([]uint32, len(v))
+	case []int32:
+		return make([]int32, len(v))
+	case []exifcommon.Rational:
+		return make([]exifcommon.Rational, len(v))
+	case []exifcommon.SignedRational:
+		return make([]exifcommon.SignedRational, len(v))
+	}
+
+	return nil
+}
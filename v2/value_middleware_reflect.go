@@ -0,0 +1,24 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"reflect"
+)
+
+// redactValue returns the zero value of the value's type. Slices keep their
+// length. See value_middleware_minimal.go for the reduced-footprint build.
+func redactValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(value)
+	if t.Kind() == reflect.Slice {
+		n := reflect.ValueOf(value).Len()
+		return reflect.MakeSlice(t, n, n).Interface()
+	}
+
+	return reflect.Zero(t).Interface()
+}
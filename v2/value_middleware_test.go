@@ -0,0 +1,185 @@
+package exif
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+// getTestMiddlewareExif returns realistic EXIF with a Model with extra
+// whitespace.
+func getTestMiddlewareExif() []byte {
+	root := exiftest.NewRealisticIfd()
+	root.Tags[1].Value = "  Canon EOS   5D Mark III "
+
+	rawExif, err := exiftest.Build(root, binary.BigEndian)
+	log.PanicIf(err)
+
+	return rawExif
+}
+
+// collectTestMiddleware parses the EXIF with the given middleware, or with the
+// registered middleware if nil.
+func collectTestMiddleware(rawExif []byte, middleware []ValueMiddleware) (index IfdIndex, err error) {
+	eh, err := ParseExifHeader(rawExif)
+	log.PanicIf(err)
+
+	ie := NewIfdEnumerate(NewIfdMappingWithStandard(), NewTagIndex(), rawExif, eh.ByteOrder)
+
+	if middleware != nil {
+		ie.SetValueMiddleware(middleware)
+	}
+
+	return ie.Collect(eh.FirstIfdOffset)
+}
+
+func TestIfdEnumerate_SetValueMiddleware(t *testing.T) {
+	seen := make([]uint16, 0)
+
+	observe := ValueMiddleware{
+		Name:    "observe",
+		IfdPath: exifcommon.IfdPathStandardExif,
+		Transform: func(ifdPath string, tagId uint16, value interface{}) (transformed interface{}, err error) {
+			seen = append(seen, tagId)
+			return value, nil
+		},
+	}
+
+	// The focal length is converted to centimeters.
+	centimeters := ValueMiddleware{
+		Name:    "centimeters",
+		IfdPath: exifcommon.IfdPathStandardExif,
+		TagIds:  []uint16{0x920a},
+		Transform: func(ifdPath string, tagId uint16, value interface{}) (transformed interface{}, err error) {
+			rationals := value.([]exifcommon.Rational)
+			return []exifcommon.Rational{{Numerator: rationals[0].Numerator, Denominator: rationals[0].Denominator * 10}}, nil
+		},
+	}
+
+	middleware := []ValueMiddleware{
+		NewWhitespaceMiddleware(),
+		NewRedactMiddleware(exifcommon.IfdPathStandardGps),
+		centimeters,
+		observe,
+	}
+
+	index, err := collectTestMiddleware(getTestMiddlewareExif(), middleware)
+	log.PanicIf(err)
+
+	model, err := getIfdTagString(index.RootIfd, "Model")
+	log.PanicIf(err)
+
+	if model != "Canon EOS 5D Mark III" {
+		t.Fatalf("Whitespace not normalized: [%s]", model)
+	}
+
+	gpsIfd := index.Lookup[exifcommon.IfdPathStandardGps][0]
+
+	latitude, err := gpsIfd.EntriesByTagId[0x0002][0].Value()
+	log.PanicIf(err)
+
+	latitudeRef, err := gpsIfd.EntriesByTagId[0x0001][0].Value()
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(latitude, make([]exifcommon.Rational, 3)) != true || latitudeRef != "" {
+		t.Fatalf("GPS not redacted: %v [%v]", latitude, latitudeRef)
+	}
+
+	// The raw bytes aren't redacted.
+	raw, err := gpsIfd.EntriesByTagId[0x0002][0].GetRawBytes()
+	log.PanicIf(err)
+
+	if raw[3] != 26 {
+		t.Fatalf("Raw bytes should be as they were: %v", raw)
+	}
+
+	exifIfd := index.Lookup[exifcommon.IfdPathStandardExif][0]
+
+	focalLength, err := exifIfd.EntriesByTagId[0x920a][0].Value()
+	log.PanicIf(err)
+
+	if reflect.DeepEqual(focalLength, []exifcommon.Rational{{Numerator: 50, Denominator: 10}}) != true {
+		t.Fatalf("Focal length not converted: %v", focalLength)
+	} else if len(seen) != len(exifIfd.Entries) {
+		t.Fatalf("Not every tag observed: %v", seen)
+	}
+}
+
+func TestIfdEnumerate_SetValueMiddleware_Structure(t *testing.T) {
+	// Every tag of IFD0 and IFD1 is redacted, but the child IFDs and the
+	// thumbnail are still found.
+	index, err := collectTestMiddleware(getTestExifData(), []ValueMiddleware{NewRedactMiddleware(exifcommon.IfdPathStandard)})
+	log.PanicIf(err)
+
+	model, err := getIfdTagString(index.RootIfd, "Model")
+	log.PanicIf(err)
+
+	if model != "" {
+		t.Fatalf("Model not redacted: [%s]", model)
+	} else if len(index.Lookup[exifcommon.IfdPathStandardExif]) != 1 {
+		t.Fatalf("Exif IFD not found.")
+	}
+
+	thumbnail, err := index.RootIfd.NextIfd.Thumbnail()
+	log.PanicIf(err)
+
+	if len(thumbnail) == 0 {
+		t.Fatalf("Thumbnail not found.")
+	}
+}
+
+func TestIfdEnumerate_SetValueMiddleware_Error(t *testing.T) {
+	errRejected := errors.New("rejected")
+
+	reject := ValueMiddleware{
+		Name:   "reject",
+		TagIds: []uint16{0x0110},
+		Transform: func(ifdPath string, tagId uint16, value interface{}) (transformed interface{}, err error) {
+			return nil, errRejected
+		},
+	}
+
+	_, err := collectTestMiddleware(getTestMiddlewareExif(), []ValueMiddleware{reject})
+	if errors.Is(err, errRejected) == false {
+		t.Fatalf("Expected the middleware's error: %v", err)
+	}
+}
+
+func TestRegisterValueMiddleware(t *testing.T) {
+	original := valueMiddleware
+	defer func() {
+		valueMiddleware = original
+	}()
+
+	RegisterValueMiddleware(NewWhitespaceMiddleware())
+
+	rawExif := getTestMiddlewareExif()
+
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), rawExif)
+	log.PanicIf(err)
+
+	model, err := getIfdTagString(index.RootIfd, "Model")
+	log.PanicIf(err)
+
+	if model != "Canon EOS 5D Mark III" {
+		t.Fatalf("Registered middleware not applied: [%s]", model)
+	}
+
+	// An empty list overrides the registered middleware.
+	index, err = collectTestMiddleware(rawExif, []ValueMiddleware{})
+	log.PanicIf(err)
+
+	value, err := index.RootIfd.EntriesByTagId[0x0110][0].Value()
+	log.PanicIf(err)
+
+	if value != "  Canon EOS   5D Mark III" {
+		t.Fatalf("Middleware should not be applied: [%v]", value)
+	}
+}
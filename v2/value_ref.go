@@ -0,0 +1,248 @@
+package exif
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/common"
+)
+
+var (
+	// ErrValueRefReleased means that the reference has been released or its
+	// source has been closed, so its bytes may no longer be valid.
+	ErrValueRefReleased = errors.New("value reference released")
+
+	// ErrValueRefsOutstanding is returned when a source is closed while
+	// references to it haven't been released. The source is closed anyway.
+	ErrValueRefsOutstanding = errors.New("value references outstanding")
+)
+
+// ValueSource is data that values are read from without being copied, such
+// as a memory-mapped file. Parsing never copies the EXIF data, so the raw
+// bytes of every entry point into it and are only valid for as long as it's
+// mapped. Take a `ValueRef` to each value that's kept and `Release()` (or
+// `Copy()` and then release) it before calling `Close()`, which is what
+// unmaps the data. Build with the `exif_audit` tag to check that they are
+// (see `MemoryAuditEnabled()`).
+type ValueSource struct {
+	data   []byte
+	closer func() error
+
+	m      sync.Mutex
+	refs   int
+	closed bool
+
+	// audit is true in audit mode (see `MemoryAuditEnabled()`).
+	audit      bool
+	audited    []*ValueRef
+	violations []error
+}
+
+// NewValueSource returns a source for the given data. `closer`, if not nil,
+// is called by `Close()` (e.g. to unmap the data).
+func NewValueSource(data []byte, closer func() error) *ValueSource {
+	return &ValueSource{
+		data:   data,
+		closer: closer,
+		audit:  memoryAuditBuild,
+	}
+}
+
+// Data returns the data, to be parsed.
+func (vs *ValueSource) Data() []byte {
+	return vs.data
+}
+
+// Outstanding returns the number of references that haven't been released.
+func (vs *ValueSource) Outstanding() int {
+	vs.m.Lock()
+	defer vs.m.Unlock()
+
+	return vs.refs
+}
+
+// rawView returns the raw (undecoded) bytes of the value as they are in the
+// data that it was parsed from. Unlike `GetRawBytes()`, the bytes of
+// undefined-type values aren't decoded and re-encoded.
+func (ite *IfdTagEntry) rawView() (raw []byte, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	size, err := ite.EncodedSize()
+	log.PanicIf(err)
+
+	if size <= 4 {
+		return ite.rawValueOffset[:size], nil
+	} else if ite.addressableReader != nil {
+		// There's nothing in memory to view, so it's read.
+
+		raw, err = ite.getValueContext().ReadRawEncoded()
+		log.PanicIf(err)
+
+		return raw, nil
+	}
+
+	raw, err = exifcommon.CheckedSlice(ite.addressableData, ite.getValueOffset(), size)
+	log.PanicIf(err)
+
+	return raw, nil
+}
+
+// Ref returns a reference to the raw bytes of the entry, which must have been
+// parsed from this source.
+func (vs *ValueSource) Ref(ite *IfdTagEntry) (vr *ValueRef, err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	raw, err := ite.rawView()
+	log.PanicIf(err)
+
+	vs.m.Lock()
+	defer vs.m.Unlock()
+
+	if vs.closed == true {
+		return nil, ErrValueRefReleased
+	}
+
+	vs.refs++
+
+	vr = &ValueRef{
+		source: vs,
+		data:   raw,
+	}
+
+	if vs.audit == true {
+		vr.guarded = newGuardedBuffer(raw)
+		vr.data = vr.guarded.value()
+		vr.auditId = len(vs.audited)
+
+		vs.audited = append(vs.audited, vr)
+	}
+
+	return vr, nil
+}
+
+// Close invalidates every reference and calls the closer.
+// `ErrValueRefsOutstanding` is returned if any of them weren't released,
+// since their bytes may still be in use. In audit mode,
+// `ErrMemoryAuditFailed` is returned instead if any of them were misused.
+func (vs *ValueSource) Close() (err error) {
+	defer func() {
+		if state := recover(); state != nil {
+			err = log.Wrap(state.(error))
+		}
+	}()
+
+	vs.m.Lock()
+
+	if vs.closed == true {
+		vs.m.Unlock()
+		return nil
+	}
+
+	vs.closed = true
+	outstanding := vs.refs
+
+	for _, vr := range vs.audited {
+		vr.auditCheck()
+
+		if vr.guarded.poisoned == false {
+			vr.guarded.poison()
+		}
+	}
+
+	violations := len(vs.violations)
+
+	vs.m.Unlock()
+
+	if vs.closer != nil {
+		err := vs.closer()
+		log.PanicIf(err)
+	}
+
+	if violations > 0 {
+		return ErrMemoryAuditFailed
+	} else if outstanding > 0 {
+		return ErrValueRefsOutstanding
+	}
+
+	return nil
+}
+
+// ValueRef is a read-only view of a value's raw bytes in a `ValueSource`. The
+// bytes returned by `Bytes()` are only valid until the reference is released
+// or the source is closed, and mustn't be modified. Use `Copy()` to keep
+// them longer.
+type ValueRef struct {
+	source   *ValueSource
+	data     []byte
+	released bool
+
+	// guarded has the bytes in audit mode.
+	guarded *guardedBuffer
+	auditId int
+}
+
+// Len returns the number of bytes.
+func (vr *ValueRef) Len() int {
+	return len(vr.data)
+}
+
+// valid returns true if the reference can still be read.
+func (vr *ValueRef) valid() bool {
+	vr.source.m.Lock()
+	defer vr.source.m.Unlock()
+
+	return vr.released == false && vr.source.closed == false
+}
+
+// Bytes returns the bytes in the source, without copying them.
+// `ErrValueRefReleased` is returned if the reference was released or the
+// source was closed.
+func (vr *ValueRef) Bytes() (data []byte, err error) {
+	if vr.valid() == false {
+		return nil, ErrValueRefReleased
+	}
+
+	return vr.data, nil
+}
+
+// Copy returns a copy of the bytes that the caller owns.
+// `ErrValueRefReleased` is returned if the reference was released or the
+// source was closed.
+func (vr *ValueRef) Copy() (data []byte, err error) {
+	if vr.valid() == false {
+		return nil, ErrValueRefReleased
+	}
+
+	data = make([]byte, len(vr.data))
+	copy(data, vr.data)
+
+	return data, nil
+}
+
+// Release gives up the reference. It can be called more than once.
+func (vr *ValueRef) Release() {
+	vr.source.m.Lock()
+	defer vr.source.m.Unlock()
+
+	if vr.released == true {
+		return
+	}
+
+	vr.released = true
+	vr.source.refs--
+
+	if vr.guarded != nil && vr.source.closed == false {
+		vr.auditCheck()
+		vr.guarded.poison()
+	}
+}
@@ -0,0 +1,118 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"encoding/binary"
+
+	"github.com/dsoprea/go-logging"
+
+	"github.com/dsoprea/go-exif/v2/exiftest"
+)
+
+func getTestValueSourceIndex(vs *ValueSource) IfdIndex {
+	_, index, err := Collect(NewIfdMappingWithStandard(), NewTagIndex(), vs.Data())
+	log.PanicIf(err)
+
+	return index
+}
+
+func TestValueRef(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	vs := NewValueSource(rawExif, nil)
+	index := getTestValueSourceIndex(vs)
+
+	results, err := index.RootIfd.FindTagWithName("Make")
+	log.PanicIf(err)
+
+	vr, err := vs.Ref(results[0])
+	log.PanicIf(err)
+
+	view, err := vr.Bytes()
+	log.PanicIf(err)
+
+	if string(view) != "Canon\x00" {
+		t.Fatalf("View not correct: %q", view)
+	} else if vs.Outstanding() != 1 {
+		t.Fatalf("Expected one outstanding reference: (%d)", vs.Outstanding())
+	}
+
+	// The view is of the source itself, except in audit mode, where changing
+	// it would be a violation.
+	if MemoryAuditEnabled() == false {
+		view[0] = 'c'
+		if bytes.Contains(rawExif, []byte("canon")) == false {
+			t.Fatalf("View was copied.")
+		}
+
+		copied, err := vr.Copy()
+		log.PanicIf(err)
+
+		copied[0] = 'C'
+		if bytes.Contains(rawExif, []byte("canon")) == false {
+			t.Fatalf("Copy wasn't copied.")
+		}
+	}
+
+	vr.Release()
+	vr.Release()
+
+	if vs.Outstanding() != 0 {
+		t.Fatalf("Expected no outstanding references: (%d)", vs.Outstanding())
+	} else if _, err := vr.Bytes(); err != ErrValueRefReleased {
+		t.Fatalf("Expected released error: %v", err)
+	}
+
+	err = vs.Close()
+	log.PanicIf(err)
+}
+
+func TestValueSource_Close_Outstanding(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	closed := 0
+	vs := NewValueSource(rawExif, func() error {
+		closed++
+		return nil
+	})
+
+	index := getTestValueSourceIndex(vs)
+
+	vr, err := vs.Ref(index.RootIfd.Entries[0])
+	log.PanicIf(err)
+
+	if err := vs.Close(); err != ErrValueRefsOutstanding {
+		t.Fatalf("Expected outstanding error: %v", err)
+	} else if closed != 1 {
+		t.Fatalf("Closer not called once: (%d)", closed)
+	} else if _, err := vr.Copy(); err != ErrValueRefReleased {
+		t.Fatalf("Expected released error after close: %v", err)
+	} else if _, err := vs.Ref(index.RootIfd.Entries[0]); err != ErrValueRefReleased {
+		t.Fatalf("Expected released error for a new reference: %v", err)
+	}
+
+	// Closing again does nothing.
+	if err := vs.Close(); err != nil {
+		t.Fatalf("Second close failed: %v", err)
+	} else if closed != 1 {
+		t.Fatalf("Closer called again: (%d)", closed)
+	}
+}
+
+func TestValueSource_Close_CloserError(t *testing.T) {
+	rawExif, err := exiftest.Build(exiftest.NewRealisticIfd(), binary.BigEndian)
+	log.PanicIf(err)
+
+	vs := NewValueSource(rawExif, func() error {
+		return errors.New("unmap failed")
+	})
+
+	if err := vs.Close(); err == nil || err.Error() != "unmap failed" {
+		t.Fatalf("Expected closer error: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"encoding/xml"
+)
+
+const (
+	// DcNamespace is the namespace of the Dublin Core XMP properties (e.g.
+	// dc:creator and dc:subject).
+	DcNamespace = "http://purl.org/dc/elements/1.1/"
+)
+
+var (
+	rdfDescriptionTagRe = regexp.MustCompile(`<rdf:Description\b[^>]*?(/?)>`)
+	rdfListItemRe       = regexp.MustCompile(`(?s)<rdf:li\b[^>]*>(.*?)</rdf:li>`)
+)
+
+// xmpArrayItems returns the items of an XMP array property (e.g.
+// "dc:creator"), whether it's a Seq, Bag, or Alt. A simple (non-array) value
+// is returned as a single item.
+func xmpArrayItems(xmp []byte, property string) []string {
+	items := make([]string, 0)
+
+	re := regexp.MustCompile(`(?s)<` + regexp.QuoteMeta(property) + `>(.*?)</` + regexp.QuoteMeta(property) + `>`)
+
+	matches := re.FindSubmatch(xmp)
+	if matches == nil {
+		return items
+	}
+
+	content := matches[1]
+
+	listItems := rdfListItemRe.FindAllSubmatch(content, -1)
+	if listItems == nil {
+		if value := strings.TrimSpace(html.UnescapeString(string(content))); value != "" {
+			items = append(items, value)
+		}
+
+		return items
+	}
+
+	for _, listItem := range listItems {
+		items = append(items, strings.TrimSpace(html.UnescapeString(string(listItem[1]))))
+	}
+
+	return items
+}
+
+// mergeXmpArray returns the XMP packet with the given array property (e.g.
+// "dc:subject") replaced by the given items. `arrayType` is "Seq", "Bag", or
+// "Alt" (whose items are given the "x-default" language). The property is
+// removed if there are no items. The namespace is declared on the
+// description if it isn't declared anywhere. A new packet is created if
+// `xmp` is empty or has no description.
+func mergeXmpArray(xmp []byte, property, arrayType, namespaceUri string, items []string) []byte {
+	prefix := property[:strings.Index(property, ":")]
+
+	if rdfDescriptionTagRe.Match(xmp) == false {
+		xmp = []byte(fmt.Sprintf(xmpPacketTemplate, ""))
+	}
+
+	re := regexp.MustCompile(`(?s)\s*<` + regexp.QuoteMeta(property) + `>.*?</` + regexp.QuoteMeta(property) + `>`)
+	cleaned := re.ReplaceAll(xmp, nil)
+
+	if len(items) == 0 {
+		return cleaned
+	}
+
+	element := new(bytes.Buffer)
+
+	fmt.Fprintf(element, "\n    <%s>\n     <rdf:%s>", property, arrayType)
+
+	for _, item := range items {
+		escaped := new(bytes.Buffer)
+		xml.EscapeText(escaped, []byte(item))
+
+		if arrayType == "Alt" {
+			fmt.Fprintf(element, "\n      <rdf:li xml:lang=\"x-default\">%s</rdf:li>", escaped)
+		} else {
+			fmt.Fprintf(element, "\n      <rdf:li>%s</rdf:li>", escaped)
+		}
+	}
+
+	fmt.Fprintf(element, "\n     </rdf:%s>\n    </%s>", arrayType, property)
+
+	namespaceDeclaration := ""
+	if bytes.Contains(cleaned, []byte("xmlns:"+prefix+"=")) == false {
+		namespaceDeclaration = fmt.Sprintf(" xmlns:%s=\"%s\"", prefix, namespaceUri)
+	}
+
+	location := rdfDescriptionTagRe.FindSubmatchIndex(cleaned)
+	tagEnd := location[1]
+	isSelfClosing := location[3] > location[2]
+
+	merged := make([]byte, 0, len(cleaned)+element.Len()+len(namespaceDeclaration)+32)
+
+	if isSelfClosing == true {
+		// Turn "<rdf:Description ... />" into an element with content.
+		merged = append(merged, cleaned[:location[2]]...)
+		merged = append(merged, namespaceDeclaration...)
+		merged = append(merged, '>')
+		merged = append(merged, element.Bytes()...)
+		merged = append(merged, "\n</rdf:Description>"...)
+	} else {
+		merged = append(merged, cleaned[:tagEnd-1]...)
+		merged = append(merged, namespaceDeclaration...)
+		merged = append(merged, '>')
+		merged = append(merged, element.Bytes()...)
+	}
+
+	merged = append(merged, cleaned[tagEnd:]...)
+
+	return merged
+}
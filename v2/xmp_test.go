@@ -0,0 +1,79 @@
+//go:build !tinygo && !exif_minimal
+// +build !tinygo,!exif_minimal
+
+package exif
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXmpArrayItems(t *testing.T) {
+	xmp := []byte(`<rdf:Description>
+    <dc:creator>
+     <rdf:Seq>
+      <rdf:li>Jane Doe</rdf:li>
+      <rdf:li>John &amp; Co</rdf:li>
+     </rdf:Seq>
+    </dc:creator>
+    <xmp:Label>Red</xmp:Label>
+   </rdf:Description>`)
+
+	items := xmpArrayItems(xmp, "dc:creator")
+	if reflect.DeepEqual(items, []string{"Jane Doe", "John & Co"}) != true {
+		t.Fatalf("Array items not correct: %v", items)
+	}
+
+	items = xmpArrayItems(xmp, "xmp:Label")
+	if reflect.DeepEqual(items, []string{"Red"}) != true {
+		t.Fatalf("Simple value not correct: %v", items)
+	}
+
+	items = xmpArrayItems(xmp, "dc:subject")
+	if len(items) != 0 {
+		t.Fatalf("Missing property should have no items: %v", items)
+	}
+}
+
+func TestMergeXmpArray(t *testing.T) {
+	xmp := mergeXmpArray(nil, "dc:subject", "Bag", DcNamespace, []string{"beach", "sunset <evening>"})
+
+	items := xmpArrayItems(xmp, "dc:subject")
+	if reflect.DeepEqual(items, []string{"beach", "sunset <evening>"}) != true {
+		t.Fatalf("Items not correct in new packet: %v\n%s", items, xmp)
+	}
+
+	xmp = mergeXmpArray(xmp, "dc:subject", "Bag", DcNamespace, []string{"mountains"})
+
+	items = xmpArrayItems(xmp, "dc:subject")
+	if reflect.DeepEqual(items, []string{"mountains"}) != true {
+		t.Fatalf("Items not replaced: %v\n%s", items, xmp)
+	}
+
+	// A description that isn't self-closing, with the namespace already
+	// declared.
+
+	original := []byte(`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <xmp:Label>Red</xmp:Label>
+   </rdf:Description>`)
+
+	xmp = mergeXmpArray(original, "dc:rights", "Alt", DcNamespace, []string{"(c) Jane Doe"})
+
+	expected := `<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:rights>
+     <rdf:Alt>
+      <rdf:li xml:lang="x-default">(c) Jane Doe</rdf:li>
+     </rdf:Alt>
+    </dc:rights>
+    <xmp:Label>Red</xmp:Label>
+   </rdf:Description>`
+
+	if string(xmp) != expected {
+		t.Fatalf("Merged XMP not correct:\n%s", xmp)
+	}
+
+	xmp = mergeXmpArray(xmp, "dc:rights", "Alt", DcNamespace, nil)
+	if string(xmp) != string(original) {
+		t.Fatalf("Property not removed:\n%s", xmp)
+	}
+}